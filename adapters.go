@@ -0,0 +1,46 @@
+// adapters.go - Registry of framework adapters resolved by For
+package ratelimit
+
+import (
+	"sync"
+)
+
+// registeredAdapter creates the middleware of a framework adapter package
+type registeredAdapter struct {
+	create func(limiter Limiter) interface{}
+	typ    string // Go type of the middleware, e.g. "gin.HandlerFunc"
+}
+
+// adapters holds the adapters registered by adapter packages, keyed by framework
+var adapters sync.Map
+
+// RegisterAdapter makes For(framework) return the middleware adapter creates instead of the
+// built-in one. The adapter modules, such as ginlimit, echolimit and fiberlimit, register
+// themselves when imported, so For(Gin) returns a gin.HandlerFunc in applications that
+// import ginlimit while the core module itself depends on no framework. Adapters are looked
+// up on every call to For, so the order of imports and builds does not matter.
+// Example: ratelimit.RegisterAdapter(ratelimit.Echo, echolimit.Middleware)
+func RegisterAdapter[M any](framework FrameworkType, adapter func(limiter Limiter) M) {
+	adapters.Store(framework, registeredAdapter{
+		create: func(limiter Limiter) interface{} { return adapter(limiter) },
+		typ:    typeName[M](),
+	})
+}
+
+// registeredAdapterFor returns the adapter registered for a framework
+func registeredAdapterFor(framework FrameworkType) (registeredAdapter, bool) {
+	adapter, ok := adapters.Load(framework)
+	if !ok {
+		return registeredAdapter{}, false
+	}
+	return adapter.(registeredAdapter), true
+}
+
+// adapterFor returns the middleware of the adapter registered for a framework, if any
+func adapterFor(limiter Limiter, framework FrameworkType) (interface{}, bool) {
+	adapter, ok := registeredAdapterFor(framework)
+	if !ok {
+		return nil, false
+	}
+	return adapter.create(limiter), true
+}
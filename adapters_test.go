@@ -0,0 +1,155 @@
+// adapters_test.go
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRegisterAdapter(t *testing.T) {
+	// A framework no built-in adapter knows, so other tests keep the built-in adapters
+	const framework = FrameworkType(99)
+	var created Limiter
+	RegisterAdapter(framework, func(limiter Limiter) http.HandlerFunc {
+		created = limiter
+		return func(http.ResponseWriter, *http.Request) {}
+	})
+	defer adapters.Delete(framework)
+
+	limiter, err := New().Limit("global", "10/minute").Build()
+	if err != nil {
+		t.Fatalf("Failed to build limiter: %v", err)
+	}
+	defer limiter.Close()
+
+	if _, ok := limiter.For(framework).(http.HandlerFunc); !ok {
+		t.Errorf("Expected For to return the registered adapter, got %T", limiter.For(framework))
+	}
+	if created != limiter {
+		t.Error("Expected the adapter to be created for the limiter")
+	}
+
+	composed := All(limiter)
+	if _, ok := composed.For(framework).(http.HandlerFunc); !ok {
+		t.Errorf("Expected compositions to use the registered adapter, got %T", composed.For(framework))
+	}
+
+	adapter, ok := registeredAdapterFor(framework)
+	if !ok || adapter.typ != "http.HandlerFunc" {
+		t.Errorf("Expected the adapter type to be recorded, got %q", adapter.typ)
+	}
+	if _, ok := limiter.For(HTTP).(func(http.Handler) http.Handler); !ok {
+		t.Errorf("Expected unregistered frameworks to keep the built-in adapter, got %T", limiter.For(HTTP))
+	}
+}
+
+// fakeFiberCtx has the methods of *fiber.Ctx the built-in Fiber adapter calls, so its
+// reflection-based handler is tested without the core module depending on Fiber
+type fakeFiberCtx struct {
+	method, path, ip string
+	reqHeaders       map[string][]string
+	respHeaders      http.Header
+	status           int
+	body             []byte
+	next             func(*fakeFiberCtx) error
+}
+
+func newFakeFiberCtx(method, path string, headers map[string]string) *fakeFiberCtx {
+	ctx := &fakeFiberCtx{method: method, path: path, ip: "192.168.1.1", reqHeaders: make(map[string][]string), respHeaders: make(http.Header), status: http.StatusOK}
+	for name, value := range headers {
+		ctx.reqHeaders[name] = []string{value}
+	}
+	return ctx
+}
+
+func (c *fakeFiberCtx) Method(override ...string) string { return c.method }
+func (c *fakeFiberCtx) Path(override ...string) string   { return c.path }
+func (c *fakeFiberCtx) IP() string                       { return c.ip }
+
+func (c *fakeFiberCtx) GetReqHeaders() map[string][]string { return c.reqHeaders }
+
+func (c *fakeFiberCtx) Set(key, val string) { c.respHeaders.Set(key, val) }
+
+func (c *fakeFiberCtx) Status(status int) *fakeFiberCtx {
+	c.status = status
+	return c
+}
+
+func (c *fakeFiberCtx) Send(body []byte) error {
+	c.body = append(c.body, body...)
+	return nil
+}
+
+func (c *fakeFiberCtx) Next() error {
+	if c.next == nil {
+		return nil
+	}
+	return c.next(c)
+}
+
+func (c *fakeFiberCtx) Response() fakeFiberResponse { return fakeFiberResponse{c} }
+
+// fakeFiberResponse stands in for the *fasthttp.Response of a Fiber context
+type fakeFiberResponse struct{ ctx *fakeFiberCtx }
+
+func (r fakeFiberResponse) Body() []byte { return r.ctx.body }
+
+// fakeGinCtx has the fields and methods of *gin.Context the built-in Gin adapter uses
+type fakeGinCtx struct {
+	Request *http.Request
+	Writer  *fakeGinWriter
+	aborted bool
+	next    func(*fakeGinCtx)
+}
+
+func newFakeGinCtx(r *http.Request) *fakeGinCtx {
+	return &fakeGinCtx{Request: r, Writer: &fakeGinWriter{ResponseRecorder: httptest.NewRecorder()}}
+}
+
+func (c *fakeGinCtx) Abort() { c.aborted = true }
+
+func (c *fakeGinCtx) Next() {
+	if c.next != nil {
+		c.next(c)
+	}
+}
+
+// fakeGinWriter counts the bytes written, as gin.ResponseWriter does
+type fakeGinWriter struct {
+	*httptest.ResponseRecorder
+	size int
+}
+
+func (w *fakeGinWriter) Write(b []byte) (int, error) {
+	w.size += len(b)
+	return w.ResponseRecorder.Write(b)
+}
+
+func (w *fakeGinWriter) Size() int { return w.size }
+
+// fakeEchoCtx has the methods of echo.Context the built-in Echo adapter uses
+type fakeEchoCtx struct {
+	request  *http.Request
+	response *fakeEchoResponse
+}
+
+func newFakeEchoCtx(r *http.Request) *fakeEchoCtx {
+	return &fakeEchoCtx{request: r, response: &fakeEchoResponse{Writer: httptest.NewRecorder()}}
+}
+
+func (c *fakeEchoCtx) Request() *http.Request { return c.request }
+
+func (c *fakeEchoCtx) Response() *fakeEchoResponse { return c.response }
+
+// fakeEchoResponse has the fields of *echo.Response, which counts the bytes written
+type fakeEchoResponse struct {
+	Writer http.ResponseWriter
+	Size   int64
+}
+
+func (r *fakeEchoResponse) Write(b []byte) (int, error) {
+	n, err := r.Writer.Write(b)
+	r.Size += int64(n)
+	return n, err
+}
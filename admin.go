@@ -0,0 +1,69 @@
+// Package ratelimit provides administrative HTTP handlers for operators
+package ratelimit
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/itsatony/gorly/internal/core"
+)
+
+// maxLimitScale is the largest multiplier accepted for Scale
+const maxLimitScale = core.MaxScale
+
+// ScaleHandler creates an admin handler that reads (GET) or sets (PUT/POST) the limit multiplier.
+// The new factor is taken from ?factor= or a JSON body like {"scale": 0.5}.
+// The handler performs no authentication; mount it behind your admin auth or ProtectAdmin.
+// Example: adminMux.Handle("/admin/scale", ratelimit.ScaleHandler(limiter))
+func ScaleHandler(limiter Limiter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead:
+		case http.MethodPut, http.MethodPost:
+			factor, err := requestedScale(r)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if err := limiter.Scale(factor); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		default:
+			w.Header().Set("Allow", "GET, HEAD, PUT, POST")
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(http.StatusOK)
+
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"timestamp": time.Now().Unix(),
+			"scale":     limiter.ScaleFactor(),
+		})
+	}
+}
+
+// requestedScale reads the new limit multiplier from the query string or JSON body
+func requestedScale(r *http.Request) (float64, error) {
+	if factor := r.URL.Query().Get("factor"); factor != "" {
+		value, err := strconv.ParseFloat(factor, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid factor: %s", factor)
+		}
+		return value, nil
+	}
+
+	var body struct {
+		Scale *float64 `json:"scale"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Scale == nil {
+		return 0, fmt.Errorf(`expected ?factor= or a JSON body like {"scale": 0.5}`)
+	}
+	return *body.Scale, nil
+}
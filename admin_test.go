@@ -0,0 +1,337 @@
+// admin_test.go - Tests for administrative handlers
+package ratelimit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/itsatony/gorly/internal/core"
+)
+
+func TestScale(t *testing.T) {
+	limiter, err := New().Limit("global", "4/minute").Build()
+	if err != nil {
+		t.Fatalf("Failed to build limiter: %v", err)
+	}
+	defer limiter.Close()
+
+	if err := limiter.Scale(0.5); err != nil {
+		t.Fatalf("Failed to scale: %v", err)
+	}
+
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		result, err := limiter.Check(ctx, "user-1")
+		if err != nil || !result.Allowed || result.Limit != 2 {
+			t.Fatalf("Request %d: expected allowed with scaled limit 2, got %+v (%v)", i+1, result, err)
+		}
+	}
+	if allowed, _ := limiter.Allow(ctx, "user-1"); allowed {
+		t.Error("Expected scaled limit to deny third request")
+	}
+
+	limits, _ := limiter.Limits("user-1")
+	if len(limits) != 1 || limits[0].Requests != 2 {
+		t.Errorf("Expected documented limit to reflect scale, got %+v", limits)
+	}
+
+	// Tiny limits never scale to zero
+	if err := limiter.Scale(0.01); err != nil {
+		t.Fatalf("Failed to scale: %v", err)
+	}
+	if result, _ := limiter.Check(ctx, "user-2"); result.Limit != 1 {
+		t.Errorf("Expected limit floor of 1, got %d", result.Limit)
+	}
+
+	for _, factor := range []float64{0, -1, 1000} {
+		if err := limiter.Scale(factor); err == nil {
+			t.Errorf("Expected error for scale %g", factor)
+		}
+	}
+}
+
+func TestScaleHandler(t *testing.T) {
+	limiter, err := New().Limit("global", "100/minute").Build()
+	if err != nil {
+		t.Fatalf("Failed to build limiter: %v", err)
+	}
+	defer limiter.Close()
+
+	handler := ScaleHandler(limiter)
+
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest("PUT", "/admin/scale", strings.NewReader(`{"scale": 0.25}`)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if limiter.ScaleFactor() != 0.25 {
+		t.Errorf("Expected scale 0.25, got %g", limiter.ScaleFactor())
+	}
+
+	w = httptest.NewRecorder()
+	handler(w, httptest.NewRequest("GET", "/admin/scale", nil))
+	var body struct {
+		Scale float64 `json:"scale"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&body); err != nil || body.Scale != 0.25 {
+		t.Errorf("Expected scale 0.25 in response, got %+v (%v)", body, err)
+	}
+
+	w = httptest.NewRecorder()
+	handler(w, httptest.NewRequest("POST", "/admin/scale?factor=-2", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for invalid factor, got %d", w.Code)
+	}
+}
+
+func TestHotReloadScale(t *testing.T) {
+	limiter, err := New().Limit("global", "100/minute").Build()
+	if err != nil {
+		t.Fatalf("Failed to build limiter: %v", err)
+	}
+	defer limiter.Close()
+
+	manager := NewHotReloadManager(limiter, nil)
+	if err := manager.applyConfig(context.Background(), &HotReloadConfig{Scale: 2}); err != nil {
+		t.Fatalf("Failed to apply config: %v", err)
+	}
+	if limiter.ScaleFactor() != 2 {
+		t.Errorf("Expected reloaded scale 2, got %g", limiter.ScaleFactor())
+	}
+
+	if err := manager.applyConfig(context.Background(), &HotReloadConfig{Scale: -1}); err == nil {
+		t.Error("Expected validation error for negative scale")
+	}
+}
+
+func TestHotReloadLimits(t *testing.T) {
+	limiter, err := New().Limit("global", "100/minute").Build()
+	if err != nil {
+		t.Fatalf("Failed to build limiter: %v", err)
+	}
+	defer limiter.Close()
+
+	manager := NewHotReloadManager(limiter, nil)
+	config := &HotReloadConfig{
+		Limits:     map[string]string{"global": "3/minute"},
+		TierLimits: map[string]string{"premium": "30/minute"},
+	}
+	if err := manager.applyConfig(context.Background(), config); err != nil {
+		t.Fatalf("Failed to apply config: %v", err)
+	}
+	if result, _ := limiter.Check(context.Background(), "user-1"); result.Limit != 3 {
+		t.Errorf("Expected reloaded limit 3, got %d", result.Limit)
+	}
+
+	// A config arriving after its deadline is not applied
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := manager.applyConfig(ctx, &HotReloadConfig{Limits: map[string]string{"global": "1/minute"}}); err == nil {
+		t.Error("Expected a cancelled reload to fail")
+	}
+	if result, _ := limiter.Check(context.Background(), "user-2"); result.Limit != 3 {
+		t.Errorf("Expected the cancelled reload to keep limit 3, got %d", result.Limit)
+	}
+}
+
+func TestHotReloadStress(t *testing.T) {
+	limiter, err := New().Limit("global", "100/minute").Build()
+	if err != nil {
+		t.Fatalf("Failed to build limiter: %v", err)
+	}
+	defer limiter.Close()
+
+	// Each config yields an effective limit of its own; a check mixing the limit of
+	// one config with the scale of the other would report 40 or 200
+	configs := []*HotReloadConfig{
+		{Limits: map[string]string{"global": "100/minute"}, Scale: 1},
+		{Limits: map[string]string{"global": "40/minute"}, Scale: 2},
+	}
+	consistent := map[int64]bool{100: true, 80: true}
+
+	// Each reload logs the applied configuration
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	manager := NewHotReloadManager(limiter, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	const reloads = 500
+	var wg sync.WaitGroup
+	wg.Add(1)
+	start := time.Now()
+	go func() {
+		defer wg.Done()
+		defer cancel()
+		for i := 0; i < reloads; i++ {
+			if err := manager.applyConfig(context.Background(), configs[i%2]); err != nil {
+				t.Errorf("Reload %d failed: %v", i, err)
+				return
+			}
+			runtime.Gosched()
+		}
+	}()
+
+	var checks atomic.Int64
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; ctx.Err() == nil; i++ {
+				result, err := limiter.Check(context.Background(), fmt.Sprintf("user-%d-%d", w, i%50))
+				if err != nil {
+					t.Errorf("Check failed: %v", err)
+					return
+				}
+				if !consistent[result.Limit] {
+					t.Errorf("Torn read: limit %d belongs to no config", result.Limit)
+					return
+				}
+				checks.Add(1)
+				runtime.Gosched()
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	if rate := reloads / time.Since(start).Seconds(); rate < 100 {
+		t.Errorf("Expected hundreds of reloads per second, got %.0f", rate)
+	}
+	if checks.Load() == 0 {
+		t.Error("Expected traffic during the reloads")
+	}
+}
+
+// snapshotStore stands in for a store shared by several instances
+type snapshotStore struct {
+	mu   sync.Mutex
+	data []byte
+}
+
+// sharedStoreLimiter gives a limiter the shared snapshot store
+type sharedStoreLimiter struct {
+	Limiter
+	store *snapshotStore
+}
+
+func (sl *sharedStoreLimiter) updateLimits(update core.LimitUpdate) error {
+	return updateLimiterLimits(sl.Limiter, update)
+}
+
+func (sl *sharedStoreLimiter) saveConfigSnapshot(ctx context.Context, data []byte) error {
+	sl.store.mu.Lock()
+	defer sl.store.mu.Unlock()
+	sl.store.data = data
+	return nil
+}
+
+func (sl *sharedStoreLimiter) loadConfigSnapshot(ctx context.Context) ([]byte, error) {
+	sl.store.mu.Lock()
+	defer sl.store.mu.Unlock()
+	return sl.store.data, nil
+}
+
+// fixedConfigSource serves one config on request and never pushes updates
+type fixedConfigSource struct {
+	config *HotReloadConfig
+}
+
+func (fs *fixedConfigSource) Watch(ctx context.Context) (<-chan *HotReloadConfig, error) {
+	return make(chan *HotReloadConfig), nil
+}
+
+func (fs *fixedConfigSource) GetConfig(ctx context.Context) (*HotReloadConfig, error) {
+	return fs.config, nil
+}
+
+func (fs *fixedConfigSource) Close() error { return nil }
+
+func TestHotReloadBootstrapFromStore(t *testing.T) {
+	shared := &snapshotStore{}
+	instance := func(source *HotReloadConfig) (Limiter, *HotReloadManager) {
+		t.Helper()
+		base, err := New().Limit("global", "100/minute").Build()
+		if err != nil {
+			t.Fatalf("Failed to build limiter: %v", err)
+		}
+		limiter := &sharedStoreLimiter{Limiter: base, store: shared}
+		manager := NewHotReloadManager(limiter, &fixedConfigSource{config: source})
+		manager.SetBootstrapFromStore(true)
+		t.Cleanup(func() {
+			manager.Stop()
+			base.Close()
+		})
+		return limiter, manager
+	}
+	limit := func(limiter Limiter) int64 {
+		t.Helper()
+		result, err := limiter.Check(context.Background(), "user-1")
+		if err != nil {
+			t.Fatalf("Check failed: %v", err)
+		}
+		return result.Limit
+	}
+
+	// The first instance starts with its built-in limits and shares the reload it applies
+	now := time.Now()
+	first, manager := instance(&HotReloadConfig{Limits: map[string]string{"global": "5/minute"}, Version: "v2", UpdatedAt: now})
+	if err := manager.Start(); err != nil {
+		t.Fatalf("Failed to start: %v", err)
+	}
+	if got := limit(first); got != 100 {
+		t.Errorf("Expected the built-in limit 100 without a shared config, got %d", got)
+	}
+	if err := manager.ForceReload(); err != nil {
+		t.Fatalf("Failed to reload: %v", err)
+	}
+
+	// A new instance comes up with the shared config before its source responds
+	second, manager := instance(nil)
+	if err := manager.Start(); err != nil {
+		t.Fatalf("Failed to start: %v", err)
+	}
+	if got := limit(second); got != 5 {
+		t.Errorf("Expected the bootstrapped limit 5, got %d", got)
+	}
+	if current := manager.GetCurrentConfig(); current == nil || current.Version != "v2" {
+		t.Errorf("Expected the bootstrapped config as current, got %+v", current)
+	}
+
+	// An instance applying an older config does not replace the shared one
+	_, manager = instance(&HotReloadConfig{Limits: map[string]string{"global": "50/minute"}, Version: "v1", UpdatedAt: now.Add(-time.Hour)})
+	if err := manager.ForceReload(); err != nil {
+		t.Fatalf("Failed to reload: %v", err)
+	}
+	var snapshot HotReloadConfig
+	if err := json.Unmarshal(shared.data, &snapshot); err != nil || snapshot.Version != "v2" {
+		t.Errorf("Expected the shared config to stay at v2, got %+v (%v)", snapshot, err)
+	}
+
+	// Limiters without a store to share through report the failure and keep their limits
+	base, _ := New().Limit("global", "100/minute").Build()
+	defer base.Close()
+	var reported error
+	manager = NewHotReloadManager(All(base), &fixedConfigSource{})
+	manager.errorHandler = func(err error) { reported = err }
+	manager.SetBootstrapFromStore(true)
+	if err := manager.Start(); err != nil {
+		t.Fatalf("Failed to start: %v", err)
+	}
+	manager.Stop()
+	if reported == nil || !strings.Contains(reported.Error(), "bootstrap") {
+		t.Errorf("Expected a bootstrap error, got %v", reported)
+	}
+}
@@ -0,0 +1,244 @@
+// algorithm_registry.go - Custom algorithms selectable by name
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/itsatony/gorly/internal/core"
+)
+
+// AlgorithmFactory creates a custom algorithm. It is called once for every limiter
+// selecting the algorithm, so state kept outside the store is not shared between limiters.
+type AlgorithmFactory func() Algorithm
+
+// AlgorithmPeeker is implemented by custom algorithms that can report the state of a key
+// without consuming quota: the quota remaining now, and whether one more request would be
+// allowed. Without it, Limiter.Peek and the usage reports built on it fail for limiters
+// using the algorithm.
+type AlgorithmPeeker interface {
+	Peek(ctx context.Context, store Store, key string, limit int64, window time.Duration) (*Result, error)
+}
+
+// LimitValidator is implemented by custom algorithms that cannot enforce every limit, e.g.
+// one keeping a log of at most so many requests. Every configured limit is validated when a
+// limiter is built and when its limits are updated; an error fails the build or the update.
+type LimitValidator interface {
+	ValidateLimit(limit int64, window time.Duration) error
+}
+
+// RegisterAlgorithm makes a custom algorithm selectable with Algorithm(name), in config
+// files and in NewRateLimiter configurations, alongside the built-in ones. Register from
+// an init function; registering a name again replaces its factory for limiters built
+// afterwards. The name is part of every store key the algorithm uses, so keep it stable.
+// It panics if the name is not lowercase letters, digits, '_' and '-', names a built-in
+// algorithm, or the factory is nil. CheckAlgorithmConformance tests an implementation.
+// Example: ratelimit.RegisterAlgorithm("decaying_log", func() ratelimit.Algorithm { return &DecayingLog{HalfLife: time.Minute} })
+func RegisterAlgorithm(name string, factory AlgorithmFactory) {
+	var coreFactory core.AlgorithmFactory
+	if factory != nil {
+		coreFactory = func() core.Algorithm {
+			algorithm := factory()
+			if algorithm == nil {
+				return nil
+			}
+			return &customAlgorithm{algorithm}
+		}
+	}
+	if err := core.RegisterAlgorithm(name, coreFactory); err != nil {
+		panic("ratelimit: " + err.Error())
+	}
+}
+
+// RegisteredAlgorithms returns the names of the custom algorithms, sorted
+func RegisteredAlgorithms() []string {
+	return core.RegisteredAlgorithms()
+}
+
+// customAlgorithm adapts a custom algorithm to the algorithm interface of the core limiter
+type customAlgorithm struct {
+	algorithm Algorithm
+}
+
+func (c *customAlgorithm) Name() string {
+	return c.algorithm.Name()
+}
+
+func (c *customAlgorithm) Allow(ctx context.Context, store core.Store, key string, limit int64, window time.Duration, n int64) (*core.AlgorithmResult, error) {
+	result, err := c.algorithm.Allow(ctx, &coreStoreAdapter{store}, key, limit, window, n)
+	if err != nil {
+		return nil, err
+	}
+	return customResult(result, limit, window)
+}
+
+func (c *customAlgorithm) Peek(ctx context.Context, store core.Store, key string, limit int64, window time.Duration) (*core.AlgorithmResult, error) {
+	peeker, ok := c.algorithm.(AlgorithmPeeker)
+	if !ok {
+		return nil, fmt.Errorf("algorithm %s does not support peeking", c.algorithm.Name())
+	}
+	result, err := peeker.Peek(ctx, &coreStoreAdapter{store}, key, limit, window)
+	if err != nil {
+		return nil, err
+	}
+	return customResult(result, limit, window)
+}
+
+func (c *customAlgorithm) Reset(ctx context.Context, store core.Store, key string) error {
+	return c.algorithm.Reset(ctx, &coreStoreAdapter{store}, key)
+}
+
+// ValidateLimit forwards to the custom algorithm, if it validates limits
+func (c *customAlgorithm) ValidateLimit(limit int64, window time.Duration) error {
+	if validator, ok := c.algorithm.(LimitValidator); ok {
+		return validator.ValidateLimit(limit, window)
+	}
+	return nil
+}
+
+// customResult converts the result of a custom algorithm, defaulting the limit and window
+// it may leave unset to the ones it was asked to enforce
+func customResult(result *Result, limit int64, window time.Duration) (*core.AlgorithmResult, error) {
+	if result == nil {
+		return nil, fmt.Errorf("algorithm returned no result")
+	}
+	converted := &core.AlgorithmResult{
+		Allowed:    result.Allowed,
+		Remaining:  result.Remaining,
+		Limit:      result.Limit,
+		Used:       result.Used,
+		RetryAfter: result.RetryAfter,
+		Window:     result.Window,
+		ResetTime:  result.ResetTime,
+	}
+	if converted.Limit == 0 {
+		converted.Limit = limit
+	}
+	if converted.Window == 0 {
+		converted.Window = window
+	}
+	return converted, nil
+}
+
+// coreStoreAdapter exposes the store of the core limiter as a Store to custom algorithms
+type coreStoreAdapter struct {
+	core.Store
+}
+
+func (a *coreStoreAdapter) Increment(ctx context.Context, key string, expiration time.Duration) (int64, error) {
+	return a.Store.IncrementBy(ctx, key, 1, expiration)
+}
+
+// CheckAlgorithmConformance checks that an algorithm behaves as the limiter expects of
+// every algorithm, on an in-memory store with a limit of 5 requests per minute: fresh keys
+// allow the limit and no more, denials carry a retry delay, keys are independent, costs
+// above one consume that much quota, Reset restores the quota and, for AlgorithmPeeker
+// implementations, Peek consumes nothing. It returns the first violation found.
+// Example: if err := ratelimit.CheckAlgorithmConformance(NewDecayingLog); err != nil { t.Fatal(err) }
+func CheckAlgorithmConformance(factory AlgorithmFactory) error {
+	const limit = 5
+	window := time.Minute
+	ctx := context.Background()
+
+	store, err := createStore(&Config{Store: "memory"})
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+	algorithm := factory()
+	if algorithm == nil {
+		return fmt.Errorf("factory returned nil")
+	}
+	allow := func(key string, n int64) (*Result, error) {
+		result, err := algorithm.Allow(ctx, store, key, limit, window, n)
+		if err == nil && result == nil {
+			err = fmt.Errorf("no result from Allow for key %s", key)
+		}
+		return result, err
+	}
+
+	remaining := int64(limit)
+	for i := 1; i <= limit; i++ {
+		result, err := allow("conformance:a", 1)
+		if err != nil {
+			return err
+		}
+		if !result.Allowed {
+			return fmt.Errorf("request %d of %d was denied", i, limit)
+		}
+		if result.Remaining < 0 || result.Remaining > remaining {
+			return fmt.Errorf("remaining went from %d to %d after request %d", remaining, result.Remaining, i)
+		}
+		remaining = result.Remaining
+	}
+	result, err := allow("conformance:a", 1)
+	if err != nil {
+		return err
+	}
+	if result.Allowed {
+		return fmt.Errorf("request %d over a limit of %d was allowed", limit+1, limit)
+	}
+	if result.Remaining != 0 || result.RetryAfter <= 0 {
+		return fmt.Errorf("a denial must report 0 remaining and a retry delay, got %d and %s", result.Remaining, result.RetryAfter)
+	}
+
+	if result, err = allow("conformance:b", 1); err != nil {
+		return err
+	}
+	if !result.Allowed {
+		return fmt.Errorf("a key was denied because another key reached its limit")
+	}
+
+	if result, err = allow("conformance:c", limit); err != nil {
+		return err
+	}
+	if !result.Allowed {
+		return fmt.Errorf("a request costing the whole limit was denied on a fresh key")
+	}
+	if result, err = allow("conformance:c", 1); err != nil {
+		return err
+	}
+	if result.Allowed {
+		return fmt.Errorf("a request was allowed after one costing the whole limit")
+	}
+	if result, err = allow("conformance:d", limit+1); err != nil {
+		return err
+	}
+	if result.Allowed {
+		return fmt.Errorf("a request costing more than the limit was allowed")
+	}
+
+	if err := algorithm.Reset(ctx, store, "conformance:a"); err != nil {
+		return err
+	}
+	if result, err = allow("conformance:a", 1); err != nil {
+		return err
+	}
+	if !result.Allowed {
+		return fmt.Errorf("a request was denied after Reset")
+	}
+
+	peeker, ok := algorithm.(AlgorithmPeeker)
+	if !ok {
+		return nil
+	}
+	before, err := peeker.Peek(ctx, store, "conformance:a", limit, window)
+	if err != nil {
+		return err
+	}
+	after, err := peeker.Peek(ctx, store, "conformance:a", limit, window)
+	if err != nil {
+		return err
+	}
+	if before == nil || after == nil || after.Remaining != before.Remaining {
+		return fmt.Errorf("peeking twice must report the same state, without consuming quota")
+	}
+	if result, err = allow("conformance:a", 1); err != nil {
+		return err
+	}
+	if result.Remaining >= before.Remaining {
+		return fmt.Errorf("Peek reported %d remaining, but the next request left %d", before.Remaining, result.Remaining)
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+// algorithm_registry_test.go
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// countingAlgorithm is a minimal custom algorithm: a counter per key expiring with the window
+type countingAlgorithm struct {
+	maxLimit int64
+}
+
+func (c *countingAlgorithm) Name() string { return "counting" }
+
+func (c *countingAlgorithm) Allow(ctx context.Context, store Store, key string, limit int64, window time.Duration, n int64) (*Result, error) {
+	used, err := store.IncrementBy(ctx, key, n, window)
+	if err != nil {
+		return nil, err
+	}
+	return c.result(used, limit), nil
+}
+
+func (c *countingAlgorithm) Peek(ctx context.Context, store Store, key string, limit int64, window time.Duration) (*Result, error) {
+	used, err := store.IncrementBy(ctx, key, 0, window)
+	if err != nil {
+		return nil, err
+	}
+	return &Result{Allowed: used < limit, Used: used, Remaining: max(limit-used, 0)}, nil
+}
+
+func (c *countingAlgorithm) result(used, limit int64) *Result {
+	result := &Result{Allowed: used <= limit, Used: used, Remaining: limit - used}
+	if !result.Allowed {
+		result.Remaining = 0
+		result.RetryAfter = time.Second
+	}
+	return result
+}
+
+func (c *countingAlgorithm) Reset(ctx context.Context, store Store, key string) error {
+	return store.Delete(ctx, key)
+}
+
+func (c *countingAlgorithm) ValidateLimit(limit int64, window time.Duration) error {
+	if limit > c.maxLimit {
+		return fmt.Errorf("at most %d requests", c.maxLimit)
+	}
+	return nil
+}
+
+// generousAlgorithm allows every request
+type generousAlgorithm struct{ countingAlgorithm }
+
+func (g *generousAlgorithm) Allow(ctx context.Context, store Store, key string, limit int64, window time.Duration, n int64) (*Result, error) {
+	return &Result{Allowed: true, Remaining: limit}, nil
+}
+
+func TestCheckAlgorithmConformance(t *testing.T) {
+	for _, name := range []string{"token_bucket", "sliding_window", "fixed_window"} {
+		algorithm, err := createAlgorithm(name, CompressionConfig{}, "")
+		if err != nil {
+			t.Fatalf("Failed to create %s: %v", name, err)
+		}
+		if err := CheckAlgorithmConformance(func() Algorithm { return algorithm }); err != nil {
+			t.Errorf("Expected built-in %s to conform, got %v", name, err)
+		}
+	}
+
+	if err := CheckAlgorithmConformance(func() Algorithm { return &countingAlgorithm{maxLimit: 100} }); err != nil {
+		t.Errorf("Expected the counting algorithm to conform, got %v", err)
+	}
+	err := CheckAlgorithmConformance(func() Algorithm { return &generousAlgorithm{} })
+	if err == nil || !strings.Contains(err.Error(), "over a limit of 5 was allowed") {
+		t.Errorf("Expected an algorithm ignoring its limit to fail, got %v", err)
+	}
+}
+
+func TestRegisterAlgorithm(t *testing.T) {
+	RegisterAlgorithm("test-counting", func() Algorithm { return &countingAlgorithm{maxLimit: 100} })
+	if names := RegisteredAlgorithms(); !strings.Contains(strings.Join(names, ","), "test-counting") {
+		t.Errorf("Expected the algorithm to be listed, got %v", names)
+	}
+
+	limiter, err := New().Algorithm("test-counting").Limit("global", "3/minute").Build()
+	if err != nil {
+		t.Fatalf("Failed to build with a custom algorithm: %v", err)
+	}
+	defer limiter.Close()
+	ctx := context.Background()
+	for i := 1; i <= 4; i++ {
+		result, err := limiter.Check(ctx, "user-1")
+		if err != nil {
+			t.Fatalf("Check failed: %v", err)
+		}
+		if result.Allowed != (i <= 3) || result.Limit != 3 || result.Window != time.Minute {
+			t.Errorf("Unexpected result of request %d: %+v", i, result)
+		}
+	}
+	if result, err := limiter.Peek(ctx, "user-2"); err != nil || result.Remaining != 3 {
+		t.Errorf("Expected Peek to use the algorithm, got %+v (%v)", result, err)
+	}
+
+	if _, err := New().Algorithm("test-counting").Limit("global", "500/minute").Build(); err == nil ||
+		!strings.Contains(err.Error(), "at most 100 requests") {
+		t.Errorf("Expected the algorithm to reject the limit, got %v", err)
+	}
+	if _, err := New().Algorithm("test-unregistered").Limit("global", "3/minute").Build(); err == nil {
+		t.Error("Expected an unregistered algorithm to fail the build")
+	}
+
+	config := DefaultConfig()
+	config.Algorithm = "test-counting"
+	if err := config.Validate(); err != nil {
+		t.Errorf("Expected configurations to accept the algorithm, got %v", err)
+	}
+}
+
+func TestRegisterAlgorithmPanics(t *testing.T) {
+	factory := func() Algorithm { return &countingAlgorithm{} }
+	tests := []struct {
+		name    string
+		factory AlgorithmFactory
+	}{
+		{"token_bucket", factory},
+		{"Decaying Log", factory},
+		{"test-nil", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Expected registering %q to panic", tt.name)
+				}
+			}()
+			RegisterAlgorithm(tt.name, tt.factory)
+		})
+	}
+}
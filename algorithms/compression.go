@@ -0,0 +1,217 @@
+// algorithms/compression.go
+package algorithms
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/klauspost/compress/snappy"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compression codecs for serialized algorithm state
+const (
+	CompressionNone   = "none"
+	CompressionSnappy = "snappy"
+	CompressionZstd   = "zstd"
+)
+
+// DefaultCompressionThreshold is the serialized size above which state is compressed
+const DefaultCompressionThreshold = 4096
+
+// compressedStateMagic prefixes compressed blobs. Serialized JSON state always
+// starts with '{', so blobs without the prefix are read as plain JSON.
+const compressedStateMagic byte = 0x00
+
+// Codec identifiers stored after the magic byte
+const (
+	codecIDSnappy byte = 1
+	codecIDZstd   byte = 2
+)
+
+// CompressionConfig configures compression of serialized state blobs
+type CompressionConfig struct {
+	// Codec to use: "none", "snappy" or "zstd"
+	Algorithm string `yaml:"algorithm" json:"algorithm" mapstructure:"algorithm"`
+
+	// Minimum serialized size in bytes before compression is applied
+	Threshold int `yaml:"threshold" json:"threshold" mapstructure:"threshold"`
+}
+
+// CompressionStats reports compression savings and CPU cost
+type CompressionStats struct {
+	Algorithm       string        `json:"algorithm"`
+	Compressed      int64         `json:"compressed"`       // Blobs written compressed
+	Skipped         int64         `json:"skipped"`          // Blobs below the threshold or not worth compressing
+	Decompressed    int64         `json:"decompressed"`     // Compressed blobs read back
+	BytesIn         int64         `json:"bytes_in"`         // Uncompressed size of compressed blobs
+	BytesOut        int64         `json:"bytes_out"`        // Compressed size of compressed blobs
+	CompressTime    time.Duration `json:"compress_time"`    // Total time spent compressing
+	DecompressTime  time.Duration `json:"decompress_time"`  // Total time spent decompressing
+	CompressionRate float64       `json:"compression_rate"` // BytesOut / BytesIn
+}
+
+// SavedBytes returns the number of bytes saved by compression
+func (cs CompressionStats) SavedBytes() int64 {
+	return cs.BytesIn - cs.BytesOut
+}
+
+// stateCompressor compresses and decompresses serialized state blobs
+type stateCompressor struct {
+	config CompressionConfig
+
+	compressed      int64
+	skipped         int64
+	decompressed    int64
+	bytesIn         int64
+	bytesOut        int64
+	compressNanos   int64
+	decompressNanos int64
+}
+
+// zstd encoders and decoders are safe for concurrent use via EncodeAll/DecodeAll
+var (
+	zstdOnce    sync.Once
+	zstdEncoder *zstd.Encoder
+	zstdDecoder *zstd.Decoder
+	zstdErr     error
+)
+
+func zstdCodec() (*zstd.Encoder, *zstd.Decoder, error) {
+	zstdOnce.Do(func() {
+		zstdEncoder, zstdErr = zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.SpeedFastest))
+		if zstdErr != nil {
+			return
+		}
+		zstdDecoder, zstdErr = zstd.NewReader(nil)
+	})
+	return zstdEncoder, zstdDecoder, zstdErr
+}
+
+// newStateCompressor validates config and creates a compressor.
+// A nil compressor is returned when compression is disabled.
+func newStateCompressor(config CompressionConfig) (*stateCompressor, error) {
+	switch config.Algorithm {
+	case "", CompressionNone:
+		return nil, nil
+	case CompressionSnappy:
+	case CompressionZstd:
+		if _, _, err := zstdCodec(); err != nil {
+			return nil, NewRateLimitError("config", "failed to initialize zstd codec", err)
+		}
+	default:
+		return nil, NewRateLimitError("config", fmt.Sprintf("unknown compression algorithm: %s", config.Algorithm), nil)
+	}
+
+	if config.Threshold < 0 {
+		return nil, NewRateLimitError("config", "compression threshold cannot be negative", nil)
+	}
+	if config.Threshold == 0 {
+		config.Threshold = DefaultCompressionThreshold
+	}
+
+	return &stateCompressor{config: config}, nil
+}
+
+// encode compresses data if it exceeds the threshold and compression pays off
+func (sc *stateCompressor) encode(data []byte) ([]byte, error) {
+	if sc == nil {
+		return data, nil
+	}
+	if len(data) < sc.config.Threshold {
+		atomic.AddInt64(&sc.skipped, 1)
+		return data, nil
+	}
+
+	start := time.Now()
+	var id byte
+	var body []byte
+	switch sc.config.Algorithm {
+	case CompressionSnappy:
+		id = codecIDSnappy
+		body = snappy.Encode(nil, data)
+	case CompressionZstd:
+		id = codecIDZstd
+		encoder, _, err := zstdCodec()
+		if err != nil {
+			return nil, NewRateLimitError("store", "failed to compress state", err)
+		}
+		body = encoder.EncodeAll(data, make([]byte, 0, len(data)/2))
+	}
+	atomic.AddInt64(&sc.compressNanos, int64(time.Since(start)))
+
+	// Keep the plain form when compression doesn't help
+	if len(body)+2 >= len(data) {
+		atomic.AddInt64(&sc.skipped, 1)
+		return data, nil
+	}
+
+	out := make([]byte, 0, len(body)+2)
+	out = append(out, compressedStateMagic, id)
+	out = append(out, body...)
+
+	atomic.AddInt64(&sc.compressed, 1)
+	atomic.AddInt64(&sc.bytesIn, int64(len(data)))
+	atomic.AddInt64(&sc.bytesOut, int64(len(out)))
+
+	return out, nil
+}
+
+// decode returns the plain serialized form of a stored blob.
+// Compressed blobs are decoded by the codec recorded in their header, so
+// state written under a previous configuration remains readable.
+func (sc *stateCompressor) decode(data []byte) ([]byte, error) {
+	if len(data) < 2 || data[0] != compressedStateMagic {
+		return data, nil
+	}
+
+	start := time.Now()
+	var out []byte
+	var err error
+	switch data[1] {
+	case codecIDSnappy:
+		out, err = snappy.Decode(nil, data[2:])
+	case codecIDZstd:
+		_, decoder, codecErr := zstdCodec()
+		if codecErr != nil {
+			return nil, NewRateLimitError("store", "failed to decompress state", codecErr)
+		}
+		out, err = decoder.DecodeAll(data[2:], nil)
+	default:
+		return nil, NewRateLimitError("store", fmt.Sprintf("unknown compression codec id: %d", data[1]), nil)
+	}
+	if err != nil {
+		return nil, NewRateLimitError("store", "failed to decompress state", err)
+	}
+
+	if sc != nil {
+		atomic.AddInt64(&sc.decompressNanos, int64(time.Since(start)))
+		atomic.AddInt64(&sc.decompressed, 1)
+	}
+
+	return out, nil
+}
+
+// stats returns a snapshot of compression statistics
+func (sc *stateCompressor) stats() CompressionStats {
+	if sc == nil {
+		return CompressionStats{Algorithm: CompressionNone}
+	}
+
+	stats := CompressionStats{
+		Algorithm:      sc.config.Algorithm,
+		Compressed:     atomic.LoadInt64(&sc.compressed),
+		Skipped:        atomic.LoadInt64(&sc.skipped),
+		Decompressed:   atomic.LoadInt64(&sc.decompressed),
+		BytesIn:        atomic.LoadInt64(&sc.bytesIn),
+		BytesOut:       atomic.LoadInt64(&sc.bytesOut),
+		CompressTime:   time.Duration(atomic.LoadInt64(&sc.compressNanos)),
+		DecompressTime: time.Duration(atomic.LoadInt64(&sc.decompressNanos)),
+	}
+	if stats.BytesIn > 0 {
+		stats.CompressionRate = float64(stats.BytesOut) / float64(stats.BytesIn)
+	}
+	return stats
+}
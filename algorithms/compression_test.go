@@ -0,0 +1,134 @@
+// algorithms/compression_test.go
+package algorithms
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestSlidingWindowAlgorithm_Compression(t *testing.T) {
+	for _, codec := range []string{CompressionSnappy, CompressionZstd} {
+		t.Run(codec, func(t *testing.T) {
+			algorithm := NewSlidingWindowAlgorithm()
+			if err := algorithm.SetCompression(CompressionConfig{Algorithm: codec, Threshold: 512}); err != nil {
+				t.Fatalf("SetCompression failed: %v", err)
+			}
+
+			store := newMockStore()
+			ctx := context.Background()
+
+			for i := 0; i < 500; i++ {
+				if _, err := algorithm.Allow(ctx, store, "test:big", 1000, time.Hour, 1); err != nil {
+					t.Fatalf("Allow failed: %v", err)
+				}
+			}
+
+			data, _ := store.Get(ctx, "test:big")
+			if data[0] != compressedStateMagic {
+				t.Fatal("Expected large state to be stored compressed")
+			}
+
+			// Reads are transparent
+			info, err := algorithm.GetWindowInfo(ctx, store, "test:big", 1000, time.Hour)
+			if err != nil {
+				t.Fatalf("GetWindowInfo failed: %v", err)
+			}
+			if info["current_requests"].(int) != 500 {
+				t.Errorf("Expected 500 requests in window, got %v", info["current_requests"])
+			}
+
+			stats := algorithm.CompressionStats()
+			if stats.Compressed == 0 || stats.Decompressed == 0 {
+				t.Errorf("Expected compression activity, got %+v", stats)
+			}
+			if stats.SavedBytes() <= 0 {
+				t.Errorf("Expected compression to save bytes, got %d", stats.SavedBytes())
+			}
+			if stats.Skipped == 0 {
+				t.Error("Expected small early states to skip compression")
+			}
+		})
+	}
+}
+
+func TestSlidingWindowAlgorithm_CompressionReadsPlainState(t *testing.T) {
+	store := newMockStore()
+	ctx := context.Background()
+
+	// State written without compression
+	plain := NewSlidingWindowAlgorithm()
+	for i := 0; i < 10; i++ {
+		if _, err := plain.Allow(ctx, store, "test:key", 100, time.Hour, 1); err != nil {
+			t.Fatalf("Allow failed: %v", err)
+		}
+	}
+
+	compressed := NewSlidingWindowAlgorithm()
+	if err := compressed.SetCompression(CompressionConfig{Algorithm: CompressionZstd, Threshold: 1}); err != nil {
+		t.Fatalf("SetCompression failed: %v", err)
+	}
+
+	result, err := compressed.Allow(ctx, store, "test:key", 100, time.Hour, 1)
+	if err != nil {
+		t.Fatalf("Allow failed: %v", err)
+	}
+	if result.Used != 11 {
+		t.Errorf("Expected 11 used, got %d", result.Used)
+	}
+
+	// And plain readers understand compressed state written by others
+	data, _ := store.Get(ctx, "test:key")
+	if data[0] == '{' {
+		t.Fatal("Expected state to be stored compressed")
+	}
+	result, err = plain.Allow(ctx, store, "test:key", 100, time.Hour, 1)
+	if err != nil {
+		t.Fatalf("Plain Allow failed on compressed state: %v", err)
+	}
+	if result.Used != 12 {
+		t.Errorf("Expected 12 used, got %d", result.Used)
+	}
+}
+
+func TestTokenBucketAlgorithm_CompressionBelowThreshold(t *testing.T) {
+	algorithm := NewTokenBucketAlgorithm()
+	if err := algorithm.SetCompression(CompressionConfig{Algorithm: CompressionSnappy}); err != nil {
+		t.Fatalf("SetCompression failed: %v", err)
+	}
+
+	store := newMockStore()
+	ctx := context.Background()
+
+	if _, err := algorithm.Allow(ctx, store, "test:bucket", 10, time.Minute, 1); err != nil {
+		t.Fatalf("Allow failed: %v", err)
+	}
+
+	data, _ := store.Get(ctx, "test:bucket")
+	var state TokenBucketState
+	if err := json.Unmarshal(data, &state); err != nil {
+		t.Fatalf("Expected small state to stay plain JSON: %v", err)
+	}
+
+	if stats := algorithm.CompressionStats(); stats.Skipped != 1 || stats.Compressed != 0 {
+		t.Errorf("Expected one skipped blob, got %+v", stats)
+	}
+}
+
+func TestSetCompression_InvalidConfig(t *testing.T) {
+	algorithm := NewSlidingWindowAlgorithm()
+
+	if err := algorithm.SetCompression(CompressionConfig{Algorithm: "lz4"}); err == nil {
+		t.Error("Expected error for unknown codec")
+	}
+	if err := algorithm.SetCompression(CompressionConfig{Algorithm: CompressionSnappy, Threshold: -1}); err == nil {
+		t.Error("Expected error for negative threshold")
+	}
+	if err := algorithm.SetCompression(CompressionConfig{Algorithm: CompressionNone}); err != nil {
+		t.Errorf("Expected none to disable compression, got: %v", err)
+	}
+	if stats := algorithm.CompressionStats(); stats.Algorithm != CompressionNone {
+		t.Errorf("Expected disabled stats, got %+v", stats)
+	}
+}
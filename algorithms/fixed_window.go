@@ -0,0 +1,298 @@
+// algorithms/fixed_window.go
+package algorithms
+
+import (
+	"context"
+	"strconv"
+	"time"
+)
+
+// WindowAlignment decides where the windows of the fixed window algorithm start
+type WindowAlignment string
+
+// Window alignments
+const (
+	// AlignWallClock starts windows at multiples of their length since the Unix epoch, so
+	// every key resets at the same predictable time, e.g. on the minute
+	AlignWallClock WindowAlignment = "wall_clock"
+
+	// AlignFirstRequest starts the window of a key with its first request, and the next
+	// window with the first request after it ended
+	AlignFirstRequest WindowAlignment = "first_request"
+)
+
+// CounterStore is a store with atomic counters, which the fixed window algorithm counts in
+type CounterStore interface {
+	Store
+	IncrementBy(ctx context.Context, key string, amount int64, expiration time.Duration) (int64, error)
+}
+
+// anchorStore is implemented by stores that set a key only if it is missing. Windows
+// aligned to the first request use it so instances racing on a new window agree on its start.
+type anchorStore interface {
+	SetNX(ctx context.Context, key string, value []byte, expiration time.Duration) (bool, error)
+}
+
+// FixedWindowAlgorithm implements the fixed window counter algorithm.
+// Each window of a key is a single counter incremented atomically by the store, so a
+// check costs one store operation instead of reading and rewriting a request log. The
+// price is accuracy at window boundaries: up to twice the limit can pass around a reset.
+//
+// The key itself holds the start of the current window, and the counter of each window
+// is kept under the key suffixed with its start.
+type FixedWindowAlgorithm struct {
+	name      string
+	now       func() time.Time
+	alignment WindowAlignment
+}
+
+// NewFixedWindowAlgorithm creates a new fixed window algorithm with wall-clock windows
+func NewFixedWindowAlgorithm() *FixedWindowAlgorithm {
+	return &FixedWindowAlgorithm{
+		name:      "fixed_window",
+		now:       time.Now,
+		alignment: AlignWallClock,
+	}
+}
+
+// Name returns the algorithm name
+func (fw *FixedWindowAlgorithm) Name() string {
+	return fw.name
+}
+
+// SetClock replaces the time source, e.g. with a virtual clock in simulations.
+// It must be called before the algorithm is used concurrently.
+func (fw *FixedWindowAlgorithm) SetClock(now func() time.Time) {
+	fw.now = now
+}
+
+// SetAlignment sets where windows start; an empty alignment keeps wall-clock windows.
+// It must be called before the algorithm is used concurrently.
+func (fw *FixedWindowAlgorithm) SetAlignment(alignment WindowAlignment) error {
+	switch alignment {
+	case "":
+		fw.alignment = AlignWallClock
+	case AlignWallClock, AlignFirstRequest:
+		fw.alignment = alignment
+	default:
+		return NewRateLimitError("config", "window alignment must be 'wall_clock' or 'first_request', got "+strconv.Quote(string(alignment)), nil)
+	}
+	return nil
+}
+
+// Alignment returns where windows start
+func (fw *FixedWindowAlgorithm) Alignment() WindowAlignment {
+	return fw.alignment
+}
+
+// Allow checks if N requests are allowed within the current window and counts them.
+// Denied requests are taken back out of the counter, so they do not use up the window.
+func (fw *FixedWindowAlgorithm) Allow(ctx context.Context, store Store, key string, limit int64, window time.Duration, n int64) (*Result, error) {
+	if n <= 0 {
+		return &Result{
+			Allowed:   false,
+			Limit:     limit,
+			Window:    window,
+			Algorithm: fw.name,
+		}, NewRateLimitError("validation", "request count must be greater than 0", nil)
+	}
+	counters, err := fw.counterStore(store)
+	if err != nil {
+		return nil, err
+	}
+
+	now := fw.now()
+	start, err := fw.windowStart(ctx, counters, key, window, now)
+	if err != nil {
+		return nil, err
+	}
+	resetTime := start.Add(window)
+	counterKey := windowCounterKey(key, start)
+	ttl := resetTime.Sub(now) + time.Second
+
+	used, err := counters.IncrementBy(ctx, counterKey, n, ttl)
+	if err != nil {
+		return nil, NewRateLimitError("store", "failed to increment window counter", err)
+	}
+	if used == n && fw.alignment == AlignWallClock {
+		// The first charge of a window records its start, which Reset needs to find it
+		if err := counters.Set(ctx, key, encodeWindowStart(start), ttl); err != nil {
+			return nil, NewRateLimitError("store", "failed to save window start", err)
+		}
+	}
+
+	allowed := used <= limit
+	var retryAfter time.Duration
+	if !allowed {
+		if used, err = counters.IncrementBy(ctx, counterKey, -n, ttl); err != nil {
+			return nil, NewRateLimitError("store", "failed to release denied requests", err)
+		}
+		retryAfter = resetTime.Sub(now)
+	}
+
+	remaining := limit - used
+	if remaining < 0 {
+		remaining = 0
+	}
+	return &Result{
+		Allowed:    allowed,
+		Remaining:  remaining,
+		RetryAfter: retryAfter,
+		ResetTime:  resetTime,
+		Limit:      limit,
+		Window:     window,
+		Used:       used,
+		Algorithm:  fw.name,
+	}, nil
+}
+
+// Peek reports whether a single request would be allowed without counting it
+func (fw *FixedWindowAlgorithm) Peek(ctx context.Context, store Store, key string, limit int64, window time.Duration) (*Result, error) {
+	now := fw.now()
+	result := &Result{
+		Allowed:   true,
+		Remaining: limit,
+		ResetTime: now.Add(window),
+		Limit:     limit,
+		Window:    window,
+		Algorithm: fw.name,
+	}
+
+	start, ok := fw.currentStart(ctx, store, key, window, now)
+	if !ok {
+		// No window has started; the first request starts one
+		return result, nil
+	}
+	result.ResetTime = start.Add(window)
+
+	data, err := store.Get(ctx, windowCounterKey(key, start))
+	if err != nil {
+		return result, nil
+	}
+	used, err := decodeCounter(data)
+	if err != nil {
+		return nil, err
+	}
+	result.Used = used
+	result.Remaining = max(limit-used, 0)
+	result.Allowed = result.Remaining >= 1
+	if !result.Allowed {
+		result.RetryAfter = result.ResetTime.Sub(now)
+	}
+	return result, nil
+}
+
+// Reset clears the current window of a key
+func (fw *FixedWindowAlgorithm) Reset(ctx context.Context, store Store, key string) error {
+	if data, err := store.Get(ctx, key); err == nil {
+		if start, err := decodeWindowStart(data); err == nil {
+			if err := store.Delete(ctx, windowCounterKey(key, start)); err != nil {
+				return err
+			}
+		}
+	}
+	return store.Delete(ctx, key)
+}
+
+// ValidateConfig validates the fixed window configuration
+func (fw *FixedWindowAlgorithm) ValidateConfig(limit int64, window time.Duration) error {
+	if limit <= 0 {
+		return NewRateLimitError("config", "limit must be greater than 0", nil)
+	}
+	if window <= 0 {
+		return NewRateLimitError("config", "window must be greater than 0", nil)
+	}
+	return nil
+}
+
+// counterStore returns store as a store with atomic counters
+func (fw *FixedWindowAlgorithm) counterStore(store Store) (CounterStore, error) {
+	counters, ok := store.(CounterStore)
+	if !ok {
+		return nil, NewRateLimitError("store", "fixed window needs a store with atomic counters", nil)
+	}
+	return counters, nil
+}
+
+// windowStart returns the start of the window now falls in, starting a window for keys
+// aligned to their first request that have none
+func (fw *FixedWindowAlgorithm) windowStart(ctx context.Context, store CounterStore, key string, window time.Duration, now time.Time) (time.Time, error) {
+	if start, ok := fw.currentStart(ctx, store, key, window, now); ok {
+		return start, nil
+	}
+
+	data := encodeWindowStart(now)
+	if anchors, ok := store.(anchorStore); ok {
+		set, err := anchors.SetNX(ctx, key, data, window)
+		if err != nil {
+			return time.Time{}, NewRateLimitError("store", "failed to save window start", err)
+		}
+		if !set {
+			// Another instance started the window first
+			if start, ok := fw.currentStart(ctx, store, key, window, now); ok {
+				return start, nil
+			}
+		}
+		return now, nil
+	}
+	if err := store.Set(ctx, key, data, window); err != nil {
+		return time.Time{}, NewRateLimitError("store", "failed to save window start", err)
+	}
+	return now, nil
+}
+
+// currentStart returns the start of the window of key that now falls in, if one started.
+// A first-request start the store has not yet expired is moved forward by whole windows,
+// so every instance derives the same window from it.
+func (fw *FixedWindowAlgorithm) currentStart(ctx context.Context, store Store, key string, window time.Duration, now time.Time) (time.Time, bool) {
+	if fw.alignment == AlignWallClock {
+		return now.Truncate(window), true
+	}
+	data, err := store.Get(ctx, key)
+	if err != nil {
+		return time.Time{}, false
+	}
+	start, err := decodeWindowStart(data)
+	if err != nil {
+		return time.Time{}, false
+	}
+	if elapsed := now.Sub(start); elapsed >= window {
+		start = start.Add(elapsed / window * window)
+	}
+	return start, true
+}
+
+// windowCounterKey is the store key of the counter of the window starting at start
+func windowCounterKey(key string, start time.Time) string {
+	return key + ":" + strconv.FormatInt(start.UnixNano(), 10)
+}
+
+// encodeWindowStart serializes the start of a window
+func encodeWindowStart(start time.Time) []byte {
+	return strconv.AppendInt(nil, start.UnixNano(), 10)
+}
+
+// decodeWindowStart parses a window start saved by encodeWindowStart
+func decodeWindowStart(data []byte) (time.Time, error) {
+	nanos, err := strconv.ParseInt(string(data), 10, 64)
+	if err != nil {
+		return time.Time{}, NewRateLimitError("store", "invalid window start", err)
+	}
+	return time.Unix(0, nanos), nil
+}
+
+// decodeCounter parses a counter kept with IncrementBy: a decimal string in Redis,
+// eight big-endian bytes in the memory store
+func decodeCounter(data []byte) (int64, error) {
+	if value, err := strconv.ParseInt(string(data), 10, 64); err == nil {
+		return value, nil
+	}
+	if len(data) == 8 {
+		var value int64
+		for _, b := range data {
+			value = value<<8 | int64(b)
+		}
+		return value, nil
+	}
+	return 0, NewRateLimitError("store", "invalid window counter", nil)
+}
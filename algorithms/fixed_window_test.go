@@ -0,0 +1,128 @@
+// algorithms/fixed_window_test.go
+package algorithms
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// mockCounterStore adds atomic counters and SetNX to mockStore; counters are stored as
+// decimal strings, as in Redis
+type mockCounterStore struct {
+	*mockStore
+	mu sync.Mutex
+}
+
+func newMockCounterStore() *mockCounterStore {
+	return &mockCounterStore{mockStore: newMockStore()}
+}
+
+func (m *mockCounterStore) IncrementBy(ctx context.Context, key string, amount int64, expiration time.Duration) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var value int64
+	if data, err := m.Get(ctx, key); err == nil {
+		value, _ = strconv.ParseInt(string(data), 10, 64)
+	}
+	value += amount
+	return value, m.Set(ctx, key, []byte(strconv.FormatInt(value, 10)), expiration)
+}
+
+func (m *mockCounterStore) SetNX(ctx context.Context, key string, value []byte, expiration time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, err := m.Get(ctx, key); err == nil {
+		return false, nil
+	}
+	return true, m.Set(ctx, key, value, expiration)
+}
+
+func TestFixedWindowAlgorithm(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 10, 16, 12, 0, 30, 0, time.UTC)
+
+	t.Run("wall clock", func(t *testing.T) {
+		fw := NewFixedWindowAlgorithm()
+		fw.SetClock(func() time.Time { return now })
+		store := newMockCounterStore()
+
+		for i := int64(1); i <= 3; i++ {
+			result, err := fw.Allow(ctx, store, "key", 3, time.Minute, 1)
+			if err != nil || !result.Allowed || result.Remaining != 3-i {
+				t.Fatalf("Request %d: expected to be allowed, got %+v (%v)", i, result, err)
+			}
+		}
+		result, err := fw.Allow(ctx, store, "key", 3, time.Minute, 1)
+		if err != nil || result.Allowed {
+			t.Fatalf("Expected the fourth request to be denied, got %+v (%v)", result, err)
+		}
+		reset := time.Date(2026, 10, 16, 12, 1, 0, 0, time.UTC)
+		if !result.ResetTime.Equal(reset) || result.RetryAfter != 30*time.Second || result.Used != 3 {
+			t.Errorf("Expected a reset on the minute with 3 used, got %+v", result)
+		}
+		if peek, _ := fw.Peek(ctx, store, "key", 3, time.Minute); peek.Allowed || peek.Used != 3 {
+			t.Errorf("Expected Peek to see the full window, got %+v", peek)
+		}
+
+		// The next minute is a new window
+		now = reset
+		if result, _ := fw.Allow(ctx, store, "key", 3, time.Minute, 1); !result.Allowed || result.Used != 1 {
+			t.Errorf("Expected a fresh window, got %+v", result)
+		}
+
+		if err := fw.Reset(ctx, store, "key"); err != nil {
+			t.Fatalf("Reset failed: %v", err)
+		}
+		if peek, _ := fw.Peek(ctx, store, "key", 3, time.Minute); peek.Used != 0 {
+			t.Errorf("Expected Reset to clear the window, got %+v", peek)
+		}
+	})
+
+	t.Run("denied requests are not counted", func(t *testing.T) {
+		fw := NewFixedWindowAlgorithm()
+		fw.SetClock(func() time.Time { return now })
+		store := newMockCounterStore()
+
+		fw.Allow(ctx, store, "key", 5, time.Minute, 3)
+		if result, _ := fw.Allow(ctx, store, "key", 5, time.Minute, 3); result.Allowed || result.Remaining != 2 {
+			t.Errorf("Expected the request of 3 to be denied with 2 left, got %+v", result)
+		}
+		if result, _ := fw.Allow(ctx, store, "key", 5, time.Minute, 2); !result.Allowed {
+			t.Errorf("Expected the remaining 2 to be allowed, got %+v", result)
+		}
+	})
+
+	t.Run("first request", func(t *testing.T) {
+		fw := NewFixedWindowAlgorithm()
+		if err := fw.SetAlignment(AlignFirstRequest); err != nil {
+			t.Fatalf("SetAlignment failed: %v", err)
+		}
+		start := now
+		fw.SetClock(func() time.Time { return now })
+		store := newMockCounterStore()
+
+		fw.Allow(ctx, store, "key", 2, time.Minute, 2)
+		now = start.Add(45 * time.Second)
+		result, _ := fw.Allow(ctx, store, "key", 2, time.Minute, 1)
+		if result.Allowed || !result.ResetTime.Equal(start.Add(time.Minute)) {
+			t.Errorf("Expected the window to end a minute after the first request, got %+v", result)
+		}
+
+		// A start the store kept too long is moved forward by whole windows
+		now = start.Add(150 * time.Second)
+		result, _ = fw.Allow(ctx, store, "key", 2, time.Minute, 1)
+		if !result.Allowed || !result.ResetTime.Equal(start.Add(3*time.Minute)) {
+			t.Errorf("Expected the third window, got %+v", result)
+		}
+	})
+
+	if err := NewFixedWindowAlgorithm().SetAlignment("hourly"); err == nil {
+		t.Error("Expected an unknown alignment to be rejected")
+	}
+	if _, err := NewFixedWindowAlgorithm().Allow(ctx, newMockStore(), "key", 1, time.Minute, 1); err == nil {
+		t.Error("Expected a store without counters to be rejected")
+	}
+}
@@ -0,0 +1,295 @@
+// algorithms/scripts.go
+package algorithms
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// ScriptRunner is implemented by stores that run Lua scripts atomically, such as Redis.
+// The token bucket and sliding window algorithms read, update and write their state in
+// one script on such stores, so checks of instances sharing the store cannot overwrite
+// each other's updates. Other stores keep the Get/Set path.
+type ScriptRunner interface {
+	// RunScript runs a script with KEYS and ARGV and returns its reply: Lua tables become
+	// []interface{}, integers int64 and strings string
+	RunScript(ctx context.Context, script string, keys []string, args ...interface{}) (interface{}, error)
+}
+
+// ErrScriptsUnsupported is returned by a ScriptRunner that cannot run scripts after all,
+// e.g. a wrapper around a store without them; the algorithms then use the Get/Set path
+var ErrScriptsUnsupported = errors.New("store does not run scripts")
+
+// scriptFallback is the first value of the reply of a script that found state it does not
+// understand, such as compressed state or state written before it existed. The check then
+// takes the Get/Set path, which rewrites the state in a form the script understands.
+const scriptFallback = -1
+
+// luaTimestamps are helpers shared by the scripts. Redis runs Lua 5.1, whose numbers are
+// doubles and cannot hold Unix nanoseconds exactly, so timestamps are kept as decimal
+// strings and split into seconds and nanoseconds for arithmetic.
+const luaTimestamps = `
+local function split(ts)
+	local len = string.len(ts)
+	if len <= 9 then
+		return 0, tonumber(ts)
+	end
+	return tonumber(string.sub(ts, 1, len - 9)), tonumber(string.sub(ts, len - 8))
+end
+
+local function before(as, an, bs, bn)
+	return as < bs or (as == bs and an < bn)
+end
+
+local function rfc3339(ts)
+	local secs, nanos = split(ts)
+	local days = math.floor(secs / 86400)
+	local rem = secs - days * 86400
+	local z = days + 719468
+	local era = math.floor(z / 146097)
+	local doe = z - era * 146097
+	local yoe = math.floor((doe - math.floor(doe / 1460) + math.floor(doe / 36524) - math.floor(doe / 146096)) / 365)
+	local doy = doe - (365 * yoe + math.floor(yoe / 4) - math.floor(yoe / 100))
+	local mp = math.floor((5 * doy + 2) / 153)
+	local day = doy - math.floor((153 * mp + 2) / 5) + 1
+	local month = mp + 3
+	if mp >= 10 then
+		month = mp - 9
+	end
+	local year = yoe + era * 400
+	if month <= 2 then
+		year = year + 1
+	end
+	return string.format('%04d-%02d-%02dT%02d:%02d:%02d.%09dZ', year, month, day,
+		math.floor(rem / 3600), math.floor(rem % 3600 / 60), rem % 60, nanos)
+end
+`
+
+// tokenBucketScript refills and takes tokens like TokenBucketAlgorithm.Allow and saves the
+// bucket as JSON a TokenBucketState decodes, with its refill time in both forms.
+//
+// KEYS[1]: bucket key
+// ARGV: capacity, refill rate (tokens per second), requests, now (Unix ns),
+// expiration (ms), window (ns)
+// Reply: {allowed (0 or 1), tokens left}, or {-1} for state it does not understand
+const tokenBucketScript = luaTimestamps + `
+local capacity = tonumber(ARGV[1])
+local rate = tonumber(ARGV[2])
+local n = tonumber(ARGV[3])
+local now = ARGV[4]
+
+local tokens, last, total, denied = capacity, now, 0, 0
+local data = redis.call('GET', KEYS[1])
+if data then
+	local t = string.match(data, '"tokens":([-+.%deE]+)')
+	local l = string.match(data, '"last_refill_ns":(%d+)')
+	if not t or not l then
+		return {-1}
+	end
+	tokens, last = tonumber(t), l
+	total = tonumber(string.match(data, '"total_requests":(%d+)') or '0')
+	denied = tonumber(string.match(data, '"denied_requests":(%d+)') or '0')
+end
+
+local ls, ln = split(last)
+local ns, nn = split(now)
+local elapsed = (ns - ls) + (nn - ln) / 1e9
+if elapsed > 0 then
+	tokens = math.min(tokens + rate * elapsed, capacity)
+	last = now
+end
+
+local allowed = 0
+if tokens >= n then
+	tokens = tokens - n
+	total = total + n
+	allowed = 1
+else
+	denied = denied + n
+end
+
+redis.call('SET', KEYS[1], string.format(
+	'{"tokens":%.17g,"capacity":%d,"refill_rate":%.17g,"last_refill":"%s","total_requests":%d,"denied_requests":%d,"window_duration":%s,"last_refill_ns":%s}',
+	tokens, capacity, rate, rfc3339(last), total, denied, ARGV[6], last), 'PX', ARGV[5])
+return {allowed, string.format('%.17g', tokens)}
+`
+
+// slidingWindowScript drops expired requests and records new ones like
+// SlidingWindowAlgorithm.Allow and saves the window as JSON a SlidingWindowState decodes.
+//
+// KEYS[1]: window key
+// ARGV: limit, requests, now (Unix ns), window (ns), skew tolerance (ns), expiration (ms)
+// Reply: {allowed (0 or 1), requests in the window, time evaluated at (Unix ns),
+// oldest request in the window (Unix ns, "" if none)}, or {-1} for state it does not understand
+const slidingWindowScript = luaTimestamps + `
+local limit = tonumber(ARGV[1])
+local n = tonumber(ARGV[2])
+local now = ARGV[3]
+local tolerance = tonumber(ARGV[5])
+
+local requests, total, denied = {}, 0, 0
+local data = redis.call('GET', KEYS[1])
+if data then
+	local list = string.match(data, '"requests":%[([%d,]*)%]')
+	if not list then
+		if not string.find(data, '"requests":null', 1, true) then
+			return {-1}
+		end
+		list = ''
+	end
+	for ts in string.gmatch(list, '%d+') do
+		requests[#requests + 1] = ts
+	end
+	total = tonumber(string.match(data, '"total_requests":(%d+)') or '0')
+	denied = tonumber(string.match(data, '"denied_requests":(%d+)') or '0')
+end
+
+-- Adopt the newest request when another instance's clock leads by no more than the tolerance
+local ns, nn = split(now)
+if tolerance > 0 and #requests > 0 then
+	local newest = requests[#requests]
+	local es, en = split(newest)
+	local lead = (es - ns) * 1e9 + (en - nn)
+	if lead > 0 and lead <= tolerance then
+		now, ns, nn = newest, es, en
+	end
+end
+
+local ws, wn = split(ARGV[4])
+local cs, cn = ns - ws, nn - wn
+if cn < 0 then
+	cs, cn = cs - 1, cn + 1e9
+end
+
+local kept = {}
+for _, ts in ipairs(requests) do
+	local s, ns_ = split(ts)
+	if not before(s, ns_, cs, cn) then
+		kept[#kept + 1] = ts
+	end
+end
+
+local allowed = 0
+if limit - #kept >= n then
+	local at = #kept + 1
+	for i, ts in ipairs(kept) do
+		local s, ns_ = split(ts)
+		if before(ns, nn, s, ns_) then
+			at = i
+			break
+		end
+	end
+	for _ = 1, n do
+		table.insert(kept, at, now)
+	end
+	total = total + n
+	allowed = 1
+else
+	denied = denied + n
+end
+
+redis.call('SET', KEYS[1], string.format(
+	'{"requests":[%s],"total_requests":%d,"denied_requests":%d,"window_nano":%s,"last_cleanup":%s,"limit":%d}',
+	table.concat(kept, ','), total, denied, ARGV[4], now, limit), 'PX', ARGV[6])
+return {allowed, #kept, now, kept[1] or ''}
+`
+
+// runScript runs a script on a store that supports scripts. It reports false without an
+// error when the check has to take the Get/Set path instead.
+func runScript(ctx context.Context, store Store, script string, key string, args ...interface{}) ([]interface{}, bool, error) {
+	runner, ok := store.(ScriptRunner)
+	if !ok {
+		return nil, false, nil
+	}
+	reply, err := runner.RunScript(ctx, script, []string{key}, args...)
+	if errors.Is(err, ErrScriptsUnsupported) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, NewRateLimitError("store", "failed to run script", err)
+	}
+	values, ok := reply.([]interface{})
+	if !ok || len(values) == 0 {
+		return nil, false, NewRateLimitError("store", fmt.Sprintf("unexpected script reply %v", reply), nil)
+	}
+	if code, _ := values[0].(int64); code == scriptFallback {
+		return nil, false, nil
+	}
+	return values, true, nil
+}
+
+// replyInt returns the integer at index i of a script reply; integers may also arrive as
+// decimal strings, which keeps Unix nanoseconds exact
+func replyInt(values []interface{}, i int) (int64, error) {
+	if i < len(values) {
+		switch v := values[i].(type) {
+		case int64:
+			return v, nil
+		case string:
+			if v == "" {
+				return 0, nil
+			}
+			if parsed, err := strconv.ParseInt(v, 10, 64); err == nil {
+				return parsed, nil
+			}
+		}
+	}
+	return 0, NewRateLimitError("store", fmt.Sprintf("unexpected script reply %v", values), nil)
+}
+
+// allowScripted runs a token bucket check in a single script. It reports false when the
+// store cannot run it, or state is compressed, and the check must take the Get/Set path.
+func (tb *TokenBucketAlgorithm) allowScripted(ctx context.Context, store Store, key string, limit int64, window time.Duration, n int64) (*Result, bool, error) {
+	if tb.compressor != nil {
+		return nil, false, nil
+	}
+	refillRate := float64(limit) / window.Seconds()
+	now := tb.now()
+	_, expiration, _ := tb.encodeBucketState(&TokenBucketState{}, window)
+
+	values, ok, err := runScript(ctx, store, tokenBucketScript, key,
+		limit, strconv.FormatFloat(refillRate, 'g', -1, 64), n,
+		strconv.FormatInt(now.UnixNano(), 10), expiration.Milliseconds(), window.Nanoseconds())
+	if !ok || err != nil {
+		return nil, ok, err
+	}
+	allowed, err := replyInt(values, 0)
+	if err != nil {
+		return nil, true, err
+	}
+	if len(values) < 2 {
+		return nil, true, NewRateLimitError("store", fmt.Sprintf("unexpected script reply %v", values), nil)
+	}
+	tokens, err := strconv.ParseFloat(fmt.Sprint(values[1]), 64)
+	if err != nil {
+		return nil, true, NewRateLimitError("store", fmt.Sprintf("unexpected script reply %v", values), err)
+	}
+	return tb.result(allowed == 1, tokens, limit, window, n, now), true, nil
+}
+
+// allowScripted runs a sliding window check in a single script. It reports false when the
+// store cannot run it, or state is compressed, and the check must take the Get/Set path.
+func (sw *SlidingWindowAlgorithm) allowScripted(ctx context.Context, store Store, key string, limit int64, window time.Duration, n int64) (*Result, bool, error) {
+	if sw.compressor != nil {
+		return nil, false, nil
+	}
+	_, expiration, _ := sw.encodeState(&SlidingWindowState{}, window)
+
+	values, ok, err := runScript(ctx, store, slidingWindowScript, key,
+		limit, n, strconv.FormatInt(sw.now().UnixNano(), 10), window.Nanoseconds(),
+		sw.skewTolerance.Nanoseconds(), expiration.Milliseconds())
+	if !ok || err != nil {
+		return nil, ok, err
+	}
+	var reply [4]int64
+	for i := range reply {
+		if reply[i], err = replyInt(values, i); err != nil {
+			return nil, true, err
+		}
+	}
+	allowed, used, nowNano, oldest := reply[0] == 1, reply[1], reply[2], reply[3]
+	return sw.result(allowed, used, oldest, limit, window, time.Unix(0, nowNano)), true, nil
+}
@@ -0,0 +1,158 @@
+// algorithms/scripts_test.go
+package algorithms
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// scriptStore is a mockStore that runs scripts by answering with a canned reply
+type scriptStore struct {
+	*mockStore
+	reply interface{}
+	err   error
+	keys  []string
+	args  []interface{}
+	runs  int
+}
+
+func (s *scriptStore) RunScript(ctx context.Context, script string, keys []string, args ...interface{}) (interface{}, error) {
+	s.runs++
+	s.keys, s.args = keys, args
+	return s.reply, s.err
+}
+
+func TestTokenBucketAlgorithm_Script(t *testing.T) {
+	now := time.Unix(1700000000, 500)
+	algorithm := NewTokenBucketAlgorithm()
+	algorithm.SetClock(func() time.Time { return now })
+	ctx := context.Background()
+
+	store := &scriptStore{mockStore: newMockStore(), reply: []interface{}{int64(1), "7.5"}}
+	result, err := algorithm.Allow(ctx, store, "test:bucket", 10, 10*time.Second, 2)
+	if err != nil {
+		t.Fatalf("Allow failed: %v", err)
+	}
+	if !result.Allowed || result.Remaining != 7 || result.Used != 3 {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+	if want := now.Add(2 * time.Second); !result.ResetTime.Equal(want) {
+		t.Errorf("Expected reset at %v, got %v", want, result.ResetTime)
+	}
+	if store.keys[0] != "test:bucket" || store.args[2] != int64(2) || store.args[3] != "1700000000000000500" {
+		t.Errorf("Unexpected script call: %v %v", store.keys, store.args)
+	}
+	if len(store.data) != 0 {
+		t.Error("Expected the script alone to update the bucket")
+	}
+
+	store.reply = []interface{}{int64(0), "0.5"}
+	result, err = algorithm.Allow(ctx, store, "test:bucket", 10, 10*time.Second, 2)
+	if err != nil {
+		t.Fatalf("Allow failed: %v", err)
+	}
+	if result.Allowed || result.Remaining != 0 || result.RetryAfter != time.Second {
+		t.Errorf("Expected a denial retrying after 1s, got %+v", result)
+	}
+}
+
+func TestSlidingWindowAlgorithm_Script(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	algorithm := NewSlidingWindowAlgorithm()
+	algorithm.SetClock(func() time.Time { return now })
+	ctx := context.Background()
+
+	oldest := now.Add(-40 * time.Second)
+	store := &scriptStore{mockStore: newMockStore(), reply: []interface{}{
+		int64(0), int64(5), "1700000000000000000", "1699999960000000000",
+	}}
+	result, err := algorithm.Allow(ctx, store, "test:window", 5, time.Minute, 1)
+	if err != nil {
+		t.Fatalf("Allow failed: %v", err)
+	}
+	if result.Allowed || result.Remaining != 0 || result.Used != 5 {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+	if result.RetryAfter != 20*time.Second || !result.ResetTime.Equal(oldest.Add(time.Minute)) {
+		t.Errorf("Expected a retry after 20s, got %v (reset %v)", result.RetryAfter, result.ResetTime)
+	}
+
+	store.reply = []interface{}{int64(1), int64(1), "1700000000000000000", "1700000000000000000"}
+	result, err = algorithm.Allow(ctx, store, "test:window", 5, time.Minute, 1)
+	if err != nil {
+		t.Fatalf("Allow failed: %v", err)
+	}
+	if !result.Allowed || result.Remaining != 4 || !result.ResetTime.Equal(now.Add(time.Minute)) {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+}
+
+func TestScriptFallback(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name  string
+		store *scriptStore
+	}{
+		{"unsupported", &scriptStore{err: ErrScriptsUnsupported}},
+		{"state not understood", &scriptStore{reply: []interface{}{int64(scriptFallback)}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.store.mockStore = newMockStore()
+			if _, err := NewTokenBucketAlgorithm().Allow(ctx, tt.store, "test:bucket", 10, time.Minute, 1); err != nil {
+				t.Fatalf("Token bucket failed: %v", err)
+			}
+			if _, err := NewSlidingWindowAlgorithm().Allow(ctx, tt.store, "test:window", 10, time.Minute, 1); err != nil {
+				t.Fatalf("Sliding window failed: %v", err)
+			}
+			if tt.store.runs != 2 || len(tt.store.data) != 2 {
+				t.Errorf("Expected both checks to fall back to Get/Set, got %d runs and %d keys", tt.store.runs, len(tt.store.data))
+			}
+		})
+	}
+
+	// Compressed state is never handed to a script
+	algorithm := NewSlidingWindowAlgorithm()
+	if err := algorithm.SetCompression(CompressionConfig{Algorithm: CompressionSnappy}); err != nil {
+		t.Fatalf("SetCompression failed: %v", err)
+	}
+	store := &scriptStore{mockStore: newMockStore()}
+	if _, err := algorithm.Allow(ctx, store, "test:window", 10, time.Minute, 1); err != nil || store.runs != 0 {
+		t.Errorf("Expected the Get/Set path with compression, got %d runs (%v)", store.runs, err)
+	}
+}
+
+func TestScriptErrors(t *testing.T) {
+	ctx := context.Background()
+	failing := &scriptStore{mockStore: newMockStore(), err: errors.New("connection refused")}
+	if _, err := NewTokenBucketAlgorithm().Allow(ctx, failing, "test:bucket", 10, time.Minute, 1); err == nil || !strings.Contains(err.Error(), "connection refused") {
+		t.Errorf("Expected the store failure, got %v", err)
+	}
+
+	garbled := &scriptStore{mockStore: newMockStore(), reply: []interface{}{int64(1)}}
+	if _, err := NewSlidingWindowAlgorithm().Allow(ctx, garbled, "test:window", 10, time.Minute, 1); err == nil {
+		t.Error("Expected an error for a short reply")
+	}
+}
+
+func TestTokenBucketState_RefillNano(t *testing.T) {
+	now := time.Unix(1700000000, 123456789)
+	algorithm := NewTokenBucketAlgorithm()
+	algorithm.SetClock(func() time.Time { return now })
+	store := newMockStore()
+
+	if _, err := algorithm.Allow(context.Background(), store, "test:bucket", 10, time.Minute, 1); err != nil {
+		t.Fatalf("Allow failed: %v", err)
+	}
+	var state TokenBucketState
+	if err := json.Unmarshal(store.data["test:bucket"], &state); err != nil {
+		t.Fatalf("Failed to decode state: %v", err)
+	}
+	if state.LastRefillNano != now.UnixNano() {
+		t.Errorf("Expected the refill time in nanoseconds for scripts, got %d", state.LastRefillNano)
+	}
+}
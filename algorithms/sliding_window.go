@@ -5,6 +5,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"math"
 	"sort"
 	"time"
 )
@@ -13,13 +14,17 @@ import (
 // This provides more accurate rate limiting by tracking individual requests
 // within a rolling time window
 type SlidingWindowAlgorithm struct {
-	name string
+	name          string
+	compressor    *stateCompressor
+	now           func() time.Time
+	skewTolerance time.Duration
 }
 
 // NewSlidingWindowAlgorithm creates a new sliding window algorithm
 func NewSlidingWindowAlgorithm() *SlidingWindowAlgorithm {
 	return &SlidingWindowAlgorithm{
 		name: "sliding_window",
+		now:  time.Now,
 	}
 }
 
@@ -28,6 +33,36 @@ func (sw *SlidingWindowAlgorithm) Name() string {
 	return sw.name
 }
 
+// SetClock replaces the time source, e.g. with a virtual clock in simulations.
+// It must be called before the algorithm is used concurrently.
+func (sw *SlidingWindowAlgorithm) SetClock(now func() time.Time) {
+	sw.now = now
+}
+
+// SetSkewTolerance lets the window adopt request timestamps up to d ahead of the local clock.
+// Instances sharing a store then evaluate the window at the time of the instance whose clock
+// leads, instead of keeping requests it has already expired or recording requests out of order.
+// It must be called before the algorithm is used concurrently.
+func (sw *SlidingWindowAlgorithm) SetSkewTolerance(d time.Duration) {
+	sw.skewTolerance = d
+}
+
+// SetCompression enables compression of serialized window state above a size threshold.
+// It must be called before the algorithm is used concurrently.
+func (sw *SlidingWindowAlgorithm) SetCompression(config CompressionConfig) error {
+	compressor, err := newStateCompressor(config)
+	if err != nil {
+		return err
+	}
+	sw.compressor = compressor
+	return nil
+}
+
+// CompressionStats returns compression savings and CPU cost for this algorithm
+func (sw *SlidingWindowAlgorithm) CompressionStats() CompressionStats {
+	return sw.compressor.stats()
+}
+
 // SlidingWindowState represents the current state of a sliding window
 type SlidingWindowState struct {
 	// Array of request timestamps within the current window
@@ -64,8 +99,11 @@ func (sw *SlidingWindowAlgorithm) Allow(ctx context.Context, store Store, key st
 		}, NewRateLimitError("validation", "request count must be greater than 0", nil)
 	}
 
-	now := time.Now()
-	nowNano := now.UnixNano()
+	// Clean up, record and save in one step on stores that run scripts
+	if result, ok, err := sw.allowScripted(ctx, store, key, limit, window, n); ok || err != nil {
+		return result, err
+	}
+
 	windowNano := int64(window.Nanoseconds())
 
 	// Get current state
@@ -73,58 +111,96 @@ func (sw *SlidingWindowAlgorithm) Allow(ctx context.Context, store Store, key st
 	if err != nil {
 		return nil, err
 	}
+	now := sw.skewedNow(state)
+	nowNano := now.UnixNano()
 
 	// Clean up old requests outside the current window
 	state = sw.cleanupExpiredRequests(state, nowNano)
 
-	// Calculate current usage
-	currentUsage := int64(len(state.Requests))
-	remaining := limit - currentUsage
-
 	// Check if request can be allowed
-	allowed := remaining >= n
-
-	var retryAfter time.Duration
-	var resetTime time.Time
-
+	allowed := limit-int64(len(state.Requests)) >= n
 	if allowed {
 		// Add the new requests to the window
-		for i := int64(0); i < n; i++ {
-			state.Requests = append(state.Requests, nowNano)
-		}
+		state.Requests = insertRequests(state.Requests, nowNano, n)
 		state.TotalRequests += n
-		remaining -= n
-		currentUsage += n
 	} else {
-		// Request denied - calculate retry after time
 		state.DeniedRequests += n
+	}
 
-		if len(state.Requests) > 0 {
-			// Find the oldest request that will expire
-			oldestRequest := state.Requests[0]
-			retryAfter = time.Duration(oldestRequest + windowNano - nowNano)
-		} else {
-			// No requests in window, can retry immediately
-			retryAfter = 0
-		}
+	// Update last cleanup time
+	state.LastCleanup = nowNano
+
+	// Save state back to store
+	if err := sw.saveState(ctx, store, key, state, window); err != nil {
+		return nil, err
 	}
 
-	// Calculate reset time (when the window will have capacity again)
+	var oldest int64
 	if len(state.Requests) > 0 {
+		oldest = state.Requests[0]
+	}
+	return sw.result(allowed, int64(len(state.Requests)), oldest, limit, window, now), nil
+}
+
+// result reports a check that left used requests in the window, the oldest recorded at
+// oldest (Unix nanoseconds)
+func (sw *SlidingWindowAlgorithm) result(allowed bool, used, oldest, limit int64, window time.Duration, now time.Time) *Result {
+	var retryAfter time.Duration
+	var resetTime time.Time
+
+	if !allowed && used > 0 {
+		// Request denied - retry when the oldest request expires
+		retryAfter = time.Duration(oldest + window.Nanoseconds() - now.UnixNano())
+	}
+
+	// Calculate reset time (when the window will have capacity again)
+	if used > 0 {
 		// Reset time is when the oldest request expires
-		oldestRequest := state.Requests[0]
-		resetTime = time.Unix(0, oldestRequest+windowNano)
+		resetTime = time.Unix(0, oldest+window.Nanoseconds())
 	} else {
 		resetTime = now.Add(window)
 	}
 
-	// Update last cleanup time
-	state.LastCleanup = nowNano
+	return &Result{
+		Allowed:    allowed,
+		Remaining:  limit - used,
+		RetryAfter: retryAfter,
+		ResetTime:  resetTime,
+		Limit:      limit,
+		Window:     window,
+		Used:       used,
+		Algorithm:  sw.name,
+	}
+}
 
-	// Save state back to store
-	if err := sw.saveState(ctx, store, key, state, window); err != nil {
+// Peek reports whether a single request would be allowed without recording it or saving state
+func (sw *SlidingWindowAlgorithm) Peek(ctx context.Context, store Store, key string, limit int64, window time.Duration) (*Result, error) {
+	windowNano := int64(window.Nanoseconds())
+
+	state, err := sw.getState(ctx, store, key, limit, windowNano)
+	if err != nil {
 		return nil, err
 	}
+	now := sw.skewedNow(state)
+	nowNano := now.UnixNano()
+	state = sw.cleanupExpiredRequests(state, nowNano)
+
+	currentUsage := int64(len(state.Requests))
+	remaining := limit - currentUsage
+	if remaining < 0 {
+		remaining = 0
+	}
+	allowed := remaining >= 1
+
+	var retryAfter time.Duration
+	resetTime := now.Add(window)
+	if len(state.Requests) > 0 {
+		oldestRequest := state.Requests[0]
+		resetTime = time.Unix(0, oldestRequest+windowNano)
+		if !allowed {
+			retryAfter = time.Duration(oldestRequest + windowNano - nowNano)
+		}
+	}
 
 	return &Result{
 		Allowed:    allowed,
@@ -151,7 +227,7 @@ func (sw *SlidingWindowAlgorithm) GetWindowInfo(ctx context.Context, store Store
 		return nil, err
 	}
 
-	nowNano := time.Now().UnixNano()
+	nowNano := sw.now().UnixNano()
 	state = sw.cleanupExpiredRequests(state, nowNano)
 
 	// Calculate request distribution over time
@@ -206,7 +282,7 @@ func (sw *SlidingWindowAlgorithm) GetMetrics(ctx context.Context, store Store, k
 		return nil, err
 	}
 
-	nowNano := time.Now().UnixNano()
+	nowNano := sw.now().UnixNano()
 	state = sw.cleanupExpiredRequests(state, nowNano)
 
 	metrics := &WindowMetrics{
@@ -298,11 +374,16 @@ func (sw *SlidingWindowAlgorithm) getState(ctx context.Context, store Store, key
 			TotalRequests:  0,
 			DeniedRequests: 0,
 			WindowNano:     windowNano,
-			LastCleanup:    time.Now().UnixNano(),
+			LastCleanup:    sw.now().UnixNano(),
 			Limit:          limit,
 		}, nil
 	}
 
+	data, err = sw.compressor.decode(data)
+	if err != nil {
+		return nil, err
+	}
+
 	var state SlidingWindowState
 	if err := json.Unmarshal(data, &state); err != nil {
 		return nil, NewRateLimitError("store", "failed to unmarshal sliding window state", err)
@@ -317,14 +398,75 @@ func (sw *SlidingWindowAlgorithm) getState(ctx context.Context, store Store, key
 
 // saveState saves the sliding window state to storage
 func (sw *SlidingWindowAlgorithm) saveState(ctx context.Context, store Store, key string, state *SlidingWindowState, window time.Duration) error {
+	data, expiration, err := sw.encodeState(state, window)
+	if err != nil {
+		return err
+	}
+	return store.Set(ctx, key, data, expiration)
+}
+
+// encodeState serializes the window state and returns how long the store keeps it
+func (sw *SlidingWindowAlgorithm) encodeState(state *SlidingWindowState, window time.Duration) ([]byte, time.Duration, error) {
 	data, err := json.Marshal(state)
 	if err != nil {
-		return NewRateLimitError("store", "failed to marshal sliding window state", err)
+		return nil, 0, NewRateLimitError("store", "failed to marshal sliding window state", err)
+	}
+
+	data, err = sw.compressor.encode(data)
+	if err != nil {
+		return nil, 0, err
 	}
 
 	// Set expiration to window + buffer for cleanup
-	expiration := window + time.Hour
-	return store.Set(ctx, key, data, expiration)
+	return data, window + time.Hour, nil
+}
+
+// InitialState returns the serialized state of an empty window and how long the store keeps it,
+// for creating keys before the first request arrives
+func (sw *SlidingWindowAlgorithm) InitialState(limit int64, window time.Duration) ([]byte, time.Duration, error) {
+	return sw.encodeState(&SlidingWindowState{
+		Requests:    make([]int64, 0),
+		WindowNano:  window.Nanoseconds(),
+		LastCleanup: sw.now().UnixNano(),
+		Limit:       limit,
+	}, window)
+}
+
+// skewedNow returns the time the window is evaluated at: the local time, or the newest
+// recorded request when another instance's clock leads by no more than the skew tolerance
+func (sw *SlidingWindowAlgorithm) skewedNow(state *SlidingWindowState) time.Time {
+	now := sw.now()
+	if sw.skewTolerance <= 0 || len(state.Requests) == 0 {
+		return now
+	}
+
+	newest := state.Requests[len(state.Requests)-1]
+	lead := newest - now.UnixNano()
+	if lead <= 0 || lead > int64(sw.skewTolerance) {
+		return now
+	}
+	return time.Unix(0, newest)
+}
+
+// insertRequests records n requests at nowNano, keeping the timestamps sorted even when
+// another instance with a leading clock recorded newer ones
+func insertRequests(requests []int64, nowNano, n int64) []int64 {
+	i := sort.Search(len(requests), func(i int) bool {
+		return requests[i] > nowNano
+	})
+	if i == len(requests) {
+		for j := int64(0); j < n; j++ {
+			requests = append(requests, nowNano)
+		}
+		return requests
+	}
+
+	inserted := make([]int64, 0, int64(len(requests))+n)
+	inserted = append(inserted, requests[:i]...)
+	for j := int64(0); j < n; j++ {
+		inserted = append(inserted, nowNano)
+	}
+	return append(inserted, requests[i:]...)
 }
 
 // cleanupExpiredRequests removes requests that are outside the current window
@@ -356,23 +498,34 @@ func (sw *SlidingWindowAlgorithm) GetRequestPattern(ctx context.Context, store S
 		return nil, err
 	}
 
-	nowNano := time.Now().UnixNano()
+	nowNano := sw.now().UnixNano()
 	state = sw.cleanupExpiredRequests(state, nowNano)
 
+	return AnalyzeRequests(state.Requests, time.Unix(0, nowNano-windowNano), time.Unix(0, nowNano)), nil
+}
+
+// Thresholds of request pattern analysis
+const (
+	burstThreshold   = time.Second // Requests within 1 second are considered a burst
+	minBurstRequests = 3           // Only count bursts of 3+ requests
+	peakSpan         = time.Minute // Span over which the peak rate is measured
+)
+
+// AnalyzeRequests computes the request pattern of request timestamps (Unix nanoseconds)
+// observed between windowStart and windowEnd. The timestamps are not modified.
+func AnalyzeRequests(timestamps []int64, windowStart, windowEnd time.Time) *RequestPattern {
 	pattern := &RequestPattern{
-		TotalRequests: int64(len(state.Requests)),
-		WindowStart:   time.Unix(0, nowNano-windowNano),
-		WindowEnd:     time.Unix(0, nowNano),
+		TotalRequests: int64(len(timestamps)),
+		WindowStart:   windowStart,
+		WindowEnd:     windowEnd,
 	}
 
-	if len(state.Requests) == 0 {
-		return pattern, nil
+	if len(timestamps) == 0 {
+		return pattern
 	}
 
-	// Calculate statistics
-	requests := state.Requests
-
-	// Sort to ensure chronological order (should already be sorted, but ensure it)
+	// Sort a copy to ensure chronological order (should already be sorted, but ensure it)
+	requests := append([]int64(nil), timestamps...)
 	sort.Slice(requests, func(i, j int) bool {
 		return requests[i] < requests[j]
 	})
@@ -402,6 +555,8 @@ func (sw *SlidingWindowAlgorithm) GetRequestPattern(ctx context.Context, store S
 				pattern.MaxInterval = interval
 			}
 		}
+
+		pattern.IntervalStdDev, pattern.Burstiness = intervalSpread(intervals, pattern.AverageInterval)
 	}
 
 	// Calculate request rate (requests per second)
@@ -411,7 +566,6 @@ func (sw *SlidingWindowAlgorithm) GetRequestPattern(ctx context.Context, store S
 	}
 
 	// Detect bursts (sequences of requests with small intervals)
-	burstThreshold := time.Second // Requests within 1 second are considered a burst
 	var burstCount int
 	var currentBurstSize int
 
@@ -424,19 +578,57 @@ func (sw *SlidingWindowAlgorithm) GetRequestPattern(ctx context.Context, store S
 				currentBurstSize++
 			}
 		} else {
-			if currentBurstSize >= 3 { // Only count bursts of 3+ requests
+			if currentBurstSize >= minBurstRequests {
 				burstCount++
 			}
 			currentBurstSize = 0
 		}
 	}
-	if currentBurstSize >= 3 {
+	if currentBurstSize >= minBurstRequests {
 		burstCount++
 	}
 
 	pattern.BurstCount = burstCount
+	pattern.PeakMinuteRate = peakCount(requests, int64(peakSpan))
+
+	return pattern
+}
+
+// intervalSpread returns the standard deviation of inter-arrival intervals and the
+// burstiness score (σ-μ)/(σ+μ). The score is -1 for perfectly periodic requests, around 0
+// for independent arrivals and approaches 1 for bursts separated by long pauses.
+// It needs at least two intervals; fewer yield 0.
+func intervalSpread(intervals []time.Duration, mean time.Duration) (time.Duration, float64) {
+	if len(intervals) < 2 {
+		return 0, 0
+	}
 
-	return pattern, nil
+	var sumSquares float64
+	for _, interval := range intervals {
+		diff := float64(interval - mean)
+		sumSquares += diff * diff
+	}
+	stdDev := math.Sqrt(sumSquares / float64(len(intervals)))
+
+	if stdDev+float64(mean) == 0 {
+		return 0, 1 // Every request arrived at once
+	}
+	return time.Duration(stdDev), (stdDev - float64(mean)) / (stdDev + float64(mean))
+}
+
+// peakCount returns the most sorted timestamps falling within any span of the given length
+func peakCount(requests []int64, span int64) int64 {
+	var peak int64
+	start := 0
+	for end := range requests {
+		for requests[end]-requests[start] >= span {
+			start++
+		}
+		if count := int64(end - start + 1); count > peak {
+			peak = count
+		}
+	}
+	return peak
 }
 
 // RequestPattern contains analysis of request patterns within a sliding window
@@ -449,4 +641,7 @@ type RequestPattern struct {
 	MaxInterval     time.Duration `json:"max_interval"`
 	RequestRate     float64       `json:"request_rate"` // Requests per second
 	BurstCount      int           `json:"burst_count"`  // Number of burst sequences detected
+	IntervalStdDev  time.Duration `json:"interval_std_dev"`
+	Burstiness      float64       `json:"burstiness"`       // -1 periodic, 0 random, 1 bursty; scripted clients sit near either end
+	PeakMinuteRate  int64         `json:"peak_minute_rate"` // Most requests within any one minute
 }
@@ -3,7 +3,9 @@ package algorithms
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"sort"
 	"sync"
 	"testing"
 	"time"
@@ -574,6 +576,126 @@ func TestSlidingWindowAlgorithm_GetRequestPattern(t *testing.T) {
 	}
 }
 
+func TestAnalyzeRequests(t *testing.T) {
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	at := func(offsets ...time.Duration) []int64 {
+		times := make([]int64, len(offsets))
+		for i, offset := range offsets {
+			times[i] = start.Add(offset).UnixNano()
+		}
+		return times
+	}
+	end := start.Add(10 * time.Minute)
+
+	// A client firing exactly every 10 seconds is perfectly periodic
+	var periodic []time.Duration
+	for i := 0; i < 30; i++ {
+		periodic = append(periodic, time.Duration(i)*10*time.Second)
+	}
+	pattern := AnalyzeRequests(at(periodic...), start, end)
+	if pattern.Burstiness != -1 || pattern.IntervalStdDev != 0 || pattern.AverageInterval != 10*time.Second {
+		t.Errorf("Expected periodic requests to score -1, got %+v", pattern)
+	}
+	if pattern.PeakMinuteRate != 6 {
+		t.Errorf("Expected 6 requests in the peak minute, got %d", pattern.PeakMinuteRate)
+	}
+
+	// Tight bursts separated by long pauses score close to 1
+	var bursts []time.Duration
+	for b := 0; b < 3; b++ {
+		for i := 0; i < 10; i++ {
+			bursts = append(bursts, time.Duration(b)*3*time.Minute+time.Duration(i)*10*time.Millisecond)
+		}
+	}
+	timestamps := at(bursts...)
+	timestamps[0], timestamps[len(timestamps)-1] = timestamps[len(timestamps)-1], timestamps[0]
+	pattern = AnalyzeRequests(timestamps, start, end)
+	if pattern.Burstiness < 0.5 || pattern.BurstCount != 3 || pattern.PeakMinuteRate != 10 {
+		t.Errorf("Expected bursty requests, got %+v", pattern)
+	}
+	if timestamps[0] != start.Add(bursts[len(bursts)-1]).UnixNano() {
+		t.Error("Expected the input timestamps not to be reordered")
+	}
+
+	// Too few intervals leave the score undecided
+	if pattern := AnalyzeRequests(at(0, time.Second), start, end); pattern.Burstiness != 0 {
+		t.Errorf("Expected no burstiness score for two requests, got %g", pattern.Burstiness)
+	}
+	if pattern := AnalyzeRequests(nil, start, end); pattern.TotalRequests != 0 || pattern.PeakMinuteRate != 0 {
+		t.Errorf("Expected an empty pattern, got %+v", pattern)
+	}
+}
+
+func TestSlidingWindowAlgorithm_SkewTolerance(t *testing.T) {
+	ctx := context.Background()
+	base := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	window := 10 * time.Second
+
+	recorded := func(t *testing.T, store *mockStore, key string) []int64 {
+		t.Helper()
+		data, err := store.Get(ctx, key)
+		if err != nil {
+			t.Fatalf("Failed to read state: %v", err)
+		}
+		var state SlidingWindowState
+		if err := json.Unmarshal(data, &state); err != nil {
+			t.Fatalf("Failed to decode state: %v", err)
+		}
+		return state.Requests
+	}
+
+	tests := []struct {
+		name      string
+		lead      time.Duration // How far the other instance's clock is ahead
+		tolerance time.Duration
+		adopted   bool
+	}{
+		{"lead within tolerance", 500 * time.Millisecond, time.Second, true},
+		{"lead beyond tolerance", 5 * time.Second, time.Second, false},
+		{"no tolerance", 500 * time.Millisecond, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newMockStore()
+			key := "test:skew"
+
+			leading := NewSlidingWindowAlgorithm()
+			leading.SetClock(func() time.Time { return base.Add(tt.lead) })
+			lagging := NewSlidingWindowAlgorithm()
+			lagging.SetClock(func() time.Time { return base })
+			lagging.SetSkewTolerance(tt.tolerance)
+
+			for i := 0; i < 3; i++ {
+				if _, err := leading.Allow(ctx, store, key, 10, window, 1); err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+			}
+			result, err := lagging.Allow(ctx, store, key, 10, window, 1)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !result.Allowed || result.Used != 4 {
+				t.Fatalf("Expected the fourth request to be allowed, got %+v", result)
+			}
+
+			requests := recorded(t, store, key)
+			if !sort.SliceIsSorted(requests, func(i, j int) bool { return requests[i] < requests[j] }) {
+				t.Fatalf("Expected timestamps to stay sorted, got %v", requests)
+			}
+			lagged := false
+			for _, ts := range requests {
+				if ts == base.UnixNano() {
+					lagged = true
+				}
+			}
+			if lagged == tt.adopted {
+				t.Errorf("Expected the lagging instance to adopt the leading clock: %v, got timestamps %v", tt.adopted, requests)
+			}
+		})
+	}
+}
+
 func TestSlidingWindowAlgorithm_ConcurrentAccess(t *testing.T) {
 	algorithm := NewSlidingWindowAlgorithm()
 	store := newMockStore()
@@ -670,3 +792,33 @@ func BenchmarkSlidingWindowAlgorithm_ConcurrentAllow(b *testing.B) {
 		}
 	})
 }
+
+func TestSlidingWindowAlgorithm_Peek(t *testing.T) {
+	algorithm := NewSlidingWindowAlgorithm()
+	store := newMockStore()
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		if _, err := algorithm.Allow(ctx, store, "test:peek", 3, time.Minute, 1); err != nil {
+			t.Fatalf("Allow failed: %v", err)
+		}
+	}
+
+	for i := 0; i < 2; i++ {
+		result, err := algorithm.Peek(ctx, store, "test:peek", 3, time.Minute)
+		if err != nil {
+			t.Fatalf("Peek failed: %v", err)
+		}
+		if result.Allowed || result.Remaining != 0 || result.Used != 3 {
+			t.Errorf("Expected exhausted window, got %+v", result)
+		}
+		if result.RetryAfter <= 0 {
+			t.Error("Expected positive retry after on exhausted window")
+		}
+	}
+
+	info, _ := algorithm.GetWindowInfo(ctx, store, "test:peek", 3, time.Minute)
+	if info["denied_requests"].(int64) != 0 {
+		t.Error("Peek must not record denied requests")
+	}
+}
@@ -53,13 +53,16 @@ func NewRateLimitError(errorType, message string, err error) *RateLimitError {
 
 // TokenBucketAlgorithm implements the token bucket rate limiting algorithm
 type TokenBucketAlgorithm struct {
-	name string
+	name       string
+	compressor *stateCompressor
+	now        func() time.Time
 }
 
 // NewTokenBucketAlgorithm creates a new token bucket algorithm
 func NewTokenBucketAlgorithm() *TokenBucketAlgorithm {
 	return &TokenBucketAlgorithm{
 		name: "token_bucket",
+		now:  time.Now,
 	}
 }
 
@@ -68,6 +71,28 @@ func (tb *TokenBucketAlgorithm) Name() string {
 	return tb.name
 }
 
+// SetClock replaces the time source, e.g. with a virtual clock in simulations.
+// It must be called before the algorithm is used concurrently.
+func (tb *TokenBucketAlgorithm) SetClock(now func() time.Time) {
+	tb.now = now
+}
+
+// SetCompression enables compression of serialized bucket state above a size threshold.
+// It must be called before the algorithm is used concurrently.
+func (tb *TokenBucketAlgorithm) SetCompression(config CompressionConfig) error {
+	compressor, err := newStateCompressor(config)
+	if err != nil {
+		return err
+	}
+	tb.compressor = compressor
+	return nil
+}
+
+// CompressionStats returns compression savings and CPU cost for this algorithm
+func (tb *TokenBucketAlgorithm) CompressionStats() CompressionStats {
+	return tb.compressor.stats()
+}
+
 // TokenBucketState represents the current state of a token bucket
 type TokenBucketState struct {
 	// Current number of tokens in the bucket
@@ -90,6 +115,9 @@ type TokenBucketState struct {
 
 	// Window duration for statistics
 	WindowDuration time.Duration `json:"window_duration"`
+
+	// LastRefill in Unix nanoseconds, which the Redis script reads instead of parsing times
+	LastRefillNano int64 `json:"last_refill_ns,omitempty"`
 }
 
 // Allow checks if N requests are allowed and updates the bucket state
@@ -107,6 +135,11 @@ func (tb *TokenBucketAlgorithm) Allow(ctx context.Context, store Store, key stri
 			)
 	}
 
+	// Refill, take and save in one step on stores that run scripts
+	if result, ok, err := tb.allowScripted(ctx, store, key, limit, window, n); ok || err != nil {
+		return result, err
+	}
+
 	// Calculate refill rate (tokens per second)
 	refillRate := float64(limit) / window.Seconds()
 
@@ -117,7 +150,7 @@ func (tb *TokenBucketAlgorithm) Allow(ctx context.Context, store Store, key stri
 	}
 
 	// Refill tokens based on elapsed time
-	now := time.Now()
+	now := tb.now()
 	elapsed := now.Sub(state.LastRefill)
 	if elapsed > 0 {
 		tokensToAdd := refillRate * elapsed.Seconds()
@@ -127,19 +160,32 @@ func (tb *TokenBucketAlgorithm) Allow(ctx context.Context, store Store, key stri
 
 	// Check if we have enough tokens
 	allowed := state.Tokens >= float64(n)
-	remaining := int64(math.Floor(state.Tokens))
+	if allowed {
+		state.Tokens -= float64(n)
+		state.TotalRequests += n
+	} else {
+		state.DeniedRequests += n
+	}
+
+	// Save updated state
+	if err := tb.saveBucketState(ctx, store, key, state, window); err != nil {
+		return nil, err
+	}
+
+	return tb.result(allowed, state.Tokens, limit, window, n, now), nil
+}
+
+// result reports a check of n requests that left tokens in the bucket
+func (tb *TokenBucketAlgorithm) result(allowed bool, tokens float64, limit int64, window time.Duration, n int64, now time.Time) *Result {
+	refillRate := float64(limit) / window.Seconds()
+	remaining := int64(math.Floor(tokens))
 
 	var retryAfter time.Duration
 	var resetTime time.Time
 
 	if allowed {
-		// Consume tokens
-		state.Tokens -= float64(n)
-		state.TotalRequests += n
-		remaining = int64(math.Floor(state.Tokens))
-
 		// Calculate when the bucket will be full again
-		tokensNeeded := float64(state.Capacity) - state.Tokens
+		tokensNeeded := float64(limit) - tokens
 		if tokensNeeded > 0 {
 			resetTime = now.Add(time.Duration(tokensNeeded/refillRate) * time.Second)
 		} else {
@@ -147,18 +193,53 @@ func (tb *TokenBucketAlgorithm) Allow(ctx context.Context, store Store, key stri
 		}
 	} else {
 		// Calculate retry after time
-		tokensNeeded := float64(n) - state.Tokens
+		tokensNeeded := float64(n) - tokens
 		retryAfter = time.Duration(tokensNeeded/refillRate) * time.Second
 		resetTime = now.Add(retryAfter)
-		state.DeniedRequests += n
 		remaining = 0
 	}
 
-	// Save updated state
-	if err := tb.saveBucketState(ctx, store, key, state, window); err != nil {
+	return &Result{
+		Allowed:    allowed,
+		Remaining:  remaining,
+		RetryAfter: retryAfter,
+		ResetTime:  resetTime,
+		Limit:      limit,
+		Window:     window,
+		Used:       limit - remaining,
+		Algorithm:  tb.name,
+	}
+}
+
+// Peek reports whether a single request would be allowed without consuming tokens or saving state
+func (tb *TokenBucketAlgorithm) Peek(ctx context.Context, store Store, key string, limit int64, window time.Duration) (*Result, error) {
+	refillRate := float64(limit) / window.Seconds()
+
+	state, err := tb.getBucketState(ctx, store, key, limit, refillRate, window)
+	if err != nil {
 		return nil, err
 	}
 
+	// Refill tokens to get current state
+	now := tb.now()
+	elapsed := now.Sub(state.LastRefill)
+	if elapsed > 0 {
+		tokensToAdd := refillRate * elapsed.Seconds()
+		state.Tokens = math.Min(state.Tokens+tokensToAdd, float64(state.Capacity))
+	}
+
+	remaining := int64(math.Floor(state.Tokens))
+	allowed := remaining >= 1
+
+	var retryAfter time.Duration
+	resetTime := now
+	if tokensNeeded := float64(state.Capacity) - state.Tokens; tokensNeeded > 0 {
+		resetTime = now.Add(time.Duration(tokensNeeded/refillRate) * time.Second)
+	}
+	if !allowed {
+		retryAfter = time.Duration((1-state.Tokens)/refillRate) * time.Second
+	}
+
 	return &Result{
 		Allowed:    allowed,
 		Remaining:  remaining,
@@ -185,13 +266,18 @@ func (tb *TokenBucketAlgorithm) getBucketState(ctx context.Context, store Store,
 			Tokens:         float64(capacity),
 			Capacity:       capacity,
 			RefillRate:     refillRate,
-			LastRefill:     time.Now(),
+			LastRefill:     tb.now(),
 			TotalRequests:  0,
 			DeniedRequests: 0,
 			WindowDuration: window,
 		}, nil
 	}
 
+	data, err = tb.compressor.decode(data)
+	if err != nil {
+		return nil, err
+	}
+
 	var state TokenBucketState
 	if err := json.Unmarshal(data, &state); err != nil {
 		return nil, NewRateLimitError(
@@ -211,22 +297,51 @@ func (tb *TokenBucketAlgorithm) getBucketState(ctx context.Context, store Store,
 
 // saveBucketState saves the bucket state to the store
 func (tb *TokenBucketAlgorithm) saveBucketState(ctx context.Context, store Store, key string, state *TokenBucketState, window time.Duration) error {
+	data, expiration, err := tb.encodeBucketState(state, window)
+	if err != nil {
+		return err
+	}
+	return store.Set(ctx, key, data, expiration)
+}
+
+// encodeBucketState serializes the bucket state and returns how long the store keeps it
+func (tb *TokenBucketAlgorithm) encodeBucketState(state *TokenBucketState, window time.Duration) ([]byte, time.Duration, error) {
+	if !state.LastRefill.IsZero() {
+		state.LastRefillNano = state.LastRefill.UnixNano()
+	}
 	data, err := json.Marshal(state)
 	if err != nil {
-		return NewRateLimitError(
+		return nil, 0, NewRateLimitError(
 			"algorithm",
 			"failed to marshal bucket state",
 			err,
 		)
 	}
 
+	data, err = tb.compressor.encode(data)
+	if err != nil {
+		return nil, 0, err
+	}
+
 	// Set expiration to 2x the window to account for burst scenarios
 	expiration := window * 2
 	if expiration < time.Minute {
 		expiration = time.Minute
 	}
 
-	return store.Set(ctx, key, data, expiration)
+	return data, expiration, nil
+}
+
+// InitialState returns the serialized state of a full bucket and how long the store keeps it,
+// for creating keys before the first request arrives
+func (tb *TokenBucketAlgorithm) InitialState(limit int64, window time.Duration) ([]byte, time.Duration, error) {
+	return tb.encodeBucketState(&TokenBucketState{
+		Tokens:         float64(limit),
+		Capacity:       limit,
+		RefillRate:     float64(limit) / window.Seconds(),
+		LastRefill:     tb.now(),
+		WindowDuration: window,
+	}, window)
 }
 
 // GetBucketInfo returns detailed information about a token bucket
@@ -239,7 +354,7 @@ func (tb *TokenBucketAlgorithm) GetBucketInfo(ctx context.Context, store Store,
 	}
 
 	// Refill tokens to get current state
-	now := time.Now()
+	now := tb.now()
 	elapsed := now.Sub(state.LastRefill)
 	if elapsed > 0 {
 		tokensToAdd := refillRate * elapsed.Seconds()
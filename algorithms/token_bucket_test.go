@@ -640,3 +640,31 @@ func BenchmarkTokenBucketAlgorithm_ConcurrentAllow(b *testing.B) {
 		}
 	})
 }
+
+func TestTokenBucketAlgorithm_Peek(t *testing.T) {
+	algorithm := NewTokenBucketAlgorithm()
+	store := newMockStore()
+	ctx := context.Background()
+
+	result, err := algorithm.Peek(ctx, store, "test:peek", 10, time.Hour)
+	if err != nil {
+		t.Fatalf("Peek failed: %v", err)
+	}
+	if !result.Allowed || result.Remaining != 10 {
+		t.Errorf("Expected full bucket, got %+v", result)
+	}
+
+	if _, err := algorithm.Allow(ctx, store, "test:peek", 10, time.Hour, 4); err != nil {
+		t.Fatalf("Allow failed: %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		result, err = algorithm.Peek(ctx, store, "test:peek", 10, time.Hour)
+		if err != nil {
+			t.Fatalf("Peek failed: %v", err)
+		}
+		if result.Remaining != 6 {
+			t.Errorf("Expected 6 remaining after peek %d, got %d", i+1, result.Remaining)
+		}
+	}
+}
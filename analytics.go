@@ -0,0 +1,162 @@
+// analytics.go - Request pattern analytics per entity
+package ratelimit
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/itsatony/gorly/algorithms"
+)
+
+// AnalyticsConfig configures request pattern analytics
+type AnalyticsConfig struct {
+	// MaxEntities bounds the entities tracked; the least recently seen entity is
+	// dropped to make room for a new one (default: 1000)
+	MaxEntities int
+
+	// Window is how far back arrivals are analyzed (default: 10m)
+	Window time.Duration
+
+	// MaxSamples bounds the arrivals kept per entity (default: 256)
+	MaxSamples int
+}
+
+// Analytics defaults
+const (
+	DefaultAnalyticsEntities = 1000
+	DefaultAnalyticsWindow   = 10 * time.Minute
+	DefaultAnalyticsSamples  = 256
+)
+
+// EntityPattern is the request pattern of one entity. Burstiness near -1 means
+// metronome-like requests and near 1 tight bursts; both point to scripted clients.
+type EntityPattern struct {
+	Entity string `json:"entity"`
+	*algorithms.RequestPattern
+}
+
+// requestAnalytics records the arrival times of each entity's requests
+type requestAnalytics struct {
+	config AnalyticsConfig
+	now    func() time.Time
+
+	mu       sync.Mutex
+	entities map[string]*arrivals
+}
+
+// arrivals is a ring of an entity's most recent request times
+type arrivals struct {
+	times    []int64 // Unix nanoseconds
+	next     int
+	lastSeen int64
+}
+
+// newRequestAnalytics creates analytics with defaults applied
+func newRequestAnalytics(config *AnalyticsConfig) *requestAnalytics {
+	c := *config
+	if c.MaxEntities <= 0 {
+		c.MaxEntities = DefaultAnalyticsEntities
+	}
+	if c.Window <= 0 {
+		c.Window = DefaultAnalyticsWindow
+	}
+	if c.MaxSamples <= 0 {
+		c.MaxSamples = DefaultAnalyticsSamples
+	}
+	return &requestAnalytics{
+		config:   c,
+		now:      time.Now,
+		entities: make(map[string]*arrivals),
+	}
+}
+
+// record notes a request of entity
+func (ra *requestAnalytics) record(entity string) {
+	now := ra.now().UnixNano()
+
+	ra.mu.Lock()
+	defer ra.mu.Unlock()
+
+	a, ok := ra.entities[entity]
+	if !ok {
+		if len(ra.entities) >= ra.config.MaxEntities {
+			ra.evict()
+		}
+		a = &arrivals{times: make([]int64, 0, min(ra.config.MaxSamples, 16))}
+		ra.entities[entity] = a
+	}
+	a.lastSeen = now
+
+	if len(a.times) < ra.config.MaxSamples {
+		a.times = append(a.times, now)
+		return
+	}
+	a.times[a.next] = now
+	a.next = (a.next + 1) % len(a.times)
+}
+
+// evict drops the least recently seen entity
+func (ra *requestAnalytics) evict() {
+	oldest, oldestSeen := "", int64(0)
+	for entity, a := range ra.entities {
+		if oldest == "" || a.lastSeen < oldestSeen {
+			oldest, oldestSeen = entity, a.lastSeen
+		}
+	}
+	delete(ra.entities, oldest)
+}
+
+// forget drops the arrivals of an entity
+func (ra *requestAnalytics) forget(entity string) {
+	ra.mu.Lock()
+	delete(ra.entities, entity)
+	ra.mu.Unlock()
+}
+
+// pattern analyzes the recent arrivals of one entity, or returns nil if it has none
+func (ra *requestAnalytics) pattern(entity string) *algorithms.RequestPattern {
+	now := ra.now()
+	since := now.Add(-ra.config.Window).UnixNano()
+
+	ra.mu.Lock()
+	a, ok := ra.entities[entity]
+	var recent []int64
+	if ok {
+		for _, t := range a.times {
+			if t >= since {
+				recent = append(recent, t)
+			}
+		}
+	}
+	ra.mu.Unlock()
+
+	if len(recent) == 0 {
+		return nil
+	}
+	return algorithms.AnalyzeRequests(recent, now.Add(-ra.config.Window), now)
+}
+
+// patterns analyzes every entity with recent arrivals, busiest minute first
+func (ra *requestAnalytics) patterns() []EntityPattern {
+	ra.mu.Lock()
+	entities := make([]string, 0, len(ra.entities))
+	for entity := range ra.entities {
+		entities = append(entities, entity)
+	}
+	ra.mu.Unlock()
+
+	patterns := make([]EntityPattern, 0, len(entities))
+	for _, entity := range entities {
+		if pattern := ra.pattern(entity); pattern != nil {
+			patterns = append(patterns, EntityPattern{Entity: entity, RequestPattern: pattern})
+		}
+	}
+	sort.Slice(patterns, func(i, j int) bool {
+		if patterns[i].PeakMinuteRate != patterns[j].PeakMinuteRate {
+			return patterns[i].PeakMinuteRate > patterns[j].PeakMinuteRate
+		}
+		return patterns[i].Entity < patterns[j].Entity
+	})
+	return patterns
+}
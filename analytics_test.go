@@ -0,0 +1,92 @@
+// analytics_test.go - Tests for request pattern analytics
+package ratelimit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRequestAnalytics(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	ra := newRequestAnalytics(&AnalyticsConfig{MaxEntities: 2, MaxSamples: 4, Window: time.Minute})
+	ra.now = func() time.Time { return now }
+
+	// The oldest arrivals beyond MaxSamples are overwritten
+	for i := 0; i < 6; i++ {
+		ra.record("bot")
+		now = now.Add(time.Second)
+	}
+	if pattern := ra.pattern("bot"); pattern == nil || pattern.TotalRequests != 4 || pattern.Burstiness != -1 {
+		t.Errorf("Expected 4 periodic arrivals, got %+v", pattern)
+	}
+
+	// The least recently seen entity makes room for a new one
+	ra.record("human")
+	now = now.Add(time.Second)
+	ra.record("bot")
+	ra.record("newcomer")
+	if ra.pattern("human") != nil || ra.pattern("bot") == nil || ra.pattern("newcomer") == nil {
+		t.Error("Expected the least recently seen entity to be evicted")
+	}
+
+	// Arrivals older than the window are not analyzed
+	now = now.Add(2 * time.Minute)
+	if pattern := ra.pattern("bot"); pattern != nil {
+		t.Errorf("Expected no recent arrivals, got %+v", pattern)
+	}
+}
+
+func TestRequestPatternsEndpoint(t *testing.T) {
+	base, err := New().Limit("global", "100/minute").Build()
+	if err != nil {
+		t.Fatalf("Failed to build limiter: %v", err)
+	}
+	config := DefaultObservabilityConfig()
+	config.EnableLogging = false
+	config.Analytics = &AnalyticsConfig{}
+	limiter := NewObservableLimiter(base, config)
+	defer limiter.Close()
+
+	ctx := context.Background()
+	for i := 0; i < 5; i++ {
+		limiter.Check(ctx, "acme:script")
+	}
+	limiter.Check(ctx, "globex:user")
+
+	patterns := limiter.RequestPatterns()
+	if len(patterns) != 2 || patterns[0].Entity != "acme:script" || patterns[0].PeakMinuteRate != 5 {
+		t.Fatalf("Expected the busiest entity first, got %+v", patterns)
+	}
+
+	stats, err := limiter.Stats(ctx)
+	if err != nil || stats.ByEntity["acme:script"] == nil || stats.ByEntity["acme:script"].Pattern.TotalRequests != 5 {
+		t.Errorf("Expected request patterns in the stats, got %+v (%v)", stats, err)
+	}
+
+	ms := NewMonitoringServer(limiter)
+	w := httptest.NewRecorder()
+	ms.ServeHTTP(w, httptest.NewRequest("GET", "/analytics?limit=1", nil))
+	var body struct {
+		Entities []EntityPattern `json:"entities"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&body); err != nil || len(body.Entities) != 1 || body.Entities[0].PeakMinuteRate != 5 {
+		t.Errorf("Expected the busiest entity from /analytics, got %d %+v (%v)", w.Code, body, err)
+	}
+
+	w = httptest.NewRecorder()
+	ms.ServeHTTP(w, httptest.NewRequest("GET", "/analytics?entity=globex:user", nil))
+	if err := json.NewDecoder(w.Body).Decode(&body); err != nil || len(body.Entities) != 1 || body.Entities[0].Entity != "globex:user" {
+		t.Errorf("Expected one entity's pattern, got %+v (%v)", body, err)
+	}
+
+	disabled := NewMonitoringServer(NewObservableLimiter(base, DefaultObservabilityConfig()))
+	w = httptest.NewRecorder()
+	disabled.ServeHTTP(w, httptest.NewRequest("GET", "/analytics", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 without analytics, got %d", w.Code)
+	}
+}
@@ -0,0 +1,36 @@
+// Package ratelimit provides authentication abuse protection
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// authTracker is implemented by limiters that support authentication lockouts
+type authTracker interface {
+	recordAuthFailure(ctx context.Context, entity string) (time.Duration, error)
+	recordAuthSuccess(ctx context.Context, entity string) error
+}
+
+// ReportAuthFailure records a failed login or OTP attempt by the caller of r.
+// It returns how long the caller is now locked out, or zero while below the lockout threshold.
+// Example: if !valid { lockout, _ := ratelimit.ReportAuthFailure(limiter, r) }
+func ReportAuthFailure(limiter Limiter, r *http.Request) (time.Duration, error) {
+	tracker, ok := limiter.(authTracker)
+	if !ok {
+		return 0, fmt.Errorf("limiter does not support authentication lockouts")
+	}
+	return tracker.recordAuthFailure(r.Context(), requestEntity(limiter, r))
+}
+
+// ReportAuthSuccess clears the failure history of the caller of r after a successful login
+// Example: if valid { ratelimit.ReportAuthSuccess(limiter, r) }
+func ReportAuthSuccess(limiter Limiter, r *http.Request) error {
+	tracker, ok := limiter.(authTracker)
+	if !ok {
+		return fmt.Errorf("limiter does not support authentication lockouts")
+	}
+	return tracker.recordAuthSuccess(r.Context(), requestEntity(limiter, r))
+}
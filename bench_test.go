@@ -0,0 +1,89 @@
+// bench_test.go - Hot path benchmarks compared against a baseline by gorly-ops benchcheck
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"testing"
+)
+
+// benchEntities spreads benchmark traffic over enough entities that the limits stay open
+const benchEntities = 1024
+
+// benchEntityNames avoids measuring string formatting in the benchmarks
+var benchEntityNames = func() []string {
+	names := make([]string, benchEntities)
+	for i := range names {
+		names[i] = "bench-" + strconv.Itoa(i)
+	}
+	return names
+}()
+
+func benchmarkCheck(b *testing.B, builder *Builder) {
+	limiter, err := builder.Limit("global", "1000000/second").Build()
+	if err != nil {
+		b.Fatalf("Failed to build limiter: %v", err)
+	}
+	defer limiter.Close()
+
+	ctx := context.Background()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := limiter.Check(ctx, benchEntityNames[i%benchEntities]); err != nil {
+			b.Fatalf("Check failed: %v", err)
+		}
+	}
+}
+
+func BenchmarkCheckMemory(b *testing.B) {
+	benchmarkCheck(b, New())
+}
+
+// BenchmarkCheckRedis needs a Redis server at $GORLY_BENCH_REDIS, e.g. localhost:6379
+func BenchmarkCheckRedis(b *testing.B) {
+	address := os.Getenv("GORLY_BENCH_REDIS")
+	if address == "" {
+		b.Skip("GORLY_BENCH_REDIS is not set")
+	}
+	benchmarkCheck(b, New().Redis(address))
+}
+
+// BenchmarkCheckPostgres needs a Postgres server at $GORLY_BENCH_POSTGRES, e.g.
+// postgres://localhost:5432/gorly_bench, and a driver linked into the test binary
+func BenchmarkCheckPostgres(b *testing.B) {
+	dsn := os.Getenv("GORLY_BENCH_POSTGRES")
+	if dsn == "" {
+		b.Skip("GORLY_BENCH_POSTGRES is not set")
+	}
+	benchmarkCheck(b, New().Postgres(dsn))
+}
+
+func BenchmarkMiddleware(b *testing.B) {
+	limiter, err := New().Limit("global", "1000000/second").Build()
+	if err != nil {
+		b.Fatalf("Failed to build limiter: %v", err)
+	}
+	defer limiter.Close()
+
+	handler := limiter.For(HTTP).(func(http.Handler) http.Handler)(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }))
+	requests := make([]*http.Request, benchEntities)
+	for i := range requests {
+		requests[i] = httptest.NewRequest(http.MethodGet, "/api/items", nil)
+		requests[i].RemoteAddr = "10.0." + strconv.Itoa(i/256) + "." + strconv.Itoa(i%256) + ":1234"
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, requests[i%benchEntities])
+		if w.Code != http.StatusOK {
+			b.Fatalf("Expected 200, got %d", w.Code)
+		}
+	}
+}
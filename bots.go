@@ -0,0 +1,166 @@
+// bots.go - Bot heuristics for automatic scope assignment
+package ratelimit
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// BotClassification is the outcome of classifying a request
+type BotClassification string
+
+// Bot classification outcomes
+const (
+	ClassHuman   BotClassification = "human"   // No bot signals found
+	ClassBot     BotClassification = "bot"     // Request looks automated
+	ClassPartner BotClassification = "partner" // Verified partner, never treated as a bot
+)
+
+// DefaultPartnerHeader carries the token of a verified partner
+const DefaultPartnerHeader = "X-Verified-Partner"
+
+// GeoInfo is network information about a client IP
+type GeoInfo struct {
+	Country      string
+	ASN          uint32
+	Organization string
+	Datacenter   bool // Hosting or cloud provider network
+}
+
+// GeoResolver resolves network information for a client IP
+type GeoResolver interface {
+	Resolve(ip string) (*GeoInfo, error)
+}
+
+// defaultBotUserAgents are user agent fragments of common crawlers, scrapers and HTTP libraries
+var defaultBotUserAgents = []string{
+	"bot", "crawler", "spider", "scrapy", "curl", "wget", "python-requests",
+	"python-urllib", "go-http-client", "java/", "okhttp", "libwww", "headlesschrome", "phantomjs",
+}
+
+// BotClassifier tags requests as bot-like using cheap request heuristics
+// and optional network lookups. It keeps counters of every outcome.
+type BotClassifier struct {
+	// UserAgents are lowercase user agent fragments that mark a request as a bot
+	UserAgents []string
+
+	// RequireAccept treats requests without an Accept header as bots
+	RequireAccept bool
+
+	// Resolver flags datacenter traffic when set
+	Resolver GeoResolver
+
+	// DatacenterASNs are additional networks treated as datacenters
+	DatacenterASNs map[uint32]bool
+
+	// PartnerHeader carries partner tokens (default: X-Verified-Partner)
+	PartnerHeader string
+
+	partners   map[string]bool
+	partnersMu sync.RWMutex
+
+	human   int64
+	bot     int64
+	partner int64
+}
+
+// NewBotClassifier creates a classifier with the default heuristics
+// Example: gorly.New().BotScope("bots", gorly.NewBotClassifier().IsBot)
+func NewBotClassifier() *BotClassifier {
+	return &BotClassifier{
+		UserAgents:    defaultBotUserAgents,
+		RequireAccept: true,
+		PartnerHeader: DefaultPartnerHeader,
+		partners:      make(map[string]bool),
+	}
+}
+
+// AllowPartner registers a token that exempts a verified partner from bot classification
+func (bc *BotClassifier) AllowPartner(token string) *BotClassifier {
+	bc.partnersMu.Lock()
+	defer bc.partnersMu.Unlock()
+	bc.partners[token] = true
+	return bc
+}
+
+// Classify determines whether a request is from a human, a bot or a verified partner
+func (bc *BotClassifier) Classify(r *http.Request) BotClassification {
+	class := bc.classify(r)
+
+	switch class {
+	case ClassBot:
+		atomic.AddInt64(&bc.bot, 1)
+	case ClassPartner:
+		atomic.AddInt64(&bc.partner, 1)
+	default:
+		atomic.AddInt64(&bc.human, 1)
+	}
+
+	return class
+}
+
+// IsBot reports whether a request should be routed to the bot scope
+func (bc *BotClassifier) IsBot(r *http.Request) bool {
+	return bc.Classify(r) == ClassBot
+}
+
+// GetMetrics returns the number of requests per classification outcome
+func (bc *BotClassifier) GetMetrics() map[string]interface{} {
+	return map[string]interface{}{
+		"bot_classification_total": map[string]int64{
+			string(ClassHuman):   atomic.LoadInt64(&bc.human),
+			string(ClassBot):     atomic.LoadInt64(&bc.bot),
+			string(ClassPartner): atomic.LoadInt64(&bc.partner),
+		},
+	}
+}
+
+// classify applies the heuristics without recording metrics
+func (bc *BotClassifier) classify(r *http.Request) BotClassification {
+	if bc.isPartner(r) {
+		return ClassPartner
+	}
+
+	userAgent := strings.ToLower(r.UserAgent())
+	if userAgent == "" {
+		return ClassBot
+	}
+	for _, fragment := range bc.UserAgents {
+		if strings.Contains(userAgent, fragment) {
+			return ClassBot
+		}
+	}
+
+	if bc.RequireAccept && r.Header.Get("Accept") == "" {
+		return ClassBot
+	}
+
+	if bc.Resolver != nil {
+		if info, err := bc.Resolver.Resolve(extractIP(r)); err == nil && info != nil {
+			if info.Datacenter || bc.DatacenterASNs[info.ASN] {
+				return ClassBot
+			}
+		}
+	}
+
+	return ClassHuman
+}
+
+// isPartner checks the partner header against registered tokens
+func (bc *BotClassifier) isPartner(r *http.Request) bool {
+	header := bc.PartnerHeader
+	if header == "" {
+		header = DefaultPartnerHeader
+	}
+
+	token := r.Header.Get(header)
+	if token == "" {
+		return false
+	}
+
+	bc.partnersMu.RLock()
+	defer bc.partnersMu.RUnlock()
+	return bc.partners[token]
+}
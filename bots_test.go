@@ -0,0 +1,139 @@
+// bots_test.go - Tests for bot heuristics
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type staticGeoResolver map[string]*GeoInfo
+
+func (s staticGeoResolver) Resolve(ip string) (*GeoInfo, error) {
+	return s[ip], nil
+}
+
+func TestBotClassifier_Classify(t *testing.T) {
+	classifier := NewBotClassifier().AllowPartner("partner-token")
+	classifier.Resolver = staticGeoResolver{
+		"10.0.0.1": {ASN: 16509, Datacenter: true},
+		"10.0.0.2": {ASN: 64500},
+	}
+	classifier.DatacenterASNs = map[uint32]bool{64500: true}
+
+	browser := map[string]string{"User-Agent": "Mozilla/5.0 (X11; Linux x86_64)", "Accept": "text/html"}
+
+	tests := []struct {
+		name     string
+		headers  map[string]string
+		ip       string
+		expected BotClassification
+	}{
+		{"browser", browser, "192.168.1.1", ClassHuman},
+		{"no user agent", map[string]string{"Accept": "*/*"}, "192.168.1.1", ClassBot},
+		{"crawler", map[string]string{"User-Agent": "Googlebot/2.1", "Accept": "*/*"}, "192.168.1.1", ClassBot},
+		{"library", map[string]string{"User-Agent": "python-requests/2.31", "Accept": "*/*"}, "192.168.1.1", ClassBot},
+		{"missing accept", map[string]string{"User-Agent": "Mozilla/5.0"}, "192.168.1.1", ClassBot},
+		{"datacenter", browser, "10.0.0.1", ClassBot},
+		{"listed asn", browser, "10.0.0.2", ClassBot},
+		{"verified partner", map[string]string{"User-Agent": "curl/8.0", "X-Verified-Partner": "partner-token"}, "10.0.0.1", ClassPartner},
+		{"unknown partner token", map[string]string{"User-Agent": "curl/8.0", "X-Verified-Partner": "guess"}, "192.168.1.1", ClassBot},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := createTestRequest("GET", "/", tt.headers)
+			req.RemoteAddr = tt.ip + ":1234"
+			if class := classifier.Classify(req); class != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, class)
+			}
+		})
+	}
+
+	counts := classifier.GetMetrics()["bot_classification_total"].(map[string]int64)
+	if counts["human"] != 1 || counts["bot"] != 7 || counts["partner"] != 1 {
+		t.Errorf("Unexpected classification counts: %v", counts)
+	}
+}
+
+func TestBotScope(t *testing.T) {
+	limiter, err := New().
+		Limit("global", "100/minute").
+		Limit("bots", "1/minute").
+		BotScope("bots", NewBotClassifier().IsBot).
+		Build()
+	if err != nil {
+		t.Fatalf("Failed to build limiter: %v", err)
+	}
+	defer limiter.Close()
+
+	handler := limiter.For(HTTP).(func(http.Handler) http.Handler)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	serve := func(userAgent string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, createTestRequest("GET", "/", map[string]string{"User-Agent": userAgent, "Accept": "*/*"}))
+		return w
+	}
+
+	if w := serve("Scrapy/2.11"); w.Code != http.StatusOK || w.Header().Get("X-RateLimit-Limit") != "1" {
+		t.Fatalf("Expected first bot request on the bot scope, got %d with limit %q", w.Code, w.Header().Get("X-RateLimit-Limit"))
+	}
+	if w := serve("Scrapy/2.11"); w.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected second bot request to be limited, got %d", w.Code)
+	}
+	if w := serve("Mozilla/5.0"); w.Code != http.StatusOK || w.Header().Get("X-RateLimit-Limit") != "100" {
+		t.Errorf("Expected browser on the global scope, got %d with limit %q", w.Code, w.Header().Get("X-RateLimit-Limit"))
+	}
+}
+
+func TestBotScopeFiber(t *testing.T) {
+	classifier := NewBotClassifier().AllowPartner("partner-token")
+	limiter, err := New().
+		Limit("global", "100/minute").
+		Limit("bots", "1/minute").
+		BotScope("bots", classifier.IsBot).
+		Build()
+	if err != nil {
+		t.Fatalf("Failed to build limiter: %v", err)
+	}
+	defer limiter.Close()
+	handler := limiter.For(Fiber).(func(interface{}) error)
+
+	serve := func(headers map[string]string) *fakeFiberCtx {
+		ctx := newFakeFiberCtx("GET", "/", headers)
+		if err := handler(ctx); err != nil {
+			t.Fatalf("Handler failed: %v", err)
+		}
+		return ctx
+	}
+
+	// Browsers send Accept, which the classifier requires of humans
+	browser := map[string]string{"User-Agent": "Mozilla/5.0", "Accept": "text/html"}
+	for i := 0; i < 2; i++ {
+		if ctx := serve(browser); ctx.status != http.StatusOK || ctx.respHeaders.Get("X-RateLimit-Limit") != "100" {
+			t.Errorf("Expected browser request %d on the global scope, got %d with limit %q", i+1, ctx.status, ctx.respHeaders.Get("X-RateLimit-Limit"))
+		}
+	}
+
+	partner := map[string]string{"User-Agent": "curl/8.0", "X-Verified-Partner": "partner-token"}
+	if ctx := serve(partner); ctx.status != http.StatusOK || ctx.respHeaders.Get("X-RateLimit-Limit") != "100" {
+		t.Errorf("Expected the partner on the global scope, got %d with limit %q", ctx.status, ctx.respHeaders.Get("X-RateLimit-Limit"))
+	}
+
+	crawler := map[string]string{"User-Agent": "Scrapy/2.11", "Accept": "*/*"}
+	if ctx := serve(crawler); ctx.status != http.StatusOK || ctx.respHeaders.Get("X-RateLimit-Limit") != "1" {
+		t.Errorf("Expected the crawler on the bot scope, got %d with limit %q", ctx.status, ctx.respHeaders.Get("X-RateLimit-Limit"))
+	}
+	ctx := serve(crawler)
+	if ctx.status != http.StatusTooManyRequests || ctx.respHeaders.Get("Retry-After") == "" || len(ctx.body) == 0 {
+		t.Errorf("Expected the second crawler request denied with headers and body, got %d %v %s", ctx.status, ctx.respHeaders, ctx.body)
+	}
+
+	// Each request is classified once, denied ones included
+	counts := classifier.GetMetrics()["bot_classification_total"].(map[string]int64)
+	if counts["human"] != 2 || counts["partner"] != 1 || counts["bot"] != 2 {
+		t.Errorf("Unexpected classification counts: %v", counts)
+	}
+}
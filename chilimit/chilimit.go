@@ -0,0 +1,27 @@
+// chilimit/chilimit.go
+// Package chilimit adapts a gorly limiter to Chi. Chi uses net/http middleware, so
+// Middleware is limiter.HTTPMiddleware; the package also holds the Chi plugin of the
+// middleware package, which reads route patterns and URL parameters from Chi's routing
+// context. Importing the package registers Middleware as the Chi adapter of limiter.For.
+//
+// The package is a module of its own, so the Chi dependency is only downloaded by
+// applications that use Chi; the core gorly module depends on no web framework.
+package chilimit
+
+import (
+	"net/http"
+
+	"github.com/itsatony/gorly"
+)
+
+// Middleware returns Chi middleware applying the limiter to each request. Denied requests
+// get the limiter's rate limit response and never reach the handler.
+// Example: router.Use(chilimit.Middleware(limiter))
+func Middleware(limiter ratelimit.Limiter) func(http.Handler) http.Handler {
+	return limiter.HTTPMiddleware()
+}
+
+// init lets limiter.For(ratelimit.Chi) resolve to Middleware
+func init() {
+	ratelimit.RegisterAdapter(ratelimit.Chi, Middleware)
+}
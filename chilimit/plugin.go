@@ -1,5 +1,5 @@
-// middleware/chi.go
-package middleware
+// chilimit/plugin.go
+package chilimit
 
 import (
 	"context"
@@ -10,32 +10,33 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"github.com/itsatony/gorly"
+	"github.com/itsatony/gorly/middleware"
 )
 
-// ChiPlugin implements MiddlewarePlugin for Chi router
-type ChiPlugin struct{}
+// Plugin implements MiddlewarePlugin for Chi router
+type Plugin struct{}
 
 // Name returns the plugin name
-func (p *ChiPlugin) Name() string {
+func (p *Plugin) Name() string {
 	return "chi"
 }
 
 // Version returns the supported Chi version
-func (p *ChiPlugin) Version() string {
+func (p *Plugin) Version() string {
 	return ">=5.0.0"
 }
 
 // CreateMiddleware creates Chi middleware function
-func (p *ChiPlugin) CreateMiddleware(limiter ratelimit.RateLimiter, config *Config) interface{} {
+func (p *Plugin) CreateMiddleware(limiter ratelimit.RateLimiter, config *middleware.Config) interface{} {
 	if config == nil {
-		config = DefaultConfig()
+		config = middleware.DefaultConfig()
 	}
 	config.Limiter = limiter
 
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			// Create a context-aware request wrapper for Chi
-			chiRequest := &ChiRequest{
+			chiRequest := &Request{
 				Request: r,
 				Writer:  w,
 			}
@@ -54,7 +55,7 @@ func (p *ChiPlugin) CreateMiddleware(limiter ratelimit.RateLimiter, config *Conf
 			}
 
 			// Process rate limiting
-			result, err := ProcessRequest(reqInfo, config)
+			result, err := middleware.ProcessRequest(reqInfo, config)
 			if err != nil {
 				if config.Logger != nil {
 					config.Logger.Error("Rate limiting failed", err, map[string]interface{}{
@@ -67,10 +68,7 @@ func (p *ChiPlugin) CreateMiddleware(limiter ratelimit.RateLimiter, config *Conf
 			}
 
 			// Add rate limit headers
-			headers := BuildResponseHeaders(result, &config.ResponseConfig)
-			for key, value := range headers {
-				w.Header().Set(key, value)
-			}
+			middleware.SetScopeResponseHeaders(w.Header(), result, reqInfo.Scope, &config.ResponseConfig)
 
 			// Check if request is allowed
 			if !result.Allowed {
@@ -85,7 +83,7 @@ func (p *ChiPlugin) CreateMiddleware(limiter ratelimit.RateLimiter, config *Conf
 				}
 
 				// Send rate limited response
-				p.sendRateLimitedResponse(w, result, &config.ResponseConfig)
+				p.sendRateLimitedResponse(w, result, reqInfo.Scope, &config.ResponseConfig)
 				return
 			}
 
@@ -100,17 +98,17 @@ func (p *ChiPlugin) CreateMiddleware(limiter ratelimit.RateLimiter, config *Conf
 	}
 }
 
-// ChiRequest wraps http.Request and http.ResponseWriter for Chi-specific handling
-type ChiRequest struct {
+// Request wraps http.Request and http.ResponseWriter for Chi-specific handling
+type Request struct {
 	*http.Request
 	Writer http.ResponseWriter
 }
 
 // ExtractRequest extracts request information from Chi request
-func (p *ChiPlugin) ExtractRequest(frameworkRequest interface{}) (*RequestInfo, error) {
-	chiReq, ok := frameworkRequest.(*ChiRequest)
+func (p *Plugin) ExtractRequest(frameworkRequest interface{}) (*middleware.RequestInfo, error) {
+	chiReq, ok := frameworkRequest.(*Request)
 	if !ok {
-		return nil, fmt.Errorf("expected *ChiRequest, got %T", frameworkRequest)
+		return nil, fmt.Errorf("expected *Request, got %T", frameworkRequest)
 	}
 
 	r := chiReq.Request
@@ -122,7 +120,7 @@ func (p *ChiPlugin) ExtractRequest(frameworkRequest interface{}) (*RequestInfo,
 	}
 
 	// Extract request information
-	reqInfo := &RequestInfo{
+	reqInfo := &middleware.RequestInfo{
 		Method:     r.Method,
 		Path:       r.URL.Path,
 		RemoteAddr: p.getRealIP(r),
@@ -166,10 +164,10 @@ func (p *ChiPlugin) ExtractRequest(frameworkRequest interface{}) (*RequestInfo,
 }
 
 // SendResponse sends response using Chi (standard http.ResponseWriter)
-func (p *ChiPlugin) SendResponse(frameworkResponse interface{}, status int, headers map[string]string, body []byte) error {
-	chiReq, ok := frameworkResponse.(*ChiRequest)
+func (p *Plugin) SendResponse(frameworkResponse interface{}, status int, headers map[string]string, body []byte) error {
+	chiReq, ok := frameworkResponse.(*Request)
 	if !ok {
-		return fmt.Errorf("expected *ChiRequest, got %T", frameworkResponse)
+		return fmt.Errorf("expected *Request, got %T", frameworkResponse)
 	}
 
 	w := chiReq.Writer
@@ -190,32 +188,32 @@ func (p *ChiPlugin) SendResponse(frameworkResponse interface{}, status int, head
 // Chi-specific helper functions
 // ============================================================================
 
-// ChiMiddleware creates a Chi middleware with default configuration
-func ChiMiddleware(limiter ratelimit.RateLimiter) func(http.Handler) http.Handler {
-	plugin := &ChiPlugin{}
-	middleware := plugin.CreateMiddleware(limiter, DefaultConfig())
-	return middleware.(func(http.Handler) http.Handler)
+// PluginMiddleware creates a Chi middleware with default configuration
+func PluginMiddleware(limiter ratelimit.RateLimiter) func(http.Handler) http.Handler {
+	plugin := &Plugin{}
+	mw := plugin.CreateMiddleware(limiter, middleware.DefaultConfig())
+	return mw.(func(http.Handler) http.Handler)
 }
 
-// ChiMiddlewareWithConfig creates a Chi middleware with custom configuration
-func ChiMiddlewareWithConfig(limiter ratelimit.RateLimiter, config *Config) func(http.Handler) http.Handler {
-	plugin := &ChiPlugin{}
-	middleware := plugin.CreateMiddleware(limiter, config)
-	return middleware.(func(http.Handler) http.Handler)
+// PluginMiddlewareWithConfig creates a Chi middleware with custom configuration
+func PluginMiddlewareWithConfig(limiter ratelimit.RateLimiter, config *middleware.Config) func(http.Handler) http.Handler {
+	plugin := &Plugin{}
+	mw := plugin.CreateMiddleware(limiter, config)
+	return mw.(func(http.Handler) http.Handler)
 }
 
-// ChiEntityExtractor creates an entity extractor configured for Chi patterns
-func ChiEntityExtractor() EntityExtractor {
-	return &DefaultEntityExtractor{
+// EntityExtractor creates an entity extractor configured for Chi patterns
+func EntityExtractor() middleware.EntityExtractor {
+	return &middleware.DefaultEntityExtractor{
 		APIKeyHeaders: []string{"X-API-Key", "Authorization", "X-Api-Key", "X-API-TOKEN"},
 		UserIDHeaders: []string{"X-User-ID", "X-User-Id", "X-UserId", "X-Subject"},
 		UseIPFallback: true,
 	}
 }
 
-// ChiScopeExtractor creates a scope extractor configured for REST APIs with Chi patterns
-func ChiScopeExtractor() ScopeExtractor {
-	return &DefaultScopeExtractor{
+// ScopeExtractor creates a scope extractor configured for REST APIs with Chi patterns
+func ScopeExtractor() middleware.ScopeExtractor {
+	return &middleware.DefaultScopeExtractor{
 		PathScopes: map[string]string{
 			"/api/v1/auth":     ratelimit.ScopeGlobal,
 			"/api/v1/users":    "users",
@@ -239,14 +237,14 @@ func ChiScopeExtractor() ScopeExtractor {
 	}
 }
 
-// ChiConfig creates a Chi-optimized middleware configuration
-func ChiConfig(limiter ratelimit.RateLimiter) *Config {
-	return &Config{
+// PluginConfig creates a Chi-optimized middleware configuration
+func PluginConfig(limiter ratelimit.RateLimiter) *middleware.Config {
+	return &middleware.Config{
 		Limiter:         limiter,
-		EntityExtractor: ChiEntityExtractor(),
-		ScopeExtractor:  ChiScopeExtractor(),
-		TierExtractor:   &DefaultTierExtractor{},
-		ResponseConfig: ResponseConfig{
+		EntityExtractor: EntityExtractor(),
+		ScopeExtractor:  ScopeExtractor(),
+		TierExtractor:   &middleware.DefaultTierExtractor{},
+		ResponseConfig: middleware.ResponseConfig{
 			RateLimitedStatusCode: http.StatusTooManyRequests,
 			ErrorStatusCode:       http.StatusInternalServerError,
 			IncludeHeaders:        true,
@@ -255,7 +253,7 @@ func ChiConfig(limiter ratelimit.RateLimiter) *Config {
 			RateLimitedResponse:   []byte(`{"error":"Rate limit exceeded","code":"RATE_LIMIT_EXCEEDED"}`),
 			ErrorResponse:         []byte(`{"error":"Internal server error","code":"INTERNAL_ERROR"}`),
 		},
-		Logger:         &NoOpLogger{},
+		Logger:         &middleware.NoOpLogger{},
 		MetricsEnabled: false,
 	}
 }
@@ -264,10 +262,10 @@ func ChiConfig(limiter ratelimit.RateLimiter) *Config {
 // Advanced Chi Features
 // ============================================================================
 
-// ChiSkipHealthChecks returns a skip function that skips health check endpoints
-func ChiSkipHealthChecks() SkipFunc {
+// SkipHealthChecks returns a skip function that skips health check endpoints
+func SkipHealthChecks() middleware.SkipFunc {
 	healthPaths := []string{"/health", "/healthz", "/ping", "/status", "/metrics", "/ready", "/live", "/debug/pprof"}
-	return func(req *RequestInfo) bool {
+	return func(req *middleware.RequestInfo) bool {
 		path := strings.ToLower(req.Path)
 		for _, healthPath := range healthPaths {
 			if path == healthPath || strings.HasPrefix(path, healthPath+"/") {
@@ -278,9 +276,9 @@ func ChiSkipHealthChecks() SkipFunc {
 	}
 }
 
-// ChiSkipByRoutePattern returns a skip function that skips based on Chi route patterns
-func ChiSkipByRoutePattern(patterns ...string) SkipFunc {
-	return func(req *RequestInfo) bool {
+// SkipByRoutePattern returns a skip function that skips based on Chi route patterns
+func SkipByRoutePattern(patterns ...string) middleware.SkipFunc {
+	return func(req *middleware.RequestInfo) bool {
 		if routePattern, exists := req.Metadata["route"]; exists {
 			if route, ok := routePattern.(string); ok {
 				for _, pattern := range patterns {
@@ -294,46 +292,20 @@ func ChiSkipByRoutePattern(patterns ...string) SkipFunc {
 	}
 }
 
-// ChiParameterBasedExtractor creates entity extractor that uses Chi URL parameters
-func ChiParameterBasedExtractor(paramMapping map[string]string) EntityExtractor {
-	return &ParameterBasedEntityExtractor{
+// ParameterBasedExtractor creates entity extractor that uses Chi URL parameters
+func ParameterBasedExtractor(paramMapping map[string]string) middleware.EntityExtractor {
+	return &middleware.ParameterBasedEntityExtractor{
 		ParamMapping:      paramMapping,
-		FallbackExtractor: ChiEntityExtractor(),
-	}
-}
-
-// ParameterBasedEntityExtractor extracts entities from URL parameters
-type ParameterBasedEntityExtractor struct {
-	ParamMapping      map[string]string // parameter name -> entity type
-	FallbackExtractor EntityExtractor
-}
-
-func (e *ParameterBasedEntityExtractor) Extract(req *RequestInfo) (entityID, entityType string, err error) {
-	// Check if we have parameters in metadata
-	if paramsInterface, exists := req.Metadata["params"]; exists {
-		if params, ok := paramsInterface.(map[string]string); ok {
-			for paramName, entityType := range e.ParamMapping {
-				if paramValue, exists := params[paramName]; exists && paramValue != "" {
-					return entityType + ":" + paramValue, entityType, nil
-				}
-			}
-		}
-	}
-
-	// Fall back to standard extraction
-	if e.FallbackExtractor != nil {
-		return e.FallbackExtractor.Extract(req)
+		FallbackExtractor: EntityExtractor(),
 	}
-
-	return "", "", fmt.Errorf("no entity found")
 }
 
-// ChiSubrouterConfig creates configuration for Chi subrouters with different rate limits
-func ChiSubrouterConfig(limiter ratelimit.RateLimiter, routeConfigs map[string]*Config) func(http.Handler) http.Handler {
+// SubrouterConfig creates configuration for Chi subrouters with different rate limits
+func SubrouterConfig(limiter ratelimit.RateLimiter, routeConfigs map[string]*middleware.Config) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			// Determine which configuration to use based on route
-			var config *Config
+			var config *middleware.Config
 
 			if rctx := chi.RouteContext(r.Context()); rctx != nil {
 				routePattern := rctx.RoutePattern()
@@ -344,21 +316,21 @@ func ChiSubrouterConfig(limiter ratelimit.RateLimiter, routeConfigs map[string]*
 
 			// Use default config if no specific config found
 			if config == nil {
-				config = ChiConfig(limiter)
+				config = PluginConfig(limiter)
 			}
 
 			// Apply the middleware logic
-			plugin := &ChiPlugin{}
-			middleware := plugin.CreateMiddleware(limiter, config)
-			middlewareHandler := middleware.(func(http.Handler) http.Handler)
+			plugin := &Plugin{}
+			mw := plugin.CreateMiddleware(limiter, config)
+			middlewareHandler := mw.(func(http.Handler) http.Handler)
 			middlewareHandler(next).ServeHTTP(w, r)
 		})
 	}
 }
 
-// ChiContentTypeBasedExtractor extracts different information based on Content-Type
-func ChiContentTypeBasedExtractor() ScopeExtractor {
-	return &ContentTypeBasedScopeExtractor{
+// ContentTypeBasedExtractor extracts different information based on Content-Type
+func ContentTypeBasedExtractor() middleware.ScopeExtractor {
+	return &middleware.ContentTypeBasedScopeExtractor{
 		ContentTypeScopes: map[string]string{
 			"application/json":         "json_api",
 			"application/xml":          "xml_api",
@@ -370,32 +342,12 @@ func ChiContentTypeBasedExtractor() ScopeExtractor {
 	}
 }
 
-// ContentTypeBasedScopeExtractor extracts scope based on request Content-Type
-type ContentTypeBasedScopeExtractor struct {
-	ContentTypeScopes map[string]string
-	DefaultScope      string
-}
-
-func (e *ContentTypeBasedScopeExtractor) Extract(req *RequestInfo) (scope string, err error) {
-	// Check Content-Type header
-	if contentTypeHeaders, exists := req.Headers["Content-Type"]; exists && len(contentTypeHeaders) > 0 {
-		contentType := strings.Split(contentTypeHeaders[0], ";")[0] // Remove charset etc.
-		contentType = strings.TrimSpace(strings.ToLower(contentType))
-
-		if scopeName, exists := e.ContentTypeScopes[contentType]; exists {
-			return scopeName, nil
-		}
-	}
-
-	return e.DefaultScope, nil
-}
-
 // ============================================================================
 // Helper methods for Chi plugin
 // ============================================================================
 
 // getRealIP extracts the real IP address from the request, handling proxies
-func (p *ChiPlugin) getRealIP(r *http.Request) string {
+func (p *Plugin) getRealIP(r *http.Request) string {
 	// Check X-Forwarded-For header
 	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
 		// Take the first IP in the chain (original client)
@@ -415,16 +367,16 @@ func (p *ChiPlugin) getRealIP(r *http.Request) string {
 }
 
 // sendErrorResponse sends a JSON error response
-func (p *ChiPlugin) sendErrorResponse(w http.ResponseWriter, statusCode int, message string) {
+func (p *Plugin) sendErrorResponse(w http.ResponseWriter, statusCode int, message string) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
 	fmt.Fprintf(w, `{"error":"%s","code":"ERROR"}`, message)
 }
 
 // sendRateLimitedResponse sends a rate limited response
-func (p *ChiPlugin) sendRateLimitedResponse(w http.ResponseWriter, result *ratelimit.Result, config *ResponseConfig) {
+func (p *Plugin) sendRateLimitedResponse(w http.ResponseWriter, result *ratelimit.Result, scope string, config *middleware.ResponseConfig) {
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(config.RateLimitedStatusCode)
+	w.WriteHeader(config.DeniedStatusCode(scope))
 
 	response := fmt.Sprintf(`{
 		"error":"Rate limit exceeded",
@@ -440,5 +392,5 @@ func (p *ChiPlugin) sendRateLimitedResponse(w http.ResponseWriter, result *ratel
 
 // init registers the Chi plugin
 func init() {
-	Register(&ChiPlugin{})
+	middleware.Register(&Plugin{})
 }
@@ -0,0 +1,84 @@
+// chilimit/plugin_test.go
+package chilimit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	ratelimit "github.com/itsatony/gorly"
+	"github.com/itsatony/gorly/middleware"
+)
+
+// fixedLimiter returns the same result for every request, isolating middleware overhead
+type fixedLimiter struct {
+	result *ratelimit.Result
+}
+
+func (f *fixedLimiter) Allow(ctx context.Context, entity ratelimit.AuthEntity, scope string) (*ratelimit.Result, error) {
+	return f.result, nil
+}
+
+func (f *fixedLimiter) AllowN(ctx context.Context, entity ratelimit.AuthEntity, scope string, n int64) (*ratelimit.Result, error) {
+	return f.result, nil
+}
+
+func (f *fixedLimiter) Reset(ctx context.Context, entity ratelimit.AuthEntity, scope string) error {
+	return nil
+}
+
+func (f *fixedLimiter) Stats(ctx context.Context, entity ratelimit.AuthEntity) (*ratelimit.Stats, error) {
+	return &ratelimit.Stats{}, nil
+}
+
+func (f *fixedLimiter) ScopeStats(ctx context.Context, entity ratelimit.AuthEntity, scope string) (*ratelimit.ScopeStats, error) {
+	return &ratelimit.ScopeStats{}, nil
+}
+
+func (f *fixedLimiter) Health(ctx context.Context) error { return nil }
+
+func (f *fixedLimiter) Close() error { return nil }
+
+func BenchmarkChiMiddlewareOverhead(b *testing.B) {
+	allowed := ratelimit.Result{
+		Allowed:   true,
+		Remaining: 500,
+		Limit:     1000,
+		Used:      500,
+		Window:    time.Hour,
+		ResetTime: time.Unix(1700000000, 0),
+		Algorithm: "sliding_window",
+	}
+
+	config := middleware.DefaultConfig()
+	config.Logger = nil
+	handler := (&Plugin{}).CreateMiddleware(&fixedLimiter{result: &allowed}, config).(func(http.Handler) http.Handler)(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+	)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/items", nil)
+	req.Header.Set("X-API-Key", "key-123")
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+}
+
+func TestFor(t *testing.T) {
+	limiter, err := ratelimit.New().Limit("global", "2/minute").Build()
+	if err != nil {
+		t.Fatalf("Failed to build limiter: %v", err)
+	}
+	defer limiter.Close()
+
+	if _, ok := limiter.For(ratelimit.Chi).(func(http.Handler) http.Handler); !ok {
+		t.Errorf("Expected For(Chi) to return net/http middleware, got %T", limiter.For(ratelimit.Chi))
+	}
+	if _, ok := middleware.Get("chi"); !ok {
+		t.Error("Expected the Chi plugin to be registered")
+	}
+}
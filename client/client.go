@@ -0,0 +1,230 @@
+// client/client.go
+// Package client helps Go consumers of gorly-protected APIs read rate limit
+// headers and back off correctly.
+package client
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// unixTimestampThreshold separates absolute reset timestamps from delta seconds.
+// Values above it (2001-09-09) are treated as Unix timestamps.
+const unixTimestampThreshold = 1000000000
+
+// LimitInfo is the rate limit state reported by a server in response headers
+type LimitInfo struct {
+	Limit      int64         `json:"limit"`
+	Remaining  int64         `json:"remaining"`
+	Used       int64         `json:"used"`
+	Window     time.Duration `json:"window"`
+	ResetTime  time.Time     `json:"reset_time"`
+	RetryAfter time.Duration `json:"retry_after"`
+
+	// Limited reports whether the response was a rate limit denial. Parse decides it from
+	// the status code; headers alone, Retry-After included, never set it.
+	Limited bool `json:"limited"`
+
+	// Present reports whether any rate limit headers were found
+	Present bool `json:"present"`
+}
+
+// Parse extracts rate limit information from a response.
+// It understands X-RateLimit-*, the IETF RateLimit-* headers and Retry-After.
+func Parse(resp *http.Response) *LimitInfo {
+	if resp == nil {
+		return &LimitInfo{}
+	}
+
+	info := ParseHeaders(resp.Header, time.Now())
+	if resp.StatusCode == http.StatusTooManyRequests ||
+		(resp.StatusCode == http.StatusServiceUnavailable && info.RetryAfter > 0) {
+		info.Limited = true
+	}
+	return info
+}
+
+// ParseHeaders extracts rate limit information from headers relative to now. Retry-After
+// is also sent with redirects, 202 and 503 responses, so it fills in RetryAfter but does not
+// mark the response Limited.
+func ParseHeaders(header http.Header, now time.Time) *LimitInfo {
+	info := &LimitInfo{Limit: -1, Remaining: -1, Used: -1}
+
+	if v, ok := firstHeader(header, "X-RateLimit-Limit", "RateLimit-Limit"); ok {
+		if n, w, ok := parseLimitValue(v); ok {
+			info.Limit = n
+			info.Window = w
+			info.Present = true
+		}
+	}
+
+	if v, ok := firstHeader(header, "X-RateLimit-Remaining", "RateLimit-Remaining"); ok {
+		if n, _, ok := parseLimitValue(v); ok {
+			info.Remaining = n
+			info.Present = true
+		}
+	}
+
+	if v, ok := firstHeader(header, "X-RateLimit-Used"); ok {
+		if n, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64); err == nil {
+			info.Used = n
+			info.Present = true
+		}
+	}
+
+	if v, ok := firstHeader(header, "X-RateLimit-Window"); ok {
+		if w, ok := parseDuration(v); ok {
+			info.Window = w
+			info.Present = true
+		}
+	}
+	if info.Window == 0 {
+		if v, ok := firstHeader(header, "RateLimit-Policy"); ok {
+			if _, w, ok := parseLimitValue(v); ok {
+				info.Window = w
+			}
+		}
+	}
+
+	if v, ok := firstHeader(header, "X-RateLimit-Reset", "RateLimit-Reset"); ok {
+		if n, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64); err == nil {
+			if n > unixTimestampThreshold {
+				info.ResetTime = time.Unix(n, 0)
+			} else {
+				info.ResetTime = now.Add(time.Duration(n) * time.Second)
+			}
+			info.Present = true
+		}
+	}
+
+	if v, ok := firstHeader(header, "Retry-After", "X-RateLimit-Retry-After"); ok {
+		if d, ok := parseRetryAfter(v, now); ok {
+			info.RetryAfter = d
+			info.Present = true
+		}
+	}
+
+	// Derive missing counters where possible
+	if info.Used < 0 && info.Limit >= 0 && info.Remaining >= 0 {
+		info.Used = info.Limit - info.Remaining
+	}
+	if info.Limit < 0 {
+		info.Limit = 0
+	}
+	if info.Remaining < 0 {
+		info.Remaining = 0
+	}
+	if info.Used < 0 {
+		info.Used = 0
+	}
+
+	return info
+}
+
+// IsRateLimited reports whether a response was denied by a rate limiter
+func IsRateLimited(resp *http.Response) bool {
+	return Parse(resp).Limited
+}
+
+// Wait returns how long a client should wait before its next request.
+// It is zero unless the response was denied or the quota is exhausted.
+func (li *LimitInfo) Wait(now time.Time) time.Duration {
+	if li.RetryAfter > 0 {
+		return li.RetryAfter
+	}
+	if (li.Limited || (li.Present && li.Remaining == 0 && li.Limit > 0)) && !li.ResetTime.IsZero() {
+		if d := li.ResetTime.Sub(now); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// WaitForReset blocks until the server's rate limit allows another request.
+// It returns immediately when the response shows remaining quota and
+// returns ctx.Err() if the context ends first.
+func WaitForReset(ctx context.Context, resp *http.Response) error {
+	wait := Parse(resp).Wait(time.Now())
+	if wait <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// firstHeader returns the first non-empty header among names
+func firstHeader(header http.Header, names ...string) (string, bool) {
+	for _, name := range names {
+		if v := header.Get(name); v != "" {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// parseLimitValue parses "100", "100;w=60" or "100, 100;w=60" into a count and optional window
+func parseLimitValue(v string) (int64, time.Duration, bool) {
+	items := strings.Split(v, ",")
+
+	n, err := strconv.ParseInt(strings.TrimSpace(strings.SplitN(items[0], ";", 2)[0]), 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+
+	// The window may be attached to any quota policy item
+	var window time.Duration
+	for _, item := range items {
+		for _, param := range strings.Split(item, ";")[1:] {
+			param = strings.TrimSpace(param)
+			if !strings.HasPrefix(param, "w=") {
+				continue
+			}
+			if secs, err := strconv.ParseInt(strings.TrimPrefix(param, "w="), 10, 64); err == nil && window == 0 {
+				window = time.Duration(secs) * time.Second
+			}
+		}
+	}
+
+	return n, window, true
+}
+
+// parseDuration parses integer seconds or a Go duration string such as "1h0m0s"
+func parseDuration(v string) (time.Duration, bool) {
+	v = strings.TrimSpace(v)
+	if secs, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return time.Duration(secs) * time.Second, true
+	}
+	if d, err := time.ParseDuration(v); err == nil {
+		return d, true
+	}
+	return 0, false
+}
+
+// parseRetryAfter parses Retry-After as delta seconds or an HTTP date
+func parseRetryAfter(v string, now time.Time) (time.Duration, bool) {
+	v = strings.TrimSpace(v)
+	if secs, err := strconv.ParseInt(v, 10, 64); err == nil {
+		if secs < 0 {
+			return 0, false
+		}
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		if d := t.Sub(now); d > 0 {
+			return d, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
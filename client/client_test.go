@@ -0,0 +1,131 @@
+// client/client_test.go
+package client
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestParseHeaders_XRateLimit(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	header := http.Header{}
+	header.Set("X-RateLimit-Limit", "100")
+	header.Set("X-RateLimit-Remaining", "40")
+	header.Set("X-RateLimit-Used", "60")
+	header.Set("X-RateLimit-Window", "1h0m0s")
+	header.Set("X-RateLimit-Reset", "1700000300")
+
+	info := ParseHeaders(header, now)
+
+	if !info.Present || info.Limited {
+		t.Fatalf("Expected present, non-limited info, got %+v", info)
+	}
+	if info.Limit != 100 || info.Remaining != 40 || info.Used != 60 {
+		t.Errorf("Unexpected counters: %+v", info)
+	}
+	if info.Window != time.Hour {
+		t.Errorf("Expected 1h window, got %v", info.Window)
+	}
+	if !info.ResetTime.Equal(now.Add(5 * time.Minute)) {
+		t.Errorf("Expected reset in 5m, got %v", info.ResetTime)
+	}
+	if wait := info.Wait(now); wait != 0 {
+		t.Errorf("Expected no wait with remaining quota, got %v", wait)
+	}
+}
+
+func TestParseHeaders_IETF(t *testing.T) {
+	now := time.Now()
+	header := http.Header{}
+	header.Set("RateLimit-Limit", "10, 10;w=60")
+	header.Set("RateLimit-Remaining", "0")
+	header.Set("RateLimit-Reset", "30")
+
+	info := ParseHeaders(header, now)
+
+	if info.Limit != 10 || info.Remaining != 0 || info.Used != 10 {
+		t.Errorf("Unexpected counters: %+v", info)
+	}
+	if info.Window != time.Minute {
+		t.Errorf("Expected 60s window, got %v", info.Window)
+	}
+	if wait := info.Wait(now); wait != 30*time.Second {
+		t.Errorf("Expected 30s wait on exhausted quota, got %v", wait)
+	}
+}
+
+func TestParseHeaders_RetryAfterDate(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	header := http.Header{}
+	header.Set("Retry-After", now.Add(90*time.Second).Format(http.TimeFormat))
+
+	info := ParseHeaders(header, now)
+
+	if info.RetryAfter != 90*time.Second {
+		t.Errorf("Expected 90s retry after, got %+v", info)
+	}
+}
+
+func TestParse_StatusCodes(t *testing.T) {
+	resp := &http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{}}
+	if !IsRateLimited(resp) {
+		t.Error("Expected 429 to be rate limited")
+	}
+
+	resp = &http.Response{StatusCode: http.StatusServiceUnavailable, Header: http.Header{}}
+	if IsRateLimited(resp) {
+		t.Error("Expected bare 503 not to be treated as rate limited")
+	}
+
+	resp.Header.Set("Retry-After", "5")
+	if !IsRateLimited(resp) {
+		t.Error("Expected 503 with Retry-After to be rate limited")
+	}
+
+	if info := Parse(&http.Response{StatusCode: http.StatusOK, Header: http.Header{}}); info.Present {
+		t.Error("Expected no rate limit info without headers")
+	}
+}
+
+func TestParse_RetryAfterWithoutDenial(t *testing.T) {
+	for _, status := range []int{http.StatusAccepted, http.StatusMovedPermanently, http.StatusOK} {
+		for _, retryAfter := range []string{"0", "120"} {
+			resp := &http.Response{StatusCode: status, Header: http.Header{}}
+			resp.Header.Set("Retry-After", retryAfter)
+			if IsRateLimited(resp) {
+				t.Errorf("Expected %d with Retry-After %s not to be rate limited", status, retryAfter)
+			}
+		}
+	}
+
+	// The retry delay is still reported, e.g. for polling a 202
+	resp := &http.Response{StatusCode: http.StatusAccepted, Header: http.Header{}}
+	resp.Header.Set("Retry-After", "120")
+	if info := Parse(resp); info.Limited || info.RetryAfter != 2*time.Minute {
+		t.Errorf("Expected a 2m retry delay without a denial, got %+v", info)
+	}
+}
+
+func TestWaitForReset(t *testing.T) {
+	resp := &http.Response{StatusCode: http.StatusOK, Header: http.Header{}}
+	resp.Header.Set("X-RateLimit-Remaining", "5")
+	if err := WaitForReset(context.Background(), resp); err != nil {
+		t.Errorf("Expected immediate return, got %v", err)
+	}
+
+	resp = &http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{}}
+	resp.Header.Set("Retry-After", "60")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if err := WaitForReset(ctx, resp); err != context.DeadlineExceeded {
+		t.Errorf("Expected context deadline, got %v", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Error("WaitForReset ignored context cancellation")
+	}
+}
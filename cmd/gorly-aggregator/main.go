@@ -0,0 +1,83 @@
+// cmd/gorly-aggregator/main.go - Central aggregator merging the stats of many clusters
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	ratelimit "github.com/itsatony/gorly"
+)
+
+// sourceFlags collects repeated -source cluster=url flags
+type sourceFlags map[string]string
+
+func (s sourceFlags) String() string {
+	pairs := make([]string, 0, len(s))
+	for cluster, url := range s {
+		pairs = append(pairs, cluster+"="+url)
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (s sourceFlags) Set(value string) error {
+	cluster, url, ok := strings.Cut(value, "=")
+	if !ok || cluster == "" || url == "" {
+		return fmt.Errorf("expected cluster=url, got %q", value)
+	}
+	s[cluster] = url
+	return nil
+}
+
+func main() {
+	sources := sourceFlags{}
+	listen := flag.String("listen", ":9400", "Address to serve /push, /global and /health on")
+	flag.Var(sources, "source", "Cluster to scrape as cluster=url of its MonitoringServer (repeatable)")
+	scrapeInterval := flag.Duration("scrape-interval", 30*time.Second, "Interval between scrapes of the sources")
+	staleAfter := flag.Duration("stale-after", ratelimit.DefaultFederationStaleAfter, "Age after which a cluster is excluded from the totals")
+	showVersion := flag.Bool("version", false, "Show version information")
+	flag.Parse()
+
+	if *showVersion {
+		fmt.Print(ratelimit.GetVersionInfo().Banner())
+		return
+	}
+
+	// The token is read from the environment so it does not show up in process listings
+	aggregator := ratelimit.NewStatsAggregator(&ratelimit.AggregatorConfig{
+		Sources:        sources,
+		ScrapeInterval: *scrapeInterval,
+		StaleAfter:     *staleAfter,
+		Token:          os.Getenv("GORLY_AGGREGATOR_TOKEN"),
+	})
+	aggregator.Start()
+	defer aggregator.Close()
+
+	server := &http.Server{
+		Addr:              *listen,
+		Handler:           aggregator,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	log.Printf("gorly aggregator listening on %s with %d scraped clusters", *listen, len(sources))
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Fatalf("Aggregator failed: %v", err)
+	}
+}
@@ -0,0 +1,309 @@
+// cmd/gorly-ops/benchcheck.go - Benchmark regression gate against a stored baseline
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultBenchPattern selects the hot path benchmarks of bench_test.go
+const defaultBenchPattern = "^Benchmark(CheckMemory|CheckRedis|CheckPostgres|Middleware)$"
+
+// benchResult is the median of the runs of one benchmark
+type benchResult struct {
+	NsPerOp     float64 `json:"ns_per_op"`
+	BytesPerOp  float64 `json:"bytes_per_op"`
+	AllocsPerOp float64 `json:"allocs_per_op"`
+	Runs        int     `json:"runs"`
+}
+
+// benchBaseline is the stored reference a release is compared against
+type benchBaseline struct {
+	RecordedAt time.Time              `json:"recorded_at"`
+	GoVersion  string                 `json:"go_version"`
+	CPU        string                 `json:"cpu,omitempty"`
+	Benchmarks map[string]benchResult `json:"benchmarks"`
+}
+
+// benchComparison is the verdict for one benchmark
+type benchComparison struct {
+	Name       string  `json:"name"`
+	BaseNs     float64 `json:"base_ns_per_op"`
+	NsPerOp    float64 `json:"ns_per_op"`
+	NsDelta    float64 `json:"ns_delta"` // Relative change, e.g. 0.12 for +12%
+	BaseAllocs float64 `json:"base_allocs_per_op"`
+	Allocs     float64 `json:"allocs_per_op"`
+	Regression bool    `json:"regression"`
+	Note       string  `json:"note,omitempty"`
+}
+
+func handleBenchcheck(args []string) {
+	fs := flag.NewFlagSet("benchcheck", flag.ExitOnError)
+	baselinePath := fs.String("baseline", "", "Baseline JSON file (required)")
+	update := fs.Bool("update", false, "Record the results as the new baseline instead of comparing")
+	threshold := fs.Float64("threshold", 10, "Allowed regression of ns/op and allocs/op in percent")
+	pattern := fs.String("bench", defaultBenchPattern, "Benchmarks to run")
+	pkg := fs.String("pkg", ".", "Package with the benchmarks")
+	count := fs.Int("count", 5, "Runs per benchmark; the median is compared")
+	benchtime := fs.String("benchtime", "1s", "Duration or iterations of each run")
+	redisAddr := fs.String("redis", "", "Redis address for BenchmarkCheckRedis (skipped without)")
+	postgresDSN := fs.String("postgres", "", "Postgres DSN for BenchmarkCheckPostgres (skipped without)")
+	input := fs.String("input", "", "Compare saved 'go test -bench' output instead of running the benchmarks")
+	format := fs.String("format", "table", "Output format: table, json")
+
+	fs.Parse(args)
+
+	if *baselinePath == "" {
+		fmt.Println("Error: --baseline is required")
+		fs.Usage()
+		os.Exit(1)
+	}
+	if *threshold < 0 || *count < 1 {
+		fmt.Println("Error: --threshold cannot be negative and --count must be at least 1")
+		os.Exit(1)
+	}
+
+	var output io.Reader
+	if *input != "" {
+		file, err := os.Open(*input)
+		if err != nil {
+			fmt.Printf("❌ Failed to read benchmark output: %v\n", err)
+			os.Exit(1)
+		}
+		defer file.Close()
+		output = file
+	} else {
+		fmt.Fprintf(os.Stderr, "⏱️  Running benchmarks %s in %s (%d runs of %s)...\n", *pattern, *pkg, *count, *benchtime)
+		data, err := runBenchmarks(*pkg, *pattern, *benchtime, *count, *redisAddr, *postgresDSN)
+		if err != nil {
+			fmt.Printf("❌ Benchmarks failed: %v\n", err)
+			os.Exit(1)
+		}
+		output = bytes.NewReader(data)
+	}
+
+	current, err := parseBenchOutput(output)
+	if err != nil {
+		fmt.Printf("❌ Failed to parse benchmark output: %v\n", err)
+		os.Exit(1)
+	}
+	if len(current.Benchmarks) == 0 {
+		fmt.Println("❌ No benchmark results found")
+		os.Exit(1)
+	}
+
+	if *update {
+		data, _ := json.MarshalIndent(current, "", "  ")
+		if err := os.WriteFile(*baselinePath, append(data, '\n'), 0644); err != nil {
+			fmt.Printf("❌ Failed to write baseline: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("✅ Recorded %d benchmarks in %s\n", len(current.Benchmarks), *baselinePath)
+		return
+	}
+
+	data, err := os.ReadFile(*baselinePath)
+	if err != nil {
+		fmt.Printf("❌ Failed to read baseline: %v\n", err)
+		os.Exit(1)
+	}
+	var baseline benchBaseline
+	if err := json.Unmarshal(data, &baseline); err != nil {
+		fmt.Printf("❌ Invalid baseline %s: %v\n", *baselinePath, err)
+		os.Exit(1)
+	}
+
+	comparisons := compareBenchmarks(&baseline, current, *threshold/100)
+	regressions := 0
+	for _, c := range comparisons {
+		if c.Regression {
+			regressions++
+		}
+	}
+
+	if *format == "json" {
+		data, _ := json.MarshalIndent(map[string]interface{}{
+			"baseline":    *baselinePath,
+			"threshold":   *threshold,
+			"comparisons": comparisons,
+			"regressions": regressions,
+		}, "", "  ")
+		fmt.Println(string(data))
+	} else {
+		fmt.Printf("\n📊 Benchmarks against %s (recorded %s, threshold %.1f%%):\n",
+			*baselinePath, baseline.RecordedAt.Format("2006-01-02"), *threshold)
+		if baseline.CPU != "" && current.CPU != "" && baseline.CPU != current.CPU {
+			fmt.Printf("   ⚠️  Baseline was recorded on %q, this machine is %q\n", baseline.CPU, current.CPU)
+		}
+		for _, c := range comparisons {
+			verdict := "✅"
+			if c.Regression {
+				verdict = "❌"
+			}
+			if c.Note != "" {
+				fmt.Printf("   %s %-20s %s\n", verdict, c.Name, c.Note)
+				continue
+			}
+			fmt.Printf("   %s %-20s %10.0f → %-10.0f ns/op %+7.1f%%   %4.0f → %-4.0f allocs/op\n",
+				verdict, c.Name, c.BaseNs, c.NsPerOp, c.NsDelta*100, c.BaseAllocs, c.Allocs)
+		}
+	}
+
+	if regressions > 0 {
+		fmt.Fprintf(os.Stderr, "\n❌ %d benchmark(s) regressed by more than %.1f%%\n", regressions, *threshold)
+		os.Exit(1)
+	}
+}
+
+// runBenchmarks runs the benchmarks of pkg with go test and returns their output
+func runBenchmarks(pkg, pattern, benchtime string, count int, redisAddr, postgresDSN string) ([]byte, error) {
+	cmd := exec.Command("go", "test", "-run", "^$", "-bench", pattern, "-benchmem",
+		"-benchtime", benchtime, "-count", strconv.Itoa(count), pkg)
+	cmd.Env = os.Environ()
+	if redisAddr != "" {
+		cmd.Env = append(cmd.Env, "GORLY_BENCH_REDIS="+redisAddr)
+	}
+	if postgresDSN != "" {
+		cmd.Env = append(cmd.Env, "GORLY_BENCH_POSTGRES="+postgresDSN)
+	}
+	cmd.Stderr = os.Stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return output, fmt.Errorf("%w\n%s", err, output)
+	}
+	return output, nil
+}
+
+// benchLine matches a result line of go test -bench -benchmem
+var benchLine = regexp.MustCompile(`^Benchmark(\S+?)(?:-\d+)?\s+\d+\s+(.+)$`)
+
+// parseBenchOutput collects the median ns/op, B/op and allocs/op of every benchmark in go test output
+func parseBenchOutput(r io.Reader) (*benchBaseline, error) {
+	type samples struct{ ns, bytes, allocs []float64 }
+	runs := make(map[string]*samples)
+	result := &benchBaseline{
+		RecordedAt: time.Now().UTC(),
+		GoVersion:  runtime.Version(),
+		Benchmarks: make(map[string]benchResult),
+	}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if cpu, ok := strings.CutPrefix(line, "cpu: "); ok {
+			result.CPU = cpu
+			continue
+		}
+		m := benchLine.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		s := runs[m[1]]
+		if s == nil {
+			s = &samples{}
+			runs[m[1]] = s
+		}
+		// Values come in pairs like "812 ns/op" or "6 allocs/op"
+		fields := strings.Fields(m[2])
+		for i := 0; i+1 < len(fields); i += 2 {
+			value, err := strconv.ParseFloat(fields[i], 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid value %q of Benchmark%s", fields[i], m[1])
+			}
+			switch fields[i+1] {
+			case "ns/op":
+				s.ns = append(s.ns, value)
+			case "B/op":
+				s.bytes = append(s.bytes, value)
+			case "allocs/op":
+				s.allocs = append(s.allocs, value)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	for name, s := range runs {
+		if len(s.ns) == 0 {
+			continue
+		}
+		result.Benchmarks[name] = benchResult{
+			NsPerOp:     median(s.ns),
+			BytesPerOp:  median(s.bytes),
+			AllocsPerOp: median(s.allocs),
+			Runs:        len(s.ns),
+		}
+	}
+	return result, nil
+}
+
+// median returns the middle value, or the mean of the two middle values; 0 for none
+func median(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
+
+// compareBenchmarks flags benchmarks whose ns/op or allocs/op grew by more than threshold
+// (0.1 for 10%). A baseline benchmark missing from the run is a regression, since the gate
+// would otherwise pass by not measuring; benchmarks without a baseline are only reported.
+func compareBenchmarks(baseline, current *benchBaseline, threshold float64) []benchComparison {
+	names := make([]string, 0, len(baseline.Benchmarks)+len(current.Benchmarks))
+	for name := range baseline.Benchmarks {
+		names = append(names, name)
+	}
+	for name := range current.Benchmarks {
+		if _, ok := baseline.Benchmarks[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	comparisons := make([]benchComparison, 0, len(names))
+	for _, name := range names {
+		base, inBaseline := baseline.Benchmarks[name]
+		cur, inCurrent := current.Benchmarks[name]
+		c := benchComparison{
+			Name:       name,
+			BaseNs:     base.NsPerOp,
+			NsPerOp:    cur.NsPerOp,
+			BaseAllocs: base.AllocsPerOp,
+			Allocs:     cur.AllocsPerOp,
+		}
+		switch {
+		case !inCurrent:
+			c.Regression = true
+			c.Note = "not run; skipped or removed since the baseline"
+		case !inBaseline:
+			c.Note = "no baseline; record one with --update"
+		default:
+			if base.NsPerOp > 0 {
+				c.NsDelta = cur.NsPerOp/base.NsPerOp - 1
+			}
+			c.Regression = cur.NsPerOp > base.NsPerOp*(1+threshold) ||
+				cur.AllocsPerOp > base.AllocsPerOp*(1+threshold)
+		}
+		comparisons = append(comparisons, c)
+	}
+	return comparisons
+}
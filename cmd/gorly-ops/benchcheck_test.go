@@ -0,0 +1,72 @@
+// cmd/gorly-ops/benchcheck_test.go
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+const benchOutput = `goos: linux
+goarch: amd64
+pkg: github.com/itsatony/gorly
+cpu: Intel(R) Xeon(R) Processor
+BenchmarkCheckMemory-8   	  350000	      3400 ns/op	    1283 B/op	      18 allocs/op
+BenchmarkCheckMemory-8   	  350000	      3600 ns/op	    1283 B/op	      18 allocs/op
+BenchmarkCheckMemory-8   	  350000	      9000 ns/op	    1283 B/op	      18 allocs/op
+BenchmarkMiddleware      	  170000	      6804 ns/op	    2532 B/op	      43 allocs/op
+PASS
+ok  	github.com/itsatony/gorly	6.021s
+`
+
+func TestParseBenchOutput(t *testing.T) {
+	result, err := parseBenchOutput(strings.NewReader(benchOutput))
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+	if result.CPU != "Intel(R) Xeon(R) Processor" {
+		t.Errorf("Expected the CPU to be recorded, got %q", result.CPU)
+	}
+
+	check := result.Benchmarks["CheckMemory"]
+	if check.Runs != 3 || check.NsPerOp != 3600 || check.AllocsPerOp != 18 || check.BytesPerOp != 1283 {
+		t.Errorf("Expected the median of 3 runs without the GOMAXPROCS suffix, got %+v", check)
+	}
+	if mw := result.Benchmarks["Middleware"]; mw.NsPerOp != 6804 || mw.AllocsPerOp != 43 {
+		t.Errorf("Expected the middleware result, got %+v", mw)
+	}
+}
+
+func TestCompareBenchmarks(t *testing.T) {
+	baseline := &benchBaseline{Benchmarks: map[string]benchResult{
+		"Stable":    {NsPerOp: 1000, AllocsPerOp: 10},
+		"Slower":    {NsPerOp: 1000, AllocsPerOp: 10},
+		"Allocates": {NsPerOp: 1000, AllocsPerOp: 10},
+		"Zero":      {NsPerOp: 100, AllocsPerOp: 0},
+		"Skipped":   {NsPerOp: 1000, AllocsPerOp: 10},
+	}}
+	current := &benchBaseline{Benchmarks: map[string]benchResult{
+		"Stable":    {NsPerOp: 1090, AllocsPerOp: 10},
+		"Slower":    {NsPerOp: 1150, AllocsPerOp: 10},
+		"Allocates": {NsPerOp: 900, AllocsPerOp: 12},
+		"Zero":      {NsPerOp: 100, AllocsPerOp: 1},
+		"New":       {NsPerOp: 500, AllocsPerOp: 5},
+	}}
+
+	want := map[string]bool{
+		"Stable":    false,
+		"Slower":    true,
+		"Allocates": true,
+		"Zero":      true,
+		"Skipped":   true,
+		"New":       false,
+	}
+	comparisons := compareBenchmarks(baseline, current, 0.1)
+	if len(comparisons) != len(want) {
+		t.Fatalf("Expected %d comparisons, got %+v", len(want), comparisons)
+	}
+	for _, c := range comparisons {
+		if c.Regression != want[c.Name] {
+			t.Errorf("%s: expected regression=%t, got %+v", c.Name, want[c.Name], c)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+// cmd/gorly-ops/configcheck.go - Validation of config files
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	ratelimit "github.com/itsatony/gorly"
+)
+
+// validateConfigFile loads a JSON or YAML config file and validates it. In strict mode
+// unknown keys are errors, listed one per line with the closest valid key.
+func validateConfigFile(path string, strict bool) error {
+	config, err := ratelimit.NewConfigLoader().WithStrict(strict).LoadFromFile(path)
+	if err != nil {
+		var unknown *ratelimit.UnknownConfigKeysError
+		if errors.As(err, &unknown) {
+			lines := make([]string, len(unknown.Keys))
+			for i, key := range unknown.Keys {
+				lines[i] = "  " + key.String()
+			}
+			return fmt.Errorf("%s has %d unknown keys:\n%s", path, len(unknown.Keys), strings.Join(lines, "\n"))
+		}
+		return err
+	}
+	if err := config.Validate(); err != nil {
+		return fmt.Errorf("%s is invalid: %w", path, err)
+	}
+	return nil
+}
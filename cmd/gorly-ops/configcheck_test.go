@@ -0,0 +1,35 @@
+// cmd/gorly-ops/configcheck_test.go
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidateConfigFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "gorly.yaml")
+	config := "algorithm: sliding_window\ndefualtLimits:\n  global: 100/minute\nredis:\n  adress: localhost:6379\n"
+	if err := os.WriteFile(path, []byte(config), 0o640); err != nil {
+		t.Fatal(err)
+	}
+
+	err := validateConfigFile(path, true)
+	if err == nil {
+		t.Fatal("Expected unknown keys to fail strict validation")
+	}
+	for _, want := range []string{
+		"unknown key defualtLimits (did you mean defaultLimits?)",
+		"unknown key redis.adress (did you mean address?)",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected %q in %v", want, err)
+		}
+	}
+
+	if err := validateConfigFile(path, false); err != nil {
+		t.Errorf("Expected unknown keys to be ignored without strict mode, got %v", err)
+	}
+}
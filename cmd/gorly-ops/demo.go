@@ -0,0 +1,300 @@
+// cmd/gorly-ops/demo.go - Scripted traffic scenarios for the demo server
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	ratelimit "github.com/itsatony/gorly"
+)
+
+// demoScenario is a scripted demo: the limits the demo server enforces, the entities
+// sending traffic and the steps of traffic they send, in order
+type demoScenario struct {
+	Name        string                       `yaml:"name"`
+	Description string                       `yaml:"description"`
+	Limits      map[string]string            `yaml:"limits"`      // Scope -> limit, overriding those of the preset
+	TierLimits  map[string]map[string]string `yaml:"tier_limits"` // Scope -> tier -> limit
+	Entities    []demoEntity                 `yaml:"entities"`
+	Steps       []demoStep                   `yaml:"steps"`
+}
+
+// demoEntity is a client of a scenario. Requests identify it with the X-User-ID and
+// X-User-Tier headers, so it is limited as "tier:name".
+type demoEntity struct {
+	Name string `yaml:"name"`
+	Tier string `yaml:"tier"` // Default: free
+}
+
+// demoStep is a burst of traffic, or a pause when it sends no requests
+type demoStep struct {
+	Name     string        `yaml:"name"`
+	Entities []string      `yaml:"entities"` // Default: every entity
+	Method   string        `yaml:"method"`   // Default: GET
+	Path     string        `yaml:"path"`     // Default: /api/data
+	Requests int           `yaml:"requests"` // Requests per entity
+	Rate     float64       `yaml:"rate"`     // Requests per second across entities; 0 sends them back to back
+	Pause    time.Duration `yaml:"pause"`    // Wait after the step, e.g. for a window to pass
+}
+
+// demoDecision is the outcome of one scripted request
+type demoDecision struct {
+	Elapsed    time.Duration
+	Step       string
+	Entity     string
+	Method     string
+	Path       string
+	Status     int
+	Remaining  string
+	Limit      string
+	RetryAfter string
+	Err        error
+}
+
+// demoTally counts the decisions of one entity
+type demoTally struct {
+	Entity  string
+	Allowed int
+	Denied  int
+	Errors  int
+}
+
+// loadScenario reads and validates a scenario file. Unknown keys are rejected, so a
+// misspelled setting fails loudly instead of silently changing the demo.
+func loadScenario(path string) (*demoScenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read scenario: %w", err)
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	var scenario demoScenario
+	if err := decoder.Decode(&scenario); err != nil {
+		return nil, fmt.Errorf("failed to parse scenario %s: %w", path, err)
+	}
+	if err := scenario.validate(); err != nil {
+		return nil, fmt.Errorf("invalid scenario %s: %w", path, err)
+	}
+	return &scenario, nil
+}
+
+// validate checks the scenario and fills in defaults
+func (s *demoScenario) validate() error {
+	if len(s.Entities) == 0 {
+		return fmt.Errorf("no entities")
+	}
+	if len(s.Steps) == 0 {
+		return fmt.Errorf("no steps")
+	}
+	names := make(map[string]bool, len(s.Entities))
+	for i := range s.Entities {
+		entity := &s.Entities[i]
+		if entity.Name == "" {
+			return fmt.Errorf("entity %d has no name", i+1)
+		}
+		if names[entity.Name] {
+			return fmt.Errorf("entity %s is listed twice", entity.Name)
+		}
+		names[entity.Name] = true
+		if entity.Tier == "" {
+			entity.Tier = "free"
+		}
+	}
+
+	for i := range s.Steps {
+		step := &s.Steps[i]
+		if step.Name == "" {
+			step.Name = fmt.Sprintf("step %d", i+1)
+		}
+		if step.Requests < 0 || step.Rate < 0 || step.Pause < 0 {
+			return fmt.Errorf("%s: requests, rate and pause cannot be negative", step.Name)
+		}
+		if step.Requests == 0 && step.Pause == 0 {
+			return fmt.Errorf("%s: a step needs requests or a pause", step.Name)
+		}
+		for _, name := range step.Entities {
+			if !names[name] {
+				return fmt.Errorf("%s: unknown entity %s", step.Name, name)
+			}
+		}
+		if len(step.Entities) == 0 {
+			for _, entity := range s.Entities {
+				step.Entities = append(step.Entities, entity.Name)
+			}
+		}
+		if step.Method == "" {
+			step.Method = http.MethodGet
+		}
+		if step.Path == "" {
+			step.Path = "/api/data"
+		}
+		if !strings.HasPrefix(step.Path, "/api/") {
+			return fmt.Errorf("%s: path %s is not rate limited by the demo server; use /api/...", step.Name, step.Path)
+		}
+	}
+	return nil
+}
+
+// configure makes a builder limit the entities of the scenario and enforce its limits
+func (s *demoScenario) configure(builder *ratelimit.Builder) *ratelimit.Builder {
+	builder = builder.ExtractorFunc(ratelimit.ExtractEntityWithTier)
+	if len(s.Limits) > 0 {
+		builder = builder.Limits(s.Limits)
+	}
+	for scope, tiers := range s.TierLimits {
+		builder = builder.ScopeTierLimits(scope, tiers)
+	}
+	return builder
+}
+
+// tier returns the tier of an entity of the scenario
+func (s *demoScenario) tier(name string) string {
+	for _, entity := range s.Entities {
+		if entity.Name == name {
+			return entity.Tier
+		}
+	}
+	return ""
+}
+
+// runScenario sends the traffic of a scenario to the demo server at baseURL, passing every
+// decision to onDecision as it is made, and returns the decisions tallied per entity
+func runScenario(ctx context.Context, client *http.Client, baseURL string, scenario *demoScenario, onDecision func(demoDecision)) []demoTally {
+	start := time.Now()
+	tallies := make(map[string]*demoTally, len(scenario.Entities))
+	for _, entity := range scenario.Entities {
+		tallies[entity.Name] = &demoTally{Entity: entity.Tier + ":" + entity.Name}
+	}
+
+	for _, step := range scenario.Steps {
+		var interval time.Duration
+		if step.Rate > 0 {
+			interval = time.Duration(float64(time.Second) / step.Rate)
+		}
+		next := time.Now()
+		for i := 0; i < step.Requests; i++ {
+			for _, name := range step.Entities {
+				if !sleepUntil(ctx, next) {
+					return sortedTallies(tallies)
+				}
+				next = next.Add(interval)
+
+				decision := sendDemoRequest(ctx, client, baseURL, step, name, scenario.tier(name))
+				decision.Elapsed = time.Since(start)
+				tally := tallies[name]
+				switch {
+				case decision.Err != nil:
+					tally.Errors++
+				case decision.Status == http.StatusTooManyRequests:
+					tally.Denied++
+				default:
+					tally.Allowed++
+				}
+				onDecision(decision)
+			}
+		}
+		if !sleepUntil(ctx, time.Now().Add(step.Pause)) {
+			break
+		}
+	}
+	return sortedTallies(tallies)
+}
+
+// sendDemoRequest sends one request of a step as an entity
+func sendDemoRequest(ctx context.Context, client *http.Client, baseURL string, step demoStep, name, tier string) demoDecision {
+	decision := demoDecision{
+		Step:   step.Name,
+		Entity: tier + ":" + name,
+		Method: step.Method,
+		Path:   step.Path,
+	}
+	req, err := http.NewRequestWithContext(ctx, step.Method, baseURL+step.Path, nil)
+	if err != nil {
+		decision.Err = err
+		return decision
+	}
+	req.Header.Set("X-User-ID", name)
+	req.Header.Set("X-User-Tier", tier)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		decision.Err = err
+		return decision
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	decision.Status = resp.StatusCode
+	decision.Remaining = resp.Header.Get("X-RateLimit-Remaining")
+	decision.Limit = resp.Header.Get("X-RateLimit-Limit")
+	decision.RetryAfter = resp.Header.Get("Retry-After")
+	return decision
+}
+
+// sleepUntil waits until t, returning false if ctx is done first
+func sleepUntil(ctx context.Context, t time.Time) bool {
+	wait := time.Until(t)
+	if wait <= 0 {
+		return ctx.Err() == nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// sortedTallies returns the tallies ordered by entity
+func sortedTallies(tallies map[string]*demoTally) []demoTally {
+	sorted := make([]demoTally, 0, len(tallies))
+	for _, tally := range tallies {
+		sorted = append(sorted, *tally)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Entity < sorted[j].Entity })
+	return sorted
+}
+
+// printDecision prints a decision as one line of the live demo output
+func printDecision(d demoDecision) {
+	prefix := fmt.Sprintf("   %7.1fs  %-16s %-20s %-6s %-16s", d.Elapsed.Seconds(), d.Step, d.Entity, d.Method, d.Path)
+	switch {
+	case d.Err != nil:
+		fmt.Printf("%s ⚠️  error: %v\n", prefix, d.Err)
+	case d.Status == http.StatusTooManyRequests:
+		fmt.Printf("%s ❌ %d  retry in %ss\n", prefix, d.Status, d.RetryAfter)
+	case d.Remaining != "":
+		fmt.Printf("%s ✅ %d  %s/%s left\n", prefix, d.Status, d.Remaining, d.Limit)
+	default:
+		fmt.Printf("%s ✅ %d\n", prefix, d.Status)
+	}
+}
+
+// playScenario runs a scenario against the demo server and prints it live
+func playScenario(ctx context.Context, baseURL string, scenario *demoScenario) {
+	fmt.Printf("\n🎬 Scenario: %s\n", scenario.Name)
+	if scenario.Description != "" {
+		fmt.Printf("   %s\n", strings.TrimSpace(scenario.Description))
+	}
+	fmt.Printf("\n   %8s  %-16s %-20s %-6s %-16s %s\n", "TIME", "STEP", "ENTITY", "METHOD", "PATH", "DECISION")
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	tallies := runScenario(ctx, client, baseURL, scenario, printDecision)
+
+	fmt.Printf("\n📊 Scenario Results:\n")
+	fmt.Printf("   %-20s %-8s %-8s %s\n", "ENTITY", "ALLOWED", "DENIED", "ERRORS")
+	for _, tally := range tallies {
+		fmt.Printf("   %-20s %-8d %-8d %d\n", tally.Entity, tally.Allowed, tally.Denied, tally.Errors)
+	}
+}
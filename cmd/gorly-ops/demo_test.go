@@ -0,0 +1,94 @@
+// cmd/gorly-ops/demo_test.go
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	ratelimit "github.com/itsatony/gorly"
+)
+
+func TestLoadScenario(t *testing.T) {
+	bundled, err := filepath.Glob(filepath.Join("scenarios", "*.yaml"))
+	if err != nil || len(bundled) == 0 {
+		t.Fatalf("Expected bundled scenarios, got %v (%v)", bundled, err)
+	}
+	for _, path := range bundled {
+		if _, err := loadScenario(path); err != nil {
+			t.Errorf("Bundled scenario failed to load: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		scenario string
+		want     string
+	}{
+		{"unknown key", "entities: [{name: a}]\nsteps: [{requests: 1, rps: 5}]\n", "field rps not found"},
+		{"unknown entity", "entities: [{name: a}]\nsteps: [{entities: [b], requests: 1}]\n", "unknown entity b"},
+		{"unlimited path", "entities: [{name: a}]\nsteps: [{path: /health, requests: 1}]\n", "not rate limited"},
+		{"empty step", "entities: [{name: a}]\nsteps: [{name: idle}]\n", "idle: a step needs requests or a pause"},
+	}
+	dir := t.TempDir()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, "scenario.yaml")
+			if err := os.WriteFile(path, []byte(tt.scenario), 0o640); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := loadScenario(path); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected an error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestRunScenario(t *testing.T) {
+	scenario := &demoScenario{
+		Limits:     map[string]string{"search": "2/minute"},
+		TierLimits: map[string]map[string]string{"global": {"free": "3/minute", "premium": "10/minute"}},
+		Entities:   []demoEntity{{Name: "alice"}, {Name: "bob", Tier: "premium"}},
+		Steps: []demoStep{
+			{Name: "burst", Requests: 5},
+			{Name: "search", Entities: []string{"bob"}, Path: "/api/search", Requests: 3},
+		},
+	}
+	if err := scenario.validate(); err != nil {
+		t.Fatalf("Scenario is invalid: %v", err)
+	}
+
+	limiter, err := scenario.configure(ratelimit.New().ScopeFunc(ratelimit.ExtractScope)).Build()
+	if err != nil {
+		t.Fatalf("Failed to build limiter: %v", err)
+	}
+	defer limiter.Close()
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+	server := httptest.NewServer(limiter.For(ratelimit.HTTP).(func(http.Handler) http.Handler)(ok))
+	defer server.Close()
+
+	var decisions []demoDecision
+	tallies := runScenario(context.Background(), server.Client(), server.URL, scenario, func(d demoDecision) {
+		decisions = append(decisions, d)
+	})
+
+	if len(decisions) != 13 {
+		t.Fatalf("Expected 13 decisions, got %d", len(decisions))
+	}
+	if first := decisions[0]; first.Entity != "free:alice" || first.Status != http.StatusOK || first.Remaining != "2" {
+		t.Errorf("Unexpected first decision: %+v", first)
+	}
+	want := []demoTally{
+		{Entity: "free:alice", Allowed: 3, Denied: 2},
+		{Entity: "premium:bob", Allowed: 7, Denied: 1},
+	}
+	for i, tally := range tallies {
+		if tally != want[i] {
+			t.Errorf("Expected tally %+v, got %+v", want[i], tally)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+// cmd/gorly-ops/inspect.go - Algorithm state inspection for a single entity
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	ratelimit "github.com/itsatony/gorly"
+)
+
+func handleInspectEntity(args []string) {
+	fs := flag.NewFlagSet("inspect-entity", flag.ExitOnError)
+	entity := fs.String("entity", "", "Entity to inspect (required)")
+	scope := fs.String("scope", "global", "Scope to inspect")
+	limit := fs.String("limit", "10/minute", "Rate limit configured for the scope")
+	redisAddr := fs.String("redis", "", "Redis address (optional; memory state starts empty)")
+	algorithm := fs.String("algorithm", "token_bucket", "Algorithm to use")
+	format := fs.String("format", "table", "Output format: json, table")
+
+	fs.Parse(args)
+
+	if *entity == "" {
+		fmt.Println("Error: --entity is required")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	builder := ratelimit.New().Limit(*scope, *limit).Algorithm(*algorithm)
+	if *redisAddr != "" {
+		builder = builder.Redis(*redisAddr)
+	}
+	limiter, err := builder.Build()
+	if err != nil {
+		fmt.Printf("Error building limiter: %v\n", err)
+		os.Exit(1)
+	}
+	defer limiter.Close()
+
+	diagnostics, err := limiter.Diagnostics(context.Background(), *entity, *scope)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if *format == "json" {
+		data, _ := json.MarshalIndent(diagnostics, "", "  ")
+		fmt.Println(string(data))
+		return
+	}
+	printDiagnostics(diagnostics)
+}
+
+// printDiagnostics renders diagnostics as a table
+func printDiagnostics(d *ratelimit.Diagnostics) {
+	fmt.Printf("🔎 Algorithm state of %s in scope %s\n", d.Entity, d.Scope)
+	fmt.Printf("   Algorithm: %s\n", d.Algorithm)
+	fmt.Printf("   Limit: %d per %v (%s limit)\n", d.Limit, d.Window, d.Source)
+
+	if b := d.Bucket; b != nil {
+		fmt.Printf("\n   Token bucket (%s):\n", b.BucketKey)
+		fmt.Printf("     Tokens:          %.2f of %d\n", b.CurrentTokens, b.Capacity)
+		fmt.Printf("     Refill rate:     %.3f/s\n", b.RefillRate)
+		fmt.Printf("     Time until full: %v\n", b.TimeUntilFull)
+		fmt.Printf("     Requests:        %d allowed, %d denied (%.1f%% denied)\n", b.TotalRequests, b.DeniedRequests, b.DenialRate)
+	}
+
+	if w := d.SlidingWindow; w != nil {
+		fmt.Printf("\n   Sliding window (%s):\n", w.WindowKey)
+		fmt.Printf("     In window:       %d of %d\n", w.CurrentRequests, w.Limit)
+		fmt.Printf("     Window:          %s - %s\n", w.WindowStart.Format(time.RFC3339), w.WindowEnd.Format(time.RFC3339))
+		fmt.Printf("     Requests:        %d allowed, %d denied\n", w.TotalRequests, w.DeniedRequests)
+		if len(w.RequestDistribution) > 0 {
+			fmt.Printf("     Distribution:    %v (oldest to newest)\n", w.RequestDistribution)
+		}
+	}
+
+	if p := d.Pattern; p != nil && p.TotalRequests > 1 {
+		fmt.Printf("\n   Request pattern:\n")
+		fmt.Printf("     Rate:            %.2f/s\n", p.RequestRate)
+		fmt.Printf("     Interval:        avg %v, min %v, max %v\n", p.AverageInterval, p.MinInterval, p.MaxInterval)
+		fmt.Printf("     Bursts:          %d\n", p.BurstCount)
+	}
+}
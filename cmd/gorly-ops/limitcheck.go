@@ -0,0 +1,203 @@
+// cmd/gorly-ops/limitcheck.go - Build-time validation of limit strings in Go source
+package main
+
+import (
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	ratelimit "github.com/itsatony/gorly"
+)
+
+// gorlyModule is the import path prefix of the packages whose calls take limit strings
+const gorlyModule = "github.com/itsatony/gorly"
+
+// limitArgs maps the functions and methods taking limit strings to the index of the
+// argument holding the limit
+var limitArgs = map[string]int{
+	"IPLimit":        0,
+	"APIKeyLimit":    0,
+	"UserLimit":      0,
+	"PreAuthLimit":   0,
+	"ParseLimit":     0,
+	"MustParseLimit": 0,
+	"Limit":          1,
+	"Override":       2,
+	"OverrideUntil":  2,
+}
+
+// limitMapArgs maps the functions and methods taking a map of limit strings to the index
+// of the map argument
+var limitMapArgs = map[string]int{
+	"PathLimit":  0,
+	"TierLimit":  0,
+	"TierLimits": 0,
+}
+
+// limitProblem is an invalid limit string found in source
+type limitProblem struct {
+	Pos   token.Position
+	Limit string
+	Err   error
+}
+
+func (p limitProblem) String() string {
+	return fmt.Sprintf("%s: invalid limit %q: %v", p.Pos, p.Limit, p.Err)
+}
+
+func handleLintLimits(args []string) {
+	fs := flag.NewFlagSet("lint-limits", flag.ExitOnError)
+	tests := fs.Bool("tests", false, "Also check _test.go files")
+	fs.Parse(args)
+
+	patterns := fs.Args()
+	if len(patterns) == 0 {
+		// go:generate runs in the directory of the file naming the command
+		patterns = []string{"."}
+	}
+
+	var problems []limitProblem
+	files := 0
+	for _, pattern := range patterns {
+		found, checked, err := lintLimitDir(pattern, *tests)
+		if err != nil {
+			fmt.Printf("❌ Failed to check %s: %v\n", pattern, err)
+			os.Exit(1)
+		}
+		problems = append(problems, found...)
+		files += checked
+	}
+
+	for _, p := range problems {
+		fmt.Fprintln(os.Stderr, p)
+	}
+	if len(problems) > 0 {
+		fmt.Fprintf(os.Stderr, "❌ %d invalid limit string(s) in %d file(s)\n", len(problems), files)
+		os.Exit(1)
+	}
+	fmt.Printf("✅ Limit strings in %d file(s) are valid\n", files)
+}
+
+// lintLimitDir checks the Go files of a directory, or of a directory tree when the pattern
+// ends in "/...", and returns the problems found and the number of files importing gorly
+func lintLimitDir(pattern string, tests bool) ([]limitProblem, int, error) {
+	root, recursive := strings.CutSuffix(pattern, "...")
+	root = filepath.Clean(strings.TrimSuffix(root, "/"))
+	if root == "" {
+		root = "."
+	}
+
+	var problems []limitProblem
+	files := 0
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path == root {
+				return nil
+			}
+			name := d.Name()
+			if !recursive || name == "vendor" || name == "testdata" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(path, ".go") || (!tests && strings.HasSuffix(path, "_test.go")) {
+			return nil
+		}
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		found, imports, err := lintLimitSource(path, src)
+		if err != nil {
+			return err
+		}
+		if imports {
+			files++
+		}
+		problems = append(problems, found...)
+		return nil
+	})
+	return problems, files, err
+}
+
+// lintLimitSource checks the literal limit strings passed to gorly in a Go file. Files not
+// importing gorly are skipped, since calls such as Limit are common to many packages.
+func lintLimitSource(filename string, src []byte) ([]limitProblem, bool, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filename, src, parser.SkipObjectResolution)
+	if err != nil {
+		return nil, false, err
+	}
+	if !importsGorly(file) {
+		return nil, false, nil
+	}
+
+	var problems []limitProblem
+	check := func(expr ast.Expr) {
+		lit, ok := expr.(*ast.BasicLit)
+		if !ok || lit.Kind != token.STRING {
+			return
+		}
+		limit, err := strconv.Unquote(lit.Value)
+		if err != nil {
+			return
+		}
+		if _, err := ratelimit.ParseLimit(limit); err != nil {
+			problems = append(problems, limitProblem{Pos: fset.Position(lit.Pos()), Limit: limit, Err: err})
+		}
+	}
+
+	ast.Inspect(file, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+		name := calleeName(call.Fun)
+		if i, ok := limitArgs[name]; ok && i < len(call.Args) {
+			check(call.Args[i])
+		}
+		if i, ok := limitMapArgs[name]; ok && i < len(call.Args) {
+			if m, ok := call.Args[i].(*ast.CompositeLit); ok {
+				for _, elt := range m.Elts {
+					if kv, ok := elt.(*ast.KeyValueExpr); ok {
+						check(kv.Value)
+					}
+				}
+			}
+		}
+		return true
+	})
+	return problems, true, nil
+}
+
+// importsGorly reports whether a file imports a package of the gorly module
+func importsGorly(file *ast.File) bool {
+	for _, spec := range file.Imports {
+		path, err := strconv.Unquote(spec.Path.Value)
+		if err == nil && (path == gorlyModule || strings.HasPrefix(path, gorlyModule+"/")) {
+			return true
+		}
+	}
+	return false
+}
+
+// calleeName returns the name of the called function or method
+func calleeName(fun ast.Expr) string {
+	switch f := fun.(type) {
+	case *ast.Ident:
+		return f.Name
+	case *ast.SelectorExpr:
+		return f.Sel.Name
+	}
+	return ""
+}
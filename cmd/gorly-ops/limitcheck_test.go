@@ -0,0 +1,102 @@
+// cmd/gorly-ops/limitcheck_test.go
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const limitSource = `package app
+
+import (
+	"time"
+
+	ratelimit "github.com/itsatony/gorly"
+)
+
+var (
+	ip    = ratelimit.IPLimit("10/minute")
+	bad   = ratelimit.IPLimit("10 per minute")
+	paths = ratelimit.PathLimit(map[string]string{"/upload": "5/minute", "/search": "50/fortnight"})
+	dyn   = ratelimit.IPLimit(limitFromEnv())
+)
+
+func build() {
+	ratelimit.New().
+		Limit("global", "100/minute").
+		Limit("search", "100/mintue").
+		OverrideUntil("acme", "search", "500/hour", time.Now()).
+		Build()
+}
+`
+
+func TestLintLimitSource(t *testing.T) {
+	problems, imports, err := lintLimitSource("app.go", []byte(limitSource))
+	if err != nil {
+		t.Fatalf("Failed to check: %v", err)
+	}
+	if !imports {
+		t.Fatal("Expected the gorly import to be found")
+	}
+
+	want := []struct {
+		line  int
+		limit string
+	}{
+		{11, "10 per minute"},
+		{12, "50/fortnight"},
+		{19, "100/mintue"},
+	}
+	if len(problems) != len(want) {
+		t.Fatalf("Expected %d problems, got %v", len(want), problems)
+	}
+	for i, w := range want {
+		if p := problems[i]; p.Pos.Line != w.line || p.Limit != w.limit {
+			t.Errorf("Expected %q on line %d, got %s", w.limit, w.line, p)
+		}
+	}
+	if s := problems[0].String(); !strings.HasPrefix(s, "app.go:11:") {
+		t.Errorf("Expected the position first, got %s", s)
+	}
+}
+
+func TestLintLimitSourceSkipsOtherPackages(t *testing.T) {
+	src := `package app
+
+import "example.com/pager"
+
+var page = pager.New().Limit("rows", "all")
+`
+	problems, imports, err := lintLimitSource("app.go", []byte(src))
+	if err != nil || imports || len(problems) != 0 {
+		t.Errorf("Expected files without gorly to be skipped, got %v, %v, %v", problems, imports, err)
+	}
+}
+
+func TestLintLimitDir(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, src string) {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("app.go", limitSource)
+	write("app_test.go", limitSource)
+	write("sub/sub.go", limitSource)
+	write("testdata/bad.go", limitSource)
+
+	problems, files, err := lintLimitDir(dir, false)
+	if err != nil || files != 1 || len(problems) != 3 {
+		t.Errorf("Expected the directory alone, got %d problems in %d files (%v)", len(problems), files, err)
+	}
+	problems, files, err = lintLimitDir(dir+"/...", true)
+	if err != nil || files != 3 || len(problems) != 9 {
+		t.Errorf("Expected the tree with tests but without testdata, got %d problems in %d files (%v)", len(problems), files, err)
+	}
+}
@@ -6,8 +6,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"os"
+	"os/signal"
 	"time"
 
 	"flag"
@@ -46,6 +48,16 @@ func main() {
 		handleServer(args)
 	case "validate":
 		handleValidate(args)
+	case "soak":
+		handleSoak(args)
+	case "inspect-entity":
+		handleInspectEntity(args)
+	case "overrides":
+		handleOverrides(args)
+	case "benchcheck":
+		handleBenchcheck(args)
+	case "lint-limits":
+		handleLintLimits(args)
 	case "version":
 		versionInfo := ratelimit.GetVersionInfo()
 		fmt.Print(versionInfo.Banner())
@@ -74,6 +86,11 @@ Commands:
   config     Configuration operations
   server     Start demo server with rate limiting
   validate   Validate rate limiting configuration
+  soak       Run sustained traffic and flag resource growth (leak detection)
+  inspect-entity  Show the algorithm state behind an entity's limit
+  overrides  Export or import entity overrides as CSV or JSON
+  benchcheck Compare hot path benchmarks against a baseline (release gate)
+  lint-limits  Check the literal limit strings in Go source (go:generate)
   version    Show version information
   help       Show this help message
 
@@ -85,7 +102,14 @@ Examples:
   gorly-ops stats --format json
   gorly-ops monitor --port 8080
   gorly-ops config validate --file config.json
+  gorly-ops validate --algorithm fixed_window --alignment first_request
   gorly-ops server --preset api-gateway --port 8080
+  gorly-ops server --scenario cmd/gorly-ops/scenarios/tiers.yaml
+  gorly-ops soak --duration 2h --rps 500
+  gorly-ops inspect-entity --entity "user123" --scope "global" --redis "localhost:6379"
+  gorly-ops overrides import --file overrides.csv --url http://localhost:8080/admin/overrides
+  gorly-ops benchcheck --baseline baseline.json --threshold 10 --redis "localhost:6379"
+  gorly-ops lint-limits ./...
 
 Global Options:
   --redis     Redis connection string (default: memory)
@@ -384,6 +408,7 @@ func handleConfig(args []string) {
 	case "validate":
 		fs := flag.NewFlagSet("config validate", flag.ExitOnError)
 		file := fs.String("file", "", "Configuration file to validate")
+		strict := fs.Bool("strict", true, "Reject unknown keys")
 
 		fs.Parse(subargs)
 
@@ -393,7 +418,10 @@ func handleConfig(args []string) {
 		}
 
 		fmt.Printf("Validating configuration file: %s\n", *file)
-		// In a real implementation, this would read and validate the file
+		if err := validateConfigFile(*file, *strict); err != nil {
+			fmt.Printf("❌ %v\n", err)
+			os.Exit(1)
+		}
 		fmt.Printf("✅ Configuration is valid\n")
 
 	case "generate":
@@ -431,11 +459,12 @@ func handleServer(args []string) {
 	port := fs.Int("port", 8080, "Server port")
 	preset := fs.String("preset", "", "Preset configuration: api-gateway, saas-app, public-api")
 	redisAddr := fs.String("redis", "", "Redis address")
+	scenarioFile := fs.String("scenario", "", "Scenario file to play against the server (YAML)")
+	exit := fs.Bool("exit", false, "Stop the server once the scenario has played")
 
 	fs.Parse(args)
 
-	var limiter ratelimit.Limiter
-	var err error
+	var builder *ratelimit.Builder
 
 	// Create limiter based on preset or custom config
 	if *preset != "" {
@@ -443,58 +472,42 @@ func handleServer(args []string) {
 
 		switch *preset {
 		case "api-gateway":
-			builder := ratelimit.APIGateway()
-			if *redisAddr != "" {
-				builder = builder.Redis(*redisAddr)
-			}
-			limiter, err = builder.Build()
-			if err != nil {
-				fmt.Printf("Error building limiter: %v\n", err)
-				os.Exit(1)
-			}
-
+			builder = ratelimit.APIGateway()
 		case "saas-app":
-			builder := ratelimit.SaaSApp()
-			if *redisAddr != "" {
-				builder = builder.Redis(*redisAddr)
-			}
-			limiter, err = builder.Build()
-			if err != nil {
-				fmt.Printf("Error building limiter: %v\n", err)
-				os.Exit(1)
-			}
-
+			builder = ratelimit.SaaSApp()
 		case "public-api":
-			builder := ratelimit.PublicAPI()
-			if *redisAddr != "" {
-				builder = builder.Redis(*redisAddr)
-			}
-			limiter, err = builder.Build()
-			if err != nil {
-				fmt.Printf("Error building limiter: %v\n", err)
-				os.Exit(1)
-			}
-
+			builder = ratelimit.PublicAPI()
 		default:
 			fmt.Printf("Unknown preset: %s\n", *preset)
 			os.Exit(1)
 		}
 	} else {
 		// Default configuration
-		builder := ratelimit.New().
+		builder = ratelimit.New().
+			ScopeFunc(ratelimit.ExtractScope).
 			Limit("global", "100/minute").
 			Limit("upload", "10/minute").
 			Limit("search", "50/minute")
+	}
+	if *redisAddr != "" {
+		builder = builder.Redis(*redisAddr)
+	}
 
-		if *redisAddr != "" {
-			builder = builder.Redis(*redisAddr)
-		}
-
-		limiter, err = builder.Build()
-		if err != nil {
-			fmt.Printf("Error building limiter: %v\n", err)
+	// A scenario identifies its entities by header and may bring its own limits
+	var scenario *demoScenario
+	if *scenarioFile != "" {
+		var err error
+		if scenario, err = loadScenario(*scenarioFile); err != nil {
+			fmt.Printf("Error: %v\n", err)
 			os.Exit(1)
 		}
+		builder = scenario.configure(builder)
+	}
+
+	limiter, err := builder.Build()
+	if err != nil {
+		fmt.Printf("Error building limiter: %v\n", err)
+		os.Exit(1)
 	}
 
 	// Create demo server
@@ -551,6 +564,12 @@ func handleServer(args []string) {
 	rateLimitedMux.Handle("/api/", apiHandler)
 	rateLimitedMux.Handle("/", mux)
 
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", *port))
+	if err != nil {
+		fmt.Printf("Error starting server: %v\n", err)
+		os.Exit(1)
+	}
+
 	fmt.Printf("🚀 Demo server starting on port %d\n", *port)
 	fmt.Printf("Endpoints:\n")
 	fmt.Printf("   http://localhost:%d/ (info)\n", *port)
@@ -559,18 +578,38 @@ func handleServer(args []string) {
 	fmt.Printf("   http://localhost:%d/api/search (rate limited)\n", *port)
 	fmt.Printf("   http://localhost:%d/health\n", *port)
 
-	log.Fatal(http.ListenAndServe(fmt.Sprintf(":%d", *port), rateLimitedMux))
+	if scenario == nil {
+		log.Fatal(http.Serve(listener, rateLimitedMux))
+	}
+
+	served := make(chan error, 1)
+	go func() { served <- http.Serve(listener, rateLimitedMux) }()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	playScenario(ctx, fmt.Sprintf("http://127.0.0.1:%d", *port), scenario)
+	if *exit || ctx.Err() != nil {
+		return
+	}
+
+	fmt.Printf("\n🔁 Scenario finished; the server keeps running for your own requests (Ctrl+C stops it)\n")
+	select {
+	case err := <-served:
+		log.Fatal(err)
+	case <-ctx.Done():
+	}
 }
 
 func handleValidate(args []string) {
 	fs := flag.NewFlagSet("validate", flag.ExitOnError)
 	limit := fs.String("limit", "", "Limit string to validate (e.g., '100/minute')")
 	algorithm := fs.String("algorithm", "", "Algorithm to validate")
+	alignment := fs.String("alignment", "", "Fixed window alignment to validate: wall_clock or first_request")
 
 	fs.Parse(args)
 
 	if *limit != "" {
-		if _, _, err := ratelimit.ParseLimit(*limit); err != nil {
+		if _, err := ratelimit.ParseLimit(*limit); err != nil {
 			fmt.Printf("❌ Invalid limit format: %v\n", err)
 			os.Exit(1)
 		} else {
@@ -580,16 +619,30 @@ func handleValidate(args []string) {
 
 	if *algorithm != "" {
 		switch *algorithm {
-		case "token_bucket", "sliding_window":
+		case "token_bucket", "sliding_window", "fixed_window":
 			fmt.Printf("✅ Valid algorithm: %s\n", *algorithm)
 		default:
 			fmt.Printf("❌ Invalid algorithm: %s\n", *algorithm)
-			fmt.Printf("   Supported: token_bucket, sliding_window\n")
+			fmt.Printf("   Supported: token_bucket, sliding_window, fixed_window\n")
+			os.Exit(1)
+		}
+	}
+
+	if *alignment != "" {
+		switch ratelimit.WindowAlignment(*alignment) {
+		case ratelimit.AlignWallClock, ratelimit.AlignFirstRequest:
+			fmt.Printf("✅ Valid window alignment: %s\n", *alignment)
+			if *algorithm != "" && *algorithm != "fixed_window" {
+				fmt.Printf("⚠️  Window alignment only applies to fixed_window, not %s\n", *algorithm)
+			}
+		default:
+			fmt.Printf("❌ Invalid window alignment: %s\n", *alignment)
+			fmt.Printf("   Supported: %s, %s\n", ratelimit.AlignWallClock, ratelimit.AlignFirstRequest)
 			os.Exit(1)
 		}
 	}
 
-	if *limit == "" && *algorithm == "" {
-		fmt.Println("Specify --limit and/or --algorithm to validate")
+	if *limit == "" && *algorithm == "" && *alignment == "" {
+		fmt.Println("Specify --limit, --algorithm and/or --alignment to validate")
 	}
 }
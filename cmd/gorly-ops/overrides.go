@@ -0,0 +1,273 @@
+// cmd/gorly-ops/overrides.go - Import and export of entity overrides as CSV or JSON
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	ratelimit "github.com/itsatony/gorly"
+)
+
+// overridesTarget is where overrides are read from or applied to: the admin API of a
+// running limiter or the JSON file watched by a hot-reload source
+type overridesTarget struct {
+	url    string // OverridesHandler endpoint
+	config string // HotReloadConfig file
+	token  string // Bearer token for the admin API
+	client *http.Client
+}
+
+func handleOverrides(args []string) {
+	if len(args) == 0 {
+		fmt.Println("Overrides subcommands: export, import")
+		fmt.Println("  gorly-ops overrides export --file overrides.csv --url http://localhost:8080/admin/overrides")
+		fmt.Println("  gorly-ops overrides import --file overrides.csv --config hotreload.json")
+		return
+	}
+
+	subcommand := args[0]
+	fs := flag.NewFlagSet("overrides "+subcommand, flag.ExitOnError)
+	file := fs.String("file", "", "CSV or JSON file of overrides (required)")
+	format := fs.String("format", "", "File format: csv or json (default: from the file extension)")
+	url := fs.String("url", "", "Admin API endpoint serving ratelimit.OverridesHandler")
+	config := fs.String("config", "", "Hot-reload JSON configuration file")
+	dryRun := fs.Bool("dry-run", false, "Validate the file without applying it (import only)")
+	fs.Parse(args[1:])
+
+	if *file == "" || (*url == "") == (*config == "") {
+		fmt.Println("Error: --file and exactly one of --url or --config are required")
+		fs.Usage()
+		os.Exit(1)
+	}
+	target := &overridesTarget{
+		url:    *url,
+		config: *config,
+		token:  os.Getenv("GORLY_ADMIN_TOKEN"), // Kept out of process listings
+		client: &http.Client{Timeout: 30 * time.Second},
+	}
+	fileFormat := overridesFormat(*file, *format)
+
+	switch subcommand {
+	case "export":
+		overrides, err := target.read()
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		if err := writeOverridesFile(*file, fileFormat, overrides); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("✅ Exported %d overrides to %s\n", len(overrides), *file)
+
+	case "import":
+		overrides, err := readOverridesFile(*file, fileFormat)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		if err := validateOverrides(overrides); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		if *dryRun {
+			fmt.Printf("✅ %d overrides in %s are valid\n", len(overrides), *file)
+			return
+		}
+		if err := target.apply(overrides); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("✅ Imported %d overrides from %s\n", len(overrides), *file)
+
+	default:
+		fmt.Printf("Unknown overrides subcommand: %s\n", subcommand)
+		os.Exit(1)
+	}
+}
+
+// overridesFormat returns the explicit format, or the one the file extension implies
+func overridesFormat(file, format string) string {
+	if format != "" {
+		return strings.ToLower(format)
+	}
+	if strings.EqualFold(filepath.Ext(file), ".json") {
+		return "json"
+	}
+	return "csv"
+}
+
+// readOverridesFile reads an override list in the given format
+func readOverridesFile(file, format string) ([]ratelimit.EntityOverride, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	if format == "json" {
+		return ratelimit.ReadOverridesJSON(f)
+	}
+	return ratelimit.ReadOverridesCSV(f)
+}
+
+// writeOverridesFile writes an override list in the given format
+func writeOverridesFile(file, format string, overrides []ratelimit.EntityOverride) error {
+	var buf bytes.Buffer
+	var err error
+	if format == "json" {
+		err = ratelimit.WriteOverridesJSON(&buf, overrides)
+	} else {
+		err = ratelimit.WriteOverridesCSV(&buf, overrides)
+	}
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(file, buf.Bytes(), 0o644)
+}
+
+// validateOverrides checks every override before anything is applied, so a typo in
+// one row of a spreadsheet does not leave a limiter with half of the list
+func validateOverrides(overrides []ratelimit.EntityOverride) error {
+	seen := make(map[string]int, len(overrides))
+	for i, o := range overrides {
+		if o.Entity == "" || o.Scope == "" {
+			return fmt.Errorf("override %d: entity and scope are required", i+1)
+		}
+		if _, err := ratelimit.ParseLimit(o.Limit); err != nil {
+			return fmt.Errorf("override %d (%s in %s): invalid limit %q: %v", i+1, o.Entity, o.Scope, o.Limit, err)
+		}
+		key := o.Entity + "\x00" + o.Scope
+		if first, ok := seen[key]; ok {
+			return fmt.Errorf("override %d duplicates override %d (%s in %s)", i+1, first, o.Entity, o.Scope)
+		}
+		seen[key] = i + 1
+	}
+	return nil
+}
+
+// read returns the overrides of the target
+func (t *overridesTarget) read() ([]ratelimit.EntityOverride, error) {
+	if t.config != "" {
+		var config ratelimit.HotReloadConfig
+		data, err := os.ReadFile(t.config)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(data, &config); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", t.config, err)
+		}
+		return config.Overrides, nil
+	}
+
+	resp, err := t.request(http.MethodGet, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return ratelimit.ReadOverridesJSON(resp.Body)
+}
+
+// apply replaces the overrides of the target
+func (t *overridesTarget) apply(overrides []ratelimit.EntityOverride) error {
+	if t.config != "" {
+		return writeConfigOverrides(t.config, overrides)
+	}
+
+	var body bytes.Buffer
+	if err := ratelimit.WriteOverridesJSON(&body, overrides); err != nil {
+		return err
+	}
+	resp, err := t.request(http.MethodPut, &body)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// request calls the admin API and fails on non-2xx responses
+func (t *overridesTarget) request(method string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequest(method, t.url, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if t.token != "" {
+		req.Header.Set("Authorization", "Bearer "+t.token)
+	}
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("admin API request failed: %w", err)
+	}
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		resp.Body.Close()
+		return nil, fmt.Errorf("admin API returned %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return resp, nil
+}
+
+// writeConfigOverrides replaces the overrides in a hot-reload configuration file. Other
+// fields are kept as they are, and the file is replaced atomically so a watching source
+// never reads it half-written.
+func writeConfigOverrides(file string, overrides []ratelimit.EntityOverride) error {
+	info, err := os.Stat(file)
+	if err != nil {
+		return err
+	}
+	fields := make(map[string]json.RawMessage)
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", file, err)
+	}
+
+	if overrides == nil {
+		overrides = []ratelimit.EntityOverride{}
+	}
+	if fields["overrides"], err = json.Marshal(overrides); err != nil {
+		return err
+	}
+	if fields["updated_at"], err = json.Marshal(time.Now()); err != nil {
+		return err
+	}
+	if fields["updated_by"], err = json.Marshal("gorly-ops overrides import"); err != nil {
+		return err
+	}
+	data, err = json.MarshalIndent(fields, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(file), ".overrides-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), info.Mode().Perm()); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), file)
+}
@@ -0,0 +1,73 @@
+// cmd/gorly-ops/overrides_test.go
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	ratelimit "github.com/itsatony/gorly"
+)
+
+func TestOverridesConfigFile(t *testing.T) {
+	dir := t.TempDir()
+	config := filepath.Join(dir, "hotreload.json")
+	if err := os.WriteFile(config, []byte(`{"limits":{"global":"100/minute"},"custom":"kept"}`), 0o640); err != nil {
+		t.Fatal(err)
+	}
+	csvFile := filepath.Join(dir, "overrides.csv")
+	if err := os.WriteFile(csvFile, []byte("entity,scope,limit,expiry\npartner:acme,global,5000/minute,2030-01-01T00:00:00Z\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	overrides, err := readOverridesFile(csvFile, overridesFormat(csvFile, ""))
+	if err != nil || validateOverrides(overrides) != nil {
+		t.Fatalf("Failed to read overrides: %+v (%v)", overrides, err)
+	}
+	target := &overridesTarget{config: config}
+	if err := target.apply(overrides); err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+
+	var fields map[string]interface{}
+	data, _ := os.ReadFile(config)
+	if err := json.Unmarshal(data, &fields); err != nil || fields["custom"] != "kept" {
+		t.Errorf("Expected unrelated fields to be kept, got %s", data)
+	}
+	if info, _ := os.Stat(config); info.Mode().Perm() != 0o640 {
+		t.Errorf("Expected the file mode to be kept, got %v", info.Mode().Perm())
+	}
+
+	exported, err := target.read()
+	if err != nil || len(exported) != 1 || exported[0].Limit != "5000/minute" || exported[0].ExpiresAt.IsZero() {
+		t.Errorf("Expected the imported override, got %+v (%v)", exported, err)
+	}
+}
+
+func TestOverridesAdminAPI(t *testing.T) {
+	limiter, err := ratelimit.New().Limit("global", "10/minute").Build()
+	if err != nil {
+		t.Fatalf("Failed to build limiter: %v", err)
+	}
+	defer limiter.Close()
+	server := httptest.NewServer(ratelimit.OverridesHandler(limiter))
+	defer server.Close()
+
+	target := &overridesTarget{url: server.URL, client: http.DefaultClient}
+	overrides := []ratelimit.EntityOverride{{Entity: "partner:acme", Scope: "global", Limit: "5000/minute"}}
+	if err := target.apply(overrides); err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	exported, err := target.read()
+	if err != nil || len(exported) != 1 || exported[0] != overrides[0] {
+		t.Errorf("Expected the imported override, got %+v (%v)", exported, err)
+	}
+
+	duplicated := append(overrides, overrides[0])
+	if err := validateOverrides(duplicated); err == nil {
+		t.Error("Expected duplicate overrides to be rejected")
+	}
+}
@@ -0,0 +1,341 @@
+// cmd/gorly-ops/soak.go - Soak testing with resource growth detection
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	ratelimit "github.com/itsatony/gorly"
+)
+
+// soakSample is one measurement of the resources held during a soak run
+type soakSample struct {
+	Elapsed      time.Duration `json:"elapsed"`
+	Requests     int64         `json:"requests"`
+	HeapBytes    uint64        `json:"heap_bytes"`
+	HeapObjects  uint64        `json:"heap_objects"`
+	Goroutines   int           `json:"goroutines"`
+	StoreKeys    int64         `json:"store_keys"`
+	MetricSeries int           `json:"metric_series"`
+}
+
+// soakFinding is the growth verdict for one sampled resource
+type soakFinding struct {
+	Resource string  `json:"resource"`
+	First    float64 `json:"first"`
+	Last     float64 `json:"last"`
+	Growth   float64 `json:"growth"` // Relative growth after warmup, e.g. 0.25 for +25%
+	Rising   float64 `json:"rising"` // Share of sample steps that increased
+	Leak     bool    `json:"leak"`
+	Note     string  `json:"note,omitempty"`
+}
+
+// soakReport is the outcome of a soak run
+type soakReport struct {
+	Duration time.Duration `json:"duration"`
+	Requests int64         `json:"requests"`
+	Allowed  int64         `json:"allowed"`
+	Denied   int64         `json:"denied"`
+	Errors   int64         `json:"errors"`
+	Dropped  int64         `json:"dropped"`
+	Samples  []soakSample  `json:"samples"`
+	Findings []soakFinding `json:"findings"`
+}
+
+// Growth detection thresholds
+const (
+	soakWarmupShare = 0.2 // Leading share of samples ignored while caches and pools fill
+	soakMinSamples  = 5   // Samples needed after warmup to judge growth
+	soakRisingShare = 0.7 // Share of increasing steps that makes growth monotonic
+)
+
+func handleSoak(args []string) {
+	fs := flag.NewFlagSet("soak", flag.ExitOnError)
+	duration := fs.Duration("duration", 10*time.Minute, "How long to run traffic")
+	rps := fs.Int("rps", 500, "Requests per second")
+	entities := fs.Int("entities", 1000, "Distinct entities cycled through")
+	scope := fs.String("scope", "global", "Scope to check")
+	limit := fs.String("limit", "100/minute", "Rate limit per entity")
+	algorithm := fs.String("algorithm", "token_bucket", "Algorithm to use")
+	redisAddr := fs.String("redis", "", "Redis address (optional)")
+	workers := fs.Int("workers", 8, "Concurrent workers issuing checks")
+	interval := fs.Duration("sample", 10*time.Second, "Interval between resource samples")
+	tolerance := fs.Float64("tolerance", 0.1, "Relative growth after warmup that counts as a leak")
+	format := fs.String("format", "table", "Output format: json, table")
+
+	fs.Parse(args)
+
+	if *rps <= 0 || *entities <= 0 || *workers <= 0 || *interval <= 0 {
+		fmt.Println("Error: --rps, --entities, --workers and --sample must be positive")
+		os.Exit(1)
+	}
+
+	builder := ratelimit.New().Limit(*scope, *limit).Algorithm(*algorithm)
+	if *redisAddr != "" {
+		builder = builder.Redis(*redisAddr)
+	}
+	baseLimiter, err := builder.Build()
+	if err != nil {
+		fmt.Printf("Error building limiter: %v\n", err)
+		os.Exit(1)
+	}
+	config := ratelimit.DefaultObservabilityConfig()
+	config.EnableLogging = false
+	limiter := ratelimit.NewObservableLimiter(baseLimiter, config)
+	defer limiter.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), *duration)
+	defer cancel()
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
+	defer stop()
+
+	if *format != "json" {
+		fmt.Printf("🔥 Soak test for %v at %d RPS over %d entities (Ctrl+C stops early)\n", *duration, *rps, *entities)
+		fmt.Printf("   %-10s %-10s %-12s %-12s %-10s %-10s %-8s\n",
+			"ELAPSED", "REQUESTS", "HEAP", "OBJECTS", "GOROUTINES", "KEYS", "SERIES")
+	}
+
+	report := runSoak(ctx, limiter, soakOptions{
+		rps:      *rps,
+		entities: *entities,
+		scope:    *scope,
+		workers:  *workers,
+		interval: *interval,
+		onSample: func(s soakSample) {
+			if *format != "json" {
+				fmt.Printf("   %-10v %-10d %-12s %-12d %-10d %-10d %-8d\n",
+					s.Elapsed.Round(time.Second), s.Requests, formatBytes(s.HeapBytes),
+					s.HeapObjects, s.Goroutines, s.StoreKeys, s.MetricSeries)
+			}
+		},
+	})
+	report.Findings = analyzeSoak(report.Samples, *tolerance)
+
+	leaks := 0
+	for _, finding := range report.Findings {
+		if finding.Leak {
+			leaks++
+		}
+	}
+
+	if *format == "json" {
+		data, _ := json.MarshalIndent(report, "", "  ")
+		fmt.Println(string(data))
+	} else {
+		fmt.Printf("\n📊 Soak Results:\n")
+		fmt.Printf("   Duration: %v, Requests: %d (%d allowed, %d denied)\n",
+			report.Duration.Round(time.Second), report.Requests, report.Allowed, report.Denied)
+		fmt.Printf("   Errors: %d, Dropped (limiter too slow for the rate): %d\n", report.Errors, report.Dropped)
+		fmt.Printf("\n   Resource growth after warmup:\n")
+		for _, finding := range report.Findings {
+			verdict := "✅ stable"
+			if finding.Leak {
+				verdict = "❌ monotonic growth"
+			}
+			if finding.Note != "" {
+				verdict = "➖ " + finding.Note
+			}
+			fmt.Printf("     %-14s %12.0f → %-12.0f %+7.1f%%  rising %3.0f%%  %s\n",
+				finding.Resource, finding.First, finding.Last, finding.Growth*100, finding.Rising*100, verdict)
+		}
+	}
+
+	if leaks > 0 {
+		os.Exit(1)
+	}
+}
+
+// soakOptions configures runSoak
+type soakOptions struct {
+	rps      int
+	entities int
+	scope    string
+	workers  int
+	interval time.Duration
+	onSample func(soakSample)
+}
+
+// runSoak sends paced traffic until ctx is done, sampling resources on every interval
+func runSoak(ctx context.Context, limiter *ratelimit.ObservableLimiter, opts soakOptions) *soakReport {
+	report := &soakReport{}
+	var requests, allowed, denied, errs, dropped atomic.Int64
+
+	jobs := make(chan int, opts.workers*4)
+	var wg sync.WaitGroup
+	for i := 0; i < opts.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := range jobs {
+				result, err := limiter.Check(context.Background(), fmt.Sprintf("soak-%d", n%opts.entities), opts.scope)
+				requests.Add(1)
+				switch {
+				case err != nil:
+					errs.Add(1)
+				case result.Allowed:
+					allowed.Add(1)
+				default:
+					denied.Add(1)
+				}
+			}
+		}()
+	}
+
+	start := time.Now()
+	sample := func() {
+		s := takeSoakSample(limiter)
+		s.Elapsed = time.Since(start)
+		s.Requests = requests.Load()
+		report.Samples = append(report.Samples, s)
+		if opts.onSample != nil {
+			opts.onSample(s)
+		}
+	}
+
+	// Pace in 10ms ticks, carrying fractional requests over to the next tick
+	pace := time.NewTicker(10 * time.Millisecond)
+	defer pace.Stop()
+	sampler := time.NewTicker(opts.interval)
+	defer sampler.Stop()
+
+	sample()
+	perTick := float64(opts.rps) / 100
+	var owed float64
+	next := 0
+
+loop:
+	for {
+		select {
+		case <-ctx.Done():
+			break loop
+		case <-sampler.C:
+			sample()
+		case <-pace.C:
+			owed += perTick
+			for ; owed >= 1; owed-- {
+				select {
+				case jobs <- next:
+					next++
+				default:
+					dropped.Add(1)
+				}
+			}
+		}
+	}
+
+	close(jobs)
+	wg.Wait()
+
+	report.Duration = time.Since(start)
+	report.Requests = requests.Load()
+	report.Allowed = allowed.Load()
+	report.Denied = denied.Load()
+	report.Errors = errs.Load()
+	report.Dropped = dropped.Load()
+	return report
+}
+
+// takeSoakSample measures the heap after a collection, goroutines, store keys and metric series
+func takeSoakSample(limiter *ratelimit.ObservableLimiter) soakSample {
+	runtime.GC()
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	s := soakSample{
+		HeapBytes:   mem.HeapAlloc,
+		HeapObjects: mem.HeapObjects,
+		Goroutines:  runtime.NumGoroutine(),
+	}
+	if stats, err := limiter.Stats(context.Background()); err == nil {
+		s.StoreKeys = stats.StoreKeys
+	}
+	for _, value := range limiter.GetMetrics() {
+		if series, ok := value.(map[string]int64); ok {
+			s.MetricSeries += len(series)
+		}
+	}
+	return s
+}
+
+// analyzeSoak flags resources that keep growing after warmup
+func analyzeSoak(samples []soakSample, tolerance float64) []soakFinding {
+	resources := []struct {
+		name  string
+		value func(soakSample) float64
+	}{
+		{"heap_bytes", func(s soakSample) float64 { return float64(s.HeapBytes) }},
+		{"heap_objects", func(s soakSample) float64 { return float64(s.HeapObjects) }},
+		{"goroutines", func(s soakSample) float64 { return float64(s.Goroutines) }},
+		{"store_keys", func(s soakSample) float64 { return float64(s.StoreKeys) }},
+		{"metric_series", func(s soakSample) float64 { return float64(s.MetricSeries) }},
+	}
+
+	findings := make([]soakFinding, 0, len(resources))
+	for _, resource := range resources {
+		values := make([]float64, len(samples))
+		for i, s := range samples {
+			values[i] = resource.value(s)
+		}
+		finding := detectGrowth(values, tolerance)
+		finding.Resource = resource.name
+		findings = append(findings, finding)
+	}
+	return findings
+}
+
+// detectGrowth reports monotonic growth in a series: after skipping warmup, most steps
+// must increase and the series must grow by more than tolerance overall. Sawtooth
+// patterns from garbage collection or key expiry rise and fall, so they pass.
+func detectGrowth(values []float64, tolerance float64) soakFinding {
+	warmup := int(float64(len(values)) * soakWarmupShare)
+	if warmup < 1 {
+		warmup = 1
+	}
+	if len(values)-warmup < soakMinSamples {
+		return soakFinding{Note: "too few samples, run longer"}
+	}
+	values = values[warmup:]
+
+	first, last := values[0], values[len(values)-1]
+	if first == 0 && last == 0 {
+		return soakFinding{Note: "not reported"}
+	}
+
+	rising := 0
+	for i := 1; i < len(values); i++ {
+		if values[i] > values[i-1] {
+			rising++
+		}
+	}
+
+	finding := soakFinding{
+		First:  first,
+		Last:   last,
+		Growth: (last - first) / max(first, 1),
+		Rising: float64(rising) / float64(len(values)-1),
+	}
+	finding.Leak = finding.Growth > tolerance && finding.Rising >= soakRisingShare
+	return finding
+}
+
+// formatBytes renders a byte count with a binary unit
+func formatBytes(n uint64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%dB", n)
+	}
+	div, exp := uint64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,32 @@
+// cmd/gorly-ops/soak_test.go
+package main
+
+import "testing"
+
+func TestDetectGrowth(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []float64
+		leak   bool
+		note   bool
+	}{
+		{"steady growth", []float64{100, 110, 120, 130, 140, 150, 160, 170, 180, 190}, true, false},
+		{"warmup then flat", []float64{10, 100, 100, 101, 100, 100, 101, 100, 100, 100}, false, false},
+		{"sawtooth", []float64{100, 140, 100, 150, 100, 145, 100, 150, 100, 140}, false, false},
+		{"slow growth below tolerance", []float64{1000, 1001, 1002, 1003, 1004, 1005, 1006, 1007}, false, false},
+		{"too few samples", []float64{1, 2, 3}, false, true},
+		{"not reported", []float64{0, 0, 0, 0, 0, 0, 0, 0}, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			finding := detectGrowth(tt.values, 0.1)
+			if finding.Leak != tt.leak {
+				t.Errorf("Expected leak=%t, got %+v", tt.leak, finding)
+			}
+			if (finding.Note != "") != tt.note {
+				t.Errorf("Expected note=%t, got %q", tt.note, finding.Note)
+			}
+		})
+	}
+}
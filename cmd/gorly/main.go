@@ -59,6 +59,7 @@ func printHelp() {
 	fmt.Println("")
 	fmt.Println("  • Token Bucket - Allows bursts up to bucket capacity")
 	fmt.Println("  • Sliding Window - Precise tracking with nanosecond accuracy")
+	fmt.Println("  • Fixed Window - One atomic counter per window for high volume")
 	fmt.Println("  • GCRA - Generic Cell Rate Algorithm (coming soon)")
 	fmt.Println("")
 	fmt.Println("FEATURES:")
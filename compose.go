@@ -0,0 +1,203 @@
+// compose.go - One set of rate limit headers from the decisions of several layers
+package ratelimit
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/itsatony/gorly/client"
+	"github.com/itsatony/gorly/internal/core"
+)
+
+// resultHeaders are the headers WriteResultHeaders writes
+var resultHeaders = []string{
+	"X-RateLimit-Limit",
+	"X-RateLimit-Remaining",
+	"X-RateLimit-Used",
+	"X-RateLimit-Window",
+	"X-RateLimit-Alignment",
+	"X-RateLimit-Grant-Remaining",
+	"X-RateLimit-Reset",
+	"X-RateLimit-Retry-After",
+	core.RetryAfterHeader,
+}
+
+// ComposeResults merges the decisions of limiters that all had to let a request through,
+// such as one at the edge and one in the service, into the result clients should see.
+// The most restrictive result wins: the request is allowed only if every result allows
+// it, a denial reports the longest wait, and an allowed request reports the least
+// remaining quota. Results let through by a fail-open policy have no budget to report
+// and only win when no other result is left. Nil results are skipped; without any,
+// ComposeResults returns nil.
+// Example: merged := ratelimit.ComposeResults(edgeResult, serviceResult)
+func ComposeResults(results ...*LimitResult) *LimitResult {
+	var winner *LimitResult
+	stale := false
+	for _, result := range results {
+		if result == nil {
+			continue
+		}
+		stale = stale || result.Stale
+		if winner == nil || moreRestrictive(result, winner) {
+			winner = result
+		}
+	}
+	if winner == nil {
+		return nil
+	}
+	merged := *winner
+	merged.Stale = stale
+	return &merged
+}
+
+// moreRestrictive reports whether result should be reported instead of current
+func moreRestrictive(result, current *LimitResult) bool {
+	if result.Allowed != current.Allowed {
+		return !result.Allowed
+	}
+	if !result.Allowed {
+		if result.RetryAfter != current.RetryAfter {
+			return result.RetryAfter > current.RetryAfter
+		}
+		return result.ResetTime.After(current.ResetTime)
+	}
+	if result.FailedOpen != current.FailedOpen {
+		return current.FailedOpen
+	}
+	return result.Remaining < current.Remaining
+}
+
+// ResultFromRequest returns the result the middleware stored in the request's context
+// for the handlers behind it, or nil if the request was not checked
+func ResultFromRequest(r *http.Request) *LimitResult {
+	result, ok := r.Context().Value("gorly_result").(*core.CoreResult)
+	if !ok || result == nil {
+		return nil
+	}
+	return limitResult(result)
+}
+
+// ResultFromHeaders reads the result another layer reported in its response headers, in
+// gorly's X-RateLimit-* form or as the IETF RateLimit-* headers. It returns nil when the
+// headers carry no rate limit information.
+// Example: inner := ratelimit.ResultFromHeaders(resp.Header)
+func ResultFromHeaders(h http.Header) *LimitResult {
+	info := client.ParseHeaders(h, time.Now())
+	if !info.Present {
+		return nil
+	}
+	// The middleware sends Retry-After only with denials
+	denied := h.Get("Retry-After") != "" || h.Get("X-RateLimit-Retry-After") != ""
+	result := &LimitResult{
+		Allowed:    !denied,
+		Remaining:  info.Remaining,
+		Limit:      info.Limit,
+		Used:       info.Used,
+		RetryAfter: info.RetryAfter,
+		Window:     info.Window,
+		ResetTime:  info.ResetTime,
+		Alignment:  WindowAlignment(h.Get("X-RateLimit-Alignment")),
+	}
+	if grant, err := strconv.ParseInt(h.Get("X-RateLimit-Grant-Remaining"), 10, 64); err == nil {
+		result.GrantRemaining = grant
+	}
+	return result
+}
+
+// WriteResultHeaders writes the rate limit headers of a result into h under their default
+// names, replacing the values of any other layer. Headers the result has no value for are
+// removed. A nil result removes every rate limit header.
+// Example: ratelimit.WriteResultHeaders(w.Header(), ratelimit.ComposeResults(edge, inner))
+func WriteResultHeaders(h http.Header, result *LimitResult) {
+	for _, name := range resultHeaders {
+		h.Del(name)
+	}
+	if result == nil || result.FailedOpen {
+		return
+	}
+
+	h.Set("X-RateLimit-Limit", strconv.FormatInt(result.Limit, 10))
+	h.Set("X-RateLimit-Remaining", strconv.FormatInt(result.Remaining, 10))
+	h.Set("X-RateLimit-Used", strconv.FormatInt(result.Used, 10))
+	h.Set("X-RateLimit-Window", result.Window.String())
+	if result.Alignment != "" {
+		h.Set("X-RateLimit-Alignment", string(result.Alignment))
+	}
+	if result.GrantRemaining > 0 {
+		h.Set("X-RateLimit-Grant-Remaining", strconv.FormatInt(result.GrantRemaining, 10))
+	}
+	if !result.ResetTime.IsZero() {
+		h.Set("X-RateLimit-Reset", strconv.FormatInt(result.ResetTime.Unix(), 10))
+	}
+	if !result.Allowed {
+		retryAfter := strconv.FormatInt(int64(result.RetryAfter.Seconds()), 10)
+		h.Set("X-RateLimit-Retry-After", retryAfter)
+		h.Set(core.RetryAfterHeader, retryAfter)
+	}
+}
+
+// PropagateResultHeaders wraps the handler behind a limiter's middleware, typically a
+// reverse proxy to a service that runs its own limiter, so clients get one coherent set
+// of rate limit headers. When the handler writes its response, the limit headers it set
+// are composed with the middleware's result and the most restrictive of them is sent.
+// Responses without limit headers of their own keep the middleware's.
+// Example: http.Handle("/", limiter.HTTPMiddleware()(ratelimit.PropagateResultHeaders(proxy)))
+func PropagateResultHeaders(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		outer := ResultFromRequest(r)
+		if outer == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// The middleware already wrote its headers; hide them so the handler's stand alone
+		for _, name := range resultHeaders {
+			w.Header().Del(name)
+		}
+		next.ServeHTTP(&propagatingWriter{ResponseWriter: w, outer: outer}, r)
+	})
+}
+
+// propagatingWriter composes the limit headers of a response with the middleware's result
+// right before the response header is sent
+type propagatingWriter struct {
+	http.ResponseWriter
+	outer   *LimitResult
+	written bool
+}
+
+// compose replaces the response's limit headers with the composed result
+func (pw *propagatingWriter) compose() {
+	if pw.written {
+		return
+	}
+	pw.written = true
+	h := pw.Header()
+	WriteResultHeaders(h, ComposeResults(pw.outer, ResultFromHeaders(h)))
+}
+
+// WriteHeader composes the limit headers before sending the response header
+func (pw *propagatingWriter) WriteHeader(status int) {
+	pw.compose()
+	pw.ResponseWriter.WriteHeader(status)
+}
+
+// Write composes the limit headers if the handler did not call WriteHeader
+func (pw *propagatingWriter) Write(p []byte) (int, error) {
+	pw.compose()
+	return pw.ResponseWriter.Write(p)
+}
+
+// Flush implements http.Flusher for streaming responses
+func (pw *propagatingWriter) Flush() {
+	pw.compose()
+	if flusher, ok := pw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap returns the underlying ResponseWriter for http.ResponseController
+func (pw *propagatingWriter) Unwrap() http.ResponseWriter {
+	return pw.ResponseWriter
+}
@@ -0,0 +1,127 @@
+// compose_test.go
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestComposeResults(t *testing.T) {
+	edge := &LimitResult{Allowed: true, Limit: 100, Remaining: 60, Used: 40, Window: time.Minute}
+	service := &LimitResult{Allowed: true, Limit: 10, Remaining: 3, Used: 7, Window: time.Minute, Stale: true}
+	shortWait := &LimitResult{Limit: 5, Used: 5, RetryAfter: 10 * time.Second}
+	longWait := &LimitResult{Limit: 1000, Used: 1000, RetryAfter: time.Hour}
+	failedOpen := &LimitResult{Allowed: true, Limit: 50, Remaining: 50, FailedOpen: true}
+
+	tests := []struct {
+		name    string
+		results []*LimitResult
+		want    *LimitResult
+	}{
+		{"least remaining", []*LimitResult{edge, service}, service},
+		{"denial wins", []*LimitResult{edge, shortWait, service}, shortWait},
+		{"longest wait", []*LimitResult{shortWait, longWait}, longWait},
+		{"fail open loses", []*LimitResult{failedOpen, edge}, edge},
+		{"nil skipped", []*LimitResult{nil, edge}, edge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ComposeResults(tt.results...)
+			if got == nil || got.Limit != tt.want.Limit || got.Allowed != tt.want.Allowed {
+				t.Errorf("Expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+
+	if merged := ComposeResults(edge, service); merged == service || !merged.Stale {
+		t.Error("Expected a stale copy of the winning result")
+	}
+	if merged := ComposeResults(edge, shortWait); merged.Stale {
+		t.Error("Expected no staleness without a stale result")
+	}
+	if ComposeResults() != nil || ComposeResults(nil) != nil {
+		t.Error("Expected nil without results")
+	}
+}
+
+func TestResultHeadersRoundTrip(t *testing.T) {
+	reset := time.Now().Add(30 * time.Second).Truncate(time.Second)
+	want := &LimitResult{
+		Limit:          10,
+		Used:           10,
+		RetryAfter:     30 * time.Second,
+		Window:         time.Minute,
+		ResetTime:      reset,
+		GrantRemaining: 2,
+		Alignment:      AlignWallClock,
+	}
+	h := http.Header{}
+	h.Set("X-RateLimit-Bandwidth-Limit", "1000")
+	WriteResultHeaders(h, want)
+	if h.Get("Retry-After") != "30" || h.Get("X-RateLimit-Alignment") != "wall_clock" {
+		t.Errorf("Unexpected headers: %v", h)
+	}
+
+	got := ResultFromHeaders(h)
+	if got == nil || got.Allowed || got.Limit != 10 || got.Used != 10 || got.Window != time.Minute ||
+		got.RetryAfter != want.RetryAfter || !got.ResetTime.Equal(reset) || got.GrantRemaining != 2 || got.Alignment != AlignWallClock {
+		t.Errorf("Expected %+v, got %+v", want, got)
+	}
+
+	WriteResultHeaders(h, nil)
+	if len(h) != 1 || ResultFromHeaders(h) != nil {
+		t.Errorf("Expected only unrelated headers to remain, got %v", h)
+	}
+}
+
+func TestPropagateResultHeaders(t *testing.T) {
+	edge, err := New().Limit("global", "100/minute").Build()
+	if err != nil {
+		t.Fatalf("Failed to build limiter: %v", err)
+	}
+	defer edge.Close()
+
+	service := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/limited" {
+			w.Header().Set("X-RateLimit-Limit", "5")
+			w.Header().Set("X-RateLimit-Remaining", "0")
+			w.Header().Set("Retry-After", "20")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		if r.URL.Path == "/tight" {
+			w.Header().Set("X-RateLimit-Limit", "5")
+			w.Header().Set("X-RateLimit-Remaining", "2")
+		}
+		w.Write([]byte("OK"))
+	})
+	handler := edge.HTTPMiddleware()(PropagateResultHeaders(service))
+
+	for _, tt := range []struct {
+		path      string
+		status    int
+		limit     string
+		remaining string
+	}{
+		{"/plain", http.StatusOK, "100", "99"},
+		{"/tight", http.StatusOK, "5", "2"},
+		{"/limited", http.StatusTooManyRequests, "5", "0"},
+	} {
+		req := httptest.NewRequest("GET", tt.path, nil)
+		req.RemoteAddr = "192.168.1.10:1234"
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != tt.status {
+			t.Errorf("%s: expected status %d, got %d", tt.path, tt.status, rec.Code)
+		}
+		if got := rec.Header().Values("X-RateLimit-Limit"); len(got) != 1 || got[0] != tt.limit {
+			t.Errorf("%s: expected one limit of %s, got %v", tt.path, tt.limit, got)
+		}
+		if got := rec.Header().Get("X-RateLimit-Remaining"); got != tt.remaining {
+			t.Errorf("%s: expected %s remaining, got %s", tt.path, tt.remaining, got)
+		}
+	}
+}
@@ -0,0 +1,648 @@
+// composite.go - AND/OR composition of limiters
+package ratelimit
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/itsatony/gorly/internal/core"
+	"github.com/itsatony/gorly/internal/middleware"
+)
+
+// compositeMode decides how the results of composed limiters are combined
+type compositeMode int
+
+const (
+	compositeAll compositeMode = iota // every limiter must allow
+	compositeAny                      // one allowing limiter is enough
+)
+
+// compositeLimiter combines several limiters into one
+type compositeLimiter struct {
+	mode     compositeMode
+	limiters []Limiter
+}
+
+// All combines limiters so a request passes only if every limiter allows it.
+// Limiters are checked in order and each consumes quota; checking stops at the
+// first denial, so later limiters are not charged for denied requests.
+// Example: ratelimit.All(perUser, infrastructure)
+func All(limiters ...Limiter) Limiter {
+	return &compositeLimiter{mode: compositeAll, limiters: limiters}
+}
+
+// Any combines limiters so a request passes if any limiter allows it.
+// Limiters are checked in order and checking stops at the first that allows,
+// so only that limiter (and those that denied before it) are charged.
+// Example: ratelimit.Any(subscriptionQuota, prepaidCredits)
+func Any(limiters ...Limiter) Limiter {
+	return &compositeLimiter{mode: compositeAny, limiters: limiters}
+}
+
+// Check runs the composed limiters and merges their results
+func (c *compositeLimiter) Check(ctx context.Context, entity string, scope ...string) (*LimitResult, error) {
+	return c.combine(func(limiter Limiter) (*LimitResult, error) {
+		return limiter.Check(ctx, entity, scope...)
+	})
+}
+
+// Peek merges the allowance of the composed limiters without consuming quota
+func (c *compositeLimiter) Peek(ctx context.Context, entity string, scope ...string) (*LimitResult, error) {
+	return c.combine(func(limiter Limiter) (*LimitResult, error) {
+		return limiter.Peek(ctx, entity, scope...)
+	})
+}
+
+// Diagnostics collects the diagnostics of every composed limiter
+func (c *compositeLimiter) Diagnostics(ctx context.Context, entity string, scope ...string) (*Diagnostics, error) {
+	entity, scopeName := callTarget(ctx, entity, scope)
+	d := &Diagnostics{Entity: entity, Scope: scopeName, Algorithm: "composite"}
+	for _, limiter := range c.limiters {
+		composed, err := limiter.Diagnostics(ctx, entity, scope...)
+		if err != nil {
+			return nil, err
+		}
+		d.Composed = append(d.Composed, composed)
+	}
+	return d, nil
+}
+
+// Prewarm pre-warms every composed limiter
+func (c *compositeLimiter) Prewarm(ctx context.Context, specs []PrewarmSpec) error {
+	for _, limiter := range c.limiters {
+		if err := limiter.Prewarm(ctx, specs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Grant hands out the grant on every composed limiter, so each of them lets the entity through
+func (c *compositeLimiter) Grant(ctx context.Context, entity, scope string, extra int64, ttl time.Duration) (*Grant, error) {
+	var grant *Grant
+	for _, limiter := range c.limiters {
+		g, err := limiter.Grant(ctx, entity, scope, extra, ttl)
+		if err != nil {
+			return nil, err
+		}
+		if grant == nil {
+			grant = g
+		}
+	}
+	return grant, nil
+}
+
+// RevokeGrant revokes the grant on every composed limiter
+func (c *compositeLimiter) RevokeGrant(ctx context.Context, entity, scope string) error {
+	for _, limiter := range c.limiters {
+		if err := limiter.RevokeGrant(ctx, entity, scope); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GrantStatus returns the grant of the first composed limiter
+func (c *compositeLimiter) GrantStatus(ctx context.Context, entity, scope string) (*Grant, error) {
+	if len(c.limiters) == 0 {
+		return nil, fmt.Errorf("composite limiter has no limiters")
+	}
+	return c.limiters[0].GrantStatus(ctx, entity, scope)
+}
+
+// Forget forgets the entity in every composed limiter
+func (c *compositeLimiter) Forget(ctx context.Context, entity string) (*ForgetRecord, error) {
+	return c.forget(ctx, entity, nil)
+}
+
+// forget forgets the entity in every composed limiter and merges their records. Every
+// limiter is asked even after a failure, so as much state as possible is removed.
+func (c *compositeLimiter) forget(ctx context.Context, entity string, extraScopes []string) (*ForgetRecord, error) {
+	var merged *ForgetRecord
+	var errs []error
+	for _, limiter := range c.limiters {
+		var record *ForgetRecord
+		var err error
+		if forgetter, ok := limiter.(scopedForgetter); ok {
+			record, err = forgetter.forget(ctx, entity, extraScopes)
+		} else {
+			record, err = limiter.Forget(ctx, entity)
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
+		if record == nil {
+			continue
+		}
+		if merged == nil {
+			merged = record
+			continue
+		}
+		merged.Scopes = append(merged.Scopes, record.Scopes...)
+		merged.Keys += record.Keys
+		merged.Cached += record.Cached
+	}
+
+	err := errors.Join(errs...)
+	if merged == nil {
+		if err == nil {
+			err = fmt.Errorf("composite limiter has no limiters")
+		}
+		return nil, err
+	}
+	slices.Sort(merged.Scopes)
+	merged.Scopes = slices.Compact(merged.Scopes)
+	if err != nil {
+		merged.Error = err.Error()
+	}
+	return merged, err
+}
+
+// combine evaluates the composed limiters in order and merges their results
+func (c *compositeLimiter) combine(evaluate func(Limiter) (*LimitResult, error)) (*LimitResult, error) {
+	if len(c.limiters) == 0 {
+		return nil, fmt.Errorf("composite limiter has no limiters")
+	}
+
+	results := make([]*LimitResult, 0, len(c.limiters))
+	for _, limiter := range c.limiters {
+		result, err := evaluate(limiter)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+
+		if c.mode == compositeAll && !result.Allowed {
+			return result, nil
+		}
+		if c.mode == compositeAny && result.Allowed {
+			return result, nil
+		}
+	}
+
+	if c.mode == compositeAll {
+		return mostRestrictive(results), nil
+	}
+	return soonestRetry(results), nil
+}
+
+// Allow reports whether the composed limiters allow the request
+func (c *compositeLimiter) Allow(ctx context.Context, entity string, scope ...string) (bool, error) {
+	result, err := c.Check(ctx, entity, scope...)
+	if err != nil {
+		return false, err
+	}
+	return result.Allowed, nil
+}
+
+// mostRestrictive returns the allowed result with the least remaining quota
+func mostRestrictive(results []*LimitResult) *LimitResult {
+	merged := results[0]
+	for _, result := range results[1:] {
+		if result.Remaining < merged.Remaining {
+			merged = result
+		}
+	}
+	return merged
+}
+
+// soonestRetry returns the denial that can be retried first
+func soonestRetry(results []*LimitResult) *LimitResult {
+	merged := results[0]
+	for _, result := range results[1:] {
+		if result.RetryAfter < merged.RetryAfter {
+			merged = result
+		}
+	}
+	return merged
+}
+
+// Middleware returns net/http middleware that applies the composition to each request
+func (c *compositeLimiter) Middleware() interface{} {
+	return c.httpHandler()
+}
+
+// For returns middleware for a specific framework type. Without a registered adapter,
+// composition is only supported for net/http compatible frameworks.
+func (c *compositeLimiter) For(framework middleware.FrameworkType) interface{} {
+	if mw, ok := adapterFor(c, framework); ok {
+		return mw
+	}
+	switch framework {
+	case middleware.FrameworkHTTP, middleware.FrameworkChi, middleware.FrameworkAuto:
+		return c.httpHandler()
+	default:
+		return nil
+	}
+}
+
+// HTTPMiddleware returns net/http middleware that applies the composition to each request
+func (c *compositeLimiter) HTTPMiddleware() func(http.Handler) http.Handler {
+	return c.httpHandler()
+}
+
+// compositeRecorder captures the response a composed limiter's middleware would write
+type compositeRecorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (cr *compositeRecorder) Header() http.Header { return cr.header }
+
+func (cr *compositeRecorder) Write(b []byte) (int, error) {
+	if cr.status == 0 {
+		cr.status = http.StatusOK
+	}
+	return cr.body.Write(b)
+}
+
+func (cr *compositeRecorder) WriteHeader(status int) {
+	if cr.status == 0 {
+		cr.status = status
+	}
+}
+
+// replay writes the captured response to w
+func (cr *compositeRecorder) replay(w http.ResponseWriter) {
+	copyHeaders(w.Header(), cr.header)
+	status := cr.status
+	if status == 0 {
+		status = http.StatusTooManyRequests
+	}
+	w.WriteHeader(status)
+	w.Write(cr.body.Bytes())
+}
+
+// remaining returns the X-RateLimit-Remaining header a limiter set, or -1 without one
+func (cr *compositeRecorder) remaining() int64 {
+	n, err := strconv.ParseInt(cr.header.Get("X-RateLimit-Remaining"), 10, 64)
+	if err != nil {
+		return -1
+	}
+	return n
+}
+
+// retryAfter returns the Retry-After header a limiter set, or -1 without one
+func (cr *compositeRecorder) retryAfter() int64 {
+	n, err := strconv.ParseInt(cr.header.Get("Retry-After"), 10, 64)
+	if err != nil {
+		return -1
+	}
+	return n
+}
+
+// copyHeaders copies every header value from src to dst, replacing existing values
+func copyHeaders(dst, src http.Header) {
+	for key, values := range src {
+		dst[key] = append([]string(nil), values...)
+	}
+}
+
+// httpHandler runs each composed limiter's own middleware against a recorder,
+// so extractors, scopes and denial responses behave exactly as they would alone
+func (c *compositeLimiter) httpHandler() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var allowed, denied []*compositeRecorder
+			for _, limiter := range c.limiters {
+				handler := limiter.HTTPMiddleware()
+
+				// Children store their results in the request context in place
+				rec := &compositeRecorder{header: make(http.Header)}
+				passed := false
+				handler(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+					passed = true
+				})).ServeHTTP(rec, r)
+
+				if passed {
+					allowed = append(allowed, rec)
+					if c.mode == compositeAny {
+						break
+					}
+					continue
+				}
+				denied = append(denied, rec)
+				if c.mode == compositeAll {
+					break
+				}
+			}
+
+			if (c.mode == compositeAll && len(denied) > 0) || len(allowed) == 0 {
+				denial := denied[0]
+				for _, rec := range denied[1:] {
+					if rec.retryAfter() >= 0 && (denial.retryAfter() < 0 || rec.retryAfter() < denial.retryAfter()) {
+						denial = rec
+					}
+				}
+				denial.replay(w)
+				return
+			}
+
+			// Merge headers from every limiter, letting the most restrictive one win
+			restrictive := allowed[0]
+			for _, rec := range allowed {
+				copyHeaders(w.Header(), rec.header)
+				if rec.remaining() >= 0 && (restrictive.remaining() < 0 || rec.remaining() < restrictive.remaining()) {
+					restrictive = rec
+				}
+			}
+			copyHeaders(w.Header(), restrictive.header)
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// extractEntity identifies the caller the way the first composed limiter does
+func (c *compositeLimiter) extractEntity(r *http.Request) string {
+	if len(c.limiters) == 0 {
+		return extractIP(r)
+	}
+	return requestEntity(c.limiters[0], r)
+}
+
+// Limits returns the effective limits of every composed limiter
+func (c *compositeLimiter) Limits(entity string) ([]ScopeLimit, error) {
+	var limits []ScopeLimit
+	for _, limiter := range c.limiters {
+		scoped, err := limiter.Limits(entity)
+		if err != nil {
+			return nil, err
+		}
+		limits = append(limits, scoped...)
+	}
+	return limits, nil
+}
+
+// Scale scales every composed limiter
+func (c *compositeLimiter) Scale(factor float64) error {
+	for _, limiter := range c.limiters {
+		if err := limiter.Scale(factor); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// updateLimits applies a limit update to every composed limiter
+func (c *compositeLimiter) updateLimits(update core.LimitUpdate) error {
+	for _, limiter := range c.limiters {
+		if err := updateLimiterLimits(limiter, update); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setCosts applies a cost table to every composed limiter
+func (c *compositeLimiter) setCosts(costs map[string]int64) error {
+	for _, limiter := range c.limiters {
+		if err := setLimiterCosts(limiter, costs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ScaleFactor returns the multiplier of the first composed limiter
+func (c *compositeLimiter) ScaleFactor() float64 {
+	if len(c.limiters) == 0 {
+		return 1
+	}
+	return c.limiters[0].ScaleFactor()
+}
+
+// ConfigHash hashes the mode of the composition with the hashes of the composed limiters, in order
+func (c *compositeLimiter) ConfigHash() string {
+	hashes := make([]string, 0, len(c.limiters)+1)
+	hashes = append(hashes, fmt.Sprintf("composite:%d", c.mode))
+	for _, limiter := range c.limiters {
+		hashes = append(hashes, limiter.ConfigHash())
+	}
+	return core.HashConfig([]byte(strings.Join(hashes, "\n")))
+}
+
+// MaintenanceMode toggles maintenance mode on every composed limiter
+func (c *compositeLimiter) MaintenanceMode(enabled bool, allowlist []string) {
+	for _, limiter := range c.limiters {
+		limiter.MaintenanceMode(enabled, allowlist)
+	}
+}
+
+// RunWhenLeader registers the job on the first composed limiter only, so it runs once
+func (c *compositeLimiter) RunWhenLeader(name string, interval time.Duration, fn func(ctx context.Context) error) error {
+	if len(c.limiters) == 0 {
+		return fmt.Errorf("composite limiter has no limiters")
+	}
+	return c.limiters[0].RunWhenLeader(name, interval, fn)
+}
+
+// Stats sums the statistics of every composed limiter
+func (c *compositeLimiter) Stats(ctx context.Context) (*LimitStats, error) {
+	merged := &LimitStats{
+		ByScope:  make(map[string]*LimitScopeStats),
+		ByEntity: make(map[string]*EntityStats),
+	}
+
+	for _, limiter := range c.limiters {
+		stats, err := limiter.Stats(ctx)
+		if err != nil {
+			return nil, err
+		}
+		merged.TotalRequests += stats.TotalRequests
+		merged.TotalDenied += stats.TotalDenied
+		merged.DenialCacheHits += stats.DenialCacheHits
+		merged.EmptyEntities += stats.EmptyEntities
+		merged.ProbeBypasses += stats.ProbeBypasses
+		merged.StoreKeys += stats.StoreKeys
+		merged.ScopeOverflows += stats.ScopeOverflows
+		merged.UnknownTiers += stats.UnknownTiers
+		merged.ExpiredOverrides += stats.ExpiredOverrides
+		merged.EvictedEntities += stats.EvictedEntities
+		for scope, denials := range stats.ShadowDenials {
+			if merged.ShadowDenials == nil {
+				merged.ShadowDenials = make(map[string]int64)
+			}
+			merged.ShadowDenials[scope] += denials
+		}
+		merged.FailedOpen = addScopeCounts(merged.FailedOpen, stats.FailedOpen)
+		merged.FailedClosed = addScopeCounts(merged.FailedClosed, stats.FailedClosed)
+		merged.LocalFallbackAllowed = addScopeCounts(merged.LocalFallbackAllowed, stats.LocalFallbackAllowed)
+		merged.LocalFallbackDenied = addScopeCounts(merged.LocalFallbackDenied, stats.LocalFallbackDenied)
+		if stats.ClockOffset != 0 {
+			merged.ClockOffset = stats.ClockOffset
+		}
+		if flush := stats.StatsFlush; flush != nil {
+			if merged.StatsFlush == nil {
+				merged.StatsFlush = &StatsFlushStats{}
+			}
+			merged.StatsFlush.Flushes += flush.Flushes
+			merged.StatsFlush.FlushErrors += flush.FlushErrors
+			merged.StatsFlush.FlushedEvents += flush.FlushedEvents
+			merged.StatsFlush.PendingEvents += flush.PendingEvents
+			if flush.LastFlushDuration > merged.StatsFlush.LastFlushDuration {
+				merged.StatsFlush.LastFlushDuration = flush.LastFlushDuration
+			}
+			if flush.LastFlush.After(merged.StatsFlush.LastFlush) {
+				merged.StatsFlush.LastFlush = flush.LastFlush
+			}
+		}
+		if cache := stats.TierCache; cache != nil {
+			if merged.TierCache == nil {
+				merged.TierCache = &TierCacheStats{}
+			}
+			merged.TierCache.Hits += cache.Hits
+			merged.TierCache.StaleHits += cache.StaleHits
+			merged.TierCache.Misses += cache.Misses
+			merged.TierCache.Errors += cache.Errors
+			merged.TierCache.Entries += cache.Entries
+		}
+		if trusted := stats.TrustedCalls; trusted != nil {
+			if merged.TrustedCalls == nil {
+				merged.TrustedCalls = &TrustedCallStats{Accepted: make(map[string]map[string]int64), Rejected: make(map[string]int64)}
+			}
+			for keyID, scopes := range trusted.Accepted {
+				if merged.TrustedCalls.Accepted[keyID] == nil {
+					merged.TrustedCalls.Accepted[keyID] = make(map[string]int64)
+				}
+				for scope, calls := range scopes {
+					merged.TrustedCalls.Accepted[keyID][scope] += calls
+				}
+			}
+			for reason, calls := range trusted.Rejected {
+				merged.TrustedCalls.Rejected[reason] += calls
+			}
+		}
+		if retries := stats.StoreRetries; retries != nil {
+			if merged.StoreRetries == nil {
+				merged.StoreRetries = &StoreRetryStats{}
+			}
+			merged.StoreRetries.Reads += retries.Reads
+			merged.StoreRetries.Retries += retries.Retries
+			merged.StoreRetries.Hedges += retries.Hedges
+			merged.StoreRetries.HedgeWins += retries.HedgeWins
+			merged.StoreRetries.BudgetExhausted += retries.BudgetExhausted
+			merged.StoreRetries.DeadlineSkipped += retries.DeadlineSkipped
+			if retries.HedgeDelay > merged.StoreRetries.HedgeDelay {
+				merged.StoreRetries.HedgeDelay = retries.HedgeDelay
+			}
+		}
+
+		if expiry := stats.KeyExpiry; expiry != nil {
+			if merged.KeyExpiry == nil {
+				merged.KeyExpiry = &KeyExpiryStats{ByKind: make(map[string]int64)}
+			}
+			merged.KeyExpiry.Expired += expiry.Expired
+			merged.KeyExpiry.LastMinute += expiry.LastMinute
+			for kind, expired := range expiry.ByKind {
+				merged.KeyExpiry.ByKind[kind] += expired
+			}
+		}
+
+		if compression := stats.StateCompression; compression != nil {
+			if merged.StateCompression == nil {
+				merged.StateCompression = &StateCompressionStats{Codec: compression.Codec}
+			}
+			merged.StateCompression.Compressed += compression.Compressed
+			merged.StateCompression.Skipped += compression.Skipped
+			merged.StateCompression.Decompressed += compression.Decompressed
+			merged.StateCompression.BytesIn += compression.BytesIn
+			merged.StateCompression.BytesOut += compression.BytesOut
+			merged.StateCompression.EncodeTime += compression.EncodeTime
+			merged.StateCompression.DecodeTime += compression.DecodeTime
+			if merged.StateCompression.BytesIn > 0 {
+				merged.StateCompression.Ratio = float64(merged.StateCompression.BytesOut) / float64(merged.StateCompression.BytesIn)
+			}
+		}
+
+		if cache := stats.LocalCache; cache != nil {
+			if merged.LocalCache == nil {
+				merged.LocalCache = &LocalCacheStats{}
+			}
+			merged.LocalCache.Hits += cache.Hits
+			merged.LocalCache.Misses += cache.Misses
+			merged.LocalCache.Keys += cache.Keys
+			merged.LocalCache.PendingKeys += cache.PendingKeys
+			merged.LocalCache.Syncs += cache.Syncs
+			merged.LocalCache.SyncErrors += cache.SyncErrors
+			merged.LocalCache.Drift += cache.Drift
+			merged.LocalCache.TotalDrift += cache.TotalDrift
+			if cache.MaxDrift > merged.LocalCache.MaxDrift {
+				merged.LocalCache.MaxDrift = cache.MaxDrift
+			}
+			if cache.LastSync.After(merged.LocalCache.LastSync) {
+				merged.LocalCache.LastSync = cache.LastSync
+			}
+			if total := merged.LocalCache.Hits + merged.LocalCache.Misses; total > 0 {
+				merged.LocalCache.HitRatio = float64(merged.LocalCache.Hits) / float64(total)
+			}
+		}
+
+		for scope, s := range stats.ByScope {
+			if existing, ok := merged.ByScope[scope]; ok {
+				existing.Requests += s.Requests
+				existing.Denied += s.Denied
+				if s.LastUsed.After(existing.LastUsed) {
+					existing.LastUsed = s.LastUsed
+				}
+				continue
+			}
+			copied := *s
+			merged.ByScope[scope] = &copied
+		}
+		for entity, s := range stats.ByEntity {
+			if existing, ok := merged.ByEntity[entity]; ok {
+				existing.Requests += s.Requests
+				existing.Denied += s.Denied
+				if s.LastUsed.After(existing.LastUsed) {
+					existing.LastUsed = s.LastUsed
+				}
+				continue
+			}
+			copied := *s
+			merged.ByEntity[entity] = &copied
+		}
+	}
+
+	return merged, nil
+}
+
+// addScopeCounts adds per-scope counts to merged, creating it for the first counts
+func addScopeCounts(merged, counts map[string]int64) map[string]int64 {
+	for scope, count := range counts {
+		if merged == nil {
+			merged = make(map[string]int64, len(counts))
+		}
+		merged[scope] += count
+	}
+	return merged
+}
+
+// Health reports the first unhealthy composed limiter
+func (c *compositeLimiter) Health(ctx context.Context) error {
+	for _, limiter := range c.limiters {
+		if err := limiter.Health(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close closes every composed limiter
+func (c *compositeLimiter) Close() error {
+	var errs []error
+	for _, limiter := range c.limiters {
+		if err := limiter.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
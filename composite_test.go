@@ -0,0 +1,128 @@
+// composite_test.go - Tests for AND/OR limiter composition
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func newCompositeTestLimiter(t *testing.T, limit string) Limiter {
+	t.Helper()
+
+	limiter, err := New().
+		ExtractorFunc(func(r *http.Request) string { return r.Header.Get("X-User") }).
+		Limit("global", limit).
+		Build()
+	if err != nil {
+		t.Fatalf("Failed to build limiter: %v", err)
+	}
+	return limiter
+}
+
+func TestAll(t *testing.T) {
+	perUser := newCompositeTestLimiter(t, "3/minute")
+	infra := newCompositeTestLimiter(t, "2/minute")
+	limiter := All(perUser, infra)
+	defer limiter.Close()
+
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		result, err := limiter.Check(ctx, "user-1")
+		if err != nil || !result.Allowed {
+			t.Fatalf("Request %d: expected allowed, got %+v (%v)", i+1, result, err)
+		}
+		if result.Remaining != int64(1-i) {
+			t.Errorf("Request %d: expected most restrictive remaining %d, got %d", i+1, 1-i, result.Remaining)
+		}
+	}
+
+	result, err := limiter.Check(ctx, "user-1")
+	if err != nil || result.Allowed {
+		t.Fatalf("Expected the stricter limiter to deny, got %+v (%v)", result, err)
+	}
+
+	// Each limiter consumed its own quota
+	if result, _ := perUser.Check(ctx, "user-1"); result.Allowed {
+		t.Errorf("Expected per-user limiter to be charged for every request, got %+v", result)
+	}
+}
+
+func TestAny(t *testing.T) {
+	quota := newCompositeTestLimiter(t, "1/minute")
+	credits := newCompositeTestLimiter(t, "1/minute")
+	limiter := Any(quota, credits)
+	defer limiter.Close()
+
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		if allowed, err := limiter.Allow(ctx, "user-1"); err != nil || !allowed {
+			t.Fatalf("Request %d: expected a limiter to allow, got %v (%v)", i+1, allowed, err)
+		}
+	}
+	if allowed, _ := limiter.Allow(ctx, "user-1"); allowed {
+		t.Error("Expected denial once every limiter is exhausted")
+	}
+}
+
+func TestCompositeMiddleware(t *testing.T) {
+	limiter := All(newCompositeTestLimiter(t, "5/minute"), newCompositeTestLimiter(t, "1/minute"))
+	defer limiter.Close()
+
+	handler := limiter.For(HTTP).(func(http.Handler) http.Handler)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, createTestRequest("GET", "/", map[string]string{"X-User": "alice"}))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", w.Code)
+	}
+	if w.Header().Get("X-RateLimit-Limit") != "1" || w.Header().Get("X-RateLimit-Remaining") != "0" {
+		t.Errorf("Expected headers of the most restrictive limiter, got limit %q remaining %q",
+			w.Header().Get("X-RateLimit-Limit"), w.Header().Get("X-RateLimit-Remaining"))
+	}
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, createTestRequest("GET", "/", map[string]string{"X-User": "alice"}))
+	if w.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected 429 from the stricter limiter, got %d", w.Code)
+	}
+	if w.Header().Get("Retry-After") == "" {
+		t.Error("Expected Retry-After from the denying limiter")
+	}
+}
+
+func TestCompositePeek(t *testing.T) {
+	limiter := All(newCompositeTestLimiter(t, "5/minute"), newCompositeTestLimiter(t, "2/minute"))
+	defer limiter.Close()
+
+	ctx := context.Background()
+	if _, err := limiter.Check(ctx, "user-1"); err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		result, err := limiter.Peek(ctx, "user-1")
+		if err != nil || !result.Allowed || result.Remaining != 1 {
+			t.Errorf("Peek %d: expected most restrictive remaining 1, got %+v (%v)", i+1, result, err)
+		}
+	}
+}
+
+func TestCompositeConfigHash(t *testing.T) {
+	strict, loose := newCompositeTestLimiter(t, "2/minute"), newCompositeTestLimiter(t, "5/minute")
+	defer strict.Close()
+	defer loose.Close()
+
+	hash := All(strict, loose).ConfigHash()
+	if All(strict, loose).ConfigHash() != hash {
+		t.Error("Expected the same composition to hash alike")
+	}
+	for _, other := range []Limiter{Any(strict, loose), All(loose, strict), All(strict)} {
+		if other.ConfigHash() == hash {
+			t.Errorf("Expected a different composition to change the hash %q", hash)
+		}
+	}
+}
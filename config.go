@@ -3,23 +3,28 @@ package ratelimit
 
 import (
 	"fmt"
-	"strconv"
-	"strings"
 	"time"
+
+	"github.com/itsatony/gorly/internal/core"
 )
 
 // Config represents the configuration for the rate limiter
 type Config struct {
 	// Global settings
 	Enabled   bool   `yaml:"enabled" json:"enabled" mapstructure:"enabled"`
-	Algorithm string `yaml:"algorithm" json:"algorithm" mapstructure:"algorithm"` // "token_bucket", "sliding_window", "gcra"
-	Store     string `yaml:"store" json:"store" mapstructure:"store"`             // "redis", "memory"
+	Algorithm string `yaml:"algorithm" json:"algorithm" mapstructure:"algorithm"` // "token_bucket", "sliding_window", "fixed_window", "gcra"
+	Store     string `yaml:"store" json:"store" mapstructure:"store"`             // "redis", "memory", "postgres"
 	KeyPrefix string `yaml:"key_prefix" json:"key_prefix" mapstructure:"key_prefix"`
 
+	// Where fixed windows start: "wall_clock" (default) or "first_request"
+	WindowAlignment string `yaml:"window_alignment,omitempty" json:"window_alignment,omitempty" mapstructure:"window_alignment"`
+
 	// Store configuration
 	Redis  RedisConfig  `yaml:"redis" json:"redis" mapstructure:"redis"`
 	Memory MemoryConfig `yaml:"memory" json:"memory" mapstructure:"memory"`
 
+	Postgres PostgresConfig `yaml:"postgres,omitempty" json:"postgres,omitempty" mapstructure:"postgres"`
+
 	// Default rate limits
 	DefaultLimits map[string]RateLimit `yaml:"default_limits" json:"default_limits" mapstructure:"default_limits"`
 
@@ -32,11 +37,18 @@ type Config struct {
 	// Tier-specific rate limits
 	TierLimits map[string]TierConfig `yaml:"tier_limits" json:"tier_limits" mapstructure:"tier_limits"`
 
+	// Scope name patterns (path.Match syntax, e.g. "debug*") no limit may name; scopes
+	// starting with "__" are reserved for internal bookkeeping and always denied
+	DeniedScopes []string `yaml:"denied_scopes,omitempty" json:"denied_scopes,omitempty" mapstructure:"denied_scopes"`
+
 	// Metrics and monitoring
 	EnableMetrics  bool          `yaml:"enable_metrics" json:"enable_metrics" mapstructure:"enable_metrics"`
 	MetricsPrefix  string        `yaml:"metrics_prefix" json:"metrics_prefix" mapstructure:"metrics_prefix"`
 	StatsRetention time.Duration `yaml:"stats_retention" json:"stats_retention" mapstructure:"stats_retention"`
 
+	// Compression of serialized algorithm state, keyed by algorithm name
+	StateCompression map[string]CompressionConfig `yaml:"state_compression,omitempty" json:"state_compression,omitempty" mapstructure:"state_compression"`
+
 	// Performance settings
 	MaxConcurrentRequests int           `yaml:"max_concurrent_requests" json:"max_concurrent_requests" mapstructure:"max_concurrent_requests"`
 	OperationTimeout      time.Duration `yaml:"operation_timeout" json:"operation_timeout" mapstructure:"operation_timeout"`
@@ -53,6 +65,22 @@ type RedisConfig struct {
 	MaxRetries  int           `yaml:"max_retries" json:"max_retries" mapstructure:"max_retries"`
 	Timeout     time.Duration `yaml:"timeout" json:"timeout" mapstructure:"timeout"`
 	TLS         bool          `yaml:"tls" json:"tls" mapstructure:"tls"`
+
+	// Client-side sharding across standalone instances (overrides Address when set)
+	Shards             []string `yaml:"shards,omitempty" json:"shards,omitempty" mapstructure:"shards"`
+	ShardFailurePolicy string   `yaml:"shard_failure_policy,omitempty" json:"shard_failure_policy,omitempty" mapstructure:"shard_failure_policy"` // "failover" or "fail_closed"
+
+	// Credentials fetched at runtime instead of Password: Secrets if set, else Vault
+	Vault   *VaultConfig    `yaml:"vault,omitempty" json:"vault,omitempty" mapstructure:"vault"`
+	Secrets SecretsProvider `yaml:"-" json:"-" mapstructure:"-"`
+}
+
+// PostgresConfig configures the Postgres store. The table is created on startup if missing.
+type PostgresConfig struct {
+	DSN      string `yaml:"dsn" json:"dsn" mapstructure:"dsn"`
+	Driver   string `yaml:"driver,omitempty" json:"driver,omitempty" mapstructure:"driver"` // Default: "pgx" or "postgres", whichever is registered
+	Table    string `yaml:"table,omitempty" json:"table,omitempty" mapstructure:"table"`    // Default: "gorly_limits"
+	Unlogged bool   `yaml:"unlogged,omitempty" json:"unlogged,omitempty" mapstructure:"unlogged"`
 }
 
 // MemoryConfig configures in-memory store settings
@@ -62,6 +90,12 @@ type MemoryConfig struct {
 	ShardCount      int           `yaml:"shard_count" json:"shard_count" mapstructure:"shard_count"`
 }
 
+// CompressionConfig configures compression of large serialized state blobs
+type CompressionConfig struct {
+	Algorithm string `yaml:"algorithm" json:"algorithm" mapstructure:"algorithm"` // "none", "snappy", "zstd"
+	Threshold int    `yaml:"threshold" json:"threshold" mapstructure:"threshold"` // Minimum size in bytes before compressing
+}
+
 // RateLimit represents a rate limit configuration
 type RateLimit struct {
 	// Rate specification
@@ -208,48 +242,29 @@ func DefaultConfig() *Config {
 }
 
 // ParseRateString parses a rate string like "100/1m" or "1000/1h" into requests and window
+//
+// Deprecated: use ParseLimit, which also reads burst sizes.
 func ParseRateString(rateStr string) (int64, time.Duration, error) {
-	parts := strings.Split(rateStr, "/")
-	if len(parts) != 2 {
-		return 0, 0, fmt.Errorf("invalid rate string format: %s (expected format: requests/duration)", rateStr)
-	}
-
-	requests, err := strconv.ParseInt(parts[0], 10, 64)
-	if err != nil {
-		return 0, 0, fmt.Errorf("invalid requests value in rate string: %s", parts[0])
-	}
-
-	window, err := parseDurationString(parts[1])
+	limit, err := ParseLimit(rateStr)
 	if err != nil {
-		return 0, 0, fmt.Errorf("invalid duration in rate string: %s", parts[1])
+		return 0, 0, err
 	}
-
-	return requests, window, nil
+	return limit.Requests, limit.Window, nil
 }
 
-// parseDurationString parses duration strings like "1m", "1h", "30s"
-func parseDurationString(durationStr string) (time.Duration, error) {
-	originalStr := durationStr
-
-	// Check for day or week suffixes and convert
-	if strings.HasSuffix(durationStr, "d") {
-		numStr := strings.TrimSuffix(durationStr, "d")
-		if num, err := strconv.ParseInt(numStr, 10, 64); err == nil {
-			durationStr = fmt.Sprintf("%dh", num*24)
-		}
-	} else if strings.HasSuffix(durationStr, "w") {
-		numStr := strings.TrimSuffix(durationStr, "w")
-		if num, err := strconv.ParseInt(numStr, 10, 64); err == nil {
-			durationStr = fmt.Sprintf("%dh", num*24*7)
-		}
-	}
+// Limit returns the rate limit as a Limit
+func (rl RateLimit) Limit() Limit {
+	return Limit{Requests: rl.Requests, Window: rl.Window, Burst: rl.BurstSize}
+}
 
-	duration, err := time.ParseDuration(durationStr)
-	if err != nil {
-		return 0, fmt.Errorf("invalid duration format: %s", originalStr)
+// NewRateLimit returns the config file rate limit of a Limit
+func NewRateLimit(limit Limit) RateLimit {
+	return RateLimit{
+		Requests:   limit.Requests,
+		Window:     limit.Window,
+		BurstSize:  limit.Burst,
+		RateString: limit.String(),
 	}
-
-	return duration, nil
 }
 
 // ApplyRateString updates the RateLimit with parsed values from RateString
@@ -258,13 +273,17 @@ func (rl *RateLimit) ApplyRateString() error {
 		return nil
 	}
 
-	requests, window, err := ParseRateString(rl.RateString)
+	limit, err := ParseLimit(rl.RateString)
 	if err != nil {
 		return err
 	}
 
+	requests := limit.Requests
 	rl.Requests = requests
-	rl.Window = window
+	rl.Window = limit.Window
+	if limit.Burst > 0 {
+		rl.BurstSize = limit.Burst
+	}
 
 	// Set default burst size if not specified
 	if rl.BurstSize == 0 {
@@ -287,24 +306,35 @@ func (c *Config) Validate() error {
 	validAlgorithms := map[string]bool{
 		"token_bucket":   true,
 		"sliding_window": true,
+		"fixed_window":   true,
 		"gcra":           true,
 	}
-	if !validAlgorithms[c.Algorithm] {
+	if !validAlgorithms[c.Algorithm] && !core.IsAlgorithm(c.Algorithm) {
 		return fmt.Errorf("invalid algorithm: %s", c.Algorithm)
 	}
+	switch WindowAlignment(c.WindowAlignment) {
+	case "", AlignWallClock, AlignFirstRequest:
+	default:
+		return fmt.Errorf("invalid window alignment: %s", c.WindowAlignment)
+	}
 
 	// Validate store
 	validStores := map[string]bool{
-		"redis":  true,
-		"memory": true,
+		"redis":    true,
+		"memory":   true,
+		"postgres": true,
 	}
 	if !validStores[c.Store] {
 		return fmt.Errorf("invalid store: %s", c.Store)
 	}
 
+	if c.Store == "postgres" && c.Postgres.DSN == "" {
+		return fmt.Errorf("postgres dsn is required when using postgres store")
+	}
+
 	// Validate Redis config if using Redis
 	if c.Store == "redis" {
-		if c.Redis.Address == "" {
+		if c.Redis.Address == "" && len(c.Redis.Shards) == 0 {
 			return fmt.Errorf("redis address is required when using redis store")
 		}
 		if c.Redis.PoolSize <= 0 {
@@ -313,6 +343,40 @@ func (c *Config) Validate() error {
 		if c.Redis.Timeout <= 0 {
 			c.Redis.Timeout = 5 * time.Second
 		}
+		if vault := c.Redis.Vault; vault != nil && c.Redis.Secrets == nil {
+			if vault.Path == "" && vault.TLSPath == "" {
+				return fmt.Errorf("redis vault config requires a path or tls_path")
+			}
+			switch vault.AuthMethod {
+			case "", VaultAuthToken, VaultAuthAppRole, VaultAuthKubernetes:
+			default:
+				return fmt.Errorf("invalid redis vault auth method: %s", vault.AuthMethod)
+			}
+		}
+	}
+
+	// Validate state compression
+	validCompression := map[string]bool{
+		"":       true,
+		"none":   true,
+		"snappy": true,
+		"zstd":   true,
+	}
+	for algorithm, compression := range c.StateCompression {
+		if !validAlgorithms[algorithm] {
+			return fmt.Errorf("invalid algorithm in state_compression: %s", algorithm)
+		}
+		if !validCompression[compression.Algorithm] {
+			return fmt.Errorf("invalid compression algorithm for %s: %s", algorithm, compression.Algorithm)
+		}
+		if compression.Threshold < 0 {
+			return fmt.Errorf("compression threshold for %s cannot be negative", algorithm)
+		}
+	}
+
+	// Validate scope names
+	if err := c.validateScopeNames(); err != nil {
+		return err
 	}
 
 	// Validate and apply rate strings
@@ -381,6 +445,42 @@ func (c *Config) Validate() error {
 	return nil
 }
 
+// validateScopeNames checks that no limit names a reserved or deny-listed scope
+func (c *Config) validateScopeNames() error {
+	if err := core.ValidateScopePatterns(c.DeniedScopes); err != nil {
+		return err
+	}
+	check := func(section string, limits map[string]RateLimit) error {
+		for _, scope := range sortedKeys(limits) {
+			if err := core.CheckScopeName(scope, c.DeniedScopes); err != nil {
+				return fmt.Errorf("invalid scope in %s: %w", section, err)
+			}
+		}
+		return nil
+	}
+
+	if err := check("default_limits", c.DefaultLimits); err != nil {
+		return err
+	}
+	if err := check("scope_limits", c.ScopeLimits); err != nil {
+		return err
+	}
+	for _, tier := range sortedKeys(c.TierLimits) {
+		if err := check(fmt.Sprintf("tier_limits[%s].default_limits", tier), c.TierLimits[tier].DefaultLimits); err != nil {
+			return err
+		}
+		if err := check(fmt.Sprintf("tier_limits[%s].scope_limits", tier), c.TierLimits[tier].ScopeLimits); err != nil {
+			return err
+		}
+	}
+	for _, entityID := range sortedKeys(c.EntityOverrides) {
+		if err := check(fmt.Sprintf("entity_overrides[%s].limits", entityID), c.EntityOverrides[entityID].Limits); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // GetRateLimit returns the appropriate rate limit for the given entity and scope
 func (c *Config) GetRateLimit(entity AuthEntity, scope string) RateLimit {
 	// Check entity-specific overrides first
@@ -0,0 +1,114 @@
+// config_env.go - Environment variable expansion in config files
+package ratelimit
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// expandConfigEnv replaces ${VAR} and ${VAR:-default} in every string value of a parsed
+// config file, recursing into objects and lists. Keys are not expanded, and "$$" stands
+// for a literal "$".
+func expandConfigEnv(raw map[string]interface{}) error {
+	for key, value := range raw {
+		expanded, err := expandEnvValue(key, value)
+		if err != nil {
+			return err
+		}
+		raw[key] = expanded
+	}
+	return nil
+}
+
+// expandEnvValue expands a value at a dotted path
+func expandEnvValue(path string, value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case string:
+		expanded, err := expandEnv(v)
+		if err != nil {
+			return nil, fmt.Errorf("config value %s: %w", path, err)
+		}
+		return expanded, nil
+	case map[string]interface{}:
+		for key, item := range v {
+			expanded, err := expandEnvValue(joinConfigPath(path, key), item)
+			if err != nil {
+				return nil, err
+			}
+			v[key] = expanded
+		}
+		return v, nil
+	case []interface{}:
+		for i, item := range v {
+			expanded, err := expandEnvValue(fmt.Sprintf("%s[%d]", path, i), item)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = expanded
+		}
+		return v, nil
+	default:
+		return value, nil
+	}
+}
+
+// expandEnv expands the references of one string. A variable without a default must be
+// set, so a missing secret fails loudly instead of becoming an empty password; the
+// default of ${VAR:-default} applies when VAR is unset or empty.
+func expandEnv(s string) (string, error) {
+	if !strings.Contains(s, "$") {
+		return s, nil
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '$' {
+			b.WriteByte(s[i])
+			continue
+		}
+		if i+1 < len(s) && s[i+1] == '$' {
+			b.WriteByte('$')
+			i++
+			continue
+		}
+		if i+1 >= len(s) || s[i+1] != '{' {
+			b.WriteByte('$')
+			continue
+		}
+
+		end := strings.IndexByte(s[i:], '}')
+		if end < 0 {
+			return "", fmt.Errorf("unterminated variable reference in %q", s)
+		}
+		expr := s[i+2 : i+end]
+		name, fallback, hasDefault := strings.Cut(expr, ":-")
+		if !validEnvName(name) {
+			return "", fmt.Errorf("invalid variable reference ${%s}", expr)
+		}
+
+		value, set := os.LookupEnv(name)
+		switch {
+		case hasDefault && value == "":
+			value = fallback
+		case !set:
+			return "", fmt.Errorf("environment variable %s is not set", name)
+		}
+		b.WriteString(value)
+		i += end
+	}
+	return b.String(), nil
+}
+
+// validEnvName reports whether name is a valid environment variable name
+func validEnvName(name string) bool {
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		return false
+	}
+	for _, c := range name {
+		if c != '_' && (c < 'A' || c > 'Z') && (c < 'a' || c > 'z') && (c < '0' || c > '9') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,71 @@
+// config_env_test.go
+package ratelimit
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExpandEnv(t *testing.T) {
+	t.Setenv("GORLY_TEST_HOST", "redis.internal")
+	t.Setenv("GORLY_TEST_EMPTY", "")
+
+	tests := []struct {
+		in, want string
+	}{
+		{"${GORLY_TEST_HOST}:6379", "redis.internal:6379"},
+		{"${GORLY_TEST_UNSET:-localhost}:6379", "localhost:6379"},
+		{"${GORLY_TEST_EMPTY:-fallback}", "fallback"},
+		{"${GORLY_TEST_HOST:-fallback}", "redis.internal"},
+		{"price: $$5 and $5", "price: $5 and $5"},
+		{"$${GORLY_TEST_HOST}", "${GORLY_TEST_HOST}"},
+		{"100/1m", "100/1m"},
+	}
+	for _, tt := range tests {
+		got, err := expandEnv(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("expandEnv(%q) = %q, %v; want %q", tt.in, got, err, tt.want)
+		}
+	}
+
+	for _, in := range []string{"${GORLY_TEST_UNSET}", "${GORLY_TEST_HOST", "${1BAD}", "${}"} {
+		if _, err := expandEnv(in); err == nil {
+			t.Errorf("expandEnv(%q): expected an error", in)
+		}
+	}
+}
+
+func TestConfigLoader_EnvExpansion(t *testing.T) {
+	t.Setenv("GORLY_TEST_REDIS_PASSWORD", "s3cret")
+	t.Setenv("GORLY_TEST_GLOBAL_LIMIT", "500/1m")
+	config := `
+store: redis
+redis:
+  address: ${GORLY_TEST_REDIS_ADDR:-localhost:6379}
+  password: ${GORLY_TEST_REDIS_PASSWORD}
+  shards: ["${GORLY_TEST_REDIS_ADDR:-localhost:6379}"]
+defaultLimits:
+  global: ${GORLY_TEST_GLOBAL_LIMIT}
+`
+	loaded, err := NewConfigLoader().LoadFromYAML(strings.NewReader(config))
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if loaded.Redis.Address != "localhost:6379" || loaded.Redis.Password != "s3cret" || loaded.Redis.Shards[0] != "localhost:6379" {
+		t.Errorf("Expected expanded Redis settings, got %+v", loaded.Redis)
+	}
+	if limit := loaded.DefaultLimits["global"]; limit.Requests != 500 {
+		t.Errorf("Expected the expanded limit, got %+v", limit)
+	}
+
+	// With expansion off, values are kept as written
+	loaded, err = NewConfigLoader().WithEnvExpansion(false).LoadFromJSON(strings.NewReader(`{"redis": {"password": "${NOT_A_VARIABLE}"}}`))
+	if err != nil || loaded.Redis.Password != "${NOT_A_VARIABLE}" {
+		t.Errorf("Expected the literal password, got %q (%v)", loaded.Redis.Password, err)
+	}
+
+	_, err = NewConfigLoader().LoadFromJSON(strings.NewReader(`{"redis": {"password": "${GORLY_TEST_UNSET}"}}`))
+	if err == nil || !strings.Contains(err.Error(), "redis.password") {
+		t.Errorf("Expected an error naming the value, got %v", err)
+	}
+}
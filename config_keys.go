@@ -0,0 +1,206 @@
+// config_keys.go - Strict checking of the keys of config files
+package ratelimit
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// UnknownConfigKey is a key of a config file that the loader does not read
+type UnknownConfigKey struct {
+	Path       string // Dotted path of the key, e.g. "redis.passwrod"
+	Suggestion string // Closest valid key at that position, "" if none is close
+}
+
+// String describes the key, with the suggestion if there is one
+func (k UnknownConfigKey) String() string {
+	if k.Suggestion == "" {
+		return fmt.Sprintf("unknown key %s", k.Path)
+	}
+	return fmt.Sprintf("unknown key %s (did you mean %s?)", k.Path, k.Suggestion)
+}
+
+// UnknownConfigKeysError is returned by a strict ConfigLoader for config files with unknown keys
+type UnknownConfigKeysError struct {
+	Keys []UnknownConfigKey
+}
+
+func (e *UnknownConfigKeysError) Error() string {
+	descriptions := make([]string, len(e.Keys))
+	for i, key := range e.Keys {
+		descriptions[i] = key.String()
+	}
+	return "invalid config: " + strings.Join(descriptions, "; ")
+}
+
+// configNode describes a value of a config file. An object lists its keys in fields; a
+// map keyed by names such as scopes or tiers describes the value of every entry in
+// entries. A nil node accepts any value.
+type configNode struct {
+	fields  map[string]*configNode
+	entries *configNode
+
+	// limitsFallback marks entity overrides, whose keys are scopes when "limits" is absent
+	limitsFallback bool
+}
+
+// rateLimitNode is a rate limit given as an object instead of a rate string
+var rateLimitNode = &configNode{fields: map[string]*configNode{"requests": nil, "window": nil}}
+
+// rateLimitsNode is a map of rate limits by scope
+var rateLimitsNode = &configNode{entries: rateLimitNode}
+
+// configSchema lists every key parseConfig reads
+var configSchema = &configNode{fields: map[string]*configNode{
+	"enabled":          nil,
+	"algorithm":        nil,
+	"store":            nil,
+	"keyPrefix":        nil,
+	"windowAlignment":  nil,
+	"enableMetrics":    nil,
+	"metricsPrefix":    nil,
+	"operationTimeout": nil,
+	"redis": {fields: map[string]*configNode{
+		"address":            nil,
+		"password":           nil,
+		"database":           nil,
+		"poolSize":           nil,
+		"minIdleConn":        nil,
+		"maxRetries":         nil,
+		"timeout":            nil,
+		"tls":                nil,
+		"shards":             nil,
+		"shardFailurePolicy": nil,
+		"vault": {fields: map[string]*configNode{
+			"address":         nil,
+			"namespace":       nil,
+			"authMethod":      nil,
+			"authMount":       nil,
+			"token":           nil,
+			"roleId":          nil,
+			"secretId":        nil,
+			"role":            nil,
+			"jwtPath":         nil,
+			"path":            nil,
+			"usernameField":   nil,
+			"passwordField":   nil,
+			"tlsPath":         nil,
+			"tlsParams":       nil,
+			"refreshInterval": nil,
+		}},
+	}},
+	"postgres": {fields: map[string]*configNode{
+		"dsn":      nil,
+		"driver":   nil,
+		"table":    nil,
+		"unlogged": nil,
+	}},
+	"stateCompression": {entries: &configNode{fields: map[string]*configNode{"algorithm": nil, "threshold": nil}}},
+	"defaultLimits":    rateLimitsNode,
+	"scopeLimits":      rateLimitsNode,
+	"tierLimits": {entries: &configNode{fields: map[string]*configNode{
+		"defaultLimits": rateLimitsNode,
+		"scopeLimits":   rateLimitsNode,
+	}}},
+	"deniedScopes": nil,
+	"entityOverrides": {entries: &configNode{
+		fields: map[string]*configNode{
+			"limits":    rateLimitsNode,
+			"algorithm": nil,
+			"enabled":   nil,
+			"metadata":  nil,
+		},
+		limitsFallback: true,
+	}},
+}}
+
+// checkConfigKeys reports the keys of a parsed config file that the loader does not read
+func checkConfigKeys(raw map[string]interface{}) error {
+	var unknown []UnknownConfigKey
+	configSchema.check("", raw, &unknown)
+	if len(unknown) == 0 {
+		return nil
+	}
+	sort.Slice(unknown, func(i, j int) bool { return unknown[i].Path < unknown[j].Path })
+	return &UnknownConfigKeysError{Keys: unknown}
+}
+
+// check walks a value, collecting the keys the node does not describe
+func (n *configNode) check(path string, value interface{}, unknown *[]UnknownConfigKey) {
+	if n == nil {
+		return
+	}
+	object, ok := value.(map[string]interface{})
+	if !ok {
+		return // Scalars such as rate strings are checked by the parser
+	}
+
+	if n.entries != nil {
+		for key, entry := range object {
+			n.entries.check(joinConfigPath(path, key), entry, unknown)
+		}
+		return
+	}
+
+	if _, hasLimits := object["limits"]; n.limitsFallback && !hasLimits {
+		// Legacy entity overrides list their rate limits directly
+		rateLimitsNode.check(path, object, unknown)
+		return
+	}
+	for key, field := range object {
+		child, known := n.fields[key]
+		if !known {
+			*unknown = append(*unknown, UnknownConfigKey{
+				Path:       joinConfigPath(path, key),
+				Suggestion: closestKey(key, n.fields),
+			})
+			continue
+		}
+		child.check(joinConfigPath(path, key), field, unknown)
+	}
+}
+
+// joinConfigPath appends a key to a dotted path
+func joinConfigPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// closestKey returns the valid key nearest to key by edit distance, ignoring case, or ""
+// if none is within a third of the key's length (at least 2 edits)
+func closestKey(key string, fields map[string]*configNode) string {
+	maxDistance := max(int64(len(key)/3), 2)
+	best, bestDistance := "", maxDistance+1
+	for candidate := range fields {
+		distance := int64(editDistance(strings.ToLower(key), strings.ToLower(candidate)))
+		if distance < bestDistance || (distance == bestDistance && candidate < best) {
+			best, bestDistance = candidate, distance
+		}
+	}
+	return best
+}
+
+// editDistance returns the Levenshtein distance between two strings
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = min(min(previous[j]+1, current[j-1]+1), previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(rb)]
+}
@@ -17,6 +17,12 @@ import (
 type ConfigLoader struct {
 	// Default configuration to merge with loaded config
 	defaults *Config
+
+	// strict rejects JSON and YAML files with keys the loader does not read
+	strict bool
+
+	// literalEnv turns off the expansion of environment variables in JSON and YAML files
+	literalEnv bool
 }
 
 // NewConfigLoader creates a new configuration loader
@@ -33,6 +39,24 @@ func NewConfigLoaderWithDefaults(defaults *Config) *ConfigLoader {
 	}
 }
 
+// WithStrict makes the loader reject JSON and YAML files with unknown keys, such as typos
+// like "defualtLimits", which are otherwise ignored. The error is an *UnknownConfigKeysError
+// listing every unknown key with its path and the closest valid key.
+// Example: config, err := ratelimit.NewConfigLoader().WithStrict(true).LoadFromFile("gorly.yaml")
+func (cl *ConfigLoader) WithStrict(strict bool) *ConfigLoader {
+	cl.strict = strict
+	return cl
+}
+
+// WithEnvExpansion turns the expansion of ${VAR} and ${VAR:-default} in the string values
+// of JSON and YAML files on (default) or off, e.g. for files whose values contain "${". With
+// expansion on, "$$" stands for a literal "$" and a variable without a default must be set.
+// Example: config, err := ratelimit.NewConfigLoader().WithEnvExpansion(false).LoadFromFile("gorly.yaml")
+func (cl *ConfigLoader) WithEnvExpansion(enabled bool) *ConfigLoader {
+	cl.literalEnv = !enabled
+	return cl
+}
+
 // LoadFromFile loads configuration from a file (supports JSON, YAML, TOML based on extension)
 func (cl *ConfigLoader) LoadFromFile(filename string) (*Config, error) {
 	if filename == "" {
@@ -67,6 +91,9 @@ func (cl *ConfigLoader) LoadFromJSON(reader io.Reader) (*Config, error) {
 	if err := json.Unmarshal(data, &rawConfig); err != nil {
 		return nil, fmt.Errorf("failed to parse JSON: %w", err)
 	}
+	if err := cl.prepareRaw(rawConfig); err != nil {
+		return nil, err
+	}
 
 	return cl.parseConfig(rawConfig)
 }
@@ -82,10 +109,26 @@ func (cl *ConfigLoader) LoadFromYAML(reader io.Reader) (*Config, error) {
 	if err := yaml.Unmarshal(data, &rawConfig); err != nil {
 		return nil, fmt.Errorf("failed to parse YAML: %w", err)
 	}
+	if err := cl.prepareRaw(rawConfig); err != nil {
+		return nil, err
+	}
 
 	return cl.parseConfig(rawConfig)
 }
 
+// prepareRaw checks the keys of a parsed file in strict mode and expands environment variables
+func (cl *ConfigLoader) prepareRaw(raw map[string]interface{}) error {
+	if cl.strict {
+		if err := checkConfigKeys(raw); err != nil {
+			return err
+		}
+	}
+	if !cl.literalEnv {
+		return expandConfigEnv(raw)
+	}
+	return nil
+}
+
 // LoadFromEnv loads configuration from environment variables
 func (cl *ConfigLoader) LoadFromEnv() (*Config, error) {
 	config := cl.copyDefaults()
@@ -107,6 +150,10 @@ func (cl *ConfigLoader) LoadFromEnv() (*Config, error) {
 		config.KeyPrefix = val
 	}
 
+	if val := os.Getenv("GORLY_WINDOW_ALIGNMENT"); val != "" {
+		config.WindowAlignment = val
+	}
+
 	if val := os.Getenv("GORLY_ENABLE_METRICS"); val != "" {
 		config.EnableMetrics = strings.ToLower(val) == "true"
 	}
@@ -131,15 +178,20 @@ func (cl *ConfigLoader) LoadFromEnv() (*Config, error) {
 		}
 	}
 
+	if val := os.Getenv("GORLY_POSTGRES_DSN"); val != "" {
+		config.Postgres.DSN = val
+	}
+
 	// Default limits from environment (simplified format)
 	if val := os.Getenv("GORLY_DEFAULT_LIMIT"); val != "" {
-		if requests, window, err := ParseRateString(val); err == nil {
+		if limit, err := ParseLimit(val); err == nil {
 			if config.DefaultLimits == nil {
 				config.DefaultLimits = make(map[string]RateLimit)
 			}
 			config.DefaultLimits[ScopeGlobal] = RateLimit{
-				Requests: requests,
-				Window:   window,
+				Requests:  limit.Requests,
+				Window:    limit.Window,
+				BurstSize: limit.Burst,
 			}
 		}
 	}
@@ -192,6 +244,10 @@ func (cl *ConfigLoader) parseConfig(raw map[string]interface{}) (*Config, error)
 		config.KeyPrefix = val
 	}
 
+	if val, ok := raw["windowAlignment"].(string); ok {
+		config.WindowAlignment = val
+	}
+
 	if val, ok := raw["enableMetrics"].(bool); ok {
 		config.EnableMetrics = val
 	}
@@ -214,6 +270,16 @@ func (cl *ConfigLoader) parseConfig(raw map[string]interface{}) (*Config, error)
 		}
 	}
 
+	// Parse Postgres config
+	if postgresRaw, ok := raw["postgres"].(map[string]interface{}); ok {
+		cl.parsePostgresConfig(&config.Postgres, postgresRaw)
+	}
+
+	// Parse state compression
+	if compressionRaw, ok := raw["stateCompression"].(map[string]interface{}); ok {
+		config.StateCompression = cl.parseStateCompression(compressionRaw)
+	}
+
 	// Parse default limits
 	if limitsRaw, ok := raw["defaultLimits"].(map[string]interface{}); ok {
 		limits, err := cl.parseRateLimits(limitsRaw)
@@ -241,6 +307,16 @@ func (cl *ConfigLoader) parseConfig(raw map[string]interface{}) (*Config, error)
 		config.TierLimits = tiers
 	}
 
+	// Parse denied scope patterns
+	if val, ok := raw["deniedScopes"].([]interface{}); ok {
+		config.DeniedScopes = make([]string, 0, len(val))
+		for _, item := range val {
+			if pattern, ok := item.(string); ok {
+				config.DeniedScopes = append(config.DeniedScopes, pattern)
+			}
+		}
+	}
+
 	// Parse entity overrides
 	if overridesRaw, ok := raw["entityOverrides"].(map[string]interface{}); ok {
 		overrides, err := cl.parseEntityOverrides(overridesRaw)
@@ -253,6 +329,34 @@ func (cl *ConfigLoader) parseConfig(raw map[string]interface{}) (*Config, error)
 	return config, nil
 }
 
+// parseStateCompression parses per-algorithm compression settings from raw map
+func (cl *ConfigLoader) parseStateCompression(raw map[string]interface{}) map[string]CompressionConfig {
+	result := make(map[string]CompressionConfig)
+
+	for algorithm, val := range raw {
+		compressionMap, ok := val.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		var compression CompressionConfig
+		if codec, ok := compressionMap["algorithm"].(string); ok {
+			compression.Algorithm = codec
+		}
+		if threshold, ok := compressionMap["threshold"]; ok {
+			if t, ok := threshold.(int); ok {
+				compression.Threshold = t
+			} else if tFloat, ok := threshold.(float64); ok {
+				compression.Threshold = int(tFloat)
+			}
+		}
+
+		result[algorithm] = compression
+	}
+
+	return result
+}
+
 // parseRedisConfig parses Redis configuration from raw map
 func (cl *ConfigLoader) parseRedisConfig(redis *RedisConfig, raw map[string]interface{}) error {
 	if val, ok := raw["address"].(string); ok {
@@ -305,9 +409,85 @@ func (cl *ConfigLoader) parseRedisConfig(redis *RedisConfig, raw map[string]inte
 		redis.TLS = val
 	}
 
+	if val, ok := raw["shards"].([]interface{}); ok {
+		redis.Shards = make([]string, 0, len(val))
+		for _, item := range val {
+			if address, ok := item.(string); ok {
+				redis.Shards = append(redis.Shards, address)
+			}
+		}
+	}
+
+	if val, ok := raw["shardFailurePolicy"].(string); ok {
+		redis.ShardFailurePolicy = val
+	}
+
+	if val, ok := raw["vault"].(map[string]interface{}); ok {
+		vault, err := cl.parseVaultConfig(val)
+		if err != nil {
+			return fmt.Errorf("failed to parse vault config: %w", err)
+		}
+		redis.Vault = vault
+	}
+
 	return nil
 }
 
+// parsePostgresConfig parses Postgres configuration from raw map
+func (cl *ConfigLoader) parsePostgresConfig(postgres *PostgresConfig, raw map[string]interface{}) {
+	if val, ok := raw["dsn"].(string); ok {
+		postgres.DSN = val
+	}
+	if val, ok := raw["driver"].(string); ok {
+		postgres.Driver = val
+	}
+	if val, ok := raw["table"].(string); ok {
+		postgres.Table = val
+	}
+	if val, ok := raw["unlogged"].(bool); ok {
+		postgres.Unlogged = val
+	}
+}
+
+// parseVaultConfig parses the Vault secrets of the Redis credentials from raw map
+func (cl *ConfigLoader) parseVaultConfig(raw map[string]interface{}) (*VaultConfig, error) {
+	vault := &VaultConfig{}
+	fields := map[string]*string{
+		"address":       &vault.Address,
+		"namespace":     &vault.Namespace,
+		"authMethod":    &vault.AuthMethod,
+		"authMount":     &vault.AuthMount,
+		"token":         &vault.Token,
+		"roleId":        &vault.RoleID,
+		"secretId":      &vault.SecretID,
+		"role":          &vault.Role,
+		"jwtPath":       &vault.JWTPath,
+		"path":          &vault.Path,
+		"usernameField": &vault.UsernameField,
+		"passwordField": &vault.PasswordField,
+		"tlsPath":       &vault.TLSPath,
+	}
+	for key, field := range fields {
+		if val, ok := raw[key].(string); ok {
+			*field = val
+		}
+	}
+
+	if val, ok := raw["tlsParams"].(map[string]interface{}); ok {
+		vault.TLSParams = val
+	}
+
+	if val, ok := raw["refreshInterval"].(string); ok {
+		interval, err := time.ParseDuration(val)
+		if err != nil {
+			return nil, fmt.Errorf("invalid refreshInterval: %w", err)
+		}
+		vault.RefreshInterval = interval
+	}
+
+	return vault, nil
+}
+
 // parseRateLimits parses rate limits from raw map
 func (cl *ConfigLoader) parseRateLimits(raw map[string]interface{}) (map[string]RateLimit, error) {
 	limits := make(map[string]RateLimit)
@@ -315,14 +495,15 @@ func (cl *ConfigLoader) parseRateLimits(raw map[string]interface{}) (map[string]
 	for scope, limitRaw := range raw {
 		switch v := limitRaw.(type) {
 		case string:
-			// Parse rate string like "100/min"
-			requests, window, err := ParseRateString(v)
+			// Parse rate string like "100/min" or "100/min burst 20"
+			limit, err := ParseLimit(v)
 			if err != nil {
 				return nil, fmt.Errorf("invalid rate limit for scope %s: %w", scope, err)
 			}
 			limits[scope] = RateLimit{
-				Requests: requests,
-				Window:   window,
+				Requests:  limit.Requests,
+				Window:    limit.Window,
+				BurstSize: limit.Burst,
 			}
 
 		case map[string]interface{}:
@@ -477,6 +658,10 @@ func (cl *ConfigLoader) mergeConfigs(dest, src *Config) error {
 		dest.KeyPrefix = src.KeyPrefix
 	}
 
+	if src.WindowAlignment != cl.defaults.WindowAlignment {
+		dest.WindowAlignment = src.WindowAlignment
+	}
+
 	if src.EnableMetrics != cl.defaults.EnableMetrics {
 		dest.EnableMetrics = src.EnableMetrics
 	}
@@ -496,6 +681,28 @@ func (cl *ConfigLoader) mergeConfigs(dest, src *Config) error {
 	// Merge Redis config
 	cl.mergeRedisConfig(&dest.Redis, &src.Redis)
 
+	// Merge Postgres config
+	if src.Postgres.DSN != "" {
+		dest.Postgres.DSN = src.Postgres.DSN
+	}
+	if src.Postgres.Driver != "" {
+		dest.Postgres.Driver = src.Postgres.Driver
+	}
+	if src.Postgres.Table != "" {
+		dest.Postgres.Table = src.Postgres.Table
+	}
+	if src.Postgres.Unlogged {
+		dest.Postgres.Unlogged = true
+	}
+
+	// Merge state compression
+	for algorithm, compression := range src.StateCompression {
+		if dest.StateCompression == nil {
+			dest.StateCompression = make(map[string]CompressionConfig)
+		}
+		dest.StateCompression[algorithm] = compression
+	}
+
 	// Merge rate limits maps
 	cl.mergeRateLimitMaps(dest.DefaultLimits, src.DefaultLimits)
 	cl.mergeRateLimitMaps(dest.ScopeLimits, src.ScopeLimits)
@@ -508,6 +715,9 @@ func (cl *ConfigLoader) mergeConfigs(dest, src *Config) error {
 		dest.TierLimits[tier] = tierConfig
 	}
 
+	// Denied scope patterns accumulate, so a layer cannot lift another's deny-list
+	dest.DeniedScopes = append(dest.DeniedScopes, src.DeniedScopes...)
+
 	// Merge entity overrides
 	for entity, entityConfig := range src.EntityOverrides {
 		if dest.EntityOverrides == nil {
@@ -545,6 +755,18 @@ func (cl *ConfigLoader) mergeRedisConfig(dest, src *RedisConfig) {
 	if src.TLS != cl.defaults.Redis.TLS {
 		dest.TLS = src.TLS
 	}
+	if len(src.Shards) > 0 {
+		dest.Shards = src.Shards
+	}
+	if src.ShardFailurePolicy != cl.defaults.Redis.ShardFailurePolicy {
+		dest.ShardFailurePolicy = src.ShardFailurePolicy
+	}
+	if src.Vault != nil {
+		dest.Vault = src.Vault
+	}
+	if src.Secrets != nil {
+		dest.Secrets = src.Secrets
+	}
 }
 
 // mergeRateLimitMaps merges rate limit maps
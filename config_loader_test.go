@@ -2,6 +2,7 @@
 package ratelimit
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
 	"strings"
@@ -619,3 +620,101 @@ func TestConfigSource_Implementations(t *testing.T) {
 		t.Error("Expected error for invalid format")
 	}
 }
+
+func TestConfigLoader_Strict(t *testing.T) {
+	config := `{
+		"algorithm": "sliding_window",
+		"defualtLimits": {"global": "100/1m"},
+		"scopeLimits": {"upload": {"requests": 10, "windw": "1m"}},
+		"tierLimits": {"pro": {"scopeLimits": {"upload": "50/1m"}, "limit": "1"}},
+		"entityOverrides": {
+			"user-1": {"global": "1000/1m"},
+			"user-2": {"limits": {"global": "10/1m"}, "enabeld": false}
+		}
+	}`
+
+	if _, err := NewConfigLoader().LoadFromJSON(strings.NewReader(config)); err != nil {
+		t.Fatalf("Expected unknown keys to be ignored by default, got %v", err)
+	}
+
+	_, err := NewConfigLoader().WithStrict(true).LoadFromJSON(strings.NewReader(config))
+	var unknown *UnknownConfigKeysError
+	if !errors.As(err, &unknown) {
+		t.Fatalf("Expected an UnknownConfigKeysError, got %v", err)
+	}
+	want := []UnknownConfigKey{
+		{Path: "defualtLimits", Suggestion: "defaultLimits"},
+		{Path: "entityOverrides.user-2.enabeld", Suggestion: "enabled"},
+		{Path: "scopeLimits.upload.windw", Suggestion: "window"},
+		{Path: "tierLimits.pro.limit", Suggestion: ""},
+	}
+	if len(unknown.Keys) != len(want) {
+		t.Fatalf("Expected %d unknown keys, got %+v", len(want), unknown.Keys)
+	}
+	for i, key := range want {
+		if unknown.Keys[i] != key {
+			t.Errorf("Unknown key %d: expected %+v, got %+v", i, key, unknown.Keys[i])
+		}
+	}
+
+	yamlConfig := "store: memory\nredis:\n  passwrod: secret\n"
+	if _, err := NewConfigLoader().WithStrict(true).LoadFromYAML(strings.NewReader(yamlConfig)); err == nil || !strings.Contains(err.Error(), "redis.passwrod (did you mean password?)") {
+		t.Errorf("Expected the YAML typo to be reported, got %v", err)
+	}
+}
+
+func TestConfigLoader_DeniedScopes(t *testing.T) {
+	config := `
+enabled: true
+store: memory
+algorithm: sliding_window
+deniedScopes: ["debug*"]
+scopeLimits:
+  search: 100/minute
+`
+	loaded, err := NewConfigLoader().WithStrict(true).LoadFromYAML(strings.NewReader(config))
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if len(loaded.DeniedScopes) != 1 || loaded.DeniedScopes[0] != "debug*" {
+		t.Fatalf("Expected the denied scope patterns, got %v", loaded.DeniedScopes)
+	}
+
+	for _, scope := range []string{"debug-tools", "__other"} {
+		loaded, err := NewConfigLoader().LoadFromYAML(strings.NewReader(config + "  " + scope + ": 5/minute\n"))
+		if err != nil {
+			t.Fatalf("Failed to load config: %v", err)
+		}
+		if err := loaded.Validate(); err == nil || !strings.Contains(err.Error(), "scope_limits") {
+			t.Errorf("Expected scope %s to fail validation, got %v", scope, err)
+		}
+	}
+}
+
+func TestConfigLoader_Postgres(t *testing.T) {
+	config := `
+enabled: true
+store: postgres
+algorithm: fixed_window
+postgres:
+  dsn: postgres://gorly@db:5432/app
+  table: ops.limits
+  unlogged: true
+`
+	loaded, err := NewConfigLoader().WithStrict(true).LoadFromYAML(strings.NewReader(config))
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	want := PostgresConfig{DSN: "postgres://gorly@db:5432/app", Table: "ops.limits", Unlogged: true}
+	if loaded.Postgres != want {
+		t.Errorf("Expected %+v, got %+v", want, loaded.Postgres)
+	}
+	if err := loaded.Validate(); err != nil {
+		t.Errorf("Expected a valid config, got %v", err)
+	}
+
+	loaded.Postgres.DSN = ""
+	if err := loaded.Validate(); err == nil || !strings.Contains(err.Error(), "postgres dsn") {
+		t.Errorf("Expected a missing DSN to fail validation, got %v", err)
+	}
+}
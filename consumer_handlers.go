@@ -0,0 +1,178 @@
+// Package ratelimit provides self-service HTTP handlers for API consumers
+package ratelimit
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// entityExtractor is implemented by limiters that can identify the caller of a request
+// the same way their middleware does
+type entityExtractor interface {
+	extractEntity(r *http.Request) string
+}
+
+// requestEntity identifies the caller of a request for the given limiter
+func requestEntity(limiter Limiter, r *http.Request) string {
+	if extractor, ok := limiter.(entityExtractor); ok {
+		return extractor.extractEntity(r)
+	}
+	return extractIP(r)
+}
+
+// wantsHTML reports whether the client asked for an HTML rendering
+func wantsHTML(r *http.Request) bool {
+	if format := r.URL.Query().Get("format"); format != "" {
+		return format == "html"
+	}
+	return strings.Contains(r.Header.Get("Accept"), "text/html")
+}
+
+// limitsDocTemplate renders the effective limits as an HTML table
+var limitsDocTemplate = template.Must(template.New("limits").Parse(`<!DOCTYPE html>
+<html>
+<head><title>Rate Limits</title></head>
+<body>
+<h1>Rate Limits</h1>
+<p>Tier: <strong>{{.Tier}}</strong></p>
+<table border="1" cellpadding="4" cellspacing="0">
+<tr><th>Scope</th><th>Requests</th><th>Window</th><th>Applies via</th></tr>
+{{range .Limits}}<tr><td>{{.Scope}}</td><td>{{.Requests}}</td><td>{{.Window}}</td><td>{{.Source}}</td></tr>
+{{end}}</table>
+<p>Generated {{.Generated}}</p>
+</body>
+</html>
+`))
+
+// LimitsDocHandler creates a handler that documents the effective limits for the calling entity.
+// Responses are JSON by default and an HTML table when the client accepts text/html or passes ?format=html.
+// Example: http.Handle("/rate-limits", ratelimit.LimitsDocHandler(limiter))
+func LimitsDocHandler(limiter Limiter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		limits, err := limiter.Limits(requestEntity(limiter, r))
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Error getting limits: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		tier := ""
+		if len(limits) > 0 {
+			tier = limits[0].Tier
+		}
+
+		if wantsHTML(r) {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.WriteHeader(http.StatusOK)
+			limitsDocTemplate.Execute(w, map[string]interface{}{
+				"Tier":      tier,
+				"Limits":    limits,
+				"Generated": time.Now().UTC().Format(time.RFC3339),
+			})
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"timestamp": time.Now().Unix(),
+			"tier":      tier,
+			"limits":    limits,
+		})
+	}
+}
+
+// ScopeUsage is the current usage of one scope for the calling entity
+type ScopeUsage struct {
+	Scope     string        `json:"scope"`
+	Limit     int64         `json:"limit"`
+	Used      int64         `json:"used"`
+	Remaining int64         `json:"remaining"`
+	Window    time.Duration `json:"window"`
+	ResetTime time.Time     `json:"reset_time"`
+	ResetUnix int64         `json:"reset"`
+	Stale     bool          `json:"stale,omitempty"` // Read from the read replica, which may lag behind
+
+	Alignment WindowAlignment `json:"alignment,omitempty"` // Whether the window resets on the clock, for everyone at once
+}
+
+// UsageHandler creates a handler that reports the calling entity's current usage across scopes.
+// The caller is identified with the limiter's own extractor and no quota is consumed,
+// so clients can poll their remaining budget before scheduling work.
+// Example: http.Handle("/rate-limits/usage", ratelimit.UsageHandler(limiter))
+func UsageHandler(limiter Limiter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		entity := requestEntity(limiter, r)
+		limits, err := limiter.Limits(entity)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Error getting limits: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		// Restrict to a single scope when requested
+		if scope := r.URL.Query().Get("scope"); scope != "" {
+			filtered := limits[:0]
+			for _, limit := range limits {
+				if limit.Scope == scope {
+					filtered = append(filtered, limit)
+				}
+			}
+			if len(filtered) == 0 {
+				http.Error(w, fmt.Sprintf("Unknown scope: %s", scope), http.StatusNotFound)
+				return
+			}
+			limits = filtered
+		}
+
+		usage := make([]ScopeUsage, 0, len(limits))
+		for _, limit := range limits {
+			result, err := limiter.Peek(r.Context(), entity, limit.Scope)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("Error getting usage: %v", err), http.StatusInternalServerError)
+				return
+			}
+			usage = append(usage, ScopeUsage{
+				Scope:     limit.Scope,
+				Limit:     result.Limit,
+				Used:      result.Used,
+				Remaining: result.Remaining,
+				Window:    result.Window,
+				ResetTime: result.ResetTime,
+				ResetUnix: result.ResetTime.Unix(),
+				Stale:     result.Stale,
+				Alignment: result.Alignment,
+			})
+		}
+
+		tier := ""
+		if len(limits) > 0 {
+			tier = limits[0].Tier
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(http.StatusOK)
+
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"timestamp": time.Now().Unix(),
+			"tier":      tier,
+			"usage":     usage,
+		})
+	}
+}
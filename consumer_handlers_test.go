@@ -0,0 +1,155 @@
+// consumer_handlers_test.go - Tests for API consumer self-service handlers
+package ratelimit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newConsumerTestLimiter(t *testing.T) Limiter {
+	t.Helper()
+
+	limiter, err := New().
+		ExtractorFunc(func(r *http.Request) string { return r.Header.Get("X-Entity") }).
+		Limit("global", "1000/hour").
+		Limit("upload", "10/minute").
+		TierLimits(map[string]string{"premium": "5000/hour"}).
+		Build()
+	if err != nil {
+		t.Fatalf("Failed to build limiter: %v", err)
+	}
+	t.Cleanup(func() { limiter.Close() })
+
+	return limiter
+}
+
+func TestLimitsDocHandler_JSON(t *testing.T) {
+	handler := LimitsDocHandler(newConsumerTestLimiter(t))
+
+	req := httptest.NewRequest("GET", "/rate-limits", nil)
+	req.Header.Set("X-Entity", "premium:user-1")
+	w := httptest.NewRecorder()
+	handler(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", w.Code)
+	}
+
+	var body struct {
+		Tier   string       `json:"tier"`
+		Limits []ScopeLimit `json:"limits"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	if body.Tier != "premium" {
+		t.Errorf("Expected tier premium, got %s", body.Tier)
+	}
+	if len(body.Limits) != 2 {
+		t.Fatalf("Expected 2 scopes, got %d", len(body.Limits))
+	}
+
+	byScope := make(map[string]ScopeLimit)
+	for _, limit := range body.Limits {
+		byScope[limit.Scope] = limit
+	}
+	if global := byScope["global"]; global.Requests != 5000 || global.Source != "tier" {
+		t.Errorf("Expected tier limit of 5000 on global, got %+v", global)
+	}
+	if upload := byScope["upload"]; upload.Requests != 10 || upload.Source != "scope" {
+		t.Errorf("Expected scope limit of 10 on upload, got %+v", upload)
+	}
+}
+
+func TestLimitsDocHandler_HTML(t *testing.T) {
+	handler := LimitsDocHandler(newConsumerTestLimiter(t))
+
+	req := httptest.NewRequest("GET", "/rate-limits", nil)
+	req.Header.Set("X-Entity", "free:user-2")
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+	w := httptest.NewRecorder()
+	handler(w, req)
+
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/html") {
+		t.Fatalf("Expected HTML response, got %s", ct)
+	}
+	body := w.Body.String()
+	if !strings.Contains(body, "<td>upload</td>") || !strings.Contains(body, "<td>1000</td>") {
+		t.Errorf("Expected limits table in HTML body, got:\n%s", body)
+	}
+}
+
+func TestLimitsDocHandler_MethodNotAllowed(t *testing.T) {
+	handler := LimitsDocHandler(newConsumerTestLimiter(t))
+
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest("POST", "/rate-limits", nil))
+
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405, got %d", w.Code)
+	}
+}
+
+func TestUsageHandler_DoesNotConsume(t *testing.T) {
+	limiter := newConsumerTestLimiter(t)
+	handler := UsageHandler(limiter)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		if _, err := limiter.Check(ctx, "free:user-3", "upload"); err != nil {
+			t.Fatalf("Check failed: %v", err)
+		}
+	}
+
+	getUsage := func() map[string]ScopeUsage {
+		req := httptest.NewRequest("GET", "/rate-limits/usage", nil)
+		req.Header.Set("X-Entity", "free:user-3")
+		w := httptest.NewRecorder()
+		handler(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d", w.Code)
+		}
+
+		var body struct {
+			Usage []ScopeUsage `json:"usage"`
+		}
+		if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+
+		byScope := make(map[string]ScopeUsage)
+		for _, u := range body.Usage {
+			byScope[u.Scope] = u
+		}
+		return byScope
+	}
+
+	for i := 0; i < 2; i++ {
+		usage := getUsage()
+		if upload := usage["upload"]; upload.Used != 3 || upload.Remaining != 7 {
+			t.Errorf("Poll %d: expected 3 used / 7 remaining on upload, got %+v", i+1, upload)
+		}
+		if global := usage["global"]; global.Used != 0 || global.Remaining != 1000 {
+			t.Errorf("Poll %d: expected untouched global scope, got %+v", i+1, global)
+		}
+	}
+}
+
+func TestUsageHandler_ScopeFilter(t *testing.T) {
+	handler := UsageHandler(newConsumerTestLimiter(t))
+
+	req := httptest.NewRequest("GET", "/rate-limits/usage?scope=missing", nil)
+	req.Header.Set("X-Entity", "free:user-4")
+	w := httptest.NewRecorder()
+	handler(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for unknown scope, got %d", w.Code)
+	}
+}
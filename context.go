@@ -0,0 +1,49 @@
+// context.go - Per-call entity and scope overrides carried in a context
+package ratelimit
+
+import (
+	"context"
+
+	"github.com/itsatony/gorly/internal/core"
+)
+
+// WithEntity attributes checks made with the returned context to entity.
+// Precedence: an explicit entity argument to Check wins, then the context value,
+// then the value derived by the middleware's extractor.
+// Example: ctx = ratelimit.WithEntity(ctx, "user:42")
+func WithEntity(ctx context.Context, entity string) context.Context {
+	return core.WithEntity(ctx, entity)
+}
+
+// WithScope charges checks made with the returned context to scope.
+// Precedence: an explicit scope argument to Check wins, then the context value,
+// then the scope derived by the middleware (or "global" for direct checks).
+// Example: ctx = ratelimit.WithScope(ctx, "billing")
+func WithScope(ctx context.Context, scope string) context.Context {
+	return core.WithScope(ctx, scope)
+}
+
+// EntityFromContext returns the entity override set with WithEntity
+func EntityFromContext(ctx context.Context) (string, bool) {
+	return core.EntityFromContext(ctx)
+}
+
+// ScopeFromContext returns the scope override set with WithScope
+func ScopeFromContext(ctx context.Context) (string, bool) {
+	return core.ScopeFromContext(ctx)
+}
+
+// callTarget resolves the entity and scope of a direct limiter call.
+// Explicit arguments win over context overrides; the scope defaults to "global".
+func callTarget(ctx context.Context, entity string, scope []string) (string, string) {
+	if entity == "" {
+		entity, _ = core.EntityFromContext(ctx)
+	}
+	if len(scope) > 0 && scope[0] != "" {
+		return entity, scope[0]
+	}
+	if override, ok := core.ScopeFromContext(ctx); ok {
+		return entity, override
+	}
+	return entity, "global"
+}
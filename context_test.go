@@ -0,0 +1,74 @@
+// context_test.go - Tests for context entity and scope overrides
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestContextOverrides_Check(t *testing.T) {
+	limiter, err := New().
+		Limit("global", "5/minute").
+		Limit("billing", "1/minute").
+		Build()
+	if err != nil {
+		t.Fatalf("Failed to build limiter: %v", err)
+	}
+	defer limiter.Close()
+
+	ctx := WithScope(WithEntity(context.Background(), "user:42"), "billing")
+
+	if entity, ok := EntityFromContext(ctx); !ok || entity != "user:42" {
+		t.Errorf("Expected entity override user:42, got %q", entity)
+	}
+
+	// Context values apply when no arguments are given
+	result, err := limiter.Check(ctx, "")
+	if err != nil || !result.Allowed || result.Limit != 1 {
+		t.Fatalf("Expected billing limit for context entity, got %+v (%v)", result, err)
+	}
+	if allowed, _ := limiter.Allow(ctx, "user:42", "billing"); allowed {
+		t.Error("Expected context entity and explicit entity to share the billing quota")
+	}
+
+	// Explicit arguments take precedence over context values
+	if result, _ := limiter.Check(ctx, "user:7"); !result.Allowed {
+		t.Error("Expected explicit entity to override context entity")
+	}
+	if result, _ := limiter.Check(ctx, "user:42", "global"); !result.Allowed || result.Limit != 5 {
+		t.Errorf("Expected explicit scope to override context scope, got %+v", result)
+	}
+}
+
+func TestContextOverrides_Middleware(t *testing.T) {
+	limiter, err := New().
+		ExtractorFunc(func(r *http.Request) string { return r.Header.Get("X-User") }).
+		Limit("global", "5/minute").
+		Limit("billing", "1/minute").
+		Build()
+	if err != nil {
+		t.Fatalf("Failed to build limiter: %v", err)
+	}
+	defer limiter.Close()
+
+	var entity, scope string
+	handler := limiter.For(HTTP).(func(http.Handler) http.Handler)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		entity, _ = r.Context().Value("gorly_entity").(string)
+		scope, _ = r.Context().Value("gorly_scope").(string)
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := createTestRequest("GET", "/", map[string]string{"X-User": "alice"})
+	req = req.WithContext(WithScope(WithEntity(req.Context(), "org:acme"), "billing"))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", w.Code)
+	}
+	if entity != "org:acme" || scope != "billing" {
+		t.Errorf("Expected context overrides to win over extractor, got entity %q scope %q", entity, scope)
+	}
+}
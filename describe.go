@@ -0,0 +1,286 @@
+// describe.go - Builder introspection and YAML export
+package ratelimit
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// CustomFunc names an extractor or scope function that is not built in
+const CustomFunc = "custom"
+
+// namedExtractors are the built-in entity extractors that can be referenced by name
+var namedExtractors = map[string]func(*http.Request) string{
+	"ip":             extractIP,
+	"api_key":        extractAPIKey,
+	"user":           extractUserID,
+	"user_with_tier": extractUserWithTier,
+	"api_key_or_ip":  extractAPIKeyOrIP,
+	"service_id":     extractServiceID,
+	"session_or_ip":  extractSessionOrIP,
+	"login_identity": extractLoginIdentity,
+	"device_or_ip":   extractDeviceOrIP,
+	"api_version":    extractAPIVersion,
+}
+
+// namedScopeFuncs are the built-in scope functions that can be referenced by name
+var namedScopeFuncs = map[string]func(*http.Request) string{
+	"api_gateway": extractAPIScope,
+	"public_api":  extractPublicAPIScope,
+	"service":     extractServiceScope,
+	"web":         extractWebScope,
+	"auth":        extractAuthScope,
+	"mobile":      extractMobileScope,
+	"ai":          extractAIScope,
+}
+
+// PresetDescription describes everything a builder configures
+type PresetDescription struct {
+	Store           string                       `yaml:"store" json:"store"`
+	RedisAddress    string                       `yaml:"redis_address,omitempty" json:"redis_address,omitempty"`
+	Algorithm       string                       `yaml:"algorithm" json:"algorithm"`
+	Extractor       string                       `yaml:"extractor" json:"extractor"`
+	EmptyEntity     string                       `yaml:"empty_entity,omitempty" json:"empty_entity,omitempty"` // shared, ip, deny or skip
+	ScopeFunc       string                       `yaml:"scope_func,omitempty" json:"scope_func,omitempty"`
+	VersionFunc     string                       `yaml:"version_func,omitempty" json:"version_func,omitempty"`
+	Limits          map[string]string            `yaml:"limits,omitempty" json:"limits,omitempty"`
+	TierLimits      map[string]map[string]string `yaml:"tier_limits,omitempty" json:"tier_limits,omitempty"`   // scope -> tier -> limit
+	UnknownTier     string                       `yaml:"unknown_tier,omitempty" json:"unknown_tier,omitempty"` // default, fallback or deny
+	FallbackTier    string                       `yaml:"fallback_tier,omitempty" json:"fallback_tier,omitempty"`
+	BandwidthLimits map[string]string            `yaml:"bandwidth_limits,omitempty" json:"bandwidth_limits,omitempty"`
+	TokenBudgets    map[string]string            `yaml:"token_budgets,omitempty" json:"token_budgets,omitempty"`
+	ScopeResets     map[string]string            `yaml:"scope_resets,omitempty" json:"scope_resets,omitempty"` // scope -> cron expression
+	Costs           map[string]int64             `yaml:"costs,omitempty" json:"costs,omitempty"`               // route pattern -> units consumed
+	PreAuthLimit    string                       `yaml:"pre_auth_limit,omitempty" json:"pre_auth_limit,omitempty"`
+	Lockout         *LockoutDescription          `yaml:"lockout,omitempty" json:"lockout,omitempty"`
+	ExemptMethods   []string                     `yaml:"exempt_methods,omitempty" json:"exempt_methods,omitempty"`
+	ExemptPreflight bool                         `yaml:"exempt_preflight,omitempty" json:"exempt_preflight,omitempty"`
+	ExemptProbes    *ProbeConfig                 `yaml:"exempt_probes,omitempty" json:"exempt_probes,omitempty"`
+	MethodScopes    map[string]string            `yaml:"method_scopes,omitempty" json:"method_scopes,omitempty"`
+	MethodScoping   bool                         `yaml:"method_scoping,omitempty" json:"method_scoping,omitempty"`
+	DeniedStatus    int                          `yaml:"denied_status,omitempty" json:"denied_status,omitempty"`
+	HeaderMode      string                       `yaml:"header_mode,omitempty" json:"header_mode,omitempty"` // full, minimal or none
+	ScopeHeaders    map[string]string            `yaml:"scope_headers,omitempty" json:"scope_headers,omitempty"`
+	HeaderNames     map[string]string            `yaml:"header_names,omitempty" json:"header_names,omitempty"`
+	Scale           float64                      `yaml:"scale,omitempty" json:"scale,omitempty"`
+	Prewarm         []PrewarmSpec                `yaml:"prewarm,omitempty" json:"prewarm,omitempty"` // Entities pre-warmed at build
+	Metrics         bool                         `yaml:"metrics" json:"metrics"`
+}
+
+// LockoutDescription describes authentication lockout settings
+type LockoutDescription struct {
+	Threshold      int      `yaml:"threshold" json:"threshold"`
+	BaseDuration   string   `yaml:"base_duration" json:"base_duration"` // e.g. "1m0s"
+	MaxDuration    string   `yaml:"max_duration" json:"max_duration"`
+	ChallengeAfter int      `yaml:"challenge_after,omitempty" json:"challenge_after,omitempty"`
+	Scopes         []string `yaml:"scopes,omitempty" json:"scopes,omitempty"`
+}
+
+// Describe returns the limits, scopes, extractors and algorithm the builder configures.
+// Functions are reported by their built-in name, or "custom" for user-supplied ones.
+// Example: fmt.Printf("%+v\n", gorly.APIGateway().Describe())
+func (b *Builder) Describe() *PresetDescription {
+	c := b.config
+	desc := &PresetDescription{
+		Store:           c.Store,
+		RedisAddress:    c.RedisAddress,
+		Algorithm:       c.Algorithm,
+		Extractor:       funcName(c.ExtractorFunc, namedExtractors),
+		EmptyEntity:     c.EmptyEntityPolicy,
+		UnknownTier:     c.UnknownTierPolicy,
+		FallbackTier:    c.FallbackTier,
+		ScopeFunc:       funcName(c.ScopeFunc, namedScopeFuncs),
+		VersionFunc:     funcName(c.VersionFunc, namedExtractors),
+		Limits:          copyStringMap(c.Limits),
+		BandwidthLimits: copyStringMap(c.BandwidthLimits),
+		TokenBudgets:    copyStringMap(c.TokenBudgets),
+		ScopeResets:     copyStringMap(c.ScopeResets),
+		Costs:           copyCostMap(c.Costs),
+		PreAuthLimit:    c.PreAuthLimit,
+		ExemptMethods:   append([]string(nil), c.ExemptMethods...),
+		ExemptPreflight: c.ExemptPreflight,
+		ExemptProbes:    probeConfigFromCore(c.Probes),
+		MethodScopes:    copyStringMap(c.MethodScopes),
+		MethodScoping:   c.MethodScoping,
+		DeniedStatus:    c.DeniedStatusCode,
+		HeaderMode:      c.HeaderMode,
+		ScopeHeaders:    copyStringMap(c.ScopeHeaderModes),
+		HeaderNames:     copyStringMap(c.HeaderNames),
+		Scale:           c.Scale,
+		Metrics:         c.MetricsEnabled,
+	}
+
+	for _, spec := range c.Prewarm {
+		desc.Prewarm = append(desc.Prewarm, PrewarmSpec{Entity: spec.Entity, Scopes: append([]string(nil), spec.Scopes...)})
+	}
+
+	if len(c.TierLimits) > 0 {
+		desc.TierLimits = make(map[string]map[string]string, len(c.TierLimits))
+		for scope, tiers := range c.TierLimits {
+			desc.TierLimits[scope] = copyStringMap(tiers)
+		}
+	}
+
+	if c.Lockout != nil {
+		desc.Lockout = &LockoutDescription{
+			Threshold:      c.Lockout.Threshold,
+			BaseDuration:   c.Lockout.BaseDuration.String(),
+			MaxDuration:    c.Lockout.MaxDuration.String(),
+			ChallengeAfter: c.Lockout.ChallengeAfter,
+			Scopes:         append([]string(nil), c.Lockout.Scopes...),
+		}
+	}
+
+	return desc
+}
+
+// ExportYAML materializes the builder's configuration as YAML that FromYAML can load back.
+// Handlers set with OnDenied, OnError, Challenge and TokenCost are code and must be set again after loading.
+// Example: data, _ := gorly.SaaSApp().ExportYAML(); os.WriteFile("ratelimit.yaml", data, 0644)
+func (b *Builder) ExportYAML() ([]byte, error) {
+	desc := b.Describe()
+	if desc.Extractor == CustomFunc || desc.ScopeFunc == CustomFunc || desc.VersionFunc == CustomFunc {
+		return nil, fmt.Errorf("cannot export custom extractor or scope functions; use built-in ones or set them after loading")
+	}
+	return yaml.Marshal(desc)
+}
+
+// FromYAML creates a builder from a configuration exported with ExportYAML
+// Example: builder, err := gorly.FromYAML(data); limiter, err := builder.Build()
+func FromYAML(data []byte) (*Builder, error) {
+	var desc PresetDescription
+	if err := yaml.Unmarshal(data, &desc); err != nil {
+		return nil, fmt.Errorf("failed to parse YAML: %w", err)
+	}
+	return desc.Builder()
+}
+
+// Builder creates a builder configured as described
+func (d *PresetDescription) Builder() (*Builder, error) {
+	b := New()
+	c := b.config
+
+	if d.Store != "" {
+		c.Store = d.Store
+	}
+	c.RedisAddress = d.RedisAddress
+	if d.Algorithm != "" {
+		c.Algorithm = d.Algorithm
+	}
+
+	var err error
+	if c.ExtractorFunc, err = lookupFunc("extractor", d.Extractor, namedExtractors, extractIP); err != nil {
+		return nil, err
+	}
+	c.EmptyEntityPolicy = d.EmptyEntity
+	if c.ScopeFunc, err = lookupFunc("scope function", d.ScopeFunc, namedScopeFuncs, nil); err != nil {
+		return nil, err
+	}
+	if c.VersionFunc, err = lookupFunc("version function", d.VersionFunc, namedExtractors, nil); err != nil {
+		return nil, err
+	}
+
+	b.Limits(d.Limits)
+	for scope, tiers := range d.TierLimits {
+		c.TierLimits[scope] = copyStringMap(tiers)
+	}
+	c.UnknownTierPolicy = d.UnknownTier
+	c.FallbackTier = d.FallbackTier
+	for scope, limit := range d.BandwidthLimits {
+		b.BandwidthLimit(scope, limit)
+	}
+	for scope, budget := range d.TokenBudgets {
+		b.TokenBudget(scope, budget)
+	}
+	for scope, cron := range d.ScopeResets {
+		b.ResetSchedule(scope, cron)
+	}
+	b.Costs(d.Costs)
+	if d.PreAuthLimit != "" {
+		b.PreAuthLimit(d.PreAuthLimit)
+	}
+	if d.Lockout != nil {
+		base, err := time.ParseDuration(d.Lockout.BaseDuration)
+		if err != nil {
+			return nil, fmt.Errorf("invalid lockout base duration: %w", err)
+		}
+		max, err := time.ParseDuration(d.Lockout.MaxDuration)
+		if err != nil {
+			return nil, fmt.Errorf("invalid lockout max duration: %w", err)
+		}
+		b.Lockout(d.Lockout.Threshold, base, max, d.Lockout.Scopes...)
+		c.Lockout.ChallengeAfter = d.Lockout.ChallengeAfter
+	}
+	b.ExemptMethods(d.ExemptMethods...)
+	c.ExemptPreflight = d.ExemptPreflight
+	if d.ExemptProbes != nil {
+		b.ExemptProbesWith(*d.ExemptProbes)
+	}
+	for method, scope := range d.MethodScopes {
+		b.MethodScope(method, scope)
+	}
+	c.MethodScoping = d.MethodScoping
+	c.DeniedStatusCode = d.DeniedStatus
+	c.HeaderMode = d.HeaderMode
+	c.ScopeHeaderModes = copyStringMap(d.ScopeHeaders)
+	c.HeaderNames = copyStringMap(d.HeaderNames)
+	c.Scale = d.Scale
+	c.MetricsEnabled = d.Metrics
+	b.Prewarm(d.Prewarm...)
+
+	return b, nil
+}
+
+// funcName returns the built-in name of fn, "" for nil or CustomFunc for unknown functions
+func funcName(fn func(*http.Request) string, named map[string]func(*http.Request) string) string {
+	if fn == nil {
+		return ""
+	}
+	ptr := reflect.ValueOf(fn).Pointer()
+	for name, candidate := range named {
+		if reflect.ValueOf(candidate).Pointer() == ptr {
+			return name
+		}
+	}
+	return CustomFunc
+}
+
+// lookupFunc resolves a built-in function by name, returning fallback for an empty name
+func lookupFunc(kind, name string, named map[string]func(*http.Request) string, fallback func(*http.Request) string) (func(*http.Request) string, error) {
+	if name == "" {
+		return fallback, nil
+	}
+	fn, ok := named[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown %s: %s", kind, name)
+	}
+	return fn, nil
+}
+
+// copyCostMap returns a copy of a cost table, or nil if it is empty
+func copyCostMap(m map[string]int64) map[string]int64 {
+	if len(m) == 0 {
+		return nil
+	}
+	dst := make(map[string]int64, len(m))
+	for k, v := range m {
+		dst[k] = v
+	}
+	return dst
+}
+
+// copyStringMap returns a copy of m, or nil if it is empty
+func copyStringMap(m map[string]string) map[string]string {
+	if len(m) == 0 {
+		return nil
+	}
+	dst := make(map[string]string, len(m))
+	for k, v := range m {
+		dst[k] = v
+	}
+	return dst
+}
@@ -0,0 +1,117 @@
+// describe_test.go - Tests for builder introspection and export
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDescribePreset(t *testing.T) {
+	desc := APIGateway().Describe()
+
+	if desc.Extractor != "ip" || desc.ScopeFunc != "api_gateway" {
+		t.Errorf("Expected ip extractor and api_gateway scopes, got %s / %s", desc.Extractor, desc.ScopeFunc)
+	}
+	if desc.Algorithm != "sliding_window" || desc.Store != "memory" || !desc.Metrics {
+		t.Errorf("Unexpected defaults: %+v", desc)
+	}
+	if desc.Limits["auth"] != "100/hour" || len(desc.Limits) != 5 {
+		t.Errorf("Unexpected limits: %v", desc.Limits)
+	}
+
+	// The description is a copy
+	desc.Limits["auth"] = "1/hour"
+	if APIGateway().Describe().Limits["auth"] != "100/hour" {
+		t.Error("Describe should not expose builder state")
+	}
+
+	custom := New().ExtractorFunc(func(r *http.Request) string { return "x" }).Describe()
+	if custom.Extractor != CustomFunc {
+		t.Errorf("Expected custom extractor, got %s", custom.Extractor)
+	}
+}
+
+func TestExportYAMLRoundTrip(t *testing.T) {
+	presets := map[string]*Builder{
+		"APIGateway":     APIGateway(),
+		"SaaSApp":        SaaSApp(),
+		"PublicAPI":      PublicAPI(),
+		"Microservice":   Microservice(),
+		"WebApp":         WebApp(),
+		"AuthProtection": AuthProtection(),
+		"MobileBackend":  MobileBackend(),
+		"AIGateway":      AIGateway(),
+	}
+
+	for name, preset := range presets {
+		t.Run(name, func(t *testing.T) {
+			data, err := preset.ExportYAML()
+			if err != nil {
+				t.Fatalf("Export failed: %v", err)
+			}
+
+			loaded, err := FromYAML(data)
+			if err != nil {
+				t.Fatalf("Failed to load exported YAML: %v\n%s", err, data)
+			}
+			if !reflect.DeepEqual(preset.Describe(), loaded.Describe()) {
+				t.Errorf("Round trip changed the configuration:\n%s", data)
+			}
+
+			limiter, err := loaded.Build()
+			if err != nil {
+				t.Fatalf("Failed to build loaded preset: %v", err)
+			}
+			limiter.Close()
+		})
+	}
+}
+
+func TestExportYAMLCustomFunc(t *testing.T) {
+	_, err := New().Limit("global", "10/minute").ScopeFunc(func(r *http.Request) string { return "x" }).ExportYAML()
+	if err == nil || !strings.Contains(err.Error(), "custom") {
+		t.Errorf("Expected error exporting custom scope function, got %v", err)
+	}
+
+	if _, err := FromYAML([]byte("extractor: telepathy\n")); err == nil {
+		t.Error("Expected error for unknown extractor")
+	}
+}
+
+func TestPrewarmFromYAML(t *testing.T) {
+	data := []byte(`
+limits:
+  global: 100/minute
+  search: 10/minute
+prewarm:
+  - entity: partner:acme
+  - entity: partner:globex
+    scopes: [search]
+`)
+	builder, err := FromYAML(data)
+	if err != nil {
+		t.Fatalf("Failed to load YAML: %v", err)
+	}
+
+	want := []PrewarmSpec{{Entity: "partner:acme"}, {Entity: "partner:globex", Scopes: []string{"search"}}}
+	if got := builder.Describe().Prewarm; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected pre-warm list %+v, got %+v", want, got)
+	}
+
+	var buildErrors []error
+	limiter, err := builder.OnError(func(err error) { buildErrors = append(buildErrors, err) }).Build()
+	if err != nil {
+		t.Fatalf("Failed to build: %v", err)
+	}
+	defer limiter.Close()
+	if len(buildErrors) > 0 {
+		t.Errorf("Expected pre-warming at build to succeed, got %v", buildErrors)
+	}
+
+	if err := limiter.Prewarm(context.Background(), []PrewarmSpec{{Entity: "partner:initech", Scopes: []string{"search"}}}); err != nil {
+		t.Errorf("Prewarm failed: %v", err)
+	}
+}
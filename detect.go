@@ -0,0 +1,50 @@
+// detect.go - Framework detection diagnostics and checked middleware adapters
+package ratelimit
+
+import (
+	"fmt"
+
+	"github.com/itsatony/gorly/internal/middleware"
+)
+
+// FrameworkType identifies the web framework a middleware adapter is built for
+type FrameworkType = middleware.FrameworkType
+
+// FrameworkDetection reports which middleware adapter fits an application value and why
+type FrameworkDetection = middleware.Detection
+
+// DetectFramework reports which framework an application value, such as a router, engine or
+// request context, belongs to, why, and which type For returns for it. It explains what
+// Middleware and For are doing when the middleware does not fit the application. Frameworks
+// with a registered adapter, e.g. from an imported ginlimit, report that adapter's type.
+// Example: log.Println(ratelimit.DetectFramework(router)) // gin middleware func(interface {}): *gin.Engine is declared in github.com/gin-gonic/gin
+func DetectFramework(app interface{}) FrameworkDetection {
+	d := middleware.Detect(app)
+	if adapter, ok := registeredAdapterFor(d.Framework); ok {
+		d.Adapter = adapter.typ
+	}
+	return d
+}
+
+// MiddlewareAs returns the middleware of a framework as T. Unlike a type assertion on the
+// result of For, a wrong T returns a MIDDLEWARE_ERROR naming the type For actually returns.
+// Example: mw, err := ratelimit.MiddlewareAs[func(http.Handler) http.Handler](limiter, ratelimit.HTTP)
+func MiddlewareAs[T any](limiter Limiter, framework FrameworkType) (T, error) {
+	mw := limiter.For(framework)
+	adapter, ok := mw.(T)
+	if !ok {
+		var want T
+		err := NewAdvancedRateLimitError(ErrCodeMiddlewareError, "Wrong middleware adapter type")
+		err.Details = fmt.Sprintf("For(%s) returns %T, not %s", framework, mw, typeName[T]())
+		return want, err.
+			WithContext("framework", framework.String()).
+			WithSuggestion(fmt.Sprintf("Assert the middleware of %s to %T", framework, mw)).
+			WithSuggestion("Use DetectFramework(app) to find the adapter an application needs")
+	}
+	return adapter, nil
+}
+
+// typeName returns the name of a type parameter, including interface types
+func typeName[T any]() string {
+	return fmt.Sprintf("%T", (*T)(nil))[1:]
+}
@@ -0,0 +1,61 @@
+// detect_test.go
+package ratelimit
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDetectFramework(t *testing.T) {
+	d := DetectFramework(http.NewServeMux())
+	if d.Framework != HTTP || !strings.Contains(d.String(), "func(http.Handler) http.Handler") {
+		t.Errorf("Expected a ServeMux to get the net/http adapter, got %s", d)
+	}
+}
+
+func TestMiddlewareAs(t *testing.T) {
+	limiter := IPLimit("3/minute")
+	defer limiter.Close()
+
+	if mw, err := MiddlewareAs[func(http.Handler) http.Handler](limiter, HTTP); err != nil || mw == nil {
+		t.Fatalf("Expected the net/http middleware, got %v", err)
+	}
+
+	_, err := MiddlewareAs[func(http.Handler) http.Handler](limiter, Gin)
+	var typed *AdvancedRateLimitError
+	if !errors.As(err, &typed) || typed.Code != ErrCodeMiddlewareError {
+		t.Fatalf("Expected a MIDDLEWARE_ERROR, got %v", err)
+	}
+	if !strings.Contains(typed.Details, "For(gin) returns func(interface {}), not func(http.Handler) http.Handler") {
+		t.Errorf("Expected the details to name both types, got %q", typed.Details)
+	}
+
+	if _, err := MiddlewareAs[http.Handler](limiter, Auto); err != nil {
+		t.Errorf("Expected the universal middleware to be an http.Handler, got %v", err)
+	}
+}
+
+func TestTypedHTTPMiddleware(t *testing.T) {
+	config := DefaultObservabilityConfig()
+	config.EnableLogging = false
+	limiters := map[string]Limiter{
+		"limiter":    IPLimit("1/minute"),
+		"observable": NewObservableLimiter(IPLimit("1/minute"), config),
+		"composite":  All(IPLimit("1/minute"), IPLimit("5/minute")),
+	}
+	for name, limiter := range limiters {
+		handler := limiter.HTTPMiddleware()(
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }))
+		for i, want := range []int{http.StatusOK, http.StatusTooManyRequests} {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+			if w.Code != want {
+				t.Errorf("%s request %d: expected %d, got %d", name, i+1, want, w.Code)
+			}
+		}
+		limiter.Close()
+	}
+}
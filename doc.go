@@ -0,0 +1,9 @@
+// Package ratelimit is gorly's rate limiter: build a Limiter with New or a preset, check
+// entities against per-scope limits, and mount it as middleware of any HTTP framework.
+//
+// This package is the stable API. Limiter, Builder, the presets, LimitResult and the error
+// types follow semantic versioning of the module. Subsystems that are still settling, such
+// as alerting, hot reload and the observability wrappers, are documented in the experimental
+// subpackages, which version their APIs separately; see package
+// github.com/itsatony/gorly/experimental for the deprecation policy.
+package ratelimit
@@ -0,0 +1,38 @@
+// echolimit/echolimit.go
+// Package echolimit adapts a gorly limiter to Echo. Unlike asserting the result of
+// limiter.For(ratelimit.Echo), a wrong adapter here is a compile error. Importing the package
+// also registers Middleware as the Echo adapter, so limiter.For(ratelimit.Echo) returns a
+// echo.MiddlewareFunc as well.
+//
+// The package is a module of its own, so the Echo dependencies are only downloaded by
+// applications that use Echo; the core gorly module depends on no web framework.
+package echolimit
+
+import (
+	"net/http"
+
+	"github.com/itsatony/gorly"
+	"github.com/labstack/echo/v4"
+)
+
+// Middleware returns Echo middleware applying the limiter to each request. Denied requests
+// get the limiter's rate limit response and never reach the handler.
+// Example: e.Use(echolimit.Middleware(limiter))
+func Middleware(limiter ratelimit.Limiter) echo.MiddlewareFunc {
+	mw := limiter.HTTPMiddleware()
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			var err error
+			mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				c.SetRequest(r)
+				err = next(c)
+			})).ServeHTTP(c.Response(), c.Request())
+			return err
+		}
+	}
+}
+
+// init lets limiter.For(ratelimit.Echo) resolve to Middleware
+func init() {
+	ratelimit.RegisterAdapter(ratelimit.Echo, Middleware)
+}
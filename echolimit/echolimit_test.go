@@ -0,0 +1,80 @@
+// echolimit/echolimit_test.go
+package echolimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/itsatony/gorly"
+	"github.com/itsatony/gorly/middleware"
+	"github.com/labstack/echo/v4"
+)
+
+func TestMiddleware(t *testing.T) {
+	limiter, err := ratelimit.New().Limit("global", "2/minute").Build()
+	if err != nil {
+		t.Fatalf("Failed to build limiter: %v", err)
+	}
+	defer limiter.Close()
+
+	handled := 0
+	e := echo.New()
+	e.Use(Middleware(limiter))
+	e.GET("/", func(c echo.Context) error {
+		handled++
+		return c.String(http.StatusOK, "OK")
+	})
+
+	for i, want := range []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests} {
+		w := httptest.NewRecorder()
+		e.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		if w.Code != want {
+			t.Errorf("Request %d: expected %d, got %d", i+1, want, w.Code)
+		}
+		if w.Header().Get("X-RateLimit-Limit") != "2" {
+			t.Errorf("Request %d: expected rate limit headers, got %v", i+1, w.Header())
+		}
+	}
+	if handled != 2 {
+		t.Errorf("Expected the denied request to skip the handler, handler ran %d times", handled)
+	}
+}
+
+func TestMiddlewareReturnsHandlerError(t *testing.T) {
+	limiter, err := ratelimit.New().Limit("global", "10/minute").Build()
+	if err != nil {
+		t.Fatalf("Failed to build limiter: %v", err)
+	}
+	defer limiter.Close()
+
+	e := echo.New()
+	e.Use(Middleware(limiter))
+	e.GET("/", func(c echo.Context) error {
+		return echo.NewHTTPError(http.StatusTeapot)
+	})
+
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if w.Code != http.StatusTeapot {
+		t.Errorf("Expected the handler error to reach Echo, got %d", w.Code)
+	}
+}
+
+func TestFor(t *testing.T) {
+	limiter, err := ratelimit.New().Limit("global", "2/minute").Build()
+	if err != nil {
+		t.Fatalf("Failed to build limiter: %v", err)
+	}
+	defer limiter.Close()
+
+	if _, ok := limiter.For(ratelimit.Echo).(echo.MiddlewareFunc); !ok {
+		t.Errorf("Expected For(Echo) to return a echo.MiddlewareFunc, got %T", limiter.For(ratelimit.Echo))
+	}
+	if adapter := ratelimit.DetectFramework(echo.New()).Adapter; adapter != "echo.MiddlewareFunc" {
+		t.Errorf("Expected detection to report the registered adapter, got %s", adapter)
+	}
+	if _, ok := middleware.Get("echo"); !ok {
+		t.Error("Expected the Echo plugin to be registered")
+	}
+}
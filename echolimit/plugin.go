@@ -1,5 +1,5 @@
-// middleware/echo.go
-package middleware
+// echolimit/plugin.go
+package echolimit
 
 import (
 	"fmt"
@@ -8,26 +8,27 @@ import (
 	"strings"
 
 	"github.com/itsatony/gorly"
+	"github.com/itsatony/gorly/middleware"
 	"github.com/labstack/echo/v4"
 )
 
-// EchoPlugin implements MiddlewarePlugin for Echo framework
-type EchoPlugin struct{}
+// Plugin implements MiddlewarePlugin for Echo framework
+type Plugin struct{}
 
 // Name returns the plugin name
-func (p *EchoPlugin) Name() string {
+func (p *Plugin) Name() string {
 	return "echo"
 }
 
 // Version returns the supported Echo version
-func (p *EchoPlugin) Version() string {
+func (p *Plugin) Version() string {
 	return ">=4.0.0"
 }
 
 // CreateMiddleware creates Echo middleware function
-func (p *EchoPlugin) CreateMiddleware(limiter ratelimit.RateLimiter, config *Config) interface{} {
+func (p *Plugin) CreateMiddleware(limiter ratelimit.RateLimiter, config *middleware.Config) interface{} {
 	if config == nil {
-		config = DefaultConfig()
+		config = middleware.DefaultConfig()
 	}
 	config.Limiter = limiter
 
@@ -48,7 +49,7 @@ func (p *EchoPlugin) CreateMiddleware(limiter ratelimit.RateLimiter, config *Con
 			}
 
 			// Process rate limiting
-			result, err := ProcessRequest(reqInfo, config)
+			result, err := middleware.ProcessRequest(reqInfo, config)
 			if err != nil {
 				if config.Logger != nil {
 					config.Logger.Error("Rate limiting failed", err, map[string]interface{}{
@@ -62,10 +63,7 @@ func (p *EchoPlugin) CreateMiddleware(limiter ratelimit.RateLimiter, config *Con
 			}
 
 			// Add rate limit headers
-			headers := BuildResponseHeaders(result, &config.ResponseConfig)
-			for key, value := range headers {
-				c.Response().Header().Set(key, value)
-			}
+			middleware.SetScopeResponseHeaders(c.Response().Header(), result, reqInfo.Scope, &config.ResponseConfig)
 
 			// Check if request is allowed
 			if !result.Allowed {
@@ -80,7 +78,7 @@ func (p *EchoPlugin) CreateMiddleware(limiter ratelimit.RateLimiter, config *Con
 				}
 
 				// Send rate limited response
-				return c.JSON(config.ResponseConfig.RateLimitedStatusCode, echo.Map{
+				return c.JSON(config.ResponseConfig.DeniedStatusCode(reqInfo.Scope), echo.Map{
 					"error":               "Rate limit exceeded",
 					"limit":               result.Limit,
 					"remaining":           result.Remaining,
@@ -101,7 +99,7 @@ func (p *EchoPlugin) CreateMiddleware(limiter ratelimit.RateLimiter, config *Con
 }
 
 // ExtractRequest extracts request information from Echo context
-func (p *EchoPlugin) ExtractRequest(frameworkRequest interface{}) (*RequestInfo, error) {
+func (p *Plugin) ExtractRequest(frameworkRequest interface{}) (*middleware.RequestInfo, error) {
 	c, ok := frameworkRequest.(echo.Context)
 	if !ok {
 		return nil, fmt.Errorf("expected echo.Context, got %T", frameworkRequest)
@@ -116,7 +114,7 @@ func (p *EchoPlugin) ExtractRequest(frameworkRequest interface{}) (*RequestInfo,
 	}
 
 	// Extract request information
-	reqInfo := &RequestInfo{
+	reqInfo := &middleware.RequestInfo{
 		Method:     req.Method,
 		Path:       req.URL.Path,
 		RemoteAddr: c.RealIP(), // Echo handles X-Forwarded-For automatically
@@ -156,7 +154,7 @@ func (p *EchoPlugin) ExtractRequest(frameworkRequest interface{}) (*RequestInfo,
 }
 
 // SendResponse sends response using Echo context
-func (p *EchoPlugin) SendResponse(frameworkResponse interface{}, status int, headers map[string]string, body []byte) error {
+func (p *Plugin) SendResponse(frameworkResponse interface{}, status int, headers map[string]string, body []byte) error {
 	c, ok := frameworkResponse.(echo.Context)
 	if !ok {
 		return fmt.Errorf("expected echo.Context, got %T", frameworkResponse)
@@ -175,32 +173,32 @@ func (p *EchoPlugin) SendResponse(frameworkResponse interface{}, status int, hea
 // Echo-specific helper functions
 // ============================================================================
 
-// EchoMiddleware creates an Echo middleware with default configuration
-func EchoMiddleware(limiter ratelimit.RateLimiter) echo.MiddlewareFunc {
-	plugin := &EchoPlugin{}
-	middleware := plugin.CreateMiddleware(limiter, DefaultConfig())
-	return middleware.(echo.MiddlewareFunc)
+// PluginMiddleware creates an Echo middleware with default configuration
+func PluginMiddleware(limiter ratelimit.RateLimiter) echo.MiddlewareFunc {
+	plugin := &Plugin{}
+	mw := plugin.CreateMiddleware(limiter, middleware.DefaultConfig())
+	return mw.(echo.MiddlewareFunc)
 }
 
-// EchoMiddlewareWithConfig creates an Echo middleware with custom configuration
-func EchoMiddlewareWithConfig(limiter ratelimit.RateLimiter, config *Config) echo.MiddlewareFunc {
-	plugin := &EchoPlugin{}
-	middleware := plugin.CreateMiddleware(limiter, config)
-	return middleware.(echo.MiddlewareFunc)
+// PluginMiddlewareWithConfig creates an Echo middleware with custom configuration
+func PluginMiddlewareWithConfig(limiter ratelimit.RateLimiter, config *middleware.Config) echo.MiddlewareFunc {
+	plugin := &Plugin{}
+	mw := plugin.CreateMiddleware(limiter, config)
+	return mw.(echo.MiddlewareFunc)
 }
 
-// EchoEntityExtractor creates an entity extractor configured for common Echo patterns
-func EchoEntityExtractor() EntityExtractor {
-	return &DefaultEntityExtractor{
+// EntityExtractor creates an entity extractor configured for common Echo patterns
+func EntityExtractor() middleware.EntityExtractor {
+	return &middleware.DefaultEntityExtractor{
 		APIKeyHeaders: []string{"X-API-Key", "Authorization", "X-Api-Key"},
 		UserIDHeaders: []string{"X-User-ID", "X-User-Id"},
 		UseIPFallback: true,
 	}
 }
 
-// EchoScopeExtractor creates a scope extractor configured for REST APIs
-func EchoScopeExtractor() ScopeExtractor {
-	return &DefaultScopeExtractor{
+// ScopeExtractor creates a scope extractor configured for REST APIs
+func ScopeExtractor() middleware.ScopeExtractor {
+	return &middleware.DefaultScopeExtractor{
 		PathScopes: map[string]string{
 			"/api/auth":   ratelimit.ScopeGlobal,
 			"/api/users":  "users",
@@ -221,14 +219,14 @@ func EchoScopeExtractor() ScopeExtractor {
 	}
 }
 
-// EchoConfig creates an Echo-optimized middleware configuration
-func EchoConfig(limiter ratelimit.RateLimiter) *Config {
-	return &Config{
+// PluginConfig creates an Echo-optimized middleware configuration
+func PluginConfig(limiter ratelimit.RateLimiter) *middleware.Config {
+	return &middleware.Config{
 		Limiter:         limiter,
-		EntityExtractor: EchoEntityExtractor(),
-		ScopeExtractor:  EchoScopeExtractor(),
-		TierExtractor:   &DefaultTierExtractor{},
-		ResponseConfig: ResponseConfig{
+		EntityExtractor: EntityExtractor(),
+		ScopeExtractor:  ScopeExtractor(),
+		TierExtractor:   &middleware.DefaultTierExtractor{},
+		ResponseConfig: middleware.ResponseConfig{
 			RateLimitedStatusCode: http.StatusTooManyRequests,
 			ErrorStatusCode:       http.StatusInternalServerError,
 			IncludeHeaders:        true,
@@ -237,7 +235,7 @@ func EchoConfig(limiter ratelimit.RateLimiter) *Config {
 			RateLimitedResponse:   []byte(`{"error":"Rate limit exceeded","code":"RATE_LIMIT_EXCEEDED"}`),
 			ErrorResponse:         []byte(`{"error":"Internal server error","code":"INTERNAL_ERROR"}`),
 		},
-		Logger:         &NoOpLogger{},
+		Logger:         &middleware.NoOpLogger{},
 		MetricsEnabled: false,
 	}
 }
@@ -246,10 +244,10 @@ func EchoConfig(limiter ratelimit.RateLimiter) *Config {
 // Advanced Echo Features
 // ============================================================================
 
-// EchoSkipHealthChecks returns a skip function that skips health check endpoints
-func EchoSkipHealthChecks() SkipFunc {
+// SkipHealthChecks returns a skip function that skips health check endpoints
+func SkipHealthChecks() middleware.SkipFunc {
 	healthPaths := []string{"/health", "/healthz", "/ping", "/status", "/metrics", "/debug"}
-	return func(req *RequestInfo) bool {
+	return func(req *middleware.RequestInfo) bool {
 		path := strings.ToLower(req.Path)
 		for _, healthPath := range healthPaths {
 			if path == healthPath || strings.HasPrefix(path, healthPath+"/") {
@@ -260,9 +258,9 @@ func EchoSkipHealthChecks() SkipFunc {
 	}
 }
 
-// EchoSkipByRoute returns a skip function that skips requests based on route patterns
-func EchoSkipByRoute(routes ...string) SkipFunc {
-	return func(req *RequestInfo) bool {
+// SkipByRoute returns a skip function that skips requests based on route patterns
+func SkipByRoute(routes ...string) middleware.SkipFunc {
+	return func(req *middleware.RequestInfo) bool {
 		if routePattern, exists := req.Metadata["route"]; exists {
 			if route, ok := routePattern.(string); ok {
 				for _, skipRoute := range routes {
@@ -276,9 +274,9 @@ func EchoSkipByRoute(routes ...string) SkipFunc {
 	}
 }
 
-// EchoSkipByUserAgent returns a skip function that skips based on User-Agent
-func EchoSkipByUserAgent(userAgents ...string) SkipFunc {
-	return func(req *RequestInfo) bool {
+// SkipByUserAgent returns a skip function that skips based on User-Agent
+func SkipByUserAgent(userAgents ...string) middleware.SkipFunc {
+	return func(req *middleware.RequestInfo) bool {
 		ua := strings.ToLower(req.UserAgent)
 		for _, skipUA := range userAgents {
 			if strings.Contains(ua, strings.ToLower(skipUA)) {
@@ -289,9 +287,9 @@ func EchoSkipByUserAgent(userAgents ...string) SkipFunc {
 	}
 }
 
-// EchoPathBasedTierExtractor extracts tier based on API path (e.g., /v1/premium/, /v1/basic/)
-func EchoPathBasedTierExtractor() TierExtractor {
-	return &PathBasedTierExtractor{
+// PathBasedTierExtractor extracts tier based on API path (e.g., /v1/premium/, /v1/basic/)
+func PathBasedTierExtractor() middleware.TierExtractor {
+	return &middleware.PathBasedTierExtractor{
 		PathTiers: map[string]string{
 			"/api/v1/premium":    ratelimit.TierPremium,
 			"/api/v1/enterprise": ratelimit.TierEnterprise,
@@ -303,33 +301,11 @@ func EchoPathBasedTierExtractor() TierExtractor {
 	}
 }
 
-// PathBasedTierExtractor extracts tier information from request path
-type PathBasedTierExtractor struct {
-	PathTiers   map[string]string
-	DefaultTier string
-}
-
-func (t *PathBasedTierExtractor) Extract(req *RequestInfo) (tier string, err error) {
-	// Check if path matches any tier pattern
-	for pathPrefix, tierName := range t.PathTiers {
-		if strings.HasPrefix(req.Path, pathPrefix) {
-			return tierName, nil
-		}
-	}
-
-	// Fall back to default tier
-	if t.DefaultTier != "" {
-		return t.DefaultTier, nil
-	}
-
-	return ratelimit.TierFree, nil
-}
-
-// EchoHeaderBasedEntityExtractor creates an entity extractor that can extract from custom headers
-func EchoHeaderBasedEntityExtractor(entityHeaders map[string]string) EntityExtractor {
-	return &HeaderBasedEntityExtractor{
+// HeaderBasedEntityExtractor creates an entity extractor that can extract from custom headers
+func HeaderBasedEntityExtractor(entityHeaders map[string]string) middleware.EntityExtractor {
+	return &middleware.HeaderBasedEntityExtractor{
 		EntityHeaders: entityHeaders,
-		DefaultEntityExtractor: DefaultEntityExtractor{
+		DefaultEntityExtractor: middleware.DefaultEntityExtractor{
 			APIKeyHeaders: []string{"Authorization", "X-API-Key"},
 			UserIDHeaders: []string{"X-User-ID"},
 			UseIPFallback: true,
@@ -337,27 +313,7 @@ func EchoHeaderBasedEntityExtractor(entityHeaders map[string]string) EntityExtra
 	}
 }
 
-// HeaderBasedEntityExtractor extracts entities based on configurable headers
-type HeaderBasedEntityExtractor struct {
-	EntityHeaders map[string]string // header -> entity type mapping
-	DefaultEntityExtractor
-}
-
-func (e *HeaderBasedEntityExtractor) Extract(req *RequestInfo) (entityID, entityType string, err error) {
-	// First check custom entity headers
-	for header, entType := range e.EntityHeaders {
-		if values, exists := req.Headers[header]; exists && len(values) > 0 {
-			if values[0] != "" {
-				return entType + ":" + values[0], entType, nil
-			}
-		}
-	}
-
-	// Fall back to default extraction
-	return e.DefaultEntityExtractor.Extract(req)
-}
-
 // init registers the Echo plugin
 func init() {
-	Register(&EchoPlugin{})
+	middleware.Register(&Plugin{})
 }
@@ -5,6 +5,8 @@ import (
 	"errors"
 	"fmt"
 	"time"
+
+	"github.com/itsatony/gorly/internal/core"
 )
 
 // ErrorCode represents specific error types
@@ -29,6 +31,7 @@ const (
 	ErrCodeWindowExpired     ErrorCode = "WINDOW_EXPIRED"
 	ErrCodeInvalidEntity     ErrorCode = "INVALID_ENTITY"
 	ErrCodeInvalidScope      ErrorCode = "INVALID_SCOPE"
+	ErrCodeInvalidTier       ErrorCode = "INVALID_TIER"
 
 	// System errors
 	ErrCodeInternalError  ErrorCode = "INTERNAL_ERROR"
@@ -125,7 +128,7 @@ func (e *AdvancedRateLimitError) HTTPStatusCode() int {
 	switch e.Code {
 	case ErrCodeRateLimitExceeded, ErrCodeQuotaExceeded:
 		return 429 // Too Many Requests
-	case ErrCodeInvalidEntity, ErrCodeInvalidScope, ErrCodeInvalidConfig:
+	case ErrCodeInvalidEntity, ErrCodeInvalidScope, ErrCodeInvalidTier, ErrCodeInvalidConfig:
 		return 400 // Bad Request
 	case ErrCodeRedisAuth:
 		return 401 // Unauthorized
@@ -217,6 +220,30 @@ func NewRedisError(code ErrorCode, message string, cause error) *AdvancedRateLim
 	return err
 }
 
+// newInputError converts an entity or scope validation failure into a typed error.
+// Other errors are returned unchanged.
+func newInputError(err error) error {
+	var inputErr *core.InputError
+	if !errors.As(err, &inputErr) {
+		return err
+	}
+
+	code, message := ErrCodeInvalidEntity, "Invalid entity"
+	suggestion := "Entities and scopes must be printable and within the configured length limits"
+	switch inputErr.Field {
+	case core.InputFieldScope:
+		code, message = ErrCodeInvalidScope, "Invalid scope"
+	case core.InputFieldTier:
+		code, message = ErrCodeInvalidTier, "Invalid tier"
+		suggestion = "Entities must claim one of the tiers configured with tier limits"
+	}
+
+	typed := NewAdvancedRateLimitError(code, message)
+	typed.Details = inputErr.Reason
+	typed.Cause = err
+	return typed.WithSuggestion(suggestion)
+}
+
 // NewInternalError creates an internal error
 func NewInternalError(message string, cause error) *AdvancedRateLimitError {
 	return &AdvancedRateLimitError{
@@ -259,6 +286,13 @@ func IsRateLimitExceeded(err error) bool {
 	return errors.As(err, &rateLimitErr) && rateLimitErr.Code == ErrCodeRateLimitExceeded
 }
 
+// IsInvalidInput checks if error is due to an entity or scope rejected by input validation
+func IsInvalidInput(err error) bool {
+	var rateLimitErr *AdvancedRateLimitError
+	return errors.As(err, &rateLimitErr) &&
+		(rateLimitErr.Code == ErrCodeInvalidEntity || rateLimitErr.Code == ErrCodeInvalidScope || rateLimitErr.Code == ErrCodeInvalidTier)
+}
+
 // IsConfigError checks if error is a configuration error
 func IsConfigError(err error) bool {
 	var rateLimitErr *AdvancedRateLimitError
@@ -7,9 +7,12 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"path/filepath"
 	"time"
 
 	ratelimit "github.com/itsatony/gorly"
+	"github.com/itsatony/gorly/experimental/alerting"
 )
 
 func main() {
@@ -103,8 +106,24 @@ func demonstrateHotReload() {
 	fmt.Println("\n🔄 Hot Reload Configuration")
 	fmt.Println("---------------------------")
 
-	// Create a configuration source (file-based for demo)
-	configSource := ratelimit.NewHotReloadFileConfigSource("config.json")
+	// Create a configuration source reading a directory of per-team fragments
+	configDir, err := os.MkdirTemp("", "gorly-limits.d")
+	if err != nil {
+		fmt.Printf("   Error creating config directory: %v\n", err)
+		return
+	}
+	defer os.RemoveAll(configDir)
+	fragments := map[string]string{
+		"00-base.yaml":   "limits:\n  global: 100/minute\nalgorithm: sliding_window\nenabled: true\n",
+		"50-search.yaml": "limits:\n  search: 50/minute\n",
+	}
+	for name, content := range fragments {
+		if err := os.WriteFile(filepath.Join(configDir, name), []byte(content), 0o644); err != nil {
+			fmt.Printf("   Error writing %s: %v\n", name, err)
+			return
+		}
+	}
+	configSource := ratelimit.NewHotReloadFileConfigSource(configDir)
 
 	// Create base limiter
 	baseLimiter, err := ratelimit.New().
@@ -249,9 +268,9 @@ func demonstrateObservability() {
 
 	// Demonstrate alerting
 	fmt.Println("\n   🚨 Alert Management:")
-	alertManager := ratelimit.NewAlertManager()
+	alertManager := alerting.NewManager()
 	alertManager.SetThreshold("error_rate", 30.0)
-	alertManager.AddHandler(ratelimit.ConsoleAlertHandler)
+	alertManager.AddHandler(alerting.ConsoleHandler)
 
 	alertManager.CheckMetrics(metrics)
 	alerts := alertManager.GetAlerts()
@@ -0,0 +1,55 @@
+// examples/lambda/main.go - Rate limiting a Lambda function behind API Gateway
+package main
+
+import (
+	"context"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/aws/aws-lambda-go/lambda"
+
+	ratelimit "github.com/itsatony/gorly"
+	"github.com/itsatony/gorly/lambdalimit"
+)
+
+// The limiter is built once per execution environment and reused by every invocation
+// it serves. ElastiCache holds the counters, so all concurrent environments share them.
+var limiter = func() ratelimit.Limiter {
+	limiter, err := ratelimit.New().
+		Redis(os.Getenv("REDIS_ADDRESS"), ratelimit.RedisTLS(), ratelimit.RedisLazyConnect()).
+		Limit("global", "100/minute").
+		ExtractorFunc(func(r *http.Request) string {
+			if key := r.Header.Get("X-API-Key"); key != "" {
+				return key
+			}
+			host, _, _ := net.SplitHostPort(r.RemoteAddr)
+			return host
+		}).
+		FailurePolicy(ratelimit.FailOpen).
+		Build()
+	if err != nil {
+		log.Fatalf("Failed to build limiter: %v", err)
+	}
+	return limiter
+}()
+
+func handle(ctx context.Context, event lambdalimit.HTTPRequest) (lambdalimit.HTTPResponse, error) {
+	body := `{"message": "Hello from a rate limited Lambda!"}`
+	if result := lambdalimit.Result(ctx); result != nil && result.Remaining < 10 {
+		body = `{"message": "Hello, slow down a little", "reset": "` + result.ResetTime.Format(time.RFC3339) + `"}`
+	}
+	return lambdalimit.HTTPResponse{
+		StatusCode: http.StatusOK,
+		Headers:    map[string]string{"Content-Type": "application/json"},
+		Body:       body,
+	}, nil
+}
+
+func main() {
+	// To limit before the function runs at all, deploy an authorizer instead:
+	// lambda.Start(lambdalimit.HTTPAuthorizer(limiter))
+	lambda.Start(lambdalimit.HTTPHandler(limiter, handle))
+}
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	ratelimit "github.com/itsatony/gorly"
+	"github.com/itsatony/gorly/experimental/alerting"
 )
 
 func main() {
@@ -43,10 +44,10 @@ func main() {
 	monitoringServer := ratelimit.NewMonitoringServer(limiter)
 
 	// Create alert manager
-	alertManager := ratelimit.NewAlertManager()
+	alertManager := alerting.NewManager()
 	alertManager.SetThreshold("error_rate", 50.0) // Alert if error rate > 50%
 	alertManager.SetThreshold("health", 1.0)      // Alert if unhealthy
-	alertManager.AddHandler(ratelimit.ConsoleAlertHandler)
+	alertManager.AddHandler(alerting.ConsoleHandler)
 
 	// Setup HTTP server with monitoring endpoints
 	setupHTTPServer(limiter, monitoringServer, alertManager)
@@ -58,7 +59,7 @@ func main() {
 	displayMetrics(limiter, alertManager)
 }
 
-func setupHTTPServer(limiter *ratelimit.ObservableLimiter, monitoring *ratelimit.MonitoringServer, alertManager *ratelimit.AlertManager) {
+func setupHTTPServer(limiter *ratelimit.ObservableLimiter, monitoring *ratelimit.MonitoringServer, alertManager *alerting.Manager) {
 	// Create main application handler
 	appMux := http.NewServeMux()
 
@@ -206,7 +207,7 @@ func runSimulation(limiter *ratelimit.ObservableLimiter) {
 	fmt.Println("   🎯 Simulation complete!")
 }
 
-func displayMetrics(limiter *ratelimit.ObservableLimiter, alertManager *ratelimit.AlertManager) {
+func displayMetrics(limiter *ratelimit.ObservableLimiter, alertManager *alerting.Manager) {
 	fmt.Println("\n📈 Final Metrics & Health Report")
 	fmt.Println("================================")
 
@@ -0,0 +1,166 @@
+// Package alerting raises alerts when the metrics of a limiter cross thresholds.
+//
+// Stability: experimental, API level v0 (see APIVersion). The API may change in minor
+// releases of gorly; see the experimental package for the deprecation policy.
+package alerting
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/itsatony/gorly/internal/outbound"
+)
+
+// APIVersion is the API level of this package, raised with every breaking change
+const APIVersion = "v0"
+
+// Manager provides basic alerting functionality
+type Manager struct {
+	alerts    []Alert
+	handlers  []Handler
+	threshold map[string]float64
+}
+
+// Alert represents an alert condition
+type Alert struct {
+	Name      string                 `json:"name"`
+	Message   string                 `json:"message"`
+	Severity  string                 `json:"severity"`
+	Timestamp time.Time              `json:"timestamp"`
+	Resolved  bool                   `json:"resolved"`
+	Metadata  map[string]interface{} `json:"metadata"`
+}
+
+// Handler defines how alerts are handled
+type Handler func(Alert)
+
+// NewManager creates a new alert manager
+func NewManager() *Manager {
+	return &Manager{
+		alerts:    make([]Alert, 0),
+		handlers:  make([]Handler, 0),
+		threshold: make(map[string]float64),
+	}
+}
+
+// AddHandler adds an alert handler
+func (am *Manager) AddHandler(handler Handler) {
+	am.handlers = append(am.handlers, handler)
+}
+
+// SetThreshold sets an alert threshold
+func (am *Manager) SetThreshold(name string, threshold float64) {
+	am.threshold[name] = threshold
+}
+
+// CheckMetrics checks metrics, as returned by ObservableLimiter.GetMetrics, against thresholds and triggers alerts
+func (am *Manager) CheckMetrics(metrics map[string]interface{}) {
+	// Check error rate
+	if requestTotal, ok := metrics["request_total"].(map[string]int64); ok {
+		if requestDenied, ok := metrics["request_denied"].(map[string]int64); ok {
+			for key := range requestTotal {
+				total := requestTotal[key]
+				denied := requestDenied[key]
+
+				if total > 0 {
+					errorRate := float64(denied) / float64(total) * 100
+					if threshold, exists := am.threshold["error_rate"]; exists && errorRate > threshold {
+						am.triggerAlert(Alert{
+							Name:      "High Error Rate",
+							Message:   fmt.Sprintf("Error rate %.2f%% exceeds threshold %.2f%% for %s", errorRate, threshold, key),
+							Severity:  "warning",
+							Timestamp: time.Now(),
+							Metadata: map[string]interface{}{
+								"key":        key,
+								"error_rate": errorRate,
+								"threshold":  threshold,
+								"total":      total,
+								"denied":     denied,
+							},
+						})
+					}
+				}
+			}
+		}
+	}
+
+	// Check if service is unhealthy
+	if healthy, ok := metrics["healthy"].(bool); ok && !healthy {
+		if threshold, exists := am.threshold["health"]; exists && threshold > 0 {
+			am.triggerAlert(Alert{
+				Name:      "Service Unhealthy",
+				Message:   "Rate limiter health check failed",
+				Severity:  "critical",
+				Timestamp: time.Now(),
+				Metadata: map[string]interface{}{
+					"healthy": healthy,
+				},
+			})
+		}
+	}
+}
+
+func (am *Manager) triggerAlert(alert Alert) {
+	am.alerts = append(am.alerts, alert)
+
+	// Trigger all handlers
+	for _, handler := range am.handlers {
+		handler(alert)
+	}
+}
+
+// GetAlerts returns current alerts
+func (am *Manager) GetAlerts() []Alert {
+	return am.alerts
+}
+
+// ConsoleHandler logs alerts to console
+func ConsoleHandler(alert Alert) {
+	fmt.Printf("[ALERT] %s - %s: %s\n", alert.Severity, alert.Name, alert.Message)
+}
+
+// HTTPHandler sends alerts to an HTTP endpoint
+func HTTPHandler(endpoint string) Handler {
+	return HTTPHandlerWithClient(endpoint, nil)
+}
+
+// HTTPHandlerWithClient posts each alert as JSON to an HTTP endpoint through client;
+// nil uses the client set with ratelimit.SetHTTPClient. Alerts are sent synchronously, each
+// bounded by a 10s timeout; failures are printed like ConsoleHandler prints alerts.
+// Example: alertManager.AddHandler(alerting.HTTPHandlerWithClient("https://hooks.internal/alerts", proxiedClient))
+func HTTPHandlerWithClient(endpoint string, client *http.Client) Handler {
+	return func(alert Alert) {
+		if err := postAlert(endpoint, outbound.Client(client), alert); err != nil {
+			fmt.Printf("[HTTP ALERT to %s] failed to send %s: %v\n", endpoint, alert.Name, err)
+		}
+	}
+}
+
+// postAlert sends one alert to an HTTP endpoint
+func postAlert(endpoint string, client *http.Client, alert Alert) error {
+	body, err := json.Marshal(alert)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), outbound.DefaultTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("endpoint returned %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+// experimental/alerting/alerting_test.go
+package alerting
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCheckMetrics(t *testing.T) {
+	var received []Alert
+	manager := NewManager()
+	manager.AddHandler(func(alert Alert) { received = append(received, alert) })
+	manager.SetThreshold("error_rate", 10)
+	manager.SetThreshold("health", 1)
+
+	manager.CheckMetrics(map[string]interface{}{
+		"request_total":  map[string]int64{"user1:global": 100, "user2:global": 100},
+		"request_denied": map[string]int64{"user1:global": 50, "user2:global": 5},
+		"healthy":        false,
+	})
+
+	if len(received) != 2 || len(manager.GetAlerts()) != 2 {
+		t.Fatalf("Expected an error rate and a health alert, got %+v", received)
+	}
+	if received[0].Name != "High Error Rate" || received[0].Metadata["key"] != "user1:global" {
+		t.Errorf("Expected the error rate alert of user1, got %+v", received[0])
+	}
+	if received[1].Severity != "critical" {
+		t.Errorf("Expected a critical health alert, got %+v", received[1])
+	}
+}
+
+func TestHTTPHandler(t *testing.T) {
+	alerts := make(chan Alert, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var alert Alert
+		if err := json.NewDecoder(r.Body).Decode(&alert); err != nil {
+			t.Errorf("Failed to decode alert: %v", err)
+		}
+		alerts <- alert
+	}))
+	defer server.Close()
+
+	HTTPHandler(server.URL)(Alert{Name: "Service Unhealthy", Severity: "critical"})
+	if alert := <-alerts; alert.Name != "Service Unhealthy" || alert.Severity != "critical" {
+		t.Errorf("Expected the alert to be posted, got %+v", alert)
+	}
+}
@@ -0,0 +1,26 @@
+// Package experimental documents the stability policy of gorly's experimental subpackages.
+//
+// The root package ratelimit is the stable surface: Limiter, Builder, the presets, results
+// and errors follow semantic versioning of the module. Subsystems still settling live in
+// subpackages of experimental:
+//
+//   - experimental/alerting: threshold alerts on limiter metrics
+//   - experimental/hotreload: runtime configuration from files, HTTP and the shared store
+//   - experimental/observability: logging, metrics and health check wrappers
+//
+// Only alerting is implemented in its subpackage. hotreload and observability are named
+// facades over implementations that stay in the root package: hot reload swaps the limits
+// of a running limiter through unexported root APIs, and the monitoring server, metrics push
+// and stats federation of the root package are built on the observable limiter, so moving
+// either would create an import cycle. Their root names, such as ratelimit.HotReloadManager
+// and ratelimit.ObservableLimiter, are therefore experimental too and follow the same policy.
+//
+// Each experimental package declares an APIVersion ("v0", "v1", ...) that is raised with every
+// breaking change to it. Such changes may ship in minor releases of gorly, are listed in
+// CHANGELOG.md, and are preceded by a release marking the affected names "Deprecated:" where
+// possible. A package that settles moves to the root package, keeping an alias in its old
+// place for at least one minor release.
+//
+// The root names of alerting, which predate this split, remain as Deprecated aliases and
+// forwarding functions, so existing code keeps compiling until the next major version.
+package experimental
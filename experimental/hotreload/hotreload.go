@@ -0,0 +1,66 @@
+// Package hotreload applies limit, cost, override and enforcement changes to a running
+// limiter from a file, an HTTP endpoint or the limiter's shared store.
+//
+// Stability: experimental, API level v0 (see APIVersion). The API may change in minor
+// releases of gorly; see the experimental package for the deprecation policy.
+//
+// The implementation stays in the root package, which it reaches into for runtime limit
+// swaps, so moving it here would create an import cycle. These names are the supported way
+// to use it; the root names they alias (ratelimit.HotReloadManager and friends) are the same
+// API and equally experimental.
+package hotreload
+
+import (
+	ratelimit "github.com/itsatony/gorly"
+)
+
+// APIVersion is the API level of this package, raised with every breaking change
+const APIVersion = "v0"
+
+// Config defines configuration that can be hot-reloaded
+type Config = ratelimit.HotReloadConfig
+
+// Source defines where configuration updates come from
+type Source = ratelimit.HotReloadConfigSource
+
+// FileSource watches a JSON or YAML file, or a directory of fragments, for configuration changes
+type FileSource = ratelimit.HotReloadFileConfigSource
+
+// HTTPSource polls an HTTP endpoint for configuration changes
+type HTTPSource = ratelimit.HTTPConfigSource
+
+// Manager applies the configurations of a source to a limiter
+type Manager = ratelimit.HotReloadManager
+
+// ReloadableLimiter is a limiter with a started Manager
+type ReloadableLimiter = ratelimit.HotReloadableLimiter
+
+// ValidationRules bound the configurations a Manager accepts
+type ValidationRules = ratelimit.ConfigValidationRules
+
+// NewFileSource watches a JSON or YAML file, or a directory of fragments, for configuration changes
+// Example: hotreload.NewManager(limiter, hotreload.NewFileSource("/etc/gorly/limits.d"))
+func NewFileSource(filePath string) *FileSource {
+	return ratelimit.NewHotReloadFileConfigSource(filePath)
+}
+
+// NewHTTPSource polls an HTTP endpoint for configuration changes
+// Example: hotreload.NewHTTPSource(configURL).WithHeader("Authorization", "Bearer "+token)
+func NewHTTPSource(endpoint string) *HTTPSource {
+	return ratelimit.NewHTTPConfigSource(endpoint)
+}
+
+// NewManager creates a manager applying the configurations of source to limiter; call Start to begin
+func NewManager(limiter ratelimit.Limiter, source Source) *Manager {
+	return ratelimit.NewHotReloadManager(limiter, source)
+}
+
+// NewReloadableLimiter wraps limiter with a started Manager
+func NewReloadableLimiter(limiter ratelimit.Limiter, source Source) (*ReloadableLimiter, error) {
+	return ratelimit.NewHotReloadableLimiter(limiter, source)
+}
+
+// DefaultValidationRules returns the rules a new Manager applies
+func DefaultValidationRules() *ValidationRules {
+	return ratelimit.DefaultValidationRules()
+}
@@ -0,0 +1,93 @@
+// Package observability wraps a limiter with logging, metrics and health checks.
+//
+// Stability: experimental, API level v0 (see APIVersion). The API may change in minor
+// releases of gorly; see the experimental package for the deprecation policy.
+//
+// The implementation stays in the root package, where the monitoring server, metrics push
+// and stats federation build on it, so moving it here would create an import cycle. These
+// names are the supported way to use it; the root names they alias (ratelimit.ObservableLimiter
+// and friends) are the same API and equally experimental.
+package observability
+
+import (
+	ratelimit "github.com/itsatony/gorly"
+)
+
+// APIVersion is the API level of this package, raised with every breaking change
+const APIVersion = "v0"
+
+// Limiter wraps a limiter with logging, metrics and health checks
+type Limiter = ratelimit.ObservableLimiter
+
+// Config selects the observability features of a Limiter
+type Config = ratelimit.ObservabilityConfig
+
+// Logger defines the logging interface
+type Logger = ratelimit.Logger
+
+// Field is a structured log field
+type Field = ratelimit.Field
+
+// LogLevel is the minimum level a DefaultLogger prints
+type LogLevel = ratelimit.LogLevel
+
+// Log levels
+const (
+	LogLevelDebug = ratelimit.LogLevelDebug
+	LogLevelInfo  = ratelimit.LogLevelInfo
+	LogLevelWarn  = ratelimit.LogLevelWarn
+	LogLevelError = ratelimit.LogLevelError
+)
+
+// DefaultLogger is a console logger
+type DefaultLogger = ratelimit.DefaultLogger
+
+// MetricsCollector defines the metrics collection interface
+type MetricsCollector = ratelimit.MetricsCollector
+
+// PrometheusMetrics collects metrics in memory for the Prometheus exposition
+type PrometheusMetrics = ratelimit.PrometheusMetrics
+
+// DurationHistogram is a histogram of check durations
+type DurationHistogram = ratelimit.DurationHistogram
+
+// MetricExemplar links a metric sample to a trace
+type MetricExemplar = ratelimit.MetricExemplar
+
+// HealthChecker runs health checks
+type HealthChecker = ratelimit.HealthChecker
+
+// HealthCheck is one registered health check
+type HealthCheck = ratelimit.HealthCheck
+
+// HealthStatus is the outcome of all health checks
+type HealthStatus = ratelimit.HealthStatus
+
+// CheckResult is the outcome of one health check
+type CheckResult = ratelimit.CheckResult
+
+// New wraps limiter with the observability features of config
+// Example: limiter := observability.New(base, observability.DefaultConfig())
+func New(limiter ratelimit.Limiter, config *Config) *Limiter {
+	return ratelimit.NewObservableLimiter(limiter, config)
+}
+
+// DefaultConfig returns the default observability configuration
+func DefaultConfig() *Config {
+	return ratelimit.DefaultObservabilityConfig()
+}
+
+// NewDefaultLogger creates a console logger printing messages at level and above
+func NewDefaultLogger(level LogLevel) *DefaultLogger {
+	return ratelimit.NewDefaultLogger(level)
+}
+
+// NewPrometheusMetrics creates an empty metrics collector
+func NewPrometheusMetrics() *PrometheusMetrics {
+	return ratelimit.NewPrometheusMetrics()
+}
+
+// NewHealthChecker creates a health checker without checks
+func NewHealthChecker() *HealthChecker {
+	return ratelimit.NewHealthChecker()
+}
@@ -0,0 +1,169 @@
+// exposition.go - Prometheus text format and OpenMetrics writer with Accept negotiation
+package ratelimit
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Content types of the metric formats served by the monitoring server
+const (
+	prometheusTextContentType = "text/plain; version=0.0.4; charset=utf-8"
+	openMetricsContentType    = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+)
+
+// Metric formats a scrape can negotiate
+const (
+	metricsFormatJSON        = "application/json"
+	metricsFormatText        = "text/plain"
+	metricsFormatOpenMetrics = "application/openmetrics-text"
+)
+
+var (
+	// labelValueEscaper escapes label values in both formats
+	labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	// textHelpEscaper escapes HELP text in the Prometheus text format, which leaves quotes alone
+	textHelpEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+)
+
+// expositionWriter writes metric families in the Prometheus text format 0.0.4 or OpenMetrics 1.0
+type expositionWriter struct {
+	openMetrics bool
+	buf         strings.Builder
+	families    int
+}
+
+// family starts a metric family. OpenMetrics names counter families without their _total suffix.
+func (ew *expositionWriter) family(name, metricType, help string) {
+	if ew.openMetrics {
+		if metricType == "counter" {
+			name = strings.TrimSuffix(name, "_total")
+		}
+		help = labelValueEscaper.Replace(help)
+	} else {
+		if ew.families > 0 {
+			ew.buf.WriteString("\n")
+		}
+		help = textHelpEscaper.Replace(help)
+	}
+	ew.families++
+	fmt.Fprintf(&ew.buf, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, metricType)
+}
+
+// sample writes one sample with labels given as name/value pairs
+func (ew *expositionWriter) sample(name, value string, labels ...string) {
+	ew.writeSample(name, value, labels)
+	ew.buf.WriteString("\n")
+}
+
+// sampleWithExemplar writes a sample and, in OpenMetrics, its exemplar. The text format has no exemplars.
+func (ew *expositionWriter) sampleWithExemplar(name, value string, exemplar MetricExemplar, labels ...string) {
+	ew.writeSample(name, value, labels)
+	if ew.openMetrics {
+		fmt.Fprintf(&ew.buf, " # {trace_id=\"%s\"} 1 %.3f",
+			labelValueEscaper.Replace(exemplar.TraceID), float64(exemplar.Timestamp.UnixMilli())/1000)
+	}
+	ew.buf.WriteString("\n")
+}
+
+func (ew *expositionWriter) writeSample(name, value string, labels []string) {
+	ew.buf.WriteString(name)
+	if len(labels) > 0 {
+		ew.buf.WriteString("{")
+		for i := 0; i+1 < len(labels); i += 2 {
+			if i > 0 {
+				ew.buf.WriteString(",")
+			}
+			fmt.Fprintf(&ew.buf, "%s=\"%s\"", labels[i], labelValueEscaper.Replace(labels[i+1]))
+		}
+		ew.buf.WriteString("}")
+	}
+	ew.buf.WriteString(" ")
+	ew.buf.WriteString(value)
+}
+
+// bound formats a histogram bucket bound. OpenMetrics requires canonical floats such as 1.0 for le labels.
+func (ew *expositionWriter) bound(value float64) string {
+	formatted := strconv.FormatFloat(value, 'g', -1, 64)
+	if ew.openMetrics && !strings.ContainsAny(formatted, ".eE") {
+		formatted += ".0"
+	}
+	return formatted
+}
+
+// String returns the exposition, terminated by # EOF in OpenMetrics
+func (ew *expositionWriter) String() string {
+	if ew.openMetrics {
+		return ew.buf.String() + "# EOF\n"
+	}
+	return ew.buf.String()
+}
+
+// formatInt formats an integer sample value
+func formatInt(value int64) string {
+	return strconv.FormatInt(value, 10)
+}
+
+// sortedKeys returns the keys of a map in order, so expositions are deterministic
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// negotiateMetricsFormat picks the offered format the Accept header gives the highest quality.
+// Ties go to the earlier offer, so the first offer is the default for requests accepting anything.
+func negotiateMetricsFormat(r *http.Request, offers ...string) string {
+	accept := r.Header.Get("Accept")
+	if accept == "" {
+		return offers[0]
+	}
+
+	best, bestQ := offers[0], 0.0
+	for _, offer := range offers {
+		if q := acceptQuality(accept, offer); q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+	return best
+}
+
+// acceptQuality returns the quality the most specific matching media range of an Accept header gives a media type
+func acceptQuality(accept, mediaType string) float64 {
+	quality, specificity := 0.0, -1
+	for _, part := range strings.Split(accept, ",") {
+		mediaRange, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		var rangeSpecificity int
+		switch {
+		case mediaRange == mediaType:
+			rangeSpecificity = 2
+		case mediaRange == "*/*":
+			rangeSpecificity = 0
+		case strings.HasSuffix(mediaRange, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(mediaRange, "*")):
+			rangeSpecificity = 1
+		default:
+			continue
+		}
+		if rangeSpecificity <= specificity {
+			continue
+		}
+		q := 1.0
+		if raw, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(raw, 64); err != nil {
+				continue
+			}
+		}
+		quality, specificity = q, rangeSpecificity
+	}
+	return quality
+}
@@ -0,0 +1,176 @@
+// exposition_test.go
+package ratelimit
+
+import (
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// goldenMetrics covers every family, with entities that need escaping
+func goldenMetrics() map[string]interface{} {
+	return map[string]interface{}{
+		"request_total": map[string]int64{
+			"user-1:global":          3,
+			`say "hi":global`:        2,
+			`DOMAIN\bob:uploads`:     1,
+			"multi\nline:global":     1,
+			"api-key-42:search":      7,
+			"api-key-42:search:fast": 4,
+		},
+		"request_denied": map[string]int64{
+			"user-1:global":   1,
+			`say "hi":global`: 1,
+		},
+		"request_denied_exemplars": map[string]MetricExemplar{
+			"user-1:global": {TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", Timestamp: time.UnixMilli(1767225600123)},
+		},
+		"request_allowed": map[string]int64{
+			"user-1:global":   2,
+			`say "hi":global`: 1,
+		},
+		"rate_limit_remaining": map[string]int64{"user-1:global": 0},
+		"rate_limit_used":      map[string]int64{"user-1:global": 3},
+		"avg_request_duration": 1500 * time.Microsecond,
+		"request_duration_histogram": DurationHistogram{
+			Buckets: []float64{0.001, 0.01, 1, 5},
+			Counts:  []int64{1, 2, 3, 3},
+			Count:   3,
+			Sum:     0.0145,
+		},
+		"healthy":           true,
+		"health_checks":     int64(12),
+		"empty_entities":    int64(1),
+		"scope_overflows":   int64(0),
+		"unknown_tiers":     int64(2),
+		"enforcement":       map[string]string{"search": "shadow", "uploads": "off"},
+		"shadow_denials":    map[string]int64{"search": 5},
+		"expired_overrides": int64(1),
+		"store_keys":        int64(42),
+		"store_pool":        &StorePoolStats{Hits: 90, Misses: 10, Timeouts: 1, TotalConns: 8, IdleConns: 6, StaleConns: 2},
+		"store_failover":    &StoreFailoverStats{FailedOver: true, Failovers: 2, Failbacks: 1, Replicated: 500, ReplicationDropped: 3, Reconciled: 7},
+		"config":            &ConfigVersion{Generation: 3, Version: "2026-10-16.1", Hash: "9f86d081884c7d65"},
+		"goroutines":        int64(17),
+		"heap_alloc_bytes":  int64(4194304),
+		"queue_size":        int64(0),
+	}
+}
+
+func TestExpositionGolden(t *testing.T) {
+	tests := []struct {
+		golden  string
+		options prometheusOptions
+	}{
+		{"metrics.prom", prometheusOptions{}},
+		{"metrics_legacy.prom", prometheusOptions{legacy: true}},
+		{"metrics.openmetrics", prometheusOptions{openMetrics: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.golden, func(t *testing.T) {
+			got := strings.ReplaceAll(convertToPrometheusFormat(goldenMetrics(), tt.options), GetVersion(), "VERSION")
+			// Maps are iterated in random order; repeated runs must still match
+			for i := 0; i < 5; i++ {
+				again := strings.ReplaceAll(convertToPrometheusFormat(goldenMetrics(), tt.options), GetVersion(), "VERSION")
+				if again != got {
+					t.Fatal("Expected identical expositions for identical metrics")
+				}
+			}
+
+			path := filepath.Join("testdata", tt.golden)
+			if *updateGolden {
+				if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+					t.Fatalf("Failed to update golden file: %v", err)
+				}
+			}
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("Failed to read golden file (run with -update to create it): %v", err)
+			}
+			if got != string(want) {
+				t.Errorf("Exposition differs from %s (run with -update if intended):\n%s", path, got)
+			}
+		})
+	}
+}
+
+func TestNegotiateMetricsFormat(t *testing.T) {
+	prometheus := []string{metricsFormatText, metricsFormatOpenMetrics}
+	metrics := []string{metricsFormatJSON, metricsFormatOpenMetrics, metricsFormatText}
+
+	tests := []struct {
+		name   string
+		accept string
+		offers []string
+		want   string
+	}{
+		{"no header", "", prometheus, metricsFormatText},
+		{"anything", "*/*", prometheus, metricsFormatText},
+		{"openmetrics", "application/openmetrics-text; version=1.0.0", prometheus, metricsFormatOpenMetrics},
+		{"prometheus scraper", "application/openmetrics-text;version=1.0.0;q=0.5,text/plain;version=0.0.4;q=0.4,*/*;q=0.1", prometheus, metricsFormatOpenMetrics},
+		{"text preferred", "application/openmetrics-text;q=0.3,text/plain", prometheus, metricsFormatText},
+		{"openmetrics refused", "application/openmetrics-text;q=0,*/*", prometheus, metricsFormatText},
+		{"json by default", "*/*", metrics, metricsFormatJSON},
+		{"browser", "text/html,application/xhtml+xml,*/*;q=0.8", metrics, metricsFormatJSON},
+		{"text on metrics", "text/plain", metrics, metricsFormatText},
+		{"text wildcard", "text/*", metrics, metricsFormatText},
+		{"prometheus scraper on metrics", "application/openmetrics-text;version=1.0.0;q=0.5,text/plain;version=0.0.4;q=0.4,*/*;q=0.1", metrics, metricsFormatOpenMetrics},
+		{"unsupported", "image/png", metrics, metricsFormatJSON},
+		{"malformed", "text/plain;q=high", prometheus, metricsFormatText},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			if got := negotiateMetricsFormat(req, tt.offers...); got != tt.want {
+				t.Errorf("Expected %s for Accept %q, got %s", tt.want, tt.accept, got)
+			}
+		})
+	}
+}
+
+func TestMetricsContentNegotiation(t *testing.T) {
+	_, server := newMetricsTestServer(t, func(*ObservabilityConfig) {})
+
+	tests := []struct {
+		path        string
+		accept      string
+		contentType string
+		eof         bool
+	}{
+		{"/metrics", "", "application/json", false},
+		{"/metrics", "text/plain", prometheusTextContentType, false},
+		{"/metrics", "application/openmetrics-text", openMetricsContentType, true},
+		{"/metrics/prometheus", "", prometheusTextContentType, false},
+		{"/metrics/prometheus", "application/openmetrics-text; version=1.0.0", openMetricsContentType, true},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		if tt.accept != "" {
+			req.Header.Set("Accept", tt.accept)
+		}
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s with Accept %q: expected 200, got %d", tt.path, tt.accept, rec.Code)
+		}
+		if ct := rec.Header().Get("Content-Type"); ct != tt.contentType {
+			t.Errorf("%s with Accept %q: expected content type %q, got %q", tt.path, tt.accept, tt.contentType, ct)
+		}
+		if eof := strings.HasSuffix(rec.Body.String(), "# EOF\n"); eof != tt.eof {
+			t.Errorf("%s with Accept %q: expected EOF marker %v, got %v", tt.path, tt.accept, tt.eof, eof)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+// failurepolicy_test.go
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/itsatony/gorly/internal/core"
+	"github.com/itsatony/gorly/stores"
+)
+
+// downStore is a store whose every counter update fails, like Redis during an outage
+type downStore struct {
+	core.Store
+}
+
+func (s *downStore) Get(ctx context.Context, key string) ([]byte, error) {
+	return nil, errors.New("connection refused")
+}
+
+func (s *downStore) Set(ctx context.Context, key string, value []byte, expiration time.Duration) error {
+	return errors.New("connection refused")
+}
+
+func TestScopeFailurePolicy(t *testing.T) {
+	builder := New().
+		Limit("global", "10/minute").
+		Limit("password-reset", "3/hour").
+		ScopeFunc(func(r *http.Request) string { return strings.TrimPrefix(r.URL.Path, "/") }).
+		FailurePolicy(FailOpen).
+		ScopeFailurePolicy("password-reset", FailClosed)
+	if err := builder.config.Validate(); err != nil {
+		t.Fatalf("Invalid configuration: %v", err)
+	}
+	memStore, err := stores.NewMemoryStore(stores.MemoryConfig{})
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	coreLimiter, err := core.NewLimiterWithStore(builder.config, &downStore{Store: memStore})
+	if err != nil {
+		t.Fatalf("Failed to create limiter: %v", err)
+	}
+	config := DefaultObservabilityConfig()
+	config.EnableLogging = false
+	limiter := NewObservableLimiter(&limiterImpl{core: coreLimiter, config: builder.config}, config)
+	defer limiter.Close()
+
+	handler := limiter.For(HTTP).(func(http.Handler) http.Handler)(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }))
+	send := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	if w := send("/global"); w.Code != http.StatusOK || w.Header().Get("X-RateLimit-Remaining") != "" {
+		t.Errorf("Expected the read scope to fail open without a budget header, got %d %v", w.Code, w.Header())
+	}
+	if w := send("/password-reset"); w.Code != http.StatusInternalServerError {
+		t.Errorf("Expected the password-reset scope to fail closed, got %d", w.Code)
+	}
+	if result, err := limiter.Check(context.Background(), "user:1", "global"); err != nil || !result.FailedOpen {
+		t.Errorf("Expected Check to report the fail-open result, got %+v, %v", result, err)
+	}
+
+	metrics := limiter.GetMetrics()
+	exposition := convertToPrometheusFormat(metrics, prometheusOptions{})
+	for _, want := range []string{
+		`gorly_failed_open_total{scope="global"} 2`,
+		`gorly_scope_failure_policy{scope="*",policy="open"} 1`,
+		`gorly_scope_failure_policy{scope="password-reset",policy="closed"} 1`,
+	} {
+		if !strings.Contains(exposition, want) {
+			t.Errorf("Expected %q in the exposition", want)
+		}
+	}
+}
+
+func TestOnStoreFailureLocalFallback(t *testing.T) {
+	builder := New().
+		Limit("global", "2/minute").
+		Limit("password-reset", "3/hour").
+		ScopeFunc(func(r *http.Request) string { return strings.TrimPrefix(r.URL.Path, "/") }).
+		OnStoreFailure(FailOpenWithLocalFallback).
+		ScopeFailurePolicy("password-reset", FailClosed)
+	memStore, err := stores.NewMemoryStore(stores.MemoryConfig{})
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	coreLimiter, err := core.NewLimiterWithStore(builder.config, &downStore{Store: memStore})
+	if err != nil {
+		t.Fatalf("Failed to create limiter: %v", err)
+	}
+	config := DefaultObservabilityConfig()
+	config.EnableLogging = false
+	limiter := NewObservableLimiter(&limiterImpl{core: coreLimiter, config: builder.config}, config)
+	defer limiter.Close()
+
+	handler := limiter.For(HTTP).(func(http.Handler) http.Handler)(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }))
+	send := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	for i, want := range []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests} {
+		if w := send("/global"); w.Code != want || w.Header().Get("X-RateLimit-Limit") != "2" {
+			t.Errorf("Request %d: expected %d from the local limiter with its headers, got %d %v", i+1, want, w.Code, w.Header())
+		}
+	}
+	if w := send("/password-reset"); w.Code != http.StatusInternalServerError {
+		t.Errorf("Expected the password-reset scope to fail closed, got %d", w.Code)
+	}
+	if result, err := limiter.Check(context.Background(), "user:1", "global"); err != nil || !result.Allowed || !result.Degraded {
+		t.Errorf("Expected Check to report the degraded result, got %+v, %v", result, err)
+	}
+
+	stats, err := limiter.Stats(context.Background())
+	if err != nil {
+		t.Fatalf("Stats failed: %v", err)
+	}
+	if stats.LocalFallbackAllowed["global"] != 3 || stats.LocalFallbackDenied["global"] != 1 || stats.FailedClosed["password-reset"] != 1 {
+		t.Errorf("Expected the degraded decisions in the stats, got %v %v %v", stats.LocalFallbackAllowed, stats.LocalFallbackDenied, stats.FailedClosed)
+	}
+	exposition := convertToPrometheusFormat(limiter.GetMetrics(), prometheusOptions{})
+	for _, want := range []string{
+		`gorly_local_fallback_decisions_total{scope="global",decision="allowed"} 3`,
+		`gorly_local_fallback_decisions_total{scope="global",decision="denied"} 1`,
+		`gorly_failed_closed_total{scope="password-reset"} 1`,
+		`gorly_scope_failure_policy{scope="*",policy="local"} 1`,
+	} {
+		if !strings.Contains(exposition, want) {
+			t.Errorf("Expected %q in the exposition", want)
+		}
+	}
+}
+
+func TestFailurePolicyBuilderValidation(t *testing.T) {
+	if _, err := New().Limit("global", "1/minute").ScopeFailurePolicy("global", "maybe").Build(); err == nil {
+		t.Error("Expected an unknown failure policy to be rejected")
+	}
+}
@@ -0,0 +1,539 @@
+// federation.go - Federation of stats across clusters into a global view
+package ratelimit
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/itsatony/gorly/internal/core"
+)
+
+// Federation defaults
+const (
+	DefaultFederationTimeout    = 10 * time.Second
+	DefaultFederationStaleAfter = 5 * time.Minute
+
+	// FederationPushPath is the aggregator endpoint clusters push their reports to
+	FederationPushPath = "/push"
+
+	// FederationScrapePath is the MonitoringServer endpoint an aggregator scrapes
+	FederationScrapePath = "/federate"
+)
+
+// maxFederationReportSize bounds the body of a pushed or scraped report
+const maxFederationReportSize = 16 << 20
+
+// TierStats contains statistics for a tier
+type TierStats struct {
+	Tier     string `json:"tier"`
+	Requests int64  `json:"requests"`
+	Denied   int64  `json:"denied"`
+}
+
+// FederationReport is the stats of one cluster as exchanged with an aggregator
+type FederationReport struct {
+	Cluster       string                      `json:"cluster"`
+	Timestamp     time.Time                   `json:"timestamp"`
+	TotalRequests int64                       `json:"total_requests"`
+	TotalDenied   int64                       `json:"total_denied"`
+	ByScope       map[string]*LimitScopeStats `json:"by_scope,omitempty"`
+	ByTier        map[string]*TierStats       `json:"by_tier,omitempty"`
+}
+
+// NewFederationReport summarizes the stats of a cluster for federation. Tiers are taken
+// from the "tier:" prefix of the entities in stats; entities without one count as "free".
+func NewFederationReport(cluster string, stats *LimitStats) *FederationReport {
+	report := &FederationReport{
+		Cluster:       cluster,
+		Timestamp:     time.Now(),
+		TotalRequests: stats.TotalRequests,
+		TotalDenied:   stats.TotalDenied,
+		ByScope:       make(map[string]*LimitScopeStats, len(stats.ByScope)),
+		ByTier:        make(map[string]*TierStats),
+	}
+	for scope, s := range stats.ByScope {
+		copied := *s
+		report.ByScope[scope] = &copied
+	}
+	for entity, s := range stats.ByEntity {
+		tier := core.DefaultTier
+		if prefix, _, ok := strings.Cut(entity, ":"); ok {
+			tier = prefix
+		}
+		addTierStats(report.ByTier, &TierStats{Tier: tier, Requests: s.Requests, Denied: s.Denied})
+	}
+	return report
+}
+
+// addTierStats adds the counts of s to the stats of its tier
+func addTierStats(byTier map[string]*TierStats, s *TierStats) {
+	existing, ok := byTier[s.Tier]
+	if !ok {
+		existing = &TierStats{Tier: s.Tier}
+		byTier[s.Tier] = existing
+	}
+	existing.Requests += s.Requests
+	existing.Denied += s.Denied
+}
+
+// FederationPushConfig configures pushing the stats of a cluster to a stats aggregator
+type FederationPushConfig struct {
+	// URL of the aggregator, e.g. "http://gorly-aggregator:9400"
+	URL string
+
+	// Cluster names this cluster in the global view (required)
+	Cluster string
+
+	// Token is sent as a bearer token and must match the aggregator's
+	Token string
+
+	// Interval between pushes; zero pushes only on Close
+	Interval time.Duration
+
+	// Timeout of a single push (default: 10s)
+	Timeout time.Duration
+
+	// Client sends the push requests (default: the client set with SetHTTPClient)
+	Client *http.Client
+}
+
+// validate checks the federation push configuration
+func (fc *FederationPushConfig) validate() error {
+	if fc.URL == "" {
+		return errors.New("federation URL is required")
+	}
+	if fc.Cluster == "" {
+		return errors.New("federation cluster name is required")
+	}
+	if fc.Interval < 0 || fc.Timeout < 0 {
+		return errors.New("federation interval and timeout cannot be negative")
+	}
+	return nil
+}
+
+// statsFederator periodically pushes the stats of an ObservableLimiter to an aggregator
+type statsFederator struct {
+	config  *FederationPushConfig
+	limiter *ObservableLimiter
+
+	stop      chan struct{}
+	done      sync.WaitGroup
+	closeOnce sync.Once
+}
+
+// newStatsFederator starts pushing stats on the configured interval
+func newStatsFederator(config *FederationPushConfig, limiter *ObservableLimiter) *statsFederator {
+	sf := &statsFederator{
+		config:  config,
+		limiter: limiter,
+		stop:    make(chan struct{}),
+	}
+	if config.Interval > 0 && config.validate() == nil {
+		sf.done.Add(1)
+		go sf.run()
+	}
+	return sf
+}
+
+// run pushes on every interval until stopped
+func (sf *statsFederator) run() {
+	defer sf.done.Done()
+
+	ticker := time.NewTicker(sf.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-sf.stop:
+			return
+		case <-ticker.C:
+			if err := sf.push(context.Background()); err != nil && sf.limiter.config.EnableLogging {
+				sf.limiter.config.Logger.Warn("Stats federation push failed", Field{"error", err.Error()})
+			}
+		}
+	}
+}
+
+// close stops periodic pushing and pushes the final stats
+func (sf *statsFederator) close() error {
+	var err error
+	sf.closeOnce.Do(func() {
+		close(sf.stop)
+		sf.done.Wait()
+		err = sf.push(context.Background())
+	})
+	return err
+}
+
+// push sends the current stats of the cluster to the aggregator
+func (sf *statsFederator) push(ctx context.Context) error {
+	if err := sf.config.validate(); err != nil {
+		return err
+	}
+
+	timeout := sf.config.Timeout
+	if timeout == 0 {
+		timeout = DefaultFederationTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	stats, err := sf.limiter.Stats(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to collect stats: %w", err)
+	}
+	body, err := json.Marshal(NewFederationReport(sf.config.Cluster, stats))
+	if err != nil {
+		return fmt.Errorf("failed to encode stats report: %w", err)
+	}
+
+	endpoint := strings.TrimRight(sf.config.URL, "/") + FederationPushPath
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create federation request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if sf.config.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+sf.config.Token)
+	}
+
+	resp, err := httpClient(sf.config.Client).Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to push stats: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("aggregator returned %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// PushStats pushes the current stats to the configured aggregator immediately
+func (ol *ObservableLimiter) PushStats(ctx context.Context) error {
+	if ol.federator == nil {
+		return errors.New("stats federation is not configured")
+	}
+	return ol.federator.push(ctx)
+}
+
+// handleFederate returns the stats of this cluster as a federation report for aggregators
+func (ms *MonitoringServer) handleFederate(w http.ResponseWriter, r *http.Request) {
+	stats, err := ms.limiter.Stats(r.Context())
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error getting stats: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(NewFederationReport(ms.config.Cluster, stats))
+}
+
+// AggregatorConfig configures a stats aggregator
+type AggregatorConfig struct {
+	// Sources maps cluster names to MonitoringServer URLs that are scraped, e.g.
+	// {"eu-west": "http://gorly-monitor.eu-west:9090"}. Clusters may push instead.
+	Sources map[string]string
+
+	// ScrapeInterval between scrapes of the sources; zero scrapes only on Scrape
+	ScrapeInterval time.Duration
+
+	// StaleAfter excludes clusters from the totals when their last report is older (default: 5m)
+	StaleAfter time.Duration
+
+	// Token is required as a bearer token on pushes and sent on scrapes
+	Token string
+
+	// Timeout of a single scrape (default: 10s)
+	Timeout time.Duration
+
+	// Client sends the scrape requests (default: the client set with SetHTTPClient)
+	Client *http.Client
+}
+
+// ClusterStatus describes the last report of a cluster in the global view
+type ClusterStatus struct {
+	Cluster       string    `json:"cluster"`
+	LastReport    time.Time `json:"last_report"`
+	Stale         bool      `json:"stale"` // Excluded from the totals
+	TotalRequests int64     `json:"total_requests"`
+	TotalDenied   int64     `json:"total_denied"`
+}
+
+// GlobalStats merges the latest reports of every cluster
+type GlobalStats struct {
+	Timestamp     time.Time                   `json:"timestamp"`
+	Clusters      []ClusterStatus             `json:"clusters"`
+	TotalRequests int64                       `json:"total_requests"`
+	TotalDenied   int64                       `json:"total_denied"`
+	ByScope       map[string]*LimitScopeStats `json:"by_scope"`
+	ByTier        map[string]*TierStats       `json:"by_tier"`
+}
+
+// StatsAggregator merges the stats of many clusters into a global utilization view.
+// Clusters either push reports with a FederationPushConfig or are scraped on their
+// MonitoringServer's /federate endpoint; only the latest report of each cluster counts,
+// since reports carry cumulative counts.
+type StatsAggregator struct {
+	config *AggregatorConfig
+	mux    *http.ServeMux
+
+	mu       sync.RWMutex
+	reports  map[string]*FederationReport
+	received map[string]time.Time
+
+	stop      chan struct{}
+	done      sync.WaitGroup
+	closeOnce sync.Once
+}
+
+// NewStatsAggregator creates a stats aggregator; Start begins scraping its sources
+// Example: agg := ratelimit.NewStatsAggregator(&ratelimit.AggregatorConfig{Token: token}); http.ListenAndServe(":9400", agg)
+func NewStatsAggregator(config *AggregatorConfig) *StatsAggregator {
+	if config == nil {
+		config = &AggregatorConfig{}
+	}
+	sa := &StatsAggregator{
+		config:   config,
+		mux:      http.NewServeMux(),
+		reports:  make(map[string]*FederationReport),
+		received: make(map[string]time.Time),
+		stop:     make(chan struct{}),
+	}
+	sa.mux.HandleFunc("/health", sa.handleHealth)
+	sa.mux.HandleFunc(FederationPushPath, sa.handlePush)
+	sa.mux.HandleFunc("/global", sa.handleGlobal)
+	return sa
+}
+
+// ServeHTTP implements http.Handler
+func (sa *StatsAggregator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	sa.mux.ServeHTTP(w, r)
+}
+
+// Start scrapes the sources on the configured interval until Close
+func (sa *StatsAggregator) Start() {
+	if sa.config.ScrapeInterval <= 0 || len(sa.config.Sources) == 0 {
+		return
+	}
+	sa.done.Add(1)
+	go sa.run()
+}
+
+// run scrapes on every interval until stopped
+func (sa *StatsAggregator) run() {
+	defer sa.done.Done()
+
+	ticker := time.NewTicker(sa.config.ScrapeInterval)
+	defer ticker.Stop()
+
+	for {
+		sa.Scrape(context.Background()) // Failed sources turn stale and show in the global view
+		select {
+		case <-sa.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Close stops scraping
+func (sa *StatsAggregator) Close() error {
+	sa.closeOnce.Do(func() {
+		close(sa.stop)
+		sa.done.Wait()
+	})
+	return nil
+}
+
+// Ingest records the report of a cluster, replacing its previous one
+func (sa *StatsAggregator) Ingest(report *FederationReport) error {
+	if report == nil || report.Cluster == "" {
+		return errors.New("report has no cluster name")
+	}
+
+	sa.mu.Lock()
+	defer sa.mu.Unlock()
+	if previous, ok := sa.reports[report.Cluster]; ok && report.Timestamp.Before(previous.Timestamp) {
+		return nil // Delivered out of order; the newer report stays
+	}
+	sa.reports[report.Cluster] = report
+	sa.received[report.Cluster] = time.Now()
+	return nil
+}
+
+// Scrape fetches the report of every source once and returns the errors of failed sources
+func (sa *StatsAggregator) Scrape(ctx context.Context) error {
+	clusters := make([]string, 0, len(sa.config.Sources))
+	for cluster := range sa.config.Sources {
+		clusters = append(clusters, cluster)
+	}
+	sort.Strings(clusters)
+
+	var errs []error
+	for _, cluster := range clusters {
+		if err := sa.scrape(ctx, cluster, sa.config.Sources[cluster]); err != nil {
+			errs = append(errs, fmt.Errorf("cluster %s: %w", cluster, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// scrape fetches the report of one source
+func (sa *StatsAggregator) scrape(ctx context.Context, cluster, source string) error {
+	timeout := sa.config.Timeout
+	if timeout == 0 {
+		timeout = DefaultFederationTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	endpoint := strings.TrimRight(source, "/") + FederationScrapePath
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create scrape request: %w", err)
+	}
+	if sa.config.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+sa.config.Token)
+	}
+
+	resp, err := httpClient(sa.config.Client).Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to scrape stats: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("monitoring server returned %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+
+	var report FederationReport
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxFederationReportSize)).Decode(&report); err != nil {
+		return fmt.Errorf("failed to decode stats report: %w", err)
+	}
+	report.Cluster = cluster // The aggregator's name for the source wins
+	return sa.Ingest(&report)
+}
+
+// Global merges the latest report of every cluster that is not stale
+func (sa *StatsAggregator) Global() *GlobalStats {
+	staleAfter := sa.config.StaleAfter
+	if staleAfter <= 0 {
+		staleAfter = DefaultFederationStaleAfter
+	}
+	now := time.Now()
+
+	global := &GlobalStats{
+		Timestamp: now,
+		Clusters:  []ClusterStatus{},
+		ByScope:   make(map[string]*LimitScopeStats),
+		ByTier:    make(map[string]*TierStats),
+	}
+
+	sa.mu.RLock()
+	defer sa.mu.RUnlock()
+
+	clusters := make([]string, 0, len(sa.reports))
+	for cluster := range sa.reports {
+		clusters = append(clusters, cluster)
+	}
+	sort.Strings(clusters)
+
+	for _, cluster := range clusters {
+		report := sa.reports[cluster]
+		status := ClusterStatus{
+			Cluster:       cluster,
+			LastReport:    sa.received[cluster],
+			Stale:         now.Sub(sa.received[cluster]) > staleAfter,
+			TotalRequests: report.TotalRequests,
+			TotalDenied:   report.TotalDenied,
+		}
+		global.Clusters = append(global.Clusters, status)
+		if status.Stale {
+			continue
+		}
+
+		global.TotalRequests += report.TotalRequests
+		global.TotalDenied += report.TotalDenied
+		for scope, s := range report.ByScope {
+			existing, ok := global.ByScope[scope]
+			if !ok {
+				existing = &LimitScopeStats{Scope: scope}
+				global.ByScope[scope] = existing
+			}
+			existing.Requests += s.Requests
+			existing.Denied += s.Denied
+			if s.LastUsed.After(existing.LastUsed) {
+				existing.LastUsed = s.LastUsed
+			}
+		}
+		for tier, s := range report.ByTier {
+			addTierStats(global.ByTier, &TierStats{Tier: tier, Requests: s.Requests, Denied: s.Denied})
+		}
+	}
+	return global
+}
+
+// handleHealth reports that the aggregator is up
+func (sa *StatsAggregator) handleHealth(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{"status": "healthy"})
+}
+
+// handlePush ingests a report pushed by a cluster
+func (sa *StatsAggregator) handlePush(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeMonitoringError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	if !sa.authorized(r) {
+		writeMonitoringError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	var report FederationReport
+	if err := json.NewDecoder(io.LimitReader(r.Body, maxFederationReportSize)).Decode(&report); err != nil {
+		writeMonitoringError(w, http.StatusBadRequest, "invalid report")
+		return
+	}
+	if err := sa.Ingest(&report); err != nil {
+		writeMonitoringError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleGlobal returns the merged stats of every cluster
+func (sa *StatsAggregator) handleGlobal(w http.ResponseWriter, r *http.Request) {
+	if !sa.authorized(r) {
+		writeMonitoringError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(sa.Global())
+}
+
+// authorized checks the bearer token of a request when the aggregator requires one
+func (sa *StatsAggregator) authorized(r *http.Request) bool {
+	if sa.config.Token == "" {
+		return true
+	}
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(token), []byte(sa.config.Token)) == 1
+}
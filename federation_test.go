@@ -0,0 +1,157 @@
+// federation_test.go
+package ratelimit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newFederationTestLimiter(t *testing.T, federation *FederationPushConfig, requests int) *ObservableLimiter {
+	t.Helper()
+
+	base, err := New().Limit("search", "2/minute").StatsWriteBehind(time.Hour, 1000).Build()
+	if err != nil {
+		t.Fatalf("Failed to build limiter: %v", err)
+	}
+	config := DefaultObservabilityConfig()
+	config.EnableLogging = false
+	config.Federation = federation
+	limiter := NewObservableLimiter(base, config)
+
+	for i := 0; i < requests; i++ {
+		if _, err := limiter.Check(context.Background(), "user-1", "search"); err != nil {
+			t.Fatalf("Check failed: %v", err)
+		}
+	}
+	return limiter
+}
+
+func TestNewFederationReport(t *testing.T) {
+	report := NewFederationReport("eu-west", &LimitStats{
+		TotalRequests: 6,
+		TotalDenied:   1,
+		ByScope:       map[string]*LimitScopeStats{"search": {Scope: "search", Requests: 6, Denied: 1}},
+		ByEntity: map[string]*EntityStats{
+			"pro:acme":   {Entity: "pro:acme", Requests: 3},
+			"pro:globex": {Entity: "pro:globex", Requests: 2, Denied: 1},
+			"user-1":     {Entity: "user-1", Requests: 1},
+		},
+	})
+
+	if report.Cluster != "eu-west" || report.TotalRequests != 6 || report.ByScope["search"].Denied != 1 {
+		t.Errorf("Unexpected report: %+v", report)
+	}
+	if pro := report.ByTier["pro"]; pro == nil || pro.Requests != 5 || pro.Denied != 1 {
+		t.Errorf("Expected 5 requests and 1 denial in tier pro, got %+v", pro)
+	}
+	if free := report.ByTier["free"]; free == nil || free.Requests != 1 {
+		t.Errorf("Expected entities without a tier in tier free, got %+v", free)
+	}
+}
+
+func TestStatsAggregatorPush(t *testing.T) {
+	aggregator := NewStatsAggregator(&AggregatorConfig{Token: "secret"})
+	server := httptest.NewServer(aggregator)
+	defer server.Close()
+
+	eu := newFederationTestLimiter(t, &FederationPushConfig{URL: server.URL, Cluster: "eu-west", Token: "secret"}, 3)
+	us := newFederationTestLimiter(t, &FederationPushConfig{URL: server.URL, Cluster: "us-east", Token: "secret"}, 2)
+	defer us.Close()
+
+	if err := us.PushStats(context.Background()); err != nil {
+		t.Fatalf("PushStats failed: %v", err)
+	}
+	if err := eu.Close(); err != nil { // Closing pushes the final stats
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	global := aggregator.Global()
+	if len(global.Clusters) != 2 || global.TotalRequests != 5 || global.TotalDenied != 1 {
+		t.Fatalf("Expected 5 requests and 1 denial across 2 clusters, got %+v", global)
+	}
+	if search := global.ByScope["search"]; search == nil || search.Requests != 5 {
+		t.Errorf("Expected 5 requests in scope search, got %+v", search)
+	}
+
+	// Pushes and the global view require the token
+	intruder := newFederationTestLimiter(t, &FederationPushConfig{URL: server.URL, Cluster: "eu-west", Token: "guess"}, 1)
+	defer intruder.Close()
+	if err := intruder.PushStats(context.Background()); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("Expected a push with the wrong token to be rejected, got %v", err)
+	}
+	resp, err := http.Get(server.URL + "/global")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without a token, got %d", resp.StatusCode)
+	}
+}
+
+func TestStatsAggregatorScrape(t *testing.T) {
+	limiter := newFederationTestLimiter(t, nil, 4)
+	defer limiter.Close()
+	monitor := httptest.NewServer(NewMonitoringServerWithConfig(limiter, &MonitoringConfig{Cluster: "local-name"}))
+	defer monitor.Close()
+
+	aggregator := NewStatsAggregator(&AggregatorConfig{
+		Sources: map[string]string{"ap-south": monitor.URL, "down": "http://127.0.0.1:1"},
+		Timeout: time.Second,
+	})
+	err := aggregator.Scrape(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "cluster down") {
+		t.Errorf("Expected the unreachable source to be reported, got %v", err)
+	}
+
+	server := httptest.NewServer(aggregator)
+	defer server.Close()
+	resp, err := http.Get(server.URL + "/global")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var global GlobalStats
+	if err := json.NewDecoder(resp.Body).Decode(&global); err != nil {
+		t.Fatalf("Failed to decode global stats: %v", err)
+	}
+	if len(global.Clusters) != 1 || global.Clusters[0].Cluster != "ap-south" || global.TotalRequests != 4 {
+		t.Errorf("Expected 4 requests from cluster ap-south, got %+v", global)
+	}
+}
+
+func TestStatsAggregatorStaleClusters(t *testing.T) {
+	aggregator := NewStatsAggregator(&AggregatorConfig{StaleAfter: time.Minute})
+	now := time.Now()
+
+	for _, report := range []*FederationReport{
+		{Cluster: "eu-west", Timestamp: now, TotalRequests: 10},
+		{Cluster: "us-east", Timestamp: now, TotalRequests: 7},
+		{Cluster: "eu-west", Timestamp: now.Add(-time.Second), TotalRequests: 3}, // Out of order
+	} {
+		if err := aggregator.Ingest(report); err != nil {
+			t.Fatalf("Ingest failed: %v", err)
+		}
+	}
+	if err := aggregator.Ingest(&FederationReport{}); err == nil {
+		t.Error("Expected a report without a cluster to be rejected")
+	}
+
+	aggregator.mu.Lock()
+	aggregator.received["us-east"] = now.Add(-2 * time.Minute)
+	aggregator.mu.Unlock()
+
+	global := aggregator.Global()
+	if global.TotalRequests != 10 {
+		t.Errorf("Expected only the fresh eu-west report in the totals, got %d", global.TotalRequests)
+	}
+	if len(global.Clusters) != 2 || !global.Clusters[1].Stale {
+		t.Errorf("Expected us-east to be listed as stale, got %+v", global.Clusters)
+	}
+}
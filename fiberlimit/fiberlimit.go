@@ -0,0 +1,27 @@
+// fiberlimit/fiberlimit.go
+// Package fiberlimit adapts a gorly limiter to Fiber. Unlike asserting the result of
+// limiter.For(ratelimit.Fiber), a wrong adapter here is a compile error. Importing the package
+// also registers Middleware as the Fiber adapter, so limiter.For(ratelimit.Fiber) returns a
+// fiber.Handler as well.
+//
+// The package is a module of its own, so the Fiber dependencies are only downloaded by
+// applications that use Fiber; the core gorly module depends on no web framework.
+package fiberlimit
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+	"github.com/itsatony/gorly"
+)
+
+// Middleware returns a Fiber handler applying the limiter to each request. Fiber requests
+// are converted to net/http ones, so extractors see the same headers and remote address.
+// Example: app.Use(fiberlimit.Middleware(limiter))
+func Middleware(limiter ratelimit.Limiter) fiber.Handler {
+	return adaptor.HTTPMiddleware(limiter.HTTPMiddleware())
+}
+
+// init lets limiter.For(ratelimit.Fiber) resolve to Middleware
+func init() {
+	ratelimit.RegisterAdapter(ratelimit.Fiber, Middleware)
+}
@@ -0,0 +1,60 @@
+// fiberlimit/fiberlimit_test.go
+package fiberlimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/itsatony/gorly"
+	"github.com/itsatony/gorly/middleware"
+)
+
+func TestMiddleware(t *testing.T) {
+	limiter, err := ratelimit.New().Limit("global", "2/minute").Build()
+	if err != nil {
+		t.Fatalf("Failed to build limiter: %v", err)
+	}
+	defer limiter.Close()
+
+	handled := 0
+	app := fiber.New()
+	app.Use(Middleware(limiter))
+	app.Get("/", func(c *fiber.Ctx) error {
+		handled++
+		return c.SendString("OK")
+	})
+
+	for i, want := range []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests} {
+		resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/", nil))
+		if err != nil {
+			t.Fatalf("Request %d failed: %v", i+1, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Errorf("Request %d: expected %d, got %d", i+1, want, resp.StatusCode)
+		}
+	}
+	if handled != 2 {
+		t.Errorf("Expected the denied request to skip the handler, handler ran %d times", handled)
+	}
+}
+
+func TestFor(t *testing.T) {
+	limiter, err := ratelimit.New().Limit("global", "2/minute").Build()
+	if err != nil {
+		t.Fatalf("Failed to build limiter: %v", err)
+	}
+	defer limiter.Close()
+
+	if _, ok := limiter.For(ratelimit.Fiber).(fiber.Handler); !ok {
+		t.Errorf("Expected For(Fiber) to return a fiber.Handler, got %T", limiter.For(ratelimit.Fiber))
+	}
+	if adapter := ratelimit.DetectFramework(fiber.New()).Adapter; adapter != "func(*fiber.Ctx) error" {
+		t.Errorf("Expected detection to report the registered adapter, got %s", adapter)
+	}
+	if _, ok := middleware.Get("fiber"); !ok {
+		t.Error("Expected the Fiber plugin to be registered")
+	}
+}
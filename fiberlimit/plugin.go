@@ -1,5 +1,5 @@
-// middleware/fiber.go
-package middleware
+// fiberlimit/plugin.go
+package fiberlimit
 
 import (
 	"fmt"
@@ -8,25 +8,26 @@ import (
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/itsatony/gorly"
+	"github.com/itsatony/gorly/middleware"
 )
 
-// FiberPlugin implements MiddlewarePlugin for Fiber framework
-type FiberPlugin struct{}
+// Plugin implements MiddlewarePlugin for Fiber framework
+type Plugin struct{}
 
 // Name returns the plugin name
-func (p *FiberPlugin) Name() string {
+func (p *Plugin) Name() string {
 	return "fiber"
 }
 
 // Version returns the supported Fiber version
-func (p *FiberPlugin) Version() string {
+func (p *Plugin) Version() string {
 	return ">=2.0.0"
 }
 
 // CreateMiddleware creates Fiber middleware function
-func (p *FiberPlugin) CreateMiddleware(limiter ratelimit.RateLimiter, config *Config) interface{} {
+func (p *Plugin) CreateMiddleware(limiter ratelimit.RateLimiter, config *middleware.Config) interface{} {
 	if config == nil {
-		config = DefaultConfig()
+		config = middleware.DefaultConfig()
 	}
 	config.Limiter = limiter
 
@@ -46,7 +47,7 @@ func (p *FiberPlugin) CreateMiddleware(limiter ratelimit.RateLimiter, config *Co
 		}
 
 		// Process rate limiting
-		result, err := ProcessRequest(reqInfo, config)
+		result, err := middleware.ProcessRequest(reqInfo, config)
 		if err != nil {
 			if config.Logger != nil {
 				config.Logger.Error("Rate limiting failed", err, map[string]interface{}{
@@ -60,10 +61,7 @@ func (p *FiberPlugin) CreateMiddleware(limiter ratelimit.RateLimiter, config *Co
 		}
 
 		// Add rate limit headers
-		headers := BuildResponseHeaders(result, &config.ResponseConfig)
-		for key, value := range headers {
-			c.Set(key, value)
-		}
+		middleware.WriteScopeResponseHeaders(result, reqInfo.Scope, &config.ResponseConfig, c.Set)
 
 		// Check if request is allowed
 		if !result.Allowed {
@@ -78,7 +76,7 @@ func (p *FiberPlugin) CreateMiddleware(limiter ratelimit.RateLimiter, config *Co
 			}
 
 			// Send rate limited response
-			return c.Status(config.ResponseConfig.RateLimitedStatusCode).JSON(fiber.Map{
+			return c.Status(config.ResponseConfig.DeniedStatusCode(reqInfo.Scope)).JSON(fiber.Map{
 				"error":               "Rate limit exceeded",
 				"limit":               result.Limit,
 				"remaining":           result.Remaining,
@@ -98,7 +96,7 @@ func (p *FiberPlugin) CreateMiddleware(limiter ratelimit.RateLimiter, config *Co
 }
 
 // ExtractRequest extracts request information from Fiber context
-func (p *FiberPlugin) ExtractRequest(frameworkRequest interface{}) (*RequestInfo, error) {
+func (p *Plugin) ExtractRequest(frameworkRequest interface{}) (*middleware.RequestInfo, error) {
 	c, ok := frameworkRequest.(*fiber.Ctx)
 	if !ok {
 		return nil, fmt.Errorf("expected *fiber.Ctx, got %T", frameworkRequest)
@@ -113,7 +111,7 @@ func (p *FiberPlugin) ExtractRequest(frameworkRequest interface{}) (*RequestInfo
 	})
 
 	// Extract request information
-	reqInfo := &RequestInfo{
+	reqInfo := &middleware.RequestInfo{
 		Method:     c.Method(),
 		Path:       c.Path(),
 		RemoteAddr: c.IP(), // Fiber handles X-Forwarded-For automatically
@@ -149,7 +147,7 @@ func (p *FiberPlugin) ExtractRequest(frameworkRequest interface{}) (*RequestInfo
 }
 
 // SendResponse sends response using Fiber context
-func (p *FiberPlugin) SendResponse(frameworkResponse interface{}, status int, headers map[string]string, body []byte) error {
+func (p *Plugin) SendResponse(frameworkResponse interface{}, status int, headers map[string]string, body []byte) error {
 	c, ok := frameworkResponse.(*fiber.Ctx)
 	if !ok {
 		return fmt.Errorf("expected *fiber.Ctx, got %T", frameworkResponse)
@@ -169,32 +167,32 @@ func (p *FiberPlugin) SendResponse(frameworkResponse interface{}, status int, he
 // Fiber-specific helper functions
 // ============================================================================
 
-// FiberMiddleware creates a Fiber middleware with default configuration
-func FiberMiddleware(limiter ratelimit.RateLimiter) fiber.Handler {
-	plugin := &FiberPlugin{}
-	middleware := plugin.CreateMiddleware(limiter, DefaultConfig())
-	return middleware.(fiber.Handler)
+// PluginMiddleware creates a Fiber middleware with default configuration
+func PluginMiddleware(limiter ratelimit.RateLimiter) fiber.Handler {
+	plugin := &Plugin{}
+	mw := plugin.CreateMiddleware(limiter, middleware.DefaultConfig())
+	return mw.(fiber.Handler)
 }
 
-// FiberMiddlewareWithConfig creates a Fiber middleware with custom configuration
-func FiberMiddlewareWithConfig(limiter ratelimit.RateLimiter, config *Config) fiber.Handler {
-	plugin := &FiberPlugin{}
-	middleware := plugin.CreateMiddleware(limiter, config)
-	return middleware.(fiber.Handler)
+// PluginMiddlewareWithConfig creates a Fiber middleware with custom configuration
+func PluginMiddlewareWithConfig(limiter ratelimit.RateLimiter, config *middleware.Config) fiber.Handler {
+	plugin := &Plugin{}
+	mw := plugin.CreateMiddleware(limiter, config)
+	return mw.(fiber.Handler)
 }
 
-// FiberEntityExtractor creates an entity extractor configured for common Fiber patterns
-func FiberEntityExtractor() EntityExtractor {
-	return &DefaultEntityExtractor{
+// EntityExtractor creates an entity extractor configured for common Fiber patterns
+func EntityExtractor() middleware.EntityExtractor {
+	return &middleware.DefaultEntityExtractor{
 		APIKeyHeaders: []string{"X-API-Key", "Authorization", "X-Api-Key"},
 		UserIDHeaders: []string{"X-User-ID", "X-User-Id", "X-UserId"},
 		UseIPFallback: true,
 	}
 }
 
-// FiberScopeExtractor creates a scope extractor configured for REST APIs
-func FiberScopeExtractor() ScopeExtractor {
-	return &DefaultScopeExtractor{
+// ScopeExtractor creates a scope extractor configured for REST APIs
+func ScopeExtractor() middleware.ScopeExtractor {
+	return &middleware.DefaultScopeExtractor{
 		PathScopes: map[string]string{
 			"/api/v1/auth":     ratelimit.ScopeGlobal,
 			"/api/v1/users":    "users",
@@ -216,14 +214,14 @@ func FiberScopeExtractor() ScopeExtractor {
 	}
 }
 
-// FiberConfig creates a Fiber-optimized middleware configuration
-func FiberConfig(limiter ratelimit.RateLimiter) *Config {
-	return &Config{
+// PluginConfig creates a Fiber-optimized middleware configuration
+func PluginConfig(limiter ratelimit.RateLimiter) *middleware.Config {
+	return &middleware.Config{
 		Limiter:         limiter,
-		EntityExtractor: FiberEntityExtractor(),
-		ScopeExtractor:  FiberScopeExtractor(),
-		TierExtractor:   &DefaultTierExtractor{},
-		ResponseConfig: ResponseConfig{
+		EntityExtractor: EntityExtractor(),
+		ScopeExtractor:  ScopeExtractor(),
+		TierExtractor:   &middleware.DefaultTierExtractor{},
+		ResponseConfig: middleware.ResponseConfig{
 			RateLimitedStatusCode: fiber.StatusTooManyRequests,
 			ErrorStatusCode:       fiber.StatusInternalServerError,
 			IncludeHeaders:        true,
@@ -232,7 +230,7 @@ func FiberConfig(limiter ratelimit.RateLimiter) *Config {
 			RateLimitedResponse:   []byte(`{"error":"Rate limit exceeded","code":"RATE_LIMIT_EXCEEDED"}`),
 			ErrorResponse:         []byte(`{"error":"Internal server error","code":"INTERNAL_ERROR"}`),
 		},
-		Logger:         &NoOpLogger{},
+		Logger:         &middleware.NoOpLogger{},
 		MetricsEnabled: false,
 	}
 }
@@ -241,10 +239,10 @@ func FiberConfig(limiter ratelimit.RateLimiter) *Config {
 // Advanced Fiber Features
 // ============================================================================
 
-// FiberSkipHealthChecks returns a skip function that skips health check endpoints
-func FiberSkipHealthChecks() SkipFunc {
+// SkipHealthChecks returns a skip function that skips health check endpoints
+func SkipHealthChecks() middleware.SkipFunc {
 	healthPaths := []string{"/health", "/healthz", "/ping", "/status", "/metrics", "/ready", "/live"}
-	return func(req *RequestInfo) bool {
+	return func(req *middleware.RequestInfo) bool {
 		path := strings.ToLower(req.Path)
 		for _, healthPath := range healthPaths {
 			if path == healthPath || strings.HasPrefix(path, healthPath+"/") {
@@ -255,9 +253,9 @@ func FiberSkipHealthChecks() SkipFunc {
 	}
 }
 
-// FiberSkipWebSocket returns a skip function that skips WebSocket upgrade requests
-func FiberSkipWebSocket() SkipFunc {
-	return func(req *RequestInfo) bool {
+// SkipWebSocket returns a skip function that skips WebSocket upgrade requests
+func SkipWebSocket() middleware.SkipFunc {
+	return func(req *middleware.RequestInfo) bool {
 		// Check for WebSocket upgrade headers
 		if connectionHeaders, exists := req.Headers["Connection"]; exists {
 			for _, conn := range connectionHeaders {
@@ -276,9 +274,9 @@ func FiberSkipWebSocket() SkipFunc {
 	}
 }
 
-// FiberBurstProtection returns a request count extractor that can handle burst requests
-func FiberBurstProtection() func(req *RequestInfo) int64 {
-	return func(req *RequestInfo) int64 {
+// BurstProtection returns a request count extractor that can handle burst requests
+func BurstProtection() func(req *middleware.RequestInfo) int64 {
+	return func(req *middleware.RequestInfo) int64 {
 		// For upload endpoints, count each MB as a separate request
 		if strings.Contains(req.Path, "/upload") {
 			if contentLengthStr := req.Headers["Content-Length"]; len(contentLengthStr) > 0 {
@@ -306,11 +304,11 @@ func FiberBurstProtection() func(req *RequestInfo) int64 {
 	}
 }
 
-// FiberDynamicSkip returns a skip function that can be configured at runtime
-func FiberDynamicSkip() (SkipFunc, func(string, bool)) {
+// DynamicSkip returns a skip function that can be configured at runtime
+func DynamicSkip() (middleware.SkipFunc, func(string, bool)) {
 	skipPaths := make(map[string]bool)
 
-	skipFunc := func(req *RequestInfo) bool {
+	skipFunc := func(req *middleware.RequestInfo) bool {
 		return skipPaths[req.Path]
 	}
 
@@ -325,64 +323,20 @@ func FiberDynamicSkip() (SkipFunc, func(string, bool)) {
 	return skipFunc, configurator
 }
 
-// FiberCustomEntityExtractor creates an entity extractor with custom logic
-func FiberCustomEntityExtractor(customExtract func(*RequestInfo) (string, string, error)) EntityExtractor {
-	return &CustomEntityExtractor{
+// CustomEntityExtractor creates an entity extractor with custom logic
+func CustomEntityExtractor(customExtract func(*middleware.RequestInfo) (string, string, error)) middleware.EntityExtractor {
+	return &middleware.CustomEntityExtractor{
 		CustomExtract: customExtract,
-		Fallback:      FiberEntityExtractor(),
-	}
-}
-
-// CustomEntityExtractor allows custom entity extraction logic
-type CustomEntityExtractor struct {
-	CustomExtract func(*RequestInfo) (string, string, error)
-	Fallback      EntityExtractor
-}
-
-func (e *CustomEntityExtractor) Extract(req *RequestInfo) (entityID, entityType string, err error) {
-	// Try custom extraction first
-	if e.CustomExtract != nil {
-		id, typ, err := e.CustomExtract(req)
-		if err == nil && id != "" {
-			return id, typ, nil
-		}
+		Fallback:      EntityExtractor(),
 	}
-
-	// Fall back to default extraction
-	if e.Fallback != nil {
-		return e.Fallback.Extract(req)
-	}
-
-	return "", "", fmt.Errorf("no entity extraction method available")
-}
-
-// FiberPerformanceLogger creates a logger that tracks performance metrics
-func FiberPerformanceLogger() Logger {
-	return &PerformanceLogger{}
-}
-
-// PerformanceLogger logs performance metrics
-type PerformanceLogger struct{}
-
-func (l *PerformanceLogger) Info(msg string, fields map[string]interface{}) {
-	// Could integrate with your logging system
-	fmt.Printf("[INFO] %s: %+v\n", msg, fields)
-}
-
-func (l *PerformanceLogger) Warn(msg string, fields map[string]interface{}) {
-	fmt.Printf("[WARN] %s: %+v\n", msg, fields)
-}
-
-func (l *PerformanceLogger) Error(msg string, err error, fields map[string]interface{}) {
-	fmt.Printf("[ERROR] %s: %v, fields: %+v\n", msg, err, fields)
 }
 
-func (l *PerformanceLogger) Debug(msg string, fields map[string]interface{}) {
-	// Debug logs can be disabled in production
-	fmt.Printf("[DEBUG] %s: %+v\n", msg, fields)
+// PerformanceLogger creates a logger that tracks performance metrics
+func PerformanceLogger() middleware.Logger {
+	return &middleware.PerformanceLogger{}
 }
 
 // init registers the Fiber plugin
 func init() {
-	Register(&FiberPlugin{})
+	middleware.Register(&Plugin{})
 }
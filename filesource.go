@@ -0,0 +1,338 @@
+// filesource.go - Hot reload configuration from a file or a directory of fragments
+package ratelimit
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Defaults of HotReloadFileConfigSource
+const (
+	defaultFilePollInterval = 5 * time.Second
+	defaultFileDebounce     = time.Second
+)
+
+// HotReloadFileConfigSource reads hot reload configuration from a JSON or YAML file, or from
+// a directory of fragment files such as limits.d/*.yaml, one per team. Fragments are merged
+// in the lexical order of their names: objects such as limits merge key by key, later
+// fragments winning, and lists such as overrides are concatenated. Prefix names with a
+// number, e.g. 00-base.yaml and 50-search.yaml, to decide which fragment wins. Hidden files,
+// like the temporary files of editors and config management tools, are ignored.
+//
+// Watch polls the files and delivers a configuration once their content has stopped
+// changing for the debounce interval, so a deployment writing several fragments is applied
+// once. Changes are detected by content rather than modification time, so files replaced
+// by an atomic rename, as Ansible and Kubernetes ConfigMaps write them, are picked up too.
+type HotReloadFileConfigSource struct {
+	path         string
+	patterns     []string
+	pollInterval time.Duration
+	debounce     time.Duration
+	errorHandler func(error)
+}
+
+// NewHotReloadFileConfigSource creates a configuration source reading path: a JSON or YAML
+// file, or a directory whose *.yaml, *.yml and *.json files are merged
+// Example: ratelimit.NewHotReloadManager(limiter, ratelimit.NewHotReloadFileConfigSource("/etc/gorly/limits.d"))
+func NewHotReloadFileConfigSource(path string) *HotReloadFileConfigSource {
+	return &HotReloadFileConfigSource{
+		path:         path,
+		patterns:     []string{"*.yaml", "*.yml", "*.json"},
+		pollInterval: defaultFilePollInterval,
+		debounce:     defaultFileDebounce,
+		errorHandler: DefaultErrorHandler,
+	}
+}
+
+// WithPatterns sets the name patterns of the fragments read from a directory
+// Example: ratelimit.NewHotReloadFileConfigSource("/etc/gorly/limits.d").WithPatterns("*.yaml")
+func (fcs *HotReloadFileConfigSource) WithPatterns(patterns ...string) *HotReloadFileConfigSource {
+	fcs.patterns = patterns
+	return fcs
+}
+
+// WithPollInterval sets how often Watch reads the files to look for changes (default: 5s)
+// Example: ratelimit.NewHotReloadFileConfigSource(path).WithPollInterval(time.Second)
+func (fcs *HotReloadFileConfigSource) WithPollInterval(interval time.Duration) *HotReloadFileConfigSource {
+	if interval > 0 {
+		fcs.pollInterval = interval
+	}
+	return fcs
+}
+
+// WithDebounce sets how long the files must stay unchanged before Watch delivers a change
+// (default: 1s); 0 delivers every change as soon as it is found
+// Example: ratelimit.NewHotReloadFileConfigSource(path).WithDebounce(5 * time.Second)
+func (fcs *HotReloadFileConfigSource) WithDebounce(debounce time.Duration) *HotReloadFileConfigSource {
+	if debounce >= 0 {
+		fcs.debounce = debounce
+	}
+	return fcs
+}
+
+// WithErrorHandler receives the errors Watch runs into, such as a fragment that does not
+// parse, once each; the last configuration delivered stays in effect (default: DefaultErrorHandler)
+// Example: ratelimit.NewHotReloadFileConfigSource(path).WithErrorHandler(func(err error) { log.Print(err) })
+func (fcs *HotReloadFileConfigSource) WithErrorHandler(handler func(error)) *HotReloadFileConfigSource {
+	fcs.errorHandler = handler
+	return fcs
+}
+
+// Watch implements HotReloadConfigSource interface
+func (fcs *HotReloadFileConfigSource) Watch(ctx context.Context) (<-chan *HotReloadConfig, error) {
+	snapshot, err := fcs.read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load initial config: %w", err)
+	}
+	config, err := fcs.parse(snapshot)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load initial config: %w", err)
+	}
+
+	configChan := make(chan *HotReloadConfig, 1)
+	configChan <- config
+	go fcs.watch(ctx, snapshot.digest, configChan)
+	return configChan, nil
+}
+
+// watch polls the files and delivers their configuration once a change has settled.
+// seen is the digest of the files last parsed, whether they parsed or not.
+func (fcs *HotReloadFileConfigSource) watch(ctx context.Context, seen string, configChan chan<- *HotReloadConfig) {
+	defer close(configChan)
+
+	ticker := time.NewTicker(fcs.pollInterval)
+	defer ticker.Stop()
+
+	var (
+		pending  string           // Digest of a change waiting to settle
+		settled  <-chan time.Time // Fires once the pending change has had the debounce interval to settle
+		reported string           // Last error reported, so a lasting failure is reported once
+	)
+	report := func(err error) {
+		if err.Error() != reported && fcs.errorHandler != nil {
+			fcs.errorHandler(err)
+		}
+		reported = err.Error()
+	}
+
+	for {
+		var snapshot *fileSnapshot
+		var err error
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if snapshot, err = fcs.read(); err != nil {
+				report(err)
+				continue
+			}
+			reported = ""
+			if snapshot.digest == pending {
+				continue
+			}
+			if snapshot.digest == seen {
+				pending, settled = "", nil // Changed back before it settled
+				continue
+			}
+			if fcs.debounce > 0 {
+				pending, settled = snapshot.digest, time.After(fcs.debounce)
+				continue
+			}
+		case <-settled:
+			settled = nil
+			if snapshot, err = fcs.read(); err != nil {
+				pending = ""
+				report(err)
+				continue
+			}
+			if snapshot.digest != pending {
+				// Still being written: give the new content its own interval
+				pending, settled = snapshot.digest, time.After(fcs.debounce)
+				continue
+			}
+		}
+
+		pending, seen = "", snapshot.digest
+		config, err := fcs.parse(snapshot)
+		if err != nil {
+			report(err)
+			continue
+		}
+		select {
+		case configChan <- config:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// GetConfig implements HotReloadConfigSource interface by reading and merging the files
+func (fcs *HotReloadFileConfigSource) GetConfig(ctx context.Context) (*HotReloadConfig, error) {
+	snapshot, err := fcs.read()
+	if err != nil {
+		return nil, err
+	}
+	return fcs.parse(snapshot)
+}
+
+// Close implements HotReloadConfigSource interface; Watch ends with its context
+func (fcs *HotReloadFileConfigSource) Close() error {
+	return nil
+}
+
+// fileSnapshot is the content of the configuration files at one point in time
+type fileSnapshot struct {
+	files   []configFile // In merge order
+	digest  string       // Hash of the names and contents of the files
+	modTime time.Time    // Latest modification time of the files
+}
+
+// configFile is one configuration file or fragment
+type configFile struct {
+	name string
+	data []byte
+}
+
+// read reads the file at the source's path, or the fragments of the directory there
+func (fcs *HotReloadFileConfigSource) read() (*fileSnapshot, error) {
+	info, err := os.Stat(fcs.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+	names := []string{fcs.path}
+	if info.IsDir() {
+		if names, err = fcs.fragments(); err != nil {
+			return nil, err
+		}
+	}
+
+	snapshot := &fileSnapshot{files: make([]configFile, 0, len(names))}
+	hash := sha256.New()
+	for _, name := range names {
+		// Stat and ReadFile follow symlinks, as Kubernetes mounts ConfigMap keys
+		fileInfo, err := os.Stat(name)
+		if err == nil && fileInfo.IsDir() {
+			continue
+		}
+		var data []byte
+		if err == nil {
+			data, err = os.ReadFile(name)
+		}
+		if errors.Is(err, fs.ErrNotExist) && info.IsDir() {
+			continue // Removed since the directory was listed
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config %s: %w", name, err)
+		}
+		if fileInfo.ModTime().After(snapshot.modTime) {
+			snapshot.modTime = fileInfo.ModTime()
+		}
+		snapshot.files = append(snapshot.files, configFile{name: name, data: data})
+		fmt.Fprintf(hash, "%s\x00%d\x00", filepath.Base(name), len(data))
+		hash.Write(data)
+	}
+	if len(snapshot.files) == 0 {
+		// An empty directory is more likely a deployment in progress than a wish for no limits
+		return nil, fmt.Errorf("no config fragments matching %s in %s", strings.Join(fcs.patterns, ", "), fcs.path)
+	}
+	snapshot.digest = hex.EncodeToString(hash.Sum(nil))
+	return snapshot, nil
+}
+
+// fragments returns the paths of the fragments in the source's directory, sorted by name
+func (fcs *HotReloadFileConfigSource) fragments() ([]string, error) {
+	entries, err := os.ReadDir(fcs.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config directory: %w", err)
+	}
+	var names []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if strings.HasPrefix(name, ".") {
+			continue
+		}
+		for _, pattern := range fcs.patterns {
+			if matched, _ := filepath.Match(pattern, name); matched {
+				names = append(names, filepath.Join(fcs.path, name))
+				break
+			}
+		}
+	}
+	return names, nil // ReadDir sorts entries by name
+}
+
+// parse merges the files of a snapshot into one configuration. Fragments are decoded as
+// YAML, which covers JSON, and checked on their own first, so errors name the file.
+func (fcs *HotReloadFileConfigSource) parse(snapshot *fileSnapshot) (*HotReloadConfig, error) {
+	raw := make(map[string]interface{})
+	for _, file := range snapshot.files {
+		var fragment map[string]interface{}
+		if err := yaml.Unmarshal(file.data, &fragment); err != nil {
+			return nil, fmt.Errorf("invalid config %s: %w", file.name, err)
+		}
+		if _, err := decodeHotReloadConfig(fragment); err != nil {
+			return nil, fmt.Errorf("invalid config %s: %w", file.name, err)
+		}
+		mergeConfigFragment(raw, fragment)
+	}
+
+	config, err := decodeHotReloadConfig(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config in %s: %w", fcs.path, err)
+	}
+	if config.Version == "" {
+		config.Version = "sha256:" + snapshot.digest[:12]
+	}
+	if config.UpdatedAt.IsZero() {
+		config.UpdatedAt = snapshot.modTime
+	}
+	return config, nil
+}
+
+// decodeHotReloadConfig decodes a raw configuration, rejecting unknown fields, which are
+// usually typos that would otherwise drop a limit without a word
+func decodeHotReloadConfig(raw map[string]interface{}) (*HotReloadConfig, error) {
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	var config HotReloadConfig
+	if err := decoder.Decode(&config); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+// mergeConfigFragment deep-merges a fragment into raw: objects merge key by key, lists are
+// appended to, and other values replace what earlier fragments set
+func mergeConfigFragment(raw, fragment map[string]interface{}) {
+	for key, value := range fragment {
+		switch value := value.(type) {
+		case map[string]interface{}:
+			if existing, ok := raw[key].(map[string]interface{}); ok {
+				mergeConfigFragment(existing, value)
+				continue
+			}
+		case []interface{}:
+			if existing, ok := raw[key].([]interface{}); ok {
+				raw[key] = append(existing, value...)
+				continue
+			}
+		}
+		raw[key] = value
+	}
+}
@@ -0,0 +1,169 @@
+// filesource_test.go
+package ratelimit
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// writeAtomically replaces a file the way config management tools do: write a hidden
+// temporary file next to it, then rename it over the original
+func writeAtomically(t *testing.T, path, content string) {
+	t.Helper()
+	tmp := filepath.Join(filepath.Dir(path), ".tmp-"+filepath.Base(path))
+	if err := os.WriteFile(tmp, []byte(content), 0o644); err != nil {
+		t.Fatalf("Failed to write %s: %v", tmp, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		t.Fatalf("Failed to rename %s: %v", tmp, err)
+	}
+}
+
+func TestFileConfigSourceMergesFragments(t *testing.T) {
+	dir := t.TempDir()
+	writeAtomically(t, filepath.Join(dir, "00-base.yaml"), `
+limits:
+  global: 100/minute
+  search: 50/minute
+overrides:
+  - entity: partner-a
+    scope: global
+    limit: 1000/minute
+enabled: true
+`)
+	writeAtomically(t, filepath.Join(dir, "50-search.yaml"), `
+limits:
+  search: 80/minute
+overrides:
+  - entity: partner-b
+    scope: search
+    limit: 500/minute
+`)
+	writeAtomically(t, filepath.Join(dir, "60-uploads.json"), `{"limits": {"upload": "10/minute"}, "costs": {"POST /upload": 5}}`)
+	for name, content := range map[string]string{
+		".ansible_tmp-70-search.yaml": "limits: {search: 1/minute}",
+		"README.md":                   "not a fragment",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "archive.yaml"), 0o755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+
+	source := NewHotReloadFileConfigSource(dir)
+	config, err := source.GetConfig(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	want := map[string]string{"global": "100/minute", "search": "80/minute", "upload": "10/minute"}
+	for scope, limit := range want {
+		if config.Limits[scope] != limit {
+			t.Errorf("Expected %s for %s, got %v", limit, scope, config.Limits)
+		}
+	}
+	if len(config.Overrides) != 2 || config.Overrides[0].Entity != "partner-a" || config.Overrides[1].Entity != "partner-b" {
+		t.Errorf("Expected the overrides of both fragments in order, got %+v", config.Overrides)
+	}
+	if !config.Enabled || config.Costs["POST /upload"] != 5 || !strings.HasPrefix(config.Version, "sha256:") || config.UpdatedAt.IsZero() {
+		t.Errorf("Unexpected merged config: %+v", config)
+	}
+
+	// The same content always merges to the same version
+	again, _ := source.GetConfig(context.Background())
+	if again.Version != config.Version {
+		t.Errorf("Expected a stable version, got %s and %s", config.Version, again.Version)
+	}
+}
+
+func TestFileConfigSourceErrors(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := NewHotReloadFileConfigSource(dir).GetConfig(context.Background()); err == nil || !strings.Contains(err.Error(), "no config fragments") {
+		t.Errorf("Expected an empty directory to be rejected, got %v", err)
+	}
+
+	writeAtomically(t, filepath.Join(dir, "10-team.yaml"), "limts:\n  search: 10/minute\n")
+	_, err := NewHotReloadFileConfigSource(dir).GetConfig(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "10-team.yaml") || !strings.Contains(err.Error(), "limts") {
+		t.Errorf("Expected the unknown field to be reported with its file, got %v", err)
+	}
+
+	file := filepath.Join(dir, "limits.json")
+	writeAtomically(t, file, `{"limits": {"global": "10/minute"}, "version": "7"}`)
+	config, err := NewHotReloadFileConfigSource(file).GetConfig(context.Background())
+	if err != nil || config.Limits["global"] != "10/minute" || config.Version != "7" {
+		t.Errorf("Expected a single file to load, got %+v, %v", config, err)
+	}
+}
+
+func TestFileConfigSourceWatch(t *testing.T) {
+	dir := t.TempDir()
+	writeAtomically(t, filepath.Join(dir, "00-base.yaml"), "limits:\n  global: 100/minute\n")
+
+	var mu sync.Mutex
+	var errs []error
+	source := NewHotReloadFileConfigSource(dir).
+		WithPollInterval(10 * time.Millisecond).
+		WithDebounce(200 * time.Millisecond).
+		WithErrorHandler(func(err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			errs = append(errs, err)
+		})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	configs, err := source.Watch(ctx)
+	if err != nil {
+		t.Fatalf("Failed to watch: %v", err)
+	}
+	next := func() *HotReloadConfig {
+		t.Helper()
+		select {
+		case config := <-configs:
+			return config
+		case <-time.After(5 * time.Second):
+			t.Fatal("Timed out waiting for a config")
+			return nil
+		}
+	}
+	if config := next(); config.Limits["global"] != "100/minute" {
+		t.Fatalf("Expected the initial config first, got %+v", config)
+	}
+
+	// A deployment writing two fragments in quick succession is delivered once
+	writeAtomically(t, filepath.Join(dir, "00-base.yaml"), "limits:\n  global: 200/minute\n")
+	time.Sleep(20 * time.Millisecond)
+	writeAtomically(t, filepath.Join(dir, "50-search.yaml"), "limits:\n  search: 20/minute\n")
+	if config := next(); config.Limits["global"] != "200/minute" || config.Limits["search"] != "20/minute" {
+		t.Errorf("Expected both fragments in one update, got %v", config.Limits)
+	}
+
+	// A broken fragment is reported once and leaves the last config in effect
+	writeAtomically(t, filepath.Join(dir, "50-search.yaml"), "limits: [search\n")
+	time.Sleep(500 * time.Millisecond)
+	select {
+	case config := <-configs:
+		t.Errorf("Expected no update from a broken fragment, got %+v", config)
+	default:
+	}
+	mu.Lock()
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "50-search.yaml") {
+		t.Errorf("Expected the broken fragment to be reported once, got %v", errs)
+	}
+	mu.Unlock()
+
+	writeAtomically(t, filepath.Join(dir, "50-search.yaml"), "limits:\n  search: 30/minute\n")
+	if config := next(); config.Limits["search"] != "30/minute" {
+		t.Errorf("Expected the fixed fragment to be delivered, got %v", config.Limits)
+	}
+
+	cancel()
+	for range configs {
+	}
+}
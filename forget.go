@@ -0,0 +1,109 @@
+// forget.go - Deletion of everything the limiter keeps about an entity, with an audit trail
+package ratelimit
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/itsatony/gorly/internal/core"
+)
+
+// ForgetRecord describes one deletion made by Limiter.Forget. It names the entity only by
+// its subject ID, so records can be kept as an audit trail without holding personal data.
+type ForgetRecord struct {
+	Subject string    `json:"subject"` // Anonymized entity, see SubjectID
+	Scopes  []string  `json:"scopes"`  // Scopes whose counters were removed
+	Keys    int       `json:"keys"`    // Store keys deleted
+	Cached  int       `json:"cached"`  // Local cache entries dropped
+	At      time.Time `json:"at"`
+	Error   string    `json:"error,omitempty"` // Set when some state could not be removed
+}
+
+// newForgetRecord converts a core forget report
+func newForgetRecord(report core.ForgetReport, err error) ForgetRecord {
+	record := ForgetRecord{
+		Subject: report.Subject,
+		Scopes:  report.Scopes,
+		Keys:    report.Keys,
+		Cached:  report.Cached,
+		At:      report.At,
+	}
+	if err != nil {
+		record.Error = err.Error()
+	}
+	return record
+}
+
+func (l *limiterImpl) Forget(ctx context.Context, entity string) (*ForgetRecord, error) {
+	return l.forget(ctx, entity, nil)
+}
+
+// forget removes an entity's state, including counters of the extra scopes it was seen in
+func (l *limiterImpl) forget(ctx context.Context, entity string, extraScopes []string) (*ForgetRecord, error) {
+	report, err := l.core.Forget(ctx, entity, extraScopes)
+	if report == nil {
+		return nil, newInputError(err)
+	}
+	record := newForgetRecord(*report, err)
+	return &record, err
+}
+
+// scopedForgetter is implemented by limiters that can also remove counters of scopes without a configured limit
+type scopedForgetter interface {
+	forget(ctx context.Context, entity string, extraScopes []string) (*ForgetRecord, error)
+}
+
+// entityForgetter is implemented by collectors that can drop the metrics of an entity
+type entityForgetter interface {
+	// ForgetEntity drops every series of entity and returns the scopes it had series in
+	ForgetEntity(entity string) []string
+}
+
+// ForgetAuditLog returns an OnForget handler that appends each record to w as a JSON line
+// Example: gorly.New().OnForget(gorly.ForgetAuditLog(auditFile))
+func ForgetAuditLog(w io.Writer) func(ForgetRecord) {
+	var mu sync.Mutex
+	return func(record ForgetRecord) {
+		mu.Lock()
+		defer mu.Unlock()
+		json.NewEncoder(w).Encode(record)
+	}
+}
+
+// ForgetHandler creates an admin handler for data-subject deletion requests. DELETE ?entity=
+// removes everything the limiter keeps about the entity and returns the ForgetRecord; a
+// partial deletion answers 500 with the record, so it can be retried.
+// The handler performs no authentication; mount it behind your admin auth or ProtectAdmin.
+// Example: adminMux.Handle("/admin/forget", ratelimit.ForgetHandler(limiter))
+func ForgetHandler(limiter Limiter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			w.Header().Set("Allow", "DELETE")
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		entity := r.URL.Query().Get("entity")
+		if entity == "" {
+			http.Error(w, "entity is required", http.StatusBadRequest)
+			return
+		}
+
+		record, err := limiter.Forget(r.Context(), entity)
+		if record == nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		status := http.StatusOK
+		if err != nil {
+			status = http.StatusInternalServerError
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(record)
+	}
+}
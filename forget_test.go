@@ -0,0 +1,123 @@
+// forget_test.go
+package ratelimit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestForget(t *testing.T) {
+	ctx := context.Background()
+	var audit bytes.Buffer
+	base, err := New().
+		Limit("global", "1/minute").
+		OnForget(ForgetAuditLog(&audit)).
+		Build()
+	if err != nil {
+		t.Fatalf("Failed to build limiter: %v", err)
+	}
+	config := DefaultObservabilityConfig()
+	config.EnableLogging = false
+	config.Analytics = &AnalyticsConfig{}
+	limiter := NewObservableLimiter(base, config)
+	defer limiter.Close()
+
+	for _, entity := range []string{"user:42", "user:4"} {
+		limiter.Check(ctx, entity, "global")
+		limiter.Check(ctx, entity, "adhoc") // Unconfigured scope, only known from the metrics
+	}
+
+	req := httptest.NewRequest(http.MethodDelete, "/admin/forget?entity=user:42", nil)
+	w := httptest.NewRecorder()
+	ForgetHandler(limiter).ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var record ForgetRecord
+	if err := json.NewDecoder(w.Body).Decode(&record); err != nil {
+		t.Fatalf("Failed to decode record: %v", err)
+	}
+	if !strings.HasPrefix(record.Subject, "sha256:") || strings.Join(record.Scopes, ",") != "adhoc,global" {
+		t.Errorf("Expected an anonymized record covering both scopes, got %+v", record)
+	}
+
+	// The audit trail holds the record but not the entity
+	if !strings.Contains(audit.String(), record.Subject) || strings.Contains(audit.String(), "user:42") {
+		t.Errorf("Expected an anonymized audit line, got %q", audit.String())
+	}
+
+	for _, scope := range []string{"global", "adhoc"} {
+		if result, _ := limiter.Check(ctx, "user:42", scope); !result.Allowed {
+			t.Errorf("Expected user:42 to start over in %s", scope)
+		}
+		if result, _ := limiter.Peek(ctx, "user:4", scope); result.Allowed {
+			t.Errorf("Expected user:4 to stay limited in %s", scope)
+		}
+	}
+
+	// Metrics and patterns of the entity are gone, those of similar entities stay
+	limiter.Forget(ctx, "user:42")
+	totals := limiter.GetMetrics()["request_total"].(map[string]int64)
+	for key := range totals {
+		if strings.HasPrefix(key, "user:42:") {
+			t.Errorf("Expected the metrics of user:42 to be forgotten, found %s", key)
+		}
+	}
+	if totals["user:4:global"] != 1 || totals["user:4:adhoc"] != 1 {
+		t.Errorf("Expected the metrics of user:4 to stay, got %v", totals)
+	}
+	for _, pattern := range limiter.RequestPatterns() {
+		if pattern.Entity == "user:42" {
+			t.Error("Expected the request pattern of user:42 to be forgotten")
+		}
+	}
+}
+
+func TestForgetHandlerErrors(t *testing.T) {
+	limiter, err := New().Limit("global", "1/minute").Build()
+	if err != nil {
+		t.Fatalf("Failed to build limiter: %v", err)
+	}
+	defer limiter.Close()
+
+	tests := []struct {
+		method string
+		target string
+		want   int
+	}{
+		{http.MethodGet, "/admin/forget?entity=user:42", http.StatusMethodNotAllowed},
+		{http.MethodDelete, "/admin/forget", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		ForgetHandler(limiter).ServeHTTP(w, httptest.NewRequest(tt.method, tt.target, nil))
+		if w.Code != tt.want {
+			t.Errorf("%s %s: expected %d, got %d", tt.method, tt.target, tt.want, w.Code)
+		}
+	}
+}
+
+func TestCompositeForget(t *testing.T) {
+	ctx := context.Background()
+	perUser, _ := New().Limit("global", "1/minute").Build()
+	daily, _ := New().Limit("global", "10/day").Limit("export", "1/day").SubjectID(func(string) string { return "other" }).Build()
+	limiter := All(perUser, daily)
+	defer limiter.Close()
+
+	limiter.Check(ctx, "user:42")
+	record, err := limiter.Forget(ctx, "user:42")
+	if err != nil {
+		t.Fatalf("Forget failed: %v", err)
+	}
+	if strings.Join(record.Scopes, ",") != "export,global" || !strings.HasPrefix(record.Subject, "sha256:") {
+		t.Errorf("Expected merged scopes and the first limiter's subject, got %+v", record)
+	}
+	if result, _ := limiter.Check(ctx, "user:42"); !result.Allowed {
+		t.Error("Expected the composite to start over")
+	}
+}
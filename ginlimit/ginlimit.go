@@ -0,0 +1,163 @@
+// ginlimit/ginlimit.go
+// Package ginlimit adapts a gorly limiter to Gin. Unlike asserting the result of
+// limiter.For(ratelimit.Gin), a wrong adapter here is a compile error. Importing the package
+// also registers Middleware as the Gin adapter, so limiter.For(ratelimit.Gin) returns a
+// gin.HandlerFunc as well.
+//
+// Options give the middleware the gin.Context: WithEntity and WithScope identify the caller
+// and scope from what earlier handlers stored with c.Set or from route parameters, and
+// WithDeniedHandler answers denied requests with Gin's own rendering.
+//
+// The package is a module of its own, so the Gin dependencies are only downloaded by
+// applications that use Gin; the core gorly module depends on no web framework.
+package ginlimit
+
+import (
+	"bytes"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/itsatony/gorly"
+)
+
+// Middleware returns Gin middleware applying the limiter to each request. Denied requests
+// get the limiter's rate limit response, or that of WithDeniedHandler, and the chain is aborted.
+// Example: router.Use(ginlimit.Middleware(limiter))
+func Middleware(limiter ratelimit.Limiter, options ...Option) gin.HandlerFunc {
+	var config config
+	for _, option := range options {
+		option(&config)
+	}
+	mw := limiter.HTTPMiddleware()
+	return func(c *gin.Context) {
+		r := c.Request
+		if config.entity != nil {
+			if entity := config.entity(c); entity != "" {
+				r = r.WithContext(ratelimit.WithEntity(r.Context(), entity))
+			}
+		}
+		if config.scope != nil {
+			if scope := config.scope(c); scope != "" {
+				r = r.WithContext(ratelimit.WithScope(r.Context(), scope))
+			}
+		}
+
+		var w http.ResponseWriter = c.Writer
+		var held *heldResponse
+		if config.denied != nil {
+			held = &heldResponse{header: c.Writer.Header()}
+			w = held
+		}
+
+		passed := false
+		mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			passed = true
+			c.Request = r
+			c.Next()
+		})).ServeHTTP(w, r)
+		if passed {
+			return
+		}
+		if held != nil {
+			if result := ratelimit.ResultFromHeaders(c.Writer.Header()); result != nil && !result.Allowed {
+				c.Writer.Header().Del("Content-Type") // Set for the response held back
+				config.denied(c, result)
+			} else {
+				held.flush(c.Writer) // Not a denial, e.g. a failed check
+			}
+		}
+		c.Abort()
+	}
+}
+
+// Option configures the Gin middleware
+type Option func(*config)
+
+type config struct {
+	entity func(*gin.Context) string
+	scope  func(*gin.Context) string
+	denied func(*gin.Context, *ratelimit.LimitResult)
+}
+
+// WithEntity identifies the caller from the Gin context, e.g. the user an authentication
+// middleware stored with c.Set. An empty entity leaves the request to the limiter's extractor.
+// Example: ginlimit.Middleware(limiter, ginlimit.WithEntity(func(c *gin.Context) string { return c.GetString("user_id") }))
+func WithEntity(entity func(*gin.Context) string) Option {
+	return func(c *config) {
+		c.entity = entity
+	}
+}
+
+// WithScope charges the request to a scope derived from the Gin context. An empty scope
+// leaves the request to the limiter's scope function.
+// Example: ginlimit.Middleware(limiter, ginlimit.WithScope(func(c *gin.Context) string { return c.Param("tenant") }))
+func WithScope(scope func(*gin.Context) string) Option {
+	return func(c *config) {
+		c.scope = scope
+	}
+}
+
+// RouteScope charges each request to the scope of its route pattern, e.g. "/users/:id",
+// which unlike the path has one value per route, so limits are set per route with
+// Limit("/users/:id", "10/minute"). Unmatched requests use the limiter's scope.
+// Example: ginlimit.Middleware(limiter, ginlimit.RouteScope())
+func RouteScope() Option {
+	return WithScope(func(c *gin.Context) string {
+		return c.FullPath()
+	})
+}
+
+// WithDeniedHandler answers denied requests in place of the limiter's rate limit response,
+// e.g. with c.JSON. The rate limit headers are already set and the chain is aborted after it.
+// Example: ginlimit.WithDeniedHandler(func(c *gin.Context, result *ratelimit.LimitResult) { c.JSON(http.StatusTooManyRequests, gin.H{"retry_after": result.RetryAfter.Seconds()}) })
+func WithDeniedHandler(denied func(*gin.Context, *ratelimit.LimitResult)) Option {
+	return func(c *config) {
+		c.denied = denied
+	}
+}
+
+// Result returns the rate limit result of a request the middleware let through, or nil
+// Example: if result := ginlimit.Result(c); result != nil && result.Remaining < 10 { ... }
+func Result(c *gin.Context) *ratelimit.LimitResult {
+	return ratelimit.ResultFromRequest(c.Request)
+}
+
+// heldResponse holds back what the limiter writes for a request it stops, so the denied
+// handler can answer instead. Headers go straight to the Gin writer.
+type heldResponse struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (h *heldResponse) Header() http.Header {
+	return h.header
+}
+
+func (h *heldResponse) Write(b []byte) (int, error) {
+	if h.status == 0 {
+		h.status = http.StatusOK
+	}
+	return h.body.Write(b)
+}
+
+func (h *heldResponse) WriteHeader(status int) {
+	if h.status == 0 {
+		h.status = status
+	}
+}
+
+// flush writes the response held back
+func (h *heldResponse) flush(w gin.ResponseWriter) {
+	if h.status != 0 {
+		w.WriteHeader(h.status)
+	}
+	w.Write(h.body.Bytes())
+}
+
+// init lets limiter.For(ratelimit.Gin) resolve to Middleware
+func init() {
+	ratelimit.RegisterAdapter(ratelimit.Gin, func(limiter ratelimit.Limiter) gin.HandlerFunc {
+		return Middleware(limiter)
+	})
+}
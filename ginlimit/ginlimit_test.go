@@ -0,0 +1,121 @@
+// ginlimit/ginlimit_test.go
+package ginlimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/itsatony/gorly"
+	"github.com/itsatony/gorly/middleware"
+)
+
+func TestMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	limiter, err := ratelimit.New().Limit("global", "2/minute").Build()
+	if err != nil {
+		t.Fatalf("Failed to build limiter: %v", err)
+	}
+	defer limiter.Close()
+
+	handled := 0
+	router := gin.New()
+	router.Use(Middleware(limiter))
+	router.GET("/", func(c *gin.Context) {
+		handled++
+		c.String(http.StatusOK, "OK")
+	})
+
+	for i, want := range []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		if w.Code != want {
+			t.Errorf("Request %d: expected %d, got %d", i+1, want, w.Code)
+		}
+		if w.Header().Get("X-RateLimit-Limit") != "2" {
+			t.Errorf("Request %d: expected rate limit headers, got %v", i+1, w.Header())
+		}
+	}
+	if handled != 2 {
+		t.Errorf("Expected the denied request to be aborted, handler ran %d times", handled)
+	}
+}
+
+func TestContextOptions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	limiter, err := ratelimit.New().
+		Limit("global", "100/minute").
+		Limit("/users/:id", "1/minute").
+		Build()
+	if err != nil {
+		t.Fatalf("Failed to build limiter: %v", err)
+	}
+	defer limiter.Close()
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("user_id", c.GetHeader("X-User"))
+	})
+	router.Use(Middleware(limiter,
+		WithEntity(func(c *gin.Context) string { return c.GetString("user_id") }),
+		RouteScope(),
+		WithDeniedHandler(func(c *gin.Context, result *ratelimit.LimitResult) {
+			c.JSON(http.StatusTooManyRequests, gin.H{"limit": result.Limit, "retry_after": int(result.RetryAfter.Seconds())})
+		})))
+	router.GET("/users/:id", func(c *gin.Context) {
+		result := Result(c)
+		if result == nil {
+			t.Error("Expected the result in the handler")
+			return
+		}
+		c.String(http.StatusOK, "%d", result.Limit)
+	})
+
+	get := func(path, user string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		r.Header.Set("X-User", user)
+		router.ServeHTTP(w, r)
+		return w
+	}
+	if w := get("/users/1", "alice"); w.Code != http.StatusOK || w.Body.String() != "1" {
+		t.Errorf("Expected the limit of the route, got %d %q", w.Code, w.Body.String())
+	}
+
+	// Every path of the route shares its limit
+	w := get("/users/2", "alice")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Content-Type") != "application/json; charset=utf-8" {
+		t.Errorf("Expected the denied handler's response, got %d %v", w.Code, w.Header())
+	}
+	if body := w.Body.String(); !strings.Contains(body, `"limit":1`) || !strings.Contains(body, `"retry_after"`) {
+		t.Errorf("Expected the result in the denied response, got %s", body)
+	}
+	if w.Header().Get("Retry-After") == "" {
+		t.Errorf("Expected the rate limit headers on the denied response, got %v", w.Header())
+	}
+
+	// The entity from the context has a limit of its own
+	if w := get("/users/1", "bob"); w.Code != http.StatusOK {
+		t.Errorf("Expected another user to be allowed, got %d", w.Code)
+	}
+}
+
+func TestFor(t *testing.T) {
+	limiter, err := ratelimit.New().Limit("global", "2/minute").Build()
+	if err != nil {
+		t.Fatalf("Failed to build limiter: %v", err)
+	}
+	defer limiter.Close()
+
+	if _, ok := limiter.For(ratelimit.Gin).(gin.HandlerFunc); !ok {
+		t.Errorf("Expected For(Gin) to return a gin.HandlerFunc, got %T", limiter.For(ratelimit.Gin))
+	}
+	if adapter := ratelimit.DetectFramework(gin.New()).Adapter; adapter != "gin.HandlerFunc" {
+		t.Errorf("Expected detection to report the registered adapter, got %s", adapter)
+	}
+	if _, ok := middleware.Get("gin"); !ok {
+		t.Error("Expected the Gin plugin to be registered")
+	}
+}
@@ -1,5 +1,5 @@
-// middleware/gin.go
-package middleware
+// ginlimit/plugin.go
+package ginlimit
 
 import (
 	"fmt"
@@ -9,25 +9,26 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/itsatony/gorly"
+	"github.com/itsatony/gorly/middleware"
 )
 
-// GinPlugin implements MiddlewarePlugin for Gin framework
-type GinPlugin struct{}
+// Plugin implements MiddlewarePlugin for Gin framework
+type Plugin struct{}
 
 // Name returns the plugin name
-func (p *GinPlugin) Name() string {
+func (p *Plugin) Name() string {
 	return "gin"
 }
 
 // Version returns the supported Gin version
-func (p *GinPlugin) Version() string {
+func (p *Plugin) Version() string {
 	return ">=1.9.0"
 }
 
 // CreateMiddleware creates Gin middleware function
-func (p *GinPlugin) CreateMiddleware(limiter ratelimit.RateLimiter, config *Config) interface{} {
+func (p *Plugin) CreateMiddleware(limiter ratelimit.RateLimiter, config *middleware.Config) interface{} {
 	if config == nil {
-		config = DefaultConfig()
+		config = middleware.DefaultConfig()
 	}
 	config.Limiter = limiter
 
@@ -48,7 +49,7 @@ func (p *GinPlugin) CreateMiddleware(limiter ratelimit.RateLimiter, config *Conf
 		}
 
 		// Process rate limiting
-		result, err := ProcessRequest(reqInfo, config)
+		result, err := middleware.ProcessRequest(reqInfo, config)
 		if err != nil {
 			if config.Logger != nil {
 				config.Logger.Error("Rate limiting failed", err, map[string]interface{}{
@@ -63,10 +64,7 @@ func (p *GinPlugin) CreateMiddleware(limiter ratelimit.RateLimiter, config *Conf
 		}
 
 		// Add rate limit headers
-		headers := BuildResponseHeaders(result, &config.ResponseConfig)
-		for key, value := range headers {
-			c.Header(key, value)
-		}
+		middleware.SetScopeResponseHeaders(c.Writer.Header(), result, reqInfo.Scope, &config.ResponseConfig)
 
 		// Check if request is allowed
 		if !result.Allowed {
@@ -81,7 +79,7 @@ func (p *GinPlugin) CreateMiddleware(limiter ratelimit.RateLimiter, config *Conf
 			}
 
 			// Send rate limited response
-			c.AbortWithStatusJSON(config.ResponseConfig.RateLimitedStatusCode, gin.H{
+			c.AbortWithStatusJSON(config.ResponseConfig.DeniedStatusCode(reqInfo.Scope), gin.H{
 				"error":               "Rate limit exceeded",
 				"limit":               result.Limit,
 				"remaining":           result.Remaining,
@@ -102,7 +100,7 @@ func (p *GinPlugin) CreateMiddleware(limiter ratelimit.RateLimiter, config *Conf
 }
 
 // ExtractRequest extracts request information from Gin context
-func (p *GinPlugin) ExtractRequest(frameworkRequest interface{}) (*RequestInfo, error) {
+func (p *Plugin) ExtractRequest(frameworkRequest interface{}) (*middleware.RequestInfo, error) {
 	c, ok := frameworkRequest.(*gin.Context)
 	if !ok {
 		return nil, fmt.Errorf("expected *gin.Context, got %T", frameworkRequest)
@@ -115,7 +113,7 @@ func (p *GinPlugin) ExtractRequest(frameworkRequest interface{}) (*RequestInfo,
 	}
 
 	// Extract request information
-	reqInfo := &RequestInfo{
+	reqInfo := &middleware.RequestInfo{
 		Method:     c.Request.Method,
 		Path:       c.Request.URL.Path,
 		RemoteAddr: c.ClientIP(), // Gin handles X-Forwarded-For automatically
@@ -146,7 +144,7 @@ func (p *GinPlugin) ExtractRequest(frameworkRequest interface{}) (*RequestInfo,
 }
 
 // SendResponse sends response using Gin context
-func (p *GinPlugin) SendResponse(frameworkResponse interface{}, status int, headers map[string]string, body []byte) error {
+func (p *Plugin) SendResponse(frameworkResponse interface{}, status int, headers map[string]string, body []byte) error {
 	c, ok := frameworkResponse.(*gin.Context)
 	if !ok {
 		return fmt.Errorf("expected *gin.Context, got %T", frameworkResponse)
@@ -166,32 +164,32 @@ func (p *GinPlugin) SendResponse(frameworkResponse interface{}, status int, head
 // Gin-specific helper functions
 // ============================================================================
 
-// GinMiddleware creates a Gin middleware with default configuration
-func GinMiddleware(limiter ratelimit.RateLimiter) gin.HandlerFunc {
-	plugin := &GinPlugin{}
-	middleware := plugin.CreateMiddleware(limiter, DefaultConfig())
-	return middleware.(gin.HandlerFunc)
+// PluginMiddleware creates a Gin middleware with default configuration
+func PluginMiddleware(limiter ratelimit.RateLimiter) gin.HandlerFunc {
+	plugin := &Plugin{}
+	mw := plugin.CreateMiddleware(limiter, middleware.DefaultConfig())
+	return mw.(gin.HandlerFunc)
 }
 
-// GinMiddlewareWithConfig creates a Gin middleware with custom configuration
-func GinMiddlewareWithConfig(limiter ratelimit.RateLimiter, config *Config) gin.HandlerFunc {
-	plugin := &GinPlugin{}
-	middleware := plugin.CreateMiddleware(limiter, config)
-	return middleware.(gin.HandlerFunc)
+// PluginMiddlewareWithConfig creates a Gin middleware with custom configuration
+func PluginMiddlewareWithConfig(limiter ratelimit.RateLimiter, config *middleware.Config) gin.HandlerFunc {
+	plugin := &Plugin{}
+	mw := plugin.CreateMiddleware(limiter, config)
+	return mw.(gin.HandlerFunc)
 }
 
-// GinEntityExtractor creates an entity extractor configured for common Gin patterns
-func GinEntityExtractor() EntityExtractor {
-	return &DefaultEntityExtractor{
+// EntityExtractor creates an entity extractor configured for common Gin patterns
+func EntityExtractor() middleware.EntityExtractor {
+	return &middleware.DefaultEntityExtractor{
 		APIKeyHeaders: []string{"X-API-Key", "Authorization"},
 		UserIDHeaders: []string{"X-User-ID"},
 		UseIPFallback: true,
 	}
 }
 
-// GinScopeExtractor creates a scope extractor configured for REST APIs
-func GinScopeExtractor() ScopeExtractor {
-	return &DefaultScopeExtractor{
+// ScopeExtractor creates a scope extractor configured for REST APIs
+func ScopeExtractor() middleware.ScopeExtractor {
+	return &middleware.DefaultScopeExtractor{
 		PathScopes: map[string]string{
 			"/api/v1/auth":   ratelimit.ScopeGlobal,
 			"/api/v1/users":  "users",
@@ -209,14 +207,14 @@ func GinScopeExtractor() ScopeExtractor {
 	}
 }
 
-// GinConfig creates a Gin-optimized middleware configuration
-func GinConfig(limiter ratelimit.RateLimiter) *Config {
-	return &Config{
+// PluginConfig creates a Gin-optimized middleware configuration
+func PluginConfig(limiter ratelimit.RateLimiter) *middleware.Config {
+	return &middleware.Config{
 		Limiter:         limiter,
-		EntityExtractor: GinEntityExtractor(),
-		ScopeExtractor:  GinScopeExtractor(),
-		TierExtractor:   &DefaultTierExtractor{},
-		ResponseConfig: ResponseConfig{
+		EntityExtractor: EntityExtractor(),
+		ScopeExtractor:  ScopeExtractor(),
+		TierExtractor:   &middleware.DefaultTierExtractor{},
+		ResponseConfig: middleware.ResponseConfig{
 			RateLimitedStatusCode: http.StatusTooManyRequests,
 			ErrorStatusCode:       http.StatusInternalServerError,
 			IncludeHeaders:        true,
@@ -225,7 +223,7 @@ func GinConfig(limiter ratelimit.RateLimiter) *Config {
 			RateLimitedResponse:   []byte(`{"error":"Rate limit exceeded","code":"RATE_LIMIT_EXCEEDED"}`),
 			ErrorResponse:         []byte(`{"error":"Internal server error","code":"INTERNAL_ERROR"}`),
 		},
-		Logger:         &NoOpLogger{},
+		Logger:         &middleware.NoOpLogger{},
 		MetricsEnabled: false,
 	}
 }
@@ -234,10 +232,10 @@ func GinConfig(limiter ratelimit.RateLimiter) *Config {
 // Advanced Gin Features
 // ============================================================================
 
-// GinSkipHealthChecks returns a skip function that skips health check endpoints
-func GinSkipHealthChecks() SkipFunc {
+// SkipHealthChecks returns a skip function that skips health check endpoints
+func SkipHealthChecks() middleware.SkipFunc {
 	healthPaths := []string{"/health", "/healthz", "/ping", "/status", "/metrics"}
-	return func(req *RequestInfo) bool {
+	return func(req *middleware.RequestInfo) bool {
 		path := strings.ToLower(req.Path)
 		for _, healthPath := range healthPaths {
 			if path == healthPath || strings.HasPrefix(path, healthPath+"/") {
@@ -248,20 +246,20 @@ func GinSkipHealthChecks() SkipFunc {
 	}
 }
 
-// GinSkipOptions returns a skip function that skips OPTIONS requests
-func GinSkipOptions() SkipFunc {
-	return func(req *RequestInfo) bool {
+// SkipOptions returns a skip function that skips OPTIONS requests
+func SkipOptions() middleware.SkipFunc {
+	return func(req *middleware.RequestInfo) bool {
 		return req.Method == "OPTIONS"
 	}
 }
 
-// GinSkipStatic returns a skip function that skips static file requests
-func GinSkipStatic(staticPrefixes ...string) SkipFunc {
+// SkipStatic returns a skip function that skips static file requests
+func SkipStatic(staticPrefixes ...string) middleware.SkipFunc {
 	if len(staticPrefixes) == 0 {
 		staticPrefixes = []string{"/static", "/assets", "/public", "/css", "/js", "/img"}
 	}
 
-	return func(req *RequestInfo) bool {
+	return func(req *middleware.RequestInfo) bool {
 		path := strings.ToLower(req.Path)
 		for _, prefix := range staticPrefixes {
 			if strings.HasPrefix(path, prefix) {
@@ -279,19 +277,7 @@ func GinSkipStatic(staticPrefixes ...string) SkipFunc {
 	}
 }
 
-// CombineSkipFuncs combines multiple skip functions with OR logic
-func CombineSkipFuncs(skipFuncs ...SkipFunc) SkipFunc {
-	return func(req *RequestInfo) bool {
-		for _, skipFunc := range skipFuncs {
-			if skipFunc != nil && skipFunc(req) {
-				return true
-			}
-		}
-		return false
-	}
-}
-
 // init registers the Gin plugin
 func init() {
-	Register(&GinPlugin{})
+	middleware.Register(&Plugin{})
 }
@@ -3,11 +3,13 @@ package ratelimit
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	"net/http"
 	"strings"
 	"time"
 
+	"github.com/itsatony/gorly/algorithms"
 	"github.com/itsatony/gorly/internal/core"
 	"github.com/itsatony/gorly/internal/middleware"
 )
@@ -27,16 +29,81 @@ type Limiter interface {
 	// Middleware returns a middleware function that automatically detects the framework
 	Middleware() interface{}
 
-	// For returns middleware for a specific framework type
+	// For returns middleware for a specific framework type: the adapter registered by an
+	// imported adapter module such as ginlimit, or else a built-in reflection-based one
 	// Example: limiter.For(ratelimit.Gin) for Gin-specific middleware
 	For(framework middleware.FrameworkType) interface{}
 
+	// HTTPMiddleware returns net/http middleware, also used by Chi, without a type assertion.
+	// Gin, Echo and Fiber have typed adapters in the ginlimit, echolimit and fiberlimit packages.
+	// Example: mux.Handle("/api/", limiter.HTTPMiddleware()(apiHandler))
+	HTTPMiddleware() func(http.Handler) http.Handler
+
 	// Check performs a rate limit check for the given entity and scope
 	Check(ctx context.Context, entity string, scope ...string) (*LimitResult, error)
 
+	// CheckAll consumes from every scope or from none: if one scope denies the request, the
+	// others are not charged. Costs default to 1; on Redis all scopes are committed by one script.
+	// Example: result, err := limiter.CheckAll(ctx, "user:42", []ratelimit.ScopeCost{{Scope: "global"}, {Scope: "upload", Cost: 5}})
+	CheckAll(ctx context.Context, entity string, costs []ScopeCost) (*MultiScopeResult, error)
+
+	// Peek returns the current allowance for the given entity and scope without consuming quota
+	// Example: result, _ := limiter.Peek(ctx, "user:42", "export"); fmt.Println(result.Remaining, "left")
+	Peek(ctx context.Context, entity string, scope ...string) (*LimitResult, error)
+
+	// Diagnostics returns the algorithm state behind the limit of an entity and scope without consuming quota
+	// Example: d, _ := limiter.Diagnostics(ctx, "user:42", "export"); fmt.Println(d.Bucket.CurrentTokens)
+	Diagnostics(ctx context.Context, entity string, scope ...string) (*Diagnostics, error)
+
+	// Prewarm creates the state of known heavy hitters before their first request; existing state is kept
+	// Example: limiter.Prewarm(ctx, []ratelimit.PrewarmSpec{{Entity: "partner:acme", Scopes: []string{"search"}}})
+	Prewarm(ctx context.Context, specs []PrewarmSpec) error
+
 	// Allow is an alias for Check that returns only if the request is allowed
 	Allow(ctx context.Context, entity string, scope ...string) (bool, error)
 
+	// Limits returns the effective limit of every configured scope for the given entity
+	Limits(entity string) ([]ScopeLimit, error)
+
+	// Scale multiplies every configured limit by factor at runtime
+	// Example: limiter.Scale(0.5) halves all limits during an incident
+	Scale(factor float64) error
+
+	// ScaleFactor returns the current limit multiplier
+	ScaleFactor() float64
+
+	// ConfigHash returns a stable hash of the effective configuration, which follows runtime
+	// updates. Instances configured alike report the same hash, so differing hashes reveal drift.
+	// Example: log.Printf("limits %s", limiter.ConfigHash())
+	ConfigHash() string
+
+	// MaintenanceMode rejects every entity except the allowlist until disabled
+	// Example: limiter.MaintenanceMode(true, []string{"user:admin", "10.0.0.5"})
+	MaintenanceMode(enabled bool, allowlist []string)
+
+	// Grant adds a temporary budget of extra units to an entity in a scope, replacing its current
+	// grant there; requests draw on it once the limit denies them. Requires EnableGrants.
+	// Example: limiter.Grant(ctx, "user:42", "export", 500, 48*time.Hour)
+	Grant(ctx context.Context, entity, scope string, extra int64, ttl time.Duration) (*Grant, error)
+
+	// RevokeGrant removes the grant of an entity in a scope
+	RevokeGrant(ctx context.Context, entity, scope string) error
+
+	// GrantStatus returns the current grant of an entity in a scope, or nil without one
+	GrantStatus(ctx context.Context, entity, scope string) (*Grant, error)
+
+	// Forget removes everything the limiter keeps about an entity (counters, grants, lockouts,
+	// cached denials and tiers, and observed metrics) to honor data-subject deletion requests.
+	// Each deletion is reported to OnForget. Entity overrides are configuration and stay.
+	// Example: record, err := limiter.Forget(ctx, "user:42")
+	Forget(ctx context.Context, entity string) (*ForgetRecord, error)
+
+	// RunWhenLeader runs fn every interval on exactly one of the instances sharing the store.
+	// The instance holding the job's lease runs it; fn's context is cancelled when the lease
+	// is lost or the limiter is closed. Errors returned by fn go to the error handler.
+	// Example: limiter.RunWhenLeader("cleanup", time.Hour, func(ctx context.Context) error { return sweep(ctx) })
+	RunWhenLeader(name string, interval time.Duration, fn func(ctx context.Context) error) error
+
 	// Stats returns usage statistics
 	Stats(ctx context.Context) (*LimitStats, error)
 
@@ -56,6 +123,78 @@ type LimitResult struct {
 	RetryAfter time.Duration `json:"retry_after"`
 	Window     time.Duration `json:"window"`
 	ResetTime  time.Time     `json:"reset_time"`
+
+	// Maintenance is set when the check was rejected by maintenance mode
+	Maintenance bool `json:"maintenance,omitempty"`
+
+	// Cached is set when the denial was served from the local denial cache
+	Cached bool `json:"cached,omitempty"`
+
+	// UnknownTier is the tier the entity claimed when no scope configures it
+	UnknownTier string `json:"unknown_tier,omitempty"`
+
+	// GrantRemaining is what is left of the entity's grant; it is included in Remaining
+	GrantRemaining int64 `json:"grant_remaining,omitempty"`
+
+	// Enforcement is the scope's mode when it is not enforced: "shadow" or "off"
+	Enforcement string `json:"enforcement,omitempty"`
+
+	// ShadowDenied is set when shadow mode let through a request the limit denies
+	ShadowDenied bool `json:"shadow_denied,omitempty"`
+
+	// FailedOpen is set when the store failed and the scope's FailOpen policy let the request through
+	FailedOpen bool `json:"failed_open,omitempty"`
+
+	// Degraded is set when the store failed and the scope's FailOpenWithLocalFallback policy
+	// decided the request with this instance's in-memory limiter
+	Degraded bool `json:"degraded,omitempty"`
+
+	// Stale is set when Peek was answered by the read replica, which may lag behind the primary
+	Stale bool `json:"stale,omitempty"`
+
+	// Alignment is the window alignment of the scope: rolling windows have no common reset
+	// time, wall-clock windows reset for every entity at once
+	Alignment WindowAlignment `json:"alignment,omitempty"`
+}
+
+// Rate returns the limit the check was evaluated against
+func (r *LimitResult) Rate() Limit {
+	return Limit{Requests: r.Limit, Window: r.Window}
+}
+
+// Diagnostics is the algorithm state behind the limit of an entity and scope.
+// Only the fields of the configured algorithm are set.
+type Diagnostics struct {
+	Entity    string        `json:"entity"`
+	Scope     string        `json:"scope"`
+	Algorithm string        `json:"algorithm"`
+	Limit     int64         `json:"limit"`
+	Window    time.Duration `json:"window"`
+	Source    string        `json:"source,omitempty"` // "override", "tier", "scope" or "global"
+	Stale     bool          `json:"stale,omitempty"`  // Read from the read replica, which may lag behind
+
+	Bucket        *algorithms.TokenBucketMetrics `json:"bucket,omitempty"`         // Token bucket state
+	SlidingWindow *algorithms.WindowMetrics      `json:"sliding_window,omitempty"` // Sliding window state
+	Pattern       *algorithms.RequestPattern     `json:"pattern,omitempty"`        // Request timing within the sliding window
+
+	// Composed holds the diagnostics of each limiter of an All or Any composition
+	Composed []*Diagnostics `json:"composed,omitempty"`
+}
+
+// PrewarmSpec names an entity whose state is created before its first request
+type PrewarmSpec struct {
+	Entity string   `yaml:"entity" json:"entity"`
+	Scopes []string `yaml:"scopes,omitempty" json:"scopes,omitempty"` // Default: every configured scope
+}
+
+// ScopeLimit describes the limit that applies to an entity for one scope
+type ScopeLimit struct {
+	Scope    string        `json:"scope"`
+	Tier     string        `json:"tier"`
+	Rate     string        `json:"rate"`
+	Requests int64         `json:"requests"`
+	Window   time.Duration `json:"window"`
+	Source   string        `json:"source"` // "override", "tier", "scope" or "global"
 }
 
 // LimitStats contains usage statistics
@@ -64,8 +203,263 @@ type LimitStats struct {
 	TotalDenied   int64                       `json:"total_denied"`
 	ByScope       map[string]*LimitScopeStats `json:"by_scope"`
 	ByEntity      map[string]*EntityStats     `json:"by_entity"`
+
+	// EvictedEntities counts entities dropped from ByEntity, which only breaks down the
+	// most recently seen entities (see MaxTrackedEntities)
+	EvictedEntities int64 `json:"evicted_entities,omitempty"`
+
+	// DenialCacheHits counts checks answered from the local denial cache
+	DenialCacheHits int64 `json:"denial_cache_hits,omitempty"`
+
+	// EmptyEntities counts requests whose extractor returned no entity
+	EmptyEntities int64 `json:"empty_entities,omitempty"`
+
+	// ProbeBypasses counts health probes let through without a check
+	ProbeBypasses int64 `json:"probe_bypasses,omitempty"`
+
+	// StoreKeys is the number of keys in the store; only the memory store reports it
+	StoreKeys int64 `json:"store_keys,omitempty"`
+
+	// ScopeOverflows counts checks whose scope exceeded the MaxScopes budget
+	ScopeOverflows int64 `json:"scope_overflows,omitempty"`
+
+	// UnknownTiers counts checks from entities claiming a tier no scope configures
+	UnknownTiers int64 `json:"unknown_tiers,omitempty"`
+
+	// ExpiredOverrides counts entity overrides removed when they expired
+	ExpiredOverrides int64 `json:"expired_overrides,omitempty"`
+
+	// ShadowDenials counts per scope the requests shadow mode let through over the limit
+	ShadowDenials map[string]int64 `json:"shadow_denials,omitempty"`
+
+	// FailedOpen counts per scope the checks FailOpen let through while the store failed
+	FailedOpen map[string]int64 `json:"failed_open,omitempty"`
+
+	// FailedClosed counts per scope the checks FailClosed rejected while the store failed
+	FailedClosed map[string]int64 `json:"failed_closed,omitempty"`
+
+	// LocalFallbackAllowed and LocalFallbackDenied count per scope the checks
+	// FailOpenWithLocalFallback decided with the in-memory limiter while the store failed
+	LocalFallbackAllowed map[string]int64 `json:"local_fallback_allowed,omitempty"`
+	LocalFallbackDenied  map[string]int64 `json:"local_fallback_denied,omitempty"`
+
+	// ClockOffset is how far the store clock is ahead of this instance's clock with StoreClock
+	ClockOffset time.Duration `json:"clock_offset,omitempty"`
+
+	// Stale is set when the shared counters were read from the read replica, which may lag behind
+	Stale bool `json:"stale,omitempty"`
+
+	// StatsFlush describes write-behind flushes when StatsWriteBehind is enabled
+	StatsFlush *StatsFlushStats `json:"stats_flush,omitempty"`
+
+	// TierCache describes the tier resolver cache when ResolveTiers is used
+	TierCache *TierCacheStats `json:"tier_cache,omitempty"`
+
+	// TrustedCalls counts verified and rejected trusted call headers when TrustedCallKey is used
+	TrustedCalls *TrustedCallStats `json:"trusted_calls,omitempty"`
+
+	// StoreRetries describes retried and hedged store reads when StoreRetries is used
+	StoreRetries *StoreRetryStats `json:"store_retries,omitempty"`
+
+	// KeyExpiry describes the limiter keys Redis expired when WatchKeyExpiry is used
+	KeyExpiry *KeyExpiryStats `json:"key_expiry,omitempty"`
+
+	// StateCompression describes the compression of algorithm state when StateCompression is used
+	StateCompression *StateCompressionStats `json:"state_compression,omitempty"`
+
+	// LocalCache describes the hits and drift of the local cache when RedisWithLocalCache is used
+	LocalCache *LocalCacheStats `json:"local_cache,omitempty"`
+
+	// ByStore describes every store when scopes use their own stores with StoreFor
+	ByStore map[string]*StoreStats `json:"by_store,omitempty"`
+}
+
+// StoreStats describes one of the stores of a limiter using StoreFor
+type StoreStats struct {
+	Store    string   `json:"store"`            // "memory", "redis" or "postgres"
+	Default  bool     `json:"default"`          // Serves the scopes not given to StoreFor
+	Scopes   []string `json:"scopes,omitempty"` // Scopes given to StoreFor with this store
+	Requests int64    `json:"requests"`         // Requests of its scopes in the shared counters
+	Denied   int64    `json:"denied"`
+	Keys     int64    `json:"keys,omitempty"` // Only the memory store reports its keys
+	Healthy  bool     `json:"healthy"`
+	Error    string   `json:"error,omitempty"`
+}
+
+// StatsFlushStats describes the write-behind flushes of stats counters to the store
+type StatsFlushStats struct {
+	Flushes           int64         `json:"flushes"`
+	FlushErrors       int64         `json:"flush_errors"`
+	FlushedEvents     int64         `json:"flushed_events"`
+	PendingEvents     int64         `json:"pending_events"` // Counted locally and lost if the process dies
+	LastFlushDuration time.Duration `json:"last_flush_duration"`
+	LastFlush         time.Time     `json:"last_flush,omitempty"`
+}
+
+// OverflowScope is shared by every unconfigured scope beyond the MaxScopes budget
+const OverflowScope = core.OverflowScope
+
+// HeaderMode decides which rate limit headers responses carry
+type HeaderMode string
+
+// Header modes
+const (
+	HeadersFull    HeaderMode = core.HeaderModeFull    // Every rate limit header (default)
+	HeadersMinimal HeaderMode = core.HeaderModeMinimal // Only Retry-After on denied requests
+	HeadersNone    HeaderMode = core.HeaderModeNone    // No rate limit headers
+)
+
+// UnknownTierPolicy decides how entities claiming a tier that no scope configures are limited
+type UnknownTierPolicy string
+
+// Unknown tier policies
+const (
+	UnknownTierDefault  UnknownTierPolicy = core.UnknownTierDefault  // Count the claim and apply default limits (default)
+	UnknownTierFallback UnknownTierPolicy = core.UnknownTierFallback // Apply the limits of the fallback tier
+	UnknownTierDeny     UnknownTierPolicy = core.UnknownTierDeny     // Reject with an INVALID_TIER error (400 in middleware)
+)
+
+// EnforcementMode decides what happens to requests over the limit of a scope
+type EnforcementMode string
+
+// Enforcement modes
+const (
+	EnforcementEnforce EnforcementMode = core.EnforcementEnforce // Deny requests over the limit (default)
+	EnforcementShadow  EnforcementMode = core.EnforcementShadow  // Let them through, but count and log them
+	EnforcementOff     EnforcementMode = core.EnforcementOff     // Skip the limit entirely
+)
+
+// FailurePolicy decides what happens to requests of a scope while the store is failing
+type FailurePolicy string
+
+// Failure policies
+const (
+	FailClosed FailurePolicy = core.FailClosed // Reject the request: Check returns the error, the middleware responds 500 (default)
+	FailOpen   FailurePolicy = core.FailOpen   // Let the request through unlimited and report the error
+
+	// Limit the request with an in-memory limiter of this instance and report the error.
+	// Each instance allows the full limit, so N instances admit up to N times the limit.
+	FailOpenWithLocalFallback FailurePolicy = core.FailLocal
+)
+
+// TierResolver looks up the tier of an entity in the application's own systems, such as
+// its billing plan, so tiers cannot be claimed by clients. An empty tier is "free".
+type TierResolver interface {
+	ResolveTier(ctx context.Context, entity string) (string, error)
+}
+
+// TierResolverFunc adapts a function to a TierResolver
+type TierResolverFunc func(ctx context.Context, entity string) (string, error)
+
+// ResolveTier calls f
+func (f TierResolverFunc) ResolveTier(ctx context.Context, entity string) (string, error) {
+	return f(ctx, entity)
+}
+
+// TierCacheStats describes the cache of tiers looked up by a TierResolver
+type TierCacheStats struct {
+	Hits      int64 `json:"hits"`
+	StaleHits int64 `json:"stale_hits"` // Served while refreshed in the background
+	Misses    int64 `json:"misses"`
+	Errors    int64 `json:"errors"` // Failed lookups; entities without a cached tier get "free"
+	Entries   int64 `json:"entries"`
+}
+
+// StorePoolStats describes the connection pool of a Redis-backed store
+type StorePoolStats struct {
+	Hits       int64 `json:"hits"`     // Connections taken from the pool
+	Misses     int64 `json:"misses"`   // Connections that had to be dialed
+	Timeouts   int64 `json:"timeouts"` // Waits for a free connection that timed out
+	TotalConns int64 `json:"total_conns"`
+	IdleConns  int64 `json:"idle_conns"`
+	StaleConns int64 `json:"stale_conns"` // Connections removed from the pool
+}
+
+// StoreRetryStats describes the retries and hedges of store reads made by checks
+type StoreRetryStats struct {
+	Reads           int64         `json:"reads"`
+	Retries         int64         `json:"retries"`          // Reads repeated after an error
+	Hedges          int64         `json:"hedges"`           // Second reads sent because the first was slow
+	HedgeWins       int64         `json:"hedge_wins"`       // Hedged reads that answered first
+	BudgetExhausted int64         `json:"budget_exhausted"` // Retries and hedges skipped because the budget was spent
+	DeadlineSkipped int64         `json:"deadline_skipped"` // Retries skipped because the deadline was too close
+	HedgeDelay      time.Duration `json:"hedge_delay"`      // Current delay before a hedge
+}
+
+// KeyExpiryStats describes the limiter keys Redis expired, as reported by its keyspace notifications
+type KeyExpiryStats struct {
+	Expired    int64            `json:"expired"`     // Keys expired since the limiter started
+	LastMinute int64            `json:"last_minute"` // Keys expired in the last minute, the current churn rate
+	ByKind     map[string]int64 `json:"by_kind"`     // By the first key part after the prefix, e.g. "sliding_window"
+}
+
+// StateCompressionStats describes the compression of serialized algorithm state used by StateCompression
+type StateCompressionStats struct {
+	Codec        string        `json:"codec"`
+	Compressed   int64         `json:"compressed"`   // State blobs written compressed
+	Skipped      int64         `json:"skipped"`      // Blobs below the threshold or not worth compressing
+	Decompressed int64         `json:"decompressed"` // Compressed blobs read back
+	BytesIn      int64         `json:"bytes_in"`     // Uncompressed size of the compressed blobs
+	BytesOut     int64         `json:"bytes_out"`    // Compressed size of the compressed blobs
+	Ratio        float64       `json:"ratio"`        // BytesOut / BytesIn
+	EncodeTime   time.Duration `json:"encode_time"`  // Total time spent compressing
+	DecodeTime   time.Duration `json:"decode_time"`  // Total time spent decompressing
+}
+
+// LocalCacheStats describes the local cache in front of Redis used by RedisWithLocalCache
+type LocalCacheStats struct {
+	Hits        int64     `json:"hits"`         // Store operations answered from the cache
+	Misses      int64     `json:"misses"`       // Store operations that read Redis
+	HitRatio    float64   `json:"hit_ratio"`    // Hits / (Hits + Misses)
+	Keys        int       `json:"keys"`         // Keys in the cache
+	PendingKeys int       `json:"pending_keys"` // Keys with writes not yet synced to Redis
+	Syncs       int64     `json:"syncs"`
+	SyncErrors  int64     `json:"sync_errors"` // Keys whose sync failed and is retried
+	LastSync    time.Time `json:"last_sync"`
+	Drift       int64     `json:"drift"`       // Requests of other instances found by the last sync
+	TotalDrift  int64     `json:"total_drift"` // Requests of other instances found by every sync
+	MaxDrift    int64     `json:"max_drift"`   // Most requests of other instances found in one counter by one sync
+}
+
+// StoreFailoverStats describes a Redis store with a standby
+type StoreFailoverStats struct {
+	FailedOver         bool  `json:"failed_over"` // Operations are served by the standby
+	Failovers          int64 `json:"failovers"`
+	Failbacks          int64 `json:"failbacks"`
+	Replicated         int64 `json:"replicated"`          // Writes copied to the standby
+	ReplicationDropped int64 `json:"replication_dropped"` // Writes not copied because the buffer was full
+	ReplicationErrors  int64 `json:"replication_errors"`
+	Reconciled         int64 `json:"reconciled"` // Keys copied back to the primary before failing back
 }
 
+// ConfigVersion identifies the limits a limiter enforces
+type ConfigVersion struct {
+	Generation int64  `json:"generation"`        // 1 when built, incremented by every limit update
+	Version    string `json:"version,omitempty"` // Version of the last hot-reloaded config
+	Hash       string `json:"hash"`              // Hash of the effective configuration, see Limiter.ConfigHash
+}
+
+// EmptyEntityPolicy decides how the middleware handles requests whose extractor returns no entity
+type EmptyEntityPolicy string
+
+// Empty entity policies
+const (
+	EmptyEntityShared EmptyEntityPolicy = core.EmptyEntityShared // Share one "anonymous" bucket (default)
+	EmptyEntityIP     EmptyEntityPolicy = core.EmptyEntityIP     // Limit by connection address as "ip:<addr>"
+	EmptyEntityDeny   EmptyEntityPolicy = core.EmptyEntityDeny   // Reject with 401 Unauthorized
+	EmptyEntitySkip   EmptyEntityPolicy = core.EmptyEntitySkip   // Let the request through unlimited
+)
+
+// WindowAlignment decides where the windows of a scope start
+type WindowAlignment string
+
+// Window alignments
+const (
+	AlignRolling      WindowAlignment = core.AlignRolling      // Requests age out one window after they were made (token bucket, sliding window)
+	AlignWallClock    WindowAlignment = core.AlignWallClock    // Windows start at multiples of their length, e.g. on the minute (default of fixed windows)
+	AlignFirstRequest WindowAlignment = core.AlignFirstRequest // An entity's window starts with its first request
+)
+
 // LimitScopeStats contains statistics for a specific scope
 type LimitScopeStats struct {
 	Scope    string    `json:"scope"`
@@ -80,6 +474,9 @@ type EntityStats struct {
 	Requests int64     `json:"requests"`
 	Denied   int64     `json:"denied"`
 	LastUsed time.Time `json:"last_used"`
+
+	// Pattern describes the entity's request timing when analytics are enabled
+	Pattern *algorithms.RequestPattern `json:"pattern,omitempty"`
 }
 
 // =============================================================================
@@ -197,6 +594,37 @@ func (b *Builder) Redis(address string, options ...RedisOption) *Builder {
 	return b
 }
 
+// RedisShards configures the limiter to shard keys across standalone Redis instances
+// using consistent hashing; dead shards are skipped until they recover, or fail their keys'
+// checks with RedisShardFailurePolicy("fail_closed")
+// Example: gorly.New().RedisShards([]string{"redis-a:6379", "redis-b:6379", "redis-c:6379"})
+func (b *Builder) RedisShards(addresses []string, options ...RedisOption) *Builder {
+	b.config.Store = "redis"
+	b.config.RedisShards = addresses
+	if len(addresses) > 0 {
+		b.config.RedisAddress = addresses[0]
+	}
+
+	// Apply options
+	for _, opt := range options {
+		opt(b.config)
+	}
+	return b
+}
+
+// RedisWithLocalCache configures the limiter to use Redis behind a cache in process memory.
+// Checks are answered locally and their increments written to Redis every syncInterval,
+// which also picks up the traffic of other instances. Between syncs an instance does not
+// see that traffic, so limits can be overshot by up to one sync interval of traffic per
+// other instance; fixed_window merges best, as its counters are summed exactly. CheckAll
+// is not supported.
+// Example: gorly.New().RedisWithLocalCache("localhost:6379", 100*time.Millisecond)
+func (b *Builder) RedisWithLocalCache(address string, syncInterval time.Duration, options ...RedisOption) *Builder {
+	b.Redis(address, options...)
+	b.config.RedisLocalCache = syncInterval
+	return b
+}
+
 // Memory configures the limiter to use in-memory storage (default)
 // Example: gorly.New().Memory()
 func (b *Builder) Memory() *Builder {
@@ -204,14 +632,105 @@ func (b *Builder) Memory() *Builder {
 	return b
 }
 
+// Postgres configures the limiter to keep its state in a Postgres table, for deployments
+// with Postgres but no Redis; the table is created if missing. With fixed_window every check
+// is one upsert, exact across instances at a few times the latency of Redis. A
+// database/sql driver must be imported, e.g. _ "github.com/jackc/pgx/v5/stdlib".
+// Example: gorly.New().Postgres("postgres://gorly@db:5432/app", gorly.PostgresTable("ops.limits"))
+func (b *Builder) Postgres(dsn string, options ...PostgresOption) *Builder {
+	b.config.Store = "postgres"
+	b.config.PostgresDSN = dsn
+
+	// Apply options
+	for _, opt := range options {
+		opt(b.config)
+	}
+	return b
+}
+
+// StoreFor keeps the limit state of a scope in another store than the default one: "memory"
+// for fast limits local to each instance, or "redis" for cluster-wide accuracy, which
+// connects to the address given to Redis. Scopes of one CheckAll must share a store.
+// Example: gorly.New().Redis("localhost:6379").StoreFor("static-assets", "memory")
+func (b *Builder) StoreFor(scope, store string) *Builder {
+	if b.config.ScopeStores == nil {
+		b.config.ScopeStores = make(map[string]string)
+	}
+	b.config.ScopeStores[scope] = store
+	return b
+}
+
 // Algorithm sets the rate limiting algorithm
-// Options: "token_bucket", "sliding_window" (default), "gcra"
+// Options: "token_bucket", "sliding_window" (default), "fixed_window", "gcra", or the name of
+// an algorithm registered with RegisterAlgorithm.
+// fixed_window keeps one atomic counter per entity and window, the cheapest check at high
+// request rates, at the cost of letting up to twice the limit through around a window reset.
 // Example: gorly.New().Algorithm("token_bucket")
 func (b *Builder) Algorithm(algo string) *Builder {
 	b.config.Algorithm = algo
 	return b
 }
 
+// WindowAlignment sets where the windows of the fixed window algorithm start: on wall-clock
+// multiples of the window (default), so every entity resets at the same time, or with each
+// entity's first request. First-request windows cost one more store read per check.
+// Example: gorly.New().Algorithm("fixed_window").WindowAlignment(ratelimit.AlignFirstRequest)
+func (b *Builder) WindowAlignment(alignment WindowAlignment) *Builder {
+	b.config.WindowAlignment = string(alignment)
+	return b
+}
+
+// ScopeAlignment sets the window alignment of one scope, whatever the algorithm. Scopes
+// aligned to the wall clock reset on the minute or hour for every entity, so partners can
+// publish predictable reset times; they count in fixed windows. Rolling scopes of a fixed
+// window limiter count in sliding windows.
+// Example: gorly.New().Limit("partner", "600/hour").ScopeAlignment("partner", ratelimit.AlignWallClock)
+func (b *Builder) ScopeAlignment(scope string, alignment WindowAlignment) *Builder {
+	if b.config.ScopeAlignments == nil {
+		b.config.ScopeAlignments = make(map[string]string)
+	}
+	b.config.ScopeAlignments[scope] = string(alignment)
+	return b
+}
+
+// StateCompression compresses the serialized state of the token bucket and sliding window
+// algorithms with codec, "snappy" or "zstd", once it reaches threshold bytes (0 for 4 KiB).
+// Compressed state is read and written without store scripts; Stats().StateCompression and
+// the gorly_state_compression metrics report the savings and the CPU time spent.
+// Example: gorly.New().Algorithm("sliding_window").StateCompression("zstd", 0)
+func (b *Builder) StateCompression(codec string, threshold int) *Builder {
+	b.config.StateCompression = algorithms.CompressionConfig{Algorithm: codec, Threshold: threshold}
+	return b
+}
+
+// LimitTransition sets how limits that shrink in runtime updates take effect: immediately
+// (default), after one more window of the old limit, or stepping down over LimitRamp.
+// Example: gorly.New().LimitTransition(ratelimit.TransitionDrain)
+func (b *Builder) LimitTransition(policy LimitTransition) *Builder {
+	b.config.LimitTransition = string(policy)
+	return b
+}
+
+// LimitRamp makes limits that shrink in runtime updates step down from the old to the new
+// limit over d, so entities over the new limit are slowed down instead of locked out
+// Example: gorly.New().LimitRamp(10 * time.Minute)
+func (b *Builder) LimitRamp(d time.Duration) *Builder {
+	b.config.LimitTransition = core.TransitionRamp
+	b.config.LimitRampDuration = d
+	return b
+}
+
+// OnLimitTransition sets a handler called when a runtime update starts softening a shrunk
+// limit, and for every entity admitted over its new limit only because of it. Entity events
+// are sent from the request path, so the handler must return quickly.
+// Example: gorly.New().LimitRamp(10 * time.Minute).OnLimitTransition(gorly.LimitTransitionLog(os.Stderr))
+func (b *Builder) OnLimitTransition(fn func(LimitTransitionEvent)) *Builder {
+	b.config.OnLimitTransition = func(e core.LimitTransitionEvent) {
+		fn(newLimitTransitionEvent(e))
+	}
+	return b
+}
+
 // Limit sets a rate limit for a specific scope
 // Example: gorly.New().Limit("global", "1000/hour").Limit("upload", "10/minute")
 func (b *Builder) Limit(scope, limit string) *Builder {
@@ -219,6 +738,12 @@ func (b *Builder) Limit(scope, limit string) *Builder {
 	return b
 }
 
+// RateLimit sets the limit of a scope from a Limit, e.g. one derived from another limit
+// Example: gorly.New().Limit("global", "1000/hour").RateLimit("search", gorly.MustParseLimit("1000/hour").Scale(0.5))
+func (b *Builder) RateLimit(scope string, limit Limit) *Builder {
+	return b.Limit(scope, limit.String())
+}
+
 // Limits sets multiple rate limits at once
 // Example: gorly.New().Limits(map[string]string{"global": "1000/hour", "upload": "10/minute"})
 func (b *Builder) Limits(limits map[string]string) *Builder {
@@ -228,113 +753,821 @@ func (b *Builder) Limits(limits map[string]string) *Builder {
 	return b
 }
 
-// TierLimits sets tier-based rate limits
-// Example: gorly.New().TierLimits(map[string]string{"free": "100/hour", "premium": "10000/hour"})
-func (b *Builder) TierLimits(tierLimits map[string]string) *Builder {
-	if b.config.TierLimits["global"] == nil {
-		b.config.TierLimits["global"] = make(map[string]string)
+// Override sets the limit of one entity in one scope, ahead of its tier and scope limits
+// Example: gorly.New().Limit("global", "1000/hour").Override("partner:acme", "global", "50000/hour")
+func (b *Builder) Override(entity, scope, limit string) *Builder {
+	return b.Overrides(EntityOverride{Entity: entity, Scope: scope, Limit: limit})
+}
+
+// OverrideUntil sets an entity override that is removed at expiresAt, e.g. a raised limit
+// for a partner's migration weekend. Each expiry is counted in Stats().ExpiredOverrides
+// and reported to OnOverrideExpired.
+// Example: gorly.New().OverrideUntil("partner:acme", "global", "10000/minute", time.Date(2025, 6, 30, 0, 0, 0, 0, time.UTC))
+func (b *Builder) OverrideUntil(entity, scope, limit string, expiresAt time.Time) *Builder {
+	return b.Overrides(EntityOverride{Entity: entity, Scope: scope, Limit: limit, ExpiresAt: expiresAt})
+}
+
+// Overrides sets entity overrides, e.g. read with ReadOverridesCSV; an override replaces
+// an earlier one for the same entity and scope
+// Example: gorly.New().Overrides(overrides...)
+func (b *Builder) Overrides(overrides ...EntityOverride) *Builder {
+	for _, o := range coreOverrides(overrides) {
+		replaced := false
+		for i, existing := range b.config.Overrides {
+			if existing.Entity == o.Entity && existing.Scope == o.Scope {
+				b.config.Overrides[i], replaced = o, true
+				break
+			}
+		}
+		if !replaced {
+			b.config.Overrides = append(b.config.Overrides, o)
+		}
 	}
-	for tier, limit := range tierLimits {
-		b.config.TierLimits["global"][tier] = limit
+	return b
+}
+
+// BandwidthLimit limits a scope by response volume instead of request count
+// Sizes accept B, KB, MB, GB and TB suffixes (binary multiples)
+// Example: gorly.New().BandwidthLimit("download", "500MB/hour")
+func (b *Builder) BandwidthLimit(scope, limit string) *Builder {
+	if b.config.BandwidthLimits == nil {
+		b.config.BandwidthLimits = make(map[string]string)
 	}
+	b.config.BandwidthLimits[scope] = limit
 	return b
 }
 
-// ExtractorFunc sets a custom function to extract the entity from HTTP requests
-// Example: gorly.New().ExtractorFunc(func(r *http.Request) string { return r.Header.Get("X-API-Key") })
-func (b *Builder) ExtractorFunc(fn func(*http.Request) string) *Builder {
-	b.config.ExtractorFunc = fn
+// TokenBudget limits a scope by model tokens reported with ReportTokens.
+// A budget on "global" is shared by every scope without its own budget.
+// Example: gorly.New().TokenBudget("global", "5000000/month")
+func (b *Builder) TokenBudget(scope, budget string) *Builder {
+	if b.config.TokenBudgets == nil {
+		b.config.TokenBudgets = make(map[string]string)
+	}
+	b.config.TokenBudgets[scope] = budget
 	return b
 }
 
-// ScopeFunc sets a custom function to determine the scope from HTTP requests
-// Example: gorly.New().ScopeFunc(func(r *http.Request) string { return strings.TrimPrefix(r.URL.Path, "/api/") })
-func (b *Builder) ScopeFunc(fn func(*http.Request) string) *Builder {
-	b.config.ScopeFunc = fn
+// TimeZone makes the day, week and month budgets of a scope follow the calendar of an
+// IANA time zone: they reset at local midnight, on Monday and on the first of the month,
+// through DST changes, instead of at midnight UTC. Reset schedules of the scope use it too.
+// Example: gorly.New().TokenBudget("global", "100000/day").TimeZone("global", "America/New_York")
+func (b *Builder) TimeZone(scope, zone string) *Builder {
+	if b.config.ScopeTimeZones == nil {
+		b.config.ScopeTimeZones = make(map[string]string)
+	}
+	b.config.ScopeTimeZones[scope] = zone
 	return b
 }
 
-// OnError sets a custom error handler
-// Example: gorly.New().OnError(func(err error) { log.Printf("Rate limit error: %v", err) })
-func (b *Builder) OnError(fn func(error)) *Builder {
-	b.config.ErrorHandler = fn
+// TenantTimeZone returns the time zone of each entity's calendar windows, e.g. the
+// customer's billing zone, taking precedence over TimeZone. Return "" for the scope's zone.
+// Example: gorly.New().TenantTimeZone(func(entity string) string { return accounts.BillingZone(entity) })
+func (b *Builder) TenantTimeZone(fn func(entity string) string) *Builder {
+	b.config.TimeZoneFunc = fn
 	return b
 }
 
-// OnDenied sets a custom handler for when requests are rate limited
-// Example: gorly.New().OnDenied(func(w http.ResponseWriter, r *http.Request, result *LimitResult) { ... })
-func (b *Builder) OnDenied(fn func(http.ResponseWriter, *http.Request, *LimitResult)) *Builder {
-	// Convert the user's handler to work with internal CoreResult
-	b.config.DeniedHandler = func(w http.ResponseWriter, r *http.Request, coreResult *core.CoreResult) {
-		// Convert CoreResult to LimitResult
-		limitResult := &LimitResult{
-			Allowed:    coreResult.Allowed,
-			Remaining:  coreResult.Remaining,
-			Limit:      coreResult.Limit,
-			Used:       coreResult.Used,
-			RetryAfter: coreResult.RetryAfter,
-			Window:     coreResult.Window,
-			ResetTime:  coreResult.ResetTime,
-		}
-		fn(w, r, limitResult)
+// ResetSchedule hard-resets every counter of a scope on a cron schedule (UTC unless the
+// scope has a TimeZone). Instances sharing a store reset the scope exactly once per scheduled time.
+// Example: gorly.New().Limit("daily-report", "5/day").ResetSchedule("daily-report", "0 0 * * *")
+func (b *Builder) ResetSchedule(scope, cron string) *Builder {
+	if b.config.ScopeResets == nil {
+		b.config.ScopeResets = make(map[string]string)
 	}
+	b.config.ScopeResets[scope] = cron
 	return b
 }
 
-// EnableMetrics enables Prometheus metrics collection
-// Example: gorly.New().EnableMetrics()
-func (b *Builder) EnableMetrics() *Builder {
-	b.config.MetricsEnabled = true
+// LeaderLease sets how long the leader of a singleton job keeps its lease without a heartbeat.
+// A failed leader is replaced within this time. (default: 15s)
+// Example: gorly.New().LeaderLease(30 * time.Second)
+func (b *Builder) LeaderLease(ttl time.Duration) *Builder {
+	b.config.LeaderLeaseTTL = ttl
 	return b
 }
 
-// Build creates the rate limiter from the builder configuration
-func (b *Builder) Build() (Limiter, error) {
-	// Validate configuration
-	if err := b.config.Validate(); err != nil {
-		return nil, fmt.Errorf("invalid configuration: %w", err)
-	}
+// InstanceID names this instance when it holds a leader lease (default: hostname, PID and random suffix)
+// Example: gorly.New().InstanceID(os.Getenv("POD_NAME"))
+func (b *Builder) InstanceID(id string) *Builder {
+	b.config.InstanceID = id
+	return b
+}
 
-	// Create the core limiter
-	limiter, err := core.NewLimiter(b.config)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create limiter: %w", err)
-	}
+// TokenCost sets how reported model usage is converted into budget units (default: prompt + completion tokens)
+// Example: gorly.New().TokenCost(func(model string, prompt, completion int64) int64 { return prompt + 3*completion })
+func (b *Builder) TokenCost(fn TokenCostFunc) *Builder {
+	b.config.TokenCost = fn
+	return b
+}
 
-	return &limiterImpl{
-		core:   limiter,
-		config: b.config,
-	}, nil
+// TierLimits sets tier-based rate limits
+// Example: gorly.New().TierLimits(map[string]string{"free": "100/hour", "premium": "10000/hour"})
+func (b *Builder) TierLimits(tierLimits map[string]string) *Builder {
+	return b.ScopeTierLimits("global", tierLimits)
 }
 
-// Middleware builds the limiter and returns middleware that auto-detects the framework
-func (b *Builder) Middleware() interface{} {
-	limiter, err := b.Build()
-	if err != nil {
-		panic(fmt.Sprintf("failed to build limiter: %v", err))
+// ScopeTierLimits sets tier-based rate limits for a single scope
+// Example: gorly.New().ScopeTierLimits("upload", map[string]string{"free": "10/hour", "premium": "100/hour"})
+func (b *Builder) ScopeTierLimits(scope string, tierLimits map[string]string) *Builder {
+	if b.config.TierLimits[scope] == nil {
+		b.config.TierLimits[scope] = make(map[string]string)
 	}
-	return limiter.Middleware()
+	for tier, limit := range tierLimits {
+		b.config.TierLimits[scope][tier] = limit
+	}
+	return b
 }
 
-// =============================================================================
-// Redis configuration options
-// =============================================================================
+// ExtractorFunc sets a custom function to extract the entity from HTTP requests
+// Example: gorly.New().ExtractorFunc(func(r *http.Request) string { return r.Header.Get("X-API-Key") })
+func (b *Builder) ExtractorFunc(fn func(*http.Request) string) *Builder {
+	b.config.ExtractorFunc = fn
+	return b
+}
 
-// RedisOption configures Redis connection options
-type RedisOption func(*core.Config)
+// UnknownTiers sets how entities claiming a tier that no scope configures are limited.
+// By default they silently get the scope's default limits; each claim is counted in
+// Stats().UnknownTiers either way. Entities are "tier:id", so "ip:" and "key:" entities
+// of the built-in extractors claim tiers too.
+// Example: gorly.New().TierLimits(tiers).UnknownTiers(gorly.UnknownTierDeny)
+func (b *Builder) UnknownTiers(policy UnknownTierPolicy) *Builder {
+	b.config.UnknownTierPolicy = string(policy)
+	return b
+}
 
-// RedisPassword sets the Redis password
-func RedisPassword(password string) RedisOption {
-	return func(c *core.Config) {
-		c.RedisPassword = password
+// Enforcement sets the enforcement mode of a scope. New scopes can be rolled out in shadow
+// mode, where requests over the limit pass but are counted in Stats().ShadowDenials and logged,
+// while established scopes stay enforced. Modes can be changed with a hot-reload source.
+// Example: gorly.New().Limit("search", "100/minute").Enforcement("search", gorly.EnforcementShadow)
+func (b *Builder) Enforcement(scope string, mode EnforcementMode) *Builder {
+	if b.config.ScopeEnforcement == nil {
+		b.config.ScopeEnforcement = make(map[string]string)
 	}
+	b.config.ScopeEnforcement[scope] = string(mode)
+	return b
 }
 
-// RedisDB sets the Redis database number
-func RedisDB(db int) RedisOption {
-	return func(c *core.Config) {
-		c.RedisDB = db
-	}
+// FailurePolicy sets what scopes without their own policy do while the store is failing,
+// e.g. during a Redis outage. Checks fail closed by default.
+// Example: gorly.New().Redis("localhost:6379").FailurePolicy(gorly.FailOpen)
+func (b *Builder) FailurePolicy(policy FailurePolicy) *Builder {
+	b.config.FailurePolicy = string(policy)
+	return b
+}
+
+// OnStoreFailure sets what checks do while the store is failing, like FailurePolicy:
+// FailClosed rejects them, FailOpen lets them through unlimited, and
+// FailOpenWithLocalFallback limits them with an in-memory limiter until the store is back.
+// Stats counts the decisions in FailedClosed, FailedOpen and LocalFallbackAllowed/Denied.
+// Example: gorly.New().Redis("redis:6379").OnStoreFailure(gorly.FailOpenWithLocalFallback)
+func (b *Builder) OnStoreFailure(policy FailurePolicy) *Builder {
+	return b.FailurePolicy(policy)
+}
+
+// StoreRetries retries the store reads of checks that fail, e.g. on a Redis timeout, and
+// with Hedge set sends a second read when the first is slower than usual. Retries stay
+// within the request's deadline and a budget shared by all checks, so an overloaded store
+// is not hit by a retry storm. Stats().StoreRetries reports what was retried.
+// Example: gorly.New().Redis("localhost:6379").StoreRetries(gorly.StoreRetryConfig{MaxRetries: 2, Hedge: true})
+func (b *Builder) StoreRetries(config StoreRetryConfig) *Builder {
+	b.config.StoreRetries = config.toCore()
+	return b
+}
+
+// ScopeFailurePolicy sets what a scope does while the store is failing, ahead of the default
+// FailurePolicy: read scopes can stay available while sensitive ones, like password resets,
+// stay closed. Requests let through are marked FailedOpen and counted in Stats().FailedOpen.
+// Example: gorly.New().FailurePolicy(gorly.FailOpen).ScopeFailurePolicy("password-reset", gorly.FailClosed)
+func (b *Builder) ScopeFailurePolicy(scope string, policy FailurePolicy) *Builder {
+	if b.config.ScopeFailurePolicies == nil {
+		b.config.ScopeFailurePolicies = make(map[string]string)
+	}
+	b.config.ScopeFailurePolicies[scope] = string(policy)
+	return b
+}
+
+// FallbackTier applies the limits of tier to entities claiming a tier that no scope configures
+// Example: gorly.New().TierLimits(map[string]string{"free": "100/hour", "pro": "5000/hour"}).FallbackTier("free")
+func (b *Builder) FallbackTier(tier string) *Builder {
+	b.config.UnknownTierPolicy = core.UnknownTierFallback
+	b.config.FallbackTier = tier
+	return b
+}
+
+// ResolveTiers looks up the tier of every entity with resolver instead of trusting the
+// "tier:" prefix of the entity, for both middleware and direct Check calls. Resolved tiers
+// are cached for ttl (default: 1m), then served for up to staleTTL (default: 5m, negative
+// disables) while a background lookup refreshes them. Entities whose tier cannot be
+// looked up get the "free" tier.
+// Example: gorly.New().TierLimits(tiers).ResolveTiers(gorly.TierResolverFunc(billing.Plan), time.Minute, 0)
+func (b *Builder) ResolveTiers(resolver TierResolver, ttl, staleTTL time.Duration) *Builder {
+	b.config.TierResolver = resolver
+	b.config.TierCacheTTL = ttl
+	b.config.TierStaleTTL = staleTTL
+	return b
+}
+
+// EmptyEntity sets how requests are handled when the extractor returns no entity.
+// By default they all share one "anonymous" bucket, so a single client without
+// credentials can exhaust it for everyone else.
+// Example: gorly.New().ExtractorFunc(extractUser).EmptyEntity(gorly.EmptyEntityIP)
+func (b *Builder) EmptyEntity(policy EmptyEntityPolicy) *Builder {
+	b.config.EmptyEntityPolicy = string(policy)
+	return b
+}
+
+// ScopeFunc sets a custom function to determine the scope from HTTP requests
+// Example: gorly.New().ScopeFunc(func(r *http.Request) string { return strings.TrimPrefix(r.URL.Path, "/api/") })
+func (b *Builder) ScopeFunc(fn func(*http.Request) string) *Builder {
+	b.config.ScopeFunc = fn
+	return b
+}
+
+// PreAuthLimit adds an IP-based limit that runs before entity extraction.
+// It protects the authentication system from floods, while the regular
+// per-user or per-tier limits still apply to requests that get through.
+// Example: gorly.New().PreAuthLimit("300/minute").ExtractorFunc(extractUser).TierLimits(tiers)
+func (b *Builder) PreAuthLimit(limit string) *Builder {
+	b.config.PreAuthLimit = limit
+	if b.config.PreAuthScope == "" {
+		b.config.PreAuthScope = core.DefaultPreAuthScope
+	}
+	if b.config.PreAuthExtractor == nil {
+		b.config.PreAuthExtractor = extractIP
+	}
+	return b
+}
+
+// PreAuthExtractor sets the key used for the pre-auth phase (default: client IP)
+// Example: gorly.New().PreAuthLimit("300/minute").PreAuthExtractor(func(r *http.Request) string { return r.Header.Get("CF-Connecting-IP") })
+func (b *Builder) PreAuthExtractor(fn func(*http.Request) string) *Builder {
+	b.config.PreAuthExtractor = fn
+	return b
+}
+
+// PreAuthScope sets the scope name for pre-auth counters (default: "preauth")
+// Example: gorly.New().PreAuthLimit("300/minute").PreAuthScope("edge")
+func (b *Builder) PreAuthScope(scope string) *Builder {
+	b.config.PreAuthScope = scope
+	return b
+}
+
+// VersionedScopes prefixes scopes with the API version of each request (e.g. "v1:search"),
+// read from X-API-Version, the Accept header or a /v1/ path segment.
+// Versioned scopes fall back to the limits of the plain scope, so only legacy versions need entries.
+// Example: gorly.New().Limit("search", "1000/hour").Limit("v1:search", "100/hour").VersionedScopes()
+func (b *Builder) VersionedScopes() *Builder {
+	b.config.VersionFunc = extractAPIVersion
+	return b
+}
+
+// VersionFunc sets a custom function to extract the API version for versioned scopes
+// Example: gorly.New().VersionFunc(func(r *http.Request) string { return r.URL.Query().Get("api-version") })
+func (b *Builder) VersionFunc(fn func(*http.Request) string) *Builder {
+	b.config.VersionFunc = fn
+	return b
+}
+
+// BotScope routes requests the classifier flags as automated to a stricter scope
+// Example: gorly.New().Limit("bots", "60/hour").BotScope("bots", gorly.NewBotClassifier().IsBot)
+func (b *Builder) BotScope(scope string, classifier func(*http.Request) bool) *Builder {
+	b.config.BotScope = scope
+	b.config.BotClassifier = classifier
+	return b
+}
+
+// TrustedCallKey lets internal services sign requests with SignTrustedCall to bypass rate
+// limiting or move them into an elevated scope. Add several keys to rotate them; secrets
+// must be at least MinTrustedCallKeyLength bytes. Invalid signatures are ignored and counted.
+// Example: gorly.New().Limit("internal", "10000/minute").TrustedCallKey("billing", billingSecret)
+func (b *Builder) TrustedCallKey(keyID string, secret []byte) *Builder {
+	if b.config.TrustedCalls == nil {
+		b.config.TrustedCalls = &core.TrustedCallConfig{Keys: make(map[string][]byte)}
+	}
+	b.config.TrustedCalls.Keys[keyID] = secret
+	return b
+}
+
+// TrustedCallMaxAge sets how far the timestamp of a trusted call signature may be from now
+// (default: 1 minute). Signatures can be replayed against the same endpoint within it.
+// Example: gorly.New().TrustedCallKey("billing", billingSecret).TrustedCallMaxAge(10 * time.Second)
+func (b *Builder) TrustedCallMaxAge(d time.Duration) *Builder {
+	if b.config.TrustedCalls == nil {
+		b.config.TrustedCalls = &core.TrustedCallConfig{Keys: make(map[string][]byte)}
+	}
+	b.config.TrustedCalls.MaxAge = d
+	return b
+}
+
+// OnError sets a custom error handler
+// Example: gorly.New().OnError(func(err error) { log.Printf("Rate limit error: %v", err) })
+func (b *Builder) OnError(fn func(error)) *Builder {
+	b.config.ErrorHandler = fn
+	return b
+}
+
+// OnOverrideExpired sets a handler called when an entity override is removed at its expiry.
+// It runs on a background goroutine, once per override and limiter instance.
+// Example: gorly.New().OnOverrideExpired(func(o gorly.EntityOverride) { log.Printf("override of %s in %s expired", o.Entity, o.Scope) })
+func (b *Builder) OnOverrideExpired(fn func(EntityOverride)) *Builder {
+	b.config.OnOverrideExpired = func(o core.Override) {
+		fn(EntityOverride{Entity: o.Entity, Scope: o.Scope, Limit: o.Limit, ExpiresAt: o.ExpiresAt})
+	}
+	return b
+}
+
+// WatchKeyExpiry subscribes to the Redis keyspace notifications of the limiter's keys
+// expiring and reports the churn in Stats().KeyExpiry and the gorly_store_keys_expired
+// metrics. Redis publishes them only with notify-keyspace-events "Kx"; configure enables
+// them with CONFIG SET at startup, which managed Redis services often forbid.
+// Example: gorly.New().Redis("localhost:6379").WatchKeyExpiry(true)
+func (b *Builder) WatchKeyExpiry(configure bool) *Builder {
+	b.config.WatchKeyExpiry = true
+	b.config.ConfigureKeyspaceNotifications = configure
+	return b
+}
+
+// OnKeyExpired sets a handler called with every limiter key Redis expired, e.g. to verify
+// that idle state is reaped. It runs on a background goroutine; use WatchKeyExpiry to
+// enable the notifications.
+// Example: gorly.New().Redis("localhost:6379").WatchKeyExpiry(false).OnKeyExpired(func(key string) { log.Println("expired", key) })
+func (b *Builder) OnKeyExpired(fn func(key string)) *Builder {
+	b.config.OnKeyExpired = fn
+	return b
+}
+
+// OnForget sets a handler called with the record of every Forget, e.g. ForgetAuditLog
+// to keep an audit trail of data-subject deletions.
+// Example: gorly.New().OnForget(gorly.ForgetAuditLog(auditFile))
+func (b *Builder) OnForget(fn func(ForgetRecord)) *Builder {
+	b.config.OnForget = func(report core.ForgetReport, err error) {
+		fn(newForgetRecord(report, err))
+	}
+	return b
+}
+
+// SubjectID sets how forgotten entities are named in ForgetRecords. The default is a plain
+// SHA-256 digest; a keyed hash keeps guessable entities such as emails from being recovered.
+// Example: gorly.New().SubjectID(func(entity string) string { return hmacHex(auditKey, entity) })
+func (b *Builder) SubjectID(fn func(entity string) string) *Builder {
+	b.config.SubjectID = fn
+	return b
+}
+
+// OnDenied sets a custom handler for when requests are rate limited
+// Example: gorly.New().OnDenied(func(w http.ResponseWriter, r *http.Request, result *LimitResult) { ... })
+func (b *Builder) OnDenied(fn func(http.ResponseWriter, *http.Request, *LimitResult)) *Builder {
+	// Convert the user's handler to work with internal CoreResult
+	b.config.DeniedHandler = func(w http.ResponseWriter, r *http.Request, coreResult *core.CoreResult) {
+		// Convert CoreResult to LimitResult
+		limitResult := &LimitResult{
+			Allowed:    coreResult.Allowed,
+			Remaining:  coreResult.Remaining,
+			Limit:      coreResult.Limit,
+			Used:       coreResult.Used,
+			RetryAfter: coreResult.RetryAfter,
+			Window:     coreResult.Window,
+			ResetTime:  coreResult.ResetTime,
+		}
+		fn(w, r, limitResult)
+	}
+	return b
+}
+
+// Lockout locks an entity out after repeated authentication failures reported with ReportAuthFailure.
+// The first lockout lasts base and doubles with every further failure up to max.
+// Scopes restrict the lockout to specific scopes; by default it applies to all of them.
+// Example: gorly.New().Lockout(5, time.Minute, time.Hour, "login", "otp")
+func (b *Builder) Lockout(threshold int, base, max time.Duration, scopes ...string) *Builder {
+	lockout := b.lockout()
+	lockout.Threshold = threshold
+	lockout.BaseDuration = base
+	lockout.MaxDuration = max
+	lockout.Scopes = scopes
+	return b
+}
+
+// Challenge calls fn once an entity has reported the given number of failures,
+// giving the application a hook to demand a CAPTCHA before the lockout kicks in.
+// fn returns true to let the request through or writes a challenge response and returns false.
+// Example: gorly.New().Lockout(5, time.Minute, time.Hour).Challenge(3, requireCaptcha)
+func (b *Builder) Challenge(after int, fn func(http.ResponseWriter, *http.Request) bool) *Builder {
+	b.lockout().ChallengeAfter = after
+	b.config.ChallengeHandler = fn
+	return b
+}
+
+// lockout returns the lockout configuration, creating it if needed
+func (b *Builder) lockout() *core.LockoutConfig {
+	if b.config.Lockout == nil {
+		b.config.Lockout = &core.LockoutConfig{}
+	}
+	return b.config.Lockout
+}
+
+// ExemptMethods excludes requests with the given HTTP methods from rate limiting
+// Example: gorly.New().ExemptMethods(http.MethodHead)
+func (b *Builder) ExemptMethods(methods ...string) *Builder {
+	for _, method := range methods {
+		b.config.ExemptMethods = append(b.config.ExemptMethods, strings.ToUpper(method))
+	}
+	return b
+}
+
+// ExemptPreflight excludes CORS preflight requests from rate limiting so browsers
+// don't burn user budgets before the real request is sent
+// Example: gorly.New().ExemptPreflight()
+func (b *Builder) ExemptPreflight() *Builder {
+	b.config.ExemptPreflight = true
+	return b
+}
+
+// ExemptProbes lets Kubernetes liveness, readiness and startup probes (User-Agent
+// "kube-probe/...") through without a check, so they neither consume IP-based budgets nor
+// get a 429 that restarts a healthy pod. Bypassed probes are counted in
+// Stats().ProbeBypasses and the gorly_probe_bypasses_total metric.
+// Example: gorly.New().ExemptProbes()
+func (b *Builder) ExemptProbes() *Builder {
+	return b.ExemptProbesWith(ProbeConfig{})
+}
+
+// ExemptProbesWith lets the probe traffic described by config through without a check,
+// e.g. probes marked by a header or only those from the node network to health endpoints
+// Example: gorly.New().ExemptProbesWith(gorly.ProbeConfig{Networks: []string{"10.0.0.0/8"}, Paths: []string{"/healthz"}})
+func (b *Builder) ExemptProbesWith(config ProbeConfig) *Builder {
+	b.config.Probes = config.toCore()
+	return b
+}
+
+// MethodScope routes requests with the given HTTP method to a separate scope
+// Example: gorly.New().Limit("preflight", "1000/minute").MethodScope(http.MethodOptions, "preflight")
+func (b *Builder) MethodScope(method, scope string) *Builder {
+	if b.config.MethodScopes == nil {
+		b.config.MethodScopes = make(map[string]string)
+	}
+	b.config.MethodScopes[strings.ToUpper(method)] = scope
+	return b
+}
+
+// MethodScoping qualifies every scope with the request method, so GET and POST on the same
+// path are counted and reported separately (e.g. "search:GET" and "search:POST").
+// Limits configured for a method class ("search:read" for GET, HEAD and OPTIONS,
+// "search:write" otherwise) are shared by its methods; methods without a limit of their own
+// or of their class use the limit of the plain scope.
+// Example: gorly.New().Limit("search", "1000/minute").Limit("search:POST", "100/minute").MethodScoping()
+func (b *Builder) MethodScoping() *Builder {
+	b.config.MethodScoping = true
+	return b
+}
+
+// MethodLimits sets per-method limits for a scope and enables method scoping.
+// Keys are HTTP methods or the classes "read" and "write".
+// Example: gorly.New().MethodLimits("global", map[string]string{"read": "1000/minute", "write": "100/minute"})
+func (b *Builder) MethodLimits(scope string, limits map[string]string) *Builder {
+	b.config.MethodScoping = true
+	for method, limit := range limits {
+		b.config.Limits[core.MethodScope(scope, method)] = limit
+	}
+	return b
+}
+
+// Headers sets which rate limit headers responses carry. Public APIs that don't want to
+// reveal their limits can send only Retry-After (HeadersMinimal) or nothing (HeadersNone).
+// Example: gorly.New().Headers(gorly.HeadersMinimal)
+func (b *Builder) Headers(mode HeaderMode) *Builder {
+	b.config.HeaderMode = string(mode)
+	return b
+}
+
+// ScopeHeaders overrides the header mode for one scope
+// Example: gorly.New().Headers(gorly.HeadersNone).ScopeHeaders("partner-api", gorly.HeadersFull)
+func (b *Builder) ScopeHeaders(scope string, mode HeaderMode) *Builder {
+	if b.config.ScopeHeaderModes == nil {
+		b.config.ScopeHeaderModes = make(map[string]string)
+	}
+	b.config.ScopeHeaderModes[scope] = string(mode)
+	return b
+}
+
+// HeaderName sends a rate limit header under another name, or drops it when renamed to ""
+// Example: gorly.New().HeaderName("X-RateLimit-Limit", "RateLimit-Limit").HeaderName("X-RateLimit-Used", "")
+func (b *Builder) HeaderName(name, rename string) *Builder {
+	if b.config.HeaderNames == nil {
+		b.config.HeaderNames = make(map[string]string)
+	}
+	b.config.HeaderNames[name] = rename
+	return b
+}
+
+// DeniedStatus sets the HTTP status returned for denied requests (default 429)
+// Example: gorly.New().DeniedStatus(http.StatusServiceUnavailable)
+func (b *Builder) DeniedStatus(code int) *Builder {
+	b.config.DeniedStatusCode = code
+	return b
+}
+
+// ScopeDeniedStatus sets the HTTP status returned for denied requests in a specific scope
+// Example: gorly.New().ScopeDeniedStatus("overload", http.StatusServiceUnavailable)
+func (b *Builder) ScopeDeniedStatus(scope string, code int) *Builder {
+	if b.config.ScopeDeniedStatusCodes == nil {
+		b.config.ScopeDeniedStatusCodes = make(map[string]int)
+	}
+	b.config.ScopeDeniedStatusCodes[scope] = code
+	return b
+}
+
+// KeyPrefix sets the prefix of every store key, e.g. to share a Redis instance between services
+// Example: gorly.New().KeyPrefix("billing-api:ratelimit")
+func (b *Builder) KeyPrefix(prefix string) *Builder {
+	b.config.KeyPrefix = prefix
+	return b
+}
+
+// MaxKeyLength sets the longest store key written; longer keys are replaced by a SHA-256 digest
+// Example: gorly.New().MaxKeyLength(128)
+func (b *Builder) MaxKeyLength(n int) *Builder {
+	b.config.MaxKeyLength = n
+	return b
+}
+
+// MaxEntityLength sets the longest entity kept verbatim; longer entities are shortened with a digest suffix
+// Example: gorly.New().MaxEntityLength(128)
+func (b *Builder) MaxEntityLength(n int) *Builder {
+	b.config.MaxEntityLength = n
+	return b
+}
+
+// MaxScopeLength sets the longest unconfigured scope kept verbatim
+// Example: gorly.New().ScopeFunc(extractPathScope).MaxScopeLength(32)
+func (b *Builder) MaxScopeLength(n int) *Builder {
+	b.config.MaxScopeLength = n
+	return b
+}
+
+// MaxScopes caps the distinct unconfigured scopes a scope function may create. Scopes
+// beyond the cap share the "__other" scope and are counted as scope overflows, protecting
+// the store and metrics from a scope function that derives one scope per URL.
+// A negative n removes the cap (default: 1000).
+// Example: gorly.New().ScopeFunc(extractPathScope).MaxScopes(200)
+func (b *Builder) MaxScopes(n int) *Builder {
+	b.config.MaxScopes = n
+	return b
+}
+
+// MaxTrackedEntities caps the entities Stats breaks down by entity. When more entities
+// are seen, the least recently seen is dropped, so memory stays bounded however many
+// clients a limiter keyed by IP sees. A negative n disables the breakdown (default: 1000).
+// Example: gorly.New().Limit("global", "1000/hour").MaxTrackedEntities(10000)
+func (b *Builder) MaxTrackedEntities(n int) *Builder {
+	b.config.MaxTrackedEntities = n
+	return b
+}
+
+// DenyScopes deny-lists scope name patterns in path.Match syntax. Build and hot reloads
+// fail when a limit, override or other scope setting names a matching scope. Scopes
+// starting with "__" are reserved for internal bookkeeping and always denied.
+// Example: gorly.New().Limit("global", "1000/hour").DenyScopes("debug*", "internal:*")
+func (b *Builder) DenyScopes(patterns ...string) *Builder {
+	b.config.DeniedScopes = append(b.config.DeniedScopes, patterns...)
+	return b
+}
+
+// RejectInvalidInput rejects entities and scopes with control characters, surrounding
+// whitespace or excess length instead of normalizing them. Checks return an error
+// matching IsInvalidInput and the middleware answers 400 Bad Request.
+// Example: gorly.New().RejectInvalidInput()
+func (b *Builder) RejectInvalidInput() *Builder {
+	b.config.RejectInvalidInput = true
+	return b
+}
+
+// Cost makes requests matching a route pattern consume more than one unit of quota.
+// Patterns are "METHOD /path" or "/path" for every method; a trailing "*" matches a path prefix.
+// Example: gorly.New().Limit("global", "1000/hour").Cost("POST /v1/export", 10).Cost("/v1/reports/*", 5)
+func (b *Builder) Cost(pattern string, cost int64) *Builder {
+	if b.config.Costs == nil {
+		b.config.Costs = make(map[string]int64)
+	}
+	b.config.Costs[pattern] = cost
+	return b
+}
+
+// Costs sets the cost of several route patterns at once; see Cost.
+// Costs can be changed at runtime through the costs section of a HotReloadConfig.
+// Example: gorly.New().Costs(map[string]int64{"POST /v1/export": 10, "GET /v1/items": 1})
+func (b *Builder) Costs(costs map[string]int64) *Builder {
+	for pattern, cost := range costs {
+		b.Cost(pattern, cost)
+	}
+	return b
+}
+
+// Scale sets the initial multiplier applied to every configured limit
+// Example: gorly.New().Limit("global", "1000/hour").Scale(0.5)
+func (b *Builder) Scale(factor float64) *Builder {
+	b.config.Scale = factor
+	return b
+}
+
+// MaintenanceResponse sets the status and message returned while maintenance mode is enabled
+// Example: gorly.New().MaintenanceResponse(http.StatusTooManyRequests, "Read-only maintenance until 02:00 UTC")
+func (b *Builder) MaintenanceResponse(status int, message string) *Builder {
+	b.config.MaintenanceStatusCode = status
+	b.config.MaintenanceMessage = message
+	return b
+}
+
+// MaintenanceRetryAfter sets the Retry-After suggested to callers rejected during maintenance
+// Example: gorly.New().MaintenanceRetryAfter(30 * time.Minute)
+func (b *Builder) MaintenanceRetryAfter(d time.Duration) *Builder {
+	b.config.MaintenanceRetryAfter = d
+	return b
+}
+
+// DenialCache answers repeated checks from entities far over their limit locally,
+// without evaluating the algorithm against the store. Denials retrying at least
+// threshold from now are cached for at most ttl (defaults: 1s and 5s).
+// A cached denial is never served past its retry time, and changing limits clears the cache.
+// Example: gorly.New().Redis("localhost:6379").DenialCache(5*time.Second, 10*time.Second)
+func (b *Builder) DenialCache(threshold, ttl time.Duration) *Builder {
+	b.config.DenialCache = true
+	b.config.DenialCacheThreshold = threshold
+	b.config.DenialCacheTTL = ttl
+	return b
+}
+
+// EnableGrants allows temporary budgets to be handed out with Limiter.Grant. Each check
+// then also reads the entity's grant from the store, so grants are opt-in.
+// Example: gorly.New().Redis("localhost:6379").EnableGrants()
+func (b *Builder) EnableGrants() *Builder {
+	b.config.Grants = true
+	return b
+}
+
+// Prewarm creates the state of known heavy hitters when the limiter is built, so the first
+// burst after a deploy does not pay for creating keys. Failures are passed to the error
+// handler rather than failing Build; existing state is never overwritten.
+// Example: gorly.New().Redis("localhost:6379").Prewarm(gorly.PrewarmSpec{Entity: "partner:acme"})
+func (b *Builder) Prewarm(specs ...PrewarmSpec) *Builder {
+	b.config.Prewarm = append(b.config.Prewarm, corePrewarmSpecs(specs)...)
+	return b
+}
+
+// ClockSkewTolerance lets sliding windows adopt request timestamps recorded by instances whose
+// clocks lead by up to d. A lagging instance then expires requests when the leading one does,
+// instead of holding on to them and under-admitting.
+// Example: gorly.New().Redis("localhost:6379").ClockSkewTolerance(250*time.Millisecond)
+func (b *Builder) ClockSkewTolerance(d time.Duration) *Builder {
+	b.config.ClockSkewTolerance = d
+	return b
+}
+
+// StoreClock evaluates windows on the clock of the store (Redis TIME) instead of each
+// instance's clock, so instances with NTP drift agree on window boundaries. The offset to the
+// store clock is measured every syncInterval (default: 10s) rather than on every check.
+// Example: gorly.New().Redis("localhost:6379").StoreClock(30*time.Second)
+func (b *Builder) StoreClock(syncInterval time.Duration) *Builder {
+	b.config.ClockSource = core.ClockSourceStore
+	b.config.ClockSyncInterval = syncInterval
+	return b
+}
+
+// StatsWriteBehind keeps per-scope request and denial counters in the store, shared by
+// every instance, without a store write per request: counts accumulate locally and are
+// flushed every interval or once events requests are pending (defaults: 1s and 1000).
+// A crash loses at most the pending counts; Close flushes them.
+// Example: gorly.New().Redis("localhost:6379").StatsWriteBehind(500*time.Millisecond, 5000)
+func (b *Builder) StatsWriteBehind(interval time.Duration, events int) *Builder {
+	b.config.StatsWriteBehind = true
+	b.config.StatsFlushInterval = interval
+	b.config.StatsFlushEvents = events
+	return b
+}
+
+// EnableMetrics enables Prometheus metrics collection
+// Example: gorly.New().EnableMetrics()
+func (b *Builder) EnableMetrics() *Builder {
+	b.config.MetricsEnabled = true
+	return b
+}
+
+// Build creates the rate limiter from the builder configuration
+func (b *Builder) Build() (Limiter, error) {
+	// Validate configuration
+	if err := b.config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	// Create the core limiter
+	limiter, err := core.NewLimiter(b.config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create limiter: %w", err)
+	}
+
+	return &limiterImpl{
+		core:   limiter,
+		config: b.config,
+	}, nil
+}
+
+// Middleware builds the limiter and returns middleware that auto-detects the framework
+func (b *Builder) Middleware() interface{} {
+	limiter, err := b.Build()
+	if err != nil {
+		panic(fmt.Sprintf("failed to build limiter: %v", err))
+	}
+	return limiter.Middleware()
+}
+
+// =============================================================================
+// Redis configuration options
+// =============================================================================
+
+// RedisOption configures Redis connection options
+type RedisOption func(*core.Config)
+
+// RedisPassword sets the Redis password
+func RedisPassword(password string) RedisOption {
+	return func(c *core.Config) {
+		c.RedisPassword = password
+	}
+}
+
+// RedisDB sets the Redis database number
+func RedisDB(db int) RedisOption {
+	return func(c *core.Config) {
+		c.RedisDB = db
+	}
+}
+
+// RedisTLS connects to Redis over TLS, as managed services such as ElastiCache with
+// in-transit encryption require
+// Example: gorly.New().Redis("master.cache.abc123.use1.cache.amazonaws.com:6379", gorly.RedisTLS())
+func RedisTLS() RedisOption {
+	return func(c *core.Config) {
+		c.RedisTLS = true
+	}
+}
+
+// RedisLazyConnect builds the limiter without waiting for a connection to Redis; the
+// first check connects instead. Serverless functions start faster, and a Redis that is
+// down surfaces as failed checks, handled by the failure policy, rather than a failed Build.
+// Example: gorly.New().Redis("redis:6379", gorly.RedisLazyConnect())
+func RedisLazyConnect() RedisOption {
+	return func(c *core.Config) {
+		c.RedisLazyConnect = true
+	}
+}
+
+// RedisReadReplica sends stats and inspection reads (Stats, Peek, Diagnostics) to a replica
+// of the primary, so dashboards do not add latency to checks, which always use the primary.
+// Answers from the replica are marked Stale. It connects with the primary's password and database.
+// Example: gorly.New().Redis("redis-primary:6379", gorly.RedisReadReplica("redis-replica:6379"))
+func RedisReadReplica(address string) RedisOption {
+	return func(c *core.Config) {
+		c.RedisReadReplica = address
+	}
+}
+
+// RedisStandby keeps a second Redis warm by copying writes to it in the background. After
+// consecutive primary errors checks switch to the standby, and once the primary responds
+// again the keys written meanwhile are copied back before switching back. The standby
+// connects with the primary's password and database.
+// Example: gorly.New().Redis("redis-primary:6379", gorly.RedisStandby("redis-standby:6379"))
+func RedisStandby(address string) RedisOption {
+	return func(c *core.Config) {
+		c.RedisStandby = address
+	}
+}
+
+// MemoryStandby is RedisStandby with an in-process memory store as the standby, which keeps
+// each instance limiting on its own while Redis is down
+// Example: gorly.New().Redis("localhost:6379", gorly.MemoryStandby())
+func MemoryStandby() RedisOption {
+	return func(c *core.Config) {
+		c.RedisStandby = "memory"
+	}
+}
+
+// RedisSecrets fetches the Redis username, password and TLS material from a secrets provider,
+// such as VaultSecretsProvider, instead of RedisPassword. The limiter asks for them every
+// minute and reconnects when they rotate; replicas and standbys use the same secrets.
+// Example: gorly.New().Redis("redis:6379", gorly.RedisSecrets(gorly.NewVaultSecretsProvider(vaultConfig)))
+func RedisSecrets(provider SecretsProvider) RedisOption {
+	return func(c *core.Config) {
+		c.RedisSecrets = storeSecrets(provider)
+	}
+}
+
+// RedisShardFailurePolicy decides what happens to the keys of a dead shard of RedisShards:
+// "failover" (default) moves them to the next healthy shard, whose counts start from zero,
+// and "fail_closed" fails their checks, which the limiter's failure policy then handles
+// Example: gorly.New().RedisShards(addresses, gorly.RedisShardFailurePolicy("fail_closed"))
+func RedisShardFailurePolicy(policy string) RedisOption {
+	return func(c *core.Config) {
+		c.RedisShardFailurePolicy = policy
+	}
 }
 
 // RedisPoolSize sets the Redis connection pool size
@@ -344,6 +1577,38 @@ func RedisPoolSize(size int) RedisOption {
 	}
 }
 
+// =============================================================================
+// Postgres configuration options
+// =============================================================================
+
+// PostgresOption configures the Postgres store
+type PostgresOption func(*core.Config)
+
+// PostgresTable sets the table limiter state is kept in, optionally qualified by a schema
+// (default: "gorly_limits")
+func PostgresTable(table string) PostgresOption {
+	return func(c *core.Config) {
+		c.PostgresTable = table
+	}
+}
+
+// PostgresDriver sets the database/sql driver name, for drivers registered under other
+// names than "pgx" or "postgres"
+func PostgresDriver(driver string) PostgresOption {
+	return func(c *core.Config) {
+		c.PostgresDriver = driver
+	}
+}
+
+// PostgresDB shares an open connection pool with the application instead of connecting to
+// the DSN, which is then ignored; closing the limiter leaves the pool open
+// Example: gorly.New().Postgres("", gorly.PostgresDB(db))
+func PostgresDB(db *sql.DB) PostgresOption {
+	return func(c *core.Config) {
+		c.PostgresDB = db
+	}
+}
+
 // =============================================================================
 // Default entity extractors
 // =============================================================================
@@ -442,21 +1707,174 @@ func (l *limiterImpl) Middleware() interface{} {
 }
 
 func (l *limiterImpl) For(framework middleware.FrameworkType) interface{} {
+	if mw, ok := adapterFor(l, framework); ok {
+		return mw
+	}
 	mw := middleware.New(l.core, l.config).(*middleware.UniversalMiddleware)
 	return mw.For(framework)
 }
 
+func (l *limiterImpl) HTTPMiddleware() func(http.Handler) http.Handler {
+	return middleware.New(l.core, l.config).(*middleware.UniversalMiddleware).HTTP()
+}
+
 func (l *limiterImpl) Check(ctx context.Context, entity string, scope ...string) (*LimitResult, error) {
-	scopeName := "global"
-	if len(scope) > 0 && scope[0] != "" {
-		scopeName = scope[0]
+	// Explicit arguments win over WithEntity and WithScope context values
+	entity, scopeName := callTarget(ctx, entity, scope)
+
+	// Maintenance mode rejects every entity outside the allowlist
+	result := l.core.CheckMaintenance(entity)
+	if result == nil {
+		var err error
+		result, err = l.core.Check(ctx, entity, scopeName)
+		if err != nil {
+			return nil, newInputError(err)
+		}
+	}
+
+	return &LimitResult{
+		Allowed:     result.Allowed,
+		Remaining:   result.Remaining,
+		Limit:       result.Limit,
+		Used:        result.Used,
+		RetryAfter:  result.RetryAfter,
+		Window:      result.Window,
+		ResetTime:   result.ResetTime,
+		Maintenance: result.Maintenance,
+		Cached:      result.Cached,
+		UnknownTier: result.UnknownTier,
+
+		GrantRemaining: result.GrantRemaining,
+		Enforcement:    result.Enforcement,
+		ShadowDenied:   result.ShadowDenied,
+		FailedOpen:     result.FailedOpen,
+		Degraded:       result.Degraded,
+		Alignment:      WindowAlignment(result.Alignment),
+	}, nil
+}
+
+func (l *limiterImpl) Allow(ctx context.Context, entity string, scope ...string) (bool, error) {
+	result, err := l.Check(ctx, entity, scope...)
+	if err != nil {
+		return false, err
 	}
+	return result.Allowed, nil
+}
 
-	result, err := l.core.Check(ctx, entity, scopeName)
+func (l *limiterImpl) Limits(entity string) ([]ScopeLimit, error) {
+	effective, err := l.core.Limits(entity)
 	if err != nil {
 		return nil, err
 	}
 
+	limits := make([]ScopeLimit, len(effective))
+	for i, limit := range effective {
+		limits[i] = ScopeLimit{
+			Scope:    limit.Scope,
+			Tier:     limit.Tier,
+			Rate:     limit.Rate,
+			Requests: limit.Requests,
+			Window:   limit.Window,
+			Source:   limit.Source,
+		}
+	}
+	return limits, nil
+}
+
+func (l *limiterImpl) Peek(ctx context.Context, entity string, scope ...string) (*LimitResult, error) {
+	entity, scopeName := callTarget(ctx, entity, scope)
+
+	// Report maintenance mode the same way Check would
+	result := l.core.CheckMaintenance(entity)
+	if result == nil {
+		var err error
+		result, err = l.core.Peek(ctx, entity, scopeName)
+		if err != nil {
+			return nil, newInputError(err)
+		}
+	}
+
+	return &LimitResult{
+		Allowed:     result.Allowed,
+		Remaining:   result.Remaining,
+		Limit:       result.Limit,
+		Used:        result.Used,
+		RetryAfter:  result.RetryAfter,
+		Window:      result.Window,
+		ResetTime:   result.ResetTime,
+		Maintenance: result.Maintenance,
+
+		GrantRemaining: result.GrantRemaining,
+		Enforcement:    result.Enforcement,
+		ShadowDenied:   result.ShadowDenied,
+		Stale:          result.Stale,
+		Alignment:      WindowAlignment(result.Alignment),
+	}, nil
+}
+
+func (l *limiterImpl) Diagnostics(ctx context.Context, entity string, scope ...string) (*Diagnostics, error) {
+	entity, scopeName := callTarget(ctx, entity, scope)
+
+	d, err := l.core.Diagnostics(ctx, entity, scopeName)
+	if err != nil {
+		return nil, newInputError(err)
+	}
+
+	return &Diagnostics{
+		Entity:        d.Entity,
+		Scope:         d.Scope,
+		Algorithm:     d.Algorithm,
+		Limit:         d.Limit,
+		Window:        d.Window,
+		Source:        d.Source,
+		Stale:         d.Stale,
+		Bucket:        d.Bucket,
+		SlidingWindow: d.SlidingWindow,
+		Pattern:       d.Pattern,
+	}, nil
+}
+
+// Prewarm creates the state of the given entities ahead of their first request
+func (l *limiterImpl) Prewarm(ctx context.Context, specs []PrewarmSpec) error {
+	if _, err := l.core.Prewarm(ctx, corePrewarmSpecs(specs)); err != nil {
+		return newInputError(err)
+	}
+	return nil
+}
+
+// corePrewarmSpecs converts pre-warming specs to their core form
+func corePrewarmSpecs(specs []PrewarmSpec) []core.PrewarmSpec {
+	converted := make([]core.PrewarmSpec, len(specs))
+	for i, spec := range specs {
+		converted[i] = core.PrewarmSpec{Entity: spec.Entity, Scopes: append([]string(nil), spec.Scopes...)}
+	}
+	return converted
+}
+
+// recordAuthFailure counts a failed authentication and returns the lockout now in effect
+func (l *limiterImpl) recordAuthFailure(ctx context.Context, entity string) (time.Duration, error) {
+	state, err := l.core.RecordFailure(ctx, entity)
+	if err != nil {
+		return 0, newInputError(err)
+	}
+	if !state.Locked(time.Now()) {
+		return 0, nil
+	}
+	return time.Until(state.LockedUntil), nil
+}
+
+// recordAuthSuccess clears the failure history after a successful authentication
+func (l *limiterImpl) recordAuthSuccess(ctx context.Context, entity string) error {
+	return newInputError(l.core.RecordSuccess(ctx, entity))
+}
+
+// chargeTokens converts model usage into budget units and charges them
+func (l *limiterImpl) chargeTokens(ctx context.Context, entity, scope, model string, promptTokens, completionTokens int64) (*LimitResult, error) {
+	result, err := l.core.ConsumeTokens(ctx, entity, scope, l.config.TokenCostOf(model, promptTokens, completionTokens))
+	if err != nil {
+		return nil, newInputError(err)
+	}
+
 	return &LimitResult{
 		Allowed:    result.Allowed,
 		Remaining:  result.Remaining,
@@ -468,22 +1886,354 @@ func (l *limiterImpl) Check(ctx context.Context, entity string, scope ...string)
 	}, nil
 }
 
-func (l *limiterImpl) Allow(ctx context.Context, entity string, scope ...string) (bool, error) {
-	result, err := l.Check(ctx, entity, scope...)
-	if err != nil {
-		return false, err
+// extractEntity identifies the caller of a request using the configured extractor
+func (l *limiterImpl) extractEntity(r *http.Request) string {
+	if entity, ok := core.EntityFromContext(r.Context()); ok {
+		return entity
 	}
-	return result.Allowed, nil
+	return l.config.ExtractorFunc(r)
+}
+
+func (l *limiterImpl) Scale(factor float64) error {
+	return l.core.SetScale(factor)
+}
+
+func (l *limiterImpl) ScaleFactor() float64 {
+	return l.core.Scale()
+}
+
+func (l *limiterImpl) ConfigHash() string {
+	return l.core.ConfigHash()
+}
+
+func (l *limiterImpl) MaintenanceMode(enabled bool, allowlist []string) {
+	l.core.SetMaintenance(enabled, allowlist)
+}
+
+func (l *limiterImpl) RunWhenLeader(name string, interval time.Duration, fn func(ctx context.Context) error) error {
+	return l.core.RunWhenLeader(name, interval, fn)
 }
 
 func (l *limiterImpl) Stats(ctx context.Context) (*LimitStats, error) {
-	// TODO: Implement stats collection
-	return &LimitStats{
-		TotalRequests: 0,
-		TotalDenied:   0,
-		ByScope:       make(map[string]*LimitScopeStats),
-		ByEntity:      make(map[string]*EntityStats),
-	}, nil
+	usage := l.core.UsageStats()
+	stats := &LimitStats{
+		TotalRequests:    usage.Requests,
+		TotalDenied:      usage.Denied,
+		ByScope:          make(map[string]*LimitScopeStats, len(usage.ByScope)),
+		ByEntity:         make(map[string]*EntityStats, len(usage.ByEntity)),
+		EvictedEntities:  usage.EvictedEntities,
+		DenialCacheHits:  l.core.DenialCacheHits(),
+		EmptyEntities:    l.core.EmptyEntities(),
+		ProbeBypasses:    l.core.Probes(),
+		ScopeOverflows:   l.core.ScopeOverflows(),
+		UnknownTiers:     l.core.UnknownTiers(),
+		ExpiredOverrides: l.core.ExpiredOverrides(),
+		ShadowDenials:    l.core.ShadowDenials(),
+		FailedOpen:       l.core.FailedOpen(),
+		FailedClosed:     l.core.FailedClosed(),
+		ClockOffset:      l.core.ClockOffset(),
+		StatsFlush:       l.statsFlush(),
+		TierCache:        l.tierCache(),
+		TrustedCalls:     l.trustedCalls(),
+		StoreRetries:     l.storeRetries(),
+		KeyExpiry:        l.keyExpiry(),
+		StateCompression: l.stateCompression(),
+		LocalCache:       l.localCache(),
+	}
+	stats.StoreKeys, _ = l.core.StoreKeys()
+	stats.LocalFallbackAllowed, stats.LocalFallbackDenied = l.core.LocalFallbacks()
+	for scope, counter := range usage.ByScope {
+		stats.ByScope[scope] = &LimitScopeStats{Scope: scope, Requests: counter.Requests, Denied: counter.Denied, LastUsed: counter.LastUsed}
+	}
+	for entity, counter := range usage.ByEntity {
+		stats.ByEntity[entity] = &EntityStats{Entity: entity, Requests: counter.Requests, Denied: counter.Denied, LastUsed: counter.LastUsed}
+	}
+
+	// Write-behind counters are shared by every instance using the store, so they replace
+	// the counts of this instance; entities are only ever counted locally
+	counters, err := l.core.StatsCounters(ctx)
+	if err != nil {
+		return nil, err
+	}
+	stats.Stale = counters != nil && l.core.ReadsFromReplica()
+	if counters != nil {
+		stats.TotalRequests, stats.TotalDenied = 0, 0
+		byScope := make(map[string]*LimitScopeStats, len(counters))
+		for scope, counter := range counters {
+			stats.TotalRequests += counter.Requests
+			stats.TotalDenied += counter.Denied
+			byScope[scope] = &LimitScopeStats{Scope: scope, Requests: counter.Requests, Denied: counter.Denied}
+			if local, ok := stats.ByScope[scope]; ok {
+				byScope[scope].LastUsed = local.LastUsed
+			}
+		}
+		stats.ByScope = byScope
+	}
+	stats.ByStore = l.storeStats(ctx, stats.ByScope)
+	return stats, nil
+}
+
+// storeStats reports every store of a limiter with scope stores, or nil with a single store
+func (l *limiterImpl) storeStats(ctx context.Context, byScope map[string]*LimitScopeStats) map[string]*StoreStats {
+	statuses := l.core.StoreStatuses(ctx)
+	if len(statuses) < 2 {
+		return nil
+	}
+	byStore := make(map[string]*StoreStats, len(statuses))
+	for _, status := range statuses {
+		storeStats := &StoreStats{
+			Store:   status.Name,
+			Default: status.Default,
+			Scopes:  status.Scopes,
+			Keys:    status.Keys,
+			Healthy: status.Err == nil,
+		}
+		if status.Err != nil {
+			storeStats.Error = status.Err.Error()
+		}
+		byStore[status.Name] = storeStats
+	}
+	for scope, scopeStats := range byScope {
+		if storeStats, ok := byStore[l.core.ScopeStore(scope)]; ok {
+			storeStats.Requests += scopeStats.Requests
+			storeStats.Denied += scopeStats.Denied
+		}
+	}
+	return byStore
+}
+
+// statsFlush returns the write-behind flush metrics, or nil when write-behind stats are disabled
+func (l *limiterImpl) statsFlush() *StatsFlushStats {
+	flush := l.core.StatsFlushStats()
+	if flush == nil {
+		return nil
+	}
+	return &StatsFlushStats{
+		Flushes:           flush.Flushes,
+		FlushErrors:       flush.FlushErrors,
+		FlushedEvents:     flush.FlushedEvents,
+		PendingEvents:     flush.PendingEvents,
+		LastFlushDuration: flush.LastFlushDuration,
+		LastFlush:         flush.LastFlush,
+	}
+}
+
+// tierCache returns the tier resolver cache metrics, or nil without a tier resolver
+func (l *limiterImpl) tierCache() *TierCacheStats {
+	cache := l.core.TierCacheStats()
+	if cache == nil {
+		return nil
+	}
+	return &TierCacheStats{
+		Hits:      cache.Hits,
+		StaleHits: cache.StaleHits,
+		Misses:    cache.Misses,
+		Errors:    cache.Errors,
+		Entries:   cache.Entries,
+	}
+}
+
+// storeKeys returns how many keys the store holds; ok is false for stores that cannot count them cheaply
+func (l *limiterImpl) storeKeys() (int64, bool) {
+	return l.core.StoreKeys()
+}
+
+// storePool returns the connection pool metrics, or nil for stores without a Redis pool
+func (l *limiterImpl) storePool() *StorePoolStats {
+	pool := l.core.StorePoolStats()
+	if pool == nil {
+		return nil
+	}
+	return &StorePoolStats{
+		Hits:       int64(pool.Hits),
+		Misses:     int64(pool.Misses),
+		Timeouts:   int64(pool.Timeouts),
+		TotalConns: int64(pool.TotalConns),
+		IdleConns:  int64(pool.IdleConns),
+		StaleConns: int64(pool.StaleConns),
+	}
+}
+
+// storeRetries returns the retry metrics of store reads, or nil if reads are not retried
+func (l *limiterImpl) storeRetries() *StoreRetryStats {
+	retries := l.core.StoreRetryStats()
+	if retries == nil {
+		return nil
+	}
+	return &StoreRetryStats{
+		Reads:           retries.Reads,
+		Retries:         retries.Retries,
+		Hedges:          retries.Hedges,
+		HedgeWins:       retries.HedgeWins,
+		BudgetExhausted: retries.BudgetExhausted,
+		DeadlineSkipped: retries.DeadlineSkipped,
+		HedgeDelay:      retries.HedgeDelay,
+	}
+}
+
+// keyExpiry returns the expiries of the limiter's keys, or nil unless they are watched
+func (l *limiterImpl) keyExpiry() *KeyExpiryStats {
+	expiry := l.core.KeyExpiryStats()
+	if expiry == nil {
+		return nil
+	}
+	return &KeyExpiryStats{Expired: expiry.Expired, LastMinute: expiry.LastMinute, ByKind: expiry.ByKind}
+}
+
+// stateCompression returns the compression metrics of algorithm state, or nil without StateCompression
+func (l *limiterImpl) stateCompression() *StateCompressionStats {
+	compression := l.core.StateCompressionStats()
+	if compression == nil {
+		return nil
+	}
+	return &StateCompressionStats{
+		Codec:        compression.Algorithm,
+		Compressed:   compression.Compressed,
+		Skipped:      compression.Skipped,
+		Decompressed: compression.Decompressed,
+		BytesIn:      compression.BytesIn,
+		BytesOut:     compression.BytesOut,
+		Ratio:        compression.CompressionRate,
+		EncodeTime:   compression.CompressTime,
+		DecodeTime:   compression.DecompressTime,
+	}
+}
+
+// localCache returns the local cache metrics, or nil without RedisWithLocalCache
+func (l *limiterImpl) localCache() *LocalCacheStats {
+	cache := l.core.StoreLocalCacheStats()
+	if cache == nil {
+		return nil
+	}
+	return &LocalCacheStats{
+		Hits:        cache.Hits,
+		Misses:      cache.Misses,
+		HitRatio:    cache.HitRatio,
+		Keys:        cache.Keys,
+		PendingKeys: cache.PendingKeys,
+		Syncs:       cache.Syncs,
+		SyncErrors:  cache.SyncErrors,
+		LastSync:    cache.LastSync,
+		Drift:       cache.Drift,
+		TotalDrift:  cache.TotalDrift,
+		MaxDrift:    cache.MaxDrift,
+	}
+}
+
+// storeFailover returns the failover metrics, or nil for stores without a standby
+func (l *limiterImpl) storeFailover() *StoreFailoverStats {
+	failover := l.core.StoreFailoverStats()
+	if failover == nil {
+		return nil
+	}
+	return &StoreFailoverStats{
+		FailedOver:         failover.FailedOver,
+		Failovers:          failover.Failovers,
+		Failbacks:          failover.Failbacks,
+		Replicated:         failover.Replicated,
+		ReplicationDropped: failover.ReplicationDropped,
+		ReplicationErrors:  failover.ReplicationErrors,
+		Reconciled:         failover.Reconciled,
+	}
+}
+
+// configVersion returns the generation of the limits and the version of the last hot-reloaded config
+func (l *limiterImpl) configVersion() *ConfigVersion {
+	generation, version := l.core.ConfigVersion()
+	return &ConfigVersion{Generation: generation, Version: version, Hash: l.core.ConfigHash()}
+}
+
+// scopeOverflows returns how many checks had their scope folded into OverflowScope
+func (l *limiterImpl) scopeOverflows() int64 {
+	return l.core.ScopeOverflows()
+}
+
+// guardScope returns the scope a check of scope is counted under
+func (l *limiterImpl) guardScope(scope string) string {
+	if guarded, err := l.config.SanitizeScope(scope); err == nil {
+		return guarded
+	}
+	return scope
+}
+
+// updateLimits swaps in new request limits
+func (l *limiterImpl) updateLimits(update core.LimitUpdate) error {
+	return l.core.UpdateLimits(update)
+}
+
+func (l *limiterImpl) deniedScopes() []string {
+	return l.config.DeniedScopes
+}
+
+// saveConfigSnapshot stores a configuration shared with instances that start later
+func (l *limiterImpl) saveConfigSnapshot(ctx context.Context, data []byte) error {
+	return l.core.SaveConfigSnapshot(ctx, data)
+}
+
+// loadConfigSnapshot returns the configuration last shared through the store, or nil
+func (l *limiterImpl) loadConfigSnapshot(ctx context.Context) ([]byte, error) {
+	return l.core.LoadConfigSnapshot(ctx)
+}
+
+// saveReportState stores the counters of the last daily report
+func (l *limiterImpl) saveReportState(ctx context.Context, data []byte) error {
+	return l.core.SaveReportState(ctx, data)
+}
+
+// loadReportState returns the counters of the last daily report, or nil
+func (l *limiterImpl) loadReportState(ctx context.Context) ([]byte, error) {
+	return l.core.LoadReportState(ctx)
+}
+
+// setCosts replaces the endpoint cost table
+func (l *limiterImpl) setCosts(costs map[string]int64) error {
+	return l.core.SetCosts(costs)
+}
+
+// unknownTiers returns how many checks claimed a tier no scope configures
+func (l *limiterImpl) unknownTiers() int64 {
+	return l.core.UnknownTiers()
+}
+
+// enforcement returns the scopes whose enforcement mode was configured
+func (l *limiterImpl) enforcement() map[string]string {
+	return l.core.EnforcementModes()
+}
+
+// shadowDenials returns how many requests shadow mode let through, per scope
+func (l *limiterImpl) shadowDenials() map[string]int64 {
+	return l.core.ShadowDenials()
+}
+
+// failurePolicies returns the default failure policy under "*" and every scope's own
+func (l *limiterImpl) failurePolicies() map[string]string {
+	return l.core.FailurePolicies()
+}
+
+// failedOpen returns how many checks were let through while the store failed, per scope
+func (l *limiterImpl) failedOpen() map[string]int64 {
+	return l.core.FailedOpen()
+}
+
+// failedClosed returns how many checks were rejected while the store failed, per scope
+func (l *limiterImpl) failedClosed() map[string]int64 {
+	return l.core.FailedClosed()
+}
+
+// localFallbacks returns how many checks the local fallback allowed and denied while the
+// store failed, per scope
+func (l *limiterImpl) localFallbacks() (allowed, denied map[string]int64) {
+	return l.core.LocalFallbacks()
+}
+
+// expiredOverrides returns how many entity overrides were removed at their expiry
+func (l *limiterImpl) expiredOverrides() int64 {
+	return l.core.ExpiredOverrides()
+}
+
+// emptyEntities returns how many middleware requests had no entity
+func (l *limiterImpl) emptyEntities() int64 {
+	return l.core.EmptyEntities()
 }
 
 func (l *limiterImpl) Health(ctx context.Context) error {
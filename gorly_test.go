@@ -2,9 +2,17 @@
 package ratelimit
 
 import (
+	"bufio"
 	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 )
@@ -154,6 +162,9 @@ func TestPresets(t *testing.T) {
 		{"PublicAPI", PublicAPI()},
 		{"Microservice", Microservice()},
 		{"WebApp", WebApp()},
+		{"AuthProtection", AuthProtection()},
+		{"MobileBackend", MobileBackend()},
+		{"AIGateway", AIGateway()},
 	}
 
 	for _, tt := range tests {
@@ -308,3 +319,1645 @@ func createTestRequest(method, path string, headers map[string]string) *http.Req
 
 	return req
 }
+
+func TestDeniedStatusPerScope(t *testing.T) {
+	limiter, err := New().
+		Limit("global", "1/minute").
+		Limit("overload", "1/minute").
+		ScopeFunc(func(r *http.Request) string { return r.URL.Query().Get("scope") }).
+		ScopeDeniedStatus("overload", http.StatusServiceUnavailable).
+		Build()
+	if err != nil {
+		t.Fatalf("Failed to build limiter: %v", err)
+	}
+	defer limiter.Close()
+
+	handler := limiter.For(HTTP).(func(http.Handler) http.Handler)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		path     string
+		expected int
+	}{
+		{"/?scope=global", http.StatusTooManyRequests},
+		{"/?scope=overload", http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		var w *httptest.ResponseRecorder
+		for i := 0; i < 2; i++ {
+			w = httptest.NewRecorder()
+			handler.ServeHTTP(w, createTestRequest("GET", tt.path, nil))
+		}
+		if w.Code != tt.expected {
+			t.Errorf("%s: expected status %d, got %d", tt.path, tt.expected, w.Code)
+		}
+		if w.Header().Get("Retry-After") == "" {
+			t.Errorf("%s: expected Retry-After on denial", tt.path)
+		}
+	}
+
+	if _, err := New().Limit("global", "1/minute").DeniedStatus(200).Build(); err == nil {
+		t.Error("Expected error for non-error denial status")
+	}
+}
+
+func TestMethodExemptions(t *testing.T) {
+	limiter, err := New().
+		Limit("global", "1/minute").
+		Limit("preflight", "100/minute").
+		ExemptMethods("head").
+		MethodScope(http.MethodOptions, "preflight").
+		Build()
+	if err != nil {
+		t.Fatalf("Failed to build limiter: %v", err)
+	}
+	defer limiter.Close()
+
+	handler := limiter.For(HTTP).(func(http.Handler) http.Handler)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	serve := func(method string, headers map[string]string) int {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, createTestRequest(method, "/", headers))
+		return w.Code
+	}
+
+	// HEAD and preflight requests don't burn the global budget
+	for i := 0; i < 5; i++ {
+		if code := serve("HEAD", nil); code != http.StatusOK {
+			t.Fatalf("HEAD %d: expected 200, got %d", i+1, code)
+		}
+		if code := serve("OPTIONS", map[string]string{"Access-Control-Request-Method": "POST"}); code != http.StatusOK {
+			t.Fatalf("OPTIONS %d: expected 200, got %d", i+1, code)
+		}
+	}
+
+	if code := serve("GET", nil); code != http.StatusOK {
+		t.Errorf("Expected first GET to be allowed, got %d", code)
+	}
+	if code := serve("GET", nil); code != http.StatusTooManyRequests {
+		t.Errorf("Expected second GET to be limited, got %d", code)
+	}
+}
+
+func TestExemptPreflight(t *testing.T) {
+	limiter, err := New().Limit("global", "1/minute").ExemptPreflight().Build()
+	if err != nil {
+		t.Fatalf("Failed to build limiter: %v", err)
+	}
+	defer limiter.Close()
+
+	handler := limiter.For(HTTP).(func(http.Handler) http.Handler)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	preflight := map[string]string{"Origin": "https://app.example.com", "Access-Control-Request-Method": "PUT"}
+	for i := 0; i < 3; i++ {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, createTestRequest("OPTIONS", "/", preflight))
+		if w.Code != http.StatusOK {
+			t.Fatalf("Preflight %d: expected 200, got %d", i+1, w.Code)
+		}
+	}
+
+	// Plain OPTIONS requests are still limited
+	var w *httptest.ResponseRecorder
+	for i := 0; i < 2; i++ {
+		w = httptest.NewRecorder()
+		handler.ServeHTTP(w, createTestRequest("OPTIONS", "/", nil))
+	}
+	if w.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected non-preflight OPTIONS to be limited, got %d", w.Code)
+	}
+}
+
+func TestBandwidthLimit(t *testing.T) {
+	limiter, err := New().
+		Limit("global", "1000/hour").
+		BandwidthLimit("download", "100KB/hour").
+		ScopeFunc(func(r *http.Request) string {
+			if strings.HasPrefix(r.URL.Path, "/download") {
+				return "download"
+			}
+			return "global"
+		}).
+		Build()
+	if err != nil {
+		t.Fatalf("Failed to build limiter: %v", err)
+	}
+	defer limiter.Close()
+
+	payload := make([]byte, 80*1024)
+	handler := limiter.For(HTTP).(func(http.Handler) http.Handler)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Stream in small chunks so accounting is spread over several writes
+		for i := 0; i < len(payload); i += 4096 {
+			w.Write(payload[i : i+4096])
+		}
+	}))
+
+	serve := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, createTestRequest("GET", "/download/file.bin", nil))
+		return w
+	}
+
+	if w := serve(); w.Code != http.StatusOK || w.Header().Get("X-RateLimit-Bandwidth-Remaining") != "102400" {
+		t.Fatalf("Expected full budget on first download, got %d with remaining %q", w.Code, w.Header().Get("X-RateLimit-Bandwidth-Remaining"))
+	}
+
+	// 80KB of the 100KB budget is spent, so the next download still starts
+	if w := serve(); w.Code != http.StatusOK || w.Header().Get("X-RateLimit-Bandwidth-Remaining") != "20480" {
+		t.Fatalf("Expected 20KB remaining on second download, got %d with remaining %q", w.Code, w.Header().Get("X-RateLimit-Bandwidth-Remaining"))
+	}
+
+	w := serve()
+	if w.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected exhausted bandwidth budget to deny, got %d", w.Code)
+	}
+	if w.Header().Get("Retry-After") == "" {
+		t.Error("Expected Retry-After on bandwidth denial")
+	}
+
+	// Other scopes are unaffected
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, createTestRequest("GET", "/api", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected non-download scope to be allowed, got %d", rec.Code)
+	}
+
+	if _, err := New().BandwidthLimit("download", "lots/hour").Build(); err == nil {
+		t.Error("Expected error for invalid bandwidth limit")
+	}
+}
+
+func TestBandwidthLimitFrameworks(t *testing.T) {
+	payload := make([]byte, 80*1024)
+
+	// Each built-in framework adapter serves downloads until the 100KB budget is spent
+	serves := map[FrameworkType]func(mw interface{}) int{
+		Gin: func(mw interface{}) int {
+			ctx := newFakeGinCtx(createTestRequest("GET", "/download/file.bin", nil))
+			ctx.next = func(c *fakeGinCtx) { c.Writer.Write(payload) }
+			mw.(func(interface{}))(ctx)
+			return ctx.Writer.Code
+		},
+		Echo: func(mw interface{}) int {
+			ctx := newFakeEchoCtx(createTestRequest("GET", "/download/file.bin", nil))
+			next := func(c *fakeEchoCtx) error {
+				_, err := c.Response().Write(payload)
+				return err
+			}
+			if err := mw.(func(interface{}) interface{})(next).(func(interface{}) error)(ctx); err != nil {
+				t.Fatalf("Handler failed: %v", err)
+			}
+			return ctx.response.Writer.(*httptest.ResponseRecorder).Code
+		},
+		Fiber: func(mw interface{}) int {
+			ctx := newFakeFiberCtx("GET", "/download/file.bin", nil)
+			ctx.next = func(c *fakeFiberCtx) error { return c.Send(payload) }
+			if err := mw.(func(interface{}) error)(ctx); err != nil {
+				t.Fatalf("Handler failed: %v", err)
+			}
+			return ctx.status
+		},
+	}
+
+	for framework, serve := range serves {
+		t.Run(framework.String(), func(t *testing.T) {
+			limiter, err := New().
+				BandwidthLimit("download", "100KB/hour").
+				ScopeFunc(func(r *http.Request) string { return "download" }).
+				Build()
+			if err != nil {
+				t.Fatalf("Failed to build limiter: %v", err)
+			}
+			defer limiter.Close()
+
+			mw := limiter.For(framework)
+			for i, want := range []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests} {
+				if code := serve(mw); code != want {
+					t.Errorf("Download %d: expected %d, got %d", i+1, want, code)
+				}
+			}
+		})
+	}
+}
+
+func TestPreAuthLimit(t *testing.T) {
+	limiter, err := New().
+		PreAuthLimit("3/minute").
+		ExtractorFunc(func(r *http.Request) string { return r.Header.Get("X-User") }).
+		Limit("global", "2/minute").
+		Build()
+	if err != nil {
+		t.Fatalf("Failed to build limiter: %v", err)
+	}
+	defer limiter.Close()
+
+	handler := limiter.For(HTTP).(func(http.Handler) http.Handler)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	serve := func(user string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, createTestRequest("GET", "/", map[string]string{"X-User": user}))
+		return w
+	}
+
+	// Per-user limit applies after the pre-auth phase
+	for i := 0; i < 2; i++ {
+		w := serve("alice")
+		if w.Code != http.StatusOK {
+			t.Fatalf("Request %d: expected 200, got %d", i+1, w.Code)
+		}
+		if w.Header().Get("X-RateLimit-PreAuth-Remaining") != strconv.Itoa(2-i) {
+			t.Errorf("Request %d: unexpected pre-auth remaining %q", i+1, w.Header().Get("X-RateLimit-PreAuth-Remaining"))
+		}
+		if w.Header().Get("X-RateLimit-Limit") != "2" {
+			t.Errorf("Request %d: expected user limit header, got %q", i+1, w.Header().Get("X-RateLimit-Limit"))
+		}
+	}
+
+	// A different user from the same IP passes the user limit but not the IP limit
+	if w := serve("bob"); w.Code != http.StatusOK {
+		t.Fatalf("Expected bob's first request to be allowed, got %d", w.Code)
+	}
+	w := serve("carol")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected pre-auth limit to deny, got %d", w.Code)
+	}
+	if w.Header().Get("X-RateLimit-Limit") != "3" || w.Header().Get("Retry-After") == "" {
+		t.Errorf("Expected pre-auth headers on denial, got limit %q", w.Header().Get("X-RateLimit-Limit"))
+	}
+
+	if _, err := New().PreAuthLimit("lots").Limit("global", "1/minute").Build(); err == nil {
+		t.Error("Expected error for invalid pre-auth limit")
+	}
+}
+
+func TestAuthProtectionLockout(t *testing.T) {
+	challenged := 0
+	limiter, err := AuthProtection().
+		Lockout(3, time.Minute, time.Hour).
+		Challenge(2, func(w http.ResponseWriter, r *http.Request) bool {
+			challenged++
+			if r.Header.Get("X-Captcha") == "solved" {
+				return true
+			}
+			w.WriteHeader(http.StatusUnauthorized)
+			return false
+		}).
+		Build()
+	if err != nil {
+		t.Fatalf("Failed to build limiter: %v", err)
+	}
+	defer limiter.Close()
+
+	handler := limiter.For(HTTP).(func(http.Handler) http.Handler)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Password") != "secret" {
+			ReportAuthFailure(limiter, r)
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		ReportAuthSuccess(limiter, r)
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	login := func(user, password, captcha string) int {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, createTestRequest("POST", "/login", map[string]string{
+			"X-Login-Username": user,
+			"X-Password":       password,
+			"X-Captcha":        captcha,
+		}))
+		return w.Code
+	}
+
+	for i := 0; i < 2; i++ {
+		if code := login("alice", "wrong", ""); code != http.StatusForbidden {
+			t.Fatalf("Attempt %d: expected 403, got %d", i+1, code)
+		}
+	}
+
+	// Two failures require a challenge
+	if code := login("alice", "wrong", ""); code != http.StatusUnauthorized {
+		t.Fatalf("Expected challenge after 2 failures, got %d", code)
+	}
+	if code := login("alice", "wrong", "solved"); code != http.StatusForbidden {
+		t.Fatalf("Expected solved challenge to reach the handler, got %d", code)
+	}
+
+	// Third failure locks the account, even with the right password
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, createTestRequest("POST", "/login", map[string]string{"X-Login-Username": "Alice", "X-Password": "secret"}))
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("X-RateLimit-Lockout") != "true" {
+		t.Fatalf("Expected lockout, got %d", w.Code)
+	}
+	if retry, _ := strconv.Atoi(w.Header().Get("Retry-After")); retry < 55 || retry > 60 {
+		t.Errorf("Expected ~60s lockout, got Retry-After %q", w.Header().Get("Retry-After"))
+	}
+
+	// Other accounts are unaffected and success clears history
+	if code := login("bob", "secret", ""); code != http.StatusOK {
+		t.Errorf("Expected other user to log in, got %d", code)
+	}
+	if challenged != 2 {
+		t.Errorf("Expected challenge hook to run twice, got %d", challenged)
+	}
+}
+
+func TestAuthProtectionLoginForm(t *testing.T) {
+	limiter, err := AuthProtection().Lockout(2, time.Minute, time.Hour).Build()
+	if err != nil {
+		t.Fatalf("Failed to build limiter: %v", err)
+	}
+	defer limiter.Close()
+
+	var received []string
+	handler := limiter.For(HTTP).(func(http.Handler) http.Handler)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The handler reads the body itself, after the limiter found the username in it
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("Failed to read body: %v", err)
+		}
+		form, _ := url.ParseQuery(string(body))
+		received = append(received, form.Get("password"))
+		ReportAuthFailure(limiter, r)
+		w.WriteHeader(http.StatusForbidden)
+	}))
+
+	login := func(ip, form string) int {
+		r := httptest.NewRequest("POST", "/login", strings.NewReader(form))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		r.RemoteAddr = ip + ":1234"
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	// A large body is passed on whole, though only its head is read for the username
+	padding := strings.Repeat("x", 64<<10)
+	if code := login("198.51.100.1", "username=Alice&password=wrong1&padding="+padding); code != http.StatusForbidden {
+		t.Fatalf("Expected the first attempt to reach the handler, got %d", code)
+	}
+	if code := login("198.51.100.2", "email=alice&password=wrong2"); code != http.StatusForbidden {
+		t.Fatalf("Expected the second attempt to reach the handler, got %d", code)
+	}
+	if len(received) != 2 || received[0] != "wrong1" || received[1] != "wrong2" {
+		t.Errorf("Expected the handler to receive both forms, got %q", received)
+	}
+
+	// Both failures were charged to the account, not to the two addresses
+	if code := login("198.51.100.3", "username=alice&password=wrong3"); code != http.StatusTooManyRequests {
+		t.Errorf("Expected the account to be locked out, got %d", code)
+	}
+}
+
+func TestAuthProtectionLockoutBackoff(t *testing.T) {
+	limiter, err := AuthProtection().Lockout(2, time.Minute, 5*time.Minute).Build()
+	if err != nil {
+		t.Fatalf("Failed to build limiter: %v", err)
+	}
+	defer limiter.Close()
+
+	req := createTestRequest("POST", "/login", map[string]string{"X-Login-Username": "carol"})
+	expected := []time.Duration{0, time.Minute, 2 * time.Minute, 4 * time.Minute, 5 * time.Minute}
+	for i, want := range expected {
+		lockout, err := ReportAuthFailure(limiter, req)
+		if err != nil {
+			t.Fatalf("Failure %d: %v", i+1, err)
+		}
+		if lockout > want || lockout < want-time.Second {
+			t.Errorf("Failure %d: expected lockout of %v, got %v", i+1, want, lockout)
+		}
+	}
+
+	if err := ReportAuthSuccess(limiter, req); err != nil {
+		t.Fatalf("Failed to report success: %v", err)
+	}
+	if lockout, _ := ReportAuthFailure(limiter, req); lockout != 0 {
+		t.Errorf("Expected success to reset failures, got lockout %v", lockout)
+	}
+
+	if _, err := ReportAuthFailure(IPLimit("10/minute"), req); err == nil {
+		t.Error("Expected error reporting failures without a lockout configured")
+	}
+}
+
+func TestMobileBackendPreset(t *testing.T) {
+	limiter, err := MobileBackend().Build()
+	if err != nil {
+		t.Fatalf("Failed to build limiter: %v", err)
+	}
+	defer limiter.Close()
+
+	handler := limiter.For(HTTP).(func(http.Handler) http.Handler)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	serve := func(path, deviceID string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, createTestRequest("POST", path, map[string]string{"X-Device-ID": deviceID}))
+		return w
+	}
+
+	// Devices get strict limits, shared IPs generous ones
+	if w := serve("/v1/push/register", "device-1"); w.Header().Get("X-RateLimit-Limit") != "10" {
+		t.Errorf("Expected device push limit of 10, got %q", w.Header().Get("X-RateLimit-Limit"))
+	}
+	if w := serve("/v1/push/register", ""); w.Header().Get("X-RateLimit-Limit") != "200" {
+		t.Errorf("Expected IP push limit of 200, got %q", w.Header().Get("X-RateLimit-Limit"))
+	}
+
+	var w *httptest.ResponseRecorder
+	for i := 0; i < 11; i++ {
+		w = serve("/v1/push/register", "device-2")
+	}
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected device to be limited, got %d", w.Code)
+	}
+
+	var body struct {
+		RetryAfterSeconds int64 `json:"retry_after_seconds"`
+		Backoff           struct {
+			Strategy string `json:"strategy"`
+			Jitter   bool   `json:"jitter"`
+		} `json:"backoff"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+		t.Fatalf("Failed to decode denial: %v", err)
+	}
+	if body.RetryAfterSeconds < 1 || body.Backoff.Strategy != "exponential" || !body.Backoff.Jitter {
+		t.Errorf("Expected backoff guidance, got %+v", body)
+	}
+	if w.Header().Get("Retry-After") != strconv.FormatInt(body.RetryAfterSeconds, 10) {
+		t.Errorf("Expected Retry-After to match body, got %q", w.Header().Get("Retry-After"))
+	}
+}
+
+func TestMaintenanceMode(t *testing.T) {
+	limiter, err := New().
+		ExtractorFunc(func(r *http.Request) string { return r.Header.Get("X-User") }).
+		Limit("global", "100/minute").
+		MaintenanceResponse(http.StatusTooManyRequests, "Read-only window").
+		MaintenanceRetryAfter(2 * time.Minute).
+		Build()
+	if err != nil {
+		t.Fatalf("Failed to build limiter: %v", err)
+	}
+	defer limiter.Close()
+
+	handler := limiter.For(HTTP).(func(http.Handler) http.Handler)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	serve := func(user, remoteAddr string) *httptest.ResponseRecorder {
+		req := createTestRequest("GET", "/", map[string]string{"X-User": user})
+		if remoteAddr != "" {
+			req.RemoteAddr = remoteAddr
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	limiter.MaintenanceMode(true, []string{"admin", "10.0.0.5"})
+
+	w := serve("alice", "")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected maintenance status 429, got %d", w.Code)
+	}
+	if w.Header().Get("Retry-After") != "120" {
+		t.Errorf("Expected Retry-After 120, got %q", w.Header().Get("Retry-After"))
+	}
+	var body struct {
+		Error       string `json:"error"`
+		Maintenance bool   `json:"maintenance"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+		t.Fatalf("Failed to decode maintenance body: %v", err)
+	}
+	if body.Error != "Read-only window" || !body.Maintenance {
+		t.Errorf("Unexpected maintenance body: %+v", body)
+	}
+
+	// Allowlisted entities and client IPs pass
+	if w := serve("admin", ""); w.Code != http.StatusOK {
+		t.Errorf("Expected allowlisted entity to pass, got %d", w.Code)
+	}
+	if w := serve("bob", "10.0.0.5:4242"); w.Code != http.StatusOK {
+		t.Errorf("Expected allowlisted IP to pass, got %d", w.Code)
+	}
+
+	// Direct checks honour maintenance mode too
+	ctx := context.Background()
+	result, err := limiter.Check(ctx, "alice")
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if result.Allowed || !result.Maintenance {
+		t.Errorf("Expected maintenance denial, got %+v", result)
+	}
+
+	limiter.MaintenanceMode(false, nil)
+	if w := serve("alice", ""); w.Code != http.StatusOK {
+		t.Errorf("Expected normal service after maintenance, got %d", w.Code)
+	}
+}
+
+func TestInputSanitization(t *testing.T) {
+	limiter, err := New().
+		ExtractorFunc(func(r *http.Request) string { return r.Header.Get("X-User") }).
+		Limit("global", "1/minute").
+		Build()
+	if err != nil {
+		t.Fatalf("Failed to build limiter: %v", err)
+	}
+	defer limiter.Close()
+
+	ctx := context.Background()
+
+	// Control characters are stripped, so both spellings share one counter
+	if allowed, err := limiter.Allow(ctx, "user-1\r\n"); err != nil || !allowed {
+		t.Fatalf("Expected normalized entity to be allowed, got %v (%v)", allowed, err)
+	}
+	if allowed, _ := limiter.Allow(ctx, "user-1"); allowed {
+		t.Error("Expected normalized entity to share the quota of the clean entity")
+	}
+
+	strict, err := New().
+		ExtractorFunc(func(r *http.Request) string { return r.Header.Get("X-User") }).
+		Limit("global", "10/minute").
+		MaxEntityLength(64).
+		RejectInvalidInput().
+		Build()
+	if err != nil {
+		t.Fatalf("Failed to build limiter: %v", err)
+	}
+	defer strict.Close()
+
+	_, err = strict.Check(ctx, strings.Repeat("x", 65))
+	if !IsInvalidInput(err) {
+		t.Errorf("Expected invalid input error, got %v", err)
+	}
+
+	handler := strict.For(HTTP).(func(http.Handler) http.Handler)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, createTestRequest("GET", "/", map[string]string{"X-User": strings.Repeat("x", 65)}))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for overlong entity, got %d", w.Code)
+	}
+}
+
+func TestScopeBudget(t *testing.T) {
+	base, err := New().
+		Limit("global", "100/minute").
+		ScopeFunc(func(r *http.Request) string { return r.URL.Path }).
+		MaxScopes(3).
+		Build()
+	if err != nil {
+		t.Fatalf("Failed to build limiter: %v", err)
+	}
+	config := DefaultObservabilityConfig()
+	config.EnableLogging = false
+	limiter := NewObservableLimiter(base, config)
+	defer limiter.Close()
+
+	handler := limiter.For(HTTP).(func(http.Handler) http.Handler)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		scope, _ := r.Context().Value("gorly_scope").(string)
+		w.Write([]byte(scope))
+	}))
+
+	// A scope function deriving one scope per URL is capped at three distinct scopes
+	for i := 0; i < 10; i++ {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, createTestRequest("GET", fmt.Sprintf("/items/%d", i), nil))
+		want := fmt.Sprintf("/items/%d", i)
+		if i >= 3 {
+			want = OverflowScope
+		}
+		if w.Code != http.StatusOK || w.Body.String() != want {
+			t.Errorf("Request %d: expected scope %q, got %d %q", i, want, w.Code, w.Body.String())
+		}
+	}
+
+	// Direct checks of excess scopes are folded into the overflow scope as well
+	if _, err := limiter.Check(context.Background(), "user-1", "/items/99"); err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	metrics := limiter.GetMetrics()
+	if overflows := metrics["scope_overflows"]; overflows != int64(8) {
+		t.Errorf("Expected 8 scope overflows, got %v", overflows)
+	}
+	for key := range metrics["request_total"].(map[string]int64) {
+		if strings.HasSuffix(key, "/items/99") {
+			t.Errorf("Expected the excess scope to be labeled %s, got %s", OverflowScope, key)
+		}
+	}
+	if stats, _ := limiter.Stats(context.Background()); stats.ScopeOverflows != 8 {
+		t.Errorf("Expected 8 scope overflows in stats, got %d", stats.ScopeOverflows)
+	}
+}
+
+func TestDiagnostics(t *testing.T) {
+	ctx := context.Background()
+
+	bucket, err := New().Limit("export", "5/minute").Algorithm("token_bucket").Build()
+	if err != nil {
+		t.Fatalf("Failed to build limiter: %v", err)
+	}
+	defer bucket.Close()
+	for i := 0; i < 2; i++ {
+		bucket.Check(ctx, "user-1", "export")
+	}
+
+	d, err := bucket.Diagnostics(ctx, "user-1", "export")
+	if err != nil {
+		t.Fatalf("Diagnostics failed: %v", err)
+	}
+	if d.Algorithm != "token_bucket" || d.Limit != 5 || d.Source != "scope" || d.Bucket == nil || d.SlidingWindow != nil {
+		t.Fatalf("Expected token bucket diagnostics for limit 5, got %+v", d)
+	}
+	if d.Bucket.CurrentTokens < 3 || d.Bucket.CurrentTokens >= 4 || d.Bucket.Capacity != 5 {
+		t.Errorf("Expected 3 of 5 tokens left, got %+v", d.Bucket)
+	}
+	if d, _ := bucket.Diagnostics(ctx, "user-1", "export"); d.Bucket.CurrentTokens >= 4 {
+		t.Error("Expected diagnostics not to consume or restore quota")
+	}
+
+	window, err := New().Limit("global", "5/minute").Algorithm("sliding_window").Build()
+	if err != nil {
+		t.Fatalf("Failed to build limiter: %v", err)
+	}
+	defer window.Close()
+	for i := 0; i < 3; i++ {
+		window.Check(ctx, "user-1")
+	}
+
+	d, err = window.Diagnostics(ctx, "user-1")
+	if err != nil {
+		t.Fatalf("Diagnostics failed: %v", err)
+	}
+	if d.SlidingWindow == nil || d.SlidingWindow.CurrentRequests != 3 || d.Pattern == nil || d.Pattern.TotalRequests != 3 {
+		t.Errorf("Expected 3 requests in the sliding window, got %+v", d)
+	}
+
+	composite, err := All(bucket, window).Diagnostics(ctx, "user-1", "export")
+	if err != nil || len(composite.Composed) != 2 || composite.Composed[1].Source != "global" {
+		t.Errorf("Expected diagnostics of both composed limiters, got %+v (%v)", composite, err)
+	}
+
+	// The debug endpoint reports the diagnostics of one entity
+	config := DefaultObservabilityConfig()
+	config.EnableLogging = false
+	observable := NewObservableLimiter(window, config)
+	ms := NewMonitoringServer(observable)
+	w := httptest.NewRecorder()
+	ms.ServeHTTP(w, httptest.NewRequest("GET", "/debug?entity=user-1", nil))
+	var debug struct {
+		Diagnostics *Diagnostics `json:"diagnostics"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&debug); err != nil || debug.Diagnostics == nil || debug.Diagnostics.SlidingWindow.CurrentRequests != 3 {
+		t.Errorf("Expected diagnostics in the debug output, got %+v (%v)", debug.Diagnostics, err)
+	}
+}
+
+func TestPeek(t *testing.T) {
+	limiter, err := New().
+		Limit("global", "10/minute").
+		Limit("export", "3/minute").
+		Build()
+	if err != nil {
+		t.Fatalf("Failed to build limiter: %v", err)
+	}
+	defer limiter.Close()
+
+	ctx := context.Background()
+	if _, err := limiter.Check(ctx, "user-1", "export"); err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		result, err := limiter.Peek(ctx, "user-1", "export")
+		if err != nil {
+			t.Fatalf("Peek failed: %v", err)
+		}
+		if !result.Allowed || result.Remaining != 2 || result.Used != 1 || result.Limit != 3 {
+			t.Errorf("Peek %d: expected 1 used / 2 remaining without consuming, got %+v", i+1, result)
+		}
+	}
+
+	// Context overrides apply to Peek like they do to Check
+	result, err := limiter.Peek(WithScope(WithEntity(ctx, "user-1"), "export"), "")
+	if err != nil || result.Remaining != 2 {
+		t.Errorf("Expected context-resolved peek to report 2 remaining, got %+v (%v)", result, err)
+	}
+
+	if result, _ := limiter.Peek(ctx, "user-2"); result.Remaining != 10 {
+		t.Errorf("Expected untouched global allowance of 10, got %d", result.Remaining)
+	}
+}
+
+func TestResetScheduleValidation(t *testing.T) {
+	if _, err := New().Limit("daily-report", "5/day").ResetSchedule("daily-report", "0 25 * * *").Build(); err == nil {
+		t.Error("Expected invalid cron expression to be rejected")
+	}
+
+	limiter, err := New().Limit("daily-report", "5/day").ResetSchedule("daily-report", "@daily").Build()
+	if err != nil {
+		t.Fatalf("Failed to build limiter: %v", err)
+	}
+	if err := limiter.Close(); err != nil {
+		t.Errorf("Close failed: %v", err)
+	}
+}
+
+func TestRunWhenLeader(t *testing.T) {
+	limiter, err := New().
+		Limit("global", "10/minute").
+		LeaderLease(60 * time.Millisecond).
+		InstanceID("test-instance").
+		Build()
+	if err != nil {
+		t.Fatalf("Failed to build limiter: %v", err)
+	}
+
+	ran := make(chan struct{}, 1)
+	err = limiter.RunWhenLeader("sweep", time.Hour, func(ctx context.Context) error {
+		select {
+		case ran <- struct{}{}:
+		default:
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to register job: %v", err)
+	}
+
+	select {
+	case <-ran:
+	case <-time.After(time.Second):
+		t.Fatal("Expected the only instance to become leader and run the job")
+	}
+
+	limiter.Close()
+	if err := limiter.RunWhenLeader("late", time.Hour, func(ctx context.Context) error { return nil }); err == nil {
+		t.Error("Expected error registering a job on a closed limiter")
+	}
+}
+
+func TestDenialCache(t *testing.T) {
+	metrics := NewPrometheusMetrics()
+	config := DefaultObservabilityConfig()
+	config.Metrics = metrics
+	config.EnableLogging = false
+
+	base, err := New().
+		Limit("global", "1/hour").
+		DenialCache(time.Second, time.Minute).
+		Build()
+	if err != nil {
+		t.Fatalf("Failed to build limiter: %v", err)
+	}
+	limiter := NewObservableLimiter(base, config)
+	defer limiter.Close()
+
+	ctx := context.Background()
+	limiter.Check(ctx, "user-1")
+	first, _ := limiter.Check(ctx, "user-1")
+	second, _ := limiter.Check(ctx, "user-1")
+	if first.Allowed || first.Cached {
+		t.Errorf("Expected the first denial to come from the store, got %+v", first)
+	}
+	if second.Allowed || !second.Cached {
+		t.Errorf("Expected the repeated denial to be cached, got %+v", second)
+	}
+
+	stats, err := limiter.Stats(ctx)
+	if err != nil || stats.DenialCacheHits != 1 {
+		t.Errorf("Expected 1 denial cache hit in stats, got %+v (%v)", stats, err)
+	}
+	hits := metrics.GetMetrics()["denial_cache_hits"].(map[string]int64)
+	if hits["user-1:global"] != 1 {
+		t.Errorf("Expected denial cache hit metric, got %v", hits)
+	}
+}
+
+func TestEmptyEntityPolicy(t *testing.T) {
+	extractUser := func(r *http.Request) string { return r.Header.Get("X-User") }
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+
+	serve := func(limiter Limiter, remoteAddr string) int {
+		req := createTestRequest("GET", "/", nil)
+		req.RemoteAddr = remoteAddr
+		w := httptest.NewRecorder()
+		limiter.For(HTTP).(func(http.Handler) http.Handler)(ok).ServeHTTP(w, req)
+		return w.Code
+	}
+
+	tests := []struct {
+		policy EmptyEntityPolicy
+		// Expected status of two anonymous requests from different addresses
+		first, second int
+	}{
+		{"", http.StatusOK, http.StatusTooManyRequests},
+		{EmptyEntityShared, http.StatusOK, http.StatusTooManyRequests},
+		{EmptyEntityIP, http.StatusOK, http.StatusOK},
+		{EmptyEntityDeny, http.StatusUnauthorized, http.StatusUnauthorized},
+		{EmptyEntitySkip, http.StatusOK, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			limiter, err := New().ExtractorFunc(extractUser).EmptyEntity(tt.policy).Limit("global", "1/minute").Build()
+			if err != nil {
+				t.Fatalf("Failed to build limiter: %v", err)
+			}
+			defer limiter.Close()
+
+			if code := serve(limiter, "10.0.0.1:1234"); code != tt.first {
+				t.Errorf("Expected %d for the first request, got %d", tt.first, code)
+			}
+			if code := serve(limiter, "10.0.0.2:1234"); code != tt.second {
+				t.Errorf("Expected %d for the second request, got %d", tt.second, code)
+			}
+
+			stats, _ := limiter.Stats(context.Background())
+			if stats.EmptyEntities != 2 {
+				t.Errorf("Expected 2 empty entities in stats, got %d", stats.EmptyEntities)
+			}
+		})
+	}
+
+	if _, err := New().EmptyEntity("nobody").Limit("global", "1/minute").Build(); err == nil {
+		t.Error("Expected an unknown empty entity policy to be rejected")
+	}
+}
+
+func TestMethodScoping(t *testing.T) {
+	limiter, err := New().
+		ExtractorFunc(func(r *http.Request) string { return "user-1" }).
+		MethodLimits("global", map[string]string{"read": "3/minute", "write": "1/minute"}).
+		Build()
+	if err != nil {
+		t.Fatalf("Failed to build limiter: %v", err)
+	}
+	defer limiter.Close()
+
+	handler := limiter.For(HTTP).(func(http.Handler) http.Handler)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	serve := func(method string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, createTestRequest(method, "/orders", nil))
+		return w
+	}
+
+	if w := serve(http.MethodPost); w.Code != http.StatusOK {
+		t.Fatalf("Expected the first write to be allowed, got %d", w.Code)
+	}
+	if w := serve(http.MethodPut); w.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected writes to share the write limit, got %d", w.Code)
+	}
+
+	w := serve(http.MethodGet)
+	if w.Code != http.StatusOK || w.Header().Get("X-RateLimit-Limit") != "3" {
+		t.Errorf("Expected reads to have their own limit, got %d (limit %s)", w.Code, w.Header().Get("X-RateLimit-Limit"))
+	}
+
+	// Reads and writes are counted in their class scopes
+	ctx := context.Background()
+	writes, _ := limiter.Peek(ctx, "user-1", "global:write")
+	reads, _ := limiter.Peek(ctx, "user-1", "global:read")
+	if writes.Used != 1 || reads.Used != 1 {
+		t.Errorf("Expected one counted request per class, got %d writes and %d reads", writes.Used, reads.Used)
+	}
+}
+
+func TestHeaderModes(t *testing.T) {
+	limiter, err := New().
+		Limit("global", "1/minute").
+		Limit("partner", "1/minute").
+		ScopeFunc(func(r *http.Request) string { return strings.TrimPrefix(r.URL.Path, "/") }).
+		Headers(HeadersMinimal).
+		ScopeHeaders("partner", HeadersFull).
+		HeaderName("X-RateLimit-Limit", "RateLimit-Limit").
+		Build()
+	if err != nil {
+		t.Fatalf("Failed to build limiter: %v", err)
+	}
+	defer limiter.Close()
+
+	handler := limiter.For(HTTP).(func(http.Handler) http.Handler)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	serve := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, createTestRequest("GET", path, nil))
+		return w
+	}
+
+	if w := serve("/global"); w.Header().Get("X-RateLimit-Remaining") != "" || w.Header().Get("RateLimit-Limit") != "" {
+		t.Errorf("Expected no limit headers in minimal mode, got %v", w.Header())
+	}
+	if w := serve("/global"); w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") == "" || w.Header().Get("X-RateLimit-Retry-After") != "" {
+		t.Errorf("Expected only Retry-After on denial, got %d %v", w.Code, w.Header())
+	}
+
+	w := serve("/partner")
+	if w.Header().Get("RateLimit-Limit") != "1" || w.Header().Get("X-RateLimit-Limit") != "" || w.Header().Get("X-RateLimit-Remaining") != "0" {
+		t.Errorf("Expected full, renamed headers for the partner scope, got %v", w.Header())
+	}
+
+	if _, err := New().Limit("global", "1/minute").Headers("quiet").Build(); err == nil {
+		t.Error("Expected an unknown header mode to be rejected")
+	}
+}
+
+func TestEndpointCosts(t *testing.T) {
+	limiter, err := New().
+		ExtractorFunc(func(r *http.Request) string { return "user-1" }).
+		Limit("global", "10/minute").
+		Costs(map[string]int64{"POST /v1/export": 6}).
+		Build()
+	if err != nil {
+		t.Fatalf("Failed to build limiter: %v", err)
+	}
+	defer limiter.Close()
+
+	handler := limiter.For(HTTP).(func(http.Handler) http.Handler)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	serve := func(method, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, createTestRequest(method, path, nil))
+		return w
+	}
+
+	w := serve("POST", "/v1/export")
+	if w.Code != http.StatusOK || w.Header().Get("X-RateLimit-Remaining") != "4" || w.Header().Get("X-RateLimit-Cost") != "6" {
+		t.Errorf("Expected the export to cost 6, got %d %v", w.Code, w.Header())
+	}
+	if w := serve("POST", "/v1/export"); w.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected the second export to be denied, got %d", w.Code)
+	}
+
+	// Costs are hot-reloadable
+	manager := NewHotReloadManager(limiter, nil)
+	if err := manager.applyConfig(context.Background(), &HotReloadConfig{Costs: map[string]int64{"POST /v1/export": 1}}); err != nil {
+		t.Fatalf("Failed to apply costs: %v", err)
+	}
+	if w := serve("POST", "/v1/export"); w.Code != http.StatusOK || w.Header().Get("X-RateLimit-Cost") != "" {
+		t.Errorf("Expected the reloaded cost of 1, got %d %v", w.Code, w.Header())
+	}
+	if err := manager.applyConfig(context.Background(), &HotReloadConfig{Costs: map[string]int64{"export": 1}}); err == nil {
+		t.Error("Expected invalid cost patterns to be rejected")
+	}
+
+	if _, err := New().Limit("global", "1/minute").Cost("GET /", -1).Build(); err == nil {
+		t.Error("Expected a negative cost to be rejected")
+	}
+}
+
+func TestStatsWriteBehind(t *testing.T) {
+	ctx := context.Background()
+	base, err := New().
+		Limit("search", "2/minute").
+		StatsWriteBehind(time.Hour, 1000).
+		Build()
+	if err != nil {
+		t.Fatalf("Failed to build limiter: %v", err)
+	}
+	config := DefaultObservabilityConfig()
+	config.EnableLogging = false
+	limiter := NewObservableLimiter(base, config)
+	defer limiter.Close()
+
+	for i := 0; i < 3; i++ {
+		if _, err := limiter.Check(ctx, "user-1", "search"); err != nil {
+			t.Fatalf("Check failed: %v", err)
+		}
+	}
+
+	stats, err := limiter.Stats(ctx)
+	if err != nil {
+		t.Fatalf("Stats failed: %v", err)
+	}
+	if stats.TotalRequests != 3 || stats.TotalDenied != 1 {
+		t.Errorf("Expected 3 requests and 1 denial, got %d and %d", stats.TotalRequests, stats.TotalDenied)
+	}
+	if scope := stats.ByScope["search"]; scope == nil || scope.Requests != 3 {
+		t.Errorf("Expected 3 requests in scope search, got %+v", scope)
+	}
+	if stats.StatsFlush == nil || stats.StatsFlush.PendingEvents != 3 {
+		t.Errorf("Expected 3 pending events, got %+v", stats.StatsFlush)
+	}
+
+	if flush, ok := limiter.GetMetrics()["stats_flush"].(*StatsFlushStats); !ok || flush.PendingEvents != 3 {
+		t.Errorf("Expected flush metrics, got %v", limiter.GetMetrics()["stats_flush"])
+	}
+}
+
+func TestUnknownTiers(t *testing.T) {
+	extractUser := func(r *http.Request) string { return r.Header.Get("X-User") }
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+
+	build := func(configure func(*Builder) *Builder) Limiter {
+		limiter, err := configure(New().ExtractorFunc(extractUser).TierLimits(map[string]string{"free": "1/minute", "pro": "5/minute"})).Build()
+		if err != nil {
+			t.Fatalf("Failed to build limiter: %v", err)
+		}
+		t.Cleanup(func() { limiter.Close() })
+		return limiter
+	}
+
+	t.Run("deny", func(t *testing.T) {
+		limiter := build(func(b *Builder) *Builder { return b.UnknownTiers(UnknownTierDeny) })
+
+		w := httptest.NewRecorder()
+		limiter.For(HTTP).(func(http.Handler) http.Handler)(ok).ServeHTTP(w, createTestRequest("GET", "/", map[string]string{"X-User": "gold:user-1"}))
+		if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "invalid tier") {
+			t.Errorf("Expected 400 for an invalid tier, got %d: %s", w.Code, w.Body.String())
+		}
+
+		_, err := limiter.Check(context.Background(), "gold:user-1", "global")
+		if !IsInvalidInput(err) {
+			t.Errorf("Expected an invalid input error, got %v", err)
+		}
+		stats, _ := limiter.Stats(context.Background())
+		if stats.UnknownTiers != 2 {
+			t.Errorf("Expected 2 unknown tiers in stats, got %d", stats.UnknownTiers)
+		}
+	})
+
+	t.Run("fallback", func(t *testing.T) {
+		limiter := build(func(b *Builder) *Builder { return b.FallbackTier("free") })
+
+		result, err := limiter.Check(context.Background(), "gold:user-1", "global")
+		if err != nil {
+			t.Fatalf("Check failed: %v", err)
+		}
+		if result.Limit != 1 || result.UnknownTier != "gold" {
+			t.Errorf("Expected the free tier limit for tier gold, got %d (%q)", result.Limit, result.UnknownTier)
+		}
+	})
+
+	if _, err := New().TierLimits(map[string]string{"pro": "5/minute"}).FallbackTier("gold").Build(); err == nil {
+		t.Error("Expected an unconfigured fallback tier to be rejected")
+	}
+}
+
+func TestTierResolver(t *testing.T) {
+	plans := map[string]string{"user-1": "pro"}
+	resolver := TierResolverFunc(func(ctx context.Context, entity string) (string, error) {
+		return plans[entity], nil
+	})
+	base, err := New().
+		ExtractorFunc(func(r *http.Request) string { return r.Header.Get("X-User") }).
+		TierLimits(map[string]string{"free": "1/minute", "pro": "5/minute"}).
+		ResolveTiers(resolver, time.Minute, 0).
+		EnableMetrics().
+		Build()
+	if err != nil {
+		t.Fatalf("Failed to build limiter: %v", err)
+	}
+	config := DefaultObservabilityConfig()
+	config.EnableLogging = false
+	limiter := NewObservableLimiter(base, config)
+	defer limiter.Close()
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+	handler := limiter.For(HTTP).(func(http.Handler) http.Handler)(ok)
+	serve := func(user string) int {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, createTestRequest("GET", "/", map[string]string{"X-User": user}))
+		return w.Code
+	}
+
+	// A client claiming the pro tier still gets the free tier it resolves to
+	for i, want := range []int{http.StatusOK, http.StatusTooManyRequests} {
+		if code := serve("pro:user-2"); code != want {
+			t.Errorf("Request %d of a claimed pro tier: expected %d, got %d", i+1, want, code)
+		}
+	}
+	for i := 0; i < 5; i++ {
+		if code := serve("user-1"); code != http.StatusOK {
+			t.Errorf("Request %d of a resolved pro tier: expected 200, got %d", i+1, code)
+		}
+	}
+
+	stats, err := limiter.Stats(context.Background())
+	if err != nil {
+		t.Fatalf("Stats failed: %v", err)
+	}
+	if stats.TierCache == nil || stats.TierCache.Misses != 2 || stats.TierCache.Hits != 5 {
+		t.Errorf("Expected 2 misses and 5 hits, got %+v", stats.TierCache)
+	}
+	if _, ok := limiter.GetMetrics()["tier_cache"].(*TierCacheStats); !ok {
+		t.Errorf("Expected tier cache metrics, got %v", limiter.GetMetrics()["tier_cache"])
+	}
+}
+
+func TestScopeEnforcement(t *testing.T) {
+	ctx := context.Background()
+	base, err := New().
+		Limit("global", "1/minute").
+		Limit("search", "1/minute").
+		Enforcement("search", EnforcementShadow).
+		Build()
+	if err != nil {
+		t.Fatalf("Failed to build limiter: %v", err)
+	}
+	config := DefaultObservabilityConfig()
+	config.EnableLogging = false
+	limiter := NewObservableLimiter(base, config)
+	defer limiter.Close()
+
+	for i := 0; i < 3; i++ {
+		result, err := limiter.Check(ctx, "user-1", "search")
+		if err != nil || !result.Allowed {
+			t.Fatalf("Expected shadow mode to let request %d through, got %+v (%v)", i+1, result, err)
+		}
+		if shadowed := i > 0; result.ShadowDenied != shadowed || result.Enforcement != "shadow" {
+			t.Errorf("Expected request %d to be shadow denied: %v, got %+v", i+1, shadowed, result)
+		}
+	}
+	stats, _ := limiter.Stats(ctx)
+	if stats.ShadowDenials["search"] != 2 {
+		t.Errorf("Expected 2 shadow denials in stats, got %v", stats.ShadowDenials)
+	}
+
+	ms := NewMonitoringServer(limiter)
+	w := httptest.NewRecorder()
+	ms.ServeHTTP(w, httptest.NewRequest("GET", "/metrics/prometheus", nil))
+	for _, want := range []string{`gorly_scope_enforcement{scope="search",mode="shadow"} 1`, `gorly_shadow_denials_total{scope="search"} 2`} {
+		if !strings.Contains(w.Body.String(), want) {
+			t.Errorf("Expected %s in the metrics", want)
+		}
+	}
+
+	// Hot reload promotes the scope to enforce and rolls out another one in shadow
+	manager := NewHotReloadManager(base, nil)
+	if err := manager.applyConfig(ctx, &HotReloadConfig{Enforcement: map[string]string{"global": "shadow"}}); err != nil {
+		t.Fatalf("applyConfig failed: %v", err)
+	}
+	if result, _ := limiter.Check(ctx, "user-1", "search"); result.Allowed {
+		t.Errorf("Expected search to be enforced after the reload, got %+v", result)
+	}
+
+	w = httptest.NewRecorder()
+	ms.ServeHTTP(w, httptest.NewRequest("GET", "/debug", nil))
+	var debug struct {
+		Config struct {
+			Enforcement map[string]string `json:"enforcement"`
+		} `json:"config"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&debug); err != nil || debug.Config.Enforcement["global"] != "shadow" || len(debug.Config.Enforcement) != 1 {
+		t.Errorf("Expected the modes in the debug config, got %+v (%v)", debug.Config, err)
+	}
+
+	if err := manager.applyConfig(ctx, &HotReloadConfig{Enforcement: map[string]string{"global": "log"}}); err == nil {
+		t.Error("Expected an unknown enforcement mode to be rejected")
+	}
+}
+
+func TestStoreFor(t *testing.T) {
+	if _, err := New().Limit("auth", "5/minute").StoreFor("auth", "disk").Build(); err == nil {
+		t.Error("Expected an unknown scope store to be rejected")
+	}
+	if _, err := New().Limit("auth", "5/minute").StoreFor("auth", "redis").Build(); err == nil {
+		t.Error("Expected a redis scope store without an address to be rejected")
+	}
+
+	// A scope on the default store's kind shares the default store
+	limiter, err := New().Limit("assets", "5/minute").StoreFor("assets", "memory").Build()
+	if err != nil {
+		t.Fatalf("Failed to build limiter: %v", err)
+	}
+	defer limiter.Close()
+	stats, err := limiter.Stats(context.Background())
+	if err != nil {
+		t.Fatalf("Stats failed: %v", err)
+	}
+	if stats.ByStore != nil {
+		t.Errorf("Expected no per-store stats with a single store, got %+v", stats.ByStore)
+	}
+}
+
+func TestDenyScopes(t *testing.T) {
+	if _, err := New().Limit("__other", "5/minute").Build(); err == nil || !strings.Contains(err.Error(), "reserved") {
+		t.Errorf("Expected a reserved scope to fail the build, got %v", err)
+	}
+	if _, err := New().Limit("global", "100/minute").Limit("debug-api", "5/minute").DenyScopes("debug*").Build(); err == nil || !strings.Contains(err.Error(), "debug-api") {
+		t.Errorf("Expected a denied scope to fail the build, got %v", err)
+	}
+
+	limiter, err := New().Limit("global", "100/minute").DenyScopes("debug*").Build()
+	if err != nil {
+		t.Fatalf("Failed to build limiter: %v", err)
+	}
+	defer limiter.Close()
+
+	manager := NewHotReloadManager(limiter, nil)
+	for _, config := range []*HotReloadConfig{
+		{Limits: map[string]string{"global": "100/minute", "debug": "1/minute"}},
+		{Enforcement: map[string]string{"__shared": "off"}},
+		{Overrides: []EntityOverride{{Entity: "partner", Scope: "debug", Limit: "1/minute"}}},
+	} {
+		if err := manager.applyConfig(context.Background(), config); err == nil || !IsConfigError(err) || !strings.Contains(err.Error(), "cannot be configured") {
+			t.Errorf("Expected %+v to be rejected, got %v", config, err)
+		}
+	}
+	if err := manager.applyConfig(context.Background(), &HotReloadConfig{Limits: map[string]string{"global": "50/minute"}}); err != nil {
+		t.Errorf("Expected a valid reload to apply, got %v", err)
+	}
+}
+
+func TestExemptProbes(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+	limiter, err := New().
+		ExtractorFunc(func(r *http.Request) string { return "ip:" + r.RemoteAddr }).
+		Limit("global", "1/minute").
+		PreAuthLimit("1/minute").
+		ExemptProbesWith(ProbeConfig{Networks: []string{"10.0.0.0/8"}}).
+		Build()
+	if err != nil {
+		t.Fatalf("Failed to build limiter: %v", err)
+	}
+	defer limiter.Close()
+	handler := limiter.For(HTTP).(func(http.Handler) http.Handler)(ok)
+
+	serve := func(agent, remoteAddr string) int {
+		req := createTestRequest("GET", "/healthz", nil)
+		req.Header.Set("User-Agent", agent)
+		req.RemoteAddr = remoteAddr
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// Probes from the node network never run out of budget
+	for i := 0; i < 5; i++ {
+		if code := serve("kube-probe/1.29", "10.0.0.1:1234"); code != http.StatusOK {
+			t.Fatalf("Expected probe %d to pass, got %d", i, code)
+		}
+	}
+
+	// A forged probe from outside the network is limited like everyone else
+	serve("kube-probe/1.29", "203.0.113.7:1234")
+	if code := serve("kube-probe/1.29", "203.0.113.7:1234"); code != http.StatusTooManyRequests {
+		t.Errorf("Expected the forged probe to be limited, got %d", code)
+	}
+
+	stats, err := limiter.Stats(context.Background())
+	if err != nil || stats.ProbeBypasses != 5 {
+		t.Errorf("Expected 5 probe bypasses in stats, got %+v (%v)", stats, err)
+	}
+
+	config := DefaultObservabilityConfig()
+	config.EnableLogging = false
+	observable := NewObservableLimiter(limiter, config)
+	w := httptest.NewRecorder()
+	NewMonitoringServer(observable).ServeHTTP(w, httptest.NewRequest("GET", "/metrics/prometheus", nil))
+	if !strings.Contains(w.Body.String(), "gorly_probe_bypasses_total 5") {
+		t.Errorf("Expected the probe bypass metric, got:\n%s", w.Body.String())
+	}
+
+	if _, err := New().ExemptProbesWith(ProbeConfig{Networks: []string{"node-network"}}).Build(); err == nil {
+		t.Error("Expected an invalid probe network to fail the build")
+	}
+}
+
+func TestFixedWindow(t *testing.T) {
+	ctx := context.Background()
+	for _, alignment := range []WindowAlignment{AlignWallClock, AlignFirstRequest} {
+		limiter, err := New().Algorithm("fixed_window").WindowAlignment(alignment).Limit("global", "3/hour").Build()
+		if err != nil {
+			t.Fatalf("Failed to build %s limiter: %v", alignment, err)
+		}
+		defer limiter.Close()
+
+		for i := 0; i < 3; i++ {
+			if allowed, err := limiter.Allow(ctx, "user1", "global"); err != nil || !allowed {
+				t.Fatalf("%s: expected request %d to be allowed (%v)", alignment, i+1, err)
+			}
+		}
+		result, err := limiter.Check(ctx, "user1", "global")
+		if err != nil || result.Allowed || result.Used != 3 || result.RetryAfter <= 0 {
+			t.Errorf("%s: expected the fourth request to be denied, got %+v (%v)", alignment, result, err)
+		}
+		if peek, err := limiter.Peek(ctx, "user1", "global"); err != nil || peek.Remaining != 0 {
+			t.Errorf("%s: expected Peek to see the spent window, got %+v (%v)", alignment, peek, err)
+		}
+		if _, err := limiter.Forget(ctx, "user1"); err != nil {
+			t.Fatalf("Forget failed: %v", err)
+		}
+		if allowed, _ := limiter.Allow(ctx, "user1", "global"); !allowed {
+			t.Errorf("%s: expected Forget to clear the window", alignment)
+		}
+	}
+
+	if _, err := New().Algorithm("fixed_window").WindowAlignment("hourly").Build(); err == nil {
+		t.Error("Expected an unknown window alignment to fail the build")
+	}
+}
+
+func TestScopeAlignment(t *testing.T) {
+	ctx := context.Background()
+	limiter, err := New().Limit("global", "3/hour").Limit("partner", "3/hour").
+		ScopeAlignment("partner", AlignWallClock).Build()
+	if err != nil {
+		t.Fatalf("Failed to build limiter: %v", err)
+	}
+	defer limiter.Close()
+
+	global, err := limiter.Check(ctx, "user1", "global")
+	if err != nil || global.Alignment != AlignRolling {
+		t.Errorf("Expected a rolling global window, got %+v (%v)", global, err)
+	}
+	partner, err := limiter.Check(ctx, "user1", "partner")
+	if err != nil || partner.Alignment != AlignWallClock {
+		t.Errorf("Expected a wall-clock partner window, got %+v (%v)", partner, err)
+	}
+	if reset := partner.ResetTime; !reset.Equal(reset.Truncate(time.Hour)) {
+		t.Errorf("Expected the partner window to reset on the hour, got %v", reset)
+	}
+
+	if _, err := New().ScopeAlignment("partner", "calendar").Build(); err == nil {
+		t.Error("Expected an unknown scope alignment to fail the build")
+	}
+}
+
+func TestUsageStats(t *testing.T) {
+	ctx := context.Background()
+	limiter, err := New().Limit("global", "2/minute").Limit("search", "10/minute").MaxTrackedEntities(2).Build()
+	if err != nil {
+		t.Fatalf("Failed to build limiter: %v", err)
+	}
+	defer limiter.Close()
+
+	for _, entity := range []string{"user1", "user1", "user1", "user2", "user3"} {
+		limiter.Allow(ctx, entity, "global")
+	}
+	limiter.Allow(ctx, "user3", "search")
+
+	stats, err := limiter.Stats(ctx)
+	if err != nil {
+		t.Fatalf("Stats failed: %v", err)
+	}
+	if stats.TotalRequests != 6 || stats.TotalDenied != 1 {
+		t.Errorf("Expected 6 requests and 1 denial, got %d and %d", stats.TotalRequests, stats.TotalDenied)
+	}
+	if global := stats.ByScope["global"]; global == nil || global.Requests != 5 || global.Denied != 1 || global.LastUsed.IsZero() {
+		t.Errorf("Unexpected global stats: %+v", global)
+	}
+	if len(stats.ByEntity) != 2 || stats.ByEntity["user3"] == nil || stats.ByEntity["user3"].Requests != 2 {
+		t.Errorf("Expected the 2 most recent entities, got %v", stats.ByEntity)
+	}
+	if stats.EvictedEntities != 1 {
+		t.Errorf("Expected 1 evicted entity, got %d", stats.EvictedEntities)
+	}
+}
+
+func TestStoreRetries(t *testing.T) {
+	limiter, err := New().Limit("global", "10/minute").StoreRetries(StoreRetryConfig{MaxRetries: 2, Hedge: true}).Build()
+	if err != nil {
+		t.Fatalf("Failed to build limiter: %v", err)
+	}
+	defer limiter.Close()
+
+	for i := 0; i < 3; i++ {
+		if _, err := limiter.Check(context.Background(), "user1", "global"); err != nil {
+			t.Fatalf("Check failed: %v", err)
+		}
+	}
+	stats, err := limiter.Stats(context.Background())
+	if err != nil || stats.StoreRetries == nil || stats.StoreRetries.Reads == 0 {
+		t.Fatalf("Expected store reads in stats, got %+v (%v)", stats, err)
+	}
+
+	config := DefaultObservabilityConfig()
+	config.EnableLogging = false
+	observable := NewObservableLimiter(limiter, config)
+	w := httptest.NewRecorder()
+	NewMonitoringServer(observable).ServeHTTP(w, httptest.NewRequest("GET", "/metrics/prometheus", nil))
+	for _, want := range []string{"gorly_store_reads_total", `gorly_store_read_retries_total{result="budget_exhausted"} 0`, "gorly_store_hedge_delay_seconds 0"} {
+		if !strings.Contains(w.Body.String(), want) {
+			t.Errorf("Expected %s in the metrics, got:\n%s", want, w.Body.String())
+		}
+	}
+
+	if _, err := New().StoreRetries(StoreRetryConfig{BudgetRatio: 1.5}).Build(); err == nil {
+		t.Error("Expected a budget ratio above 1 to fail the build")
+	}
+}
+
+func TestStateCompression(t *testing.T) {
+	limiter, err := New().Algorithm("sliding_window").Limit("global", "100/minute").StateCompression("zstd", 1).Build()
+	if err != nil {
+		t.Fatalf("Failed to build limiter: %v", err)
+	}
+	defer limiter.Close()
+
+	for i := 0; i < 50; i++ {
+		if _, err := limiter.Check(context.Background(), "user1", "global"); err != nil {
+			t.Fatalf("Check failed: %v", err)
+		}
+	}
+	stats, err := limiter.Stats(context.Background())
+	if err != nil || stats.StateCompression == nil || stats.StateCompression.Codec != "zstd" {
+		t.Fatalf("Expected state compression in stats, got %+v (%v)", stats, err)
+	}
+	if compression := stats.StateCompression; compression.Compressed == 0 || compression.Ratio <= 0 || compression.Ratio >= 1 {
+		t.Errorf("Expected the growing window state to be compressed, got %+v", compression)
+	}
+
+	config := DefaultObservabilityConfig()
+	config.EnableLogging = false
+	observable := NewObservableLimiter(limiter, config)
+	w := httptest.NewRecorder()
+	NewMonitoringServer(observable).ServeHTTP(w, httptest.NewRequest("GET", "/metrics/prometheus", nil))
+	for _, want := range []string{`gorly_state_compression_blobs_total{result="compressed"}`, "gorly_state_compression_ratio", `gorly_state_compression_seconds_total{operation="encode"}`} {
+		if !strings.Contains(w.Body.String(), want) {
+			t.Errorf("Expected %s in the metrics, got:\n%s", want, w.Body.String())
+		}
+	}
+
+	if _, err := New().StateCompression("gzip", 0).Build(); err == nil {
+		t.Error("Expected an unknown codec to fail the build")
+	}
+}
+
+func TestWatchKeyExpiry(t *testing.T) {
+	if _, err := New().Limit("global", "10/minute").WatchKeyExpiry(false).Build(); err == nil {
+		t.Error("Expected key expiry telemetry to fail the build without redis")
+	}
+
+	limiter, err := New().Limit("global", "10/minute").Build()
+	if err != nil {
+		t.Fatalf("Failed to build limiter: %v", err)
+	}
+	defer limiter.Close()
+	if stats, err := limiter.Stats(context.Background()); err != nil || stats.KeyExpiry != nil {
+		t.Errorf("Expected no key expiry stats unless watched, got %+v (%v)", stats, err)
+	}
+}
+
+func TestLimitTransition(t *testing.T) {
+	ctx := context.Background()
+	var log strings.Builder
+	limiter, err := New().Limit("global", "10/minute").LimitTransition(TransitionDrain).OnLimitTransition(LimitTransitionLog(&log)).Build()
+	if err != nil {
+		t.Fatalf("Failed to build limiter: %v", err)
+	}
+	defer limiter.Close()
+
+	for i := 0; i < 8; i++ {
+		limiter.Allow(ctx, "user1", "global")
+	}
+	manager := NewHotReloadManager(limiter, nil)
+	if err := manager.applyConfig(ctx, &HotReloadConfig{Limits: map[string]string{"global": "5/minute"}, Enabled: true, Version: "v2"}); err != nil {
+		t.Fatalf("Failed to apply config: %v", err)
+	}
+
+	// The usage of user1 is over the new limit, but drains under the old one
+	result, err := limiter.Check(ctx, "user1", "global")
+	if err != nil || !result.Allowed || result.Limit != 10 {
+		t.Fatalf("Expected user1 to be allowed under the old limit, got %+v (%v)", result, err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(log.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected a transition and an affected entity, got %q", log.String())
+	}
+	var event LimitTransitionEvent
+	if err := json.Unmarshal([]byte(lines[1]), &event); err != nil {
+		t.Fatalf("Failed to decode event: %v", err)
+	}
+	if event.Entity != "user1" || event.Policy != TransitionDrain || event.From != 10 || event.To != 5 {
+		t.Errorf("Unexpected event: %+v", event)
+	}
+}
+
+func TestPostgresBuilder(t *testing.T) {
+	if _, err := New().Postgres("").Limit("global", "10/minute").Build(); err == nil || !strings.Contains(err.Error(), "postgres dsn is required") {
+		t.Errorf("Expected a missing DSN to fail the build, got %v", err)
+	}
+	_, err := New().Postgres("postgres://localhost/app", PostgresTable("ops.limits")).Limit("global", "10/minute").Build()
+	if err == nil || !strings.Contains(err.Error(), "no postgres driver registered") {
+		t.Errorf("Expected the build to ask for a driver, got %v", err)
+	}
+}
+
+func TestRedisWithLocalCacheBuilder(t *testing.T) {
+	builder := New().RedisWithLocalCache("localhost:6379", 50*time.Millisecond, RedisDB(2))
+	if builder.config.Store != "redis" || builder.config.RedisAddress != "localhost:6379" ||
+		builder.config.RedisDB != 2 || builder.config.RedisLocalCache != 50*time.Millisecond {
+		t.Errorf("Expected a cached redis store, got %+v", builder.config)
+	}
+	_, err := New().RedisWithLocalCache("localhost:6379", -time.Second).Limit("global", "10/minute").Build()
+	if err == nil || !strings.Contains(err.Error(), "local cache") {
+		t.Errorf("Expected a negative sync interval to fail the build, got %v", err)
+	}
+}
+
+func TestRedisLazyConnectBuilder(t *testing.T) {
+	builder := New().Redis("127.0.0.1:1", RedisTLS(), RedisLazyConnect())
+	if !builder.config.RedisTLS || !builder.config.RedisLazyConnect {
+		t.Errorf("Expected TLS and lazy connect, got %+v", builder.config)
+	}
+	if _, err := New().Redis("127.0.0.1:1").Limit("global", "10/minute").Build(); err == nil {
+		t.Error("Expected an unreachable Redis to fail the build")
+	}
+	limiter, err := New().Redis("127.0.0.1:1", RedisLazyConnect()).Limit("global", "10/minute").Build()
+	if err != nil {
+		t.Fatalf("Expected a lazy build to succeed, got %v", err)
+	}
+	defer limiter.Close()
+	if _, err := limiter.Check(context.Background(), "user-1"); err == nil {
+		t.Error("Expected the first check to fail against an unreachable Redis")
+	}
+}
+
+// pingOnlyRedis serves just enough of the Redis protocol for a shard to pass health checks:
+// PING is answered, every other command rejected
+func pingOnlyRedis(t *testing.T) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				reader := bufio.NewReader(conn)
+				for {
+					// Commands arrive as arrays of bulk strings: *<n>, then $<len> and the value per argument
+					header, err := reader.ReadString('\n')
+					if err != nil {
+						return
+					}
+					count, _ := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(header, "*")))
+					args := make([]string, 0, count)
+					for k := 0; k < count*2; k++ {
+						line, err := reader.ReadString('\n')
+						if err != nil {
+							return
+						}
+						if k%2 == 1 {
+							args = append(args, strings.TrimSpace(line))
+						}
+					}
+					reply := "-ERR unknown command\r\n"
+					if len(args) > 0 && strings.EqualFold(args[0], "PING") {
+						reply = "+PONG\r\n"
+					}
+					if _, err := conn.Write([]byte(reply)); err != nil {
+						return
+					}
+				}
+			}()
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func TestRedisShardFailurePolicy(t *testing.T) {
+	// One shard is up, the other refuses connections
+	shards := []string{pingOnlyRedis(t), "127.0.0.1:1"}
+
+	limiter, err := New().RedisShards(shards).Limit("global", "10/minute").Build()
+	if err != nil {
+		t.Fatalf("Expected failover to build with a healthy shard, got %v", err)
+	}
+	if err := limiter.Health(context.Background()); err != nil {
+		t.Errorf("Expected failover to be healthy with a healthy shard, got %v", err)
+	}
+	limiter.Close()
+
+	limiter, err = New().
+		RedisShards(shards, RedisShardFailurePolicy("fail_closed"), RedisLazyConnect()).
+		Limit("global", "10/minute").
+		Build()
+	if err != nil {
+		t.Fatalf("Failed to build limiter: %v", err)
+	}
+	defer limiter.Close()
+	if err := limiter.Health(context.Background()); err == nil || !strings.Contains(err.Error(), "1 of 2 shards unavailable") {
+		t.Errorf("Expected fail_closed to report the dead shard, got %v", err)
+	}
+
+	if _, err := New().RedisShards(shards, RedisShardFailurePolicy("sometimes")).Build(); err == nil {
+		t.Error("Expected an unknown shard failure policy to fail the build")
+	}
+}
@@ -0,0 +1,130 @@
+// grants.go - Temporary boost budgets for single entities and their admin handler
+package ratelimit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/itsatony/gorly/internal/core"
+)
+
+// Grant is a temporary budget added to the limit of one entity in one scope, e.g. a
+// one-off boost handed out by support. Requests draw on it only once the limit denies them.
+type Grant struct {
+	Entity    string    `json:"entity"`
+	Scope     string    `json:"scope"`
+	Extra     int64     `json:"extra"`     // Units granted
+	Used      int64     `json:"used"`      // Units drawn from the grant
+	Remaining int64     `json:"remaining"` // Units left
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// newGrant converts a core grant; nil stays nil
+func newGrant(g *core.Grant) *Grant {
+	if g == nil {
+		return nil
+	}
+	return &Grant{
+		Entity:    g.Entity,
+		Scope:     g.Scope,
+		Extra:     g.Extra,
+		Used:      g.Used,
+		Remaining: g.Remaining(),
+		ExpiresAt: g.ExpiresAt,
+	}
+}
+
+func (l *limiterImpl) Grant(ctx context.Context, entity, scope string, extra int64, ttl time.Duration) (*Grant, error) {
+	grant, err := l.core.Grant(ctx, entity, scope, extra, ttl)
+	if err != nil {
+		return nil, newInputError(err)
+	}
+	return newGrant(grant), nil
+}
+
+func (l *limiterImpl) RevokeGrant(ctx context.Context, entity, scope string) error {
+	if err := l.core.RevokeGrant(ctx, entity, scope); err != nil {
+		return newInputError(err)
+	}
+	return nil
+}
+
+func (l *limiterImpl) GrantStatus(ctx context.Context, entity, scope string) (*Grant, error) {
+	grant, err := l.core.GrantStatus(ctx, entity, scope)
+	if err != nil {
+		return nil, newInputError(err)
+	}
+	return newGrant(grant), nil
+}
+
+// grantRequest is the body accepted by GrantsHandler
+type grantRequest struct {
+	Entity string `json:"entity"`
+	Scope  string `json:"scope"`
+	Extra  int64  `json:"extra"`
+	TTL    string `json:"ttl"` // Go duration, e.g. "48h"
+}
+
+// GrantsHandler creates an admin handler for grants. GET ?entity=&scope= returns the current
+// grant, POST/PUT with a JSON body like {"entity": "user:42", "scope": "export", "extra": 500,
+// "ttl": "48h"} hands one out, and DELETE ?entity=&scope= revokes it. The scope defaults to "global".
+// The handler performs no authentication; mount it behind your admin auth or ProtectAdmin.
+// Example: adminMux.Handle("/admin/grants", ratelimit.GrantsHandler(limiter))
+func GrantsHandler(limiter Limiter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var grant *Grant
+		var err error
+		switch r.Method {
+		case http.MethodGet, http.MethodHead:
+			entity, scope := grantTarget(r.URL.Query().Get("entity"), r.URL.Query().Get("scope"))
+			if grant, err = limiter.GrantStatus(r.Context(), entity, scope); err == nil && grant == nil {
+				http.Error(w, "No grant for "+entity+" in "+scope, http.StatusNotFound)
+				return
+			}
+		case http.MethodPut, http.MethodPost:
+			var body grantRequest
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				http.Error(w, `expected a JSON body like {"entity": "user:42", "extra": 500, "ttl": "48h"}`, http.StatusBadRequest)
+				return
+			}
+			ttl, parseErr := time.ParseDuration(body.TTL)
+			if parseErr != nil {
+				http.Error(w, fmt.Sprintf("invalid ttl: %q", body.TTL), http.StatusBadRequest)
+				return
+			}
+			entity, scope := grantTarget(body.Entity, body.Scope)
+			grant, err = limiter.Grant(r.Context(), entity, scope, body.Extra, ttl)
+		case http.MethodDelete:
+			entity, scope := grantTarget(r.URL.Query().Get("entity"), r.URL.Query().Get("scope"))
+			if err = limiter.RevokeGrant(r.Context(), entity, scope); err == nil {
+				w.Header().Set("Cache-Control", "no-store")
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+		default:
+			w.Header().Set("Allow", "GET, HEAD, PUT, POST, DELETE")
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(grant)
+	}
+}
+
+// grantTarget applies the default scope of a grant request
+func grantTarget(entity, scope string) (string, string) {
+	if scope == "" {
+		scope = "global"
+	}
+	return entity, scope
+}
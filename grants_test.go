@@ -0,0 +1,109 @@
+// grants_test.go
+package ratelimit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGrants(t *testing.T) {
+	ctx := context.Background()
+	limiter, err := New().
+		Limit("global", "1/minute").
+		ExtractorFunc(func(r *http.Request) string { return "user:42" }).
+		EnableGrants().
+		Build()
+	if err != nil {
+		t.Fatalf("Failed to build limiter: %v", err)
+	}
+	defer limiter.Close()
+
+	handler := limiter.For(HTTP).(func(http.Handler) http.Handler)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	serve := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, createTestRequest("GET", "/", nil))
+		return w
+	}
+
+	serve()
+	if w := serve(); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected the limit to be exhausted, got %d", w.Code)
+	}
+
+	grant, err := limiter.Grant(ctx, "user:42", "global", 2, time.Hour)
+	if err != nil || grant.Remaining != 2 {
+		t.Fatalf("Grant failed: %+v (%v)", grant, err)
+	}
+	w := serve()
+	if w.Code != http.StatusOK || w.Header().Get("X-RateLimit-Grant-Remaining") != "1" || w.Header().Get("X-RateLimit-Remaining") != "1" {
+		t.Errorf("Expected the grant to cover the request, got %d with headers %v", w.Code, w.Header())
+	}
+	if result, err := limiter.Peek(ctx, "user:42"); err != nil || result.GrantRemaining != 1 {
+		t.Errorf("Expected 1 unit of the grant left, got %+v (%v)", result, err)
+	}
+
+	if err := limiter.RevokeGrant(ctx, "user:42", "global"); err != nil {
+		t.Fatalf("RevokeGrant failed: %v", err)
+	}
+	if w := serve(); w.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected a denial after revoking the grant, got %d", w.Code)
+	}
+
+	// Limiters without EnableGrants reject grants
+	plain, err := New().Limit("global", "1/minute").Build()
+	if err != nil {
+		t.Fatalf("Failed to build limiter: %v", err)
+	}
+	defer plain.Close()
+	if _, err := plain.Grant(ctx, "user:42", "global", 2, time.Hour); err == nil {
+		t.Error("Expected grants to require EnableGrants")
+	}
+}
+
+func TestGrantsHandler(t *testing.T) {
+	limiter, err := New().Limit("export", "10/hour").EnableGrants().Build()
+	if err != nil {
+		t.Fatalf("Failed to build limiter: %v", err)
+	}
+	defer limiter.Close()
+	handler := GrantsHandler(limiter)
+
+	req := httptest.NewRequest(http.MethodPost, "/admin/grants", strings.NewReader(`{"entity":"user:42","scope":"export","extra":500,"ttl":"48h"}`))
+	w := httptest.NewRecorder()
+	handler(w, req)
+	var grant Grant
+	if err := json.NewDecoder(w.Body).Decode(&grant); err != nil || w.Code != http.StatusOK || grant.Extra != 500 {
+		t.Fatalf("Expected the grant, got %d: %+v (%v)", w.Code, grant, err)
+	}
+
+	w = httptest.NewRecorder()
+	handler(w, httptest.NewRequest(http.MethodGet, "/admin/grants?entity=user:42&scope=export", nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"remaining":500`) {
+		t.Errorf("Expected the grant to be listed, got %d: %s", w.Code, w.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/admin/grants", strings.NewReader(`{"entity":"user:42","extra":500,"ttl":"soon"}`))
+	w = httptest.NewRecorder()
+	handler(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an invalid ttl, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	handler(w, httptest.NewRequest(http.MethodDelete, "/admin/grants?entity=user:42&scope=export", nil))
+	if w.Code != http.StatusNoContent {
+		t.Errorf("Expected 204 after revoking, got %d", w.Code)
+	}
+	w = httptest.NewRecorder()
+	handler(w, httptest.NewRequest(http.MethodGet, "/admin/grants?entity=user:42&scope=export", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 without a grant, got %d", w.Code)
+	}
+}
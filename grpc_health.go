@@ -0,0 +1,144 @@
+// grpc_health.go - Limiter health reporting for the gRPC health protocol
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// DefaultHealthWatchInterval is how often WatchHealth checks the limiter when no interval is given
+const DefaultHealthWatchInterval = 5 * time.Second
+
+// ServingStatus is a serving status with the values of grpc.health.v1 HealthCheckResponse_ServingStatus,
+// so it converts directly: healthpb.HealthCheckResponse_ServingStatus(status)
+type ServingStatus int32
+
+// Serving statuses reported by HealthWatcher
+const (
+	ServingStatusUnknown    ServingStatus = 0
+	ServingStatusServing    ServingStatus = 1
+	ServingStatusNotServing ServingStatus = 2
+)
+
+// String returns the name used by the gRPC health protocol
+func (s ServingStatus) String() string {
+	switch s {
+	case ServingStatusServing:
+		return "SERVING"
+	case ServingStatusNotServing:
+		return "NOT_SERVING"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// HealthWatcher polls a limiter's health and reports serving status changes.
+// It keeps gRPC services free of an HTTP health port: report the status to the
+// standard grpc.health.v1 health server and probes see store outages directly.
+type HealthWatcher struct {
+	limiter  Limiter
+	interval time.Duration
+	services []string
+	report   func(service string, status ServingStatus)
+
+	mu     sync.Mutex
+	status ServingStatus
+
+	stop     chan struct{}
+	done     sync.WaitGroup
+	stopOnce sync.Once
+}
+
+// WatchHealth checks the limiter every interval and calls report for each service whenever
+// the serving status changes, starting with the current status. Without services the
+// server-wide status ("") is reported.
+// Example:
+//
+//	hs := health.NewServer()
+//	healthpb.RegisterHealthServer(grpcServer, hs)
+//	watcher := ratelimit.WatchHealth(limiter, 5*time.Second, func(service string, status ratelimit.ServingStatus) {
+//		hs.SetServingStatus(service, healthpb.HealthCheckResponse_ServingStatus(status))
+//	})
+//	defer watcher.Stop()
+func WatchHealth(limiter Limiter, interval time.Duration, report func(service string, status ServingStatus), services ...string) *HealthWatcher {
+	if interval <= 0 {
+		interval = DefaultHealthWatchInterval
+	}
+	if len(services) == 0 {
+		services = []string{""}
+	}
+
+	hw := &HealthWatcher{
+		limiter:  limiter,
+		interval: interval,
+		services: services,
+		report:   report,
+		status:   ServingStatusUnknown,
+		stop:     make(chan struct{}),
+	}
+
+	hw.check()
+	hw.done.Add(1)
+	go hw.run()
+	return hw
+}
+
+// run checks health on every interval until stopped
+func (hw *HealthWatcher) run() {
+	defer hw.done.Done()
+
+	ticker := time.NewTicker(hw.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-hw.stop:
+			return
+		case <-ticker.C:
+			hw.check()
+		}
+	}
+}
+
+// check evaluates the limiter's health and reports a changed status
+func (hw *HealthWatcher) check() {
+	ctx, cancel := context.WithTimeout(context.Background(), hw.interval)
+	defer cancel()
+
+	status := ServingStatusServing
+	if err := hw.limiter.Health(ctx); err != nil {
+		status = ServingStatusNotServing
+	}
+	hw.set(status)
+}
+
+// set records a status and reports it to every service if it changed
+func (hw *HealthWatcher) set(status ServingStatus) {
+	hw.mu.Lock()
+	defer hw.mu.Unlock()
+
+	if status == hw.status {
+		return
+	}
+	hw.status = status
+	for _, service := range hw.services {
+		hw.report(service, status)
+	}
+}
+
+// Status returns the most recently reported serving status
+func (hw *HealthWatcher) Status() ServingStatus {
+	hw.mu.Lock()
+	defer hw.mu.Unlock()
+	return hw.status
+}
+
+// Stop stops watching and reports NOT_SERVING, so clients drain before shutdown
+func (hw *HealthWatcher) Stop() {
+	hw.stopOnce.Do(func() {
+		close(hw.stop)
+		hw.done.Wait()
+		hw.set(ServingStatusNotServing)
+	})
+}
@@ -0,0 +1,87 @@
+// grpc_health_test.go
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// flakyHealthLimiter reports whatever health error it is given
+type flakyHealthLimiter struct {
+	Limiter
+	unhealthy atomic.Bool
+}
+
+func (f *flakyHealthLimiter) Health(ctx context.Context) error {
+	if f.unhealthy.Load() {
+		return errors.New("store unreachable")
+	}
+	return nil
+}
+
+// statusLog records reported serving statuses
+type statusLog struct {
+	mu       sync.Mutex
+	statuses []string
+}
+
+func (sl *statusLog) report(service string, status ServingStatus) {
+	sl.mu.Lock()
+	sl.statuses = append(sl.statuses, service+"="+status.String())
+	sl.mu.Unlock()
+}
+
+func (sl *statusLog) get() []string {
+	sl.mu.Lock()
+	defer sl.mu.Unlock()
+	return append([]string(nil), sl.statuses...)
+}
+
+func TestWatchHealth(t *testing.T) {
+	limiter := &flakyHealthLimiter{}
+	log := &statusLog{}
+
+	watcher := WatchHealth(limiter, 5*time.Millisecond, log.report, "", "api.v1.Search")
+	if got := log.get(); len(got) != 2 || got[0] != "=SERVING" || got[1] != "api.v1.Search=SERVING" {
+		t.Fatalf("Expected initial SERVING for every service, got %v", got)
+	}
+
+	// Unchanged status is not reported again
+	time.Sleep(20 * time.Millisecond)
+	if len(log.get()) != 2 {
+		t.Errorf("Expected no reports while healthy, got %v", log.get())
+	}
+
+	limiter.unhealthy.Store(true)
+	time.Sleep(20 * time.Millisecond)
+	if watcher.Status() != ServingStatusNotServing {
+		t.Errorf("Expected NOT_SERVING during a store outage, got %s", watcher.Status())
+	}
+
+	limiter.unhealthy.Store(false)
+	time.Sleep(20 * time.Millisecond)
+	if watcher.Status() != ServingStatusServing {
+		t.Errorf("Expected SERVING after recovery, got %s", watcher.Status())
+	}
+
+	watcher.Stop()
+	watcher.Stop()
+	got := log.get()
+	if got[len(got)-1] != "api.v1.Search=NOT_SERVING" || watcher.Status() != ServingStatusNotServing {
+		t.Errorf("Expected NOT_SERVING after Stop, got %v", got)
+	}
+	if len(got) != 8 {
+		t.Errorf("Expected 4 status changes for 2 services, got %v", got)
+	}
+}
+
+func TestServingStatusMatchesGRPCValues(t *testing.T) {
+	// Values of grpc.health.v1.HealthCheckResponse_ServingStatus
+	if ServingStatusUnknown != 0 || ServingStatusServing != 1 || ServingStatusNotServing != 2 {
+		t.Error("Serving status values must match the gRPC health protocol")
+	}
+}
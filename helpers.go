@@ -188,51 +188,11 @@ func WindowStart(windowDuration time.Duration) time.Time {
 	return time.Now().Truncate(windowDuration)
 }
 
-// ParseLimit parses a limit string like "100/minute" into rate and duration
-func ParseLimit(limit string) (int64, time.Duration, error) {
-	parts := strings.Split(limit, "/")
-	if len(parts) != 2 {
-		return 0, 0, fmt.Errorf("invalid limit format: %s (expected format: '100/minute')", limit)
-	}
-
-	// Parse rate
-	var rate int64
-	if _, err := fmt.Sscanf(parts[0], "%d", &rate); err != nil {
-		return 0, 0, fmt.Errorf("invalid rate: %s", parts[0])
-	}
-
-	// Parse duration
-	var duration time.Duration
-	switch strings.ToLower(parts[1]) {
-	case "second", "sec", "s":
-		duration = time.Second
-	case "minute", "min", "m":
-		duration = time.Minute
-	case "hour", "hr", "h":
-		duration = time.Hour
-	case "day", "d":
-		duration = time.Hour * 24
-	default:
-		return 0, 0, fmt.Errorf("invalid duration unit: %s", parts[1])
-	}
-
-	return rate, duration, nil
-}
-
 // FormatLimit formats rate and duration back into a limit string
+//
+// Deprecated: use Limit.String.
 func FormatLimit(rate int64, duration time.Duration) string {
-	switch duration {
-	case time.Second:
-		return fmt.Sprintf("%d/second", rate)
-	case time.Minute:
-		return fmt.Sprintf("%d/minute", rate)
-	case time.Hour:
-		return fmt.Sprintf("%d/hour", rate)
-	case time.Hour * 24:
-		return fmt.Sprintf("%d/day", rate)
-	default:
-		return fmt.Sprintf("%d/%s", rate, duration.String())
-	}
+	return Limit{Requests: rate, Window: duration}.String()
 }
 
 // Development helpers
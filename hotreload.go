@@ -3,11 +3,17 @@ package ratelimit
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
+	"sort"
+	"strings"
 	"sync"
 	"time"
+
+	"github.com/itsatony/gorly/internal/core"
 )
 
 // HotReloadConfig defines configuration that can be hot-reloaded
@@ -16,6 +22,15 @@ type HotReloadConfig struct {
 	TierLimits map[string]string `json:"tier_limits"`
 	Algorithm  string            `json:"algorithm"`
 	Enabled    bool              `json:"enabled"`
+	Scale      float64           `json:"scale,omitempty"` // Multiplier for every limit; 0 leaves it unchanged
+	Costs      map[string]int64  `json:"costs,omitempty"` // Endpoint costs, e.g. {"POST /v1/export": 10}; nil leaves them unchanged
+
+	// Overrides replace every entity override; nil leaves them unchanged
+	Overrides []EntityOverride `json:"overrides,omitempty"`
+
+	// Enforcement replaces the enforcement mode of every scope, e.g. {"search": "shadow"};
+	// scopes not listed are enforced, and nil leaves the modes unchanged
+	Enforcement map[string]string `json:"enforcement,omitempty"`
 
 	// Metadata
 	Version   string    `json:"version"`
@@ -23,119 +38,82 @@ type HotReloadConfig struct {
 	UpdatedBy string    `json:"updated_by"`
 }
 
-// HotReloadConfigSource defines where configuration updates come from
-type HotReloadConfigSource interface {
-	// Watch for configuration changes
-	Watch(ctx context.Context) (<-chan *HotReloadConfig, error)
-
-	// Get current configuration
-	GetConfig(ctx context.Context) (*HotReloadConfig, error)
+// costTableSetter is implemented by limiters whose endpoint costs can change at runtime
+type costTableSetter interface {
+	setCosts(costs map[string]int64) error
+}
 
-	// Close the configuration source
-	Close() error
+// setLimiterCosts replaces the endpoint costs of a limiter that supports them
+func setLimiterCosts(limiter Limiter, costs map[string]int64) error {
+	setter, ok := limiter.(costTableSetter)
+	if !ok {
+		return fmt.Errorf("limiter %T does not support endpoint costs", limiter)
+	}
+	return setter.setCosts(costs)
 }
 
-// HotReloadFileConfigSource watches a JSON file for configuration changes
-type HotReloadFileConfigSource struct {
-	filePath string
-	lastMod  time.Time
-	mu       sync.RWMutex
+// scopeDenier is implemented by limiters with deny-listed scope patterns
+type scopeDenier interface {
+	deniedScopes() []string
 }
 
-// NewHotReloadFileConfigSource creates a file-based configuration source
-func NewHotReloadFileConfigSource(filePath string) *HotReloadFileConfigSource {
-	return &HotReloadFileConfigSource{
-		filePath: filePath,
+// limiterDeniedScopes returns the deny-listed scope patterns of a limiter, nil if it has none
+func limiterDeniedScopes(limiter Limiter) []string {
+	if denier, ok := limiter.(scopeDenier); ok {
+		return denier.deniedScopes()
 	}
+	return nil
 }
 
-// Watch implements HotReloadConfigSource interface
-func (fcs *HotReloadFileConfigSource) Watch(ctx context.Context) (<-chan *HotReloadConfig, error) {
-	configChan := make(chan *HotReloadConfig, 1)
+// limitUpdater is implemented by limiters whose request limits can change at runtime
+type limitUpdater interface {
+	updateLimits(update core.LimitUpdate) error
+}
 
-	// Load initial config
-	config, err := fcs.GetConfig(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("failed to load initial config: %w", err)
+// updateLimiterLimits swaps the request limits of a limiter that supports it
+func updateLimiterLimits(limiter Limiter, update core.LimitUpdate) error {
+	updater, ok := limiter.(limitUpdater)
+	if !ok {
+		return fmt.Errorf("limiter %T does not support runtime limits", limiter)
 	}
+	return updater.updateLimits(update)
+}
 
-	configChan <- config
-
-	// Start watching for changes
-	go func() {
-		defer close(configChan)
-
-		ticker := time.NewTicker(time.Second * 5) // Check every 5 seconds
-		defer ticker.Stop()
-
-		for {
-			select {
-			case <-ctx.Done():
-				return
-			case <-ticker.C:
-				if config, err := fcs.checkForUpdates(ctx); err == nil && config != nil {
-					configChan <- config
-				}
-			}
-		}
-	}()
-
-	return configChan, nil
+// configSnapshotStore is implemented by limiters that can share a configuration through their store
+type configSnapshotStore interface {
+	saveConfigSnapshot(ctx context.Context, data []byte) error
+	loadConfigSnapshot(ctx context.Context) ([]byte, error)
 }
 
-// GetConfig implements HotReloadConfigSource interface
-func (fcs *HotReloadFileConfigSource) GetConfig(ctx context.Context) (*HotReloadConfig, error) {
-	// In a real implementation, this would read from the file
-	// For now, return a sample configuration
-	return &HotReloadConfig{
-		Limits: map[string]string{
-			"global": "100/minute",
-			"upload": "10/minute",
-			"search": "50/minute",
-		},
-		TierLimits: map[string]string{
-			"free":    "50/minute",
-			"premium": "500/minute",
-		},
-		Algorithm: "sliding_window",
-		Enabled:   true,
-		Version:   "1.0.0",
-		UpdatedAt: time.Now(),
-		UpdatedBy: "system",
-	}, nil
-}
-
-// checkForUpdates checks if the file has been modified
-func (fcs *HotReloadFileConfigSource) checkForUpdates(ctx context.Context) (*HotReloadConfig, error) {
-	// In a real implementation, this would check file modification time
-	// and reload if changed. For demo purposes, we'll simulate occasional updates.
-
-	if time.Now().Unix()%30 == 0 { // Simulate update every 30 seconds
-		config, err := fcs.GetConfig(ctx)
-		if err != nil {
-			return nil, err
-		}
+// configSnapshots returns the snapshot store of a limiter that has one
+func configSnapshots(limiter Limiter) (configSnapshotStore, error) {
+	snapshots, ok := limiter.(configSnapshotStore)
+	if !ok {
+		return nil, fmt.Errorf("limiter %T cannot share config through its store", limiter)
+	}
+	return snapshots, nil
+}
 
-		// Simulate some changes
-		config.Version = fmt.Sprintf("1.0.%d", time.Now().Unix()%100)
-		config.UpdatedAt = time.Now()
+// bootstrapTimeout bounds reading the shared configuration when a manager starts
+const bootstrapTimeout = 5 * time.Second
 
-		return config, nil
-	}
+// HotReloadConfigSource defines where configuration updates come from
+type HotReloadConfigSource interface {
+	// Watch for configuration changes
+	Watch(ctx context.Context) (<-chan *HotReloadConfig, error)
 
-	return nil, nil
-}
+	// Get current configuration
+	GetConfig(ctx context.Context) (*HotReloadConfig, error)
 
-// Close implements HotReloadConfigSource interface
-func (fcs *HotReloadFileConfigSource) Close() error {
-	return nil
+	// Close the configuration source
+	Close() error
 }
 
 // HTTPConfigSource gets configuration from HTTP endpoints
 type HTTPConfigSource struct {
 	endpoint string
 	headers  map[string]string
-	client   *http.Client
+	client   *http.Client // nil uses the client set with SetHTTPClient
 }
 
 // NewHTTPConfigSource creates an HTTP-based configuration source
@@ -143,10 +121,23 @@ func NewHTTPConfigSource(endpoint string) *HTTPConfigSource {
 	return &HTTPConfigSource{
 		endpoint: endpoint,
 		headers:  make(map[string]string),
-		client:   &http.Client{Timeout: time.Second * 10},
 	}
 }
 
+// WithHTTPClient sets the client that fetches the configuration, e.g. one with mTLS
+// Example: ratelimit.NewHTTPConfigSource(url).WithHTTPClient(mtlsClient)
+func (hcs *HTTPConfigSource) WithHTTPClient(client *http.Client) *HTTPConfigSource {
+	hcs.client = client
+	return hcs
+}
+
+// WithHeader adds a header to every configuration request, e.g. an API token
+// Example: ratelimit.NewHTTPConfigSource(url).WithHeader("Authorization", "Bearer "+token)
+func (hcs *HTTPConfigSource) WithHeader(key, value string) *HTTPConfigSource {
+	hcs.headers[key] = value
+	return hcs
+}
+
 // Watch implements HotReloadConfigSource interface
 func (hcs *HTTPConfigSource) Watch(ctx context.Context) (<-chan *HotReloadConfig, error) {
 	configChan := make(chan *HotReloadConfig, 1)
@@ -174,27 +165,35 @@ func (hcs *HTTPConfigSource) Watch(ctx context.Context) (<-chan *HotReloadConfig
 	return configChan, nil
 }
 
-// GetConfig implements HotReloadConfigSource interface
+// GetConfig implements HotReloadConfigSource interface by fetching the endpoint's JSON configuration
 func (hcs *HTTPConfigSource) GetConfig(ctx context.Context) (*HotReloadConfig, error) {
-	// In a real implementation, this would make HTTP request to the endpoint
-	// For demo, return a sample config
-	return &HotReloadConfig{
-		Limits: map[string]string{
-			"global": "200/minute",
-			"upload": "20/minute",
-			"search": "100/minute",
-		},
-		TierLimits: map[string]string{
-			"free":       "100/minute",
-			"premium":    "1000/minute",
-			"enterprise": "10000/minute",
-		},
-		Algorithm: "token_bucket",
-		Enabled:   true,
-		Version:   "http-1.0.0",
-		UpdatedAt: time.Now(),
-		UpdatedBy: "admin",
-	}, nil
+	ctx, cancel := context.WithTimeout(ctx, defaultOutboundTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, hcs.endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create config request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	for key, value := range hcs.headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := httpClient(hcs.client).Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch config: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("config endpoint returned %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	var config HotReloadConfig
+	if err := json.NewDecoder(resp.Body).Decode(&config); err != nil {
+		return nil, fmt.Errorf("invalid config from %s: %w", hcs.endpoint, err)
+	}
+	return &config, nil
 }
 
 // Close implements HotReloadConfigSource interface
@@ -214,13 +213,17 @@ type HotReloadManager struct {
 	cancel        context.CancelFunc
 	wg            sync.WaitGroup
 
+	// bootstrap shares applied configurations through the limiter's store
+	bootstrap bool
+
 	// Callbacks
 	onConfigUpdate    func(*HotReloadConfig)
 	onUpdateError     func(error)
 	onValidationError func(error)
 }
 
-// NewHotReloadManager creates a new hot reload manager
+// NewHotReloadManager creates a new hot reload manager.
+// Hot reload is experimental; new code should use the experimental/hotreload package.
 func NewHotReloadManager(limiter Limiter, source HotReloadConfigSource) *HotReloadManager {
 	ctx, cancel := context.WithCancel(context.Background())
 
@@ -234,8 +237,13 @@ func NewHotReloadManager(limiter Limiter, source HotReloadConfigSource) *HotRelo
 	}
 }
 
-// Start begins watching for configuration changes
+// Start begins watching for configuration changes. With SetBootstrapFromStore it first
+// applies the configuration last shared through the store.
 func (hrm *HotReloadManager) Start() error {
+	if hrm.bootstrap {
+		hrm.bootstrapFromStore()
+	}
+
 	// Start watching for config changes
 	configChan, err := hrm.configSource.Watch(hrm.ctx)
 	if err != nil {
@@ -295,7 +303,7 @@ func (hrm *HotReloadManager) processUpdates() {
 				return
 			}
 
-			if err := hrm.applyConfig(config); err != nil {
+			if err := hrm.applyConfig(hrm.ctx, config); err != nil {
 				if hrm.onUpdateError != nil {
 					hrm.onUpdateError(err)
 				} else {
@@ -305,6 +313,7 @@ func (hrm *HotReloadManager) processUpdates() {
 				hrm.mu.Lock()
 				hrm.currentConfig = config
 				hrm.mu.Unlock()
+				hrm.shareConfig(hrm.ctx, config)
 
 				if hrm.onConfigUpdate != nil {
 					hrm.onConfigUpdate(config)
@@ -316,8 +325,10 @@ func (hrm *HotReloadManager) processUpdates() {
 	}
 }
 
-// applyConfig applies a new configuration to the rate limiter
-func (hrm *HotReloadManager) applyConfig(config *HotReloadConfig) error {
+// applyConfig applies a new configuration to the rate limiter. Limits, tier limits and
+// scale are swapped in as one table, so in-flight checks never wait for the update and
+// never see half of it. Nothing is applied once ctx is done.
+func (hrm *HotReloadManager) applyConfig(ctx context.Context, config *HotReloadConfig) error {
 	// Validate the configuration
 	if err := hrm.validateConfig(config); err != nil {
 		if hrm.onValidationError != nil {
@@ -326,9 +337,31 @@ func (hrm *HotReloadManager) applyConfig(config *HotReloadConfig) error {
 		return fmt.Errorf("config validation failed: %w", err)
 	}
 
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("config not applied: %w", err)
+	}
+
 	// Apply the configuration
-	// Note: In a real implementation, this would update the limiter's internal configuration
-	// For now, we'll just log the changes
+	if updater, ok := hrm.limiter.(limitUpdater); ok {
+		update := core.LimitUpdate{Limits: config.Limits, Overrides: coreOverrides(config.Overrides), Modes: config.Enforcement, Scale: config.Scale, Version: config.Version}
+		if config.TierLimits != nil {
+			update.TierLimits = map[string]map[string]string{"global": config.TierLimits}
+		}
+		if err := updater.updateLimits(update); err != nil {
+			return fmt.Errorf("failed to apply limits: %w", err)
+		}
+	} else if config.Scale != 0 && config.Scale != hrm.limiter.ScaleFactor() {
+		// Limiters without runtime limits can still be scaled
+		if err := hrm.limiter.Scale(config.Scale); err != nil {
+			return fmt.Errorf("failed to apply scale: %w", err)
+		}
+	}
+
+	if config.Costs != nil {
+		if err := setLimiterCosts(hrm.limiter, config.Costs); err != nil {
+			return fmt.Errorf("failed to apply costs: %w", err)
+		}
+	}
 
 	log.Printf("Applying configuration update:")
 	log.Printf("  Version: %s", config.Version)
@@ -336,6 +369,10 @@ func (hrm *HotReloadManager) applyConfig(config *HotReloadConfig) error {
 	log.Printf("  Enabled: %t", config.Enabled)
 	log.Printf("  Limits: %v", config.Limits)
 	log.Printf("  Tier Limits: %v", config.TierLimits)
+	log.Printf("  Scale: %g", hrm.limiter.ScaleFactor())
+	log.Printf("  Costs: %v", config.Costs)
+	log.Printf("  Overrides: %d", len(config.Overrides))
+	log.Printf("  Enforcement: %v", config.Enforcement)
 	log.Printf("  Updated by: %s at %v", config.UpdatedBy, config.UpdatedAt)
 
 	return nil
@@ -359,18 +396,59 @@ func (hrm *HotReloadManager) validateConfig(config *HotReloadConfig) error {
 		}
 	}
 
+	// Validate scale
+	if config.Scale < 0 || config.Scale > maxLimitScale {
+		return NewConfigError(ErrCodeInvalidConfig,
+			fmt.Sprintf("Invalid scale: %g", config.Scale),
+			fmt.Sprintf("Scale must be between 0 and %g", maxLimitScale))
+	}
+
+	// Validate scope names, which must not be reserved or deny-listed
+	if err := validateReloadScopes(config, limiterDeniedScopes(hrm.limiter)); err != nil {
+		return err
+	}
+
 	// Validate limits format
 	for scope, limit := range config.Limits {
-		if _, _, err := ParseLimit(limit); err != nil {
+		if _, err := parseScopeLimit(limit); err != nil {
 			return NewConfigError(ErrCodeInvalidLimit,
 				fmt.Sprintf("Invalid limit for scope %s: %s", scope, limit),
 				err.Error())
 		}
 	}
 
+	// Validate endpoint costs
+	if err := core.ValidateCosts(config.Costs); err != nil {
+		return NewConfigError(ErrCodeInvalidConfig, "Invalid endpoint costs", err.Error())
+	}
+
+	// Validate entity overrides
+	for _, o := range config.Overrides {
+		if o.Entity == "" || o.Scope == "" {
+			return NewConfigError(ErrCodeInvalidConfig, "Override without entity or scope",
+				"Every override needs an entity, a scope and a limit")
+		}
+		if _, err := parseScopeLimit(o.Limit); err != nil {
+			return NewConfigError(ErrCodeInvalidLimit,
+				fmt.Sprintf("Invalid override for %s in scope %s: %s", o.Entity, o.Scope, o.Limit),
+				err.Error())
+		}
+	}
+
+	// Validate enforcement modes
+	for scope, mode := range config.Enforcement {
+		switch EnforcementMode(mode) {
+		case EnforcementEnforce, EnforcementShadow, EnforcementOff:
+		default:
+			return NewConfigError(ErrCodeInvalidConfig,
+				fmt.Sprintf("Invalid enforcement mode for scope %s: %s", scope, mode),
+				"Supported modes: enforce, shadow, off")
+		}
+	}
+
 	// Validate tier limits format
 	for tier, limit := range config.TierLimits {
-		if _, _, err := ParseLimit(limit); err != nil {
+		if _, err := parseScopeLimit(limit); err != nil {
 			return NewConfigError(ErrCodeInvalidLimit,
 				fmt.Sprintf("Invalid tier limit for %s: %s", tier, limit),
 				err.Error())
@@ -380,6 +458,31 @@ func (hrm *HotReloadManager) validateConfig(config *HotReloadConfig) error {
 	return nil
 }
 
+// validateReloadScopes checks the scopes a configuration defines or targets against the
+// reserved names and the denied patterns
+func validateReloadScopes(config *HotReloadConfig, denied []string) error {
+	scopes := make([]string, 0, len(config.Limits)+len(config.Overrides)+len(config.Enforcement))
+	for scope := range config.Limits {
+		scopes = append(scopes, scope)
+	}
+	for scope := range config.Enforcement {
+		scopes = append(scopes, scope)
+	}
+	for _, o := range config.Overrides {
+		scopes = append(scopes, o.Scope)
+	}
+	sort.Strings(scopes)
+
+	for _, scope := range scopes {
+		if err := core.CheckScopeName(scope, denied); err != nil {
+			return NewConfigError(ErrCodeInvalidConfig,
+				fmt.Sprintf("Scope %s cannot be configured", scope),
+				err.Error())
+		}
+	}
+	return nil
+}
+
 // GetCurrentConfig returns the current configuration
 func (hrm *HotReloadManager) GetCurrentConfig() *HotReloadConfig {
 	hrm.mu.RLock()
@@ -389,12 +492,102 @@ func (hrm *HotReloadManager) GetCurrentConfig() *HotReloadConfig {
 
 // ForceReload forces a configuration reload
 func (hrm *HotReloadManager) ForceReload() error {
-	config, err := hrm.configSource.GetConfig(hrm.ctx)
+	return hrm.ForceReloadContext(hrm.ctx)
+}
+
+// ForceReloadContext forces a configuration reload bounded by ctx. If ctx is done before
+// the new configuration is applied, the limiter keeps its current configuration.
+func (hrm *HotReloadManager) ForceReloadContext(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stop := context.AfterFunc(hrm.ctx, cancel)
+	defer stop()
+
+	config, err := hrm.configSource.GetConfig(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to reload config: %w", err)
 	}
 
-	return hrm.applyConfig(config)
+	if err := hrm.applyConfig(ctx, config); err != nil {
+		return err
+	}
+	hrm.shareConfig(ctx, config)
+	return nil
+}
+
+// SetBootstrapFromStore makes instances share their configuration through the limiter's
+// store, so new instances come up with current limits instead of their built-in ones. Every
+// configuration the manager applies is saved to the store, and Start applies the saved one
+// before the source responds. Use it with a store shared by all instances, such as Redis.
+// Example: manager.SetBootstrapFromStore(true)
+func (hrm *HotReloadManager) SetBootstrapFromStore(enabled bool) {
+	hrm.bootstrap = enabled
+}
+
+// bootstrapFromStore applies the configuration last shared through the store, if any.
+// Failures are reported to the error handler and leave the built-in configuration.
+func (hrm *HotReloadManager) bootstrapFromStore() {
+	config, err := hrm.sharedConfig()
+	if err == nil && config != nil {
+		err = hrm.applyConfig(hrm.ctx, config)
+	}
+	if err != nil {
+		hrm.errorHandler(fmt.Errorf("config bootstrap failed: %w", err))
+		return
+	}
+	if config == nil {
+		return
+	}
+
+	hrm.mu.Lock()
+	hrm.currentConfig = config
+	hrm.mu.Unlock()
+	log.Printf("Configuration bootstrapped from store at version %s", config.Version)
+}
+
+// sharedConfig reads the configuration last saved to the store, or nil if there is none
+func (hrm *HotReloadManager) sharedConfig() (*HotReloadConfig, error) {
+	snapshots, err := configSnapshots(hrm.limiter)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(hrm.ctx, bootstrapTimeout)
+	defer cancel()
+
+	data, err := snapshots.loadConfigSnapshot(ctx)
+	if err != nil || data == nil {
+		return nil, err
+	}
+	var config HotReloadConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("invalid config snapshot: %w", err)
+	}
+	return &config, nil
+}
+
+// shareConfig saves an applied configuration for instances that start later, unless the
+// store already holds a newer one. Failures are reported to the error handler.
+func (hrm *HotReloadManager) shareConfig(ctx context.Context, config *HotReloadConfig) {
+	if !hrm.bootstrap {
+		return
+	}
+	snapshots, err := configSnapshots(hrm.limiter)
+	if err == nil {
+		// A slow instance must not replace a newer configuration with the one it just applied
+		var shared *HotReloadConfig
+		if shared, err = hrm.sharedConfig(); err == nil && shared != nil && shared.UpdatedAt.After(config.UpdatedAt) {
+			return
+		}
+	}
+	if err == nil {
+		var data []byte
+		if data, err = json.Marshal(config); err == nil {
+			err = snapshots.saveConfigSnapshot(ctx, data)
+		}
+	}
+	if err != nil {
+		hrm.errorHandler(fmt.Errorf("failed to share config version %s: %w", config.Version, err))
+	}
 }
 
 // SetUpdateCallback sets a callback for configuration updates
@@ -509,10 +702,11 @@ func (rules *ConfigValidationRules) ValidateWithRules(config *HotReloadConfig) e
 
 	// Validate limit values
 	for scope, limitStr := range config.Limits {
-		rate, _, err := ParseLimit(limitStr)
+		limit, err := parseScopeLimit(limitStr)
 		if err != nil {
 			return err
 		}
+		rate := limit.Requests
 
 		if rate < rules.MinLimitValue || rate > rules.MaxLimitValue {
 			return NewConfigError(ErrCodeInvalidLimit,
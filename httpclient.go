@@ -0,0 +1,30 @@
+// httpclient.go - The HTTP client of outbound calls, injectable package-wide or per component
+package ratelimit
+
+import (
+	"net/http"
+
+	"github.com/itsatony/gorly/internal/outbound"
+)
+
+// defaultOutboundTimeout bounds outbound calls of components without their own timeout
+const defaultOutboundTimeout = outbound.DefaultTimeout
+
+// SetHTTPClient sets the client for outbound calls of every component that was not given
+// its own: config sources, alert handlers, metrics push and stats federation. Use it to
+// route them through a proxy, mTLS or a tracing transport. Nil restores http.DefaultClient.
+// Example: ratelimit.SetHTTPClient(&http.Client{Transport: otelhttp.NewTransport(http.DefaultTransport)})
+func SetHTTPClient(client *http.Client) {
+	outbound.SetClient(client)
+}
+
+// HTTPClient returns the client set with SetHTTPClient, or http.DefaultClient
+func HTTPClient() *http.Client {
+	return outbound.PackageClient()
+}
+
+// httpClient returns a component's own client, or the package client if it has none.
+// Components call it for every request, so SetHTTPClient also reaches components created before it.
+func httpClient(client *http.Client) *http.Client {
+	return outbound.Client(client)
+}
@@ -0,0 +1,162 @@
+// httpclient_test.go
+package ratelimit
+
+import (
+	"context"
+	"encoding/json"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// viaTransport tags requests with the client that sent them
+type viaTransport struct{ via string }
+
+func (vt viaTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("X-Via", vt.via)
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+// outboundRecorder answers outbound calls and remembers which client sent them
+type outboundRecorder struct {
+	mu   sync.Mutex
+	vias map[string]string // path -> X-Via of the latest request
+}
+
+func (or *outboundRecorder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	or.mu.Lock()
+	or.vias[r.URL.Path] = r.Header.Get("X-Via")
+	or.mu.Unlock()
+
+	switch r.URL.Path {
+	case "/config":
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(HotReloadConfig{Limits: map[string]string{"global": "5/minute"}, Version: "7"})
+	case "/broken":
+		http.Error(w, "down for maintenance", http.StatusServiceUnavailable)
+	default:
+		w.WriteHeader(http.StatusAccepted)
+	}
+}
+
+func (or *outboundRecorder) via(path string) string {
+	or.mu.Lock()
+	defer or.mu.Unlock()
+	return or.vias[path]
+}
+
+func TestHTTPClientInjection(t *testing.T) {
+	recorder := &outboundRecorder{vias: make(map[string]string)}
+	server := httptest.NewServer(recorder)
+	defer server.Close()
+
+	SetHTTPClient(&http.Client{Transport: viaTransport{"package"}})
+	t.Cleanup(func() { SetHTTPClient(nil) })
+	component := &http.Client{Transport: viaTransport{"component"}}
+
+	t.Run("config source", func(t *testing.T) {
+		source := NewHTTPConfigSource(server.URL+"/config").WithHeader("Authorization", "Bearer secret")
+		config, err := source.GetConfig(context.Background())
+		if err != nil || config.Version != "7" || config.Limits["global"] != "5/minute" {
+			t.Fatalf("Expected the served config, got %+v (%v)", config, err)
+		}
+		if via := recorder.via("/config"); via != "package" {
+			t.Errorf("Expected the package client, got %q", via)
+		}
+
+		if _, err := source.WithHTTPClient(component).GetConfig(context.Background()); err != nil {
+			t.Fatalf("GetConfig failed: %v", err)
+		}
+		if via := recorder.via("/config"); via != "component" {
+			t.Errorf("Expected the component client, got %q", via)
+		}
+
+		if _, err := NewHTTPConfigSource(server.URL + "/broken").GetConfig(context.Background()); err == nil || !strings.Contains(err.Error(), "503") {
+			t.Errorf("Expected the endpoint's status in the error, got %v", err)
+		}
+	})
+
+	t.Run("alert handler", func(t *testing.T) {
+		HTTPAlertHandler(server.URL + "/alerts")(Alert{Name: "High Error Rate"})
+		if via := recorder.via("/alerts"); via != "package" {
+			t.Errorf("Expected the package client, got %q", via)
+		}
+		HTTPAlertHandlerWithClient(server.URL+"/alerts", component)(Alert{Name: "High Error Rate"})
+		if via := recorder.via("/alerts"); via != "component" {
+			t.Errorf("Expected the component client, got %q", via)
+		}
+	})
+
+	t.Run("metrics push", func(t *testing.T) {
+		limiter := newPushTestLimiter(t, &PushConfig{URL: server.URL, Job: "worker"})
+		if err := limiter.PushMetrics(context.Background()); err != nil {
+			t.Fatalf("Push failed: %v", err)
+		}
+		if via := recorder.via("/metrics/job/worker"); via != "package" {
+			t.Errorf("Expected the package client, got %q", via)
+		}
+	})
+}
+
+// TestNoRawHTTPClients keeps outbound calls injectable: library code gets its client from
+// httpClient instead of building one or reaching for http.DefaultClient
+func TestNoRawHTTPClients(t *testing.T) {
+	skipDirs := map[string]bool{"cmd": true, "examples": true, "test": true, "testdata": true}
+	fset := token.NewFileSet()
+
+	err := filepath.WalkDir(".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != "." && (skipDirs[path] || strings.HasPrefix(d.Name(), ".")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(path, ".go") || strings.HasSuffix(path, "_test.go") || path == filepath.Join("internal", "outbound", "client.go") {
+			return nil
+		}
+
+		file, err := parser.ParseFile(fset, path, nil, 0)
+		if err != nil {
+			return err
+		}
+		ast.Inspect(file, func(n ast.Node) bool {
+			var sel *ast.SelectorExpr
+			switch node := n.(type) {
+			case *ast.CompositeLit:
+				sel, _ = node.Type.(*ast.SelectorExpr)
+				if sel == nil || sel.Sel.Name != "Client" {
+					return true
+				}
+			case *ast.SelectorExpr:
+				if node.Sel.Name != "DefaultClient" {
+					return true
+				}
+				sel = node
+			default:
+				return true
+			}
+			if pkg, ok := sel.X.(*ast.Ident); ok && pkg.Name == "http" {
+				t.Errorf("%s: outbound calls must use an injectable client, not http.%s", fset.Position(n.Pos()), sel.Sel.Name)
+			}
+			return true
+		})
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to scan sources: %v", err)
+	}
+}
@@ -0,0 +1,163 @@
+// internal/core/alignment.go
+package core
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/itsatony/gorly/algorithms"
+)
+
+// AlignRolling is the window alignment of the token bucket and sliding window algorithms:
+// every request ages out one window after it was made, so there is no common reset time
+const AlignRolling = "rolling"
+
+// newAlgorithm creates an algorithm by name; alignment applies to fixed windows only
+func newAlgorithm(config *Config, name, alignment string, now func() time.Time) (Algorithm, error) {
+	switch name {
+	case "token_bucket":
+		tokenBucket := algorithms.NewTokenBucketAlgorithm()
+		tokenBucket.SetClock(now)
+		if err := tokenBucket.SetCompression(config.StateCompression); err != nil {
+			return nil, err
+		}
+		return &algorithmAdapter{tokenBucket}, nil
+	case "sliding_window":
+		slidingWindow := algorithms.NewSlidingWindowAlgorithm()
+		slidingWindow.SetClock(now)
+		slidingWindow.SetSkewTolerance(config.ClockSkewTolerance)
+		if err := slidingWindow.SetCompression(config.StateCompression); err != nil {
+			return nil, err
+		}
+		return &algorithmAdapter{slidingWindow}, nil
+	case "fixed_window":
+		fixedWindow := algorithms.NewFixedWindowAlgorithm()
+		fixedWindow.SetClock(now)
+		if err := fixedWindow.SetAlignment(algorithms.WindowAlignment(alignment)); err != nil {
+			return nil, err
+		}
+		return &algorithmAdapter{fixedWindow}, nil
+	case "gcra":
+		// TODO: Implement GCRA algorithm
+		slidingWindow := algorithms.NewSlidingWindowAlgorithm() // Fallback for now
+		slidingWindow.SetClock(now)
+		slidingWindow.SetSkewTolerance(config.ClockSkewTolerance)
+		if err := slidingWindow.SetCompression(config.StateCompression); err != nil {
+			return nil, err
+		}
+		return &algorithmAdapter{slidingWindow}, nil
+	default:
+		return newCustomAlgorithm(name)
+	}
+}
+
+// validateAlignments checks the window alignments of scopes
+func (c *Config) validateAlignments() error {
+	for scope, alignment := range c.ScopeAlignments {
+		switch alignment {
+		case AlignRolling, AlignWallClock, AlignFirstRequest:
+		default:
+			return fmt.Errorf("window alignment of scope %s must be %q, %q or %q, got %q",
+				scope, AlignRolling, AlignWallClock, AlignFirstRequest, alignment)
+		}
+	}
+	return nil
+}
+
+// defaultAlignment is the window alignment of the configured algorithm
+func (c *Config) defaultAlignment() string {
+	if c.Algorithm != "fixed_window" {
+		return AlignRolling
+	}
+	if c.WindowAlignment == "" {
+		return AlignWallClock
+	}
+	return c.WindowAlignment
+}
+
+// alignScopes creates the algorithms of scopes whose window alignment differs from the
+// configured algorithm's. Scopes aligned to the clock or to their first request count in
+// fixed windows; rolling scopes of a fixed window limiter count in sliding windows.
+func (l *limiterImpl) alignScopes(now func() time.Time) error {
+	l.alignment = l.config.defaultAlignment()
+	byAlignment := make(map[string]Algorithm)
+	for scope, alignment := range l.config.ScopeAlignments {
+		if alignment == l.alignment {
+			continue
+		}
+		algorithm, ok := byAlignment[alignment]
+		if !ok {
+			name := "fixed_window"
+			if alignment == AlignRolling {
+				name = "sliding_window"
+			}
+			var err error
+			if algorithm, err = newAlgorithm(l.config, name, alignment, now); err != nil {
+				return err
+			}
+			byAlignment[alignment] = algorithm
+		}
+		if l.aligned == nil {
+			l.aligned = make(map[string]Algorithm)
+		}
+		l.aligned[scope] = algorithm
+	}
+	return nil
+}
+
+// algorithmFor returns the algorithm counting the requests of scope
+func (l *limiterImpl) algorithmFor(scope string) Algorithm {
+	if algorithm, ok := l.aligned[scope]; ok {
+		return algorithm
+	}
+	return l.algorithm
+}
+
+// StateCompressionStats returns the savings and CPU cost of compressing algorithm state,
+// summed over the algorithms of every alignment, or nil without StateCompression
+func (l *limiterImpl) StateCompressionStats() *algorithms.CompressionStats {
+	codec := l.config.StateCompression.Algorithm
+	if codec == "" || codec == algorithms.CompressionNone {
+		return nil
+	}
+	total := &algorithms.CompressionStats{Algorithm: codec}
+	candidates := []Algorithm{l.algorithm}
+	for _, algorithm := range l.aligned {
+		candidates = append(candidates, algorithm)
+	}
+	var counted []*algorithmAdapter
+	for _, algorithm := range candidates {
+		adapter, ok := algorithm.(*algorithmAdapter)
+		if !ok || slices.Contains(counted, adapter) {
+			continue
+		}
+		counted = append(counted, adapter)
+		compressor, ok := adapter.algorithm.(interface {
+			CompressionStats() algorithms.CompressionStats
+		})
+		if !ok {
+			continue
+		}
+		stats := compressor.CompressionStats()
+		total.Compressed += stats.Compressed
+		total.Skipped += stats.Skipped
+		total.Decompressed += stats.Decompressed
+		total.BytesIn += stats.BytesIn
+		total.BytesOut += stats.BytesOut
+		total.CompressTime += stats.CompressTime
+		total.DecompressTime += stats.DecompressTime
+	}
+	if total.BytesIn > 0 {
+		total.CompressionRate = float64(total.BytesOut) / float64(total.BytesIn)
+	}
+	return total
+}
+
+// alignmentFor returns the window alignment of scope
+func (l *limiterImpl) alignmentFor(scope string) string {
+	if _, ok := l.aligned[scope]; ok {
+		return l.config.ScopeAlignments[scope]
+	}
+	return l.alignment
+}
@@ -0,0 +1,72 @@
+// internal/core/alignment_test.go
+package core
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestScopeAlignments(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 59, 30, 0, time.UTC)
+	limiter := newTestLimiter(t, &Config{
+		Algorithm:       "sliding_window",
+		Limits:          map[string]string{"global": "2/hour", "partner": "2/hour"},
+		ScopeAlignments: map[string]string{"partner": AlignWallClock},
+		Clock:           func() time.Time { return now },
+	}, nil)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		limiter.Check(ctx, "alice", "global")
+		limiter.Check(ctx, "alice", "partner")
+	}
+	global, err := limiter.Check(ctx, "alice", "global")
+	if err != nil || global.Allowed || global.Alignment != AlignRolling {
+		t.Errorf("Expected a rolling denial in global, got %+v (%v)", global, err)
+	}
+	partner, err := limiter.Check(ctx, "alice", "partner")
+	if err != nil || partner.Allowed || partner.Alignment != AlignWallClock {
+		t.Errorf("Expected a wall-clock denial in partner, got %+v (%v)", partner, err)
+	}
+	if want := time.Date(2026, 10, 16, 13, 0, 0, 0, time.UTC); !partner.ResetTime.Equal(want) {
+		t.Errorf("Expected partner to reset on the hour at %v, got %v", want, partner.ResetTime)
+	}
+
+	// Past the hour the partner window starts over, while the rolling window still holds
+	now = now.Add(time.Minute)
+	if result, _ := limiter.Check(ctx, "alice", "partner"); !result.Allowed {
+		t.Errorf("Expected a new partner window on the hour, got %+v", result)
+	}
+	if result, _ := limiter.Check(ctx, "alice", "global"); result.Allowed {
+		t.Errorf("Expected the rolling global window to still deny, got %+v", result)
+	}
+	if peek, _ := limiter.Peek(ctx, "alice", "partner"); peek.Alignment != AlignWallClock || peek.Used != 1 {
+		t.Errorf("Expected Peek to read the partner window, got %+v", peek)
+	}
+}
+
+func TestScopeAlignmentsRolling(t *testing.T) {
+	impl := newTestLimiter(t, &Config{
+		Algorithm:       "fixed_window",
+		Limits:          map[string]string{"global": "5/minute", "search": "5/minute"},
+		ScopeAlignments: map[string]string{"search": AlignRolling, "global": AlignWallClock},
+	}, nil)
+
+	if name := impl.algorithmFor("search").Name(); name != "sliding_window" {
+		t.Errorf("Expected rolling scopes of a fixed window limiter to slide, got %s", name)
+	}
+	if impl.algorithmFor("global") != impl.algorithm {
+		t.Error("Expected scopes aligned like the algorithm to share it")
+	}
+	if key := impl.requestKey("alice", "search"); !strings.Contains(key, "sliding_window") {
+		t.Errorf("Expected the key to name the scope's algorithm, got %s", key)
+	}
+
+	config := &Config{Store: "memory", Algorithm: "sliding_window", Limits: map[string]string{"global": "1/second"},
+		ScopeAlignments: map[string]string{"global": "hourly"}}
+	if err := config.Validate(); err == nil || !strings.Contains(err.Error(), "window alignment of scope global") {
+		t.Errorf("Expected an invalid scope alignment to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,156 @@
+// internal/core/bandwidth.go
+package core
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultBandwidthFlushBytes is how many response bytes are buffered before being charged to the store
+const DefaultBandwidthFlushBytes = 64 * 1024
+
+// byteUnits maps size suffixes to their multiplier
+var byteUnits = map[string]int64{
+	"B":   1,
+	"KB":  1 << 10,
+	"KIB": 1 << 10,
+	"MB":  1 << 20,
+	"MIB": 1 << 20,
+	"GB":  1 << 30,
+	"GIB": 1 << 30,
+	"TB":  1 << 40,
+	"TIB": 1 << 40,
+}
+
+// parseBandwidth parses a bandwidth limit like "500MB/hour" into bytes and duration
+func parseBandwidth(limitStr string) (int64, time.Duration, error) {
+	parts := strings.Split(limitStr, "/")
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("invalid bandwidth format: %s (expected 'size/duration')", limitStr)
+	}
+
+	size := strings.ToUpper(strings.TrimSpace(parts[0]))
+	split := strings.IndexFunc(size, func(r rune) bool { return (r < '0' || r > '9') && r != '.' })
+	if split <= 0 {
+		return 0, 0, fmt.Errorf("invalid bandwidth size: %s", parts[0])
+	}
+
+	amount, err := strconv.ParseFloat(size[:split], 64)
+	if err != nil || amount <= 0 {
+		return 0, 0, fmt.Errorf("invalid bandwidth size: %s", parts[0])
+	}
+	unit, ok := byteUnits[strings.TrimSpace(size[split:])]
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid bandwidth unit: %s", size[split:])
+	}
+
+	window, err := parseWindow(parts[1])
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return int64(amount * float64(unit)), window, nil
+}
+
+// HasBandwidthLimit reports whether the scope is limited by response volume
+func (c *Config) HasBandwidthLimit(scope string) bool {
+	_, ok := c.BandwidthLimits[scope]
+	return ok
+}
+
+// HasRequestLimit reports whether the scope has its own request-count limit
+func (c *Config) HasRequestLimit(scope string) bool {
+	table := c.limitTable()
+	if _, ok := table.limits[scope]; ok {
+		return true
+	}
+	_, ok := table.tierLimits[scope]
+	return ok
+}
+
+// CheckBandwidth returns the remaining byte budget for an entity and scope without consuming it
+func (l *limiterImpl) CheckBandwidth(ctx context.Context, entity, scope string) (*CoreResult, error) {
+	return l.chargeBandwidth(ctx, entity, scope, 0)
+}
+
+// ConsumeBandwidth charges response bytes against the budget of an entity and scope
+func (l *limiterImpl) ConsumeBandwidth(ctx context.Context, entity, scope string, bytes int64) (*CoreResult, error) {
+	if bytes < 0 {
+		return nil, fmt.Errorf("bandwidth consumption cannot be negative")
+	}
+	return l.chargeBandwidth(ctx, entity, scope, bytes)
+}
+
+// chargeBandwidth adds bytes to the current bandwidth window of an entity and scope
+func (l *limiterImpl) chargeBandwidth(ctx context.Context, entity, scope string, bytes int64) (*CoreResult, error) {
+	limitStr, ok := l.config.BandwidthLimits[scope]
+	if !ok {
+		return nil, fmt.Errorf("no bandwidth limit configured for scope: %s", scope)
+	}
+	budget, window, err := parseBandwidth(limitStr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bandwidth limit: %w", err)
+	}
+
+	result, err := l.chargeCounter(ctx, "bandwidth", entity, scope, bytes, l.scaled(budget), window)
+	if err != nil {
+		return nil, fmt.Errorf("bandwidth accounting failed: %w", err)
+	}
+	return result, nil
+}
+
+// counterKey is the store key of a fixed window counter of an entity and scope
+func (l *limiterImpl) counterKey(kind, entity, scope string, windowStart time.Time) string {
+	parts := []string{kind, entity, scope, strconv.FormatInt(windowStart.Unix(), 10)}
+	if generation, ok := l.generation(scope); ok {
+		parts = append(parts, generation)
+	}
+	return l.config.keys().Build(parts...)
+}
+
+// chargeCounter adds amount to a fixed window counter and reports the remaining budget.
+// Charging zero reads the counter through the same atomic store operation. Day, week and
+// month windows follow the calendar of the entity's or scope's time zone, if any.
+func (l *limiterImpl) chargeCounter(ctx context.Context, kind, entity, scope string, amount, budget int64, window time.Duration) (*CoreResult, error) {
+	entity, scope, err := l.sanitize(entity, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	now := l.config.Now()
+	windowStart, resetTime := calendarWindow(now, window, l.windowLocation(entity, scope))
+	key := l.counterKey(kind, entity, scope, windowStart)
+
+	used, err := l.storeFor(scope).IncrementBy(ctx, key, amount, resetTime.Sub(now)+time.Second)
+	if err != nil {
+		// Only admission checks fail open; charges after the fact report the failure
+		if amount == 0 {
+			if result := l.failOpen(scope, budget, resetTime.Sub(windowStart), err); result != nil {
+				return result, nil
+			}
+		}
+		return nil, err
+	}
+
+	remaining := budget - used
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	result := &CoreResult{
+		Allowed:   remaining > 0,
+		Remaining: remaining,
+		Limit:     budget,
+		Used:      used,
+		Window:    resetTime.Sub(windowStart),
+		ResetTime: resetTime,
+	}
+	if !result.Allowed {
+		result.RetryAfter = resetTime.Sub(now)
+	}
+
+	return result, nil
+}
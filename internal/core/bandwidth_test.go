@@ -0,0 +1,36 @@
+// internal/core/bandwidth_test.go
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseBandwidth(t *testing.T) {
+	tests := []struct {
+		input  string
+		bytes  int64
+		window time.Duration
+		valid  bool
+	}{
+		{"500MB/hour", 500 << 20, time.Hour, true},
+		{"1.5GB/day", 3 << 29, 24 * time.Hour, true},
+		{"64kib/minute", 64 << 10, time.Minute, true},
+		{"100B/second", 100, time.Second, true},
+		{"500/hour", 0, 0, false},
+		{"500XB/hour", 0, 0, false},
+		{"500MB", 0, 0, false},
+		{"0MB/hour", 0, 0, false},
+	}
+
+	for _, tt := range tests {
+		bytes, window, err := parseBandwidth(tt.input)
+		if (err == nil) != tt.valid {
+			t.Errorf("%s: expected valid=%v, got err=%v", tt.input, tt.valid, err)
+			continue
+		}
+		if tt.valid && (bytes != tt.bytes || window != tt.window) {
+			t.Errorf("%s: expected %d bytes per %v, got %d per %v", tt.input, tt.bytes, tt.window, bytes, window)
+		}
+	}
+}
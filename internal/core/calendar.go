@@ -0,0 +1,99 @@
+// internal/core/calendar.go
+package core
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Calendar windows of fixed-window budgets. Token and bandwidth budgets per day, week
+// or month normally start at multiples of the window since the Unix epoch, i.e. at
+// midnight UTC. With a time zone they follow the calendar of that zone instead: a day
+// starts at local midnight, a week on Monday and a month on its first day, so a day
+// spanning a DST change lasts 23 or 25 hours and a month 28 to 31 days.
+const (
+	calendarDay   = 24 * time.Hour
+	calendarWeek  = 7 * calendarDay
+	calendarMonth = 30 * calendarDay // What parseWindow returns for "month"
+)
+
+// locations caches loaded time zones, which time.LoadLocation reads from disk
+var locations sync.Map // name -> *time.Location
+
+// loadLocation returns the time zone of an IANA name like "Europe/Berlin"
+func loadLocation(name string) (*time.Location, error) {
+	if loc, ok := locations.Load(name); ok {
+		return loc.(*time.Location), nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, err
+	}
+	locations.Store(name, loc)
+	return loc, nil
+}
+
+// validateTimeZones checks the reset time zones of a config
+func (c *Config) validateTimeZones() error {
+	for scope, name := range c.ScopeTimeZones {
+		if name == "" {
+			return fmt.Errorf("empty time zone for scope %s", scope)
+		}
+		if _, err := loadLocation(name); err != nil {
+			return fmt.Errorf("invalid time zone for scope %s: %w", scope, err)
+		}
+	}
+	return nil
+}
+
+// windowLocation returns the time zone the calendar windows of an entity and scope follow:
+// the tenant's zone from TimeZoneFunc, else the scope's zone, else nil for UTC-aligned windows.
+// Unknown tenant zones are reported and ignored so a bad record cannot break accounting.
+func (l *limiterImpl) windowLocation(entity, scope string) *time.Location {
+	if l.config.TimeZoneFunc != nil {
+		if name := l.config.TimeZoneFunc(entity); name != "" {
+			loc, err := loadLocation(name)
+			if err == nil {
+				return loc
+			}
+			l.reportError(fmt.Errorf("invalid time zone for entity %s: %w", entity, err))
+		}
+	}
+	if name, ok := l.config.ScopeTimeZones[scope]; ok {
+		if loc, err := loadLocation(name); err == nil {
+			return loc
+		}
+	}
+	return nil
+}
+
+// calendarWindow returns the start and end of the fixed window containing now. Without a
+// location, or for windows other than a day, week or month, windows are multiples of their
+// length since the Unix epoch. Both instants are returned in loc, or UTC without one.
+func calendarWindow(now time.Time, window time.Duration, loc *time.Location) (start, reset time.Time) {
+	if loc == nil {
+		start = now.Truncate(window).UTC()
+		return start, start.Add(window)
+	}
+
+	local := now.In(loc)
+	year, month, day := local.Date()
+	switch window {
+	case calendarDay:
+		start = time.Date(year, month, day, 0, 0, 0, 0, loc)
+		reset = time.Date(year, month, day+1, 0, 0, 0, 0, loc)
+	case calendarWeek:
+		// Weeks start on Monday, as in ISO 8601
+		monday := day - (int(local.Weekday())+6)%7
+		start = time.Date(year, month, monday, 0, 0, 0, 0, loc)
+		reset = time.Date(year, month, monday+7, 0, 0, 0, 0, loc)
+	case calendarMonth:
+		start = time.Date(year, month, 1, 0, 0, 0, 0, loc)
+		reset = time.Date(year, month+1, 1, 0, 0, 0, 0, loc)
+	default:
+		start = now.Truncate(window).In(loc)
+		reset = start.Add(window)
+	}
+	return start, reset
+}
@@ -0,0 +1,102 @@
+// internal/core/calendar_test.go
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestCalendarWindow(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("Time zone database unavailable: %v", err)
+	}
+
+	tests := []struct {
+		name             string
+		now              time.Time
+		window           time.Duration
+		loc              *time.Location
+		wantStart, reset time.Time
+	}{
+		{"UTC day", time.Date(2026, 3, 8, 15, 0, 0, 0, time.UTC), calendarDay, nil,
+			time.Date(2026, 3, 8, 0, 0, 0, 0, time.UTC), time.Date(2026, 3, 9, 0, 0, 0, 0, time.UTC)},
+		{"local day", time.Date(2026, 1, 15, 3, 0, 0, 0, time.UTC), calendarDay, newYork,
+			time.Date(2026, 1, 14, 0, 0, 0, 0, newYork), time.Date(2026, 1, 15, 0, 0, 0, 0, newYork)},
+		{"23 hour day", time.Date(2026, 3, 8, 12, 0, 0, 0, newYork), calendarDay, newYork,
+			time.Date(2026, 3, 8, 0, 0, 0, 0, newYork), time.Date(2026, 3, 9, 0, 0, 0, 0, newYork)},
+		{"week", time.Date(2026, 10, 18, 23, 0, 0, 0, newYork), calendarWeek, newYork,
+			time.Date(2026, 10, 12, 0, 0, 0, 0, newYork), time.Date(2026, 10, 19, 0, 0, 0, 0, newYork)},
+		{"month", time.Date(2026, 2, 28, 23, 59, 0, 0, newYork), calendarMonth, newYork,
+			time.Date(2026, 2, 1, 0, 0, 0, 0, newYork), time.Date(2026, 3, 1, 0, 0, 0, 0, newYork)},
+		{"year end", time.Date(2026, 12, 31, 20, 0, 0, 0, newYork), calendarMonth, newYork,
+			time.Date(2026, 12, 1, 0, 0, 0, 0, newYork), time.Date(2027, 1, 1, 0, 0, 0, 0, newYork)},
+		{"hour", time.Date(2026, 3, 8, 12, 30, 0, 0, newYork), time.Hour, newYork,
+			time.Date(2026, 3, 8, 12, 0, 0, 0, newYork), time.Date(2026, 3, 8, 13, 0, 0, 0, newYork)},
+	}
+
+	for _, tt := range tests {
+		start, reset := calendarWindow(tt.now, tt.window, tt.loc)
+		if !start.Equal(tt.wantStart) || !reset.Equal(tt.reset) {
+			t.Errorf("%s: got %v to %v, want %v to %v", tt.name, start, reset, tt.wantStart, tt.reset)
+		}
+	}
+
+	if _, reset := calendarWindow(time.Date(2026, 11, 1, 12, 0, 0, 0, newYork), calendarDay, newYork); reset.Location() != newYork {
+		t.Errorf("Expected the reset instant in the window's zone, got %v", reset.Location())
+	}
+}
+
+func TestTimeZoneBudgets(t *testing.T) {
+	ctx := context.Background()
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("Time zone database unavailable: %v", err)
+	}
+
+	now := time.Date(2026, 3, 8, 22, 0, 0, 0, newYork) // 02:00 UTC on 9 March
+	config := &Config{
+		Algorithm:      "sliding_window",
+		Limits:         map[string]string{"global": "100/minute"},
+		TokenBudgets:   map[string]string{"global": "1000/day"},
+		ScopeTimeZones: map[string]string{"global": "America/New_York"},
+		TimeZoneFunc: func(entity string) string {
+			if entity == "tokyo-customer" {
+				return "Asia/Tokyo"
+			}
+			return ""
+		},
+		Clock: func() time.Time { return now },
+	}
+	limiter := newTestLimiter(t, config, nil)
+
+	result, err := limiter.ConsumeTokens(ctx, "user1", "global", 400)
+	if err != nil {
+		t.Fatalf("ConsumeTokens failed: %v", err)
+	}
+	if want := time.Date(2026, 3, 9, 0, 0, 0, 0, newYork); !result.ResetTime.Equal(want) || result.ResetTime.Location().String() != "America/New_York" {
+		t.Errorf("Expected the budget to reset at local midnight %v, got %v", want, result.ResetTime)
+	}
+	if result.Window != 23*time.Hour {
+		t.Errorf("Expected the DST day to last 23 hours, got %v", result.Window)
+	}
+
+	// Two hours later it is a new day in New York, though still 9 March in UTC
+	now = now.Add(2 * time.Hour)
+	if result, _ := limiter.CheckTokens(ctx, "user1", "global"); result.Used != 0 {
+		t.Errorf("Expected a fresh budget after local midnight, got %d used", result.Used)
+	}
+
+	result, err = limiter.CheckTokens(ctx, "tokyo-customer", "global")
+	if err != nil {
+		t.Fatalf("CheckTokens failed: %v", err)
+	}
+	if result.ResetTime.Location().String() != "Asia/Tokyo" || result.ResetTime.Hour() != 0 {
+		t.Errorf("Expected the tenant's zone to take precedence, got %v", result.ResetTime)
+	}
+
+	if err := (&Config{Store: "memory", Algorithm: "sliding_window", Limits: map[string]string{"global": "1/minute"}, ScopeTimeZones: map[string]string{"global": "Mars/Olympus_Mons"}}).Validate(); err == nil {
+		t.Error("Expected an unknown time zone to be rejected")
+	}
+}
@@ -0,0 +1,132 @@
+// internal/core/clock.go
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Clock sources for window calculations
+const (
+	ClockSourceLocal = "local" // Each instance uses its own clock (default)
+	ClockSourceStore = "store" // Instances use the clock of the shared store, e.g. Redis TIME
+)
+
+// DefaultClockSyncInterval is how often the offset to the store clock is measured
+const DefaultClockSyncInterval = 10 * time.Second
+
+// storeTimer is implemented by stores that report their own clock
+type storeTimer interface {
+	Time(ctx context.Context) (time.Time, error)
+}
+
+// storeClock follows the clock of the shared store. Reading the store clock on every
+// check would add a round trip, so the offset between the local and the store clock is
+// measured periodically, corrected by half the round trip, and applied to the local clock.
+// Instances with drifting clocks then agree on window boundaries up to the measurement error.
+type storeClock struct {
+	timer    storeTimer
+	local    func() time.Time
+	interval time.Duration
+	limiter  *limiterImpl
+	offset   atomic.Int64 // Store clock minus local clock, in nanoseconds
+
+	stop chan struct{}
+	done sync.WaitGroup
+}
+
+// newStoreClock measures the store clock once and returns a clock following it
+func newStoreClock(l *limiterImpl) (*storeClock, error) {
+	timer, ok := l.store.(storeTimer)
+	if !ok {
+		if adapter, isAdapter := l.store.(*storeAdapter); isAdapter {
+			timer, ok = adapter.store.(storeTimer)
+		}
+	}
+	if !ok {
+		return nil, errors.New("store clock requested but the store does not report its time")
+	}
+
+	sc := &storeClock{
+		timer:    timer,
+		local:    l.config.Now,
+		interval: l.config.ClockSyncInterval,
+		limiter:  l,
+		stop:     make(chan struct{}),
+	}
+	if sc.interval <= 0 {
+		sc.interval = DefaultClockSyncInterval
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), sc.interval)
+	defer cancel()
+	if err := sc.sync(ctx); err != nil {
+		return nil, err
+	}
+	return sc, nil
+}
+
+// now returns the local time corrected by the last measured offset
+func (sc *storeClock) now() time.Time {
+	return sc.local().Add(time.Duration(sc.offset.Load()))
+}
+
+// sync measures the offset to the store clock
+func (sc *storeClock) sync(ctx context.Context) error {
+	before := sc.local()
+	storeTime, err := sc.timer.Time(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to read store clock: %w", err)
+	}
+	roundTrip := sc.local().Sub(before)
+
+	// The store read its clock about halfway through the round trip
+	sc.offset.Store(int64(storeTime.Sub(before.Add(roundTrip / 2))))
+	return nil
+}
+
+// start begins measuring the offset periodically
+func (sc *storeClock) start() {
+	sc.done.Add(1)
+	go sc.run()
+}
+
+// run re-measures the offset until stopped; failed measurements keep the last offset
+func (sc *storeClock) run() {
+	defer sc.done.Done()
+
+	ticker := time.NewTicker(sc.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-sc.stop:
+			return
+		case <-ticker.C:
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), sc.interval)
+		if err := sc.sync(ctx); err != nil {
+			sc.limiter.reportError(err)
+		}
+		cancel()
+	}
+}
+
+// close stops measuring the offset
+func (sc *storeClock) close() {
+	close(sc.stop)
+	sc.done.Wait()
+}
+
+// ClockOffset returns how far the store clock is ahead of the local clock, or 0 with the local clock source
+func (l *limiterImpl) ClockOffset() time.Duration {
+	if l.clock == nil {
+		return 0
+	}
+	return time.Duration(l.clock.offset.Load())
+}
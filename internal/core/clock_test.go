@@ -0,0 +1,69 @@
+// internal/core/clock_test.go
+package core
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+// timedStore reports a store clock running ahead of the instance clock
+type timedStore struct {
+	Store
+	now func() time.Time
+}
+
+func (s *timedStore) Time(ctx context.Context) (time.Time, error) {
+	return s.now(), nil
+}
+
+func TestStoreClock(t *testing.T) {
+	ctx := context.Background()
+	local := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	store := &timedStore{
+		Store: newTestStore(t),
+		now:   func() time.Time { return local.Add(time.Hour) },
+	}
+
+	limiter := newTestLimiter(t, &Config{
+		Algorithm:   "sliding_window",
+		Limits:      map[string]string{"global": "10/minute"},
+		ClockSource: ClockSourceStore,
+		Clock:       func() time.Time { return local },
+	}, store)
+
+	if offset := limiter.ClockOffset(); offset != time.Hour {
+		t.Fatalf("Expected a clock offset of 1h, got %v", offset)
+	}
+
+	// Windows are evaluated on the store clock
+	result, err := limiter.Check(ctx, "user-1", "global")
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if want := local.Add(time.Hour + time.Minute); !result.ResetTime.Equal(want) {
+		t.Errorf("Expected reset at %v, got %v", want, result.ResetTime)
+	}
+}
+
+func TestStoreClockUnsupported(t *testing.T) {
+	_, err := NewLimiterWithStore(&Config{
+		Algorithm:   "sliding_window",
+		Limits:      map[string]string{"global": "10/minute"},
+		ClockSource: ClockSourceStore,
+	}, newTestStore(t))
+	if err == nil {
+		t.Fatal("Expected an error for a store without a clock")
+	}
+
+	config := &Config{
+		Store:       "memory",
+		Algorithm:   "sliding_window",
+		Limits:      map[string]string{"global": "10/minute"},
+		ClockSource: "ntp",
+	}
+	if err := config.Validate(); err == nil || !strings.Contains(err.Error(), "clock source") {
+		t.Errorf("Expected an unsupported clock source to be rejected, got %v", err)
+	}
+}
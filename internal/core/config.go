@@ -2,37 +2,269 @@
 package core
 
 import (
+	"database/sql"
 	"errors"
+	"fmt"
 	"net/http"
+	"strings"
 	"time"
+
+	"github.com/itsatony/gorly/algorithms"
+	"github.com/itsatony/gorly/stores"
 )
 
 // Config holds the configuration for a rate limiter
 type Config struct {
 	// Store configuration
-	Store     string // "memory" or "redis"
-	Algorithm string // "token_bucket", "sliding_window", "gcra"
+	Store     string // "memory", "redis" or "postgres"
+	Algorithm string // "token_bucket", "sliding_window", "fixed_window", "gcra" or a name given to RegisterAlgorithm
+
+	// WindowAlignment decides where fixed windows start: AlignWallClock (default) or AlignFirstRequest
+	WindowAlignment string
+
+	// ScopeAlignments align the windows of scopes other than the algorithm does: scope ->
+	// AlignRolling, AlignWallClock or AlignFirstRequest. Scopes with aligned windows count
+	// in fixed windows, rolling scopes of a fixed window limiter in sliding windows.
+	ScopeAlignments map[string]string
 
 	// Redis configuration
 	RedisAddress  string
 	RedisPassword string
 	RedisDB       int
 	RedisPoolSize int
+	RedisShards   []string // Standalone instances for client-side sharding
+
+	// RedisShardFailurePolicy decides what happens to the keys of a dead shard: "failover"
+	// (default) moves them to the next healthy shard, "fail_closed" fails their checks
+	RedisShardFailurePolicy string
+
+	// RedisSecrets fetches the Redis credentials at runtime, ahead of RedisPassword, and
+	// reconnects when they rotate
+	RedisSecrets stores.SecretsProvider
+
+	// RedisTLS connects to Redis over TLS, e.g. to ElastiCache with in-transit encryption
+	RedisTLS bool
+
+	// RedisLazyConnect builds the limiter without connecting to Redis; the first check
+	// connects. Cold starts of serverless functions then do not wait for the connection.
+	RedisLazyConnect bool
+
+	// RedisReadReplica serves stats and inspection reads; checks always use the primary
+	RedisReadReplica string
+
+	// RedisStandby is a warm standby the limiter fails over to while the primary is down:
+	// "memory" or the address of a second Redis
+	RedisStandby string
+
+	// RedisLocalCache keeps hot state in process memory and syncs it with Redis at this
+	// interval, trading accuracy across instances for checks without a round trip; 0 disables
+	RedisLocalCache time.Duration
+
+	// Postgres configuration. PostgresDB is an open pool used instead of connecting to
+	// PostgresDSN; the table is created on startup if missing.
+	PostgresDSN    string
+	PostgresDriver string // database/sql driver name; default: "pgx" or "postgres", whichever is registered
+	PostgresTable  string // Default: stores.DefaultPostgresTable
+	PostgresDB     *sql.DB
+
+	// ScopeStores keeps the limit state of scopes in another store than Store: scope -> "memory"
+	// or "redis" (the Redis at RedisAddress). Coordination state such as leases stays in Store.
+	ScopeStores map[string]string
 
 	// Rate limits
 	Limits     map[string]string            // scope -> limit (e.g., "global" -> "1000/hour")
 	TierLimits map[string]map[string]string // scope -> tier -> limit
 
+	// Overrides set the limits of single entities, ahead of their tier and scope limits
+	Overrides []Override
+
+	// DeniedScopes are scope name patterns in path.Match syntax (e.g. "debug*") that no limit,
+	// override or other setting may name, at build time or in runtime updates. Names starting
+	// with ReservedScopePrefix are always denied.
+	DeniedScopes []string
+
+	// ScopeEnforcement sets the enforcement mode of scopes: scope -> EnforcementEnforce (default),
+	// EnforcementShadow or EnforcementOff
+	ScopeEnforcement map[string]string
+
+	// LimitTransition decides how limits that shrink in runtime updates take effect:
+	// TransitionImmediate (default), TransitionDrain or TransitionRamp over LimitRampDuration
+	LimitTransition   string
+	LimitRampDuration time.Duration // Default: DefaultLimitRampDuration
+
+	// OnLimitTransition is called when an update starts a transition, and on the request path
+	// for every entity admitted over its new limit only because of the transition
+	OnLimitTransition func(LimitTransitionEvent)
+
+	// StoreRetries retries and hedges the store reads of checks (nil disables)
+	StoreRetries *StoreRetryConfig
+
+	// What checks do while the store fails: FailClosed (default) returns the error, so the
+	// middleware rejects the request, FailOpen lets the request through and FailLocal
+	// limits it on an in-memory store of this instance
+	FailurePolicy        string            // Default for every scope
+	ScopeFailurePolicies map[string]string // scope -> FailOpen, FailClosed or FailLocal, ahead of FailurePolicy
+
+	// Entities claiming a tier that no scope configures
+	UnknownTierPolicy string // UnknownTierDefault (default), UnknownTierFallback or UnknownTierDeny
+	FallbackTier      string // Tier whose limits apply to unknown tiers under UnknownTierFallback
+
+	// Tiers looked up server-side instead of taken from the entity's "tier:" prefix
+	TierResolver TierResolver
+	TierCacheTTL time.Duration // How long a resolved tier is fresh (default: 1m)
+	TierStaleTTL time.Duration // How long a stale tier is served while refreshed (default: 5m, negative: never)
+
+	// Bandwidth limits count response bytes instead of requests
+	BandwidthLimits     map[string]string // scope -> volume (e.g., "download" -> "500MB/hour")
+	BandwidthFlushBytes int64             // Bytes buffered before charging the store (default: 64KB)
+
+	// Token budgets count model tokens reported by the application (e.g. LLM usage)
+	TokenBudgets map[string]string                                              // scope -> budget (e.g., "global" -> "5000000/month")
+	TokenCost    func(model string, promptTokens, completionTokens int64) int64 // Converts usage into budget units (default: sum)
+
+	// Time zones of calendar windows: day, week and month budgets reset at local midnight,
+	// on Monday and on the first of the month instead of at UTC multiples of the window
+	ScopeTimeZones map[string]string          // scope -> IANA zone (e.g. "billing" -> "America/New_York"); also applies to ScopeResets
+	TimeZoneFunc   func(entity string) string // Per-tenant zone, e.g. the customer's billing zone; "" falls back to the scope's
+
+	// Pre-authentication limit, evaluated before the entity is extracted
+	PreAuthLimit     string                     // e.g. "300/minute"; empty disables the pre-auth phase
+	PreAuthScope     string                     // Scope for pre-auth counters (default: "preauth")
+	PreAuthExtractor func(*http.Request) string // Cheap key for the pre-auth phase, usually the client IP
+
+	// Authentication abuse protection
+	Lockout          *LockoutConfig                                // Exponential lockout after repeated failures (nil disables)
+	ChallengeHandler func(http.ResponseWriter, *http.Request) bool // CAPTCHA hook; returns false after writing a challenge response
+
+	// Trusted internal calls, which lift the limits of single requests with a signed header
+	TrustedCalls *TrustedCallConfig
+
+	// Token introspection: requests are identified by the subject of their bearer token or API key
+	Introspection *IntrospectionConfig
+
+	// Bot handling
+	BotClassifier func(*http.Request) bool // Reports whether a request looks automated
+	BotScope      string                   // Scope for requests the classifier flags
+
 	// Extractor functions
-	ExtractorFunc func(*http.Request) string // Extract entity from request
-	ScopeFunc     func(*http.Request) string // Extract scope from request
+	ExtractorFunc     func(*http.Request) string // Extract entity from request
+	EmptyEntityPolicy string                     // Handling of requests without an entity (default: EmptyEntityShared)
+	ScopeFunc         func(*http.Request) string // Extract scope from request
+	VersionFunc       func(*http.Request) string // Extract API version; scopes become "version:scope" (e.g. "v1:search")
 
 	// Event handlers
 	ErrorHandler  func(error)                                           // Handle errors
 	DeniedHandler func(http.ResponseWriter, *http.Request, *CoreResult) // Handle denied requests
 
+	// OnOverrideExpired is called from a background goroutine for each entity override removed at its expiry
+	OnOverrideExpired func(Override)
+
+	// Keyspace expiry telemetry: with a Redis store, the limiter subscribes to the notifications
+	// of its keys expiring and counts them in KeyExpiryStats, for capacity planning and for
+	// verifying that state is reaped
+	WatchKeyExpiry                 bool
+	ConfigureKeyspaceNotifications bool             // Enable the notifications with CONFIG SET at startup
+	OnKeyExpired                   func(key string) // Called from a background goroutine for each expired key
+
+	// OnForget receives the report of every Forget call, with the error it returned
+	OnForget func(ForgetReport, error)
+	// SubjectID turns a forgotten entity into the subject recorded in reports (default: SHA256Subject)
+	SubjectID func(entity string) string
+
+	// Method handling
+	ExemptMethods   []string          // HTTP methods that never consume quota (e.g. "HEAD")
+	ExemptPreflight bool              // Skip CORS preflight (OPTIONS with Access-Control-Request-Method)
+	Probes          *ProbeConfig      // Skip health probes such as Kubernetes' kube-probe (nil disables)
+	MethodScopes    map[string]string // HTTP method -> scope, e.g. "OPTIONS" -> "preflight"
+	MethodScoping   bool              // Qualify scopes with the request method, e.g. "search:GET"
+
+	// Denial responses
+	DeniedStatusCode       int            // Status for denied requests (default: 429)
+	ScopeDeniedStatusCodes map[string]int // Per-scope status overrides (e.g. 503 for overload scopes)
+
+	// Response headers
+	HeaderMode       string            // Rate limit headers sent: HeaderModeFull (default), HeaderModeMinimal or HeaderModeNone
+	ScopeHeaderModes map[string]string // Per-scope header mode overrides
+	HeaderNames      map[string]string // Header renames, e.g. "X-RateLimit-Limit" -> "RateLimit-Limit"; "" drops a header
+
+	// Maintenance mode responses
+	MaintenanceStatusCode int           // Status for requests blocked by maintenance mode (default: 503)
+	MaintenanceMessage    string        // Message in the maintenance response body
+	MaintenanceRetryAfter time.Duration // Retry-After during maintenance (default: 5m)
+
+	// Request costs: route pattern -> units consumed, e.g. "POST /v1/export" -> 10 (default: 1)
+	Costs map[string]int64
+
+	// Scale multiplies every configured limit (default: 1; adjustable at runtime)
+	Scale float64
+
+	// Scheduled resets: scope -> cron expression (e.g. "daily-report" -> "0 0 * * *", UTC unless ScopeTimeZones names a zone)
+	ScopeResets       map[string]string
+	ResetPollInterval time.Duration // How often instances check schedules and pick up resets (default: 1s)
+
+	// Leader election for singleton jobs
+	LeaderLeaseTTL time.Duration // How long a leader keeps its lease without a heartbeat (default: 15s)
+	InstanceID     string        // Identifies this instance as lease holder (default: hostname, PID and random suffix)
+
+	// Local cache of denials for entities far over their limit
+	DenialCache          bool          // Opt in to the denial cache
+	DenialCacheThreshold time.Duration // Only denials retrying at least this far out are cached (default: 1s)
+	DenialCacheTTL       time.Duration // Longest a denial is served without asking the store (default: 5s)
+	DenialCacheSize      int           // Maximum cached entity and scope pairs (default: 10000)
+
+	// Temporary budgets added to single entities with Grant
+	Grants bool // Opt in to grants; checks then read the entity's grant from the store
+
+	// Shared stats counters, counted locally and written to the store in the background
+	StatsWriteBehind   bool          // Opt in to store-backed stats counters
+	StatsFlushInterval time.Duration // Longest counts stay local before a flush (default: 1s)
+	StatsFlushEvents   int           // Pending requests that trigger an early flush (default: 1000)
+
+	// Local usage stats
+	MaxTrackedEntities int // Entities broken down in usage stats, least recently seen dropped first (default: 1000; negative disables)
+
+	// Store keys
+	KeyPrefix    string // Prefix of every store key (default: "ratelimit")
+	MaxKeyLength int    // Keys longer than this are hashed (default: 256)
+
+	// Input validation for entity and scope values
+	MaxEntityLength    int  // Longer entities are shortened with a digest suffix (default: 256)
+	MaxScopeLength     int  // Longer unconfigured scopes are shortened likewise (default: 64)
+	RejectInvalidInput bool // Return an *InputError instead of normalizing
+	MaxScopes          int  // Distinct unconfigured scopes before the rest share OverflowScope (default: 1000; negative disables)
+
 	// Features
 	MetricsEnabled bool
+
+	// Entities whose state is created when the limiter is built
+	Prewarm []PrewarmSpec
+
+	// Clock skew between instances sharing a store
+	ClockSource        string        // ClockSourceLocal (default) or ClockSourceStore to follow the store's clock
+	ClockSyncInterval  time.Duration // How often the store clock offset is measured (default: 10s)
+	ClockSkewTolerance time.Duration // Largest lead of another instance's clock the sliding window adopts (default: 0)
+
+	// Compression of the serialized token bucket and sliding window state (default: none).
+	// Compressed state is read and written with Get/Set instead of store scripts.
+	StateCompression algorithms.CompressionConfig
+
+	// Clock is the time source of algorithms and local caches (default: time.Now).
+	// Simulations substitute a virtual clock.
+	Clock func() time.Time
+
+	// live holds the limits in effect once a limiter is built; they change at runtime
+	live *liveLimits
+
+	// scopes tracks the unconfigured scopes seen, nil until a limiter is built or when MaxScopes is negative
+	scopes *scopeBudget
+}
+
+// Now returns the current time of the configured clock
+func (c *Config) Now() time.Time {
+	if c.Clock != nil {
+		return c.Clock()
+	}
+	return time.Now()
 }
 
 // CoreResult represents the result of a rate limit check
@@ -44,29 +276,348 @@ type CoreResult struct {
 	RetryAfter time.Duration
 	Window     time.Duration
 	ResetTime  time.Time
+
+	// Maintenance is set when the request was blocked by maintenance mode
+	Maintenance bool
+
+	// Cached is set when the denial was served from the local denial cache
+	Cached bool
+
+	// UnknownTier is the tier the entity claimed when no scope configures it
+	UnknownTier string
+
+	// GrantRemaining is what is left of the entity's grant; included in Remaining
+	GrantRemaining int64
+
+	// Enforcement is the scope's mode when it is not enforced: EnforcementShadow or EnforcementOff
+	Enforcement string
+
+	// ShadowDenied is set when shadow mode let through a request the limit denies
+	ShadowDenied bool
+
+	// FailedOpen is set when the store failed and FailOpen let the request through
+	FailedOpen bool
+
+	// Degraded is set when the store failed and FailLocal decided the request on the
+	// in-memory fallback store
+	Degraded bool
+
+	// Stale is set when a peek was answered by the read replica, which may lag behind the primary
+	Stale bool
+
+	// Alignment is the window alignment of the scope: AlignRolling, AlignWallClock or AlignFirstRequest
+	Alignment string
+}
+
+// Limit sources reported in EffectiveLimit
+const (
+	LimitSourceTier    = "tier"     // Tier-specific limit for the scope
+	LimitSourceScope   = "scope"    // Scope limit shared by all tiers
+	LimitSourceGlobal  = "global"   // Global limit applied to an unconfigured scope
+	LimitSourcePreAuth = "pre_auth" // Pre-authentication limit
+)
+
+// Window alignments of the fixed window algorithm
+const (
+	AlignWallClock    = "wall_clock"    // Windows start at multiples of their length since the Unix epoch
+	AlignFirstRequest = "first_request" // An entity's window starts with its first request
+)
+
+// DefaultPreAuthScope is the scope used for pre-authentication counters
+const DefaultPreAuthScope = "preauth"
+
+// EffectiveLimit describes the limit that applies to an entity for one scope
+type EffectiveLimit struct {
+	Scope    string
+	Tier     string
+	Rate     string
+	Requests int64
+	Window   time.Duration
+	Source   string
+}
+
+// IsExempt reports whether a request should bypass rate limiting because of its method
+func (c *Config) IsExempt(r *http.Request) bool {
+	if c.ExemptPreflight && r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+		return true
+	}
+	for _, method := range c.ExemptMethods {
+		if strings.EqualFold(method, r.Method) {
+			return true
+		}
+	}
+	return false
+}
+
+// HasPreAuth reports whether a pre-authentication limit is configured
+func (c *Config) HasPreAuth() bool {
+	return c.PreAuthLimit != ""
+}
+
+// PreAuthScopeName returns the scope used for pre-authentication counters
+func (c *Config) PreAuthScopeName() string {
+	if c.PreAuthScope != "" {
+		return c.PreAuthScope
+	}
+	return DefaultPreAuthScope
+}
+
+// VersionedScope combines an API version and a resource scope, e.g. "v1" and "search" into "v1:search"
+func VersionedScope(version, scope string) string {
+	return version + ":" + scope
+}
+
+// SplitVersionedScope splits a scope like "v1:search" into its version and resource scope
+func SplitVersionedScope(scope string) (string, string, bool) {
+	version, base, ok := strings.Cut(scope, ":")
+	if !ok || !IsVersion(version) {
+		return "", scope, false
+	}
+	return version, base, true
+}
+
+// NormalizeVersion turns "2", "V2" or "v2" into "v2" and returns "" for anything that isn't a version
+func NormalizeVersion(version string) string {
+	version = strings.ToLower(strings.Trim(strings.TrimSpace(version), `"`))
+	if version != "" && version[0] >= '0' && version[0] <= '9' {
+		version = "v" + version
+	}
+	if !IsVersion(version) {
+		return ""
+	}
+	return version
+}
+
+// IsVersion reports whether s is a normalized API version such as "v1" or "v2.1"
+func IsVersion(s string) bool {
+	if len(s) < 2 || s[0] != 'v' || s[1] < '0' || s[1] > '9' {
+		return false
+	}
+	for _, c := range s[2:] {
+		if (c < '0' || c > '9') && c != '.' {
+			return false
+		}
+	}
+	return true
+}
+
+// DeniedStatus returns the HTTP status to use when a request in scope is denied
+func (c *Config) DeniedStatus(scope string) int {
+	if code, ok := c.ScopeDeniedStatusCodes[scope]; ok {
+		return code
+	}
+	if c.DeniedStatusCode != 0 {
+		return c.DeniedStatusCode
+	}
+	return http.StatusTooManyRequests
 }
 
 // Validate checks if the configuration is valid
 func (c *Config) Validate() error {
-	if c.Store != "memory" && c.Store != "redis" {
-		return errors.New("store must be 'memory' or 'redis'")
+	if c.Store != "memory" && c.Store != "redis" && c.Store != "postgres" {
+		return errors.New("store must be 'memory', 'redis' or 'postgres'")
 	}
 
 	if c.Store == "redis" && c.RedisAddress == "" {
 		return errors.New("redis address is required when using redis store")
 	}
 
-	if c.Algorithm != "token_bucket" && c.Algorithm != "sliding_window" && c.Algorithm != "gcra" {
-		return errors.New("algorithm must be 'token_bucket', 'sliding_window', or 'gcra'")
+	if c.Store == "postgres" && c.PostgresDSN == "" && c.PostgresDB == nil {
+		return errors.New("postgres dsn is required when using postgres store")
+	}
+
+	if c.RedisReadReplica != "" && (c.Store != "redis" || len(c.RedisShards) > 0) {
+		return errors.New("a read replica requires a single redis primary")
+	}
+
+	if c.RedisStandby != "" && (c.Store != "redis" || len(c.RedisShards) > 0) {
+		return errors.New("a standby requires a single redis primary")
+	}
+
+	if c.RedisLocalCache < 0 || (c.RedisLocalCache > 0 && c.Store != "redis") {
+		return errors.New("a local cache requires a redis store and a positive sync interval")
+	}
+
+	if c.WatchKeyExpiry && c.Store != "redis" {
+		return errors.New("key expiry telemetry requires a redis store")
+	}
+
+	for scope, store := range c.ScopeStores {
+		if store != "memory" && store != "redis" {
+			return fmt.Errorf("store of scope %s must be 'memory' or 'redis', got %q", scope, store)
+		}
+		if store == "redis" && c.RedisAddress == "" {
+			return fmt.Errorf("redis address is required for the store of scope %s", scope)
+		}
+	}
+
+	if !IsAlgorithm(c.Algorithm) {
+		return errors.New("algorithm must be 'token_bucket', 'sliding_window', 'fixed_window', 'gcra' or registered with RegisterAlgorithm")
+	}
+	switch c.WindowAlignment {
+	case "", AlignWallClock, AlignFirstRequest:
+	default:
+		return fmt.Errorf("window alignment must be %q or %q, got %q", AlignWallClock, AlignFirstRequest, c.WindowAlignment)
+	}
+	if err := c.validateAlignments(); err != nil {
+		return err
+	}
+	if err := c.validateTransition(); err != nil {
+		return err
+	}
+
+	for scope, expr := range c.ScopeResets {
+		if _, err := ParseSchedule(expr); err != nil {
+			return fmt.Errorf("invalid reset schedule for scope %s: %w", scope, err)
+		}
+	}
+
+	if c.DenialCacheThreshold < 0 || c.DenialCacheTTL < 0 || c.DenialCacheSize < 0 {
+		return errors.New("denial cache settings cannot be negative")
+	}
+
+	if err := c.validateTimeZones(); err != nil {
+		return err
+	}
+
+	if err := c.validateTiers(); err != nil {
+		return err
+	}
+	if err := validateOverrides(c.Overrides); err != nil {
+		return err
+	}
+	if err := validateEnforcement(c.ScopeEnforcement); err != nil {
+		return err
+	}
+	if err := c.validateScopeNames(); err != nil {
+		return err
+	}
+	if err := c.validateFailurePolicies(); err != nil {
+		return err
+	}
+
+	switch c.ClockSource {
+	case "", ClockSourceLocal, ClockSourceStore:
+	default:
+		return fmt.Errorf("unsupported clock source: %s", c.ClockSource)
+	}
+	if c.ClockSyncInterval < 0 || c.ClockSkewTolerance < 0 {
+		return errors.New("clock sync interval and skew tolerance cannot be negative")
 	}
 
-	if len(c.Limits) == 0 && len(c.TierLimits) == 0 {
+	switch c.StateCompression.Algorithm {
+	case "", algorithms.CompressionNone, algorithms.CompressionSnappy, algorithms.CompressionZstd:
+	default:
+		return fmt.Errorf("unsupported state compression: %s", c.StateCompression.Algorithm)
+	}
+	if c.StateCompression.Threshold < 0 {
+		return errors.New("state compression threshold cannot be negative")
+	}
+
+	if c.LeaderLeaseTTL < 0 {
+		return errors.New("leader lease TTL cannot be negative")
+	}
+
+	if c.MaxEntityLength < 0 || c.MaxScopeLength < 0 {
+		return errors.New("max entity and scope lengths cannot be negative")
+	}
+
+	if c.MaxKeyLength != 0 && c.MaxKeyLength < MinMaxKeyLength+len(c.KeyPrefix) {
+		return fmt.Errorf("max key length must be at least %d, got %d", MinMaxKeyLength+len(c.KeyPrefix), c.MaxKeyLength)
+	}
+
+	for scope, budget := range c.TokenBudgets {
+		if _, _, err := parseLimit(budget); err != nil {
+			return fmt.Errorf("invalid token budget for scope %s: %w", scope, err)
+		}
+	}
+
+	if len(c.Limits) == 0 && len(c.TierLimits) == 0 && len(c.BandwidthLimits) == 0 {
 		return errors.New("at least one rate limit must be configured")
 	}
 
+	for scope, limit := range c.BandwidthLimits {
+		if _, _, err := parseBandwidth(limit); err != nil {
+			return fmt.Errorf("invalid bandwidth limit for scope %s: %w", scope, err)
+		}
+	}
+
 	if c.ExtractorFunc == nil {
 		return errors.New("extractor function is required")
 	}
+	if err := c.validateEmptyEntityPolicy(); err != nil {
+		return err
+	}
+	if err := c.validateHeaders(); err != nil {
+		return err
+	}
+	if err := ValidateCosts(c.Costs); err != nil {
+		return err
+	}
+
+	if c.HasPreAuth() {
+		if _, _, err := parseLimit(c.PreAuthLimit); err != nil {
+			return fmt.Errorf("invalid pre-auth limit: %w", err)
+		}
+		if c.PreAuthExtractor == nil {
+			return errors.New("pre-auth extractor function is required when a pre-auth limit is set")
+		}
+		if _, ok := c.Limits[c.PreAuthScopeName()]; ok {
+			return fmt.Errorf("pre-auth scope %s must not also be configured as a regular scope", c.PreAuthScopeName())
+		}
+	}
+
+	if c.StoreRetries != nil {
+		if err := c.StoreRetries.validate(); err != nil {
+			return err
+		}
+	}
+
+	if c.Probes != nil {
+		if err := c.Probes.validate(); err != nil {
+			return err
+		}
+	}
+
+	if c.TrustedCalls != nil {
+		if err := c.TrustedCalls.validate(); err != nil {
+			return err
+		}
+	}
+
+	if c.Introspection != nil {
+		if err := c.Introspection.validate(); err != nil {
+			return err
+		}
+	}
+
+	if c.BotClassifier != nil && c.BotScope == "" {
+		return errors.New("bot scope is required when a bot classifier is set")
+	}
+
+	if c.Scale != 0 {
+		if err := validateScale(c.Scale); err != nil {
+			return err
+		}
+	}
+
+	if c.Lockout != nil {
+		if err := c.Lockout.validate(); err != nil {
+			return err
+		}
+	}
+
+	if c.DeniedStatusCode != 0 && (c.DeniedStatusCode < 400 || c.DeniedStatusCode > 599) {
+		return fmt.Errorf("denied status code must be 4xx or 5xx, got %d", c.DeniedStatusCode)
+	}
+	if c.MaintenanceStatusCode != 0 && (c.MaintenanceStatusCode < 400 || c.MaintenanceStatusCode > 599) {
+		return fmt.Errorf("maintenance status code must be 4xx or 5xx, got %d", c.MaintenanceStatusCode)
+	}
+	for scope, code := range c.ScopeDeniedStatusCodes {
+		if code < 400 || code > 599 {
+			return fmt.Errorf("denied status code for scope %s must be 4xx or 5xx, got %d", scope, code)
+		}
+	}
 
 	return nil
 }
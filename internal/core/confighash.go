@@ -0,0 +1,151 @@
+// internal/core/confighash.go
+package core
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+)
+
+// configHashLength is the number of hex digits of a config hash
+const configHashLength = 16
+
+// configFingerprint is the effective configuration a config hash is computed over: the
+// settings that decide how requests are limited. Connection details, credentials, the
+// instance ID, intervals of background work and functions are left out, as they may
+// differ between identical instances or cannot be compared. Settings are hashed as
+// configured, so a setting left at zero and one set to its default hash differently.
+// JSON encodes map keys in sorted order, which makes the encoding deterministic.
+type configFingerprint struct {
+	Store      string                         `json:"store"`
+	Algorithm  string                         `json:"algorithm"`
+	Alignment  string                         `json:"window_alignment"`
+	Limits     map[string]string              `json:"limits"`
+	TierLimits map[string]map[string]string   `json:"tier_limits"`
+	Overrides  map[string]map[string]Override `json:"overrides"`
+	Modes      map[string]string              `json:"modes"`
+	Scale      float64                        `json:"scale"`
+
+	ScopeStores          map[string]string `json:"scope_stores"`
+	DeniedScopes         []string          `json:"denied_scopes"`
+	FailurePolicy        string            `json:"failure_policy"`
+	ScopeFailurePolicies map[string]string `json:"scope_failure_policies"`
+	UnknownTierPolicy    string            `json:"unknown_tier_policy"`
+	FallbackTier         string            `json:"fallback_tier"`
+	BandwidthLimits      map[string]string `json:"bandwidth_limits"`
+	TokenBudgets         map[string]string `json:"token_budgets"`
+	ScopeTimeZones       map[string]string `json:"scope_time_zones"`
+	ScopeResets          map[string]string `json:"scope_resets"`
+	PreAuthLimit         string            `json:"preauth_limit"`
+	PreAuthScope         string            `json:"preauth_scope"`
+	BotScope             string            `json:"bot_scope"`
+	EmptyEntityPolicy    string            `json:"empty_entity_policy"`
+	ExemptMethods        []string          `json:"exempt_methods"`
+	ExemptPreflight      bool              `json:"exempt_preflight"`
+	MethodScopes         map[string]string `json:"method_scopes"`
+	MethodScoping        bool              `json:"method_scoping"`
+	Costs                map[string]int64  `json:"costs"`
+	DeniedStatusCode     int               `json:"denied_status_code"`
+	ScopeDeniedCodes     map[string]int    `json:"scope_denied_status_codes"`
+	HeaderMode           string            `json:"header_mode"`
+	ScopeHeaderModes     map[string]string `json:"scope_header_modes"`
+	HeaderNames          map[string]string `json:"header_names"`
+	KeyPrefix            string            `json:"key_prefix"`
+	MaxKeyLength         int               `json:"max_key_length"`
+	MaxEntityLength      int               `json:"max_entity_length"`
+	MaxScopeLength       int               `json:"max_scope_length"`
+	RejectInvalidInput   bool              `json:"reject_invalid_input"`
+	MaxScopes            int               `json:"max_scopes"`
+	DenialCache          bool              `json:"denial_cache"`
+	DenialCacheThreshold time.Duration     `json:"denial_cache_threshold"`
+	DenialCacheTTL       time.Duration     `json:"denial_cache_ttl"`
+	Grants               bool              `json:"grants"`
+	ClockSource          string            `json:"clock_source"`
+	ClockSkewTolerance   time.Duration     `json:"clock_skew_tolerance"`
+	LimitTransition      string            `json:"limit_transition,omitempty"`
+	LimitRampDuration    time.Duration     `json:"limit_ramp_duration,omitempty"`
+	ScopeAlignments      map[string]string `json:"scope_alignments,omitempty"`
+}
+
+// tableHash is the config hash computed for a limit table
+type tableHash struct {
+	table *limitTable
+	hash  string
+}
+
+// fingerprint returns the effective configuration of a config with the limits of table.
+// The generation and version label of the table are left out: generations count from 1
+// on every instance, and the same limits may be applied under different labels.
+func (c *Config) fingerprint(table *limitTable) configFingerprint {
+	return configFingerprint{
+		Store:      c.Store,
+		Algorithm:  c.Algorithm,
+		Alignment:  c.WindowAlignment,
+		Limits:     table.limits,
+		TierLimits: table.tierLimits,
+		Overrides:  table.overrides,
+		Modes:      table.modes,
+		Scale:      table.scale,
+
+		ScopeStores:          c.ScopeStores,
+		DeniedScopes:         c.DeniedScopes,
+		FailurePolicy:        c.FailurePolicy,
+		ScopeFailurePolicies: c.ScopeFailurePolicies,
+		UnknownTierPolicy:    c.UnknownTierPolicy,
+		FallbackTier:         c.FallbackTier,
+		BandwidthLimits:      c.BandwidthLimits,
+		TokenBudgets:         c.TokenBudgets,
+		ScopeTimeZones:       c.ScopeTimeZones,
+		ScopeResets:          c.ScopeResets,
+		PreAuthLimit:         c.PreAuthLimit,
+		PreAuthScope:         c.PreAuthScope,
+		BotScope:             c.BotScope,
+		EmptyEntityPolicy:    c.EmptyEntityPolicy,
+		ExemptMethods:        c.ExemptMethods,
+		ExemptPreflight:      c.ExemptPreflight,
+		MethodScopes:         c.MethodScopes,
+		MethodScoping:        c.MethodScoping,
+		Costs:                c.Costs,
+		DeniedStatusCode:     c.DeniedStatusCode,
+		ScopeDeniedCodes:     c.ScopeDeniedStatusCodes,
+		HeaderMode:           c.HeaderMode,
+		ScopeHeaderModes:     c.ScopeHeaderModes,
+		HeaderNames:          c.HeaderNames,
+		KeyPrefix:            c.KeyPrefix,
+		MaxKeyLength:         c.MaxKeyLength,
+		MaxEntityLength:      c.MaxEntityLength,
+		MaxScopeLength:       c.MaxScopeLength,
+		RejectInvalidInput:   c.RejectInvalidInput,
+		MaxScopes:            c.MaxScopes,
+		DenialCache:          c.DenialCache,
+		DenialCacheThreshold: c.DenialCacheThreshold,
+		DenialCacheTTL:       c.DenialCacheTTL,
+		Grants:               c.Grants,
+		ClockSource:          c.ClockSource,
+		ClockSkewTolerance:   c.ClockSkewTolerance,
+		LimitTransition:      c.LimitTransition,
+		LimitRampDuration:    c.LimitRampDuration,
+		ScopeAlignments:      c.ScopeAlignments,
+	}
+}
+
+// HashConfig returns a hash of encoded configuration data, in the form of ConfigHash
+func HashConfig(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])[:configHashLength]
+}
+
+// ConfigHash returns a stable hash of the effective configuration: limiters configured
+// alike return the same hash whatever the order they were configured or updated in, so
+// instances whose hashes differ have drifted apart. The hash follows runtime limit updates.
+func (l *limiterImpl) ConfigHash() string {
+	table := l.limitTable()
+	if cached := l.configHash.Load(); cached != nil && cached.table == table {
+		return cached.hash
+	}
+	data, _ := json.Marshal(l.config.fingerprint(table)) // Every field of the fingerprint encodes
+	hash := HashConfig(data)
+	l.configHash.Store(&tableHash{table: table, hash: hash})
+	return hash
+}
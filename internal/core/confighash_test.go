@@ -0,0 +1,37 @@
+// internal/core/confighash_test.go
+package core
+
+import (
+	"testing"
+)
+
+func TestConfigHash(t *testing.T) {
+	newLimiter := func(limits map[string]string) Limiter {
+		t.Helper()
+		return newTestLimiter(t, &Config{Limits: limits}, nil)
+	}
+
+	first := newLimiter(map[string]string{"global": "100/minute", "search": "10/minute"})
+	second := newLimiter(map[string]string{"search": "10/minute", "global": "100/minute"})
+	hash := first.ConfigHash()
+	if len(hash) != configHashLength || second.ConfigHash() != hash {
+		t.Fatalf("Expected identical configs to hash alike, got %q and %q", hash, second.ConfigHash())
+	}
+	if other := newLimiter(map[string]string{"global": "100/minute", "search": "20/minute"}); other.ConfigHash() == hash {
+		t.Error("Expected a different limit to change the hash")
+	}
+
+	// Updates change the hash; the generation and version label do not
+	if err := first.UpdateLimits(LimitUpdate{Scale: 2}); err != nil {
+		t.Fatalf("UpdateLimits failed: %v", err)
+	}
+	if first.ConfigHash() == hash {
+		t.Error("Expected a scale update to change the hash")
+	}
+	if err := first.UpdateLimits(LimitUpdate{Scale: 1, Version: "v2"}); err != nil {
+		t.Fatalf("UpdateLimits failed: %v", err)
+	}
+	if got := first.ConfigHash(); got != hash {
+		t.Errorf("Expected the original hash after reverting the update, got %q, want %q", got, hash)
+	}
+}
@@ -0,0 +1,35 @@
+// internal/core/configsnapshot.go
+package core
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/itsatony/gorly/stores"
+)
+
+// configSnapshotKey is the store key of the configuration shared between instances
+func (c *Config) configSnapshotKey() string {
+	return c.keys().Build("config", "snapshot")
+}
+
+// SaveConfigSnapshot stores an encoded configuration for instances that start later. The
+// snapshot never expires; each save replaces the previous one.
+func (l *limiterImpl) SaveConfigSnapshot(ctx context.Context, data []byte) error {
+	if err := l.store.Set(ctx, l.config.configSnapshotKey(), data, 0); err != nil {
+		return fmt.Errorf("failed to save config snapshot: %w", err)
+	}
+	return nil
+}
+
+// LoadConfigSnapshot returns the stored configuration, or nil if no instance saved one
+func (l *limiterImpl) LoadConfigSnapshot(ctx context.Context) ([]byte, error) {
+	data, err := l.store.Get(ctx, l.config.configSnapshotKey())
+	if err != nil {
+		if stores.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to load config snapshot: %w", err)
+	}
+	return data, nil
+}
@@ -0,0 +1,29 @@
+// internal/core/configsnapshot_test.go
+package core
+
+import (
+	"context"
+	"testing"
+)
+
+func TestConfigSnapshot(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStore(t)
+	newInstance := func() Limiter {
+		t.Helper()
+		return newTestLimiter(t, &Config{Limits: map[string]string{"global": "2/minute"}}, store)
+	}
+
+	first := newInstance()
+	if data, err := first.LoadConfigSnapshot(ctx); err != nil || data != nil {
+		t.Fatalf("Expected no snapshot in an empty store, got %q (%v)", data, err)
+	}
+	if err := first.SaveConfigSnapshot(ctx, []byte(`{"version":"v2"}`)); err != nil {
+		t.Fatalf("Failed to save snapshot: %v", err)
+	}
+
+	// Instances sharing the store see the snapshot
+	if data, err := newInstance().LoadConfigSnapshot(ctx); err != nil || string(data) != `{"version":"v2"}` {
+		t.Errorf("Expected the saved snapshot, got %q (%v)", data, err)
+	}
+}
@@ -0,0 +1,34 @@
+// internal/core/context.go
+package core
+
+import "context"
+
+// contextKey is the type of values stored in a context by the limiter
+type contextKey int
+
+const (
+	entityOverrideKey contextKey = iota
+	scopeOverrideKey
+)
+
+// WithEntity returns a context whose checks are attributed to entity
+func WithEntity(ctx context.Context, entity string) context.Context {
+	return context.WithValue(ctx, entityOverrideKey, entity)
+}
+
+// WithScope returns a context whose checks are charged to scope
+func WithScope(ctx context.Context, scope string) context.Context {
+	return context.WithValue(ctx, scopeOverrideKey, scope)
+}
+
+// EntityFromContext returns the entity override stored in ctx, if any
+func EntityFromContext(ctx context.Context) (string, bool) {
+	entity, ok := ctx.Value(entityOverrideKey).(string)
+	return entity, ok && entity != ""
+}
+
+// ScopeFromContext returns the scope override stored in ctx, if any
+func ScopeFromContext(ctx context.Context) (string, bool) {
+	scope, ok := ctx.Value(scopeOverrideKey).(string)
+	return scope, ok && scope != ""
+}
@@ -0,0 +1,110 @@
+// internal/core/costs.go
+package core
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DefaultRequestCost is the cost of requests no cost table entry matches
+const DefaultRequestCost = 1
+
+// costTable maps route patterns to request costs.
+//
+// Patterns are "METHOD /path" or "/path" for every method. A trailing "*" matches
+// any path with that prefix. Exact paths win over prefixes, longer prefixes over
+// shorter ones, and method-specific patterns over method-less ones.
+type costTable struct {
+	exact    map[string]int64 // "METHOD /path" or " /path" -> cost
+	prefixes []costPrefix     // Longest first
+}
+
+// costPrefix is a prefix pattern of a cost table
+type costPrefix struct {
+	method string // "" for every method
+	prefix string
+	cost   int64
+}
+
+// newCostTable parses a cost table
+func newCostTable(costs map[string]int64) (*costTable, error) {
+	table := &costTable{exact: make(map[string]int64, len(costs))}
+
+	for pattern, cost := range costs {
+		if cost < 1 {
+			return nil, fmt.Errorf("cost of %q must be at least 1, got %d", pattern, cost)
+		}
+		method, path, err := parseCostPattern(pattern)
+		if err != nil {
+			return nil, err
+		}
+		if prefix, ok := strings.CutSuffix(path, "*"); ok {
+			table.prefixes = append(table.prefixes, costPrefix{method: method, prefix: prefix, cost: cost})
+			continue
+		}
+		table.exact[method+" "+path] = cost
+	}
+
+	sort.Slice(table.prefixes, func(i, j int) bool {
+		a, b := table.prefixes[i], table.prefixes[j]
+		if len(a.prefix) != len(b.prefix) {
+			return len(a.prefix) > len(b.prefix)
+		}
+		return a.method > b.method // Method-specific before method-less
+	})
+	return table, nil
+}
+
+// parseCostPattern splits "POST /v1/export" into its method and path
+func parseCostPattern(pattern string) (string, string, error) {
+	fields := strings.Fields(pattern)
+	switch {
+	case len(fields) == 1 && strings.HasPrefix(fields[0], "/"):
+		return "", fields[0], nil
+	case len(fields) == 2 && strings.HasPrefix(fields[1], "/"):
+		return strings.ToUpper(fields[0]), fields[1], nil
+	default:
+		return "", "", fmt.Errorf("invalid cost pattern %q: expected \"METHOD /path\" or \"/path\"", pattern)
+	}
+}
+
+// cost returns the cost of a request
+func (t *costTable) cost(method, path string) int64 {
+	if t == nil {
+		return DefaultRequestCost
+	}
+	if cost, ok := t.exact[method+" "+path]; ok {
+		return cost
+	}
+	if cost, ok := t.exact[" "+path]; ok {
+		return cost
+	}
+	for _, p := range t.prefixes {
+		if (p.method == "" || p.method == method) && strings.HasPrefix(path, p.prefix) {
+			return p.cost
+		}
+	}
+	return DefaultRequestCost
+}
+
+// ValidateCosts checks the patterns and costs of a cost table
+func ValidateCosts(costs map[string]int64) error {
+	_, err := newCostTable(costs)
+	return err
+}
+
+// SetCosts replaces the cost table at runtime; an empty table charges every request 1
+func (l *limiterImpl) SetCosts(costs map[string]int64) error {
+	table, err := newCostTable(costs)
+	if err != nil {
+		return err
+	}
+	l.costs.Store(table)
+	return nil
+}
+
+// RequestCost returns how much quota a request with the given method and path consumes
+func (l *limiterImpl) RequestCost(method, path string) int64 {
+	return l.costs.Load().cost(method, path)
+}
@@ -0,0 +1,79 @@
+// internal/core/costs_test.go
+package core
+
+import (
+	"context"
+	"testing"
+)
+
+func TestCostTable(t *testing.T) {
+	table, err := newCostTable(map[string]int64{
+		"POST /v1/export":   10,
+		"/v1/export":        2,
+		"GET /v1/items":     1,
+		"/v1/reports/*":     5,
+		"GET /v1/reports/*": 3,
+		"/v1/reports/big*":  20,
+	})
+	if err != nil {
+		t.Fatalf("Failed to parse cost table: %v", err)
+	}
+
+	tests := []struct {
+		method, path string
+		want         int64
+	}{
+		{"POST", "/v1/export", 10},
+		{"GET", "/v1/export", 2},
+		{"GET", "/v1/items", 1},
+		{"POST", "/v1/reports/daily", 5},
+		{"GET", "/v1/reports/daily", 3},
+		{"GET", "/v1/reports/big-one", 20},
+		{"GET", "/health", DefaultRequestCost},
+	}
+	for _, tt := range tests {
+		if got := table.cost(tt.method, tt.path); got != tt.want {
+			t.Errorf("%s %s: expected cost %d, got %d", tt.method, tt.path, tt.want, got)
+		}
+	}
+
+	for _, invalid := range []map[string]int64{
+		{"POST /v1/export": 0},
+		{"v1/export": 2},
+		{"POST /v1 extra": 2},
+	} {
+		if err := ValidateCosts(invalid); err == nil {
+			t.Errorf("Expected %v to be rejected", invalid)
+		}
+	}
+}
+
+func TestCheckN(t *testing.T) {
+	limiter := newTestLimiter(t, &Config{
+		Algorithm: "sliding_window",
+		Limits:    map[string]string{"global": "10/minute"},
+		Costs:     map[string]int64{"POST /v1/export": 6},
+	}, nil)
+	ctx := context.Background()
+
+	cost := limiter.RequestCost("POST", "/v1/export")
+	if result, err := limiter.CheckN(ctx, "user-1", "global", cost); err != nil || !result.Allowed || result.Remaining != 4 {
+		t.Fatalf("Expected the first export to consume 6 units, got %+v (%v)", result, err)
+	}
+	if result, _ := limiter.CheckN(ctx, "user-1", "global", cost); result.Allowed {
+		t.Error("Expected the second export to exceed the limit")
+	}
+	if result, _ := limiter.Check(ctx, "user-1", "global"); !result.Allowed {
+		t.Error("Expected cheaper requests to use the remaining quota")
+	}
+
+	if err := limiter.SetCosts(map[string]int64{"POST /v1/export": 2}); err != nil {
+		t.Fatalf("Failed to replace costs: %v", err)
+	}
+	if cost := limiter.RequestCost("POST", "/v1/export"); cost != 2 {
+		t.Errorf("Expected the replaced cost, got %d", cost)
+	}
+	if _, err := limiter.CheckN(ctx, "user-1", "global", 0); err == nil {
+		t.Error("Expected a zero cost to be rejected")
+	}
+}
@@ -0,0 +1,133 @@
+// internal/core/customalgorithms.go
+package core
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+)
+
+// AlgorithmFactory creates an algorithm registered with RegisterAlgorithm, once per limiter
+type AlgorithmFactory func() Algorithm
+
+// registeredAlgorithms holds the factories of custom algorithms, keyed by name
+var registeredAlgorithms sync.Map
+
+// algorithmName matches names usable as the algorithm part of store keys
+var algorithmName = regexp.MustCompile(`^[a-z][a-z0-9_-]*$`)
+
+// builtinAlgorithms are the algorithms of this package, which cannot be replaced
+var builtinAlgorithms = map[string]bool{"token_bucket": true, "sliding_window": true, "fixed_window": true, "gcra": true}
+
+// RegisterAlgorithm makes an algorithm selectable by name. Registering a name again
+// replaces its factory for limiters built afterwards.
+func RegisterAlgorithm(name string, factory AlgorithmFactory) error {
+	switch {
+	case !algorithmName.MatchString(name):
+		return fmt.Errorf("algorithm name %q must be lowercase letters, digits, '_' and '-', starting with a letter", name)
+	case builtinAlgorithms[name]:
+		return fmt.Errorf("algorithm %s is built in and cannot be replaced", name)
+	case factory == nil:
+		return fmt.Errorf("algorithm %s needs a factory", name)
+	}
+	registeredAlgorithms.Store(name, factory)
+	return nil
+}
+
+// IsAlgorithm reports whether name is a built-in or registered algorithm
+func IsAlgorithm(name string) bool {
+	if builtinAlgorithms[name] {
+		return true
+	}
+	_, ok := registeredAlgorithms.Load(name)
+	return ok
+}
+
+// RegisteredAlgorithms returns the names of the registered custom algorithms, sorted
+func RegisteredAlgorithms() []string {
+	var names []string
+	registeredAlgorithms.Range(func(name, _ interface{}) bool {
+		names = append(names, name.(string))
+		return true
+	})
+	sort.Strings(names)
+	return names
+}
+
+// RegisteredAlgorithm returns the factory of a custom algorithm
+func RegisteredAlgorithm(name string) (AlgorithmFactory, bool) {
+	factory, ok := registeredAlgorithms.Load(name)
+	if !ok {
+		return nil, false
+	}
+	return factory.(AlgorithmFactory), true
+}
+
+// newCustomAlgorithm creates a registered algorithm
+func newCustomAlgorithm(name string) (Algorithm, error) {
+	factory, ok := RegisteredAlgorithm(name)
+	if !ok {
+		return nil, fmt.Errorf("unsupported algorithm: %s", name)
+	}
+	algorithm := factory()
+	if algorithm == nil {
+		return nil, fmt.Errorf("factory of algorithm %s returned nil", name)
+	}
+	return &namedAlgorithm{Algorithm: algorithm, name: name}, nil
+}
+
+// namedAlgorithm keys the state of a custom algorithm by its registered name, so the keys
+// of two limiters selecting one algorithm never depend on what its Name method returns
+type namedAlgorithm struct {
+	Algorithm
+	name string
+}
+
+func (a *namedAlgorithm) Name() string {
+	return a.name
+}
+
+// ValidateLimit forwards to the wrapped algorithm, if it validates limits
+func (a *namedAlgorithm) ValidateLimit(limit int64, window time.Duration) error {
+	if validator, ok := a.Algorithm.(limitValidator); ok {
+		return validator.ValidateLimit(limit, window)
+	}
+	return nil
+}
+
+// limitValidator is implemented by algorithms that cannot enforce every limit, e.g. an
+// algorithm with a minimum window or one keeping a log of at most so many requests
+type limitValidator interface {
+	ValidateLimit(limit int64, window time.Duration) error
+}
+
+// validateAlgorithmLimits asks the algorithm whether it can enforce the given limits.
+// Built-in algorithms enforce any parsable limit.
+func (l *limiterImpl) validateAlgorithmLimits(limits map[string]string, tierLimits map[string]map[string]string) error {
+	validator, ok := l.algorithm.(limitValidator)
+	if !ok {
+		return nil
+	}
+	validate := func(limit string) error {
+		requests, window, err := parseLimit(limit)
+		if err != nil {
+			return err
+		}
+		return validator.ValidateLimit(requests, window)
+	}
+	for scope, limit := range limits {
+		if err := validate(limit); err != nil {
+			return fmt.Errorf("algorithm %s rejects limit %s of scope %s: %w", l.algorithm.Name(), limit, scope, err)
+		}
+	}
+	for scope, tiers := range tierLimits {
+		for tier, limit := range tiers {
+			if err := validate(limit); err != nil {
+				return fmt.Errorf("algorithm %s rejects %s tier limit %s of scope %s: %w", l.algorithm.Name(), tier, limit, scope, err)
+			}
+		}
+	}
+	return nil
+}
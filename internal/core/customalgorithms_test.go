@@ -0,0 +1,62 @@
+// internal/core/customalgorithms_test.go
+package core
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// cappedAlgorithm wraps a built-in algorithm and rejects limits above its cap
+type cappedAlgorithm struct {
+	Algorithm
+	cap int64
+}
+
+func (c *cappedAlgorithm) ValidateLimit(limit int64, window time.Duration) error {
+	if limit > c.cap {
+		return errors.New("limit too high")
+	}
+	return nil
+}
+
+func TestRegisterAlgorithm(t *testing.T) {
+	factory := func() Algorithm {
+		fixedWindow, _ := newAlgorithm(&Config{}, "fixed_window", "", time.Now)
+		return &cappedAlgorithm{Algorithm: fixedWindow, cap: 10}
+	}
+	for _, name := range []string{"", "Capped", "capped:v2", "fixed_window"} {
+		if err := RegisterAlgorithm(name, factory); err == nil {
+			t.Errorf("Expected name %q to be rejected", name)
+		}
+	}
+	if err := RegisterAlgorithm("capped", nil); err == nil {
+		t.Error("Expected a nil factory to be rejected")
+	}
+	if err := RegisterAlgorithm("capped", factory); err != nil {
+		t.Fatalf("Failed to register: %v", err)
+	}
+	if !IsAlgorithm("capped") || IsAlgorithm("uncapped") {
+		t.Error("Expected registered names to be algorithms")
+	}
+
+	store := newTestStore(t)
+	limiter, err := newLimiter(&Config{Store: "memory", Algorithm: "capped", Limits: map[string]string{"global": "5/minute"}}, store, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create limiter: %v", err)
+	}
+	defer limiter.Close()
+	if _, err := limiter.Check(context.Background(), "user-1", "global"); err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if key := limiter.(*limiterImpl).requestKey("user-1", "global"); !strings.Contains(key, ":capped:") {
+		t.Errorf("Expected the registered name in the key, got %s", key)
+	}
+
+	err = limiter.UpdateLimits(LimitUpdate{TierLimits: map[string]map[string]string{"global": {"pro": "50/minute"}}})
+	if err == nil || !strings.Contains(err.Error(), "rejects pro tier limit 50/minute of scope global") {
+		t.Errorf("Expected the update to be rejected, got %v", err)
+	}
+}
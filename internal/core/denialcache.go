@@ -0,0 +1,145 @@
+// internal/core/denialcache.go
+package core
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Denial cache defaults
+const (
+	DefaultDenialCacheThreshold = time.Second
+	DefaultDenialCacheTTL       = 5 * time.Second
+	DefaultDenialCacheSize      = 10000
+)
+
+// denialCache remembers recent denials locally so that entities far over their
+// limit are rejected without evaluating the algorithm against the store.
+//
+// A denial is only cached when its RetryAfter is at least the threshold, and is
+// served no longer than its RetryAfter or the TTL, whichever is shorter. Denied
+// requests consume no quota, so a cached denial matches what the store would
+// have answered unless limits change, which clears the cache. Scheduled resets
+// change the request key, so they are never hidden by cached denials.
+type denialCache struct {
+	threshold time.Duration
+	ttl       time.Duration
+	size      int
+	now       func() time.Time
+
+	mu      sync.RWMutex
+	entries map[string]denialEntry
+	hits    atomic.Int64
+}
+
+// denialEntry is a cached denial and the time it stops being served
+type denialEntry struct {
+	until  time.Time
+	result CoreResult
+}
+
+// newDenialCache creates the denial cache of a config, or returns nil when it is disabled
+func newDenialCache(config *Config) *denialCache {
+	if !config.DenialCache {
+		return nil
+	}
+
+	dc := &denialCache{
+		threshold: config.DenialCacheThreshold,
+		ttl:       config.DenialCacheTTL,
+		size:      config.DenialCacheSize,
+		now:       config.Now,
+		entries:   make(map[string]denialEntry),
+	}
+	if dc.threshold <= 0 {
+		dc.threshold = DefaultDenialCacheThreshold
+	}
+	if dc.ttl <= 0 {
+		dc.ttl = DefaultDenialCacheTTL
+	}
+	if dc.size <= 0 {
+		dc.size = DefaultDenialCacheSize
+	}
+	return dc
+}
+
+// get returns the cached denial for a request key, or nil if none is current
+func (dc *denialCache) get(key string) *CoreResult {
+	dc.mu.RLock()
+	entry, ok := dc.entries[key]
+	dc.mu.RUnlock()
+	if !ok {
+		return nil
+	}
+
+	remaining := entry.until.Sub(dc.now())
+	if remaining <= 0 {
+		return nil
+	}
+
+	dc.hits.Add(1)
+	result := entry.result
+	result.RetryAfter = remaining
+	result.Cached = true
+	return &result
+}
+
+// put caches a denial whose retry time is far enough away
+func (dc *denialCache) put(key string, result *CoreResult) {
+	if result.Allowed || result.RetryAfter < dc.threshold {
+		return
+	}
+
+	now := dc.now()
+	lifetime := result.RetryAfter
+	if lifetime > dc.ttl {
+		lifetime = dc.ttl
+	}
+
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+
+	if _, exists := dc.entries[key]; !exists && len(dc.entries) >= dc.size {
+		for k, entry := range dc.entries {
+			if !entry.until.After(now) {
+				delete(dc.entries, k)
+			}
+		}
+		if len(dc.entries) >= dc.size {
+			return // Full of current denials; this one is simply not cached
+		}
+	}
+
+	dc.entries[key] = denialEntry{until: now.Add(lifetime), result: *result}
+}
+
+// clear drops every cached denial
+func (dc *denialCache) clear() {
+	dc.mu.Lock()
+	dc.entries = make(map[string]denialEntry)
+	dc.mu.Unlock()
+}
+
+// forget drops the cached denials of request keys and returns how many were dropped
+func (dc *denialCache) forget(keys []string) int {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+
+	dropped := 0
+	for _, key := range keys {
+		if _, ok := dc.entries[key]; ok {
+			delete(dc.entries, key)
+			dropped++
+		}
+	}
+	return dropped
+}
+
+// DenialCacheHits returns how many checks were answered from the denial cache
+func (l *limiterImpl) DenialCacheHits() int64 {
+	if l.denials == nil {
+		return 0
+	}
+	return l.denials.hits.Load()
+}
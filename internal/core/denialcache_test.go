@@ -0,0 +1,103 @@
+// internal/core/denialcache_test.go
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestDenialCacheBounds(t *testing.T) {
+	now := time.Date(2024, 3, 16, 12, 0, 0, 0, time.UTC)
+	dc := newDenialCache(&Config{DenialCache: true, DenialCacheThreshold: 2 * time.Second, DenialCacheTTL: 10 * time.Second, DenialCacheSize: 2})
+	dc.now = func() time.Time { return now }
+
+	// Allowed results and denials retrying soon are not cached
+	dc.put("allowed", &CoreResult{Allowed: true, RetryAfter: time.Minute})
+	dc.put("soon", &CoreResult{RetryAfter: time.Second})
+	if dc.get("allowed") != nil || dc.get("soon") != nil {
+		t.Fatal("Expected only denials beyond the threshold to be cached")
+	}
+
+	dc.put("short", &CoreResult{RetryAfter: 4 * time.Second, Limit: 5})
+	dc.put("long", &CoreResult{RetryAfter: time.Hour, Limit: 5})
+
+	now = now.Add(3 * time.Second)
+	if cached := dc.get("short"); cached == nil || !cached.Cached || cached.RetryAfter != time.Second || cached.Limit != 5 {
+		t.Errorf("Expected cached denial with the remaining retry time, got %+v", cached)
+	}
+
+	// Never served past the retry time or the TTL
+	now = now.Add(2 * time.Second)
+	if dc.get("short") != nil {
+		t.Error("Expected denial to expire at its retry time")
+	}
+	if cached := dc.get("long"); cached == nil || cached.RetryAfter != 5*time.Second {
+		t.Errorf("Expected long denial to be bounded by the TTL, got %+v", cached)
+	}
+	now = now.Add(5 * time.Second)
+	if dc.get("long") != nil {
+		t.Error("Expected denial to expire after the TTL")
+	}
+
+	if dc.hits.Load() != 2 {
+		t.Errorf("Expected 2 hits, got %d", dc.hits.Load())
+	}
+}
+
+func TestDenialCacheSize(t *testing.T) {
+	now := time.Date(2024, 3, 16, 12, 0, 0, 0, time.UTC)
+	dc := newDenialCache(&Config{DenialCache: true, DenialCacheSize: 2})
+	dc.now = func() time.Time { return now }
+
+	dc.put("a", &CoreResult{RetryAfter: time.Minute})
+	dc.put("b", &CoreResult{RetryAfter: 2 * time.Second})
+	dc.put("c", &CoreResult{RetryAfter: time.Minute})
+	if dc.get("c") != nil {
+		t.Error("Expected a full cache to skip new denials")
+	}
+
+	// Expired entries make room
+	now = now.Add(3 * time.Second)
+	dc.put("c", &CoreResult{RetryAfter: time.Minute})
+	if dc.get("c") == nil {
+		t.Error("Expected expired entries to be pruned for new denials")
+	}
+	if len(dc.entries) != 2 {
+		t.Errorf("Expected the cache to stay within its size, got %d entries", len(dc.entries))
+	}
+}
+
+func TestDenialCacheInLimiter(t *testing.T) {
+	l := newTestLimiter(t, &Config{
+		Algorithm:            "sliding_window",
+		Limits:               map[string]string{"global": "2/hour"},
+		DenialCache:          true,
+		DenialCacheThreshold: time.Second,
+	}, nil)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		l.Check(ctx, "user-1", "global")
+	}
+	result, err := l.Check(ctx, "user-1", "global")
+	if err != nil || result.Allowed || !result.Cached {
+		t.Fatalf("Expected a cached denial, got %+v (%v)", result, err)
+	}
+	if l.DenialCacheHits() != 1 {
+		t.Errorf("Expected 1 cache hit, got %d", l.DenialCacheHits())
+	}
+
+	// Other entities are unaffected
+	if result, _ := l.Check(ctx, "user-2", "global"); !result.Allowed {
+		t.Error("Expected other entities to be allowed")
+	}
+
+	// Raising limits invalidates cached denials
+	if err := l.SetScale(2); err != nil {
+		t.Fatalf("SetScale failed: %v", err)
+	}
+	if result, _ := l.Check(ctx, "user-1", "global"); !result.Allowed || result.Cached {
+		t.Errorf("Expected scaled limits to be evaluated against the store, got %+v", result)
+	}
+}
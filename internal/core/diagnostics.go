@@ -0,0 +1,81 @@
+// internal/core/diagnostics.go
+package core
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/itsatony/gorly/algorithms"
+)
+
+// Diagnostics is the algorithm state behind the limit of an entity and scope.
+// Only the fields of the configured algorithm are set.
+type Diagnostics struct {
+	Entity    string
+	Scope     string
+	Algorithm string
+	Limit     int64
+	Window    time.Duration
+	Source    string // LimitSourceOverride, LimitSourceTier, LimitSourceScope or LimitSourceGlobal
+	Stale     bool   // Read from the read replica, which may lag behind the primary
+
+	Bucket        *algorithms.TokenBucketMetrics // Token bucket state
+	SlidingWindow *algorithms.WindowMetrics      // Sliding window state
+	Pattern       *algorithms.RequestPattern     // Request timing within the sliding window
+}
+
+// algorithmInspector is implemented by algorithms that report their state for diagnostics
+type algorithmInspector interface {
+	Diagnose(ctx context.Context, store Store, key string, limit int64, window time.Duration, d *Diagnostics) error
+}
+
+// Diagnose fills in the state of the wrapped algorithm
+func (a *algorithmAdapter) Diagnose(ctx context.Context, store Store, key string, limit int64, window time.Duration, d *Diagnostics) error {
+	algStore := &algorithmStoreAdapter{store}
+
+	var err error
+	switch algorithm := a.algorithm.(type) {
+	case *algorithms.TokenBucketAlgorithm:
+		d.Bucket, err = algorithm.GetMetrics(ctx, algStore, key, limit, window)
+	case *algorithms.SlidingWindowAlgorithm:
+		if d.SlidingWindow, err = algorithm.GetMetrics(ctx, algStore, key, limit, window); err != nil {
+			return err
+		}
+		d.Pattern, err = algorithm.GetRequestPattern(ctx, algStore, key, limit, window)
+	}
+	return err
+}
+
+// Diagnostics reports the algorithm state of an entity and scope without consuming quota
+func (l *limiterImpl) Diagnostics(ctx context.Context, entity, scope string) (*Diagnostics, error) {
+	entity, scope, err := l.sanitize(entity, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	table := l.limitTable()
+	tier := l.entityTier(ctx, entity)
+	limit, window, err := l.getLimit(table, entity, tier, scope)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get limit: %w", err)
+	}
+	_, source := l.resolveLimit(table, entity, tier, scope)
+
+	d := &Diagnostics{
+		Entity:    entity,
+		Scope:     scope,
+		Algorithm: l.algorithmFor(scope).Name(),
+		Limit:     limit,
+		Window:    window,
+		Source:    source,
+	}
+	if inspector, ok := l.algorithmFor(scope).(algorithmInspector); ok {
+		store, stale := l.readStore(scope)
+		d.Stale = stale
+		if err := inspector.Diagnose(ctx, store, l.requestKey(entity, scope), limit, window, d); err != nil {
+			return nil, fmt.Errorf("failed to read algorithm state: %w", err)
+		}
+	}
+	return d, nil
+}
@@ -0,0 +1,43 @@
+// internal/core/emptyentity.go
+package core
+
+import "fmt"
+
+// Policies for requests whose extractor returns no entity
+const (
+	EmptyEntityShared = "shared" // All such requests share the AnonymousEntity bucket (default)
+	EmptyEntityIP     = "ip"     // Requests are limited by their connection address
+	EmptyEntityDeny   = "deny"   // Requests are rejected with 401
+	EmptyEntitySkip   = "skip"   // Requests are not limited
+)
+
+// AnonymousEntity is the entity shared by requests without one under EmptyEntityShared
+const AnonymousEntity = "anonymous"
+
+// EmptyEntityPolicyName returns the configured empty entity policy, or the default
+func (c *Config) EmptyEntityPolicyName() string {
+	if c.EmptyEntityPolicy == "" {
+		return EmptyEntityShared
+	}
+	return c.EmptyEntityPolicy
+}
+
+// validateEmptyEntityPolicy checks that the empty entity policy is known
+func (c *Config) validateEmptyEntityPolicy() error {
+	switch c.EmptyEntityPolicyName() {
+	case EmptyEntityShared, EmptyEntityIP, EmptyEntityDeny, EmptyEntitySkip:
+		return nil
+	default:
+		return fmt.Errorf("unknown empty entity policy: %s", c.EmptyEntityPolicy)
+	}
+}
+
+// CountEmptyEntity records a request whose extractor returned no entity
+func (l *limiterImpl) CountEmptyEntity() {
+	l.emptyEntities.Add(1)
+}
+
+// EmptyEntities returns how many requests had no entity
+func (l *limiterImpl) EmptyEntities() int64 {
+	return l.emptyEntities.Load()
+}
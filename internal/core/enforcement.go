@@ -0,0 +1,97 @@
+// internal/core/enforcement.go
+package core
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Enforcement modes of a scope
+const (
+	EnforcementEnforce = "enforce" // Deny requests over the limit (default)
+	EnforcementShadow  = "shadow"  // Count requests over the limit and report them, but let them through
+	EnforcementOff     = "off"     // Let every request through without evaluating the limit
+)
+
+// validateEnforcement checks that every scope has a known enforcement mode
+func validateEnforcement(modes map[string]string) error {
+	for scope, mode := range modes {
+		switch mode {
+		case EnforcementEnforce, EnforcementShadow, EnforcementOff:
+		default:
+			return fmt.Errorf("unknown enforcement mode %q for scope %s: expected enforce, shadow or off", mode, scope)
+		}
+	}
+	return nil
+}
+
+// enforcement returns the mode of a scope in the table
+func (t *limitTable) enforcement(scope string) string {
+	if mode, ok := t.modes[scope]; ok {
+		return mode
+	}
+	return EnforcementEnforce
+}
+
+// scopeCounter counts events per scope, such as requests shadow mode let through
+type scopeCounter struct {
+	scopes sync.Map // scope -> *atomic.Int64
+}
+
+// add counts one event in scope
+func (sc *scopeCounter) add(scope string) {
+	counter, ok := sc.scopes.Load(scope)
+	if !ok {
+		counter, _ = sc.scopes.LoadOrStore(scope, new(atomic.Int64))
+	}
+	counter.(*atomic.Int64).Add(1)
+}
+
+// snapshot returns the counts of every scope with events
+func (sc *scopeCounter) snapshot() map[string]int64 {
+	counts := make(map[string]int64)
+	sc.scopes.Range(func(scope, counter any) bool {
+		counts[scope.(string)] = counter.(*atomic.Int64).Load()
+		return true
+	})
+	return counts
+}
+
+// enforce applies the enforcement mode of a scope to the result of its limit. Shadow
+// mode turns a denial into an allowed request marked ShadowDenied and counts it.
+func (l *limiterImpl) enforce(mode, scope string, result *CoreResult, count bool) {
+	if mode == EnforcementEnforce {
+		return
+	}
+	result.Enforcement = mode
+	if mode == EnforcementShadow && !result.Allowed {
+		result.Allowed = true
+		result.ShadowDenied = true
+		if count {
+			l.shadowDenials.add(scope)
+		}
+	}
+}
+
+// unenforced is the result of a check in a scope whose enforcement is off
+func unenforced(limit int64, window time.Duration) *CoreResult {
+	return &CoreResult{
+		Allowed:     true,
+		Remaining:   limit,
+		Limit:       limit,
+		Window:      window,
+		Enforcement: EnforcementOff,
+	}
+}
+
+// EnforcementModes returns the scopes whose enforcement mode was configured
+func (l *limiterImpl) EnforcementModes() map[string]string {
+	return copyLimits(l.limitTable().modes)
+}
+
+// ShadowDenials returns how many requests shadow mode let through, per scope
+func (l *limiterImpl) ShadowDenials() map[string]int64 {
+	return l.shadowDenials.snapshot()
+}
@@ -0,0 +1,69 @@
+// internal/core/enforcement_test.go
+package core
+
+import (
+	"context"
+	"testing"
+)
+
+func TestScopeEnforcement(t *testing.T) {
+	ctx := context.Background()
+	config := &Config{
+		Algorithm:        "sliding_window",
+		Limits:           map[string]string{"global": "1/minute", "search": "1/minute", "beta": "1/minute"},
+		ScopeEnforcement: map[string]string{"search": EnforcementShadow, "beta": EnforcementOff},
+		DenialCache:      true,
+	}
+	if err := validateEnforcement(config.ScopeEnforcement); err != nil {
+		t.Fatalf("Invalid config: %v", err)
+	}
+	l := newTestLimiter(t, config, nil)
+
+	check := func(scope string) *CoreResult {
+		t.Helper()
+		result, err := l.Check(ctx, "user-1", scope)
+		if err != nil {
+			t.Fatalf("Check in %s failed: %v", scope, err)
+		}
+		return result
+	}
+
+	check("global")
+	if result := check("global"); result.Allowed || result.Enforcement != "" {
+		t.Errorf("Expected the enforced scope to deny, got %+v", result)
+	}
+
+	check("search")
+	for i := 0; i < 2; i++ {
+		if result := check("search"); !result.Allowed || !result.ShadowDenied || result.Enforcement != EnforcementShadow {
+			t.Errorf("Expected the shadow scope to let the request through, got %+v", result)
+		}
+	}
+	if peek, _ := l.Peek(ctx, "user-1", "search"); !peek.Allowed || !peek.ShadowDenied {
+		t.Errorf("Expected Peek to report the shadow denial, got %+v", peek)
+	}
+
+	for i := 0; i < 3; i++ {
+		if result := check("beta"); !result.Allowed || result.Enforcement != EnforcementOff || result.Remaining != 1 {
+			t.Errorf("Expected the scope without enforcement to skip its limit, got %+v", result)
+		}
+	}
+
+	if denials := l.ShadowDenials(); denials["search"] != 2 || len(denials) != 1 {
+		t.Errorf("Expected 2 shadow denials in search, got %v", denials)
+	}
+
+	// Promoting the scope to enforce takes effect immediately
+	if err := l.UpdateLimits(LimitUpdate{Modes: map[string]string{"beta": EnforcementShadow}}); err != nil {
+		t.Fatalf("UpdateLimits failed: %v", err)
+	}
+	if result := check("search"); result.Allowed {
+		t.Errorf("Expected search to be enforced once dropped from the modes, got %+v", result)
+	}
+	if modes := l.EnforcementModes(); len(modes) != 1 || modes["beta"] != EnforcementShadow {
+		t.Errorf("Expected only beta in shadow mode, got %v", modes)
+	}
+	if err := l.UpdateLimits(LimitUpdate{Modes: map[string]string{"beta": "log"}}); err == nil {
+		t.Error("Expected an unknown mode to be rejected")
+	}
+}
@@ -0,0 +1,159 @@
+// internal/core/failurepolicy.go
+package core
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/itsatony/gorly/stores"
+)
+
+// Failure policies decide what a check does while the store cannot answer it
+const (
+	FailClosed = "closed" // Return the store error; the middleware rejects the request (default)
+	FailOpen   = "open"   // Let the request through without a limit and report the error
+	FailLocal  = "local"  // Limit the request on this instance's in-memory fallback store and report the error
+)
+
+// validateFailurePolicies checks the default and per-scope failure policies
+func (c *Config) validateFailurePolicies() error {
+	switch c.FailurePolicy {
+	case "", FailClosed, FailOpen, FailLocal:
+	default:
+		return fmt.Errorf("unknown failure policy %q: expected open, closed or local", c.FailurePolicy)
+	}
+	for scope, policy := range c.ScopeFailurePolicies {
+		switch policy {
+		case FailClosed, FailOpen, FailLocal:
+		default:
+			return fmt.Errorf("unknown failure policy %q for scope %s: expected open, closed or local", policy, scope)
+		}
+	}
+	return nil
+}
+
+// FailurePolicyFor returns the failure policy of a scope: its own, else the default, else FailClosed
+func (c *Config) FailurePolicyFor(scope string) string {
+	if policy, ok := c.ScopeFailurePolicies[scope]; ok {
+		return policy
+	}
+	return c.defaultFailurePolicy()
+}
+
+// defaultFailurePolicy returns the failure policy of scopes without their own
+func (c *Config) defaultFailurePolicy() string {
+	if c.FailurePolicy == "" {
+		return FailClosed
+	}
+	return c.FailurePolicy
+}
+
+// failOpen returns the result letting a request through a scope whose store check failed,
+// or nil if the scope fails closed. The error goes to the error handler, since the
+// caller never sees it. Checks without a request to replay, such as bandwidth and token
+// budgets, are let through under FailLocal too.
+func (l *limiterImpl) failOpen(scope string, limit int64, window time.Duration, err error) *CoreResult {
+	if l.config.FailurePolicyFor(scope) == FailClosed {
+		l.failedClosed.add(scope)
+		return nil
+	}
+	l.failedOpen.add(scope)
+	l.reportError(fmt.Errorf("store failed, letting request through scope %s: %w", scope, err))
+	return &CoreResult{
+		Allowed:    true,
+		Remaining:  limit,
+		Limit:      limit,
+		Window:     window,
+		FailedOpen: true,
+	}
+}
+
+// storeFailed returns the result of a request check the store failed, by the scope's
+// failure policy: nil under FailClosed, an unlimited result under FailOpen and under
+// FailLocal the decision of the scope's algorithm on the fallback store. The fallback
+// counts only this instance's requests, each instance allowing the full limit.
+func (l *limiterImpl) storeFailed(ctx context.Context, scope, key string, limit int64, window time.Duration, n int64, err error) *CoreResult {
+	if l.config.FailurePolicyFor(scope) != FailLocal {
+		return l.failOpen(scope, limit, window, err)
+	}
+	store, fallbackErr := l.fallback.get()
+	if fallbackErr != nil {
+		return l.failOpen(scope, limit, window, fmt.Errorf("%w (no fallback store: %v)", err, fallbackErr))
+	}
+	algResult, fallbackErr := l.algorithmFor(scope).Allow(ctx, store, key, limit, window, n)
+	if fallbackErr != nil {
+		return l.failOpen(scope, limit, window, fmt.Errorf("%w (fallback check failed: %v)", err, fallbackErr))
+	}
+	if algResult.Allowed {
+		l.fallbackAllowed.add(scope)
+	} else {
+		l.fallbackDenied.add(scope)
+	}
+	l.reportError(fmt.Errorf("store failed, limiting request to scope %s locally: %w", scope, err))
+	return &CoreResult{
+		Allowed:    algResult.Allowed,
+		Remaining:  algResult.Remaining,
+		Limit:      algResult.Limit,
+		Used:       algResult.Used,
+		RetryAfter: algResult.RetryAfter,
+		Window:     algResult.Window,
+		ResetTime:  algResult.ResetTime,
+		Alignment:  l.alignmentFor(scope),
+		Degraded:   true,
+	}
+}
+
+// fallbackStore is the in-memory store FailLocal limits requests on, created on the
+// first store failure
+type fallbackStore struct {
+	once  sync.Once
+	store Store
+	err   error
+}
+
+// get returns the fallback store, creating it on first use
+func (f *fallbackStore) get() (Store, error) {
+	f.once.Do(func() {
+		memStore, err := stores.NewMemoryStore(stores.MemoryConfig{CleanupInterval: time.Minute})
+		if err != nil {
+			f.err = fmt.Errorf("failed to create fallback store: %w", err)
+			return
+		}
+		f.store = &storeAdapter{memStore}
+	})
+	return f.store, f.err
+}
+
+// close releases the fallback store, if it was created
+func (f *fallbackStore) close() {
+	f.once.Do(func() {})
+	if f.store != nil {
+		f.store.Close()
+	}
+}
+
+// FailurePolicies returns the default failure policy under "*" and the policy of every
+// scope that sets its own
+func (l *limiterImpl) FailurePolicies() map[string]string {
+	policies := copyLimits(l.config.ScopeFailurePolicies)
+	policies["*"] = l.config.defaultFailurePolicy()
+	return policies
+}
+
+// FailedOpen returns how many checks FailOpen let through while the store failed, per scope
+func (l *limiterImpl) FailedOpen() map[string]int64 {
+	return l.failedOpen.snapshot()
+}
+
+// FailedClosed returns how many checks FailClosed rejected while the store failed, per scope
+func (l *limiterImpl) FailedClosed() map[string]int64 {
+	return l.failedClosed.snapshot()
+}
+
+// LocalFallbacks returns how many checks FailLocal allowed and denied on the fallback
+// store while the store failed, per scope
+func (l *limiterImpl) LocalFallbacks() (allowed, denied map[string]int64) {
+	return l.fallbackAllowed.snapshot(), l.fallbackDenied.snapshot()
+}
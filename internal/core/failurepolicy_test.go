@@ -0,0 +1,198 @@
+// internal/core/failurepolicy_test.go
+package core
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/itsatony/gorly/stores"
+)
+
+// outageStore fails reads, writes and health checks while down is set
+type outageStore struct {
+	Store
+	down atomic.Bool
+}
+
+var errStoreDown = errors.New("store unavailable")
+
+func (s *outageStore) Get(ctx context.Context, key string) ([]byte, error) {
+	if s.down.Load() {
+		return nil, errStoreDown
+	}
+	return s.Store.Get(ctx, key)
+}
+
+func (s *outageStore) Set(ctx context.Context, key string, value []byte, expiration time.Duration) error {
+	if s.down.Load() {
+		return errStoreDown
+	}
+	return s.Store.Set(ctx, key, value, expiration)
+}
+
+func (s *outageStore) IncrementBy(ctx context.Context, key string, amount int64, expiration time.Duration) (int64, error) {
+	if s.down.Load() {
+		return 0, errStoreDown
+	}
+	return s.Store.IncrementBy(ctx, key, amount, expiration)
+}
+
+func (s *outageStore) Health(ctx context.Context) error {
+	if s.down.Load() {
+		return errStoreDown
+	}
+	return s.Store.Health(ctx)
+}
+
+func TestScopeFailurePolicies(t *testing.T) {
+	ctx := context.Background()
+	store := &outageStore{Store: newTestStore(t)}
+	var reported atomic.Int64
+	limiter := newTestLimiter(t, &Config{
+		Algorithm:            "sliding_window",
+		Limits:               map[string]string{"read": "2/minute", "password-reset": "2/minute"},
+		BandwidthLimits:      map[string]string{"read": "1MB/hour"},
+		FailurePolicy:        FailOpen,
+		ScopeFailurePolicies: map[string]string{"password-reset": FailClosed},
+		ErrorHandler:         func(error) { reported.Add(1) },
+	}, store)
+
+	store.down.Store(true)
+	for i := 0; i < 3; i++ {
+		result, err := limiter.Check(ctx, "user:1", "read")
+		if err != nil || !result.Allowed || !result.FailedOpen || result.Limit != 2 {
+			t.Fatalf("Expected the read scope to fail open, got %+v, %v", result, err)
+		}
+	}
+	if _, err := limiter.Check(ctx, "user:1", "password-reset"); !errors.Is(err, errStoreDown) {
+		t.Errorf("Expected the password-reset scope to fail closed, got %v", err)
+	}
+	if result, err := limiter.CheckBandwidth(ctx, "user:1", "read"); err != nil || !result.FailedOpen {
+		t.Errorf("Expected the bandwidth check to fail open, got %+v, %v", result, err)
+	}
+	if _, err := limiter.ConsumeBandwidth(ctx, "user:1", "read", 100); err == nil {
+		t.Error("Expected charging bandwidth to report the failure")
+	}
+
+	if failed := limiter.FailedOpen(); failed["read"] != 4 || len(failed) != 1 {
+		t.Errorf("Expected 4 checks let through in read, got %v", failed)
+	}
+	if reported.Load() != 4 {
+		t.Errorf("Expected every fail-open check to be reported, got %d", reported.Load())
+	}
+	if policies := limiter.FailurePolicies(); policies["*"] != FailOpen || policies["password-reset"] != FailClosed {
+		t.Errorf("Expected the default and the scope policy, got %v", policies)
+	}
+
+	// Once the store recovers, limits apply again
+	store.down.Store(false)
+	for i, want := range []bool{true, true, false} {
+		if result, err := limiter.Check(ctx, "user:1", "read"); err != nil || result.Allowed != want || result.FailedOpen {
+			t.Errorf("Check %d after recovery: expected allowed=%t, got %+v, %v", i+1, want, result, err)
+		}
+	}
+}
+
+func TestLocalFallbackFailurePolicy(t *testing.T) {
+	ctx := context.Background()
+	store := &outageStore{Store: newTestStore(t)}
+	var reported atomic.Int64
+	limiter := newTestLimiter(t, &Config{
+		Algorithm:            "sliding_window",
+		Limits:               map[string]string{"read": "2/minute", "password-reset": "2/minute"},
+		FailurePolicy:        FailLocal,
+		ScopeFailurePolicies: map[string]string{"password-reset": FailClosed},
+		ErrorHandler:         func(error) { reported.Add(1) },
+	}, store)
+
+	// The store's count is not carried over: the fallback starts from zero
+	if _, err := limiter.Check(ctx, "user:1", "read"); err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	store.down.Store(true)
+	for i, want := range []bool{true, true, false} {
+		result, err := limiter.Check(ctx, "user:1", "read")
+		if err != nil || result.Allowed != want || !result.Degraded || result.FailedOpen {
+			t.Fatalf("Check %d during the outage: expected a degraded allowed=%t, got %+v, %v", i+1, want, result, err)
+		}
+	}
+	if result, err := limiter.Check(ctx, "user:2", "read"); err != nil || !result.Allowed || result.Remaining != 1 {
+		t.Errorf("Expected entities to be limited apart, got %+v, %v", result, err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := limiter.Check(ctx, "user:1", "password-reset"); !errors.Is(err, errStoreDown) {
+			t.Errorf("Expected the password-reset scope to fail closed, got %v", err)
+		}
+	}
+
+	allowed, denied := limiter.LocalFallbacks()
+	if allowed["read"] != 3 || denied["read"] != 1 {
+		t.Errorf("Expected 3 allowed and 1 denied by the fallback, got %v and %v", allowed, denied)
+	}
+	if closed := limiter.FailedClosed(); closed["password-reset"] != 2 || len(closed) != 1 {
+		t.Errorf("Expected 2 checks rejected in password-reset, got %v", closed)
+	}
+	if reported.Load() != 4 {
+		t.Errorf("Expected every fallback decision to be reported, got %d", reported.Load())
+	}
+
+	// Once the store recovers, its own count applies again
+	store.down.Store(false)
+	if result, err := limiter.Check(ctx, "user:1", "read"); err != nil || !result.Allowed || result.Degraded || result.Remaining != 0 {
+		t.Errorf("Expected the store's count after recovery, got %+v, %v", result, err)
+	}
+}
+
+func TestFailurePolicyValidation(t *testing.T) {
+	tests := []struct {
+		name   string
+		config Config
+	}{
+		{"unknown default", Config{FailurePolicy: "sometimes"}},
+		{"unknown scope policy", Config{ScopeFailurePolicies: map[string]string{"read": "ajar"}}},
+		{"empty scope policy", Config{ScopeFailurePolicies: map[string]string{"read": ""}}},
+	}
+	for _, tt := range tests {
+		if err := tt.config.validateFailurePolicies(); err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+		}
+	}
+	if policy := (&Config{}).FailurePolicyFor("read"); policy != FailClosed {
+		t.Errorf("Expected scopes to fail closed by default, got %s", policy)
+	}
+}
+
+func TestStoreFailover(t *testing.T) {
+	config := &Config{Store: "memory", Algorithm: "sliding_window", RedisStandby: "memory"}
+	if err := config.Validate(); err == nil {
+		t.Error("Expected a standby without a redis primary to be rejected")
+	}
+
+	primary, err := stores.NewMemoryStore(stores.MemoryConfig{CleanupInterval: time.Minute})
+	if err != nil {
+		t.Fatalf("Failed to create memory store: %v", err)
+	}
+	standby, err := newStandbyStore("memory", stores.RedisConfig{})
+	if err != nil {
+		t.Fatalf("Failed to create standby store: %v", err)
+	}
+	failover, err := stores.NewFailoverStore(stores.FailoverConfig{}, primary, standby)
+	if err != nil {
+		t.Fatalf("Failed to create failover store: %v", err)
+	}
+	limiter := newTestLimiter(t, &Config{
+		Algorithm: "sliding_window",
+		Limits:    map[string]string{"global": "10/minute"},
+	}, &storeAdapter{failover})
+
+	if _, err := limiter.Check(context.Background(), "user-1", "global"); err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	stats := limiter.StoreFailoverStats()
+	if stats == nil || stats.FailedOver {
+		t.Errorf("Expected failover stats on the primary, got %+v", stats)
+	}
+}
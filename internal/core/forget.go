@@ -0,0 +1,140 @@
+// internal/core/forget.go
+package core
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// ForgetReport describes the state removed for a forgotten entity
+type ForgetReport struct {
+	Entity  string    // Entity as stored, after sanitizing
+	Subject string    // Anonymized entity for audit records
+	Scopes  []string  // Scopes whose counters were removed, sorted
+	Keys    int       // Store keys deleted, including keys that held no state
+	Cached  int       // Local cache entries dropped (denial and tier cache)
+	At      time.Time // Time of the deletion
+}
+
+// SHA256Subject is the default subject of forgotten entities: the hex SHA-256 digest of the entity
+func SHA256Subject(entity string) string {
+	sum := sha256.Sum256([]byte(entity))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// Forget removes the stored state of an entity: the request counters of every configured scope
+// and of the extra scopes given, the current bandwidth and token windows, grants and lockouts,
+// plus cached denials and tiers. Counters of earlier windows or reset generations are not
+// addressable and expire with their TTL. Entity overrides are configuration and stay in place.
+// Deletion continues past store errors; the report covers what was removed and the error
+// joins every failure. Both are passed to OnForget, which makes an audit trail of deletions.
+func (l *limiterImpl) Forget(ctx context.Context, entity string, extraScopes []string) (*ForgetReport, error) {
+	if entity == "" {
+		return nil, &InputError{Field: InputFieldEntity, Reason: "must not be empty"}
+	}
+	entity, err := l.config.SanitizeEntity(entity)
+	if err != nil {
+		return nil, err
+	}
+
+	subjectID := l.config.SubjectID
+	if subjectID == nil {
+		subjectID = SHA256Subject
+	}
+	report := &ForgetReport{Entity: entity, Subject: subjectID(entity), At: l.config.Now()}
+	var errs []error
+	deleteKey := func(key string, del func(context.Context, string) error) {
+		if err := del(ctx, key); err != nil {
+			errs = append(errs, err)
+			return
+		}
+		report.Keys++
+	}
+
+	now := l.config.Now()
+	var requestKeys []string
+	for _, scope := range l.forgetScopes(entity, extraScopes) {
+		report.Scopes = append(report.Scopes, scope)
+		store := l.storeFor(scope)
+		key := l.requestKey(entity, scope)
+		requestKeys = append(requestKeys, key)
+		deleteKey(key, func(ctx context.Context, key string) error {
+			return l.algorithmFor(scope).Reset(ctx, store, key)
+		})
+
+		if l.config.Grants {
+			deleteKey(l.grantKey(entity, scope), store.Delete)
+			deleteKey(l.grantUsedKey(entity, scope), store.Delete)
+		}
+		if limit, ok := l.config.BandwidthLimits[scope]; ok {
+			if _, window, err := parseBandwidth(limit); err == nil {
+				windowStart, _ := calendarWindow(now, window, l.windowLocation(entity, scope))
+				deleteKey(l.counterKey("bandwidth", entity, scope, windowStart), store.Delete)
+			}
+		}
+		if budget, ok := l.config.TokenBudgets[scope]; ok {
+			if _, window, err := parseLimit(budget); err == nil {
+				windowStart, _ := calendarWindow(now, window, l.windowLocation(entity, scope))
+				deleteKey(l.counterKey("tokens", entity, scope, windowStart), store.Delete)
+			}
+		}
+	}
+
+	if l.config.Lockout != nil {
+		deleteKey(l.lockoutFailuresKey(entity), l.store.Delete)
+		deleteKey(l.lockoutKey(entity), l.store.Delete)
+	}
+
+	if l.denials != nil {
+		report.Cached += l.denials.forget(requestKeys)
+	}
+	if l.tiers != nil {
+		report.Cached += l.tiers.forget(entity)
+	}
+
+	if len(errs) > 0 {
+		err = fmt.Errorf("failed to forget entity %s: %w", report.Subject, errors.Join(errs...))
+	}
+	if l.config.OnForget != nil {
+		l.config.OnForget(*report, err)
+	}
+	return report, err
+}
+
+// forgetScopes returns every scope that can hold state of an entity, sorted. Extra scopes are
+// sanitized without being admitted to the scope budget.
+func (l *limiterImpl) forgetScopes(entity string, extraScopes []string) []string {
+	table := l.limitTable()
+	scopes := []string{"global"}
+	for scope := range table.limits {
+		scopes = append(scopes, scope)
+	}
+	for scope := range table.tierLimits {
+		scopes = append(scopes, scope)
+	}
+	for scope := range table.overrides[entity] {
+		scopes = append(scopes, scope)
+	}
+	for scope := range l.config.BandwidthLimits {
+		scopes = append(scopes, scope)
+	}
+	for scope := range l.config.TokenBudgets {
+		scopes = append(scopes, scope)
+	}
+	if l.config.HasPreAuth() {
+		scopes = append(scopes, l.config.PreAuthScopeName())
+	}
+	for _, scope := range extraScopes {
+		if scope, err := l.config.sanitize(InputFieldScope, scope, l.config.maxScopeLength(), isScopeRune); err == nil && scope != "" {
+			scopes = append(scopes, scope)
+		}
+	}
+
+	slices.Sort(scopes)
+	return slices.Compact(scopes)
+}
@@ -0,0 +1,104 @@
+// internal/core/forget_test.go
+package core
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestForget(t *testing.T) {
+	ctx := context.Background()
+
+	var reports []ForgetReport
+	config := &Config{
+		Algorithm:       "sliding_window",
+		Limits:          map[string]string{"global": "2/minute", "export": "1/hour"},
+		BandwidthLimits: map[string]string{"download": "1MB/hour"},
+		Grants:          true,
+		Lockout:         &LockoutConfig{Threshold: 5, BaseDuration: time.Minute, MaxDuration: time.Hour},
+		DenialCache:     true,
+		OnForget:        func(report ForgetReport, err error) { reports = append(reports, report) },
+	}
+	l := newTestLimiter(t, config, nil)
+
+	if _, err := l.Grant(ctx, "user-1", "export", 5, time.Hour); err != nil {
+		t.Fatalf("Grant failed: %v", err)
+	}
+	for _, entity := range []string{"user-1", "user-2"} {
+		for i := 0; i < 3; i++ {
+			l.Check(ctx, entity, "global")
+		}
+		l.Check(ctx, entity, "export")
+		l.Check(ctx, entity, "adhoc")
+		l.ConsumeBandwidth(ctx, entity, "download", 1000)
+		l.RecordFailure(ctx, entity)
+	}
+	if result, _ := l.Check(ctx, "user-1", "global"); result.Allowed || !result.Cached {
+		t.Fatalf("Expected a cached denial before forgetting, got %+v", result)
+	}
+
+	report, err := l.Forget(ctx, "user-1", []string{"adhoc"})
+	if err != nil {
+		t.Fatalf("Forget failed: %v", err)
+	}
+	if want := []string{"adhoc", "download", "export", "global"}; !slices.Equal(report.Scopes, want) {
+		t.Errorf("Expected scopes %v, got %v", want, report.Scopes)
+	}
+	if report.Entity != "user-1" || report.Subject != SHA256Subject("user-1") || !strings.HasPrefix(report.Subject, "sha256:") {
+		t.Errorf("Expected the entity and its SHA-256 subject, got %+v", report)
+	}
+	if report.Keys == 0 || report.Cached != 1 {
+		t.Errorf("Expected deleted keys and one dropped cached denial, got %+v", report)
+	}
+	if len(reports) != 1 || reports[0].Subject != report.Subject {
+		t.Errorf("Expected OnForget to receive the report, got %+v", reports)
+	}
+
+	// The forgotten entity starts over everywhere
+	if result, err := l.Peek(ctx, "user-1", "global"); err != nil || result.Remaining != 2 {
+		t.Errorf("Expected a fresh global limit, got %+v (%v)", result, err)
+	}
+	if result, _ := l.Peek(ctx, "user-1", "adhoc"); result.Remaining != 2 {
+		t.Errorf("Expected the extra scope to be forgotten, got %+v", result)
+	}
+	if result, _ := l.Peek(ctx, "user-1", "export"); result.Remaining != 1 || result.GrantRemaining != 0 {
+		t.Errorf("Expected a fresh export limit without grant, got %+v", result)
+	}
+	if result, _ := l.CheckBandwidth(ctx, "user-1", "download"); result.Used != 0 {
+		t.Errorf("Expected the bandwidth window to be forgotten, got %+v", result)
+	}
+	if state, _ := l.Lockout(ctx, "user-1"); state.Failures != 0 {
+		t.Errorf("Expected the lockout history to be forgotten, got %+v", state)
+	}
+	if grant, _ := l.GrantStatus(ctx, "user-1", "export"); grant != nil {
+		t.Errorf("Expected the grant to be forgotten, got %+v", grant)
+	}
+
+	// Other entities are untouched
+	if result, _ := l.Peek(ctx, "user-2", "global"); result.Allowed {
+		t.Errorf("Expected user-2 to stay limited, got %+v", result)
+	}
+	if state, _ := l.Lockout(ctx, "user-2"); state.Failures != 1 {
+		t.Errorf("Expected user-2's lockout history to stay, got %+v", state)
+	}
+}
+
+func TestForgetSubjectID(t *testing.T) {
+	config := &Config{
+		Algorithm: "sliding_window",
+		Limits:    map[string]string{"global": "2/minute"},
+		SubjectID: func(entity string) string { return "subject-" + strings.ToUpper(entity) },
+	}
+	limiter := newTestLimiter(t, config, nil)
+
+	report, err := limiter.Forget(context.Background(), "user-1", nil)
+	if err != nil || report.Subject != "subject-USER-1" {
+		t.Errorf("Expected the configured subject ID, got %+v (%v)", report, err)
+	}
+	if _, err := limiter.Forget(context.Background(), "", nil); err == nil {
+		t.Error("Expected an error for an empty entity")
+	}
+}
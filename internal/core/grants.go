@@ -0,0 +1,193 @@
+// internal/core/grants.go
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/itsatony/gorly/stores"
+)
+
+// Grant is a temporary budget added to the limit of one entity in one scope.
+//
+// Grants are kept apart from the limit: requests are counted against the limit
+// first and only draw on the grant once the limit denies them, so a grant is
+// spent on the requests the entity could not have made without it. Grants live
+// in the store and are shared by every instance using it.
+type Grant struct {
+	Entity    string
+	Scope     string
+	Extra     int64 // Units granted
+	Used      int64 // Units drawn from the grant
+	ExpiresAt time.Time
+}
+
+// Remaining returns the units left of the grant
+func (g *Grant) Remaining() int64 {
+	return max(g.Extra-g.Used, 0)
+}
+
+// grantRecord is the stored form of a grant; its usage is a separate counter
+type grantRecord struct {
+	Extra     int64     `json:"extra"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// errGrantsDisabled is returned by grant operations when Config.Grants is off
+var errGrantsDisabled = errors.New("grants are not enabled")
+
+// Grant gives an entity extra units in scope for ttl. A grant replaces the current
+// grant of the entity in the scope, including what was used of it.
+func (l *limiterImpl) Grant(ctx context.Context, entity, scope string, extra int64, ttl time.Duration) (*Grant, error) {
+	if !l.config.Grants {
+		return nil, errGrantsDisabled
+	}
+	if extra < 1 {
+		return nil, fmt.Errorf("grant must add at least 1 unit, got %d", extra)
+	}
+	if ttl <= 0 {
+		return nil, fmt.Errorf("grant duration must be positive, got %v", ttl)
+	}
+	entity, scope, err := l.sanitize(entity, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	grant := &Grant{Entity: entity, Scope: scope, Extra: extra, ExpiresAt: l.config.Now().Add(ttl)}
+	data, err := json.Marshal(grantRecord{Extra: extra, ExpiresAt: grant.ExpiresAt})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode grant: %w", err)
+	}
+	if err := l.storeFor(scope).Delete(ctx, l.grantUsedKey(entity, scope)); err != nil {
+		return nil, fmt.Errorf("failed to reset grant usage: %w", err)
+	}
+	if err := l.storeFor(scope).Set(ctx, l.grantKey(entity, scope), data, ttl); err != nil {
+		return nil, fmt.Errorf("failed to store grant: %w", err)
+	}
+
+	// A cached denial would hide the grant until it lapses
+	if l.denials != nil {
+		l.denials.clear()
+	}
+	return grant, nil
+}
+
+// RevokeGrant removes the grant of an entity in scope; revoking a missing grant is not an error
+func (l *limiterImpl) RevokeGrant(ctx context.Context, entity, scope string) error {
+	if !l.config.Grants {
+		return errGrantsDisabled
+	}
+	entity, scope, err := l.sanitize(entity, scope)
+	if err != nil {
+		return err
+	}
+	if err := l.storeFor(scope).Delete(ctx, l.grantKey(entity, scope)); err != nil {
+		return fmt.Errorf("failed to revoke grant: %w", err)
+	}
+	if err := l.storeFor(scope).Delete(ctx, l.grantUsedKey(entity, scope)); err != nil {
+		return fmt.Errorf("failed to clear grant usage: %w", err)
+	}
+	return nil
+}
+
+// GrantStatus returns the current grant of an entity in scope, or nil without one
+func (l *limiterImpl) GrantStatus(ctx context.Context, entity, scope string) (*Grant, error) {
+	if !l.config.Grants {
+		return nil, errGrantsDisabled
+	}
+	entity, scope, err := l.sanitize(entity, scope)
+	if err != nil {
+		return nil, err
+	}
+	return l.readGrant(ctx, entity, scope)
+}
+
+// readGrant loads the grant of a sanitized entity and scope, or nil without one
+func (l *limiterImpl) readGrant(ctx context.Context, entity, scope string) (*Grant, error) {
+	data, err := l.storeFor(scope).Get(ctx, l.grantKey(entity, scope))
+	if err != nil {
+		if stores.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read grant: %w", err)
+	}
+	var record grantRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, fmt.Errorf("invalid grant: %w", err)
+	}
+	ttl := record.ExpiresAt.Sub(l.config.Now())
+	if ttl <= 0 {
+		return nil, nil
+	}
+
+	// Adding nothing reads the counter the same way on every store
+	used, err := l.storeFor(scope).IncrementBy(ctx, l.grantUsedKey(entity, scope), 0, ttl)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read grant usage: %w", err)
+	}
+	return &Grant{Entity: entity, Scope: scope, Extra: record.Extra, Used: used, ExpiresAt: record.ExpiresAt}, nil
+}
+
+// drawGrant takes n units from a grant and reports whether it had enough left.
+// The grant's Used is updated either way.
+func (l *limiterImpl) drawGrant(ctx context.Context, grant *Grant, n int64) (bool, error) {
+	ttl := grant.ExpiresAt.Sub(l.config.Now())
+	if ttl <= 0 || grant.Remaining() < n {
+		return false, nil
+	}
+	key := l.grantUsedKey(grant.Entity, grant.Scope)
+	store := l.storeFor(grant.Scope)
+	used, err := store.IncrementBy(ctx, key, n, ttl)
+	if err != nil {
+		return false, fmt.Errorf("failed to draw on grant: %w", err)
+	}
+	if used > grant.Extra {
+		// Another instance drew the last units first
+		if used, err = store.IncrementBy(ctx, key, -n, ttl); err != nil {
+			return false, fmt.Errorf("failed to return grant units: %w", err)
+		}
+		grant.Used = used
+		return false, nil
+	}
+	grant.Used = used
+	return true, nil
+}
+
+// applyGrant lets the entity's grant in scope cover a denied result and adds what is
+// left of the grant to the remaining quota. Without draw the grant is only looked at, as by Peek.
+func (l *limiterImpl) applyGrant(ctx context.Context, entity, scope string, n int64, result *CoreResult, draw bool) error {
+	grant, err := l.readGrant(ctx, entity, scope)
+	if err != nil || grant == nil {
+		return err
+	}
+	if !result.Allowed {
+		covered := grant.Remaining() >= n
+		if draw {
+			if covered, err = l.drawGrant(ctx, grant, n); err != nil {
+				return err
+			}
+		}
+		if covered {
+			result.Allowed = true
+			result.RetryAfter = 0
+		}
+	}
+	result.GrantRemaining = grant.Remaining()
+	if result.Allowed {
+		result.Remaining += result.GrantRemaining
+	}
+	return nil
+}
+
+// grantKey is the store key holding the grant of an entity in scope
+func (l *limiterImpl) grantKey(entity, scope string) string {
+	return l.config.keys().Build("grant", entity, scope)
+}
+
+// grantUsedKey is the store key counting the units drawn from a grant
+func (l *limiterImpl) grantUsedKey(entity, scope string) string {
+	return l.config.keys().Build("grant", entity, scope, "used")
+}
@@ -0,0 +1,112 @@
+// internal/core/grants_test.go
+package core
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGrants(t *testing.T) {
+	ctx := context.Background()
+	l := newTestLimiter(t, &Config{Limits: map[string]string{"global": "2/minute"}, Grants: true}, nil)
+
+	for i := 0; i < 2; i++ {
+		if result, err := l.Check(ctx, "user-1", "global"); err != nil || !result.Allowed {
+			t.Fatalf("Expected request %d within the limit, got %+v (%v)", i+1, result, err)
+		}
+	}
+	if result, _ := l.Check(ctx, "user-1", "global"); result.Allowed {
+		t.Fatal("Expected the limit to be exhausted before the grant")
+	}
+
+	grant, err := l.Grant(ctx, "user-1", "global", 3, time.Hour)
+	if err != nil || grant.Remaining() != 3 {
+		t.Fatalf("Grant failed: %+v (%v)", grant, err)
+	}
+	if result, _ := l.Peek(ctx, "user-1", "global"); !result.Allowed || result.Remaining != 3 || result.GrantRemaining != 3 {
+		t.Errorf("Expected Peek to count the grant, got %+v", result)
+	}
+
+	// Denied requests draw on the grant until it is spent
+	for want := int64(2); want >= 0; want-- {
+		result, err := l.Check(ctx, "user-1", "global")
+		if err != nil || !result.Allowed || result.GrantRemaining != want || result.Remaining != want {
+			t.Fatalf("Expected the grant to cover the request with %d left, got %+v (%v)", want, result, err)
+		}
+	}
+	if result, _ := l.Check(ctx, "user-1", "global"); result.Allowed || result.GrantRemaining != 0 {
+		t.Errorf("Expected a denial once the grant is spent, got %+v", result)
+	}
+	if status, err := l.GrantStatus(ctx, "user-1", "global"); err != nil || status.Used != 3 {
+		t.Errorf("Expected 3 units used, got %+v (%v)", status, err)
+	}
+
+	// Requests within the limit leave the grant alone but report it in Remaining
+	if _, err := l.Grant(ctx, "user-2", "global", 5, time.Hour); err != nil {
+		t.Fatalf("Grant failed: %v", err)
+	}
+	if result, _ := l.Check(ctx, "user-2", "global"); !result.Allowed || result.Remaining != 6 || result.GrantRemaining != 5 {
+		t.Errorf("Expected 1 remaining plus the grant of 5, got %+v", result)
+	}
+
+	if err := l.RevokeGrant(ctx, "user-2", "global"); err != nil {
+		t.Fatalf("RevokeGrant failed: %v", err)
+	}
+	if status, err := l.GrantStatus(ctx, "user-2", "global"); err != nil || status != nil {
+		t.Errorf("Expected no grant after revoking, got %+v (%v)", status, err)
+	}
+}
+
+// grantOutageStore fails every read of a grant key
+type grantOutageStore struct {
+	Store
+}
+
+func (s *grantOutageStore) Get(ctx context.Context, key string) ([]byte, error) {
+	if strings.Contains(key, ":grant:") {
+		return nil, errStoreDown
+	}
+	return s.Store.Get(ctx, key)
+}
+
+func TestGrantReadFailure(t *testing.T) {
+	ctx := context.Background()
+	var reported atomic.Int64
+	limiter := newTestLimiter(t, &Config{
+		Algorithm:    "sliding_window",
+		Limits:       map[string]string{"global": "1/minute"},
+		Grants:       true,
+		ErrorHandler: func(error) { reported.Add(1) },
+	}, &grantOutageStore{newTestStore(t)})
+
+	// The check is decided without the grant, and the failure is reported
+	if result, err := limiter.Check(ctx, "user-1", "global"); err != nil || !result.Allowed {
+		t.Fatalf("Expected the check to be allowed without its grant, got %+v (%v)", result, err)
+	}
+	if result, err := limiter.Check(ctx, "user-1", "global"); err != nil || result.Allowed {
+		t.Fatalf("Expected the check to be denied without its grant, got %+v (%v)", result, err)
+	}
+	if reported.Load() != 2 {
+		t.Errorf("Expected both grant failures to be reported, got %d", reported.Load())
+	}
+}
+
+func TestGrantValidation(t *testing.T) {
+	ctx := context.Background()
+	l := newTestLimiter(t, &Config{Limits: map[string]string{"global": "2/minute"}, Grants: true}, nil)
+
+	if _, err := l.Grant(ctx, "user-1", "global", 0, time.Hour); err == nil {
+		t.Error("Expected a grant of 0 units to be rejected")
+	}
+	if _, err := l.Grant(ctx, "user-1", "global", 10, 0); err == nil {
+		t.Error("Expected a grant without duration to be rejected")
+	}
+
+	disabled := newTestLimiter(t, &Config{Limits: map[string]string{"global": "2/minute"}}, nil)
+	if _, err := disabled.Grant(ctx, "user-1", "global", 10, time.Hour); err != errGrantsDisabled {
+		t.Errorf("Expected grants to require opting in, got %v", err)
+	}
+}
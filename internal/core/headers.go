@@ -0,0 +1,76 @@
+// internal/core/headers.go
+package core
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Header modes controlling which rate limit headers responses carry
+const (
+	HeaderModeFull    = "full"    // Every rate limit header (default)
+	HeaderModeMinimal = "minimal" // Only Retry-After on denied requests
+	HeaderModeNone    = "none"    // No rate limit headers
+)
+
+// RetryAfterHeader is the standard header kept by HeaderModeMinimal
+const RetryAfterHeader = "Retry-After"
+
+// HeaderModeFor returns the header mode of a scope
+func (c *Config) HeaderModeFor(scope string) string {
+	if mode, ok := c.ScopeHeaderModes[scope]; ok && mode != "" {
+		return mode
+	}
+	if c.HeaderMode != "" {
+		return c.HeaderMode
+	}
+	return HeaderModeFull
+}
+
+// ResponseHeader returns the name a rate limit header is sent as in a scope, or ""
+// when the scope's header mode or a rename to "" suppresses it
+func (c *Config) ResponseHeader(scope, name string) string {
+	switch c.HeaderModeFor(scope) {
+	case HeaderModeNone:
+		return ""
+	case HeaderModeMinimal:
+		if name != RetryAfterHeader {
+			return ""
+		}
+	}
+
+	for from, to := range c.HeaderNames {
+		if strings.EqualFold(from, name) {
+			return to
+		}
+	}
+	return name
+}
+
+// validateHeaders checks the header modes and renames
+func (c *Config) validateHeaders() error {
+	if err := validateHeaderMode(c.HeaderMode); err != nil {
+		return err
+	}
+	for scope, mode := range c.ScopeHeaderModes {
+		if err := validateHeaderMode(mode); err != nil {
+			return fmt.Errorf("scope %s: %w", scope, err)
+		}
+	}
+	for from, to := range c.HeaderNames {
+		if strings.ContainsAny(to, " \t:\r\n") {
+			return fmt.Errorf("invalid header name for %s: %q", from, to)
+		}
+	}
+	return nil
+}
+
+// validateHeaderMode checks that a header mode is known; empty means the default
+func validateHeaderMode(mode string) error {
+	switch mode {
+	case "", HeaderModeFull, HeaderModeMinimal, HeaderModeNone:
+		return nil
+	default:
+		return fmt.Errorf("unknown header mode: %s", mode)
+	}
+}
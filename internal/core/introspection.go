@@ -0,0 +1,327 @@
+// internal/core/introspection.go
+package core
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/itsatony/gorly/stores"
+)
+
+// Token introspection cache defaults
+const (
+	DefaultIntrospectionCacheTTL  = 5 * time.Minute
+	DefaultIntrospectionCacheSize = 10000
+)
+
+// IntrospectionCacheKeyLength is the length of the AES-256 key encrypting shared introspection results
+const IntrospectionCacheKeyLength = 32
+
+// TokenInfo is what an identity provider reports about an active token
+type TokenInfo struct {
+	Subject   string            `json:"sub"`              // Identity the token was issued to
+	Tier      string            `json:"tier,omitempty"`   // Tier of the subject; "" for DefaultTier
+	ExpiresAt time.Time         `json:"exp,omitempty"`    // When the token expires; zero if it does not
+	Claims    map[string]string `json:"claims,omitempty"` // Further claims the application needs
+}
+
+// Entity returns the rate limiting entity of the token: "tier:subject", or the subject without a tier
+func (ti *TokenInfo) Entity() string {
+	if ti.Tier == "" {
+		return ti.Subject
+	}
+	return ti.Tier + ":" + ti.Subject
+}
+
+// TokenIntrospector resolves a bearer token or API key with the identity provider, e.g. through
+// an OAuth 2.0 introspection endpoint. It returns nil for inactive or unknown tokens.
+type TokenIntrospector interface {
+	IntrospectToken(ctx context.Context, token string) (*TokenInfo, error)
+}
+
+// IntrospectionConfig makes the middleware identify requests by the subject of their token.
+//
+// Answers are cached in the instance for CacheTTL, never past the token's expiry. With a
+// SharedCacheKey they are also stored in the limiter's store, encrypted with AES-256-GCM and
+// keyed by an HMAC of the token, so instances sharing a store ask the identity provider once
+// per token instead of once each. Inactive tokens are not cached.
+type IntrospectionConfig struct {
+	Introspector   TokenIntrospector
+	CacheTTL       time.Duration              // Longest an answer is reused (default: 5m)
+	SharedCacheKey []byte                     // IntrospectionCacheKeyLength bytes; nil keeps the cache local
+	TokenFunc      func(*http.Request) string // Token of a request (default: Bearer token, then X-API-Key)
+}
+
+// IntrospectionStats counts how introspected tokens were answered
+type IntrospectionStats struct {
+	LocalHits  int64 // Answered by the instance's own cache
+	SharedHits int64 // Answered by the shared cache in the store
+	Misses     int64 // Asked the identity provider
+	Inactive   int64 // Tokens the identity provider did not accept
+	Errors     int64 // Failed identity provider calls
+	Entries    int64 // Tokens in the instance's cache
+}
+
+// validate checks the introspector, cache TTL and shared cache key
+func (ic *IntrospectionConfig) validate() error {
+	if ic.Introspector == nil {
+		return errors.New("token introspection requires an introspector")
+	}
+	if ic.CacheTTL < 0 {
+		return errors.New("introspection cache TTL cannot be negative")
+	}
+	if ic.SharedCacheKey != nil && len(ic.SharedCacheKey) != IntrospectionCacheKeyLength {
+		return fmt.Errorf("introspection cache key must be %d bytes", IntrospectionCacheKeyLength)
+	}
+	return nil
+}
+
+// Token returns the token of a request with TokenFunc, or its Bearer token or X-API-Key header
+func (ic *IntrospectionConfig) Token(r *http.Request) string {
+	if ic.TokenFunc != nil {
+		return ic.TokenFunc(r)
+	}
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return strings.TrimSpace(token)
+	}
+	return r.Header.Get("X-API-Key")
+}
+
+// introspectionCache remembers introspection results locally and, with a shared cache
+// key, in the store. Tokens are only held as hashes.
+type introspectionCache struct {
+	limiter *limiterImpl
+	config  *IntrospectionConfig
+	ttl     time.Duration
+	size    int
+	aead    cipher.AEAD // nil without a shared cache
+	now     func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*introspectionEntry // Token hash -> answer
+
+	localHits  atomic.Int64
+	sharedHits atomic.Int64
+	misses     atomic.Int64
+	inactive   atomic.Int64
+	errors     atomic.Int64
+}
+
+// introspectionEntry is a cached answer and when it must no longer be used
+type introspectionEntry struct {
+	info      *TokenInfo
+	expiresAt time.Time
+}
+
+// newIntrospectionCache creates the introspection cache of a limiter, or returns nil without an introspector
+func newIntrospectionCache(l *limiterImpl) (*introspectionCache, error) {
+	config := l.config.Introspection
+	if config == nil {
+		return nil, nil
+	}
+
+	ic := &introspectionCache{
+		limiter: l,
+		config:  config,
+		ttl:     config.CacheTTL,
+		size:    DefaultIntrospectionCacheSize,
+		now:     l.config.Now,
+		entries: make(map[string]*introspectionEntry),
+	}
+	if ic.ttl <= 0 {
+		ic.ttl = DefaultIntrospectionCacheTTL
+	}
+	if config.SharedCacheKey != nil {
+		block, err := aes.NewCipher(config.SharedCacheKey)
+		if err != nil {
+			return nil, fmt.Errorf("invalid introspection cache key: %w", err)
+		}
+		if ic.aead, err = cipher.NewGCM(block); err != nil {
+			return nil, fmt.Errorf("invalid introspection cache key: %w", err)
+		}
+	}
+	return ic, nil
+}
+
+// IntrospectToken returns what the identity provider reports about a token, or nil if the
+// token is not active. Cached answers are reused until the cache TTL or the token's expiry.
+func (l *limiterImpl) IntrospectToken(ctx context.Context, token string) (*TokenInfo, error) {
+	ic := l.introspection
+	if ic == nil {
+		return nil, errors.New("token introspection is not configured")
+	}
+	if token == "" {
+		return nil, nil
+	}
+	return ic.introspect(ctx, token)
+}
+
+// introspect answers from the local cache, then the shared cache, then the identity provider
+func (ic *introspectionCache) introspect(ctx context.Context, token string) (*TokenInfo, error) {
+	hash := ic.hash(token)
+	now := ic.now()
+
+	ic.mu.Lock()
+	entry, ok := ic.entries[hash]
+	if ok && now.Before(entry.expiresAt) {
+		ic.mu.Unlock()
+		ic.localHits.Add(1)
+		return entry.info, nil
+	}
+	ic.mu.Unlock()
+
+	if ic.aead != nil {
+		info, expiresAt, err := ic.loadShared(ctx, hash)
+		if err != nil {
+			ic.limiter.reportError(err)
+		} else if info != nil && now.Before(expiresAt) {
+			ic.sharedHits.Add(1)
+			ic.remember(hash, info, expiresAt, now)
+			return info, nil
+		}
+	}
+
+	ic.misses.Add(1)
+	info, err := ic.config.Introspector.IntrospectToken(ctx, token)
+	if err != nil {
+		ic.errors.Add(1)
+		return nil, fmt.Errorf("token introspection failed: %w", err)
+	}
+	if info == nil || info.Subject == "" || (!info.ExpiresAt.IsZero() && !now.Before(info.ExpiresAt)) {
+		ic.inactive.Add(1)
+		return nil, nil
+	}
+
+	expiresAt := now.Add(ic.ttl)
+	if !info.ExpiresAt.IsZero() && info.ExpiresAt.Before(expiresAt) {
+		expiresAt = info.ExpiresAt
+	}
+	ic.remember(hash, info, expiresAt, now)
+	if ic.aead != nil {
+		if err := ic.saveShared(ctx, hash, info, expiresAt.Sub(now)); err != nil {
+			ic.limiter.reportError(err)
+		}
+	}
+	return info, nil
+}
+
+// hash identifies a token without revealing it: an HMAC with the shared cache key, so store
+// keys cannot be matched against guessed tokens, or a plain SHA-256 for the local cache
+func (ic *introspectionCache) hash(token string) string {
+	if ic.config.SharedCacheKey != nil {
+		mac := hmac.New(sha256.New, ic.config.SharedCacheKey)
+		mac.Write([]byte(token))
+		return hex.EncodeToString(mac.Sum(nil))
+	}
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// remember caches an answer locally, unless the cache is full of current entries
+func (ic *introspectionCache) remember(hash string, info *TokenInfo, expiresAt, now time.Time) {
+	ic.mu.Lock()
+	defer ic.mu.Unlock()
+
+	if _, exists := ic.entries[hash]; !exists && len(ic.entries) >= ic.size {
+		for k, entry := range ic.entries {
+			if !entry.expiresAt.After(now) {
+				delete(ic.entries, k)
+			}
+		}
+		if len(ic.entries) >= ic.size {
+			return
+		}
+	}
+	ic.entries[hash] = &introspectionEntry{info: info, expiresAt: expiresAt}
+}
+
+// sharedIntrospection is the encrypted payload of a shared cache entry
+type sharedIntrospection struct {
+	Info      *TokenInfo `json:"info"`
+	ExpiresAt time.Time  `json:"expires_at"`
+}
+
+// sharedKey is the store key of a token's shared cache entry
+func (ic *introspectionCache) sharedKey(hash string) string {
+	return ic.limiter.config.keys().Build("introspection", hash)
+}
+
+// saveShared encrypts an answer into the store until it expires
+func (ic *introspectionCache) saveShared(ctx context.Context, hash string, info *TokenInfo, ttl time.Duration) error {
+	plaintext, err := json.Marshal(sharedIntrospection{Info: info, ExpiresAt: ic.now().Add(ttl)})
+	if err != nil {
+		return fmt.Errorf("failed to encode introspection result: %w", err)
+	}
+	nonce := make([]byte, ic.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("failed to encrypt introspection result: %w", err)
+	}
+	// The store key is bound as additional data, so entries cannot be swapped between tokens
+	key := ic.sharedKey(hash)
+	sealed := ic.aead.Seal(nonce, nonce, plaintext, []byte(key))
+	if err := ic.limiter.store.Set(ctx, key, sealed, ttl); err != nil {
+		return fmt.Errorf("failed to share introspection result: %w", err)
+	}
+	return nil
+}
+
+// loadShared decrypts a token's shared cache entry; a missing entry returns a nil info
+func (ic *introspectionCache) loadShared(ctx context.Context, hash string) (*TokenInfo, time.Time, error) {
+	key := ic.sharedKey(hash)
+	sealed, err := ic.limiter.store.Get(ctx, key)
+	if err != nil {
+		if stores.IsNotFound(err) {
+			return nil, time.Time{}, nil
+		}
+		return nil, time.Time{}, fmt.Errorf("failed to read shared introspection result: %w", err)
+	}
+
+	nonceSize := ic.aead.NonceSize()
+	if len(sealed) < nonceSize {
+		return nil, time.Time{}, errors.New("corrupt shared introspection result")
+	}
+	plaintext, err := ic.aead.Open(nil, sealed[:nonceSize], sealed[nonceSize:], []byte(key))
+	if err != nil {
+		// Written with another key, e.g. during key rotation; the token is introspected again
+		return nil, time.Time{}, nil
+	}
+	var shared sharedIntrospection
+	if err := json.Unmarshal(plaintext, &shared); err != nil || shared.Info == nil {
+		return nil, time.Time{}, errors.New("corrupt shared introspection result")
+	}
+	return shared.Info, shared.ExpiresAt, nil
+}
+
+// IntrospectionStats returns the token introspection metrics, or nil without an introspector
+func (l *limiterImpl) IntrospectionStats() *IntrospectionStats {
+	ic := l.introspection
+	if ic == nil {
+		return nil
+	}
+
+	ic.mu.Lock()
+	entries := int64(len(ic.entries))
+	ic.mu.Unlock()
+
+	return &IntrospectionStats{
+		LocalHits:  ic.localHits.Load(),
+		SharedHits: ic.sharedHits.Load(),
+		Misses:     ic.misses.Load(),
+		Inactive:   ic.inactive.Load(),
+		Errors:     ic.errors.Load(),
+		Entries:    entries,
+	}
+}
@@ -0,0 +1,129 @@
+// internal/core/introspection_test.go
+package core
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingIntrospector answers from a token table and counts its calls
+type countingIntrospector struct {
+	tokens map[string]*TokenInfo
+	calls  atomic.Int64
+	err    error
+}
+
+func (ci *countingIntrospector) IntrospectToken(ctx context.Context, token string) (*TokenInfo, error) {
+	ci.calls.Add(1)
+	if ci.err != nil {
+		return nil, ci.err
+	}
+	return ci.tokens[token], nil
+}
+
+func TestIntrospectionSharedCache(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(1760572800, 0)
+	store := newTestStore(t)
+	key := bytes.Repeat([]byte{7}, IntrospectionCacheKeyLength)
+	idp := &countingIntrospector{tokens: map[string]*TokenInfo{
+		"token-alice": {Subject: "alice", Tier: "premium", ExpiresAt: now.Add(time.Minute), Claims: map[string]string{"org": "acme"}},
+		"token-bob":   {Subject: "bob"},
+	}}
+
+	newInstance := func(key []byte) *limiterImpl {
+		t.Helper()
+		return newTestLimiter(t, &Config{
+			Limits:        map[string]string{"global": "10/minute"},
+			Introspection: &IntrospectionConfig{Introspector: idp, SharedCacheKey: key},
+			Clock:         func() time.Time { return now },
+		}, store)
+	}
+
+	first := newInstance(key)
+	defer first.Close() // Closes the shared store
+	info, err := first.IntrospectToken(ctx, "token-alice")
+	if err != nil || info == nil || info.Entity() != "premium:alice" || info.Claims["org"] != "acme" {
+		t.Fatalf("Expected alice's token info, got %+v (%v)", info, err)
+	}
+	if _, err := first.IntrospectToken(ctx, "token-alice"); err != nil || idp.calls.Load() != 1 {
+		t.Fatalf("Expected the second lookup from the local cache, got %d calls (%v)", idp.calls.Load(), err)
+	}
+
+	// Neither the token nor its claims are readable in the store
+	sealed, err := store.Get(ctx, first.introspection.sharedKey(first.introspection.hash("token-alice")))
+	if err != nil {
+		t.Fatalf("Expected the answer in the store: %v", err)
+	}
+	if bytes.Contains(sealed, []byte("alice")) || bytes.Contains(sealed, []byte("acme")) {
+		t.Error("Expected the shared answer to be encrypted")
+	}
+
+	// Another instance reuses the answer instead of asking the identity provider
+	second := newInstance(key)
+	if info, err := second.IntrospectToken(ctx, "token-alice"); err != nil || info == nil || info.Subject != "alice" {
+		t.Fatalf("Expected alice from the shared cache, got %+v (%v)", info, err)
+	}
+	if idp.calls.Load() != 1 {
+		t.Errorf("Expected one identity provider call, got %d", idp.calls.Load())
+	}
+
+	// An instance with another key cannot read the answer and asks again
+	rotated := newInstance(bytes.Repeat([]byte{8}, IntrospectionCacheKeyLength))
+	if info, _ := rotated.IntrospectToken(ctx, "token-alice"); info == nil || idp.calls.Load() != 2 {
+		t.Errorf("Expected a fresh introspection after key rotation, got %+v after %d calls", info, idp.calls.Load())
+	}
+
+	// Cached answers end with the token
+	now = now.Add(2 * time.Minute)
+	if info, _ := second.IntrospectToken(ctx, "token-alice"); info != nil {
+		t.Errorf("Expected the expired token to be inactive, got %+v", info)
+	}
+	if info, _ := second.IntrospectToken(ctx, "token-mallory"); info != nil {
+		t.Errorf("Expected an unknown token to be inactive, got %+v", info)
+	}
+
+	stats := second.IntrospectionStats()
+	if stats.SharedHits != 1 || stats.Misses != 2 || stats.Inactive != 2 {
+		t.Errorf("Unexpected introspection stats: %+v", stats)
+	}
+}
+
+func TestIntrospectionErrors(t *testing.T) {
+	idp := &countingIntrospector{err: errors.New("idp unavailable")}
+	limiter := newTestLimiter(t, &Config{
+		Limits:        map[string]string{"global": "10/minute"},
+		Introspection: &IntrospectionConfig{Introspector: idp},
+	}, nil)
+
+	if _, err := limiter.IntrospectToken(context.Background(), "token"); err == nil || !strings.Contains(err.Error(), "idp unavailable") {
+		t.Errorf("Expected the identity provider's error, got %v", err)
+	}
+	if stats := limiter.IntrospectionStats(); stats.Errors != 1 || stats.Entries != 0 {
+		t.Errorf("Expected one uncached error, got %+v", stats)
+	}
+}
+
+func TestIntrospectionConfigValidation(t *testing.T) {
+	tests := []struct {
+		name   string
+		config *IntrospectionConfig
+		valid  bool
+	}{
+		{"valid", &IntrospectionConfig{Introspector: &countingIntrospector{}}, true},
+		{"shared", &IntrospectionConfig{Introspector: &countingIntrospector{}, SharedCacheKey: make([]byte, 32)}, true},
+		{"no introspector", &IntrospectionConfig{}, false},
+		{"short key", &IntrospectionConfig{Introspector: &countingIntrospector{}, SharedCacheKey: []byte("secret")}, false},
+		{"negative TTL", &IntrospectionConfig{Introspector: &countingIntrospector{}, CacheTTL: -time.Second}, false},
+	}
+	for _, tt := range tests {
+		if err := tt.config.validate(); (err == nil) != tt.valid {
+			t.Errorf("%s: expected valid=%v, got %v", tt.name, tt.valid, err)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+// internal/core/keyexpiry.go
+package core
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/itsatony/gorly/stores"
+)
+
+// keyExpiryKindHashed is the kind of keys hashed for their length, whose parts are unknown
+const keyExpiryKindHashed = "hashed"
+
+// KeyExpiryStats describes the limiter keys the store expired, as reported by its keyspace notifications
+type KeyExpiryStats struct {
+	Expired    int64            // Keys expired since the watch started
+	LastMinute int64            // Keys expired in the last minute, the current churn rate
+	ByKind     map[string]int64 // Expired keys by their first part after the prefix, e.g. the algorithm of request counters
+}
+
+// keyExpiryWatch counts the expiries of the limiter's keys. Expiries of the last minute are
+// counted in one bucket per second, so the churn rate needs no timer.
+type keyExpiryWatch struct {
+	l      *limiterImpl
+	prefix string
+	cancel context.CancelFunc
+	total  atomic.Int64
+
+	mu      sync.Mutex
+	seconds [60]int64 // Expiries by Unix second modulo 60
+	stamps  [60]int64 // Unix second each bucket counts
+	byKind  map[string]int64
+}
+
+// newKeyExpiryWatch subscribes to the expiries of the limiter's keys, or returns nil if
+// the config does not watch them
+func newKeyExpiryWatch(l *limiterImpl) (*keyExpiryWatch, error) {
+	if !l.config.WatchKeyExpiry {
+		return nil, nil
+	}
+	var watcher stores.ExpiryWatcher
+	if adapter, ok := l.store.(*storeAdapter); ok {
+		watcher, _ = adapter.store.(stores.ExpiryWatcher)
+	}
+	if watcher == nil {
+		return nil, fmt.Errorf("key expiry telemetry requires a redis store, got %s", l.config.Store)
+	}
+
+	prefix := l.config.KeyPrefix
+	if prefix == "" {
+		prefix = DefaultKeyPrefix
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	w := &keyExpiryWatch{l: l, prefix: prefix, cancel: cancel, byKind: make(map[string]int64)}
+	// The trailing separator keeps keys of longer prefixes out, e.g. "ratelimit2:"
+	if err := watcher.WatchExpiries(ctx, prefix+":", l.config.ConfigureKeyspaceNotifications, w.expired); err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to watch key expiries: %w", err)
+	}
+	return w, nil
+}
+
+// expired counts an expired key and reports it to OnKeyExpired
+func (w *keyExpiryWatch) expired(key string) {
+	w.total.Add(1)
+	kind := w.kind(key)
+	second := w.l.config.Now().Unix()
+
+	w.mu.Lock()
+	i := second % int64(len(w.seconds))
+	if w.stamps[i] != second {
+		w.stamps[i], w.seconds[i] = second, 0
+	}
+	w.seconds[i]++
+	w.byKind[kind]++
+	w.mu.Unlock()
+
+	if handler := w.l.config.OnKeyExpired; handler != nil {
+		handler(key)
+	}
+}
+
+// kind returns the first part of a key after the prefix
+func (w *keyExpiryWatch) kind(key string) string {
+	rest := strings.TrimPrefix(key, w.prefix+":")
+	if strings.HasPrefix(rest, hashedKeyMarker[1:]) {
+		return keyExpiryKindHashed
+	}
+	if i := strings.IndexByte(rest, ':'); i >= 0 {
+		return rest[:i]
+	}
+	return rest
+}
+
+// stats returns the expiries counted so far
+func (w *keyExpiryWatch) stats() *KeyExpiryStats {
+	now := w.l.config.Now().Unix()
+	stats := &KeyExpiryStats{Expired: w.total.Load(), ByKind: make(map[string]int64)}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for i, stamp := range w.stamps {
+		if now-stamp < int64(len(w.seconds)) {
+			stats.LastMinute += w.seconds[i]
+		}
+	}
+	for kind, expired := range w.byKind {
+		stats.ByKind[kind] = expired
+	}
+	return stats
+}
+
+// close ends the subscription
+func (w *keyExpiryWatch) close() {
+	w.cancel()
+}
+
+// KeyExpiryStats returns the expiries of the limiter's keys, nil unless WatchKeyExpiry is set
+func (l *limiterImpl) KeyExpiryStats() *KeyExpiryStats {
+	if l.keyExpiry == nil {
+		return nil
+	}
+	return l.keyExpiry.stats()
+}
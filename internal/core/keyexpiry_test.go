@@ -0,0 +1,83 @@
+// internal/core/keyexpiry_test.go
+package core
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/itsatony/gorly/stores"
+)
+
+func TestKeyExpiryWatch(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	var reported []string
+	l := &limiterImpl{config: &Config{
+		Clock:        func() time.Time { return now },
+		OnKeyExpired: func(key string) { reported = append(reported, key) },
+	}}
+	w := &keyExpiryWatch{l: l, prefix: DefaultKeyPrefix, byKind: make(map[string]int64)}
+
+	keys := KeyBuilder{}
+	w.expired(keys.Build("sliding_window", "user:42", "global"))
+	w.expired(keys.Build("sliding_window", "user:43", "global"))
+	now = now.Add(30 * time.Second)
+	w.expired(keys.Build("grant", "user:42", "global"))
+	w.expired(HashKey(DefaultKeyPrefix, "ratelimit:"+strings.Repeat("x", 300)))
+
+	stats := w.stats()
+	if stats.Expired != 4 || stats.LastMinute != 4 {
+		t.Errorf("Expected 4 expiries, all in the last minute, got %+v", stats)
+	}
+	if stats.ByKind["sliding_window"] != 2 || stats.ByKind["grant"] != 1 || stats.ByKind[keyExpiryKindHashed] != 1 {
+		t.Errorf("Unexpected expiries by kind: %v", stats.ByKind)
+	}
+	if len(reported) != 4 || reported[0] != "ratelimit:sliding_window:user%3A42:global" {
+		t.Errorf("Expected every key to be reported, got %v", reported)
+	}
+
+	// Expiries age out of the last minute one second at a time
+	now = now.Add(45 * time.Second)
+	if stats := w.stats(); stats.Expired != 4 || stats.LastMinute != 2 {
+		t.Errorf("Expected 2 expiries in the last minute, got %+v", stats)
+	}
+	now = now.Add(time.Hour)
+	w.expired(keys.Build("sliding_window", "user:44", "global"))
+	if stats := w.stats(); stats.Expired != 5 || stats.LastMinute != 1 {
+		t.Errorf("Expected stale buckets to be reset, got %+v", stats)
+	}
+
+	config := &Config{Store: "memory", Algorithm: "sliding_window", Limits: map[string]string{"global": "1/second"}, WatchKeyExpiry: true}
+	if err := config.Validate(); err == nil || !strings.Contains(err.Error(), "redis store") {
+		t.Errorf("Expected key expiry telemetry to require redis, got %v", err)
+	}
+}
+
+// watchingStore is a memory store that records whether its expiries were ever watched
+type watchingStore struct {
+	*stores.MemoryStore
+	watched bool
+}
+
+func (s *watchingStore) WatchExpiries(ctx context.Context, prefix string, configure bool, onExpire func(key string)) error {
+	s.watched = true
+	return nil
+}
+
+func TestKeyExpiryWatchStartsAfterValidation(t *testing.T) {
+	memory, err := stores.NewMemoryStore(stores.MemoryConfig{})
+	if err != nil {
+		t.Fatalf("Failed to create memory store: %v", err)
+	}
+	store := &watchingStore{MemoryStore: memory}
+	defer store.Close()
+
+	config := &Config{Store: "redis", Algorithm: "no_such_algorithm", Limits: map[string]string{"global": "1/second"}, WatchKeyExpiry: true}
+	if _, err := NewLimiterWithStore(config, &storeAdapter{store}); err == nil {
+		t.Fatal("Expected an unknown algorithm to be rejected")
+	}
+	if store.watched {
+		t.Error("Expected no expiry watch for a limiter that failed to start")
+	}
+}
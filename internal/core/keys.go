@@ -0,0 +1,121 @@
+// internal/core/keys.go
+package core
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// DefaultKeyPrefix prefixes every store key written by the limiter
+const DefaultKeyPrefix = "ratelimit"
+
+// DefaultMaxKeyLength is the longest store key written before hashing
+const DefaultMaxKeyLength = 256
+
+// MinMaxKeyLength is the smallest accepted MaxKeyLength; hashed keys need room for the digest
+const MinMaxKeyLength = 96
+
+// hashedKeyMarker separates the prefix from the digest of an overlong key.
+// Escaped parts never contain '#', so hashed keys cannot collide with regular keys.
+const hashedKeyMarker = ":#"
+
+// KeyBuilder turns entity, scope and counter names into store keys.
+//
+// Every part is escaped so that no entity or scope value can produce the key of
+// another: '%', ':', '#', whitespace and control characters are percent-encoded.
+// Keys longer than MaxLength are replaced by the prefix and a SHA-256 digest of the
+// full key, which bounds key size without sacrificing uniqueness.
+type KeyBuilder struct {
+	Prefix    string // Trusted prefix, written unescaped (default: "ratelimit")
+	MaxLength int    // Longest key before hashing (default: 256)
+}
+
+// Build joins the escaped parts under the prefix
+// Example: KeyBuilder{}.Build("sliding_window", "user:42", "global") = "ratelimit:sliding_window:user%3A42:global"
+func (kb KeyBuilder) Build(parts ...string) string {
+	prefix := kb.Prefix
+	if prefix == "" {
+		prefix = DefaultKeyPrefix
+	}
+	maxLength := kb.MaxLength
+	if maxLength == 0 {
+		maxLength = DefaultMaxKeyLength
+	}
+
+	var b strings.Builder
+	b.WriteString(prefix)
+	for _, part := range parts {
+		b.WriteByte(':')
+		b.WriteString(EscapeKeyPart(part))
+	}
+
+	key := b.String()
+	if len(key) <= maxLength {
+		return key
+	}
+
+	return HashKey(prefix, key)
+}
+
+// HashKey replaces an overlong key by its prefix and the SHA-256 digest of the full key
+func HashKey(prefix, key string) string {
+	sum := sha256.Sum256([]byte(key))
+	if prefix == "" {
+		return hashedKeyMarker[1:] + hex.EncodeToString(sum[:])
+	}
+	return prefix + hashedKeyMarker + hex.EncodeToString(sum[:])
+}
+
+// EscapeKeyPart percent-encodes the characters that would make a key part ambiguous
+func EscapeKeyPart(part string) string {
+	if !needsKeyEscape(part) {
+		return part
+	}
+
+	const hexDigits = "0123456789ABCDEF"
+	var b strings.Builder
+	b.Grow(len(part) + 8)
+	for i := 0; i < len(part); i++ {
+		c := part[i]
+		if isKeyReserved(c) {
+			b.WriteByte('%')
+			b.WriteByte(hexDigits[c>>4])
+			b.WriteByte(hexDigits[c&0x0F])
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+// needsKeyEscape reports whether part contains any reserved character
+func needsKeyEscape(part string) bool {
+	for i := 0; i < len(part); i++ {
+		if isKeyReserved(part[i]) {
+			return true
+		}
+	}
+	return false
+}
+
+// isKeyReserved reports whether c must be escaped inside a key part
+func isKeyReserved(c byte) bool {
+	return c == '%' || c == ':' || c == '#' || c <= ' ' || c == 0x7F
+}
+
+// keys returns the key builder configured for the limiter
+func (c *Config) keys() KeyBuilder {
+	return KeyBuilder{Prefix: c.KeyPrefix, MaxLength: c.MaxKeyLength}
+}
+
+// requestKey is the store key of the request counter for an entity and scope.
+// The algorithm name keeps counters apart when the algorithm changes, since
+// each algorithm stores its state in its own format.
+func (l *limiterImpl) requestKey(entity, scope string) string {
+	algorithm := l.algorithmFor(scope).Name()
+	if generation, ok := l.generation(scope); ok {
+		return l.config.keys().Build(algorithm, entity, scope, generation)
+	}
+	return l.config.keys().Build(algorithm, entity, scope)
+}
@@ -0,0 +1,101 @@
+// internal/core/keys_test.go
+package core
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestKeyBuilderEscaping(t *testing.T) {
+	kb := KeyBuilder{}
+
+	tests := []struct {
+		parts []string
+		want  string
+	}{
+		{[]string{"sliding_window", "user-1", "global"}, "ratelimit:sliding_window:user-1:global"},
+		{[]string{"sliding_window", "user:42", "global"}, "ratelimit:sliding_window:user%3A42:global"},
+		{[]string{"lockout", "a b\tc\n"}, "ratelimit:lockout:a%20b%09c%0A"},
+		{[]string{"lockout", "100%#"}, "ratelimit:lockout:100%25%23"},
+		{[]string{"lockout", "üser"}, "ratelimit:lockout:üser"},
+	}
+
+	for _, tt := range tests {
+		if got := kb.Build(tt.parts...); got != tt.want {
+			t.Errorf("Build(%q) = %q, want %q", tt.parts, got, tt.want)
+		}
+	}
+}
+
+func TestKeyBuilderCollisions(t *testing.T) {
+	kb := KeyBuilder{}
+
+	// Each pair would produce the same key if parts were joined without escaping
+	pairs := [][2][]string{
+		{{"alg", "a:b", "c"}, {"alg", "a", "b:c"}},
+		{{"alg", "user", "1:global"}, {"alg", "user:1", "global"}},
+		{{"lockout", "e", "failures"}, {"lockout", "e:failures"}},
+		{{"alg", "a%3Ab", "c"}, {"alg", "a:b", "c"}},
+		{{"alg", "x", ""}, {"alg", "x:"}},
+	}
+
+	for _, pair := range pairs {
+		a, b := kb.Build(pair[0]...), kb.Build(pair[1]...)
+		if a == b {
+			t.Errorf("Parts %q and %q collide on key %q", pair[0], pair[1], a)
+		}
+	}
+
+	// Exhaustively compare short entities built from reserved and ordinary characters
+	alphabet := []string{"a", ":", "%", "#", " ", "3", "A"}
+	seen := make(map[string]string)
+	var visit func(prefix string, depth int)
+	visit = func(prefix string, depth int) {
+		for _, scope := range []string{"global", "s"} {
+			key := kb.Build("alg", prefix, scope)
+			id := prefix + "\x00" + scope
+			if other, ok := seen[key]; ok && other != id {
+				t.Fatalf("Entities %q and %q collide on key %q", other, id, key)
+			}
+			seen[key] = id
+		}
+		if depth == 0 {
+			return
+		}
+		for _, c := range alphabet {
+			visit(prefix+c, depth-1)
+		}
+	}
+	visit("", 4)
+}
+
+func TestKeyBuilderMaxLength(t *testing.T) {
+	kb := KeyBuilder{Prefix: "app", MaxLength: 128}
+
+	long := strings.Repeat("x", 500)
+	key := kb.Build("alg", long, "global")
+	if len(key) > 128 {
+		t.Fatalf("Expected key of at most 128 bytes, got %d", len(key))
+	}
+	if !strings.HasPrefix(key, "app:#") {
+		t.Errorf("Expected hashed key under prefix, got %q", key)
+	}
+	if key != kb.Build("alg", long, "global") {
+		t.Error("Expected hashed keys to be deterministic")
+	}
+	if key == kb.Build("alg", long+"y", "global") {
+		t.Error("Expected distinct long entities to hash to distinct keys")
+	}
+
+	// Hashed keys cannot collide with regular keys since '#' is always escaped
+	if regular := kb.Build("#" + key[len("app:#"):]); regular == key {
+		t.Errorf("Regular key collides with hashed key %q", key)
+	}
+}
+
+func TestConfigValidateMaxKeyLength(t *testing.T) {
+	config := &Config{Store: "memory", Algorithm: "sliding_window", MaxKeyLength: 10}
+	if err := config.Validate(); err == nil || !strings.Contains(err.Error(), "max key length") {
+		t.Errorf("Expected max key length error, got %v", err)
+	}
+}
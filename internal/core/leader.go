@@ -0,0 +1,215 @@
+// internal/core/leader.go
+package core
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DefaultLeaderLeaseTTL is how long a leader keeps its lease without renewing it
+const DefaultLeaderLeaseTTL = 15 * time.Second
+
+// ErrLimiterClosed is returned when jobs are registered on a closed limiter
+var ErrLimiterClosed = errors.New("limiter is closed")
+
+// LeaderJob is a function run periodically by exactly one instance.
+// Its context is cancelled when the instance loses leadership or the limiter is closed.
+type LeaderJob func(ctx context.Context) error
+
+// leaderElector runs singleton jobs on the instance holding each job's lease.
+//
+// A lease is a store key holding the instance ID. It is acquired with SetNX and
+// kept alive by refreshing its expiration every third of the lease TTL, but only
+// while it still holds this instance's ID. An instance that stops heartbeating
+// loses the lease once it expires, and another instance takes over.
+type leaderElector struct {
+	limiter    *limiterImpl
+	instanceID []byte
+	ttl        time.Duration
+
+	mu     sync.Mutex
+	jobs   map[string]struct{}
+	closed bool
+	stop   chan struct{}
+	done   sync.WaitGroup
+}
+
+// newLeaderElector creates the elector of a limiter
+func newLeaderElector(l *limiterImpl) *leaderElector {
+	id := l.config.InstanceID
+	if id == "" {
+		id = defaultInstanceID()
+	}
+	ttl := l.config.LeaderLeaseTTL
+	if ttl <= 0 {
+		ttl = DefaultLeaderLeaseTTL
+	}
+
+	return &leaderElector{
+		limiter:    l,
+		instanceID: []byte(id),
+		ttl:        ttl,
+		jobs:       make(map[string]struct{}),
+		stop:       make(chan struct{}),
+	}
+}
+
+// defaultInstanceID combines the hostname, process ID and a random suffix
+func defaultInstanceID() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	suffix := make([]byte, 4)
+	rand.Read(suffix)
+	return host + "-" + strconv.Itoa(os.Getpid()) + "-" + hex.EncodeToString(suffix)
+}
+
+// run registers a job and starts competing for its lease
+func (le *leaderElector) run(name string, interval time.Duration, job LeaderJob) error {
+	if name == "" {
+		return errors.New("leader job name is required")
+	}
+	if interval <= 0 {
+		return fmt.Errorf("leader job %s: interval must be positive", name)
+	}
+	if job == nil {
+		return fmt.Errorf("leader job %s: function is required", name)
+	}
+
+	le.mu.Lock()
+	defer le.mu.Unlock()
+	if le.closed {
+		return ErrLimiterClosed
+	}
+	if _, exists := le.jobs[name]; exists {
+		return fmt.Errorf("leader job %s is already running", name)
+	}
+	le.jobs[name] = struct{}{}
+
+	le.done.Add(1)
+	go le.loop(name, interval, job)
+	return nil
+}
+
+// close stops every job, waits for them to exit and releases held leases
+func (le *leaderElector) close() {
+	le.mu.Lock()
+	if le.closed {
+		le.mu.Unlock()
+		return
+	}
+	le.closed = true
+	close(le.stop)
+	le.mu.Unlock()
+
+	le.done.Wait()
+}
+
+// leaseKey is the store key holding the lease of a job
+func (le *leaderElector) leaseKey(name string) string {
+	return le.limiter.config.keys().Build("leader", name)
+}
+
+// loop heartbeats the lease of one job and runs the job while leading
+func (le *leaderElector) loop(name string, interval time.Duration, job LeaderJob) {
+	defer le.done.Done()
+
+	key := le.leaseKey(name)
+	heartbeat := le.ttl / 3
+	ticker := time.NewTicker(heartbeat)
+	defer ticker.Stop()
+
+	var (
+		leading  bool
+		lastRun  time.Time
+		cancel   context.CancelFunc = func() {}
+		finished chan struct{}
+	)
+	defer func() {
+		cancel()
+		if finished != nil {
+			<-finished
+		}
+		if leading {
+			ctx, release := context.WithTimeout(context.Background(), heartbeat)
+			le.limiter.store.CompareAndDelete(ctx, key, le.instanceID)
+			release()
+		}
+	}()
+
+	for {
+		ctx, done := context.WithTimeout(context.Background(), heartbeat)
+		held, err := le.heartbeat(ctx, key, leading)
+		done()
+		if err != nil {
+			le.limiter.reportError(fmt.Errorf("leader job %s: lease heartbeat failed: %w", name, err))
+		}
+
+		if leading && !held {
+			// Leadership lost: stop the running job before anyone else starts it
+			cancel()
+			lastRun = time.Time{}
+		}
+		leading = held
+
+		running := finished != nil
+		if running {
+			select {
+			case <-finished:
+				cancel()
+				finished = nil
+				running = false
+			default:
+			}
+		}
+
+		if leading && !running && time.Since(lastRun) >= interval {
+			lastRun = time.Now()
+			cancel, finished = le.start(name, job)
+		}
+
+		select {
+		case <-le.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// start runs one execution of a job in the background
+func (le *leaderElector) start(name string, job LeaderJob) (context.CancelFunc, chan struct{}) {
+	ctx, cancel := context.WithCancel(context.Background())
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		if err := job(ctx); err != nil {
+			le.limiter.reportError(fmt.Errorf("leader job %s failed: %w", name, err))
+		}
+	}()
+	return cancel, finished
+}
+
+// heartbeat acquires or renews the lease of a job, reporting whether this instance holds it
+func (le *leaderElector) heartbeat(ctx context.Context, key string, leading bool) (bool, error) {
+	if leading {
+		renewed, err := le.limiter.store.CompareAndExpire(ctx, key, le.instanceID, le.ttl)
+		if err != nil || renewed {
+			return renewed, err
+		}
+		// The lease expired before it was renewed; try to take it back below
+	}
+	return le.limiter.store.SetNX(ctx, key, le.instanceID, le.ttl)
+}
+
+// RunWhenLeader runs job every interval on exactly one of the instances sharing the store
+func (l *limiterImpl) RunWhenLeader(name string, interval time.Duration, job LeaderJob) error {
+	return l.leader.run(name, interval, job)
+}
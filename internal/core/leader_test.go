@@ -0,0 +1,129 @@
+// internal/core/leader_test.go
+package core
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// leaderTestConfig configures an instance with a short leader lease
+func leaderTestConfig(instanceID string) *Config {
+	return &Config{
+		Limits:         map[string]string{"global": "10/minute"},
+		LeaderLeaseTTL: 60 * time.Millisecond,
+		InstanceID:     instanceID,
+	}
+}
+
+// withSharedStore creates another instance using the store of l
+func withSharedStore(l *limiterImpl, instanceID string) *limiterImpl {
+	config := *l.config
+	config.InstanceID = instanceID
+	other := &limiterImpl{config: &config, store: l.store, algorithm: l.algorithm}
+	other.leader = newLeaderElector(other)
+	return other
+}
+
+func TestRunWhenLeaderSingleInstance(t *testing.T) {
+	first := newTestLimiter(t, leaderTestConfig("instance-1"), nil)
+	second := withSharedStore(first, "instance-2")
+	defer second.leader.close()
+
+	var firstRuns, secondRuns atomic.Int64
+	if err := first.RunWhenLeader("sweep", 20*time.Millisecond, func(ctx context.Context) error {
+		firstRuns.Add(1)
+		return nil
+	}); err != nil {
+		t.Fatalf("Failed to register job: %v", err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	if err := second.RunWhenLeader("sweep", 20*time.Millisecond, func(ctx context.Context) error {
+		secondRuns.Add(1)
+		return nil
+	}); err != nil {
+		t.Fatalf("Failed to register job: %v", err)
+	}
+
+	time.Sleep(150 * time.Millisecond)
+	if firstRuns.Load() == 0 {
+		t.Error("Expected the leader to run the job")
+	}
+	if secondRuns.Load() != 0 {
+		t.Errorf("Expected the follower not to run the job, ran %d times", secondRuns.Load())
+	}
+}
+
+func TestRunWhenLeaderFailover(t *testing.T) {
+	first := newTestLimiter(t, leaderTestConfig("instance-1"), nil)
+	second := withSharedStore(first, "instance-2")
+	defer second.leader.close()
+
+	job := func(runs *atomic.Int64) LeaderJob {
+		return func(ctx context.Context) error {
+			runs.Add(1)
+			return nil
+		}
+	}
+	var firstRuns, secondRuns atomic.Int64
+	first.RunWhenLeader("sweep", 10*time.Millisecond, job(&firstRuns))
+	time.Sleep(10 * time.Millisecond)
+	second.RunWhenLeader("sweep", 10*time.Millisecond, job(&secondRuns))
+
+	// Stopping the leader releases its lease so the follower takes over
+	first.leader.close()
+	time.Sleep(100 * time.Millisecond)
+	if secondRuns.Load() == 0 {
+		t.Error("Expected the follower to take over after the leader stopped")
+	}
+}
+
+func TestRunWhenLeaderCancelsOnLoss(t *testing.T) {
+	l := newTestLimiter(t, leaderTestConfig("instance-1"), nil)
+
+	cancelled := make(chan struct{})
+	l.RunWhenLeader("long", time.Hour, func(ctx context.Context) error {
+		<-ctx.Done()
+		close(cancelled)
+		return nil
+	})
+	time.Sleep(10 * time.Millisecond)
+
+	// Another instance steals the lease
+	key := l.leader.leaseKey("long")
+	l.store.Delete(context.Background(), key)
+	l.store.SetNX(context.Background(), key, []byte("intruder"), time.Minute)
+
+	select {
+	case <-cancelled:
+	case <-time.After(200 * time.Millisecond):
+		t.Fatal("Expected the job to be cancelled after losing the lease")
+	}
+}
+
+func TestRunWhenLeaderValidation(t *testing.T) {
+	l := newTestLimiter(t, leaderTestConfig("instance-1"), nil)
+	noop := func(ctx context.Context) error { return nil }
+
+	if err := l.RunWhenLeader("", time.Second, noop); err == nil {
+		t.Error("Expected error for empty job name")
+	}
+	if err := l.RunWhenLeader("job", 0, noop); err == nil {
+		t.Error("Expected error for non-positive interval")
+	}
+	if err := l.RunWhenLeader("job", time.Second, nil); err == nil {
+		t.Error("Expected error for nil job")
+	}
+	if err := l.RunWhenLeader("job", time.Second, noop); err != nil {
+		t.Fatalf("Failed to register job: %v", err)
+	}
+	if err := l.RunWhenLeader("job", time.Second, noop); err == nil {
+		t.Error("Expected error for duplicate job name")
+	}
+
+	l.Close()
+	if err := l.RunWhenLeader("late", time.Second, noop); err != ErrLimiterClosed {
+		t.Errorf("Expected ErrLimiterClosed after close, got %v", err)
+	}
+}
@@ -3,9 +3,13 @@ package core
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"strconv"
+	"net/http"
+	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/itsatony/gorly/algorithms"
@@ -20,6 +24,9 @@ type storeAdapter struct {
 		IncrementBy(ctx context.Context, key string, amount int64, expiration time.Duration) (int64, error)
 		Delete(ctx context.Context, key string) error
 		Exists(ctx context.Context, key string) (bool, error)
+		SetNX(ctx context.Context, key string, value []byte, expiration time.Duration) (bool, error)
+		CompareAndExpire(ctx context.Context, key string, value []byte, expiration time.Duration) (bool, error)
+		CompareAndDelete(ctx context.Context, key string, value []byte) (bool, error)
 		Health(ctx context.Context) error
 		Close() error
 	}
@@ -45,6 +52,18 @@ func (s *storeAdapter) Exists(ctx context.Context, key string) (bool, error) {
 	return s.store.Exists(ctx, key)
 }
 
+func (s *storeAdapter) SetNX(ctx context.Context, key string, value []byte, expiration time.Duration) (bool, error) {
+	return s.store.SetNX(ctx, key, value, expiration)
+}
+
+func (s *storeAdapter) CompareAndExpire(ctx context.Context, key string, value []byte, expiration time.Duration) (bool, error) {
+	return s.store.CompareAndExpire(ctx, key, value, expiration)
+}
+
+func (s *storeAdapter) CompareAndDelete(ctx context.Context, key string, value []byte) (bool, error) {
+	return s.store.CompareAndDelete(ctx, key, value)
+}
+
 func (s *storeAdapter) Health(ctx context.Context) error {
 	return s.store.Health(ctx)
 }
@@ -70,11 +89,51 @@ func (s *algorithmStoreAdapter) Delete(ctx context.Context, key string) error {
 	return s.store.Delete(ctx, key)
 }
 
+func (s *algorithmStoreAdapter) IncrementBy(ctx context.Context, key string, amount int64, expiration time.Duration) (int64, error) {
+	return s.store.IncrementBy(ctx, key, amount, expiration)
+}
+
+func (s *algorithmStoreAdapter) SetNX(ctx context.Context, key string, value []byte, expiration time.Duration) (bool, error) {
+	return s.store.SetNX(ctx, key, value, expiration)
+}
+
+// RunScript runs a script on stores that run scripts, such as Redis. Checks on other
+// stores, and on stores staging the writes of a multi-scope check, take the Get/Set path.
+func (s *algorithmStoreAdapter) RunScript(ctx context.Context, script string, keys []string, args ...interface{}) (interface{}, error) {
+	runner := storeScriptRunner(s.store)
+	if runner == nil {
+		return nil, algorithms.ErrScriptsUnsupported
+	}
+	result, err := runner.RunScript(ctx, script, keys, args...)
+	if errors.Is(err, stores.ErrScriptsUnsupported) {
+		return nil, algorithms.ErrScriptsUnsupported
+	}
+	return result, err
+}
+
+// storeScriptRunner returns the script runner of a store, or nil if it runs no scripts.
+// Scripts write, so they bypass the retried reads of a retryStore.
+func storeScriptRunner(store Store) algorithms.ScriptRunner {
+	if retry, ok := store.(*retryStore); ok {
+		store = retry.Store
+	}
+	if runner, ok := store.(algorithms.ScriptRunner); ok {
+		return runner
+	}
+	if adapter, ok := store.(*storeAdapter); ok {
+		if runner, ok := adapter.store.(algorithms.ScriptRunner); ok {
+			return runner
+		}
+	}
+	return nil
+}
+
 // algorithmAdapter adapts concrete algorithm implementations to our Algorithm interface
 type algorithmAdapter struct {
 	algorithm interface {
 		Name() string
 		Allow(ctx context.Context, store algorithms.Store, key string, limit int64, window time.Duration, n int64) (*algorithms.Result, error)
+		Peek(ctx context.Context, store algorithms.Store, key string, limit int64, window time.Duration) (*algorithms.Result, error)
 		Reset(ctx context.Context, store algorithms.Store, key string) error
 	}
 }
@@ -103,6 +162,25 @@ func (a *algorithmAdapter) Allow(ctx context.Context, store Store, key string, l
 	}, nil
 }
 
+func (a *algorithmAdapter) Peek(ctx context.Context, store Store, key string, limit int64, window time.Duration) (*AlgorithmResult, error) {
+	algStore := &algorithmStoreAdapter{store}
+
+	result, err := a.algorithm.Peek(ctx, algStore, key, limit, window)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AlgorithmResult{
+		Allowed:    result.Allowed,
+		Remaining:  result.Remaining,
+		Limit:      result.Limit,
+		Used:       result.Used,
+		RetryAfter: result.RetryAfter,
+		Window:     result.Window,
+		ResetTime:  result.ResetTime,
+	}, nil
+}
+
 func (a *algorithmAdapter) Reset(ctx context.Context, store Store, key string) error {
 	algStore := &algorithmStoreAdapter{store}
 	return a.algorithm.Reset(ctx, algStore, key)
@@ -111,6 +189,72 @@ func (a *algorithmAdapter) Reset(ctx context.Context, store Store, key string) e
 // Limiter is the internal interface for rate limiting
 type Limiter interface {
 	Check(ctx context.Context, entity, scope string) (*CoreResult, error)
+	CheckN(ctx context.Context, entity, scope string, n int64) (*CoreResult, error)
+	CheckAll(ctx context.Context, entity string, costs []ScopeCost) (*MultiScopeResult, error)
+	Peek(ctx context.Context, entity, scope string) (*CoreResult, error)
+	Diagnostics(ctx context.Context, entity, scope string) (*Diagnostics, error)
+	Prewarm(ctx context.Context, specs []PrewarmSpec) (int, error)
+	CheckPreAuth(ctx context.Context, key string) (*CoreResult, error)
+	CheckBandwidth(ctx context.Context, entity, scope string) (*CoreResult, error)
+	ConsumeBandwidth(ctx context.Context, entity, scope string, bytes int64) (*CoreResult, error)
+	CheckTokens(ctx context.Context, entity, scope string) (*CoreResult, error)
+	ConsumeTokens(ctx context.Context, entity, scope string, tokens int64) (*CoreResult, error)
+	Limits(entity string) ([]EffectiveLimit, error)
+	Overrides() []Override
+	Grant(ctx context.Context, entity, scope string, extra int64, ttl time.Duration) (*Grant, error)
+	RevokeGrant(ctx context.Context, entity, scope string) error
+	GrantStatus(ctx context.Context, entity, scope string) (*Grant, error)
+	Forget(ctx context.Context, entity string, extraScopes []string) (*ForgetReport, error)
+	SetScale(factor float64) error
+	UpdateLimits(update LimitUpdate) error
+	SaveConfigSnapshot(ctx context.Context, data []byte) error
+	LoadConfigSnapshot(ctx context.Context) ([]byte, error)
+	SaveReportState(ctx context.Context, data []byte) error
+	LoadReportState(ctx context.Context) ([]byte, error)
+	SetMaintenance(enabled bool, allowlist []string)
+	CheckMaintenance(keys ...string) *CoreResult
+	VerifyTrustedCall(r *http.Request) *TrustedCall
+	IntrospectToken(ctx context.Context, token string) (*TokenInfo, error)
+	Scale() float64
+	RecordFailure(ctx context.Context, entity string) (*LockoutState, error)
+	RecordSuccess(ctx context.Context, entity string) error
+	Lockout(ctx context.Context, entity string) (*LockoutState, error)
+	RunWhenLeader(name string, interval time.Duration, job LeaderJob) error
+	DenialCacheHits() int64
+	CountEmptyEntity()
+	CountProbe()
+	SetCosts(costs map[string]int64) error
+	RequestCost(method, path string) int64
+	EmptyEntities() int64
+	Probes() int64
+	UnknownTiers() int64
+	EnforcementModes() map[string]string
+	ShadowDenials() map[string]int64
+	FailurePolicies() map[string]string
+	FailedOpen() map[string]int64
+	FailedClosed() map[string]int64
+	LocalFallbacks() (allowed, denied map[string]int64)
+	ExpiredOverrides() int64
+	ScopeOverflows() int64
+	ClockOffset() time.Duration
+	StoreKeys() (int64, bool)
+	StorePoolStats() *stores.PoolStats
+	StoreFailoverStats() *stores.FailoverStats
+	StoreLocalCacheStats() *stores.LocalCacheStats
+	StoreRetryStats() *StoreRetryStats
+	KeyExpiryStats() *KeyExpiryStats
+	StateCompressionStats() *algorithms.CompressionStats
+	ScopeStore(scope string) string
+	StoreStatuses(ctx context.Context) []StoreStatus
+	ConfigVersion() (generation int64, version string)
+	ConfigHash() string
+	UsageStats() *UsageStats
+	StatsCounters(ctx context.Context) (map[string]StatsCounter, error)
+	ReadsFromReplica() bool
+	StatsFlushStats() *StatsFlushStats
+	TierCacheStats() *TierCacheStats
+	TrustedCallStats() *TrustedCallStats
+	IntrospectionStats() *IntrospectionStats
 	Health(ctx context.Context) error
 	Close() error
 }
@@ -122,6 +266,9 @@ type Store interface {
 	IncrementBy(ctx context.Context, key string, amount int64, expiration time.Duration) (int64, error)
 	Delete(ctx context.Context, key string) error
 	Exists(ctx context.Context, key string) (bool, error)
+	SetNX(ctx context.Context, key string, value []byte, expiration time.Duration) (bool, error)
+	CompareAndExpire(ctx context.Context, key string, value []byte, expiration time.Duration) (bool, error)
+	CompareAndDelete(ctx context.Context, key string, value []byte) (bool, error)
 	Health(ctx context.Context) error
 	Close() error
 }
@@ -130,6 +277,7 @@ type Store interface {
 type Algorithm interface {
 	Name() string
 	Allow(ctx context.Context, store Store, key string, limit int64, window time.Duration, n int64) (*AlgorithmResult, error)
+	Peek(ctx context.Context, store Store, key string, limit int64, window time.Duration) (*AlgorithmResult, error)
 	Reset(ctx context.Context, store Store, key string) error
 }
 
@@ -146,16 +294,86 @@ type AlgorithmResult struct {
 
 // limiterImpl implements the Limiter interface
 type limiterImpl struct {
-	config    *Config
-	store     Store
-	algorithm Algorithm
+	config      *Config
+	store       Store
+	replica     Store            // nil without a read replica
+	scopeStores map[string]Store // Stores of ScopeStores other than store, by name
+	algorithm   Algorithm
+	alignment   string               // Window alignment of algorithm
+	aligned     map[string]Algorithm // Algorithms of scopes aligned other than algorithm, by scope
+
+	maintenance   atomic.Pointer[maintenanceState]
+	resets        *resetCoordinator // nil without scheduled resets
+	leader        *leaderElector
+	denials       *denialCache        // nil unless the denial cache is enabled
+	usage         *usageStats         // Counted by this instance only
+	stats         *statsBuffer        // nil unless write-behind stats are enabled
+	clock         *storeClock         // nil unless the store clock is used
+	tiers         *tierCache          // nil without a tier resolver
+	introspection *introspectionCache // nil without a token introspector
+	expiry        *overrideExpiry
+	retries       *retryPolicy    // nil unless store reads are retried
+	keyExpiry     *keyExpiryWatch // nil unless key expiries are watched
+	configHash    atomic.Pointer[tableHash]
+
+	emptyEntities   atomic.Int64 // Requests whose extractor returned no entity
+	probes          atomic.Int64 // Health probes let through without a check
+	unknownTiers    atomic.Int64 // Checks from entities claiming an unconfigured tier
+	shadowDenials   scopeCounter
+	failedOpen      scopeCounter  // Checks let through by FailOpen while the store failed
+	failedClosed    scopeCounter  // Checks rejected by FailClosed while the store failed
+	fallback        fallbackStore // In-memory store of FailLocal
+	fallbackAllowed scopeCounter  // Checks FailLocal allowed while the store failed
+	fallbackDenied  scopeCounter  // Checks FailLocal denied while the store failed
+	trusted         trustedCallCounter
+	costs           atomic.Pointer[costTable]
+
+	closeOnce sync.Once
+	closeErr  error
 }
 
 // NewLimiter creates a new core rate limiter
 func NewLimiter(config *Config) (Limiter, error) {
-	// Create store
-	var store Store
+	redisConfig := stores.RedisConfig{
+		Address:  config.RedisAddress,
+		Password: config.RedisPassword,
+		Database: config.RedisDB,
+		PoolSize: config.RedisPoolSize,
+		Secrets:  config.RedisSecrets,
+
+		TLS:         config.RedisTLS,
+		LazyConnect: config.RedisLazyConnect,
+	}
+	if redisConfig.PoolSize == 0 {
+		redisConfig.PoolSize = 10 // Default pool size
+	}
 
+	store, replica, err := newStores(config, redisConfig)
+	if err != nil {
+		return nil, err
+	}
+	scopeStores, err := newScopeStores(config, redisConfig)
+	if err != nil {
+		store.Close()
+		if replica != nil {
+			replica.Close()
+		}
+		return nil, err
+	}
+	limiter, err := newLimiter(config, store, replica, scopeStores)
+	if err != nil {
+		store.Close()
+		if replica != nil {
+			replica.Close()
+		}
+		closeStores(scopeStores)
+		return nil, err
+	}
+	return limiter, nil
+}
+
+// newStores creates the default store and, if configured, its read replica
+func newStores(config *Config, redisConfig stores.RedisConfig) (store, replica Store, err error) {
 	switch config.Store {
 	case "memory":
 		memConfig := stores.MemoryConfig{
@@ -163,68 +381,232 @@ func NewLimiter(config *Config) (Limiter, error) {
 		}
 		memStore, err := stores.NewMemoryStore(memConfig)
 		if err != nil {
-			return nil, fmt.Errorf("failed to create memory store: %w", err)
+			return nil, nil, fmt.Errorf("failed to create memory store: %w", err)
 		}
-		store = &storeAdapter{memStore}
+		return &storeAdapter{memStore}, nil, nil
 	case "redis":
-		redisConfig := stores.RedisConfig{
-			Address:  config.RedisAddress,
-			Password: config.RedisPassword,
-			Database: config.RedisDB,
-			PoolSize: config.RedisPoolSize,
-		}
-		if redisConfig.PoolSize == 0 {
-			redisConfig.PoolSize = 10 // Default pool size
+		if len(config.RedisShards) > 0 {
+			shardedStore, err := stores.NewShardedRedisStore(stores.ShardedRedisConfig{
+				Addresses: config.RedisShards,
+				Redis:     redisConfig,
+				Sharding:  stores.ShardedConfig{FailurePolicy: config.RedisShardFailurePolicy},
+			})
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to create sharded redis store: %w", err)
+			}
+			store, err := withLocalCache(config, shardedStore)
+			return store, nil, err
 		}
 		redisStore, err := stores.NewRedisStore(redisConfig)
 		if err != nil {
-			return nil, fmt.Errorf("failed to create redis store: %w", err)
+			return nil, nil, fmt.Errorf("failed to create redis store: %w", err)
+		}
+		var primary stores.ShardBackend = redisStore
+		if config.RedisStandby != "" {
+			standby, err := newStandbyStore(config.RedisStandby, redisConfig)
+			if err != nil {
+				redisStore.Close()
+				return nil, nil, err
+			}
+			failoverStore, err := stores.NewFailoverStore(stores.FailoverConfig{}, redisStore, standby)
+			if err != nil {
+				redisStore.Close()
+				standby.Close()
+				return nil, nil, fmt.Errorf("failed to create failover store: %w", err)
+			}
+			primary = failoverStore
+		}
+		if store, err = withLocalCache(config, primary); err != nil {
+			return nil, nil, err
+		}
+		if config.RedisReadReplica == "" {
+			return store, nil, nil
+		}
+		replicaConfig := redisConfig
+		replicaConfig.Address = config.RedisReadReplica
+		replicaStore, err := stores.NewRedisStore(replicaConfig)
+		if err != nil {
+			store.Close()
+			return nil, nil, fmt.Errorf("failed to create redis read replica store: %w", err)
 		}
-		store = &storeAdapter{redisStore}
+		return store, &storeAdapter{replicaStore}, nil
+	case "postgres":
+		postgresStore, err := stores.NewPostgresStore(stores.PostgresConfig{
+			DSN:     config.PostgresDSN,
+			Driver:  config.PostgresDriver,
+			Table:   config.PostgresTable,
+			DB:      config.PostgresDB,
+			Migrate: true,
+		})
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create postgres store: %w", err)
+		}
+		return &storeAdapter{postgresStore}, nil, nil
 	default:
-		return nil, fmt.Errorf("unsupported store: %s", config.Store)
+		return nil, nil, fmt.Errorf("unsupported store: %s", config.Store)
+	}
+}
+
+// withLocalCache puts a local cache in front of a Redis store if the config asks for one
+func withLocalCache(config *Config, backend stores.ShardBackend) (Store, error) {
+	if config.RedisLocalCache <= 0 {
+		return &storeAdapter{backend}, nil
+	}
+	cache, err := stores.NewLocalCacheStore(backend, stores.LocalCacheConfig{SyncInterval: config.RedisLocalCache})
+	if err != nil {
+		backend.Close()
+		return nil, fmt.Errorf("failed to create local cache: %w", err)
+	}
+	return &storeAdapter{cache}, nil
+}
+
+// NewLimiterWithStore creates a core rate limiter on an existing store. Limiters sharing a
+// store behave like instances of a cluster; the simulation harness uses this with a fake store.
+func NewLimiterWithStore(config *Config, store Store) (Limiter, error) {
+	return NewLimiterWithStores(config, store, nil)
+}
+
+// NewLimiterWithStores creates a core rate limiter on an existing store and an optional
+// read replica of it, which serves stats and inspection reads
+func NewLimiterWithStores(config *Config, store, replica Store) (Limiter, error) {
+	return newLimiter(config, store, replica, nil)
+}
+
+// newLimiter creates a core rate limiter on its stores
+func newLimiter(config *Config, store, replica Store, scopeStores map[string]Store) (Limiter, error) {
+	config.attachLimits()
+	config.attachScopeBudget()
+
+	l := &limiterImpl{
+		config:      config,
+		store:       store,
+		replica:     replica,
+		scopeStores: scopeStores,
+		denials:     newDenialCache(config),
+		usage:       newUsageStats(config),
+		retries:     newRetryPolicy(config),
+	}
+	l.tiers = newTierCache(l)
+	introspection, err := newIntrospectionCache(l)
+	if err != nil {
+		return nil, err
+	}
+	l.introspection = introspection
+	l.expiry = newOverrideExpiry(l)
+
+	// Window calculations follow the store clock when instances' clocks cannot be trusted
+	now := config.Now
+	if config.ClockSource == ClockSourceStore {
+		clock, err := newStoreClock(l)
+		if err != nil {
+			return nil, err
+		}
+		l.clock = clock
+		now = clock.now
 	}
 
 	// Create algorithm
-	var algorithm Algorithm
-	switch config.Algorithm {
-	case "token_bucket":
-		algorithm = &algorithmAdapter{algorithms.NewTokenBucketAlgorithm()}
-	case "sliding_window":
-		algorithm = &algorithmAdapter{algorithms.NewSlidingWindowAlgorithm()}
-	case "gcra":
-		// TODO: Implement GCRA algorithm
-		algorithm = &algorithmAdapter{algorithms.NewSlidingWindowAlgorithm()} // Fallback for now
-	default:
-		return nil, fmt.Errorf("unsupported algorithm: %s", config.Algorithm)
+	l.algorithm, err = newAlgorithm(config, config.Algorithm, config.WindowAlignment, now)
+	if err != nil {
+		return nil, err
+	}
+	if err := l.validateAlgorithmLimits(config.Limits, config.TierLimits); err != nil {
+		return nil, err
+	}
+	if err := l.alignScopes(now); err != nil {
+		return nil, err
 	}
 
-	return &limiterImpl{
-		config:    config,
-		store:     store,
-		algorithm: algorithm,
-	}, nil
+	l.leader = newLeaderElector(l)
+	if err := l.SetCosts(config.Costs); err != nil {
+		return nil, err
+	}
+
+	if len(config.ScopeResets) > 0 {
+		resets, err := newResetCoordinator(l)
+		if err != nil {
+			return nil, err
+		}
+		l.resets = resets
+	}
+
+	// The watch runs a subscription, so it starts only once the configuration is known to be valid
+	if l.keyExpiry, err = newKeyExpiryWatch(l); err != nil {
+		return nil, err
+	}
+	if l.resets != nil {
+		l.resets.start()
+	}
+
+	if l.clock != nil {
+		l.clock.start()
+	}
+	if l.stats = newStatsBuffer(l); l.stats != nil {
+		l.stats.start()
+	}
+	l.expiry.schedule()
+	l.prewarmConfigured()
+
+	return l, nil
 }
 
 // Check performs a rate limit check
 func (l *limiterImpl) Check(ctx context.Context, entity, scope string) (*CoreResult, error) {
-	// Determine the limit for this entity and scope
-	limit, window, err := l.getLimit(entity, scope)
+	return l.CheckN(ctx, entity, scope, DefaultRequestCost)
+}
+
+// CheckN performs a rate limit check for a request consuming n units of quota
+func (l *limiterImpl) CheckN(ctx context.Context, entity, scope string, n int64) (*CoreResult, error) {
+	if n < 1 {
+		return nil, fmt.Errorf("request cost must be at least 1, got %d", n)
+	}
+	entity, scope, err := l.sanitize(entity, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	// Determine the limit for this entity and scope from one snapshot of the limits
+	table := l.limitTable()
+	tier := l.entityTier(ctx, entity)
+	unknownTier := table.unknownTier(tier)
+	if unknownTier != "" {
+		l.unknownTiers.Add(1)
+	}
+	limit, window, err := l.getLimit(table, entity, tier, scope)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get limit: %w", err)
 	}
+	mode := table.enforcement(scope)
+	if mode == EnforcementOff {
+		l.count(entity, scope, true)
+		return unenforced(limit, window), nil
+	}
 
 	// Build the key for this entity and scope
-	key := fmt.Sprintf("ratelimit:%s:%s", entity, scope)
+	key := l.requestKey(entity, scope)
+
+	// Entities far over their limit are denied without asking the store.
+	// Only single-unit denials are cached: they imply a denial at every cost.
+	// Shadow scopes never deny, so they have nothing to cache.
+	if l.denials != nil && mode == EnforcementEnforce {
+		if cached := l.denials.get(key); cached != nil {
+			l.count(entity, scope, false)
+			return cached, nil
+		}
+	}
 
 	// Check the rate limit using the algorithm
-	algResult, err := l.algorithm.Allow(ctx, l.store, key, limit, window, 1)
+	algResult, err := l.algorithmFor(scope).Allow(ctx, l.checkStore(scope), key, limit, window, n)
 	if err != nil {
+		if result := l.storeFailed(ctx, scope, key, limit, window, n, err); result != nil {
+			l.count(entity, scope, result.Allowed)
+			return result, nil
+		}
 		return nil, fmt.Errorf("rate limit check failed: %w", err)
 	}
 
 	// Convert from AlgorithmResult to CoreResult
-	return &CoreResult{
+	result := &CoreResult{
 		Allowed:    algResult.Allowed,
 		Remaining:  algResult.Remaining,
 		Limit:      algResult.Limit,
@@ -232,79 +614,335 @@ func (l *limiterImpl) Check(ctx context.Context, entity, scope string) (*CoreRes
 		RetryAfter: algResult.RetryAfter,
 		Window:     algResult.Window,
 		ResetTime:  algResult.ResetTime,
-	}, nil
-}
+		Alignment:  l.alignmentFor(scope),
 
-// getLimit determines the rate limit for an entity and scope
-func (l *limiterImpl) getLimit(entity, scope string) (int64, time.Duration, error) {
-	// First check for tier-based limits if available
-	if tierLimits, ok := l.config.TierLimits[scope]; ok {
-		// Extract tier from entity (assumes format "tier:entity" or just "tier")
-		tier := "free" // default tier
-		if strings.Contains(entity, ":") {
-			parts := strings.SplitN(entity, ":", 2)
-			if len(parts) == 2 {
-				tier = parts[0]
-			}
+		UnknownTier: unknownTier,
+	}
+	if l.config.Grants {
+		if err := l.applyGrant(ctx, entity, scope, n, result, true); err != nil {
+			// The request is already counted, so a grant that cannot be read is treated as none
+			l.reportError(fmt.Errorf("failed to read the grant of %s in scope %s, checking without it: %w", entity, scope, err))
 		}
+	}
+	if l.denials != nil && !result.Allowed && n == DefaultRequestCost {
+		l.denials.put(key, result)
+	}
+	l.enforce(mode, scope, result, true)
+	l.noteTransition(table, entity, tier, scope, result)
+	l.count(entity, scope, result.Allowed)
+	return result, nil
+}
 
-		if limitStr, ok := tierLimits[tier]; ok {
-			return parseLimit(limitStr)
+// CheckPreAuth performs the pre-authentication check for a request key such as the client IP
+func (l *limiterImpl) CheckPreAuth(ctx context.Context, key string) (*CoreResult, error) {
+	if !l.config.HasPreAuth() {
+		return nil, fmt.Errorf("no pre-auth limit configured")
+	}
+	return l.Check(ctx, key, l.config.PreAuthScopeName())
+}
+
+// Peek returns the current state of a rate limit without consuming quota
+func (l *limiterImpl) Peek(ctx context.Context, entity, scope string) (*CoreResult, error) {
+	entity, scope, err := l.sanitize(entity, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	table := l.limitTable()
+	limit, window, err := l.getLimit(table, entity, l.entityTier(ctx, entity), scope)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get limit: %w", err)
+	}
+	mode := table.enforcement(scope)
+	if mode == EnforcementOff {
+		return unenforced(limit, window), nil
+	}
+
+	key := l.requestKey(entity, scope)
+
+	store, stale := l.readStore(scope)
+	algResult, err := l.algorithmFor(scope).Peek(ctx, store, key, limit, window)
+	if err != nil {
+		return nil, fmt.Errorf("rate limit peek failed: %w", err)
+	}
+
+	result := &CoreResult{
+		Allowed:    algResult.Allowed,
+		Remaining:  algResult.Remaining,
+		Limit:      algResult.Limit,
+		Used:       algResult.Used,
+		RetryAfter: algResult.RetryAfter,
+		Window:     algResult.Window,
+		ResetTime:  algResult.ResetTime,
+		Alignment:  l.alignmentFor(scope),
+		Stale:      stale,
+	}
+	if l.config.Grants {
+		if err := l.applyGrant(ctx, entity, scope, DefaultRequestCost, result, false); err != nil {
+			return nil, err
 		}
 	}
+	l.enforce(mode, scope, result, false)
+	return result, nil
+}
 
-	// Fall back to scope-based limits
-	if limitStr, ok := l.config.Limits[scope]; ok {
-		return parseLimit(limitStr)
+// Limits returns the effective limit of every configured scope for an entity
+func (l *limiterImpl) Limits(entity string) ([]EffectiveLimit, error) {
+	table := l.limitTable()
+	tier := l.entityTier(context.Background(), entity)
+	scopes := make(map[string]bool)
+	for scope := range table.limits {
+		scopes[scope] = true
+	}
+	for scope := range table.tierLimits {
+		scopes[scope] = true
+	}
+	for scope := range table.overrides[entity] {
+		scopes[scope] = true
 	}
 
-	// Fall back to global limit
-	if limitStr, ok := l.config.Limits["global"]; ok {
-		return parseLimit(limitStr)
+	names := make([]string, 0, len(scopes))
+	for scope := range scopes {
+		names = append(names, scope)
+	}
+	sort.Strings(names)
+
+	limits := make([]EffectiveLimit, 0, len(names))
+	for _, scope := range names {
+		limitStr, source := l.resolveLimit(table, entity, tier, scope)
+		if limitStr == "" {
+			continue
+		}
+		requests, window, err := parseLimit(limitStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid limit for scope %s: %w", scope, err)
+		}
+		limits = append(limits, EffectiveLimit{
+			Scope:    scope,
+			Tier:     l.limitTier(table, tier),
+			Rate:     limitStr,
+			Requests: table.scaled(requests),
+			Window:   window,
+			Source:   source,
+		})
 	}
 
-	return 0, 0, fmt.Errorf("no limit configured for scope: %s", scope)
+	return limits, nil
 }
 
-// parseLimit parses a limit string like "100/hour" into requests and duration
-func parseLimit(limitStr string) (int64, time.Duration, error) {
-	parts := strings.Split(limitStr, "/")
-	if len(parts) != 2 {
-		return 0, 0, fmt.Errorf("invalid limit format: %s (expected 'requests/duration')", limitStr)
+// getLimit determines the rate limit for an entity of tier in scope, softened while a
+// limit transition runs
+func (l *limiterImpl) getLimit(table *limitTable, entity, tier, scope string) (int64, time.Duration, error) {
+	limit, window, err := l.settledLimit(table, entity, tier, scope)
+	if err != nil {
+		return 0, 0, err
 	}
+	return l.transitionLimit(table, entity, tier, scope, limit, window), window, nil
+}
 
-	requests, err := strconv.ParseInt(parts[0], 10, 64)
+// settledLimit determines the rate limit the table sets for an entity of tier in scope
+func (l *limiterImpl) settledLimit(table *limitTable, entity, tier, scope string) (int64, time.Duration, error) {
+	if err := l.checkTier(table, tier); err != nil {
+		return 0, 0, err
+	}
+	limitStr, _ := l.resolveLimit(table, entity, tier, scope)
+	if limitStr == "" {
+		return 0, 0, fmt.Errorf("no limit configured for scope: %s", scope)
+	}
+
+	requests, window, err := parseLimit(limitStr)
 	if err != nil {
-		return 0, 0, fmt.Errorf("invalid request count: %s", parts[0])
-	}
-
-	var duration time.Duration
-	switch parts[1] {
-	case "second", "s":
-		duration = time.Second
-	case "minute", "min", "m":
-		duration = time.Minute
-	case "hour", "h":
-		duration = time.Hour
-	case "day", "d":
-		duration = 24 * time.Hour
-	default:
-		// Try to parse as Go duration string
-		duration, err = time.ParseDuration(parts[1])
-		if err != nil {
-			return 0, 0, fmt.Errorf("invalid duration: %s", parts[1])
+		return 0, 0, err
+	}
+	return table.scaled(requests), window, nil
+}
+
+// resolveLimit finds the limit string for an entity of tier in scope along with where it came from
+func (l *limiterImpl) resolveLimit(table *limitTable, entity, tier, scope string) (string, string) {
+	// The pre-auth scope ignores tiers since the caller is not yet known
+	if l.config.HasPreAuth() && scope == l.config.PreAuthScopeName() {
+		return l.config.PreAuthLimit, LimitSourcePreAuth
+	}
+
+	// Entity overrides win over tier and scope limits
+	if limitStr, ok := table.override(entity, scope, l.config.Now()); ok {
+		return limitStr, LimitSourceOverride
+	}
+
+	limitTier := l.limitTier(table, tier)
+	if limitStr, source := table.configuredLimit(limitTier, scope); limitStr != "" {
+		return limitStr, source
+	}
+
+	// Method-qualified scopes inherit the limits of their method class, then of their resource scope
+	if l.config.MethodScoping {
+		if base, method, ok := SplitMethodScope(scope); ok {
+			if class := MethodClass(method); method != class {
+				if limitStr, source := table.configuredLimit(limitTier, MethodScope(base, class)); limitStr != "" {
+					return limitStr, source
+				}
+			}
+			return l.resolveLimit(table, entity, tier, base)
+		}
+	}
+
+	// Versioned scopes inherit the limits of their resource scope
+	if l.config.VersionFunc != nil {
+		if _, base, ok := SplitVersionedScope(scope); ok {
+			return l.resolveLimit(table, entity, tier, base)
 		}
 	}
 
-	return requests, duration, nil
+	// Fall back to global limit
+	if limitStr, ok := table.limits["global"]; ok {
+		return limitStr, LimitSourceGlobal
+	}
+
+	return "", ""
+}
+
+// configuredLimit returns the tier or scope limit configured for exactly this scope
+func (t *limitTable) configuredLimit(tier, scope string) (string, string) {
+	// First check for tier-based limits if available
+	if tierLimits, ok := t.tierLimits[scope]; ok {
+		if limitStr, ok := tierLimits[tier]; ok {
+			return limitStr, LimitSourceTier
+		}
+	}
+
+	// Fall back to scope-based limits
+	if limitStr, ok := t.limits[scope]; ok {
+		return limitStr, LimitSourceScope
+	}
+
+	return "", ""
+}
+
+// tierOf extracts the tier from an entity (assumes format "tier:entity" or just "tier")
+func tierOf(entity string) string {
+	tier := DefaultTier
+	if strings.Contains(entity, ":") {
+		parts := strings.SplitN(entity, ":", 2)
+		if len(parts) == 2 {
+			tier = parts[0]
+		}
+	}
+	return tier
 }
 
 // Health checks if the limiter is healthy
 func (l *limiterImpl) Health(ctx context.Context) error {
-	return l.store.Health(ctx)
+	if err := l.store.Health(ctx); err != nil {
+		return err
+	}
+	return l.scopeStoresHealth(ctx)
 }
 
-// Close cleans up resources
+// StoreKeys returns how many keys the store holds; ok is false for stores that cannot count them cheaply
+func (l *limiterImpl) StoreKeys() (keys int64, ok bool) {
+	adapter, ok := l.store.(*storeAdapter)
+	if !ok {
+		return 0, false
+	}
+	if sized, ok := adapter.store.(interface{ Size() int }); ok {
+		return int64(sized.Size()), true
+	}
+	return 0, false
+}
+
+// StorePoolStats returns the connection pool statistics of Redis-backed stores, or nil for other stores
+func (l *limiterImpl) StorePoolStats() *stores.PoolStats {
+	adapter, ok := l.store.(*storeAdapter)
+	if !ok {
+		return nil
+	}
+	if pooled, ok := adapter.store.(interface{ PoolStats() stores.PoolStats }); ok {
+		stats := pooled.PoolStats()
+		return &stats
+	}
+	return nil
+}
+
+// StoreFailoverStats returns the state of a store with a standby, or nil for other stores
+func (l *limiterImpl) StoreFailoverStats() *stores.FailoverStats {
+	adapter, ok := l.store.(*storeAdapter)
+	if !ok {
+		return nil
+	}
+	store := adapter.store
+	if cache, ok := store.(*stores.LocalCacheStore); ok {
+		store = cache.Backend()
+	}
+	if failover, ok := store.(*stores.FailoverStore); ok {
+		stats := failover.FailoverStats()
+		return &stats
+	}
+	return nil
+}
+
+// StoreLocalCacheStats returns the hits and drift of the local cache in front of Redis, or
+// nil without one
+func (l *limiterImpl) StoreLocalCacheStats() *stores.LocalCacheStats {
+	adapter, ok := l.store.(*storeAdapter)
+	if !ok {
+		return nil
+	}
+	if cache, ok := adapter.store.(*stores.LocalCacheStore); ok {
+		stats := cache.CacheStats()
+		return &stats
+	}
+	return nil
+}
+
+// newStandbyStore creates the standby of a Redis primary: a memory store or a second Redis
+// configured like the primary
+func newStandbyStore(standby string, redisConfig stores.RedisConfig) (stores.ShardBackend, error) {
+	if standby == "memory" {
+		memStore, err := stores.NewMemoryStore(stores.MemoryConfig{CleanupInterval: 10 * time.Minute})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create memory standby store: %w", err)
+		}
+		return memStore, nil
+	}
+	redisConfig.Address = standby
+	redisStore, err := stores.NewRedisStore(redisConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create redis standby store: %w", err)
+	}
+	return redisStore, nil
+}
+
+// Close cleans up resources; later calls return the result of the first
 func (l *limiterImpl) Close() error {
+	l.closeOnce.Do(func() { l.closeErr = l.close() })
+	return l.closeErr
+}
+
+// close stops the background work of the limiter and closes its stores
+func (l *limiterImpl) close() error {
+	if l.leader != nil {
+		l.leader.close()
+	}
+	if l.resets != nil {
+		l.resets.close()
+	}
+	if l.stats != nil {
+		l.stats.close()
+	}
+	if l.clock != nil {
+		l.clock.close()
+	}
+	if l.tiers != nil {
+		l.tiers.close()
+	}
+	l.expiry.close()
+	if l.keyExpiry != nil {
+		l.keyExpiry.close()
+	}
+	if l.replica != nil {
+		l.replica.Close()
+	}
+	l.fallback.close()
+	closeStores(l.scopeStores)
 	return l.store.Close()
 }
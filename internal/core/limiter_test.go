@@ -0,0 +1,64 @@
+// internal/core/limiter_test.go
+package core
+
+import (
+	"testing"
+
+	"github.com/itsatony/gorly/stores"
+)
+
+// newTestLimiter creates a limiter on store, or on a fresh memory store if store is nil,
+// and closes it when the test ends. The sliding window algorithm is used unless config
+// names another.
+func newTestLimiter(t *testing.T, config *Config, store Store) *limiterImpl {
+	t.Helper()
+	if config.Algorithm == "" {
+		config.Algorithm = "sliding_window"
+	}
+	if store == nil {
+		store = newTestStore(t)
+	}
+	limiter, err := NewLimiterWithStore(config, store)
+	if err != nil {
+		t.Fatalf("Failed to create limiter: %v", err)
+	}
+	l := limiter.(*limiterImpl)
+	t.Cleanup(func() { l.Close() })
+	return l
+}
+
+// newTestStore creates a memory store that lives until the test ends. Closing a limiter
+// leaves it open, so instances of a cluster can share it.
+func newTestStore(t *testing.T) Store {
+	t.Helper()
+
+	memStore, err := stores.NewMemoryStore(stores.MemoryConfig{})
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	t.Cleanup(func() { memStore.Close() })
+	return &storeAdapter{testMemoryStore{memStore}}
+}
+
+// testMemoryStore is a memory store owned by a test rather than by the limiters using it
+type testMemoryStore struct {
+	*stores.MemoryStore
+}
+
+func (testMemoryStore) Close() error {
+	return nil
+}
+
+func TestCloseIsIdempotent(t *testing.T) {
+	l := newTestLimiter(t, &Config{
+		Limits:           map[string]string{"global": "10/minute"},
+		ScopeResets:      map[string]string{"global": "@daily"},
+		StatsWriteBehind: true,
+	}, nil)
+	if err := l.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if err := l.Close(); err != nil {
+		t.Errorf("Expected a second Close to succeed, got %v", err)
+	}
+}
@@ -0,0 +1,85 @@
+// internal/core/limitparse.go
+package core
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// windowUnits are the named units of limit windows; a count may precede them, as in "15min" or "2w"
+var windowUnits = map[string]time.Duration{
+	"s": time.Second, "sec": time.Second, "second": time.Second, "seconds": time.Second,
+	"m": time.Minute, "min": time.Minute, "minute": time.Minute, "minutes": time.Minute,
+	"h": time.Hour, "hr": time.Hour, "hour": time.Hour, "hours": time.Hour,
+	"d": 24 * time.Hour, "day": 24 * time.Hour, "days": 24 * time.Hour,
+	"w": 7 * 24 * time.Hour, "week": 7 * 24 * time.Hour, "weeks": 7 * 24 * time.Hour,
+	"mo": 30 * 24 * time.Hour, "month": 30 * 24 * time.Hour, "months": 30 * 24 * time.Hour, // Fixed 30-day windows
+}
+
+// ParseLimit parses a limit string such as "100/hour", "500/5m", "1000/1d" or
+// "100/minute burst 20" into its requests, window and burst (0 without one). Windows are
+// a named unit with an optional count, or a Go duration such as "1h30m".
+func ParseLimit(limitStr string) (requests int64, window time.Duration, burst int64, err error) {
+	rate, burstStr, hasBurst := strings.Cut(strings.TrimSpace(limitStr), " burst ")
+	parts := strings.Split(rate, "/")
+	if len(parts) != 2 {
+		return 0, 0, 0, fmt.Errorf("invalid limit format: %s (expected 'requests/duration')", limitStr)
+	}
+
+	requests, err = strconv.ParseInt(strings.TrimSpace(parts[0]), 10, 64)
+	if err != nil || requests < 0 {
+		return 0, 0, 0, fmt.Errorf("invalid request count: %s", parts[0])
+	}
+
+	window, err = parseWindow(strings.TrimSpace(parts[1]))
+	if err != nil {
+		return 0, 0, 0, err
+	}
+
+	if hasBurst {
+		burst, err = strconv.ParseInt(strings.TrimSpace(burstStr), 10, 64)
+		if err != nil || burst < 1 {
+			return 0, 0, 0, fmt.Errorf("invalid burst: %s", burstStr)
+		}
+	}
+	return requests, window, burst, nil
+}
+
+// parseLimit parses a limit of the builder API into requests and window. Its algorithms
+// size buckets by the requests, so a burst is rejected rather than ignored.
+func parseLimit(limitStr string) (int64, time.Duration, error) {
+	requests, window, burst, err := ParseLimit(limitStr)
+	if err != nil {
+		return 0, 0, err
+	}
+	if burst != 0 {
+		return 0, 0, fmt.Errorf("invalid limit %s: bursts are only supported in config files", limitStr)
+	}
+	return requests, window, nil
+}
+
+// parseWindow parses the duration part of a limit string like "hour", "15min" or "30s"
+func parseWindow(window string) (time.Duration, error) {
+	lower := strings.ToLower(window)
+	digits := len(lower) - len(strings.TrimLeft(lower, "0123456789"))
+	if unit, ok := windowUnits[lower[digits:]]; ok {
+		count := int64(1)
+		if digits > 0 {
+			parsed, err := strconv.ParseInt(lower[:digits], 10, 64)
+			if err != nil || parsed < 1 {
+				return 0, fmt.Errorf("invalid duration: %s", window)
+			}
+			count = parsed
+		}
+		return time.Duration(count) * unit, nil
+	}
+
+	// Try to parse as Go duration string
+	duration, err := time.ParseDuration(window)
+	if err != nil || duration <= 0 {
+		return 0, fmt.Errorf("invalid duration: %s", window)
+	}
+	return duration, nil
+}
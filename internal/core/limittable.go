@@ -0,0 +1,194 @@
+// internal/core/limittable.go
+package core
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"sync/atomic"
+)
+
+// limitTable is an immutable snapshot of the request limits and their scale.
+// Runtime changes build a new table and swap it in, so checks never wait for an
+// update and every check evaluates a single table: a reload cannot pair the new
+// limit of one scope with the old scale, or a new tier limit with an old fallback.
+type limitTable struct {
+	limits     map[string]string              // scope -> limit
+	tierLimits map[string]map[string]string   // scope -> tier -> limit
+	overrides  map[string]map[string]Override // entity -> scope -> override
+	modes      map[string]string              // scope -> enforcement mode
+	scale      float64
+	generation int64            // 1 for the built configuration, incremented by every update
+	version    string           // Label of the configuration last applied, if the update named one
+	transition *limitTransition // Softens limits that shrank in the update building the table
+}
+
+// liveLimits holds the current limit table of a config
+type liveLimits struct {
+	table atomic.Pointer[limitTable]
+	mu    sync.Mutex // Serializes updates, which derive the next table from the current one
+}
+
+// LimitUpdate replaces request limits at runtime. Nil maps and a zero scale keep their current values.
+type LimitUpdate struct {
+	Limits     map[string]string            // scope -> limit; replaces every scope limit
+	TierLimits map[string]map[string]string // scope -> tier -> limit; replaces the tiers of the listed scopes
+	Overrides  []Override                   // Replaces every entity override
+	Modes      map[string]string            // Replaces every scope's enforcement mode
+	Scale      float64                      // Limit multiplier
+	Version    string                       // Labels the applied configuration, e.g. a hot-reload version
+}
+
+// newLimitTable builds the initial table of a config
+func newLimitTable(c *Config) *limitTable {
+	scale := c.Scale
+	if scale == 0 {
+		scale = 1
+	}
+	return &limitTable{limits: c.Limits, tierLimits: c.TierLimits, overrides: indexOverrides(c.Overrides, c.Now()), modes: c.ScopeEnforcement, scale: scale, generation: 1}
+}
+
+// limitTable returns the current limits. Configs not yet attached to a limiter use their static limits.
+func (c *Config) limitTable() *limitTable {
+	if c.live != nil {
+		if table := c.live.table.Load(); table != nil {
+			return table
+		}
+	}
+	return newLimitTable(c)
+}
+
+// attachLimits makes the config's limits adjustable at runtime. Limiters built from the
+// same config share its limits, as they share every other setting.
+func (c *Config) attachLimits() {
+	if c.live == nil {
+		c.live = &liveLimits{}
+	}
+	table := newLimitTable(c)
+	table.limits = copyLimits(table.limits)
+	table.tierLimits = make(map[string]map[string]string, len(c.TierLimits))
+	for scope, tiers := range c.TierLimits {
+		table.tierLimits[scope] = copyLimits(tiers)
+	}
+	c.live.table.CompareAndSwap(nil, table)
+}
+
+// UpdateLimits swaps in new request limits. The update is validated in full first and
+// either applied as a whole or not at all; in-flight checks finish with the limits they started with.
+func (l *limiterImpl) UpdateLimits(update LimitUpdate) error {
+	if err := l.config.validateUpdateScopes(update); err != nil {
+		return err
+	}
+	for scope, limit := range update.Limits {
+		if _, _, err := parseLimit(limit); err != nil {
+			return fmt.Errorf("invalid limit for scope %s: %w", scope, err)
+		}
+	}
+	for scope, tiers := range update.TierLimits {
+		for tier, limit := range tiers {
+			if _, _, err := parseLimit(limit); err != nil {
+				return fmt.Errorf("invalid %s tier limit for scope %s: %w", tier, scope, err)
+			}
+		}
+	}
+	if err := l.validateAlgorithmLimits(update.Limits, update.TierLimits); err != nil {
+		return err
+	}
+	if err := validateOverrides(update.Overrides); err != nil {
+		return err
+	}
+	if err := validateEnforcement(update.Modes); err != nil {
+		return err
+	}
+	if update.Scale != 0 {
+		if err := validateScale(update.Scale); err != nil {
+			return err
+		}
+	}
+	live := l.config.live
+	live.mu.Lock()
+	defer live.mu.Unlock()
+
+	current := live.table.Load()
+	next := &limitTable{limits: current.limits, tierLimits: current.tierLimits, overrides: current.overrides, modes: current.modes, scale: current.scale,
+		generation: current.generation + 1, version: current.version}
+	if update.Limits != nil {
+		next.limits = copyLimits(update.Limits)
+	}
+	if update.TierLimits != nil {
+		next.tierLimits = make(map[string]map[string]string, len(current.tierLimits)+len(update.TierLimits))
+		for scope, tiers := range current.tierLimits {
+			next.tierLimits[scope] = tiers
+		}
+		for scope, tiers := range update.TierLimits {
+			next.tierLimits[scope] = copyLimits(tiers)
+		}
+	}
+	if update.Overrides != nil {
+		next.overrides = indexOverrides(update.Overrides, l.config.Now())
+	}
+	if update.Modes != nil {
+		next.modes = copyLimits(update.Modes)
+	}
+	if update.Scale != 0 {
+		next.scale = update.Scale
+	}
+	if update.Version != "" {
+		next.version = update.Version
+	}
+	if len(next.limits) == 0 && len(next.tierLimits) == 0 && len(l.config.BandwidthLimits) == 0 {
+		return errors.New("at least one rate limit must be configured")
+	}
+	var events []LimitTransitionEvent
+	next.transition, events = l.newTransition(current, next)
+	live.table.Store(next)
+	if l.config.OnLimitTransition != nil {
+		for _, event := range events {
+			l.config.OnLimitTransition(event)
+		}
+	}
+
+	// Cached denials were decided under the old limits
+	if l.denials != nil {
+		l.denials.clear()
+	}
+	if update.Overrides != nil {
+		l.expiry.schedule()
+	}
+	return nil
+}
+
+// ConfigVersion returns how often the limits were updated since the limiter was built
+// (starting at 1) and the label of the configuration last applied
+func (l *limiterImpl) ConfigVersion() (generation int64, version string) {
+	table := l.limitTable()
+	return table.generation, table.version
+}
+
+// scaled applies the table's multiplier to a configured amount, never scaling a limit below 1
+func (t *limitTable) scaled(amount int64) int64 {
+	if t.scale == 1 {
+		return amount
+	}
+
+	scaled := int64(math.Round(float64(amount) * t.scale))
+	if scaled < 1 {
+		scaled = 1
+	}
+	return scaled
+}
+
+// limitTable returns the limits checks are currently evaluated against
+func (l *limiterImpl) limitTable() *limitTable {
+	return l.config.limitTable()
+}
+
+// copyLimits copies a limit map so later changes by the caller cannot tear a table
+func copyLimits(src map[string]string) map[string]string {
+	dst := make(map[string]string, len(src))
+	for k, v := range src {
+		dst[k] = v
+	}
+	return dst
+}
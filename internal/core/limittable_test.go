@@ -0,0 +1,73 @@
+// internal/core/limittable_test.go
+package core
+
+import (
+	"context"
+	"testing"
+)
+
+func TestUpdateLimits(t *testing.T) {
+	limiter := newTestLimiter(t, &Config{
+		Algorithm: "sliding_window",
+		Limits:    map[string]string{"global": "2/minute"},
+	}, nil)
+	ctx := context.Background()
+
+	limit := func(entity, scope string) int64 {
+		t.Helper()
+		result, err := limiter.Check(ctx, entity, scope)
+		if err != nil {
+			t.Fatalf("Check failed: %v", err)
+		}
+		return result.Limit
+	}
+
+	// An update with any invalid part is rejected as a whole
+	err := limiter.UpdateLimits(LimitUpdate{
+		Limits:     map[string]string{"global": "5/minute"},
+		TierLimits: map[string]map[string]string{"global": {"premium": "lots"}},
+	})
+	if err == nil {
+		t.Fatal("Expected invalid tier limit to be rejected")
+	}
+	if got := limit("user-1", "global"); got != 2 {
+		t.Errorf("Expected the rejected update to leave limit 2, got %d", got)
+	}
+	if err := limiter.UpdateLimits(LimitUpdate{Limits: map[string]string{}}); err == nil {
+		t.Error("Expected an update removing every limit to be rejected")
+	}
+
+	limits := map[string]string{"global": "5/minute", "upload": "1/minute"}
+	if err := limiter.UpdateLimits(LimitUpdate{Limits: limits, Scale: 2}); err != nil {
+		t.Fatalf("Failed to update limits: %v", err)
+	}
+	limits["global"] = "50/minute"
+	if got := limit("user-2", "global"); got != 10 {
+		t.Errorf("Expected scaled limit 10 unaffected by later map changes, got %d", got)
+	}
+	if got := limit("user-2", "upload"); got != 2 {
+		t.Errorf("Expected new upload scope with limit 2, got %d", got)
+	}
+	if !limiter.config.HasRequestLimit("upload") {
+		t.Error("Expected the config to report the new scope")
+	}
+
+	// Scale-only updates keep the limits
+	if err := limiter.SetScale(1); err != nil {
+		t.Fatalf("Failed to scale: %v", err)
+	}
+	if got := limit("user-3", "global"); got != 5 {
+		t.Errorf("Expected unscaled limit 5, got %d", got)
+	}
+
+	// Rejected updates leave the generation alone; a version sticks until the next one names another
+	if err := limiter.UpdateLimits(LimitUpdate{Scale: 1, Version: "v7"}); err != nil {
+		t.Fatalf("Failed to update limits: %v", err)
+	}
+	if err := limiter.SetScale(2); err != nil {
+		t.Fatalf("Failed to scale: %v", err)
+	}
+	if generation, version := limiter.ConfigVersion(); generation != 5 || version != "v7" {
+		t.Errorf("Expected generation 5 of v7, got %d of %q", generation, version)
+	}
+}
@@ -0,0 +1,153 @@
+// internal/core/lockout.go
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/itsatony/gorly/stores"
+)
+
+// LockoutConfig configures exponential lockouts after repeated authentication failures
+type LockoutConfig struct {
+	Threshold      int           // Failures before the first lockout
+	BaseDuration   time.Duration // First lockout duration, doubled for every further failure
+	MaxDuration    time.Duration // Upper bound for a single lockout; also how long failures are remembered
+	ChallengeAfter int           // Failures before ChallengeHandler is consulted (0 disables challenges)
+	Scopes         []string      // Scopes the lockout applies to (empty means all scopes)
+}
+
+// LockoutState is the failure history of an entity
+type LockoutState struct {
+	Failures    int64
+	LockedUntil time.Time
+}
+
+// Locked reports whether the entity is locked out at now
+func (s *LockoutState) Locked(now time.Time) bool {
+	return now.Before(s.LockedUntil)
+}
+
+// AppliesTo reports whether the lockout covers a scope
+func (lc *LockoutConfig) AppliesTo(scope string) bool {
+	if len(lc.Scopes) == 0 {
+		return true
+	}
+	for _, s := range lc.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// duration returns the lockout duration for a failure count
+func (lc *LockoutConfig) duration(failures int64) time.Duration {
+	if failures < int64(lc.Threshold) {
+		return 0
+	}
+
+	d := lc.BaseDuration
+	for i := int64(lc.Threshold); i < failures && d < lc.MaxDuration; i++ {
+		d *= 2
+	}
+	if d > lc.MaxDuration {
+		d = lc.MaxDuration
+	}
+	return d
+}
+
+// validate checks the lockout settings
+func (lc *LockoutConfig) validate() error {
+	if lc.Threshold <= 0 {
+		return fmt.Errorf("lockout threshold must be positive")
+	}
+	if lc.BaseDuration <= 0 || lc.MaxDuration < lc.BaseDuration {
+		return fmt.Errorf("lockout durations must be positive with max >= base")
+	}
+	if lc.ChallengeAfter < 0 {
+		return fmt.Errorf("lockout challenge threshold cannot be negative")
+	}
+	return nil
+}
+
+// RecordFailure counts a failed authentication for an entity and locks it out once the threshold is reached
+func (l *limiterImpl) RecordFailure(ctx context.Context, entity string) (*LockoutState, error) {
+	lc := l.config.Lockout
+	if lc == nil {
+		return nil, fmt.Errorf("no lockout configured")
+	}
+	entity, err := l.config.SanitizeEntity(entity)
+	if err != nil {
+		return nil, err
+	}
+
+	failures, err := l.store.IncrementBy(ctx, l.lockoutFailuresKey(entity), 1, lc.MaxDuration)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record authentication failure: %w", err)
+	}
+
+	state := &LockoutState{Failures: failures}
+	if d := lc.duration(failures); d > 0 {
+		state.LockedUntil = l.config.Now().Add(d)
+	}
+
+	// The counter is authoritative; the state snapshot lets requests read it without writing
+	data, err := json.Marshal(state)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode lockout state: %w", err)
+	}
+	if err := l.store.Set(ctx, l.lockoutKey(entity), data, lc.MaxDuration); err != nil {
+		return nil, fmt.Errorf("failed to store lockout state: %w", err)
+	}
+
+	return state, nil
+}
+
+// RecordSuccess clears the failure history of an entity after a successful authentication
+func (l *limiterImpl) RecordSuccess(ctx context.Context, entity string) error {
+	entity, err := l.config.SanitizeEntity(entity)
+	if err != nil {
+		return err
+	}
+	if err := l.store.Delete(ctx, l.lockoutFailuresKey(entity)); err != nil {
+		return fmt.Errorf("failed to clear authentication failures: %w", err)
+	}
+	if err := l.store.Delete(ctx, l.lockoutKey(entity)); err != nil {
+		return fmt.Errorf("failed to clear lockout state: %w", err)
+	}
+	return nil
+}
+
+// Lockout returns the current failure history of an entity
+func (l *limiterImpl) Lockout(ctx context.Context, entity string) (*LockoutState, error) {
+	entity, err := l.config.SanitizeEntity(entity)
+	if err != nil {
+		return nil, err
+	}
+	data, err := l.store.Get(ctx, l.lockoutKey(entity))
+	if err != nil {
+		if stores.IsNotFound(err) {
+			return &LockoutState{}, nil
+		}
+		return nil, fmt.Errorf("failed to read lockout state: %w", err)
+	}
+
+	var state LockoutState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("invalid lockout state: %w", err)
+	}
+	return &state, nil
+}
+
+// lockoutFailuresKey is the store key counting failures of an entity
+func (l *limiterImpl) lockoutFailuresKey(entity string) string {
+	return l.config.keys().Build("lockout", entity, "failures")
+}
+
+// lockoutKey is the store key holding the lockout state of an entity
+func (l *limiterImpl) lockoutKey(entity string) string {
+	return l.config.keys().Build("lockout", entity)
+}
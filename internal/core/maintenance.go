@@ -0,0 +1,69 @@
+// internal/core/maintenance.go
+package core
+
+import (
+	"net/http"
+	"time"
+)
+
+// DefaultMaintenanceMessage is returned to callers blocked by maintenance mode
+const DefaultMaintenanceMessage = "Service is under maintenance"
+
+// DefaultMaintenanceRetryAfter is the Retry-After suggested during maintenance
+const DefaultMaintenanceRetryAfter = 5 * time.Minute
+
+// maintenanceState is the allowlist of an active maintenance window
+type maintenanceState struct {
+	allowlist map[string]bool
+}
+
+// MaintenanceStatus returns the HTTP status for requests blocked by maintenance mode
+func (c *Config) MaintenanceStatus() int {
+	if c.MaintenanceStatusCode != 0 {
+		return c.MaintenanceStatusCode
+	}
+	return http.StatusServiceUnavailable
+}
+
+// SetMaintenance enables or disables maintenance mode; only allowlisted keys pass while enabled
+func (l *limiterImpl) SetMaintenance(enabled bool, allowlist []string) {
+	if !enabled {
+		l.maintenance.Store(nil)
+		return
+	}
+
+	state := &maintenanceState{allowlist: make(map[string]bool, len(allowlist))}
+	for _, key := range allowlist {
+		state.allowlist[key] = true
+	}
+	l.maintenance.Store(state)
+}
+
+// CheckMaintenance returns a denial when maintenance mode is enabled and none of the keys
+// (typically the entity and client IP) are allowlisted, or nil when the caller may proceed
+func (l *limiterImpl) CheckMaintenance(keys ...string) *CoreResult {
+	state := l.maintenance.Load()
+	if state == nil {
+		return nil
+	}
+	for _, key := range keys {
+		if state.allowlist[key] {
+			return nil
+		}
+	}
+	return l.maintenanceResult()
+}
+
+// maintenanceResult is the denial returned for requests blocked by maintenance mode
+func (l *limiterImpl) maintenanceResult() *CoreResult {
+	retryAfter := l.config.MaintenanceRetryAfter
+	if retryAfter == 0 {
+		retryAfter = DefaultMaintenanceRetryAfter
+	}
+	return &CoreResult{
+		Allowed:     false,
+		RetryAfter:  retryAfter,
+		ResetTime:   time.Now().Add(retryAfter),
+		Maintenance: true,
+	}
+}
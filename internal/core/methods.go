@@ -0,0 +1,71 @@
+// internal/core/methods.go
+package core
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Method classes that method-qualified scopes fall back to
+const (
+	MethodClassRead  = "read"  // GET, HEAD and OPTIONS
+	MethodClassWrite = "write" // Every other method
+)
+
+// MethodScope qualifies a scope with an HTTP method or method class, e.g. "search" and "GET" into "search:GET".
+// Methods are upper-cased and classes lower-cased so both spellings configure the same scope.
+func MethodScope(scope, method string) string {
+	if class := strings.ToLower(method); class == MethodClassRead || class == MethodClassWrite {
+		return scope + ":" + class
+	}
+	return scope + ":" + strings.ToUpper(method)
+}
+
+// MethodQualifiedScope returns the scope a request with the given method is counted in.
+// A scope configured for the exact method wins; otherwise a configured method class is shared
+// by all of its methods, so "search:write" caps POST, PUT and DELETE together.
+// Without either, each method is counted separately under the limits of the plain scope.
+func (c *Config) MethodQualifiedScope(scope, method string) string {
+	exact := MethodScope(scope, method)
+	if c.isConfiguredScope(exact) {
+		return exact
+	}
+	if class := MethodScope(scope, MethodClass(method)); c.isConfiguredScope(class) {
+		return class
+	}
+	return exact
+}
+
+// SplitMethodScope splits a scope like "search:GET" or "search:write" into its resource scope and method
+func SplitMethodScope(scope string) (string, string, bool) {
+	i := strings.LastIndexByte(scope, ':')
+	if i < 0 {
+		return scope, "", false
+	}
+	base, method := scope[:i], scope[i+1:]
+	if base == "" || !isMethodQualifier(method) {
+		return scope, "", false
+	}
+	return base, method, true
+}
+
+// MethodClass returns the class of an HTTP method: MethodClassRead for safe methods, otherwise MethodClassWrite
+func MethodClass(method string) string {
+	switch strings.ToUpper(method) {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return MethodClassRead
+	default:
+		return MethodClassWrite
+	}
+}
+
+// isMethodQualifier reports whether s is a standard HTTP method or a method class
+func isMethodQualifier(s string) bool {
+	switch s {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace,
+		MethodClassRead, MethodClassWrite:
+		return true
+	}
+	return false
+}
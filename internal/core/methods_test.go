@@ -0,0 +1,74 @@
+// internal/core/methods_test.go
+package core
+
+import (
+	"testing"
+)
+
+func TestSplitMethodScope(t *testing.T) {
+	tests := []struct {
+		scope, base, method string
+		ok                  bool
+	}{
+		{"search:GET", "search", "GET", true},
+		{"v1:search:write", "v1:search", "write", true},
+		{"search", "search", "", false},
+		{"v1:search", "v1:search", "", false},
+		{"search:get", "search:get", "", false},
+		{":GET", ":GET", "", false},
+	}
+	for _, tt := range tests {
+		base, method, ok := SplitMethodScope(tt.scope)
+		if base != tt.base || method != tt.method || ok != tt.ok {
+			t.Errorf("SplitMethodScope(%q) = %q, %q, %v; want %q, %q, %v", tt.scope, base, method, ok, tt.base, tt.method, tt.ok)
+		}
+	}
+
+	if got := MethodScope("search", "post"); got != "search:POST" {
+		t.Errorf("Expected methods to be upper-cased, got %q", got)
+	}
+	if got := MethodScope("search", "READ"); got != "search:read" {
+		t.Errorf("Expected classes to be lower-cased, got %q", got)
+	}
+}
+
+func TestMethodScopedLimits(t *testing.T) {
+	l := newTestLimiter(t, &Config{
+		Algorithm: "sliding_window",
+		Limits: map[string]string{
+			"global":       "10/minute",
+			"search":       "1000/minute",
+			"search:write": "100/minute",
+			"search:PATCH": "5/minute",
+		},
+		MethodScoping: true,
+	}, nil)
+
+	tests := map[string]string{
+		"search:GET":    "1000/minute", // no read class limit, so the plain scope applies
+		"search:POST":   "100/minute",
+		"search:PATCH":  "5/minute",
+		"orders:DELETE": "10/minute",
+	}
+	for scope, want := range tests {
+		if got, _ := l.resolveLimit(l.limitTable(), "user-1", DefaultTier, scope); got != want {
+			t.Errorf("%s: expected %s, got %s", scope, want, got)
+		}
+	}
+}
+
+func TestMethodQualifiedScope(t *testing.T) {
+	c := &Config{Limits: map[string]string{"search:write": "100/minute", "search:DELETE": "5/minute"}}
+
+	tests := map[string]string{
+		"POST":   "search:write",
+		"PUT":    "search:write",
+		"DELETE": "search:DELETE",
+		"GET":    "search:GET",
+	}
+	for method, want := range tests {
+		if got := c.MethodQualifiedScope("search", method); got != want {
+			t.Errorf("%s: expected %s, got %s", method, want, got)
+		}
+	}
+}
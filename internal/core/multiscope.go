@@ -0,0 +1,372 @@
+// internal/core/multiscope.go
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/itsatony/gorly/stores"
+)
+
+// multiScopeAttempts bounds how often a multi-scope check is retried after concurrent
+// checks changed one of its keys between staging and commit
+const multiScopeAttempts = 5
+
+// ScopeCost is one scope of a multi-scope check and the units the request consumes there
+type ScopeCost struct {
+	Scope string
+	Cost  int64
+}
+
+// MultiScopeResult is the outcome of an all-or-nothing check across several scopes
+type MultiScopeResult struct {
+	Allowed bool
+	Denied  string        // First scope that denied the request
+	Results []*CoreResult // One per scope, in the order they were given
+}
+
+// multiSwapper is implemented by stores that can write several keys atomically
+type multiSwapper interface {
+	CompareAndSwapMulti(ctx context.Context, swaps []stores.Swap) (bool, error)
+}
+
+// scopeTarget is a resolved scope of a multi-scope check
+type scopeTarget struct {
+	scope  string
+	cost   int64
+	limit  int64
+	window time.Duration
+	mode   string
+	key    string
+}
+
+// CheckAll consumes cost units in every scope or in none. Each scope is evaluated by the
+// algorithm against a staged view of the store, and the staged state of all scopes is
+// written by one compare-and-swap, so a scope denying the request leaves the budgets of
+// the others untouched. Counters of fixed window scopes are incremented at once and taken
+// back if the check is denied. Grants and the denial cache are not consulted.
+func (l *limiterImpl) CheckAll(ctx context.Context, entity string, costs []ScopeCost) (*MultiScopeResult, error) {
+	if len(costs) == 0 {
+		return nil, errors.New("at least one scope is required")
+	}
+	entity, err := l.config.SanitizeEntity(entity)
+	if err != nil {
+		return nil, err
+	}
+
+	table := l.limitTable()
+	tier := l.entityTier(ctx, entity)
+	unknownTier := table.unknownTier(tier)
+	if unknownTier != "" {
+		l.unknownTiers.Add(1)
+	}
+	targets := make([]scopeTarget, len(costs))
+	seen := make(map[string]bool, len(costs))
+	for i, cost := range costs {
+		if cost.Cost < 1 {
+			return nil, fmt.Errorf("request cost must be at least 1, got %d in scope %s", cost.Cost, cost.Scope)
+		}
+		scope, err := l.config.SanitizeScope(cost.Scope)
+		if err != nil {
+			return nil, err
+		}
+		if seen[scope] {
+			return nil, fmt.Errorf("scope %s is listed twice", scope)
+		}
+		seen[scope] = true
+		limit, window, err := l.getLimit(table, entity, tier, scope)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get limit: %w", err)
+		}
+		targets[i] = scopeTarget{
+			scope:  scope,
+			cost:   cost.Cost,
+			limit:  limit,
+			window: window,
+			mode:   table.enforcement(scope),
+			key:    l.requestKey(entity, scope),
+		}
+	}
+
+	// One compare-and-swap commits every scope, so they must share a store
+	store := l.storeFor(targets[0].scope)
+	for _, target := range targets[1:] {
+		if l.storeFor(target.scope) != store {
+			return nil, fmt.Errorf("scopes %s and %s are kept in different stores", targets[0].scope, target.scope)
+		}
+	}
+	swapper := storeMultiSwapper(store)
+	if swapper == nil {
+		return nil, errors.New("the store does not support atomic multi-scope checks")
+	}
+
+	for attempt := 0; attempt < multiScopeAttempts; attempt++ {
+		result, stage, err := l.stageAll(ctx, l.retries.wrap(store), targets)
+		if err != nil {
+			stage.release(ctx)
+			return nil, err
+		}
+		if !result.Allowed {
+			if err := stage.release(ctx); err != nil {
+				return nil, fmt.Errorf("failed to release counters of a denied check: %w", err)
+			}
+		} else if swaps := stage.swaps(); len(swaps) > 0 {
+			swapped, err := swapper.CompareAndSwapMulti(ctx, swaps)
+			if err != nil {
+				// Nothing was committed, so the counters are taken back whatever the policy decides
+				stage.release(ctx)
+				result, err = l.failAllOpen(ctx, targets, result, err)
+				if err != nil {
+					return nil, err
+				}
+			} else if !swapped {
+				if err := stage.release(ctx); err != nil {
+					return nil, fmt.Errorf("failed to release counters of a conflicting check: %w", err)
+				}
+				continue
+			}
+		}
+		l.finishAll(table, entity, tier, targets, result, unknownTier)
+		return result, nil
+	}
+	return nil, fmt.Errorf("multi-scope check conflicted with concurrent checks %d times", multiScopeAttempts)
+}
+
+// stageAll evaluates every scope against one staged view of the store and returns the
+// combined result and the stage holding the writes that commit it
+func (l *limiterImpl) stageAll(ctx context.Context, store Store, targets []scopeTarget) (*MultiScopeResult, *stagingStore, error) {
+	stage := newStagingStore(store)
+	result := &MultiScopeResult{Allowed: true, Results: make([]*CoreResult, len(targets))}
+	for i, target := range targets {
+		if target.mode == EnforcementOff {
+			result.Results[i] = unenforced(target.limit, target.window)
+			continue
+		}
+
+		algResult, err := l.algorithmFor(target.scope).Allow(ctx, stage, target.key, target.limit, target.window, target.cost)
+		if err == nil {
+			err = stage.takeErr()
+		}
+		if err != nil {
+			failed := l.storeFailed(ctx, target.scope, target.key, target.limit, target.window, target.cost, err)
+			if failed == nil {
+				return nil, stage, fmt.Errorf("rate limit check failed: %w", err)
+			}
+			stage.discard(target.key)
+			if !failed.Allowed && result.Allowed {
+				result.Allowed = false
+				result.Denied = target.scope
+			}
+			result.Results[i] = failed
+			continue
+		}
+
+		scopeResult := &CoreResult{
+			Allowed:    algResult.Allowed,
+			Remaining:  algResult.Remaining,
+			Limit:      algResult.Limit,
+			Used:       algResult.Used,
+			RetryAfter: algResult.RetryAfter,
+			Window:     algResult.Window,
+			ResetTime:  algResult.ResetTime,
+			Alignment:  l.alignmentFor(target.scope),
+		}
+		l.enforce(target.mode, target.scope, scopeResult, false)
+		if !scopeResult.Allowed && result.Allowed {
+			result.Allowed = false
+			result.Denied = target.scope
+		}
+		result.Results[i] = scopeResult
+	}
+	return result, stage, nil
+}
+
+// failAllOpen handles a failed commit: the request goes through only if every scope
+// that was to be charged fails open, or is allowed by the local fallback under FailLocal
+func (l *limiterImpl) failAllOpen(ctx context.Context, targets []scopeTarget, result *MultiScopeResult, err error) (*MultiScopeResult, error) {
+	for i, target := range targets {
+		if target.mode != EnforcementOff && !storeFailedResult(result.Results[i]) && l.config.FailurePolicyFor(target.scope) == FailClosed {
+			l.failedClosed.add(target.scope)
+			return nil, fmt.Errorf("rate limit check failed: %w", err)
+		}
+	}
+	for i, target := range targets {
+		if target.mode != EnforcementOff && !storeFailedResult(result.Results[i]) {
+			result.Results[i] = l.storeFailed(ctx, target.scope, target.key, target.limit, target.window, target.cost, err)
+			if !result.Results[i].Allowed && result.Allowed {
+				result.Allowed = false
+				result.Denied = target.scope
+			}
+		}
+	}
+	return result, nil
+}
+
+// storeFailedResult reports whether a result was decided by a failure policy
+func storeFailedResult(result *CoreResult) bool {
+	return result.FailedOpen || result.Degraded
+}
+
+// finishAll counts a multi-scope check once its outcome is final and reports entities
+// admitted only thanks to a limit transition. Scopes that allowed a denied request were
+// not charged, so their budget is reported as it was before the check.
+func (l *limiterImpl) finishAll(table *limitTable, entity, tier string, targets []scopeTarget, result *MultiScopeResult, unknownTier string) {
+	for i, target := range targets {
+		scopeResult := result.Results[i]
+		scopeResult.UnknownTier = unknownTier
+		if scopeResult.ShadowDenied {
+			l.shadowDenials.add(target.scope)
+		}
+		if !result.Allowed && scopeResult.Allowed && scopeResult.Enforcement != EnforcementOff && !storeFailedResult(scopeResult) {
+			scopeResult.Remaining = min(scopeResult.Remaining+target.cost, scopeResult.Limit)
+			scopeResult.Used = max(scopeResult.Used-target.cost, 0)
+		}
+		if result.Allowed {
+			l.noteTransition(table, entity, tier, target.scope, scopeResult)
+		}
+		l.count(entity, target.scope, result.Allowed)
+	}
+}
+
+// storeMultiSwapper returns the atomic multi-key write of a store, or nil if it has none
+func storeMultiSwapper(store Store) multiSwapper {
+	if swapper, ok := store.(multiSwapper); ok {
+		return swapper
+	}
+	if adapter, ok := store.(*storeAdapter); ok {
+		if swapper, ok := adapter.store.(multiSwapper); ok {
+			return swapper
+		}
+	}
+	return nil
+}
+
+// stagingStore records what the algorithms read from the store and holds their writes
+// back, so a multi-scope check can commit all of them together or none. Window anchors
+// set with SetNX are held back too, as writes expecting the key to be absent. Counters,
+// which the fixed window algorithm increments atomically, cannot be held back: their
+// increments reach the store at once and are taken back by release if the check is not
+// committed.
+type stagingStore struct {
+	Store
+	reads    map[string][]byte // Values read from the store, nil for absent keys
+	writes   map[string]stores.Swap
+	counters map[string]counterCharge // Net increments of counters, by key
+	err      error                    // First store failure, which algorithms would mistake for a missing key
+}
+
+// counterCharge is what a multi-scope check added to a counter
+type counterCharge struct {
+	amount     int64
+	expiration time.Duration
+}
+
+func newStagingStore(store Store) *stagingStore {
+	return &stagingStore{
+		Store:    store,
+		reads:    make(map[string][]byte),
+		writes:   make(map[string]stores.Swap),
+		counters: make(map[string]counterCharge),
+	}
+}
+
+func (s *stagingStore) Get(ctx context.Context, key string) ([]byte, error) {
+	if write, ok := s.writes[key]; ok {
+		return append([]byte(nil), write.Value...), nil
+	}
+	if value, ok := s.reads[key]; ok {
+		if value == nil {
+			return nil, stores.NewStoreError("store", "key not found", nil)
+		}
+		return append([]byte(nil), value...), nil
+	}
+
+	value, err := s.Store.Get(ctx, key)
+	if err != nil {
+		if !stores.IsNotFound(err) && s.err == nil {
+			s.err = err
+		}
+		s.reads[key] = nil
+		return nil, err
+	}
+	s.reads[key] = append([]byte{}, value...)
+	return value, nil
+}
+
+func (s *stagingStore) Set(ctx context.Context, key string, value []byte, expiration time.Duration) error {
+	if _, ok := s.reads[key]; !ok {
+		// The swap must expect what the key holds, even if the algorithm did not read it
+		if _, err := s.Get(ctx, key); err != nil && !stores.IsNotFound(err) {
+			return err
+		}
+	}
+	s.writes[key] = stores.Swap{Key: key, Value: append([]byte{}, value...), Expiration: expiration}
+	return nil
+}
+
+func (s *stagingStore) IncrementBy(ctx context.Context, key string, amount int64, expiration time.Duration) (int64, error) {
+	value, err := s.Store.IncrementBy(ctx, key, amount, expiration)
+	if err != nil {
+		return 0, err
+	}
+	charge := s.counters[key]
+	charge.amount += amount
+	charge.expiration = expiration
+	s.counters[key] = charge
+	return value, nil
+}
+
+func (s *stagingStore) SetNX(ctx context.Context, key string, value []byte, expiration time.Duration) (bool, error) {
+	if _, err := s.Get(ctx, key); err == nil {
+		return false, nil
+	} else if !stores.IsNotFound(err) {
+		return false, err
+	}
+	// The swap expects the key to be absent, so an anchor set by another check meanwhile
+	// makes the commit conflict and the check is staged again
+	s.writes[key] = stores.Swap{Key: key, Value: append([]byte{}, value...), Expiration: expiration}
+	return true, nil
+}
+
+func (s *stagingStore) Delete(ctx context.Context, key string) error {
+	return errors.New("deleting keys is not supported in a multi-scope check")
+}
+
+// takeErr returns and clears the store failure of the last evaluation
+func (s *stagingStore) takeErr() error {
+	err := s.err
+	s.err = nil
+	return err
+}
+
+// release takes back the counter increments of a check that is not committed
+func (s *stagingStore) release(ctx context.Context) error {
+	for key, charge := range s.counters {
+		if charge.amount != 0 {
+			if _, err := s.Store.IncrementBy(ctx, key, -charge.amount, charge.expiration); err != nil {
+				return err
+			}
+		}
+		delete(s.counters, key)
+	}
+	return nil
+}
+
+// discard drops the staged write of a key whose evaluation failed
+func (s *stagingStore) discard(key string) {
+	delete(s.writes, key)
+}
+
+// swaps returns the staged writes, each expecting the value read before it, in key order
+func (s *stagingStore) swaps() []stores.Swap {
+	swaps := make([]stores.Swap, 0, len(s.writes))
+	for key, write := range s.writes {
+		write.Old = s.reads[key]
+		swaps = append(swaps, write)
+	}
+	sort.Slice(swaps, func(i, j int) bool { return swaps[i].Key < swaps[j].Key })
+	return swaps
+}
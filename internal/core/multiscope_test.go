@@ -0,0 +1,217 @@
+// internal/core/multiscope_test.go
+package core
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/itsatony/gorly/stores"
+)
+
+func TestCheckAllIsAllOrNothing(t *testing.T) {
+	for _, algorithm := range []string{"sliding_window", "token_bucket", "fixed_window"} {
+		t.Run(algorithm, func(t *testing.T) {
+			ctx := context.Background()
+			limiter := newTestLimiter(t, &Config{
+				Algorithm: algorithm,
+				Limits:    map[string]string{"global": "10/minute", "upload": "4/minute"},
+			}, nil)
+			costs := []ScopeCost{{Scope: "global", Cost: 1}, {Scope: "upload", Cost: 2}}
+
+			for i := 0; i < 2; i++ {
+				if result, err := limiter.CheckAll(ctx, "user:1", costs); err != nil || !result.Allowed {
+					t.Fatalf("Check %d: expected to be allowed, got %+v, %v", i+1, result, err)
+				}
+			}
+			for i := 0; i < 3; i++ {
+				result, err := limiter.CheckAll(ctx, "user:1", costs)
+				if err != nil || result.Allowed || result.Denied != "upload" {
+					t.Fatalf("Expected upload to deny the check, got %+v, %v", result, err)
+				}
+				if global := result.Results[0]; !global.Allowed || global.Remaining != 8 {
+					t.Errorf("Expected global to report its uncharged budget, got %+v", global)
+				}
+			}
+
+			if peek, _ := limiter.Peek(ctx, "user:1", "global"); peek.Remaining != 8 || peek.Used != 2 {
+				t.Errorf("Expected the denied checks not to charge global, used %d with %d remaining", peek.Used, peek.Remaining)
+			}
+		})
+	}
+}
+
+func TestCheckAllConcurrent(t *testing.T) {
+	ctx := context.Background()
+	limiter := newTestLimiter(t, &Config{
+		Limits: map[string]string{"global": "100/minute", "upload": "20/minute"},
+	}, nil)
+
+	var allowed atomic.Int64
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 5; j++ {
+				result, err := limiter.CheckAll(ctx, "user:1", []ScopeCost{{Scope: "global", Cost: 1}, {Scope: "upload", Cost: 1}})
+				if err == nil && result.Allowed {
+					allowed.Add(1)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if allowed.Load() > 20 {
+		t.Errorf("Expected at most 20 allowed checks, got %d", allowed.Load())
+	}
+	for _, scope := range []string{"global", "upload"} {
+		if peek, _ := limiter.Peek(ctx, "user:1", scope); peek.Used != allowed.Load() {
+			t.Errorf("Expected %s to be charged exactly for the %d allowed checks, used %d", scope, allowed.Load(), peek.Used)
+		}
+	}
+}
+
+func TestCheckAllFailurePolicies(t *testing.T) {
+	ctx := context.Background()
+	store := &outageStore{Store: newTestStore(t)}
+	limiter := newTestLimiter(t, &Config{
+		Limits:               map[string]string{"read": "5/minute", "upload": "5/minute", "password-reset": "5/minute"},
+		FailurePolicy:        FailOpen,
+		ScopeFailurePolicies: map[string]string{"password-reset": FailClosed},
+	}, &swappingOutageStore{store})
+
+	store.down.Store(true)
+	result, err := limiter.CheckAll(ctx, "user:1", []ScopeCost{{Scope: "read", Cost: 1}, {Scope: "upload", Cost: 1}})
+	if err != nil || !result.Allowed || !result.Results[0].FailedOpen || !result.Results[1].FailedOpen {
+		t.Errorf("Expected open scopes to fail open, got %+v, %v", result, err)
+	}
+	if _, err := limiter.CheckAll(ctx, "user:1", []ScopeCost{{Scope: "read", Cost: 1}, {Scope: "password-reset", Cost: 1}}); err == nil {
+		t.Error("Expected a closed scope to fail the check")
+	}
+}
+
+func TestCheckAllValidation(t *testing.T) {
+	ctx := context.Background()
+	limiter := newTestLimiter(t, &Config{Limits: map[string]string{"global": "5/minute"}}, nil)
+
+	tests := []struct {
+		name  string
+		costs []ScopeCost
+	}{
+		{"no scopes", nil},
+		{"zero cost", []ScopeCost{{Scope: "global"}}},
+		{"duplicate scope", []ScopeCost{{Scope: "global", Cost: 1}, {Scope: "global", Cost: 1}}},
+	}
+	for _, tt := range tests {
+		if _, err := limiter.CheckAll(ctx, "user:1", tt.costs); err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+		}
+	}
+
+	// Stores without an atomic multi-key write cannot run the check
+	plain := newTestLimiter(t, &Config{Limits: map[string]string{"global": "5/minute"}}, &outageStore{Store: newTestStore(t)})
+	if _, err := plain.CheckAll(ctx, "user:1", []ScopeCost{{Scope: "global", Cost: 1}}); err == nil {
+		t.Error("Expected an error from a store without multi-key writes")
+	}
+}
+
+func TestCheckAllFailedCommitReleasesCharges(t *testing.T) {
+	ctx := context.Background()
+	store := &failingSwapStore{Store: newTestStore(t)}
+	limiter := newTestLimiter(t, &Config{
+		Algorithm:     "fixed_window",
+		Limits:        map[string]string{"global": "10/minute", "upload": "4/minute"},
+		FailurePolicy: FailClosed,
+	}, store)
+	costs := []ScopeCost{{Scope: "global", Cost: 1}, {Scope: "upload", Cost: 2}}
+
+	store.failing.Store(true)
+	if _, err := limiter.CheckAll(ctx, "user:1", costs); err == nil {
+		t.Fatal("Expected a failed commit to fail the check closed")
+	}
+	for _, key := range []string{"global", "upload"} {
+		if exists, _ := store.Exists(ctx, limiter.requestKey("user:1", key)); exists {
+			t.Errorf("Expected no window anchor for %s after a failed commit", key)
+		}
+		if peek, _ := limiter.Peek(ctx, "user:1", key); peek.Used != 0 {
+			t.Errorf("Expected %s not to be charged by a failed commit, used %d", key, peek.Used)
+		}
+	}
+
+	store.failing.Store(false)
+	if result, err := limiter.CheckAll(ctx, "user:1", costs); err != nil || !result.Allowed {
+		t.Fatalf("Expected the check to be allowed once commits succeed, got %+v, %v", result, err)
+	}
+	if peek, _ := limiter.Peek(ctx, "user:1", "upload"); peek.Used != 2 {
+		t.Errorf("Expected upload to be charged once, used %d", peek.Used)
+	}
+}
+
+// failingSwapStore is a store whose multi-key writes fail while failing is set
+type failingSwapStore struct {
+	Store
+	failing atomic.Bool
+}
+
+func (s *failingSwapStore) CompareAndSwapMulti(ctx context.Context, swaps []stores.Swap) (bool, error) {
+	if s.failing.Load() {
+		return false, errStoreDown
+	}
+	return storeMultiSwapper(s.Store).CompareAndSwapMulti(ctx, swaps)
+}
+
+// swappingOutageStore adds the memory store's multi-key write to an outageStore
+type swappingOutageStore struct {
+	*outageStore
+}
+
+func (s *swappingOutageStore) CompareAndSwapMulti(ctx context.Context, swaps []stores.Swap) (bool, error) {
+	if s.down.Load() {
+		return false, errStoreDown
+	}
+	return s.Store.(*storeAdapter).store.(multiSwapper).CompareAndSwapMulti(ctx, swaps)
+}
+
+// scriptingStore runs scripts by asking for the Get/Set path, counting the runs
+type scriptingStore struct {
+	*stores.MemoryStore
+	runs atomic.Int64
+}
+
+func (s *scriptingStore) RunScript(ctx context.Context, script string, keys []string, args ...interface{}) (interface{}, error) {
+	s.runs.Add(1)
+	return []interface{}{int64(-1)}, nil
+}
+
+func TestChecksRunScripts(t *testing.T) {
+	memory, err := stores.NewMemoryStore(stores.MemoryConfig{})
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	store := &scriptingStore{MemoryStore: memory}
+	limiter := newTestLimiter(t, &Config{
+		Algorithm:    "token_bucket",
+		Limits:       map[string]string{"global": "10/minute", "search": "5/minute"},
+		StoreRetries: &StoreRetryConfig{},
+	}, &storeAdapter{store})
+	ctx := context.Background()
+
+	// Scripts reach the store through the retried reads
+	if result, err := limiter.Check(ctx, "alice", "global"); err != nil || !result.Allowed {
+		t.Fatalf("Expected the check to fall back and allow, got %+v (%v)", result, err)
+	}
+	if runs := store.runs.Load(); runs != 1 {
+		t.Errorf("Expected 1 script run, got %d", runs)
+	}
+
+	// Multi-scope checks stage their writes, so they never run scripts
+	if _, err := limiter.CheckAll(ctx, "alice", []ScopeCost{{Scope: "global", Cost: 1}, {Scope: "search", Cost: 1}}); err != nil {
+		t.Fatalf("CheckAll failed: %v", err)
+	}
+	if runs := store.runs.Load(); runs != 1 {
+		t.Errorf("Expected no script runs for CheckAll, got %d", runs-1)
+	}
+}
@@ -0,0 +1,237 @@
+// internal/core/overrides.go
+package core
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// LimitSourceOverride is the source of a limit set for one entity
+const LimitSourceOverride = "override"
+
+// maxOverrideExpiryWait bounds how long the expiry sweeper sleeps, so a clock that
+// jumps ahead (a resumed VM, a test clock) is noticed without waiting for the next update
+const maxOverrideExpiryWait = time.Minute
+
+// Override sets the limit of one entity in one scope, e.g. a partner's negotiated quota
+type Override struct {
+	Entity    string
+	Scope     string
+	Limit     string
+	ExpiresAt time.Time // Zero never expires
+}
+
+// validateOverrides checks that every override names an entity and scope and has a valid limit
+func validateOverrides(overrides []Override) error {
+	seen := make(map[[2]string]bool, len(overrides))
+	for _, o := range overrides {
+		if o.Entity == "" || o.Scope == "" {
+			return fmt.Errorf("override needs an entity and a scope, got %q in %q", o.Entity, o.Scope)
+		}
+		if _, _, err := parseLimit(o.Limit); err != nil {
+			return fmt.Errorf("invalid override for %s in scope %s: %w", o.Entity, o.Scope, err)
+		}
+		key := [2]string{o.Entity, o.Scope}
+		if seen[key] {
+			return fmt.Errorf("duplicate override for %s in scope %s", o.Entity, o.Scope)
+		}
+		seen[key] = true
+	}
+	return nil
+}
+
+// expired reports whether the override no longer applies at now
+func (o Override) expired(now time.Time) bool {
+	return !o.ExpiresAt.IsZero() && !now.Before(o.ExpiresAt)
+}
+
+// indexOverrides maps entity -> scope -> override. Overrides that have already expired
+// are dropped, so reloading a file that still lists them does not expire them again.
+func indexOverrides(overrides []Override, now time.Time) map[string]map[string]Override {
+	index := make(map[string]map[string]Override)
+	for _, o := range overrides {
+		if o.expired(now) {
+			continue
+		}
+		if index[o.Entity] == nil {
+			index[o.Entity] = make(map[string]Override)
+		}
+		index[o.Entity][o.Scope] = o
+	}
+	return index
+}
+
+// override returns the limit an override sets for an entity in scope, unless it has expired
+func (t *limitTable) override(entity, scope string, now time.Time) (string, bool) {
+	o, ok := t.overrides[entity][scope]
+	if !ok || o.expired(now) {
+		return "", false
+	}
+	return o.Limit, true
+}
+
+// nextExpiry returns the earliest expiry of the table's overrides
+func (t *limitTable) nextExpiry() (time.Time, bool) {
+	var next time.Time
+	for _, scopes := range t.overrides {
+		for _, o := range scopes {
+			if !o.ExpiresAt.IsZero() && (next.IsZero() || o.ExpiresAt.Before(next)) {
+				next = o.ExpiresAt
+			}
+		}
+	}
+	return next, !next.IsZero()
+}
+
+// sortOverrides orders overrides by entity and scope
+func sortOverrides(overrides []Override) {
+	sort.Slice(overrides, func(i, j int) bool {
+		if overrides[i].Entity != overrides[j].Entity {
+			return overrides[i].Entity < overrides[j].Entity
+		}
+		return overrides[i].Scope < overrides[j].Scope
+	})
+}
+
+// Overrides returns the configured entity overrides, sorted by entity and scope
+func (l *limiterImpl) Overrides() []Override {
+	table := l.limitTable()
+	overrides := make([]Override, 0, len(table.overrides))
+	for _, scopes := range table.overrides {
+		for _, o := range scopes {
+			overrides = append(overrides, o)
+		}
+	}
+	sortOverrides(overrides)
+	return overrides
+}
+
+// ExpiredOverrides returns how many entity overrides have expired and been removed
+func (l *limiterImpl) ExpiredOverrides() int64 {
+	return l.expiry.expired.Load()
+}
+
+// overrideExpiry removes entity overrides from the live limits once they expire and
+// reports each one to Config.OnOverrideExpired.
+//
+// Checks already ignore an expired override, so enforcement does not depend on the
+// sweeper; it keeps the admin view accurate and turns expiries into events. The sweeper
+// sleeps until the next expiry and is only started once an override with an expiry is set.
+type overrideExpiry struct {
+	limiter *limiterImpl
+	expired atomic.Int64
+
+	mu      sync.Mutex
+	started bool
+	closed  bool
+	wake    chan struct{}
+	stop    chan struct{}
+	done    sync.WaitGroup
+}
+
+// newOverrideExpiry creates the sweeper of a limiter
+func newOverrideExpiry(l *limiterImpl) *overrideExpiry {
+	return &overrideExpiry{
+		limiter: l,
+		wake:    make(chan struct{}, 1),
+		stop:    make(chan struct{}),
+	}
+}
+
+// schedule starts the sweeper if the current overrides expire, and makes it
+// recompute the next expiry after the overrides changed
+func (e *overrideExpiry) schedule() {
+	if _, ok := e.limiter.limitTable().nextExpiry(); ok {
+		e.mu.Lock()
+		if !e.started && !e.closed {
+			e.started = true
+			e.done.Add(1)
+			go e.run()
+		}
+		e.mu.Unlock()
+	}
+	select {
+	case e.wake <- struct{}{}:
+	default:
+	}
+}
+
+// close stops the sweeper and waits for it to exit
+func (e *overrideExpiry) close() {
+	e.mu.Lock()
+	started := e.started && !e.closed
+	e.closed = true
+	e.mu.Unlock()
+	if started {
+		close(e.stop)
+		e.done.Wait()
+	}
+}
+
+// run sweeps expired overrides until stopped
+func (e *overrideExpiry) run() {
+	defer e.done.Done()
+
+	for {
+		wait := maxOverrideExpiryWait
+		if next, ok := e.limiter.limitTable().nextExpiry(); ok {
+			wait = min(max(next.Sub(e.limiter.config.Now()), 0), maxOverrideExpiryWait)
+		}
+		timer := time.NewTimer(wait)
+
+		select {
+		case <-e.stop:
+			timer.Stop()
+			return
+		case <-e.wake:
+			timer.Stop()
+		case <-timer.C:
+		}
+		e.sweep()
+	}
+}
+
+// sweep removes the overrides that have expired and reports them, sorted by entity and scope
+func (e *overrideExpiry) sweep() []Override {
+	l := e.limiter
+	now := l.config.Now()
+	live := l.config.live
+
+	live.mu.Lock()
+	current := live.table.Load()
+	var expired, remaining []Override
+	for _, scopes := range current.overrides {
+		for _, o := range scopes {
+			if o.expired(now) {
+				expired = append(expired, o)
+			} else {
+				remaining = append(remaining, o)
+			}
+		}
+	}
+	if len(expired) > 0 {
+		next := *current
+		next.overrides = indexOverrides(remaining, now)
+		live.table.Store(&next)
+	}
+	live.mu.Unlock()
+
+	if len(expired) == 0 {
+		return nil
+	}
+	// Cached denials may have been decided under an override's limit
+	if l.denials != nil {
+		l.denials.clear()
+	}
+	sortOverrides(expired)
+	e.expired.Add(int64(len(expired)))
+	if handler := l.config.OnOverrideExpired; handler != nil {
+		for _, o := range expired {
+			handler(o)
+		}
+	}
+	return expired
+}
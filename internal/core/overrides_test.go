@@ -0,0 +1,141 @@
+// internal/core/overrides_test.go
+package core
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestOverrides(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	l := newTestLimiter(t, tierTestConfig("", ""), nil)
+	l.config.Clock = func() time.Time { return now }
+
+	err := l.UpdateLimits(LimitUpdate{Overrides: []Override{
+		{Entity: "pro:acme", Scope: "global", Limit: "100/minute"},
+		{Entity: "user-1", Scope: "global", Limit: "50/minute", ExpiresAt: now.Add(time.Hour)},
+		{Entity: "user-2", Scope: "global", Limit: "50/minute", ExpiresAt: now},
+	}})
+	if err != nil {
+		t.Fatalf("UpdateLimits failed: %v", err)
+	}
+
+	tests := map[string]int64{
+		"pro:acme": 100, // Wins over the pro tier limit
+		"pro:ac":   5,
+		"user-1":   50,
+		"user-2":   1, // Already expired, so never added
+	}
+	for entity, want := range tests {
+		result, err := l.Check(ctx, entity, "global")
+		if err != nil {
+			t.Fatalf("Check of %s failed: %v", entity, err)
+		}
+		if result.Limit != want {
+			t.Errorf("Expected limit %d for %s, got %d", want, entity, result.Limit)
+		}
+	}
+
+	limits, err := l.Limits("pro:acme")
+	if err != nil || len(limits) != 1 || limits[0].Source != LimitSourceOverride {
+		t.Errorf("Expected the override as the source of the limit, got %+v (%v)", limits, err)
+	}
+	if overrides := l.Overrides(); len(overrides) != 2 || overrides[0].Entity != "pro:acme" {
+		t.Errorf("Expected 2 sorted overrides, got %+v", overrides)
+	}
+
+	// Updates without overrides keep them; an empty list removes them
+	if err := l.UpdateLimits(LimitUpdate{Scale: 2}); err != nil || len(l.Overrides()) != 2 {
+		t.Errorf("Expected the overrides to survive an unrelated update, got %d (%v)", len(l.Overrides()), err)
+	}
+	if err := l.UpdateLimits(LimitUpdate{Overrides: []Override{}}); err != nil || len(l.Overrides()) != 0 {
+		t.Errorf("Expected the overrides to be removed, got %d (%v)", len(l.Overrides()), err)
+	}
+}
+
+func TestOverrideExpiry(t *testing.T) {
+	ctx := context.Background()
+	var now atomic.Pointer[time.Time]
+	start := time.Now()
+	now.Store(&start)
+	expiredEvents := make(chan Override, 4)
+
+	config := &Config{
+		Algorithm: "sliding_window",
+		Limits:    map[string]string{"global": "3/minute"},
+		Overrides: []Override{
+			{Entity: "partner:acme", Scope: "global", Limit: "30/minute", ExpiresAt: start.Add(48 * time.Hour)},
+			{Entity: "partner:globex", Scope: "global", Limit: "20/minute"},
+		},
+		Clock:             func() time.Time { return *now.Load() },
+		OnOverrideExpired: func(o Override) { expiredEvents <- o },
+	}
+	l := newTestLimiter(t, config, nil)
+
+	later := start.Add(72 * time.Hour)
+	now.Store(&later)
+
+	// Checks ignore the expired override before the sweeper removes it
+	result, err := l.Check(ctx, "partner:acme", "global")
+	if err != nil || result.Limit != 3 {
+		t.Fatalf("Expected the scope limit after expiry, got %+v (%v)", result, err)
+	}
+
+	expired := l.expiry.sweep()
+	if len(expired) != 1 || expired[0].Entity != "partner:acme" {
+		t.Fatalf("Expected partner:acme to expire, got %+v", expired)
+	}
+	if event := <-expiredEvents; event.Entity != "partner:acme" {
+		t.Errorf("Expected an expiry event for partner:acme, got %+v", event)
+	}
+	if overrides := l.Overrides(); len(overrides) != 1 || overrides[0].Entity != "partner:globex" {
+		t.Errorf("Expected only the permanent override to remain, got %+v", overrides)
+	}
+	if l.ExpiredOverrides() != 1 || l.expiry.sweep() != nil {
+		t.Errorf("Expected one expiry in total, got %d", l.ExpiredOverrides())
+	}
+
+	// The background sweeper wakes up at the next expiry
+	deadline := later.Add(50 * time.Millisecond)
+	if err := l.UpdateLimits(LimitUpdate{Overrides: []Override{
+		{Entity: "partner:initech", Scope: "global", Limit: "10/minute", ExpiresAt: deadline},
+	}}); err != nil {
+		t.Fatalf("UpdateLimits failed: %v", err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	now.Store(&deadline)
+	select {
+	case event := <-expiredEvents:
+		if event.Entity != "partner:initech" {
+			t.Errorf("Expected an expiry event for partner:initech, got %+v", event)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the sweeper to expire partner:initech")
+	}
+	if len(l.Overrides()) != 0 {
+		t.Errorf("Expected no overrides left, got %+v", l.Overrides())
+	}
+}
+
+func TestValidateOverrides(t *testing.T) {
+	tests := []struct {
+		name      string
+		overrides []Override
+		wantErr   bool
+	}{
+		{"valid", []Override{{Entity: "a", Scope: "global", Limit: "10/minute"}, {Entity: "a", Scope: "search", Limit: "5/minute"}}, false},
+		{"missing scope", []Override{{Entity: "a", Limit: "10/minute"}}, true},
+		{"invalid limit", []Override{{Entity: "a", Scope: "global", Limit: "lots"}}, true},
+		{"duplicate", []Override{{Entity: "a", Scope: "global", Limit: "10/minute"}, {Entity: "a", Scope: "global", Limit: "20/minute"}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateOverrides(tt.overrides); (err != nil) != tt.wantErr {
+				t.Errorf("Expected error: %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
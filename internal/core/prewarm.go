@@ -0,0 +1,105 @@
+// internal/core/prewarm.go
+package core
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// DefaultPrewarmTimeout bounds how long building a limiter waits for configured pre-warming
+const DefaultPrewarmTimeout = 5 * time.Second
+
+// PrewarmSpec names an entity whose state is created before its first request
+type PrewarmSpec struct {
+	Entity string
+	Scopes []string // Scopes to pre-warm (default: every configured scope)
+}
+
+// statePrewarmer is implemented by algorithms that can serialize the state of an unused key
+type statePrewarmer interface {
+	InitialState(limit int64, window time.Duration) ([]byte, time.Duration, error)
+}
+
+// Prewarm creates the state of a key unless it already exists, and reports whether it did
+func (a *algorithmAdapter) Prewarm(ctx context.Context, store Store, key string, limit int64, window time.Duration) (bool, error) {
+	prewarmer, ok := a.algorithm.(statePrewarmer)
+	if !ok {
+		return false, nil
+	}
+	data, expiration, err := prewarmer.InitialState(limit, window)
+	if err != nil {
+		return false, err
+	}
+	return store.SetNX(ctx, key, data, expiration)
+}
+
+// keyPrewarmer is implemented by algorithms that can create keys ahead of the first request
+type keyPrewarmer interface {
+	Prewarm(ctx context.Context, store Store, key string, limit int64, window time.Duration) (bool, error)
+}
+
+// Prewarm creates the state of known heavy hitters ahead of their first request, so a burst
+// after a deploy neither pays for creating keys nor races on creating them. Existing state is
+// never overwritten, which makes pre-warming safe while traffic flows. It returns how many
+// keys it created.
+func (l *limiterImpl) Prewarm(ctx context.Context, specs []PrewarmSpec) (int, error) {
+	table := l.limitTable()
+	configured := make([]string, 0, len(table.limits)+len(table.tierLimits))
+	for scope := range table.limits {
+		configured = append(configured, scope)
+	}
+	for scope := range table.tierLimits {
+		if _, ok := table.limits[scope]; !ok {
+			configured = append(configured, scope)
+		}
+	}
+	sort.Strings(configured)
+
+	created := 0
+	for _, spec := range specs {
+		scopes := spec.Scopes
+		if len(scopes) == 0 {
+			scopes = configured
+		}
+		for _, scope := range scopes {
+			if err := ctx.Err(); err != nil {
+				return created, err
+			}
+			entity, scope, err := l.sanitize(spec.Entity, scope)
+			if err != nil {
+				return created, err
+			}
+			prewarmer, ok := l.algorithmFor(scope).(keyPrewarmer)
+			if !ok {
+				continue
+			}
+			limit, window, err := l.getLimit(table, entity, l.entityTier(ctx, entity), scope)
+			if err != nil {
+				return created, fmt.Errorf("failed to get limit of %s in scope %s: %w", entity, scope, err)
+			}
+			fresh, err := prewarmer.Prewarm(ctx, l.storeFor(scope), l.requestKey(entity, scope), limit, window)
+			if err != nil {
+				return created, fmt.Errorf("failed to pre-warm %s in scope %s: %w", entity, scope, err)
+			}
+			if fresh {
+				created++
+			}
+		}
+	}
+	return created, nil
+}
+
+// prewarmConfigured pre-warms the entities listed in the config, reporting failures
+// instead of failing construction: pre-warming only saves work on the first requests
+func (l *limiterImpl) prewarmConfigured() {
+	if len(l.config.Prewarm) == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultPrewarmTimeout)
+	defer cancel()
+	if _, err := l.Prewarm(ctx, l.config.Prewarm); err != nil {
+		l.reportError(fmt.Errorf("pre-warming failed: %w", err))
+	}
+}
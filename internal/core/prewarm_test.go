@@ -0,0 +1,74 @@
+// internal/core/prewarm_test.go
+package core
+
+import (
+	"context"
+	"testing"
+)
+
+func TestPrewarm(t *testing.T) {
+	for _, algorithm := range []string{"token_bucket", "sliding_window"} {
+		t.Run(algorithm, func(t *testing.T) {
+			ctx := context.Background()
+			store := newTestStore(t)
+			l := newTestLimiter(t, &Config{
+				Algorithm: algorithm,
+				Limits:    map[string]string{"global": "10/minute", "search": "5/minute"},
+			}, store)
+
+			// State consumed before pre-warming is kept
+			if _, err := l.Check(ctx, "partner:acme", "search"); err != nil {
+				t.Fatalf("Check failed: %v", err)
+			}
+
+			created, err := l.Prewarm(ctx, []PrewarmSpec{
+				{Entity: "partner:acme"},
+				{Entity: "partner:globex", Scopes: []string{"search"}},
+			})
+			if err != nil {
+				t.Fatalf("Prewarm failed: %v", err)
+			}
+			if created != 2 {
+				t.Errorf("Expected 2 keys created (acme/global, globex/search), got %d", created)
+			}
+			for _, key := range []string{l.requestKey("partner:acme", "global"), l.requestKey("partner:globex", "search")} {
+				if exists, _ := store.Exists(ctx, key); !exists {
+					t.Errorf("Expected key %s to exist", key)
+				}
+			}
+
+			result, err := l.Peek(ctx, "partner:acme", "search")
+			if err != nil {
+				t.Fatalf("Peek failed: %v", err)
+			}
+			if result.Remaining != 4 {
+				t.Errorf("Expected pre-warming to keep existing state with 4 remaining, got %d", result.Remaining)
+			}
+			result, err = l.Peek(ctx, "partner:globex", "search")
+			if err != nil {
+				t.Fatalf("Peek failed: %v", err)
+			}
+			if result.Remaining != 5 {
+				t.Errorf("Expected a pre-warmed key to have full quota, got %d remaining", result.Remaining)
+			}
+
+			if created, _ := l.Prewarm(ctx, []PrewarmSpec{{Entity: "partner:acme"}}); created != 0 {
+				t.Errorf("Expected pre-warming again to create nothing, got %d", created)
+			}
+		})
+	}
+}
+
+func TestPrewarmConfigured(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStore(t)
+	limiter := newTestLimiter(t, &Config{
+		Algorithm: "token_bucket",
+		Limits:    map[string]string{"global": "10/minute"},
+		Prewarm:   []PrewarmSpec{{Entity: "partner:acme"}},
+	}, store)
+
+	if exists, _ := store.Exists(ctx, limiter.requestKey("partner:acme", "global")); !exists {
+		t.Error("Expected the configured entity to be pre-warmed at construction")
+	}
+}
@@ -0,0 +1,129 @@
+// internal/core/probes.go
+package core
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"slices"
+	"strings"
+)
+
+// DefaultProbeUserAgent is the User-Agent prefix of Kubernetes liveness, readiness and
+// startup probes, e.g. "kube-probe/1.29"
+const DefaultProbeUserAgent = "kube-probe/"
+
+// ProbeConfig exempts health probe traffic from rate limiting. Probes share the node's
+// address, so they would otherwise consume IP-based budgets, and a 429 on a liveness probe
+// restarts a healthy pod.
+//
+// A request is a probe when its User-Agent starts with one of UserAgents or it carries
+// Header. User agents and headers are easily forged, so Networks and Paths narrow the
+// exemption to the addresses and endpoints probes actually use.
+type ProbeConfig struct {
+	UserAgents  []string // User-Agent prefixes of probes (nil: DefaultProbeUserAgent; empty: no User-Agent check)
+	Header      string   // Header marking probes, e.g. "X-Health-Probe"; "" disables the header check
+	HeaderValue string   // Value Header must have; "" accepts any non-empty value
+	Networks    []string // When set, probes must come from these addresses or CIDRs
+	Paths       []string // When set, probes must request one of these paths, e.g. "/healthz"
+}
+
+// validate checks that the probe networks parse
+func (pc *ProbeConfig) validate() error {
+	for _, entry := range pc.Networks {
+		if _, err := parseProbeNetwork(entry); err != nil {
+			return fmt.Errorf("invalid probe network %q: %w", entry, err)
+		}
+	}
+	if pc.HeaderValue != "" && pc.Header == "" {
+		return errors.New("a probe header value requires a probe header")
+	}
+	return nil
+}
+
+// parseProbeNetwork parses a CIDR or a single address
+func parseProbeNetwork(entry string) (netip.Prefix, error) {
+	entry = strings.TrimSpace(entry)
+	if prefix, err := netip.ParsePrefix(entry); err == nil {
+		return prefix.Masked(), nil
+	}
+	addr, err := netip.ParseAddr(entry)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	addr = addr.Unmap()
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+// userAgents returns the configured User-Agent prefixes, or the default
+func (pc *ProbeConfig) userAgents() []string {
+	if pc.UserAgents == nil {
+		return []string{DefaultProbeUserAgent}
+	}
+	return pc.UserAgents
+}
+
+// IsProbe reports whether a request is health probe traffic exempt from rate limiting
+func (c *Config) IsProbe(r *http.Request) bool {
+	pc := c.Probes
+	if pc == nil {
+		return false
+	}
+	if !pc.identifies(r) {
+		return false
+	}
+	if len(pc.Paths) > 0 && !slices.Contains(pc.Paths, r.URL.Path) {
+		return false
+	}
+	return len(pc.Networks) == 0 || pc.fromNetworks(r)
+}
+
+// identifies reports whether the request's User-Agent or header marks it as a probe
+func (pc *ProbeConfig) identifies(r *http.Request) bool {
+	userAgent := r.UserAgent()
+	for _, prefix := range pc.userAgents() {
+		if prefix != "" && strings.HasPrefix(userAgent, prefix) {
+			return true
+		}
+	}
+	if pc.Header == "" {
+		return false
+	}
+	value := r.Header.Get(pc.Header)
+	if pc.HeaderValue == "" {
+		return value != ""
+	}
+	return value == pc.HeaderValue
+}
+
+// fromNetworks reports whether the connection address is in one of the probe networks.
+// Only the connection address is used; forwarding headers are trivially spoofed.
+func (pc *ProbeConfig) fromNetworks(r *http.Request) bool {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, entry := range pc.Networks {
+		if prefix, err := parseProbeNetwork(entry); err == nil && prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// CountProbe records a probe request let through without a check
+func (l *limiterImpl) CountProbe() {
+	l.probes.Add(1)
+}
+
+// Probes returns how many probe requests were let through without a check
+func (l *limiterImpl) Probes() int64 {
+	return l.probes.Load()
+}
@@ -0,0 +1,53 @@
+// internal/core/probes_test.go
+package core
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestIsProbe(t *testing.T) {
+	tests := []struct {
+		name   string
+		probes *ProbeConfig
+		agent  string
+		header string
+		addr   string
+		path   string
+		want   bool
+	}{
+		{"disabled", nil, "kube-probe/1.29", "", "10.0.0.1:1234", "/healthz", false},
+		{"kube-probe", &ProbeConfig{}, "kube-probe/1.29", "", "10.0.0.1:1234", "/healthz", true},
+		{"other agent", &ProbeConfig{}, "curl/8.0", "", "10.0.0.1:1234", "/healthz", false},
+		{"custom agent", &ProbeConfig{UserAgents: []string{"ELB-HealthChecker/"}}, "ELB-HealthChecker/2.0", "", "10.0.0.1:1234", "/", true},
+		{"no agents", &ProbeConfig{UserAgents: []string{}, Header: "X-Health-Probe"}, "kube-probe/1.29", "", "10.0.0.1:1234", "/", false},
+		{"header", &ProbeConfig{Header: "X-Health-Probe"}, "curl/8.0", "1", "10.0.0.1:1234", "/", true},
+		{"header value", &ProbeConfig{Header: "X-Health-Probe", HeaderValue: "s3cret"}, "curl/8.0", "guess", "10.0.0.1:1234", "/", false},
+		{"in network", &ProbeConfig{Networks: []string{"10.0.0.0/8"}}, "kube-probe/1.29", "", "10.1.2.3:1234", "/", true},
+		{"single address", &ProbeConfig{Networks: []string{"192.168.1.5"}}, "kube-probe/1.29", "", "192.168.1.5:1234", "/", true},
+		{"outside network", &ProbeConfig{Networks: []string{"10.0.0.0/8"}}, "kube-probe/1.29", "", "203.0.113.7:1234", "/", false},
+		{"health path", &ProbeConfig{Paths: []string{"/healthz", "/readyz"}}, "kube-probe/1.29", "", "10.0.0.1:1234", "/readyz", true},
+		{"other path", &ProbeConfig{Paths: []string{"/healthz"}}, "kube-probe/1.29", "", "10.0.0.1:1234", "/api/search", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", tt.path, nil)
+			r.Header.Set("User-Agent", tt.agent)
+			if tt.header != "" {
+				r.Header.Set("X-Health-Probe", tt.header)
+			}
+			r.RemoteAddr = tt.addr
+			config := &Config{Probes: tt.probes}
+			if got := config.IsProbe(r); got != tt.want {
+				t.Errorf("IsProbe() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if err := (&ProbeConfig{Networks: []string{"10.0.0.0/33"}}).validate(); err == nil {
+		t.Error("Expected an invalid probe network to fail validation")
+	}
+	if err := (&ProbeConfig{HeaderValue: "s3cret"}).validate(); err == nil {
+		t.Error("Expected a header value without a header to fail validation")
+	}
+}
@@ -0,0 +1,61 @@
+// internal/core/replica.go
+package core
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/itsatony/gorly/stores"
+)
+
+// readStore returns the store serving non-critical reads of a scope such as peeks and
+// diagnostics: the read replica if one is configured, else the primary. Scopes kept in a
+// store of ScopeStores read from that store. stale reports whether the answer may lag
+// behind the primary. Checks never use it.
+func (l *limiterImpl) readStore(scope string) (store Store, stale bool) {
+	if store := l.storeFor(scope); store != l.store {
+		return store, false
+	}
+	if l.replica != nil {
+		return l.replica, true
+	}
+	return l.store, false
+}
+
+// ReadsFromReplica reports whether stats and inspection reads go to a read replica
+func (l *limiterImpl) ReadsFromReplica() bool {
+	return l.replica != nil
+}
+
+// readCounter reads a counter kept with IncrementBy. The primary is read by incrementing by
+// zero, which is atomic; replicas reject writes, so they are read with a plain get.
+func (l *limiterImpl) readCounter(ctx context.Context, key string) (int64, error) {
+	if l.replica == nil {
+		return l.store.IncrementBy(ctx, key, 0, 0)
+	}
+	data, err := l.replica.Get(ctx, key)
+	if err != nil {
+		if stores.IsNotFound(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	return parseCounter(data)
+}
+
+// parseCounter decodes a counter as stored by IncrementBy: a decimal string in Redis,
+// eight big-endian bytes in the memory store
+func parseCounter(data []byte) (int64, error) {
+	if value, err := strconv.ParseInt(string(data), 10, 64); err == nil {
+		return value, nil
+	}
+	if len(data) == 8 {
+		var value int64
+		for _, b := range data {
+			value = value<<8 | int64(b)
+		}
+		return value, nil
+	}
+	return 0, fmt.Errorf("invalid counter value %q", data)
+}
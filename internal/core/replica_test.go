@@ -0,0 +1,116 @@
+// internal/core/replica_test.go
+package core
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// readOnlyStore is a replica that rejects writes like a Redis replica does
+type readOnlyStore struct {
+	Store
+}
+
+var errReadOnly = errors.New("READONLY You can't write against a read only replica")
+
+func (s *readOnlyStore) Set(ctx context.Context, key string, value []byte, expiration time.Duration) error {
+	return errReadOnly
+}
+
+func (s *readOnlyStore) IncrementBy(ctx context.Context, key string, amount int64, expiration time.Duration) (int64, error) {
+	return 0, errReadOnly
+}
+
+// replicate copies keys from the primary to the replica, like replication catching up
+func replicate(t *testing.T, primary Store, replica *readOnlyStore, keys ...string) {
+	t.Helper()
+	for _, key := range keys {
+		value, err := primary.Get(context.Background(), key)
+		if err != nil {
+			t.Fatalf("Failed to read %s from the primary: %v", key, err)
+		}
+		replica.Store.Set(context.Background(), key, value, time.Minute)
+	}
+}
+
+func TestReadReplica(t *testing.T) {
+	ctx := context.Background()
+	primary := newTestStore(t)
+	replica := &readOnlyStore{Store: newTestStore(t)}
+	limiter, err := NewLimiterWithStores(&Config{
+		Algorithm:          "sliding_window",
+		Limits:             map[string]string{"search": "3/minute"},
+		StatsWriteBehind:   true,
+		StatsFlushInterval: time.Hour,
+		StatsFlushEvents:   1000,
+	}, primary, replica)
+	if err != nil {
+		t.Fatalf("Failed to create limiter: %v", err)
+	}
+	l := limiter.(*limiterImpl)
+	defer l.Close()
+
+	// Checks go to the primary
+	for i := 0; i < 2; i++ {
+		if result, err := l.Check(ctx, "user:1", "search"); err != nil || !result.Allowed {
+			t.Fatalf("Check %d: expected to be allowed, got %+v, %v", i+1, result, err)
+		}
+	}
+
+	// Until replication catches up, inspection reads lag behind and say so
+	peek, err := l.Peek(ctx, "user:1", "search")
+	if err != nil || !peek.Stale || peek.Remaining != 3 {
+		t.Fatalf("Expected a stale peek with the full budget, got %+v, %v", peek, err)
+	}
+	key := l.requestKey("user:1", "search")
+	replicate(t, primary, replica, key)
+	if peek, _ := l.Peek(ctx, "user:1", "search"); peek.Remaining != 1 {
+		t.Errorf("Expected the replicated peek to see 2 requests, %d remaining", peek.Remaining)
+	}
+	if d, err := l.Diagnostics(ctx, "user:1", "search"); err != nil || !d.Stale || d.SlidingWindow == nil {
+		t.Errorf("Expected stale diagnostics from the replica, got %+v, %v", d, err)
+	}
+
+	// Shared stats counters are read without writing to the replica
+	if err := l.stats.flush(ctx); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	replicate(t, primary, replica, statsKey(l.config.keys(), "search", "requests"))
+	counters, err := l.StatsCounters(ctx)
+	if err != nil {
+		t.Fatalf("StatsCounters failed: %v", err)
+	}
+	if got := counters["search"]; got.Requests != 2 || got.Denied != 0 {
+		t.Errorf("Expected 2 requests read from the replica, got %+v", got)
+	}
+	if !l.ReadsFromReplica() {
+		t.Error("Expected the limiter to report its read replica")
+	}
+}
+
+func TestParseCounter(t *testing.T) {
+	tests := []struct {
+		data []byte
+		want int64
+	}{
+		{[]byte("42"), 42},                    // Redis
+		{[]byte{0, 0, 0, 0, 0, 0, 1, 2}, 258}, // Memory store
+	}
+	for _, tt := range tests {
+		if got, err := parseCounter(tt.data); err != nil || got != tt.want {
+			t.Errorf("parseCounter(%q) = %d, %v; want %d", tt.data, got, err, tt.want)
+		}
+	}
+	if _, err := parseCounter([]byte("many")); err == nil {
+		t.Error("Expected an error for a value that is no counter")
+	}
+}
+
+func TestReadReplicaValidation(t *testing.T) {
+	config := &Config{Store: "memory", Algorithm: "sliding_window", RedisReadReplica: "replica:6379"}
+	if err := config.Validate(); err == nil {
+		t.Error("Expected a read replica without a redis primary to be rejected")
+	}
+}
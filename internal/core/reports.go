@@ -0,0 +1,35 @@
+// internal/core/reports.go
+package core
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/itsatony/gorly/stores"
+)
+
+// reportStateKey is the store key of the state daily reports are computed from
+func (c *Config) reportStateKey() string {
+	return c.keys().Build("report", "state")
+}
+
+// SaveReportState stores the counters of the last daily report, so the instance sending the
+// next one computes the day's numbers from them. The state never expires.
+func (l *limiterImpl) SaveReportState(ctx context.Context, data []byte) error {
+	if err := l.store.Set(ctx, l.config.reportStateKey(), data, 0); err != nil {
+		return fmt.Errorf("failed to save report state: %w", err)
+	}
+	return nil
+}
+
+// LoadReportState returns the stored report state, or nil if no report was sent yet
+func (l *limiterImpl) LoadReportState(ctx context.Context) ([]byte, error) {
+	data, err := l.store.Get(ctx, l.config.reportStateKey())
+	if err != nil {
+		if stores.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to load report state: %w", err)
+	}
+	return data, nil
+}
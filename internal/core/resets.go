@@ -0,0 +1,176 @@
+// internal/core/resets.go
+package core
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultResetPollInterval is how often instances check schedules and pick up resets made elsewhere
+const DefaultResetPollInterval = time.Second
+
+// resetClaimTTL bounds how long the claim of a single reset occurrence is kept
+const resetClaimTTL = time.Hour
+
+// resetCoordinator performs the scheduled resets of a limiter.
+//
+// A scope is reset by bumping its generation counter in the shared store; the
+// generation is part of every counter key of the scope, so all counters start
+// from zero at once. Each occurrence is claimed through an atomic increment and
+// only the instance that claims it first bumps the generation, so instances
+// sharing a store reset a scope exactly once per scheduled time.
+type resetCoordinator struct {
+	limiter      *limiterImpl
+	schedules    map[string]*Schedule
+	generations  map[string]*atomic.Int64 // read-only map; values updated atomically
+	pollInterval time.Duration
+	now          func() time.Time
+
+	stop chan struct{}
+	done sync.WaitGroup
+}
+
+// newResetCoordinator parses the reset schedules of a config
+func newResetCoordinator(l *limiterImpl) (*resetCoordinator, error) {
+	rc := &resetCoordinator{
+		limiter:      l,
+		schedules:    make(map[string]*Schedule, len(l.config.ScopeResets)),
+		generations:  make(map[string]*atomic.Int64, len(l.config.ScopeResets)),
+		pollInterval: l.config.ResetPollInterval,
+		now:          l.config.Now,
+		stop:         make(chan struct{}),
+	}
+	if rc.pollInterval <= 0 {
+		rc.pollInterval = DefaultResetPollInterval
+	}
+
+	for scope, expr := range l.config.ScopeResets {
+		schedule, err := ParseSchedule(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid reset schedule for scope %s: %w", scope, err)
+		}
+		if name, ok := l.config.ScopeTimeZones[scope]; ok {
+			loc, err := loadLocation(name)
+			if err != nil {
+				return nil, fmt.Errorf("invalid time zone for scope %s: %w", scope, err)
+			}
+			schedule = schedule.In(loc)
+		}
+		rc.schedules[scope] = schedule
+		rc.generations[scope] = new(atomic.Int64)
+	}
+	return rc, nil
+}
+
+// start loads the current generations and begins polling
+func (rc *resetCoordinator) start() {
+	ctx, cancel := context.WithTimeout(context.Background(), rc.pollInterval)
+	rc.refresh(ctx)
+	cancel()
+
+	rc.done.Add(1)
+	go rc.run()
+}
+
+// close stops polling and waits for the coordinator to exit
+func (rc *resetCoordinator) close() {
+	close(rc.stop)
+	rc.done.Wait()
+}
+
+// run fires due resets and refreshes generations until stopped
+func (rc *resetCoordinator) run() {
+	defer rc.done.Done()
+
+	next := make(map[string]time.Time, len(rc.schedules))
+	for scope, schedule := range rc.schedules {
+		next[scope] = schedule.Next(rc.now())
+	}
+
+	ticker := time.NewTicker(rc.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-rc.stop:
+			return
+		case <-ticker.C:
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), rc.pollInterval)
+		now := rc.now()
+		for scope, at := range next {
+			if at.IsZero() || now.Before(at) {
+				continue
+			}
+			if err := rc.fire(ctx, scope, at); err != nil {
+				rc.limiter.reportError(err)
+			}
+			next[scope] = rc.schedules[scope].Next(now)
+		}
+		rc.refresh(ctx)
+		cancel()
+	}
+}
+
+// fire claims the occurrence of a scope's reset at the given time and performs it if the claim wins
+func (rc *resetCoordinator) fire(ctx context.Context, scope string, at time.Time) error {
+	keys := rc.limiter.config.keys()
+
+	claims, err := rc.limiter.store.IncrementBy(ctx, keys.Build("reset", scope, strconv.FormatInt(at.Unix(), 10)), 1, resetClaimTTL)
+	if err != nil {
+		return fmt.Errorf("failed to claim reset of scope %s: %w", scope, err)
+	}
+	if claims != 1 {
+		return nil // Another instance performs this reset
+	}
+
+	generation, err := rc.limiter.store.IncrementBy(ctx, generationKey(keys, scope), 1, 0)
+	if err != nil {
+		return fmt.Errorf("failed to reset scope %s: %w", scope, err)
+	}
+	rc.generations[scope].Store(generation)
+	return nil
+}
+
+// refresh reads the generation of every scheduled scope from the store
+func (rc *resetCoordinator) refresh(ctx context.Context) {
+	keys := rc.limiter.config.keys()
+	for scope, generation := range rc.generations {
+		// Incrementing by zero reads the counter atomically, creating it if needed
+		current, err := rc.limiter.store.IncrementBy(ctx, generationKey(keys, scope), 0, 0)
+		if err != nil {
+			rc.limiter.reportError(fmt.Errorf("failed to read reset generation of scope %s: %w", scope, err))
+			continue
+		}
+		generation.Store(current)
+	}
+}
+
+// generationKey is the store key holding the reset generation of a scope
+func generationKey(keys KeyBuilder, scope string) string {
+	return keys.Build("generation", scope)
+}
+
+// generation returns the key part identifying the current reset generation of a scope
+func (l *limiterImpl) generation(scope string) (string, bool) {
+	if l.resets == nil {
+		return "", false
+	}
+	generation, ok := l.resets.generations[scope]
+	if !ok {
+		return "", false
+	}
+	return "g" + strconv.FormatInt(generation.Load(), 10), true
+}
+
+// reportError passes background errors to the configured error handler
+func (l *limiterImpl) reportError(err error) {
+	if l.config.ErrorHandler != nil {
+		l.config.ErrorHandler(err)
+	}
+}
@@ -0,0 +1,70 @@
+// internal/core/resets_test.go
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// resetTestConfig configures a daily limit reset at midnight
+func resetTestConfig() *Config {
+	return &Config{
+		Limits:      map[string]string{"daily-report": "2/day"},
+		ScopeResets: map[string]string{"daily-report": "@daily"},
+	}
+}
+
+func TestScheduledReset(t *testing.T) {
+	l := newTestLimiter(t, resetTestConfig(), nil)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if result, err := l.Check(ctx, "user-1", "daily-report"); err != nil || !result.Allowed {
+			t.Fatalf("Request %d: expected allowed, got %+v (%v)", i+1, result, err)
+		}
+	}
+	if result, _ := l.Check(ctx, "user-1", "daily-report"); result.Allowed {
+		t.Fatal("Expected daily quota to be exhausted")
+	}
+
+	if err := l.resets.fire(ctx, "daily-report", time.Date(2024, 3, 16, 0, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatalf("Reset failed: %v", err)
+	}
+
+	if result, _ := l.Check(ctx, "user-1", "daily-report"); !result.Allowed || result.Used != 1 {
+		t.Errorf("Expected fresh quota after reset, got %+v", result)
+	}
+}
+
+func TestScheduledResetRunsOncePerOccurrence(t *testing.T) {
+	first := newTestLimiter(t, resetTestConfig(), nil)
+
+	// A second instance sharing the first one's store
+	second := &limiterImpl{config: first.config, store: first.store, algorithm: first.algorithm}
+	resets, err := newResetCoordinator(second)
+	if err != nil {
+		t.Fatalf("Failed to create coordinator: %v", err)
+	}
+	second.resets = resets
+
+	ctx := context.Background()
+	at := time.Date(2024, 3, 16, 0, 0, 0, 0, time.UTC)
+	for _, l := range []*limiterImpl{first, second, first} {
+		if err := l.resets.fire(ctx, "daily-report", at); err != nil {
+			t.Fatalf("Reset failed: %v", err)
+		}
+	}
+
+	second.resets.refresh(ctx)
+	first.resets.refresh(ctx)
+	g1, _ := first.generation("daily-report")
+	g2, _ := second.generation("daily-report")
+	if g1 != "g1" || g2 != "g1" {
+		t.Errorf("Expected a single reset seen by both instances, got %s and %s", g1, g2)
+	}
+
+	if _, ok := first.generation("other"); ok {
+		t.Error("Expected unscheduled scopes to have no generation")
+	}
+}
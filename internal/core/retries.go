@@ -0,0 +1,350 @@
+// internal/core/retries.go
+package core
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/itsatony/gorly/stores"
+)
+
+// Defaults of store read retries
+const (
+	DefaultStoreRetries          = 1
+	DefaultStoreRetryBackoff     = 5 * time.Millisecond
+	DefaultStoreRetryBudget      = 0.1 // Retries and hedges per read
+	DefaultStoreRetriesPerSecond = 10  // Allowed whatever the read volume
+)
+
+// Latency samples kept for the hedge delay, and how many are needed before hedging on them
+const (
+	retryLatencySamples    = 1024
+	retryMinLatencySamples = 100
+	retryLatencyRefresh    = 64 // Samples between recomputations of the P99
+)
+
+// StoreRetryConfig retries the store reads of checks that fail and hedges reads that are
+// slow. Only reads are repeated: they are idempotent, while repeating a write could count
+// a request twice. Retries and hedges draw on a shared budget, so a store that is down or
+// overloaded sees at most a fraction more traffic instead of a retry storm, and a retry
+// is only made if the check's deadline leaves time for it.
+type StoreRetryConfig struct {
+	MaxRetries int           // Retries of a failed read (default: DefaultStoreRetries)
+	Backoff    time.Duration // Wait before the first retry, doubled for every further one (default: 5ms)
+
+	// Hedging sends a second read when the first takes longer than the observed P99 read
+	// latency, or HedgeAfter if set, and uses whichever answers first
+	Hedge      bool
+	HedgeAfter time.Duration
+
+	BudgetRatio         float64 // Retries and hedges allowed per read (default: 0.1)
+	MinRetriesPerSecond int     // Retries and hedges allowed per second regardless of reads (default: 10)
+}
+
+// validate checks the retry settings
+func (rc *StoreRetryConfig) validate() error {
+	if rc.MaxRetries < 0 || rc.Backoff < 0 || rc.HedgeAfter < 0 || rc.BudgetRatio < 0 || rc.MinRetriesPerSecond < 0 {
+		return errors.New("store retry settings cannot be negative")
+	}
+	if rc.BudgetRatio > 1 {
+		return errors.New("store retry budget ratio cannot exceed 1")
+	}
+	return nil
+}
+
+// StoreRetryStats describes the retries and hedges of store reads
+type StoreRetryStats struct {
+	Reads           int64         // Reads made by checks
+	Retries         int64         // Reads repeated after an error
+	Hedges          int64         // Second reads sent because the first was slow
+	HedgeWins       int64         // Hedged reads that answered first
+	BudgetExhausted int64         // Retries and hedges skipped because the budget was spent
+	DeadlineSkipped int64         // Retries skipped because the check's deadline was too close
+	HedgeDelay      time.Duration // Current delay before a hedge; 0 until enough reads were observed
+}
+
+// retryPolicy retries and hedges the store reads of checks
+type retryPolicy struct {
+	config  StoreRetryConfig
+	budget  retryBudget
+	latency latencyWindow
+
+	reads           atomic.Int64
+	retries         atomic.Int64
+	hedges          atomic.Int64
+	hedgeWins       atomic.Int64
+	budgetExhausted atomic.Int64
+	deadlineSkipped atomic.Int64
+}
+
+// newRetryPolicy creates the retry policy of a config, nil if store retries are disabled
+func newRetryPolicy(config *Config) *retryPolicy {
+	if config.StoreRetries == nil {
+		return nil
+	}
+	rc := *config.StoreRetries
+	if rc.MaxRetries == 0 {
+		rc.MaxRetries = DefaultStoreRetries
+	}
+	if rc.Backoff == 0 {
+		rc.Backoff = DefaultStoreRetryBackoff
+	}
+	if rc.BudgetRatio == 0 {
+		rc.BudgetRatio = DefaultStoreRetryBudget
+	}
+	if rc.MinRetriesPerSecond == 0 {
+		rc.MinRetriesPerSecond = DefaultStoreRetriesPerSecond
+	}
+	return &retryPolicy{
+		config:  rc,
+		budget:  retryBudget{ratio: rc.BudgetRatio, min: float64(rc.MinRetriesPerSecond)},
+		latency: latencyWindow{samples: make([]time.Duration, 0, retryLatencySamples)},
+	}
+}
+
+// wrap returns store with retried and hedged reads
+func (p *retryPolicy) wrap(store Store) Store {
+	if p == nil {
+		return store
+	}
+	return &retryStore{Store: store, policy: p}
+}
+
+// retryStore retries and hedges the reads of a store; every other operation passes through
+type retryStore struct {
+	Store
+	policy *retryPolicy
+}
+
+func (s *retryStore) Get(ctx context.Context, key string) ([]byte, error) {
+	return s.policy.get(ctx, s.Store, key)
+}
+
+// get reads a key, retrying failures within the budget and the deadline of ctx
+func (p *retryPolicy) get(ctx context.Context, store Store, key string) ([]byte, error) {
+	p.reads.Add(1)
+	p.budget.deposit(time.Now())
+
+	backoff := p.config.Backoff
+	for attempt := 0; ; attempt++ {
+		data, err := p.read(ctx, store, key)
+		if !p.retryable(ctx, err) || attempt >= p.config.MaxRetries {
+			return data, err
+		}
+		if !p.leavesTime(ctx, backoff) {
+			p.deadlineSkipped.Add(1)
+			return data, err
+		}
+		if !p.budget.withdraw(time.Now()) {
+			p.budgetExhausted.Add(1)
+			return data, err
+		}
+		p.retries.Add(1)
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		}
+		backoff *= 2
+	}
+}
+
+// retryable reports whether a failed read is worth repeating: missing keys are an answer,
+// and a check whose context is done has no use for one
+func (p *retryPolicy) retryable(ctx context.Context, err error) bool {
+	return err != nil && !stores.IsNotFound(err) && ctx.Err() == nil
+}
+
+// leavesTime reports whether the deadline of ctx leaves room for a read after waiting
+func (p *retryPolicy) leavesTime(ctx context.Context, wait time.Duration) bool {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return true
+	}
+	return time.Until(deadline) > wait+p.latency.p99()
+}
+
+// answered reports whether a read got an answer from the store
+func answered(err error) bool {
+	return err == nil || stores.IsNotFound(err)
+}
+
+// readReply is the answer of one of the reads of a hedged read
+type readReply struct {
+	data   []byte
+	err    error
+	hedged bool
+}
+
+// read reads a key once, sending a second read if the first is slower than the hedge delay
+func (p *retryPolicy) read(ctx context.Context, store Store, key string) ([]byte, error) {
+	delay := p.hedgeDelay()
+	if delay == 0 {
+		start := time.Now()
+		data, err := store.Get(ctx, key)
+		if answered(err) {
+			p.latency.observe(time.Since(start))
+		}
+		return data, err
+	}
+
+	// The read that loses is canceled once the other answers
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	replies := make(chan readReply, 2)
+	send := func(hedged bool) {
+		start := time.Now()
+		data, err := store.Get(ctx, key)
+		if answered(err) {
+			p.latency.observe(time.Since(start))
+		}
+		replies <- readReply{data: data, err: err, hedged: hedged}
+	}
+	go send(false)
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	hedge := timer.C
+	pending := 1
+	for {
+		select {
+		case reply := <-replies:
+			pending--
+			if answered(reply.err) || pending == 0 {
+				if reply.hedged && answered(reply.err) {
+					p.hedgeWins.Add(1)
+				}
+				return reply.data, reply.err
+			}
+			// One read failed while the other is still on its way; wait for it
+		case <-hedge:
+			hedge = nil
+			if !p.leavesTime(ctx, 0) {
+				continue
+			}
+			if !p.budget.withdraw(time.Now()) {
+				p.budgetExhausted.Add(1)
+				continue
+			}
+			p.hedges.Add(1)
+			pending++
+			go send(true)
+		}
+	}
+}
+
+// hedgeDelay returns how long a read may take before it is hedged, 0 to not hedge
+func (p *retryPolicy) hedgeDelay() time.Duration {
+	if !p.config.Hedge {
+		return 0
+	}
+	if p.config.HedgeAfter > 0 {
+		return p.config.HedgeAfter
+	}
+	return p.latency.p99()
+}
+
+// stats returns the counters of the policy
+func (p *retryPolicy) stats() *StoreRetryStats {
+	return &StoreRetryStats{
+		Reads:           p.reads.Load(),
+		Retries:         p.retries.Load(),
+		Hedges:          p.hedges.Load(),
+		HedgeWins:       p.hedgeWins.Load(),
+		BudgetExhausted: p.budgetExhausted.Load(),
+		DeadlineSkipped: p.deadlineSkipped.Load(),
+		HedgeDelay:      p.hedgeDelay(),
+	}
+}
+
+// retryBudget allows, per second, MinRetriesPerSecond retries plus BudgetRatio of the
+// reads made in that second
+type retryBudget struct {
+	ratio float64
+	min   float64
+
+	mu      sync.Mutex
+	second  int64 // Unix second the counts belong to
+	reads   float64
+	retries float64
+}
+
+// roll starts a new second's counts; the caller holds b.mu
+func (b *retryBudget) roll(now time.Time) {
+	if second := now.Unix(); second != b.second {
+		b.second, b.reads, b.retries = second, 0, 0
+	}
+}
+
+// deposit counts a read
+func (b *retryBudget) deposit(now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.roll(now)
+	b.reads++
+}
+
+// withdraw takes one retry from the budget, reporting whether one was left
+func (b *retryBudget) withdraw(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.roll(now)
+	if b.retries >= b.min+b.ratio*b.reads {
+		return false
+	}
+	b.retries++
+	return true
+}
+
+// latencyWindow keeps the latencies of recent reads and their P99
+type latencyWindow struct {
+	mu      sync.Mutex
+	samples []time.Duration // Ring buffer of at most retryLatencySamples
+	next    int
+	count   int // Samples since the P99 was computed
+	cached  atomic.Int64
+}
+
+// observe records the latency of a read that got an answer
+func (w *latencyWindow) observe(latency time.Duration) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.samples) < cap(w.samples) {
+		w.samples = append(w.samples, latency)
+	} else {
+		w.samples[w.next] = latency
+		w.next = (w.next + 1) % len(w.samples)
+	}
+	w.count++
+	if len(w.samples) >= retryMinLatencySamples && (w.count >= retryLatencyRefresh || w.cached.Load() == 0) {
+		w.count = 0
+		sorted := slices.Clone(w.samples)
+		slices.Sort(sorted)
+		w.cached.Store(int64(sorted[len(sorted)*99/100]))
+	}
+}
+
+// p99 returns the P99 read latency, 0 until enough reads were observed
+func (w *latencyWindow) p99() time.Duration {
+	return time.Duration(w.cached.Load())
+}
+
+// StoreRetryStats returns the retries and hedges of store reads, nil if they are disabled
+func (l *limiterImpl) StoreRetryStats() *StoreRetryStats {
+	if l.retries == nil {
+		return nil
+	}
+	return l.retries.stats()
+}
+
+// checkStore returns the store a check of scope reads and writes its limit state in
+func (l *limiterImpl) checkStore(scope string) Store {
+	return l.retries.wrap(l.storeFor(scope))
+}
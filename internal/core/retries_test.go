@@ -0,0 +1,146 @@
+// internal/core/retries_test.go
+package core
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/itsatony/gorly/stores"
+)
+
+// unreliableStore fails its first failures reads and delays the following slow reads by delay
+type unreliableStore struct {
+	Store
+	failures atomic.Int64
+	slow     atomic.Int64
+	delay    time.Duration
+	reads    atomic.Int64
+}
+
+func (s *unreliableStore) Get(ctx context.Context, key string) ([]byte, error) {
+	s.reads.Add(1)
+	if s.failures.Add(-1) >= 0 {
+		return nil, errors.New("i/o timeout")
+	}
+	if s.slow.Add(-1) >= 0 {
+		select {
+		case <-time.After(s.delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	return s.Store.Get(ctx, key)
+}
+
+func newRetryTestPolicy(rc StoreRetryConfig) *retryPolicy {
+	return newRetryPolicy(&Config{StoreRetries: &rc})
+}
+
+func TestStoreRetries(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("retries failed reads", func(t *testing.T) {
+		store := &unreliableStore{Store: newTestStore(t)}
+		store.failures.Store(1)
+		policy := newRetryTestPolicy(StoreRetryConfig{Backoff: time.Millisecond})
+
+		_, err := policy.wrap(store).Get(ctx, "missing")
+		if !stores.IsNotFound(err) {
+			t.Fatalf("Expected the retry to reach the store, got %v", err)
+		}
+		if stats := policy.stats(); stats.Reads != 1 || stats.Retries != 1 {
+			t.Errorf("Expected 1 read and 1 retry, got %+v", stats)
+		}
+	})
+
+	t.Run("missing keys are not retried", func(t *testing.T) {
+		store := &unreliableStore{Store: newTestStore(t)}
+		policy := newRetryTestPolicy(StoreRetryConfig{MaxRetries: 3})
+
+		if _, err := policy.wrap(store).Get(ctx, "missing"); !stores.IsNotFound(err) {
+			t.Fatalf("Expected not found, got %v", err)
+		}
+		if reads := store.reads.Load(); reads != 1 {
+			t.Errorf("Expected a single read, got %d", reads)
+		}
+	})
+
+	t.Run("budget", func(t *testing.T) {
+		store := &unreliableStore{Store: newTestStore(t)}
+		store.failures.Store(100)
+		policy := newRetryTestPolicy(StoreRetryConfig{Backoff: time.Microsecond, BudgetRatio: 0.1, MinRetriesPerSecond: 2})
+
+		for i := 0; i < 10; i++ {
+			policy.wrap(store).Get(ctx, "key")
+		}
+		stats := policy.stats()
+		// Two retries per second plus one for every ten reads; a second boundary may add a few
+		if stats.Retries < 3 || stats.Retries > 6 || stats.BudgetExhausted == 0 {
+			t.Errorf("Expected the budget to cap retries, got %+v", stats)
+		}
+	})
+
+	t.Run("deadline", func(t *testing.T) {
+		store := &unreliableStore{Store: newTestStore(t)}
+		store.failures.Store(1)
+		policy := newRetryTestPolicy(StoreRetryConfig{Backoff: 50 * time.Millisecond})
+
+		deadlineCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+		defer cancel()
+		if _, err := policy.wrap(store).Get(deadlineCtx, "key"); err == nil || stores.IsNotFound(err) {
+			t.Fatalf("Expected the read error, got %v", err)
+		}
+		if stats := policy.stats(); stats.Retries != 0 || stats.DeadlineSkipped != 1 {
+			t.Errorf("Expected the retry to be skipped, got %+v", stats)
+		}
+	})
+
+	t.Run("hedge", func(t *testing.T) {
+		store := &unreliableStore{Store: newTestStore(t), delay: time.Second}
+		store.slow.Store(1)
+		policy := newRetryTestPolicy(StoreRetryConfig{Hedge: true, HedgeAfter: 5 * time.Millisecond})
+
+		// The first read is slow, the hedge answers at once
+		start := time.Now()
+		if _, err := policy.wrap(store).Get(ctx, "missing"); !stores.IsNotFound(err) {
+			t.Fatalf("Expected the hedged read to answer, got %v", err)
+		}
+		if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+			t.Errorf("Expected the hedge to answer before the slow read, took %v", elapsed)
+		}
+		if stats := policy.stats(); stats.Hedges != 1 || stats.HedgeWins != 1 || stats.HedgeDelay != 5*time.Millisecond {
+			t.Errorf("Expected a winning hedge, got %+v", stats)
+		}
+	})
+
+	if err := (&StoreRetryConfig{BudgetRatio: 2}).validate(); err == nil {
+		t.Error("Expected a budget ratio above 1 to fail validation")
+	}
+	if err := (&StoreRetryConfig{MaxRetries: -1}).validate(); err == nil {
+		t.Error("Expected negative retries to fail validation")
+	}
+}
+
+func TestCheckRetriesStoreReads(t *testing.T) {
+	store := &unreliableStore{Store: newTestStore(t)}
+	limiter := newTestLimiter(t, &Config{
+		Algorithm:    "sliding_window",
+		Limits:       map[string]string{"global": "10/minute"},
+		StoreRetries: &StoreRetryConfig{MaxRetries: 2, Backoff: time.Millisecond},
+	}, store)
+
+	store.failures.Store(1)
+	result, err := limiter.Check(context.Background(), "user1", "global")
+	if err != nil {
+		t.Fatalf("Expected the retry to hide the failed read, got %v", err)
+	}
+	if !result.Allowed {
+		t.Error("Expected the request to be allowed")
+	}
+	if stats := limiter.StoreRetryStats(); stats == nil || stats.Retries != 1 {
+		t.Errorf("Expected one retry, got %+v", stats)
+	}
+}
@@ -0,0 +1,179 @@
+// internal/core/sanitize.go
+package core
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// DefaultMaxEntityLength is the longest entity accepted before normalization
+const DefaultMaxEntityLength = 256
+
+// DefaultMaxScopeLength is the longest scope accepted before normalization
+const DefaultMaxScopeLength = 64
+
+// overflowDigestLength is how many hex digits of a digest replace the tail of an overlong value
+const overflowDigestLength = 16
+
+// Fields reported in InputError
+const (
+	InputFieldEntity = "entity"
+	InputFieldScope  = "scope"
+)
+
+// InputError reports an entity or scope rejected by input validation.
+// The offending value is deliberately not included, since it may be attacker-controlled.
+type InputError struct {
+	Field  string // InputFieldEntity, InputFieldScope or InputFieldTier
+	Reason string
+}
+
+// Error implements the error interface
+func (e *InputError) Error() string {
+	return "invalid " + e.Field + ": " + e.Reason
+}
+
+// SanitizeEntity validates an entity before it is used in keys, metrics or logs.
+// Control characters and invalid UTF-8 are stripped, surrounding whitespace is trimmed
+// and overlong values are shortened with a digest suffix so distinct entities stay distinct.
+// With RejectInvalidInput set, an entity that would change returns an *InputError instead.
+func (c *Config) SanitizeEntity(entity string) (string, error) {
+	return c.sanitize(InputFieldEntity, entity, c.maxEntityLength(), isEntityRune)
+}
+
+// SanitizeScope validates a scope the same way as SanitizeEntity.
+// Scopes are additionally restricted to letters, digits and "_-.:/".
+// Unconfigured scopes beyond the MaxScopes budget are replaced by OverflowScope.
+func (c *Config) SanitizeScope(scope string) (string, error) {
+	if c.isConfiguredScope(scope) {
+		return scope, nil
+	}
+	scope, err := c.sanitize(InputFieldScope, scope, c.maxScopeLength(), isScopeRune)
+	if err != nil || c.isConfiguredScope(scope) {
+		return scope, err
+	}
+	return c.scopes.admit(scope), nil
+}
+
+// sanitize normalizes value, or rejects it when normalization would change it and
+// the config asks for rejection
+func (c *Config) sanitize(field, value string, maxLength int, allowed func(rune) bool) (string, error) {
+	normalized, reason := NormalizeInput(value, maxLength, allowed)
+	if reason != "" && c.RejectInvalidInput {
+		return "", &InputError{Field: field, Reason: reason}
+	}
+	return normalized, nil
+}
+
+// NormalizeInput strips disallowed runes and trims whitespace, then shortens values
+// longer than maxLength bytes. A nil allowed function permits every printable rune.
+// The returned reason describes the first change made, or is empty if value was kept.
+func NormalizeInput(value string, maxLength int, allowed func(rune) bool) (string, string) {
+	if allowed == nil {
+		allowed = isEntityRune
+	}
+
+	reason := ""
+	if !utf8.ValidString(value) {
+		reason = "invalid UTF-8"
+	}
+
+	var b strings.Builder
+	for _, r := range value {
+		if r == utf8.RuneError || !allowed(r) {
+			if reason == "" {
+				reason = "disallowed character"
+			}
+			continue
+		}
+		b.WriteRune(r)
+	}
+
+	normalized := strings.TrimSpace(b.String())
+	if reason == "" && len(normalized) != b.Len() {
+		reason = "surrounding whitespace"
+	}
+
+	if maxLength > 0 && len(normalized) > maxLength {
+		if reason == "" {
+			reason = "too long"
+		}
+		normalized = shorten(normalized, maxLength)
+	}
+
+	return normalized, reason
+}
+
+// shorten cuts value to maxLength bytes, replacing the tail with a digest of the full value
+func shorten(value string, maxLength int) string {
+	sum := sha256.Sum256([]byte(value))
+	digest := hex.EncodeToString(sum[:])[:overflowDigestLength]
+	if maxLength <= overflowDigestLength+1 {
+		return digest[:maxLength]
+	}
+
+	// Cut on a rune boundary so the result stays valid UTF-8
+	cut := maxLength - overflowDigestLength - 1
+	for cut > 0 && !utf8.RuneStart(value[cut]) {
+		cut--
+	}
+	return value[:cut] + "~" + digest
+}
+
+// isEntityRune permits every printable rune; key escaping handles separators
+func isEntityRune(r rune) bool {
+	return unicode.IsPrint(r)
+}
+
+// isScopeRune permits the characters used by scope names, including versioned scopes like "v1:search"
+func isScopeRune(r rune) bool {
+	return r < utf8.RuneSelf && (unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("_-.:/", r))
+}
+
+// maxEntityLength returns the configured entity length limit
+func (c *Config) maxEntityLength() int {
+	if c.MaxEntityLength != 0 {
+		return c.MaxEntityLength
+	}
+	return DefaultMaxEntityLength
+}
+
+// maxScopeLength returns the configured scope length limit
+func (c *Config) maxScopeLength() int {
+	if c.MaxScopeLength != 0 {
+		return c.MaxScopeLength
+	}
+	return DefaultMaxScopeLength
+}
+
+// isConfiguredScope reports whether scope was set up by the application and is therefore trusted
+func (c *Config) isConfiguredScope(scope string) bool {
+	table := c.limitTable()
+	if _, ok := table.limits[scope]; ok {
+		return true
+	}
+	if _, ok := table.tierLimits[scope]; ok {
+		return true
+	}
+	if _, ok := c.BandwidthLimits[scope]; ok {
+		return true
+	}
+	_, ok := c.TokenBudgets[scope]
+	return ok
+}
+
+// sanitize validates the entity and scope of a limiter call
+func (l *limiterImpl) sanitize(entity, scope string) (string, string, error) {
+	entity, err := l.config.SanitizeEntity(entity)
+	if err != nil {
+		return "", "", err
+	}
+	scope, err = l.config.SanitizeScope(scope)
+	if err != nil {
+		return "", "", err
+	}
+	return entity, scope, nil
+}
@@ -0,0 +1,129 @@
+// internal/core/sanitize_test.go
+package core
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestSanitizeEntity(t *testing.T) {
+	config := &Config{}
+
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"user-42", "user-42"},
+		{"api:key with space", "api:key with space"},
+		{"  padded  ", "padded"},
+		{"evil\r\nINFO fake log line", "evilINFO fake log line"},
+		{"bell\x07\x00", "bell"},
+		{"bad\xffutf8", "badutf8"},
+		{"ünïcode", "ünïcode"},
+	}
+
+	for _, tt := range tests {
+		got, err := config.SanitizeEntity(tt.input)
+		if err != nil {
+			t.Errorf("SanitizeEntity(%q) returned error: %v", tt.input, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("SanitizeEntity(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestSanitizeEntityLength(t *testing.T) {
+	config := &Config{MaxEntityLength: 32}
+
+	long := strings.Repeat("é", 40)
+	got, _ := config.SanitizeEntity(long)
+	if len(got) > 32 || !utf8.ValidString(got) {
+		t.Errorf("Expected valid UTF-8 of at most 32 bytes, got %q (%d)", got, len(got))
+	}
+
+	// Entities sharing a long prefix stay distinct after shortening
+	a, _ := config.SanitizeEntity(strings.Repeat("x", 100) + "a")
+	b, _ := config.SanitizeEntity(strings.Repeat("x", 100) + "b")
+	if a == b {
+		t.Errorf("Expected distinct shortened entities, both were %q", a)
+	}
+}
+
+func TestSanitizeScope(t *testing.T) {
+	config := &Config{Limits: map[string]string{"my scope": "10/minute"}}
+
+	if got, _ := config.SanitizeScope("v1:search"); got != "v1:search" {
+		t.Errorf("Expected versioned scope to be kept, got %q", got)
+	}
+	if got, _ := config.SanitizeScope("search<script>"); got != "searchscript" {
+		t.Errorf("Expected disallowed characters to be stripped, got %q", got)
+	}
+	if got, _ := config.SanitizeScope("my scope"); got != "my scope" {
+		t.Errorf("Expected configured scope to be trusted, got %q", got)
+	}
+	if got, _ := config.SanitizeScope(strings.Repeat("s", 200)); len(got) != DefaultMaxScopeLength {
+		t.Errorf("Expected scope shortened to %d bytes, got %d", DefaultMaxScopeLength, len(got))
+	}
+}
+
+func TestSanitizeReject(t *testing.T) {
+	config := &Config{RejectInvalidInput: true, MaxEntityLength: 16}
+
+	if got, err := config.SanitizeEntity("user-1"); err != nil || got != "user-1" {
+		t.Errorf("Expected valid entity to pass, got %q (%v)", got, err)
+	}
+
+	for _, input := range []string{"line\nbreak", strings.Repeat("x", 17), " padded"} {
+		_, err := config.SanitizeEntity(input)
+		var inputErr *InputError
+		if !errors.As(err, &inputErr) || inputErr.Field != InputFieldEntity {
+			t.Errorf("Expected entity InputError for %q, got %v", input, err)
+			continue
+		}
+		if strings.Contains(err.Error(), input) {
+			t.Errorf("Error message must not echo the rejected value: %v", err)
+		}
+	}
+
+	if _, err := config.SanitizeScope("bad scope"); err == nil {
+		t.Error("Expected scope with whitespace to be rejected")
+	}
+}
+
+func TestScopeBudget(t *testing.T) {
+	config := &Config{Limits: map[string]string{"global": "10/minute", "upload": "5/minute"}, MaxScopes: 2}
+	config.attachScopeBudget()
+
+	for _, scope := range []string{"a", "b", "a"} {
+		if got, _ := config.SanitizeScope(scope); got != scope {
+			t.Errorf("Expected scope %q within the budget to be kept, got %q", scope, got)
+		}
+	}
+	if got, _ := config.SanitizeScope("c"); got != OverflowScope {
+		t.Errorf("Expected scope beyond the budget to overflow, got %q", got)
+	}
+
+	// Configured scopes and the overflow scope never count against the budget
+	for _, scope := range []string{"upload", "global", OverflowScope} {
+		if got, _ := config.SanitizeScope(scope); got != scope {
+			t.Errorf("Expected scope %q to be kept, got %q", scope, got)
+		}
+	}
+	if overflows := config.scopes.overflows.Load(); overflows != 1 {
+		t.Errorf("Expected 1 overflow, got %d", overflows)
+	}
+
+	unlimited := &Config{MaxScopes: -1}
+	unlimited.attachScopeBudget()
+	for i := 0; i < DefaultMaxScopes+1; i++ {
+		scope := fmt.Sprintf("s%d", i)
+		if got, _ := unlimited.SanitizeScope(scope); got != scope {
+			t.Fatalf("Expected a negative budget to keep every scope, got %q", got)
+		}
+	}
+}
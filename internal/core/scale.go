@@ -0,0 +1,36 @@
+// internal/core/scale.go
+package core
+
+import (
+	"fmt"
+	"math"
+)
+
+// MaxScale bounds the runtime limit multiplier
+const MaxScale = 100.0
+
+// validateScale checks a limit multiplier
+func validateScale(factor float64) error {
+	if math.IsNaN(factor) || factor <= 0 || factor > MaxScale {
+		return fmt.Errorf("scale must be greater than 0 and at most %g, got %g", MaxScale, factor)
+	}
+	return nil
+}
+
+// SetScale multiplies every configured limit by factor until changed again
+func (l *limiterImpl) SetScale(factor float64) error {
+	if err := validateScale(factor); err != nil {
+		return err
+	}
+	return l.UpdateLimits(LimitUpdate{Scale: factor})
+}
+
+// Scale returns the current limit multiplier
+func (l *limiterImpl) Scale() float64 {
+	return l.limitTable().scale
+}
+
+// scaled applies the current limit multiplier to a configured amount
+func (l *limiterImpl) scaled(amount int64) int64 {
+	return l.limitTable().scaled(amount)
+}
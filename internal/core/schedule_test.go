@@ -0,0 +1,91 @@
+// internal/core/schedule_test.go
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseSchedule(t *testing.T) {
+	valid := []string{"0 0 * * *", "*/15 * * * *", "0 9-17 * * 1-5", "30 4 1,15 * *", "@daily", "@hourly", "0 0 * * 7"}
+	for _, expr := range valid {
+		if _, err := ParseSchedule(expr); err != nil {
+			t.Errorf("Expected %q to parse, got %v", expr, err)
+		}
+	}
+
+	invalid := []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "*/0 * * * *", "5-1 * * * *", "@sometimes"}
+	for _, expr := range invalid {
+		if _, err := ParseSchedule(expr); err == nil {
+			t.Errorf("Expected %q to be rejected", expr)
+		}
+	}
+}
+
+func TestScheduleNext(t *testing.T) {
+	base := time.Date(2024, 3, 15, 10, 7, 30, 0, time.UTC) // Friday
+
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{"@daily", time.Date(2024, 3, 16, 0, 0, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2024, 3, 15, 10, 15, 0, 0, time.UTC)},
+		{"0 9 * * 1", time.Date(2024, 3, 18, 9, 0, 0, 0, time.UTC)},
+		{"0 0 1 * *", time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 0", time.Date(2024, 3, 17, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2024, 3, 17, 0, 0, 0, 0, time.UTC)},
+		// Restricted day-of-month and day-of-week match if either matches
+		{"0 0 20 * 6", time.Date(2024, 3, 16, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		schedule, err := ParseSchedule(tt.expr)
+		if err != nil {
+			t.Fatalf("Failed to parse %q: %v", tt.expr, err)
+		}
+		if got := schedule.Next(base); !got.Equal(tt.want) {
+			t.Errorf("%q: Next = %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+
+	// Next is strictly after the given time
+	schedule, _ := ParseSchedule("@hourly")
+	at := time.Date(2024, 3, 15, 11, 0, 0, 0, time.UTC)
+	if got := schedule.Next(at); !got.Equal(at.Add(time.Hour)) {
+		t.Errorf("Expected next occurrence after %v, got %v", at, got)
+	}
+}
+
+func TestScheduleNextInTimeZone(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("Time zone database unavailable: %v", err)
+	}
+
+	tests := []struct {
+		expr string
+		from time.Time
+		want time.Time
+	}{
+		// Local midnight is 05:00 UTC in winter and 04:00 UTC in summer
+		{"@daily", time.Date(2026, 3, 7, 12, 0, 0, 0, newYork), time.Date(2026, 3, 8, 5, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2026, 3, 8, 12, 0, 0, 0, newYork), time.Date(2026, 3, 9, 4, 0, 0, 0, time.UTC)},
+		// 02:30 does not exist on 8 March 2026
+		{"30 2 * * *", time.Date(2026, 3, 8, 0, 0, 0, 0, newYork), time.Date(2026, 3, 9, 6, 30, 0, 0, time.UTC)},
+		// 01:30 occurs twice on 1 November 2026 and fires the first time only
+		{"30 1 * * *", time.Date(2026, 11, 1, 0, 0, 0, 0, newYork), time.Date(2026, 11, 1, 5, 30, 0, 0, time.UTC)},
+		{"30 1 * * *", time.Date(2026, 11, 1, 5, 30, 0, 0, time.UTC), time.Date(2026, 11, 2, 6, 30, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		schedule, err := ParseSchedule(tt.expr)
+		if err != nil {
+			t.Fatalf("Failed to parse %q: %v", tt.expr, err)
+		}
+		if got := schedule.In(newYork).Next(tt.from); !got.Equal(tt.want) {
+			t.Errorf("%q after %v: Next = %v, want %v", tt.expr, tt.from, got, tt.want.In(newYork))
+		}
+	}
+}
@@ -0,0 +1,72 @@
+// internal/core/scopebudget.go
+package core
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultMaxScopes is how many distinct unconfigured scopes are tracked before the rest overflow
+const DefaultMaxScopes = 1000
+
+// OverflowScope is shared by every unconfigured scope beyond the MaxScopes budget
+const OverflowScope = "__other"
+
+// scopeBudget caps the distinct scopes a scope function can create. A buggy function
+// deriving one scope per URL would otherwise grow store keys and metric series without
+// bound; scopes beyond the budget share OverflowScope instead.
+type scopeBudget struct {
+	max       int
+	mu        sync.RWMutex
+	seen      map[string]struct{}
+	overflows atomic.Int64 // Checks whose scope was folded into OverflowScope
+}
+
+// attachScopeBudget sets up the scope budget of a config. Limiters built from the same
+// config share it, so their scopes count against one budget.
+func (c *Config) attachScopeBudget() {
+	if c.scopes != nil || c.MaxScopes < 0 {
+		return
+	}
+	max := c.MaxScopes
+	if max == 0 {
+		max = DefaultMaxScopes
+	}
+	c.scopes = &scopeBudget{max: max, seen: make(map[string]struct{})}
+}
+
+// admit returns scope while it fits the budget and OverflowScope once the budget is spent.
+// Scopes admitted earlier stay admitted.
+func (b *scopeBudget) admit(scope string) string {
+	if b == nil || scope == "" || scope == OverflowScope {
+		return scope
+	}
+
+	b.mu.RLock()
+	_, ok := b.seen[scope]
+	b.mu.RUnlock()
+	if ok {
+		return scope
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.seen[scope]; ok {
+		return scope
+	}
+	if len(b.seen) >= b.max {
+		b.overflows.Add(1)
+		return OverflowScope
+	}
+	b.seen[scope] = struct{}{}
+	return scope
+}
+
+// ScopeOverflows returns how many checks had their scope folded into OverflowScope.
+// A growing count means the scope function creates more scopes than MaxScopes allows.
+func (l *limiterImpl) ScopeOverflows() int64 {
+	if l.config.scopes == nil {
+		return 0
+	}
+	return l.config.scopes.overflows.Load()
+}
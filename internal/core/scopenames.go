@@ -0,0 +1,140 @@
+// internal/core/scopenames.go
+package core
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// ReservedScopePrefix starts the names of scopes the limiter keeps for its own bookkeeping,
+// such as OverflowScope. Configuration can neither define nor target them.
+const ReservedScopePrefix = "__"
+
+// IsReservedScope reports whether scope is kept for the limiter's own bookkeeping
+func IsReservedScope(scope string) bool {
+	return strings.HasPrefix(scope, ReservedScopePrefix)
+}
+
+// ValidateScopePatterns checks the syntax of deny-listed scope patterns, which use
+// path.Match syntax, e.g. "debug*" or "internal:*"
+func ValidateScopePatterns(patterns []string) error {
+	for _, pattern := range patterns {
+		if pattern == "" {
+			return fmt.Errorf("denied scope pattern cannot be empty")
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid denied scope pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// CheckScopeName returns an error naming the reason if configuration may not use scope:
+// it is reserved or matches one of the denied patterns
+func CheckScopeName(scope string, denied []string) error {
+	if IsReservedScope(scope) {
+		return fmt.Errorf("scope %s is reserved: names starting with %s are used internally", scope, ReservedScopePrefix)
+	}
+	for _, pattern := range denied {
+		if matched, _ := path.Match(pattern, scope); matched {
+			return fmt.Errorf("scope %s matches the denied scope pattern %q", scope, pattern)
+		}
+	}
+	return nil
+}
+
+// checkScopeNames checks the scope names of a config section, in sorted order so the
+// same config always reports the same scope
+func checkScopeNames[V any](what string, scopes map[string]V, denied []string) error {
+	names := make([]string, 0, len(scopes))
+	for scope := range scopes {
+		names = append(names, scope)
+	}
+	sort.Strings(names)
+	for _, scope := range names {
+		if err := CheckScopeName(scope, denied); err != nil {
+			return fmt.Errorf("invalid %s: %w", what, err)
+		}
+	}
+	return nil
+}
+
+// validateScopeNames checks that no part of the config defines or targets a reserved or
+// denied scope
+func (c *Config) validateScopeNames() error {
+	if err := ValidateScopePatterns(c.DeniedScopes); err != nil {
+		return err
+	}
+
+	sections := []struct {
+		what   string
+		scopes map[string]string
+	}{
+		{"limit", c.Limits},
+		{"bandwidth limit", c.BandwidthLimits},
+		{"token budget", c.TokenBudgets},
+		{"enforcement mode", c.ScopeEnforcement},
+		{"failure policy", c.ScopeFailurePolicies},
+		{"store", c.ScopeStores},
+		{"time zone", c.ScopeTimeZones},
+		{"reset schedule", c.ScopeResets},
+		{"header mode", c.ScopeHeaderModes},
+	}
+	for _, section := range sections {
+		if err := checkScopeNames(section.what, section.scopes, c.DeniedScopes); err != nil {
+			return err
+		}
+	}
+	if err := checkScopeNames("tier limit", c.TierLimits, c.DeniedScopes); err != nil {
+		return err
+	}
+	if err := checkScopeNames("denied status code", c.ScopeDeniedStatusCodes, c.DeniedScopes); err != nil {
+		return err
+	}
+	if err := checkOverrideScopes(c.Overrides, c.DeniedScopes); err != nil {
+		return err
+	}
+
+	for method, scope := range c.MethodScopes {
+		if err := CheckScopeName(scope, c.DeniedScopes); err != nil {
+			return fmt.Errorf("invalid method scope for %s: %w", method, err)
+		}
+	}
+	if c.BotScope != "" {
+		if err := CheckScopeName(c.BotScope, c.DeniedScopes); err != nil {
+			return fmt.Errorf("invalid bot scope: %w", err)
+		}
+	}
+	if c.HasPreAuth() {
+		if err := CheckScopeName(c.PreAuthScopeName(), c.DeniedScopes); err != nil {
+			return fmt.Errorf("invalid pre-auth scope: %w", err)
+		}
+	}
+	return nil
+}
+
+// checkOverrideScopes checks the scopes entity overrides target
+func checkOverrideScopes(overrides []Override, denied []string) error {
+	for _, o := range overrides {
+		if err := CheckScopeName(o.Scope, denied); err != nil {
+			return fmt.Errorf("invalid override for %s: %w", o.Entity, err)
+		}
+	}
+	return nil
+}
+
+// validateUpdateScopes checks that a runtime limit update defines no reserved or denied scope
+func (c *Config) validateUpdateScopes(update LimitUpdate) error {
+	if err := checkScopeNames("limit", update.Limits, c.DeniedScopes); err != nil {
+		return err
+	}
+	if err := checkScopeNames("tier limit", update.TierLimits, c.DeniedScopes); err != nil {
+		return err
+	}
+	if err := checkScopeNames("enforcement mode", update.Modes, c.DeniedScopes); err != nil {
+		return err
+	}
+	return checkOverrideScopes(update.Overrides, c.DeniedScopes)
+}
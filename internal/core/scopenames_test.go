@@ -0,0 +1,77 @@
+// internal/core/scopenames_test.go
+package core
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestValidateScopeNames(t *testing.T) {
+	base := func() *Config {
+		return &Config{
+			Store:         "memory",
+			Algorithm:     "sliding_window",
+			Limits:        map[string]string{"global": "100/minute"},
+			ExtractorFunc: func(*http.Request) string { return "user" },
+			DeniedScopes:  []string{"debug*", "internal:*"},
+		}
+	}
+	if err := base().Validate(); err != nil {
+		t.Fatalf("Expected a valid config, got %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(*Config)
+		want   string
+	}{
+		{"reserved limit", func(c *Config) { c.Limits[OverflowScope] = "10/minute" }, "reserved"},
+		{"reserved shared scope", func(c *Config) { c.Limits["__shared"] = "10/minute" }, "reserved"},
+		{"denied limit", func(c *Config) { c.Limits["debug-tools"] = "10/minute" }, `"debug*"`},
+		{"denied tier limit", func(c *Config) {
+			c.TierLimits = map[string]map[string]string{"internal:admin": {"free": "1/minute"}}
+		}, `"internal:*"`},
+		{"override", func(c *Config) {
+			c.Overrides = []Override{{Entity: "partner", Scope: "__other", Limit: "1/minute"}}
+		}, "override for partner"},
+		{"enforcement", func(c *Config) { c.ScopeEnforcement = map[string]string{"debug": EnforcementOff} }, "enforcement mode"},
+		{"method scope", func(c *Config) { c.MethodScopes = map[string]string{"OPTIONS": "__preflight"} }, "method scope for OPTIONS"},
+		{"bot scope", func(c *Config) {
+			c.BotClassifier = func(*http.Request) bool { return false }
+			c.BotScope = "internal:bots"
+		}, "bot scope"},
+		{"bad pattern", func(c *Config) { c.DeniedScopes = []string{"debug["} }, "invalid denied scope pattern"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := base()
+			tt.modify(config)
+			err := config.Validate()
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected an error mentioning %s, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestUpdateLimitsRejectsDeniedScopes(t *testing.T) {
+	config := &Config{
+		Algorithm:    "sliding_window",
+		Limits:       map[string]string{"global": "10/minute"},
+		DeniedScopes: []string{"debug*"},
+	}
+	l := newTestLimiter(t, config, nil)
+
+	for _, update := range []LimitUpdate{
+		{Limits: map[string]string{"global": "10/minute", "debug": "1/minute"}},
+		{Limits: map[string]string{"global": "10/minute"}, Modes: map[string]string{"__other": EnforcementOff}},
+	} {
+		if err := l.UpdateLimits(update); err == nil {
+			t.Errorf("Expected %+v to be rejected", update)
+		}
+	}
+	if err := l.UpdateLimits(LimitUpdate{Limits: map[string]string{"global": "20/minute", "search": "5/minute"}}); err != nil {
+		t.Errorf("Expected a valid update to apply, got %v", err)
+	}
+}
@@ -0,0 +1,121 @@
+// internal/core/scopestores.go
+package core
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/itsatony/gorly/stores"
+)
+
+// StoreStatus describes one of the stores of a limiter
+type StoreStatus struct {
+	Name      string   // "memory" or "redis"
+	Default   bool     // Serves the scopes not listed in ScopeStores and coordination state
+	Scopes    []string // Scopes configured on this store with ScopeStores
+	Keys      int64
+	KeysKnown bool  // Keys is only counted by the memory store
+	Err       error // Failed health check
+}
+
+// newScopeStores creates the stores of ScopeStores that differ from the default store
+func newScopeStores(config *Config, redisConfig stores.RedisConfig) (map[string]Store, error) {
+	scopeStores := make(map[string]Store)
+	for _, name := range config.ScopeStores {
+		if name == config.Store || scopeStores[name] != nil {
+			continue
+		}
+		switch name {
+		case "memory":
+			memStore, err := stores.NewMemoryStore(stores.MemoryConfig{CleanupInterval: 10 * time.Minute})
+			if err != nil {
+				closeStores(scopeStores)
+				return nil, fmt.Errorf("failed to create memory scope store: %w", err)
+			}
+			scopeStores[name] = &storeAdapter{memStore}
+		case "redis":
+			redisStore, err := stores.NewRedisStore(redisConfig)
+			if err != nil {
+				closeStores(scopeStores)
+				return nil, fmt.Errorf("failed to create redis scope store: %w", err)
+			}
+			scopeStores[name] = &storeAdapter{redisStore}
+		}
+	}
+	return scopeStores, nil
+}
+
+// closeStores closes every store of a map
+func closeStores(scopeStores map[string]Store) {
+	for _, store := range scopeStores {
+		store.Close()
+	}
+}
+
+// storeFor returns the store keeping the limit state of a scope
+func (l *limiterImpl) storeFor(scope string) Store {
+	if store, ok := l.scopeStores[l.config.ScopeStores[scope]]; ok {
+		return store
+	}
+	return l.store
+}
+
+// ScopeStore returns the name of the store keeping the limit state of a scope
+func (l *limiterImpl) ScopeStore(scope string) string {
+	if _, ok := l.scopeStores[l.config.ScopeStores[scope]]; ok {
+		return l.config.ScopeStores[scope]
+	}
+	return l.config.Store
+}
+
+// StoreStatuses checks the health of every store and lists the scopes each one serves,
+// default store first
+func (l *limiterImpl) StoreStatuses(ctx context.Context) []StoreStatus {
+	statuses := []StoreStatus{l.storeStatus(ctx, l.config.Store, l.store)}
+	statuses[0].Default = true
+
+	names := make([]string, 0, len(l.scopeStores))
+	for name := range l.scopeStores {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		statuses = append(statuses, l.storeStatus(ctx, name, l.scopeStores[name]))
+	}
+
+	for scope := range l.config.ScopeStores {
+		name := l.ScopeStore(scope)
+		for i := range statuses {
+			if statuses[i].Name == name {
+				statuses[i].Scopes = append(statuses[i].Scopes, scope)
+			}
+		}
+	}
+	for i := range statuses {
+		sort.Strings(statuses[i].Scopes)
+	}
+	return statuses
+}
+
+// storeStatus checks one store
+func (l *limiterImpl) storeStatus(ctx context.Context, name string, store Store) StoreStatus {
+	status := StoreStatus{Name: name, Err: store.Health(ctx)}
+	if adapter, ok := store.(*storeAdapter); ok {
+		if sized, ok := adapter.store.(interface{ Size() int }); ok {
+			status.Keys, status.KeysKnown = int64(sized.Size()), true
+		}
+	}
+	return status
+}
+
+// scopeStoresHealth checks the stores of ScopeStores, naming the failing one
+func (l *limiterImpl) scopeStoresHealth(ctx context.Context) error {
+	for name, store := range l.scopeStores {
+		if err := store.Health(ctx); err != nil {
+			return fmt.Errorf("%s scope store: %w", name, err)
+		}
+	}
+	return nil
+}
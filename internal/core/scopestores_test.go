@@ -0,0 +1,74 @@
+// internal/core/scopestores_test.go
+package core
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestScopeStores(t *testing.T) {
+	ctx := context.Background()
+	store, authStore := newTestStore(t), &outageStore{Store: newTestStore(t)}
+	limiter, err := newLimiter(&Config{
+		Store:       "memory",
+		Algorithm:   "sliding_window",
+		Limits:      map[string]string{"auth": "2/minute", "assets": "100/minute"},
+		ScopeStores: map[string]string{"auth": "redis", "assets": "memory"},
+	}, store, nil, map[string]Store{"redis": authStore})
+	if err != nil {
+		t.Fatalf("Failed to create limiter: %v", err)
+	}
+	defer limiter.Close()
+
+	for _, scope := range []string{"auth", "assets"} {
+		if _, err := limiter.Check(ctx, "user-1", scope); err != nil {
+			t.Fatalf("Check failed: %v", err)
+		}
+	}
+	key := limiter.(*limiterImpl).requestKey("user-1", "auth")
+	if _, err := authStore.Get(ctx, key); err != nil {
+		t.Errorf("Expected the auth scope in its own store: %v", err)
+	}
+	if _, err := store.Get(ctx, key); err == nil {
+		t.Error("Expected the auth scope not to be in the default store")
+	}
+	if name := limiter.ScopeStore("assets"); name != "memory" {
+		t.Errorf("Expected a scope on the default store's kind to use the default store, got %s", name)
+	}
+
+	if _, err := limiter.CheckAll(ctx, "user-1", []ScopeCost{{Scope: "auth", Cost: 1}, {Scope: "assets", Cost: 1}}); err == nil {
+		t.Error("Expected CheckAll across stores to be rejected")
+	}
+
+	// A failing scope store fails the health check and is reported on its own
+	authStore.down.Store(true)
+	if err := limiter.Health(ctx); err == nil || !strings.Contains(err.Error(), "redis scope store") {
+		t.Errorf("Expected the failing scope store to be named, got %v", err)
+	}
+	statuses := limiter.StoreStatuses(ctx)
+	if len(statuses) != 2 || !statuses[0].Default || statuses[0].Err != nil {
+		t.Fatalf("Unexpected store statuses: %+v", statuses)
+	}
+	if statuses[1].Name != "redis" || len(statuses[1].Scopes) != 1 || statuses[1].Scopes[0] != "auth" {
+		t.Errorf("Unexpected scope store status: %+v", statuses[1])
+	}
+	if statuses[1].Err == nil {
+		t.Error("Expected the scope store's health check to fail")
+	}
+}
+
+func TestScopeStoreValidation(t *testing.T) {
+	tests := []struct {
+		name   string
+		config Config
+	}{
+		{"unknown store", Config{Store: "memory", Algorithm: "sliding_window", ScopeStores: map[string]string{"auth": "disk"}}},
+		{"redis without address", Config{Store: "memory", Algorithm: "sliding_window", ScopeStores: map[string]string{"auth": "redis"}}},
+	}
+	for _, tt := range tests {
+		if err := tt.config.Validate(); err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+		}
+	}
+}
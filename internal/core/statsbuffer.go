@@ -0,0 +1,306 @@
+// internal/core/statsbuffer.go
+package core
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Write-behind stats defaults
+const (
+	DefaultStatsFlushInterval = time.Second
+	DefaultStatsFlushEvents   = 1000
+)
+
+// StatsCounter is the number of requests and denials counted for a scope
+type StatsCounter struct {
+	Requests int64
+	Denied   int64
+}
+
+// StatsFlushStats describes the write-behind flushes of a limiter
+type StatsFlushStats struct {
+	Flushes           int64         // Flushes that wrote every pending delta
+	FlushErrors       int64         // Flushes that failed; their deltas are retried with the next flush
+	FlushedEvents     int64         // Requests written to the store
+	PendingEvents     int64         // Requests counted locally and not yet written; lost if the process dies
+	LastFlushDuration time.Duration // Duration of the most recent flush
+	LastFlush         time.Time     // Completion of the most recent successful flush
+}
+
+// statsBuffer counts requests per scope locally and writes the deltas to the store
+// in the background.
+//
+// Writing a counter on every request would double the store traffic of a check.
+// The buffer instead flushes once per interval, or earlier once maxEvents requests
+// are pending, so a crash loses at most one interval or maxEvents requests worth of
+// counts. Flushes that fail keep their deltas pending for the next attempt.
+type statsBuffer struct {
+	limiter   *limiterImpl
+	interval  time.Duration
+	maxEvents int64
+
+	mu      sync.Mutex
+	pending map[string]*StatsCounter
+	events  int64               // Requests in pending
+	scopes  map[string]struct{} // Scopes ever flushed by this instance
+
+	flushMu sync.Mutex // Serializes flushes so deltas are written in order
+	kick    chan struct{}
+	stop    chan struct{}
+	done    sync.WaitGroup
+
+	flushes       atomic.Int64
+	flushErrors   atomic.Int64
+	flushedEvents atomic.Int64
+	lastDuration  atomic.Int64 // Nanoseconds
+	lastFlush     atomic.Int64 // Unix nanoseconds
+}
+
+// newStatsBuffer creates the write-behind buffer of a limiter, or returns nil when
+// write-behind stats are disabled
+func newStatsBuffer(l *limiterImpl) *statsBuffer {
+	if !l.config.StatsWriteBehind {
+		return nil
+	}
+	sb := &statsBuffer{
+		limiter:   l,
+		interval:  l.config.StatsFlushInterval,
+		maxEvents: int64(l.config.StatsFlushEvents),
+		pending:   make(map[string]*StatsCounter),
+		scopes:    make(map[string]struct{}),
+		kick:      make(chan struct{}, 1),
+		stop:      make(chan struct{}),
+	}
+	if sb.interval <= 0 {
+		sb.interval = DefaultStatsFlushInterval
+	}
+	if sb.maxEvents <= 0 {
+		sb.maxEvents = DefaultStatsFlushEvents
+	}
+	return sb
+}
+
+// start begins flushing in the background
+func (sb *statsBuffer) start() {
+	sb.done.Add(1)
+	go sb.run()
+}
+
+// close stops the background flushes and writes what is still pending
+func (sb *statsBuffer) close() {
+	close(sb.stop)
+	sb.done.Wait()
+
+	ctx, cancel := context.WithTimeout(context.Background(), sb.interval)
+	defer cancel()
+	if err := sb.flush(ctx); err != nil {
+		sb.limiter.reportError(err)
+	}
+}
+
+// run flushes on every tick and whenever record signals a full buffer
+func (sb *statsBuffer) run() {
+	defer sb.done.Done()
+
+	ticker := time.NewTicker(sb.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-sb.stop:
+			return
+		case <-ticker.C:
+		case <-sb.kick:
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), sb.interval)
+		if err := sb.flush(ctx); err != nil {
+			sb.limiter.reportError(err)
+		}
+		cancel()
+	}
+}
+
+// record counts one request of scope
+func (sb *statsBuffer) record(scope string, allowed bool) {
+	if sb == nil {
+		return
+	}
+
+	sb.mu.Lock()
+	counter, ok := sb.pending[scope]
+	if !ok {
+		counter = &StatsCounter{}
+		sb.pending[scope] = counter
+	}
+	counter.Requests++
+	if !allowed {
+		counter.Denied++
+	}
+	sb.events++
+	full := sb.events >= sb.maxEvents
+	sb.mu.Unlock()
+
+	if full {
+		select {
+		case sb.kick <- struct{}{}:
+		default: // A flush is already requested
+		}
+	}
+}
+
+// flush writes the pending deltas to the store
+func (sb *statsBuffer) flush(ctx context.Context) error {
+	sb.flushMu.Lock()
+	defer sb.flushMu.Unlock()
+
+	sb.mu.Lock()
+	batch := sb.pending
+	sb.pending, sb.events = make(map[string]*StatsCounter, len(batch)), 0
+	sb.mu.Unlock()
+
+	if len(batch) == 0 {
+		return nil
+	}
+
+	start := time.Now()
+	keys := sb.limiter.config.keys()
+	var written int64
+	var failed error
+	for scope, counter := range batch {
+		if failed != nil {
+			continue // Kept in batch and returned to pending below
+		}
+		if counter.Requests > 0 {
+			if _, err := sb.limiter.store.IncrementBy(ctx, statsKey(keys, scope, "requests"), counter.Requests, 0); err != nil {
+				failed = fmt.Errorf("failed to flush request stats of scope %s: %w", scope, err)
+				continue
+			}
+			written += counter.Requests
+			counter.Requests = 0
+		}
+		if counter.Denied > 0 {
+			if _, err := sb.limiter.store.IncrementBy(ctx, statsKey(keys, scope, "denied"), counter.Denied, 0); err != nil {
+				failed = fmt.Errorf("failed to flush denial stats of scope %s: %w", scope, err)
+				continue
+			}
+			counter.Denied = 0
+		}
+		delete(batch, scope)
+
+		sb.mu.Lock()
+		sb.scopes[scope] = struct{}{}
+		sb.mu.Unlock()
+	}
+	sb.lastDuration.Store(int64(time.Since(start)))
+	sb.flushedEvents.Add(written)
+
+	if failed == nil {
+		sb.flushes.Add(1)
+		sb.lastFlush.Store(time.Now().UnixNano())
+		return nil
+	}
+
+	// Return the unwritten deltas so the next flush retries them
+	sb.flushErrors.Add(1)
+	sb.mu.Lock()
+	for scope, counter := range batch {
+		sb.merge(scope, counter)
+	}
+	sb.mu.Unlock()
+	return failed
+}
+
+// merge adds a delta to the pending counters; callers hold sb.mu
+func (sb *statsBuffer) merge(scope string, delta *StatsCounter) {
+	counter, ok := sb.pending[scope]
+	if !ok {
+		counter = &StatsCounter{}
+		sb.pending[scope] = counter
+	}
+	counter.Requests += delta.Requests
+	counter.Denied += delta.Denied
+	sb.events += delta.Requests
+}
+
+// statsKey is the store key of a shared stats counter of a scope
+func statsKey(keys KeyBuilder, scope, counter string) string {
+	return keys.Build("stats", scope, counter)
+}
+
+// StatsCounters returns the requests and denials per scope counted by every instance
+// sharing the store, plus the deltas this instance has not flushed yet. It covers the
+// configured scopes and the dynamic scopes this instance has seen; it returns nil when
+// write-behind stats are disabled.
+func (l *limiterImpl) StatsCounters(ctx context.Context) (map[string]StatsCounter, error) {
+	sb := l.stats
+	if sb == nil {
+		return nil, nil
+	}
+
+	scopes := make(map[string]struct{})
+	table := l.limitTable()
+	for scope := range table.limits {
+		scopes[scope] = struct{}{}
+	}
+	for scope := range table.tierLimits {
+		scopes[scope] = struct{}{}
+	}
+
+	counters := make(map[string]StatsCounter)
+	sb.mu.Lock()
+	for scope := range sb.scopes {
+		scopes[scope] = struct{}{}
+	}
+	for scope, counter := range sb.pending {
+		counters[scope] = *counter
+	}
+	sb.mu.Unlock()
+
+	keys := l.config.keys()
+	for scope := range scopes {
+		requests, err := l.readCounter(ctx, statsKey(keys, scope, "requests"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read request stats of scope %s: %w", scope, err)
+		}
+		denied, err := l.readCounter(ctx, statsKey(keys, scope, "denied"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read denial stats of scope %s: %w", scope, err)
+		}
+		counter := counters[scope]
+		counter.Requests += requests
+		counter.Denied += denied
+		if counter.Requests > 0 {
+			counters[scope] = counter
+		}
+	}
+	return counters, nil
+}
+
+// StatsFlushStats returns the flush metrics of write-behind stats, or nil when they are disabled
+func (l *limiterImpl) StatsFlushStats() *StatsFlushStats {
+	sb := l.stats
+	if sb == nil {
+		return nil
+	}
+
+	sb.mu.Lock()
+	pending := sb.events
+	sb.mu.Unlock()
+
+	stats := &StatsFlushStats{
+		Flushes:           sb.flushes.Load(),
+		FlushErrors:       sb.flushErrors.Load(),
+		FlushedEvents:     sb.flushedEvents.Load(),
+		PendingEvents:     pending,
+		LastFlushDuration: time.Duration(sb.lastDuration.Load()),
+	}
+	if last := sb.lastFlush.Load(); last != 0 {
+		stats.LastFlush = time.Unix(0, last)
+	}
+	return stats
+}
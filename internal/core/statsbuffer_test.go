@@ -0,0 +1,150 @@
+// internal/core/statsbuffer_test.go
+package core
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// flakyStore fails counter writes while broken is set
+type flakyStore struct {
+	Store
+	broken atomic.Bool
+}
+
+func (s *flakyStore) IncrementBy(ctx context.Context, key string, amount int64, expiration time.Duration) (int64, error) {
+	if s.broken.Load() && amount != 0 {
+		return 0, errors.New("store unavailable")
+	}
+	return s.Store.IncrementBy(ctx, key, amount, expiration)
+}
+
+// statsTestConfig configures write-behind stats flushed every flushEvents events
+func statsTestConfig(flushEvents int) *Config {
+	return &Config{
+		Limits:             map[string]string{"search": "3/minute"},
+		StatsWriteBehind:   true,
+		StatsFlushInterval: time.Hour, // Flushes are triggered by events or explicitly
+		StatsFlushEvents:   flushEvents,
+	}
+}
+
+func TestStatsWriteBehind(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStore(t)
+	first := newTestLimiter(t, statsTestConfig(1000), store)
+	second := newTestLimiter(t, statsTestConfig(1000), store)
+
+	for i := 0; i < 5; i++ {
+		if _, err := first.Check(ctx, "user-1", "search"); err != nil {
+			t.Fatalf("Check failed: %v", err)
+		}
+	}
+	if _, err := second.Check(ctx, "user-2", "search"); err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+
+	// Nothing is written per request
+	if flush := first.StatsFlushStats(); flush.PendingEvents != 5 || flush.FlushedEvents != 0 {
+		t.Fatalf("Expected 5 pending and 0 flushed events, got %+v", flush)
+	}
+	requests, _ := store.IncrementBy(ctx, statsKey(first.config.keys(), "search", "requests"), 0, 0)
+	if requests != 0 {
+		t.Fatalf("Expected no store writes before a flush, got %d requests", requests)
+	}
+
+	// Pending counts are included locally before they are flushed
+	counters, err := first.StatsCounters(ctx)
+	if err != nil {
+		t.Fatalf("StatsCounters failed: %v", err)
+	}
+	if got := counters["search"]; got.Requests != 5 || got.Denied != 2 {
+		t.Fatalf("Expected 5 requests and 2 denials before the flush, got %+v", got)
+	}
+
+	if err := first.stats.flush(ctx); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	second.Close() // Closing flushes what is pending
+
+	counters, err = first.StatsCounters(ctx)
+	if err != nil {
+		t.Fatalf("StatsCounters failed: %v", err)
+	}
+	if got := counters["search"]; got.Requests != 6 || got.Denied != 2 {
+		t.Fatalf("Expected 6 requests and 2 denials across instances, got %+v", got)
+	}
+
+	flush := first.StatsFlushStats()
+	if flush.Flushes != 1 || flush.FlushedEvents != 5 || flush.PendingEvents != 0 || flush.LastFlush.IsZero() {
+		t.Fatalf("Unexpected flush stats: %+v", flush)
+	}
+}
+
+func TestStatsWriteBehindFlushEvents(t *testing.T) {
+	ctx := context.Background()
+	l := newTestLimiter(t, statsTestConfig(10), nil)
+
+	for i := 0; i < 10; i++ {
+		if _, err := l.Check(ctx, "user-1", "search"); err != nil {
+			t.Fatalf("Check failed: %v", err)
+		}
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for l.StatsFlushStats().Flushes == 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected a flush after 10 events, got %+v", l.StatsFlushStats())
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if flush := l.StatsFlushStats(); flush.FlushedEvents != 10 {
+		t.Fatalf("Expected 10 flushed events, got %+v", flush)
+	}
+}
+
+func TestStatsWriteBehindRetry(t *testing.T) {
+	ctx := context.Background()
+	store := &flakyStore{Store: newTestStore(t)}
+	l := newTestLimiter(t, statsTestConfig(1000), store)
+
+	for i := 0; i < 4; i++ {
+		if _, err := l.Check(ctx, "user-1", "search"); err != nil {
+			t.Fatalf("Check failed: %v", err)
+		}
+	}
+
+	store.broken.Store(true)
+	if err := l.stats.flush(ctx); err == nil {
+		t.Fatal("Expected the flush to fail")
+	}
+	if flush := l.StatsFlushStats(); flush.FlushErrors != 1 || flush.PendingEvents != 4 {
+		t.Fatalf("Expected the failed flush to keep 4 pending events, got %+v", flush)
+	}
+
+	store.broken.Store(false)
+	if err := l.stats.flush(ctx); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	counters, err := l.StatsCounters(ctx)
+	if err != nil {
+		t.Fatalf("StatsCounters failed: %v", err)
+	}
+	if got := counters["search"]; got.Requests != 4 || got.Denied != 1 {
+		t.Fatalf("Expected 4 requests and 1 denial after the retry, got %+v", got)
+	}
+}
+
+func TestStatsWriteBehindDisabled(t *testing.T) {
+	l := newTestLimiter(t, resetTestConfig(), nil)
+
+	if l.StatsFlushStats() != nil {
+		t.Error("Expected no flush stats without write-behind stats")
+	}
+	if counters, err := l.StatsCounters(context.Background()); err != nil || counters != nil {
+		t.Errorf("Expected no counters without write-behind stats, got %v (%v)", counters, err)
+	}
+}
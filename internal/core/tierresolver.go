@@ -0,0 +1,222 @@
+// internal/core/tierresolver.go
+package core
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Tier resolver cache defaults
+const (
+	DefaultTierCacheTTL       = time.Minute
+	DefaultTierStaleTTL       = 5 * time.Minute
+	DefaultTierCacheSize      = 10000
+	DefaultTierRefreshTimeout = 5 * time.Second
+)
+
+// TierResolver looks up the tier of an entity in the application's own systems, e.g. its
+// billing plan. An empty tier is DefaultTier.
+type TierResolver interface {
+	ResolveTier(ctx context.Context, entity string) (string, error)
+}
+
+// TierCacheStats describes the tier resolver cache of a limiter
+type TierCacheStats struct {
+	Hits      int64 // Lookups answered by a fresh entry
+	StaleHits int64 // Lookups answered by a stale entry while it was refreshed
+	Misses    int64 // Lookups that called the resolver
+	Errors    int64 // Failed resolver calls
+	Entries   int64 // Cached entities
+}
+
+// tierCache remembers resolved tiers so the resolver is not called on every check.
+//
+// Entries are fresh for the TTL. After that they are served for up to the stale TTL
+// while a single background refresh runs, so a slow resolver never delays checks of
+// known entities. A failed refresh keeps serving the stale tier until the stale TTL
+// ends. An entity whose tier cannot be resolved at all gets DefaultTier: the tier an
+// entity claims is never trusted once a resolver is configured.
+type tierCache struct {
+	resolver TierResolver
+	limiter  *limiterImpl
+	ttl      time.Duration
+	stale    time.Duration
+	size     int
+	now      func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*tierEntry
+	refresh sync.WaitGroup
+
+	hits      atomic.Int64
+	staleHits atomic.Int64
+	misses    atomic.Int64
+	errors    atomic.Int64
+}
+
+// tierEntry is a resolved tier and the times it turns stale and expires
+type tierEntry struct {
+	tier       string
+	staleAt    time.Time
+	expiresAt  time.Time
+	refreshing bool
+}
+
+// newTierCache creates the tier cache of a limiter, or returns nil without a tier resolver
+func newTierCache(l *limiterImpl) *tierCache {
+	if l.config.TierResolver == nil {
+		return nil
+	}
+
+	tc := &tierCache{
+		resolver: l.config.TierResolver,
+		limiter:  l,
+		ttl:      l.config.TierCacheTTL,
+		stale:    l.config.TierStaleTTL,
+		size:     DefaultTierCacheSize,
+		now:      l.config.Now,
+		entries:  make(map[string]*tierEntry),
+	}
+	if tc.ttl <= 0 {
+		tc.ttl = DefaultTierCacheTTL
+	}
+	if tc.stale < 0 {
+		tc.stale = 0
+	} else if tc.stale == 0 {
+		tc.stale = DefaultTierStaleTTL
+	}
+	return tc
+}
+
+// tier returns the tier of an entity, calling the resolver when no entry can be served
+func (tc *tierCache) tier(ctx context.Context, entity string) string {
+	now := tc.now()
+
+	tc.mu.Lock()
+	entry, ok := tc.entries[entity]
+	if ok && now.Before(entry.staleAt) {
+		tc.mu.Unlock()
+		tc.hits.Add(1)
+		return entry.tier
+	}
+	if ok && now.Before(entry.expiresAt) {
+		refresh := !entry.refreshing
+		entry.refreshing = true
+		tier := entry.tier
+		if refresh {
+			tc.refresh.Add(1)
+		}
+		tc.mu.Unlock()
+
+		tc.staleHits.Add(1)
+		if refresh {
+			go tc.revalidate(entity)
+		}
+		return tier
+	}
+	tc.mu.Unlock()
+
+	tc.misses.Add(1)
+	tier, err := tc.resolve(ctx, entity)
+	if err != nil {
+		tc.limiter.reportError(err)
+		return DefaultTier
+	}
+	return tier
+}
+
+// revalidate refreshes a stale entry in the background
+func (tc *tierCache) revalidate(entity string) {
+	defer tc.refresh.Done()
+
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultTierRefreshTimeout)
+	defer cancel()
+	if _, err := tc.resolve(ctx, entity); err != nil {
+		tc.mu.Lock()
+		if entry, ok := tc.entries[entity]; ok {
+			entry.refreshing = false // The next stale lookup retries
+		}
+		tc.mu.Unlock()
+		tc.limiter.reportError(err)
+	}
+}
+
+// resolve calls the resolver and caches its answer
+func (tc *tierCache) resolve(ctx context.Context, entity string) (string, error) {
+	tier, err := tc.resolver.ResolveTier(ctx, entity)
+	if err != nil {
+		tc.errors.Add(1)
+		return "", fmt.Errorf("failed to resolve tier of %s: %w", entity, err)
+	}
+	if tier == "" {
+		tier = DefaultTier
+	}
+
+	now := tc.now()
+	staleAt := now.Add(tc.ttl)
+
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+
+	if _, exists := tc.entries[entity]; !exists && len(tc.entries) >= tc.size {
+		for k, entry := range tc.entries {
+			if !entry.expiresAt.After(now) {
+				delete(tc.entries, k)
+			}
+		}
+		if len(tc.entries) >= tc.size {
+			return tier, nil // Full of current entries; this one is simply not cached
+		}
+	}
+	tc.entries[entity] = &tierEntry{tier: tier, staleAt: staleAt, expiresAt: staleAt.Add(tc.stale)}
+	return tier, nil
+}
+
+// close waits for background refreshes
+func (tc *tierCache) close() {
+	tc.refresh.Wait()
+}
+
+// forget drops the cached tier of an entity and returns how many entries were dropped
+func (tc *tierCache) forget(entity string) int {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+
+	if _, ok := tc.entries[entity]; !ok {
+		return 0
+	}
+	delete(tc.entries, entity)
+	return 1
+}
+
+// entityTier returns the tier whose limits an entity claims: resolved by the tier
+// resolver when one is configured, otherwise taken from the entity's "tier:" prefix
+func (l *limiterImpl) entityTier(ctx context.Context, entity string) string {
+	if l.tiers != nil {
+		return l.tiers.tier(ctx, entity)
+	}
+	return tierOf(entity)
+}
+
+// TierCacheStats returns the tier resolver cache metrics, or nil without a tier resolver
+func (l *limiterImpl) TierCacheStats() *TierCacheStats {
+	tc := l.tiers
+	if tc == nil {
+		return nil
+	}
+
+	tc.mu.Lock()
+	entries := int64(len(tc.entries))
+	tc.mu.Unlock()
+
+	return &TierCacheStats{
+		Hits:      tc.hits.Load(),
+		StaleHits: tc.staleHits.Load(),
+		Misses:    tc.misses.Load(),
+		Errors:    tc.errors.Load(),
+		Entries:   entries,
+	}
+}
@@ -0,0 +1,128 @@
+// internal/core/tierresolver_test.go
+package core
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeTierResolver answers from a plan table and counts its calls
+type fakeTierResolver struct {
+	mu     sync.Mutex
+	plans  map[string]string
+	broken bool
+	calls  atomic.Int64
+}
+
+func (r *fakeTierResolver) ResolveTier(ctx context.Context, entity string) (string, error) {
+	r.calls.Add(1)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.broken {
+		return "", errors.New("billing unavailable")
+	}
+	return r.plans[entity], nil
+}
+
+func (r *fakeTierResolver) set(entity, plan string, broken bool) {
+	r.mu.Lock()
+	r.plans[entity] = plan
+	r.broken = broken
+	r.mu.Unlock()
+}
+
+// tierResolverTestConfig configures tier limits resolved by resolver on a clock reading now
+func tierResolverTestConfig(resolver TierResolver, now *time.Time) *Config {
+	return &Config{
+		TierLimits: map[string]map[string]string{
+			"global": {"free": "1/minute", "pro": "5/minute"},
+		},
+		TierResolver: resolver,
+		TierCacheTTL: time.Minute,
+		TierStaleTTL: time.Minute,
+		Clock:        func() time.Time { return *now },
+	}
+}
+
+func TestTierResolver(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	resolver := &fakeTierResolver{plans: map[string]string{"user-1": "pro"}}
+	l := newTestLimiter(t, tierResolverTestConfig(resolver, &now), nil)
+
+	// The resolved tier applies, and a claimed tier is ignored
+	for _, entity := range []string{"user-1", "pro:user-2"} {
+		result, err := l.Check(ctx, entity, "global")
+		if err != nil {
+			t.Fatalf("Check failed: %v", err)
+		}
+		want := map[string]int64{"user-1": 5, "pro:user-2": 1}[entity]
+		if result.Limit != want {
+			t.Errorf("Expected limit %d for %s, got %d", want, entity, result.Limit)
+		}
+	}
+
+	// Fresh entries are served from the cache
+	if _, err := l.Check(ctx, "user-1", "global"); err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if calls := resolver.calls.Load(); calls != 2 {
+		t.Errorf("Expected 2 resolver calls, got %d", calls)
+	}
+
+	// Stale entries are served while refreshed in the background
+	resolver.set("user-1", "free", false)
+	now = now.Add(90 * time.Second)
+	limits, err := l.Limits("user-1")
+	if err != nil || len(limits) != 1 || limits[0].Tier != "pro" {
+		t.Fatalf("Expected the stale pro tier, got %+v (%v)", limits, err)
+	}
+	l.tiers.refresh.Wait()
+	if limits, _ := l.Limits("user-1"); limits[0].Tier != DefaultTier {
+		t.Errorf("Expected the refreshed free tier, got %s", limits[0].Tier)
+	}
+
+	stats := l.TierCacheStats()
+	if stats.Misses != 2 || stats.StaleHits != 1 || stats.Hits != 2 || stats.Entries != 2 {
+		t.Errorf("Unexpected tier cache stats: %+v", stats)
+	}
+}
+
+func TestTierResolverErrors(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	resolver := &fakeTierResolver{plans: map[string]string{"user-1": "pro"}}
+	l := newTestLimiter(t, tierResolverTestConfig(resolver, &now), nil)
+
+	if got := l.entityTier(ctx, "user-1"); got != "pro" {
+		t.Fatalf("Expected tier pro, got %s", got)
+	}
+	resolver.set("user-1", "pro", true)
+
+	// A failed refresh keeps serving the stale tier
+	now = now.Add(90 * time.Second)
+	if got := l.entityTier(ctx, "user-1"); got != "pro" {
+		t.Errorf("Expected the stale pro tier, got %s", got)
+	}
+	l.tiers.refresh.Wait()
+	if got := l.entityTier(ctx, "user-1"); got != "pro" {
+		t.Errorf("Expected the stale pro tier after a failed refresh, got %s", got)
+	}
+	l.tiers.refresh.Wait()
+
+	// Entities without a usable entry get the default tier
+	now = now.Add(time.Minute)
+	if got := l.entityTier(ctx, "user-1"); got != DefaultTier {
+		t.Errorf("Expected the default tier once the entry expired, got %s", got)
+	}
+	if got := l.entityTier(ctx, "pro:user-2"); got != DefaultTier {
+		t.Errorf("Expected the default tier for an unresolved claim, got %s", got)
+	}
+	if errs := l.TierCacheStats().Errors; errs != 4 {
+		t.Errorf("Expected 4 resolver errors, got %d", errs)
+	}
+}
@@ -0,0 +1,88 @@
+// internal/core/tiers.go
+package core
+
+import "errors"
+
+// DefaultTier is the tier of entities that claim none; it is always known
+const DefaultTier = "free"
+
+// InputFieldTier is reported in an InputError for an entity claiming an unknown tier
+const InputFieldTier = "tier"
+
+// Unknown tier policies
+const (
+	UnknownTierDefault  = "default"  // Count the claim and apply the scope's default limits (default)
+	UnknownTierFallback = "fallback" // Apply the limits of FallbackTier
+	UnknownTierDeny     = "deny"     // Reject the check with an *InputError
+)
+
+// UnknownTierPolicyName returns the configured unknown tier policy, falling back to the default
+func (c *Config) UnknownTierPolicyName() string {
+	if c.UnknownTierPolicy == "" {
+		return UnknownTierDefault
+	}
+	return c.UnknownTierPolicy
+}
+
+// validateTiers checks the unknown tier policy, that a fallback tier is configured and the tier cache TTL
+func (c *Config) validateTiers() error {
+	if c.TierCacheTTL < 0 {
+		return errors.New("tier cache TTL cannot be negative")
+	}
+
+	switch c.UnknownTierPolicyName() {
+	case UnknownTierDefault, UnknownTierDeny:
+		return nil
+	case UnknownTierFallback:
+		if c.FallbackTier == "" {
+			return errors.New("fallback tier is required by the fallback unknown tier policy")
+		}
+		if c.FallbackTier != DefaultTier && !newLimitTable(c).knownTier(c.FallbackTier) {
+			return errors.New("fallback tier " + c.FallbackTier + " has no tier limits")
+		}
+		return nil
+	default:
+		return errors.New("unsupported unknown tier policy: " + c.UnknownTierPolicy)
+	}
+}
+
+// knownTier reports whether any scope configures limits for tier
+func (t *limitTable) knownTier(tier string) bool {
+	for _, tiers := range t.tierLimits {
+		if _, ok := tiers[tier]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// unknownTier returns tier when no scope configures it, or ""
+func (t *limitTable) unknownTier(tier string) string {
+	if len(t.tierLimits) == 0 || tier == DefaultTier || t.knownTier(tier) {
+		return ""
+	}
+	return tier
+}
+
+// limitTier returns the tier whose limits apply to an entity of tier: tier itself, or the
+// fallback tier when tier is unknown under UnknownTierFallback
+func (l *limiterImpl) limitTier(table *limitTable, tier string) string {
+	if table.unknownTier(tier) != "" && l.config.UnknownTierPolicyName() == UnknownTierFallback {
+		return l.config.FallbackTier
+	}
+	return tier
+}
+
+// checkTier applies the unknown tier policy to an entity of tier. Under UnknownTierDeny an unknown
+// tier returns an *InputError; the tier itself is not included since clients may choose it.
+func (l *limiterImpl) checkTier(table *limitTable, tier string) error {
+	if l.config.UnknownTierPolicyName() != UnknownTierDeny || table.unknownTier(tier) == "" {
+		return nil
+	}
+	return &InputError{Field: InputFieldTier, Reason: "tier is not configured"}
+}
+
+// UnknownTiers returns how many checks came from entities claiming a tier no scope configures
+func (l *limiterImpl) UnknownTiers() int64 {
+	return l.unknownTiers.Load()
+}
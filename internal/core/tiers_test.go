@@ -0,0 +1,108 @@
+// internal/core/tiers_test.go
+package core
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// tierTestConfig configures a global limit with free and pro tier limits
+func tierTestConfig(policy, fallback string) *Config {
+	return &Config{
+		Limits: map[string]string{"global": "3/minute"},
+		TierLimits: map[string]map[string]string{
+			"global": {"free": "1/minute", "pro": "5/minute"},
+		},
+		UnknownTierPolicy: policy,
+		FallbackTier:      fallback,
+	}
+}
+
+func TestUnknownTierPolicies(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		policy    string
+		fallback  string
+		wantLimit int64
+		wantErr   bool
+	}{
+		{UnknownTierDefault, "", 3, false}, // Scope limit, as before
+		{UnknownTierFallback, "free", 1, false},
+		{UnknownTierDeny, "", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			config := tierTestConfig(tt.policy, tt.fallback)
+			if err := config.validateTiers(); err != nil {
+				t.Fatalf("Invalid config: %v", err)
+			}
+			l := newTestLimiter(t, config, nil)
+
+			result, err := l.Check(ctx, "platinum:user-1", "global")
+			if tt.wantErr {
+				var inputErr *InputError
+				if !errors.As(err, &inputErr) || inputErr.Field != InputFieldTier {
+					t.Fatalf("Expected a tier input error, got %v", err)
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("Check failed: %v", err)
+				}
+				if result.Limit != tt.wantLimit || result.UnknownTier != "platinum" {
+					t.Errorf("Expected limit %d for unknown tier platinum, got %d (%q)", tt.wantLimit, result.Limit, result.UnknownTier)
+				}
+			}
+			if got := l.UnknownTiers(); got != 1 {
+				t.Errorf("Expected 1 unknown tier, got %d", got)
+			}
+
+			// Known tiers and entities without a tier are unaffected
+			for entity, want := range map[string]int64{"pro:user-2": 5, "user-3": 1} {
+				result, err := l.Check(ctx, entity, "global")
+				if err != nil {
+					t.Fatalf("Check of %s failed: %v", entity, err)
+				}
+				if result.Limit != want || result.UnknownTier != "" {
+					t.Errorf("Expected limit %d for %s, got %d (%q)", want, entity, result.Limit, result.UnknownTier)
+				}
+			}
+			if got := l.UnknownTiers(); got != 1 {
+				t.Errorf("Expected known tiers not to be counted, got %d", got)
+			}
+		})
+	}
+}
+
+func TestUnknownTierValidation(t *testing.T) {
+	base := func() *Config {
+		return &Config{
+			TierLimits: map[string]map[string]string{"global": {"pro": "5/minute"}},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(*Config)
+		wantErr bool
+	}{
+		{"default", func(c *Config) {}, false},
+		{"fallback to configured tier", func(c *Config) { c.UnknownTierPolicy, c.FallbackTier = UnknownTierFallback, "pro" }, false},
+		{"fallback to default tier", func(c *Config) { c.UnknownTierPolicy, c.FallbackTier = UnknownTierFallback, DefaultTier }, false},
+		{"fallback without tier", func(c *Config) { c.UnknownTierPolicy = UnknownTierFallback }, true},
+		{"fallback to unknown tier", func(c *Config) { c.UnknownTierPolicy, c.FallbackTier = UnknownTierFallback, "gold" }, true},
+		{"unsupported policy", func(c *Config) { c.UnknownTierPolicy = "ignore" }, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := base()
+			tt.modify(config)
+			if err := config.validateTiers(); (err != nil) != tt.wantErr {
+				t.Errorf("Expected error: %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+// internal/core/tokens.go
+package core
+
+import (
+	"context"
+	"fmt"
+)
+
+// TokenBudgetScope returns the scope whose token budget applies to a request scope.
+// Scopes without their own budget share the global budget if one is configured.
+func (c *Config) TokenBudgetScope(scope string) (string, bool) {
+	if _, ok := c.TokenBudgets[scope]; ok {
+		return scope, true
+	}
+	if _, ok := c.TokenBudgets["global"]; ok {
+		return "global", true
+	}
+	return "", false
+}
+
+// HasTokenBudget reports whether requests in scope draw from a token budget
+func (c *Config) HasTokenBudget(scope string) bool {
+	_, ok := c.TokenBudgetScope(scope)
+	return ok
+}
+
+// TokenCostOf converts model usage into budget units using the configured cost function
+func (c *Config) TokenCostOf(model string, promptTokens, completionTokens int64) int64 {
+	if c.TokenCost != nil {
+		return c.TokenCost(model, promptTokens, completionTokens)
+	}
+	return promptTokens + completionTokens
+}
+
+// CheckTokens returns the remaining token budget for an entity and scope without consuming it
+func (l *limiterImpl) CheckTokens(ctx context.Context, entity, scope string) (*CoreResult, error) {
+	return l.chargeTokens(ctx, entity, scope, 0)
+}
+
+// ConsumeTokens charges tokens against the budget of an entity and scope
+func (l *limiterImpl) ConsumeTokens(ctx context.Context, entity, scope string, tokens int64) (*CoreResult, error) {
+	if tokens < 0 {
+		return nil, fmt.Errorf("token consumption cannot be negative")
+	}
+	return l.chargeTokens(ctx, entity, scope, tokens)
+}
+
+// chargeTokens adds tokens to the current budget window of an entity
+func (l *limiterImpl) chargeTokens(ctx context.Context, entity, scope string, tokens int64) (*CoreResult, error) {
+	budgetScope, ok := l.config.TokenBudgetScope(scope)
+	if !ok {
+		return nil, fmt.Errorf("no token budget configured for scope: %s", scope)
+	}
+	budget, window, err := parseLimit(l.config.TokenBudgets[budgetScope])
+	if err != nil {
+		return nil, fmt.Errorf("failed to get token budget: %w", err)
+	}
+
+	result, err := l.chargeCounter(ctx, "tokens", entity, budgetScope, tokens, l.scaled(budget), window)
+	if err != nil {
+		return nil, fmt.Errorf("token accounting failed: %w", err)
+	}
+	return result, nil
+}
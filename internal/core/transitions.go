@@ -0,0 +1,209 @@
+// internal/core/transitions.go
+package core
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Limit transition policies, deciding how limits that shrink at runtime take effect
+const (
+	TransitionImmediate = "immediate" // The new limit applies to the next check (default)
+	TransitionDrain     = "drain"     // The old limit applies for one more of its windows, so usage under it ages out first
+	TransitionRamp      = "ramp"      // The limit steps down from the old to the new one over LimitRampDuration
+)
+
+// DefaultLimitRampDuration is how long ramped limits take to reach their new value by default
+const DefaultLimitRampDuration = 5 * time.Minute
+
+// maxTransitionEntities bounds the entities remembered as affected by one transition;
+// entities beyond it are still admitted under the transition but no longer reported
+const maxTransitionEntities = 10000
+
+// LimitTransitionEvent reports a limit that shrank at runtime. The event starting a
+// transition names no entity; every entity later admitted over the new limit only
+// because of the transition is reported once, with the limits that applied to it.
+type LimitTransitionEvent struct {
+	Scope  string
+	Tier   string // Tier whose limit shrank; empty for scope limits and entities
+	Entity string // Entity admitted over the new limit; empty when the transition starts
+	Policy string
+	From   int64 // Requests per window before the update, in the new window; for entities, the softened limit that admitted them
+	To     int64 // Requests per window after the update
+	Window time.Duration
+	Until  time.Time // When the new limit applies in full
+}
+
+// limitTransition softens the limits that shrank in an update. It keeps the table it
+// replaced, so checks can compare an entity's old and new limits whatever their source.
+type limitTransition struct {
+	previous *limitTable
+	policy   string
+	start    time.Time
+	until    time.Time     // When every limit of the transition applies in full
+	ramp     time.Duration // Ramped transitions only
+
+	affected sync.Map // entity + "\x00" + scope -> struct{}
+	reported atomic.Int64
+}
+
+// validateTransition checks the limit transition settings of a config
+func (c *Config) validateTransition() error {
+	switch c.LimitTransition {
+	case "", TransitionImmediate, TransitionDrain, TransitionRamp:
+	default:
+		return fmt.Errorf("limit transition must be %q, %q or %q, got %q", TransitionImmediate, TransitionDrain, TransitionRamp, c.LimitTransition)
+	}
+	if c.LimitRampDuration < 0 {
+		return fmt.Errorf("limit ramp duration cannot be negative, got %v", c.LimitRampDuration)
+	}
+	return nil
+}
+
+// newTransition starts the transition from current to next, or returns nil if limits
+// change immediately or no scope or tier limit shrank. It returns the events starting it.
+// Entity overrides that shrink in the same update are softened alongside.
+func (l *limiterImpl) newTransition(current, next *limitTable) (*limitTransition, []LimitTransitionEvent) {
+	policy := l.config.LimitTransition
+	if policy == "" || policy == TransitionImmediate {
+		return nil, nil
+	}
+	now := l.config.Now()
+
+	// Tables chain only while their transitions run: a running transition keeps softening
+	// the limits it started from, a finished one is dropped
+	previous := current
+	if t := current.transition; t != nil && !now.Before(t.until) {
+		settled := *current
+		settled.transition = nil
+		previous = &settled
+	}
+	t := &limitTransition{previous: previous, policy: policy, start: now, until: now}
+	if policy == TransitionRamp {
+		t.ramp = l.config.LimitRampDuration
+		if t.ramp == 0 {
+			t.ramp = DefaultLimitRampDuration
+		}
+	}
+
+	var events []LimitTransitionEvent
+	shrunk := func(scope, tier, from, to string) {
+		oldRequests, oldWindow, err := parseLimit(from)
+		if err != nil {
+			return
+		}
+		requests, window, err := parseLimit(to)
+		if err != nil {
+			return
+		}
+		old := inWindow(current.scaled(oldRequests), oldWindow, window)
+		if old <= next.scaled(requests) {
+			return
+		}
+		until := now.Add(t.ramp)
+		if policy == TransitionDrain {
+			until = now.Add(oldWindow)
+		}
+		if until.After(t.until) {
+			t.until = until
+		}
+		events = append(events, LimitTransitionEvent{Scope: scope, Tier: tier, Policy: policy,
+			From: old, To: next.scaled(requests), Window: window, Until: until})
+	}
+	for scope, limit := range next.limits {
+		if old, ok := current.limits[scope]; ok {
+			shrunk(scope, "", old, limit)
+		}
+	}
+	for scope, tiers := range next.tierLimits {
+		for tier, limit := range tiers {
+			if old, ok := current.tierLimits[scope][tier]; ok {
+				shrunk(scope, tier, old, limit)
+			}
+		}
+	}
+	if len(events) == 0 {
+		return nil, nil
+	}
+	sort.Slice(events, func(i, j int) bool {
+		if events[i].Scope != events[j].Scope {
+			return events[i].Scope < events[j].Scope
+		}
+		return events[i].Tier < events[j].Tier
+	})
+	return t, events
+}
+
+// transitionLimit returns the limit of an entity in scope while the table's transition
+// softens it: the old limit while draining, or a limit stepping down towards the new one
+// while ramping. It returns limit itself when the old limit was not higher.
+func (l *limiterImpl) transitionLimit(table *limitTable, entity, tier, scope string, limit int64, window time.Duration) int64 {
+	t := table.transition
+	if t == nil {
+		return limit
+	}
+	elapsed := l.config.Now().Sub(t.start)
+	if elapsed >= t.until.Sub(t.start) {
+		return limit
+	}
+	oldRequests, oldWindow, err := l.getLimit(t.previous, entity, tier, scope)
+	if err != nil {
+		return limit
+	}
+	old := inWindow(oldRequests, oldWindow, window)
+	if old <= limit {
+		return limit
+	}
+
+	switch t.policy {
+	case TransitionDrain:
+		if elapsed < oldWindow {
+			return old
+		}
+	case TransitionRamp:
+		if elapsed < t.ramp {
+			return old - int64(float64(old-limit)*float64(elapsed)/float64(t.ramp))
+		}
+	}
+	return limit
+}
+
+// noteTransition reports an entity admitted over its new limit only because of the
+// table's transition, once per entity and scope
+func (l *limiterImpl) noteTransition(table *limitTable, entity, tier, scope string, result *CoreResult) {
+	t := table.transition
+	if t == nil || l.config.OnLimitTransition == nil || !result.Allowed {
+		return
+	}
+	requests, window, err := l.settledLimit(table, entity, tier, scope)
+	if err != nil || result.Used <= requests || result.Limit <= requests {
+		return
+	}
+	if t.reported.Load() >= maxTransitionEntities {
+		return
+	}
+	if _, seen := t.affected.LoadOrStore(entity+"\x00"+scope, struct{}{}); seen {
+		return
+	}
+	t.reported.Add(1)
+
+	until := t.start.Add(t.ramp)
+	if t.policy == TransitionDrain {
+		if _, oldWindow, err := l.getLimit(t.previous, entity, tier, scope); err == nil {
+			until = t.start.Add(oldWindow)
+		}
+	}
+	l.config.OnLimitTransition(LimitTransitionEvent{Scope: scope, Entity: entity, Policy: t.policy,
+		From: result.Limit, To: requests, Window: window, Until: until})
+}
+
+// inWindow expresses a limit of requests per window in another window
+func inWindow(requests int64, window, other time.Duration) int64 {
+	if window == other {
+		return requests
+	}
+	return int64(float64(requests) * float64(other) / float64(window))
+}
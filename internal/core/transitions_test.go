@@ -0,0 +1,117 @@
+// internal/core/transitions_test.go
+package core
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTransitionLimiter creates a limiter on a clock reading now that records its limit transitions
+func newTransitionLimiter(t *testing.T, config *Config, now *time.Time) (Limiter, *[]LimitTransitionEvent) {
+	t.Helper()
+	var events []LimitTransitionEvent
+	config.Clock = func() time.Time { return *now }
+	config.OnLimitTransition = func(e LimitTransitionEvent) { events = append(events, e) }
+	return newTestLimiter(t, config, nil), &events
+}
+
+func TestLimitTransitionDrain(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	limiter, events := newTransitionLimiter(t, &Config{
+		Limits:          map[string]string{"global": "10/minute", "search": "5/minute"},
+		LimitTransition: TransitionDrain,
+	}, &now)
+	ctx := context.Background()
+
+	for i := 0; i < 8; i++ {
+		limiter.Check(ctx, "alice", "global")
+	}
+	if err := limiter.UpdateLimits(LimitUpdate{Limits: map[string]string{"global": "5/minute", "search": "20/minute"}}); err != nil {
+		t.Fatalf("UpdateLimits failed: %v", err)
+	}
+	want := LimitTransitionEvent{Scope: "global", Policy: TransitionDrain, From: 10, To: 5, Window: time.Minute, Until: now.Add(time.Minute)}
+	if len(*events) != 1 || (*events)[0] != want {
+		t.Fatalf("Expected only the global limit to start a transition, got %+v", *events)
+	}
+
+	// Alice keeps the old limit while her usage drains, and is reported once
+	now = now.Add(10 * time.Second)
+	for i := 0; i < 3; i++ {
+		result, err := limiter.Check(ctx, "alice", "global")
+		if err != nil {
+			t.Fatalf("Check failed: %v", err)
+		}
+		if result.Allowed != (i < 2) || result.Limit != 10 {
+			t.Errorf("Check %d: expected the old limit of 10, got %+v", i, result)
+		}
+	}
+	if len(*events) != 2 || (*events)[1].Entity != "alice" || (*events)[1].From != 10 || (*events)[1].To != 5 {
+		t.Errorf("Expected alice to be reported once, got %+v", *events)
+	}
+
+	// Bob stays within the new limit, so he is not affected
+	limiter.Check(ctx, "bob", "global")
+	if len(*events) != 2 {
+		t.Errorf("Expected no event for bob, got %+v", (*events)[2:])
+	}
+
+	now = now.Add(time.Minute)
+	result, err := limiter.Check(ctx, "bob", "global")
+	if err != nil || result.Limit != 5 {
+		t.Errorf("Expected the new limit once drained, got %+v (%v)", result, err)
+	}
+}
+
+func TestLimitTransitionRamp(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	limiter, events := newTransitionLimiter(t, &Config{
+		Limits:            map[string]string{"global": "100/minute"},
+		TierLimits:        map[string]map[string]string{"global": {"premium": "1000/minute"}},
+		LimitTransition:   TransitionRamp,
+		LimitRampDuration: 10 * time.Minute,
+	}, &now)
+	ctx := context.Background()
+	start := now
+
+	// Scaling down shrinks every limit
+	if err := limiter.UpdateLimits(LimitUpdate{Scale: 0.2}); err != nil {
+		t.Fatalf("UpdateLimits failed: %v", err)
+	}
+	if len(*events) != 2 || (*events)[0].Tier != "" || (*events)[1].Tier != "premium" || (*events)[1].To != 200 {
+		t.Fatalf("Expected transitions of the scope and premium limits, got %+v", *events)
+	}
+
+	for _, step := range []struct {
+		after time.Duration
+		limit int64
+	}{{0, 100}, {5 * time.Minute, 60}, {10 * time.Minute, 20}} {
+		now = start.Add(step.after)
+		result, err := limiter.Peek(ctx, "alice", "global")
+		if err != nil {
+			t.Fatalf("Peek failed: %v", err)
+		}
+		if result.Limit != step.limit {
+			t.Errorf("After %v: expected a limit of %d, got %d", step.after, step.limit, result.Limit)
+		}
+	}
+}
+
+func TestLimitTransitionImmediate(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	limiter, events := newTransitionLimiter(t, &Config{Limits: map[string]string{"global": "10/minute"}}, &now)
+
+	if err := limiter.UpdateLimits(LimitUpdate{Limits: map[string]string{"global": "5/minute"}}); err != nil {
+		t.Fatalf("UpdateLimits failed: %v", err)
+	}
+	result, err := limiter.Check(context.Background(), "alice", "global")
+	if err != nil || result.Limit != 5 || len(*events) != 0 {
+		t.Errorf("Expected the new limit at once and no events, got %+v (%v, %d events)", result, err, len(*events))
+	}
+
+	config := &Config{Store: "memory", Algorithm: "sliding_window", Limits: map[string]string{"global": "1/second"}, LimitTransition: "gradual"}
+	if err := config.Validate(); err == nil || !strings.Contains(err.Error(), "limit transition") {
+		t.Errorf("Expected an invalid transition to be rejected, got %v", err)
+	}
+}
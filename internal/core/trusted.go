@@ -0,0 +1,218 @@
+// internal/core/trusted.go
+package core
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TrustedCallHeader carries the signed grant of a trusted internal call
+const TrustedCallHeader = "X-Gorly-Trusted-Call"
+
+// TrustedBypass is the grant scope of trusted calls that skip rate limiting
+const TrustedBypass = "*"
+
+// DefaultTrustedCallMaxAge is how long a trusted call signature stays valid
+const DefaultTrustedCallMaxAge = time.Minute
+
+// MinTrustedCallKeyLength is the shortest secret accepted for signing trusted calls
+const MinTrustedCallKeyLength = 32
+
+// Reasons a trusted call header is rejected
+const (
+	TrustedCallMalformed    = "malformed"     // The header could not be parsed
+	TrustedCallUnknownKey   = "unknown_key"   // The key ID is not configured
+	TrustedCallExpired      = "expired"       // The timestamp is further than MaxAge from now
+	TrustedCallBadSignature = "bad_signature" // The signature does not match
+	TrustedCallUnknownScope = "unknown_scope" // The granted scope has no request limit
+)
+
+// TrustedCallConfig lets internal services lift the limits of single requests. A request
+// carrying a valid signature in TrustedCallHeader bypasses rate limiting or is limited
+// under the scope the signature grants instead of its own.
+type TrustedCallConfig struct {
+	Keys   map[string][]byte // Key ID -> HMAC-SHA256 secret; several keys allow rotation
+	MaxAge time.Duration     // How far a signature's timestamp may be from now (default: 1m)
+}
+
+// TrustedCall is a verified grant of a trusted call
+type TrustedCall struct {
+	KeyID string // Key the call was signed with
+	Scope string // Scope the request is limited under, or TrustedBypass
+}
+
+// Bypass reports whether the call skips rate limiting
+func (tc *TrustedCall) Bypass() bool {
+	return tc.Scope == TrustedBypass
+}
+
+// TrustedCallStats counts the trusted call headers the middleware verified
+type TrustedCallStats struct {
+	Accepted map[string]map[string]int64 // Key ID -> granted scope -> requests
+	Rejected map[string]int64            // Rejection reason -> requests
+}
+
+// validate checks the keys and signature lifetime
+func (tc *TrustedCallConfig) validate() error {
+	if len(tc.Keys) == 0 {
+		return errors.New("trusted calls require at least one key")
+	}
+	for id, secret := range tc.Keys {
+		if id == "" || strings.ContainsAny(id, ";= \t") {
+			return fmt.Errorf("invalid trusted call key ID %q: must be non-empty without ';', '=' or spaces", id)
+		}
+		if len(secret) < MinTrustedCallKeyLength {
+			return fmt.Errorf("trusted call key %s must be at least %d bytes", id, MinTrustedCallKeyLength)
+		}
+	}
+	if tc.MaxAge < 0 {
+		return errors.New("trusted call max age cannot be negative")
+	}
+	return nil
+}
+
+// maxAge returns the configured signature lifetime, or the default
+func (tc *TrustedCallConfig) maxAge() time.Duration {
+	if tc.MaxAge == 0 {
+		return DefaultTrustedCallMaxAge
+	}
+	return tc.MaxAge
+}
+
+// SignTrustedCall returns the TrustedCallHeader value granting scope (or TrustedBypass) to
+// one request. The signature covers the method and path, so it cannot be moved to other
+// endpoints; it can be replayed against the same endpoint until it expires.
+func SignTrustedCall(keyID string, secret []byte, scope, method, path string, at time.Time) string {
+	ts := strconv.FormatInt(at.Unix(), 10)
+	sig := trustedCallMAC(secret, keyID, ts, scope, method, path)
+	return "kid=" + keyID + ";ts=" + ts + ";scope=" + scope + ";sig=" + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+// trustedCallMAC signs the fields of a trusted call
+func trustedCallMAC(secret []byte, keyID, ts, scope, method, path string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(strings.Join([]string{"v1", keyID, ts, scope, method, path}, "\n")))
+	return mac.Sum(nil)
+}
+
+// VerifyTrustedCall returns the grant of a request with a valid trusted call signature.
+// Requests without one get nil; invalid signatures are counted and also get nil, so a
+// forged header gains nothing over sending none.
+func (l *limiterImpl) VerifyTrustedCall(r *http.Request) *TrustedCall {
+	config := l.config.TrustedCalls
+	if config == nil {
+		return nil
+	}
+	header := r.Header.Get(TrustedCallHeader)
+	if header == "" {
+		return nil
+	}
+
+	call, reason := l.verifyTrustedCall(config, header, r.Method, r.URL.Path)
+	if call == nil {
+		l.trusted.reject(reason)
+		return nil
+	}
+	l.trusted.accept(call)
+	return call
+}
+
+// verifyTrustedCall checks a header value, returning the grant or why it was rejected
+func (l *limiterImpl) verifyTrustedCall(config *TrustedCallConfig, header, method, path string) (*TrustedCall, string) {
+	fields := make(map[string]string, 4)
+	for _, part := range strings.Split(header, ";") {
+		name, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return nil, TrustedCallMalformed
+		}
+		fields[name] = value
+	}
+	keyID, ts, scope := fields["kid"], fields["ts"], fields["scope"]
+	sig, err := base64.RawURLEncoding.DecodeString(fields["sig"])
+	if err != nil || len(sig) == 0 || scope == "" {
+		return nil, TrustedCallMalformed
+	}
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return nil, TrustedCallMalformed
+	}
+
+	secret, ok := config.Keys[keyID]
+	if !ok {
+		return nil, TrustedCallUnknownKey
+	}
+	if !hmac.Equal(sig, trustedCallMAC(secret, keyID, ts, scope, method, path)) {
+		return nil, TrustedCallBadSignature
+	}
+	// Checked after the signature, so forged headers count as bad signatures whatever their timestamp
+	if age := l.config.Now().Sub(time.Unix(unix, 0)); age > config.maxAge() || age < -config.maxAge() {
+		return nil, TrustedCallExpired
+	}
+	if scope != TrustedBypass && !l.config.HasRequestLimit(scope) {
+		return nil, TrustedCallUnknownScope
+	}
+	return &TrustedCall{KeyID: keyID, Scope: scope}, ""
+}
+
+// TrustedCallStats returns the verified trusted calls, or nil when trusted calls are not configured
+func (l *limiterImpl) TrustedCallStats() *TrustedCallStats {
+	if l.config.TrustedCalls == nil {
+		return nil
+	}
+	return l.trusted.snapshot()
+}
+
+// trustedCallCounter counts trusted call headers by outcome
+type trustedCallCounter struct {
+	mu       sync.Mutex
+	accepted map[TrustedCall]int64
+	rejected map[string]int64
+}
+
+// accept counts a verified call
+func (tc *trustedCallCounter) accept(call *TrustedCall) {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+	if tc.accepted == nil {
+		tc.accepted = make(map[TrustedCall]int64)
+	}
+	tc.accepted[*call]++
+}
+
+// reject counts a header rejected for reason
+func (tc *trustedCallCounter) reject(reason string) {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+	if tc.rejected == nil {
+		tc.rejected = make(map[string]int64)
+	}
+	tc.rejected[reason]++
+}
+
+// snapshot copies the counts
+func (tc *trustedCallCounter) snapshot() *TrustedCallStats {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+	stats := &TrustedCallStats{
+		Accepted: make(map[string]map[string]int64),
+		Rejected: make(map[string]int64, len(tc.rejected)),
+	}
+	for call, count := range tc.accepted {
+		if stats.Accepted[call.KeyID] == nil {
+			stats.Accepted[call.KeyID] = make(map[string]int64)
+		}
+		stats.Accepted[call.KeyID][call.Scope] = count
+	}
+	for reason, count := range tc.rejected {
+		stats.Rejected[reason] = count
+	}
+	return stats
+}
@@ -0,0 +1,85 @@
+// internal/core/trusted_test.go
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVerifyTrustedCall(t *testing.T) {
+	now := time.Unix(1760572800, 0)
+	secret := []byte(strings.Repeat("k", MinTrustedCallKeyLength))
+	config := &Config{
+		Algorithm:    "sliding_window",
+		Limits:       map[string]string{"global": "1/minute", "internal": "1000/minute"},
+		TrustedCalls: &TrustedCallConfig{Keys: map[string][]byte{"billing": secret}},
+		Clock:        func() time.Time { return now },
+	}
+	limiter := newTestLimiter(t, config, nil)
+
+	request := func(header string) *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "/v1/invoices", nil)
+		if header != "" {
+			r.Header.Set(TrustedCallHeader, header)
+		}
+		return r
+	}
+	sign := func(keyID string, secret []byte, scope, path string, at time.Time) string {
+		return SignTrustedCall(keyID, secret, scope, http.MethodPost, path, at)
+	}
+
+	tests := []struct {
+		name   string
+		header string
+		want   *TrustedCall
+	}{
+		{"no header", "", nil},
+		{"bypass", sign("billing", secret, TrustedBypass, "/v1/invoices", now), &TrustedCall{KeyID: "billing", Scope: TrustedBypass}},
+		{"elevated scope", sign("billing", secret, "internal", "/v1/invoices", now.Add(-30*time.Second)), &TrustedCall{KeyID: "billing", Scope: "internal"}},
+		{"other path", sign("billing", secret, TrustedBypass, "/v1/refunds", now), nil},
+		{"forged", sign("billing", []byte(strings.Repeat("x", 32)), TrustedBypass, "/v1/invoices", now), nil},
+		{"unknown key", sign("ops", secret, TrustedBypass, "/v1/invoices", now), nil},
+		{"expired", sign("billing", secret, TrustedBypass, "/v1/invoices", now.Add(-2*time.Minute)), nil},
+		{"unknown scope", sign("billing", secret, "admin", "/v1/invoices", now), nil},
+		{"malformed", "bypass please", nil},
+	}
+	for _, tt := range tests {
+		got := limiter.VerifyTrustedCall(request(tt.header))
+		if (got == nil) != (tt.want == nil) || (got != nil && *got != *tt.want) {
+			t.Errorf("%s: expected %+v, got %+v", tt.name, tt.want, got)
+		}
+	}
+
+	stats := limiter.TrustedCallStats()
+	if stats.Accepted["billing"][TrustedBypass] != 1 || stats.Accepted["billing"]["internal"] != 1 {
+		t.Errorf("Expected one accepted call per grant, got %v", stats.Accepted)
+	}
+	want := map[string]int64{TrustedCallBadSignature: 2, TrustedCallUnknownKey: 1, TrustedCallExpired: 1, TrustedCallUnknownScope: 1, TrustedCallMalformed: 1}
+	for reason, count := range want {
+		if stats.Rejected[reason] != count {
+			t.Errorf("Expected %d rejections for %s, got %v", count, reason, stats.Rejected)
+		}
+	}
+}
+
+func TestTrustedCallConfigValidation(t *testing.T) {
+	tests := []struct {
+		name   string
+		config *TrustedCallConfig
+		valid  bool
+	}{
+		{"valid", &TrustedCallConfig{Keys: map[string][]byte{"billing": make([]byte, 32)}}, true},
+		{"no keys", &TrustedCallConfig{}, false},
+		{"short secret", &TrustedCallConfig{Keys: map[string][]byte{"billing": []byte("hunter2")}}, false},
+		{"separator in key ID", &TrustedCallConfig{Keys: map[string][]byte{"bill;ing": make([]byte, 32)}}, false},
+		{"negative max age", &TrustedCallConfig{Keys: map[string][]byte{"billing": make([]byte, 32)}, MaxAge: -time.Second}, false},
+	}
+	for _, tt := range tests {
+		if err := tt.config.validate(); (err == nil) != tt.valid {
+			t.Errorf("%s: expected valid=%v, got %v", tt.name, tt.valid, err)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+// internal/core/usagestats.go
+package core
+
+import (
+	"container/list"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultMaxTrackedEntities is the number of entities whose usage is broken down by default
+const DefaultMaxTrackedEntities = 1000
+
+// UsageCounter is the number of requests and denials this instance counted for a scope
+// or entity, and when it last counted one
+type UsageCounter struct {
+	Requests int64
+	Denied   int64
+	LastUsed time.Time
+}
+
+// UsageStats is the usage this instance counted since it was built. Requests are counted
+// once per scope they are checked in, so a multi-scope check counts in each of its scopes.
+type UsageStats struct {
+	Requests int64
+	Denied   int64
+	ByScope  map[string]UsageCounter
+	ByEntity map[string]UsageCounter // The most recently seen entities only
+
+	// EvictedEntities counts entities dropped from ByEntity to make room for others
+	EvictedEntities int64
+}
+
+// usageStats counts requests and denials locally, in total, per scope and per entity.
+//
+// Scopes are already bounded by the scope budget, so every scope is counted. Entities
+// are not: a limiter keyed by client IP may see millions. Only the maxEntities most
+// recently seen entities are kept, the least recently seen being dropped to make room,
+// so busy and abusive entities stay listed while one-off visitors come and go.
+type usageStats struct {
+	now         func() time.Time
+	maxEntities int // 0 disables the entity breakdown
+
+	requests atomic.Int64
+	denied   atomic.Int64
+	scopes   sync.Map // scope -> *usageCounter
+
+	mu       sync.Mutex
+	entities map[string]*list.Element // entity -> element of recent holding an *entityUsage
+	recent   *list.List               // Most recently seen entity first
+	evicted  int64
+}
+
+// usageCounter counts the usage of a scope
+type usageCounter struct {
+	requests atomic.Int64
+	denied   atomic.Int64
+	lastUsed atomic.Int64 // Unix nanoseconds
+}
+
+// entityUsage counts the usage of an entity; it is guarded by usageStats.mu
+type entityUsage struct {
+	entity  string
+	counter UsageCounter
+}
+
+// newUsageStats creates the usage counters of a config
+func newUsageStats(config *Config) *usageStats {
+	us := &usageStats{
+		now:         config.Now,
+		maxEntities: config.MaxTrackedEntities,
+		entities:    make(map[string]*list.Element),
+		recent:      list.New(),
+	}
+	if us.maxEntities == 0 {
+		us.maxEntities = DefaultMaxTrackedEntities
+	}
+	if us.maxEntities < 0 {
+		us.maxEntities = 0
+	}
+	return us
+}
+
+// record counts one request of entity in scope
+func (us *usageStats) record(entity, scope string, allowed bool) {
+	now := us.now()
+	us.requests.Add(1)
+	if !allowed {
+		us.denied.Add(1)
+	}
+
+	counter, ok := us.scopes.Load(scope)
+	if !ok {
+		counter, _ = us.scopes.LoadOrStore(scope, &usageCounter{})
+	}
+	sc := counter.(*usageCounter)
+	sc.requests.Add(1)
+	if !allowed {
+		sc.denied.Add(1)
+	}
+	sc.lastUsed.Store(now.UnixNano())
+
+	if us.maxEntities == 0 {
+		return
+	}
+	us.mu.Lock()
+	defer us.mu.Unlock()
+
+	var usage *entityUsage
+	if element, ok := us.entities[entity]; ok {
+		us.recent.MoveToFront(element)
+		usage = element.Value.(*entityUsage)
+	} else {
+		if us.recent.Len() >= us.maxEntities {
+			oldest := us.recent.Back()
+			us.recent.Remove(oldest)
+			delete(us.entities, oldest.Value.(*entityUsage).entity)
+			us.evicted++
+		}
+		usage = &entityUsage{entity: entity}
+		us.entities[entity] = us.recent.PushFront(usage)
+	}
+	usage.counter.Requests++
+	if !allowed {
+		usage.counter.Denied++
+	}
+	usage.counter.LastUsed = now
+}
+
+// snapshot returns the counts so far
+func (us *usageStats) snapshot() *UsageStats {
+	stats := &UsageStats{
+		Requests: us.requests.Load(),
+		Denied:   us.denied.Load(),
+		ByScope:  make(map[string]UsageCounter),
+	}
+	us.scopes.Range(func(scope, counter any) bool {
+		sc := counter.(*usageCounter)
+		stats.ByScope[scope.(string)] = UsageCounter{
+			Requests: sc.requests.Load(),
+			Denied:   sc.denied.Load(),
+			LastUsed: time.Unix(0, sc.lastUsed.Load()),
+		}
+		return true
+	})
+
+	us.mu.Lock()
+	stats.ByEntity = make(map[string]UsageCounter, len(us.entities))
+	for entity, element := range us.entities {
+		stats.ByEntity[entity] = element.Value.(*entityUsage).counter
+	}
+	stats.EvictedEntities = us.evicted
+	us.mu.Unlock()
+	return stats
+}
+
+// count records the outcome of a check in the local usage counters and, with
+// write-behind stats, in the shared counters
+func (l *limiterImpl) count(entity, scope string, allowed bool) {
+	l.usage.record(entity, scope, allowed)
+	l.stats.record(scope, allowed)
+}
+
+// UsageStats returns the requests and denials this instance counted, in total, per scope
+// and for the most recently seen entities
+func (l *limiterImpl) UsageStats() *UsageStats {
+	return l.usage.snapshot()
+}
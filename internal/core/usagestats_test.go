@@ -0,0 +1,65 @@
+// internal/core/usagestats_test.go
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestUsageStats(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	limiter := newTestLimiter(t, &Config{
+		Algorithm:          "sliding_window",
+		Limits:             map[string]string{"global": "2/minute", "search": "10/minute"},
+		MaxTrackedEntities: 2,
+		Clock:              func() time.Time { return now },
+	}, nil)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		limiter.Check(ctx, "alice", "global")
+	}
+	now = now.Add(time.Second)
+	limiter.Check(ctx, "bob", "search")
+	limiter.CheckAll(ctx, "carol", []ScopeCost{{Scope: "global", Cost: 1}, {Scope: "search", Cost: 1}})
+
+	stats := limiter.UsageStats()
+	if stats.Requests != 6 || stats.Denied != 1 {
+		t.Errorf("Expected 6 requests and 1 denial, got %d and %d", stats.Requests, stats.Denied)
+	}
+	if global := stats.ByScope["global"]; global.Requests != 4 || global.Denied != 1 || !global.LastUsed.Equal(now) {
+		t.Errorf("Unexpected global usage: %+v", global)
+	}
+
+	// Alice was seen least recently, so she made room for carol
+	if len(stats.ByEntity) != 2 || stats.EvictedEntities != 1 {
+		t.Fatalf("Expected 2 tracked entities and 1 eviction, got %v and %d", stats.ByEntity, stats.EvictedEntities)
+	}
+	if _, ok := stats.ByEntity["alice"]; ok {
+		t.Error("Expected alice to be evicted")
+	}
+	if carol := stats.ByEntity["carol"]; carol.Requests != 2 || carol.Denied != 0 {
+		t.Errorf("Unexpected usage of carol: %+v", carol)
+	}
+
+	// Seeing bob again keeps him over carol
+	limiter.Check(ctx, "bob", "search")
+	limiter.Check(ctx, "dave", "search")
+	stats = limiter.UsageStats()
+	if bob := stats.ByEntity["bob"]; bob.Requests != 2 {
+		t.Errorf("Expected bob to be tracked with 2 requests, got %+v", bob)
+	}
+	if _, ok := stats.ByEntity["carol"]; ok {
+		t.Error("Expected carol to be evicted")
+	}
+}
+
+func TestUsageStatsWithoutEntities(t *testing.T) {
+	us := newUsageStats(&Config{MaxTrackedEntities: -1})
+	us.record("alice", "global", false)
+	stats := us.snapshot()
+	if stats.Requests != 1 || stats.Denied != 1 || len(stats.ByEntity) != 0 {
+		t.Errorf("Expected totals without entities, got %+v", stats)
+	}
+}
@@ -2,10 +2,15 @@
 package middleware
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
+	"net"
 	"net/http"
 	"reflect"
 	"strconv"
+	"time"
 
 	"github.com/itsatony/gorly/internal/core"
 )
@@ -72,15 +77,18 @@ func (um *UniversalMiddleware) ginHandler() interface{} {
 		ctx := reflect.ValueOf(c)
 
 		// Extract request and writer from Gin context
-		request := ctx.FieldByName("Request").Interface().(*http.Request)
-		writer := ctx.MethodByName("Writer").Call(nil)[0].Interface().(http.ResponseWriter)
+		request := member(ctx, "Request").Interface().(*http.Request)
+		writer := member(ctx, "Writer")
 
-		if !um.checkRateLimit(writer, request) {
+		if !um.checkRateLimit(writer.Interface().(http.ResponseWriter), request) {
 			ctx.MethodByName("Abort").Call(nil)
 			return
 		}
 
 		ctx.MethodByName("Next").Call(nil)
+
+		// gin.ResponseWriter cannot be wrapped without Gin; it counts what was written
+		um.chargeBandwidth(request, writer.MethodByName("Size").Call(nil)[0].Int())
 	}
 }
 
@@ -89,20 +97,20 @@ func (um *UniversalMiddleware) echoHandler() interface{} {
 	return func(next interface{}) interface{} {
 		return func(c interface{}) error {
 			ctx := reflect.ValueOf(c)
-			request := ctx.MethodByName("Request").Call(nil)[0].Interface().(*http.Request)
-			response := ctx.MethodByName("Response").Call(nil)[0]
-			writer := response.MethodByName("Writer").Call(nil)[0].Interface().(http.ResponseWriter)
+			request := member(ctx, "Request").Interface().(*http.Request)
+			response := member(ctx, "Response")
+			writer := member(response, "Writer").Interface().(http.ResponseWriter)
 
 			if !um.checkRateLimit(writer, request) {
 				return nil
 			}
 
 			nextFunc := reflect.ValueOf(next)
-			results := nextFunc.Call([]reflect.Value{ctx})
-			if len(results) > 0 && !results[0].IsNil() {
-				return results[0].Interface().(error)
-			}
-			return nil
+			err := callError(nextFunc.Call([]reflect.Value{ctx}))
+
+			// echo.Response counts what was written
+			um.chargeBandwidth(request, member(response, "Size").Int())
+			return err
 		}
 	}
 }
@@ -117,22 +125,93 @@ func (um *UniversalMiddleware) fiberHandler() interface{} {
 		path := ctx.MethodByName("Path").Call(nil)[0].String()
 		ip := ctx.MethodByName("IP").Call(nil)[0].String()
 
-		// Create minimal HTTP request for rate limiting
+		// Create an HTTP request for rate limiting carrying every header, which extractors
+		// and the bot classifier read
 		req, _ := http.NewRequest(method, path, nil)
 		req.RemoteAddr = ip + ":0"
+		req.Header = fiberHeaders(ctx)
+
+		// Fiber has no http.ResponseWriter: the check writes to a recorder whose headers,
+		// and response if the request is stopped, are copied to the Fiber context
+		rec := &recorder{header: make(http.Header)}
+		allowed := um.checkRateLimit(rec, req)
+		for name := range rec.header {
+			ctx.MethodByName("Set").Call([]reflect.Value{reflect.ValueOf(name), reflect.ValueOf(rec.header.Get(name))})
+		}
+		if !allowed {
+			status := rec.status
+			if status == 0 {
+				status = http.StatusTooManyRequests
+			}
+			ctx.MethodByName("Status").Call([]reflect.Value{reflect.ValueOf(status)})
+			return callError(ctx.MethodByName("Send").Call([]reflect.Value{reflect.ValueOf(rec.body.Bytes())}))
+		}
 
-		// Add common headers
-		userAgent := ctx.MethodByName("Get").Call([]reflect.Value{reflect.ValueOf("User-Agent")})[0].String()
-		req.Header = make(http.Header)
-		req.Header.Set("User-Agent", userAgent)
+		err := callError(ctx.MethodByName("Next").Call(nil))
 
-		if !um.checkRateLimit(nil, req) {
-			ctx.MethodByName("Status").Call([]reflect.Value{reflect.ValueOf(429)})
-			body := map[string]string{"error": "Rate limit exceeded"}
-			return ctx.MethodByName("JSON").Call([]reflect.Value{reflect.ValueOf(body)})[0].Interface().(error)
+		// Fiber buffers the response body, which is charged once the handlers are done
+		response := ctx.MethodByName("Response").Call(nil)[0]
+		um.chargeBandwidth(req, int64(response.MethodByName("Body").Call(nil)[0].Len()))
+		return err
+	}
+}
+
+// fiberHeaders returns the request headers of a Fiber context
+func fiberHeaders(ctx reflect.Value) http.Header {
+	header := make(http.Header)
+	headers := ctx.MethodByName("GetReqHeaders").Call(nil)[0]
+	for iter := headers.MapRange(); iter.Next(); {
+		name, value := iter.Key().String(), iter.Value()
+		if value.Kind() == reflect.String {
+			header.Add(name, value.String()) // Fiber before v2.50 joins the values
+			continue
 		}
+		for i := 0; i < value.Len(); i++ {
+			header.Add(name, value.Index(i).String())
+		}
+	}
+	return header
+}
 
-		return ctx.MethodByName("Next").Call(nil)[0].Interface().(error)
+// member returns the result of a framework context's method name, or its field name,
+// such as the Request and Writer fields of gin.Context
+func member(v reflect.Value, name string) reflect.Value {
+	if method := v.MethodByName(name); method.IsValid() {
+		return method.Call(nil)[0]
+	}
+	return reflect.Indirect(v).FieldByName(name)
+}
+
+// callError returns the error result of a reflected call, which is nil for a nil interface
+func callError(results []reflect.Value) error {
+	if len(results) == 0 {
+		return nil
+	}
+	err, _ := results[0].Interface().(error)
+	return err
+}
+
+// recorder captures what the rate limit check writes for frameworks without an http.ResponseWriter
+type recorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (r *recorder) Header() http.Header {
+	return r.header
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	return r.body.Write(b)
+}
+
+func (r *recorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
 	}
 }
 
@@ -143,6 +222,8 @@ func (um *UniversalMiddleware) chiHandler() interface{} {
 			if !um.checkRateLimit(w, r) {
 				return
 			}
+			w, finish := um.wrapBandwidth(w, r)
+			defer finish()
 			next.ServeHTTP(w, r)
 		})
 	}
@@ -150,11 +231,18 @@ func (um *UniversalMiddleware) chiHandler() interface{} {
 
 // httpHandler returns a standard HTTP middleware
 func (um *UniversalMiddleware) httpHandler() interface{} {
+	return um.HTTP()
+}
+
+// HTTP returns the standard net/http middleware, typed for callers that need no assertion
+func (um *UniversalMiddleware) HTTP() func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			if !um.checkRateLimit(w, r) {
 				return
 			}
+			w, finish := um.wrapBandwidth(w, r)
+			defer finish()
 			next.ServeHTTP(w, r)
 		})
 	}
@@ -166,59 +254,161 @@ func (um *UniversalMiddleware) httpHandler() interface{} {
 
 // checkRateLimit performs the actual rate limit check
 func (um *UniversalMiddleware) checkRateLimit(w http.ResponseWriter, r *http.Request) bool {
-	// Extract entity using the configured extractor
-	entity := um.config.ExtractorFunc(r)
-	if entity == "" {
-		entity = "anonymous"
+	// Exempt methods never consume quota
+	if um.config.IsExempt(r) {
+		return true
 	}
 
-	// Extract scope using the configured scope function (if any)
-	scope := "global"
-	if um.config.ScopeFunc != nil {
-		if s := um.config.ScopeFunc(r); s != "" {
-			scope = s
+	// Health probes bypass every limit, including the IP-based pre-auth limit they
+	// would share with everything else on the node
+	if um.config.IsProbe(r) {
+		um.limiter.CountProbe()
+		return true
+	}
+
+	// Trusted internal calls carry a signed grant that bypasses the limits or moves the
+	// request into an elevated scope; they skip the per-client pre-auth limit
+	var trusted *core.TrustedCall
+	if um.config.TrustedCalls != nil {
+		trusted = um.limiter.VerifyTrustedCall(r)
+	}
+
+	// Pre-auth phase: a cheap per-client check that runs before the entity is
+	// extracted, so floods are rejected before they reach the auth system
+	if um.config.HasPreAuth() && trusted == nil {
+		preAuth, err := um.limiter.CheckPreAuth(r.Context(), um.config.PreAuthExtractor(r))
+		if err != nil {
+			um.fail(w, err)
+			return false
+		}
+
+		preAuthScope := um.config.PreAuthScopeName()
+		um.setHeader(w, preAuthScope, "X-RateLimit-PreAuth-Limit", toString(preAuth.Limit))
+		um.setHeader(w, preAuthScope, "X-RateLimit-PreAuth-Remaining", toString(preAuth.Remaining))
+
+		if !preAuth.Allowed {
+			um.deny(w, r, um.config.PreAuthScopeName(), preAuth, nil)
+			return false
 		}
 	}
 
-	// Perform rate limit check
-	result, err := um.limiter.Check(r.Context(), entity, scope)
+	// Extract entity using the configured extractor unless an upstream
+	// handler attributed the request with WithEntity or it carries an active token
+	entity, ok := core.EntityFromContext(r.Context())
+	if !ok && um.config.Introspection != nil {
+		entity, ok = um.introspectedEntity(r)
+	}
+	if !ok {
+		entity = um.config.ExtractorFunc(r)
+	}
+
+	// Header-derived values are untrusted; normalize them before they reach
+	// store keys, metrics labels and logs
+	entity, err := um.config.SanitizeEntity(entity)
 	if err != nil {
-		// Handle error
-		if um.config.ErrorHandler != nil {
-			um.config.ErrorHandler(err)
-		}
+		um.reject(w, err)
+		return false
+	}
 
-		if w != nil {
-			http.Error(w, "Rate limiting service unavailable", http.StatusInternalServerError)
+	// Requests without an identity are handled by the empty entity policy
+	if entity == "" {
+		um.limiter.CountEmptyEntity()
+		switch um.config.EmptyEntityPolicyName() {
+		case core.EmptyEntitySkip:
+			return true
+		case core.EmptyEntityDeny:
+			um.unidentified(w)
+			return false
+		case core.EmptyEntityIP:
+			entity = "ip:" + remoteIP(r)
+		default:
+			entity = core.AnonymousEntity
 		}
+	}
+
+	// Maintenance mode rejects everyone except allowlisted entities and client IPs
+	if denied := um.limiter.CheckMaintenance(entity, remoteIP(r)); denied != nil {
+		um.maintenance(w, denied)
 		return false
 	}
 
-	// Add rate limit headers if we have a response writer
-	if w != nil {
-		w.Header().Set("X-RateLimit-Limit", toString(result.Limit))
-		w.Header().Set("X-RateLimit-Remaining", toString(result.Remaining))
-		w.Header().Set("X-RateLimit-Used", toString(result.Used))
-		w.Header().Set("X-RateLimit-Window", result.Window.String())
+	if trusted != nil && trusted.Bypass() {
+		return true
+	}
+
+	// Extract scope using the configured scope function (if any), unless a trusted call granted one
+	scopeName := um.scopeFor(r)
+	if trusted != nil {
+		scopeName = trusted.Scope
+	}
+	scope, err := um.config.SanitizeScope(scopeName)
+	if err != nil {
+		um.reject(w, err)
+		return false
+	}
+
+	// Locked-out entities are rejected before consuming quota
+	if um.config.Lockout != nil && um.config.Lockout.AppliesTo(scope) {
+		if !um.checkLockout(w, r, entity, scope) {
+			return false
+		}
+	}
+
+	// Token budgets are charged by the application once usage is known,
+	// so the middleware only rejects entities whose budget is already spent
+	var tokens *core.CoreResult
+	if um.config.HasTokenBudget(scope) {
+		tokens, err = um.limiter.CheckTokens(r.Context(), entity, scope)
+		if err != nil {
+			um.fail(w, err)
+			return false
+		}
 
-		if !result.Allowed {
-			w.Header().Set("X-RateLimit-Retry-After", toString(int64(result.RetryAfter.Seconds())))
-			w.Header().Set("Retry-After", toString(int64(result.RetryAfter.Seconds())))
+		um.setHeader(w, scope, "X-RateLimit-Tokens-Limit", toString(tokens.Limit))
+		um.setHeader(w, scope, "X-RateLimit-Tokens-Remaining", toString(tokens.Remaining))
+		um.setHeader(w, scope, "X-RateLimit-Tokens-Reset", toString(tokens.ResetTime.Unix()))
+		um.setHeader(w, scope, "X-RateLimit-Tokens-Reset-At", tokens.ResetTime.Format(time.RFC3339))
+
+		if !tokens.Allowed {
+			um.deny(w, r, scope, tokens, tokens)
+			return false
+		}
+	}
+
+	// Perform rate limit check, weighted by the cost of the endpoint
+	cost := um.limiter.RequestCost(r.Method, r.URL.Path)
+	if cost != core.DefaultRequestCost {
+		um.setHeader(w, scope, "X-RateLimit-Cost", toString(cost))
+	}
+	result, bandwidth, err := um.check(r.Context(), entity, scope, cost)
+	if err != nil {
+		// Entities claiming an unconfigured tier are rejected like other invalid input
+		var inputErr *core.InputError
+		if errors.As(err, &inputErr) {
+			um.reject(w, inputErr)
+			return false
 		}
+		um.fail(w, err)
+		return false
+	}
+
+	// Add rate limit headers if we have a response writer
+	if bandwidth != nil {
+		um.setHeader(w, scope, "X-RateLimit-Bandwidth-Limit", toString(bandwidth.Limit))
+		um.setHeader(w, scope, "X-RateLimit-Bandwidth-Remaining", toString(bandwidth.Remaining))
+		um.setHeader(w, scope, "X-RateLimit-Bandwidth-Reset", toString(bandwidth.ResetTime.Unix()))
+		um.setHeader(w, scope, "X-RateLimit-Bandwidth-Reset-At", bandwidth.ResetTime.Format(time.RFC3339))
 	}
 
 	// Check if request is allowed
 	if !result.Allowed {
-		if um.config.DeniedHandler != nil && w != nil {
-			um.config.DeniedHandler(w, r, result)
-		} else if w != nil {
-			// Default denied response
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusTooManyRequests)
-			w.Write([]byte(`{"error":"Rate limit exceeded","retry_after_seconds":` + toString(int64(result.RetryAfter.Seconds())) + `}`))
-		}
+		um.deny(w, r, scope, result, tokens)
 		return false
 	}
+	// Requests let through by a fail-open policy have no budget to report
+	if !result.FailedOpen {
+		um.setHeaders(w, scope, result)
+	}
 
 	// Add rate limit info to request context for downstream handlers
 	ctx := context.WithValue(r.Context(), "gorly_result", result)
@@ -229,6 +419,238 @@ func (um *UniversalMiddleware) checkRateLimit(w http.ResponseWriter, r *http.Req
 	return true
 }
 
+// checkLockout rejects locked-out entities and consults the challenge hook after repeated failures
+func (um *UniversalMiddleware) checkLockout(w http.ResponseWriter, r *http.Request, entity, scope string) bool {
+	state, err := um.limiter.Lockout(r.Context(), entity)
+	if err != nil {
+		um.fail(w, err)
+		return false
+	}
+
+	now := um.config.Now()
+	if state.Locked(now) {
+		um.setHeader(w, scope, "X-RateLimit-Lockout", "true")
+		um.deny(w, r, scope, &core.CoreResult{
+			Allowed:    false,
+			Used:       state.Failures,
+			RetryAfter: state.LockedUntil.Sub(now),
+			ResetTime:  state.LockedUntil,
+		}, nil)
+		return false
+	}
+
+	// Repeated failures require the client to pass a challenge such as a CAPTCHA
+	if um.config.ChallengeHandler != nil && um.config.Lockout.ChallengeAfter > 0 &&
+		state.Failures >= int64(um.config.Lockout.ChallengeAfter) {
+		return um.config.ChallengeHandler(w, r)
+	}
+
+	return true
+}
+
+// setHeaders adds the standard rate limit headers for a result in scope
+func (um *UniversalMiddleware) setHeaders(w http.ResponseWriter, scope string, result *core.CoreResult) {
+	if w == nil {
+		return
+	}
+
+	um.setHeader(w, scope, "X-RateLimit-Limit", toString(result.Limit))
+	um.setHeader(w, scope, "X-RateLimit-Remaining", toString(result.Remaining))
+	um.setHeader(w, scope, "X-RateLimit-Used", toString(result.Used))
+	um.setHeader(w, scope, "X-RateLimit-Window", result.Window.String())
+	if result.Alignment != "" {
+		um.setHeader(w, scope, "X-RateLimit-Alignment", result.Alignment)
+	}
+	if result.GrantRemaining > 0 {
+		um.setHeader(w, scope, "X-RateLimit-Grant-Remaining", toString(result.GrantRemaining))
+	}
+	if !result.ResetTime.IsZero() {
+		um.setHeader(w, scope, "X-RateLimit-Reset", toString(result.ResetTime.Unix()))
+	}
+
+	if !result.Allowed {
+		um.setHeader(w, scope, "X-RateLimit-Retry-After", toString(int64(result.RetryAfter.Seconds())))
+		um.setHeader(w, scope, core.RetryAfterHeader, toString(int64(result.RetryAfter.Seconds())))
+	}
+}
+
+// setHeader writes a rate limit header under its configured name, unless the
+// header mode of the scope suppresses it
+func (um *UniversalMiddleware) setHeader(w http.ResponseWriter, scope, name, value string) {
+	if w == nil {
+		return
+	}
+	if name = um.config.ResponseHeader(scope, name); name != "" {
+		w.Header().Set(name, value)
+	}
+}
+
+// deny writes the denied response for a request in scope.
+// tokens is the entity's token budget, reported in the default body when the scope has one.
+func (um *UniversalMiddleware) deny(w http.ResponseWriter, r *http.Request, scope string, result, tokens *core.CoreResult) {
+	um.setHeaders(w, scope, result)
+
+	if um.config.DeniedHandler != nil && w != nil {
+		um.config.DeniedHandler(w, r, result)
+	} else if w != nil {
+		// Default denied response
+		body := `{"error":"Rate limit exceeded","retry_after_seconds":` + toString(int64(result.RetryAfter.Seconds()))
+		if tokens != nil {
+			body += `,"tokens_limit":` + toString(tokens.Limit) + `,"tokens_remaining":` + toString(tokens.Remaining)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(um.config.DeniedStatus(scope))
+		w.Write([]byte(body + `}`))
+	}
+}
+
+// maintenance writes the configured maintenance response
+func (um *UniversalMiddleware) maintenance(w http.ResponseWriter, result *core.CoreResult) {
+	if w == nil {
+		return
+	}
+
+	retryAfter := result.RetryAfter
+	message := um.config.MaintenanceMessage
+	if message == "" {
+		message = core.DefaultMaintenanceMessage
+	}
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"error":               message,
+		"maintenance":         true,
+		"retry_after_seconds": int64(retryAfter.Seconds()),
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Retry-After", toString(int64(retryAfter.Seconds())))
+	w.WriteHeader(um.config.MaintenanceStatus())
+	w.Write(body)
+}
+
+// remoteIP returns the host part of the request's remote address
+func remoteIP(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
+
+// introspectedEntity returns the entity of the request's token. Requests without an active
+// token, or whose token cannot be introspected, fall back to the extractor; the identity
+// provider being down must not take rate limiting with it.
+func (um *UniversalMiddleware) introspectedEntity(r *http.Request) (string, bool) {
+	info, err := um.limiter.IntrospectToken(r.Context(), um.config.Introspection.Token(r))
+	if err != nil {
+		if um.config.ErrorHandler != nil {
+			um.config.ErrorHandler(err)
+		}
+		return "", false
+	}
+	if info == nil {
+		return "", false
+	}
+	return info.Entity(), true
+}
+
+// unidentified rejects a request without an entity under the deny policy
+func (um *UniversalMiddleware) unidentified(w http.ResponseWriter) {
+	if w == nil {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnauthorized)
+	w.Write([]byte(`{"error":"Request could not be identified for rate limiting"}`))
+}
+
+// reject answers a request whose entity or scope failed input validation
+func (um *UniversalMiddleware) reject(w http.ResponseWriter, err error) {
+	if um.config.ErrorHandler != nil {
+		um.config.ErrorHandler(err)
+	}
+
+	if w != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	}
+}
+
+// fail reports a limiter error and rejects the request
+func (um *UniversalMiddleware) fail(w http.ResponseWriter, err error) {
+	if um.config.ErrorHandler != nil {
+		um.config.ErrorHandler(err)
+	}
+
+	if w != nil {
+		http.Error(w, "Rate limiting service unavailable", http.StatusInternalServerError)
+	}
+}
+
+// check evaluates the request-count and bandwidth limits that apply to a scope,
+// charging cost units against the request-count limit.
+// The second result is the bandwidth budget, or nil if the scope has no bandwidth limit.
+func (um *UniversalMiddleware) check(ctx context.Context, entity, scope string, cost int64) (*core.CoreResult, *core.CoreResult, error) {
+	var bandwidth *core.CoreResult
+	if um.config.HasBandwidthLimit(scope) {
+		var err error
+		bandwidth, err = um.limiter.CheckBandwidth(ctx, entity, scope)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		// Scopes limited only by volume skip request counting
+		if !bandwidth.Allowed || !um.config.HasRequestLimit(scope) {
+			return bandwidth, bandwidth, nil
+		}
+	}
+
+	result, err := um.limiter.CheckN(ctx, entity, scope, cost)
+	if err != nil {
+		return nil, nil, err
+	}
+	return result, bandwidth, nil
+}
+
+// scopeFor determines the scope of a request, qualified with the method when method scoping is enabled
+// and prefixed with the API version when versioned scoping is enabled
+func (um *UniversalMiddleware) scopeFor(r *http.Request) string {
+	scope := um.resourceScope(r)
+	if um.config.MethodScoping {
+		method := r.Method
+		if method == "" {
+			method = http.MethodGet // net/http treats an empty method as GET
+		}
+		scope = um.config.MethodQualifiedScope(scope, method)
+	}
+	if um.config.VersionFunc != nil {
+		if version := core.NormalizeVersion(um.config.VersionFunc(r)); version != "" {
+			return core.VersionedScope(version, scope)
+		}
+	}
+	return scope
+}
+
+// resourceScope determines the scope of a request from a WithScope override, method scopes,
+// the bot classifier or the configured scope function (if any)
+func (um *UniversalMiddleware) resourceScope(r *http.Request) string {
+	if scope, ok := core.ScopeFromContext(r.Context()); ok {
+		return scope
+	}
+	if scope, ok := um.config.MethodScopes[r.Method]; ok && scope != "" {
+		return scope
+	}
+	if um.config.BotClassifier != nil && um.config.BotClassifier(r) {
+		return um.config.BotScope
+	}
+	if um.config.ScopeFunc != nil {
+		if s := um.config.ScopeFunc(r); s != "" {
+			return s
+		}
+	}
+	return "global"
+}
+
 // toString converts int64 to string
 func toString(n int64) string {
 	return strconv.FormatInt(n, 10)
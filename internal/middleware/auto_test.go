@@ -0,0 +1,55 @@
+// internal/middleware/auto_test.go
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/itsatony/gorly/internal/core"
+	"github.com/itsatony/gorly/stores"
+)
+
+func TestLockoutFollowsConfigClock(t *testing.T) {
+	now := time.Date(2001, 1, 1, 12, 0, 0, 0, time.UTC) // Far behind the wall clock
+	config := &core.Config{
+		Algorithm:     "sliding_window",
+		Limits:        map[string]string{"global": "10/minute"},
+		Lockout:       &core.LockoutConfig{Threshold: 1, BaseDuration: time.Minute, MaxDuration: time.Hour},
+		ExtractorFunc: func(r *http.Request) string { return "alice" },
+		ScopeFunc:     func(r *http.Request) string { return "global" },
+		Clock:         func() time.Time { return now },
+	}
+	store, err := stores.NewMemoryStore(stores.MemoryConfig{})
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	limiter, err := core.NewLimiterWithStore(config, store)
+	if err != nil {
+		t.Fatalf("Failed to create limiter: %v", err)
+	}
+	t.Cleanup(func() { limiter.Close() })
+
+	if _, err := limiter.RecordFailure(context.Background(), "alice"); err != nil {
+		t.Fatalf("RecordFailure failed: %v", err)
+	}
+
+	handler := New(limiter, config).(*UniversalMiddleware).HTTP()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/login", nil))
+
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("X-RateLimit-Lockout") != "true" {
+		t.Fatalf("Expected the lockout to hold on the configured clock, got %d", w.Code)
+	}
+	if got := w.Header().Get("Retry-After"); got != "60" {
+		t.Errorf("Expected Retry-After 60, got %q", got)
+	}
+	if got, want := w.Header().Get("X-RateLimit-Reset"), strconv.FormatInt(now.Add(time.Minute).Unix(), 10); got != want {
+		t.Errorf("Expected X-RateLimit-Reset %s, got %q", want, got)
+	}
+}
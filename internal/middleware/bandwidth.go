@@ -0,0 +1,97 @@
+// internal/middleware/bandwidth.go - Response volume accounting
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/itsatony/gorly/internal/core"
+)
+
+// bandwidthWriter counts response bytes and charges them to the entity's bandwidth budget
+// in chunks, so large downloads don't hit the store on every write
+type bandwidthWriter struct {
+	http.ResponseWriter
+	ctx        context.Context
+	limiter    core.Limiter
+	config     *core.Config
+	entity     string
+	scope      string
+	flushBytes int64
+	pending    int64
+}
+
+// wrapBandwidth wraps w with byte accounting when the request's scope has a bandwidth limit.
+// The returned function charges any remaining bytes and must be called once the handler returns.
+func (um *UniversalMiddleware) wrapBandwidth(w http.ResponseWriter, r *http.Request) (http.ResponseWriter, func()) {
+	scope, _ := r.Context().Value("gorly_scope").(string)
+	entity, _ := r.Context().Value("gorly_entity").(string)
+	if scope == "" || !um.config.HasBandwidthLimit(scope) {
+		return w, func() {}
+	}
+
+	flushBytes := um.config.BandwidthFlushBytes
+	if flushBytes <= 0 {
+		flushBytes = core.DefaultBandwidthFlushBytes
+	}
+
+	bw := &bandwidthWriter{
+		ResponseWriter: w,
+		// Bytes already sent must be charged even if the client goes away
+		ctx:        context.WithoutCancel(r.Context()),
+		limiter:    um.limiter,
+		config:     um.config,
+		entity:     entity,
+		scope:      scope,
+		flushBytes: flushBytes,
+	}
+	return bw, bw.charge
+}
+
+// chargeBandwidth charges a response the framework counted itself to the bandwidth budget of
+// the request's scope, for frameworks whose writer cannot be wrapped
+func (um *UniversalMiddleware) chargeBandwidth(r *http.Request, bytes int64) {
+	if bytes <= 0 {
+		return
+	}
+	w, _ := um.wrapBandwidth(nil, r)
+	if bw, ok := w.(*bandwidthWriter); ok {
+		bw.pending = bytes
+		bw.charge()
+	}
+}
+
+// Write counts the bytes written and charges them once the flush threshold is reached
+func (bw *bandwidthWriter) Write(p []byte) (int, error) {
+	n, err := bw.ResponseWriter.Write(p)
+	bw.pending += int64(n)
+	if bw.pending >= bw.flushBytes {
+		bw.charge()
+	}
+	return n, err
+}
+
+// Flush implements http.Flusher for streaming responses
+func (bw *bandwidthWriter) Flush() {
+	if flusher, ok := bw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap returns the underlying ResponseWriter for http.ResponseController
+func (bw *bandwidthWriter) Unwrap() http.ResponseWriter {
+	return bw.ResponseWriter
+}
+
+// charge sends pending bytes to the store
+func (bw *bandwidthWriter) charge() {
+	if bw.pending == 0 {
+		return
+	}
+
+	bytes := bw.pending
+	bw.pending = 0
+	if _, err := bw.limiter.ConsumeBandwidth(bw.ctx, bw.entity, bw.scope, bytes); err != nil && bw.config.ErrorHandler != nil {
+		bw.config.ErrorHandler(err)
+	}
+}
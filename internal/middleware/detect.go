@@ -0,0 +1,133 @@
+// internal/middleware/detect.go - Framework detection diagnostics
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+// String returns the name of a framework type
+func (f FrameworkType) String() string {
+	switch f {
+	case FrameworkGin:
+		return "gin"
+	case FrameworkEcho:
+		return "echo"
+	case FrameworkFiber:
+		return "fiber"
+	case FrameworkChi:
+		return "chi"
+	case FrameworkHTTP:
+		return "http"
+	case FrameworkAuto:
+		return "auto"
+	default:
+		return fmt.Sprintf("FrameworkType(%d)", int(f))
+	}
+}
+
+// AdapterType returns the Go type of the middleware For returns for a framework,
+// i.e. what its result must be type-asserted to
+func AdapterType(framework FrameworkType) string {
+	switch framework {
+	case FrameworkGin:
+		return "func(interface {})"
+	case FrameworkEcho:
+		return "func(interface {}) interface {}"
+	case FrameworkFiber:
+		return "func(interface {}) error"
+	case FrameworkAuto:
+		return "*middleware.UniversalMiddleware"
+	default:
+		return "func(http.Handler) http.Handler"
+	}
+}
+
+// Detection reports which middleware adapter fits an application value and why
+type Detection struct {
+	Framework FrameworkType `json:"-"`
+	Name      string        `json:"framework"`
+	Type      string        `json:"type"`    // Go type of the inspected value
+	Reason    string        `json:"reason"`  // Why the framework was chosen
+	Adapter   string        `json:"adapter"` // Go type For(Framework) returns
+}
+
+// String describes a detection in one line, e.g. for a debug log
+func (d Detection) String() string {
+	return fmt.Sprintf("%s middleware %s: %s", d.Name, d.Adapter, d.Reason)
+}
+
+// frameworkPackages maps the import paths of supported frameworks to their adapters.
+// Prefixes also match major versions such as github.com/go-chi/chi/v5.
+var frameworkPackages = []struct {
+	prefix    string
+	framework FrameworkType
+}{
+	{"github.com/gin-gonic/gin", FrameworkGin},
+	{"github.com/labstack/echo", FrameworkEcho},
+	{"github.com/gofiber/fiber", FrameworkFiber},
+	{"github.com/go-chi/chi", FrameworkChi},
+}
+
+var handlerType = reflect.TypeOf((*http.Handler)(nil)).Elem()
+
+// Detect inspects an application value, such as a router, engine or context, and reports
+// which middleware adapter it needs. Values are matched by the package declaring their
+// type; anything else gets the net/http adapter, with the reason saying why.
+func Detect(app interface{}) Detection {
+	d := detect(app)
+	d.Name = d.Framework.String()
+	d.Adapter = AdapterType(d.Framework)
+	return d
+}
+
+func detect(app interface{}) Detection {
+	if app == nil {
+		return Detection{Framework: FrameworkHTTP, Type: "<nil>", Reason: "nothing to inspect, defaulting to net/http"}
+	}
+
+	t := reflect.TypeOf(app)
+	d := Detection{Framework: FrameworkHTTP, Type: t.String()}
+	pkg := declaringPackage(t)
+	if framework, ok := frameworkForPackage(pkg); ok {
+		d.Framework = framework
+		d.Reason = fmt.Sprintf("%s is declared in %s", t, pkg)
+		return d
+	}
+
+	switch {
+	case t.Implements(handlerType):
+		d.Reason = fmt.Sprintf("%s implements http.Handler", t)
+	case pkg != "":
+		d.Reason = fmt.Sprintf("%s is declared in %s, which is not a supported framework, defaulting to net/http", t, pkg)
+	default:
+		d.Reason = fmt.Sprintf("%s is not declared by a supported framework, defaulting to net/http", t)
+	}
+	return d
+}
+
+// frameworkForPackage returns the framework an import path belongs to
+func frameworkForPackage(pkg string) (FrameworkType, bool) {
+	for _, known := range frameworkPackages {
+		if pkg == known.prefix || strings.HasPrefix(pkg, known.prefix+"/") {
+			return known.framework, true
+		}
+	}
+	return FrameworkHTTP, false
+}
+
+// declaringPackage returns the import path of the package declaring t, looking through
+// pointers, slices and maps; "" for unnamed types like func literals
+func declaringPackage(t reflect.Type) string {
+	for t.Name() == "" {
+		switch t.Kind() {
+		case reflect.Pointer, reflect.Slice, reflect.Array, reflect.Map, reflect.Chan:
+			t = t.Elem()
+		default:
+			return ""
+		}
+	}
+	return t.PkgPath()
+}
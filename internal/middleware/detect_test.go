@@ -0,0 +1,60 @@
+// internal/middleware/detect_test.go
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestFrameworkForPackage(t *testing.T) {
+	tests := []struct {
+		pkg   string
+		want  FrameworkType
+		known bool
+	}{
+		{"github.com/gin-gonic/gin", FrameworkGin, true},
+		{"github.com/labstack/echo/v4", FrameworkEcho, true},
+		{"github.com/gofiber/fiber/v2", FrameworkFiber, true},
+		{"github.com/go-chi/chi/v5", FrameworkChi, true},
+		{"github.com/go-chi/chiextra", FrameworkHTTP, false},
+		{"net/http", FrameworkHTTP, false},
+		{"", FrameworkHTTP, false},
+	}
+	for _, tt := range tests {
+		if got, ok := frameworkForPackage(tt.pkg); got != tt.want || ok != tt.known {
+			t.Errorf("frameworkForPackage(%q) = %s, %t; want %s, %t", tt.pkg, got, ok, tt.want, tt.known)
+		}
+	}
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		app    interface{}
+		reason string
+	}{
+		{http.NewServeMux(), "*http.ServeMux implements http.Handler"},
+		{&strings.Builder{}, "declared in strings, which is not a supported framework"},
+		{func() {}, "func() is not declared by a supported framework"},
+		{nil, "nothing to inspect"},
+	}
+	for _, tt := range tests {
+		d := Detect(tt.app)
+		if d.Framework != FrameworkHTTP || d.Name != "http" || d.Adapter != "func(http.Handler) http.Handler" {
+			t.Errorf("Detect(%T): expected the net/http adapter, got %+v", tt.app, d)
+		}
+		if !strings.Contains(d.Reason, tt.reason) {
+			t.Errorf("Detect(%T): expected %q in the reason, got %q", tt.app, tt.reason, d.Reason)
+		}
+	}
+}
+
+func TestAdapterTypeMatchesFor(t *testing.T) {
+	um := &UniversalMiddleware{}
+	for _, framework := range []FrameworkType{FrameworkGin, FrameworkEcho, FrameworkFiber, FrameworkChi, FrameworkHTTP, FrameworkAuto} {
+		if got := fmt.Sprintf("%T", um.For(framework)); got != AdapterType(framework) {
+			t.Errorf("AdapterType(%s) = %q, but For returns %q", framework, AdapterType(framework), got)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+// Package outbound holds the HTTP client of outbound calls, shared by the root package and
+// the experimental subpackages so SetHTTPClient reaches all of them.
+package outbound
+
+import (
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultTimeout bounds outbound calls of components without their own timeout
+const DefaultTimeout = 10 * time.Second
+
+// packageClient is the client set with SetClient; nil means http.DefaultClient
+var packageClient atomic.Pointer[http.Client]
+
+// SetClient sets the client of components that were not given their own; nil restores http.DefaultClient
+func SetClient(client *http.Client) {
+	packageClient.Store(client)
+}
+
+// PackageClient returns the client set with SetClient, or http.DefaultClient
+func PackageClient() *http.Client {
+	if client := packageClient.Load(); client != nil {
+		return client
+	}
+	return http.DefaultClient
+}
+
+// Client returns a component's own client, or the package client if it has none.
+// Components call it for every request, so SetClient also reaches components created before it.
+func Client(client *http.Client) *http.Client {
+	if client != nil {
+		return client
+	}
+	return PackageClient()
+}
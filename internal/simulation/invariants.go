@@ -0,0 +1,73 @@
+// internal/simulation/invariants.go
+package simulation
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// Violation describes a breach of a global invariant
+type Violation struct {
+	Entity  string
+	Scope   string
+	From    time.Time
+	To      time.Time
+	Allowed int
+	Max     int64
+}
+
+// Error implements error
+func (v *Violation) Error() string {
+	return fmt.Sprintf("%s/%s: %d requests allowed between %s and %s, at most %d permitted",
+		v.Entity, v.Scope, v.Allowed, v.From.Format(time.RFC3339Nano), v.To.Format(time.RFC3339Nano), v.Max)
+}
+
+// allowedTimes groups the times of allowed decisions by entity and scope, in time order
+func allowedTimes(decisions []Decision) map[[2]string][]time.Time {
+	times := make(map[[2]string][]time.Time)
+	for _, d := range decisions {
+		if d.Allowed {
+			key := [2]string{d.Entity, d.Scope}
+			times[key] = append(times[key], d.Time)
+		}
+	}
+	return times
+}
+
+// CheckWindowLimit verifies that no window of the given length, ending at any allowed
+// request, contains more than limit allowed requests of an entity and scope. This is
+// the guarantee of the sliding window algorithm, whose window includes both ends.
+func CheckWindowLimit(decisions []Decision, limit int64, window time.Duration) error {
+	for key, times := range allowedTimes(decisions) {
+		start := 0
+		for end, t := range times {
+			for times[start].Before(t.Add(-window)) {
+				start++
+			}
+			if allowed := end - start + 1; int64(allowed) > limit {
+				return &Violation{Entity: key[0], Scope: key[1], From: times[start], To: t, Allowed: allowed, Max: limit}
+			}
+		}
+	}
+	return nil
+}
+
+// CheckTokenBucket verifies that between any two allowed requests of an entity and scope
+// no more were allowed than a full bucket plus the tokens refilled in between. This is
+// the guarantee of the token bucket algorithm, which refills limit tokens per window.
+func CheckTokenBucket(decisions []Decision, limit int64, window time.Duration) error {
+	rate := float64(limit) / window.Seconds()
+	for key, times := range allowedTimes(decisions) {
+		for from := range times {
+			for to := from; to < len(times); to++ {
+				elapsed := times[to].Sub(times[from]).Seconds()
+				max := int64(math.Floor(float64(limit) + rate*elapsed + 1e-9))
+				if allowed := to - from + 1; int64(allowed) > max {
+					return &Violation{Entity: key[0], Scope: key[1], From: times[from], To: times[to], Allowed: allowed, Max: max}
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,254 @@
+// internal/simulation/simulation.go
+//
+// Package simulation runs several limiter instances against one shared fake store
+// under a virtual clock. Concurrent requests are interleaved by a seeded scheduler,
+// one store operation at a time, so every run is reproducible from its seed and
+// global invariants can be asserted without a real cluster.
+package simulation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/itsatony/gorly/internal/core"
+)
+
+// Interleaving is the granularity at which concurrent requests are interleaved
+type Interleaving int
+
+const (
+	// InterleaveOperations switches instances before every store operation, so a
+	// request's reads and writes may be separated by other instances' requests
+	InterleaveOperations Interleaving = iota
+
+	// InterleaveRequests runs each request to completion before the next one starts,
+	// in a random order; this models a store that evaluates checks atomically
+	InterleaveRequests
+)
+
+// DefaultStart is the virtual time a simulation starts at unless configured otherwise
+var DefaultStart = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Config configures a simulation
+type Config struct {
+	// Instances is the number of limiter instances sharing the store (default: 3)
+	Instances int
+
+	// Limiter configures every instance. Store and Clock are replaced by the simulation's.
+	Limiter *core.Config
+
+	// Seed makes the scheduling order reproducible
+	Seed int64
+
+	// Interleaving granularity (default: InterleaveOperations)
+	Interleaving Interleaving
+
+	// Start is the initial virtual time (default: DefaultStart)
+	Start time.Time
+}
+
+// Decision is one rate limit decision made during a simulation
+type Decision struct {
+	Instance int
+	Entity   string
+	Scope    string
+	Time     time.Time
+	Allowed  bool
+}
+
+// Simulation is a set of limiter instances sharing a store and a virtual clock
+type Simulation struct {
+	config    Config
+	clock     *Clock
+	store     *Store
+	instances []core.Limiter
+	rng       *rand.Rand
+	decisions []Decision
+}
+
+// New creates a simulation with its instances
+func New(config Config) (*Simulation, error) {
+	if config.Limiter == nil {
+		return nil, errors.New("simulation requires a limiter config")
+	}
+	if config.Instances <= 0 {
+		config.Instances = 3
+	}
+	if config.Start.IsZero() {
+		config.Start = DefaultStart
+	}
+
+	clock := NewClock(config.Start)
+	sim := &Simulation{
+		config: config,
+		clock:  clock,
+		store:  NewStore(clock),
+		rng:    rand.New(rand.NewSource(config.Seed)),
+	}
+
+	for i := 0; i < config.Instances; i++ {
+		limiterConfig := *config.Limiter
+		limiterConfig.Clock = clock.Now
+		limiter, err := core.NewLimiterWithStore(&limiterConfig, sim.store)
+		if err != nil {
+			sim.Close()
+			return nil, fmt.Errorf("failed to create instance %d: %w", i, err)
+		}
+		sim.instances = append(sim.instances, limiter)
+	}
+	return sim, nil
+}
+
+// Clock returns the virtual clock
+func (s *Simulation) Clock() *Clock {
+	return s.clock
+}
+
+// Store returns the shared store
+func (s *Simulation) Store() *Store {
+	return s.store
+}
+
+// Instance returns the limiter of an instance, e.g. to inspect or reconfigure it
+func (s *Simulation) Instance(i int) core.Limiter {
+	return s.instances[i]
+}
+
+// Advance moves the virtual clock forward
+func (s *Simulation) Advance(d time.Duration) {
+	s.clock.Advance(d)
+}
+
+// Round sends perInstance concurrent requests for an entity and scope through every
+// instance and returns once all of them are decided. The requests are interleaved by
+// the scheduler and all happen at the current virtual time.
+func (s *Simulation) Round(ctx context.Context, entity, scope string, perInstance int) error {
+	var tasks []*task
+	for i := range s.instances {
+		for j := 0; j < perInstance; j++ {
+			tasks = append(tasks, &task{
+				instance: i,
+				entity:   entity,
+				scope:    scope,
+				resume:   make(chan struct{}),
+			})
+		}
+	}
+	return s.run(ctx, tasks)
+}
+
+// Decisions returns every decision made so far, in the order they completed
+func (s *Simulation) Decisions() []Decision {
+	return append([]Decision(nil), s.decisions...)
+}
+
+// Allowed returns how many requests for an entity and scope were allowed
+func (s *Simulation) Allowed(entity, scope string) int {
+	allowed := 0
+	for _, d := range s.decisions {
+		if d.Allowed && d.Entity == entity && d.Scope == scope {
+			allowed++
+		}
+	}
+	return allowed
+}
+
+// Close closes every instance
+func (s *Simulation) Close() error {
+	var errs []error
+	for _, limiter := range s.instances {
+		if err := limiter.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// task is one simulated request
+type task struct {
+	instance int
+	entity   string
+	scope    string
+
+	resume chan struct{}
+	events chan<- taskEvent
+}
+
+// taskEvent reports that the running task paused at a scheduling point or finished
+type taskEvent struct {
+	finished bool
+	decision Decision
+	err      error
+}
+
+// taskKey stores the running task in the context handed to the store
+type taskKey struct{}
+
+// yield pauses the task of a context at a scheduling point until the scheduler resumes it.
+// Operations outside a simulated request never yield.
+func yield(ctx context.Context) {
+	t, ok := ctx.Value(taskKey{}).(*task)
+	if !ok {
+		return
+	}
+	t.events <- taskEvent{}
+	<-t.resume
+}
+
+// run executes tasks one at a time. Each task runs until its next scheduling point,
+// then the scheduler picks which paused task continues, so only one goroutine ever
+// touches the store and the order depends on nothing but the seed.
+func (s *Simulation) run(ctx context.Context, tasks []*task) error {
+	events := make(chan taskEvent)
+	for _, t := range tasks {
+		t.events = events
+		go s.execute(ctx, t)
+	}
+
+	var errs []error
+	paused := tasks
+	for len(paused) > 0 {
+		i := s.rng.Intn(len(paused))
+		t := paused[i]
+
+		t.resume <- struct{}{}
+		event := <-events
+		if !event.finished {
+			continue
+		}
+
+		paused = append(paused[:i], paused[i+1:]...)
+		if event.err != nil {
+			errs = append(errs, event.err)
+			continue
+		}
+		s.decisions = append(s.decisions, event.decision)
+	}
+	return errors.Join(errs...)
+}
+
+// execute performs a task's rate limit check once the scheduler first resumes it
+func (s *Simulation) execute(ctx context.Context, t *task) {
+	<-t.resume
+
+	checkCtx := ctx
+	if s.config.Interleaving == InterleaveOperations {
+		checkCtx = context.WithValue(ctx, taskKey{}, t)
+	}
+	result, err := s.instances[t.instance].Check(checkCtx, t.entity, t.scope)
+
+	event := taskEvent{finished: true, err: err}
+	if err == nil {
+		event.decision = Decision{
+			Instance: t.instance,
+			Entity:   t.entity,
+			Scope:    t.scope,
+			Time:     s.clock.Now(),
+			Allowed:  result.Allowed,
+		}
+	}
+	t.events <- event
+}
@@ -0,0 +1,137 @@
+// internal/simulation/simulation_test.go
+package simulation
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/itsatony/gorly/internal/core"
+)
+
+func newTestSimulation(t *testing.T, algorithm string, seed int64, interleaving Interleaving) *Simulation {
+	t.Helper()
+	sim, err := New(Config{
+		Instances: 4,
+		Limiter: &core.Config{
+			Algorithm: algorithm,
+			Limits:    map[string]string{"global": "10/minute"},
+		},
+		Seed:         seed,
+		Interleaving: interleaving,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create simulation: %v", err)
+	}
+	t.Cleanup(func() { sim.Close() })
+	return sim
+}
+
+// runTraffic sends bursts through every instance while the clock moves on
+func runTraffic(t *testing.T, sim *Simulation) {
+	t.Helper()
+	ctx := context.Background()
+	for round := 0; round < 12; round++ {
+		if err := sim.Round(ctx, "user-1", "global", 3); err != nil {
+			t.Fatalf("Round %d failed: %v", round, err)
+		}
+		sim.Advance(7 * time.Second)
+	}
+}
+
+func TestSimulationAtomicChecksHoldInvariants(t *testing.T) {
+	for _, tc := range []struct {
+		algorithm string
+		check     func([]Decision, int64, time.Duration) error
+	}{
+		{"sliding_window", CheckWindowLimit},
+		{"token_bucket", CheckTokenBucket},
+	} {
+		t.Run(tc.algorithm, func(t *testing.T) {
+			for seed := int64(1); seed <= 20; seed++ {
+				sim := newTestSimulation(t, tc.algorithm, seed, InterleaveRequests)
+				runTraffic(t, sim)
+
+				if err := tc.check(sim.Decisions(), 10, time.Minute); err != nil {
+					t.Fatalf("Seed %d: %v", seed, err)
+				}
+				if sim.Allowed("user-1", "global") < 10 {
+					t.Errorf("Seed %d: expected at least the first window's 10 requests to be allowed, got %d",
+						seed, sim.Allowed("user-1", "global"))
+				}
+			}
+		})
+	}
+}
+
+func TestSimulationIsDeterministic(t *testing.T) {
+	first := newTestSimulation(t, "sliding_window", 42, InterleaveOperations)
+	second := newTestSimulation(t, "sliding_window", 42, InterleaveOperations)
+	runTraffic(t, first)
+	runTraffic(t, second)
+
+	if !reflect.DeepEqual(first.Decisions(), second.Decisions()) {
+		t.Error("Expected identical decisions for the same seed")
+	}
+	if first.Store().Operations() != second.Store().Operations() {
+		t.Errorf("Expected identical store operations, got %d and %d",
+			first.Store().Operations(), second.Store().Operations())
+	}
+}
+
+// Both algorithms read, modify and write their state with separate store operations,
+// so instances interleaving between the read and the write lose each other's updates.
+// The harness must surface this; atomic store-side evaluation is what prevents it.
+func TestSimulationDetectsLostUpdates(t *testing.T) {
+	for seed := int64(1); seed <= 20; seed++ {
+		sim := newTestSimulation(t, "sliding_window", seed, InterleaveOperations)
+		runTraffic(t, sim)
+
+		var violation *Violation
+		if err := CheckWindowLimit(sim.Decisions(), 10, time.Minute); errors.As(err, &violation) {
+			if violation.Allowed <= int(violation.Max) {
+				t.Errorf("Violation reports %d allowed within a limit of %d", violation.Allowed, violation.Max)
+			}
+			return
+		}
+	}
+	t.Error("Expected interleaved read-modify-write checks to exceed the limit for some seed")
+}
+
+func TestCheckWindowLimit(t *testing.T) {
+	start := DefaultStart
+	decisions := []Decision{
+		{Entity: "e", Scope: "s", Time: start, Allowed: true},
+		{Entity: "e", Scope: "s", Time: start.Add(30 * time.Second), Allowed: true},
+		{Entity: "e", Scope: "s", Time: start.Add(40 * time.Second), Allowed: false},
+		{Entity: "e", Scope: "s", Time: start.Add(61 * time.Second), Allowed: true},
+	}
+	if err := CheckWindowLimit(decisions, 2, time.Minute); err != nil {
+		t.Errorf("Expected no violation, got %v", err)
+	}
+
+	decisions = append(decisions, Decision{Entity: "e", Scope: "s", Time: start.Add(62 * time.Second), Allowed: true})
+	if err := CheckWindowLimit(decisions, 2, time.Minute); err == nil {
+		t.Error("Expected three requests within one window to violate a limit of 2")
+	}
+}
+
+func TestStoreExpiresOnVirtualClock(t *testing.T) {
+	ctx := context.Background()
+	clock := NewClock(DefaultStart)
+	store := NewStore(clock)
+
+	if err := store.Set(ctx, "k", []byte("v"), time.Minute); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	clock.Advance(59 * time.Second)
+	if ok, _ := store.Exists(ctx, "k"); !ok {
+		t.Error("Expected key to exist before its expiry")
+	}
+	clock.Advance(time.Second)
+	if _, err := store.Get(ctx, "k"); err == nil {
+		t.Error("Expected key to expire on the virtual clock")
+	}
+}
@@ -0,0 +1,183 @@
+// internal/simulation/store.go
+package simulation
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"sync"
+	"time"
+
+	"github.com/itsatony/gorly/stores"
+)
+
+// Store is an in-memory store shared by every simulated instance. Keys expire on the
+// virtual clock, and every operation is a scheduling point: before it runs, the
+// scheduler may switch to another instance, which exposes read-modify-write races.
+type Store struct {
+	clock *Clock
+
+	mu    sync.Mutex
+	items map[string]storeItem
+	ops   int64
+}
+
+// storeItem is a stored value and its expiry on the virtual clock
+type storeItem struct {
+	value     []byte
+	expiresAt time.Time // zero for keys without expiry
+}
+
+// NewStore creates an empty store expiring keys on clock
+func NewStore(clock *Clock) *Store {
+	return &Store{
+		clock: clock,
+		items: make(map[string]storeItem),
+	}
+}
+
+// Operations returns how many operations the store has served
+func (s *Store) Operations() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.ops
+}
+
+// begin yields to the scheduler and then locks the store for one operation
+func (s *Store) begin(ctx context.Context) {
+	yield(ctx)
+	s.mu.Lock()
+	s.ops++
+}
+
+// lookup returns a live item, dropping it if it has expired. The store must be locked.
+func (s *Store) lookup(key string) (storeItem, bool) {
+	item, ok := s.items[key]
+	if !ok {
+		return storeItem{}, false
+	}
+	if !item.expiresAt.IsZero() && !s.clock.Now().Before(item.expiresAt) {
+		delete(s.items, key)
+		return storeItem{}, false
+	}
+	return item, true
+}
+
+// put stores a value. The store must be locked.
+func (s *Store) put(key string, value []byte, expiration time.Duration) {
+	item := storeItem{value: append([]byte(nil), value...)}
+	if expiration > 0 {
+		item.expiresAt = s.clock.Now().Add(expiration)
+	}
+	s.items[key] = item
+}
+
+// Get retrieves a value, failing with a "key not found" store error for missing keys
+func (s *Store) Get(ctx context.Context, key string) ([]byte, error) {
+	s.begin(ctx)
+	defer s.mu.Unlock()
+
+	item, ok := s.lookup(key)
+	if !ok {
+		return nil, stores.NewStoreError("store", "key not found", nil)
+	}
+	return append([]byte(nil), item.value...), nil
+}
+
+// Set stores a value with an optional expiration
+func (s *Store) Set(ctx context.Context, key string, value []byte, expiration time.Duration) error {
+	s.begin(ctx)
+	defer s.mu.Unlock()
+
+	s.put(key, value, expiration)
+	return nil
+}
+
+// IncrementBy atomically adds amount to a counter, keeping the expiry of existing counters
+func (s *Store) IncrementBy(ctx context.Context, key string, amount int64, expiration time.Duration) (int64, error) {
+	s.begin(ctx)
+	defer s.mu.Unlock()
+
+	item, ok := s.lookup(key)
+	var n int64
+	if ok && len(item.value) == 8 {
+		n = int64(binary.BigEndian.Uint64(item.value))
+	}
+	n += amount
+
+	value := make([]byte, 8)
+	binary.BigEndian.PutUint64(value, uint64(n))
+	if ok {
+		item.value = value
+		s.items[key] = item
+	} else {
+		s.put(key, value, expiration)
+	}
+	return n, nil
+}
+
+// Delete removes a key
+func (s *Store) Delete(ctx context.Context, key string) error {
+	s.begin(ctx)
+	defer s.mu.Unlock()
+
+	delete(s.items, key)
+	return nil
+}
+
+// Exists reports whether a live key exists
+func (s *Store) Exists(ctx context.Context, key string) (bool, error) {
+	s.begin(ctx)
+	defer s.mu.Unlock()
+
+	_, ok := s.lookup(key)
+	return ok, nil
+}
+
+// SetNX stores a value only if the key does not exist
+func (s *Store) SetNX(ctx context.Context, key string, value []byte, expiration time.Duration) (bool, error) {
+	s.begin(ctx)
+	defer s.mu.Unlock()
+
+	if _, ok := s.lookup(key); ok {
+		return false, nil
+	}
+	s.put(key, value, expiration)
+	return true, nil
+}
+
+// CompareAndExpire resets the expiration of a key only if it holds value
+func (s *Store) CompareAndExpire(ctx context.Context, key string, value []byte, expiration time.Duration) (bool, error) {
+	s.begin(ctx)
+	defer s.mu.Unlock()
+
+	item, ok := s.lookup(key)
+	if !ok || !bytes.Equal(item.value, value) {
+		return false, nil
+	}
+	s.put(key, value, expiration)
+	return true, nil
+}
+
+// CompareAndDelete removes a key only if it holds value
+func (s *Store) CompareAndDelete(ctx context.Context, key string, value []byte) (bool, error) {
+	s.begin(ctx)
+	defer s.mu.Unlock()
+
+	item, ok := s.lookup(key)
+	if !ok || !bytes.Equal(item.value, value) {
+		return false, nil
+	}
+	delete(s.items, key)
+	return true, nil
+}
+
+// Health always succeeds
+func (s *Store) Health(ctx context.Context) error {
+	return nil
+}
+
+// Close is a no-op; the store outlives the instances sharing it
+func (s *Store) Close() error {
+	return nil
+}
@@ -0,0 +1,85 @@
+// introspection_test.go
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIntrospectTokens(t *testing.T) {
+	var calls int
+	introspector := TokenIntrospectorFunc(func(ctx context.Context, token string) (*TokenInfo, error) {
+		calls++
+		switch token {
+		case "alice-laptop", "alice-phone":
+			return &TokenInfo{Subject: "alice", Tier: "premium", ExpiresAt: time.Now().Add(time.Hour)}, nil
+		}
+		return nil, nil
+	})
+	base, err := New().
+		TierLimits(map[string]string{"free": "1/minute", "premium": "3/minute"}).
+		IntrospectTokens(introspector, time.Minute).
+		Build()
+	if err != nil {
+		t.Fatalf("Failed to build limiter: %v", err)
+	}
+	config := DefaultObservabilityConfig()
+	config.EnableLogging = false
+	limiter := NewObservableLimiter(base, config)
+	defer limiter.Close()
+
+	handler := limiter.For(HTTP).(func(http.Handler) http.Handler)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	send := func(token string) int {
+		r := httptest.NewRequest(http.MethodGet, "/v1/items", nil)
+		if token != "" {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	// Both of alice's tokens draw from her premium budget
+	for i, token := range []string{"alice-laptop", "alice-phone", "alice-laptop", "alice-phone"} {
+		want := http.StatusOK
+		if i == 3 {
+			want = http.StatusTooManyRequests
+		}
+		if code := send(token); code != want {
+			t.Errorf("Request %d with %s: expected %d, got %d", i+1, token, want, code)
+		}
+	}
+	if calls != 2 {
+		t.Errorf("Expected each token to be introspected once, got %d calls", calls)
+	}
+
+	// Inactive tokens fall back to the extractor, here the client IP on the free tier
+	if code := send("forged"); code != http.StatusOK {
+		t.Errorf("Expected an inactive token to be limited by IP, got %d", code)
+	}
+	if code := send(""); code != http.StatusTooManyRequests {
+		t.Errorf("Expected the IP's free budget to be spent, got %d", code)
+	}
+
+	exposition := convertToPrometheusFormat(limiter.GetMetrics(), prometheusOptions{})
+	for _, want := range []string{
+		`gorly_token_introspections_total{result="local_hit"} 2`,
+		`gorly_token_introspections_total{result="miss"} 3`,
+		`gorly_token_introspection_inactive_total 1`,
+	} {
+		if !strings.Contains(exposition, want) {
+			t.Errorf("Expected %q in the exposition", want)
+		}
+	}
+}
+
+func TestSharedIntrospectionCacheKey(t *testing.T) {
+	introspector := TokenIntrospectorFunc(func(ctx context.Context, token string) (*TokenInfo, error) { return nil, nil })
+	if _, err := New().Limit("global", "1/minute").IntrospectTokens(introspector, 0).SharedIntrospectionCache([]byte("short")).Build(); err == nil {
+		t.Error("Expected a short shared cache key to be rejected")
+	}
+}
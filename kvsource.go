@@ -0,0 +1,656 @@
+// kvsource.go - Config sources backed by Consul KV and etcd, layered by key prefix
+package ratelimit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// KVConfigSource reads configuration from a key-value store. Every key under a prefix
+// sets the config value at its path relative to the prefix, e.g. "gorly/base/redis/address"
+// under prefix "gorly/base" sets redis.address; values are decoded as YAML, so numbers and
+// booleans keep their type and a key holding a whole JSON or YAML document sets an object.
+// The prefix key itself may hold the complete document. Prefixes are layered in order,
+// later prefixes overriding earlier ones, e.g. "gorly/global" then "gorly/eu-west".
+//
+// A KVConfigSource is both a ConfigSource for a ConfigLoader and a HotReloadConfigSource,
+// whose Watch delivers a new configuration as soon as the store reports a change.
+type KVConfigSource struct {
+	backend  kvBackend
+	prefixes []string
+	required bool
+
+	retryDelay time.Duration // First wait after a failed watch, doubling up to maxKVRetryDelay
+}
+
+// kvBackend is the API of one key-value store
+type kvBackend interface {
+	// read returns the value of every key under the prefix, keyed by the path relative to it
+	read(ctx context.Context, prefix string) (map[string][]byte, error)
+
+	// wait blocks until a key under the prefix changes after the last read or wait
+	wait(ctx context.Context, prefix string) error
+
+	// kind names the store in errors
+	kind() string
+}
+
+const (
+	defaultKVRetryDelay = time.Second
+	maxKVRetryDelay     = 30 * time.Second
+)
+
+// ConsulConfig configures a Consul KV config source
+type ConsulConfig struct {
+	Address    string   // e.g. "http://consul:8500"
+	Prefixes   []string // Layered in order, later prefixes overriding earlier ones
+	Token      string   // ACL token, sent as X-Consul-Token
+	Datacenter string   // Default: the agent's datacenter
+
+	// WaitTime bounds each blocking query of Watch. Default: 5m
+	WaitTime time.Duration
+
+	HTTPClient *http.Client // Default: the client set with SetHTTPClient
+	Required   bool         // Fail LoadFromMultipleSources if the source fails
+}
+
+// EtcdConfig configures an etcd config source, using the JSON gateway of etcd v3
+type EtcdConfig struct {
+	Endpoint string   // e.g. "http://etcd:2379"
+	Prefixes []string // Layered in order, later prefixes overriding earlier ones
+
+	// Username and Password authenticate against etcd's auth API; leave them empty if
+	// authentication is disabled or done by the client, e.g. with mTLS
+	Username string
+	Password string
+
+	HTTPClient *http.Client // Default: the client set with SetHTTPClient
+	Required   bool         // Fail LoadFromMultipleSources if the source fails
+}
+
+// NewConsulConfigSource creates a config source reading the prefixes from Consul KV and
+// watching them with blocking queries
+// Example: ratelimit.NewConsulConfigSource(ratelimit.ConsulConfig{Address: "http://consul:8500", Prefixes: []string{"gorly/global", "gorly/eu-west"}})
+func NewConsulConfigSource(config ConsulConfig) *KVConfigSource {
+	if config.WaitTime <= 0 {
+		config.WaitTime = 5 * time.Minute
+	}
+	return &KVConfigSource{
+		backend: &consulBackend{
+			config:  config,
+			address: strings.TrimSuffix(config.Address, "/"),
+			indexes: make(map[string]uint64),
+		},
+		prefixes:   normalizeKVPrefixes(config.Prefixes),
+		required:   config.Required,
+		retryDelay: defaultKVRetryDelay,
+	}
+}
+
+// NewEtcdConfigSource creates a config source reading the prefixes from etcd and watching them
+// Example: ratelimit.NewEtcdConfigSource(ratelimit.EtcdConfig{Endpoint: "http://etcd:2379", Prefixes: []string{"gorly/global"}})
+func NewEtcdConfigSource(config EtcdConfig) *KVConfigSource {
+	return &KVConfigSource{
+		backend: &etcdBackend{
+			config:    config,
+			endpoint:  strings.TrimSuffix(config.Endpoint, "/"),
+			revisions: make(map[string]int64),
+		},
+		prefixes:   normalizeKVPrefixes(config.Prefixes),
+		required:   config.Required,
+		retryDelay: defaultKVRetryDelay,
+	}
+}
+
+// normalizeKVPrefixes strips the slashes around each prefix
+func normalizeKVPrefixes(prefixes []string) []string {
+	normalized := make([]string, 0, len(prefixes))
+	for _, prefix := range prefixes {
+		if prefix = strings.Trim(prefix, "/"); prefix != "" {
+			normalized = append(normalized, prefix)
+		}
+	}
+	return normalized
+}
+
+// Load implements ConfigSource. Strict checking and environment expansion apply as for config files.
+func (s *KVConfigSource) Load(loader *ConfigLoader) (*Config, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultOutboundTimeout)
+	defer cancel()
+
+	raw, err := s.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(raw) == 0 {
+		return nil, fmt.Errorf("no %s keys under %s", s.backend.kind(), strings.Join(s.prefixes, ", "))
+	}
+	if err := loader.prepareRaw(raw); err != nil {
+		return nil, err
+	}
+	return loader.parseConfig(raw)
+}
+
+// IsRequired implements ConfigSource
+func (s *KVConfigSource) IsRequired() bool {
+	return s.required
+}
+
+// GetConfig implements HotReloadConfigSource, reading the layered prefixes as a HotReloadConfig
+func (s *KVConfigSource) GetConfig(ctx context.Context) (*HotReloadConfig, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultOutboundTimeout)
+	defer cancel()
+
+	raw, err := s.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s config: %w", s.backend.kind(), err)
+	}
+	var config HotReloadConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("invalid %s config: %w", s.backend.kind(), err)
+	}
+	return &config, nil
+}
+
+// Watch implements HotReloadConfigSource. It watches every prefix and delivers the layered
+// configuration after each change; failed watches are retried with backoff.
+func (s *KVConfigSource) Watch(ctx context.Context) (<-chan *HotReloadConfig, error) {
+	if len(s.prefixes) == 0 {
+		return nil, fmt.Errorf("%s config source has no prefixes", s.backend.kind())
+	}
+
+	configChan := make(chan *HotReloadConfig, 1)
+	changed := make(chan struct{}, 1)
+	for _, prefix := range s.prefixes {
+		go s.watchPrefix(ctx, prefix, changed)
+	}
+
+	go func() {
+		defer close(configChan)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-changed:
+				config, err := s.GetConfig(ctx)
+				if err != nil {
+					continue
+				}
+				select {
+				case configChan <- config:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return configChan, nil
+}
+
+// watchPrefix signals changed whenever a key under the prefix changes
+func (s *KVConfigSource) watchPrefix(ctx context.Context, prefix string, changed chan<- struct{}) {
+	delay := s.retryDelay
+	for {
+		err := s.backend.wait(ctx, prefix)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(delay):
+			}
+			if delay *= 2; delay > maxKVRetryDelay {
+				delay = maxKVRetryDelay
+			}
+			continue
+		}
+		delay = s.retryDelay
+
+		select {
+		case changed <- struct{}{}:
+		default: // A reload is already pending
+		}
+	}
+}
+
+// Close implements HotReloadConfigSource; watches end with the context given to Watch
+func (s *KVConfigSource) Close() error {
+	return nil
+}
+
+// load reads every prefix and layers the values into one raw config
+func (s *KVConfigSource) load(ctx context.Context) (map[string]interface{}, error) {
+	raw := make(map[string]interface{})
+	for _, prefix := range s.prefixes {
+		values, err := s.backend.read(ctx, prefix)
+		if err != nil {
+			return nil, err
+		}
+
+		// Sorted paths put a document before the keys that refine it
+		paths := make([]string, 0, len(values))
+		for path := range values {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+
+		for _, path := range paths {
+			value, err := decodeKVValue(values[path])
+			if err != nil {
+				return nil, fmt.Errorf("%s key %s/%s: %w", s.backend.kind(), prefix, path, err)
+			}
+			setKVPath(raw, path, value)
+		}
+	}
+	return raw, nil
+}
+
+// decodeKVValue decodes a value as YAML, falling back to the plain string
+func decodeKVValue(data []byte) (interface{}, error) {
+	var value interface{}
+	if err := yaml.Unmarshal(data, &value); err != nil {
+		return string(data), nil
+	}
+	if value == nil {
+		return string(data), nil
+	}
+	return value, nil
+}
+
+// setKVPath merges a value into raw at a slash-separated path; "" is the root
+func setKVPath(raw map[string]interface{}, path string, value interface{}) {
+	if path == "" {
+		if object, ok := value.(map[string]interface{}); ok {
+			mergeKVObjects(raw, object)
+		}
+		return
+	}
+
+	parts := strings.Split(path, "/")
+	node := raw
+	for _, part := range parts[:len(parts)-1] {
+		child, ok := node[part].(map[string]interface{})
+		if !ok {
+			child = make(map[string]interface{})
+			node[part] = child
+		}
+		node = child
+	}
+
+	leaf := parts[len(parts)-1]
+	existing, existingObject := node[leaf].(map[string]interface{})
+	object, isObject := value.(map[string]interface{})
+	if existingObject && isObject {
+		mergeKVObjects(existing, object)
+		return
+	}
+	node[leaf] = value
+}
+
+// mergeKVObjects deep-merges src into dst, src winning on conflicts
+func mergeKVObjects(dst, src map[string]interface{}) {
+	for key, value := range src {
+		existing, existingObject := dst[key].(map[string]interface{})
+		object, isObject := value.(map[string]interface{})
+		if existingObject && isObject {
+			mergeKVObjects(existing, object)
+			continue
+		}
+		dst[key] = value
+	}
+}
+
+// relativeKVPath returns the path of a key relative to the prefix, and false for keys
+// outside it, e.g. "gorly/baseline" under "gorly/base"
+func relativeKVPath(prefix, key string) (string, bool) {
+	key = strings.Trim(key, "/")
+	if key == prefix {
+		return "", true
+	}
+	if !strings.HasPrefix(key, prefix+"/") {
+		return "", false
+	}
+	return key[len(prefix)+1:], true
+}
+
+// kvResponseError describes a failed response of a key-value store
+func kvResponseError(kind string, resp *http.Response) error {
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	return fmt.Errorf("%s returned %d: %s", kind, resp.StatusCode, strings.TrimSpace(string(msg)))
+}
+
+// consulBackend reads Consul KV through its HTTP API
+type consulBackend struct {
+	config  ConsulConfig
+	address string
+
+	mu      sync.Mutex
+	indexes map[string]uint64 // X-Consul-Index of the last response by prefix
+}
+
+func (c *consulBackend) kind() string { return "consul" }
+
+func (c *consulBackend) read(ctx context.Context, prefix string) (map[string][]byte, error) {
+	values, index, err := c.get(ctx, prefix, 0)
+	if err != nil {
+		return nil, err
+	}
+	c.setIndex(prefix, index)
+	return values, nil
+}
+
+// wait runs blocking queries until the index of the prefix moves. Consul may also reset
+// the index to a lower value, which counts as a change.
+func (c *consulBackend) wait(ctx context.Context, prefix string) error {
+	index := c.index(prefix)
+	for {
+		_, newIndex, err := c.get(ctx, prefix, index)
+		if err != nil {
+			return err
+		}
+		c.setIndex(prefix, newIndex)
+		if index != 0 && newIndex != index {
+			return nil
+		}
+		index = newIndex
+	}
+}
+
+// get lists the keys under the prefix, blocking until the index moves past waitIndex if it is set
+func (c *consulBackend) get(ctx context.Context, prefix string, waitIndex uint64) (map[string][]byte, uint64, error) {
+	query := url.Values{"recurse": {"true"}}
+	if c.config.Datacenter != "" {
+		query.Set("dc", c.config.Datacenter)
+	}
+	if waitIndex != 0 {
+		query.Set("index", strconv.FormatUint(waitIndex, 10))
+		query.Set("wait", c.config.WaitTime.String())
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		c.address+"/v1/kv/"+prefix+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to create consul request: %w", err)
+	}
+	if c.config.Token != "" {
+		req.Header.Set("X-Consul-Token", c.config.Token)
+	}
+
+	resp, err := httpClient(c.config.HTTPClient).Do(req)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query consul: %w", err)
+	}
+	defer resp.Body.Close()
+
+	index, _ := strconv.ParseUint(resp.Header.Get("X-Consul-Index"), 10, 64)
+	if resp.StatusCode == http.StatusNotFound {
+		return map[string][]byte{}, index, nil // No keys under the prefix yet
+	}
+	if resp.StatusCode/100 != 2 {
+		return nil, 0, kvResponseError("consul", resp)
+	}
+
+	var entries []struct {
+		Key   string
+		Value []byte // Base64 in the response, decoded by encoding/json
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&entries); err != nil {
+		return nil, 0, fmt.Errorf("invalid consul response: %w", err)
+	}
+
+	values := make(map[string][]byte, len(entries))
+	for _, entry := range entries {
+		if strings.HasSuffix(entry.Key, "/") {
+			continue // Folder
+		}
+		if path, ok := relativeKVPath(prefix, entry.Key); ok {
+			values[path] = entry.Value
+		}
+	}
+	return values, index, nil
+}
+
+func (c *consulBackend) index(prefix string) uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.indexes[prefix]
+}
+
+func (c *consulBackend) setIndex(prefix string, index uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.indexes[prefix] = index
+}
+
+// etcdBackend reads etcd through the JSON gateway of its v3 API
+type etcdBackend struct {
+	config   EtcdConfig
+	endpoint string
+
+	mu        sync.Mutex
+	token     string           // Auth token, fetched on first use and after it expires
+	revisions map[string]int64 // Store revision of the last read by prefix
+}
+
+func (e *etcdBackend) kind() string { return "etcd" }
+
+// etcdRangeRequest selects the keys under a prefix; etcd's JSON API encodes keys as base64
+type etcdRangeRequest struct {
+	Key      []byte `json:"key"`
+	RangeEnd []byte `json:"range_end"`
+}
+
+// etcdKeyRange returns the range of keys under the prefix, including the prefix key
+func etcdKeyRange(prefix string) etcdRangeRequest {
+	// The range ends after every key starting with the prefix
+	end := []byte(prefix)
+	end[len(end)-1]++
+	return etcdRangeRequest{Key: []byte(prefix), RangeEnd: end}
+}
+
+func (e *etcdBackend) read(ctx context.Context, prefix string) (map[string][]byte, error) {
+	var response struct {
+		Header struct {
+			Revision string `json:"revision"` // int64 fields are strings in the JSON API
+		} `json:"header"`
+		Kvs []struct {
+			Key   []byte `json:"key"`
+			Value []byte `json:"value"`
+		} `json:"kvs"`
+	}
+	if err := e.call(ctx, "/v3/kv/range", etcdKeyRange(prefix), &response); err != nil {
+		return nil, err
+	}
+
+	values := make(map[string][]byte, len(response.Kvs))
+	for _, kv := range response.Kvs {
+		if path, ok := relativeKVPath(prefix, string(kv.Key)); ok {
+			values[path] = kv.Value
+		}
+	}
+
+	revision, _ := strconv.ParseInt(response.Header.Revision, 10, 64)
+	e.mu.Lock()
+	e.revisions[prefix] = revision
+	e.mu.Unlock()
+	return values, nil
+}
+
+// wait opens a watch from the revision after the last read and returns with its first
+// event. A compacted start revision also returns, since the changes may have been missed.
+func (e *etcdBackend) wait(ctx context.Context, prefix string) error {
+	e.mu.Lock()
+	revision, known := e.revisions[prefix]
+	e.mu.Unlock()
+	if !known {
+		if _, err := e.read(ctx, prefix); err != nil {
+			return err
+		}
+		e.mu.Lock()
+		revision = e.revisions[prefix]
+		e.mu.Unlock()
+	}
+
+	keyRange := etcdKeyRange(prefix)
+	request := map[string]interface{}{
+		"create_request": map[string]interface{}{
+			"key":            keyRange.Key,
+			"range_end":      keyRange.RangeEnd,
+			"start_revision": strconv.FormatInt(revision+1, 10),
+		},
+	}
+	resp, err := e.post(ctx, "/v3/watch", request)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var message struct {
+			Result struct {
+				Canceled        bool              `json:"canceled"`
+				CancelReason    string            `json:"cancel_reason"`
+				CompactRevision string            `json:"compact_revision"`
+				Events          []json.RawMessage `json:"events"`
+			} `json:"result"`
+			Error *struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if err := decoder.Decode(&message); err != nil {
+			return fmt.Errorf("etcd watch ended: %w", err)
+		}
+		switch {
+		case message.Error != nil:
+			return fmt.Errorf("etcd watch failed: %s", message.Error.Message)
+		case len(message.Result.Events) > 0:
+			return nil
+		case message.Result.CompactRevision != "" && message.Result.CompactRevision != "0":
+			return nil
+		case message.Result.Canceled:
+			return fmt.Errorf("etcd watch canceled: %s", message.Result.CancelReason)
+		}
+	}
+}
+
+// call posts a request and decodes the response into out
+func (e *etcdBackend) call(ctx context.Context, path string, request, out interface{}) error {
+	resp, err := e.post(ctx, path, request)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("invalid etcd response: %w", err)
+	}
+	return nil
+}
+
+// post sends a request with the auth token, authenticating again once if the token was rejected
+func (e *etcdBackend) post(ctx context.Context, path string, request interface{}) (*http.Response, error) {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode etcd request: %w", err)
+	}
+
+	for attempt := 0; ; attempt++ {
+		token, err := e.authToken(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.endpoint+path, bytes.NewReader(body))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create etcd request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", token)
+		}
+
+		resp, err := httpClient(e.config.HTTPClient).Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query etcd: %w", err)
+		}
+		if resp.StatusCode == http.StatusUnauthorized && token != "" && attempt == 0 {
+			resp.Body.Close()
+			e.mu.Lock()
+			e.token = ""
+			e.mu.Unlock()
+			continue
+		}
+		if resp.StatusCode/100 != 2 {
+			defer resp.Body.Close()
+			return nil, kvResponseError("etcd", resp)
+		}
+		return resp, nil
+	}
+}
+
+// authToken returns the auth token, authenticating if there is none; "" without credentials
+func (e *etcdBackend) authToken(ctx context.Context) (string, error) {
+	if e.config.Username == "" {
+		return "", nil
+	}
+	e.mu.Lock()
+	token := e.token
+	e.mu.Unlock()
+	if token != "" {
+		return token, nil
+	}
+
+	body, err := json.Marshal(map[string]string{"name": e.config.Username, "password": e.config.Password})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode etcd credentials: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.endpoint+"/v3/auth/authenticate", bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("failed to create etcd request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := httpClient(e.config.HTTPClient).Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to authenticate with etcd: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return "", kvResponseError("etcd authentication", resp)
+	}
+
+	var response struct {
+		Token string `json:"token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return "", fmt.Errorf("invalid etcd authentication response: %w", err)
+	}
+	if response.Token == "" {
+		return "", fmt.Errorf("etcd authentication returned no token")
+	}
+
+	e.mu.Lock()
+	e.token = response.Token
+	e.mu.Unlock()
+	return response.Token, nil
+}
@@ -0,0 +1,294 @@
+// kvsource_test.go
+package ratelimit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeKV is an in-memory key-value store with a change index
+type fakeKV struct {
+	mu      sync.Mutex
+	values  map[string]string
+	index   uint64
+	changed chan struct{} // Closed and replaced on every change
+}
+
+func newFakeKV(values map[string]string) *fakeKV {
+	return &fakeKV{values: values, index: 1, changed: make(chan struct{})}
+}
+
+func (kv *fakeKV) set(key, value string) {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+	kv.values[key] = value
+	kv.index++
+	close(kv.changed)
+	kv.changed = make(chan struct{})
+}
+
+// snapshot returns the keys under the prefix, the index and the channel of the next change
+func (kv *fakeKV) snapshot(prefix string) (map[string]string, uint64, chan struct{}) {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+	values := make(map[string]string)
+	for key, value := range kv.values {
+		if strings.HasPrefix(key, prefix) {
+			values[key] = value
+		}
+	}
+	return values, kv.index, kv.changed
+}
+
+// consulServer serves the KV endpoint of Consul with blocking queries
+func consulServer(t *testing.T, kv *fakeKV, token string) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Consul-Token") != token {
+			http.Error(w, "ACL not found", http.StatusForbidden)
+			return
+		}
+		prefix := strings.TrimPrefix(r.URL.Path, "/v1/kv/")
+		values, index, changed := kv.snapshot(prefix)
+		if wait, _ := strconv.ParseUint(r.URL.Query().Get("index"), 10, 64); wait == index {
+			wait, _ := time.ParseDuration(r.URL.Query().Get("wait"))
+			select {
+			case <-changed:
+			case <-time.After(wait):
+			case <-r.Context().Done():
+				return
+			}
+			values, index, _ = kv.snapshot(prefix)
+		}
+
+		w.Header().Set("X-Consul-Index", strconv.FormatUint(index, 10))
+		if len(values) == 0 {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		type entry struct {
+			Key   string
+			Value []byte
+		}
+		var entries []entry
+		for key, value := range values {
+			entries = append(entries, entry{Key: key, Value: []byte(value)})
+		}
+		json.NewEncoder(w).Encode(entries)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// etcdServer serves the range, watch and auth endpoints of etcd's JSON gateway
+func etcdServer(t *testing.T, kv *fakeKV, username, password string) *httptest.Server {
+	const token = "etcd-token"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v3/auth/authenticate" {
+			var credentials struct{ Name, Password string }
+			json.NewDecoder(r.Body).Decode(&credentials)
+			if credentials.Name != username || credentials.Password != password {
+				http.Error(w, "authentication failed", http.StatusBadRequest)
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]string{"token": token})
+			return
+		}
+		if username != "" && r.Header.Get("Authorization") != token {
+			http.Error(w, "invalid auth token", http.StatusUnauthorized)
+			return
+		}
+
+		switch r.URL.Path {
+		case "/v3/kv/range":
+			var request etcdRangeRequest
+			json.NewDecoder(r.Body).Decode(&request)
+			values, index, _ := kv.snapshot(string(request.Key))
+			type keyValue struct {
+				Key   []byte `json:"key"`
+				Value []byte `json:"value"`
+			}
+			var kvs []keyValue
+			for key, value := range values {
+				kvs = append(kvs, keyValue{Key: []byte(key), Value: []byte(value)})
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"header": map[string]string{"revision": strconv.FormatUint(index, 10)},
+				"kvs":    kvs,
+			})
+		case "/v3/watch":
+			var request struct {
+				CreateRequest struct {
+					Key           []byte `json:"key"`
+					StartRevision string `json:"start_revision"`
+				} `json:"create_request"`
+			}
+			json.NewDecoder(r.Body).Decode(&request)
+			json.NewEncoder(w).Encode(map[string]interface{}{"result": map[string]interface{}{"created": true}})
+			w.(http.Flusher).Flush()
+
+			start, _ := strconv.ParseUint(request.CreateRequest.StartRevision, 10, 64)
+			for {
+				_, index, changed := kv.snapshot(string(request.CreateRequest.Key))
+				if index >= start {
+					json.NewEncoder(w).Encode(map[string]interface{}{
+						"result": map[string]interface{}{"events": []map[string]string{{"type": "PUT"}}},
+					})
+					w.(http.Flusher).Flush()
+					start = index + 1
+				}
+				select {
+				case <-changed:
+				case <-r.Context().Done():
+					return
+				}
+			}
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestKVConfigSource_ConsulLayering(t *testing.T) {
+	kv := newFakeKV(map[string]string{
+		"gorly/global/store":                "memory",
+		"gorly/global/defaultLimits/global": "1000/1h",
+		"gorly/global/scopeLimits":          `{"search": "50/1m", "upload": "10/1m"}`,
+		"gorly/eu-west/scopeLimits/search":  "20/1m",
+		"gorly/eu-westish/store":            "redis", // Outside the prefix
+	})
+	server := consulServer(t, kv, "acl-token")
+
+	source := NewConsulConfigSource(ConsulConfig{
+		Address:  server.URL,
+		Prefixes: []string{"gorly/global", "/gorly/eu-west/"},
+		Token:    "acl-token",
+	})
+	config, err := NewConfigLoader().LoadFromMultipleSources(source)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if config.Store != "memory" {
+		t.Errorf("Expected store memory, got %s", config.Store)
+	}
+	if limit := config.DefaultLimits["global"]; limit.Requests != 1000 || limit.Window != time.Hour {
+		t.Errorf("Expected the global limit of the base layer, got %+v", limit)
+	}
+	if limit := config.ScopeLimits["search"]; limit.Requests != 20 {
+		t.Errorf("Expected the eu-west layer to override search, got %+v", limit)
+	}
+	if limit := config.ScopeLimits["upload"]; limit.Requests != 10 {
+		t.Errorf("Expected upload from the base layer, got %+v", limit)
+	}
+
+	// A wrong token fails a required source
+	source = NewConsulConfigSource(ConsulConfig{Address: server.URL, Prefixes: []string{"gorly/global"}, Required: true})
+	if _, err := NewConfigLoader().LoadFromMultipleSources(source); err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("Expected the ACL error, got %v", err)
+	}
+
+	// Strict loading checks the keys of the store
+	kv.set("gorly/global/redis/passwrod", "secret")
+	source = NewConsulConfigSource(ConsulConfig{Address: server.URL, Prefixes: []string{"gorly/global"}, Token: "acl-token"})
+	if _, err := NewConfigLoader().WithStrict(true).LoadFromMultipleSources(source); err != nil {
+		t.Errorf("Expected a non-required source to be skipped, got %v", err)
+	}
+	if _, err := source.Load(NewConfigLoader().WithStrict(true)); err == nil || !strings.Contains(err.Error(), "redis.passwrod") {
+		t.Errorf("Expected the unknown key with a suggestion, got %v", err)
+	}
+}
+
+func TestKVConfigSource_ConsulWatch(t *testing.T) {
+	kv := newFakeKV(map[string]string{
+		"gorly/hot/enabled":       "true",
+		"gorly/hot/limits/global": "100/1m",
+	})
+	server := consulServer(t, kv, "")
+	source := NewConsulConfigSource(ConsulConfig{
+		Address:  server.URL,
+		Prefixes: []string{"gorly/hot"},
+		WaitTime: time.Second,
+	})
+
+	config, err := source.GetConfig(context.Background())
+	if err != nil {
+		t.Fatalf("GetConfig failed: %v", err)
+	}
+	if !config.Enabled || config.Limits["global"] != "100/1m" {
+		t.Fatalf("Unexpected config %+v", config)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	updates, err := source.Watch(ctx)
+	if err != nil {
+		t.Fatalf("Watch failed: %v", err)
+	}
+
+	kv.set("gorly/hot/limits/global", "200/1m")
+	select {
+	case config := <-updates:
+		if config.Limits["global"] != "200/1m" {
+			t.Errorf("Expected the changed limit, got %+v", config.Limits)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for the change")
+	}
+
+	cancel()
+	for range updates {
+	}
+}
+
+func TestKVConfigSource_Etcd(t *testing.T) {
+	kv := newFakeKV(map[string]string{
+		"gorly/global":               "enabled: true\nlimits:\n  global: 100/1m\n  search: 10/1m\n",
+		"gorly/region/limits/search": "5/1m",
+	})
+	server := etcdServer(t, kv, "gorly", "s3cret")
+
+	source := NewEtcdConfigSource(EtcdConfig{
+		Endpoint: server.URL,
+		Prefixes: []string{"gorly/global", "gorly/region"},
+		Username: "gorly",
+		Password: "s3cret",
+	})
+	config, err := source.GetConfig(context.Background())
+	if err != nil {
+		t.Fatalf("GetConfig failed: %v", err)
+	}
+	if !config.Enabled || config.Limits["global"] != "100/1m" || config.Limits["search"] != "5/1m" {
+		t.Fatalf("Expected the layered config, got %+v", config)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	updates, err := source.Watch(ctx)
+	if err != nil {
+		t.Fatalf("Watch failed: %v", err)
+	}
+
+	kv.set("gorly/region/limits/global", "50/1m")
+	select {
+	case config := <-updates:
+		if config.Limits["global"] != "50/1m" {
+			t.Errorf("Expected the changed limit, got %+v", config.Limits)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for the change")
+	}
+
+	// Wrong credentials fail
+	source = NewEtcdConfigSource(EtcdConfig{Endpoint: server.URL, Prefixes: []string{"gorly/global"}, Username: "gorly"})
+	if _, err := source.GetConfig(context.Background()); err == nil || !strings.Contains(err.Error(), "authentication") {
+		t.Errorf("Expected the authentication error, got %v", err)
+	}
+}
@@ -0,0 +1,157 @@
+// lambdalimit/events.go
+package lambdalimit
+
+// The event types below carry the fields of the API Gateway payloads the adapters read.
+// Their JSON matches the events of github.com/aws/aws-lambda-go/events, so handlers built
+// here can be passed to lambda.Start directly, without this package depending on the SDK.
+
+// ProxyRequest is the payload of a REST API (v1) Lambda proxy integration
+type ProxyRequest struct {
+	Resource                        string              `json:"resource"`
+	Path                            string              `json:"path"`
+	HTTPMethod                      string              `json:"httpMethod"`
+	Headers                         map[string]string   `json:"headers"`
+	MultiValueHeaders               map[string][]string `json:"multiValueHeaders"`
+	QueryStringParameters           map[string]string   `json:"queryStringParameters"`
+	MultiValueQueryStringParameters map[string][]string `json:"multiValueQueryStringParameters"`
+	PathParameters                  map[string]string   `json:"pathParameters"`
+	StageVariables                  map[string]string   `json:"stageVariables"`
+	RequestContext                  ProxyRequestContext `json:"requestContext"`
+	Body                            string              `json:"body"`
+	IsBase64Encoded                 bool                `json:"isBase64Encoded,omitempty"`
+}
+
+// ProxyRequestContext describes the API and caller of a REST API request
+type ProxyRequestContext struct {
+	AccountID    string                 `json:"accountId"`
+	ResourceID   string                 `json:"resourceId"`
+	Stage        string                 `json:"stage"`
+	RequestID    string                 `json:"requestId"`
+	Identity     ProxyIdentity          `json:"identity"`
+	ResourcePath string                 `json:"resourcePath"`
+	HTTPMethod   string                 `json:"httpMethod"`
+	APIID        string                 `json:"apiId"`
+	Authorizer   map[string]interface{} `json:"authorizer"`
+}
+
+// ProxyIdentity identifies the caller of a REST API request
+type ProxyIdentity struct {
+	APIKey    string `json:"apiKey"`
+	SourceIP  string `json:"sourceIp"`
+	UserAgent string `json:"userAgent"`
+}
+
+// ProxyResponse is the response of a REST API (v1) Lambda proxy integration
+type ProxyResponse struct {
+	StatusCode        int                 `json:"statusCode"`
+	Headers           map[string]string   `json:"headers"`
+	MultiValueHeaders map[string][]string `json:"multiValueHeaders"`
+	Body              string              `json:"body"`
+	IsBase64Encoded   bool                `json:"isBase64Encoded,omitempty"`
+}
+
+// HTTPRequest is the payload of an HTTP API (v2) Lambda integration
+type HTTPRequest struct {
+	Version               string             `json:"version"`
+	RouteKey              string             `json:"routeKey"`
+	RawPath               string             `json:"rawPath"`
+	RawQueryString        string             `json:"rawQueryString"`
+	Cookies               []string           `json:"cookies,omitempty"`
+	Headers               map[string]string  `json:"headers"`
+	QueryStringParameters map[string]string  `json:"queryStringParameters,omitempty"`
+	PathParameters        map[string]string  `json:"pathParameters,omitempty"`
+	RequestContext        HTTPRequestContext `json:"requestContext"`
+	StageVariables        map[string]string  `json:"stageVariables,omitempty"`
+	Body                  string             `json:"body,omitempty"`
+	IsBase64Encoded       bool               `json:"isBase64Encoded"`
+}
+
+// HTTPRequestContext describes the API and caller of an HTTP API request
+type HTTPRequestContext struct {
+	RouteKey   string                 `json:"routeKey"`
+	AccountID  string                 `json:"accountId"`
+	Stage      string                 `json:"stage"`
+	RequestID  string                 `json:"requestId"`
+	APIID      string                 `json:"apiId"`
+	DomainName string                 `json:"domainName"`
+	HTTP       HTTPDescription        `json:"http"`
+	Authorizer map[string]interface{} `json:"authorizer,omitempty"`
+}
+
+// HTTPDescription describes the HTTP request behind an HTTP API event
+type HTTPDescription struct {
+	Method    string `json:"method"`
+	Path      string `json:"path"`
+	Protocol  string `json:"protocol"`
+	SourceIP  string `json:"sourceIp"`
+	UserAgent string `json:"userAgent"`
+}
+
+// HTTPResponse is the response of an HTTP API (v2) Lambda integration
+type HTTPResponse struct {
+	StatusCode        int                 `json:"statusCode"`
+	Headers           map[string]string   `json:"headers"`
+	MultiValueHeaders map[string][]string `json:"multiValueHeaders"`
+	Body              string              `json:"body"`
+	IsBase64Encoded   bool                `json:"isBase64Encoded,omitempty"`
+	Cookies           []string            `json:"cookies"`
+}
+
+// AuthorizerRequest is the payload of a REST API Lambda authorizer of type REQUEST
+type AuthorizerRequest struct {
+	Type                            string              `json:"type"`
+	MethodArn                       string              `json:"methodArn"`
+	Resource                        string              `json:"resource"`
+	Path                            string              `json:"path"`
+	HTTPMethod                      string              `json:"httpMethod"`
+	Headers                         map[string]string   `json:"headers"`
+	MultiValueHeaders               map[string][]string `json:"multiValueHeaders"`
+	QueryStringParameters           map[string]string   `json:"queryStringParameters"`
+	MultiValueQueryStringParameters map[string][]string `json:"multiValueQueryStringParameters"`
+	PathParameters                  map[string]string   `json:"pathParameters"`
+	StageVariables                  map[string]string   `json:"stageVariables"`
+	RequestContext                  ProxyRequestContext `json:"requestContext"`
+}
+
+// AuthorizerResponse is the IAM policy a REST API Lambda authorizer returns
+type AuthorizerResponse struct {
+	PrincipalID    string                 `json:"principalId"`
+	PolicyDocument PolicyDocument         `json:"policyDocument"`
+	Context        map[string]interface{} `json:"context,omitempty"`
+}
+
+// PolicyDocument is an IAM policy
+type PolicyDocument struct {
+	Version   string            `json:"Version"`
+	Statement []PolicyStatement `json:"Statement"`
+}
+
+// PolicyStatement allows or denies actions on resources
+type PolicyStatement struct {
+	Action   []string `json:"Action"`
+	Effect   string   `json:"Effect"`
+	Resource []string `json:"Resource"`
+}
+
+// HTTPAuthorizerRequest is the payload (format 2.0) of an HTTP API Lambda authorizer
+type HTTPAuthorizerRequest struct {
+	Version               string             `json:"version"`
+	Type                  string             `json:"type"`
+	RouteArn              string             `json:"routeArn"`
+	IdentitySource        []string           `json:"identitySource"`
+	RouteKey              string             `json:"routeKey"`
+	RawPath               string             `json:"rawPath"`
+	RawQueryString        string             `json:"rawQueryString"`
+	Cookies               []string           `json:"cookies"`
+	Headers               map[string]string  `json:"headers"`
+	QueryStringParameters map[string]string  `json:"queryStringParameters"`
+	RequestContext        HTTPRequestContext `json:"requestContext"`
+	PathParameters        map[string]string  `json:"pathParameters"`
+	StageVariables        map[string]string  `json:"stageVariables"`
+}
+
+// SimpleAuthorizerResponse is the simple response of an HTTP API Lambda authorizer
+type SimpleAuthorizerResponse struct {
+	IsAuthorized bool                   `json:"isAuthorized"`
+	Context      map[string]interface{} `json:"context,omitempty"`
+}
@@ -0,0 +1,303 @@
+// lambdalimit/lambdalimit.go
+// Package lambdalimit applies a gorly limiter in AWS Lambda functions behind API Gateway,
+// either around the handler of a proxy integration or as a Lambda authorizer. Events are
+// turned into http.Requests and checked by the limiter's HTTP middleware, so extractors,
+// path limits, tiers and the rate limit response work as they do in a server.
+//
+// The package has no dependencies beyond gorly: its event types marshal like those of
+// github.com/aws/aws-lambda-go/events, so its handlers are passed to lambda.Start as is.
+// Build the limiter once per execution environment, outside the handler, with a shared
+// store such as ElastiCache (Redis with RedisTLS); RedisLazyConnect keeps the connection
+// out of the cold start.
+package lambdalimit
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/itsatony/gorly"
+)
+
+// ProxyHandler wraps the handler of a REST API (v1) proxy integration. Denied requests get
+// the limiter's rate limit response without invoking next; allowed ones get the rate limit
+// headers added to the response of next. Result(ctx) returns the result within next.
+// Example: lambda.Start(lambdalimit.ProxyHandler(limiter, handle))
+func ProxyHandler(limiter ratelimit.Limiter, next func(context.Context, ProxyRequest) (ProxyResponse, error)) func(context.Context, ProxyRequest) (ProxyResponse, error) {
+	mw := limiter.HTTPMiddleware()
+	return func(ctx context.Context, event ProxyRequest) (ProxyResponse, error) {
+		r, err := newRequest(ctx, event.HTTPMethod, event.Path, proxyQuery(event.QueryStringParameters, event.MultiValueQueryStringParameters),
+			proxyHeader(event.Headers, event.MultiValueHeaders), event.Body, event.IsBase64Encoded, event.RequestContext.Identity.SourceIP)
+		if err != nil {
+			return ProxyResponse{}, err
+		}
+		passed, rec := check(mw, r)
+		if passed == nil {
+			return ProxyResponse{StatusCode: rec.status, Headers: singleValues(rec.header), Body: rec.body.String()}, nil
+		}
+		response, err := next(passed.Context(), event)
+		if err != nil {
+			return response, err
+		}
+		response.Headers = addHeaders(response.Headers, rec.header)
+		return response, nil
+	}
+}
+
+// HTTPHandler wraps the handler of an HTTP API (v2) integration, as ProxyHandler does for
+// REST APIs
+// Example: lambda.Start(lambdalimit.HTTPHandler(limiter, handle))
+func HTTPHandler(limiter ratelimit.Limiter, next func(context.Context, HTTPRequest) (HTTPResponse, error)) func(context.Context, HTTPRequest) (HTTPResponse, error) {
+	mw := limiter.HTTPMiddleware()
+	return func(ctx context.Context, event HTTPRequest) (HTTPResponse, error) {
+		r, err := newRequest(ctx, event.RequestContext.HTTP.Method, event.RawPath, event.RawQueryString,
+			httpHeader(event.Headers, event.Cookies), event.Body, event.IsBase64Encoded, event.RequestContext.HTTP.SourceIP)
+		if err != nil {
+			return HTTPResponse{}, err
+		}
+		passed, rec := check(mw, r)
+		if passed == nil {
+			return HTTPResponse{StatusCode: rec.status, Headers: singleValues(rec.header), Body: rec.body.String()}, nil
+		}
+		response, err := next(passed.Context(), event)
+		if err != nil {
+			return response, err
+		}
+		response.Headers = addHeaders(response.Headers, rec.header)
+		return response, nil
+	}
+}
+
+// Authorizer returns a REST API Lambda authorizer of type REQUEST that allows the method
+// while the caller is within its limits and denies it beyond them. API Gateway answers
+// denied calls with 403; a gateway response for ACCESS_DENIED can turn that into a 429 with
+// $context.authorizer.rateLimitRetryAfter as Retry-After. Authorizer results must not be
+// cached (TTL 0), or cached policies skip the limiter. A failing check returns an error,
+// which API Gateway answers with 500.
+// Example: lambda.Start(lambdalimit.Authorizer(limiter))
+func Authorizer(limiter ratelimit.Limiter, options ...AuthorizerOption) func(context.Context, AuthorizerRequest) (AuthorizerResponse, error) {
+	config := authorizerConfig{principal: sourceIP}
+	for _, option := range options {
+		option(&config)
+	}
+	mw := limiter.HTTPMiddleware()
+	return func(ctx context.Context, event AuthorizerRequest) (AuthorizerResponse, error) {
+		r, err := newRequest(ctx, event.HTTPMethod, event.Path, proxyQuery(event.QueryStringParameters, event.MultiValueQueryStringParameters),
+			proxyHeader(event.Headers, event.MultiValueHeaders), "", false, event.RequestContext.Identity.SourceIP)
+		if err != nil {
+			return AuthorizerResponse{}, err
+		}
+		passed, rec := check(mw, r)
+		if passed == nil && rec.status >= http.StatusInternalServerError {
+			return AuthorizerResponse{}, fmt.Errorf("rate limit check failed with status %d", rec.status)
+		}
+		effect := "Allow"
+		if passed == nil {
+			effect = "Deny"
+		}
+		return AuthorizerResponse{
+			PrincipalID: config.principal(r),
+			PolicyDocument: PolicyDocument{
+				Version:   "2012-10-17",
+				Statement: []PolicyStatement{{Action: []string{"execute-api:Invoke"}, Effect: effect, Resource: []string{event.MethodArn}}},
+			},
+			Context: resultContext(rec.header),
+		}, nil
+	}
+}
+
+// HTTPAuthorizer returns an HTTP API Lambda authorizer with simple responses (payload
+// format 2.0), authorizing callers within their limits. The caveats of Authorizer apply.
+// Example: lambda.Start(lambdalimit.HTTPAuthorizer(limiter))
+func HTTPAuthorizer(limiter ratelimit.Limiter) func(context.Context, HTTPAuthorizerRequest) (SimpleAuthorizerResponse, error) {
+	mw := limiter.HTTPMiddleware()
+	return func(ctx context.Context, event HTTPAuthorizerRequest) (SimpleAuthorizerResponse, error) {
+		r, err := newRequest(ctx, event.RequestContext.HTTP.Method, event.RawPath, event.RawQueryString,
+			httpHeader(event.Headers, event.Cookies), "", false, event.RequestContext.HTTP.SourceIP)
+		if err != nil {
+			return SimpleAuthorizerResponse{}, err
+		}
+		passed, rec := check(mw, r)
+		if passed == nil && rec.status >= http.StatusInternalServerError {
+			return SimpleAuthorizerResponse{}, fmt.Errorf("rate limit check failed with status %d", rec.status)
+		}
+		return SimpleAuthorizerResponse{IsAuthorized: passed != nil, Context: resultContext(rec.header)}, nil
+	}
+}
+
+// AuthorizerOption configures a REST API authorizer
+type AuthorizerOption func(*authorizerConfig)
+
+type authorizerConfig struct {
+	principal func(r *http.Request) string
+}
+
+// WithPrincipal sets the principal ID of the policies the authorizer returns, derived from
+// the request (default: the caller's IP)
+// Example: lambdalimit.Authorizer(limiter, lambdalimit.WithPrincipal(func(r *http.Request) string { return r.Header.Get("X-API-Key") }))
+func WithPrincipal(principal func(r *http.Request) string) AuthorizerOption {
+	return func(c *authorizerConfig) {
+		c.principal = principal
+	}
+}
+
+// Result returns the rate limit result of an allowed invocation from the context
+// ProxyHandler and HTTPHandler pass to the wrapped handler, or nil
+func Result(ctx context.Context) *ratelimit.LimitResult {
+	return ratelimit.ResultFromRequest(new(http.Request).WithContext(ctx))
+}
+
+// newRequest builds the request the middleware checks from the parts of an event
+func newRequest(ctx context.Context, method, path, rawQuery string, header http.Header, body string, base64Body bool, sourceIP string) (*http.Request, error) {
+	var reader io.Reader = strings.NewReader(body)
+	if base64Body {
+		decoded, err := base64.StdEncoding.DecodeString(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode request body: %w", err)
+		}
+		reader = bytes.NewReader(decoded)
+	}
+	if path == "" {
+		path = "/"
+	}
+	r, err := http.NewRequestWithContext(ctx, method, (&url.URL{Path: path, RawQuery: rawQuery}).String(), reader)
+	if err != nil {
+		return nil, fmt.Errorf("invalid request in event: %w", err)
+	}
+	r.Header = header
+	r.Host = header.Get("Host")
+	if sourceIP != "" {
+		r.RemoteAddr = net.JoinHostPort(sourceIP, "0")
+	}
+	return r, nil
+}
+
+// proxyQuery encodes the query of a REST API event; multi-value parameters hold every value
+func proxyQuery(single map[string]string, multi map[string][]string) string {
+	query := make(url.Values, len(single))
+	for name, value := range single {
+		query.Set(name, value)
+	}
+	for name, values := range multi {
+		query[name] = values
+	}
+	return query.Encode()
+}
+
+// proxyHeader builds the header of a REST API event; multi-value headers hold every value
+func proxyHeader(single map[string]string, multi map[string][]string) http.Header {
+	header := make(http.Header, len(single))
+	for name, value := range single {
+		header.Set(name, value)
+	}
+	for name, values := range multi {
+		header.Del(name)
+		for _, value := range values {
+			header.Add(name, value)
+		}
+	}
+	return header
+}
+
+// httpHeader builds the header of an HTTP API event, which carries cookies apart
+func httpHeader(headers map[string]string, cookies []string) http.Header {
+	header := make(http.Header, len(headers)+1)
+	for name, value := range headers {
+		header.Set(name, value)
+	}
+	if len(cookies) > 0 {
+		header.Set("Cookie", strings.Join(cookies, "; "))
+	}
+	return header
+}
+
+// sourceIP is the default principal: the address the event came from
+func sourceIP(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
+
+// check runs the middleware on r. It returns the request as the middleware passed it on,
+// or nil if the middleware answered it; rec holds the headers and any response written.
+func check(mw func(http.Handler) http.Handler, r *http.Request) (passed *http.Request, rec *recorder) {
+	rec = &recorder{header: make(http.Header)}
+	mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		passed = r
+	})).ServeHTTP(rec, r)
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	return passed, rec
+}
+
+// resultContext is the authorizer context describing the result, which API Gateway passes
+// to the integration and gateway responses as $context.authorizer.<key>
+func resultContext(header http.Header) map[string]interface{} {
+	result := ratelimit.ResultFromHeaders(header)
+	if result == nil {
+		return nil
+	}
+	values := map[string]interface{}{
+		"rateLimitLimit":     result.Limit,
+		"rateLimitRemaining": result.Remaining,
+	}
+	if !result.Allowed {
+		values["rateLimitRetryAfter"] = int64(math.Ceil(result.RetryAfter.Seconds()))
+	}
+	return values
+}
+
+// singleValues converts a header to the single-value form of Lambda responses
+func singleValues(header http.Header) map[string]string {
+	return addHeaders(nil, header)
+}
+
+// addHeaders adds the headers the response does not set already
+func addHeaders(headers map[string]string, header http.Header) map[string]string {
+	if headers == nil {
+		headers = make(map[string]string, len(header))
+	}
+	set := make(map[string]bool, len(headers))
+	for name := range headers {
+		set[http.CanonicalHeaderKey(name)] = true
+	}
+	for name, values := range header {
+		if !set[name] && len(values) > 0 {
+			headers[name] = strings.Join(values, ", ")
+		}
+	}
+	return headers
+}
+
+// recorder captures what the middleware writes for a request
+type recorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (r *recorder) Header() http.Header {
+	return r.header
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	return r.body.Write(b)
+}
+
+func (r *recorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+}
@@ -0,0 +1,154 @@
+// lambdalimit/lambdalimit_test.go
+package lambdalimit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/itsatony/gorly"
+)
+
+func newTestLimiter(t *testing.T, limit string) ratelimit.Limiter {
+	t.Helper()
+	limiter, err := ratelimit.New().Limit("global", limit).Build()
+	if err != nil {
+		t.Fatalf("Failed to build limiter: %v", err)
+	}
+	t.Cleanup(func() { limiter.Close() })
+	return limiter
+}
+
+func TestProxyHandler(t *testing.T) {
+	limiter := newTestLimiter(t, "2/minute")
+	invoked := 0
+	handler := ProxyHandler(limiter, func(ctx context.Context, event ProxyRequest) (ProxyResponse, error) {
+		invoked++
+		if result := Result(ctx); result == nil || result.Limit != 2 {
+			t.Errorf("Expected the result in the handler's context, got %+v", result)
+		}
+		return ProxyResponse{StatusCode: http.StatusOK, Headers: map[string]string{"content-type": "text/plain"}, Body: "OK"}, nil
+	})
+
+	event := ProxyRequest{HTTPMethod: http.MethodGet, Path: "/orders", RequestContext: ProxyRequestContext{Identity: ProxyIdentity{SourceIP: "203.0.113.7"}}}
+	for i, want := range []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests} {
+		response, err := handler(context.Background(), event)
+		if err != nil {
+			t.Fatalf("Invocation %d failed: %v", i+1, err)
+		}
+		if response.StatusCode != want || response.Headers["X-Ratelimit-Limit"] != "2" {
+			t.Errorf("Invocation %d: expected %d with rate limit headers, got %d %v", i+1, want, response.StatusCode, response.Headers)
+		}
+	}
+	if invoked != 2 {
+		t.Errorf("Expected the denied invocation not to reach the handler, it ran %d times", invoked)
+	}
+
+	// Each source IP has its own limit
+	event.RequestContext.Identity.SourceIP = "203.0.113.8"
+	if response, _ := handler(context.Background(), event); response.StatusCode != http.StatusOK {
+		t.Errorf("Expected another caller to be allowed, got %d", response.StatusCode)
+	}
+}
+
+func TestHTTPHandler(t *testing.T) {
+	limiter, err := ratelimit.New().
+		Limit("upload", "1/minute").
+		Limit("orders", "100/minute").
+		ScopeFunc(func(r *http.Request) string { return strings.TrimPrefix(r.URL.Path, "/") }).
+		Build()
+	if err != nil {
+		t.Fatalf("Failed to build limiter: %v", err)
+	}
+	defer limiter.Close()
+	handler := HTTPHandler(limiter, func(ctx context.Context, event HTTPRequest) (HTTPResponse, error) {
+		return HTTPResponse{StatusCode: http.StatusCreated}, nil
+	})
+
+	event := HTTPRequest{
+		RawPath:         "/upload",
+		RawQueryString:  "draft=1",
+		Body:            "aGVsbG8=",
+		IsBase64Encoded: true,
+		RequestContext:  HTTPRequestContext{HTTP: HTTPDescription{Method: http.MethodPost, SourceIP: "2001:db8::1"}},
+	}
+	for i, want := range []int{http.StatusCreated, http.StatusTooManyRequests} {
+		response, err := handler(context.Background(), event)
+		if err != nil {
+			t.Fatalf("Invocation %d failed: %v", i+1, err)
+		}
+		if response.StatusCode != want {
+			t.Errorf("Invocation %d: expected %d, got %d", i+1, want, response.StatusCode)
+		}
+	}
+
+	event.IsBase64Encoded, event.Body = true, "not base64!"
+	if _, err := handler(context.Background(), event); err == nil {
+		t.Error("Expected an undecodable body to fail the invocation")
+	}
+}
+
+func TestAuthorizer(t *testing.T) {
+	limiter := newTestLimiter(t, "1/minute")
+	authorizer := Authorizer(limiter, WithPrincipal(func(r *http.Request) string { return r.Header.Get("X-Api-Key") }))
+	event := AuthorizerRequest{
+		Type:           "REQUEST",
+		MethodArn:      "arn:aws:execute-api:eu-west-1:123456789012:abc123/prod/GET/orders",
+		HTTPMethod:     http.MethodGet,
+		Path:           "/orders",
+		Headers:        map[string]string{"x-api-key": "key-1"},
+		RequestContext: ProxyRequestContext{Identity: ProxyIdentity{SourceIP: "203.0.113.7"}},
+	}
+
+	response, err := authorizer(context.Background(), event)
+	if err != nil {
+		t.Fatalf("Authorizer failed: %v", err)
+	}
+	statement := response.PolicyDocument.Statement[0]
+	if statement.Effect != "Allow" || statement.Resource[0] != event.MethodArn || response.PrincipalID != "key-1" {
+		t.Errorf("Expected the method to be allowed for the key, got %+v", response)
+	}
+	if response.Context["rateLimitRemaining"] != int64(0) {
+		t.Errorf("Expected the remaining quota in the context, got %v", response.Context)
+	}
+
+	response, err = authorizer(context.Background(), event)
+	if err != nil {
+		t.Fatalf("Authorizer failed: %v", err)
+	}
+	if effect := response.PolicyDocument.Statement[0].Effect; effect != "Deny" {
+		t.Errorf("Expected the second call to be denied, got %s", effect)
+	}
+	if retryAfter, _ := response.Context["rateLimitRetryAfter"].(int64); retryAfter < 1 {
+		t.Errorf("Expected a retry delay in the context, got %v", response.Context)
+	}
+
+	// The policy marshals as API Gateway expects
+	data, _ := json.Marshal(response)
+	if !strings.Contains(string(data), `"Statement":[{"Action":["execute-api:Invoke"],"Effect":"Deny"`) {
+		t.Errorf("Unexpected policy JSON: %s", data)
+	}
+}
+
+func TestHTTPAuthorizer(t *testing.T) {
+	limiter := newTestLimiter(t, "1/minute")
+	authorizer := HTTPAuthorizer(limiter)
+
+	var event HTTPAuthorizerRequest
+	payload := `{"version":"2.0","type":"REQUEST","routeArn":"arn:aws:execute-api:eu-west-1:123456789012:abc123/$default/GET/orders",
+		"rawPath":"/orders","headers":{"user-agent":"curl"},"requestContext":{"http":{"method":"GET","sourceIp":"198.51.100.4"}}}`
+	if err := json.Unmarshal([]byte(payload), &event); err != nil {
+		t.Fatalf("Failed to unmarshal event: %v", err)
+	}
+	for i, want := range []bool{true, false} {
+		response, err := authorizer(context.Background(), event)
+		if err != nil {
+			t.Fatalf("Authorizer failed: %v", err)
+		}
+		if response.IsAuthorized != want {
+			t.Errorf("Call %d: expected authorized %v, got %+v", i+1, want, response)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+// limit.go - The Limit type behind limit strings, config files, the builder and results
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/itsatony/gorly/internal/core"
+)
+
+// Limit is a rate limit of Requests per Window. Its string form is the limit string used
+// by the builder, config files and hot reload, e.g. "100/minute" or "500/5m".
+type Limit struct {
+	Requests int64
+	Window   time.Duration
+
+	// Burst is the token bucket capacity of config file limits (RateLimit.BurstSize), written
+	// "100/minute burst 20"; 0 uses the default. Builder and hot-reload limits reject it.
+	Burst int64
+}
+
+// ParseLimit parses a limit string such as "100/minute", "500/5m", "1000/1d", "10/2w" or
+// "100/minute burst 20". Windows are a unit (second, minute, hour, day, week, month or
+// their abbreviations) with an optional count, or a Go duration such as "1h30m".
+func ParseLimit(s string) (Limit, error) {
+	requests, window, burst, err := core.ParseLimit(s)
+	if err != nil {
+		return Limit{}, err
+	}
+	return Limit{Requests: requests, Window: window, Burst: burst}, nil
+}
+
+// MustParseLimit is ParseLimit for limits known to be valid, panicking on errors
+// Example: base := ratelimit.MustParseLimit("1000/hour")
+func MustParseLimit(s string) Limit {
+	limit, err := ParseLimit(s)
+	if err != nil {
+		panic(err)
+	}
+	return limit
+}
+
+// String returns the limit string, naming single units ("100/minute") and giving multiples
+// in the largest whole unit ("500/5m", "1000/2d")
+func (l Limit) String() string {
+	s := fmt.Sprintf("%d/%s", l.Requests, formatWindow(l.Window))
+	if l.Burst > 0 {
+		s += fmt.Sprintf(" burst %d", l.Burst)
+	}
+	return s
+}
+
+// formatWindow formats a window for a limit string
+func formatWindow(window time.Duration) string {
+	units := []struct {
+		duration time.Duration
+		name     string
+		short    string
+	}{
+		{7 * 24 * time.Hour, "week", "w"},
+		{24 * time.Hour, "day", "d"},
+		{time.Hour, "hour", "h"},
+		{time.Minute, "minute", "m"},
+		{time.Second, "second", "s"},
+	}
+	for _, unit := range units {
+		if window == unit.duration {
+			return unit.name
+		}
+		if window > 0 && window%unit.duration == 0 {
+			return fmt.Sprintf("%d%s", window/unit.duration, unit.short)
+		}
+	}
+	return window.String()
+}
+
+// IsZero reports whether the limit is unset
+func (l Limit) IsZero() bool {
+	return l == Limit{}
+}
+
+// PerSecond returns the sustained rate of the limit in requests per second
+func (l Limit) PerSecond() float64 {
+	if l.Window <= 0 {
+		return 0
+	}
+	return float64(l.Requests) / l.Window.Seconds()
+}
+
+// Scale multiplies the requests and burst by factor, rounding to the nearest request and
+// keeping at least one, like Builder.Scale does for configured limits
+// Example: half := ratelimit.MustParseLimit("1000/hour").Scale(0.5) // 500/hour
+func (l Limit) Scale(factor float64) Limit {
+	l.Requests = scaleAmount(l.Requests, factor)
+	if l.Burst > 0 {
+		l.Burst = scaleAmount(l.Burst, factor)
+	}
+	return l
+}
+
+// scaleAmount scales a request count, keeping at least one
+func scaleAmount(amount int64, factor float64) int64 {
+	return max(int64(math.Round(float64(amount)*factor)), 1)
+}
+
+// Per returns the limit over another window at the same rate, e.g. 100/minute per hour is
+// 6000/hour. Requests are rounded to the nearest whole request.
+func (l Limit) Per(window time.Duration) Limit {
+	if l.Window > 0 && window > 0 {
+		l.Requests = int64(math.Round(float64(l.Requests) * float64(window) / float64(l.Window)))
+	}
+	l.Window = window
+	return l
+}
+
+// MarshalText encodes the limit as its limit string, so JSON and YAML carry "100/minute"
+func (l Limit) MarshalText() ([]byte, error) {
+	return []byte(l.String()), nil
+}
+
+// UnmarshalText parses a limit string
+func (l *Limit) UnmarshalText(text []byte) error {
+	limit, err := ParseLimit(string(text))
+	if err != nil {
+		return err
+	}
+	*l = limit
+	return nil
+}
+
+// parseScopeLimit parses a limit of the builder or hot reload, whose algorithms size
+// buckets by the requests and so take no burst
+func parseScopeLimit(s string) (Limit, error) {
+	limit, err := ParseLimit(s)
+	if err != nil {
+		return Limit{}, err
+	}
+	if limit.Burst != 0 {
+		return Limit{}, fmt.Errorf("invalid limit %s: bursts are only supported in config files", s)
+	}
+	return limit, nil
+}
@@ -0,0 +1,119 @@
+// limit_test.go
+package ratelimit
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseLimit(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Limit
+	}{
+		{"100/minute", Limit{Requests: 100, Window: time.Minute}},
+		{"100/min", Limit{Requests: 100, Window: time.Minute}},
+		{"500/5m", Limit{Requests: 500, Window: 5 * time.Minute}},
+		{"1000/1h", Limit{Requests: 1000, Window: time.Hour}},
+		{"1000/Hour", Limit{Requests: 1000, Window: time.Hour}},
+		{"10000/1d", Limit{Requests: 10000, Window: 24 * time.Hour}},
+		{"50000/2w", Limit{Requests: 50000, Window: 14 * 24 * time.Hour}},
+		{"10/15minutes", Limit{Requests: 10, Window: 15 * time.Minute}},
+		{"90/1h30m", Limit{Requests: 90, Window: 90 * time.Minute}},
+		{"100/minute burst 20", Limit{Requests: 100, Window: time.Minute, Burst: 20}},
+	}
+	for _, tt := range tests {
+		got, err := ParseLimit(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseLimit(%q) = %+v, %v; want %+v", tt.in, got, err, tt.want)
+		}
+	}
+
+	for _, in := range []string{"", "100", "abc/minute", "-1/minute", "100/xyz", "100/0s", "100/minute burst", "100/minute burst 0"} {
+		if _, err := ParseLimit(in); err == nil {
+			t.Errorf("ParseLimit(%q): expected an error", in)
+		}
+	}
+}
+
+func TestLimit_String(t *testing.T) {
+	tests := []struct {
+		limit Limit
+		want  string
+	}{
+		{Limit{Requests: 100, Window: time.Minute}, "100/minute"},
+		{Limit{Requests: 500, Window: 5 * time.Minute}, "500/5m"},
+		{Limit{Requests: 90, Window: 90 * time.Minute}, "90/90m"},
+		{Limit{Requests: 1000, Window: 48 * time.Hour}, "1000/2d"},
+		{Limit{Requests: 10, Window: 1500 * time.Millisecond}, "10/1.5s"},
+		{Limit{Requests: 100, Window: time.Minute, Burst: 20}, "100/minute burst 20"},
+	}
+	for _, tt := range tests {
+		if got := tt.limit.String(); got != tt.want {
+			t.Errorf("%+v.String() = %q, want %q", tt.limit, got, tt.want)
+		}
+		if parsed, err := ParseLimit(tt.want); err != nil || parsed != tt.limit {
+			t.Errorf("ParseLimit(%q) = %+v, %v; want the round trip %+v", tt.want, parsed, err, tt.limit)
+		}
+	}
+}
+
+func TestLimit_Math(t *testing.T) {
+	base := MustParseLimit("1000/hour burst 100")
+	if half := base.Scale(0.5); half.String() != "500/hour burst 50" {
+		t.Errorf("Expected half the limit, got %s", half)
+	}
+	if tiny := base.Scale(0.0001); tiny.Requests != 1 || tiny.Burst != 1 {
+		t.Errorf("Expected scaling to keep one request, got %s", tiny)
+	}
+	if perMinute := MustParseLimit("100/minute").Per(time.Hour); perMinute.String() != "6000/hour" {
+		t.Errorf("Expected 6000/hour, got %s", perMinute)
+	}
+	if rate := MustParseLimit("120/minute").PerSecond(); rate != 2 {
+		t.Errorf("Expected 2 requests per second, got %g", rate)
+	}
+
+	data, err := json.Marshal(map[string]Limit{"search": base})
+	if err != nil || string(data) != `{"search":"1000/hour burst 100"}` {
+		t.Errorf("Expected the limit string in JSON, got %s, %v", data, err)
+	}
+	var decoded map[string]Limit
+	if err := json.Unmarshal(data, &decoded); err != nil || decoded["search"] != base {
+		t.Errorf("Expected the JSON round trip, got %+v, %v", decoded, err)
+	}
+
+	rateLimit := NewRateLimit(base)
+	if rateLimit.BurstSize != 100 || rateLimit.Limit() != base {
+		t.Errorf("Expected the config rate limit to carry the burst, got %+v", rateLimit)
+	}
+}
+
+func TestBuilder_RateLimit(t *testing.T) {
+	base := MustParseLimit("10/minute")
+	limiter, err := New().Memory().RateLimit("search", base.Scale(0.5)).Build()
+	if err != nil {
+		t.Fatalf("Failed to build limiter: %v", err)
+	}
+	defer limiter.Close()
+
+	result, err := limiter.Check(context.Background(), "user:1", "search")
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if result.Rate() != MustParseLimit("5/minute") {
+		t.Errorf("Expected the scaled limit, got %s", result.Rate())
+	}
+
+	// The builder's algorithms take no burst
+	bursty, err := New().Memory().Limit("search", "10/minute burst 5").Build()
+	if err != nil {
+		t.Fatalf("Failed to build limiter: %v", err)
+	}
+	defer bursty.Close()
+	if _, err := bursty.Check(context.Background(), "user:1", "search"); err == nil || !strings.Contains(err.Error(), "burst") {
+		t.Errorf("Expected a burst to be rejected, got %v", err)
+	}
+}
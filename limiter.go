@@ -3,11 +3,13 @@ package ratelimit
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync"
 	"time"
 
 	"github.com/itsatony/gorly/algorithms"
+	"github.com/itsatony/gorly/internal/core"
 	"github.com/itsatony/gorly/stores"
 )
 
@@ -40,7 +42,7 @@ func NewRateLimiter(config *Config) (RateLimiter, error) {
 	}
 
 	// Create algorithm
-	algorithm, err := createAlgorithm(config.Algorithm)
+	algorithm, err := createAlgorithm(config.Algorithm, config.StateCompression[config.Algorithm], config.WindowAlignment)
 	if err != nil {
 		store.Close() // Clean up store on error
 		return nil, NewRateLimitError(ErrorTypeAlgorithm, "failed to create algorithm", err)
@@ -290,8 +292,26 @@ func createStore(config *Config) (Store, error) {
 			MaxRetries:  config.Redis.MaxRetries,
 			Timeout:     config.Redis.Timeout,
 			TLS:         config.Redis.TLS,
+			Secrets:     storeSecrets(config.Redis.secretsProvider()),
+		}
+		if len(config.Redis.Shards) > 0 {
+			return stores.NewShardedRedisStore(stores.ShardedRedisConfig{
+				Addresses: config.Redis.Shards,
+				Redis:     redisConfig,
+				Sharding: stores.ShardedConfig{
+					FailurePolicy: config.Redis.ShardFailurePolicy,
+				},
+			})
 		}
 		return stores.NewRedisStore(redisConfig)
+	case "postgres":
+		return stores.NewPostgresStore(stores.PostgresConfig{
+			DSN:      config.Postgres.DSN,
+			Driver:   config.Postgres.Driver,
+			Table:    config.Postgres.Table,
+			Unlogged: config.Postgres.Unlogged,
+			Migrate:  true,
+		})
 	case "memory":
 		// Convert to stores.MemoryConfig with defaults
 		memoryConfig := stores.MemoryConfig{
@@ -306,22 +326,49 @@ func createStore(config *Config) (Store, error) {
 }
 
 // createAlgorithm creates an algorithm based on the configuration
-func createAlgorithm(algorithmName string) (Algorithm, error) {
+func createAlgorithm(algorithmName string, compression CompressionConfig, alignment string) (Algorithm, error) {
+	stateCompression := algorithms.CompressionConfig{
+		Algorithm: compression.Algorithm,
+		Threshold: compression.Threshold,
+	}
+
 	switch algorithmName {
 	case "token_bucket":
 		// Create a wrapper for the token bucket algorithm
+		algorithm := algorithms.NewTokenBucketAlgorithm()
+		if err := algorithm.SetCompression(stateCompression); err != nil {
+			return nil, err
+		}
 		return &tokenBucketWrapper{
-			algorithm: algorithms.NewTokenBucketAlgorithm(),
+			algorithm: algorithm,
 		}, nil
 	case "sliding_window":
 		// Create a wrapper for the sliding window algorithm
+		algorithm := algorithms.NewSlidingWindowAlgorithm()
+		if err := algorithm.SetCompression(stateCompression); err != nil {
+			return nil, err
+		}
 		return &slidingWindowWrapper{
-			algorithm: algorithms.NewSlidingWindowAlgorithm(),
+			algorithm: algorithm,
+		}, nil
+	case "fixed_window":
+		algorithm := algorithms.NewFixedWindowAlgorithm()
+		if err := algorithm.SetAlignment(algorithms.WindowAlignment(alignment)); err != nil {
+			return nil, err
+		}
+		return &fixedWindowWrapper{
+			algorithm: algorithm,
 		}, nil
 	case "gcra":
 		// TODO: Implement GCRA algorithm
 		return nil, fmt.Errorf("GCRA algorithm not implemented yet")
 	default:
+		if factory, ok := core.RegisteredAlgorithm(algorithmName); ok {
+			if custom, ok := factory().(*customAlgorithm); ok {
+				return custom.algorithm, nil
+			}
+			return nil, fmt.Errorf("factory of algorithm %s returned nil", algorithmName)
+		}
 		return nil, fmt.Errorf("unknown algorithm: %s", algorithmName)
 	}
 }
@@ -388,6 +435,23 @@ func (sa *storeAdapter) Delete(ctx context.Context, key string) error {
 	return sa.store.Delete(ctx, key)
 }
 
+func (sa *storeAdapter) IncrementBy(ctx context.Context, key string, amount int64, expiration time.Duration) (int64, error) {
+	return sa.store.IncrementBy(ctx, key, amount, expiration)
+}
+
+// RunScript runs a script if the store runs scripts, such as Redis
+func (sa *storeAdapter) RunScript(ctx context.Context, script string, keys []string, args ...interface{}) (interface{}, error) {
+	runner, ok := sa.store.(algorithms.ScriptRunner)
+	if !ok {
+		return nil, algorithms.ErrScriptsUnsupported
+	}
+	result, err := runner.RunScript(ctx, script, keys, args...)
+	if errors.Is(err, stores.ErrScriptsUnsupported) {
+		return nil, algorithms.ErrScriptsUnsupported
+	}
+	return result, err
+}
+
 // tokenBucketWrapper wraps the algorithms.TokenBucketAlgorithm to match our Algorithm interface
 type tokenBucketWrapper struct {
 	algorithm *algorithms.TokenBucketAlgorithm
@@ -471,3 +535,37 @@ func (sww *slidingWindowWrapper) GetWindowInfo(ctx context.Context, store Store,
 	storeAdapter := &storeAdapter{store: store}
 	return sww.algorithm.GetWindowInfo(ctx, storeAdapter, key, limit, window)
 }
+
+// fixedWindowWrapper wraps the algorithms.FixedWindowAlgorithm to match our Algorithm interface
+type fixedWindowWrapper struct {
+	algorithm *algorithms.FixedWindowAlgorithm
+}
+
+func (fww *fixedWindowWrapper) Name() string {
+	return fww.algorithm.Name()
+}
+
+func (fww *fixedWindowWrapper) Allow(ctx context.Context, store Store, key string, limit int64, window time.Duration, n int64) (*Result, error) {
+	storeAdapter := &storeAdapter{store: store}
+
+	algorithmResult, err := fww.algorithm.Allow(ctx, storeAdapter, key, limit, window, n)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Result{
+		Allowed:    algorithmResult.Allowed,
+		Remaining:  algorithmResult.Remaining,
+		RetryAfter: algorithmResult.RetryAfter,
+		ResetTime:  algorithmResult.ResetTime,
+		Limit:      algorithmResult.Limit,
+		Window:     algorithmResult.Window,
+		Used:       algorithmResult.Used,
+		Algorithm:  algorithmResult.Algorithm,
+	}, nil
+}
+
+func (fww *fixedWindowWrapper) Reset(ctx context.Context, store Store, key string) error {
+	storeAdapter := &storeAdapter{store: store}
+	return fww.algorithm.Reset(ctx, storeAdapter, key)
+}
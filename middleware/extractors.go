@@ -0,0 +1,120 @@
+// middleware/extractors.go
+package middleware
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/itsatony/gorly"
+)
+
+// ParameterBasedEntityExtractor extracts entities from URL parameters
+type ParameterBasedEntityExtractor struct {
+	ParamMapping      map[string]string // parameter name -> entity type
+	FallbackExtractor EntityExtractor
+}
+
+func (e *ParameterBasedEntityExtractor) Extract(req *RequestInfo) (entityID, entityType string, err error) {
+	// Check if we have parameters in metadata
+	if paramsInterface, exists := req.Metadata["params"]; exists {
+		if params, ok := paramsInterface.(map[string]string); ok {
+			for paramName, entityType := range e.ParamMapping {
+				if paramValue, exists := params[paramName]; exists && paramValue != "" {
+					return entityType + ":" + paramValue, entityType, nil
+				}
+			}
+		}
+	}
+
+	// Fall back to standard extraction
+	if e.FallbackExtractor != nil {
+		return e.FallbackExtractor.Extract(req)
+	}
+
+	return "", "", fmt.Errorf("no entity found")
+}
+
+// ContentTypeBasedScopeExtractor extracts scope based on request Content-Type
+type ContentTypeBasedScopeExtractor struct {
+	ContentTypeScopes map[string]string
+	DefaultScope      string
+}
+
+func (e *ContentTypeBasedScopeExtractor) Extract(req *RequestInfo) (scope string, err error) {
+	// Check Content-Type header
+	if contentTypeHeaders, exists := req.Headers["Content-Type"]; exists && len(contentTypeHeaders) > 0 {
+		contentType := strings.Split(contentTypeHeaders[0], ";")[0] // Remove charset etc.
+		contentType = strings.TrimSpace(strings.ToLower(contentType))
+
+		if scopeName, exists := e.ContentTypeScopes[contentType]; exists {
+			return scopeName, nil
+		}
+	}
+
+	return e.DefaultScope, nil
+}
+
+// PathBasedTierExtractor extracts tier information from request path
+type PathBasedTierExtractor struct {
+	PathTiers   map[string]string
+	DefaultTier string
+}
+
+func (t *PathBasedTierExtractor) Extract(req *RequestInfo) (tier string, err error) {
+	// Check if path matches any tier pattern
+	for pathPrefix, tierName := range t.PathTiers {
+		if strings.HasPrefix(req.Path, pathPrefix) {
+			return tierName, nil
+		}
+	}
+
+	// Fall back to default tier
+	if t.DefaultTier != "" {
+		return t.DefaultTier, nil
+	}
+
+	return ratelimit.TierFree, nil
+}
+
+// HeaderBasedEntityExtractor extracts entities based on configurable headers
+type HeaderBasedEntityExtractor struct {
+	EntityHeaders map[string]string // header -> entity type mapping
+	DefaultEntityExtractor
+}
+
+func (e *HeaderBasedEntityExtractor) Extract(req *RequestInfo) (entityID, entityType string, err error) {
+	// First check custom entity headers
+	for header, entType := range e.EntityHeaders {
+		if values, exists := req.Headers[header]; exists && len(values) > 0 {
+			if values[0] != "" {
+				return entType + ":" + values[0], entType, nil
+			}
+		}
+	}
+
+	// Fall back to default extraction
+	return e.DefaultEntityExtractor.Extract(req)
+}
+
+// CustomEntityExtractor allows custom entity extraction logic
+type CustomEntityExtractor struct {
+	CustomExtract func(*RequestInfo) (string, string, error)
+	Fallback      EntityExtractor
+}
+
+func (e *CustomEntityExtractor) Extract(req *RequestInfo) (entityID, entityType string, err error) {
+	// Try custom extraction first
+	if e.CustomExtract != nil {
+		id, typ, err := e.CustomExtract(req)
+		if err == nil && id != "" {
+			return id, typ, nil
+		}
+	}
+
+	// Fall back to default extraction
+	if e.Fallback != nil {
+		return e.Fallback.Extract(req)
+	}
+
+	return "", "", fmt.Errorf("no entity extraction method available")
+}
@@ -0,0 +1,217 @@
+// middleware/headers.go
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/itsatony/gorly"
+)
+
+// DefaultHeaderPrefix prefixes the rate limit response headers
+const DefaultHeaderPrefix = "X-RateLimit-"
+
+// responseHeaderKeys holds the header names for one prefix, so they are concatenated once
+// rather than on every request
+type responseHeaderKeys struct {
+	limit      string
+	remaining  string
+	used       string
+	window     string
+	retryAfter string
+	reset      string
+	algorithm  string
+
+	// canonical holds the same names in canonical MIME form, for writing into http.Header directly
+	canonical *responseHeaderKeys
+}
+
+// defaultHeaderKeys are the header names for DefaultHeaderPrefix
+var defaultHeaderKeys = newResponseHeaderKeys(DefaultHeaderPrefix)
+
+// headerKeyCache maps custom prefixes to their *responseHeaderKeys
+var headerKeyCache sync.Map
+
+// newResponseHeaderKeys builds the header names for a prefix
+func newResponseHeaderKeys(prefix string) *responseHeaderKeys {
+	keys := &responseHeaderKeys{
+		limit:      prefix + "Limit",
+		remaining:  prefix + "Remaining",
+		used:       prefix + "Used",
+		window:     prefix + "Window",
+		retryAfter: prefix + "Retry-After",
+		reset:      prefix + "Reset",
+		algorithm:  prefix + "Algorithm",
+	}
+	keys.canonical = &responseHeaderKeys{
+		limit:      http.CanonicalHeaderKey(keys.limit),
+		remaining:  http.CanonicalHeaderKey(keys.remaining),
+		used:       http.CanonicalHeaderKey(keys.used),
+		window:     http.CanonicalHeaderKey(keys.window),
+		retryAfter: http.CanonicalHeaderKey(keys.retryAfter),
+		reset:      http.CanonicalHeaderKey(keys.reset),
+		algorithm:  http.CanonicalHeaderKey(keys.algorithm),
+	}
+	return keys
+}
+
+// headerKeysFor returns the cached header names for a prefix
+func headerKeysFor(prefix string) *responseHeaderKeys {
+	if prefix == "" || prefix == DefaultHeaderPrefix {
+		return defaultHeaderKeys
+	}
+	if keys, ok := headerKeyCache.Load(prefix); ok {
+		return keys.(*responseHeaderKeys)
+	}
+	keys, _ := headerKeyCache.LoadOrStore(prefix, newResponseHeaderKeys(prefix))
+	return keys.(*responseHeaderKeys)
+}
+
+// responseHeaderValues holds the formatted numeric header values of a result.
+// Empty values are omitted from the response.
+type responseHeaderValues struct {
+	limit      string
+	remaining  string
+	used       string
+	window     string
+	retryAfter string
+	reset      string
+}
+
+// formatResponseHeaderValues appends every numeric value to one stack buffer and
+// slices the values out of a single string, so a response costs one allocation
+// for all of its numbers
+func formatResponseHeaderValues(result *ratelimit.Result) responseHeaderValues {
+	var buf [6 * 20]byte // six values of at most 20 digits each
+	var ends [6]int
+
+	b := strconv.AppendInt(buf[:0], result.Limit, 10)
+	ends[0] = len(b)
+	b = strconv.AppendInt(b, result.Remaining, 10)
+	ends[1] = len(b)
+	b = strconv.AppendInt(b, result.Used, 10)
+	ends[2] = len(b)
+	if result.Window > 0 {
+		b = strconv.AppendInt(b, int64(result.Window.Seconds()), 10)
+	}
+	ends[3] = len(b)
+	if !result.Allowed && result.RetryAfter > 0 {
+		b = strconv.AppendInt(b, int64(result.RetryAfter.Seconds()), 10)
+	}
+	ends[4] = len(b)
+	if !result.ResetTime.IsZero() {
+		b = strconv.AppendInt(b, result.ResetTime.Unix(), 10)
+	}
+	ends[5] = len(b)
+
+	s := string(b)
+	return responseHeaderValues{
+		limit:      s[:ends[0]],
+		remaining:  s[ends[0]:ends[1]],
+		used:       s[ends[1]:ends[2]],
+		window:     s[ends[2]:ends[3]],
+		retryAfter: s[ends[3]:ends[4]],
+		reset:      s[ends[4]:ends[5]],
+	}
+}
+
+// WriteResponseHeaders passes every rate limit response header to set, without building
+// an intermediate map. Custom headers come first so standard headers take precedence.
+// Example: middleware.WriteResponseHeaders(result, &config.ResponseConfig, c.Set)
+func WriteResponseHeaders(result *ratelimit.Result, config *ResponseConfig, set func(key, value string)) {
+	WriteScopeResponseHeaders(result, "", config, set)
+}
+
+// WriteScopeResponseHeaders is WriteResponseHeaders honoring the header mode of a scope
+// Example: middleware.WriteScopeResponseHeaders(result, reqInfo.Scope, &config.ResponseConfig, c.Set)
+func WriteScopeResponseHeaders(result *ratelimit.Result, scope string, config *ResponseConfig, set func(key, value string)) {
+	for k, v := range config.CustomHeaders {
+		set(k, v)
+	}
+	writeStandardHeaders(result, config.HeaderModeFor(scope), headerKeysFor(config.HeaderPrefix),
+		renamingSetter(config.HeaderNames, false, set))
+}
+
+// SetResponseHeaders writes the rate limit response headers straight into h.
+// Header names are canonicalized once per prefix and all values share one backing
+// array, which makes this the cheapest option for net/http based frameworks.
+// Example: middleware.SetResponseHeaders(w.Header(), result, &config.ResponseConfig)
+func SetResponseHeaders(h http.Header, result *ratelimit.Result, config *ResponseConfig) {
+	SetScopeResponseHeaders(h, result, "", config)
+}
+
+// SetScopeResponseHeaders is SetResponseHeaders honoring the header mode of a scope
+// Example: middleware.SetScopeResponseHeaders(w.Header(), result, reqInfo.Scope, &config.ResponseConfig)
+func SetScopeResponseHeaders(h http.Header, result *ratelimit.Result, scope string, config *ResponseConfig) {
+	values := make([]string, 0, len(config.CustomHeaders)+9)
+	set := func(key, value string) {
+		values = append(values, value)
+		h[key] = values[len(values)-1 : len(values) : len(values)]
+	}
+
+	for k, v := range config.CustomHeaders {
+		set(http.CanonicalHeaderKey(k), v)
+	}
+	writeStandardHeaders(result, config.HeaderModeFor(scope), headerKeysFor(config.HeaderPrefix).canonical,
+		renamingSetter(config.HeaderNames, true, set))
+}
+
+// renamingSetter applies header renames before calling set. Renames match header names
+// case-insensitively; a rename to "" drops the header.
+func renamingSetter(names map[string]string, canonical bool, set func(key, value string)) func(key, value string) {
+	if len(names) == 0 {
+		return set
+	}
+	return func(key, value string) {
+		for from, to := range names {
+			if !strings.EqualFold(from, key) {
+				continue
+			}
+			if to == "" {
+				return
+			}
+			key = to
+			if canonical {
+				key = http.CanonicalHeaderKey(to)
+			}
+			break
+		}
+		set(key, value)
+	}
+}
+
+// writeStandardHeaders passes the standard rate limit headers of a result allowed by mode to set
+func writeStandardHeaders(result *ratelimit.Result, mode ratelimit.HeaderMode, keys *responseHeaderKeys, set func(key, value string)) {
+	switch mode {
+	case ratelimit.HeadersNone:
+		return
+	case ratelimit.HeadersMinimal:
+		if !result.Allowed && result.RetryAfter > 0 {
+			set("Retry-After", strconv.FormatInt(int64(result.RetryAfter.Seconds()), 10))
+		}
+		return
+	}
+
+	values := formatResponseHeaderValues(result)
+
+	set(keys.limit, values.limit)
+	set(keys.remaining, values.remaining)
+	set(keys.used, values.used)
+
+	if values.window != "" {
+		set(keys.window, values.window)
+	}
+
+	if values.retryAfter != "" {
+		set(keys.retryAfter, values.retryAfter)
+		set("Retry-After", values.retryAfter) // Standard header
+	}
+
+	if values.reset != "" {
+		set(keys.reset, values.reset)
+	}
+
+	set(keys.algorithm, result.Algorithm)
+}
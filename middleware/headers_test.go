@@ -0,0 +1,239 @@
+// middleware/headers_test.go
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	ratelimit "github.com/itsatony/gorly"
+)
+
+// sprintfResponseHeaders is the fmt.Sprintf based header builder that
+// BuildResponseHeaders replaced, kept as a reference for output and allocations
+func sprintfResponseHeaders(result *ratelimit.Result, config *ResponseConfig) map[string]string {
+	if !config.IncludeHeaders {
+		return config.CustomHeaders
+	}
+
+	headers := make(map[string]string)
+	for k, v := range config.CustomHeaders {
+		headers[k] = v
+	}
+
+	prefix := config.HeaderPrefix
+	if prefix == "" {
+		prefix = "X-RateLimit-"
+	}
+
+	headers[prefix+"Limit"] = fmt.Sprintf("%d", result.Limit)
+	headers[prefix+"Remaining"] = fmt.Sprintf("%d", result.Remaining)
+	headers[prefix+"Used"] = fmt.Sprintf("%d", result.Used)
+	if result.Window > 0 {
+		headers[prefix+"Window"] = fmt.Sprintf("%d", int64(result.Window.Seconds()))
+	}
+	if !result.Allowed && result.RetryAfter > 0 {
+		headers[prefix+"Retry-After"] = fmt.Sprintf("%d", int64(result.RetryAfter.Seconds()))
+		headers["Retry-After"] = fmt.Sprintf("%d", int64(result.RetryAfter.Seconds()))
+	}
+	if !result.ResetTime.IsZero() {
+		headers[prefix+"Reset"] = fmt.Sprintf("%d", result.ResetTime.Unix())
+	}
+	headers[prefix+"Algorithm"] = result.Algorithm
+
+	return headers
+}
+
+// benchmarkResult is a typical denied result carrying every header
+var benchmarkResult = &ratelimit.Result{
+	Allowed:    false,
+	Remaining:  0,
+	Limit:      1000,
+	Used:       1000,
+	Window:     time.Hour,
+	RetryAfter: 1234 * time.Second,
+	ResetTime:  time.Unix(1700000000, 0),
+	Algorithm:  "sliding_window",
+}
+
+func TestWriteResponseHeadersMatchesSprintf(t *testing.T) {
+	results := []*ratelimit.Result{
+		benchmarkResult,
+		{Allowed: true, Remaining: 99, Limit: 100, Used: 1, Window: time.Minute, Algorithm: "token_bucket"},
+		{Allowed: false, Remaining: -3, Limit: 5, Used: 8, RetryAfter: 500 * time.Millisecond},
+		{},
+	}
+	configs := []*ResponseConfig{
+		{IncludeHeaders: true, HeaderPrefix: "X-RateLimit-"},
+		{IncludeHeaders: true},
+		{IncludeHeaders: true, HeaderPrefix: "RateLimit-", CustomHeaders: map[string]string{"X-Service": "api"}},
+		{IncludeHeaders: false, CustomHeaders: map[string]string{"X-Service": "api"}},
+	}
+
+	for i, result := range results {
+		for j, config := range configs {
+			want := sprintfResponseHeaders(result, config)
+			got := BuildResponseHeaders(result, config)
+			if len(got) != len(want) {
+				t.Errorf("Result %d, config %d: expected %v, got %v", i, j, want, got)
+				continue
+			}
+			for key, value := range want {
+				if got[key] != value {
+					t.Errorf("Result %d, config %d: header %s expected %q, got %q", i, j, key, value, got[key])
+				}
+			}
+		}
+	}
+}
+
+func TestWriteResponseHeadersIntoHTTPHeader(t *testing.T) {
+	config := &ResponseConfig{
+		IncludeHeaders: true,
+		CustomHeaders:  map[string]string{"X-RateLimit-Limit": "overridden"},
+	}
+	header := make(http.Header)
+	WriteResponseHeaders(benchmarkResult, config, header.Set)
+
+	if header.Get("X-RateLimit-Limit") != "1000" {
+		t.Errorf("Expected standard headers to take precedence over custom ones, got %q", header.Get("X-RateLimit-Limit"))
+	}
+	if header.Get("Retry-After") != "1234" || header.Get("X-RateLimit-Reset") != "1700000000" {
+		t.Errorf("Unexpected headers: %v", header)
+	}
+}
+
+func TestSetResponseHeaders(t *testing.T) {
+	config := &ResponseConfig{
+		IncludeHeaders: true,
+		CustomHeaders:  map[string]string{"x-service": "api"},
+	}
+	header := make(http.Header)
+	SetResponseHeaders(header, benchmarkResult, config)
+
+	want := make(http.Header)
+	WriteResponseHeaders(benchmarkResult, config, want.Set)
+	if len(header) != len(want) {
+		t.Fatalf("Expected %v, got %v", want, header)
+	}
+	for key := range want {
+		if header.Get(key) != want.Get(key) || len(header[key]) != 1 {
+			t.Errorf("Header %s: expected %q, got %v", key, want.Get(key), header[key])
+		}
+	}
+}
+
+func TestScopeHeaderModes(t *testing.T) {
+	config := &ResponseConfig{
+		IncludeHeaders: true,
+		HeaderMode:     ratelimit.HeadersMinimal,
+		ScopeHeaderModes: map[string]ratelimit.HeaderMode{
+			"partner": ratelimit.HeadersFull,
+			"stealth": ratelimit.HeadersNone,
+		},
+		CustomHeaders: map[string]string{"X-Service": "api"},
+	}
+
+	minimal := make(http.Header)
+	SetScopeResponseHeaders(minimal, benchmarkResult, "search", config)
+	if len(minimal) != 2 || minimal.Get("Retry-After") != "1234" || minimal.Get("X-Service") != "api" {
+		t.Errorf("Expected only Retry-After and custom headers, got %v", minimal)
+	}
+
+	full := make(http.Header)
+	SetScopeResponseHeaders(full, benchmarkResult, "partner", config)
+	if full.Get("X-RateLimit-Limit") != "1000" {
+		t.Errorf("Expected the full set for the partner scope, got %v", full)
+	}
+
+	none := make(http.Header)
+	WriteScopeResponseHeaders(benchmarkResult, "stealth", config, none.Set)
+	if len(none) != 1 {
+		t.Errorf("Expected only custom headers, got %v", none)
+	}
+
+	allowed := *benchmarkResult
+	allowed.Allowed = true
+	quiet := make(http.Header)
+	SetScopeResponseHeaders(quiet, &allowed, "search", config)
+	if quiet.Get("Retry-After") != "" {
+		t.Errorf("Expected no Retry-After on allowed requests, got %v", quiet)
+	}
+
+	if (&ResponseConfig{}).HeaderModeFor("") != ratelimit.HeadersNone {
+		t.Error("Expected headers to stay off without IncludeHeaders or a header mode")
+	}
+}
+
+func TestHeaderNames(t *testing.T) {
+	config := &ResponseConfig{
+		IncludeHeaders: true,
+		HeaderNames: map[string]string{
+			"X-RateLimit-Limit": "RateLimit-Limit",
+			"x-ratelimit-used":  "",
+		},
+	}
+
+	header := make(http.Header)
+	SetResponseHeaders(header, benchmarkResult, config)
+	if header.Get("RateLimit-Limit") != "1000" || header.Get("X-RateLimit-Limit") != "" {
+		t.Errorf("Expected X-RateLimit-Limit to be renamed, got %v", header)
+	}
+	if _, ok := header["X-Ratelimit-Used"]; ok {
+		t.Errorf("Expected X-RateLimit-Used to be dropped, got %v", header)
+	}
+
+	written := make(map[string]string)
+	WriteResponseHeaders(benchmarkResult, config, func(k, v string) { written[k] = v })
+	if written["RateLimit-Limit"] != "1000" {
+		t.Errorf("Expected renames with WriteResponseHeaders, got %v", written)
+	}
+}
+
+func TestHeaderKeysForCachesPrefixes(t *testing.T) {
+	if headerKeysFor("") != defaultHeaderKeys || headerKeysFor(DefaultHeaderPrefix) != defaultHeaderKeys {
+		t.Error("Expected the default prefix to use the precomputed keys")
+	}
+	first := headerKeysFor("RateLimit-")
+	if first != headerKeysFor("RateLimit-") {
+		t.Error("Expected custom prefixes to be cached")
+	}
+	if first.remaining != "RateLimit-Remaining" {
+		t.Errorf("Unexpected header name %q", first.remaining)
+	}
+}
+
+func BenchmarkBuildResponseHeadersSprintf(b *testing.B) {
+	config := &DefaultConfig().ResponseConfig
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		sprintfResponseHeaders(benchmarkResult, config)
+	}
+}
+
+func BenchmarkBuildResponseHeaders(b *testing.B) {
+	config := &DefaultConfig().ResponseConfig
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		BuildResponseHeaders(benchmarkResult, config)
+	}
+}
+
+func BenchmarkWriteResponseHeaders(b *testing.B) {
+	config := &DefaultConfig().ResponseConfig
+	header := make(http.Header, 10)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		WriteResponseHeaders(benchmarkResult, config, header.Set)
+	}
+}
+
+func BenchmarkSetResponseHeaders(b *testing.B) {
+	config := &DefaultConfig().ResponseConfig
+	header := make(http.Header, 10)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		SetResponseHeaders(header, benchmarkResult, config)
+	}
+}
@@ -38,11 +38,23 @@ type HTTPMiddlewareConfig struct {
 	// SkipPaths contains paths to skip rate limiting
 	SkipPaths []string
 
+	// SkipMethods contains HTTP methods to skip rate limiting (e.g. "HEAD")
+	SkipMethods []string
+
+	// SkipPreflight skips CORS preflight requests (OPTIONS with Access-Control-Request-Method)
+	SkipPreflight bool
+
 	// Headers to add to responses
 	AddHeaders bool
 
 	// Custom response when rate limited
 	CustomResponse *HTTPRateLimitResponse
+
+	// DeniedStatusCode is the status for denied requests (default: 429)
+	DeniedStatusCode int
+
+	// ScopeStatusCodes overrides DeniedStatusCode for specific scopes (e.g. 503 for overload scopes)
+	ScopeStatusCodes map[string]int
 }
 
 // HTTPEntityExtractor extracts an AuthEntity from an HTTP request
@@ -90,8 +102,8 @@ func NewHTTPMiddleware(config *HTTPMiddlewareConfig) (*HTTPMiddleware, error) {
 // Middleware returns the HTTP middleware function
 func (m *HTTPMiddleware) Middleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Check if path should be skipped
-		if m.shouldSkipPath(r.URL.Path) {
+		// Check if path or method should be skipped
+		if m.shouldSkipPath(r.URL.Path) || m.shouldSkipMethod(r) {
 			next.ServeHTTP(w, r)
 			return
 		}
@@ -123,7 +135,7 @@ func (m *HTTPMiddleware) Middleware(next http.Handler) http.Handler {
 
 		// Check if request is allowed
 		if !result.Allowed {
-			m.handleRateLimit(w, r, result)
+			m.handleRateLimit(w, r, scope, result)
 			return
 		}
 
@@ -147,6 +159,19 @@ func (m *HTTPMiddleware) shouldSkipPath(path string) bool {
 	return false
 }
 
+// shouldSkipMethod checks if the request method should skip rate limiting
+func (m *HTTPMiddleware) shouldSkipMethod(r *http.Request) bool {
+	if m.config.SkipPreflight && r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+		return true
+	}
+	for _, method := range m.config.SkipMethods {
+		if strings.EqualFold(method, r.Method) {
+			return true
+		}
+	}
+	return false
+}
+
 // addRateLimitHeaders adds standard rate limit headers to the response
 func (m *HTTPMiddleware) addRateLimitHeaders(w http.ResponseWriter, result *ratelimit.Result) {
 	w.Header().Set("X-RateLimit-Limit", strconv.FormatInt(result.Limit, 10))
@@ -159,7 +184,7 @@ func (m *HTTPMiddleware) addRateLimitHeaders(w http.ResponseWriter, result *rate
 }
 
 // handleRateLimit handles rate limit exceeded responses
-func (m *HTTPMiddleware) handleRateLimit(w http.ResponseWriter, r *http.Request, result *ratelimit.Result) {
+func (m *HTTPMiddleware) handleRateLimit(w http.ResponseWriter, r *http.Request, scope string, result *ratelimit.Result) {
 	if m.config.CustomResponse != nil {
 		// Use custom response
 		for key, value := range m.config.CustomResponse.Headers {
@@ -181,7 +206,7 @@ func (m *HTTPMiddleware) handleRateLimit(w http.ResponseWriter, r *http.Request,
 	// Default rate limit response
 	m.addRateLimitHeaders(w, result)
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusTooManyRequests)
+	w.WriteHeader(m.deniedStatusCode(scope))
 
 	response := map[string]interface{}{
 		"error":       "Rate limit exceeded",
@@ -193,6 +218,17 @@ func (m *HTTPMiddleware) handleRateLimit(w http.ResponseWriter, r *http.Request,
 	json.NewEncoder(w).Encode(response)
 }
 
+// deniedStatusCode returns the configured status for a denied request in scope
+func (m *HTTPMiddleware) deniedStatusCode(scope string) int {
+	if code, ok := m.config.ScopeStatusCodes[scope]; ok && code > 0 {
+		return code
+	}
+	if m.config.DeniedStatusCode > 0 {
+		return m.config.DeniedStatusCode
+	}
+	return http.StatusTooManyRequests
+}
+
 // DefaultIPEntityExtractor extracts entity information based on IP address
 func DefaultIPEntityExtractor(r *http.Request) (ratelimit.AuthEntity, error) {
 	ip := getClientIP(r)
@@ -4,6 +4,7 @@ package middleware
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/itsatony/gorly"
 )
@@ -61,7 +62,9 @@ type Config struct {
 	ResponseConfig ResponseConfig
 
 	// Skip conditions
-	SkipFunc SkipFunc
+	SkipFunc      SkipFunc
+	SkipMethods   []string // HTTP methods that never consume quota (e.g. "HEAD")
+	SkipPreflight bool     // Skip CORS preflight (OPTIONS with Access-Control-Request-Method)
 
 	// Error handling
 	ErrorHandler ErrorHandler
@@ -92,6 +95,18 @@ type TierExtractor interface {
 // SkipFunc determines if rate limiting should be skipped for this request
 type SkipFunc func(req *RequestInfo) bool
 
+// CombineSkipFuncs combines multiple skip functions with OR logic
+func CombineSkipFuncs(skipFuncs ...SkipFunc) SkipFunc {
+	return func(req *RequestInfo) bool {
+		for _, skipFunc := range skipFuncs {
+			if skipFunc != nil && skipFunc(req) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
 // ErrorHandler handles rate limiting errors
 type ErrorHandler func(req *RequestInfo, err error) bool // return true to continue, false to stop
 
@@ -106,13 +121,17 @@ type Logger interface {
 // ResponseConfig configures rate limit responses
 type ResponseConfig struct {
 	// Status codes
-	RateLimitedStatusCode int // Default: 429
-	ErrorStatusCode       int // Default: 500
+	RateLimitedStatusCode int            // Default: 429
+	ErrorStatusCode       int            // Default: 500
+	ScopeStatusCodes      map[string]int // Per-scope denial status overrides (e.g. 503 for overload scopes)
 
 	// Response headers
-	IncludeHeaders bool   // Include rate limit headers
-	HeaderPrefix   string // Header prefix (default: "X-RateLimit-")
-	CustomHeaders  map[string]string
+	IncludeHeaders   bool                            // Include rate limit headers
+	HeaderMode       ratelimit.HeaderMode            // Overrides IncludeHeaders: HeadersFull, HeadersMinimal (Retry-After only) or HeadersNone
+	ScopeHeaderModes map[string]ratelimit.HeaderMode // Per-scope header mode overrides
+	HeaderNames      map[string]string               // Header renames, e.g. "X-RateLimit-Limit" -> "RateLimit-Limit"; "" drops a header
+	HeaderPrefix     string                          // Header prefix (default: "X-RateLimit-")
+	CustomHeaders    map[string]string
 
 	// Response body
 	RateLimitedResponse []byte // Custom rate limited response
@@ -122,6 +141,31 @@ type ResponseConfig struct {
 	ContentType string // Default: "application/json"
 }
 
+// DeniedStatusCode returns the HTTP status to use when a request in scope is denied
+func (rc *ResponseConfig) DeniedStatusCode(scope string) int {
+	if code, ok := rc.ScopeStatusCodes[scope]; ok && code > 0 {
+		return code
+	}
+	if rc.RateLimitedStatusCode > 0 {
+		return rc.RateLimitedStatusCode
+	}
+	return 429
+}
+
+// HeaderModeFor returns the header mode of a scope
+func (rc *ResponseConfig) HeaderModeFor(scope string) ratelimit.HeaderMode {
+	if mode, ok := rc.ScopeHeaderModes[scope]; ok && mode != "" {
+		return mode
+	}
+	if rc.HeaderMode != "" {
+		return rc.HeaderMode
+	}
+	if rc.IncludeHeaders {
+		return ratelimit.HeadersFull
+	}
+	return ratelimit.HeadersNone
+}
+
 // DefaultConfig returns default middleware configuration
 func DefaultConfig() *Config {
 	return &Config{
@@ -132,7 +176,7 @@ func DefaultConfig() *Config {
 			RateLimitedStatusCode: 429,
 			ErrorStatusCode:       500,
 			IncludeHeaders:        true,
-			HeaderPrefix:          "X-RateLimit-",
+			HeaderPrefix:          DefaultHeaderPrefix,
 			ContentType:           "application/json",
 			RateLimitedResponse:   []byte(`{"error":"Rate limit exceeded","retry_after_seconds":60}`),
 			ErrorResponse:         []byte(`{"error":"Internal server error"}`),
@@ -271,6 +315,23 @@ func (t *DefaultTierExtractor) Extract(req *RequestInfo) (tier string, err error
 // Helper Functions
 // ============================================================================
 
+// isExemptMethod reports whether the request method is excluded from rate limiting
+func isExemptMethod(req *RequestInfo, config *Config) bool {
+	if config.SkipPreflight && req.Method == "OPTIONS" {
+		for name, values := range req.Headers {
+			if strings.EqualFold(name, "Access-Control-Request-Method") && len(values) > 0 && values[0] != "" {
+				return true
+			}
+		}
+	}
+	for _, method := range config.SkipMethods {
+		if strings.EqualFold(method, req.Method) {
+			return true
+		}
+	}
+	return false
+}
+
 // extractIPAddress extracts IP address from request, handling proxies
 func extractIPAddress(req *RequestInfo) string {
 	// Check X-Forwarded-For header
@@ -313,6 +374,11 @@ func ProcessRequest(req *RequestInfo, config *Config) (*ratelimit.Result, error)
 		return &ratelimit.Result{Allowed: true}, nil
 	}
 
+	// Skip exempt methods and CORS preflight
+	if isExemptMethod(req, config) {
+		return &ratelimit.Result{Allowed: true}, nil
+	}
+
 	// Extract entity information
 	entityID, entityType, err := config.EntityExtractor.Extract(req)
 	if err != nil {
@@ -417,44 +483,18 @@ func ProcessRequest(req *RequestInfo, config *Config) (*ratelimit.Result, error)
 	return result, nil
 }
 
-// BuildResponseHeaders builds rate limit response headers
+// BuildResponseHeaders builds rate limit response headers.
+// Middleware writing straight into a response should prefer WriteResponseHeaders,
+// which avoids allocating the map.
 func BuildResponseHeaders(result *ratelimit.Result, config *ResponseConfig) map[string]string {
-	if !config.IncludeHeaders {
+	if config.HeaderModeFor("") == ratelimit.HeadersNone {
 		return config.CustomHeaders
 	}
 
-	headers := make(map[string]string)
-
-	// Copy custom headers first
-	for k, v := range config.CustomHeaders {
-		headers[k] = v
-	}
-
-	prefix := config.HeaderPrefix
-	if prefix == "" {
-		prefix = "X-RateLimit-"
-	}
-
-	// Standard rate limit headers
-	headers[prefix+"Limit"] = fmt.Sprintf("%d", result.Limit)
-	headers[prefix+"Remaining"] = fmt.Sprintf("%d", result.Remaining)
-	headers[prefix+"Used"] = fmt.Sprintf("%d", result.Used)
-
-	if result.Window > 0 {
-		headers[prefix+"Window"] = fmt.Sprintf("%d", int64(result.Window.Seconds()))
-	}
-
-	if !result.Allowed && result.RetryAfter > 0 {
-		headers[prefix+"Retry-After"] = fmt.Sprintf("%d", int64(result.RetryAfter.Seconds()))
-		headers["Retry-After"] = fmt.Sprintf("%d", int64(result.RetryAfter.Seconds())) // Standard header
-	}
-
-	if !result.ResetTime.IsZero() {
-		headers[prefix+"Reset"] = fmt.Sprintf("%d", result.ResetTime.Unix())
-	}
-
-	headers[prefix+"Algorithm"] = result.Algorithm
-
+	headers := make(map[string]string, len(config.CustomHeaders)+9)
+	WriteResponseHeaders(result, config, func(key, value string) {
+		headers[key] = value
+	})
 	return headers
 }
 
@@ -470,6 +510,27 @@ func (l *NoOpLogger) Warn(msg string, fields map[string]interface{})
 func (l *NoOpLogger) Error(msg string, err error, fields map[string]interface{}) {}
 func (l *NoOpLogger) Debug(msg string, fields map[string]interface{})            {}
 
+// PerformanceLogger logs performance metrics
+type PerformanceLogger struct{}
+
+func (l *PerformanceLogger) Info(msg string, fields map[string]interface{}) {
+	// Could integrate with your logging system
+	fmt.Printf("[INFO] %s: %+v\n", msg, fields)
+}
+
+func (l *PerformanceLogger) Warn(msg string, fields map[string]interface{}) {
+	fmt.Printf("[WARN] %s: %+v\n", msg, fields)
+}
+
+func (l *PerformanceLogger) Error(msg string, err error, fields map[string]interface{}) {
+	fmt.Printf("[ERROR] %s: %v, fields: %+v\n", msg, err, fields)
+}
+
+func (l *PerformanceLogger) Debug(msg string, fields map[string]interface{}) {
+	// Debug logs can be disabled in production
+	fmt.Printf("[DEBUG] %s: %+v\n", msg, fields)
+}
+
 // ============================================================================
 // Plugin Registry
 // ============================================================================
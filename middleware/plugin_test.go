@@ -9,40 +9,47 @@ import (
 	ratelimit "github.com/itsatony/gorly"
 )
 
-func TestPluginRegistry(t *testing.T) {
-	// Test that all expected plugins are registered
-	expectedPlugins := []string{"gin", "echo", "fiber", "chi"}
+// stubPlugin is a plugin for a framework-less test; framework plugins register themselves
+// from their adapter modules, such as ginlimit
+type stubPlugin struct{}
+
+func (p *stubPlugin) Name() string    { return "stub" }
+func (p *stubPlugin) Version() string { return "1.0.0" }
+
+func (p *stubPlugin) CreateMiddleware(limiter ratelimit.RateLimiter, config *Config) interface{} {
+	return func() {}
+}
 
-	registeredPlugins := List()
-	if len(registeredPlugins) == 0 {
-		t.Fatal("No plugins registered")
+func (p *stubPlugin) ExtractRequest(frameworkRequest interface{}) (*RequestInfo, error) {
+	return &RequestInfo{}, nil
+}
+
+func (p *stubPlugin) SendResponse(frameworkResponse interface{}, status int, headers map[string]string, body []byte) error {
+	return nil
+}
+
+func TestPluginRegistry(t *testing.T) {
+	registry := NewPluginRegistry()
+	if len(registry.List()) != 0 {
+		t.Fatalf("Expected a new registry to be empty, got %v", registry.List())
 	}
 
-	for _, expected := range expectedPlugins {
-		found := false
-		for _, registered := range registeredPlugins {
-			if registered == expected {
-				found = true
-				break
-			}
-		}
-		if !found {
-			t.Errorf("Plugin %s not found in registered plugins: %v", expected, registeredPlugins)
-		}
+	registry.Register(&stubPlugin{})
+	if plugins := registry.List(); len(plugins) != 1 || plugins[0] != "stub" {
+		t.Errorf("Expected the stub plugin to be registered, got %v", plugins)
 	}
 }
 
 func TestGlobalRegistry(t *testing.T) {
+	Register(&stubPlugin{})
+
 	// Test get functionality
-	ginPlugin, exists := Get("gin")
+	plugin, exists := Get("stub")
 	if !exists {
-		t.Error("Gin plugin not found in registry")
-	}
-	if ginPlugin == nil {
-		t.Error("Gin plugin is nil")
+		t.Fatal("Stub plugin not found in registry")
 	}
-	if ginPlugin.Name() != "gin" {
-		t.Errorf("Expected plugin name 'gin', got '%s'", ginPlugin.Name())
+	if plugin.Name() != "stub" {
+		t.Errorf("Expected plugin name 'stub', got '%s'", plugin.Name())
 	}
 
 	// Test non-existent plugin
@@ -324,3 +331,44 @@ func TestBuildResponseHeaders(t *testing.T) {
 		}
 	}
 }
+
+func TestResponseConfig_DeniedStatusCode(t *testing.T) {
+	config := DefaultConfig().ResponseConfig
+	config.ScopeStatusCodes = map[string]int{"overload": 503}
+
+	if code := config.DeniedStatusCode("global"); code != 429 {
+		t.Errorf("Expected default 429, got %d", code)
+	}
+	if code := config.DeniedStatusCode("overload"); code != 503 {
+		t.Errorf("Expected scope override 503, got %d", code)
+	}
+
+	empty := ResponseConfig{}
+	if code := empty.DeniedStatusCode("global"); code != 429 {
+		t.Errorf("Expected fallback 429 with zero config, got %d", code)
+	}
+}
+
+func TestIsExemptMethod(t *testing.T) {
+	config := DefaultConfig()
+	config.SkipMethods = []string{"HEAD"}
+	config.SkipPreflight = true
+
+	tests := []struct {
+		method   string
+		headers  map[string][]string
+		expected bool
+	}{
+		{"HEAD", nil, true},
+		{"GET", nil, false},
+		{"OPTIONS", nil, false},
+		{"OPTIONS", map[string][]string{"Access-Control-Request-Method": {"POST"}}, true},
+	}
+
+	for _, tt := range tests {
+		req := &RequestInfo{Method: tt.method, Headers: tt.headers}
+		if got := isExemptMethod(req, config); got != tt.expected {
+			t.Errorf("%s %v: expected %v, got %v", tt.method, tt.headers, tt.expected, got)
+		}
+	}
+}
@@ -5,20 +5,38 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
+
+	"github.com/itsatony/gorly/experimental/alerting"
 )
 
 // MonitoringServer provides HTTP endpoints for metrics and health checks
 type MonitoringServer struct {
 	limiter *ObservableLimiter
+	config  *MonitoringConfig
+	guard   *accessGuard
 	mux     *http.ServeMux
 }
 
 // NewMonitoringServer creates a new monitoring server
 func NewMonitoringServer(limiter *ObservableLimiter) *MonitoringServer {
+	return NewMonitoringServerWithConfig(limiter, &MonitoringConfig{})
+}
+
+// NewMonitoringServerWithConfig creates a monitoring server with access control.
+// Health endpoints stay open for probes and ignore the network allowlist; /debug is limited to admins, and /stats and
+// the metrics endpoints show tenant admins only the entities of their namespaces.
+// Example: ratelimit.NewMonitoringServerWithConfig(limiter, &ratelimit.MonitoringConfig{Authorizer: auth})
+func NewMonitoringServerWithConfig(limiter *ObservableLimiter, config *MonitoringConfig) *MonitoringServer {
+	if config == nil {
+		config = &MonitoringConfig{}
+	}
 	ms := &MonitoringServer{
 		limiter: limiter,
+		config:  config,
+		guard:   newAccessGuard(config),
 		mux:     http.NewServeMux(),
 	}
 
@@ -37,14 +55,26 @@ func (ms *MonitoringServer) GetHandler() http.Handler {
 }
 
 func (ms *MonitoringServer) setupRoutes() {
-	ms.mux.HandleFunc("/health", ms.handleHealth)
-	ms.mux.HandleFunc("/healthz", ms.handleHealth) // Kubernetes standard
-	ms.mux.HandleFunc("/ready", ms.handleReady)
-	ms.mux.HandleFunc("/metrics", ms.handleMetrics)
-	ms.mux.HandleFunc("/metrics/prometheus", ms.handlePrometheusMetrics)
-	ms.mux.HandleFunc("/stats", ms.handleStats)
-	ms.mux.HandleFunc("/debug", ms.handleDebug)
-	ms.mux.HandleFunc("/", ms.handleIndex)
+	ms.handle("/health", ms.handleHealth)
+	ms.handle("/healthz", ms.handleHealth) // Kubernetes standard
+	ms.handle("/ready", ms.handleReady)
+	ms.handle("/metrics", ms.authorized(ms.handleMetrics))
+	ms.handle("/metrics/prometheus", ms.authorized(ms.handlePrometheusMetrics))
+	ms.handle("/stats", ms.authorized(ms.handleStats))
+	ms.handle("/analytics", ms.authorized(ms.handleAnalytics))
+	ms.handle("/debug", ms.adminOnly(ms.handleDebug))
+	ms.handle(FederationScrapePath, ms.adminOnly(ms.handleFederate))
+	ms.handle(pprofPath, ms.adminOnly(ms.handlePprof))
+	ms.handle(goroutinesPath, ms.adminOnly(ms.handleGoroutines))
+	ms.handle("/", ms.authorized(ms.handleIndex))
+}
+
+// handle registers an endpoint, or a not found handler if it is disabled
+func (ms *MonitoringServer) handle(path string, handler http.HandlerFunc) {
+	if !ms.config.endpointEnabled(path) {
+		handler = http.NotFound
+	}
+	ms.mux.HandleFunc(path, handler)
 }
 
 // handleHealth returns health check status
@@ -70,7 +100,17 @@ func (ms *MonitoringServer) handleReady(w http.ResponseWriter, r *http.Request)
 
 // handleMetrics returns JSON metrics
 func (ms *MonitoringServer) handleMetrics(w http.ResponseWriter, r *http.Request) {
-	metrics := ms.limiter.GetMetrics()
+	view, ok := ms.resolveView(w, r)
+	if !ok {
+		return
+	}
+	metrics := ms.scopeMetrics(view, ms.limiter.GetMetrics())
+
+	// Scrapers asking for a text format get it; everyone else gets JSON
+	if format := negotiateMetricsFormat(r, metricsFormatJSON, metricsFormatOpenMetrics, metricsFormatText); format != metricsFormatJSON {
+		ms.writeExposition(w, metrics, format)
+		return
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
@@ -81,25 +121,43 @@ func (ms *MonitoringServer) handleMetrics(w http.ResponseWriter, r *http.Request
 	})
 }
 
-// handlePrometheusMetrics returns Prometheus-formatted metrics
+// handlePrometheusMetrics returns Prometheus-formatted metrics, as OpenMetrics to scrapers that prefer it
 func (ms *MonitoringServer) handlePrometheusMetrics(w http.ResponseWriter, r *http.Request) {
-	metrics := ms.limiter.GetMetrics()
+	view, ok := ms.resolveView(w, r)
+	if !ok {
+		return
+	}
+	metrics := ms.scopeMetrics(view, ms.limiter.GetMetrics())
+	ms.writeExposition(w, metrics, negotiateMetricsFormat(r, metricsFormatText, metricsFormatOpenMetrics))
+}
 
-	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+// writeExposition writes metrics in the Prometheus text format or OpenMetrics
+func (ms *MonitoringServer) writeExposition(w http.ResponseWriter, metrics map[string]interface{}, format string) {
+	options := prometheusOptions{
+		legacy:      ms.limiter.config.LegacyMetricNames,
+		openMetrics: format == metricsFormatOpenMetrics,
+	}
+	if options.openMetrics {
+		w.Header().Set("Content-Type", openMetricsContentType)
+	} else {
+		w.Header().Set("Content-Type", prometheusTextContentType)
+	}
 	w.WriteHeader(http.StatusOK)
-
-	// Convert metrics to Prometheus format
-	prometheus := ms.convertToPrometheusFormat(metrics)
-	w.Write([]byte(prometheus))
+	w.Write([]byte(convertToPrometheusFormat(metrics, options)))
 }
 
 // handleStats returns comprehensive statistics
 func (ms *MonitoringServer) handleStats(w http.ResponseWriter, r *http.Request) {
+	view, ok := ms.resolveView(w, r)
+	if !ok {
+		return
+	}
 	stats, err := ms.limiter.Stats(r.Context())
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Error getting stats: %v", err), http.StatusInternalServerError)
 		return
 	}
+	stats = ms.scopeStats(view, stats)
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
@@ -110,21 +168,83 @@ func (ms *MonitoringServer) handleStats(w http.ResponseWriter, r *http.Request)
 	})
 }
 
+// handleAnalytics returns the request patterns of recently seen entities, busiest first
+func (ms *MonitoringServer) handleAnalytics(w http.ResponseWriter, r *http.Request) {
+	view, ok := ms.resolveView(w, r)
+	if !ok {
+		return
+	}
+	if ms.limiter.analytics == nil {
+		writeMonitoringError(w, http.StatusNotFound, "analytics not enabled")
+		return
+	}
+
+	limit := 100
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 {
+			writeMonitoringError(w, http.StatusBadRequest, "invalid limit")
+			return
+		}
+		limit = n
+	}
+	entity := r.URL.Query().Get("entity")
+
+	patterns := make([]EntityPattern, 0)
+	for _, pattern := range ms.limiter.RequestPatterns() {
+		if len(patterns) == limit {
+			break
+		}
+		if (entity == "" || pattern.Entity == entity) && ms.includes(view, pattern.Entity) {
+			patterns = append(patterns, pattern)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"timestamp": time.Now().Unix(),
+		"entities":  patterns,
+	})
+}
+
 // handleDebug returns debug information
 func (ms *MonitoringServer) handleDebug(w http.ResponseWriter, r *http.Request) {
 	health := ms.limiter.GetHealthStatus(r.Context())
 	metrics := ms.limiter.GetMetrics()
 
+	config := map[string]interface{}{
+		"metrics_enabled":       ms.limiter.config.EnableMetrics,
+		"logging_enabled":       ms.limiter.config.EnableLogging,
+		"health_checks_enabled": ms.limiter.config.EnableHealthCheck,
+		"log_level":             ms.limiter.config.LogLevel,
+		"hash":                  ms.limiter.ConfigHash(),
+	}
+	// Scopes not listed are enforced
+	if reporter, ok := ms.limiter.limiter.(enforcementReporter); ok {
+		config["enforcement"] = reporter.enforcement()
+	}
+
 	debug := map[string]interface{}{
 		"timestamp": time.Now().Unix(),
 		"health":    health,
 		"metrics":   metrics,
-		"config": map[string]interface{}{
-			"metrics_enabled":       ms.limiter.config.EnableMetrics,
-			"logging_enabled":       ms.limiter.config.EnableLogging,
-			"health_checks_enabled": ms.limiter.config.EnableHealthCheck,
-			"log_level":             ms.limiter.config.LogLevel,
-		},
+		"config":    config,
+	}
+
+	// ?entity= adds the algorithm state of one entity, e.g. /debug?entity=user:42&scope=export
+	if entity := r.URL.Query().Get("entity"); entity != "" {
+		diagnostics, err := ms.limiter.Diagnostics(r.Context(), entity, r.URL.Query().Get("scope"))
+		if IsInvalidInput(err) {
+			writeMonitoringError(w, http.StatusBadRequest, "Invalid entity or scope")
+			return
+		}
+		if err != nil {
+			writeMonitoringError(w, http.StatusInternalServerError, "Failed to read diagnostics")
+			return
+		}
+		debug["diagnostics"] = diagnostics
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -135,18 +255,29 @@ func (ms *MonitoringServer) handleDebug(w http.ResponseWriter, r *http.Request)
 
 // handleIndex returns available endpoints
 func (ms *MonitoringServer) handleIndex(w http.ResponseWriter, r *http.Request) {
+	available := map[string]string{
+		"/health":             "Health check status (JSON)",
+		"/healthz":            "Health check status (Kubernetes standard)",
+		"/ready":              "Readiness check status",
+		"/metrics":            "Metrics in JSON format",
+		"/metrics/prometheus": "Metrics in Prometheus format",
+		"/stats":              "Rate limiting statistics (?namespace= for a tenant view)",
+		"/analytics":          "Request patterns per entity (?entity=, ?limit=, ?namespace=)",
+		"/debug":              "Debug information (?entity=&scope= for algorithm diagnostics)",
+		FederationScrapePath:  "Stats report for stats aggregators",
+		pprofPath:             "Runtime profiles (with MonitoringConfig.Profiling)",
+		goroutinesPath:        "Stacks of gorly's goroutines, ?all=1 for every goroutine (with MonitoringConfig.Profiling)",
+	}
+	for path := range available {
+		if !ms.config.endpointEnabled(path) {
+			delete(available, path)
+		}
+	}
+
 	endpoints := map[string]interface{}{
-		"service": "Gorly Rate Limiter Monitoring",
-		"version": "1.0.0",
-		"endpoints": map[string]string{
-			"/health":             "Health check status (JSON)",
-			"/healthz":            "Health check status (Kubernetes standard)",
-			"/ready":              "Readiness check status",
-			"/metrics":            "Metrics in JSON format",
-			"/metrics/prometheus": "Metrics in Prometheus format",
-			"/stats":              "Rate limiting statistics",
-			"/debug":              "Debug information",
-		},
+		"service":   "Gorly Rate Limiter Monitoring",
+		"version":   "1.0.0",
+		"endpoints": available,
 		"timestamp": time.Now().Unix(),
 	}
 
@@ -156,104 +287,358 @@ func (ms *MonitoringServer) handleIndex(w http.ResponseWriter, r *http.Request)
 	json.NewEncoder(w).Encode(endpoints)
 }
 
-// convertToPrometheusFormat converts metrics to Prometheus text format
-func (ms *MonitoringServer) convertToPrometheusFormat(metrics map[string]interface{}) string {
-	var lines []string
+// MetricsSchemaVersion identifies the metric names and types exposed to Prometheus. It is
+// reported as the schema label of gorly_info and bumped whenever a metric changes meaning.
+//
+// Schema 2 replaced the average gauge gorly_request_duration_seconds with a histogram of
+// the same name; ObservabilityConfig.LegacyMetricNames keeps schema 1.
+const MetricsSchemaVersion = "2"
+
+// legacyMetricsSchemaVersion is the schema exposed with LegacyMetricNames
+const legacyMetricsSchemaVersion = "1"
+
+// prometheusOptions controls the Prometheus exposition
+type prometheusOptions struct {
+	legacy      bool // Expose metrics schema 1
+	openMetrics bool // OpenMetrics 1.0 with exemplars on denied request counters, instead of the text format 0.0.4
+}
+
+// convertToPrometheusFormat converts metrics to the Prometheus text format or OpenMetrics.
+// Series are sorted so that scrapes of unchanged metrics are identical.
+func convertToPrometheusFormat(metrics map[string]interface{}, options prometheusOptions) string {
+	ew := &expositionWriter{openMetrics: options.openMetrics}
+
+	schema := MetricsSchemaVersion
+	if options.legacy {
+		schema = legacyMetricsSchemaVersion
+	}
 
 	// Add metadata
-	lines = append(lines, "# HELP gorly_info Information about Gorly rate limiter")
-	lines = append(lines, "# TYPE gorly_info gauge")
-	lines = append(lines, fmt.Sprintf("gorly_info{version=\"1.0.0\"} 1"))
-	lines = append(lines, "")
+	ew.family("gorly_info", "gauge", "Information about Gorly rate limiter")
+	ew.sample("gorly_info", "1", "version", GetVersion(), "schema", schema)
 
 	// Process request counters
 	if requestTotal, ok := metrics["request_total"].(map[string]int64); ok {
-		lines = append(lines, "# HELP gorly_requests_total Total number of rate limit checks")
-		lines = append(lines, "# TYPE gorly_requests_total counter")
-		for key, value := range requestTotal {
+		ew.family("gorly_requests_total", "counter", "Total number of rate limit checks")
+		for _, key := range sortedKeys(requestTotal) {
 			entity, scope := parseKey(key)
-			lines = append(lines, fmt.Sprintf("gorly_requests_total{entity=\"%s\",scope=\"%s\"} %d", entity, scope, value))
+			ew.sample("gorly_requests_total", formatInt(requestTotal[key]), "entity", entity, "scope", scope)
 		}
-		lines = append(lines, "")
 	}
 
 	if requestDenied, ok := metrics["request_denied"].(map[string]int64); ok {
-		lines = append(lines, "# HELP gorly_requests_denied_total Total number of denied requests")
-		lines = append(lines, "# TYPE gorly_requests_denied_total counter")
-		for key, value := range requestDenied {
+		exemplars, _ := metrics["request_denied_exemplars"].(map[string]MetricExemplar)
+		ew.family("gorly_requests_denied_total", "counter", "Total number of denied requests")
+		for _, key := range sortedKeys(requestDenied) {
 			entity, scope := parseKey(key)
-			lines = append(lines, fmt.Sprintf("gorly_requests_denied_total{entity=\"%s\",scope=\"%s\"} %d", entity, scope, value))
+			if exemplar, ok := exemplars[key]; ok {
+				ew.sampleWithExemplar("gorly_requests_denied_total", formatInt(requestDenied[key]), exemplar, "entity", entity, "scope", scope)
+				continue
+			}
+			ew.sample("gorly_requests_denied_total", formatInt(requestDenied[key]), "entity", entity, "scope", scope)
 		}
-		lines = append(lines, "")
 	}
 
 	if requestAllowed, ok := metrics["request_allowed"].(map[string]int64); ok {
-		lines = append(lines, "# HELP gorly_requests_allowed_total Total number of allowed requests")
-		lines = append(lines, "# TYPE gorly_requests_allowed_total counter")
-		for key, value := range requestAllowed {
+		ew.family("gorly_requests_allowed_total", "counter", "Total number of allowed requests")
+		for _, key := range sortedKeys(requestAllowed) {
 			entity, scope := parseKey(key)
-			lines = append(lines, fmt.Sprintf("gorly_requests_allowed_total{entity=\"%s\",scope=\"%s\"} %d", entity, scope, value))
+			ew.sample("gorly_requests_allowed_total", formatInt(requestAllowed[key]), "entity", entity, "scope", scope)
 		}
-		lines = append(lines, "")
 	}
 
 	// Process gauge metrics
 	if rateLimitRemaining, ok := metrics["rate_limit_remaining"].(map[string]int64); ok {
-		lines = append(lines, "# HELP gorly_rate_limit_remaining Current remaining requests in rate limit window")
-		lines = append(lines, "# TYPE gorly_rate_limit_remaining gauge")
-		for key, value := range rateLimitRemaining {
+		ew.family("gorly_rate_limit_remaining", "gauge", "Current remaining requests in rate limit window")
+		for _, key := range sortedKeys(rateLimitRemaining) {
 			entity, scope := parseKey(key)
-			lines = append(lines, fmt.Sprintf("gorly_rate_limit_remaining{entity=\"%s\",scope=\"%s\"} %d", entity, scope, value))
+			ew.sample("gorly_rate_limit_remaining", formatInt(rateLimitRemaining[key]), "entity", entity, "scope", scope)
 		}
-		lines = append(lines, "")
 	}
 
 	if rateLimitUsed, ok := metrics["rate_limit_used"].(map[string]int64); ok {
-		lines = append(lines, "# HELP gorly_rate_limit_used Current used requests in rate limit window")
-		lines = append(lines, "# TYPE gorly_rate_limit_used gauge")
-		for key, value := range rateLimitUsed {
+		ew.family("gorly_rate_limit_used", "gauge", "Current used requests in rate limit window")
+		for _, key := range sortedKeys(rateLimitUsed) {
 			entity, scope := parseKey(key)
-			lines = append(lines, fmt.Sprintf("gorly_rate_limit_used{entity=\"%s\",scope=\"%s\"} %d", entity, scope, value))
+			ew.sample("gorly_rate_limit_used", formatInt(rateLimitUsed[key]), "entity", entity, "scope", scope)
 		}
-		lines = append(lines, "")
 	}
 
 	// Process duration metrics
-	if avgDuration, ok := metrics["avg_request_duration"].(time.Duration); ok {
-		lines = append(lines, "# HELP gorly_request_duration_seconds Average request processing duration")
-		lines = append(lines, "# TYPE gorly_request_duration_seconds gauge")
-		lines = append(lines, fmt.Sprintf("gorly_request_duration_seconds %f", avgDuration.Seconds()))
-		lines = append(lines, "")
+	if avgDuration, ok := metrics["avg_request_duration"].(time.Duration); ok && options.legacy {
+		ew.family("gorly_request_duration_seconds", "gauge", "Average request processing duration")
+		ew.sample("gorly_request_duration_seconds", fmt.Sprintf("%f", avgDuration.Seconds()))
+	}
+
+	if histogram, ok := metrics["request_duration_histogram"].(DurationHistogram); ok && !options.legacy && histogram.Count > 0 {
+		ew.family("gorly_request_duration_seconds", "histogram", "Request processing duration")
+		for i, bound := range histogram.Buckets {
+			ew.sample("gorly_request_duration_seconds_bucket", formatInt(histogram.Counts[i]), "le", ew.bound(bound))
+		}
+		ew.sample("gorly_request_duration_seconds_bucket", formatInt(histogram.Count), "le", "+Inf")
+		ew.sample("gorly_request_duration_seconds_sum", fmt.Sprintf("%f", histogram.Sum))
+		ew.sample("gorly_request_duration_seconds_count", formatInt(histogram.Count))
 	}
 
 	// Process health metrics
 	if healthy, ok := metrics["healthy"].(bool); ok {
-		lines = append(lines, "# HELP gorly_healthy Whether the rate limiter is healthy")
-		lines = append(lines, "# TYPE gorly_healthy gauge")
+		ew.family("gorly_healthy", "gauge", "Whether the rate limiter is healthy")
 		healthValue := "0"
 		if healthy {
 			healthValue = "1"
 		}
-		lines = append(lines, fmt.Sprintf("gorly_healthy %s", healthValue))
-		lines = append(lines, "")
+		ew.sample("gorly_healthy", healthValue)
 	}
 
 	if healthChecks, ok := metrics["health_checks"].(int64); ok {
-		lines = append(lines, "# HELP gorly_health_checks_total Total number of health checks performed")
-		lines = append(lines, "# TYPE gorly_health_checks_total counter")
-		lines = append(lines, fmt.Sprintf("gorly_health_checks_total %d", healthChecks))
-		lines = append(lines, "")
+		ew.family("gorly_health_checks_total", "counter", "Total number of health checks performed")
+		ew.sample("gorly_health_checks_total", formatInt(healthChecks))
+	}
+
+	if emptyEntities, ok := metrics["empty_entities"].(int64); ok {
+		ew.family("gorly_empty_entities_total", "counter", "Total number of requests without an entity")
+		ew.sample("gorly_empty_entities_total", formatInt(emptyEntities))
+	}
+
+	if probes, ok := metrics["probe_bypasses"].(int64); ok {
+		ew.family("gorly_probe_bypasses_total", "counter", "Total number of health probes let through without a rate limit check")
+		ew.sample("gorly_probe_bypasses_total", formatInt(probes))
+	}
+
+	if overflows, ok := metrics["scope_overflows"].(int64); ok {
+		ew.family("gorly_scope_overflows_total", "counter", "Total number of checks whose scope exceeded the scope budget; growth means the scope function creates too many scopes")
+		ew.sample("gorly_scope_overflows_total", formatInt(overflows))
+	}
+
+	if unknown, ok := metrics["unknown_tiers"].(int64); ok {
+		ew.family("gorly_unknown_tiers_total", "counter", "Total number of checks from entities claiming a tier that no scope configures")
+		ew.sample("gorly_unknown_tiers_total", formatInt(unknown))
+	}
+
+	if modes, ok := metrics["enforcement"].(map[string]string); ok && len(modes) > 0 {
+		ew.family("gorly_scope_enforcement", "gauge", "Enforcement mode of scopes not enforced by default (1 for the active mode)")
+		for _, scope := range sortedKeys(modes) {
+			ew.sample("gorly_scope_enforcement", "1", "scope", scope, "mode", modes[scope])
+		}
+	}
+
+	if denials, ok := metrics["shadow_denials"].(map[string]int64); ok && len(denials) > 0 {
+		ew.family("gorly_shadow_denials_total", "counter", "Total number of requests over the limit let through by shadow mode")
+		for _, scope := range sortedKeys(denials) {
+			ew.sample("gorly_shadow_denials_total", formatInt(denials[scope]), "scope", scope)
+		}
+	}
+
+	if policies, ok := metrics["failure_policies"].(map[string]string); ok && len(policies) > 0 {
+		ew.family("gorly_scope_failure_policy", "gauge", "Failure policy during store outages of scopes, \"*\" for the default (1 for the active policy)")
+		for _, scope := range sortedKeys(policies) {
+			ew.sample("gorly_scope_failure_policy", "1", "scope", scope, "policy", policies[scope])
+		}
+	}
+
+	if failed, ok := metrics["failed_open"].(map[string]int64); ok && len(failed) > 0 {
+		ew.family("gorly_failed_open_total", "counter", "Total number of checks let through by a fail-open policy while the store failed")
+		for _, scope := range sortedKeys(failed) {
+			ew.sample("gorly_failed_open_total", formatInt(failed[scope]), "scope", scope)
+		}
+	}
+
+	if failed, ok := metrics["failed_closed"].(map[string]int64); ok && len(failed) > 0 {
+		ew.family("gorly_failed_closed_total", "counter", "Total number of checks rejected by a fail-closed policy while the store failed")
+		for _, scope := range sortedKeys(failed) {
+			ew.sample("gorly_failed_closed_total", formatInt(failed[scope]), "scope", scope)
+		}
+	}
+
+	allowed, _ := metrics["local_fallback_allowed"].(map[string]int64)
+	denied, _ := metrics["local_fallback_denied"].(map[string]int64)
+	if len(allowed) > 0 || len(denied) > 0 {
+		ew.family("gorly_local_fallback_decisions_total", "counter", "Total number of checks decided by the in-memory fallback limiter while the store failed")
+		for _, scope := range sortedKeys(allowed) {
+			ew.sample("gorly_local_fallback_decisions_total", formatInt(allowed[scope]), "scope", scope, "decision", "allowed")
+		}
+		for _, scope := range sortedKeys(denied) {
+			ew.sample("gorly_local_fallback_decisions_total", formatInt(denied[scope]), "scope", scope, "decision", "denied")
+		}
+	}
+
+	if expired, ok := metrics["expired_overrides"].(int64); ok {
+		ew.family("gorly_overrides_expired_total", "counter", "Total number of entity overrides removed at their expiry")
+		ew.sample("gorly_overrides_expired_total", formatInt(expired))
+	}
+
+	if flush, ok := metrics["stats_flush"].(*StatsFlushStats); ok {
+		ew.family("gorly_stats_flushes_total", "counter", "Total number of write-behind stats flushes")
+		ew.sample("gorly_stats_flushes_total", formatInt(flush.Flushes))
+		ew.family("gorly_stats_flush_errors_total", "counter", "Total number of failed write-behind stats flushes; their counts are retried")
+		ew.sample("gorly_stats_flush_errors_total", formatInt(flush.FlushErrors))
+		ew.family("gorly_stats_flushed_events_total", "counter", "Total number of requests written to the store by write-behind stats")
+		ew.sample("gorly_stats_flushed_events_total", formatInt(flush.FlushedEvents))
+		ew.family("gorly_stats_pending_events", "gauge", "Requests counted locally and not yet flushed; lost if the process dies")
+		ew.sample("gorly_stats_pending_events", formatInt(flush.PendingEvents))
+		ew.family("gorly_stats_last_flush_duration_seconds", "gauge", "Duration of the most recent write-behind stats flush")
+		ew.sample("gorly_stats_last_flush_duration_seconds", fmt.Sprintf("%g", flush.LastFlushDuration.Seconds()))
+	}
+
+	if cache, ok := metrics["tier_cache"].(*TierCacheStats); ok {
+		ew.family("gorly_tier_cache_lookups_total", "counter", "Total number of tier lookups by result")
+		ew.sample("gorly_tier_cache_lookups_total", formatInt(cache.Hits), "result", "hit")
+		ew.sample("gorly_tier_cache_lookups_total", formatInt(cache.StaleHits), "result", "stale")
+		ew.sample("gorly_tier_cache_lookups_total", formatInt(cache.Misses), "result", "miss")
+		ew.family("gorly_tier_resolver_errors_total", "counter", "Total number of failed tier resolver calls")
+		ew.sample("gorly_tier_resolver_errors_total", formatInt(cache.Errors))
+		ew.family("gorly_tier_cache_entries", "gauge", "Entities with a cached tier")
+		ew.sample("gorly_tier_cache_entries", formatInt(cache.Entries))
+	}
+
+	if trusted, ok := metrics["trusted_calls"].(*TrustedCallStats); ok {
+		ew.family("gorly_trusted_calls_total", "counter", "Total number of requests with a valid trusted call signature by key and granted scope")
+		for _, keyID := range sortedKeys(trusted.Accepted) {
+			for _, scope := range sortedKeys(trusted.Accepted[keyID]) {
+				ew.sample("gorly_trusted_calls_total", formatInt(trusted.Accepted[keyID][scope]), "key", keyID, "scope", scope)
+			}
+		}
+		ew.family("gorly_trusted_call_rejections_total", "counter", "Total number of requests with an invalid trusted call signature by reason")
+		for _, reason := range sortedKeys(trusted.Rejected) {
+			ew.sample("gorly_trusted_call_rejections_total", formatInt(trusted.Rejected[reason]), "reason", reason)
+		}
+	}
+
+	if introspection, ok := metrics["token_introspection"].(*IntrospectionStats); ok {
+		ew.family("gorly_token_introspections_total", "counter", "Total number of token lookups by result")
+		ew.sample("gorly_token_introspections_total", formatInt(introspection.LocalHits), "result", "local_hit")
+		ew.sample("gorly_token_introspections_total", formatInt(introspection.SharedHits), "result", "shared_hit")
+		ew.sample("gorly_token_introspections_total", formatInt(introspection.Misses), "result", "miss")
+		ew.family("gorly_token_introspection_inactive_total", "counter", "Total number of tokens the identity provider did not accept")
+		ew.sample("gorly_token_introspection_inactive_total", formatInt(introspection.Inactive))
+		ew.family("gorly_token_introspection_errors_total", "counter", "Total number of failed token introspections")
+		ew.sample("gorly_token_introspection_errors_total", formatInt(introspection.Errors))
+		ew.family("gorly_token_introspection_cache_entries", "gauge", "Tokens in the instance's introspection cache")
+		ew.sample("gorly_token_introspection_cache_entries", formatInt(introspection.Entries))
+	}
+
+	if keys, ok := metrics["store_keys"].(int64); ok {
+		ew.family("gorly_store_keys", "gauge", "Keys held by the store")
+		ew.sample("gorly_store_keys", formatInt(keys))
+	}
+
+	if pool, ok := metrics["store_pool"].(*StorePoolStats); ok {
+		ew.family("gorly_store_pool_connections", "gauge", "Connections in the store's pool by state")
+		ew.sample("gorly_store_pool_connections", formatInt(pool.TotalConns), "state", "total")
+		ew.sample("gorly_store_pool_connections", formatInt(pool.IdleConns), "state", "idle")
+		ew.family("gorly_store_pool_requests_total", "counter", "Total number of connection requests to the store's pool by result")
+		ew.sample("gorly_store_pool_requests_total", formatInt(pool.Hits), "result", "hit")
+		ew.sample("gorly_store_pool_requests_total", formatInt(pool.Misses), "result", "miss")
+		ew.sample("gorly_store_pool_requests_total", formatInt(pool.Timeouts), "result", "timeout")
+		ew.family("gorly_store_pool_stale_connections_total", "counter", "Total number of stale connections removed from the store's pool")
+		ew.sample("gorly_store_pool_stale_connections_total", formatInt(pool.StaleConns))
+	}
+	if failover, ok := metrics["store_failover"].(*StoreFailoverStats); ok {
+		failedOver := "0"
+		if failover.FailedOver {
+			failedOver = "1"
+		}
+		ew.family("gorly_store_failed_over", "gauge", "Whether the store is served by its standby (1) or its primary (0)")
+		ew.sample("gorly_store_failed_over", failedOver)
+		ew.family("gorly_store_failovers_total", "counter", "Total number of switches between the primary and the standby by direction")
+		ew.sample("gorly_store_failovers_total", formatInt(failover.Failovers), "direction", "to_standby")
+		ew.sample("gorly_store_failovers_total", formatInt(failover.Failbacks), "direction", "to_primary")
+		ew.family("gorly_store_replication_total", "counter", "Total number of writes copied to the standby by result")
+		ew.sample("gorly_store_replication_total", formatInt(failover.Replicated), "result", "replicated")
+		ew.sample("gorly_store_replication_total", formatInt(failover.ReplicationDropped), "result", "dropped")
+		ew.sample("gorly_store_replication_total", formatInt(failover.ReplicationErrors), "result", "error")
+		ew.family("gorly_store_reconciled_keys_total", "counter", "Total number of keys copied back to the primary before failing back")
+		ew.sample("gorly_store_reconciled_keys_total", formatInt(failover.Reconciled))
+	}
+	if retries, ok := metrics["store_retries"].(*StoreRetryStats); ok {
+		ew.family("gorly_store_reads_total", "counter", "Total number of store reads made by checks")
+		ew.sample("gorly_store_reads_total", formatInt(retries.Reads))
+		ew.family("gorly_store_read_retries_total", "counter", "Total number of failed store reads by what became of their retry")
+		ew.sample("gorly_store_read_retries_total", formatInt(retries.Retries), "result", "retried")
+		ew.sample("gorly_store_read_retries_total", formatInt(retries.BudgetExhausted), "result", "budget_exhausted")
+		ew.sample("gorly_store_read_retries_total", formatInt(retries.DeadlineSkipped), "result", "deadline_skipped")
+		ew.family("gorly_store_read_hedges_total", "counter", "Total number of hedged store reads by result")
+		ew.sample("gorly_store_read_hedges_total", formatInt(retries.Hedges), "result", "sent")
+		ew.sample("gorly_store_read_hedges_total", formatInt(retries.HedgeWins), "result", "won")
+		ew.family("gorly_store_hedge_delay_seconds", "gauge", "Delay before a slow store read is hedged")
+		ew.sample("gorly_store_hedge_delay_seconds", fmt.Sprintf("%g", retries.HedgeDelay.Seconds()))
+	}
+
+	if expiry, ok := metrics["key_expiry"].(*KeyExpiryStats); ok {
+		ew.family("gorly_store_keys_expired_total", "counter", "Total number of limiter keys expired by the store by kind")
+		for _, kind := range sortedKeys(expiry.ByKind) {
+			ew.sample("gorly_store_keys_expired_total", formatInt(expiry.ByKind[kind]), "kind", kind)
+		}
+		ew.family("gorly_store_keys_expired_last_minute", "gauge", "Number of limiter keys expired by the store in the last minute")
+		ew.sample("gorly_store_keys_expired_last_minute", formatInt(expiry.LastMinute))
+	}
+
+	if compression, ok := metrics["state_compression"].(*StateCompressionStats); ok {
+		ew.family("gorly_state_compression_blobs_total", "counter", "Total number of algorithm state blobs by what compression did with them")
+		ew.sample("gorly_state_compression_blobs_total", formatInt(compression.Compressed), "result", "compressed")
+		ew.sample("gorly_state_compression_blobs_total", formatInt(compression.Skipped), "result", "skipped")
+		ew.sample("gorly_state_compression_blobs_total", formatInt(compression.Decompressed), "result", "decompressed")
+		ew.family("gorly_state_compression_bytes_total", "counter", "Total size of compressed algorithm state before and after compression")
+		ew.sample("gorly_state_compression_bytes_total", formatInt(compression.BytesIn), "stage", "in")
+		ew.sample("gorly_state_compression_bytes_total", formatInt(compression.BytesOut), "stage", "out")
+		ew.family("gorly_state_compression_ratio", "gauge", "Compressed size of algorithm state divided by its uncompressed size")
+		ew.sample("gorly_state_compression_ratio", fmt.Sprintf("%g", compression.Ratio))
+		ew.family("gorly_state_compression_seconds_total", "counter", "Total time spent compressing and decompressing algorithm state by operation")
+		ew.sample("gorly_state_compression_seconds_total", fmt.Sprintf("%g", compression.EncodeTime.Seconds()), "operation", "encode")
+		ew.sample("gorly_state_compression_seconds_total", fmt.Sprintf("%g", compression.DecodeTime.Seconds()), "operation", "decode")
+	}
+
+	if cache, ok := metrics["local_cache"].(*LocalCacheStats); ok {
+		ew.family("gorly_local_cache_requests_total", "counter", "Total number of store operations on the local cache by result")
+		ew.sample("gorly_local_cache_requests_total", formatInt(cache.Hits), "result", "hit")
+		ew.sample("gorly_local_cache_requests_total", formatInt(cache.Misses), "result", "miss")
+		ew.family("gorly_local_cache_hit_ratio", "gauge", "Ratio of store operations answered by the local cache")
+		ew.sample("gorly_local_cache_hit_ratio", fmt.Sprintf("%g", cache.HitRatio))
+		ew.family("gorly_local_cache_keys", "gauge", "Keys in the local cache by state")
+		ew.sample("gorly_local_cache_keys", formatInt(int64(cache.Keys)), "state", "cached")
+		ew.sample("gorly_local_cache_keys", formatInt(int64(cache.PendingKeys)), "state", "pending")
+		ew.family("gorly_local_cache_syncs_total", "counter", "Total number of syncs of the local cache with the store")
+		ew.sample("gorly_local_cache_syncs_total", formatInt(cache.Syncs))
+		ew.family("gorly_local_cache_sync_errors_total", "counter", "Total number of keys whose sync failed")
+		ew.sample("gorly_local_cache_sync_errors_total", formatInt(cache.SyncErrors))
+		ew.family("gorly_local_cache_drift", "gauge", "Requests of other instances found in the counters by the last sync")
+		ew.sample("gorly_local_cache_drift", formatInt(cache.Drift))
+		ew.family("gorly_local_cache_drift_total", "counter", "Total number of requests of other instances found in the counters by syncs")
+		ew.sample("gorly_local_cache_drift_total", formatInt(cache.TotalDrift))
+		ew.family("gorly_local_cache_max_drift", "gauge", "Most requests of other instances found in one counter by one sync")
+		ew.sample("gorly_local_cache_max_drift", formatInt(cache.MaxDrift))
+	}
+
+	if config, ok := metrics["config"].(*ConfigVersion); ok {
+		ew.family("gorly_config_generation", "gauge", "Generation of the enforced limits, incremented by every update")
+		ew.sample("gorly_config_generation", formatInt(config.Generation))
+		if config.Version != "" {
+			ew.family("gorly_config_info", "gauge", "Version of the last hot-reloaded config")
+			ew.sample("gorly_config_info", "1", "version", config.Version)
+		}
+		// Instances that should be identical expose the same hash, e.g. alert on
+		// count(count by (hash) (gorly_config_hash_info)) > 1
+		ew.family("gorly_config_hash_info", "gauge", "Hash of the effective configuration")
+		ew.sample("gorly_config_hash_info", "1", "hash", config.Hash)
+	}
+
+	if goroutines, ok := metrics["goroutines"].(int64); ok {
+		ew.family("gorly_goroutines", "gauge", "Goroutines in the process")
+		ew.sample("gorly_goroutines", formatInt(goroutines))
+	}
+
+	if heap, ok := metrics["heap_alloc_bytes"].(int64); ok {
+		ew.family("gorly_heap_alloc_bytes", "gauge", "Bytes of allocated heap objects")
+		ew.sample("gorly_heap_alloc_bytes", formatInt(heap))
 	}
 
 	// Process queue size
 	if queueSize, ok := metrics["queue_size"].(int64); ok {
-		lines = append(lines, "# HELP gorly_queue_size Current queue size")
-		lines = append(lines, "# TYPE gorly_queue_size gauge")
-		lines = append(lines, fmt.Sprintf("gorly_queue_size %d", queueSize))
-		lines = append(lines, "")
+		ew.family("gorly_queue_size", "gauge", "Current queue size")
+		ew.sample("gorly_queue_size", formatInt(queueSize))
 	}
 
-	return strings.Join(lines, "\n")
+	return ew.String()
 }
 
 // parseKey splits "entity:scope" back into entity and scope
@@ -306,7 +691,7 @@ func MetricsHandler(limiter *ObservableLimiter) http.HandlerFunc {
 
 // PrometheusHandler creates a Prometheus metrics handler
 func PrometheusHandler(limiter *ObservableLimiter) http.HandlerFunc {
-	ms := &MonitoringServer{limiter: limiter}
+	ms := &MonitoringServer{limiter: limiter, config: &MonitoringConfig{}}
 
 	return func(w http.ResponseWriter, r *http.Request) {
 		ms.handlePrometheusMetrics(w, r)
@@ -378,116 +763,48 @@ func (sr *statusRecorder) WriteHeader(code int) {
 	sr.ResponseWriter.WriteHeader(code)
 }
 
-// AlertManager provides basic alerting functionality
-type AlertManager struct {
-	alerts    []Alert
-	handlers  []AlertHandler
-	threshold map[string]float64
-}
-
-// Alert represents an alert condition
-type Alert struct {
-	Name      string                 `json:"name"`
-	Message   string                 `json:"message"`
-	Severity  string                 `json:"severity"`
-	Timestamp time.Time              `json:"timestamp"`
-	Resolved  bool                   `json:"resolved"`
-	Metadata  map[string]interface{} `json:"metadata"`
-}
+// Alerting moved to the experimental alerting package; these aliases keep existing code
+// compiling and will be removed in the next major version.
 
-// AlertHandler defines how alerts are handled
-type AlertHandler func(Alert)
+// AlertManager provides basic alerting functionality.
+//
+// Deprecated: use alerting.Manager.
+type AlertManager = alerting.Manager
 
-// NewAlertManager creates a new alert manager
-func NewAlertManager() *AlertManager {
-	return &AlertManager{
-		alerts:    make([]Alert, 0),
-		handlers:  make([]AlertHandler, 0),
-		threshold: make(map[string]float64),
-	}
-}
+// Alert represents an alert condition.
+//
+// Deprecated: use alerting.Alert.
+type Alert = alerting.Alert
 
-// AddHandler adds an alert handler
-func (am *AlertManager) AddHandler(handler AlertHandler) {
-	am.handlers = append(am.handlers, handler)
-}
+// AlertHandler defines how alerts are handled.
+//
+// Deprecated: use alerting.Handler.
+type AlertHandler = alerting.Handler
 
-// SetThreshold sets an alert threshold
-func (am *AlertManager) SetThreshold(name string, threshold float64) {
-	am.threshold[name] = threshold
-}
-
-// CheckMetrics checks metrics against thresholds and triggers alerts
-func (am *AlertManager) CheckMetrics(metrics map[string]interface{}) {
-	// Check error rate
-	if requestTotal, ok := metrics["request_total"].(map[string]int64); ok {
-		if requestDenied, ok := metrics["request_denied"].(map[string]int64); ok {
-			for key := range requestTotal {
-				total := requestTotal[key]
-				denied := requestDenied[key]
-
-				if total > 0 {
-					errorRate := float64(denied) / float64(total) * 100
-					if threshold, exists := am.threshold["error_rate"]; exists && errorRate > threshold {
-						am.triggerAlert(Alert{
-							Name:      "High Error Rate",
-							Message:   fmt.Sprintf("Error rate %.2f%% exceeds threshold %.2f%% for %s", errorRate, threshold, key),
-							Severity:  "warning",
-							Timestamp: time.Now(),
-							Metadata: map[string]interface{}{
-								"key":        key,
-								"error_rate": errorRate,
-								"threshold":  threshold,
-								"total":      total,
-								"denied":     denied,
-							},
-						})
-					}
-				}
-			}
-		}
-	}
-
-	// Check if service is unhealthy
-	if healthy, ok := metrics["healthy"].(bool); ok && !healthy {
-		if threshold, exists := am.threshold["health"]; exists && threshold > 0 {
-			am.triggerAlert(Alert{
-				Name:      "Service Unhealthy",
-				Message:   "Rate limiter health check failed",
-				Severity:  "critical",
-				Timestamp: time.Now(),
-				Metadata: map[string]interface{}{
-					"healthy": healthy,
-				},
-			})
-		}
-	}
-}
-
-func (am *AlertManager) triggerAlert(alert Alert) {
-	am.alerts = append(am.alerts, alert)
-
-	// Trigger all handlers
-	for _, handler := range am.handlers {
-		handler(alert)
-	}
-}
-
-// GetAlerts returns current alerts
-func (am *AlertManager) GetAlerts() []Alert {
-	return am.alerts
+// NewAlertManager creates a new alert manager.
+//
+// Deprecated: use alerting.NewManager.
+func NewAlertManager() *AlertManager {
+	return alerting.NewManager()
 }
 
-// ConsoleAlertHandler logs alerts to console
+// ConsoleAlertHandler logs alerts to console.
+//
+// Deprecated: use alerting.ConsoleHandler.
 func ConsoleAlertHandler(alert Alert) {
-	fmt.Printf("[ALERT] %s - %s: %s\n", alert.Severity, alert.Name, alert.Message)
+	alerting.ConsoleHandler(alert)
 }
 
-// HTTPAlertHandler sends alerts to an HTTP endpoint
+// HTTPAlertHandler sends alerts to an HTTP endpoint.
+//
+// Deprecated: use alerting.HTTPHandler.
 func HTTPAlertHandler(endpoint string) AlertHandler {
-	return func(alert Alert) {
-		// In a real implementation, you would send the alert to an HTTP endpoint
-		// For now, just log it
-		fmt.Printf("[HTTP ALERT to %s] %s\n", endpoint, alert.Message)
-	}
+	return alerting.HTTPHandler(endpoint)
+}
+
+// HTTPAlertHandlerWithClient posts each alert as JSON to an HTTP endpoint through client.
+//
+// Deprecated: use alerting.HTTPHandlerWithClient.
+func HTTPAlertHandlerWithClient(endpoint string, client *http.Client) AlertHandler {
+	return alerting.HTTPHandlerWithClient(endpoint, client)
 }
@@ -0,0 +1,249 @@
+// monitoring_access.go - Authorization and tenant-scoped views for the monitoring server
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// MonitoringConfig configures access to the monitoring endpoints
+type MonitoringConfig struct {
+	// Authorizer decides what each caller may see. Without one every endpoint is open
+	// and callers may still narrow views with ?namespace=.
+	Authorizer Authorizer
+
+	// Namespace maps an entity to the tenant namespace it belongs to
+	// (default: the part before the first ':', so "acme:user-42" is in "acme")
+	Namespace func(entity string) string
+
+	// AllowedNetworks limits the protected endpoints to clients in these networks or
+	// addresses, e.g. "10.0.0.0/8" or "127.0.0.1". Health endpoints stay open for probes.
+	AllowedNetworks []string
+
+	// DisabledEndpoints are not served at all, e.g. []string{"/debug"}
+	DisabledEndpoints []string
+
+	// Profiling serves runtime profiles under /debug/pprof/ and the stacks of gorly's
+	// goroutines at /debug/goroutines to admins. Off by default: profiles reveal memory
+	// contents and CPU profiles and traces cost throughput while they run.
+	Profiling bool
+
+	// Cluster names this cluster in the reports /federate serves to stats aggregators
+	Cluster string
+}
+
+// endpointEnabled reports whether a monitoring endpoint is served
+func (mc *MonitoringConfig) endpointEnabled(path string) bool {
+	if profilingEndpoint(path) && !mc.Profiling {
+		return false
+	}
+	for _, disabled := range mc.DisabledEndpoints {
+		if disabled == path {
+			return false
+		}
+	}
+	return true
+}
+
+// MonitoringAccess describes what an authorized monitoring caller may see
+type MonitoringAccess struct {
+	// Admin callers see every namespace, global aggregates and the debug endpoint
+	Admin bool
+
+	// Namespaces a tenant admin may view
+	Namespaces []string
+}
+
+// allows reports whether the access covers a namespace
+func (a *MonitoringAccess) allows(namespace string) bool {
+	if a.Admin {
+		return true
+	}
+	for _, ns := range a.Namespaces {
+		if ns == namespace {
+			return true
+		}
+	}
+	return false
+}
+
+// Authorizer authenticates monitoring requests. BearerTokenAuthorizer and
+// BasicAuthAuthorizer cover static credentials.
+// Returning ErrMonitoringUnauthenticated (or any error) rejects the request with 401;
+// access without namespaces is rejected with 403.
+type Authorizer interface {
+	Authorize(r *http.Request) (*MonitoringAccess, error)
+}
+
+// AuthorizerFunc adapts a function to the Authorizer interface
+// Example: ratelimit.AuthorizerFunc(func(r *http.Request) (*ratelimit.MonitoringAccess, error) { ... })
+type AuthorizerFunc func(r *http.Request) (*MonitoringAccess, error)
+
+// Authorize implements Authorizer
+func (f AuthorizerFunc) Authorize(r *http.Request) (*MonitoringAccess, error) {
+	return f(r)
+}
+
+// ErrMonitoringUnauthenticated is returned by authorizers for requests without valid credentials
+var ErrMonitoringUnauthenticated = errors.New("monitoring request is not authenticated")
+
+// DefaultNamespace returns the part of an entity before the first ':', or "" without one
+func DefaultNamespace(entity string) string {
+	namespace, _, found := strings.Cut(entity, ":")
+	if !found {
+		return ""
+	}
+	return namespace
+}
+
+// monitoringAccessKey stores the caller's access in the request context
+type monitoringAccessKey struct{}
+
+// adminAccess is the access of callers when no authorizer is configured
+var adminAccess = &MonitoringAccess{Admin: true}
+
+// authorized wraps a handler so it only runs for authorized callers
+func (ms *MonitoringServer) authorized(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		access := ms.guard.authorize(w, r)
+		if access == nil {
+			return
+		}
+		handler(w, r.WithContext(context.WithValue(r.Context(), monitoringAccessKey{}, access)))
+	}
+}
+
+// adminOnly wraps a handler that exposes data across all tenants
+func (ms *MonitoringServer) adminOnly(handler http.HandlerFunc) http.HandlerFunc {
+	return ms.authorized(func(w http.ResponseWriter, r *http.Request) {
+		if !requestAccess(r).Admin {
+			writeMonitoringError(w, http.StatusForbidden, "forbidden")
+			return
+		}
+		handler(w, r)
+	})
+}
+
+// requestAccess returns the access stored by authorized
+func requestAccess(r *http.Request) *MonitoringAccess {
+	if access, ok := r.Context().Value(monitoringAccessKey{}).(*MonitoringAccess); ok {
+		return access
+	}
+	return adminAccess
+}
+
+// monitoringView is the namespace a request is limited to
+type monitoringView struct {
+	namespace string
+	scoped    bool // false for the unfiltered admin view
+}
+
+// resolveView determines the namespace a request may see, writing an error response if it may not
+func (ms *MonitoringServer) resolveView(w http.ResponseWriter, r *http.Request) (monitoringView, bool) {
+	access := requestAccess(r)
+	namespace, requested := r.URL.Query()["namespace"]
+
+	if !requested || len(namespace) == 0 {
+		if access.Admin {
+			return monitoringView{}, true
+		}
+		// Tenant admins with a single namespace need not name it
+		if len(access.Namespaces) == 1 {
+			return monitoringView{namespace: access.Namespaces[0], scoped: true}, true
+		}
+		writeMonitoringError(w, http.StatusBadRequest, "namespace parameter required")
+		return monitoringView{}, false
+	}
+
+	if !access.allows(namespace[0]) {
+		writeMonitoringError(w, http.StatusForbidden, "forbidden")
+		return monitoringView{}, false
+	}
+	return monitoringView{namespace: namespace[0], scoped: true}, true
+}
+
+// includes reports whether an entity is visible in the view
+func (ms *MonitoringServer) includes(view monitoringView, entity string) bool {
+	if !view.scoped {
+		return true
+	}
+	namespace := ms.config.Namespace
+	if namespace == nil {
+		namespace = DefaultNamespace
+	}
+	return namespace(entity) == view.namespace
+}
+
+// scopeStats limits statistics to the entities of a view. Scope aggregates and totals
+// span tenants, so a scoped view recomputes totals from its own entities only.
+func (ms *MonitoringServer) scopeStats(view monitoringView, stats *LimitStats) *LimitStats {
+	if !view.scoped {
+		return stats
+	}
+
+	scoped := &LimitStats{
+		ByScope:  make(map[string]*LimitScopeStats),
+		ByEntity: make(map[string]*EntityStats),
+	}
+	for entity, s := range stats.ByEntity {
+		if !ms.includes(view, entity) {
+			continue
+		}
+		scoped.ByEntity[entity] = s
+		scoped.TotalRequests += s.Requests
+		scoped.TotalDenied += s.Denied
+	}
+	return scoped
+}
+
+// scopeMetrics limits per-entity metrics to the entities of a view
+func (ms *MonitoringServer) scopeMetrics(view monitoringView, metrics map[string]interface{}) map[string]interface{} {
+	if !view.scoped {
+		return metrics
+	}
+
+	scoped := make(map[string]interface{}, len(metrics))
+	for name, value := range metrics {
+		if exemplars, ok := value.(map[string]MetricExemplar); ok {
+			filtered := make(map[string]MetricExemplar)
+			for key, exemplar := range exemplars {
+				if ms.includes(view, metricKeyEntity(key)) {
+					filtered[key] = exemplar
+				}
+			}
+			scoped[name] = filtered
+			continue
+		}
+		perEntity, ok := value.(map[string]int64)
+		if !ok {
+			scoped[name] = value
+			continue
+		}
+		filtered := make(map[string]int64)
+		for key, n := range perEntity {
+			if ms.includes(view, metricKeyEntity(key)) {
+				filtered[key] = n
+			}
+		}
+		scoped[name] = filtered
+	}
+	return scoped
+}
+
+// metricKeyEntity returns the entity of an "entity:scope" metric key.
+// Entities often contain ':' themselves, so the scope is taken as the last part.
+func metricKeyEntity(key string) string {
+	if i := strings.LastIndexByte(key, ':'); i >= 0 {
+		return key[:i]
+	}
+	return key
+}
+
+// writeMonitoringError writes a JSON error response
+func writeMonitoringError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write([]byte(`{"error":"` + message + `"}`))
+}
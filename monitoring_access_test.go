@@ -0,0 +1,183 @@
+// monitoring_access_test.go
+package ratelimit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// tenantStatsLimiter returns fixed statistics spanning two tenants
+type tenantStatsLimiter struct {
+	Limiter
+}
+
+func (t *tenantStatsLimiter) Stats(ctx context.Context) (*LimitStats, error) {
+	return &LimitStats{
+		TotalRequests: 60,
+		TotalDenied:   6,
+		ByScope:       map[string]*LimitScopeStats{"global": {Scope: "global", Requests: 60, Denied: 6}},
+		ByEntity: map[string]*EntityStats{
+			"acme:user-1":   {Entity: "acme:user-1", Requests: 10, Denied: 1},
+			"acme:user-2":   {Entity: "acme:user-2", Requests: 20, Denied: 2},
+			"globex:user-1": {Entity: "globex:user-1", Requests: 30, Denied: 3},
+		},
+	}, nil
+}
+
+// tokenAuthorizer maps bearer tokens to access
+func tokenAuthorizer(tokens map[string]*MonitoringAccess) Authorizer {
+	return AuthorizerFunc(func(r *http.Request) (*MonitoringAccess, error) {
+		access, ok := tokens[strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")]
+		if !ok {
+			return nil, ErrMonitoringUnauthenticated
+		}
+		return access, nil
+	})
+}
+
+func newTenantMonitoringServer(t *testing.T) *MonitoringServer {
+	t.Helper()
+
+	base, err := New().Limit("global", "100/minute").Build()
+	if err != nil {
+		t.Fatalf("Failed to build limiter: %v", err)
+	}
+	config := DefaultObservabilityConfig()
+	config.EnableLogging = false
+	limiter := NewObservableLimiter(&tenantStatsLimiter{Limiter: base}, config)
+	t.Cleanup(func() { limiter.Close() })
+
+	limiter.Check(context.Background(), "acme:user-1")
+	limiter.Check(context.Background(), "globex:user-1")
+
+	return NewMonitoringServerWithConfig(limiter, &MonitoringConfig{
+		Authorizer: tokenAuthorizer(map[string]*MonitoringAccess{
+			"root":   {Admin: true},
+			"acme":   {Namespaces: []string{"acme"}},
+			"multi":  {Namespaces: []string{"acme", "initech"}},
+			"nobody": {},
+		}),
+	})
+}
+
+func monitoringGet(ms *MonitoringServer, path, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	ms.ServeHTTP(w, req)
+	return w
+}
+
+func TestMonitoringTenantStats(t *testing.T) {
+	ms := newTenantMonitoringServer(t)
+
+	var body struct {
+		Stats LimitStats `json:"stats"`
+	}
+
+	w := monitoringGet(ms, "/stats", "acme")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200 for tenant admin, got %d", w.Code)
+	}
+	json.NewDecoder(w.Body).Decode(&body)
+	if len(body.Stats.ByEntity) != 2 || body.Stats.ByEntity["globex:user-1"] != nil {
+		t.Errorf("Expected only acme entities, got %v", body.Stats.ByEntity)
+	}
+	if body.Stats.TotalRequests != 30 || body.Stats.TotalDenied != 3 || len(body.Stats.ByScope) != 0 {
+		t.Errorf("Expected totals of acme entities only, got %+v", body.Stats)
+	}
+
+	if w := monitoringGet(ms, "/stats?namespace=globex", "acme"); w.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 for another tenant's namespace, got %d", w.Code)
+	}
+	if w := monitoringGet(ms, "/stats", "multi"); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 when a multi-namespace caller names none, got %d", w.Code)
+	}
+
+	w = monitoringGet(ms, "/stats", "root")
+	body.Stats = LimitStats{}
+	json.NewDecoder(w.Body).Decode(&body)
+	if w.Code != http.StatusOK || len(body.Stats.ByEntity) != 3 || body.Stats.TotalRequests != 60 {
+		t.Errorf("Expected the full view for admins, got %d %+v", w.Code, body.Stats)
+	}
+
+	w = monitoringGet(ms, "/stats?namespace=globex", "root")
+	body.Stats = LimitStats{}
+	json.NewDecoder(w.Body).Decode(&body)
+	if len(body.Stats.ByEntity) != 1 || body.Stats.TotalRequests != 30 {
+		t.Errorf("Expected admins to narrow to one namespace, got %+v", body.Stats)
+	}
+}
+
+func TestMonitoringTenantMetrics(t *testing.T) {
+	ms := newTenantMonitoringServer(t)
+
+	w := monitoringGet(ms, "/metrics/prometheus", "acme")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", w.Code)
+	}
+	if strings.Contains(w.Body.String(), "globex") || !strings.Contains(w.Body.String(), "acme") {
+		t.Errorf("Expected only acme series, got:\n%s", w.Body.String())
+	}
+
+	w = monitoringGet(ms, "/metrics", "root")
+	if !strings.Contains(w.Body.String(), "globex") {
+		t.Error("Expected admins to see every tenant's metrics")
+	}
+}
+
+func TestMonitoringAuthorization(t *testing.T) {
+	ms := newTenantMonitoringServer(t)
+
+	if w := monitoringGet(ms, "/stats", ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without credentials, got %d", w.Code)
+	}
+	if w := monitoringGet(ms, "/stats", "nobody"); w.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 for access without namespaces, got %d", w.Code)
+	}
+	if w := monitoringGet(ms, "/debug", "acme"); w.Code != http.StatusForbidden {
+		t.Errorf("Expected /debug to be admin-only, got %d", w.Code)
+	}
+	if w := monitoringGet(ms, "/debug", "root"); w.Code != http.StatusOK {
+		t.Errorf("Expected admins to reach /debug, got %d", w.Code)
+	}
+	if w := monitoringGet(ms, "/healthz", ""); w.Code == http.StatusUnauthorized {
+		t.Error("Expected health probes to stay open")
+	}
+}
+
+func TestMonitoringWithoutAuthorizer(t *testing.T) {
+	base, _ := New().Limit("global", "100/minute").Build()
+	config := DefaultObservabilityConfig()
+	config.EnableLogging = false
+	limiter := NewObservableLimiter(&tenantStatsLimiter{Limiter: base}, config)
+	defer limiter.Close()
+	ms := NewMonitoringServer(limiter)
+
+	var body struct {
+		Stats LimitStats `json:"stats"`
+	}
+	w := monitoringGet(ms, "/stats?namespace=acme", "")
+	json.NewDecoder(w.Body).Decode(&body)
+	if w.Code != http.StatusOK || len(body.Stats.ByEntity) != 2 {
+		t.Errorf("Expected namespace views without an authorizer, got %d %v", w.Code, body.Stats.ByEntity)
+	}
+}
+
+func TestDefaultNamespace(t *testing.T) {
+	cases := map[string]string{"acme:user-1": "acme", "acme:team:7": "acme", "user-1": ""}
+	for entity, want := range cases {
+		if got := DefaultNamespace(entity); got != want {
+			t.Errorf("DefaultNamespace(%q) = %q, want %q", entity, got, want)
+		}
+	}
+	if got := metricKeyEntity("acme:user-1:global"); got != "acme:user-1" {
+		t.Errorf("Expected entity before the last ':', got %q", got)
+	}
+}
@@ -0,0 +1,177 @@
+// monitoring_auth.go - Built-in authentication and network allowlists for monitoring and admin endpoints
+package ratelimit
+
+import (
+	"crypto/subtle"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// BearerTokenAuthorizer grants admin access to requests carrying "Authorization: Bearer <token>".
+// An empty token rejects every request.
+// Example: ratelimit.BearerTokenAuthorizer(os.Getenv("GORLY_MONITORING_TOKEN"))
+func BearerTokenAuthorizer(token string) Authorizer {
+	return &bearerTokenAuthorizer{token: []byte(token)}
+}
+
+// bearerTokenAuthorizer checks a static bearer token
+type bearerTokenAuthorizer struct {
+	token []byte
+}
+
+// Authorize implements Authorizer
+func (a *bearerTokenAuthorizer) Authorize(r *http.Request) (*MonitoringAccess, error) {
+	scheme, token, _ := strings.Cut(r.Header.Get("Authorization"), " ")
+	if len(a.token) == 0 || !strings.EqualFold(scheme, "Bearer") || !secretEqual([]byte(token), a.token) {
+		return nil, ErrMonitoringUnauthenticated
+	}
+	return adminAccess, nil
+}
+
+// challenge implements authChallenger
+func (a *bearerTokenAuthorizer) challenge() string {
+	return `Bearer realm="gorly"`
+}
+
+// BasicAuthAuthorizer grants admin access to requests with matching HTTP basic auth credentials.
+// An empty password rejects every request.
+// Example: ratelimit.BasicAuthAuthorizer("ops", os.Getenv("GORLY_MONITORING_PASSWORD"))
+func BasicAuthAuthorizer(username, password string) Authorizer {
+	return &basicAuthAuthorizer{username: []byte(username), password: []byte(password)}
+}
+
+// basicAuthAuthorizer checks static basic auth credentials
+type basicAuthAuthorizer struct {
+	username []byte
+	password []byte
+}
+
+// Authorize implements Authorizer
+func (a *basicAuthAuthorizer) Authorize(r *http.Request) (*MonitoringAccess, error) {
+	username, password, ok := r.BasicAuth()
+	if !ok || len(a.password) == 0 {
+		return nil, ErrMonitoringUnauthenticated
+	}
+	// Compare both so the response time does not reveal which one was wrong
+	userOK := secretEqual([]byte(username), a.username)
+	passOK := secretEqual([]byte(password), a.password)
+	if !userOK || !passOK {
+		return nil, ErrMonitoringUnauthenticated
+	}
+	return adminAccess, nil
+}
+
+// challenge implements authChallenger
+func (a *basicAuthAuthorizer) challenge() string {
+	return `Basic realm="gorly", charset="UTF-8"`
+}
+
+// authChallenger is implemented by authorizers that tell clients how to authenticate
+type authChallenger interface {
+	challenge() string
+}
+
+// secretEqual compares secrets in constant time
+func secretEqual(given, want []byte) bool {
+	return subtle.ConstantTimeCompare(given, want) == 1
+}
+
+// accessGuard applies the network allowlist and authorizer of a monitoring config
+type accessGuard struct {
+	authorizer Authorizer
+	networks   []netip.Prefix
+	restricted bool // true when an allowlist is configured, even if no entry parsed
+}
+
+// newAccessGuard parses the allowlist of a config. Entries that fail to parse match
+// no client, so a mistyped allowlist fails closed.
+func newAccessGuard(config *MonitoringConfig) *accessGuard {
+	guard := &accessGuard{
+		authorizer: config.Authorizer,
+		restricted: len(config.AllowedNetworks) > 0,
+	}
+	for _, entry := range config.AllowedNetworks {
+		entry = strings.TrimSpace(entry)
+		if prefix, err := netip.ParsePrefix(entry); err == nil {
+			guard.networks = append(guard.networks, prefix.Masked())
+		} else if addr, err := netip.ParseAddr(entry); err == nil {
+			guard.networks = append(guard.networks, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+		}
+	}
+	return guard
+}
+
+// allowsClient reports whether the request comes from an allowed network.
+// Only the connection address is used; forwarding headers are trivially spoofed.
+func (g *accessGuard) allowsClient(r *http.Request) bool {
+	if !g.restricted {
+		return true
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+
+	for _, network := range g.networks {
+		if network.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// authorize checks a request against the allowlist and authorizer, writing an error
+// response and returning nil if it is rejected
+func (g *accessGuard) authorize(w http.ResponseWriter, r *http.Request) *MonitoringAccess {
+	if !g.allowsClient(r) {
+		writeMonitoringError(w, http.StatusForbidden, "forbidden")
+		return nil
+	}
+	if g.authorizer == nil {
+		return adminAccess
+	}
+
+	access, err := g.authorizer.Authorize(r)
+	if err != nil || access == nil {
+		if c, ok := g.authorizer.(authChallenger); ok {
+			w.Header().Set("WWW-Authenticate", c.challenge())
+		}
+		writeMonitoringError(w, http.StatusUnauthorized, "unauthorized")
+		return nil
+	}
+	if !access.Admin && len(access.Namespaces) == 0 {
+		writeMonitoringError(w, http.StatusForbidden, "forbidden")
+		return nil
+	}
+	return access
+}
+
+// ProtectAdmin guards an admin handler such as ScaleHandler with the network allowlist and
+// authorizer of a monitoring config. Only admin access reaches the handler.
+// Example: adminMux.Handle("/admin/scale", ratelimit.ProtectAdmin(ratelimit.ScaleHandler(limiter), config))
+func ProtectAdmin(handler http.Handler, config *MonitoringConfig) http.Handler {
+	if config == nil {
+		config = &MonitoringConfig{}
+	}
+	guard := newAccessGuard(config)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		access := guard.authorize(w, r)
+		if access == nil {
+			return
+		}
+		if !access.Admin {
+			writeMonitoringError(w, http.StatusForbidden, "forbidden")
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
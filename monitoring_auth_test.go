@@ -0,0 +1,183 @@
+// monitoring_auth_test.go
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newAuthMonitoringServer(t *testing.T, config *MonitoringConfig) *MonitoringServer {
+	t.Helper()
+
+	base, err := New().Limit("global", "100/minute").Build()
+	if err != nil {
+		t.Fatalf("Failed to build limiter: %v", err)
+	}
+	observability := DefaultObservabilityConfig()
+	observability.EnableLogging = false
+	limiter := NewObservableLimiter(base, observability)
+	t.Cleanup(func() { limiter.Close() })
+
+	return NewMonitoringServerWithConfig(limiter, config)
+}
+
+func serveMonitoring(h http.Handler, path, remoteAddr string, prepare func(r *http.Request)) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	if remoteAddr != "" {
+		req.RemoteAddr = remoteAddr
+	}
+	if prepare != nil {
+		prepare(req)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	return w
+}
+
+func TestBearerTokenAuthorizer(t *testing.T) {
+	ms := newAuthMonitoringServer(t, &MonitoringConfig{Authorizer: BearerTokenAuthorizer("s3cret")})
+
+	w := serveMonitoring(ms, "/debug", "", nil)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without a token, got %d", w.Code)
+	}
+	if got := w.Header().Get("WWW-Authenticate"); got != `Bearer realm="gorly"` {
+		t.Errorf("Expected a bearer challenge, got %q", got)
+	}
+
+	w = serveMonitoring(ms, "/debug", "", func(r *http.Request) { r.Header.Set("Authorization", "Bearer wrong") })
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for a wrong token, got %d", w.Code)
+	}
+
+	w = serveMonitoring(ms, "/debug", "", func(r *http.Request) { r.Header.Set("Authorization", "Bearer s3cret") })
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected 200 for the right token, got %d", w.Code)
+	}
+
+	if w := serveMonitoring(ms, "/health", "", nil); w.Code == http.StatusUnauthorized {
+		t.Error("Expected health endpoints to stay open")
+	}
+
+	empty := newAuthMonitoringServer(t, &MonitoringConfig{Authorizer: BearerTokenAuthorizer("")})
+	w = serveMonitoring(empty, "/stats", "", func(r *http.Request) { r.Header.Set("Authorization", "Bearer ") })
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected an empty token to reject every request, got %d", w.Code)
+	}
+}
+
+func TestBasicAuthAuthorizer(t *testing.T) {
+	ms := newAuthMonitoringServer(t, &MonitoringConfig{Authorizer: BasicAuthAuthorizer("ops", "hunter2")})
+
+	w := serveMonitoring(ms, "/stats", "", nil)
+	if w.Code != http.StatusUnauthorized || w.Header().Get("WWW-Authenticate") == "" {
+		t.Errorf("Expected 401 with a basic challenge, got %d %q", w.Code, w.Header().Get("WWW-Authenticate"))
+	}
+
+	w = serveMonitoring(ms, "/stats", "", func(r *http.Request) { r.SetBasicAuth("ops", "wrong") })
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for a wrong password, got %d", w.Code)
+	}
+
+	w = serveMonitoring(ms, "/stats", "", func(r *http.Request) { r.SetBasicAuth("ops", "hunter2") })
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected 200 for the right credentials, got %d", w.Code)
+	}
+}
+
+func TestMonitoringAllowedNetworks(t *testing.T) {
+	ms := newAuthMonitoringServer(t, &MonitoringConfig{
+		AllowedNetworks: []string{"10.0.0.0/8", "192.168.1.7", "fd00::/8"},
+	})
+
+	tests := []struct {
+		remoteAddr string
+		allowed    bool
+	}{
+		{"10.1.2.3:5000", true},
+		{"192.168.1.7:5000", true},
+		{"192.168.1.8:5000", false},
+		{"[fd00::1]:5000", true},
+		{"[::ffff:10.0.0.1]:5000", true},
+		{"203.0.113.9:5000", false},
+		{"not-an-address", false},
+	}
+	for _, tt := range tests {
+		w := serveMonitoring(ms, "/stats", tt.remoteAddr, nil)
+		if allowed := w.Code == http.StatusOK; allowed != tt.allowed {
+			t.Errorf("%s: expected allowed=%v, got status %d", tt.remoteAddr, tt.allowed, w.Code)
+		}
+	}
+
+	if w := serveMonitoring(ms, "/healthz", "203.0.113.9:5000", nil); w.Code == http.StatusForbidden {
+		t.Error("Expected health endpoints to ignore the allowlist")
+	}
+
+	// A mistyped allowlist fails closed
+	closed := newAuthMonitoringServer(t, &MonitoringConfig{AllowedNetworks: []string{"10.0.0.0/33"}})
+	if w := serveMonitoring(closed, "/stats", "10.0.0.1:5000", nil); w.Code != http.StatusForbidden {
+		t.Errorf("Expected an unparseable allowlist to deny, got %d", w.Code)
+	}
+}
+
+func TestMonitoringDisabledEndpoints(t *testing.T) {
+	ms := newAuthMonitoringServer(t, &MonitoringConfig{DisabledEndpoints: []string{"/debug", "/metrics/prometheus"}})
+
+	if w := serveMonitoring(ms, "/debug", "", nil); w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for a disabled endpoint, got %d", w.Code)
+	}
+	if w := serveMonitoring(ms, "/metrics/prometheus", "", nil); w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for a disabled endpoint, got %d", w.Code)
+	}
+	if w := serveMonitoring(ms, "/metrics", "", nil); w.Code != http.StatusOK {
+		t.Errorf("Expected enabled endpoints to be served, got %d", w.Code)
+	}
+
+	w := serveMonitoring(ms, "/", "", nil)
+	if body := w.Body.String(); strings.Contains(body, "/debug") {
+		t.Errorf("Expected the index to omit disabled endpoints, got %s", body)
+	}
+}
+
+func TestProtectAdmin(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) })
+	config := &MonitoringConfig{
+		Authorizer: AuthorizerFunc(func(r *http.Request) (*MonitoringAccess, error) {
+			switch r.Header.Get("Authorization") {
+			case "Bearer root":
+				return &MonitoringAccess{Admin: true}, nil
+			case "Bearer tenant":
+				return &MonitoringAccess{Namespaces: []string{"acme"}}, nil
+			}
+			return nil, ErrMonitoringUnauthenticated
+		}),
+		AllowedNetworks: []string{"127.0.0.1"},
+	}
+	protected := ProtectAdmin(handler, config)
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		token      string
+		want       int
+	}{
+		{"admin", "127.0.0.1:1", "root", http.StatusNoContent},
+		{"tenant", "127.0.0.1:1", "tenant", http.StatusForbidden},
+		{"anonymous", "127.0.0.1:1", "", http.StatusUnauthorized},
+		{"outside allowlist", "10.0.0.1:1", "root", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serveMonitoring(protected, "/admin/scale", tt.remoteAddr, func(r *http.Request) {
+				if tt.token != "" {
+					r.Header.Set("Authorization", "Bearer "+tt.token)
+				}
+			})
+			if w.Code != tt.want {
+				t.Errorf("Expected %d, got %d", tt.want, w.Code)
+			}
+		})
+	}
+}
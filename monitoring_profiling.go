@@ -0,0 +1,173 @@
+// monitoring_profiling.go - Opt-in runtime profiling and goroutine dumps on the monitoring server
+package ratelimit
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"reflect"
+	"runtime"
+	"runtime/pprof"
+	"runtime/trace"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Profiling endpoints, served only with MonitoringConfig.Profiling
+const (
+	pprofPath      = "/debug/pprof/"
+	goroutinesPath = "/debug/goroutines"
+)
+
+// maxProfileDuration bounds CPU profiles and execution traces requested with ?seconds=
+const maxProfileDuration = 5 * time.Minute
+
+// gorlyPackagePath prefixes the functions of this module in stack traces
+var gorlyPackagePath = reflect.TypeOf(MonitoringServer{}).PkgPath()
+
+// profilingEndpoint reports whether path is one of the profiling endpoints
+func profilingEndpoint(path string) bool {
+	return path == goroutinesPath || strings.HasPrefix(path, pprofPath)
+}
+
+// handlePprof serves the profiles of runtime/pprof like net/http/pprof, without registering
+// them on http.DefaultServeMux: /debug/pprof/ lists them, /debug/pprof/<name> writes one
+// (?debug=1 for text, ?gc=1 to collect garbage before a heap profile), /debug/pprof/profile
+// and /debug/pprof/trace record for ?seconds= (default 30 and 1), and /debug/pprof/cmdline
+// shows the command line.
+func (ms *MonitoringServer) handlePprof(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+
+	name := strings.TrimPrefix(r.URL.Path, pprofPath)
+	switch name {
+	case "":
+		ms.writePprofIndex(w)
+	case "cmdline":
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		fmt.Fprint(w, strings.Join(os.Args, "\x00"))
+	case "profile":
+		ms.recordProfile(w, r, 30*time.Second, pprof.StartCPUProfile, pprof.StopCPUProfile)
+	case "trace":
+		ms.recordProfile(w, r, time.Second, trace.Start, trace.Stop)
+	default:
+		profile := pprof.Lookup(name)
+		if profile == nil {
+			writeMonitoringError(w, http.StatusNotFound, "Unknown profile")
+			return
+		}
+		debug, _ := strconv.Atoi(r.URL.Query().Get("debug"))
+		if name == "heap" && r.URL.Query().Get("gc") != "" {
+			runtime.GC()
+		}
+		if debug != 0 {
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		} else {
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.Header().Set("Content-Disposition", `attachment; filename="`+name+`"`)
+		}
+		profile.WriteTo(w, debug)
+	}
+}
+
+// recordProfile runs a CPU profile or execution trace for ?seconds= and streams it to w.
+// Only one of each can run at a time in the process.
+func (ms *MonitoringServer) recordProfile(w http.ResponseWriter, r *http.Request, duration time.Duration, start func(w io.Writer) error, stop func()) {
+	if seconds := r.URL.Query().Get("seconds"); seconds != "" {
+		n, err := strconv.ParseFloat(seconds, 64)
+		if err != nil || n <= 0 {
+			writeMonitoringError(w, http.StatusBadRequest, "seconds must be a positive number")
+			return
+		}
+		duration = time.Duration(n * float64(time.Second))
+	}
+	if duration > maxProfileDuration {
+		duration = maxProfileDuration
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	if err := start(w); err != nil {
+		w.Header().Del("Content-Type")
+		writeMonitoringError(w, http.StatusConflict, "Profiling already in progress")
+		return
+	}
+	defer stop()
+
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-r.Context().Done():
+	}
+}
+
+// writePprofIndex lists the available profiles
+func (ms *MonitoringServer) writePprofIndex(w http.ResponseWriter) {
+	profiles := pprof.Profiles()
+	sort.Slice(profiles, func(i, j int) bool { return profiles[i].Name() < profiles[j].Name() })
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprintf(w, "Profiles at %s<name>, binary unless ?debug=1:\n\n", pprofPath)
+	for _, profile := range profiles {
+		fmt.Fprintf(w, "%6d %s\n", profile.Count(), profile.Name())
+	}
+	fmt.Fprintf(w, "\n%sprofile?seconds=30  CPU profile\n", pprofPath)
+	fmt.Fprintf(w, "%strace?seconds=1     Execution trace\n", pprofPath)
+	fmt.Fprintf(w, "%scmdline             Command line\n", pprofPath)
+	fmt.Fprintf(w, "%s           Stacks of gorly's goroutines\n", goroutinesPath)
+}
+
+// handleGoroutines dumps the stacks of the goroutines running gorly code, such as the
+// background workers of stores, stats, federation and hot reload, so operators can see
+// what the limiter is doing without wading through the whole process. ?all=1 dumps every goroutine.
+func (ms *MonitoringServer) handleGoroutines(w http.ResponseWriter, r *http.Request) {
+	all := r.URL.Query().Get("all") != ""
+	stacks := goroutineStacks()
+
+	var dump bytes.Buffer
+	matched := 0
+	// The first stack is the goroutine serving this request
+	for _, stack := range stacks[1:] {
+		if all || runsGorlyCode(stack) {
+			matched++
+			dump.WriteString(stack)
+			dump.WriteString("\n\n")
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	if all {
+		fmt.Fprintf(w, "# %d goroutines\n\n", matched)
+	} else {
+		fmt.Fprintf(w, "# %d of %d goroutines run gorly code\n\n", matched, len(stacks)-1)
+	}
+	w.Write(dump.Bytes())
+}
+
+// goroutineStacks returns the stack trace of every goroutine, the calling goroutine first
+func goroutineStacks() []string {
+	buf := make([]byte, 64<<10)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			return strings.Split(strings.TrimSpace(string(buf[:n])), "\n\n")
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+}
+
+// runsGorlyCode reports whether a goroutine's stack has a frame of this module, or was created by one
+func runsGorlyCode(stack string) bool {
+	for _, line := range strings.Split(stack, "\n") {
+		fn := strings.TrimPrefix(line, "created by ")
+		if strings.HasPrefix(fn, gorlyPackagePath+".") || strings.HasPrefix(fn, gorlyPackagePath+"/") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,95 @@
+// monitoring_profiling_test.go
+package ratelimit
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func newProfilingMonitoringServer(t *testing.T, profiling bool) *MonitoringServer {
+	t.Helper()
+
+	base, err := New().Limit("global", "100/minute").Build()
+	if err != nil {
+		t.Fatalf("Failed to build limiter: %v", err)
+	}
+	config := DefaultObservabilityConfig()
+	config.EnableLogging = false
+	limiter := NewObservableLimiter(base, config)
+	t.Cleanup(func() { limiter.Close() })
+
+	return NewMonitoringServerWithConfig(limiter, &MonitoringConfig{
+		Authorizer: tokenAuthorizer(map[string]*MonitoringAccess{
+			"root": {Admin: true},
+			"acme": {Namespaces: []string{"acme"}},
+		}),
+		Profiling: profiling,
+	})
+}
+
+func TestProfilingDisabledByDefault(t *testing.T) {
+	ms := newProfilingMonitoringServer(t, false)
+	for _, path := range []string{"/debug/pprof/", "/debug/pprof/heap", "/debug/goroutines"} {
+		if w := monitoringGet(ms, path, "root"); w.Code != http.StatusNotFound {
+			t.Errorf("%s: expected 404 without Profiling, got %d", path, w.Code)
+		}
+	}
+	if w := monitoringGet(ms, "/", "root"); strings.Contains(w.Body.String(), "/debug/pprof/") {
+		t.Error("Expected the index to leave out disabled profiling endpoints")
+	}
+}
+
+func TestProfilingEndpoints(t *testing.T) {
+	ms := newProfilingMonitoringServer(t, true)
+
+	if w := monitoringGet(ms, "/debug/pprof/heap", "acme"); w.Code != http.StatusForbidden {
+		t.Errorf("Expected profiles to be limited to admins, got %d", w.Code)
+	}
+
+	tests := []struct {
+		path        string
+		contentType string
+		contains    string
+	}{
+		{"/debug/pprof/", "text/plain; charset=utf-8", "goroutine"},
+		{"/debug/pprof/heap?debug=1", "text/plain; charset=utf-8", "heap profile"},
+		{"/debug/pprof/goroutine", "application/octet-stream", ""},
+		{"/debug/pprof/profile?seconds=0.05", "application/octet-stream", ""},
+		{"/debug/goroutines", "text/plain; charset=utf-8", "created by " + gorlyPackagePath + "/stores"},
+	}
+	for _, tt := range tests {
+		w := monitoringGet(ms, tt.path, "root")
+		if w.Code != http.StatusOK || w.Header().Get("Content-Type") != tt.contentType || w.Body.Len() == 0 {
+			t.Errorf("%s: expected 200 with %s, got %d with %q (%d bytes)", tt.path, tt.contentType, w.Code, w.Header().Get("Content-Type"), w.Body.Len())
+			continue
+		}
+		if !strings.Contains(w.Body.String(), tt.contains) {
+			t.Errorf("%s: expected %q in the body", tt.path, tt.contains)
+		}
+	}
+
+	if w := monitoringGet(ms, "/debug/pprof/nonsense", "root"); w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown profile, got %d", w.Code)
+	}
+	if w := monitoringGet(ms, "/debug/pprof/profile?seconds=-1", "root"); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a negative duration, got %d", w.Code)
+	}
+}
+
+func TestRunsGorlyCode(t *testing.T) {
+	tests := []struct {
+		stack string
+		want  bool
+	}{
+		{"goroutine 7 [select]:\n" + gorlyPackagePath + "/internal/core.(*statsBuffer).run(0xc0000a2000)\n\t/src/statsbuffer.go:120 +0x8c", true},
+		{"goroutine 9 [chan receive]:\nmain.worker()\n\t/app/main.go:10\ncreated by " + gorlyPackagePath + ".(*HotReloadManager).Start in goroutine 1", true},
+		{"goroutine 3 [IO wait]:\nnet/http.(*conn).serve(0xc000180000)\n\t/go/src/net/http/server.go:2009", false},
+		{"goroutine 4 [sleep]:\n" + gorlyPackagePath + "x/other.run()", false},
+	}
+	for _, tt := range tests {
+		if got := runsGorlyCode(tt.stack); got != tt.want {
+			t.Errorf("runsGorlyCode(%q) = %v, want %v", tt.stack, got, tt.want)
+		}
+	}
+}
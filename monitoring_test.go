@@ -0,0 +1,146 @@
+// monitoring_test.go
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// traceKey carries a fake trace ID in request contexts
+type traceKey struct{}
+
+func newMetricsTestServer(t *testing.T, configure func(*ObservabilityConfig)) (*ObservableLimiter, *MonitoringServer) {
+	t.Helper()
+
+	base, err := New().Limit("global", "1/minute").Build()
+	if err != nil {
+		t.Fatalf("Failed to build limiter: %v", err)
+	}
+	config := DefaultObservabilityConfig()
+	config.EnableLogging = false
+	configure(config)
+	limiter := NewObservableLimiter(base, config)
+	t.Cleanup(func() { limiter.Close() })
+	return limiter, NewMonitoringServer(limiter)
+}
+
+func scrape(server *MonitoringServer, accept string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/metrics/prometheus", nil)
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestPrometheusMetricsSchema(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("schema 2 exposes a duration histogram", func(t *testing.T) {
+		limiter, server := newMetricsTestServer(t, func(*ObservabilityConfig) {})
+		limiter.Check(ctx, "user-1", "global")
+
+		body := scrape(server, "").Body.String()
+		for _, want := range []string{
+			`gorly_info{version="` + GetVersion() + `",schema="` + MetricsSchemaVersion + `"} 1`,
+			"# TYPE gorly_request_duration_seconds histogram",
+			`gorly_request_duration_seconds_bucket{le="+Inf"} 1`,
+			"gorly_request_duration_seconds_count 1",
+		} {
+			if !strings.Contains(body, want) {
+				t.Errorf("Expected %q in:\n%s", want, body)
+			}
+		}
+		if strings.Contains(body, "# EOF") || strings.Contains(body, "trace_id") {
+			t.Error("Expected the classic text format without OpenMetrics additions")
+		}
+	})
+
+	t.Run("legacy names keep the average gauge", func(t *testing.T) {
+		limiter, server := newMetricsTestServer(t, func(c *ObservabilityConfig) { c.LegacyMetricNames = true })
+		limiter.Check(ctx, "user-1", "global")
+
+		body := scrape(server, "").Body.String()
+		if !strings.Contains(body, `schema="1"`) || !strings.Contains(body, "# TYPE gorly_request_duration_seconds gauge") {
+			t.Errorf("Expected schema 1 with the duration gauge, got:\n%s", body)
+		}
+		if strings.Contains(body, "gorly_request_duration_seconds_bucket") {
+			t.Error("Expected no histogram in legacy mode")
+		}
+	})
+}
+
+func TestDeniedRequestExemplars(t *testing.T) {
+	limiter, server := newMetricsTestServer(t, func(c *ObservabilityConfig) {
+		c.TraceID = func(ctx context.Context) string {
+			id, _ := ctx.Value(traceKey{}).(string)
+			return id
+		}
+	})
+
+	limiter.Check(context.WithValue(context.Background(), traceKey{}, "trace-allowed"), "user-1", "global")
+	limiter.Check(context.WithValue(context.Background(), traceKey{}, "4bf92f3577b34da6a3ce929d0e0e4736"), "user-1", "global")
+
+	rec := scrape(server, "application/openmetrics-text; version=1.0.0")
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/openmetrics-text") {
+		t.Errorf("Expected OpenMetrics content type, got %q", ct)
+	}
+	body := rec.Body.String()
+	if !strings.Contains(body, `gorly_requests_denied_total{entity="user-1",scope="global"} 1 # {trace_id="4bf92f3577b34da6a3ce929d0e0e4736"} 1 `) {
+		t.Errorf("Expected the denied counter to carry the denied request's trace, got:\n%s", body)
+	}
+	if strings.Contains(body, "trace-allowed") {
+		t.Error("Expected allowed requests not to become exemplars")
+	}
+	if !strings.HasSuffix(body, "# EOF\n") {
+		t.Error("Expected the OpenMetrics EOF marker")
+	}
+}
+
+func TestProcessMetrics(t *testing.T) {
+	base, err := New().Limit("global", "10/minute").Build()
+	if err != nil {
+		t.Fatalf("Failed to build limiter: %v", err)
+	}
+	config := DefaultObservabilityConfig()
+	config.EnableLogging = false
+	limiter := NewObservableLimiter(base, config)
+	defer limiter.Close()
+
+	for _, entity := range []string{"user-1", "user-2"} {
+		limiter.Check(context.Background(), entity, "global")
+	}
+	manager := NewHotReloadManager(base, nil)
+	if err := manager.applyConfig(context.Background(), &HotReloadConfig{Limits: map[string]string{"global": "20/minute"}, Version: "v2"}); err != nil {
+		t.Fatalf("Failed to apply config: %v", err)
+	}
+
+	metrics := limiter.GetMetrics()
+	if keys, ok := metrics["store_keys"].(int64); !ok || keys < 2 {
+		t.Errorf("Expected the memory store's key count, got %v", metrics["store_keys"])
+	}
+	if _, ok := metrics["store_pool"]; ok {
+		t.Error("Expected no pool metrics for the memory store")
+	}
+	if version, ok := metrics["config"].(*ConfigVersion); !ok || version.Generation != 2 || version.Version != "v2" {
+		t.Errorf("Expected generation 2 of config v2, got %+v", metrics["config"])
+	}
+	if goroutines, _ := metrics["goroutines"].(int64); goroutines < 1 {
+		t.Errorf("Expected a goroutine count, got %v", metrics["goroutines"])
+	}
+	if heap, _ := metrics["heap_alloc_bytes"].(int64); heap <= 0 {
+		t.Errorf("Expected the heap size, got %v", metrics["heap_alloc_bytes"])
+	}
+
+	exposition := convertToPrometheusFormat(metrics, prometheusOptions{})
+	for _, want := range []string{"gorly_goroutines ", "gorly_heap_alloc_bytes ", "gorly_store_keys ", "gorly_config_generation 2", `gorly_config_info{version="v2"} 1`,
+		`gorly_config_hash_info{hash="` + base.ConfigHash() + `"} 1`} {
+		if !strings.Contains(exposition, want) {
+			t.Errorf("Expected %q in the exposition", want)
+		}
+	}
+}
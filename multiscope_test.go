@@ -0,0 +1,58 @@
+// multiscope_test.go
+package ratelimit
+
+import (
+	"context"
+	"testing"
+)
+
+func TestCheckAll(t *testing.T) {
+	ctx := context.Background()
+	limiter, err := New().Limit("global", "5/minute").Limit("upload", "1/minute").Build()
+	if err != nil {
+		t.Fatalf("Failed to build limiter: %v", err)
+	}
+	defer limiter.Close()
+
+	costs := []ScopeCost{{Scope: "global"}, {Scope: "upload"}}
+	if result, err := limiter.CheckAll(ctx, "user:1", costs); err != nil || !result.Allowed || len(result.Results) != 2 {
+		t.Fatalf("Expected the first upload to be allowed, got %+v, %v", result, err)
+	}
+	result, err := limiter.CheckAll(ctx, "user:1", costs)
+	if err != nil || result.Allowed || result.Denied != "upload" || result.RetryAfter <= 0 {
+		t.Fatalf("Expected upload to deny the second upload, got %+v, %v", result, err)
+	}
+	if peek, _ := limiter.Peek(ctx, "user:1", "global"); peek.Remaining != 4 {
+		t.Errorf("Expected the denied upload not to charge global, %d remaining", peek.Remaining)
+	}
+
+	limiter.MaintenanceMode(true, nil)
+	if result, err := limiter.CheckAll(ctx, "user:2", costs); err != nil || result.Allowed || !result.Results[1].Maintenance {
+		t.Errorf("Expected maintenance mode to deny every scope, got %+v, %v", result, err)
+	}
+}
+
+func TestCheckAllComposite(t *testing.T) {
+	ctx := context.Background()
+	perUser, err := New().Limit("global", "5/minute").Limit("upload", "2/minute").Build()
+	if err != nil {
+		t.Fatalf("Failed to build limiter: %v", err)
+	}
+	shared, err := New().Limit("global", "5/minute").Limit("upload", "1/minute").Build()
+	if err != nil {
+		t.Fatalf("Failed to build limiter: %v", err)
+	}
+	limiter := All(perUser, shared)
+	defer limiter.Close()
+
+	costs := []ScopeCost{{Scope: "global"}, {Scope: "upload"}}
+	if result, err := limiter.CheckAll(ctx, "user:1", costs); err != nil || !result.Allowed {
+		t.Fatalf("Expected the first upload to be allowed, got %+v, %v", result, err)
+	}
+	if result, err := limiter.CheckAll(ctx, "user:1", costs); err != nil || result.Allowed {
+		t.Fatalf("Expected the shared limiter to deny the second upload, got %+v, %v", result, err)
+	}
+	if peek, _ := shared.Peek(ctx, "user:1", "global"); peek.Remaining != 4 {
+		t.Errorf("Expected the denying limiter not to charge global, %d remaining", peek.Remaining)
+	}
+}
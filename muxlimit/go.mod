@@ -0,0 +1,19 @@
+module github.com/itsatony/gorly/muxlimit
+
+go 1.23.0
+
+require (
+	github.com/gorilla/mux v1.8.0
+	github.com/itsatony/gorly v0.0.0-00010101000000-000000000000
+)
+
+require (
+	github.com/cespare/xxhash/v2 v2.2.0 // indirect
+	github.com/dgryski/go-rendezvous v0.0.0-20200823014737-9f7001d12a5f // indirect
+	github.com/klauspost/compress v1.17.9 // indirect
+	github.com/redis/go-redis/v9 v9.3.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+// Adapters are developed against the core module in the same repository
+replace github.com/itsatony/gorly => ../
@@ -1,5 +1,8 @@
-// middleware/mux.go
-package middleware
+// muxlimit/muxlimit.go
+// Package muxlimit rate limits Gorilla Mux routers with scopes and entities taken from
+// route names and variables. It is a module of its own, so the Gorilla Mux dependency is
+// only downloaded by applications that use it.
+package muxlimit
 
 import (
 	"context"
@@ -8,17 +11,18 @@ import (
 
 	"github.com/gorilla/mux"
 	"github.com/itsatony/gorly"
+	"github.com/itsatony/gorly/middleware"
 )
 
-// MuxMiddleware provides rate limiting middleware specifically for Gorilla Mux
-type MuxMiddleware struct {
-	httpMiddleware *HTTPMiddleware
-	config         *MuxMiddlewareConfig
+// Middleware provides rate limiting middleware specifically for Gorilla Mux
+type Middleware struct {
+	httpMiddleware *middleware.HTTPMiddleware
+	config         *MiddlewareConfig
 }
 
-// MuxMiddlewareConfig extends HTTPMiddlewareConfig with Mux-specific features
-type MuxMiddlewareConfig struct {
-	*HTTPMiddlewareConfig
+// MiddlewareConfig extends HTTPMiddlewareConfig with Mux-specific features
+type MiddlewareConfig struct {
+	*middleware.HTTPMiddlewareConfig
 
 	// RouteBasedScopes maps route names to scopes
 	RouteBasedScopes map[string]string
@@ -38,8 +42,8 @@ type RouteRateLimit struct {
 	Scope     string `json:"scope,omitempty"`
 }
 
-// NewMuxMiddleware creates a new Gorilla Mux middleware
-func NewMuxMiddleware(config *MuxMiddlewareConfig) (*MuxMiddleware, error) {
+// NewMiddleware creates a new Gorilla Mux middleware
+func NewMiddleware(config *MiddlewareConfig) (*Middleware, error) {
 	if config == nil {
 		return nil, ratelimit.NewRateLimitError(
 			ratelimit.ErrorTypeConfig,
@@ -62,29 +66,29 @@ func NewMuxMiddleware(config *MuxMiddlewareConfig) (*MuxMiddleware, error) {
 		return extractMuxScope(r, config, originalScopeExtractor)
 	}
 
-	httpMiddleware, err := NewHTTPMiddleware(config.HTTPMiddlewareConfig)
+	httpMiddleware, err := middleware.NewHTTPMiddleware(config.HTTPMiddlewareConfig)
 	if err != nil {
 		return nil, err
 	}
 
-	return &MuxMiddleware{
+	return &Middleware{
 		httpMiddleware: httpMiddleware,
 		config:         config,
 	}, nil
 }
 
 // Middleware returns the Mux middleware function
-func (m *MuxMiddleware) Middleware(next http.Handler) http.Handler {
+func (m *Middleware) Middleware(next http.Handler) http.Handler {
 	return m.httpMiddleware.Middleware(next)
 }
 
 // MiddlewareFunc returns the middleware as a function for use with mux.Router.Use()
-func (m *MuxMiddleware) MiddlewareFunc(next http.HandlerFunc) http.HandlerFunc {
+func (m *Middleware) MiddlewareFunc(next http.HandlerFunc) http.HandlerFunc {
 	return m.httpMiddleware.MiddlewareFunc(next)
 }
 
 // RouteMiddleware creates middleware for specific routes with custom rate limits
-func (m *MuxMiddleware) RouteMiddleware(routeName string, customLimit *RouteRateLimit) func(http.Handler) http.Handler {
+func (m *Middleware) RouteMiddleware(routeName string, customLimit *RouteRateLimit) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			// Add route-specific information to context for scope extraction
@@ -102,7 +106,7 @@ func (m *MuxMiddleware) RouteMiddleware(routeName string, customLimit *RouteRate
 }
 
 // extractMuxScope extracts scope information using Mux-specific context
-func extractMuxScope(r *http.Request, config *MuxMiddlewareConfig, fallback HTTPScopeExtractor) string {
+func extractMuxScope(r *http.Request, config *MiddlewareConfig, fallback middleware.HTTPScopeExtractor) string {
 	// Check for custom route-specific limits first
 	if customLimit := getMuxCustomLimit(r.Context()); customLimit != nil && customLimit.Scope != "" {
 		return customLimit.Scope
@@ -156,17 +160,17 @@ func extractMuxScope(r *http.Request, config *MuxMiddlewareConfig, fallback HTTP
 	return ratelimit.ScopeGlobal
 }
 
-// MuxEntityExtractor creates an entity extractor that can use Mux route variables
-func MuxEntityExtractor(variableName string, entityType string, getTierFunc func(string) string) HTTPEntityExtractor {
+// EntityExtractor creates an entity extractor that can use Mux route variables
+func EntityExtractor(variableName string, entityType string, getTierFunc func(string) string) middleware.HTTPEntityExtractor {
 	return func(r *http.Request) (ratelimit.AuthEntity, error) {
 		vars := mux.Vars(r)
 		if vars == nil {
-			return DefaultIPEntityExtractor(r)
+			return middleware.DefaultIPEntityExtractor(r)
 		}
 
 		entityID, exists := vars[variableName]
 		if !exists || entityID == "" {
-			return DefaultIPEntityExtractor(r)
+			return middleware.DefaultIPEntityExtractor(r)
 		}
 
 		tier := ratelimit.TierFree
@@ -178,23 +182,23 @@ func MuxEntityExtractor(variableName string, entityType string, getTierFunc func
 	}
 }
 
-// MuxUserEntityExtractor creates an entity extractor for user-based routing
-func MuxUserEntityExtractor(getTierFunc func(string) string) HTTPEntityExtractor {
-	return MuxEntityExtractor("userId", ratelimit.EntityTypeUser, getTierFunc)
+// UserEntityExtractor creates an entity extractor for user-based routing
+func UserEntityExtractor(getTierFunc func(string) string) middleware.HTTPEntityExtractor {
+	return EntityExtractor("userId", ratelimit.EntityTypeUser, getTierFunc)
 }
 
-// MuxAPIKeyEntityExtractor creates an entity extractor for API key-based routing
-func MuxAPIKeyEntityExtractor(getTierFunc func(string) string) HTTPEntityExtractor {
-	return MuxEntityExtractor("apiKey", ratelimit.EntityTypeAPIKey, getTierFunc)
+// APIKeyEntityExtractor creates an entity extractor for API key-based routing
+func APIKeyEntityExtractor(getTierFunc func(string) string) middleware.HTTPEntityExtractor {
+	return EntityExtractor("apiKey", ratelimit.EntityTypeAPIKey, getTierFunc)
 }
 
-// MuxTenantEntityExtractor creates an entity extractor for tenant-based routing
-func MuxTenantEntityExtractor(getTierFunc func(string) string) HTTPEntityExtractor {
-	return MuxEntityExtractor("tenantId", ratelimit.EntityTypeTenant, getTierFunc)
+// TenantEntityExtractor creates an entity extractor for tenant-based routing
+func TenantEntityExtractor(getTierFunc func(string) string) middleware.HTTPEntityExtractor {
+	return EntityExtractor("tenantId", ratelimit.EntityTypeTenant, getTierFunc)
 }
 
 // ResourceBasedScopeExtractor creates a scope extractor based on resource types in the URL
-func ResourceBasedScopeExtractor() HTTPScopeExtractor {
+func ResourceBasedScopeExtractor() middleware.HTTPScopeExtractor {
 	return func(r *http.Request) string {
 		path := strings.ToLower(r.URL.Path)
 
@@ -221,7 +225,7 @@ func ResourceBasedScopeExtractor() HTTPScopeExtractor {
 }
 
 // CRUDScopeExtractor combines HTTP method with resource detection for CRUD operations
-func CRUDScopeExtractor() HTTPScopeExtractor {
+func CRUDScopeExtractor() middleware.HTTPScopeExtractor {
 	resourceExtractor := ResourceBasedScopeExtractor()
 
 	return func(r *http.Request) string {
@@ -244,11 +248,11 @@ func CRUDScopeExtractor() HTTPScopeExtractor {
 	}
 }
 
-// DefaultMuxMiddlewareConfig returns a default configuration for Mux middleware
-func DefaultMuxMiddlewareConfig(limiter ratelimit.RateLimiter) *MuxMiddlewareConfig {
-	httpConfig := DefaultHTTPMiddlewareConfig(limiter)
+// DefaultMiddlewareConfig returns a default configuration for Mux middleware
+func DefaultMiddlewareConfig(limiter ratelimit.RateLimiter) *MiddlewareConfig {
+	httpConfig := middleware.DefaultHTTPMiddlewareConfig(limiter)
 
-	return &MuxMiddlewareConfig{
+	return &MiddlewareConfig{
 		HTTPMiddlewareConfig: httpConfig,
 		RouteBasedScopes: map[string]string{
 			"memories":      ratelimit.ScopeMemory,
@@ -302,13 +306,13 @@ func getMuxCustomLimit(ctx context.Context) *RouteRateLimit {
 }
 
 // Helper method to set up common M3MO-specific middleware
-func NewM3MOMuxMiddleware(limiter ratelimit.RateLimiter, authExtractor HTTPEntityExtractor) (*MuxMiddleware, error) {
-	config := &MuxMiddlewareConfig{
-		HTTPMiddlewareConfig: &HTTPMiddlewareConfig{
+func NewM3MOMiddleware(limiter ratelimit.RateLimiter, authExtractor middleware.HTTPEntityExtractor) (*Middleware, error) {
+	config := &MiddlewareConfig{
+		HTTPMiddlewareConfig: &middleware.HTTPMiddlewareConfig{
 			Limiter:         limiter,
 			EntityExtractor: authExtractor,
 			ScopeExtractor:  CRUDScopeExtractor(),
-			ErrorHandler:    DefaultHTTPErrorHandler,
+			ErrorHandler:    middleware.DefaultHTTPErrorHandler,
 			AddHeaders:      true,
 			SkipPaths: []string{
 				"/health",
@@ -332,5 +336,5 @@ func NewM3MOMuxMiddleware(limiter ratelimit.RateLimiter, authExtractor HTTPEntit
 		},
 	}
 
-	return NewMuxMiddleware(config)
+	return NewMiddleware(config)
 }
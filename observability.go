@@ -3,12 +3,17 @@ package ratelimit
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"net/http"
+	"runtime"
+	runtimemetrics "runtime/metrics"
 	"sync"
 	"sync/atomic"
 	"time"
 
+	"github.com/itsatony/gorly/internal/core"
 	"github.com/itsatony/gorly/internal/middleware"
 )
 
@@ -95,14 +100,145 @@ type MetricsCollector interface {
 	IncrementHealthCheck()
 }
 
+// emptyEntityCounter is implemented by limiters that count requests without an entity
+type emptyEntityCounter interface {
+	emptyEntities() int64
+}
+
+// unknownTierCounter is implemented by limiters that count claims of unconfigured tiers
+type unknownTierCounter interface {
+	unknownTiers() int64
+}
+
+// overrideExpiryCounter is implemented by limiters that count entity overrides removed at their expiry
+type overrideExpiryCounter interface {
+	expiredOverrides() int64
+}
+
+// enforcementReporter is implemented by limiters with per-scope enforcement modes
+type enforcementReporter interface {
+	enforcement() map[string]string
+	shadowDenials() map[string]int64
+}
+
+// failurePolicyReporter is implemented by limiters with failure policies for store outages
+type failurePolicyReporter interface {
+	failurePolicies() map[string]string
+	failedOpen() map[string]int64
+	failedClosed() map[string]int64
+	localFallbacks() (allowed, denied map[string]int64)
+}
+
+// scopeGuard is implemented by limiters that fold excess scopes into OverflowScope
+type scopeGuard interface {
+	guardScope(scope string) string
+	scopeOverflows() int64
+}
+
+// statsFlusher is implemented by limiters that flush stats counters to the store write-behind
+type statsFlusher interface {
+	statsFlush() *StatsFlushStats
+}
+
+// tierCacheReporter is implemented by limiters that cache tiers looked up by a TierResolver
+type tierCacheReporter interface {
+	tierCache() *TierCacheStats
+}
+
+// trustedCallReporter is implemented by limiters that verify trusted call signatures
+type trustedCallReporter interface {
+	trustedCalls() *TrustedCallStats
+}
+
+// introspectionReporter is implemented by limiters that introspect tokens
+type introspectionReporter interface {
+	introspection() *IntrospectionStats
+}
+
+// storeReporter is implemented by limiters that report the size, connection pool and failover state of their store
+type storeReporter interface {
+	storeKeys() (int64, bool)
+	storePool() *StorePoolStats
+	storeFailover() *StoreFailoverStats
+}
+
+// storeRetryReporter is implemented by limiters that retry and hedge store reads
+type storeRetryReporter interface {
+	storeRetries() *StoreRetryStats
+}
+
+// keyExpiryReporter is implemented by limiters that watch their keys expire
+type keyExpiryReporter interface {
+	keyExpiry() *KeyExpiryStats
+}
+
+// stateCompressionReporter is implemented by limiters that compress algorithm state
+type stateCompressionReporter interface {
+	stateCompression() *StateCompressionStats
+}
+
+// localCacheReporter is implemented by limiters that cache Redis in process memory
+type localCacheReporter interface {
+	localCache() *LocalCacheStats
+}
+
+// configVersionReporter is implemented by limiters that track updates of their limits
+type configVersionReporter interface {
+	configVersion() *ConfigVersion
+}
+
+// denialCacheRecorder is implemented by collectors that count denial cache hits
+type denialCacheRecorder interface {
+	IncrementDenialCacheHit(entity, scope string)
+}
+
+// exemplarRecorder is implemented by collectors that link denied requests to traces
+type exemplarRecorder interface {
+	RecordDeniedExemplar(entity, scope, traceID string)
+}
+
+// heapAlloc returns the bytes of allocated heap objects like runtime.MemStats.HeapAlloc,
+// without stopping the world
+func heapAlloc() int64 {
+	sample := []runtimemetrics.Sample{{Name: "/memory/classes/heap/objects:bytes"}}
+	runtimemetrics.Read(sample)
+	if sample[0].Value.Kind() != runtimemetrics.KindUint64 {
+		return 0
+	}
+	return int64(sample[0].Value.Uint64())
+}
+
+// DefaultDurationBuckets are the upper bounds, in seconds, of the request duration histogram
+var DefaultDurationBuckets = []float64{0.0001, 0.00025, 0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1}
+
+// DurationHistogram is a snapshot of the request duration histogram
+type DurationHistogram struct {
+	Buckets []float64 `json:"buckets"` // Upper bounds in seconds
+	Counts  []int64   `json:"counts"`  // Cumulative count of durations up to each bound
+	Count   int64     `json:"count"`
+	Sum     float64   `json:"sum"` // Seconds
+}
+
+// MetricExemplar links a counter to the trace of one request it counted
+type MetricExemplar struct {
+	TraceID   string    `json:"trace_id"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
 // PrometheusMetrics implements MetricsCollector for Prometheus
 type PrometheusMetrics struct {
 	requestTotal       map[string]int64
 	requestDenied      map[string]int64
 	requestAllowed     map[string]int64
+	denialCacheHits    map[string]int64
 	rateLimitRemaining map[string]int64
 	rateLimitUsed      map[string]int64
 	requestDurations   []time.Duration
+	durationBuckets    []int64 // Per bucket of DefaultDurationBuckets, not cumulative
+	durationCount      int64
+	durationSum        time.Duration
+	deniedExemplars    map[string]MetricExemplar
+	entityScopes       map[string]map[string]struct{} // entity -> scopes with series, for ForgetEntity
 	queueSize          int64
 	healthy            int64
 	healthChecks       int64
@@ -115,9 +251,13 @@ func NewPrometheusMetrics() *PrometheusMetrics {
 		requestTotal:       make(map[string]int64),
 		requestDenied:      make(map[string]int64),
 		requestAllowed:     make(map[string]int64),
+		denialCacheHits:    make(map[string]int64),
 		rateLimitRemaining: make(map[string]int64),
 		rateLimitUsed:      make(map[string]int64),
 		requestDurations:   make([]time.Duration, 0),
+		durationBuckets:    make([]int64, len(DefaultDurationBuckets)),
+		deniedExemplars:    make(map[string]MetricExemplar),
+		entityScopes:       make(map[string]map[string]struct{}),
 		healthy:            1,
 	}
 }
@@ -130,6 +270,12 @@ func (pm *PrometheusMetrics) IncrementRequestTotal(entity, scope string) {
 	key := pm.makeKey(entity, scope)
 	pm.mu.Lock()
 	pm.requestTotal[key]++
+	scopes, ok := pm.entityScopes[entity]
+	if !ok {
+		scopes = make(map[string]struct{})
+		pm.entityScopes[entity] = scopes
+	}
+	scopes[scope] = struct{}{}
 	pm.mu.Unlock()
 }
 
@@ -147,6 +293,42 @@ func (pm *PrometheusMetrics) IncrementRequestAllowed(entity, scope string) {
 	pm.mu.Unlock()
 }
 
+// ForgetEntity drops every series of an entity and returns the scopes it had series in
+func (pm *PrometheusMetrics) ForgetEntity(entity string) []string {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	scopes := sortedKeys(pm.entityScopes[entity])
+	for _, scope := range scopes {
+		key := pm.makeKey(entity, scope)
+		delete(pm.requestTotal, key)
+		delete(pm.requestDenied, key)
+		delete(pm.requestAllowed, key)
+		delete(pm.denialCacheHits, key)
+		delete(pm.rateLimitRemaining, key)
+		delete(pm.rateLimitUsed, key)
+		delete(pm.deniedExemplars, key)
+	}
+	delete(pm.entityScopes, entity)
+	return scopes
+}
+
+// RecordDeniedExemplar remembers the trace of the latest denied request of an entity and scope
+func (pm *PrometheusMetrics) RecordDeniedExemplar(entity, scope, traceID string) {
+	key := pm.makeKey(entity, scope)
+	pm.mu.Lock()
+	pm.deniedExemplars[key] = MetricExemplar{TraceID: traceID, Timestamp: time.Now()}
+	pm.mu.Unlock()
+}
+
+// IncrementDenialCacheHit counts a denial served from the local denial cache
+func (pm *PrometheusMetrics) IncrementDenialCacheHit(entity, scope string) {
+	key := pm.makeKey(entity, scope)
+	pm.mu.Lock()
+	pm.denialCacheHits[key]++
+	pm.mu.Unlock()
+}
+
 func (pm *PrometheusMetrics) SetRateLimitRemaining(entity, scope string, remaining int64) {
 	key := pm.makeKey(entity, scope)
 	pm.mu.Lock()
@@ -168,6 +350,15 @@ func (pm *PrometheusMetrics) RecordRequestDuration(entity, scope string, duratio
 	if len(pm.requestDurations) > 1000 {
 		pm.requestDurations = pm.requestDurations[len(pm.requestDurations)-1000:]
 	}
+	seconds := duration.Seconds()
+	for i, bound := range DefaultDurationBuckets {
+		if seconds <= bound {
+			pm.durationBuckets[i]++
+			break
+		}
+	}
+	pm.durationCount++
+	pm.durationSum += duration
 	pm.mu.Unlock()
 }
 
@@ -198,6 +389,7 @@ func (pm *PrometheusMetrics) GetMetrics() map[string]interface{} {
 	metrics["request_total"] = copyInt64Map(pm.requestTotal)
 	metrics["request_denied"] = copyInt64Map(pm.requestDenied)
 	metrics["request_allowed"] = copyInt64Map(pm.requestAllowed)
+	metrics["denial_cache_hits"] = copyInt64Map(pm.denialCacheHits)
 	metrics["rate_limit_remaining"] = copyInt64Map(pm.rateLimitRemaining)
 	metrics["rate_limit_used"] = copyInt64Map(pm.rateLimitUsed)
 
@@ -211,6 +403,25 @@ func (pm *PrometheusMetrics) GetMetrics() map[string]interface{} {
 		metrics["request_duration_samples"] = len(pm.requestDurations)
 	}
 
+	histogram := DurationHistogram{
+		Buckets: append([]float64(nil), DefaultDurationBuckets...),
+		Counts:  make([]int64, len(DefaultDurationBuckets)),
+		Count:   pm.durationCount,
+		Sum:     pm.durationSum.Seconds(),
+	}
+	var cumulative int64
+	for i, n := range pm.durationBuckets {
+		cumulative += n
+		histogram.Counts[i] = cumulative
+	}
+	metrics["request_duration_histogram"] = histogram
+
+	exemplars := make(map[string]MetricExemplar, len(pm.deniedExemplars))
+	for k, v := range pm.deniedExemplars {
+		exemplars[k] = v
+	}
+	metrics["request_denied_exemplars"] = exemplars
+
 	metrics["queue_size"] = atomic.LoadInt64(&pm.queueSize)
 	metrics["healthy"] = atomic.LoadInt64(&pm.healthy) == 1
 	metrics["health_checks"] = atomic.LoadInt64(&pm.healthChecks)
@@ -344,6 +555,24 @@ type ObservabilityConfig struct {
 	Metrics           MetricsCollector
 	HealthChecker     *HealthChecker
 	LogLevel          LogLevel
+
+	// Push exports metrics to a Prometheus Pushgateway on Close and optionally on an interval
+	Push *PushConfig
+
+	// Federation pushes stats to a stats aggregator on Close and optionally on an interval
+	Federation *FederationPushConfig
+
+	// LegacyMetricNames exposes metrics schema 1 for dashboards built on it, with request
+	// duration as an average gauge instead of a histogram
+	LegacyMetricNames bool
+
+	// TraceID returns the trace ID of a request context, e.g. of its OpenTelemetry span.
+	// Denied request counters then carry exemplars linking to a denied request's trace.
+	TraceID func(ctx context.Context) string
+
+	// Analytics tracks request arrival times per entity for burstiness and inter-arrival
+	// statistics, reported in Stats and on the /analytics endpoint (nil disables)
+	Analytics *AnalyticsConfig
 }
 
 // DefaultObservabilityConfig returns a default observability configuration
@@ -364,9 +593,13 @@ type ObservableLimiter struct {
 	limiter   Limiter
 	config    *ObservabilityConfig
 	startTime time.Time
+	pusher    *metricsPusher    // nil unless metrics push is configured
+	federator *statsFederator   // nil unless stats federation is configured
+	analytics *requestAnalytics // nil unless analytics are configured
 }
 
-// NewObservableLimiter creates a limiter with observability features
+// NewObservableLimiter creates a limiter with observability features.
+// The wrapper is experimental; new code should use the experimental/observability package.
 func NewObservableLimiter(limiter Limiter, config *ObservabilityConfig) *ObservableLimiter {
 	ol := &ObservableLimiter{
 		limiter:   limiter,
@@ -380,6 +613,18 @@ func NewObservableLimiter(limiter Limiter, config *ObservabilityConfig) *Observa
 		config.HealthChecker.AddCheck("uptime", ol.checkUptime, time.Millisecond*100, false)
 	}
 
+	if config.EnableMetrics && config.Push != nil {
+		ol.pusher = newMetricsPusher(config.Push, ol)
+	}
+
+	if config.Federation != nil {
+		ol.federator = newStatsFederator(config.Federation, ol)
+	}
+
+	if config.Analytics != nil {
+		ol.analytics = newRequestAnalytics(config.Analytics)
+	}
+
 	return ol
 }
 
@@ -387,21 +632,32 @@ func NewObservableLimiter(limiter Limiter, config *ObservabilityConfig) *Observa
 func (ol *ObservableLimiter) Check(ctx context.Context, entity string, scope ...string) (*LimitResult, error) {
 	start := time.Now()
 
-	scopeStr := "global"
-	if len(scope) > 0 {
-		scopeStr = scope[0]
+	// Resolve context overrides so logs and metrics match the checked entity and scope
+	// Scopes beyond the limiter's scope budget are checked and labeled as the overflow scope they share
+	entity, scopeStr := callTarget(ctx, entity, scope)
+	if guard, ok := ol.limiter.(scopeGuard); ok {
+		scopeStr = guard.guardScope(scopeStr)
+	}
+	scope = []string{scopeStr}
+
+	// Entities usually come from request headers; keep raw values out of logs and labels
+	entityLabel := logSafe(entity)
+	scopeStr = logSafe(scopeStr)
+
+	if ol.analytics != nil {
+		ol.analytics.record(entityLabel)
 	}
 
 	// Log request
 	if ol.config.EnableLogging {
 		ol.config.Logger.Debug("Rate limit check",
-			Field{"entity", entity},
+			Field{"entity", entityLabel},
 			Field{"scope", scopeStr})
 	}
 
 	// Record metrics
 	if ol.config.EnableMetrics {
-		ol.config.Metrics.IncrementRequestTotal(entity, scopeStr)
+		ol.config.Metrics.IncrementRequestTotal(entityLabel, scopeStr)
 	}
 
 	// Perform the actual check
@@ -412,34 +668,55 @@ func (ol *ObservableLimiter) Check(ctx context.Context, entity string, scope ...
 	// Record metrics based on result
 	if ol.config.EnableMetrics && err == nil {
 		if result.Allowed {
-			ol.config.Metrics.IncrementRequestAllowed(entity, scopeStr)
+			ol.config.Metrics.IncrementRequestAllowed(entityLabel, scopeStr)
 		} else {
-			ol.config.Metrics.IncrementRequestDenied(entity, scopeStr)
+			ol.config.Metrics.IncrementRequestDenied(entityLabel, scopeStr)
+			if recorder, ok := ol.config.Metrics.(denialCacheRecorder); ok && result.Cached {
+				recorder.IncrementDenialCacheHit(entityLabel, scopeStr)
+			}
+			if recorder, ok := ol.config.Metrics.(exemplarRecorder); ok && ol.config.TraceID != nil {
+				if traceID := ol.config.TraceID(ctx); traceID != "" {
+					recorder.RecordDeniedExemplar(entityLabel, scopeStr, traceID)
+				}
+			}
 		}
 
-		ol.config.Metrics.SetRateLimitRemaining(entity, scopeStr, result.Remaining)
-		ol.config.Metrics.SetRateLimitUsed(entity, scopeStr, result.Used)
-		ol.config.Metrics.RecordRequestDuration(entity, scopeStr, duration)
+		ol.config.Metrics.SetRateLimitRemaining(entityLabel, scopeStr, result.Remaining)
+		ol.config.Metrics.SetRateLimitUsed(entityLabel, scopeStr, result.Used)
+		ol.config.Metrics.RecordRequestDuration(entityLabel, scopeStr, duration)
 	}
 
 	// Log result
 	if ol.config.EnableLogging {
 		if err != nil {
 			ol.config.Logger.Error("Rate limit check error",
-				Field{"entity", entity},
+				Field{"entity", entityLabel},
 				Field{"scope", scopeStr},
 				Field{"error", err.Error()},
 				Field{"duration", duration})
+		} else if result.UnknownTier != "" && result.Allowed {
+			ol.config.Logger.Warn("Rate limit check with unknown tier",
+				Field{"entity", entityLabel},
+				Field{"scope", scopeStr},
+				Field{"tier", logSafe(result.UnknownTier)},
+				Field{"remaining", result.Remaining},
+				Field{"duration", duration})
+		} else if result.ShadowDenied {
+			ol.config.Logger.Info("Rate limit exceeded in shadow mode",
+				Field{"entity", entityLabel},
+				Field{"scope", scopeStr},
+				Field{"retry_after", result.RetryAfter},
+				Field{"duration", duration})
 		} else if !result.Allowed {
 			ol.config.Logger.Warn("Rate limit exceeded",
-				Field{"entity", entity},
+				Field{"entity", entityLabel},
 				Field{"scope", scopeStr},
 				Field{"remaining", result.Remaining},
 				Field{"retry_after", result.RetryAfter},
 				Field{"duration", duration})
 		} else {
 			ol.config.Logger.Debug("Rate limit check passed",
-				Field{"entity", entity},
+				Field{"entity", entityLabel},
 				Field{"scope", scopeStr},
 				Field{"remaining", result.Remaining},
 				Field{"duration", duration})
@@ -449,6 +726,50 @@ func (ol *ObservableLimiter) Check(ctx context.Context, entity string, scope ...
 	return result, err
 }
 
+// CheckAll implements the Limiter interface, counting the outcome in every scope it covers
+func (ol *ObservableLimiter) CheckAll(ctx context.Context, entity string, costs []ScopeCost) (*MultiScopeResult, error) {
+	if entity == "" {
+		entity, _ = core.EntityFromContext(ctx)
+	}
+	entityLabel := logSafe(entity)
+	if ol.analytics != nil {
+		ol.analytics.record(entityLabel)
+	}
+
+	result, err := ol.limiter.CheckAll(ctx, entity, costs)
+	if err != nil {
+		if ol.config.EnableLogging {
+			ol.config.Logger.Error("Multi-scope rate limit check error",
+				Field{"entity", entityLabel},
+				Field{"error", err.Error()})
+		}
+		return nil, err
+	}
+
+	if ol.config.EnableMetrics {
+		for i, cost := range costs {
+			scopeStr := logSafe(cost.Scope)
+			ol.config.Metrics.IncrementRequestTotal(entityLabel, scopeStr)
+			if result.Allowed {
+				ol.config.Metrics.IncrementRequestAllowed(entityLabel, scopeStr)
+			} else {
+				ol.config.Metrics.IncrementRequestDenied(entityLabel, scopeStr)
+			}
+			if i < len(result.Results) {
+				ol.config.Metrics.SetRateLimitRemaining(entityLabel, scopeStr, result.Results[i].Remaining)
+				ol.config.Metrics.SetRateLimitUsed(entityLabel, scopeStr, result.Results[i].Used)
+			}
+		}
+	}
+	if ol.config.EnableLogging && !result.Allowed {
+		ol.config.Logger.Warn("Rate limit exceeded",
+			Field{"entity", entityLabel},
+			Field{"scope", logSafe(result.Denied)},
+			Field{"retry_after", result.RetryAfter})
+	}
+	return result, nil
+}
+
 // Allow implements the Limiter interface with observability
 func (ol *ObservableLimiter) Allow(ctx context.Context, entity string, scope ...string) (bool, error) {
 	result, err := ol.Check(ctx, entity, scope...)
@@ -458,15 +779,250 @@ func (ol *ObservableLimiter) Allow(ctx context.Context, entity string, scope ...
 	return result.Allowed, nil
 }
 
+// Limits implements the Limiter interface
+func (ol *ObservableLimiter) Limits(entity string) ([]ScopeLimit, error) {
+	return ol.limiter.Limits(entity)
+}
+
+// Peek implements the Limiter interface
+func (ol *ObservableLimiter) Peek(ctx context.Context, entity string, scope ...string) (*LimitResult, error) {
+	return ol.limiter.Peek(ctx, entity, scope...)
+}
+
+// Diagnostics implements the Limiter interface
+func (ol *ObservableLimiter) Diagnostics(ctx context.Context, entity string, scope ...string) (*Diagnostics, error) {
+	return ol.limiter.Diagnostics(ctx, entity, scope...)
+}
+
+// Prewarm delegates to the underlying limiter
+func (ol *ObservableLimiter) Prewarm(ctx context.Context, specs []PrewarmSpec) error {
+	return ol.limiter.Prewarm(ctx, specs)
+}
+
+// Grant delegates to the underlying limiter and logs the grant
+func (ol *ObservableLimiter) Grant(ctx context.Context, entity, scope string, extra int64, ttl time.Duration) (*Grant, error) {
+	grant, err := ol.limiter.Grant(ctx, entity, scope, extra, ttl)
+	if ol.config.EnableLogging {
+		if err != nil {
+			ol.config.Logger.Error("Failed to grant budget", Field{"entity", entity}, Field{"scope", scope}, Field{"error", err.Error()})
+		} else {
+			ol.config.Logger.Info("Granted budget", Field{"entity", entity}, Field{"scope", scope}, Field{"extra", extra}, Field{"expires_at", grant.ExpiresAt})
+		}
+	}
+	return grant, err
+}
+
+// RevokeGrant delegates to the underlying limiter and logs the revocation
+func (ol *ObservableLimiter) RevokeGrant(ctx context.Context, entity, scope string) error {
+	err := ol.limiter.RevokeGrant(ctx, entity, scope)
+	if ol.config.EnableLogging && err == nil {
+		ol.config.Logger.Info("Revoked grant", Field{"entity", entity}, Field{"scope", scope})
+	}
+	return err
+}
+
+// GrantStatus delegates to the underlying limiter
+func (ol *ObservableLimiter) GrantStatus(ctx context.Context, entity, scope string) (*Grant, error) {
+	return ol.limiter.GrantStatus(ctx, entity, scope)
+}
+
+// Forget drops the entity's metrics and request patterns, then forgets it in the underlying
+// limiter, including counters of the scopes it was observed in
+func (ol *ObservableLimiter) Forget(ctx context.Context, entity string) (*ForgetRecord, error) {
+	// Metrics and patterns are kept under the log-safe label of the entity
+	var scopes []string
+	if forgetter, ok := ol.config.Metrics.(entityForgetter); ok {
+		scopes = forgetter.ForgetEntity(logSafe(entity))
+	}
+	if ol.analytics != nil {
+		ol.analytics.forget(logSafe(entity))
+	}
+
+	var record *ForgetRecord
+	var err error
+	if forgetter, ok := ol.limiter.(scopedForgetter); ok {
+		record, err = forgetter.forget(ctx, entity, scopes)
+	} else {
+		record, err = ol.limiter.Forget(ctx, entity)
+	}
+	if ol.config.EnableLogging && record != nil {
+		if err != nil {
+			ol.config.Logger.Error("Failed to forget entity", Field{"subject", record.Subject}, Field{"error", err.Error()})
+		} else {
+			ol.config.Logger.Info("Forgot entity", Field{"subject", record.Subject}, Field{"keys", record.Keys})
+		}
+	}
+	return record, err
+}
+
+// recordAuthFailure delegates failure reporting to the wrapped limiter
+func (ol *ObservableLimiter) recordAuthFailure(ctx context.Context, entity string) (time.Duration, error) {
+	tracker, ok := ol.limiter.(authTracker)
+	if !ok {
+		return 0, fmt.Errorf("limiter does not support authentication lockouts")
+	}
+	return tracker.recordAuthFailure(ctx, entity)
+}
+
+// recordAuthSuccess delegates success reporting to the wrapped limiter
+func (ol *ObservableLimiter) recordAuthSuccess(ctx context.Context, entity string) error {
+	tracker, ok := ol.limiter.(authTracker)
+	if !ok {
+		return fmt.Errorf("limiter does not support authentication lockouts")
+	}
+	return tracker.recordAuthSuccess(ctx, entity)
+}
+
+// chargeTokens delegates token accounting to the wrapped limiter
+func (ol *ObservableLimiter) chargeTokens(ctx context.Context, entity, scope, model string, promptTokens, completionTokens int64) (*LimitResult, error) {
+	charger, ok := ol.limiter.(tokenCharger)
+	if !ok {
+		return nil, fmt.Errorf("limiter does not support token budgets")
+	}
+	return charger.chargeTokens(ctx, entity, scope, model, promptTokens, completionTokens)
+}
+
+// extractEntity delegates entity extraction to the wrapped limiter
+func (ol *ObservableLimiter) extractEntity(r *http.Request) string {
+	if extractor, ok := ol.limiter.(entityExtractor); ok {
+		return extractor.extractEntity(r)
+	}
+	return extractIP(r)
+}
+
+// updateLimits delegates limit updates to the wrapped limiter
+func (ol *ObservableLimiter) updateLimits(update core.LimitUpdate) error {
+	return updateLimiterLimits(ol.limiter, update)
+}
+
+// setCosts delegates cost table updates to the wrapped limiter
+func (ol *ObservableLimiter) setCosts(costs map[string]int64) error {
+	return setLimiterCosts(ol.limiter, costs)
+}
+
+// saveConfigSnapshot delegates to the wrapped limiter's store
+func (ol *ObservableLimiter) saveConfigSnapshot(ctx context.Context, data []byte) error {
+	snapshots, err := configSnapshots(ol.limiter)
+	if err != nil {
+		return err
+	}
+	return snapshots.saveConfigSnapshot(ctx, data)
+}
+
+// loadConfigSnapshot delegates to the wrapped limiter's store
+func (ol *ObservableLimiter) loadConfigSnapshot(ctx context.Context) ([]byte, error) {
+	snapshots, err := configSnapshots(ol.limiter)
+	if err != nil {
+		return nil, err
+	}
+	return snapshots.loadConfigSnapshot(ctx)
+}
+
+// saveReportState delegates to the wrapped limiter's store
+func (ol *ObservableLimiter) saveReportState(ctx context.Context, data []byte) error {
+	state, err := reportStates(ol.limiter)
+	if err != nil {
+		return err
+	}
+	return state.saveReportState(ctx, data)
+}
+
+// loadReportState delegates to the wrapped limiter's store
+func (ol *ObservableLimiter) loadReportState(ctx context.Context) ([]byte, error) {
+	state, err := reportStates(ol.limiter)
+	if err != nil {
+		return nil, err
+	}
+	return state.loadReportState(ctx)
+}
+
+// configVersion delegates to the wrapped limiter
+func (ol *ObservableLimiter) configVersion() *ConfigVersion {
+	if reporter, ok := ol.limiter.(configVersionReporter); ok {
+		return reporter.configVersion()
+	}
+	return nil
+}
+
+// Scale implements the Limiter interface with observability
+func (ol *ObservableLimiter) Scale(factor float64) error {
+	err := ol.limiter.Scale(factor)
+	if ol.config.EnableLogging {
+		if err != nil {
+			ol.config.Logger.Error("Failed to scale limits", Field{"factor", factor}, Field{"error", err.Error()})
+		} else {
+			ol.config.Logger.Warn("Scaled all limits", Field{"factor", factor})
+		}
+	}
+	return err
+}
+
+// ScaleFactor implements the Limiter interface
+func (ol *ObservableLimiter) ScaleFactor() float64 {
+	return ol.limiter.ScaleFactor()
+}
+
+// ConfigHash implements the Limiter interface
+func (ol *ObservableLimiter) ConfigHash() string {
+	return ol.limiter.ConfigHash()
+}
+
+// RunWhenLeader implements the Limiter interface with observability
+func (ol *ObservableLimiter) RunWhenLeader(name string, interval time.Duration, fn func(ctx context.Context) error) error {
+	if err := ol.limiter.RunWhenLeader(name, interval, fn); err != nil {
+		return err
+	}
+	if ol.config.EnableLogging {
+		ol.config.Logger.Info("Leader job registered", Field{"job", name}, Field{"interval", interval.String()})
+	}
+	return nil
+}
+
+// MaintenanceMode implements the Limiter interface with observability
+func (ol *ObservableLimiter) MaintenanceMode(enabled bool, allowlist []string) {
+	ol.limiter.MaintenanceMode(enabled, allowlist)
+	if ol.config.EnableLogging {
+		if enabled {
+			ol.config.Logger.Warn("Maintenance mode enabled", Field{"allowlist", len(allowlist)})
+		} else {
+			ol.config.Logger.Info("Maintenance mode disabled")
+		}
+	}
+}
+
 // Stats implements the Limiter interface with observability
 func (ol *ObservableLimiter) Stats(ctx context.Context) (*LimitStats, error) {
 	stats, err := ol.limiter.Stats(ctx)
 	if err != nil && ol.config.EnableLogging {
 		ol.config.Logger.Error("Failed to get stats", Field{"error", err.Error()})
 	}
+
+	// Attach the request pattern of every entity seen recently
+	if err == nil && ol.analytics != nil {
+		if stats.ByEntity == nil {
+			stats.ByEntity = make(map[string]*EntityStats)
+		}
+		for _, p := range ol.analytics.patterns() {
+			entityStats, ok := stats.ByEntity[p.Entity]
+			if !ok {
+				entityStats = &EntityStats{Entity: p.Entity}
+				stats.ByEntity[p.Entity] = entityStats
+			}
+			entityStats.Pattern = p.RequestPattern
+		}
+	}
 	return stats, err
 }
 
+// RequestPatterns returns the request pattern of every entity seen within the analytics
+// window, busiest minute first, or nil if analytics are not enabled
+func (ol *ObservableLimiter) RequestPatterns() []EntityPattern {
+	if ol.analytics == nil {
+		return nil
+	}
+	return ol.analytics.patterns()
+}
+
 // Health implements the Limiter interface with observability
 func (ol *ObservableLimiter) Health(ctx context.Context) error {
 	if ol.config.EnableHealthCheck {
@@ -504,7 +1060,91 @@ func (ol *ObservableLimiter) GetMetrics() map[string]interface{} {
 	}
 
 	if pm, ok := ol.config.Metrics.(*PrometheusMetrics); ok {
-		return pm.GetMetrics()
+		metrics := pm.GetMetrics()
+		if counter, ok := ol.limiter.(emptyEntityCounter); ok {
+			metrics["empty_entities"] = counter.emptyEntities()
+		}
+		if counter, ok := ol.limiter.(probeCounter); ok {
+			metrics["probe_bypasses"] = counter.probeBypasses()
+		}
+		if guard, ok := ol.limiter.(scopeGuard); ok {
+			metrics["scope_overflows"] = guard.scopeOverflows()
+		}
+		if counter, ok := ol.limiter.(unknownTierCounter); ok {
+			metrics["unknown_tiers"] = counter.unknownTiers()
+		}
+		if counter, ok := ol.limiter.(overrideExpiryCounter); ok {
+			metrics["expired_overrides"] = counter.expiredOverrides()
+		}
+		if reporter, ok := ol.limiter.(enforcementReporter); ok {
+			metrics["enforcement"] = reporter.enforcement()
+			metrics["shadow_denials"] = reporter.shadowDenials()
+		}
+		if reporter, ok := ol.limiter.(failurePolicyReporter); ok {
+			metrics["failure_policies"] = reporter.failurePolicies()
+			metrics["failed_open"] = reporter.failedOpen()
+			metrics["failed_closed"] = reporter.failedClosed()
+			metrics["local_fallback_allowed"], metrics["local_fallback_denied"] = reporter.localFallbacks()
+		}
+		if flusher, ok := ol.limiter.(statsFlusher); ok {
+			if flush := flusher.statsFlush(); flush != nil {
+				metrics["stats_flush"] = flush
+			}
+		}
+		if reporter, ok := ol.limiter.(tierCacheReporter); ok {
+			if cache := reporter.tierCache(); cache != nil {
+				metrics["tier_cache"] = cache
+			}
+		}
+		if reporter, ok := ol.limiter.(trustedCallReporter); ok {
+			if trusted := reporter.trustedCalls(); trusted != nil {
+				metrics["trusted_calls"] = trusted
+			}
+		}
+		if reporter, ok := ol.limiter.(introspectionReporter); ok {
+			if introspection := reporter.introspection(); introspection != nil {
+				metrics["token_introspection"] = introspection
+			}
+		}
+		if reporter, ok := ol.limiter.(storeReporter); ok {
+			if keys, ok := reporter.storeKeys(); ok {
+				metrics["store_keys"] = keys
+			}
+			if pool := reporter.storePool(); pool != nil {
+				metrics["store_pool"] = pool
+			}
+			if failover := reporter.storeFailover(); failover != nil {
+				metrics["store_failover"] = failover
+			}
+		}
+		if reporter, ok := ol.limiter.(storeRetryReporter); ok {
+			if retries := reporter.storeRetries(); retries != nil {
+				metrics["store_retries"] = retries
+			}
+		}
+		if reporter, ok := ol.limiter.(keyExpiryReporter); ok {
+			if expiry := reporter.keyExpiry(); expiry != nil {
+				metrics["key_expiry"] = expiry
+			}
+		}
+		if reporter, ok := ol.limiter.(stateCompressionReporter); ok {
+			if compression := reporter.stateCompression(); compression != nil {
+				metrics["state_compression"] = compression
+			}
+		}
+		if reporter, ok := ol.limiter.(localCacheReporter); ok {
+			if cache := reporter.localCache(); cache != nil {
+				metrics["local_cache"] = cache
+			}
+		}
+		if reporter, ok := ol.limiter.(configVersionReporter); ok {
+			if version := reporter.configVersion(); version != nil {
+				metrics["config"] = version
+			}
+		}
+		metrics["goroutines"] = int64(runtime.NumGoroutine())
+		metrics["heap_alloc_bytes"] = heapAlloc()
+		return metrics
 	}
 
 	return map[string]interface{}{
@@ -517,14 +1157,33 @@ func (ol *ObservableLimiter) Middleware() interface{} {
 	return ol.limiter.Middleware()
 }
 
-// For implements the Limiter interface
+// For implements the Limiter interface, logging the adapter it returns at debug level
 func (ol *ObservableLimiter) For(framework middleware.FrameworkType) interface{} {
-	return ol.limiter.For(framework)
+	mw := ol.limiter.For(framework)
+	if ol.config.EnableLogging {
+		ol.config.Logger.Debug("Middleware adapter created",
+			Field{"framework", framework.String()},
+			Field{"adapter", fmt.Sprintf("%T", mw)})
+	}
+	return mw
+}
+
+// HTTPMiddleware implements the Limiter interface
+func (ol *ObservableLimiter) HTTPMiddleware() func(http.Handler) http.Handler {
+	return ol.limiter.HTTPMiddleware()
 }
 
-// Close implements the Limiter interface
+// Close implements the Limiter interface, pushing the final metrics and stats first if push
+// or federation is configured
 func (ol *ObservableLimiter) Close() error {
-	return ol.limiter.Close()
+	var pushErr, federationErr error
+	if ol.pusher != nil {
+		pushErr = ol.pusher.close()
+	}
+	if ol.federator != nil {
+		federationErr = ol.federator.close()
+	}
+	return errors.Join(pushErr, federationErr, ol.limiter.Close())
 }
 
 // Private health check methods
@@ -540,3 +1199,9 @@ func (ol *ObservableLimiter) checkUptime(ctx context.Context) error {
 	}
 	return nil
 }
+
+// logSafe strips control characters from a value and bounds its length before it is logged or used as a label
+func logSafe(value string) string {
+	safe, _ := core.NormalizeInput(value, core.DefaultMaxEntityLength, nil)
+	return safe
+}
@@ -0,0 +1,105 @@
+// op.go - Throttling of internal operations such as batch jobs and cron tasks
+package ratelimit
+
+import (
+	"context"
+	"time"
+)
+
+// opRetryInterval is how long a waiting operation sleeps when a denial names no wait
+const opRetryInterval = 100 * time.Millisecond
+
+// Operation throttles calls of an internal operation, such as a batch job, a cron task or
+// an expensive function, with a limiter's limits. The operation name is the scope, so its
+// limit, tiers, overrides, stats and metrics are configured and reported like those of HTTP
+// scopes. Operations are immutable; For and Wait return adjusted copies.
+type Operation struct {
+	limiter Limiter
+	name    string
+	entity  string
+	wait    bool
+	maxWait time.Duration
+}
+
+// Op returns the operation name throttled by limiter. Calls are counted against the
+// entity "op:<name>" unless For names another, e.g. the tenant a job runs for.
+// Example: err := ratelimit.Op(limiter, "reindex").Do(ctx, reindex)
+func Op(limiter Limiter, name string) *Operation {
+	return &Operation{limiter: limiter, name: name, entity: "op:" + name}
+}
+
+// For returns the operation counted against entity instead of the operation itself
+// Example: ratelimit.Op(limiter, "export").For("tenant:" + tenantID).Do(ctx, export)
+func (o *Operation) For(entity string) *Operation {
+	op := *o
+	op.entity = entity
+	return &op
+}
+
+// Wait returns the operation waiting up to max for the limiter to allow a denied call
+// instead of failing it at once; 0 waits as long as the context allows
+// Example: ratelimit.Op(limiter, "sync").Wait(time.Minute).Do(ctx, sync)
+func (o *Operation) Wait(max time.Duration) *Operation {
+	op := *o
+	op.wait = true
+	op.maxWait = max
+	return &op
+}
+
+// Name returns the operation name, which is also its scope
+func (o *Operation) Name() string {
+	return o.name
+}
+
+// Do runs fn if the limiter allows the call and returns its error. A denied call returns
+// a RATE_LIMIT_EXCEEDED error, see IsRateLimitExceeded, once waiting gives up; limiter and
+// context errors are returned as they are. fn is never run without being allowed.
+func (o *Operation) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, err := o.Acquire(ctx); err != nil {
+		return err
+	}
+	return fn(ctx)
+}
+
+// Acquire charges one call of the operation, waiting if the operation waits, and returns
+// the allowing result. Use it when the work does not fit in a function passed to Do.
+func (o *Operation) Acquire(ctx context.Context) (*LimitResult, error) {
+	var deadline time.Time
+	if o.wait && o.maxWait > 0 {
+		deadline = time.Now().Add(o.maxWait)
+	}
+
+	for {
+		result, err := o.limiter.Check(ctx, o.entity, o.name)
+		if err != nil {
+			return nil, err
+		}
+		if result.Allowed {
+			return result, nil
+		}
+
+		delay := o.retryDelay(result)
+		if !o.wait || (!deadline.IsZero() && time.Now().Add(delay).After(deadline)) {
+			return nil, NewRateLimitExceededError(o.entity, o.name, result.Limit, result.Used, result.RetryAfter)
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// retryDelay returns how long to wait before retrying a denied call
+func (o *Operation) retryDelay(result *LimitResult) time.Duration {
+	if result.RetryAfter > 0 {
+		return result.RetryAfter
+	}
+	if wait := time.Until(result.ResetTime); !result.ResetTime.IsZero() && wait > 0 {
+		return wait
+	}
+	return opRetryInterval
+}
@@ -0,0 +1,83 @@
+// op_test.go
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestOperation(t *testing.T) {
+	limiter, err := New().Limit("reindex", "2/minute").Build()
+	if err != nil {
+		t.Fatalf("Failed to build limiter: %v", err)
+	}
+	defer limiter.Close()
+	ctx := context.Background()
+
+	runs := 0
+	reindex := func(ctx context.Context) error {
+		runs++
+		return nil
+	}
+	op := Op(limiter, "reindex")
+	for i := 0; i < 2; i++ {
+		if err := op.Do(ctx, reindex); err != nil {
+			t.Fatalf("Call %d: expected to run, got %v", i+1, err)
+		}
+	}
+	err = op.Do(ctx, reindex)
+	if !IsRateLimitExceeded(err) || runs != 2 {
+		t.Errorf("Expected the third call to be denied without running, got %v after %d runs", err, runs)
+	}
+	if retryAfter, ok := GetRetryAfter(err); !ok || retryAfter <= 0 {
+		t.Errorf("Expected the denial to say when to retry, got %v", err)
+	}
+
+	// Other entities have their own budget, and errors of fn are returned as they are
+	failure := errors.New("index unavailable")
+	err = op.For("tenant:acme").Do(ctx, func(context.Context) error { return failure })
+	if !errors.Is(err, failure) {
+		t.Errorf("Expected the function's error, got %v", err)
+	}
+
+	stats, err := limiter.Stats(ctx)
+	if err != nil {
+		t.Fatalf("Stats failed: %v", err)
+	}
+	if scope := stats.ByScope["reindex"]; scope == nil || scope.Requests != 4 || scope.Denied != 1 {
+		t.Errorf("Expected operations in the scope stats, got %+v", scope)
+	}
+}
+
+func TestOperationWait(t *testing.T) {
+	limiter, err := New().Limit("sync", "1/100ms").Build()
+	if err != nil {
+		t.Fatalf("Failed to build limiter: %v", err)
+	}
+	defer limiter.Close()
+	ctx := context.Background()
+
+	op := Op(limiter, "sync").Wait(time.Second)
+	start := time.Now()
+	for i := 0; i < 2; i++ {
+		if _, err := op.Acquire(ctx); err != nil {
+			t.Fatalf("Call %d: expected to wait for its turn, got %v", i+1, err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("Expected the second call to wait, took %v", elapsed)
+	}
+
+	// Waits longer than allowed fail at once
+	if _, err := Op(limiter, "sync").Wait(time.Millisecond).Acquire(ctx); !IsRateLimitExceeded(err) {
+		t.Errorf("Expected a denial when the wait is too short, got %v", err)
+	}
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := Op(limiter, "sync").Wait(0).Do(canceled, func(context.Context) error { return nil }); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected the context error, got %v", err)
+	}
+}
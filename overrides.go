@@ -0,0 +1,249 @@
+// overrides.go - Per-entity limit overrides, their CSV/JSON form and admin handler
+package ratelimit
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/itsatony/gorly/internal/core"
+)
+
+// overridesCSVHeader is the header row of override CSV files
+var overridesCSVHeader = []string{"entity", "scope", "limit", "expiry"}
+
+// maxOverridesBody bounds the override list accepted by OverridesHandler
+const maxOverridesBody = 16 << 20
+
+// EntityOverride sets the limit of one entity in one scope, ahead of its tier and scope
+// limits, e.g. a partner's negotiated quota
+type EntityOverride struct {
+	Entity    string    `yaml:"entity" json:"entity"`
+	Scope     string    `yaml:"scope" json:"scope"`
+	Limit     string    `yaml:"limit" json:"limit"`
+	ExpiresAt time.Time `yaml:"expires_at,omitempty" json:"expires_at,omitempty"` // Zero never expires
+}
+
+// overrideManager is implemented by limiters whose entity overrides can be read and replaced
+type overrideManager interface {
+	overrides() []EntityOverride
+	setOverrides(overrides []EntityOverride) error
+}
+
+// Overrides returns the entity overrides of a limiter, or an error if it does not support them
+func Overrides(limiter Limiter) ([]EntityOverride, error) {
+	manager, ok := limiter.(overrideManager)
+	if !ok {
+		return nil, fmt.Errorf("limiter %T does not support entity overrides", limiter)
+	}
+	return manager.overrides(), nil
+}
+
+// SetOverrides replaces every entity override of a limiter at runtime
+// Example: ratelimit.SetOverrides(limiter, []ratelimit.EntityOverride{{Entity: "partner:acme", Scope: "global", Limit: "50000/hour"}})
+func SetOverrides(limiter Limiter, overrides []EntityOverride) error {
+	manager, ok := limiter.(overrideManager)
+	if !ok {
+		return fmt.Errorf("limiter %T does not support entity overrides", limiter)
+	}
+	return manager.setOverrides(overrides)
+}
+
+// coreOverrides converts overrides to their core form; nil stays nil
+func coreOverrides(overrides []EntityOverride) []core.Override {
+	if overrides == nil {
+		return nil
+	}
+	converted := make([]core.Override, len(overrides))
+	for i, o := range overrides {
+		converted[i] = core.Override{Entity: o.Entity, Scope: o.Scope, Limit: o.Limit, ExpiresAt: o.ExpiresAt}
+	}
+	return converted
+}
+
+// overrides returns the entity overrides of the limiter
+func (l *limiterImpl) overrides() []EntityOverride {
+	configured := l.core.Overrides()
+	overrides := make([]EntityOverride, len(configured))
+	for i, o := range configured {
+		overrides[i] = EntityOverride{Entity: o.Entity, Scope: o.Scope, Limit: o.Limit, ExpiresAt: o.ExpiresAt}
+	}
+	return overrides
+}
+
+// setOverrides replaces the entity overrides of the limiter
+func (l *limiterImpl) setOverrides(overrides []EntityOverride) error {
+	if overrides == nil {
+		overrides = []EntityOverride{} // Replace with none rather than keep the current ones
+	}
+	return l.core.UpdateLimits(core.LimitUpdate{Overrides: coreOverrides(overrides)})
+}
+
+// overrides delegates reading entity overrides to the wrapped limiter
+func (ol *ObservableLimiter) overrides() []EntityOverride {
+	overrides, _ := Overrides(ol.limiter)
+	return overrides
+}
+
+// setOverrides delegates replacing entity overrides to the wrapped limiter
+func (ol *ObservableLimiter) setOverrides(overrides []EntityOverride) error {
+	return SetOverrides(ol.limiter, overrides)
+}
+
+// ReadOverridesCSV reads overrides from CSV with the columns entity, scope, limit and expiry.
+// A header row is optional; an empty expiry never expires, others are RFC 3339 timestamps.
+func ReadOverridesCSV(r io.Reader) ([]EntityOverride, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1 // The expiry column may be left out
+	reader.TrimLeadingSpace = true
+
+	overrides := []EntityOverride{}
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return overrides, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read overrides: %w", err)
+		}
+		if line == 1 && strings.EqualFold(strings.TrimSpace(record[0]), overridesCSVHeader[0]) {
+			continue
+		}
+		if len(record) < 3 || len(record) > 4 {
+			return nil, fmt.Errorf("line %d: expected entity, scope, limit and optional expiry, got %d columns", line, len(record))
+		}
+
+		o := EntityOverride{
+			Entity: strings.TrimSpace(record[0]),
+			Scope:  strings.TrimSpace(record[1]),
+			Limit:  strings.TrimSpace(record[2]),
+		}
+		if len(record) == 4 && strings.TrimSpace(record[3]) != "" {
+			if o.ExpiresAt, err = time.Parse(time.RFC3339, strings.TrimSpace(record[3])); err != nil {
+				return nil, fmt.Errorf("line %d: invalid expiry %q, expected RFC 3339 like 2025-06-30T00:00:00Z", line, record[3])
+			}
+		}
+		overrides = append(overrides, o)
+	}
+}
+
+// WriteOverridesCSV writes overrides as CSV with a header row
+func WriteOverridesCSV(w io.Writer, overrides []EntityOverride) error {
+	writer := csv.NewWriter(w)
+	writer.Write(overridesCSVHeader)
+	for _, o := range overrides {
+		expiry := ""
+		if !o.ExpiresAt.IsZero() {
+			expiry = o.ExpiresAt.UTC().Format(time.RFC3339)
+		}
+		writer.Write([]string{o.Entity, o.Scope, o.Limit, expiry})
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// ReadOverridesJSON reads overrides from a JSON array of EntityOverride
+func ReadOverridesJSON(r io.Reader) ([]EntityOverride, error) {
+	overrides := []EntityOverride{}
+	if err := json.NewDecoder(r).Decode(&overrides); err != nil {
+		return nil, fmt.Errorf("failed to read overrides: %w", err)
+	}
+	return overrides, nil
+}
+
+// WriteOverridesJSON writes overrides as an indented JSON array
+func WriteOverridesJSON(w io.Writer, overrides []EntityOverride) error {
+	if overrides == nil {
+		overrides = []EntityOverride{}
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(overrides)
+}
+
+// OverridesHandler creates an admin handler that lists (GET) or replaces (PUT/POST) the entity
+// overrides of a limiter. Bodies and responses are JSON, or CSV with Content-Type or Accept text/csv.
+// Listed overrides carry their expiry; ?expiring_within=72h lists only those expiring in that time.
+// The handler performs no authentication; mount it behind your admin auth or ProtectAdmin.
+// Example: adminMux.Handle("/admin/overrides", ratelimit.OverridesHandler(limiter))
+func OverridesHandler(limiter Limiter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var within time.Duration
+		if value := r.URL.Query().Get("expiring_within"); value != "" {
+			var err error
+			if within, err = time.ParseDuration(value); err != nil || within <= 0 {
+				http.Error(w, "expiring_within must be a positive duration like 72h", http.StatusBadRequest)
+				return
+			}
+		}
+
+		switch r.Method {
+		case http.MethodGet, http.MethodHead:
+		case http.MethodPut, http.MethodPost:
+			overrides, err := readOverridesBody(r)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if err := SetOverrides(limiter, overrides); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		default:
+			w.Header().Set("Allow", "GET, HEAD, PUT, POST")
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		overrides, err := Overrides(limiter)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotImplemented)
+			return
+		}
+		if within > 0 {
+			overrides = expiringOverrides(overrides, time.Now().Add(within))
+		}
+
+		w.Header().Set("Cache-Control", "no-store")
+		if strings.Contains(r.Header.Get("Accept"), "text/csv") {
+			w.Header().Set("Content-Type", "text/csv")
+			w.WriteHeader(http.StatusOK)
+			WriteOverridesCSV(w, overrides)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		WriteOverridesJSON(w, overrides)
+	}
+}
+
+// expiringOverrides returns the overrides that expire before deadline
+func expiringOverrides(overrides []EntityOverride, deadline time.Time) []EntityOverride {
+	expiring := []EntityOverride{}
+	for _, o := range overrides {
+		if !o.ExpiresAt.IsZero() && o.ExpiresAt.Before(deadline) {
+			expiring = append(expiring, o)
+		}
+	}
+	return expiring
+}
+
+// readOverridesBody reads an override list in the format of the request's Content-Type
+func readOverridesBody(r *http.Request) ([]EntityOverride, error) {
+	body := io.LimitReader(r.Body, maxOverridesBody)
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case "text/csv":
+		return ReadOverridesCSV(body)
+	case "", "application/json":
+		return ReadOverridesJSON(body)
+	default:
+		return nil, errors.New("overrides must be sent as application/json or text/csv")
+	}
+}
@@ -0,0 +1,167 @@
+// overrides_test.go
+package ratelimit
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOverridesCSV(t *testing.T) {
+	expiry := time.Date(2030, 6, 30, 0, 0, 0, 0, time.UTC)
+	overrides := []EntityOverride{
+		{Entity: "partner:acme", Scope: "global", Limit: "50000/hour"},
+		{Entity: "partner:globex", Scope: "search", Limit: "600/minute", ExpiresAt: expiry},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteOverridesCSV(&buf, overrides); err != nil {
+		t.Fatalf("WriteOverridesCSV failed: %v", err)
+	}
+	want := "entity,scope,limit,expiry\npartner:acme,global,50000/hour,\npartner:globex,search,600/minute,2030-06-30T00:00:00Z\n"
+	if buf.String() != want {
+		t.Errorf("Unexpected CSV:\n%s", buf.String())
+	}
+
+	read, err := ReadOverridesCSV(&buf)
+	if err != nil {
+		t.Fatalf("ReadOverridesCSV failed: %v", err)
+	}
+	if len(read) != 2 || read[0] != overrides[0] || !read[1].ExpiresAt.Equal(expiry) {
+		t.Errorf("Expected the overrides to round-trip, got %+v", read)
+	}
+
+	// Spreadsheet exports may lack the header and the expiry column
+	read, err = ReadOverridesCSV(strings.NewReader("user-1, global, 10/minute\n"))
+	if err != nil || len(read) != 1 || read[0].Scope != "global" {
+		t.Errorf("Expected one override without header, got %+v (%v)", read, err)
+	}
+	if _, err := ReadOverridesCSV(strings.NewReader("user-1,global,10/minute,next week\n")); err == nil {
+		t.Error("Expected an invalid expiry to be rejected")
+	}
+}
+
+func TestOverridesHandler(t *testing.T) {
+	limiter, err := New().
+		Limit("global", "10/minute").
+		Override("partner:acme", "global", "1000/minute").
+		Build()
+	if err != nil {
+		t.Fatalf("Failed to build limiter: %v", err)
+	}
+	defer limiter.Close()
+	handler := OverridesHandler(limiter)
+
+	result, err := limiter.Check(context.Background(), "partner:acme", "global")
+	if err != nil || result.Limit != 1000 {
+		t.Fatalf("Expected the override limit of 1000, got %+v (%v)", result, err)
+	}
+
+	// Replace the overrides with a CSV upload
+	req := httptest.NewRequest(http.MethodPut, "/admin/overrides", strings.NewReader("entity,scope,limit,expiry\npartner:globex,global,500/minute,\n"))
+	req.Header.Set("Content-Type", "text/csv")
+	w := httptest.NewRecorder()
+	handler(w, req)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "partner:globex") || strings.Contains(w.Body.String(), "partner:acme") {
+		t.Fatalf("Expected the uploaded overrides, got %d: %s", w.Code, w.Body.String())
+	}
+
+	limits, _ := limiter.Limits("partner:globex")
+	if len(limits) != 1 || limits[0].Requests != 500 || limits[0].Source != "override" {
+		t.Errorf("Expected the override to apply, got %+v", limits)
+	}
+
+	// Invalid lists are rejected as a whole
+	req = httptest.NewRequest(http.MethodPut, "/admin/overrides", strings.NewReader(`[{"entity":"a","scope":"global","limit":"lots"}]`))
+	w = httptest.NewRecorder()
+	handler(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an invalid limit, got %d", w.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/admin/overrides", nil)
+	req.Header.Set("Accept", "text/csv")
+	w = httptest.NewRecorder()
+	handler(w, req)
+	if w.Body.String() != "entity,scope,limit,expiry\npartner:globex,global,500/minute,\n" {
+		t.Errorf("Unexpected CSV export: %s", w.Body.String())
+	}
+}
+
+func TestHotReloadOverrides(t *testing.T) {
+	limiter, err := New().Limit("global", "10/minute").Build()
+	if err != nil {
+		t.Fatalf("Failed to build limiter: %v", err)
+	}
+	defer limiter.Close()
+
+	manager := NewHotReloadManager(limiter, nil)
+	err = manager.applyConfig(context.Background(), &HotReloadConfig{
+		Overrides: []EntityOverride{{Entity: "partner:acme", Scope: "global", Limit: "100/minute"}},
+	})
+	if err != nil {
+		t.Fatalf("applyConfig failed: %v", err)
+	}
+	if overrides, _ := Overrides(limiter); len(overrides) != 1 {
+		t.Errorf("Expected the reloaded override, got %+v", overrides)
+	}
+
+	err = manager.applyConfig(context.Background(), &HotReloadConfig{
+		Overrides: []EntityOverride{{Entity: "partner:acme", Limit: "100/minute"}},
+	})
+	if err == nil {
+		t.Error("Expected an override without scope to be rejected")
+	}
+}
+
+func TestOverrideExpiry(t *testing.T) {
+	expired := make(chan EntityOverride, 1)
+	limiter, err := New().
+		Limit("global", "10/minute").
+		Override("partner:acme", "global", "1000/minute").
+		OverrideUntil("partner:globex", "global", "5000/minute", time.Now().Add(time.Hour)).
+		OverrideUntil("partner:initech", "global", "5000/minute", time.Now().Add(50*time.Millisecond)).
+		OnOverrideExpired(func(o EntityOverride) { expired <- o }).
+		Build()
+	if err != nil {
+		t.Fatalf("Failed to build limiter: %v", err)
+	}
+	defer limiter.Close()
+
+	// The admin API lists the overrides expiring soon
+	handler := OverridesHandler(limiter)
+	req := httptest.NewRequest(http.MethodGet, "/admin/overrides?expiring_within=2h", nil)
+	w := httptest.NewRecorder()
+	handler(w, req)
+	body := w.Body.String()
+	if w.Code != http.StatusOK || !strings.Contains(body, "partner:globex") || !strings.Contains(body, "expires_at") || strings.Contains(body, "partner:acme") {
+		t.Errorf("Expected only expiring overrides, got %d: %s", w.Code, body)
+	}
+	req = httptest.NewRequest(http.MethodGet, "/admin/overrides?expiring_within=soon", nil)
+	w = httptest.NewRecorder()
+	handler(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an invalid duration, got %d", w.Code)
+	}
+
+	select {
+	case o := <-expired:
+		if o.Entity != "partner:initech" || o.Limit != "5000/minute" {
+			t.Errorf("Expected an expiry event for partner:initech, got %+v", o)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected partner:initech to expire")
+	}
+
+	if overrides, _ := Overrides(limiter); len(overrides) != 2 {
+		t.Errorf("Expected the expired override to be removed, got %+v", overrides)
+	}
+	stats, err := limiter.Stats(context.Background())
+	if err != nil || stats.ExpiredOverrides != 1 {
+		t.Errorf("Expected 1 expired override in stats, got %+v (%v)", stats, err)
+	}
+}
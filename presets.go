@@ -2,8 +2,16 @@
 package ratelimit
 
 import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"math"
+	"math/rand"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
+	"time"
 )
 
 // APIGateway creates a rate limiter optimized for API gateway scenarios
@@ -90,6 +98,70 @@ func WebApp() *Builder {
 		})
 }
 
+// AuthProtection creates a rate limiter for login, OTP and password reset endpoints
+// Features: per-IP pre-auth limit, per-username limits and exponential lockout.
+// Report outcomes with ReportAuthFailure and ReportAuthSuccess, and add a CAPTCHA hook with Challenge.
+func AuthProtection() *Builder {
+	return New().
+		PreAuthLimit("30/minute"). // Per IP, stops stuffing from a single source
+		ExtractorFunc(extractLoginIdentity).
+		ScopeFunc(extractAuthScope).
+		Limits(map[string]string{
+			"global":         "100/hour", // Fallback for direct checks
+			"login":          "10/15m",   // Per username
+			"otp":            "5/15m",    // One-time codes are short and easy to guess
+			"password_reset": "3/hour",   // Reset emails
+		}).
+		Lockout(5, time.Minute, time.Hour).
+		EnableMetrics()
+}
+
+// MobileBackend creates a rate limiter for mobile app backends
+// Features: device-ID based limiting with IP fallback. Devices get strict limits while
+// IP fallbacks get generous ones, since carrier-grade NAT puts many phones behind one IP.
+// Denials carry jittered Retry-After values and backoff hints for mobile SDKs.
+func MobileBackend() *Builder {
+	return New().
+		ExtractorFunc(extractDeviceOrIP).
+		ScopeFunc(extractMobileScope).
+		Limit("global", "1000/hour"). // Entities from custom extractors
+		TierLimits(map[string]string{
+			"device": "1000/hour",
+			"ip":     "20000/hour",
+		}).
+		ScopeTierLimits("sync", map[string]string{
+			"device": "120/hour", // Background sync every 30s at most
+			"ip":     "2400/hour",
+		}).
+		ScopeTierLimits("push", map[string]string{
+			"device": "10/hour", // Push token registration
+			"ip":     "200/hour",
+		}).
+		ScopeTierLimits("feed", map[string]string{
+			"device": "300/hour",
+			"ip":     "6000/hour",
+		}).
+		OnDenied(mobileDeniedResponse).
+		EnableMetrics()
+}
+
+// AIGateway creates a rate limiter for gateways proxying LLM providers
+// Features: API key-based request limits, monthly token budgets charged with ReportTokens,
+// short windows that smooth bursts on streaming endpoints, and token budgets in denials.
+func AIGateway() *Builder {
+	return New().
+		ExtractorFunc(extractAPIKeyOrIP).
+		ScopeFunc(extractAIScope).
+		Limits(map[string]string{
+			"global":     "600/minute",  // General API access
+			"completion": "120/minute",  // Chat and text completions
+			"stream":     "10/10s",      // Short window keeps streaming bursts smooth
+			"embedding":  "3000/minute", // Cheap, high volume
+		}).
+		TokenBudget("global", "10000000/month"). // Per API key, shared by all scopes
+		EnableMetrics()
+}
+
 // =============================================================================
 // Preset-specific extractors and scope functions
 // =============================================================================
@@ -187,6 +259,161 @@ func extractWebScope(r *http.Request) string {
 	return "global"
 }
 
+// extractAuthScope extracts scope for authentication endpoints
+func extractAuthScope(r *http.Request) string {
+	path := strings.ToLower(r.URL.Path)
+
+	if strings.Contains(path, "/otp") || strings.Contains(path, "/2fa") || strings.Contains(path, "/mfa") || strings.Contains(path, "/verify") {
+		return "otp"
+	}
+
+	if strings.Contains(path, "/reset") || strings.Contains(path, "/forgot") {
+		return "password_reset"
+	}
+
+	return "login"
+}
+
+// extractLoginIdentity extracts the username being authenticated so limits follow the
+// targeted account rather than the client. JSON APIs should set X-Login-Username or
+// supply their own extractor since JSON bodies are not parsed.
+func extractLoginIdentity(r *http.Request) string {
+	username := r.Header.Get("X-Login-Username")
+	if username == "" && strings.HasPrefix(r.Header.Get("Content-Type"), "application/x-www-form-urlencoded") {
+		username = loginFormUsername(r)
+	}
+
+	username = strings.ToLower(strings.TrimSpace(username))
+	if username == "" {
+		return "ip:" + extractIP(r)
+	}
+	return "login:" + username
+}
+
+// maxLoginFormBytes caps how much of a login form is read to find the username, so the
+// limiter does not buffer the large bodies an attacker may send to the endpoints it protects
+const maxLoginFormBytes = 4 << 10
+
+// loginBody is a login form whose head was read to find the username. It replays the head
+// to the handler, and keeps the username for later extractions such as ReportAuthFailure,
+// which may run after the handler consumed the body.
+type loginBody struct {
+	io.Reader
+	io.Closer
+	username string
+}
+
+// loginFormUsername returns the username or email field of a URL-encoded login form. Only
+// its first maxLoginFormBytes are read, and the body is restored for the handler.
+func loginFormUsername(r *http.Request) string {
+	if body, ok := r.Body.(*loginBody); ok {
+		return body.username
+	}
+	if r.Body == nil || r.Body == http.NoBody {
+		return ""
+	}
+
+	head, err := io.ReadAll(io.LimitReader(r.Body, maxLoginFormBytes))
+	body := &loginBody{Reader: io.MultiReader(bytes.NewReader(head), r.Body), Closer: r.Body}
+	r.Body = body
+	if err != nil {
+		return ""
+	}
+	if len(head) == maxLoginFormBytes {
+		// The last field may be cut off
+		if i := bytes.LastIndexByte(head, '&'); i >= 0 {
+			head = head[:i]
+		}
+	}
+
+	values, _ := url.ParseQuery(string(head))
+	body.username = values.Get("username")
+	if body.username == "" {
+		body.username = values.Get("email")
+	}
+	return body.username
+}
+
+// extractAIScope extracts scope for AI gateway scenarios
+func extractAIScope(r *http.Request) string {
+	path := strings.ToLower(r.URL.Path)
+
+	if strings.Contains(path, "/embeddings") {
+		return "embedding"
+	}
+
+	if strings.Contains(path, "/completions") || strings.Contains(path, "/messages") || strings.Contains(path, "/generate") {
+		// Streaming requests hold connections open and arrive in bursts
+		if r.URL.Query().Get("stream") == "true" || strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+			return "stream"
+		}
+		return "completion"
+	}
+
+	return "global"
+}
+
+// extractMobileScope extracts scope for mobile backend scenarios
+func extractMobileScope(r *http.Request) string {
+	path := strings.ToLower(r.URL.Path)
+
+	if strings.Contains(path, "/sync") {
+		return "sync"
+	}
+
+	if strings.Contains(path, "/push") || strings.Contains(path, "/devices/token") {
+		return "push"
+	}
+
+	if strings.Contains(path, "/feed") || strings.Contains(path, "/timeline") {
+		return "feed"
+	}
+
+	return "global"
+}
+
+// maxDeviceIDLength bounds device identifiers so abusive headers can't bloat keys
+const maxDeviceIDLength = 128
+
+// extractDeviceOrIP extracts the device identifier or falls back to IP
+func extractDeviceOrIP(r *http.Request) string {
+	for _, header := range []string{"X-Device-ID", "X-Install-ID"} {
+		if deviceID := strings.TrimSpace(r.Header.Get(header)); deviceID != "" && len(deviceID) <= maxDeviceIDLength {
+			return "device:" + deviceID
+		}
+	}
+
+	// Fall back to IP, which may be shared by many devices
+	return "ip:" + extractIP(r)
+}
+
+// mobileDeniedResponse writes a denial with backoff guidance for mobile SDKs.
+// Retry-After gets up to 20% jitter so devices denied together don't retry in lockstep.
+func mobileDeniedResponse(w http.ResponseWriter, r *http.Request, result *LimitResult) {
+	retryAfter := result.RetryAfter
+	if retryAfter < time.Second {
+		retryAfter = time.Second
+	}
+	retryAfter += time.Duration(rand.Int63n(int64(retryAfter)/5 + 1))
+	seconds := int64(math.Ceil(retryAfter.Seconds()))
+
+	w.Header().Set("Retry-After", strconv.FormatInt(seconds, 10))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusTooManyRequests)
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error":               "Rate limit exceeded",
+		"retry_after_seconds": seconds,
+		"retry_at":            time.Now().Add(time.Duration(seconds) * time.Second).Unix(),
+		"backoff": map[string]interface{}{
+			"strategy":     "exponential",
+			"base_seconds": seconds,
+			"max_seconds":  int64(result.Window.Seconds()),
+			"jitter":       true,
+		},
+	})
+}
+
 // extractUserWithTier extracts user ID and includes tier information
 func extractUserWithTier(r *http.Request) string {
 	// Try to get user ID from header
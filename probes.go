@@ -0,0 +1,61 @@
+// probes.go - Rate limiting bypass for health probe traffic
+package ratelimit
+
+import (
+	"slices"
+
+	"github.com/itsatony/gorly/internal/core"
+)
+
+// DefaultProbeUserAgent is the User-Agent prefix of Kubernetes probes, e.g. "kube-probe/1.29"
+const DefaultProbeUserAgent = core.DefaultProbeUserAgent
+
+// ProbeConfig describes the health probe traffic the middleware lets through without a
+// check. A request is a probe when its User-Agent starts with one of UserAgents or it
+// carries Header; Networks and Paths, when set, must match as well.
+//
+// User agents and headers are easily forged, so anyone can pose as a probe. Restrict the
+// bypass with Networks (the node or pod CIDR kubelet probes from) or Paths (the health
+// endpoints) wherever clients can reach the service directly.
+type ProbeConfig struct {
+	UserAgents  []string `yaml:"user_agents,omitempty" json:"user_agents,omitempty"`   // User-Agent prefixes (default: DefaultProbeUserAgent; empty: none)
+	Header      string   `yaml:"header,omitempty" json:"header,omitempty"`             // Header marking probes, e.g. "X-Health-Probe"
+	HeaderValue string   `yaml:"header_value,omitempty" json:"header_value,omitempty"` // Value Header must have; "" accepts any
+	Networks    []string `yaml:"networks,omitempty" json:"networks,omitempty"`         // Addresses or CIDRs probes must come from
+	Paths       []string `yaml:"paths,omitempty" json:"paths,omitempty"`               // Paths probes must request, e.g. "/healthz"
+}
+
+// toCore converts the probe config for the core limiter
+func (pc ProbeConfig) toCore() *core.ProbeConfig {
+	return &core.ProbeConfig{
+		UserAgents:  slices.Clone(pc.UserAgents),
+		Header:      pc.Header,
+		HeaderValue: pc.HeaderValue,
+		Networks:    slices.Clone(pc.Networks),
+		Paths:       slices.Clone(pc.Paths),
+	}
+}
+
+// probeConfigFromCore converts a core probe config back, nil if probes are not exempt
+func probeConfigFromCore(pc *core.ProbeConfig) *ProbeConfig {
+	if pc == nil {
+		return nil
+	}
+	return &ProbeConfig{
+		UserAgents:  slices.Clone(pc.UserAgents),
+		Header:      pc.Header,
+		HeaderValue: pc.HeaderValue,
+		Networks:    slices.Clone(pc.Networks),
+		Paths:       slices.Clone(pc.Paths),
+	}
+}
+
+// probeCounter is implemented by limiters that count bypassed probe requests
+type probeCounter interface {
+	probeBypasses() int64
+}
+
+// probeBypasses returns how many probe requests the middleware let through without a check
+func (l *limiterImpl) probeBypasses() int64 {
+	return l.core.Probes()
+}
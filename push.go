@@ -0,0 +1,172 @@
+// push.go - Push-based metric export to a Prometheus Pushgateway
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultPushTimeout bounds a single push to the Pushgateway
+const DefaultPushTimeout = 10 * time.Second
+
+// PushConfig configures pushing metrics to a Prometheus Pushgateway.
+// Short-lived processes such as batch jobs exit before Prometheus scrapes them;
+// pushing on Close (and optionally on an interval) keeps their metrics.
+type PushConfig struct {
+	// URL of the Pushgateway, e.g. "http://pushgateway:9091"
+	URL string
+
+	// Job name the metrics are grouped under (required)
+	Job string
+
+	// Grouping adds labels to the grouping key, e.g. {"instance": "worker-3"}
+	Grouping map[string]string
+
+	// Interval between pushes; zero pushes only on Close
+	Interval time.Duration
+
+	// Timeout of a single push (default: 10s)
+	Timeout time.Duration
+
+	// Client sends the push requests (default: the client set with SetHTTPClient)
+	Client *http.Client
+}
+
+// validate checks the push configuration
+func (pc *PushConfig) validate() error {
+	if pc.URL == "" {
+		return errors.New("push URL is required")
+	}
+	if pc.Job == "" {
+		return errors.New("push job name is required")
+	}
+	if pc.Interval < 0 || pc.Timeout < 0 {
+		return errors.New("push interval and timeout cannot be negative")
+	}
+	return nil
+}
+
+// endpoint returns the Pushgateway URL of the configured grouping key
+func (pc *PushConfig) endpoint() string {
+	var b strings.Builder
+	b.WriteString(strings.TrimRight(pc.URL, "/"))
+	b.WriteString("/metrics/job/")
+	b.WriteString(url.PathEscape(pc.Job))
+
+	names := make([]string, 0, len(pc.Grouping))
+	for name := range pc.Grouping {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		b.WriteString("/")
+		b.WriteString(url.PathEscape(name))
+		b.WriteString("/")
+		b.WriteString(url.PathEscape(pc.Grouping[name]))
+	}
+	return b.String()
+}
+
+// metricsPusher periodically pushes the metrics of an ObservableLimiter
+type metricsPusher struct {
+	config  *PushConfig
+	limiter *ObservableLimiter
+
+	stop      chan struct{}
+	done      sync.WaitGroup
+	closeOnce sync.Once
+}
+
+// newMetricsPusher starts pushing metrics on the configured interval
+func newMetricsPusher(config *PushConfig, limiter *ObservableLimiter) *metricsPusher {
+	mp := &metricsPusher{
+		config:  config,
+		limiter: limiter,
+		stop:    make(chan struct{}),
+	}
+	if config.Interval > 0 && config.validate() == nil {
+		mp.done.Add(1)
+		go mp.run()
+	}
+	return mp
+}
+
+// run pushes on every interval until stopped
+func (mp *metricsPusher) run() {
+	defer mp.done.Done()
+
+	ticker := time.NewTicker(mp.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-mp.stop:
+			return
+		case <-ticker.C:
+			if err := mp.push(context.Background()); err != nil && mp.limiter.config.EnableLogging {
+				mp.limiter.config.Logger.Warn("Metrics push failed", Field{"error", err.Error()})
+			}
+		}
+	}
+}
+
+// close stops periodic pushing and pushes the final metrics
+func (mp *metricsPusher) close() error {
+	var err error
+	mp.closeOnce.Do(func() {
+		close(mp.stop)
+		mp.done.Wait()
+		err = mp.push(context.Background())
+	})
+	return err
+}
+
+// push replaces the metrics of the grouping key with the current metrics
+func (mp *metricsPusher) push(ctx context.Context) error {
+	if err := mp.config.validate(); err != nil {
+		return err
+	}
+
+	timeout := mp.config.Timeout
+	if timeout == 0 {
+		timeout = DefaultPushTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	options := prometheusOptions{legacy: mp.limiter.config.LegacyMetricNames}
+	body := convertToPrometheusFormat(mp.limiter.GetMetrics(), options)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, mp.config.endpoint(), strings.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create push request: %w", err)
+	}
+	req.Header.Set("Content-Type", prometheusTextContentType)
+
+	resp, err := httpClient(mp.config.Client).Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to push metrics: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("pushgateway returned %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// PushMetrics pushes the current metrics to the configured Pushgateway immediately
+func (ol *ObservableLimiter) PushMetrics(ctx context.Context) error {
+	if ol.pusher == nil {
+		return errors.New("metrics push is not configured")
+	}
+	return ol.pusher.push(ctx)
+}
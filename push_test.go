@@ -0,0 +1,138 @@
+// push_test.go
+package ratelimit
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// pushRecorder is a fake Pushgateway recording every push
+type pushRecorder struct {
+	mu     sync.Mutex
+	pushes []recordedPush
+	status int
+}
+
+type recordedPush struct {
+	method, path, contentType, body string
+}
+
+func (pr *pushRecorder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	pr.mu.Lock()
+	pr.pushes = append(pr.pushes, recordedPush{r.Method, r.URL.EscapedPath(), r.Header.Get("Content-Type"), string(body)})
+	status := pr.status
+	pr.mu.Unlock()
+	if status != 0 {
+		http.Error(w, "push rejected", status)
+	}
+}
+
+func (pr *pushRecorder) recorded() []recordedPush {
+	pr.mu.Lock()
+	defer pr.mu.Unlock()
+	return append([]recordedPush(nil), pr.pushes...)
+}
+
+func newPushTestLimiter(t *testing.T, push *PushConfig) *ObservableLimiter {
+	t.Helper()
+
+	base, err := New().Limit("global", "10/minute").Build()
+	if err != nil {
+		t.Fatalf("Failed to build limiter: %v", err)
+	}
+	config := DefaultObservabilityConfig()
+	config.EnableLogging = false
+	config.Push = push
+	return NewObservableLimiter(base, config)
+}
+
+func TestPushMetricsOnClose(t *testing.T) {
+	gateway := &pushRecorder{}
+	server := httptest.NewServer(gateway)
+	defer server.Close()
+
+	limiter := newPushTestLimiter(t, &PushConfig{
+		URL:      server.URL + "/",
+		Job:      "nightly import",
+		Grouping: map[string]string{"instance": "worker-3", "env": "prod"},
+	})
+	limiter.Check(context.Background(), "user-1")
+
+	if pushes := gateway.recorded(); len(pushes) != 0 {
+		t.Fatalf("Expected no push before Close without an interval, got %d", len(pushes))
+	}
+	if err := limiter.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	pushes := gateway.recorded()
+	if len(pushes) != 1 {
+		t.Fatalf("Expected exactly one push on Close, got %d", len(pushes))
+	}
+	push := pushes[0]
+	if push.method != http.MethodPut {
+		t.Errorf("Expected PUT, got %s", push.method)
+	}
+	if push.path != "/metrics/job/nightly%20import/env/prod/instance/worker-3" {
+		t.Errorf("Unexpected grouping path %s", push.path)
+	}
+	if !strings.HasPrefix(push.contentType, "text/plain; version=0.0.4") {
+		t.Errorf("Unexpected content type %s", push.contentType)
+	}
+	if !strings.Contains(push.body, `gorly_requests_total{entity="user-1",scope="global"} 1`) {
+		t.Errorf("Expected request counter in pushed metrics, got:\n%s", push.body)
+	}
+
+	// Closing again does not push twice
+	limiter.Close()
+	if len(gateway.recorded()) != 1 {
+		t.Error("Expected a second Close not to push again")
+	}
+}
+
+func TestPushMetricsInterval(t *testing.T) {
+	gateway := &pushRecorder{}
+	server := httptest.NewServer(gateway)
+	defer server.Close()
+
+	limiter := newPushTestLimiter(t, &PushConfig{URL: server.URL, Job: "worker", Interval: 10 * time.Millisecond})
+	time.Sleep(55 * time.Millisecond)
+	limiter.Close()
+
+	if pushes := gateway.recorded(); len(pushes) < 3 {
+		t.Errorf("Expected periodic pushes plus a final one, got %d", len(pushes))
+	}
+}
+
+func TestPushMetricsErrors(t *testing.T) {
+	gateway := &pushRecorder{status: http.StatusBadRequest}
+	server := httptest.NewServer(gateway)
+	defer server.Close()
+
+	limiter := newPushTestLimiter(t, &PushConfig{URL: server.URL, Job: "worker"})
+	if err := limiter.PushMetrics(context.Background()); err == nil || !strings.Contains(err.Error(), "400") {
+		t.Errorf("Expected the gateway status in the push error, got %v", err)
+	}
+	if err := limiter.Close(); err == nil {
+		t.Error("Expected Close to report the failed final push")
+	}
+
+	invalid := newPushTestLimiter(t, &PushConfig{URL: server.URL})
+	if err := invalid.PushMetrics(context.Background()); err == nil {
+		t.Error("Expected error for a push config without job name")
+	}
+	invalid.Close()
+
+	unconfigured := newPushTestLimiter(t, nil)
+	defer unconfigured.Close()
+	if err := unconfigured.PushMetrics(context.Background()); err == nil {
+		t.Error("Expected error when push is not configured")
+	}
+}
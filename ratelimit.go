@@ -3,7 +3,10 @@ package ratelimit
 
 import (
 	"context"
+	"strings"
 	"time"
+
+	"github.com/itsatony/gorly/internal/core"
 )
 
 // AuthEntity represents a flexible authentication entity for rate limiting
@@ -77,6 +80,11 @@ type Result struct {
 	Algorithm string `json:"algorithm"`
 }
 
+// Rate returns the limit the request was checked against
+func (r *Result) Rate() Limit {
+	return Limit{Requests: r.Limit, Window: r.Window}
+}
+
 // Stats represents usage statistics for an entity
 type Stats struct {
 	// Entity information
@@ -203,30 +211,56 @@ const (
 	ScopeAdmin     = "admin"
 )
 
-// KeyBuilder helps build consistent keys for rate limiting
+// KeyBuilder helps build consistent keys for rate limiting.
+// Entity and scope values are escaped with EscapeKeyPart so crafted values cannot
+// produce another entity's key, and keys longer than the maximum length are hashed.
 type KeyBuilder struct {
-	prefix string
+	prefix    string
+	maxLength int
 }
 
 // NewKeyBuilder creates a new KeyBuilder with the given prefix
 func NewKeyBuilder(prefix string) *KeyBuilder {
-	return &KeyBuilder{prefix: prefix}
+	return &KeyBuilder{prefix: prefix, maxLength: core.DefaultMaxKeyLength}
+}
+
+// WithMaxLength sets the longest key built before hashing
+func (kb *KeyBuilder) WithMaxLength(n int) *KeyBuilder {
+	kb.maxLength = n
+	return kb
 }
 
 // BuildKey builds a key for the given entity and scope
 func (kb *KeyBuilder) BuildKey(entity AuthEntity, scope string) string {
-	if kb.prefix == "" {
-		return entity.Type() + ":" + entity.ID() + ":" + scope
-	}
-	return kb.prefix + ":" + entity.Type() + ":" + entity.ID() + ":" + scope
+	return kb.build(entity.Type(), entity.ID(), scope)
 }
 
 // BuildStatsKey builds a key for statistics storage
 func (kb *KeyBuilder) BuildStatsKey(entity AuthEntity) string {
-	if kb.prefix == "" {
-		return "stats:" + entity.Type() + ":" + entity.ID()
+	return kb.build("stats", entity.Type(), entity.ID())
+}
+
+// build joins the escaped parts under the (trusted) prefix
+func (kb *KeyBuilder) build(parts ...string) string {
+	escaped := make([]string, len(parts))
+	for i, part := range parts {
+		escaped[i] = core.EscapeKeyPart(part)
+	}
+
+	key := strings.Join(escaped, ":")
+	if kb.prefix != "" {
+		key = kb.prefix + ":" + key
 	}
-	return kb.prefix + ":stats:" + entity.Type() + ":" + entity.ID()
+	if kb.maxLength > 0 && len(key) > kb.maxLength {
+		return core.HashKey(kb.prefix, key)
+	}
+	return key
+}
+
+// EscapeKeyPart percent-encodes '%', ':', '#', whitespace and control characters
+// so a value can be embedded in a store key without colliding with other keys
+func EscapeKeyPart(part string) string {
+	return core.EscapeKeyPart(part)
 }
 
 // BuildGlobalStatsKey builds a key for global statistics
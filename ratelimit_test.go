@@ -3,6 +3,7 @@ package ratelimit
 
 import (
 	"context"
+	"strings"
 	"testing"
 	"time"
 )
@@ -168,6 +169,25 @@ func TestKeyBuilder(t *testing.T) {
 	}
 }
 
+func TestKeyBuilderEscapesEntities(t *testing.T) {
+	kb := NewKeyBuilder("test:rl")
+
+	// Without escaping both entities would map to "test:rl:user:a:b:global"
+	first := kb.BuildKey(NewDefaultAuthEntity("a:b", EntityTypeUser, TierFree), ScopeGlobal)
+	second := kb.BuildKey(NewDefaultAuthEntity("a", EntityTypeUser, TierFree), "b:global")
+	if first == second {
+		t.Errorf("Expected distinct keys, both were %s", first)
+	}
+	if first != "test:rl:user:a%3Ab:global" {
+		t.Errorf("Unexpected escaped key %s", first)
+	}
+
+	long := NewDefaultAuthEntity(strings.Repeat("x", 1000), EntityTypeUser, TierFree)
+	if key := kb.WithMaxLength(128).BuildKey(long, ScopeGlobal); len(key) > 128 || !strings.HasPrefix(key, "test:rl:#") {
+		t.Errorf("Expected hashed key within 128 bytes, got %s", key)
+	}
+}
+
 func TestKeyBuilderStats(t *testing.T) {
 	kb := NewKeyBuilder("test:rl")
 	entity := NewDefaultAuthEntity("user123", EntityTypeUser, TierFree)
@@ -0,0 +1,539 @@
+// reports.go - Daily summaries of requests and denials delivered to pluggable reporters
+package ratelimit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Daily report defaults
+const (
+	DefaultReportTopEntities = 10
+	DefaultReportTopScopes   = 10
+	DefaultReportTrendDays   = 7
+	DefaultReporterTimeout   = 10 * time.Second
+)
+
+// reportJob is the leader job sending daily reports
+const reportJob = "daily-report"
+
+// reportCheckInterval is how often the leader checks whether a report is due
+const reportCheckInterval = time.Minute
+
+// reportDayLayout names the days of reports
+const reportDayLayout = "2006-01-02"
+
+// ReportCounts are the requests and denials of an entity, a scope or a day in a report
+type ReportCounts struct {
+	Name     string  `json:"name"`
+	Requests int64   `json:"requests"`
+	Denied   int64   `json:"denied"`
+	DenyRate float64 `json:"deny_rate"`
+}
+
+// DailyReport summarizes one day of rate limiting across the instances sharing the store
+type DailyReport struct {
+	Day   string    `json:"day"`   // Reported day in the report location, e.g. "2026-10-15"
+	Start time.Time `json:"start"` // When the counters of the previous report were taken
+	End   time.Time `json:"end"`
+
+	Requests int64   `json:"requests"`
+	Denied   int64   `json:"denied"`
+	DenyRate float64 `json:"deny_rate"`
+
+	TopDenied     []ReportCounts `json:"top_denied"`     // Entities with the most denials
+	BusiestScopes []ReportCounts `json:"busiest_scopes"` // Scopes with the most requests
+	Trend         []ReportCounts `json:"trend"`          // The last days by date, oldest first, ending with this one
+
+	// ConfigChanges counts limit updates during the day, as seen by the reporting instance
+	ConfigChanges int64  `json:"config_changes"`
+	ConfigVersion string `json:"config_version,omitempty"`
+}
+
+// Summary renders a report as plain text, as sent by the Slack and email reporters
+func (r *DailyReport) Summary() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Gorly daily report for %s\n", r.Day)
+	fmt.Fprintf(&b, "Requests: %d, denied: %d (%s)\n", r.Requests, r.Denied, formatRate(r.DenyRate))
+
+	if len(r.TopDenied) > 0 {
+		b.WriteString("\nTop denied entities:\n")
+		for _, entity := range r.TopDenied {
+			fmt.Fprintf(&b, "  %s: %d of %d denied (%s)\n", entity.Name, entity.Denied, entity.Requests, formatRate(entity.DenyRate))
+		}
+	}
+	if len(r.BusiestScopes) > 0 {
+		b.WriteString("\nBusiest scopes:\n")
+		for _, scope := range r.BusiestScopes {
+			fmt.Fprintf(&b, "  %s: %d requests, %d denied (%s)\n", scope.Name, scope.Requests, scope.Denied, formatRate(scope.DenyRate))
+		}
+	}
+	if len(r.Trend) > 1 {
+		b.WriteString("\nDeny rate trend:\n")
+		for _, day := range r.Trend {
+			fmt.Fprintf(&b, "  %s: %s of %d requests\n", day.Name, formatRate(day.DenyRate), day.Requests)
+		}
+	}
+
+	fmt.Fprintf(&b, "\nConfig changes: %d", r.ConfigChanges)
+	if r.ConfigVersion != "" {
+		fmt.Fprintf(&b, " (now %s)", r.ConfigVersion)
+	}
+	b.WriteString("\n")
+	return b.String()
+}
+
+// formatRate formats a deny rate as a percentage
+func formatRate(rate float64) string {
+	return fmt.Sprintf("%.2f%%", rate*100)
+}
+
+// Reporter delivers daily reports
+type Reporter interface {
+	Report(ctx context.Context, report *DailyReport) error
+}
+
+// ReporterFunc adapts a function to the Reporter interface
+type ReporterFunc func(ctx context.Context, report *DailyReport) error
+
+// Report implements Reporter
+func (f ReporterFunc) Report(ctx context.Context, report *DailyReport) error {
+	return f(ctx, report)
+}
+
+// ReportConfig configures daily reports
+type ReportConfig struct {
+	// Reporters receive every report; a failing reporter does not stop the others
+	Reporters []Reporter
+
+	// At is how long after midnight the previous day is reported (default: 0)
+	At time.Duration
+
+	// Location sets where days start (default: UTC)
+	Location *time.Location
+
+	// TopEntities bounds the denied entities listed (default: 10)
+	TopEntities int
+
+	// TopScopes bounds the scopes listed (default: 10)
+	TopScopes int
+
+	// TrendDays is how many days the deny rate trend covers (default: 7)
+	TrendDays int
+
+	// Timeout bounds the delivery by a single reporter (default: 10s)
+	Timeout time.Duration
+}
+
+// validate checks the report configuration
+func (rc *ReportConfig) validate() error {
+	if len(rc.Reporters) == 0 {
+		return errors.New("at least one reporter is required")
+	}
+	for i, reporter := range rc.Reporters {
+		if reporter == nil {
+			return fmt.Errorf("reporter %d is nil", i)
+		}
+	}
+	if rc.At < 0 || rc.At >= 24*time.Hour {
+		return fmt.Errorf("report time must be within the day, got %v", rc.At)
+	}
+	if rc.TopEntities < 0 || rc.TopScopes < 0 || rc.TrendDays < 0 || rc.Timeout < 0 {
+		return errors.New("report sizes and timeout cannot be negative")
+	}
+	return nil
+}
+
+// reportStateStore is implemented by limiters that keep the state of daily reports in their store
+type reportStateStore interface {
+	saveReportState(ctx context.Context, data []byte) error
+	loadReportState(ctx context.Context) ([]byte, error)
+}
+
+// reportStates returns the report state store of a limiter that has one
+func reportStates(limiter Limiter) (reportStateStore, error) {
+	state, ok := limiter.(reportStateStore)
+	if !ok {
+		return nil, fmt.Errorf("limiter %T cannot keep report state in its store", limiter)
+	}
+	return state, nil
+}
+
+// reportTotals are the cumulative counters a report was computed from
+type reportTotals struct {
+	Requests int64 `json:"requests"`
+	Denied   int64 `json:"denied"`
+}
+
+// reportState is kept in the store between reports, so whichever instance leads computes
+// the next day's numbers as the difference to the counters of the previous report
+type reportState struct {
+	Day        string                  `json:"day"` // Last reported day
+	Taken      time.Time               `json:"taken"`
+	Totals     reportTotals            `json:"totals"`
+	Scopes     map[string]reportTotals `json:"scopes"`
+	Entities   map[string]reportTotals `json:"entities"`
+	Generation int64                   `json:"generation"`
+	Trend      []ReportCounts          `json:"trend"`
+}
+
+// dailyReports builds and delivers the daily reports of a limiter
+type dailyReports struct {
+	config  ReportConfig
+	limiter Limiter
+	state   reportStateStore
+	now     func() time.Time
+}
+
+// ScheduleDailyReports sends a report of the previous day to every reporter once a day, at
+// config.At after midnight. The job runs through RunWhenLeader, so one instance reports
+// for the cluster. Counts are the difference between the shared counters of Stats and
+// those of the previous report, so requests are counted with StatsWriteBehind, and denied
+// entities are listed as far as Stats breaks them down by entity. The first run only
+// records the counters; the first report follows at the next report time. A report is
+// marked sent before it is delivered, so a failed delivery is logged, not retried.
+// Example: ratelimit.ScheduleDailyReports(limiter, ratelimit.ReportConfig{Reporters: []ratelimit.Reporter{ratelimit.NewSlackReporter(webhookURL)}})
+func ScheduleDailyReports(limiter Limiter, config ReportConfig) error {
+	if err := config.validate(); err != nil {
+		return err
+	}
+	state, err := reportStates(limiter)
+	if err != nil {
+		return err
+	}
+	if config.Location == nil {
+		config.Location = time.UTC
+	}
+	if config.TopEntities == 0 {
+		config.TopEntities = DefaultReportTopEntities
+	}
+	if config.TopScopes == 0 {
+		config.TopScopes = DefaultReportTopScopes
+	}
+	if config.TrendDays == 0 {
+		config.TrendDays = DefaultReportTrendDays
+	}
+	if config.Timeout == 0 {
+		config.Timeout = DefaultReporterTimeout
+	}
+
+	dr := &dailyReports{config: config, limiter: limiter, state: state, now: time.Now}
+	return limiter.RunWhenLeader(reportJob, reportCheckInterval, dr.run)
+}
+
+// run sends the report of the last completed day unless it was sent already
+func (dr *dailyReports) run(ctx context.Context) error {
+	now := dr.now().In(dr.config.Location)
+	day := dueReportDay(now, dr.config.At)
+
+	data, err := dr.state.loadReportState(ctx)
+	if err != nil {
+		return err
+	}
+	var previous *reportState
+	if data != nil {
+		previous = &reportState{}
+		if err := json.Unmarshal(data, previous); err != nil {
+			return fmt.Errorf("failed to decode report state: %w", err)
+		}
+		if previous.Day >= day {
+			return nil
+		}
+	}
+
+	stats, err := dr.limiter.Stats(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get stats for the daily report: %w", err)
+	}
+	report, state := dr.build(day, now, stats, previous)
+	if data, err = json.Marshal(state); err != nil {
+		return fmt.Errorf("failed to encode report state: %w", err)
+	}
+	if err := dr.state.saveReportState(ctx, data); err != nil {
+		return err
+	}
+	if report == nil {
+		return nil
+	}
+	return dr.deliver(ctx, report)
+}
+
+// dueReportDay returns the last day whose report time has passed
+func dueReportDay(now time.Time, at time.Duration) string {
+	year, month, day := now.Add(-at).Date()
+	return time.Date(year, month, day-1, 0, 0, 0, 0, now.Location()).Format(reportDayLayout)
+}
+
+// build computes the report of a day and the state the next report starts from. Without
+// a previous state there is nothing to compare to, so no report is built.
+func (dr *dailyReports) build(day string, now time.Time, stats *LimitStats, previous *reportState) (*DailyReport, *reportState) {
+	state := &reportState{
+		Day:      day,
+		Taken:    now,
+		Totals:   reportTotals{Requests: stats.TotalRequests, Denied: stats.TotalDenied},
+		Scopes:   make(map[string]reportTotals, len(stats.ByScope)),
+		Entities: make(map[string]reportTotals, len(stats.ByEntity)),
+	}
+	for scope, scopeStats := range stats.ByScope {
+		state.Scopes[scope] = reportTotals{Requests: scopeStats.Requests, Denied: scopeStats.Denied}
+	}
+	for entity, entityStats := range stats.ByEntity {
+		state.Entities[entity] = reportTotals{Requests: entityStats.Requests, Denied: entityStats.Denied}
+	}
+	version := limiterConfigVersion(dr.limiter)
+	if version != nil {
+		state.Generation = version.Generation
+	}
+	if previous == nil {
+		return nil, state
+	}
+
+	report := &DailyReport{
+		Day:           day,
+		Start:         previous.Taken,
+		End:           now,
+		Requests:      counterDelta(state.Totals.Requests, previous.Totals.Requests),
+		Denied:        counterDelta(state.Totals.Denied, previous.Totals.Denied),
+		BusiestScopes: topCounts(state.Scopes, previous.Scopes, dr.config.TopScopes, false),
+		TopDenied:     topCounts(state.Entities, previous.Entities, dr.config.TopEntities, true),
+	}
+	report.DenyRate = denyRate(report.Requests, report.Denied)
+
+	state.Trend = append(previous.Trend, ReportCounts{
+		Name:     day,
+		Requests: report.Requests,
+		Denied:   report.Denied,
+		DenyRate: report.DenyRate,
+	})
+	if len(state.Trend) > dr.config.TrendDays {
+		state.Trend = state.Trend[len(state.Trend)-dr.config.TrendDays:]
+	}
+	report.Trend = state.Trend
+
+	if version != nil {
+		report.ConfigVersion = version.Version
+		// Generations count from 1 on every instance, so a restarted instance reports its own updates
+		if version.Generation >= previous.Generation {
+			report.ConfigChanges = version.Generation - previous.Generation
+		} else {
+			report.ConfigChanges = version.Generation - 1
+		}
+	}
+	return report, state
+}
+
+// topCounts returns the day's counts of the entities or scopes with the most denials
+// (byDenied) or requests, skipping those without any
+func topCounts(current, previous map[string]reportTotals, n int, byDenied bool) []ReportCounts {
+	counts := make([]ReportCounts, 0, len(current))
+	for name, totals := range current {
+		c := ReportCounts{
+			Name:     name,
+			Requests: counterDelta(totals.Requests, previous[name].Requests),
+			Denied:   counterDelta(totals.Denied, previous[name].Denied),
+		}
+		if (byDenied && c.Denied == 0) || c.Requests == 0 {
+			continue
+		}
+		c.DenyRate = denyRate(c.Requests, c.Denied)
+		counts = append(counts, c)
+	}
+
+	sort.Slice(counts, func(i, j int) bool {
+		a, b := counts[i], counts[j]
+		if byDenied && a.Denied != b.Denied {
+			return a.Denied > b.Denied
+		}
+		if a.Requests != b.Requests {
+			return a.Requests > b.Requests
+		}
+		return a.Name < b.Name
+	})
+	if len(counts) > n {
+		counts = counts[:n]
+	}
+	return counts
+}
+
+// counterDelta returns how much a cumulative counter grew; a counter below its previous
+// value was reset, so all of it is new
+func counterDelta(current, previous int64) int64 {
+	if current < previous {
+		return current
+	}
+	return current - previous
+}
+
+// denyRate returns the share of denied requests
+func denyRate(requests, denied int64) float64 {
+	if requests == 0 {
+		return 0
+	}
+	return float64(denied) / float64(requests)
+}
+
+// limiterConfigVersion returns the config version of a limiter that tracks it
+func limiterConfigVersion(limiter Limiter) *ConfigVersion {
+	if reporter, ok := limiter.(configVersionReporter); ok {
+		return reporter.configVersion()
+	}
+	return nil
+}
+
+// deliver sends a report to every reporter
+func (dr *dailyReports) deliver(ctx context.Context, report *DailyReport) error {
+	var errs []error
+	for _, reporter := range dr.config.Reporters {
+		reportCtx, cancel := context.WithTimeout(ctx, dr.config.Timeout)
+		err := reporter.Report(reportCtx, report)
+		cancel()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("daily report for %s: %w", report.Day, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// =============================================================================
+// Built-in reporters
+// =============================================================================
+
+// FileReporter writes every report as JSON to gorly-report-<day>.json in a directory
+type FileReporter struct {
+	Dir string
+}
+
+// NewFileReporter creates a reporter writing into dir, which must exist
+// Example: ratelimit.NewFileReporter("/var/log/gorly")
+func NewFileReporter(dir string) *FileReporter {
+	return &FileReporter{Dir: dir}
+}
+
+// Report implements Reporter
+func (fr *FileReporter) Report(ctx context.Context, report *DailyReport) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode report: %w", err)
+	}
+	path := filepath.Join(fr.Dir, "gorly-report-"+report.Day+".json")
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+	return nil
+}
+
+// SlackReporter posts the summary of every report to a Slack incoming webhook
+type SlackReporter struct {
+	webhookURL string
+	client     *http.Client
+}
+
+// NewSlackReporter creates a reporter posting to a Slack incoming webhook URL
+// Example: ratelimit.NewSlackReporter("https://hooks.slack.com/services/T000/B000/XXXX")
+func NewSlackReporter(webhookURL string) *SlackReporter {
+	return &SlackReporter{webhookURL: webhookURL}
+}
+
+// WithHTTPClient sets the client that posts reports (default: the client set with SetHTTPClient)
+func (sr *SlackReporter) WithHTTPClient(client *http.Client) *SlackReporter {
+	sr.client = client
+	return sr
+}
+
+// Report implements Reporter
+func (sr *SlackReporter) Report(ctx context.Context, report *DailyReport) error {
+	body, err := json.Marshal(map[string]string{"text": report.Summary()})
+	if err != nil {
+		return fmt.Errorf("failed to encode slack message: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sr.webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create slack request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := httpClient(sr.client).Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post report to slack: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("slack returned %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// EmailReporterConfig configures sending reports by email
+type EmailReporterConfig struct {
+	// Addr of the SMTP server, e.g. "smtp.example.com:587"; STARTTLS is used when offered
+	Addr string
+
+	// Username and Password authenticate with PLAIN auth when Username is set
+	Username string
+	Password string
+
+	From string
+	To   []string
+
+	// Subject of the mails (default: "Gorly daily report for <day>")
+	Subject string
+}
+
+// EmailReporter mails the summary of every report through an SMTP server
+type EmailReporter struct {
+	config EmailReporterConfig
+	send   func(addr string, auth smtp.Auth, from string, to []string, msg []byte) error
+}
+
+// NewEmailReporter creates a reporter sending mail through an SMTP server
+// Example: ratelimit.NewEmailReporter(ratelimit.EmailReporterConfig{Addr: "smtp.example.com:587", From: "gorly@example.com", To: []string{"ops@example.com"}})
+func NewEmailReporter(config EmailReporterConfig) *EmailReporter {
+	return &EmailReporter{config: config, send: smtp.SendMail}
+}
+
+// Report implements Reporter. The SMTP exchange does not observe ctx.
+func (er *EmailReporter) Report(ctx context.Context, report *DailyReport) error {
+	if er.config.Addr == "" || er.config.From == "" || len(er.config.To) == 0 {
+		return errors.New("email reporter requires a server address, a sender and recipients")
+	}
+	var auth smtp.Auth
+	if er.config.Username != "" {
+		host, _, err := net.SplitHostPort(er.config.Addr)
+		if err != nil {
+			return fmt.Errorf("invalid smtp address %q: %w", er.config.Addr, err)
+		}
+		auth = smtp.PlainAuth("", er.config.Username, er.config.Password, host)
+	}
+	subject := er.config.Subject
+	if subject == "" {
+		subject = "Gorly daily report for " + report.Day
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", er.config.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(er.config.To, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", subject)
+	fmt.Fprintf(&msg, "Date: %s\r\n", report.End.Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(report.Summary(), "\n", "\r\n"))
+
+	if err := er.send(er.config.Addr, auth, er.config.From, er.config.To, msg.Bytes()); err != nil {
+		return fmt.Errorf("failed to send report mail: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,220 @@
+// reports_test.go
+package ratelimit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newReportTestRun(t *testing.T, reporter Reporter) (*dailyReports, *LimitStats, *time.Time) {
+	t.Helper()
+
+	limiter, err := New().Limit("search", "2/minute").Build()
+	if err != nil {
+		t.Fatalf("Failed to build limiter: %v", err)
+	}
+	t.Cleanup(func() { limiter.Close() })
+
+	stats := &LimitStats{ByScope: map[string]*LimitScopeStats{}, ByEntity: map[string]*EntityStats{}}
+	now := time.Date(2026, 10, 15, 9, 0, 0, 0, time.UTC)
+	dr := &dailyReports{
+		config: ReportConfig{
+			Reporters:   []Reporter{reporter},
+			At:          6 * time.Hour,
+			Location:    time.UTC,
+			TopEntities: 1,
+			TopScopes:   DefaultReportTopScopes,
+			TrendDays:   2,
+			Timeout:     time.Second,
+		},
+		limiter: statsStub{Limiter: limiter, stats: stats},
+		state:   limiter.(reportStateStore),
+		now:     func() time.Time { return now },
+	}
+	return dr, stats, &now
+}
+
+// statsStub answers Stats with fixed counters
+type statsStub struct {
+	Limiter
+	stats *LimitStats
+}
+
+func (s statsStub) Stats(ctx context.Context) (*LimitStats, error) {
+	return s.stats, nil
+}
+
+func TestDailyReports(t *testing.T) {
+	var reports []*DailyReport
+	dr, stats, now := newReportTestRun(t, ReporterFunc(func(ctx context.Context, report *DailyReport) error {
+		reports = append(reports, report)
+		return nil
+	}))
+	ctx := context.Background()
+
+	// The first run only records the counters
+	stats.TotalRequests, stats.TotalDenied = 100, 10
+	stats.ByScope["search"] = &LimitScopeStats{Requests: 100, Denied: 10}
+	if err := dr.run(ctx); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if len(reports) != 0 {
+		t.Fatalf("Expected no report without earlier counters, got %d", len(reports))
+	}
+
+	// A day later, the growth of the counters is reported once
+	*now = now.Add(24 * time.Hour)
+	stats.TotalRequests, stats.TotalDenied = 300, 60
+	stats.ByScope["search"] = &LimitScopeStats{Requests: 250, Denied: 60}
+	stats.ByScope["upload"] = &LimitScopeStats{Requests: 50}
+	stats.ByEntity["user-1"] = &EntityStats{Requests: 80, Denied: 40}
+	stats.ByEntity["user-2"] = &EntityStats{Requests: 20, Denied: 10}
+	for i := 0; i < 2; i++ {
+		if err := dr.run(ctx); err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+	}
+	if len(reports) != 1 {
+		t.Fatalf("Expected one report, got %d", len(reports))
+	}
+	report := reports[0]
+	if report.Day != "2026-10-15" || report.Requests != 200 || report.Denied != 50 || report.DenyRate != 0.25 {
+		t.Errorf("Unexpected totals: %+v", report)
+	}
+	if len(report.BusiestScopes) != 2 || report.BusiestScopes[0].Name != "search" || report.BusiestScopes[0].Requests != 150 {
+		t.Errorf("Unexpected scopes: %+v", report.BusiestScopes)
+	}
+	if len(report.TopDenied) != 1 || report.TopDenied[0].Name != "user-1" || report.TopDenied[0].Denied != 40 {
+		t.Errorf("Unexpected top denied entities: %+v", report.TopDenied)
+	}
+	if report.ConfigVersion != "" || report.ConfigChanges != 0 {
+		t.Errorf("Expected no config changes, got %d", report.ConfigChanges)
+	}
+
+	// The trend keeps the configured number of days
+	for day := 0; day < 2; day++ {
+		*now = now.Add(24 * time.Hour)
+		stats.TotalRequests += 100
+		if err := dr.run(ctx); err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+	}
+	trend := reports[len(reports)-1].Trend
+	if len(trend) != 2 || trend[0].Name != "2026-10-16" || trend[1].Name != "2026-10-17" {
+		t.Errorf("Unexpected trend: %+v", trend)
+	}
+	if summary := report.Summary(); !strings.Contains(summary, "user-1: 40 of 80 denied (50.00%)") {
+		t.Errorf("Unexpected summary:\n%s", summary)
+	}
+}
+
+func TestDueReportDay(t *testing.T) {
+	tests := []struct {
+		now  time.Time
+		want string
+	}{
+		{time.Date(2026, 10, 16, 5, 59, 0, 0, time.UTC), "2026-10-14"},
+		{time.Date(2026, 10, 16, 6, 0, 0, 0, time.UTC), "2026-10-15"},
+		{time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC), "2025-12-31"},
+	}
+	for _, tt := range tests {
+		if got := dueReportDay(tt.now, 6*time.Hour); got != tt.want {
+			t.Errorf("dueReportDay(%v) = %s, want %s", tt.now, got, tt.want)
+		}
+	}
+}
+
+func TestScheduleDailyReportsValidation(t *testing.T) {
+	limiter, err := New().Limit("search", "2/minute").Build()
+	if err != nil {
+		t.Fatalf("Failed to build limiter: %v", err)
+	}
+	defer limiter.Close()
+
+	if err := ScheduleDailyReports(limiter, ReportConfig{}); err == nil {
+		t.Error("Expected an error without reporters")
+	}
+	reporters := []Reporter{NewFileReporter(t.TempDir())}
+	if err := ScheduleDailyReports(limiter, ReportConfig{Reporters: reporters, At: 25 * time.Hour}); err == nil {
+		t.Error("Expected an error for a report time beyond the day")
+	}
+	if err := ScheduleDailyReports(limiter, ReportConfig{Reporters: reporters}); err != nil {
+		t.Errorf("Failed to schedule reports: %v", err)
+	}
+}
+
+func TestReporters(t *testing.T) {
+	report := &DailyReport{
+		Day:       "2026-10-15",
+		End:       time.Date(2026, 10, 16, 6, 0, 0, 0, time.UTC),
+		Requests:  200,
+		Denied:    50,
+		DenyRate:  0.25,
+		TopDenied: []ReportCounts{{Name: "user-1", Requests: 80, Denied: 40, DenyRate: 0.5}},
+	}
+	ctx := context.Background()
+
+	t.Run("file", func(t *testing.T) {
+		dir := t.TempDir()
+		if err := NewFileReporter(dir).Report(ctx, report); err != nil {
+			t.Fatalf("Report failed: %v", err)
+		}
+		data, err := os.ReadFile(filepath.Join(dir, "gorly-report-2026-10-15.json"))
+		if err != nil {
+			t.Fatalf("Failed to read report: %v", err)
+		}
+		var written DailyReport
+		if err := json.Unmarshal(data, &written); err != nil || written.Denied != 50 {
+			t.Errorf("Unexpected report file: %s", data)
+		}
+	})
+
+	t.Run("slack", func(t *testing.T) {
+		var text string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var message map[string]string
+			json.NewDecoder(r.Body).Decode(&message)
+			text = message["text"]
+		}))
+		defer server.Close()
+
+		if err := NewSlackReporter(server.URL).Report(ctx, report); err != nil {
+			t.Fatalf("Report failed: %v", err)
+		}
+		if !strings.HasPrefix(text, "Gorly daily report for 2026-10-15") {
+			t.Errorf("Unexpected slack message: %q", text)
+		}
+	})
+
+	t.Run("email", func(t *testing.T) {
+		reporter := NewEmailReporter(EmailReporterConfig{
+			Addr:     "smtp.example.com:587",
+			Username: "gorly",
+			Password: "secret",
+			From:     "gorly@example.com",
+			To:       []string{"ops@example.com"},
+		})
+		var sent string
+		reporter.send = func(addr string, auth smtp.Auth, from string, to []string, msg []byte) error {
+			if auth == nil || addr != "smtp.example.com:587" {
+				t.Errorf("Expected authentication with %s", addr)
+			}
+			sent = string(msg)
+			return nil
+		}
+		if err := reporter.Report(ctx, report); err != nil {
+			t.Fatalf("Report failed: %v", err)
+		}
+		if !strings.Contains(sent, "Subject: Gorly daily report for 2026-10-15\r\n") || !strings.Contains(sent, "denied: 50 (25.00%)") {
+			t.Errorf("Unexpected mail:\n%s", sent)
+		}
+	})
+}
@@ -0,0 +1,138 @@
+// resultcodec.go - Binary and compact text encodings of limit results
+package ratelimit
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// resultEncodingVersion is the first byte of encoded results
+const resultEncodingVersion = 1
+
+// Flags of the boolean fields of an encoded result
+const (
+	resultAllowed byte = 1 << iota
+	resultMaintenance
+	resultCached
+	resultShadowDenied
+	resultFailedOpen
+	resultStale
+	resultDegraded
+)
+
+// errTruncatedResult is returned for encoded results that end early
+var errTruncatedResult = errors.New("truncated limit result")
+
+// MarshalBinary encodes the result in a compact, versioned binary form, so gateways can
+// cache decisions or hand them to another process. Counts and durations are varints;
+// ResetTime keeps its instant and decodes in UTC. Results also encode to JSON through
+// their field tags.
+func (r *LimitResult) MarshalBinary() ([]byte, error) {
+	flags := resultFlag(r.Allowed, resultAllowed) | resultFlag(r.Maintenance, resultMaintenance) |
+		resultFlag(r.Cached, resultCached) | resultFlag(r.ShadowDenied, resultShadowDenied) |
+		resultFlag(r.FailedOpen, resultFailedOpen) | resultFlag(r.Stale, resultStale) |
+		resultFlag(r.Degraded, resultDegraded)
+
+	var reset int64
+	if !r.ResetTime.IsZero() {
+		reset = r.ResetTime.UnixNano()
+	}
+
+	data := []byte{resultEncodingVersion, flags}
+	for _, value := range []int64{r.Remaining, r.Limit, r.Used, r.GrantRemaining, int64(r.RetryAfter), int64(r.Window), reset} {
+		data = binary.AppendVarint(data, value)
+	}
+	for _, value := range []string{r.UnknownTier, r.Enforcement, string(r.Alignment)} {
+		data = binary.AppendUvarint(data, uint64(len(value)))
+		data = append(data, value...)
+	}
+	return data, nil
+}
+
+// resultFlag returns flag if set, otherwise 0
+func resultFlag(set bool, flag byte) byte {
+	if set {
+		return flag
+	}
+	return 0
+}
+
+// UnmarshalBinary decodes a result encoded by MarshalBinary. Bytes after the known fields
+// are ignored, so results from newer versions that append fields still decode.
+func (r *LimitResult) UnmarshalBinary(data []byte) error {
+	if len(data) < 2 {
+		return errTruncatedResult
+	}
+	if data[0] != resultEncodingVersion {
+		return fmt.Errorf("unsupported limit result encoding version %d", data[0])
+	}
+	flags := data[1]
+	data = data[2:]
+
+	var numbers [7]int64
+	for i := range numbers {
+		value, n := binary.Varint(data)
+		if n <= 0 {
+			return errTruncatedResult
+		}
+		numbers[i], data = value, data[n:]
+	}
+	var texts [3]string
+	for i := range texts {
+		if i == 2 && len(data) == 0 {
+			break // Encoded before results carried their alignment
+		}
+		length, n := binary.Uvarint(data)
+		if n <= 0 || length > uint64(len(data)-n) {
+			return errTruncatedResult
+		}
+		texts[i], data = string(data[n:n+int(length)]), data[n+int(length):]
+	}
+
+	*r = LimitResult{
+		Allowed:        flags&resultAllowed != 0,
+		Remaining:      numbers[0],
+		Limit:          numbers[1],
+		Used:           numbers[2],
+		GrantRemaining: numbers[3],
+		RetryAfter:     time.Duration(numbers[4]),
+		Window:         time.Duration(numbers[5]),
+		Maintenance:    flags&resultMaintenance != 0,
+		Cached:         flags&resultCached != 0,
+		ShadowDenied:   flags&resultShadowDenied != 0,
+		FailedOpen:     flags&resultFailedOpen != 0,
+		Stale:          flags&resultStale != 0,
+		Degraded:       flags&resultDegraded != 0,
+		UnknownTier:    texts[0],
+		Enforcement:    texts[1],
+		Alignment:      WindowAlignment(texts[2]),
+	}
+	if numbers[6] != 0 {
+		r.ResetTime = time.Unix(0, numbers[6]).UTC()
+	}
+	return nil
+}
+
+// EncodeResult returns the binary form of a result as unpadded URL-safe base64, which fits
+// in HTTP headers and gRPC metadata
+// Example: md.Set("x-ratelimit-result", gorly.EncodeResult(result))
+func EncodeResult(result *LimitResult) string {
+	data, _ := result.MarshalBinary()
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeResult decodes a result encoded by EncodeResult
+func DecodeResult(s string) (*LimitResult, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid limit result encoding: %w", err)
+	}
+	result := &LimitResult{}
+	if err := result.UnmarshalBinary(data); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,101 @@
+// resultcodec_test.go
+package ratelimit
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestResultEncoding(t *testing.T) {
+	results := []*LimitResult{
+		{},
+		{
+			Allowed:        true,
+			Remaining:      41,
+			Limit:          100,
+			Used:           59,
+			Window:         time.Minute,
+			ResetTime:      time.Date(2026, 3, 9, 12, 30, 0, 123, time.UTC),
+			GrantRemaining: 20,
+			UnknownTier:    "platinum",
+			Enforcement:    "shadow",
+			ShadowDenied:   true,
+			Stale:          true,
+			Alignment:      AlignWallClock,
+		},
+		{RetryAfter: 90 * time.Second, Limit: 5, Used: 5, Window: time.Hour, Maintenance: true, Cached: true, FailedOpen: true},
+		{RetryAfter: time.Second, Limit: 2, Used: 2, Window: time.Minute, Degraded: true},
+	}
+	for _, want := range results {
+		data, err := want.MarshalBinary()
+		if err != nil {
+			t.Fatalf("MarshalBinary failed: %v", err)
+		}
+		got := &LimitResult{}
+		if err := got.UnmarshalBinary(data); err != nil {
+			t.Fatalf("UnmarshalBinary failed: %v", err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Binary round trip: got %+v, want %+v", got, want)
+		}
+
+		decoded, err := DecodeResult(EncodeResult(want))
+		if err != nil || !reflect.DeepEqual(decoded, want) {
+			t.Errorf("Text round trip: got %+v (%v), want %+v", decoded, err, want)
+		}
+
+		encoded, err := json.Marshal(want)
+		if err != nil {
+			t.Fatalf("json.Marshal failed: %v", err)
+		}
+		fromJSON := &LimitResult{}
+		if err := json.Unmarshal(encoded, fromJSON); err != nil || !reflect.DeepEqual(fromJSON, want) {
+			t.Errorf("JSON round trip: got %+v (%v), want %+v", fromJSON, err, want)
+		}
+	}
+
+	// Fields appended by newer versions are skipped
+	data, _ := results[1].MarshalBinary()
+	got := &LimitResult{}
+	if err := got.UnmarshalBinary(append(data, 0x01, 0x02)); err != nil || got.Remaining != 41 {
+		t.Errorf("Expected trailing bytes to be ignored, got %+v (%v)", got, err)
+	}
+
+	// Results encoded before alignments were added decode without one
+	legacy := append([]byte{resultEncodingVersion, resultAllowed}, 0, 0, 0, 0, 0, 0, 0, 0, 0)
+	if err := got.UnmarshalBinary(legacy); err != nil || !got.Allowed || got.Alignment != "" {
+		t.Errorf("Expected a result without alignment to decode, got %+v (%v)", got, err)
+	}
+
+	for _, invalid := range []string{"", "!!", EncodeResult(results[1])[:6]} {
+		if _, err := DecodeResult(invalid); err == nil {
+			t.Errorf("Expected %q to fail to decode", invalid)
+		}
+	}
+	if err := got.UnmarshalBinary([]byte{9, 0}); err == nil {
+		t.Error("Expected an unknown version to fail to decode")
+	}
+}
+
+func TestEncodeCheckResult(t *testing.T) {
+	limiter, err := New().Limit("global", "10/minute").Build()
+	if err != nil {
+		t.Fatalf("Failed to build limiter: %v", err)
+	}
+	defer limiter.Close()
+
+	result, err := limiter.Check(context.Background(), "user1", "global")
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	decoded, err := DecodeResult(EncodeResult(result))
+	if err != nil {
+		t.Fatalf("DecodeResult failed: %v", err)
+	}
+	if !decoded.ResetTime.Equal(result.ResetTime) || decoded.Remaining != result.Remaining || decoded.Allowed != result.Allowed {
+		t.Errorf("Expected %+v, got %+v", result, decoded)
+	}
+}
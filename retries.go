@@ -0,0 +1,38 @@
+// retries.go - Retries and hedging of the store reads made by checks
+package ratelimit
+
+import (
+	"time"
+
+	"github.com/itsatony/gorly/internal/core"
+)
+
+// StoreRetryConfig configures retries of failed store reads and hedging of slow ones.
+// Only reads are repeated, since repeating a write could count a request twice. Retries
+// and hedges share a budget of MinRetriesPerSecond plus BudgetRatio of the reads made each
+// second, so a store that is down sees little extra traffic, and a retry is skipped when
+// the request's deadline leaves no time for it.
+type StoreRetryConfig struct {
+	MaxRetries int           // Retries of a failed read (default: 1)
+	Backoff    time.Duration // Wait before the first retry, doubled for every further one (default: 5ms)
+
+	// Hedge sends a second read when the first takes longer than the P99 of recent reads,
+	// or HedgeAfter if set, and uses whichever answers first
+	Hedge      bool
+	HedgeAfter time.Duration
+
+	BudgetRatio         float64 // Retries and hedges allowed per read, at most 1 (default: 0.1)
+	MinRetriesPerSecond int     // Retries and hedges allowed per second regardless of reads (default: 10)
+}
+
+// toCore converts the retry config for the core limiter
+func (rc StoreRetryConfig) toCore() *core.StoreRetryConfig {
+	return &core.StoreRetryConfig{
+		MaxRetries:          rc.MaxRetries,
+		Backoff:             rc.Backoff,
+		Hedge:               rc.Hedge,
+		HedgeAfter:          rc.HedgeAfter,
+		BudgetRatio:         rc.BudgetRatio,
+		MinRetriesPerSecond: rc.MinRetriesPerSecond,
+	}
+}
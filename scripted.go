@@ -0,0 +1,227 @@
+// scripted.go - Scriptable limiter for testing services that consume gorly
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/itsatony/gorly/internal/core"
+)
+
+// ScriptedCall is one rate limit decision asked of a ScriptedLimiter
+type ScriptedCall struct {
+	N      int    // 1 for the first call, counting Check, CheckN and middleware requests
+	Entity string // Entity the decision is for
+	Scope  string // Scope the decision is for
+	Cost   int64  // Units the call consumes
+}
+
+// ScriptedDecision is the answer a script gives to a call. A non-nil Err fails the call
+// like an unreachable store: Check returns it and the middleware responds with 500.
+type ScriptedDecision struct {
+	Allowed    bool
+	Limit      int64
+	Remaining  int64
+	RetryAfter time.Duration // How long a denied caller should wait; also the reset time
+	Err        error
+}
+
+// Script decides every call of a ScriptedLimiter
+type Script func(call ScriptedCall) ScriptedDecision
+
+// ScriptStep repeats one decision for a number of calls in a Sequence
+type ScriptStep struct {
+	Times    int // Calls answered by this step; less than 1 counts as 1
+	Decision ScriptedDecision
+}
+
+// ScriptAllow allows the next n calls
+func ScriptAllow(n int) ScriptStep {
+	return ScriptStep{Times: n, Decision: ScriptedDecision{Allowed: true}}
+}
+
+// ScriptDeny denies the next n calls, telling callers to retry after retryAfter
+func ScriptDeny(n int, retryAfter time.Duration) ScriptStep {
+	return ScriptStep{Times: n, Decision: ScriptedDecision{RetryAfter: retryAfter}}
+}
+
+// ScriptError fails the next n calls with err
+func ScriptError(n int, err error) ScriptStep {
+	return ScriptStep{Times: n, Decision: ScriptedDecision{Err: err}}
+}
+
+// Sequence plays steps in order, repeating the last decision once they are used up.
+// Decisions without a Limit report the number of allowed calls in the sequence as the
+// limit and count Remaining down as they are allowed, so headers look like a real limit.
+// Example: ratelimit.Sequence(ratelimit.ScriptAllow(3), ratelimit.ScriptDeny(1, 5*time.Second), ratelimit.ScriptError(1, err))
+func Sequence(steps ...ScriptStep) Script {
+	var decisions []ScriptedDecision
+	var allowed int64
+	for _, step := range steps {
+		times := step.Times
+		if times < 1 {
+			times = 1
+		}
+		for i := 0; i < times; i++ {
+			decisions = append(decisions, step.Decision)
+		}
+		if step.Decision.Allowed && step.Decision.Err == nil {
+			allowed += int64(times)
+		}
+	}
+	if len(decisions) == 0 {
+		decisions = append(decisions, ScriptedDecision{Allowed: true})
+	}
+
+	// Allowed calls left after each decision
+	remaining := make([]int64, len(decisions))
+	left := allowed
+	for i, d := range decisions {
+		if d.Allowed && d.Err == nil && left > 0 {
+			left--
+		}
+		remaining[i] = left
+	}
+
+	return func(call ScriptedCall) ScriptedDecision {
+		i := min(call.N, len(decisions)) - 1
+		d := decisions[i]
+		if d.Limit == 0 {
+			d.Limit = max(allowed, 1)
+			d.Remaining = remaining[i]
+		}
+		return d
+	}
+}
+
+// ScriptedLimiter answers rate limit checks from a script instead of an algorithm, so the
+// services using gorly can test how they handle allowed, denied and failing checks. The
+// middleware of a ScriptedLimiter is the real one: headers, 429 and 500 responses, and every
+// builder option affecting them, behave as in production.
+type ScriptedLimiter struct {
+	Limiter
+	script Script
+
+	mu    sync.Mutex
+	calls []ScriptedCall
+}
+
+// NewScriptedLimiter creates a limiter answering checks from script, with the default
+// configuration of New and a global limit that is never consulted; use BuildScripted to
+// configure extractors, scopes or headers.
+// Example: limiter := ratelimit.NewScriptedLimiter(ratelimit.Sequence(ratelimit.ScriptAllow(3), ratelimit.ScriptDeny(1, 5*time.Second)))
+func NewScriptedLimiter(script Script) *ScriptedLimiter {
+	limiter, err := New().Limit("global", "1000/minute").BuildScripted(script)
+	if err != nil {
+		panic(fmt.Sprintf("failed to build scripted limiter: %v", err))
+	}
+	return limiter
+}
+
+// BuildScripted builds the limiter configured so far with its checks answered from script.
+// The configured limits are validated like in Build, but never consulted.
+// Example: limiter, err := gorly.New().Limit("global", "10/minute").ExtractorFunc(userID).BuildScripted(gorly.Sequence(gorly.ScriptAllow(10)))
+func (b *Builder) BuildScripted(script Script) (*ScriptedLimiter, error) {
+	if script == nil {
+		return nil, fmt.Errorf("invalid configuration: scripted limiter requires a script")
+	}
+	built, err := b.Build()
+	if err != nil {
+		return nil, err
+	}
+	impl := built.(*limiterImpl)
+
+	sl := &ScriptedLimiter{script: script}
+	impl.core = &scriptedCore{Limiter: impl.core, limiter: sl}
+	sl.Limiter = impl
+	return sl, nil
+}
+
+// Calls returns the calls decided so far, in order
+func (sl *ScriptedLimiter) Calls() []ScriptedCall {
+	sl.mu.Lock()
+	defer sl.mu.Unlock()
+	return append([]ScriptedCall(nil), sl.calls...)
+}
+
+// Reset forgets the recorded calls and starts the script over
+func (sl *ScriptedLimiter) Reset() {
+	sl.mu.Lock()
+	defer sl.mu.Unlock()
+	sl.calls = nil
+}
+
+// decide records a call and asks the script for its decision
+func (sl *ScriptedLimiter) decide(entity, scope string, cost int64) (*core.CoreResult, error) {
+	sl.mu.Lock()
+	call := ScriptedCall{N: len(sl.calls) + 1, Entity: entity, Scope: scope, Cost: cost}
+	sl.calls = append(sl.calls, call)
+	sl.mu.Unlock()
+	return scriptedResult(sl.script(call))
+}
+
+// peek asks the script what the next call would get without recording it
+func (sl *ScriptedLimiter) peek(entity, scope string) (*core.CoreResult, error) {
+	sl.mu.Lock()
+	call := ScriptedCall{N: len(sl.calls) + 1, Entity: entity, Scope: scope}
+	sl.mu.Unlock()
+	return scriptedResult(sl.script(call))
+}
+
+// scriptedResult converts a decision into the result of a check
+func scriptedResult(d ScriptedDecision) (*core.CoreResult, error) {
+	if d.Err != nil {
+		return nil, d.Err
+	}
+	result := &core.CoreResult{
+		Allowed:    d.Allowed,
+		Limit:      d.Limit,
+		Remaining:  d.Remaining,
+		Used:       max(d.Limit-d.Remaining, 0),
+		RetryAfter: d.RetryAfter,
+		Window:     time.Minute,
+		ResetTime:  time.Now().Add(d.RetryAfter),
+	}
+	if d.Allowed {
+		result.RetryAfter = 0
+	}
+	return result, nil
+}
+
+// scriptedCore answers the checks of a limiter from its script and leaves everything else,
+// such as configuration, health and stats, to the real core
+type scriptedCore struct {
+	core.Limiter
+	limiter *ScriptedLimiter
+}
+
+func (sc *scriptedCore) Check(ctx context.Context, entity, scope string) (*core.CoreResult, error) {
+	return sc.limiter.decide(entity, scope, core.DefaultRequestCost)
+}
+
+func (sc *scriptedCore) CheckN(ctx context.Context, entity, scope string, n int64) (*core.CoreResult, error) {
+	return sc.limiter.decide(entity, scope, n)
+}
+
+func (sc *scriptedCore) Peek(ctx context.Context, entity, scope string) (*core.CoreResult, error) {
+	return sc.limiter.peek(entity, scope)
+}
+
+// CheckAll asks the script once per scope; the first denial denies the whole check
+func (sc *scriptedCore) CheckAll(ctx context.Context, entity string, costs []core.ScopeCost) (*core.MultiScopeResult, error) {
+	result := &core.MultiScopeResult{Allowed: true, Results: make([]*core.CoreResult, len(costs))}
+	for i, cost := range costs {
+		scopeResult, err := sc.limiter.decide(entity, cost.Scope, cost.Cost)
+		if err != nil {
+			return nil, err
+		}
+		if !scopeResult.Allowed && result.Allowed {
+			result.Allowed = false
+			result.Denied = cost.Scope
+		}
+		result.Results[i] = scopeResult
+	}
+	return result, nil
+}
@@ -0,0 +1,94 @@
+// scripted_test.go
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestScriptedLimiterSequence(t *testing.T) {
+	storeDown := errors.New("store down")
+	limiter := NewScriptedLimiter(Sequence(ScriptAllow(3), ScriptDeny(1, 5*time.Second), ScriptError(1, storeDown)))
+	defer limiter.Close()
+	ctx := context.Background()
+
+	if peek, err := limiter.Peek(ctx, "user:1"); err != nil || !peek.Allowed || peek.Remaining != 2 {
+		t.Fatalf("Expected Peek to show the first decision, got %+v, %v", peek, err)
+	}
+	for i, want := range []int64{2, 1, 0} {
+		result, err := limiter.Check(ctx, "user:1")
+		if err != nil || !result.Allowed || result.Limit != 3 || result.Remaining != want {
+			t.Errorf("Call %d: expected allowed with %d remaining of 3, got %+v, %v", i+1, want, result, err)
+		}
+	}
+	result, err := limiter.Check(ctx, "user:1", "export")
+	if err != nil || result.Allowed || result.RetryAfter != 5*time.Second {
+		t.Errorf("Call 4: expected a denial with RetryAfter 5s, got %+v, %v", result, err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := limiter.Check(ctx, "user:1"); !errors.Is(err, storeDown) {
+			t.Errorf("Expected the last decision to repeat, got %v", err)
+		}
+	}
+
+	calls := limiter.Calls()
+	if len(calls) != 6 || calls[3].Scope != "export" || calls[3].N != 4 || calls[0].Entity != "user:1" {
+		t.Errorf("Expected the calls to be recorded, got %+v", calls)
+	}
+	limiter.Reset()
+	if result, err := limiter.Check(ctx, "user:1"); err != nil || !result.Allowed || len(limiter.Calls()) != 1 {
+		t.Errorf("Expected Reset to start the script over, got %+v, %v", result, err)
+	}
+}
+
+func TestScriptedLimiterMiddleware(t *testing.T) {
+	limiter, err := New().
+		Limit("global", "1/hour").
+		ExtractorFunc(func(r *http.Request) string { return r.Header.Get("X-User") }).
+		BuildScripted(func(call ScriptedCall) ScriptedDecision {
+			switch call.Entity {
+			case "banned":
+				return ScriptedDecision{Limit: 10, RetryAfter: time.Minute}
+			case "broken":
+				return ScriptedDecision{Err: errors.New("store down")}
+			}
+			return ScriptedDecision{Allowed: true, Limit: 10, Remaining: 7}
+		})
+	if err != nil {
+		t.Fatalf("Failed to build scripted limiter: %v", err)
+	}
+	defer limiter.Close()
+
+	handler := limiter.For(HTTP).(func(http.Handler) http.Handler)(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }))
+	send := func(user string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("X-User", user)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := send("alice"); w.Code != http.StatusOK || w.Header().Get("X-RateLimit-Limit") != "10" || w.Header().Get("X-RateLimit-Remaining") != "7" {
+		t.Errorf("Expected 200 with the scripted headers, got %d %v", w.Code, w.Header())
+	}
+	if w := send("banned"); w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "60" {
+		t.Errorf("Expected 429 with Retry-After 60, got %d %v", w.Code, w.Header())
+	}
+	if w := send("broken"); w.Code != http.StatusInternalServerError {
+		t.Errorf("Expected 500 for a failing check, got %d", w.Code)
+	}
+	if calls := limiter.Calls(); len(calls) != 3 || calls[1].Entity != "banned" || calls[1].Scope != "global" {
+		t.Errorf("Expected the middleware calls to be recorded, got %+v", calls)
+	}
+}
+
+func TestBuildScriptedRequiresScript(t *testing.T) {
+	if _, err := New().BuildScripted(nil); err == nil {
+		t.Error("Expected a scripted limiter without a script to be rejected")
+	}
+}
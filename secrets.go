@@ -0,0 +1,60 @@
+// secrets.go - Redis credentials fetched at runtime from a secrets provider
+package ratelimit
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+
+	"github.com/itsatony/gorly/stores"
+)
+
+// RedisCredentials are the credentials of Redis connections, fetched at runtime instead of configured
+type RedisCredentials struct {
+	Username    string // ACL user; "" authenticates as the default user
+	Password    string
+	Certificate *tls.Certificate // Client certificate for mutual TLS; nil without
+	RootCAs     *x509.CertPool   // CAs of the server certificate; nil uses the system pool
+}
+
+// SecretsProvider supplies Redis credentials that may rotate, such as VaultSecretsProvider.
+// The store asks for them when it connects and then every minute; when they changed it
+// replaces its connections, so every connection authenticates with the new secrets.
+// Providers cache secrets and renew them themselves.
+type SecretsProvider interface {
+	Secrets(ctx context.Context) (*RedisCredentials, error)
+}
+
+// SecretsProviderFunc adapts a function to a SecretsProvider
+type SecretsProviderFunc func(ctx context.Context) (*RedisCredentials, error)
+
+// Secrets implements SecretsProvider
+func (f SecretsProviderFunc) Secrets(ctx context.Context) (*RedisCredentials, error) {
+	return f(ctx)
+}
+
+// storeSecrets adapts a SecretsProvider to the stores package
+func storeSecrets(provider SecretsProvider) stores.SecretsProvider {
+	if provider == nil {
+		return nil
+	}
+	return stores.SecretsProviderFunc(func(ctx context.Context) (*stores.RedisCredentials, error) {
+		secrets, err := provider.Secrets(ctx)
+		if err != nil || secrets == nil {
+			return nil, err
+		}
+		converted := stores.RedisCredentials(*secrets)
+		return &converted, nil
+	})
+}
+
+// secretsProvider returns the provider of the Redis credentials, or nil if Password is used
+func (r RedisConfig) secretsProvider() SecretsProvider {
+	if r.Secrets != nil {
+		return r.Secrets
+	}
+	if r.Vault != nil {
+		return NewVaultSecretsProvider(*r.Vault)
+	}
+	return nil
+}
@@ -0,0 +1,261 @@
+// store_instrumentation.go - Latency, error and tracing instrumentation for any Store
+package ratelimit
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/itsatony/gorly/stores"
+)
+
+// StoreInstrumentation configures InstrumentStore. Every field is optional; without
+// any the instrumented store only keeps its own operation statistics.
+type StoreInstrumentation struct {
+	// Name identifies the store in log fields and observations (default: "store")
+	Name string
+
+	// Logger receives slow operations and errors (default: a Warn level DefaultLogger)
+	Logger Logger
+
+	// SlowThreshold logs operations that take at least this long; zero disables slow logging
+	SlowThreshold time.Duration
+
+	// LogErrors logs failed operations. Missing keys are not failures.
+	LogErrors bool
+
+	// Observe is called after every operation, e.g. to feed a Prometheus histogram
+	Observe func(op StoreOperation)
+
+	// StartSpan starts a tracing span for an operation and returns the context to run it
+	// with and a function ending the span with the operation's error
+	// Example:
+	//
+	//	StartSpan: func(ctx context.Context, op, key string) (context.Context, func(error)) {
+	//		ctx, span := tracer.Start(ctx, "gorly.store."+op)
+	//		return ctx, func(err error) {
+	//			if err != nil {
+	//				span.RecordError(err)
+	//			}
+	//			span.End()
+	//		}
+	//	}
+	StartSpan func(ctx context.Context, operation, key string) (context.Context, func(err error))
+}
+
+// StoreOperation describes one completed store operation
+type StoreOperation struct {
+	Store     string
+	Operation string
+	Key       string
+	Duration  time.Duration
+	Err       error // nil for successful operations and missing keys
+	Miss      bool  // the key did not exist
+}
+
+// StoreOperationStats summarizes the calls of one store operation
+type StoreOperationStats struct {
+	Calls        int64         `json:"calls"`
+	Errors       int64         `json:"errors"`
+	Misses       int64         `json:"misses"`
+	Slow         int64         `json:"slow"`
+	TotalLatency time.Duration `json:"total_latency"`
+	MaxLatency   time.Duration `json:"max_latency"`
+}
+
+// AverageLatency returns the mean latency of the calls
+func (s StoreOperationStats) AverageLatency() time.Duration {
+	if s.Calls == 0 {
+		return 0
+	}
+	return s.TotalLatency / time.Duration(s.Calls)
+}
+
+// InstrumentedStore is a Store that measures every operation of the store it wraps
+type InstrumentedStore struct {
+	store  Store
+	config StoreInstrumentation
+
+	mu    sync.Mutex
+	stats map[string]*StoreOperationStats
+}
+
+// InstrumentStore wraps a store so that every operation is timed, counted, optionally
+// traced and, when slow or failing, logged. Third-party stores get the same observability
+// as the built-in ones without any code of their own.
+// Example: store = ratelimit.InstrumentStore(store, &ratelimit.StoreInstrumentation{SlowThreshold: 50 * time.Millisecond})
+func InstrumentStore(store Store, opts *StoreInstrumentation) *InstrumentedStore {
+	is := &InstrumentedStore{
+		store: store,
+		stats: make(map[string]*StoreOperationStats),
+	}
+	if opts != nil {
+		is.config = *opts
+	}
+	if is.config.Name == "" {
+		is.config.Name = "store"
+	}
+	if is.config.Logger == nil {
+		is.config.Logger = NewDefaultLogger(LogLevelWarn)
+	}
+	return is
+}
+
+// Unwrap returns the instrumented store
+func (is *InstrumentedStore) Unwrap() Store {
+	return is.store
+}
+
+// Stats returns a snapshot of the statistics of every operation called so far
+func (is *InstrumentedStore) Stats() map[string]StoreOperationStats {
+	is.mu.Lock()
+	defer is.mu.Unlock()
+
+	stats := make(map[string]StoreOperationStats, len(is.stats))
+	for op, s := range is.stats {
+		stats[op] = *s
+	}
+	return stats
+}
+
+// Operations returns the names of the operations called so far, sorted
+func (is *InstrumentedStore) Operations() []string {
+	is.mu.Lock()
+	defer is.mu.Unlock()
+
+	ops := make([]string, 0, len(is.stats))
+	for op := range is.stats {
+		ops = append(ops, op)
+	}
+	sort.Strings(ops)
+	return ops
+}
+
+// start begins an operation, returning the context to run it with and the function
+// that finishes it
+func (is *InstrumentedStore) start(ctx context.Context, operation, key string) (context.Context, func(err error)) {
+	var endSpan func(error)
+	if is.config.StartSpan != nil {
+		ctx, endSpan = is.config.StartSpan(ctx, operation, key)
+	}
+	started := time.Now()
+
+	return ctx, func(err error) {
+		op := StoreOperation{
+			Store:     is.config.Name,
+			Operation: operation,
+			Key:       key,
+			Duration:  time.Since(started),
+			Err:       err,
+		}
+		if err != nil && stores.IsNotFound(err) {
+			op.Err = nil
+			op.Miss = true
+		}
+		if endSpan != nil {
+			endSpan(op.Err)
+		}
+		is.record(op)
+	}
+}
+
+// record counts an operation and reports it to the logger and observer
+func (is *InstrumentedStore) record(op StoreOperation) {
+	slow := is.config.SlowThreshold > 0 && op.Duration >= is.config.SlowThreshold
+
+	is.mu.Lock()
+	s, ok := is.stats[op.Operation]
+	if !ok {
+		s = &StoreOperationStats{}
+		is.stats[op.Operation] = s
+	}
+	s.Calls++
+	s.TotalLatency += op.Duration
+	if op.Duration > s.MaxLatency {
+		s.MaxLatency = op.Duration
+	}
+	if op.Err != nil {
+		s.Errors++
+	}
+	if op.Miss {
+		s.Misses++
+	}
+	if slow {
+		s.Slow++
+	}
+	is.mu.Unlock()
+
+	if slow {
+		is.config.Logger.Warn("Slow store operation",
+			Field{"store", op.Store},
+			Field{"operation", op.Operation},
+			Field{"key", op.Key},
+			Field{"duration", op.Duration.String()},
+		)
+	}
+	if op.Err != nil && is.config.LogErrors {
+		is.config.Logger.Error("Store operation failed",
+			Field{"store", op.Store},
+			Field{"operation", op.Operation},
+			Field{"key", op.Key},
+			Field{"error", op.Err.Error()},
+		)
+	}
+	if is.config.Observe != nil {
+		is.config.Observe(op)
+	}
+}
+
+func (is *InstrumentedStore) Get(ctx context.Context, key string) ([]byte, error) {
+	ctx, done := is.start(ctx, "get", key)
+	value, err := is.store.Get(ctx, key)
+	done(err)
+	return value, err
+}
+
+func (is *InstrumentedStore) Set(ctx context.Context, key string, value []byte, expiration time.Duration) error {
+	ctx, done := is.start(ctx, "set", key)
+	err := is.store.Set(ctx, key, value, expiration)
+	done(err)
+	return err
+}
+
+func (is *InstrumentedStore) Increment(ctx context.Context, key string, expiration time.Duration) (int64, error) {
+	ctx, done := is.start(ctx, "increment", key)
+	n, err := is.store.Increment(ctx, key, expiration)
+	done(err)
+	return n, err
+}
+
+func (is *InstrumentedStore) IncrementBy(ctx context.Context, key string, amount int64, expiration time.Duration) (int64, error) {
+	ctx, done := is.start(ctx, "increment_by", key)
+	n, err := is.store.IncrementBy(ctx, key, amount, expiration)
+	done(err)
+	return n, err
+}
+
+func (is *InstrumentedStore) Delete(ctx context.Context, key string) error {
+	ctx, done := is.start(ctx, "delete", key)
+	err := is.store.Delete(ctx, key)
+	done(err)
+	return err
+}
+
+func (is *InstrumentedStore) Exists(ctx context.Context, key string) (bool, error) {
+	ctx, done := is.start(ctx, "exists", key)
+	exists, err := is.store.Exists(ctx, key)
+	done(err)
+	return exists, err
+}
+
+func (is *InstrumentedStore) Health(ctx context.Context) error {
+	ctx, done := is.start(ctx, "health", "")
+	err := is.store.Health(ctx)
+	done(err)
+	return err
+}
+
+func (is *InstrumentedStore) Close() error {
+	return is.store.Close()
+}
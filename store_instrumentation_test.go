@@ -0,0 +1,143 @@
+// store_instrumentation_test.go
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/itsatony/gorly/stores"
+)
+
+// slowStore delays every operation of a store and can fail them
+type slowStore struct {
+	Store
+	delay time.Duration
+	fail  error
+}
+
+func (s *slowStore) Set(ctx context.Context, key string, value []byte, expiration time.Duration) error {
+	time.Sleep(s.delay)
+	if s.fail != nil {
+		return s.fail
+	}
+	return s.Store.Set(ctx, key, value, expiration)
+}
+
+// recordingLogger remembers the messages it was given
+type recordingLogger struct {
+	mu       sync.Mutex
+	messages []string
+}
+
+func (rl *recordingLogger) add(msg string) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	rl.messages = append(rl.messages, msg)
+}
+
+func (rl *recordingLogger) Debug(msg string, fields ...Field) { rl.add(msg) }
+func (rl *recordingLogger) Info(msg string, fields ...Field)  { rl.add(msg) }
+func (rl *recordingLogger) Warn(msg string, fields ...Field)  { rl.add(msg) }
+func (rl *recordingLogger) Error(msg string, fields ...Field) { rl.add(msg) }
+
+func newInstrumentationTestStore(t *testing.T) Store {
+	store, err := stores.NewMemoryStore(stores.MemoryConfig{MaxKeys: 100, CleanupInterval: time.Minute, DefaultTTL: time.Minute})
+	if err != nil {
+		t.Fatalf("Failed to create memory store: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func TestInstrumentStore(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("counts operations, misses and errors", func(t *testing.T) {
+		var observed []StoreOperation
+		store := InstrumentStore(newInstrumentationTestStore(t), &StoreInstrumentation{
+			Name:    "memory",
+			Observe: func(op StoreOperation) { observed = append(observed, op) },
+		})
+
+		if err := store.Set(ctx, "a", []byte("1"), time.Minute); err != nil {
+			t.Fatalf("Set failed: %v", err)
+		}
+		if _, err := store.Get(ctx, "a"); err != nil {
+			t.Fatalf("Get failed: %v", err)
+		}
+		if _, err := store.Get(ctx, "missing"); err == nil {
+			t.Fatal("Expected an error for a missing key")
+		}
+		if _, err := store.IncrementBy(ctx, "n", 3, time.Minute); err != nil {
+			t.Fatalf("IncrementBy failed: %v", err)
+		}
+
+		stats := store.Stats()
+		if stats["get"].Calls != 2 || stats["get"].Misses != 1 || stats["get"].Errors != 0 {
+			t.Errorf("Expected 2 gets with 1 miss and no errors, got %+v", stats["get"])
+		}
+		if stats["set"].Calls != 1 || stats["increment_by"].Calls != 1 {
+			t.Errorf("Expected one set and one increment_by, got %+v", stats)
+		}
+		if len(observed) != 4 || observed[0].Store != "memory" || observed[0].Operation != "set" || observed[0].Key != "a" {
+			t.Errorf("Unexpected observations: %+v", observed)
+		}
+		if !observed[2].Miss || observed[2].Err != nil {
+			t.Errorf("Expected the missing key to be observed as a miss, got %+v", observed[2])
+		}
+		if ops := store.Operations(); len(ops) != 3 || ops[0] != "get" {
+			t.Errorf("Expected sorted operations [get increment_by set], got %v", ops)
+		}
+	})
+
+	t.Run("logs slow and failed operations", func(t *testing.T) {
+		logger := &recordingLogger{}
+		failure := errors.New("connection reset")
+		store := InstrumentStore(&slowStore{Store: newInstrumentationTestStore(t), delay: 5 * time.Millisecond, fail: failure},
+			&StoreInstrumentation{Logger: logger, SlowThreshold: time.Millisecond, LogErrors: true})
+
+		if err := store.Set(ctx, "a", []byte("1"), time.Minute); !errors.Is(err, failure) {
+			t.Fatalf("Expected the store error to be returned, got %v", err)
+		}
+
+		stats := store.Stats()["set"]
+		if stats.Errors != 1 || stats.Slow != 1 || stats.MaxLatency < 5*time.Millisecond {
+			t.Errorf("Expected one slow failed set, got %+v", stats)
+		}
+		if len(logger.messages) != 2 || logger.messages[0] != "Slow store operation" || logger.messages[1] != "Store operation failed" {
+			t.Errorf("Expected slow and failure log messages, got %v", logger.messages)
+		}
+	})
+
+	t.Run("traces operations", func(t *testing.T) {
+		type spanKey struct{}
+		var spans []string
+		var spanErrs []error
+		inner := newInstrumentationTestStore(t)
+		store := InstrumentStore(inner, &StoreInstrumentation{
+			StartSpan: func(ctx context.Context, operation, key string) (context.Context, func(error)) {
+				spans = append(spans, operation+" "+key)
+				return context.WithValue(ctx, spanKey{}, operation), func(err error) { spanErrs = append(spanErrs, err) }
+			},
+		})
+
+		store.Exists(ctx, "a")
+		store.Get(ctx, "a")
+		store.Health(ctx)
+
+		if len(spans) != 3 || spans[0] != "exists a" || spans[2] != "health " {
+			t.Errorf("Unexpected spans: %v", spans)
+		}
+		for i, err := range spanErrs {
+			if err != nil {
+				t.Errorf("Span %d ended with %v, misses are not errors", i, err)
+			}
+		}
+		if store.Unwrap() != inner {
+			t.Error("Expected Unwrap to return the wrapped store")
+		}
+	})
+}
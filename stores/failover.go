@@ -0,0 +1,550 @@
+// stores/failover.go
+package stores
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// FailoverConfig configures a primary store with a warm standby
+type FailoverConfig struct {
+	FailureThreshold    int           `yaml:"failure_threshold" json:"failure_threshold" mapstructure:"failure_threshold"`             // Consecutive primary errors before failing over
+	HealthCheckInterval time.Duration `yaml:"health_check_interval" json:"health_check_interval" mapstructure:"health_check_interval"` // How often the failed primary is probed
+	ReplicationBuffer   int           `yaml:"replication_buffer" json:"replication_buffer" mapstructure:"replication_buffer"`          // Writes queued for the standby before new ones are dropped
+	ReconcileTTL        time.Duration `yaml:"reconcile_ttl" json:"reconcile_ttl" mapstructure:"reconcile_ttl"`                         // Expiration of reconciled keys whose TTL the standby cannot report
+}
+
+// FailoverStats describes the state of a failover store
+type FailoverStats struct {
+	FailedOver         bool
+	Failovers          int64 // Switches from the primary to the standby
+	Failbacks          int64 // Switches back to the primary after reconciliation
+	Replicated         int64 // Writes copied to the standby
+	ReplicationDropped int64 // Writes not copied because the replication buffer was full
+	ReplicationErrors  int64 // Writes the standby rejected
+	Reconciled         int64 // Keys copied back to the primary before failing back
+}
+
+// Kinds of replicated writes; counters are copied with IncrementBy, since stores encode them differently
+const (
+	replicateValue = iota
+	replicateCounter
+	replicateDelete
+)
+
+// replication is a write copied to the standby
+type replication struct {
+	epoch      int64
+	kind       int
+	key        string
+	value      []byte
+	counter    int64
+	expiration time.Duration
+}
+
+// ttlReporter is implemented by stores that report the remaining lifetime of a key
+type ttlReporter interface {
+	TTL(ctx context.Context, key string) (time.Duration, error)
+}
+
+// FailoverStore serves every operation from a primary store, such as Redis, and keeps a
+// standby (a second Redis or a memory store) warm by copying successful writes to it in
+// the background. Concurrent writes of one key may reach the standby out of order, so it
+// trails the primary slightly even when the buffer keeps up. After FailureThreshold
+// consecutive primary errors it switches to the standby. While failed over the primary is
+// probed; once it responds, the keys written on the standby are copied back and operations
+// return to the primary.
+type FailoverStore struct {
+	primary ShardBackend
+	standby ShardBackend
+	config  FailoverConfig
+
+	mu         sync.RWMutex // Held for reading by operations, for writing to switch stores
+	failedOver bool
+	failures   atomic.Int64 // Consecutive primary errors
+	epoch      atomic.Int64 // Incremented on every switch; queued writes from earlier epochs are dropped
+
+	dirtyMu sync.Mutex
+	dirty   map[string]int // Keys written on the standby while failed over, by kind
+
+	queue     chan replication
+	stop      chan struct{}
+	done      sync.WaitGroup
+	closeOnce sync.Once
+
+	failovers          atomic.Int64
+	failbacks          atomic.Int64
+	replicated         atomic.Int64
+	replicationDropped atomic.Int64
+	replicationErrors  atomic.Int64
+	reconciled         atomic.Int64
+}
+
+// NewFailoverStore creates a store that fails over from primary to standby
+func NewFailoverStore(config FailoverConfig, primary, standby ShardBackend) (*FailoverStore, error) {
+	if primary == nil || standby == nil {
+		return nil, NewStoreError("config", "a primary and a standby store are required", nil)
+	}
+
+	// Set defaults
+	if config.FailureThreshold <= 0 {
+		config.FailureThreshold = 3
+	}
+	if config.HealthCheckInterval <= 0 {
+		config.HealthCheckInterval = 5 * time.Second
+	}
+	if config.ReplicationBuffer <= 0 {
+		config.ReplicationBuffer = 10000
+	}
+	if config.ReconcileTTL <= 0 {
+		config.ReconcileTTL = time.Hour
+	}
+
+	store := &FailoverStore{
+		primary: primary,
+		standby: standby,
+		config:  config,
+		dirty:   make(map[string]int),
+		queue:   make(chan replication, config.ReplicationBuffer),
+		stop:    make(chan struct{}),
+	}
+	store.done.Add(2)
+	go store.replicateLoop()
+	go store.healthLoop()
+
+	return store, nil
+}
+
+// FailedOver reports whether operations are served by the standby
+func (s *FailoverStore) FailedOver() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.failedOver
+}
+
+// active returns the store serving operations; the caller holds s.mu for reading
+func (s *FailoverStore) active() ShardBackend {
+	if s.failedOver {
+		return s.standby
+	}
+	return s.primary
+}
+
+// do runs an operation on the active store. A primary error that reaches the failure
+// threshold fails over and runs the operation again on the standby. written returns the
+// writes of a successful operation: they are copied to the standby, or reconciled later
+// when the standby served them.
+func (s *FailoverStore) do(op func(ShardBackend) error, written func() []replication) error {
+	s.mu.RLock()
+	if s.failedOver {
+		err := op(s.standby)
+		if err == nil {
+			s.markDirty(written)
+		}
+		s.mu.RUnlock()
+		return err
+	}
+	epoch := s.epoch.Load()
+	err := op(s.primary)
+	s.mu.RUnlock()
+
+	if !s.record(err) {
+		if err == nil {
+			s.replicate(epoch, written)
+		}
+		return err
+	}
+	// The primary failed over; the standby serves this operation too
+	return s.do(op, written)
+}
+
+// record counts consecutive primary errors and reports whether the store failed over
+func (s *FailoverStore) record(err error) bool {
+	if err == nil || IsNotFound(err) {
+		if s.failures.Load() != 0 {
+			s.failures.Store(0)
+		}
+		return false
+	}
+	if s.failures.Add(1) < int64(s.config.FailureThreshold) {
+		return false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.failedOver {
+		s.failedOver = true
+		s.epoch.Add(1)
+		s.failovers.Add(1)
+	}
+	s.failures.Store(0)
+	return true
+}
+
+// replicate queues the writes of an operation on the primary for the standby without
+// blocking the caller
+func (s *FailoverStore) replicate(epoch int64, written func() []replication) {
+	if written == nil {
+		return
+	}
+	for _, r := range written() {
+		r.epoch = epoch
+		select {
+		case s.queue <- r:
+		default:
+			s.replicationDropped.Add(1)
+		}
+	}
+}
+
+// markDirty remembers keys written on the standby so they are copied back before failing
+// back; the caller holds s.mu for reading, so a failback cannot miss them
+func (s *FailoverStore) markDirty(written func() []replication) {
+	if written == nil {
+		return
+	}
+	s.dirtyMu.Lock()
+	for _, r := range written() {
+		s.dirty[r.key] = r.kind
+	}
+	s.dirtyMu.Unlock()
+}
+
+// write returns the single write of an operation, or none if it changed nothing
+func write(changed bool, r replication) []replication {
+	if !changed {
+		return nil
+	}
+	return []replication{r}
+}
+
+// replicateLoop applies queued writes to the standby in order
+func (s *FailoverStore) replicateLoop() {
+	defer s.done.Done()
+	for {
+		select {
+		case r := <-s.queue:
+			s.applyReplication(r)
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// applyReplication copies a write to the standby. Writes queued before a switch are
+// dropped: they would overwrite what the standby has served since.
+func (s *FailoverStore) applyReplication(r replication) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if r.epoch != s.epoch.Load() || s.failedOver {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.config.HealthCheckInterval)
+	defer cancel()
+	if err := s.apply(ctx, s.standby, r); err != nil {
+		s.replicationErrors.Add(1)
+		return
+	}
+	s.replicated.Add(1)
+}
+
+// apply writes a replicated key to a store
+func (s *FailoverStore) apply(ctx context.Context, store ShardBackend, r replication) error {
+	switch r.kind {
+	case replicateCounter:
+		if err := store.Delete(ctx, r.key); err != nil {
+			return err
+		}
+		_, err := store.IncrementBy(ctx, r.key, r.counter, r.expiration)
+		return err
+	case replicateDelete:
+		return store.Delete(ctx, r.key)
+	default:
+		return store.Set(ctx, r.key, r.value, r.expiration)
+	}
+}
+
+// healthLoop probes the primary while failed over and fails back once it responds
+func (s *FailoverStore) healthLoop() {
+	defer s.done.Done()
+	ticker := time.NewTicker(s.config.HealthCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if !s.FailedOver() {
+				continue
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), s.config.HealthCheckInterval)
+			if s.primary.Health(ctx) == nil {
+				s.failback(ctx)
+			}
+			cancel()
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// failback copies the keys written on the standby to the primary and switches back.
+// Keys are copied while the standby keeps serving; the last ones are copied with
+// operations paused, so no write is lost in between.
+func (s *FailoverStore) failback(ctx context.Context) {
+	if err := s.reconcile(ctx); err != nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.reconcile(ctx); err != nil {
+		return
+	}
+	s.failedOver = false
+	s.failures.Store(0)
+	s.epoch.Add(1)
+	s.failbacks.Add(1)
+}
+
+// reconcile copies every dirty key from the standby to the primary
+func (s *FailoverStore) reconcile(ctx context.Context) error {
+	s.dirtyMu.Lock()
+	dirty := s.dirty
+	s.dirty = make(map[string]int)
+	s.dirtyMu.Unlock()
+
+	for key, kind := range dirty {
+		if err := s.reconcileKey(ctx, key, kind); err != nil {
+			// Keep what was not copied for the next attempt, unless it was written again since
+			s.dirtyMu.Lock()
+			for key, kind := range dirty {
+				if _, ok := s.dirty[key]; !ok {
+					s.dirty[key] = kind
+				}
+			}
+			s.dirtyMu.Unlock()
+			return err
+		}
+		delete(dirty, key)
+		s.reconciled.Add(1)
+	}
+	return nil
+}
+
+// reconcileKey copies the standby's state of a key to the primary
+func (s *FailoverStore) reconcileKey(ctx context.Context, key string, kind int) error {
+	r := replication{kind: kind, key: key, expiration: s.config.ReconcileTTL}
+	if reporter, ok := s.standby.(ttlReporter); ok {
+		ttl, err := reporter.TTL(ctx, key)
+		if err != nil {
+			return err
+		}
+		if ttl > 0 {
+			r.expiration = ttl
+		}
+	}
+
+	switch kind {
+	case replicateCounter:
+		exists, err := s.standby.Exists(ctx, key)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			r.kind = replicateDelete
+			break
+		}
+		// Incrementing by zero reads a counter atomically without changing its expiration
+		if r.counter, err = s.standby.IncrementBy(ctx, key, 0, 0); err != nil {
+			return err
+		}
+	case replicateValue:
+		value, err := s.standby.Get(ctx, key)
+		if IsNotFound(err) {
+			r.kind = replicateDelete
+			break
+		}
+		if err != nil {
+			return err
+		}
+		r.value = value
+	}
+	return s.apply(ctx, s.primary, r)
+}
+
+// Get retrieves a value from the active store
+func (s *FailoverStore) Get(ctx context.Context, key string) ([]byte, error) {
+	var value []byte
+	err := s.do(func(store ShardBackend) (err error) {
+		value, err = store.Get(ctx, key)
+		return err
+	}, nil)
+	return value, err
+}
+
+// Set stores a value in the active store
+func (s *FailoverStore) Set(ctx context.Context, key string, value []byte, expiration time.Duration) error {
+	return s.do(func(store ShardBackend) error {
+		return store.Set(ctx, key, value, expiration)
+	}, func() []replication {
+		return write(true, replication{kind: replicateValue, key: key, value: value, expiration: expiration})
+	})
+}
+
+// Increment atomically increments a counter in the active store
+func (s *FailoverStore) Increment(ctx context.Context, key string, expiration time.Duration) (int64, error) {
+	return s.IncrementBy(ctx, key, 1, expiration)
+}
+
+// IncrementBy atomically increments a counter by the given amount in the active store
+func (s *FailoverStore) IncrementBy(ctx context.Context, key string, amount int64, expiration time.Duration) (int64, error) {
+	var counter int64
+	err := s.do(func(store ShardBackend) (err error) {
+		counter, err = store.IncrementBy(ctx, key, amount, expiration)
+		return err
+	}, func() []replication {
+		// Incrementing by zero is a read
+		return write(amount != 0, replication{kind: replicateCounter, key: key, counter: counter, expiration: expiration})
+	})
+	return counter, err
+}
+
+// Delete removes a key from the active store
+func (s *FailoverStore) Delete(ctx context.Context, key string) error {
+	return s.do(func(store ShardBackend) error {
+		return store.Delete(ctx, key)
+	}, func() []replication {
+		return write(true, replication{kind: replicateDelete, key: key})
+	})
+}
+
+// Exists checks if a key exists in the active store
+func (s *FailoverStore) Exists(ctx context.Context, key string) (bool, error) {
+	var exists bool
+	err := s.do(func(store ShardBackend) (err error) {
+		exists, err = store.Exists(ctx, key)
+		return err
+	}, nil)
+	return exists, err
+}
+
+// SetNX stores a value in the active store only if the key does not exist
+func (s *FailoverStore) SetNX(ctx context.Context, key string, value []byte, expiration time.Duration) (bool, error) {
+	var stored bool
+	err := s.do(func(store ShardBackend) (err error) {
+		stored, err = store.SetNX(ctx, key, value, expiration)
+		return err
+	}, func() []replication {
+		return write(stored, replication{kind: replicateValue, key: key, value: value, expiration: expiration})
+	})
+	return stored, err
+}
+
+// CompareAndExpire resets the expiration of a key in the active store only if it holds value
+func (s *FailoverStore) CompareAndExpire(ctx context.Context, key string, value []byte, expiration time.Duration) (bool, error) {
+	var refreshed bool
+	err := s.do(func(store ShardBackend) (err error) {
+		refreshed, err = store.CompareAndExpire(ctx, key, value, expiration)
+		return err
+	}, func() []replication {
+		return write(refreshed, replication{kind: replicateValue, key: key, value: value, expiration: expiration})
+	})
+	return refreshed, err
+}
+
+// CompareAndDelete removes a key from the active store only if it holds value
+func (s *FailoverStore) CompareAndDelete(ctx context.Context, key string, value []byte) (bool, error) {
+	var deleted bool
+	err := s.do(func(store ShardBackend) (err error) {
+		deleted, err = store.CompareAndDelete(ctx, key, value)
+		return err
+	}, func() []replication {
+		return write(deleted, replication{kind: replicateDelete, key: key})
+	})
+	return deleted, err
+}
+
+// CompareAndSwapMulti writes every swap to the active store only if every key still holds
+// its Old value; both stores must support multi-key swaps
+func (s *FailoverStore) CompareAndSwapMulti(ctx context.Context, swaps []Swap) (bool, error) {
+	var swapped bool
+	err := s.do(func(store ShardBackend) (err error) {
+		swapper, ok := store.(interface {
+			CompareAndSwapMulti(ctx context.Context, swaps []Swap) (bool, error)
+		})
+		if !ok {
+			return NewStoreError("config", "store does not support multi-key swaps", nil)
+		}
+		swapped, err = swapper.CompareAndSwapMulti(ctx, swaps)
+		return err
+	}, func() []replication {
+		if !swapped {
+			return nil
+		}
+		writes := make([]replication, len(swaps))
+		for i, swap := range swaps {
+			writes[i] = replication{kind: replicateValue, key: swap.Key, value: swap.Value, expiration: swap.Expiration}
+		}
+		return writes
+	})
+	return swapped, err
+}
+
+// Health reports whether the active store can serve requests
+func (s *FailoverStore) Health(ctx context.Context) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.active().Health(ctx)
+}
+
+// Time returns the clock of the active store, for stores that report one
+func (s *FailoverStore) Time(ctx context.Context) (time.Time, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	clock, ok := s.active().(interface {
+		Time(ctx context.Context) (time.Time, error)
+	})
+	if !ok {
+		return time.Time{}, NewStoreError("config", "the active store does not report a clock", nil)
+	}
+	return clock.Time(ctx)
+}
+
+// PoolStats returns the connection pool statistics of the primary, if it is backed by Redis
+func (s *FailoverStore) PoolStats() PoolStats {
+	if pooled, ok := s.primary.(interface{ PoolStats() PoolStats }); ok {
+		return pooled.PoolStats()
+	}
+	return PoolStats{}
+}
+
+// FailoverStats returns the failover state and replication counts
+func (s *FailoverStore) FailoverStats() FailoverStats {
+	return FailoverStats{
+		FailedOver:         s.FailedOver(),
+		Failovers:          s.failovers.Load(),
+		Failbacks:          s.failbacks.Load(),
+		Replicated:         s.replicated.Load(),
+		ReplicationDropped: s.replicationDropped.Load(),
+		ReplicationErrors:  s.replicationErrors.Load(),
+		Reconciled:         s.reconciled.Load(),
+	}
+}
+
+// Close stops replication and health checking and closes both stores
+func (s *FailoverStore) Close() error {
+	var firstErr error
+	s.closeOnce.Do(func() {
+		close(s.stop)
+		s.done.Wait()
+		for _, store := range []ShardBackend{s.primary, s.standby} {
+			if err := store.Close(); err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+	})
+	return firstErr
+}
@@ -0,0 +1,117 @@
+// stores/failover_test.go
+package stores
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func newFailoverTestStore(t *testing.T) (*FailoverStore, *flakyBackend, *MemoryStore) {
+	t.Helper()
+
+	primaryMem, err := NewMemoryStore(MemoryConfig{CleanupInterval: time.Minute})
+	if err != nil {
+		t.Fatalf("Failed to create memory store: %v", err)
+	}
+	standby, err := NewMemoryStore(MemoryConfig{CleanupInterval: time.Minute})
+	if err != nil {
+		t.Fatalf("Failed to create memory store: %v", err)
+	}
+	primary := &flakyBackend{MemoryStore: primaryMem}
+
+	store, err := NewFailoverStore(FailoverConfig{
+		FailureThreshold:    2,
+		HealthCheckInterval: 10 * time.Millisecond,
+	}, primary, standby)
+	if err != nil {
+		t.Fatalf("Failed to create failover store: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store, primary, standby
+}
+
+// eventually waits up to a second for cond to hold
+func eventually(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestFailoverStore_Replication(t *testing.T) {
+	store, _, standby := newFailoverTestStore(t)
+	ctx := context.Background()
+
+	store.Set(ctx, "state", []byte("v1"), time.Minute)
+	store.IncrementBy(ctx, "counter", 5, time.Minute)
+	store.Set(ctx, "gone", []byte("x"), time.Minute)
+	store.Delete(ctx, "gone")
+
+	eventually(t, "writes to reach the standby", func() bool {
+		return store.FailoverStats().Replicated == 4
+	})
+	if value, _ := standby.Get(ctx, "state"); string(value) != "v1" {
+		t.Errorf("Expected the standby to hold the value, got %q", value)
+	}
+	if counter, _ := standby.IncrementBy(ctx, "counter", 0, 0); counter != 5 {
+		t.Errorf("Expected the standby to hold the counter, got %d", counter)
+	}
+	if exists, _ := standby.Exists(ctx, "gone"); exists {
+		t.Error("Expected the delete to be replicated")
+	}
+}
+
+func TestFailoverStore_FailoverAndFailback(t *testing.T) {
+	store, primary, standby := newFailoverTestStore(t)
+	ctx := context.Background()
+
+	store.Set(ctx, "state", []byte("before"), time.Minute)
+	eventually(t, "the standby to be warm", func() bool {
+		value, _ := standby.Get(ctx, "state")
+		return string(value) == "before"
+	})
+
+	// The first error is returned, the second fails over and is served by the standby
+	primary.setDown(true)
+	if _, err := store.Get(ctx, "state"); err == nil {
+		t.Fatal("Expected the first primary error to be returned")
+	}
+	value, err := store.Get(ctx, "state")
+	if err != nil || string(value) != "before" || !store.FailedOver() {
+		t.Fatalf("Expected the standby to serve after failing over, got %q, %v", value, err)
+	}
+	if err := store.Set(ctx, "state", []byte("during"), time.Minute); err != nil {
+		t.Fatalf("Expected writes to go to the standby, got %v", err)
+	}
+
+	// Once the primary is back, the standby's writes are copied to it before failing back
+	primary.setDown(false)
+	eventually(t, "the failback", func() bool { return !store.FailedOver() })
+	if value, _ := primary.MemoryStore.Get(ctx, "state"); string(value) != "during" {
+		t.Errorf("Expected the primary to be reconciled, got %q", value)
+	}
+	if ttl, _ := primary.MemoryStore.TTL(ctx, "state"); ttl <= 0 || ttl > time.Minute {
+		t.Errorf("Expected the reconciled key to keep its expiration, got %v", ttl)
+	}
+
+	stats := store.FailoverStats()
+	if stats.Failovers != 1 || stats.Failbacks != 1 || stats.Reconciled != 1 {
+		t.Errorf("Unexpected failover stats: %+v", stats)
+	}
+}
+
+func TestFailoverStore_RequiresBothStores(t *testing.T) {
+	mem, err := NewMemoryStore(MemoryConfig{CleanupInterval: time.Minute})
+	if err != nil {
+		t.Fatalf("Failed to create memory store: %v", err)
+	}
+	defer mem.Close()
+	if _, err := NewFailoverStore(FailoverConfig{}, mem, nil); err == nil {
+		t.Error("Expected an error without a standby")
+	}
+}
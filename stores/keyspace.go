@@ -0,0 +1,142 @@
+// stores/keyspace.go
+package stores
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ExpiryNotificationFlags are the notify-keyspace-events flags WatchExpiries needs:
+// keyspace events (K) for expired keys (x)
+const ExpiryNotificationFlags = "Kx"
+
+// expiryResubscribeDelay is how long a watch waits before subscribing again after its
+// subscription ended, e.g. because rotated secrets replaced the client
+const expiryResubscribeDelay = time.Second
+
+// ErrNotificationsUnsupported is returned by WatchExpiries of stores without keyspace notifications
+var ErrNotificationsUnsupported = NewStoreError("config", "store does not publish keyspace notifications", nil)
+
+// ExpiryWatcher is implemented by stores that report keys expiring on their own
+type ExpiryWatcher interface {
+	// WatchExpiries calls onExpire with every expired key starting with prefix until ctx
+	// is done. With configure it enables the notifications the store needs first.
+	WatchExpiries(ctx context.Context, prefix string, configure bool, onExpire func(key string)) error
+}
+
+// WatchExpiries subscribes to the keyspace notifications of expired keys starting with
+// prefix and calls onExpire with each key, from a background goroutine, until ctx is done.
+// Redis only publishes them when notify-keyspace-events includes ExpiryNotificationFlags;
+// configure adds the flags with CONFIG SET, which managed Redis services often forbid, so
+// they can be set in the server configuration instead. Notifications are fire and forget:
+// expiries while the subscription is down are not reported.
+func (r *RedisStore) WatchExpiries(ctx context.Context, prefix string, configure bool, onExpire func(key string)) error {
+	if configure {
+		if err := r.enableExpiryNotifications(ctx); err != nil {
+			return err
+		}
+	}
+
+	channelPrefix := fmt.Sprintf("__keyspace@%d__:", r.config.Database)
+	pattern := channelPrefix + escapeGlob(prefix) + "*"
+	pubsub := r.client().PSubscribe(ctx, pattern)
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return NewStoreError("notifications", "failed to subscribe to keyspace notifications", err)
+	}
+
+	go func() {
+		defer func() { pubsub.Close() }()
+		for {
+			messages := pubsub.Channel()
+			for open := true; open; {
+				select {
+				case <-ctx.Done():
+					return
+				case msg, ok := <-messages:
+					if !ok {
+						open = false
+					} else if msg.Payload == "expired" {
+						onExpire(strings.TrimPrefix(msg.Channel, channelPrefix))
+					}
+				}
+			}
+			pubsub.Close()
+
+			// The channel also closes when rotated secrets close the client
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(expiryResubscribeDelay):
+			}
+			pubsub = r.client().PSubscribe(ctx, pattern)
+		}
+	}()
+	return nil
+}
+
+// enableExpiryNotifications adds ExpiryNotificationFlags to the notify-keyspace-events of
+// the server, keeping the events already enabled
+func (r *RedisStore) enableExpiryNotifications(ctx context.Context) error {
+	current, err := r.client().ConfigGet(ctx, "notify-keyspace-events").Result()
+	if err != nil {
+		return NewStoreError("notifications", "failed to read notify-keyspace-events", err)
+	}
+	flags := current["notify-keyspace-events"]
+	updated := flags
+	for _, flag := range ExpiryNotificationFlags {
+		// "A" stands for every event class, including expired keys
+		if !strings.ContainsRune(updated, flag) && !(flag == 'x' && strings.ContainsRune(updated, 'A')) {
+			updated += string(flag)
+		}
+	}
+	if updated == flags {
+		return nil
+	}
+	if err := r.client().ConfigSet(ctx, "notify-keyspace-events", updated).Err(); err != nil {
+		return NewStoreError("notifications", "failed to enable keyspace notifications", err)
+	}
+	return nil
+}
+
+// escapeGlob escapes the characters Redis patterns treat specially
+func escapeGlob(s string) string {
+	var b strings.Builder
+	for _, c := range s {
+		switch c {
+		case '*', '?', '[', ']', '\\':
+			b.WriteByte('\\')
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+// WatchExpiries watches the expiries of every shard. Shards that cannot be watched fail
+// the call, since their expiries would go unnoticed.
+func (s *ShardedStore) WatchExpiries(ctx context.Context, prefix string, configure bool, onExpire func(key string)) error {
+	var errs []error
+	for i, sh := range s.shards {
+		watcher, ok := sh.backend.(ExpiryWatcher)
+		if !ok {
+			return ErrNotificationsUnsupported
+		}
+		if err := watcher.WatchExpiries(ctx, prefix, configure, onExpire); err != nil {
+			errs = append(errs, fmt.Errorf("shard %d: %w", i, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// WatchExpiries watches the expiries of the primary. Keys expiring on the standby while
+// failed over are not reported.
+func (f *FailoverStore) WatchExpiries(ctx context.Context, prefix string, configure bool, onExpire func(key string)) error {
+	watcher, ok := f.primary.(ExpiryWatcher)
+	if !ok {
+		return ErrNotificationsUnsupported
+	}
+	return watcher.WatchExpiries(ctx, prefix, configure, onExpire)
+}
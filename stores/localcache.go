@@ -0,0 +1,464 @@
+// stores/localcache.go
+package stores
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// LocalCacheConfig configures an in-process cache in front of a shared store
+type LocalCacheConfig struct {
+	SyncInterval time.Duration // How often local writes are written behind and idle keys dropped; default: 100ms
+	Timeout      time.Duration // Timeout of each sync; default: 5s
+}
+
+// LocalCacheStats describes the hits and the drift of a local cache
+type LocalCacheStats struct {
+	Hits        int64   // Operations answered from the cache
+	Misses      int64   // Operations that read the backend
+	HitRatio    float64 // Hits / (Hits + Misses)
+	Keys        int     // Keys in the cache
+	PendingKeys int     // Keys with writes not yet synced
+	Syncs       int64
+	SyncErrors  int64     // Keys whose write-behind failed; they are retried with the next sync
+	LastSync    time.Time // End of the last sync
+	Drift       int64     // Increments by other instances the last sync found in the counters
+	TotalDrift  int64     // Increments by other instances found by every sync
+	MaxDrift    int64     // Largest drift of one counter in one sync
+}
+
+// localEntry is a cached key: a value written with Set, or a counter of base, the value
+// the backend last reported, plus delta, the local increments not written behind yet
+type localEntry struct {
+	absent     bool // The backend had no such key
+	value      []byte
+	counter    bool
+	base       int64
+	delta      int64
+	dirty      bool          // value was written locally and not synced yet
+	expiration time.Duration // TTL of the latest local write, applied when syncing
+	expiresAt  time.Time
+	synced     time.Time // Last read from or write to the backend
+}
+
+// live reports whether the entry is unexpired at now
+func (e *localEntry) live(now time.Time) bool {
+	return e.expiresAt.IsZero() || now.Before(e.expiresAt)
+}
+
+// pending reports whether the entry holds writes the backend has not seen
+func (e *localEntry) pending() bool {
+	return e.dirty || e.delta != 0
+}
+
+// LocalCacheStore keeps hot limiter state in process memory in front of a shared backend
+// such as Redis. Reads of recently synced keys and all counter increments and value writes
+// are answered locally; every SyncInterval the increments are added to the backend's
+// counters and the values written, which reconciles each counter with the increments of
+// other instances. Keys idle for a sync interval are dropped and read again on next use.
+//
+// The price is accuracy: between syncs an instance does not see the traffic of others,
+// so a cluster of n instances can let up to n times the traffic of one sync interval
+// through beyond a limit. Counters merge by addition; values written by Set, such as
+// sliding window logs, are last-writer-wins per sync. Compare-and-set operations, which
+// guard leases and window anchors, bypass the cache. Close writes pending state behind.
+type LocalCacheStore struct {
+	backend ShardBackend
+	config  LocalCacheConfig
+	now     func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*localEntry
+
+	syncMu    sync.Mutex // Serializes syncs
+	stop      chan struct{}
+	done      sync.WaitGroup
+	closeOnce sync.Once
+
+	hits, misses, syncs, syncErrors atomic.Int64
+	drift, totalDrift, maxDrift     atomic.Int64
+	lastSync                        atomic.Int64 // Unix nanoseconds
+}
+
+// NewLocalCacheStore creates a local cache in front of backend and starts syncing it
+func NewLocalCacheStore(backend ShardBackend, config LocalCacheConfig) (*LocalCacheStore, error) {
+	if backend == nil {
+		return nil, NewStoreError("config", "a backend store is required", nil)
+	}
+	if config.SyncInterval <= 0 {
+		config.SyncInterval = 100 * time.Millisecond
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 5 * time.Second
+	}
+
+	store := &LocalCacheStore{
+		backend: backend,
+		config:  config,
+		now:     time.Now,
+		entries: make(map[string]*localEntry),
+		stop:    make(chan struct{}),
+	}
+	store.done.Add(1)
+	go store.syncLoop()
+	return store, nil
+}
+
+// Get returns a value from the cache, or reads it from the backend if the key is not
+// cached or has not been synced for a sync interval. Counters are returned as decimal
+// strings, as Redis returns them.
+func (c *LocalCacheStore) Get(ctx context.Context, key string) ([]byte, error) {
+	entry, err := c.entry(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if entry.absent || !entry.live(c.now()) {
+		return nil, NewStoreError("store", "key not found", nil)
+	}
+	if entry.counter {
+		return []byte(strconv.FormatInt(entry.base+entry.delta, 10)), nil
+	}
+	value := make([]byte, len(entry.value))
+	copy(value, entry.value)
+	return value, nil
+}
+
+// Set writes a value locally; the backend receives it with the next sync
+func (c *LocalCacheStore) Set(ctx context.Context, key string, value []byte, expiration time.Duration) error {
+	now := c.now()
+	stored := make([]byte, len(value))
+	copy(stored, value)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry := &localEntry{value: stored, dirty: true, expiration: expiration, synced: now}
+	if expiration > 0 {
+		entry.expiresAt = now.Add(expiration)
+	}
+	c.entries[key] = entry
+	return nil
+}
+
+// Increment atomically increments a counter and returns the new value
+func (c *LocalCacheStore) Increment(ctx context.Context, key string, expiration time.Duration) (int64, error) {
+	return c.IncrementBy(ctx, key, 1, expiration)
+}
+
+// IncrementBy increments a counter locally and returns the local view of its value: what
+// the backend reported at the last sync plus the local increments since. A counter that
+// is not cached is read from the backend first.
+func (c *LocalCacheStore) IncrementBy(ctx context.Context, key string, amount int64, expiration time.Duration) (int64, error) {
+	entry, err := c.entry(ctx, key)
+	if err != nil {
+		return 0, err
+	}
+
+	now := c.now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if current, ok := c.entries[key]; ok {
+		// Replaced since it was looked up; count on the newer entry
+		entry = current
+	} else {
+		// Dropped by a sync since it was looked up
+		c.entries[key] = entry
+	}
+	if entry.absent || !entry.live(now) || !entry.counter {
+		// A new counter, like INCRBY on a missing key; the expired one is overwritten
+		// by the sync too, since the backend expired it as well
+		entry = &localEntry{counter: true, synced: now}
+		c.entries[key] = entry
+	}
+	entry.delta += amount
+	if expiration > 0 {
+		entry.expiration = expiration
+		entry.expiresAt = now.Add(expiration)
+	}
+	return entry.base + entry.delta, nil
+}
+
+// entry returns the cached entry of a key, reading the backend on a miss
+func (c *LocalCacheStore) entry(ctx context.Context, key string) (*localEntry, error) {
+	now := c.now()
+	c.mu.Lock()
+	if entry, ok := c.entries[key]; ok && (entry.pending() || now.Sub(entry.synced) < c.config.SyncInterval) {
+		c.mu.Unlock()
+		c.hits.Add(1)
+		return entry, nil
+	}
+	c.mu.Unlock()
+	c.misses.Add(1)
+
+	fresh := &localEntry{synced: now}
+	value, err := c.backend.Get(ctx, key)
+	switch {
+	case IsNotFound(err):
+		fresh.absent = true
+	case err != nil:
+		return nil, err
+	default:
+		if counter, parseErr := strconv.ParseInt(string(value), 10, 64); parseErr == nil {
+			fresh.counter, fresh.base = true, counter
+		} else {
+			fresh.value = value
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if current, ok := c.entries[key]; ok && current.pending() {
+		// Written locally while the backend was read; the local write is newer
+		return current, nil
+	}
+	c.entries[key] = fresh
+	return fresh, nil
+}
+
+// Delete removes a key locally and from the backend
+func (c *LocalCacheStore) Delete(ctx context.Context, key string) error {
+	c.mu.Lock()
+	delete(c.entries, key)
+	c.mu.Unlock()
+	return c.backend.Delete(ctx, key)
+}
+
+// Exists checks if a key exists, in the cache if it holds the key
+func (c *LocalCacheStore) Exists(ctx context.Context, key string) (bool, error) {
+	entry, err := c.entry(ctx, key)
+	if err != nil {
+		return false, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return !entry.absent && entry.live(c.now()), nil
+}
+
+// SetNX stores a value in the backend only if the key does not exist there
+func (c *LocalCacheStore) SetNX(ctx context.Context, key string, value []byte, expiration time.Duration) (bool, error) {
+	if err := c.writeThrough(ctx, key); err != nil {
+		return false, err
+	}
+	return c.backend.SetNX(ctx, key, value, expiration)
+}
+
+// CompareAndExpire resets the expiration of a key in the backend only if it holds value
+func (c *LocalCacheStore) CompareAndExpire(ctx context.Context, key string, value []byte, expiration time.Duration) (bool, error) {
+	if err := c.writeThrough(ctx, key); err != nil {
+		return false, err
+	}
+	return c.backend.CompareAndExpire(ctx, key, value, expiration)
+}
+
+// CompareAndDelete removes a key from the backend only if it holds value
+func (c *LocalCacheStore) CompareAndDelete(ctx context.Context, key string, value []byte) (bool, error) {
+	if err := c.writeThrough(ctx, key); err != nil {
+		return false, err
+	}
+	return c.backend.CompareAndDelete(ctx, key, value)
+}
+
+// writeThrough drops a key from the cache before an operation on the backend, writing
+// its pending local writes first so the operation sees them
+func (c *LocalCacheStore) writeThrough(ctx context.Context, key string) error {
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	delete(c.entries, key)
+	c.mu.Unlock()
+	if !ok || !entry.pending() {
+		return nil
+	}
+	if entry.counter {
+		_, err := c.backend.IncrementBy(ctx, key, entry.delta, entry.expiration)
+		return err
+	}
+	return c.backend.Set(ctx, key, entry.value, entry.expiration)
+}
+
+// Health checks the health of the backend
+func (c *LocalCacheStore) Health(ctx context.Context) error {
+	return c.backend.Health(ctx)
+}
+
+// Time returns the clock of the backend, if it reports one
+func (c *LocalCacheStore) Time(ctx context.Context) (time.Time, error) {
+	timer, ok := c.backend.(interface {
+		Time(ctx context.Context) (time.Time, error)
+	})
+	if !ok {
+		return time.Time{}, NewStoreError("config", "backend does not report its clock", nil)
+	}
+	return timer.Time(ctx)
+}
+
+// WatchExpiries watches the expiries of the backend
+func (c *LocalCacheStore) WatchExpiries(ctx context.Context, prefix string, configure bool, onExpire func(key string)) error {
+	watcher, ok := c.backend.(ExpiryWatcher)
+	if !ok {
+		return ErrNotificationsUnsupported
+	}
+	return watcher.WatchExpiries(ctx, prefix, configure, onExpire)
+}
+
+// Backend returns the store the cache syncs with
+func (c *LocalCacheStore) Backend() ShardBackend {
+	return c.backend
+}
+
+// PoolStats returns the connection pool statistics of the backend
+func (c *LocalCacheStore) PoolStats() PoolStats {
+	if pooled, ok := c.backend.(interface{ PoolStats() PoolStats }); ok {
+		return pooled.PoolStats()
+	}
+	return PoolStats{}
+}
+
+// Sync writes the pending local writes behind, reconciles the synced counters with the
+// backend and drops the keys idle since the last sync. It runs every SyncInterval.
+func (c *LocalCacheStore) Sync(ctx context.Context) error {
+	c.syncMu.Lock()
+	defer c.syncMu.Unlock()
+
+	type write struct {
+		key   string
+		entry *localEntry
+		delta int64
+		value []byte
+	}
+	now := c.now()
+	var writes []write
+	c.mu.Lock()
+	for key, entry := range c.entries {
+		switch {
+		case entry.pending():
+			w := write{key: key, entry: entry, delta: entry.delta, value: entry.value}
+			// The written increments move into base, so the local view is unchanged
+			entry.base += entry.delta
+			entry.delta = 0
+			entry.dirty = false
+			writes = append(writes, w)
+		case !entry.live(now) || now.Sub(entry.synced) >= c.config.SyncInterval:
+			delete(c.entries, key)
+		}
+	}
+	c.mu.Unlock()
+
+	var firstErr error
+	var drift int64
+	for _, w := range writes {
+		var total int64
+		var err error
+		if w.entry.counter {
+			total, err = c.backend.IncrementBy(ctx, w.key, w.delta, w.entry.expiration)
+		} else {
+			err = c.backend.Set(ctx, w.key, w.value, w.entry.expiration)
+		}
+
+		c.mu.Lock()
+		current := c.entries[w.key] == w.entry
+		switch {
+		case err != nil:
+			c.syncErrors.Add(1)
+			if firstErr == nil {
+				firstErr = err
+			}
+			if current && w.entry.counter {
+				w.entry.base -= w.delta
+				w.entry.delta += w.delta
+			} else if current && w.entry.value != nil {
+				w.entry.dirty = true
+			}
+		case current:
+			if w.entry.counter {
+				// Everything beyond the local view was counted by other instances
+				keyDrift := total - w.entry.base
+				w.entry.base = total
+				if keyDrift > 0 {
+					drift += keyDrift
+					for {
+						largest := c.maxDrift.Load()
+						if keyDrift <= largest || c.maxDrift.CompareAndSwap(largest, keyDrift) {
+							break
+						}
+					}
+				}
+			}
+			w.entry.synced = c.now()
+		}
+		c.mu.Unlock()
+	}
+
+	c.syncs.Add(1)
+	c.drift.Store(drift)
+	c.totalDrift.Add(drift)
+	c.lastSync.Store(c.now().UnixNano())
+	return firstErr
+}
+
+// syncLoop syncs every SyncInterval until the store is closed
+func (c *LocalCacheStore) syncLoop() {
+	defer c.done.Done()
+	ticker := time.NewTicker(c.config.SyncInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.stop:
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), c.config.Timeout)
+			c.Sync(ctx)
+			cancel()
+		}
+	}
+}
+
+// CacheStats returns the hits, misses and drift of the cache
+func (c *LocalCacheStore) CacheStats() LocalCacheStats {
+	stats := LocalCacheStats{
+		Hits:       c.hits.Load(),
+		Misses:     c.misses.Load(),
+		Syncs:      c.syncs.Load(),
+		SyncErrors: c.syncErrors.Load(),
+		Drift:      c.drift.Load(),
+		TotalDrift: c.totalDrift.Load(),
+		MaxDrift:   c.maxDrift.Load(),
+	}
+	if total := stats.Hits + stats.Misses; total > 0 {
+		stats.HitRatio = float64(stats.Hits) / float64(total)
+	}
+	if last := c.lastSync.Load(); last > 0 {
+		stats.LastSync = time.Unix(0, last)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats.Keys = len(c.entries)
+	for _, entry := range c.entries {
+		if entry.pending() {
+			stats.PendingKeys++
+		}
+	}
+	return stats
+}
+
+// Close stops syncing, writes the pending local writes behind and closes the backend
+func (c *LocalCacheStore) Close() error {
+	var err error
+	c.closeOnce.Do(func() {
+		close(c.stop)
+		c.done.Wait()
+		ctx, cancel := context.WithTimeout(context.Background(), c.config.Timeout)
+		syncErr := c.Sync(ctx)
+		cancel()
+		err = c.backend.Close()
+		if err == nil {
+			err = syncErr
+		}
+	})
+	return err
+}
@@ -0,0 +1,156 @@
+// stores/localcache_test.go
+package stores
+
+import (
+	"context"
+	"encoding/binary"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// redisLikeBackend returns counters as decimal strings, as Redis does, instead of the
+// binary values of the memory store
+type redisLikeBackend struct {
+	*flakyBackend
+}
+
+func (r *redisLikeBackend) Get(ctx context.Context, key string) ([]byte, error) {
+	value, err := r.flakyBackend.Get(ctx, key)
+	if err == nil && len(value) == 8 {
+		return []byte(strconv.FormatInt(int64(binary.BigEndian.Uint64(value)), 10)), nil
+	}
+	return value, err
+}
+
+// Close leaves the memory store open, as caches sharing it close it too
+func (r *redisLikeBackend) Close() error { return nil }
+
+func newRedisLikeBackend(t *testing.T) *redisLikeBackend {
+	t.Helper()
+	mem, err := NewMemoryStore(MemoryConfig{CleanupInterval: time.Minute})
+	if err != nil {
+		t.Fatalf("Failed to create memory store: %v", err)
+	}
+	t.Cleanup(func() { mem.Close() })
+	return &redisLikeBackend{&flakyBackend{MemoryStore: mem}}
+}
+
+// newLocalCacheTestStore creates a cache that only syncs when the test calls Sync
+func newLocalCacheTestStore(t *testing.T, backend ShardBackend) *LocalCacheStore {
+	t.Helper()
+	cache, err := NewLocalCacheStore(backend, LocalCacheConfig{SyncInterval: time.Hour})
+	if err != nil {
+		t.Fatalf("Failed to create local cache: %v", err)
+	}
+	t.Cleanup(func() { cache.Close() })
+	return cache
+}
+
+func TestLocalCacheStoreDrift(t *testing.T) {
+	backend := newRedisLikeBackend(t)
+	a := newLocalCacheTestStore(t, backend)
+	b := newLocalCacheTestStore(t, backend)
+	ctx := context.Background()
+
+	for i := 1; i <= 3; i++ {
+		if count, err := a.Increment(ctx, "counter", time.Minute); err != nil || count != int64(i) {
+			t.Fatalf("Expected local count %d, got %d (%v)", i, count, err)
+		}
+	}
+	if count, _ := b.IncrementBy(ctx, "counter", 5, time.Minute); count != 5 {
+		t.Errorf("Expected the other instance to count alone before a sync, got %d", count)
+	}
+	if _, err := backend.Get(ctx, "counter"); !IsNotFound(err) {
+		t.Errorf("Expected nothing written behind before a sync, got %v", err)
+	}
+
+	if err := a.Sync(ctx); err != nil {
+		t.Fatalf("Failed to sync: %v", err)
+	}
+	if err := b.Sync(ctx); err != nil {
+		t.Fatalf("Failed to sync: %v", err)
+	}
+	if value, _ := backend.Get(ctx, "counter"); string(value) != "8" {
+		t.Errorf("Expected the increments of both instances to add up, got %q", value)
+	}
+	if value, _ := b.Get(ctx, "counter"); string(value) != "8" {
+		t.Errorf("Expected the second instance to see the first one's increments, got %q", value)
+	}
+	if stats := b.CacheStats(); stats.Drift != 3 || stats.MaxDrift != 3 || stats.Syncs != 1 || stats.PendingKeys != 0 {
+		t.Errorf("Expected a drift of 3, got %+v", stats)
+	}
+
+	// The first instance learns of the second one's increments with its next sync
+	a.Increment(ctx, "counter", time.Minute)
+	a.Sync(ctx)
+	if stats := a.CacheStats(); stats.Drift != 5 || stats.TotalDrift != 5 || stats.Hits != 3 || stats.Misses != 1 || stats.HitRatio != 0.75 {
+		t.Errorf("Expected a drift of 5 and 3 hits of 4, got %+v", stats)
+	}
+}
+
+func TestLocalCacheStoreWriteThrough(t *testing.T) {
+	backend := newRedisLikeBackend(t)
+	cache := newLocalCacheTestStore(t, backend)
+	ctx := context.Background()
+
+	if err := cache.Set(ctx, "lease", []byte("instance-a"), time.Minute); err != nil {
+		t.Fatalf("Failed to set: %v", err)
+	}
+	if deleted, err := cache.CompareAndDelete(ctx, "lease", []byte("instance-a")); err != nil || !deleted {
+		t.Errorf("Expected the pending value to be written before the delete, got %v (%v)", deleted, err)
+	}
+	if exists, _ := cache.Exists(ctx, "lease"); exists {
+		t.Error("Expected the key to be gone")
+	}
+
+	if stored, err := cache.SetNX(ctx, "lease", []byte("instance-b"), time.Minute); err != nil || !stored {
+		t.Errorf("Expected SetNX to write to the backend, got %v (%v)", stored, err)
+	}
+	if value, _ := backend.Get(ctx, "lease"); string(value) != "instance-b" {
+		t.Errorf("Expected the lease in the backend, got %q", value)
+	}
+}
+
+func TestLocalCacheStoreSyncErrors(t *testing.T) {
+	backend := newRedisLikeBackend(t)
+	cache := newLocalCacheTestStore(t, backend)
+	ctx := context.Background()
+
+	cache.Set(ctx, "window", []byte("[1,2,3]"), time.Minute)
+	backend.setDown(true)
+	if err := cache.Sync(ctx); err == nil {
+		t.Fatal("Expected the failed write to be reported")
+	}
+	if stats := cache.CacheStats(); stats.SyncErrors != 1 || stats.PendingKeys != 1 {
+		t.Errorf("Expected the write to stay pending, got %+v", stats)
+	}
+
+	backend.setDown(false)
+	if err := cache.Close(); err != nil {
+		t.Fatalf("Failed to close: %v", err)
+	}
+	if value, _ := backend.MemoryStore.Get(ctx, "window"); string(value) != "[1,2,3]" {
+		t.Errorf("Expected Close to write the pending value behind, got %q", value)
+	}
+}
+
+func TestLocalCacheStoreDropsIdleKeys(t *testing.T) {
+	backend := newRedisLikeBackend(t)
+	cache := newLocalCacheTestStore(t, backend)
+	ctx := context.Background()
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+
+	cache.IncrementBy(ctx, "counter", 2, 0)
+	cache.Sync(ctx)
+	if stats := cache.CacheStats(); stats.Keys != 1 {
+		t.Fatalf("Expected the synced key to stay cached, got %+v", stats)
+	}
+
+	now = now.Add(2 * time.Hour)
+	cache.Sync(ctx)
+	if stats := cache.CacheStats(); stats.Keys != 0 {
+		t.Errorf("Expected the idle key to be dropped, got %+v", stats)
+	}
+}
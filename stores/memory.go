@@ -2,6 +2,7 @@
 package stores
 
 import (
+	"bytes"
 	"context"
 	"sync"
 	"time"
@@ -228,6 +229,76 @@ func (m *MemoryStore) setWithLock(key string, value []byte, expiration time.Dura
 	return nil
 }
 
+// SetNX stores a value only if the key does not exist, reporting whether it was stored
+func (m *MemoryStore) SetNX(ctx context.Context, key string, value []byte, expiration time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if item, exists := m.data[key]; exists && !item.IsExpired() {
+		return false, nil
+	}
+	if err := m.setWithLock(key, value, expiration); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// CompareAndExpire resets the expiration of a key only if it holds value
+func (m *MemoryStore) CompareAndExpire(ctx context.Context, key string, value []byte, expiration time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	item, exists := m.data[key]
+	if !exists || item.IsExpired() || !bytes.Equal(item.Value, value) {
+		return false, nil
+	}
+	if expiration > 0 {
+		item.ExpiresAt = time.Now().Add(expiration)
+	} else {
+		item.ExpiresAt = time.Time{}
+	}
+	return true, nil
+}
+
+// CompareAndDelete removes a key only if it holds value
+func (m *MemoryStore) CompareAndDelete(ctx context.Context, key string, value []byte) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	item, exists := m.data[key]
+	if !exists || item.IsExpired() || !bytes.Equal(item.Value, value) {
+		return false, nil
+	}
+	delete(m.data, key)
+	return true, nil
+}
+
+// CompareAndSwapMulti writes every swap only if every key still holds its Old value,
+// reporting whether they were written
+func (m *MemoryStore) CompareAndSwapMulti(ctx context.Context, swaps []Swap) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, swap := range swaps {
+		item, exists := m.data[swap.Key]
+		if !exists || item.IsExpired() {
+			if swap.Old != nil {
+				return false, nil
+			}
+			continue
+		}
+		if swap.Old == nil || !bytes.Equal(item.Value, swap.Old) {
+			return false, nil
+		}
+	}
+	for _, swap := range swaps {
+		if err := m.setWithLock(swap.Key, swap.Value, swap.Expiration); err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
 // Delete removes a key from memory
 func (m *MemoryStore) Delete(ctx context.Context, key string) error {
 	// Update stats
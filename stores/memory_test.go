@@ -370,6 +370,91 @@ func TestMemoryStore_Delete(t *testing.T) {
 	}
 }
 
+func TestMemoryStore_Lease(t *testing.T) {
+	store, err := NewMemoryStore(MemoryConfig{
+		MaxKeys:         1000,
+		CleanupInterval: time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create memory store: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+	key := "test:lease"
+
+	if stored, err := store.SetNX(ctx, key, []byte("a"), 50*time.Millisecond); err != nil || !stored {
+		t.Fatalf("Expected first SetNX to store, got %v (%v)", stored, err)
+	}
+	if stored, _ := store.SetNX(ctx, key, []byte("b"), time.Minute); stored {
+		t.Fatal("Expected SetNX on an existing key to fail")
+	}
+
+	// Only the holder can refresh or delete
+	if refreshed, _ := store.CompareAndExpire(ctx, key, []byte("b"), time.Minute); refreshed {
+		t.Error("Expected refresh with a different value to fail")
+	}
+	if deleted, _ := store.CompareAndDelete(ctx, key, []byte("b")); deleted {
+		t.Error("Expected delete with a different value to fail")
+	}
+	if refreshed, _ := store.CompareAndExpire(ctx, key, []byte("a"), time.Minute); !refreshed {
+		t.Error("Expected holder to refresh the expiration")
+	}
+	if ttl, _ := store.TTL(ctx, key); ttl < 50*time.Second {
+		t.Errorf("Expected refreshed TTL of about a minute, got %v", ttl)
+	}
+	if deleted, _ := store.CompareAndDelete(ctx, key, []byte("a")); !deleted {
+		t.Error("Expected holder to delete the key")
+	}
+
+	// Expired keys can be taken over
+	store.SetNX(ctx, key, []byte("a"), 10*time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+	if refreshed, _ := store.CompareAndExpire(ctx, key, []byte("a"), time.Minute); refreshed {
+		t.Error("Expected refresh of an expired key to fail")
+	}
+	if stored, _ := store.SetNX(ctx, key, []byte("b"), time.Minute); !stored {
+		t.Error("Expected SetNX to take over an expired key")
+	}
+}
+
+func TestMemoryStore_CompareAndSwapMulti(t *testing.T) {
+	store, err := NewMemoryStore(MemoryConfig{CleanupInterval: time.Minute})
+	if err != nil {
+		t.Fatalf("Failed to create memory store: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+	store.Set(ctx, "a", []byte("1"), time.Minute)
+
+	// A stale expectation on one key writes neither
+	swapped, err := store.CompareAndSwapMulti(ctx, []Swap{
+		{Key: "a", Old: []byte("1"), Value: []byte("2"), Expiration: time.Minute},
+		{Key: "b", Old: []byte("x"), Value: []byte("2"), Expiration: time.Minute},
+	})
+	if err != nil || swapped {
+		t.Fatalf("Expected the swap to fail, got %v (%v)", swapped, err)
+	}
+	if value, _ := store.Get(ctx, "a"); string(value) != "1" {
+		t.Errorf("Expected a to keep its value, got %q", value)
+	}
+
+	// A nil Old expects the key to be absent
+	swapped, err = store.CompareAndSwapMulti(ctx, []Swap{
+		{Key: "a", Old: []byte("1"), Value: []byte("2"), Expiration: time.Minute},
+		{Key: "b", Value: []byte("2"), Expiration: time.Minute},
+	})
+	if err != nil || !swapped {
+		t.Fatalf("Expected the swap to succeed, got %v (%v)", swapped, err)
+	}
+	for _, key := range []string{"a", "b"} {
+		if value, _ := store.Get(ctx, key); string(value) != "2" {
+			t.Errorf("Expected %s to be swapped, got %q", key, value)
+		}
+	}
+}
+
 func TestMemoryStore_MaxKeys(t *testing.T) {
 	config := MemoryConfig{
 		MaxKeys:         5, // Small limit for testing
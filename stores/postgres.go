@@ -0,0 +1,495 @@
+// stores/postgres.go
+package stores
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultPostgresTable is the table limiter state is kept in unless configured otherwise
+const DefaultPostgresTable = "gorly_limits"
+
+// postgresDrivers are the database/sql driver names of the common Postgres drivers, in the
+// order they are tried when the config names none
+var postgresDrivers = []string{"pgx", "postgres"}
+
+// postgresTableName matches table names, optionally qualified by a schema
+var postgresTableName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// PostgresConfig configures the Postgres store
+type PostgresConfig struct {
+	DSN    string // Connection string, e.g. "postgres://gorly@db:5432/app?sslmode=require"
+	Driver string // database/sql driver name; default: "pgx" or "postgres", whichever is registered
+	Table  string // Default: DefaultPostgresTable
+
+	// DB is an open pool to use instead of opening one from DSN; Close leaves it open
+	DB *sql.DB
+
+	Migrate         bool          // Create the table and its index on startup if missing
+	Unlogged        bool          // Migrate creates an UNLOGGED table: no WAL writes, emptied after a crash
+	Timeout         time.Duration // Timeout of the connection test and migration; default: 5s
+	CleanupInterval time.Duration // How often expired rows are deleted; default: 1m, negative disables
+}
+
+// PostgresStore implements the Store interface on a Postgres table. Each operation is one
+// statement, so it is atomic without Lua: counters are incremented by an INSERT ... ON
+// CONFLICT upsert and compare-and-set operations are conditional writes. Expiry uses the
+// server clock, so every instance agrees on it. Expired rows are invisible to reads and
+// deleted in the background.
+type PostgresStore struct {
+	db      *sql.DB
+	ownDB   bool // Whether Close closes db
+	config  PostgresConfig
+	queries postgresQueries
+
+	closeOnce sync.Once
+	stop      chan struct{}
+	done      sync.WaitGroup
+}
+
+// postgresQueries are the statements of a store, built once for its table
+type postgresQueries struct {
+	get, set, increment, setNX, compareAndExpire, compareAndDelete string
+	swap, delete, exists, ttl, expire, deleteExpired               string
+}
+
+// NewPostgresStore connects to Postgres, migrates the schema if configured to and starts
+// deleting expired rows. A driver must be registered by importing it, e.g.
+// _ "github.com/jackc/pgx/v5/stdlib" or _ "github.com/lib/pq".
+func NewPostgresStore(config PostgresConfig) (*PostgresStore, error) {
+	if config.Table == "" {
+		config.Table = DefaultPostgresTable
+	}
+	if !postgresTableName.MatchString(config.Table) {
+		return nil, NewStoreError("config", fmt.Sprintf("invalid postgres table name %q", config.Table), nil)
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 5 * time.Second
+	}
+	if config.CleanupInterval == 0 {
+		config.CleanupInterval = time.Minute
+	}
+
+	store := &PostgresStore{db: config.DB, config: config, queries: newPostgresQueries(config.Table)}
+	if store.db == nil {
+		driver, err := postgresDriver(config.Driver)
+		if err != nil {
+			return nil, err
+		}
+		db, err := sql.Open(driver, config.DSN)
+		if err != nil {
+			return nil, NewStoreError("config", "failed to open postgres connection", err)
+		}
+		store.db, store.ownDB = db, true
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), config.Timeout)
+	defer cancel()
+	if err := store.Health(ctx); err != nil {
+		store.closeDB()
+		return nil, fmt.Errorf("failed to connect to Postgres: %w", err)
+	}
+	if config.Migrate {
+		if err := store.Migrate(ctx); err != nil {
+			store.closeDB()
+			return nil, err
+		}
+	}
+
+	store.stop = make(chan struct{})
+	if config.CleanupInterval > 0 {
+		store.done.Add(1)
+		go store.cleanup()
+	}
+	return store, nil
+}
+
+// postgresDriver returns the driver to open connections with: the configured one, or
+// the first registered common Postgres driver
+func postgresDriver(configured string) (string, error) {
+	if configured != "" {
+		return configured, nil
+	}
+	registered := sql.Drivers()
+	for _, name := range postgresDrivers {
+		for _, driver := range registered {
+			if driver == name {
+				return name, nil
+			}
+		}
+	}
+	return "", NewStoreError("config",
+		`no postgres driver registered: import one, e.g. _ "github.com/jackc/pgx/v5/stdlib", or set the driver name`, nil)
+}
+
+// PostgresSchema returns the statements creating the table of a store and the index its
+// cleanup uses, for running migrations with your own tooling
+func PostgresSchema(table string, unlogged bool) ([]string, error) {
+	if table == "" {
+		table = DefaultPostgresTable
+	}
+	if !postgresTableName.MatchString(table) {
+		return nil, NewStoreError("config", fmt.Sprintf("invalid postgres table name %q", table), nil)
+	}
+	create := "CREATE TABLE"
+	if unlogged {
+		create = "CREATE UNLOGGED TABLE"
+	}
+	// Indexes live in the schema of their table and are named without it
+	index := table[strings.LastIndexByte(table, '.')+1:] + "_expires_at_idx"
+	return []string{
+		create + " IF NOT EXISTS " + table + ` (
+	key        TEXT PRIMARY KEY,
+	value      BYTEA,
+	counter    BIGINT,
+	expires_at TIMESTAMPTZ
+)`,
+		"CREATE INDEX IF NOT EXISTS " + index + " ON " + table + " (expires_at) WHERE expires_at IS NOT NULL",
+	}, nil
+}
+
+// Migrate creates the table and index of the store if they do not exist yet. It is safe
+// to run from every instance on startup.
+func (p *PostgresStore) Migrate(ctx context.Context) error {
+	statements, err := PostgresSchema(p.config.Table, p.config.Unlogged)
+	if err != nil {
+		return err
+	}
+	for _, statement := range statements {
+		if _, err := p.db.ExecContext(ctx, statement); err != nil {
+			return NewStoreError("config", "failed to migrate postgres schema", err)
+		}
+	}
+	return nil
+}
+
+// newPostgresQueries builds the statements of a table. A row is live while expires_at is
+// NULL or in the future; values written by Set are in value, counters in counter.
+func newPostgresQueries(table string) postgresQueries {
+	const (
+		live      = "(expires_at IS NULL OR expires_at > now())"
+		liveStore = "(s.expires_at IS NULL OR s.expires_at > now())"
+	)
+	// expiresAt is the expiry of a TTL in milliseconds, NULL for none
+	expiresAt := func(param string) string {
+		return "CASE WHEN " + param + "::bigint > 0 THEN now() + " + param + "::bigint * interval '1 millisecond' END"
+	}
+
+	return postgresQueries{
+		get: "SELECT value, counter FROM " + table + " WHERE key = $1 AND " + live,
+		set: "INSERT INTO " + table + " AS s (key, value, counter, expires_at) VALUES ($1, $2, NULL, " + expiresAt("$3") + ")" +
+			" ON CONFLICT (key) DO UPDATE SET value = EXCLUDED.value, counter = NULL, expires_at = EXCLUDED.expires_at",
+		// Like INCRBY with EXPIRE: the TTL is refreshed when given and kept otherwise, and
+		// an expired row counts from zero with the new TTL
+		increment: "INSERT INTO " + table + " AS s (key, value, counter, expires_at) VALUES ($1, NULL, $2::bigint, " + expiresAt("$3") + ")" +
+			" ON CONFLICT (key) DO UPDATE SET" +
+			" counter = CASE WHEN " + liveStore + " THEN COALESCE(s.counter, 0) + EXCLUDED.counter ELSE EXCLUDED.counter END," +
+			" value = NULL," +
+			" expires_at = CASE WHEN $3::bigint > 0 OR NOT " + liveStore + " THEN EXCLUDED.expires_at ELSE s.expires_at END" +
+			" RETURNING counter",
+		setNX: "INSERT INTO " + table + " AS s (key, value, counter, expires_at) VALUES ($1, $2, NULL, " + expiresAt("$3") + ")" +
+			" ON CONFLICT (key) DO UPDATE SET value = EXCLUDED.value, counter = NULL, expires_at = EXCLUDED.expires_at" +
+			" WHERE NOT " + liveStore,
+		compareAndExpire: "UPDATE " + table + " SET expires_at = " + expiresAt("$3") + " WHERE key = $1 AND value = $2 AND " + live,
+		compareAndDelete: "DELETE FROM " + table + " WHERE key = $1 AND value = $2 AND " + live,
+		swap:             "UPDATE " + table + " SET value = $3, counter = NULL, expires_at = " + expiresAt("$4") + " WHERE key = $1 AND value = $2 AND " + live,
+		delete:           "DELETE FROM " + table + " WHERE key = $1",
+		exists:           "SELECT EXISTS (SELECT 1 FROM " + table + " WHERE key = $1 AND " + live + ")",
+		ttl:              "SELECT CAST(EXTRACT(EPOCH FROM expires_at - now()) * 1000 AS BIGINT) FROM " + table + " WHERE key = $1 AND " + live,
+		expire:           "UPDATE " + table + " SET expires_at = " + expiresAt("$2") + " WHERE key = $1 AND " + live,
+		deleteExpired:    "DELETE FROM " + table + " WHERE expires_at <= now()",
+	}
+}
+
+// Get retrieves a value from Postgres. Counters are returned as decimal strings, as Redis
+// returns them.
+func (p *PostgresStore) Get(ctx context.Context, key string) ([]byte, error) {
+	var value []byte
+	var counter sql.NullInt64
+	err := p.db.QueryRowContext(ctx, p.queries.get, key).Scan(&value, &counter)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, NewStoreError(
+				"store",
+				"key not found",
+				err,
+			)
+		}
+		return nil, NewStoreError(
+			"store",
+			"failed to get value from Postgres",
+			err,
+		)
+	}
+	if counter.Valid {
+		return []byte(strconv.FormatInt(counter.Int64, 10)), nil
+	}
+	if value == nil {
+		value = []byte{}
+	}
+	return value, nil
+}
+
+// Set stores a value in Postgres with optional expiration
+func (p *PostgresStore) Set(ctx context.Context, key string, value []byte, expiration time.Duration) error {
+	if _, err := p.db.ExecContext(ctx, p.queries.set, key, nonNil(value), expiration.Milliseconds()); err != nil {
+		return NewStoreError(
+			"store",
+			"failed to set value in Postgres",
+			err,
+		)
+	}
+	return nil
+}
+
+// Increment atomically increments a counter and returns the new value
+func (p *PostgresStore) Increment(ctx context.Context, key string, expiration time.Duration) (int64, error) {
+	return p.IncrementBy(ctx, key, 1, expiration)
+}
+
+// IncrementBy atomically increments a counter by the given amount in one upsert, which
+// locks only the counter's row
+func (p *PostgresStore) IncrementBy(ctx context.Context, key string, amount int64, expiration time.Duration) (int64, error) {
+	var result int64
+	err := p.db.QueryRowContext(ctx, p.queries.increment, key, amount, expiration.Milliseconds()).Scan(&result)
+	if err != nil {
+		return 0, NewStoreError(
+			"store",
+			"failed to increment counter in Postgres",
+			err,
+		)
+	}
+	return result, nil
+}
+
+// SetNX stores a value only if the key does not exist, reporting whether it was stored
+func (p *PostgresStore) SetNX(ctx context.Context, key string, value []byte, expiration time.Duration) (bool, error) {
+	return p.execAffected(ctx, "failed to set value in Postgres", p.queries.setNX, key, nonNil(value), expiration.Milliseconds())
+}
+
+// CompareAndExpire resets the expiration of a key only if it holds value
+func (p *PostgresStore) CompareAndExpire(ctx context.Context, key string, value []byte, expiration time.Duration) (bool, error) {
+	return p.execAffected(ctx, "failed to refresh expiration in Postgres", p.queries.compareAndExpire, key, nonNil(value), expiration.Milliseconds())
+}
+
+// CompareAndDelete removes a key only if it holds value
+func (p *PostgresStore) CompareAndDelete(ctx context.Context, key string, value []byte) (bool, error) {
+	return p.execAffected(ctx, "failed to delete key from Postgres", p.queries.compareAndDelete, key, nonNil(value))
+}
+
+// CompareAndSwapMulti writes every swap in one transaction only if every key still holds
+// its Old value, reporting whether they were written. The rows stay locked until the
+// transaction ends, so no other writer sees some swaps without the others.
+func (p *PostgresStore) CompareAndSwapMulti(ctx context.Context, swaps []Swap) (bool, error) {
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, NewStoreError(
+			"store",
+			"failed to swap values in Postgres",
+			err,
+		)
+	}
+	defer tx.Rollback()
+
+	for _, swap := range swaps {
+		var result sql.Result
+		if swap.Old == nil {
+			result, err = tx.ExecContext(ctx, p.queries.setNX, swap.Key, nonNil(swap.Value), swap.Expiration.Milliseconds())
+		} else {
+			result, err = tx.ExecContext(ctx, p.queries.swap, swap.Key, swap.Old, nonNil(swap.Value), swap.Expiration.Milliseconds())
+		}
+		if err == nil {
+			var affected int64
+			if affected, err = result.RowsAffected(); err == nil && affected == 0 {
+				return false, nil
+			}
+		}
+		if err != nil {
+			return false, NewStoreError(
+				"store",
+				"failed to swap values in Postgres",
+				err,
+			)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return false, NewStoreError(
+			"store",
+			"failed to swap values in Postgres",
+			err,
+		)
+	}
+	return true, nil
+}
+
+// Delete removes a key from Postgres
+func (p *PostgresStore) Delete(ctx context.Context, key string) error {
+	if _, err := p.db.ExecContext(ctx, p.queries.delete, key); err != nil {
+		return NewStoreError(
+			"store",
+			"failed to delete key from Postgres",
+			err,
+		)
+	}
+	return nil
+}
+
+// Exists checks if a key exists in Postgres
+func (p *PostgresStore) Exists(ctx context.Context, key string) (bool, error) {
+	var exists bool
+	if err := p.db.QueryRowContext(ctx, p.queries.exists, key).Scan(&exists); err != nil {
+		return false, NewStoreError(
+			"store",
+			"failed to check key existence in Postgres",
+			err,
+		)
+	}
+	return exists, nil
+}
+
+// TTL returns the time-to-live for a key: -1 for keys without expiration and -2 for
+// missing keys, as Redis reports them
+func (p *PostgresStore) TTL(ctx context.Context, key string) (time.Duration, error) {
+	var ttl sql.NullInt64
+	err := p.db.QueryRowContext(ctx, p.queries.ttl, key).Scan(&ttl)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return -2, nil
+	case err != nil:
+		return 0, NewStoreError(
+			"store",
+			"failed to get TTL from Postgres",
+			err,
+		)
+	case !ttl.Valid:
+		return -1, nil
+	}
+	return time.Duration(ttl.Int64) * time.Millisecond, nil
+}
+
+// Expire sets an expiration time for a key
+func (p *PostgresStore) Expire(ctx context.Context, key string, expiration time.Duration) error {
+	if _, err := p.db.ExecContext(ctx, p.queries.expire, key, expiration.Milliseconds()); err != nil {
+		return NewStoreError(
+			"store",
+			"failed to set expiration in Postgres",
+			err,
+		)
+	}
+	return nil
+}
+
+// DeleteExpired deletes the expired rows and returns how many there were. The store does
+// this every CleanupInterval; expired rows are never read, so it only reclaims space.
+func (p *PostgresStore) DeleteExpired(ctx context.Context) (int64, error) {
+	result, err := p.db.ExecContext(ctx, p.queries.deleteExpired)
+	if err != nil {
+		return 0, NewStoreError(
+			"store",
+			"failed to delete expired keys from Postgres",
+			err,
+		)
+	}
+	return result.RowsAffected()
+}
+
+// Health checks the health of the Postgres connection
+func (p *PostgresStore) Health(ctx context.Context) error {
+	if err := p.db.PingContext(ctx); err != nil {
+		return NewStoreError(
+			"network",
+			"Postgres health check failed",
+			err,
+		)
+	}
+	return nil
+}
+
+// Time returns the clock of the Postgres server, shared by every instance using it
+func (p *PostgresStore) Time(ctx context.Context) (time.Time, error) {
+	var now time.Time
+	if err := p.db.QueryRowContext(ctx, "SELECT now()").Scan(&now); err != nil {
+		return time.Time{}, NewStoreError(
+			"network",
+			"failed to read Postgres server time",
+			err,
+		)
+	}
+	return now, nil
+}
+
+// Stats returns connection pool statistics
+func (p *PostgresStore) Stats() map[string]interface{} {
+	stats := p.db.Stats()
+	return map[string]interface{}{
+		"open_connections": stats.OpenConnections,
+		"in_use":           stats.InUse,
+		"idle":             stats.Idle,
+		"wait_count":       stats.WaitCount,
+		"wait_duration":    stats.WaitDuration.String(),
+		"table":            p.config.Table,
+	}
+}
+
+// Close stops the cleanup and closes the connection pool, unless it was passed in the config
+func (p *PostgresStore) Close() error {
+	var err error
+	p.closeOnce.Do(func() {
+		close(p.stop)
+		p.done.Wait()
+		err = p.closeDB()
+	})
+	return err
+}
+
+// closeDB closes the connection pool if the store opened it
+func (p *PostgresStore) closeDB() error {
+	if !p.ownDB {
+		return nil
+	}
+	return p.db.Close()
+}
+
+// cleanup deletes expired rows every CleanupInterval until the store is closed
+func (p *PostgresStore) cleanup() {
+	defer p.done.Done()
+	ticker := time.NewTicker(p.config.CleanupInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), p.config.Timeout)
+			p.DeleteExpired(ctx)
+			cancel()
+		}
+	}
+}
+
+// execAffected runs a conditional write and reports whether it changed a row
+func (p *PostgresStore) execAffected(ctx context.Context, message, query string, args ...interface{}) (bool, error) {
+	result, err := p.db.ExecContext(ctx, query, args...)
+	if err == nil {
+		var affected int64
+		if affected, err = result.RowsAffected(); err == nil {
+			return affected > 0, nil
+		}
+	}
+	return false, NewStoreError("store", message, err)
+}
+
+// nonNil returns an empty value for nil, which would be written as NULL, the marker of counters
+func nonNil(value []byte) []byte {
+	if value == nil {
+		return []byte{}
+	}
+	return value
+}
@@ -0,0 +1,231 @@
+// stores/postgres_test.go
+package stores
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakePostgres is a database/sql driver answering statements with scripted responses,
+// recording what the store sent. It checks how the store uses database/sql, not the SQL
+// itself, which needs a real server.
+type fakePostgres struct {
+	mu         sync.Mutex
+	statements []string
+	args       [][]driver.Value
+	respond    func(query string) ([]driver.Value, int64, error) // Row (nil for none) and rows affected
+}
+
+func (f *fakePostgres) Connect(context.Context) (driver.Conn, error) {
+	return &fakePostgresConn{f}, nil
+}
+func (f *fakePostgres) Driver() driver.Driver { return nil }
+
+func (f *fakePostgres) record(query string, args []driver.NamedValue) ([]driver.Value, int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		values[i] = arg.Value
+	}
+	f.statements = append(f.statements, query)
+	f.args = append(f.args, values)
+	if f.respond == nil {
+		return nil, 1, nil
+	}
+	return f.respond(query)
+}
+
+// sent returns the statements starting with prefix
+func (f *fakePostgres) sent(prefix string) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var matching []string
+	for _, statement := range f.statements {
+		if strings.HasPrefix(statement, prefix) {
+			matching = append(matching, statement)
+		}
+	}
+	return matching
+}
+
+type fakePostgresConn struct{ f *fakePostgres }
+
+func (c *fakePostgresConn) Prepare(string) (driver.Stmt, error) { return nil, driver.ErrSkip }
+func (c *fakePostgresConn) Close() error                        { return nil }
+func (c *fakePostgresConn) Begin() (driver.Tx, error)           { return c, nil }
+func (c *fakePostgresConn) Commit() error                       { c.f.record("COMMIT", nil); return nil }
+func (c *fakePostgresConn) Rollback() error                     { c.f.record("ROLLBACK", nil); return nil }
+
+func (c *fakePostgresConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	_, affected, err := c.f.record(query, args)
+	if err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(affected), nil
+}
+
+func (c *fakePostgresConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	row, _, err := c.f.record(query, args)
+	if err != nil {
+		return nil, err
+	}
+	return &fakePostgresRows{row: row}, nil
+}
+
+type fakePostgresRows struct {
+	row  []driver.Value
+	done bool
+}
+
+func (r *fakePostgresRows) Columns() []string {
+	columns := make([]string, len(r.row))
+	for i := range columns {
+		columns[i] = "column"
+	}
+	return columns
+}
+
+func (r *fakePostgresRows) Close() error { return nil }
+
+func (r *fakePostgresRows) Next(dest []driver.Value) error {
+	if r.row == nil || r.done {
+		return io.EOF
+	}
+	r.done = true
+	copy(dest, r.row)
+	return nil
+}
+
+func newFakePostgresStore(t *testing.T, config PostgresConfig) (*PostgresStore, *fakePostgres) {
+	t.Helper()
+	fake := &fakePostgres{}
+	config.DB = sql.OpenDB(fake)
+	config.CleanupInterval = -1
+	store, err := NewPostgresStore(config)
+	if err != nil {
+		t.Fatalf("Failed to create postgres store: %v", err)
+	}
+	t.Cleanup(func() {
+		store.Close()
+		config.DB.Close()
+	})
+	return store, fake
+}
+
+func TestPostgresSchema(t *testing.T) {
+	statements, err := PostgresSchema("", false)
+	if err != nil || len(statements) != 2 {
+		t.Fatalf("Expected the table and its index, got %v (%v)", statements, err)
+	}
+	if !strings.HasPrefix(statements[0], "CREATE TABLE IF NOT EXISTS gorly_limits (") ||
+		statements[1] != "CREATE INDEX IF NOT EXISTS gorly_limits_expires_at_idx ON gorly_limits (expires_at) WHERE expires_at IS NOT NULL" {
+		t.Errorf("Unexpected schema: %v", statements)
+	}
+
+	statements, err = PostgresSchema("ops.limits", true)
+	if err != nil || !strings.HasPrefix(statements[0], "CREATE UNLOGGED TABLE IF NOT EXISTS ops.limits (") ||
+		!strings.HasPrefix(statements[1], "CREATE INDEX IF NOT EXISTS limits_expires_at_idx ON ops.limits ") {
+		t.Errorf("Expected an unlogged table in its schema, got %v (%v)", statements, err)
+	}
+
+	for _, table := range []string{"limits; DROP TABLE users", "a.b.c", "1limits", `"limits"`} {
+		if _, err := PostgresSchema(table, false); err == nil {
+			t.Errorf("Expected table name %q to be rejected", table)
+		}
+	}
+}
+
+func TestPostgresStore(t *testing.T) {
+	store, fake := newFakePostgresStore(t, PostgresConfig{Migrate: true})
+	ctx := context.Background()
+
+	if len(fake.sent("CREATE TABLE IF NOT EXISTS gorly_limits")) != 1 || len(fake.sent("CREATE INDEX")) != 1 {
+		t.Errorf("Expected the schema to be migrated, got %v", fake.statements)
+	}
+
+	fake.respond = func(query string) ([]driver.Value, int64, error) { return nil, 0, nil }
+	if _, err := store.Get(ctx, "missing"); !IsNotFound(err) {
+		t.Errorf("Expected a not found error, got %v", err)
+	}
+	if ttl, err := store.TTL(ctx, "missing"); err != nil || ttl != -2 {
+		t.Errorf("Expected -2 for a missing key, got %v (%v)", ttl, err)
+	}
+	if stored, err := store.SetNX(ctx, "lease", []byte("a"), time.Second); err != nil || stored {
+		t.Errorf("Expected SetNX to report the live key, got %v (%v)", stored, err)
+	}
+
+	// Counters read back as decimal strings, as from Redis
+	fake.respond = func(query string) ([]driver.Value, int64, error) {
+		if strings.HasPrefix(query, "INSERT") {
+			return []driver.Value{int64(6)}, 1, nil
+		}
+		return []driver.Value{nil, int64(6)}, 0, nil
+	}
+	used, err := store.IncrementBy(ctx, "counter", 5, time.Minute)
+	if err != nil || used != 6 {
+		t.Errorf("Expected the upserted count, got %d (%v)", used, err)
+	}
+	if args := fake.args[len(fake.args)-1]; args[0] != "counter" || args[1] != int64(5) || args[2] != int64(60000) {
+		t.Errorf("Expected the key, amount and TTL in milliseconds, got %v", args)
+	}
+	if value, err := store.Get(ctx, "counter"); err != nil || string(value) != "6" {
+		t.Errorf("Expected the counter as a string, got %q (%v)", value, err)
+	}
+
+	failure := errors.New("connection reset")
+	fake.respond = func(query string) ([]driver.Value, int64, error) { return nil, 0, failure }
+	if err := store.Set(ctx, "key", []byte("v"), 0); err == nil || !strings.Contains(err.Error(), failure.Error()) || IsNotFound(err) {
+		t.Errorf("Expected the driver error wrapped in a store error, got %v", err)
+	}
+}
+
+func TestPostgresCompareAndSwapMulti(t *testing.T) {
+	store, fake := newFakePostgresStore(t, PostgresConfig{Table: "limits"})
+	ctx := context.Background()
+	swaps := []Swap{
+		{Key: "a", Value: []byte("1"), Expiration: time.Minute},
+		{Key: "b", Old: []byte("1"), Value: []byte("2")},
+	}
+
+	if ok, err := store.CompareAndSwapMulti(ctx, swaps); err != nil || !ok {
+		t.Fatalf("Expected the swaps to be written, got %v (%v)", ok, err)
+	}
+	if len(fake.sent("INSERT INTO limits")) != 1 || len(fake.sent("UPDATE limits")) != 1 || len(fake.sent("COMMIT")) != 1 {
+		t.Errorf("Expected an insert and an update in one transaction, got %v", fake.statements)
+	}
+
+	// A key holding another value rolls back the swaps written before it
+	fake.respond = func(query string) ([]driver.Value, int64, error) {
+		if strings.HasPrefix(query, "UPDATE") {
+			return nil, 0, nil
+		}
+		return nil, 1, nil
+	}
+	if ok, err := store.CompareAndSwapMulti(ctx, swaps); err != nil || ok {
+		t.Errorf("Expected the swaps to be rejected, got %v (%v)", ok, err)
+	}
+	if len(fake.sent("ROLLBACK")) != 1 || len(fake.sent("COMMIT")) != 1 {
+		t.Errorf("Expected the transaction to be rolled back, got %v", fake.statements)
+	}
+}
+
+func TestPostgresDriver(t *testing.T) {
+	if _, err := NewPostgresStore(PostgresConfig{DSN: "postgres://localhost/app"}); err == nil ||
+		!strings.Contains(err.Error(), "no postgres driver registered") {
+		t.Errorf("Expected a missing driver to be named, got %v", err)
+	}
+	if driver, err := postgresDriver("cloudsqlpostgres"); err != nil || driver != "cloudsqlpostgres" {
+		t.Errorf("Expected the configured driver, got %q (%v)", driver, err)
+	}
+	if _, err := NewPostgresStore(PostgresConfig{Table: "drop table"}); err == nil {
+		t.Error("Expected an invalid table name to be rejected")
+	}
+}
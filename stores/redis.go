@@ -4,7 +4,10 @@ package stores
 import (
 	"context"
 	"crypto/tls"
+	"errors"
 	"fmt"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/redis/go-redis/v9"
@@ -20,6 +23,14 @@ type RedisConfig struct {
 	MaxRetries  int           `yaml:"max_retries" json:"max_retries" mapstructure:"max_retries"`
 	Timeout     time.Duration `yaml:"timeout" json:"timeout" mapstructure:"timeout"`
 	TLS         bool          `yaml:"tls" json:"tls" mapstructure:"tls"`
+
+	// LazyConnect skips the connection test of NewRedisStore, so the first operation connects
+	LazyConnect bool `yaml:"lazy_connect" json:"lazy_connect" mapstructure:"lazy_connect"`
+
+	// Secrets fetches the username, password and TLS material at runtime, ahead of Password.
+	// Rotated secrets replace the connection pool, so every connection authenticates again.
+	Secrets        SecretsProvider `yaml:"-" json:"-" mapstructure:"-"`
+	SecretsRefresh time.Duration   `yaml:"-" json:"-" mapstructure:"-"` // Default: 1m
 }
 
 // StoreError represents an error from the store
@@ -45,14 +56,76 @@ func NewStoreError(errorType, message string, err error) *StoreError {
 	}
 }
 
+// IsNotFound reports whether err is a "key not found" store error
+func IsNotFound(err error) bool {
+	var storeErr *StoreError
+	return errors.As(err, &storeErr) && storeErr.Message == "key not found"
+}
+
+// ErrScriptsUnsupported is returned by RunScript of stores whose backend runs no scripts
+var ErrScriptsUnsupported = NewStoreError("config", "store does not run scripts", nil)
+
 // RedisStore implements the Store interface using Redis
 type RedisStore struct {
-	client *redis.Client
+	rdb    atomic.Pointer[redis.Client] // Replaced when the secrets rotate
 	config RedisConfig
+
+	rotateMu  sync.Mutex
+	secrets   *RedisCredentials // Secrets of the current client; nil without a provider
+	rotations atomic.Int64
+	closed    bool
+	stop      chan struct{} // Closed to end the secrets refresh
+
+	scripts sync.Map // Script source -> *redis.Script
 }
 
 // NewRedisStore creates a new Redis store
 func NewRedisStore(config RedisConfig) (*RedisStore, error) {
+	store, err := newRedisStore(config)
+	if err != nil {
+		return nil, err
+	}
+
+	if config.LazyConnect {
+		return store, nil
+	}
+
+	// Test the connection
+	ctx, cancel := context.WithTimeout(context.Background(), config.Timeout)
+	defer cancel()
+
+	if err := store.Health(ctx); err != nil {
+		store.Close()
+		return nil, fmt.Errorf("failed to connect to Redis: %w", err)
+	}
+
+	return store, nil
+}
+
+// newRedisStore creates a Redis store without testing the connection. With a secrets
+// provider it fetches the secrets first and keeps refreshing them.
+func newRedisStore(config RedisConfig) (*RedisStore, error) {
+	store := &RedisStore{config: config}
+	if config.Secrets != nil {
+		secrets, err := fetchSecrets(config.Secrets, config.Timeout)
+		if err != nil {
+			return nil, err
+		}
+		store.secrets = secrets
+	}
+
+	// Create Redis client
+	store.rdb.Store(redis.NewClient(redisOptions(config, store.secrets)))
+
+	if config.Secrets != nil {
+		store.stop = make(chan struct{})
+		go store.refreshSecrets()
+	}
+	return store, nil
+}
+
+// redisOptions configures a client, authenticating with the secrets if there are any
+func redisOptions(config RedisConfig, secrets *RedisCredentials) *redis.Options {
 	// Configure Redis client options
 	opts := &redis.Options{
 		Addr:         config.Address,
@@ -73,28 +146,79 @@ func NewRedisStore(config RedisConfig) (*RedisStore, error) {
 		}
 	}
 
-	// Create Redis client
-	client := redis.NewClient(opts)
+	if secrets != nil {
+		opts.Username = secrets.Username
+		opts.Password = secrets.Password
+		if secrets.Certificate != nil || secrets.RootCAs != nil {
+			if opts.TLSConfig == nil {
+				opts.TLSConfig = &tls.Config{}
+			}
+			if secrets.Certificate != nil {
+				opts.TLSConfig.Certificates = []tls.Certificate{*secrets.Certificate}
+			}
+			opts.TLSConfig.RootCAs = secrets.RootCAs
+		}
+	}
+	return opts
+}
+
+// client returns the current Redis client
+func (r *RedisStore) client() *redis.Client {
+	return r.rdb.Load()
+}
 
-	store := &RedisStore{
-		client: client,
-		config: config,
+// refreshSecrets asks the provider for rotated secrets until the store is closed. Failed
+// fetches keep the current connections, which stay valid until the old secrets expire.
+func (r *RedisStore) refreshSecrets() {
+	interval := r.config.SecretsRefresh
+	if interval <= 0 {
+		interval = defaultSecretsRefresh
 	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-ticker.C:
+			secrets, err := fetchSecrets(r.config.Secrets, r.config.Timeout)
+			if err != nil {
+				continue
+			}
+			r.rotate(secrets)
+		}
+	}
+}
 
-	// Test the connection
-	ctx, cancel := context.WithTimeout(context.Background(), config.Timeout)
-	defer cancel()
+// rotate replaces the client if the secrets changed, so every connection authenticates
+// again. The old client closes after a grace period that lets running commands finish.
+func (r *RedisStore) rotate(secrets *RedisCredentials) {
+	r.rotateMu.Lock()
+	defer r.rotateMu.Unlock()
+	if r.closed || sameSecrets(r.secrets, secrets) {
+		return
+	}
 
-	if err := store.Health(ctx); err != nil {
-		return nil, fmt.Errorf("failed to connect to Redis: %w", err)
+	r.secrets = secrets
+	old := r.rdb.Swap(redis.NewClient(redisOptions(r.config, secrets)))
+	r.rotations.Add(1)
+
+	grace := r.config.Timeout
+	if grace <= 0 {
+		grace = 5 * time.Second
 	}
+	time.AfterFunc(grace, func() { old.Close() })
+}
 
-	return store, nil
+// SecretRotations returns how often rotated secrets replaced the connection pool
+func (r *RedisStore) SecretRotations() int64 {
+	return r.rotations.Load()
 }
 
 // Get retrieves a value from Redis
 func (r *RedisStore) Get(ctx context.Context, key string) ([]byte, error) {
-	val, err := r.client.Get(ctx, key).Bytes()
+	val, err := r.client().Get(ctx, key).Bytes()
 	if err != nil {
 		if err == redis.Nil {
 			return nil, NewStoreError(
@@ -114,7 +238,7 @@ func (r *RedisStore) Get(ctx context.Context, key string) ([]byte, error) {
 
 // Set stores a value in Redis with optional expiration
 func (r *RedisStore) Set(ctx context.Context, key string, value []byte, expiration time.Duration) error {
-	err := r.client.Set(ctx, key, value, expiration).Err()
+	err := r.client().Set(ctx, key, value, expiration).Err()
 	if err != nil {
 		return NewStoreError(
 			"store",
@@ -142,7 +266,7 @@ func (r *RedisStore) IncrementBy(ctx context.Context, key string, amount int64,
 	`
 
 	expirationSeconds := int64(expiration.Seconds())
-	result, err := r.client.Eval(ctx, luaScript, []string{key}, amount, expirationSeconds).Int64()
+	result, err := r.client().Eval(ctx, luaScript, []string{key}, amount, expirationSeconds).Int64()
 	if err != nil {
 		return 0, NewStoreError(
 			"store",
@@ -154,9 +278,134 @@ func (r *RedisStore) IncrementBy(ctx context.Context, key string, amount int64,
 	return result, nil
 }
 
+// SetNX stores a value only if the key does not exist, reporting whether it was stored
+func (r *RedisStore) SetNX(ctx context.Context, key string, value []byte, expiration time.Duration) (bool, error) {
+	stored, err := r.client().SetNX(ctx, key, value, expiration).Result()
+	if err != nil {
+		return false, NewStoreError(
+			"store",
+			"failed to set value in Redis",
+			err,
+		)
+	}
+	return stored, nil
+}
+
+// CompareAndExpire resets the expiration of a key only if it holds value
+func (r *RedisStore) CompareAndExpire(ctx context.Context, key string, value []byte, expiration time.Duration) (bool, error) {
+	luaScript := `
+		if redis.call('GET', KEYS[1]) ~= ARGV[1] then
+			return 0
+		end
+		if tonumber(ARGV[2]) > 0 then
+			return redis.call('PEXPIRE', KEYS[1], ARGV[2])
+		end
+		return redis.call('PERSIST', KEYS[1]) + 1
+	`
+
+	result, err := r.client().Eval(ctx, luaScript, []string{key}, value, expiration.Milliseconds()).Int64()
+	if err != nil {
+		return false, NewStoreError(
+			"store",
+			"failed to refresh expiration in Redis",
+			err,
+		)
+	}
+	return result > 0, nil
+}
+
+// CompareAndDelete removes a key only if it holds value
+func (r *RedisStore) CompareAndDelete(ctx context.Context, key string, value []byte) (bool, error) {
+	luaScript := `
+		if redis.call('GET', KEYS[1]) ~= ARGV[1] then
+			return 0
+		end
+		return redis.call('DEL', KEYS[1])
+	`
+
+	result, err := r.client().Eval(ctx, luaScript, []string{key}, value).Int64()
+	if err != nil {
+		return false, NewStoreError(
+			"store",
+			"failed to delete key from Redis",
+			err,
+		)
+	}
+	return result > 0, nil
+}
+
+// CompareAndSwapMulti writes every swap in one script only if every key still holds its
+// Old value, reporting whether they were written
+func (r *RedisStore) CompareAndSwapMulti(ctx context.Context, swaps []Swap) (bool, error) {
+	// ARGV holds four values per key: whether it must exist, its old value, its new value
+	// and its expiration in milliseconds
+	luaScript := `
+		for i, key in ipairs(KEYS) do
+			local current = redis.call('GET', key)
+			local base = (i - 1) * 4
+			if ARGV[base + 1] == '1' then
+				if current ~= ARGV[base + 2] then
+					return 0
+				end
+			elseif current then
+				return 0
+			end
+		end
+		for i, key in ipairs(KEYS) do
+			local base = (i - 1) * 4
+			if tonumber(ARGV[base + 4]) > 0 then
+				redis.call('SET', key, ARGV[base + 3], 'PX', ARGV[base + 4])
+			else
+				redis.call('SET', key, ARGV[base + 3])
+			end
+		end
+		return 1
+	`
+
+	keys := make([]string, len(swaps))
+	args := make([]interface{}, 0, 4*len(swaps))
+	for i, swap := range swaps {
+		keys[i] = swap.Key
+		exists := "0"
+		if swap.Old != nil {
+			exists = "1"
+		}
+		args = append(args, exists, swap.Old, swap.Value, swap.Expiration.Milliseconds())
+	}
+
+	result, err := r.client().Eval(ctx, luaScript, keys, args...).Int64()
+	if err != nil {
+		return false, NewStoreError(
+			"store",
+			"failed to swap values in Redis",
+			err,
+		)
+	}
+	return result > 0, nil
+}
+
+// RunScript runs a Lua script atomically. Scripts are sent by their SHA1 digest and only
+// sent in full when Redis does not know them yet, e.g. after a restart or failover.
+func (r *RedisStore) RunScript(ctx context.Context, script string, keys []string, args ...interface{}) (interface{}, error) {
+	compiled, ok := r.scripts.Load(script)
+	if !ok {
+		compiled, _ = r.scripts.LoadOrStore(script, redis.NewScript(script))
+	}
+
+	result, err := compiled.(*redis.Script).Run(ctx, r.client(), keys, args...).Result()
+	if err != nil && err != redis.Nil {
+		return nil, NewStoreError(
+			"store",
+			"failed to run script in Redis",
+			err,
+		)
+	}
+	return result, nil
+}
+
 // Delete removes a key from Redis
 func (r *RedisStore) Delete(ctx context.Context, key string) error {
-	err := r.client.Del(ctx, key).Err()
+	err := r.client().Del(ctx, key).Err()
 	if err != nil {
 		return NewStoreError(
 			"store",
@@ -169,7 +418,7 @@ func (r *RedisStore) Delete(ctx context.Context, key string) error {
 
 // Exists checks if a key exists in Redis
 func (r *RedisStore) Exists(ctx context.Context, key string) (bool, error) {
-	count, err := r.client.Exists(ctx, key).Result()
+	count, err := r.client().Exists(ctx, key).Result()
 	if err != nil {
 		return false, NewStoreError(
 			"store",
@@ -182,7 +431,7 @@ func (r *RedisStore) Exists(ctx context.Context, key string) (bool, error) {
 
 // Health checks the health of the Redis connection
 func (r *RedisStore) Health(ctx context.Context) error {
-	_, err := r.client.Ping(ctx).Result()
+	_, err := r.client().Ping(ctx).Result()
 	if err != nil {
 		return NewStoreError(
 			"network",
@@ -193,9 +442,28 @@ func (r *RedisStore) Health(ctx context.Context) error {
 	return nil
 }
 
-// Close closes the Redis connection
+// Time returns the clock of the Redis server, shared by every instance using it
+func (r *RedisStore) Time(ctx context.Context) (time.Time, error) {
+	now, err := r.client().Time(ctx).Result()
+	if err != nil {
+		return time.Time{}, NewStoreError(
+			"network",
+			"failed to read Redis server time",
+			err,
+		)
+	}
+	return now, nil
+}
+
+// Close closes the Redis connection and stops refreshing secrets
 func (r *RedisStore) Close() error {
-	return r.client.Close()
+	r.rotateMu.Lock()
+	defer r.rotateMu.Unlock()
+	if !r.closed && r.stop != nil {
+		close(r.stop)
+	}
+	r.closed = true
+	return r.client().Close()
 }
 
 // MultiGet retrieves multiple values at once for better performance
@@ -204,7 +472,7 @@ func (r *RedisStore) MultiGet(ctx context.Context, keys []string) (map[string][]
 		return make(map[string][]byte), nil
 	}
 
-	values, err := r.client.MGet(ctx, keys...).Result()
+	values, err := r.client().MGet(ctx, keys...).Result()
 	if err != nil {
 		return nil, NewStoreError(
 			"store",
@@ -232,7 +500,7 @@ func (r *RedisStore) MultiSet(ctx context.Context, keyValues map[string][]byte,
 	}
 
 	// Use pipeline for better performance
-	pipe := r.client.Pipeline()
+	pipe := r.client().Pipeline()
 
 	for key, value := range keyValues {
 		pipe.Set(ctx, key, value, expiration)
@@ -265,7 +533,7 @@ func (r *RedisStore) IncrementMulti(ctx context.Context, keys []string, amounts
 	}
 
 	// Use pipeline for better performance
-	pipe := r.client.Pipeline()
+	pipe := r.client().Pipeline()
 
 	luaScript := `
 		local current = redis.call('INCRBY', KEYS[1], ARGV[1])
@@ -310,7 +578,7 @@ func (r *RedisStore) IncrementMulti(ctx context.Context, keys []string, amounts
 
 // TTL returns the time-to-live for a key
 func (r *RedisStore) TTL(ctx context.Context, key string) (time.Duration, error) {
-	duration, err := r.client.TTL(ctx, key).Result()
+	duration, err := r.client().TTL(ctx, key).Result()
 	if err != nil {
 		return 0, NewStoreError(
 			"store",
@@ -323,7 +591,7 @@ func (r *RedisStore) TTL(ctx context.Context, key string) (time.Duration, error)
 
 // Expire sets an expiration time for a key
 func (r *RedisStore) Expire(ctx context.Context, key string, expiration time.Duration) error {
-	err := r.client.Expire(ctx, key, expiration).Err()
+	err := r.client().Expire(ctx, key, expiration).Err()
 	if err != nil {
 		return NewStoreError(
 			"store",
@@ -334,14 +602,38 @@ func (r *RedisStore) Expire(ctx context.Context, key string, expiration time.Dur
 	return nil
 }
 
-// GetClient returns the underlying Redis client for advanced operations
+// GetClient returns the underlying Redis client for advanced operations. With a secrets
+// provider the client is replaced when the secrets rotate, so do not keep it.
 func (r *RedisStore) GetClient() *redis.Client {
-	return r.client
+	return r.client()
+}
+
+// PoolStats is a snapshot of Redis connection pool statistics
+type PoolStats struct {
+	Hits       uint32 // Connections taken from the pool
+	Misses     uint32 // Connections that had to be dialed
+	Timeouts   uint32 // Waits for a free connection that timed out
+	TotalConns uint32
+	IdleConns  uint32
+	StaleConns uint32 // Connections removed from the pool
+}
+
+// PoolStats returns the connection pool statistics
+func (r *RedisStore) PoolStats() PoolStats {
+	stats := r.client().PoolStats()
+	return PoolStats{
+		Hits:       stats.Hits,
+		Misses:     stats.Misses,
+		Timeouts:   stats.Timeouts,
+		TotalConns: stats.TotalConns,
+		IdleConns:  stats.IdleConns,
+		StaleConns: stats.StaleConns,
+	}
 }
 
 // Stats returns Redis connection statistics
 func (r *RedisStore) Stats() map[string]interface{} {
-	stats := r.client.PoolStats()
+	stats := r.client().PoolStats()
 	return map[string]interface{}{
 		"hits":        stats.Hits,
 		"misses":      stats.Misses,
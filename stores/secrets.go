@@ -0,0 +1,83 @@
+// stores/secrets.go
+package stores
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"time"
+)
+
+// RedisCredentials are the credentials of Redis connections, fetched at runtime instead of configured
+type RedisCredentials struct {
+	Username    string // ACL user; "" authenticates as the default user
+	Password    string
+	Certificate *tls.Certificate // Client certificate for mutual TLS; nil without
+	RootCAs     *x509.CertPool   // CAs of the server certificate; nil uses the system pool
+}
+
+// SecretsProvider supplies Redis credentials that may rotate. The store calls Secrets when it
+// connects and then every refresh interval, so providers cache and renew secrets themselves.
+type SecretsProvider interface {
+	Secrets(ctx context.Context) (*RedisCredentials, error)
+}
+
+// SecretsProviderFunc adapts a function to a SecretsProvider
+type SecretsProviderFunc func(ctx context.Context) (*RedisCredentials, error)
+
+// Secrets implements SecretsProvider
+func (f SecretsProviderFunc) Secrets(ctx context.Context) (*RedisCredentials, error) {
+	return f(ctx)
+}
+
+// defaultSecretsRefresh is how often a store asks its provider for rotated secrets
+const defaultSecretsRefresh = time.Minute
+
+// fetchSecrets asks the provider for the current secrets
+func fetchSecrets(provider SecretsProvider, timeout time.Duration) (*RedisCredentials, error) {
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	secrets, err := provider.Secrets(ctx)
+	if err != nil {
+		return nil, NewStoreError("secrets", "failed to fetch Redis secrets", err)
+	}
+	if secrets == nil {
+		return nil, NewStoreError("secrets", "secrets provider returned no secrets", nil)
+	}
+	return secrets, nil
+}
+
+// sameSecrets reports whether two sets of secrets authenticate the same way
+func sameSecrets(a, b *RedisCredentials) bool {
+	if a.Username != b.Username || a.Password != b.Password {
+		return false
+	}
+	if (a.Certificate == nil) != (b.Certificate == nil) {
+		return false
+	}
+	if a.Certificate != nil && !sameCertificate(a.Certificate, b.Certificate) {
+		return false
+	}
+	if (a.RootCAs == nil) != (b.RootCAs == nil) {
+		return false
+	}
+	return a.RootCAs == nil || a.RootCAs.Equal(b.RootCAs)
+}
+
+// sameCertificate compares the certificate chains of two client certificates
+func sameCertificate(a, b *tls.Certificate) bool {
+	if len(a.Certificate) != len(b.Certificate) {
+		return false
+	}
+	for i := range a.Certificate {
+		if !bytes.Equal(a.Certificate[i], b.Certificate[i]) {
+			return false
+		}
+	}
+	return true
+}
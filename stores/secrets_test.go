@@ -0,0 +1,75 @@
+// stores/secrets_test.go
+package stores
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRedisStore_SecretsRotation(t *testing.T) {
+	var mu sync.Mutex
+	current := &RedisCredentials{Username: "gorly", Password: "v1"}
+	var failing bool
+	provider := SecretsProviderFunc(func(ctx context.Context) (*RedisCredentials, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if failing {
+			return nil, errors.New("vault sealed")
+		}
+		return &RedisCredentials{Username: current.Username, Password: current.Password}, nil
+	})
+
+	// The client connects lazily, so no Redis is needed to watch the rotation
+	store, err := newRedisStore(RedisConfig{
+		Address:        "127.0.0.1:0",
+		Password:       "from-config",
+		Timeout:        50 * time.Millisecond,
+		Secrets:        provider,
+		SecretsRefresh: 5 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	opts := store.GetClient().Options()
+	if opts.Username != "gorly" || opts.Password != "v1" {
+		t.Fatalf("Expected the provider's secrets ahead of the config, got %s/%s", opts.Username, opts.Password)
+	}
+
+	// Unchanged secrets keep the client
+	time.Sleep(30 * time.Millisecond)
+	if store.SecretRotations() != 0 {
+		t.Fatalf("Expected no rotation, got %d", store.SecretRotations())
+	}
+
+	// A failing provider keeps the current client
+	mu.Lock()
+	failing = true
+	mu.Unlock()
+	time.Sleep(30 * time.Millisecond)
+	if store.SecretRotations() != 0 || store.GetClient().Options().Password != "v1" {
+		t.Fatal("Expected the client to survive a failing provider")
+	}
+
+	mu.Lock()
+	failing = false
+	current.Password = "v2"
+	mu.Unlock()
+	eventually(t, "the rotation", func() bool { return store.SecretRotations() == 1 })
+	if opts := store.GetClient().Options(); opts.Password != "v2" {
+		t.Errorf("Expected the rotated password, got %s", opts.Password)
+	}
+}
+
+func TestRedisStore_SecretsRequired(t *testing.T) {
+	provider := SecretsProviderFunc(func(ctx context.Context) (*RedisCredentials, error) {
+		return nil, errors.New("permission denied")
+	})
+	if _, err := NewRedisStore(RedisConfig{Address: "127.0.0.1:0", Secrets: provider}); err == nil {
+		t.Fatal("Expected the provider's error")
+	}
+}
@@ -0,0 +1,478 @@
+// stores/sharded.go
+package stores
+
+import (
+	"context"
+	"fmt"
+	"hash/crc32"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Shard failure policies
+const (
+	// ShardFailurePolicyFailover routes keys owned by a dead shard to the next healthy shard on the ring
+	ShardFailurePolicyFailover = "failover"
+
+	// ShardFailurePolicyFailClosed returns an error for keys owned by a dead shard
+	ShardFailurePolicyFailClosed = "fail_closed"
+)
+
+// ShardBackend is the set of operations a single shard must support
+type ShardBackend interface {
+	Get(ctx context.Context, key string) ([]byte, error)
+	Set(ctx context.Context, key string, value []byte, expiration time.Duration) error
+	IncrementBy(ctx context.Context, key string, amount int64, expiration time.Duration) (int64, error)
+	Delete(ctx context.Context, key string) error
+	Exists(ctx context.Context, key string) (bool, error)
+	SetNX(ctx context.Context, key string, value []byte, expiration time.Duration) (bool, error)
+	CompareAndExpire(ctx context.Context, key string, value []byte, expiration time.Duration) (bool, error)
+	CompareAndDelete(ctx context.Context, key string, value []byte) (bool, error)
+	Health(ctx context.Context) error
+	Close() error
+}
+
+// ShardedConfig configures consistent-hash sharding across several backends
+type ShardedConfig struct {
+	VirtualNodes        int           `yaml:"virtual_nodes" json:"virtual_nodes" mapstructure:"virtual_nodes"`                         // Ring points per shard
+	FailureThreshold    int           `yaml:"failure_threshold" json:"failure_threshold" mapstructure:"failure_threshold"`             // Consecutive errors before a shard is marked dead
+	HealthCheckInterval time.Duration `yaml:"health_check_interval" json:"health_check_interval" mapstructure:"health_check_interval"` // How often dead shards are probed
+	FailurePolicy       string        `yaml:"failure_policy" json:"failure_policy" mapstructure:"failure_policy"`                      // "failover" or "fail_closed"
+}
+
+// ShardedRedisConfig configures a sharded store over standalone Redis instances
+type ShardedRedisConfig struct {
+	// Addresses of the standalone Redis instances, one per shard
+	Addresses []string `yaml:"addresses" json:"addresses" mapstructure:"addresses"`
+
+	// Redis settings shared by all shards (Address is ignored)
+	Redis RedisConfig `yaml:"redis" json:"redis" mapstructure:"redis"`
+
+	// Sharding behaviour
+	Sharding ShardedConfig `yaml:"sharding" json:"sharding" mapstructure:"sharding"`
+}
+
+// shard is a single backend plus its health state
+type shard struct {
+	name     string
+	backend  ShardBackend
+	mu       sync.Mutex
+	healthy  bool
+	failures int
+}
+
+// ShardedStore distributes keys across several backends using consistent hashing
+type ShardedStore struct {
+	shards []*shard
+	ring   []uint32
+	owners map[uint32]int
+	config ShardedConfig
+
+	healthStop chan struct{}
+	closeOnce  sync.Once
+}
+
+// NewShardedRedisStore creates a sharded store over standalone Redis instances.
+// Shards that are unreachable at startup are marked dead rather than failing construction.
+func NewShardedRedisStore(config ShardedRedisConfig) (*ShardedStore, error) {
+	if len(config.Addresses) == 0 {
+		return nil, NewStoreError("config", "at least one shard address is required", nil)
+	}
+
+	names := make([]string, len(config.Addresses))
+	backends := make([]ShardBackend, len(config.Addresses))
+	for i, address := range config.Addresses {
+		redisConfig := config.Redis
+		redisConfig.Address = address
+		if redisConfig.Timeout <= 0 {
+			redisConfig.Timeout = 5 * time.Second
+		}
+		backend, err := newRedisStore(redisConfig)
+		if err != nil {
+			for _, created := range backends[:i] {
+				created.Close()
+			}
+			return nil, err
+		}
+		names[i] = address
+		backends[i] = backend
+	}
+
+	store, err := NewShardedStore(config.Sharding, names, backends)
+	if err != nil {
+		for _, backend := range backends {
+			backend.Close()
+		}
+		return nil, err
+	}
+
+	// Probe every shard once so dead instances are excluded from the start
+	ctx, cancel := context.WithTimeout(context.Background(), config.Redis.Timeout+time.Second)
+	defer cancel()
+	store.checkShards(ctx, true)
+
+	return store, nil
+}
+
+// NewShardedStore creates a sharded store over arbitrary backends.
+// names identify shards on the hash ring and must be stable across restarts.
+func NewShardedStore(config ShardedConfig, names []string, backends []ShardBackend) (*ShardedStore, error) {
+	if len(backends) == 0 {
+		return nil, NewStoreError("config", "at least one shard is required", nil)
+	}
+	if len(names) != len(backends) {
+		return nil, NewStoreError("config", "shard names and backends must have the same length", nil)
+	}
+
+	// Set defaults
+	if config.VirtualNodes <= 0 {
+		config.VirtualNodes = 160
+	}
+	if config.FailureThreshold <= 0 {
+		config.FailureThreshold = 3
+	}
+	if config.HealthCheckInterval <= 0 {
+		config.HealthCheckInterval = 5 * time.Second
+	}
+	switch config.FailurePolicy {
+	case "":
+		config.FailurePolicy = ShardFailurePolicyFailover
+	case ShardFailurePolicyFailover, ShardFailurePolicyFailClosed:
+	default:
+		return nil, NewStoreError("config", fmt.Sprintf("unknown shard failure policy: %s", config.FailurePolicy), nil)
+	}
+
+	store := &ShardedStore{
+		shards:     make([]*shard, len(backends)),
+		owners:     make(map[uint32]int),
+		config:     config,
+		healthStop: make(chan struct{}),
+	}
+
+	for i, backend := range backends {
+		store.shards[i] = &shard{name: names[i], backend: backend, healthy: true}
+		for v := 0; v < config.VirtualNodes; v++ {
+			point := crc32.ChecksumIEEE([]byte(names[i] + "#" + strconv.Itoa(v)))
+			if _, taken := store.owners[point]; taken {
+				continue
+			}
+			store.owners[point] = i
+			store.ring = append(store.ring, point)
+		}
+	}
+	sort.Slice(store.ring, func(a, b int) bool { return store.ring[a] < store.ring[b] })
+
+	go store.healthLoop()
+
+	return store, nil
+}
+
+// ShardFor returns the name of the shard that currently serves key
+func (s *ShardedStore) ShardFor(key string) (string, error) {
+	sh, err := s.pick(key)
+	if err != nil {
+		return "", err
+	}
+	return sh.name, nil
+}
+
+// pick walks the ring clockwise from the key's hash to find its serving shard
+func (s *ShardedStore) pick(key string) (*shard, error) {
+	hash := crc32.ChecksumIEEE([]byte(key))
+	start := sort.Search(len(s.ring), func(i int) bool { return s.ring[i] >= hash })
+
+	owner := s.owners[s.ring[start%len(s.ring)]]
+	if sh := s.shards[owner]; sh.isHealthy() {
+		return sh, nil
+	} else if s.config.FailurePolicy == ShardFailurePolicyFailClosed {
+		return nil, NewStoreError("network", fmt.Sprintf("shard %s is unavailable", sh.name), nil)
+	}
+
+	// Fail over to the next distinct shard clockwise; only this path allocates
+	tried := make([]bool, len(s.shards))
+	tried[owner] = true
+	for i, left := 1, len(s.shards)-1; i < len(s.ring) && left > 0; i++ {
+		idx := s.owners[s.ring[(start+i)%len(s.ring)]]
+		if tried[idx] {
+			continue
+		}
+		tried[idx] = true
+		left--
+
+		if sh := s.shards[idx]; sh.isHealthy() {
+			return sh, nil
+		}
+	}
+
+	return nil, NewStoreError("network", "no healthy shards available", nil)
+}
+
+// record updates shard health from the outcome of an operation
+func (s *ShardedStore) record(sh *shard, err error) {
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	if err == nil || IsNotFound(err) {
+		sh.failures = 0
+		return
+	}
+
+	sh.failures++
+	if sh.failures >= s.config.FailureThreshold {
+		sh.healthy = false
+	}
+}
+
+func (sh *shard) isHealthy() bool {
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	return sh.healthy
+}
+
+// Get retrieves a value from the shard owning key
+func (s *ShardedStore) Get(ctx context.Context, key string) ([]byte, error) {
+	sh, err := s.pick(key)
+	if err != nil {
+		return nil, err
+	}
+	value, err := sh.backend.Get(ctx, key)
+	s.record(sh, err)
+	return value, err
+}
+
+// Set stores a value on the shard owning key
+func (s *ShardedStore) Set(ctx context.Context, key string, value []byte, expiration time.Duration) error {
+	sh, err := s.pick(key)
+	if err != nil {
+		return err
+	}
+	err = sh.backend.Set(ctx, key, value, expiration)
+	s.record(sh, err)
+	return err
+}
+
+// Increment atomically increments a counter on the shard owning key
+func (s *ShardedStore) Increment(ctx context.Context, key string, expiration time.Duration) (int64, error) {
+	return s.IncrementBy(ctx, key, 1, expiration)
+}
+
+// IncrementBy atomically increments a counter by the given amount on the shard owning key
+func (s *ShardedStore) IncrementBy(ctx context.Context, key string, amount int64, expiration time.Duration) (int64, error) {
+	sh, err := s.pick(key)
+	if err != nil {
+		return 0, err
+	}
+	value, err := sh.backend.IncrementBy(ctx, key, amount, expiration)
+	s.record(sh, err)
+	return value, err
+}
+
+// Delete removes a key from the shard owning it
+func (s *ShardedStore) Delete(ctx context.Context, key string) error {
+	sh, err := s.pick(key)
+	if err != nil {
+		return err
+	}
+	err = sh.backend.Delete(ctx, key)
+	s.record(sh, err)
+	return err
+}
+
+// Exists checks if a key exists on the shard owning it
+func (s *ShardedStore) Exists(ctx context.Context, key string) (bool, error) {
+	sh, err := s.pick(key)
+	if err != nil {
+		return false, err
+	}
+	exists, err := sh.backend.Exists(ctx, key)
+	s.record(sh, err)
+	return exists, err
+}
+
+// SetNX stores a value on the shard owning key only if the key does not exist
+func (s *ShardedStore) SetNX(ctx context.Context, key string, value []byte, expiration time.Duration) (bool, error) {
+	sh, err := s.pick(key)
+	if err != nil {
+		return false, err
+	}
+	stored, err := sh.backend.SetNX(ctx, key, value, expiration)
+	s.record(sh, err)
+	return stored, err
+}
+
+// CompareAndExpire resets the expiration of a key on the shard owning it only if it holds value
+func (s *ShardedStore) CompareAndExpire(ctx context.Context, key string, value []byte, expiration time.Duration) (bool, error) {
+	sh, err := s.pick(key)
+	if err != nil {
+		return false, err
+	}
+	refreshed, err := sh.backend.CompareAndExpire(ctx, key, value, expiration)
+	s.record(sh, err)
+	return refreshed, err
+}
+
+// CompareAndDelete removes a key from the shard owning it only if it holds value
+func (s *ShardedStore) CompareAndDelete(ctx context.Context, key string, value []byte) (bool, error) {
+	sh, err := s.pick(key)
+	if err != nil {
+		return false, err
+	}
+	deleted, err := sh.backend.CompareAndDelete(ctx, key, value)
+	s.record(sh, err)
+	return deleted, err
+}
+
+// RunScript runs a script on the shard owning its keys, which must all live on the same
+// shard. Shards whose backend runs no scripts return ErrScriptsUnsupported.
+func (s *ShardedStore) RunScript(ctx context.Context, script string, keys []string, args ...interface{}) (interface{}, error) {
+	if len(keys) == 0 {
+		return nil, NewStoreError("config", "scripts need a key to pick a shard", nil)
+	}
+	sh, err := s.pick(keys[0])
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range keys[1:] {
+		if other, err := s.pick(key); err != nil || other != sh {
+			return nil, NewStoreError("config", "script keys live on different shards", err)
+		}
+	}
+	runner, ok := sh.backend.(interface {
+		RunScript(ctx context.Context, script string, keys []string, args ...interface{}) (interface{}, error)
+	})
+	if !ok {
+		return nil, ErrScriptsUnsupported
+	}
+	result, err := runner.RunScript(ctx, script, keys, args...)
+	s.record(sh, err)
+	return result, err
+}
+
+// Health reports whether the store can serve requests under its failure policy.
+// With failover one healthy shard suffices; fail_closed requires every shard.
+func (s *ShardedStore) Health(ctx context.Context) error {
+	healthy := 0
+	for _, sh := range s.shards {
+		if sh.isHealthy() {
+			healthy++
+		}
+	}
+
+	if healthy == 0 {
+		return NewStoreError("network", "no healthy shards available", nil)
+	}
+	if s.config.FailurePolicy == ShardFailurePolicyFailClosed && healthy < len(s.shards) {
+		return NewStoreError("network", fmt.Sprintf("%d of %d shards unavailable", len(s.shards)-healthy, len(s.shards)), nil)
+	}
+	return nil
+}
+
+// Time returns the clock of the first healthy shard that reports one. Shards keep their
+// own clocks, so every instance reads the same shard while the shard set is healthy.
+func (s *ShardedStore) Time(ctx context.Context) (time.Time, error) {
+	for _, sh := range s.shards {
+		clock, ok := sh.backend.(interface {
+			Time(ctx context.Context) (time.Time, error)
+		})
+		if !ok || !sh.isHealthy() {
+			continue
+		}
+		return clock.Time(ctx)
+	}
+	return time.Time{}, NewStoreError("network", "no healthy shard reports a clock", nil)
+}
+
+// Close stops health checking and closes every shard
+func (s *ShardedStore) Close() error {
+	var firstErr error
+	s.closeOnce.Do(func() {
+		close(s.healthStop)
+		for _, sh := range s.shards {
+			if err := sh.backend.Close(); err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+	})
+	return firstErr
+}
+
+// PoolStats sums the connection pool statistics of the shards backed by Redis
+func (s *ShardedStore) PoolStats() PoolStats {
+	var total PoolStats
+	for _, sh := range s.shards {
+		pooled, ok := sh.backend.(interface{ PoolStats() PoolStats })
+		if !ok {
+			continue
+		}
+		stats := pooled.PoolStats()
+		total.Hits += stats.Hits
+		total.Misses += stats.Misses
+		total.Timeouts += stats.Timeouts
+		total.TotalConns += stats.TotalConns
+		total.IdleConns += stats.IdleConns
+		total.StaleConns += stats.StaleConns
+	}
+	return total
+}
+
+// Stats returns per-shard health information
+func (s *ShardedStore) Stats() map[string]interface{} {
+	shards := make(map[string]interface{}, len(s.shards))
+	healthy := 0
+	for _, sh := range s.shards {
+		sh.mu.Lock()
+		shards[sh.name] = map[string]interface{}{
+			"healthy":  sh.healthy,
+			"failures": sh.failures,
+		}
+		if sh.healthy {
+			healthy++
+		}
+		sh.mu.Unlock()
+	}
+
+	return map[string]interface{}{
+		"shards":         shards,
+		"total_shards":   len(s.shards),
+		"healthy_shards": healthy,
+		"virtual_nodes":  s.config.VirtualNodes,
+		"failure_policy": s.config.FailurePolicy,
+	}
+}
+
+// healthLoop periodically probes dead shards and readmits them once they respond
+func (s *ShardedStore) healthLoop() {
+	ticker := time.NewTicker(s.config.HealthCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), s.config.HealthCheckInterval)
+			s.checkShards(ctx, false)
+			cancel()
+		case <-s.healthStop:
+			return
+		}
+	}
+}
+
+// checkShards probes shards; when all is false only dead shards are probed
+func (s *ShardedStore) checkShards(ctx context.Context, all bool) {
+	for _, sh := range s.shards {
+		if !all && sh.isHealthy() {
+			continue
+		}
+
+		err := sh.backend.Health(ctx)
+
+		sh.mu.Lock()
+		sh.healthy = err == nil
+		if err == nil {
+			sh.failures = 0
+		}
+		sh.mu.Unlock()
+	}
+}
@@ -0,0 +1,231 @@
+// stores/sharded_test.go
+package stores
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// flakyBackend wraps a memory store and can be switched into a failing state
+type flakyBackend struct {
+	*MemoryStore
+	mu   sync.Mutex
+	down bool
+}
+
+func (f *flakyBackend) setDown(down bool) {
+	f.mu.Lock()
+	f.down = down
+	f.mu.Unlock()
+}
+
+func (f *flakyBackend) err() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.down {
+		return NewStoreError("network", "connection refused", nil)
+	}
+	return nil
+}
+
+func (f *flakyBackend) Get(ctx context.Context, key string) ([]byte, error) {
+	if err := f.err(); err != nil {
+		return nil, err
+	}
+	return f.MemoryStore.Get(ctx, key)
+}
+
+func (f *flakyBackend) Set(ctx context.Context, key string, value []byte, expiration time.Duration) error {
+	if err := f.err(); err != nil {
+		return err
+	}
+	return f.MemoryStore.Set(ctx, key, value, expiration)
+}
+
+func (f *flakyBackend) Health(ctx context.Context) error {
+	return f.err()
+}
+
+func newShardedTestStore(t *testing.T, config ShardedConfig, count int) (*ShardedStore, []*flakyBackend) {
+	t.Helper()
+
+	names := make([]string, count)
+	backends := make([]ShardBackend, count)
+	flaky := make([]*flakyBackend, count)
+	for i := 0; i < count; i++ {
+		mem, err := NewMemoryStore(MemoryConfig{CleanupInterval: time.Minute})
+		if err != nil {
+			t.Fatalf("Failed to create memory store: %v", err)
+		}
+		flaky[i] = &flakyBackend{MemoryStore: mem}
+		names[i] = fmt.Sprintf("shard-%d", i)
+		backends[i] = flaky[i]
+	}
+
+	store, err := NewShardedStore(config, names, backends)
+	if err != nil {
+		t.Fatalf("Failed to create sharded store: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	return store, flaky
+}
+
+func TestShardedStore_Distribution(t *testing.T) {
+	store, _ := newShardedTestStore(t, ShardedConfig{}, 3)
+
+	counts := make(map[string]int)
+	for i := 0; i < 3000; i++ {
+		shard, err := store.ShardFor(fmt.Sprintf("ratelimit:user-%d:global", i))
+		if err != nil {
+			t.Fatalf("ShardFor failed: %v", err)
+		}
+		counts[shard]++
+	}
+
+	if len(counts) != 3 {
+		t.Fatalf("Expected keys on 3 shards, got %d", len(counts))
+	}
+	for shard, count := range counts {
+		if count < 500 {
+			t.Errorf("Shard %s is underloaded: %d of 3000 keys", shard, count)
+		}
+	}
+}
+
+func TestShardedStore_StableRouting(t *testing.T) {
+	store, _ := newShardedTestStore(t, ShardedConfig{}, 4)
+	ctx := context.Background()
+
+	if err := store.Set(ctx, "key-a", []byte("value"), time.Minute); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+
+	value, err := store.Get(ctx, "key-a")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if string(value) != "value" {
+		t.Errorf("Expected 'value', got %q", value)
+	}
+
+	first, _ := store.ShardFor("key-a")
+	for i := 0; i < 10; i++ {
+		if shard, _ := store.ShardFor("key-a"); shard != first {
+			t.Fatalf("Key moved from %s to %s without topology change", first, shard)
+		}
+	}
+}
+
+func TestShardedStore_PickAllocations(t *testing.T) {
+	store, _ := newShardedTestStore(t, ShardedConfig{}, 16)
+
+	allocs := testing.AllocsPerRun(100, func() {
+		if _, err := store.pick("ratelimit:user-1:global"); err != nil {
+			t.Fatalf("pick failed: %v", err)
+		}
+	})
+	// The key's bytes escape into the CRC; nothing else may be allocated on the healthy path
+	if allocs > 1 {
+		t.Errorf("Expected only the hashed key to be allocated while the owning shard is healthy, got %v allocations", allocs)
+	}
+}
+
+func TestShardedStore_Failover(t *testing.T) {
+	store, backends := newShardedTestStore(t, ShardedConfig{
+		FailureThreshold:    2,
+		HealthCheckInterval: time.Hour,
+	}, 3)
+	ctx := context.Background()
+
+	owner, _ := store.ShardFor("hot-key")
+	var dead *flakyBackend
+	for i, b := range backends {
+		if fmt.Sprintf("shard-%d", i) == owner {
+			dead = b
+		}
+	}
+	dead.setDown(true)
+
+	// Errors below the threshold are surfaced to the caller
+	for i := 0; i < 2; i++ {
+		if err := store.Set(ctx, "hot-key", []byte("v"), time.Minute); err == nil {
+			t.Fatalf("Expected error from dead shard on attempt %d", i+1)
+		}
+	}
+
+	// Once excluded, the key is served by another shard
+	if err := store.Set(ctx, "hot-key", []byte("v"), time.Minute); err != nil {
+		t.Fatalf("Expected failover to succeed, got: %v", err)
+	}
+	if shard, _ := store.ShardFor("hot-key"); shard == owner {
+		t.Errorf("Expected key to move off dead shard %s", owner)
+	}
+	if err := store.Health(ctx); err != nil {
+		t.Errorf("Expected failover store to be healthy, got: %v", err)
+	}
+
+	// Recovery readmits the shard
+	dead.setDown(false)
+	store.checkShards(ctx, false)
+	if shard, _ := store.ShardFor("hot-key"); shard != owner {
+		t.Errorf("Expected key to return to %s after recovery, got %s", owner, shard)
+	}
+}
+
+func TestShardedStore_FailClosed(t *testing.T) {
+	store, backends := newShardedTestStore(t, ShardedConfig{
+		FailureThreshold:    1,
+		HealthCheckInterval: time.Hour,
+		FailurePolicy:       ShardFailurePolicyFailClosed,
+	}, 2)
+	ctx := context.Background()
+
+	backends[0].setDown(true)
+	store.checkShards(ctx, true)
+
+	// Keys owned by the dead shard fail instead of moving to shard-1
+	failed := 0
+	for i := 0; i < 100; i++ {
+		if _, err := store.ShardFor(fmt.Sprintf("key-%d", i)); err != nil {
+			failed++
+		}
+	}
+	if failed == 0 || failed == 100 {
+		t.Errorf("Expected only keys owned by the dead shard to fail, got %d of 100", failed)
+	}
+
+	if err := store.Health(ctx); err == nil {
+		t.Error("Expected fail_closed store to be unhealthy with a dead shard")
+	}
+}
+
+func TestShardedStore_NotFoundIsNotFailure(t *testing.T) {
+	store, _ := newShardedTestStore(t, ShardedConfig{FailureThreshold: 1}, 2)
+	ctx := context.Background()
+
+	for i := 0; i < 5; i++ {
+		if _, err := store.Get(ctx, "missing"); !IsNotFound(err) {
+			t.Fatalf("Expected not found error, got: %v", err)
+		}
+	}
+
+	if err := store.Health(ctx); err != nil {
+		t.Errorf("Missing keys must not mark shards dead: %v", err)
+	}
+}
+
+func TestNewShardedStore_InvalidConfig(t *testing.T) {
+	if _, err := NewShardedStore(ShardedConfig{}, nil, nil); err == nil {
+		t.Error("Expected error for zero shards")
+	}
+
+	mem, _ := NewMemoryStore(MemoryConfig{})
+	defer mem.Close()
+	if _, err := NewShardedStore(ShardedConfig{FailurePolicy: "bogus"}, []string{"a"}, []ShardBackend{mem}); err == nil {
+		t.Error("Expected error for unknown failure policy")
+	}
+}
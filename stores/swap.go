@@ -0,0 +1,13 @@
+// stores/swap.go
+package stores
+
+import "time"
+
+// Swap is one key of a multi-key compare-and-swap: Value replaces the key's value only if
+// the key still holds Old. A nil Old expects the key to be absent or expired.
+type Swap struct {
+	Key        string
+	Old        []byte
+	Value      []byte
+	Expiration time.Duration
+}
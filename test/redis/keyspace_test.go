@@ -0,0 +1,67 @@
+//go:build redis
+// +build redis
+
+// test/redis/keyspace_test.go
+package redis_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	ratelimit "github.com/itsatony/gorly"
+)
+
+// TestRedisKeyExpiryNotifications lets a limiter's keys expire and expects the
+// notifications to be counted and reported
+func TestRedisKeyExpiryNotifications(t *testing.T) {
+	var mu sync.Mutex
+	var expired []string
+	prefix := fmt.Sprintf("expiry%d", time.Now().UnixNano())
+	limiter, err := ratelimit.New().
+		Redis("localhost:6379").
+		KeyPrefix(prefix).
+		Limit("global", "5/second").
+		WatchKeyExpiry(true).
+		OnKeyExpired(func(key string) {
+			mu.Lock()
+			defer mu.Unlock()
+			expired = append(expired, key)
+		}).
+		Build()
+	if err != nil {
+		t.Fatalf("Failed to build limiter: %v", err)
+	}
+	defer limiter.Close()
+
+	ctx := context.Background()
+	for _, entity := range []string{"user1", "user2"} {
+		if _, err := limiter.Check(ctx, entity, "global"); err != nil {
+			t.Fatalf("Check failed: %v", err)
+		}
+	}
+
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		mu.Lock()
+		n := len(expired)
+		mu.Unlock()
+		if n >= 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected 2 expired keys, got %d", n)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	stats, err := limiter.Stats(ctx)
+	if err != nil {
+		t.Fatalf("Stats failed: %v", err)
+	}
+	if stats.KeyExpiry == nil || stats.KeyExpiry.Expired < 2 || stats.KeyExpiry.LastMinute < 2 || stats.KeyExpiry.ByKind["sliding_window"] < 2 {
+		t.Errorf("Expected the expiries in stats, got %+v", stats.KeyExpiry)
+	}
+}
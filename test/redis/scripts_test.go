@@ -0,0 +1,129 @@
+//go:build redis
+// +build redis
+
+// test/redis/scripts_test.go
+package redis_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/itsatony/gorly/algorithms"
+	"github.com/itsatony/gorly/stores"
+)
+
+// scriptedAlgorithm is an algorithm that runs in a Redis script when the store allows it
+type scriptedAlgorithm interface {
+	SetClock(now func() time.Time)
+	Allow(ctx context.Context, store algorithms.Store, key string, limit int64, window time.Duration, n int64) (*algorithms.Result, error)
+}
+
+// getSetStore hides RunScript, so checks take the Get/Set path
+type getSetStore struct {
+	algorithms.Store
+}
+
+func newScriptStore(t *testing.T) *stores.RedisStore {
+	t.Helper()
+	store, err := stores.NewRedisStore(stores.RedisConfig{
+		Address:  "localhost:6379",
+		Database: 2, // Use a different DB for script tests
+		Timeout:  5 * time.Second,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create Redis store: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+// TestRedisScriptsMatchGetSet alternates scripted and Get/Set checks on one key and
+// expects the same decisions as Get/Set checks alone, so both read each other's state
+func TestRedisScriptsMatchGetSet(t *testing.T) {
+	store := newScriptStore(t)
+	ctx := context.Background()
+
+	for _, name := range []string{"token_bucket", "sliding_window"} {
+		t.Run(name, func(t *testing.T) {
+			now := time.Now()
+			clock := func() time.Time { return now }
+			mixed, plain := newScriptedAlgorithm(name), newScriptedAlgorithm(name)
+			mixed.SetClock(clock)
+			plain.SetClock(clock)
+
+			mixedKey := fmt.Sprintf("scripts:%s:mixed:%d", name, now.UnixNano())
+			plainKey := fmt.Sprintf("scripts:%s:plain:%d", name, now.UnixNano())
+			defer store.Delete(ctx, mixedKey)
+			defer store.Delete(ctx, plainKey)
+
+			for i := 0; i < 40; i++ {
+				var via algorithms.Store = store
+				if i%3 == 2 {
+					via = getSetStore{store}
+				}
+				got, err := mixed.Allow(ctx, via, mixedKey, 10, 10*time.Second, int64(1+i%2))
+				if err != nil {
+					t.Fatalf("Check %d failed: %v", i, err)
+				}
+				want, err := plain.Allow(ctx, getSetStore{store}, plainKey, 10, 10*time.Second, int64(1+i%2))
+				if err != nil {
+					t.Fatalf("Check %d failed: %v", i, err)
+				}
+				if got.Allowed != want.Allowed || got.Remaining != want.Remaining || !got.ResetTime.Equal(want.ResetTime) {
+					t.Fatalf("Check %d: scripted %+v, Get/Set %+v", i, got, want)
+				}
+				now = now.Add(time.Duration(i%4) * time.Second)
+			}
+		})
+	}
+}
+
+// TestRedisScriptsConcurrent checks that instances sharing a key never allow more than
+// the limit, which racing Get/Set checks could
+func TestRedisScriptsConcurrent(t *testing.T) {
+	store := newScriptStore(t)
+	ctx := context.Background()
+
+	for _, name := range []string{"token_bucket", "sliding_window"} {
+		t.Run(name, func(t *testing.T) {
+			key := fmt.Sprintf("scripts:%s:concurrent:%d", name, time.Now().UnixNano())
+			defer store.Delete(ctx, key)
+
+			var allowed atomic.Int64
+			var wg sync.WaitGroup
+			for i := 0; i < 4; i++ {
+				algorithm := newScriptedAlgorithm(name)
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for j := 0; j < 25; j++ {
+						result, err := algorithm.Allow(ctx, store, key, 20, time.Hour, 1)
+						if err != nil {
+							t.Errorf("Check failed: %v", err)
+							return
+						}
+						if result.Allowed {
+							allowed.Add(1)
+						}
+					}
+				}()
+			}
+			wg.Wait()
+
+			if got := allowed.Load(); got != 20 {
+				t.Errorf("Expected exactly 20 allowed requests, got %d", got)
+			}
+		})
+	}
+}
+
+func newScriptedAlgorithm(name string) scriptedAlgorithm {
+	if name == "token_bucket" {
+		return algorithms.NewTokenBucketAlgorithm()
+	}
+	return algorithms.NewSlidingWindowAlgorithm()
+}
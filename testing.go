@@ -252,7 +252,7 @@ func NewMockHTTPTest(limiter Limiter) *MockHTTPTest {
 	})
 
 	// Apply rate limiting middleware
-	middleware := limiter.For(HTTP).(func(http.Handler) http.Handler)
+	middleware := limiter.HTTPMiddleware()
 	handler := middleware(mux)
 
 	return &MockHTTPTest{
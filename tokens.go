@@ -0,0 +1,35 @@
+// tokens.go - Token budget accounting for LLM and other metered APIs
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// TokenCostFunc converts model usage into budget units, e.g. to weight expensive models higher
+type TokenCostFunc func(model string, promptTokens, completionTokens int64) int64
+
+// tokenCharger is implemented by limiters that support token budgets
+type tokenCharger interface {
+	chargeTokens(ctx context.Context, entity, scope, model string, promptTokens, completionTokens int64) (*LimitResult, error)
+}
+
+// ReportTokens charges the model usage of a request against the caller's token budget.
+// Call it from the handler once the upstream response reports its token counts;
+// the middleware denies further requests once the budget is spent.
+// Example: ratelimit.ReportTokens(limiter, r, "gpt-4o", usage.PromptTokens, usage.CompletionTokens)
+func ReportTokens(limiter Limiter, r *http.Request, model string, promptTokens, completionTokens int64) (*LimitResult, error) {
+	charger, ok := limiter.(tokenCharger)
+	if !ok {
+		return nil, fmt.Errorf("limiter does not support token budgets")
+	}
+
+	// Use the scope the middleware assigned to the request
+	scope, _ := r.Context().Value("gorly_scope").(string)
+	if scope == "" {
+		scope = "global"
+	}
+
+	return charger.chargeTokens(r.Context(), requestEntity(limiter, r), scope, model, promptTokens, completionTokens)
+}
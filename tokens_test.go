@@ -0,0 +1,75 @@
+// tokens_test.go - Tests for token budget accounting
+package ratelimit
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTokenBudget(t *testing.T) {
+	limiter, err := AIGateway().
+		TokenBudget("global", "1000/month").
+		TokenCost(func(model string, prompt, completion int64) int64 {
+			if model == "large" {
+				return 2 * (prompt + completion)
+			}
+			return prompt + completion
+		}).
+		Build()
+	if err != nil {
+		t.Fatalf("Failed to build limiter: %v", err)
+	}
+	defer limiter.Close()
+
+	handler := limiter.For(HTTP).(func(http.Handler) http.Handler)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := ReportTokens(limiter, r, "large", 200, 100); err != nil {
+			t.Errorf("Failed to report tokens: %v", err)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, createTestRequest("POST", path, map[string]string{"X-API-Key": "key-1"}))
+		return w
+	}
+
+	// Each call costs 600 units; the second starts with 400 left
+	if w := serve("/v1/chat/completions"); w.Code != http.StatusOK || w.Header().Get("X-RateLimit-Tokens-Remaining") != "1000" {
+		t.Fatalf("Expected full budget, got %d with %q", w.Code, w.Header().Get("X-RateLimit-Tokens-Remaining"))
+	}
+	if w := serve("/v1/chat/completions?stream=true"); w.Code != http.StatusOK || w.Header().Get("X-RateLimit-Tokens-Remaining") != "400" {
+		t.Fatalf("Expected shared budget across scopes, got %d with %q", w.Code, w.Header().Get("X-RateLimit-Tokens-Remaining"))
+	}
+
+	w := serve("/v1/embeddings")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected exhausted budget to deny, got %d", w.Code)
+	}
+
+	var body map[string]interface{}
+	if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+		t.Fatalf("Failed to decode denial: %v", err)
+	}
+	if body["tokens_remaining"] != float64(0) || body["tokens_limit"] != float64(1000) {
+		t.Errorf("Expected token budget in denial, got %v", body)
+	}
+
+	// Other API keys have their own budget
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, createTestRequest("POST", "/v1/chat/completions", map[string]string{"X-API-Key": "key-2"}))
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected other key to be allowed, got %d", w.Code)
+	}
+}
+
+func TestReportTokensUnsupported(t *testing.T) {
+	limiter := IPLimit("10/minute")
+	defer limiter.Close()
+
+	if _, err := ReportTokens(limiter, createTestRequest("POST", "/", nil), "model", 1, 1); err == nil {
+		t.Error("Expected error without a token budget")
+	}
+}
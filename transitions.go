@@ -0,0 +1,63 @@
+// transitions.go - Gradual take-over of limits that shrink at runtime
+package ratelimit
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/itsatony/gorly/internal/core"
+)
+
+// LimitTransition decides how limits that shrink in runtime updates, such as hot reloads,
+// take effect. Immediately shrinking a limit below an entity's usage locks it out until
+// its usage ages out, which takes up to a full window.
+type LimitTransition string
+
+// Limit transitions
+const (
+	TransitionImmediate LimitTransition = core.TransitionImmediate // The new limit applies to the next check (default)
+	TransitionDrain     LimitTransition = core.TransitionDrain     // The old limit applies for one more of its windows
+	TransitionRamp      LimitTransition = core.TransitionRamp      // The limit steps down to the new one over the ramp duration
+)
+
+// LimitTransitionEvent reports a limit that shrank in a runtime update. An event without
+// an entity starts the transition of a scope or tier limit; an event with an entity
+// reports an entity admitted over its new limit only because of the transition, once per
+// entity and scope.
+type LimitTransitionEvent struct {
+	Scope  string          `json:"scope"`
+	Tier   string          `json:"tier,omitempty"`
+	Entity string          `json:"entity,omitempty"`
+	Policy LimitTransition `json:"policy"`
+	From   int64           `json:"from"` // Limit before the update; for entities, the softened limit that admitted them
+	To     int64           `json:"to"`   // Limit after the update
+	Window time.Duration   `json:"window"`
+	Until  time.Time       `json:"until"` // When the new limit applies in full
+}
+
+// newLimitTransitionEvent converts a core transition event
+func newLimitTransitionEvent(e core.LimitTransitionEvent) LimitTransitionEvent {
+	return LimitTransitionEvent{
+		Scope:  e.Scope,
+		Tier:   e.Tier,
+		Entity: e.Entity,
+		Policy: LimitTransition(e.Policy),
+		From:   e.From,
+		To:     e.To,
+		Window: e.Window,
+		Until:  e.Until,
+	}
+}
+
+// LimitTransitionLog returns an OnLimitTransition handler that appends each event to w as a JSON line
+// Example: gorly.New().LimitRamp(10 * time.Minute).OnLimitTransition(gorly.LimitTransitionLog(os.Stderr))
+func LimitTransitionLog(w io.Writer) func(LimitTransitionEvent) {
+	var mu sync.Mutex
+	return func(event LimitTransitionEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+		json.NewEncoder(w).Encode(event)
+	}
+}
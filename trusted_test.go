@@ -0,0 +1,93 @@
+// trusted_test.go
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestTrustedCalls(t *testing.T) {
+	secret := []byte(strings.Repeat("s", MinTrustedCallKeyLength))
+	base, err := New().
+		Limit("global", "1/minute").
+		Limit("internal", "3/minute").
+		PreAuthLimit("1/minute").
+		TrustedCallKey("billing", secret).
+		Build()
+	if err != nil {
+		t.Fatalf("Failed to build limiter: %v", err)
+	}
+	config := DefaultObservabilityConfig()
+	config.EnableLogging = false
+	limiter := NewObservableLimiter(base, config)
+	defer limiter.Close()
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+	server := httptest.NewServer(limiter.For(HTTP).(func(http.Handler) http.Handler)(handler))
+	defer server.Close()
+
+	send := func(client *http.Client) *http.Response {
+		t.Helper()
+		resp, err := client.Get(server.URL + "/v1/invoices")
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	// Spend the pre-auth and global budgets of the client
+	if resp := send(http.DefaultClient); resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected the first request to pass, got %d", resp.StatusCode)
+	}
+	if resp := send(http.DefaultClient); resp.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("Expected the client to be limited, got %d", resp.StatusCode)
+	}
+
+	// Signed calls skip the limits, or are limited under their elevated scope
+	bypass := &http.Client{Transport: TrustedCallTransport(nil, "billing", secret, TrustedBypass)}
+	for i := 0; i < 5; i++ {
+		if resp := send(bypass); resp.StatusCode != http.StatusOK {
+			t.Errorf("Bypass %d: expected 200, got %d", i+1, resp.StatusCode)
+		}
+	}
+	elevated := &http.Client{Transport: TrustedCallTransport(nil, "billing", secret, "internal")}
+	for i, want := range []int{200, 200, 200, 429} {
+		resp := send(elevated)
+		if resp.StatusCode != want {
+			t.Errorf("Elevated %d: expected %d, got %d", i+1, want, resp.StatusCode)
+		}
+		if want == 200 && resp.Header.Get("X-RateLimit-Limit") != "3" {
+			t.Errorf("Elevated %d: expected the internal limit, got %q", i+1, resp.Header.Get("X-RateLimit-Limit"))
+		}
+	}
+
+	// A forged header is no better than none
+	forged := &http.Client{Transport: TrustedCallTransport(nil, "billing", []byte(strings.Repeat("f", 32)), TrustedBypass)}
+	if resp := send(forged); resp.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("Expected a forged call to be limited, got %d", resp.StatusCode)
+	}
+
+	metrics := limiter.GetMetrics()
+	trusted, ok := metrics["trusted_calls"].(*TrustedCallStats)
+	if !ok || trusted.Accepted["billing"][TrustedBypass] != 5 || trusted.Accepted["billing"]["internal"] != 4 || trusted.Rejected["bad_signature"] != 1 {
+		t.Fatalf("Expected the trusted calls to be counted, got %+v", metrics["trusted_calls"])
+	}
+	exposition := convertToPrometheusFormat(metrics, prometheusOptions{})
+	for _, want := range []string{
+		`gorly_trusted_calls_total{key="billing",scope="*"} 5`,
+		`gorly_trusted_call_rejections_total{reason="bad_signature"} 1`,
+	} {
+		if !strings.Contains(exposition, want) {
+			t.Errorf("Expected %q in the exposition", want)
+		}
+	}
+}
+
+func TestTrustedCallKeyValidation(t *testing.T) {
+	if _, err := New().Limit("global", "1/minute").TrustedCallKey("billing", []byte("short")).Build(); err == nil {
+		t.Error("Expected a short trusted call secret to be rejected")
+	}
+}
@@ -134,6 +134,7 @@ func TestMiddlewareHeaders(t *testing.T) {
 		"X-RateLimit-Remaining",
 		"X-RateLimit-Used",
 		"X-RateLimit-Window",
+		"X-RateLimit-Alignment",
 	}
 
 	for _, header := range headers {
@@ -0,0 +1,369 @@
+// vault.go - Redis credentials from HashiCorp Vault
+package ratelimit
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Vault auth methods
+const (
+	VaultAuthToken      = "token"      // A token given in VaultConfig.Token or $VAULT_TOKEN
+	VaultAuthAppRole    = "approle"    // Login with a role ID and secret ID
+	VaultAuthKubernetes = "kubernetes" // Login with the pod's service account token
+)
+
+// defaultKubernetesJWTPath is where Kubernetes mounts the service account token
+const defaultKubernetesJWTPath = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+
+// VaultConfig configures a VaultSecretsProvider
+type VaultConfig struct {
+	Address   string `yaml:"address" json:"address" mapstructure:"address"`       // Default: $VAULT_ADDR
+	Namespace string `yaml:"namespace" json:"namespace" mapstructure:"namespace"` // Vault Enterprise namespace
+
+	// Authentication
+	AuthMethod string `yaml:"auth_method" json:"auth_method" mapstructure:"auth_method"` // VaultAuthToken (default), VaultAuthAppRole or VaultAuthKubernetes
+	AuthMount  string `yaml:"auth_mount" json:"auth_mount" mapstructure:"auth_mount"`    // Mount of the auth method. Default: the method's name
+	Token      string `yaml:"token" json:"token" mapstructure:"token"`                   // Default: $VAULT_TOKEN
+	RoleID     string `yaml:"role_id" json:"role_id" mapstructure:"role_id"`             // AppRole
+	SecretID   string `yaml:"secret_id" json:"secret_id" mapstructure:"secret_id"`       // AppRole
+	Role       string `yaml:"role" json:"role" mapstructure:"role"`                      // Kubernetes role
+	JWTPath    string `yaml:"jwt_path" json:"jwt_path" mapstructure:"jwt_path"`          // Kubernetes. Default: the service account token
+
+	// Path is the secret with the Redis credentials: a KV secret, e.g. "secret/data/gorly/redis",
+	// or dynamic credentials, e.g. "database/creds/gorly"
+	Path          string `yaml:"path" json:"path" mapstructure:"path"`
+	UsernameField string `yaml:"username_field" json:"username_field" mapstructure:"username_field"` // Default: "username"
+	PasswordField string `yaml:"password_field" json:"password_field" mapstructure:"password_field"` // Default: "password"
+
+	// TLSPath is an optional secret with the client certificate in the fields "certificate",
+	// "private_key" and "issuing_ca", such as a PKI role. With TLSParams it is issued with a
+	// POST, e.g. {"common_name": "gorly.internal"} for "pki/issue/redis-client".
+	TLSPath   string                 `yaml:"tls_path" json:"tls_path" mapstructure:"tls_path"`
+	TLSParams map[string]interface{} `yaml:"tls_params" json:"tls_params" mapstructure:"tls_params"`
+
+	// RefreshInterval is how often secrets without a lease, such as KV secrets, are read
+	// again to pick up rotations. Default: 5m
+	RefreshInterval time.Duration `yaml:"refresh_interval" json:"refresh_interval" mapstructure:"refresh_interval"`
+
+	HTTPClient *http.Client `yaml:"-" json:"-" mapstructure:"-"` // Default: the client set with SetHTTPClient
+}
+
+// VaultSecretsProvider reads Redis credentials from Vault. Leased secrets, such as dynamic
+// database credentials, are renewed at two thirds of their lease and read again once Vault
+// stops renewing them; secrets without a lease are read again every RefreshInterval.
+type VaultSecretsProvider struct {
+	config VaultConfig
+	now    func() time.Time
+
+	mu          sync.Mutex
+	token       string
+	tokenExpiry time.Time         // Zero for tokens that are not renewed by login
+	secrets     *RedisCredentials // Last secrets read; nil before the first read
+	leases      []vaultLease      // Leases of the secrets read
+	refreshAt   time.Time         // When to renew the leases or read again
+}
+
+// vaultLease is the lease of a secret read from Vault
+type vaultLease struct {
+	id        string
+	renewable bool
+	expiresAt time.Time
+}
+
+// NewVaultSecretsProvider creates a provider reading the secret at config.Path
+// Example: gorly.New().Redis("redis:6379", gorly.RedisSecrets(gorly.NewVaultSecretsProvider(gorly.VaultConfig{Path: "database/creds/gorly"})))
+func NewVaultSecretsProvider(config VaultConfig) *VaultSecretsProvider {
+	if config.Address == "" {
+		config.Address = os.Getenv("VAULT_ADDR")
+	}
+	config.Address = strings.TrimSuffix(config.Address, "/")
+	if config.AuthMethod == "" {
+		config.AuthMethod = VaultAuthToken
+	}
+	if config.AuthMount == "" {
+		config.AuthMount = config.AuthMethod
+	}
+	if config.Token == "" && config.AuthMethod == VaultAuthToken {
+		config.Token = os.Getenv("VAULT_TOKEN")
+	}
+	if config.JWTPath == "" {
+		config.JWTPath = defaultKubernetesJWTPath
+	}
+	if config.UsernameField == "" {
+		config.UsernameField = "username"
+	}
+	if config.PasswordField == "" {
+		config.PasswordField = "password"
+	}
+	if config.RefreshInterval <= 0 {
+		config.RefreshInterval = 5 * time.Minute
+	}
+	config.Path = strings.Trim(config.Path, "/")
+	config.TLSPath = strings.Trim(config.TLSPath, "/")
+
+	return &VaultSecretsProvider{config: config, now: time.Now}
+}
+
+// Secrets implements SecretsProvider, returning the cached secrets until they are due for a refresh
+func (v *VaultSecretsProvider) Secrets(ctx context.Context) (*RedisCredentials, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	now := v.now()
+	if v.secrets != nil && now.Before(v.refreshAt) {
+		return v.secrets, nil
+	}
+	if v.secrets != nil && v.renewLeases(ctx, now) {
+		return v.secrets, nil
+	}
+	return v.read(ctx, now)
+}
+
+// renewLeases extends every lease, reporting false if a lease cannot be renewed or was
+// shortened to its end, so the secrets must be read again
+func (v *VaultSecretsProvider) renewLeases(ctx context.Context, now time.Time) bool {
+	if len(v.leases) == 0 {
+		return false
+	}
+	refreshAt := time.Time{}
+	for i, lease := range v.leases {
+		if !lease.renewable || !now.Before(lease.expiresAt) {
+			return false
+		}
+		var response struct {
+			LeaseDuration int64 `json:"lease_duration"`
+		}
+		request := map[string]interface{}{"lease_id": lease.id}
+		if err := v.call(ctx, http.MethodPut, "sys/leases/renew", request, &response); err != nil || response.LeaseDuration <= 0 {
+			return false
+		}
+		duration := time.Duration(response.LeaseDuration) * time.Second
+		v.leases[i].expiresAt = now.Add(duration)
+		if renewAt := now.Add(duration * 2 / 3); refreshAt.IsZero() || renewAt.Before(refreshAt) {
+			refreshAt = renewAt
+		}
+	}
+	v.refreshAt = refreshAt
+	return true
+}
+
+// read reads the credentials and, if configured, the TLS material
+func (v *VaultSecretsProvider) read(ctx context.Context, now time.Time) (*RedisCredentials, error) {
+	if v.config.Path == "" && v.config.TLSPath == "" {
+		return nil, fmt.Errorf("vault: no secret path configured")
+	}
+
+	secrets := &RedisCredentials{}
+	var leases []vaultLease
+	refreshAt := now.Add(v.config.RefreshInterval)
+
+	if v.config.Path != "" {
+		data, lease, err := v.readSecret(ctx, http.MethodGet, v.config.Path, nil, now)
+		if err != nil {
+			return nil, err
+		}
+		secrets.Username, _ = data[v.config.UsernameField].(string)
+		password, ok := data[v.config.PasswordField].(string)
+		if !ok {
+			return nil, fmt.Errorf("vault: secret %s has no field %s", v.config.Path, v.config.PasswordField)
+		}
+		secrets.Password = password
+		if lease != nil {
+			leases = append(leases, *lease)
+		}
+	}
+
+	if v.config.TLSPath != "" {
+		method := http.MethodGet
+		if v.config.TLSParams != nil {
+			method = http.MethodPost
+		}
+		data, lease, err := v.readSecret(ctx, method, v.config.TLSPath, v.config.TLSParams, now)
+		if err != nil {
+			return nil, err
+		}
+		if err := vaultTLSMaterial(data, secrets); err != nil {
+			return nil, fmt.Errorf("vault: secret %s: %w", v.config.TLSPath, err)
+		}
+		if lease != nil {
+			leases = append(leases, *lease)
+		}
+	}
+
+	for _, lease := range leases {
+		if renewAt := now.Add(lease.expiresAt.Sub(now) * 2 / 3); renewAt.Before(refreshAt) {
+			refreshAt = renewAt
+		}
+	}
+	v.secrets, v.leases, v.refreshAt = secrets, leases, refreshAt
+	return secrets, nil
+}
+
+// readSecret reads a secret, unwrapping KV version 2 data, and returns its lease if it has one
+func (v *VaultSecretsProvider) readSecret(ctx context.Context, method, path string, body interface{}, now time.Time) (map[string]interface{}, *vaultLease, error) {
+	var response struct {
+		LeaseID       string                 `json:"lease_id"`
+		Renewable     bool                   `json:"renewable"`
+		LeaseDuration int64                  `json:"lease_duration"`
+		Data          map[string]interface{} `json:"data"`
+	}
+	if err := v.call(ctx, method, path, body, &response); err != nil {
+		return nil, nil, err
+	}
+
+	data := response.Data
+	if inner, ok := data["data"].(map[string]interface{}); ok {
+		if _, kv2 := data["metadata"]; kv2 {
+			data = inner
+		}
+	}
+	if data == nil {
+		return nil, nil, fmt.Errorf("vault: secret %s has no data", path)
+	}
+
+	if response.LeaseDuration <= 0 {
+		return data, nil, nil
+	}
+	return data, &vaultLease{
+		id:        response.LeaseID,
+		renewable: response.Renewable && response.LeaseID != "",
+		expiresAt: now.Add(time.Duration(response.LeaseDuration) * time.Second),
+	}, nil
+}
+
+// vaultTLSMaterial parses the PEM certificate, key and CA of a secret into the secrets
+func vaultTLSMaterial(data map[string]interface{}, secrets *RedisCredentials) error {
+	certificate, _ := data["certificate"].(string)
+	privateKey, _ := data["private_key"].(string)
+	if certificate != "" || privateKey != "" {
+		pair, err := tls.X509KeyPair([]byte(certificate), []byte(privateKey))
+		if err != nil {
+			return fmt.Errorf("invalid client certificate: %w", err)
+		}
+		secrets.Certificate = &pair
+	}
+	if ca, _ := data["issuing_ca"].(string); ca != "" {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM([]byte(ca)) {
+			return fmt.Errorf("invalid issuing_ca")
+		}
+		secrets.RootCAs = pool
+	}
+	return nil
+}
+
+// call sends an authenticated request, logging in again once if Vault rejects the token
+func (v *VaultSecretsProvider) call(ctx context.Context, method, path string, body, out interface{}) error {
+	for attempt := 0; ; attempt++ {
+		token, err := v.authToken(ctx)
+		if err != nil {
+			return err
+		}
+		status, err := v.do(ctx, method, path, token, body, out)
+		if status == http.StatusForbidden && attempt == 0 && v.config.AuthMethod != VaultAuthToken {
+			v.token = "" // Expired or revoked before its lease ended
+			continue
+		}
+		return err
+	}
+}
+
+// authToken returns the Vault token, logging in if there is none or it is due for renewal
+func (v *VaultSecretsProvider) authToken(ctx context.Context) (string, error) {
+	if v.config.AuthMethod == VaultAuthToken {
+		if v.config.Token == "" {
+			return "", fmt.Errorf("vault: no token configured")
+		}
+		return v.config.Token, nil
+	}
+	if v.token != "" && (v.tokenExpiry.IsZero() || v.now().Before(v.tokenExpiry)) {
+		return v.token, nil
+	}
+
+	var login map[string]interface{}
+	switch v.config.AuthMethod {
+	case VaultAuthAppRole:
+		login = map[string]interface{}{"role_id": v.config.RoleID, "secret_id": v.config.SecretID}
+	case VaultAuthKubernetes:
+		jwt, err := os.ReadFile(v.config.JWTPath)
+		if err != nil {
+			return "", fmt.Errorf("vault: failed to read service account token: %w", err)
+		}
+		login = map[string]interface{}{"role": v.config.Role, "jwt": strings.TrimSpace(string(jwt))}
+	default:
+		return "", fmt.Errorf("vault: unsupported auth method %q", v.config.AuthMethod)
+	}
+
+	var response struct {
+		Auth *struct {
+			ClientToken   string `json:"client_token"`
+			LeaseDuration int64  `json:"lease_duration"`
+		} `json:"auth"`
+	}
+	path := "auth/" + strings.Trim(v.config.AuthMount, "/") + "/login"
+	if _, err := v.do(ctx, http.MethodPost, path, "", login, &response); err != nil {
+		return "", err
+	}
+	if response.Auth == nil || response.Auth.ClientToken == "" {
+		return "", fmt.Errorf("vault: %s login returned no token", v.config.AuthMethod)
+	}
+
+	v.token = response.Auth.ClientToken
+	v.tokenExpiry = time.Time{}
+	if response.Auth.LeaseDuration > 0 {
+		v.tokenExpiry = v.now().Add(time.Duration(response.Auth.LeaseDuration) * time.Second * 2 / 3)
+	}
+	return v.token, nil
+}
+
+// do sends one request to the Vault API, returning the status code with any error
+func (v *VaultSecretsProvider) do(ctx context.Context, method, path, token string, body, out interface{}) (int, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return 0, fmt.Errorf("vault: failed to encode request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, v.config.Address+"/v1/"+path, reader)
+	if err != nil {
+		return 0, fmt.Errorf("vault: failed to create request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("X-Vault-Token", token)
+	}
+	if v.config.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", v.config.Namespace)
+	}
+
+	resp, err := httpClient(v.config.HTTPClient).Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("vault: request to %s failed: %w", path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return resp.StatusCode, fmt.Errorf("vault: %s returned %d: %s", path, resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return resp.StatusCode, fmt.Errorf("vault: invalid response from %s: %w", path, err)
+	}
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,249 @@
+// vault_test.go
+package ratelimit
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeVault serves AppRole login, dynamic credentials with renewable leases and a KV v2 secret
+type fakeVault struct {
+	mu         sync.Mutex
+	logins     int
+	reads      int
+	renewals   int
+	generation int  // Suffix of the dynamic password, bumped on every read
+	maxedOut   bool // Renewals return a zero lease, as at the lease's max TTL
+}
+
+func (f *fakeVault) handler(t *testing.T) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+
+		if r.URL.Path == "/v1/auth/approle/login" {
+			var login map[string]string
+			json.NewDecoder(r.Body).Decode(&login)
+			if login["role_id"] != "gorly" || login["secret_id"] != "s3cret" {
+				http.Error(w, `{"errors":["invalid role or secret ID"]}`, http.StatusBadRequest)
+				return
+			}
+			f.logins++
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"auth": map[string]interface{}{"client_token": "approle-token", "lease_duration": 3600},
+			})
+			return
+		}
+		if token := r.Header.Get("X-Vault-Token"); token != "approle-token" && token != "static-token" {
+			http.Error(w, `{"errors":["permission denied"]}`, http.StatusForbidden)
+			return
+		}
+
+		switch r.URL.Path {
+		case "/v1/database/creds/gorly":
+			f.reads++
+			f.generation++
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"lease_id":       "database/creds/gorly/lease",
+				"renewable":      true,
+				"lease_duration": 60,
+				"data":           map[string]interface{}{"username": "v-gorly", "password": fmt.Sprintf("pw-%d", f.generation)},
+			})
+		case "/v1/sys/leases/renew":
+			f.renewals++
+			duration := 60
+			if f.maxedOut {
+				duration = 0
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"lease_id": "database/creds/gorly/lease", "lease_duration": duration})
+		case "/v1/secret/data/gorly/redis":
+			f.reads++
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"data": map[string]interface{}{
+					"data":     map[string]interface{}{"pass": "kv-password"},
+					"metadata": map[string]interface{}{"version": 3},
+				},
+			})
+		case "/v1/pki/issue/redis":
+			var params map[string]interface{}
+			json.NewDecoder(r.Body).Decode(&params)
+			if r.Method != http.MethodPost || params["common_name"] != "gorly.internal" {
+				http.Error(w, `{"errors":["common_name required"]}`, http.StatusBadRequest)
+				return
+			}
+			certificate, key := testCertificatePEM(t)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"data": map[string]interface{}{"certificate": certificate, "private_key": key, "issuing_ca": certificate},
+			})
+		default:
+			http.NotFound(w, r)
+		}
+	})
+}
+
+// testCertificatePEM returns a self-signed certificate and its key
+func testCertificatePEM(t *testing.T) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "gorly.internal"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IsCA:         true,
+		KeyUsage:     x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("Failed to encode key: %v", err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+		string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}))
+}
+
+func TestVaultSecretsProvider_DynamicCredentials(t *testing.T) {
+	vault := &fakeVault{}
+	server := httptest.NewServer(vault.handler(t))
+	defer server.Close()
+
+	provider := NewVaultSecretsProvider(VaultConfig{
+		Address:    server.URL,
+		AuthMethod: VaultAuthAppRole,
+		RoleID:     "gorly",
+		SecretID:   "s3cret",
+		Path:       "database/creds/gorly",
+	})
+	now := time.Now()
+	provider.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	secrets, err := provider.Secrets(ctx)
+	if err != nil {
+		t.Fatalf("Secrets failed: %v", err)
+	}
+	if secrets.Username != "v-gorly" || secrets.Password != "pw-1" {
+		t.Fatalf("Unexpected secrets %+v", secrets)
+	}
+
+	// Cached until two thirds of the lease
+	now = now.Add(30 * time.Second)
+	provider.Secrets(ctx)
+	if vault.reads != 1 || vault.renewals != 0 {
+		t.Fatalf("Expected the cached secrets, got %d reads and %d renewals", vault.reads, vault.renewals)
+	}
+
+	// Renewed afterwards, keeping the credentials
+	now = now.Add(15 * time.Second)
+	secrets, err = provider.Secrets(ctx)
+	if err != nil || secrets.Password != "pw-1" || vault.renewals != 1 {
+		t.Fatalf("Expected a renewal of the same credentials, got %+v, %v, %d renewals", secrets, err, vault.renewals)
+	}
+
+	// Read again once the lease cannot be extended
+	vault.maxedOut = true
+	now = now.Add(45 * time.Second)
+	secrets, err = provider.Secrets(ctx)
+	if err != nil || secrets.Password != "pw-2" {
+		t.Fatalf("Expected new credentials, got %+v, %v", secrets, err)
+	}
+	if vault.logins != 1 {
+		t.Errorf("Expected one login, got %d", vault.logins)
+	}
+}
+
+func TestVaultSecretsProvider_KVAndTLS(t *testing.T) {
+	vault := &fakeVault{}
+	server := httptest.NewServer(vault.handler(t))
+	defer server.Close()
+
+	t.Setenv("VAULT_ADDR", server.URL)
+	t.Setenv("VAULT_TOKEN", "static-token")
+	provider := NewVaultSecretsProvider(VaultConfig{
+		Path:          "secret/data/gorly/redis",
+		PasswordField: "pass",
+		TLSPath:       "pki/issue/redis",
+		TLSParams:     map[string]interface{}{"common_name": "gorly.internal"},
+	})
+	now := time.Now()
+	provider.now = func() time.Time { return now }
+
+	secrets, err := provider.Secrets(context.Background())
+	if err != nil {
+		t.Fatalf("Secrets failed: %v", err)
+	}
+	if secrets.Password != "kv-password" || secrets.Username != "" {
+		t.Errorf("Expected the unwrapped KV v2 secret, got %+v", secrets)
+	}
+	if secrets.Certificate == nil || secrets.RootCAs == nil {
+		t.Errorf("Expected the issued certificate and CA")
+	}
+
+	// Secrets without a lease are read again after the refresh interval
+	now = now.Add(6 * time.Minute)
+	provider.Secrets(context.Background())
+	if vault.reads != 2 {
+		t.Errorf("Expected the KV secret to be read again, got %d reads", vault.reads)
+	}
+
+	// A rejected token fails
+	provider = NewVaultSecretsProvider(VaultConfig{Address: server.URL, Token: "revoked", Path: "secret/data/gorly/redis"})
+	if _, err := provider.Secrets(context.Background()); err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("Expected the permission error, got %v", err)
+	}
+}
+
+func TestConfigLoader_Vault(t *testing.T) {
+	t.Setenv("GORLY_TEST_SECRET_ID", "s3cret")
+	config := `
+store: redis
+redis:
+  address: redis:6379
+  vault:
+    address: https://vault:8200
+    authMethod: approle
+    roleId: gorly
+    secretId: ${GORLY_TEST_SECRET_ID}
+    path: database/creds/gorly
+    refreshInterval: 1m
+`
+	loaded, err := NewConfigLoader().WithStrict(true).LoadFromYAML(strings.NewReader(config))
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	vault := loaded.Redis.Vault
+	if vault == nil || vault.AuthMethod != VaultAuthAppRole || vault.SecretID != "s3cret" || vault.RefreshInterval != time.Minute {
+		t.Fatalf("Unexpected vault config %+v", vault)
+	}
+	if loaded.Redis.secretsProvider() == nil {
+		t.Error("Expected a Vault secrets provider")
+	}
+
+	loaded.Redis.Vault = &VaultConfig{AuthMethod: "ldap", Path: "database/creds/gorly"}
+	if err := loaded.Validate(); err == nil {
+		t.Error("Expected an unsupported auth method to fail validation")
+	}
+	loaded.Redis.Vault = &VaultConfig{}
+	if err := loaded.Validate(); err == nil {
+		t.Error("Expected a vault config without a path to fail validation")
+	}
+}
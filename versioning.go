@@ -0,0 +1,56 @@
+// versioning.go - API version extraction for versioned scopes
+package ratelimit
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/itsatony/gorly/internal/core"
+)
+
+// extractAPIVersion finds the API version of a request from the X-API-Version header,
+// the Accept header (vendor media type or version parameter) or a /v1/ path segment
+func extractAPIVersion(r *http.Request) string {
+	if version := core.NormalizeVersion(r.Header.Get("X-API-Version")); version != "" {
+		return version
+	}
+
+	if version := acceptVersion(r.Header.Get("Accept")); version != "" {
+		return version
+	}
+
+	for _, segment := range strings.Split(r.URL.Path, "/") {
+		if version := strings.ToLower(segment); core.IsVersion(version) {
+			return version
+		}
+	}
+
+	return ""
+}
+
+// acceptVersion extracts a version from "application/vnd.acme.v2+json" or "application/json; version=2"
+func acceptVersion(accept string) string {
+	for _, mediaRange := range strings.Split(accept, ",") {
+		parts := strings.Split(mediaRange, ";")
+
+		for _, param := range parts[1:] {
+			if key, value, ok := strings.Cut(strings.TrimSpace(param), "="); ok && strings.EqualFold(key, "version") {
+				if version := core.NormalizeVersion(value); version != "" {
+					return version
+				}
+			}
+		}
+
+		mediaType := strings.ToLower(strings.TrimSpace(parts[0]))
+		if !strings.Contains(mediaType, "vnd.") {
+			continue
+		}
+		mediaType, _, _ = strings.Cut(mediaType, "+")
+		for _, token := range strings.FieldsFunc(mediaType, func(r rune) bool { return r == '.' || r == '/' || r == '-' }) {
+			if core.IsVersion(token) {
+				return token
+			}
+		}
+	}
+	return ""
+}
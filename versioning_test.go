@@ -0,0 +1,78 @@
+// versioning_test.go - Tests for API version scoping
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestExtractAPIVersion(t *testing.T) {
+	tests := []struct {
+		name     string
+		path     string
+		headers  map[string]string
+		expected string
+	}{
+		{"header", "/search", map[string]string{"X-API-Version": "2"}, "v2"},
+		{"vendor media type", "/search", map[string]string{"Accept": "application/vnd.acme.v3+json"}, "v3"},
+		{"accept parameter", "/search", map[string]string{"Accept": "application/json; version=1.1"}, "v1.1"},
+		{"path segment", "/api/V1/search", nil, "v1"},
+		{"header wins over path", "/v1/search", map[string]string{"X-API-Version": "v2"}, "v2"},
+		{"unversioned", "/search", map[string]string{"Accept": "application/json"}, ""},
+		{"not a version", "/video/search", map[string]string{"X-API-Version": "latest"}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if version := extractAPIVersion(createTestRequest("GET", tt.path, tt.headers)); version != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, version)
+			}
+		})
+	}
+}
+
+func TestVersionedScopes(t *testing.T) {
+	limiter, err := New().
+		Limit("global", "100/minute").
+		Limit("search", "3/minute").
+		Limit("v1:search", "1/minute").
+		ScopeFunc(func(r *http.Request) string {
+			if r.URL.Query().Get("q") != "" {
+				return "search"
+			}
+			return "global"
+		}).
+		VersionedScopes().
+		Build()
+	if err != nil {
+		t.Fatalf("Failed to build limiter: %v", err)
+	}
+	defer limiter.Close()
+
+	handler := limiter.For(HTTP).(func(http.Handler) http.Handler)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	serve := func(version string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, createTestRequest("GET", "/search?q=go", map[string]string{"X-API-Version": version}))
+		return w
+	}
+
+	// Legacy version is throttled harder
+	serve("1")
+	if w := serve("1"); w.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected v1 search to be limited after 1 request, got %d", w.Code)
+	}
+
+	// v2 inherits the plain search limit with its own counter
+	if w := serve("2"); w.Code != http.StatusOK || w.Header().Get("X-RateLimit-Limit") != "3" {
+		t.Errorf("Expected v2 to use the search limit, got %d with limit %q", w.Code, w.Header().Get("X-RateLimit-Limit"))
+	}
+
+	// Unversioned requests keep the plain scope
+	if w := serve(""); w.Code != http.StatusOK || w.Header().Get("X-RateLimit-Remaining") != "2" {
+		t.Errorf("Expected unversioned search on its own counter, got %d with remaining %q", w.Code, w.Header().Get("X-RateLimit-Remaining"))
+	}
+}